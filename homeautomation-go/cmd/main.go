@@ -2,36 +2,91 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"homeautomation/internal/api"
+	"homeautomation/internal/areas"
+	"homeautomation/internal/buildinfo"
 	"homeautomation/internal/config"
+	"homeautomation/internal/configcheck"
 	dayphaselib "homeautomation/internal/dayphase"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/entities"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/focusmode"
+	"homeautomation/internal/forecast"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/journal"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/lifecycle"
+	"homeautomation/internal/metrics"
+	"homeautomation/internal/mqtt"
+	"homeautomation/internal/namespace"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/plugins/bedroomcomfort"
+	"homeautomation/internal/plugins/climate"
 	"homeautomation/internal/plugins/dayphase"
+	"homeautomation/internal/plugins/emergency"
 	"homeautomation/internal/plugins/energy"
+	"homeautomation/internal/plugins/freezeprotection"
+	"homeautomation/internal/plugins/growlights"
+	"homeautomation/internal/plugins/hotwater"
 	"homeautomation/internal/plugins/lighting"
 	"homeautomation/internal/plugins/loadshedding"
+	"homeautomation/internal/plugins/lowbattery"
 	"homeautomation/internal/plugins/music"
+	"homeautomation/internal/plugins/openreminder"
 	"homeautomation/internal/plugins/reset"
+	"homeautomation/internal/plugins/rules"
+	"homeautomation/internal/plugins/scenescheduler"
 	"homeautomation/internal/plugins/security"
+	"homeautomation/internal/plugins/sleepfan"
 	"homeautomation/internal/plugins/sleephygiene"
 	"homeautomation/internal/plugins/statetracking"
 	"homeautomation/internal/plugins/tv"
+	"homeautomation/internal/ratelimit"
+	"homeautomation/internal/reports"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/scheduler"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
+	"homeautomation/internal/statediff"
+	"homeautomation/internal/tts"
+	"homeautomation/internal/warmup"
+	"homeautomation/internal/writescope"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 )
 
 func main() {
+	// Subcommands run instead of the automation service
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(runValidateConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lighting-sim" {
+		os.Exit(runLightingSim(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "state-diff" {
+		os.Exit(runStateDiff(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Initialize logger
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -40,6 +95,19 @@ func main() {
 	}
 	defer logger.Sync()
 
+	build := buildinfo.Get()
+	logger.Info("Starting home automation",
+		zap.String("version", build.Version),
+		zap.String("commit", build.Commit),
+		zap.String("build_time", build.BuildTime),
+		zap.String("go_version", build.GoVersion),
+		zap.Bool("modified", build.Modified))
+
+	// Plugin managers run under ctx; cancelling it on shutdown interrupts
+	// their waits and retries before the deferred Stops run
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Load environment variables from .env file if present
 	if err := godotenv.Load(); err != nil {
 		logger.Info("No .env file found, using environment variables")
@@ -47,9 +115,10 @@ func main() {
 
 	haURL := os.Getenv("HA_URL")
 	haToken := os.Getenv("HA_TOKEN")
-	readOnly := os.Getenv("READ_ONLY") == "true"
+	// SIMULATION runs against an in-memory HA seeded from SIMULATION_SNAPSHOT
+	simulation := os.Getenv("SIMULATION") == "true"
 
-	if haURL == "" || haToken == "" {
+	if !simulation && (haURL == "" || haToken == "") {
 		logger.Fatal("HA_URL and HA_TOKEN environment variables must be set")
 	}
 
@@ -63,10 +132,41 @@ func main() {
 		}
 	}
 
-	// Load timezone (default to UTC if not set)
+	// Requests slower than this are logged with their route and status
+	slowRequestThreshold := api.DefaultSlowRequestThreshold
+	if thresholdStr := os.Getenv("HTTP_SLOW_REQUEST_THRESHOLD"); thresholdStr != "" {
+		if threshold, err := time.ParseDuration(thresholdStr); err == nil && threshold >= 0 {
+			slowRequestThreshold = threshold
+		} else {
+			logger.Warn("Invalid HTTP_SLOW_REQUEST_THRESHOLD value, using default", zap.String("value", thresholdStr), zap.Duration("default", api.DefaultSlowRequestThreshold))
+		}
+	}
+
+	// Retries of sensitive commands (garage open, unlock) within this window are not re-sent
+	idempotencyWindow := ha.DefaultIdempotencyWindow
+	if windowStr := os.Getenv("HA_IDEMPOTENCY_WINDOW"); windowStr != "" {
+		if window, err := time.ParseDuration(windowStr); err == nil && window >= 0 {
+			idempotencyWindow = window
+		} else {
+			logger.Warn("Invalid HA_IDEMPOTENCY_WINDOW value, using default", zap.String("value", windowStr), zap.Duration("default", ha.DefaultIdempotencyWindow))
+		}
+	}
+
+	// Warn at startup about state writes that cascade deeper than this
+	maxCascadeDepth := state.DefaultMaxCascadeDepth
+	if depthStr := os.Getenv("STATE_MAX_CASCADE_DEPTH"); depthStr != "" {
+		if depth, err := strconv.Atoi(depthStr); err == nil && depth > 0 {
+			maxCascadeDepth = depth
+		} else {
+			logger.Warn("Invalid STATE_MAX_CASCADE_DEPTH value, using default", zap.String("value", depthStr), zap.Int("default", state.DefaultMaxCascadeDepth))
+		}
+	}
+
+	// Load timezone. Required: schedules are wall-clock times, and containers
+	// usually run in UTC
 	timezoneName := os.Getenv("TIMEZONE")
 	if timezoneName == "" {
-		timezoneName = "UTC"
+		logger.Fatal("TIMEZONE environment variable must be set (e.g. America/Chicago, or UTC)")
 	}
 	timezone, err := time.LoadLocation(timezoneName)
 	if err != nil {
@@ -76,19 +176,15 @@ func main() {
 	}
 	logger.Info("Using timezone", zap.String("timezone", timezoneName))
 
-	// Determine config directory path
-	// Priority: CONFIG_DIR env var > ./configs (container) > ../configs (local dev)
-	configDir := os.Getenv("CONFIG_DIR")
-	if configDir == "" {
-		// Auto-detect: prefer ./configs if it exists (container), otherwise ../configs (local dev)
-		if _, err := os.Stat("./configs"); err == nil {
-			configDir = "./configs"
-		} else {
-			configDir = "../configs"
-		}
-	}
+	configDir := resolveConfigDir()
 	logger.Info("Using config directory", zap.String("path", configDir))
 
+	// Which plugins and service domains may write to Home Assistant
+	writeScopes, err := loadWriteScopes(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load write scopes", zap.Error(err))
+	}
+
 	// Get location coordinates for sun event calculations
 	// Default: Austin, TX area (32.85486, -97.50515)
 	latitude := 32.85486
@@ -116,21 +212,92 @@ func main() {
 
 	logger.Info("Starting Home Automation Client",
 		zap.String("url", haURL),
-		zap.Bool("read_only", readOnly))
+		zap.Stringer("write_scopes", writeScopes),
+		zap.Bool("simulation", simulation))
+
+	// Load named entity groups before anything makes service calls
+	entityGroups, err := loadEntityGroups(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load entity groups config", zap.Error(err))
+	}
 
-	// Create HA client
-	client := ha.NewClient(haURL, haToken, logger)
+	// Metrics served on /metrics for Prometheus
+	metricsRegistry := metrics.NewRegistry()
+
+	// Create HA client, expanding entity group references in service calls.
+	// In simulation the virtual client stands in for Home Assistant.
+	var haClient interface {
+		ha.HAClient
+		ha.RegistryClient
+	}
+	var virtualClient *ha.VirtualClient
+	if simulation {
+		virtualClient, err = newVirtualClient(logger)
+		if err != nil {
+			logger.Fatal("Failed to start simulation", zap.Error(err))
+		}
+		haClient = virtualClient
+	} else {
+		realClient := ha.NewClient(haURL, haToken, logger)
+		realClient.SetIdempotency(idempotencyWindow, ha.DefaultIdempotentServices)
+		realClient.SetMetrics(metricsRegistry)
+		haClient = realClient
+	}
+	client := entitygroups.NewClient(haClient, entityGroups)
 
-	// Connect to Home Assistant
-	if err := client.Connect(); err != nil {
+	// Connect to Home Assistant (bounded by ha.DefaultRequestTimeout)
+	if err := client.Connect(context.Background()); err != nil {
 		logger.Fatal("Failed to connect to Home Assistant", zap.Error(err))
 	}
 	defer client.Disconnect()
 
 	logger.Info("Connected to Home Assistant")
 
+	// Fail fast if an entity the plugins refer to by name was renamed or
+	// removed in Home Assistant, listing every missing one
+	entityRegistry, err := loadEntities(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load entities config", zap.Error(err))
+	}
+	if err := entityRegistry.Verify(ctx, client); err != nil {
+		var missingErr *entities.MissingError
+		if errors.As(err, &missingErr) {
+			for _, m := range missingErr.Missing {
+				logger.Error("Entity not found in Home Assistant",
+					zap.String("name", string(m.Name)),
+					zap.String("entity_id", m.EntityID))
+			}
+		}
+		logger.Fatal("Entity check failed, fix entities_config.yaml or Home Assistant", zap.Error(err))
+	}
+	logger.Info("Verified entities", zap.Int("entities", len(entities.Names())))
+
+	// Cache HA's area registry so configs can refer to areas instead of
+	// listing their entities
+	areaRegistry := areas.NewRegistry(haClient, logger)
+	if err := areaRegistry.Start(time.Duration(entityGroups.AreaRefreshMinutes) * time.Minute); err != nil {
+		logger.Warn("Failed to load HA area registry, area references will not resolve", zap.Error(err))
+	}
+	defer areaRegistry.Stop()
+	entityGroups.SetAreaResolver(areaRegistry)
+
 	// Create State Manager
-	stateManager := state.NewManager(client, logger, readOnly)
+	stateManager := state.NewManager(client, logger, writeScopes.ReadOnly("state"))
+	stateManager.SetMetrics(metricsRegistry)
+
+	// Read variables straight from HA if anything asks before the sync below fills the cache
+	stateManager.SetReadThrough(stateReadThroughTimeout)
+
+	// Register namespaces (e.g. a rental suite) so their variables are synced below
+	namespaces, err := registerNamespaces(stateManager, logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load namespace config", zap.Error(err))
+	}
+
+	// Register music zones so each zone's musicPlaybackType.<zone> is synced below
+	if err := registerMusicZones(stateManager, logger, configDir); err != nil {
+		logger.Fatal("Failed to register music zones", zap.Error(err))
+	}
 
 	// Sync all state from HA
 	if err := stateManager.SyncFromHA(); err != nil {
@@ -141,6 +308,41 @@ func main() {
 	if err := stateManager.SetupComputedState(); err != nil {
 		logger.Fatal("Failed to setup computed state", zap.Error(err))
 	}
+	for _, ns := range namespaces {
+		if !ns.config.HasVariable("isAnyoneHomeAndAwake") {
+			continue
+		}
+		if err := ns.state.SetupComputedState(); err != nil {
+			logger.Fatal("Failed to setup computed state",
+				zap.String("namespace", ns.config.Name),
+				zap.Error(err))
+		}
+	}
+
+	// Keep plugins from acting on half-synced state right after startup
+	warmupGate, err := loadWarmupGate(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load warm-up config", zap.Error(err))
+	}
+
+	// Keep a misbehaving plugin from flooding Home Assistant with service calls
+	rateLimiter, err := loadRateLimiter(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load rate limits config", zap.Error(err))
+	}
+
+	// Resolve plugins sending conflicting commands to the same entity
+	arbiter := ha.NewArbiter(ha.DefaultCommandPriorities, ha.DefaultArbiterHold, logger)
+
+	// Each plugin's HA client: writes are held back during warm-up, limited
+	// to the plugin's write scope, rate limited and arbitrated against other
+	// plugins
+	pluginClient := func(plugin string) ha.HAClient {
+		return writeScopes.Client(plugin, warmupGate.Client(plugin, rateLimiter.Client(plugin, arbiter.Client(plugin, client))))
+	}
+
+	// Re-sync state after the HA client reconnects and publish isHomeAssistantConnected
+	watchHAConnection(haClient, stateManager, logger)
 
 	// Create Shadow State Tracker
 	shadowTracker := shadowstate.NewTracker()
@@ -152,6 +354,12 @@ func main() {
 
 	// Start HTTP API server
 	apiServer := api.NewServer(stateManager, shadowTracker, logger, httpPort, timezone)
+	apiServer.SetSlowRequestThreshold(slowRequestThreshold)
+	apiServer.SetMetrics(metricsRegistry)
+	// Writes over the API need this bearer token; without it they are off
+	apiServer.SetWriteToken(os.Getenv("API_TOKEN"))
+	// /api/diff compares this instance with PEER_URL, e.g. the live one during a blue/green rollout
+	apiServer.SetDiffPeer(os.Getenv("PEER_URL"))
 	if err := apiServer.Start(); err != nil {
 		logger.Fatal("Failed to start HTTP API server", zap.Error(err))
 	}
@@ -160,117 +368,234 @@ func main() {
 		zap.Int("port", httpPort),
 		zap.String("endpoint", fmt.Sprintf("http://localhost:%d/api/state", httpPort)))
 
+	// Start Event Journal (recent state changes and plugin actions for /api/history)
+	eventJournal := journal.New(stateManager, shadowTracker, logger, journal.Capacity)
+	if err := eventJournal.Start(); err != nil {
+		logger.Fatal("Failed to start event journal", zap.Error(err))
+	}
+	defer eventJournal.Stop()
+	apiServer.SetHistoryProvider(eventJournal)
+
+	// Start MQTT bridge (mirrors state variables to and from an MQTT broker)
+	mqttBridge, err := startMQTTBridge(stateManager, logger, writeScopes.ReadOnly("mqtt"), simulation, configDir)
+	if err != nil {
+		logger.Fatal("Failed to start MQTT bridge", zap.Error(err))
+	}
+	if mqttBridge != nil {
+		defer mqttBridge.Stop()
+	}
+
+	// Start the solar forecast client (feeds the energy plugin's look-ahead)
+	forecastClient, err := startSolarForecast(stateManager, logger, simulation, configDir)
+	if err != nil {
+		logger.Fatal("Failed to start solar forecast client", zap.Error(err))
+	}
+	if forecastClient != nil {
+		defer forecastClient.Stop()
+	}
+
 	// Display current state
 	displayState(stateManager, logger)
 
 	// Subscribe to interesting state changes
 	subscribeToChanges(stateManager, logger)
 
+	// Load per-bedroom do-not-disturb toggles (shared by several plugins)
+	dndGuard, err := loadDoNotDisturbGuard(stateManager, logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load do-not-disturb config", zap.Error(err))
+	}
+
+	// Load the evening notification silencing schedule (shared by several plugins)
+	notificationRouter, err := loadNotificationRouter(stateManager, logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load notification router config", zap.Error(err))
+	}
+
+	// Load the TTS announcer (queues announcements from every plugin)
+	announcer, err := loadAnnouncer(stateManager, notificationRouter, logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load TTS config", zap.Error(err))
+	}
+	defer announcer.Stop()
+
+	// Load office focus mode (its guard is shared by several plugins; the manager starts after them)
+	focusModeConfig, err := loadFocusModeConfig(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load focus mode config", zap.Error(err))
+	}
+	var focusGuard *focusmode.Guard
+	if focusModeConfig != nil {
+		focusGuard = focusmode.NewGuard(focusModeConfig, stateManager)
+	}
+
+	// Load kid mode (its guard is shared by several plugins; the manager starts after them)
+	kidModeConfig, err := loadKidModeConfig(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load kid mode config", zap.Error(err))
+	}
+	var kidGuard *kidmode.Guard
+	if kidModeConfig != nil {
+		kidGuard = kidmode.NewGuard(kidModeConfig, stateManager)
+	}
+
 	// Start State Tracking Manager (MUST start before other plugins that depend on derived states)
-	stateTrackingManager := statetracking.NewManager(client, stateManager, logger, readOnly, subscriptionRegistry)
-	if err := stateTrackingManager.Start(); err != nil {
+	stateTrackingManager := statetracking.NewManager(pluginClient("statetracking"), stateManager, logger, writeScopes.ReadOnly("statetracking"), subscriptionRegistry)
+	stateTrackingManager.SetEntities(entityRegistry)
+	stateTrackingManager.SetDoNotDisturb(dndGuard)
+	stateTrackingManager.SetAnnouncer(announcer)
+	stateTrackingManager.SetFocusMode(focusGuard)
+	consistencyConfig, err := loadConsistencyCheckConfig(logger, configDir)
+	if err != nil {
+		logger.Fatal("Failed to load consistency check config", zap.Error(err))
+	}
+	stateTrackingManager.SetConsistencyCheck(consistencyConfig, timezone)
+	if err := stateTrackingManager.Start(ctx); err != nil {
 		logger.Fatal("Failed to start State Tracking Manager", zap.Error(err))
 	}
 	defer stateTrackingManager.Stop()
 	logger.Info("State Tracking Manager started - computing derived states and sleep detection")
 
+	// Create the scheduler shared by every plugin's cron, sun event and one-shot jobs
+	jobScheduler := scheduler.New(logger, timezone)
+	jobScheduler.SetLocation(latitude, longitude)
+	defer jobScheduler.Stop()
+	apiServer.SetJobScheduler(jobScheduler)
+
 	// Create day phase calculator
 	dayPhaseCalc := dayphaselib.NewCalculator(latitude, longitude, logger)
+	dayPhaseCalc.SetTimezone(timezone)
 
 	// Start Day Phase Manager (sun events and day phase)
-	dayPhaseManager, err := startDayPhaseManager(client, stateManager, logger, readOnly, configDir, dayPhaseCalc)
+	dayPhaseManager, err := startDayPhaseManager(ctx, pluginClient("dayphase"), stateManager, logger, writeScopes.ReadOnly("dayphase"), configDir, timezone, dayPhaseCalc)
 	if err != nil {
 		logger.Fatal("Failed to start Day Phase Manager", zap.Error(err))
 	}
 	defer dayPhaseManager.Stop()
 
+	// Plugins below are started through the lifecycle manager so they can be
+	// disabled and re-enabled at runtime; disabled ones aren't started at all
+	pluginLifecycle, err := newPluginLifecycle(ctx, logger, configDir, shadowTracker)
+	if err != nil {
+		logger.Fatal("Failed to load plugins config", zap.Error(err))
+	}
+	defer pluginLifecycle.StopAll()
+	apiServer.SetPluginController(pluginLifecycle)
+	addPlugin := func(name string, plugin lifecycle.Plugin, provider func() shadowstate.PluginShadowState) {
+		if err := pluginLifecycle.Add(name, plugin, provider); err != nil {
+			logger.Fatal("Failed to start plugin", zap.String("plugin", name), zap.Error(err))
+		}
+		if checker, ok := plugin.(health.Checker); ok {
+			apiServer.AddHealthChecker(name, checker)
+		}
+	}
+
+	// Battery safe mode is turned on and off by the energy plugin and read
+	// by the plugins that change their behavior in it
+	safeModeSwitch := safemode.NewSwitch()
+
 	// Start Energy State Manager
-	energyManager, err := startEnergyManager(client, stateManager, logger, readOnly, configDir, timezone, subscriptionRegistry)
+	energyManager, err := newEnergyManager(pluginClient("energy"), stateManager, logger, writeScopes.ReadOnly("energy"), configDir, timezone, subscriptionRegistry)
 	if err != nil {
-		logger.Fatal("Failed to start Energy State Manager", zap.Error(err))
+		logger.Fatal("Failed to create Energy State Manager", zap.Error(err))
 	}
-	defer energyManager.Stop()
+	energyManager.SetEntities(entityRegistry)
+	energyManager.SetNotificationRouter(notificationRouter)
+	energyManager.SetSafeMode(safeModeSwitch)
+	addPlugin("energy", energyManager, func() shadowstate.PluginShadowState {
+		return energyManager.GetShadowState()
+	})
 
 	// Start Music Manager
-	musicManager, err := startMusicManager(client, stateManager, logger, readOnly, configDir)
+	musicManager, err := newMusicManager(pluginClient("music"), stateManager, logger, writeScopes.ReadOnly("music"), configDir, timezone, dndGuard, jobScheduler)
 	if err != nil {
-		logger.Fatal("Failed to start Music Manager", zap.Error(err))
+		logger.Fatal("Failed to create Music Manager", zap.Error(err))
 	}
-	defer musicManager.Stop()
-
-	// Register music shadow state provider with tracker
-	shadowTracker.RegisterPluginProvider("music", func() shadowstate.PluginShadowState {
+	musicManager.SetKidMode(kidGuard)
+	addPlugin("music", musicManager, func() shadowstate.PluginShadowState {
 		return musicManager.GetShadowState()
 	})
-	logger.Info("Registered music shadow state with tracker")
+	apiServer.SetSpeakerGroupController(musicManager)
+	apiServer.SetNowPlayingProvider(musicManager)
 
 	// Start Lighting Manager
-	lightingManager, err := startLightingManager(client, stateManager, logger, readOnly, configDir, subscriptionRegistry)
+	lightingManager, err := newLightingManager(pluginClient("lighting"), stateManager, logger, writeScopes.ReadOnly("lighting"), configDir, timezone, subscriptionRegistry, focusGuard, jobScheduler)
 	if err != nil {
-		logger.Fatal("Failed to start Lighting Manager", zap.Error(err))
+		logger.Fatal("Failed to create Lighting Manager", zap.Error(err))
 	}
-	defer lightingManager.Stop()
-
-	// Register lighting shadow state provider with tracker
-	shadowTracker.RegisterPluginProvider("lighting", func() shadowstate.PluginShadowState {
+	lightingManager.SetSafeMode(safeModeSwitch)
+	addPlugin("lighting", lightingManager, func() shadowstate.PluginShadowState {
 		return lightingManager.GetShadowState()
 	})
-	logger.Info("Registered lighting shadow state with tracker")
+
+	// Apply edits to the energy, music and hue configs without a restart
+	configWatcher := config.NewWatcher(config.DefaultWatchInterval, logger)
+	config.WatchFile(configWatcher, filepath.Join(configDir, "energy_config.yaml"), energy.LoadConfig, energyManager.Reload)
+	config.WatchFile(configWatcher, filepath.Join(configDir, "music_config.yaml"), music.LoadConfig, musicManager.Reload)
+	config.WatchFile(configWatcher, filepath.Join(configDir, "hue_config.yaml"), lighting.LoadConfig, lightingManager.Reload)
+	configWatcher.Start()
+	defer configWatcher.Stop()
 
 	// Start Security Manager
-	securityManager := security.NewManager(client, stateManager, logger, readOnly, subscriptionRegistry)
-	if err := securityManager.Start(); err != nil {
-		logger.Fatal("Failed to start Security Manager", zap.Error(err))
+	securityConfig, err := security.LoadConfig(filepath.Join(configDir, "security_config.yaml"))
+	if err != nil {
+		logger.Fatal("Failed to load security config", zap.Error(err))
 	}
-	defer securityManager.Stop()
-	logger.Info("Security Manager started successfully")
-
-	// Register security shadow state provider with tracker
-	shadowTracker.RegisterPluginProvider("security", func() shadowstate.PluginShadowState {
+	logger.Info("Loaded security configuration",
+		zap.Int("camera_privacy_switches", len(securityConfig.Security.CameraPrivacy.PrivacySwitches)))
+
+	securityManager := security.NewManager(pluginClient("security"), stateManager, logger, writeScopes.ReadOnly("security"), subscriptionRegistry)
+	securityManager.SetConfig(securityConfig)
+	securityManager.SetEntities(entityRegistry)
+	securityManager.SetDoNotDisturb(dndGuard)
+	securityManager.SetAnnouncer(announcer)
+	securityManager.SetFocusMode(focusGuard)
+	securityManager.SetKidMode(kidGuard)
+	securityManager.SetTimezone(timezone)
+	addPlugin("security", securityManager, func() shadowstate.PluginShadowState {
 		return securityManager.GetShadowState()
 	})
-	logger.Info("Registered security shadow state with tracker")
+	apiServer.SetSecurityDrillRunner(securityManager)
+	apiServer.SetSecurityAlarmController(securityManager)
+	apiServer.SetSecurityLocksProvider(securityManager)
 
 	// Start Sleep Hygiene Manager
-	sleepHygieneManager, err := startSleepHygieneManager(client, stateManager, logger, readOnly, configDir)
+	sleepHygieneManager, err := newSleepHygieneManager(pluginClient("sleephygiene"), stateManager, logger, writeScopes.ReadOnly("sleephygiene"), configDir, timezone, dndGuard, announcer, jobScheduler)
 	if err != nil {
-		logger.Fatal("Failed to start Sleep Hygiene Manager", zap.Error(err))
+		logger.Fatal("Failed to create Sleep Hygiene Manager", zap.Error(err))
 	}
-	defer sleepHygieneManager.Stop()
-
-	// Register sleep hygiene shadow state provider with tracker
-	shadowTracker.RegisterPluginProvider("sleephygiene", func() shadowstate.PluginShadowState {
+	sleepHygieneManager.SetEntities(entityRegistry)
+	sleepHygieneManager.SetKidMode(kidGuard)
+	addPlugin("sleephygiene", sleepHygieneManager, func() shadowstate.PluginShadowState {
 		return sleepHygieneManager.GetShadowState()
 	})
-	logger.Info("Registered sleep hygiene shadow state with tracker")
+	stateTrackingManager.AddNightlyCheck("sonos_alarm", sleepHygieneManager.VerifySonosAlarm)
 
 	// Start Load Shedding Manager
-	loadSheddingManager := loadshedding.NewManager(client, stateManager, logger, readOnly, subscriptionRegistry)
-	if err := loadSheddingManager.Start(); err != nil {
-		logger.Fatal("Failed to start Load Shedding Manager", zap.Error(err))
+	loadSheddingManager := loadshedding.NewManager(pluginClient("loadshedding"), stateManager, logger, writeScopes.ReadOnly("loadshedding"), subscriptionRegistry)
+	loadSheddingManager.SetEntities(entityRegistry)
+	loadSheddingManager.SetKidMode(kidGuard)
+	// Tiered devices are optional: without loadshedding_config.yaml only the thermostat is shed
+	loadSheddingConfigPath := filepath.Join(configDir, "loadshedding_config.yaml")
+	if loadSheddingConfig, err := loadshedding.LoadConfig(loadSheddingConfigPath); err == nil {
+		loadSheddingManager.SetConfig(loadSheddingConfig)
+		logger.Info("Loaded load shedding configuration",
+			zap.Int("devices", len(loadSheddingConfig.LoadShedding.Devices)))
+	} else if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No load shedding config found, only the thermostat is shed", zap.String("path", loadSheddingConfigPath))
+	} else {
+		logger.Fatal("Failed to load load shedding config", zap.Error(err))
 	}
-	defer loadSheddingManager.Stop()
-	logger.Info("Load Shedding Manager started successfully")
-
-	// Register load shedding shadow state provider with tracker
-	shadowTracker.RegisterPluginProvider("loadshedding", func() shadowstate.PluginShadowState {
+	addPlugin("loadshedding", loadSheddingManager, func() shadowstate.PluginShadowState {
 		return loadSheddingManager.GetShadowState()
 	})
 
 	// Start TV Manager
-	tvManager := tv.NewManager(client, stateManager, logger, readOnly, subscriptionRegistry)
-	if err := tvManager.Start(); err != nil {
-		logger.Fatal("Failed to start TV Manager", zap.Error(err))
-	}
-	defer tvManager.Stop()
-	logger.Info("TV Manager started successfully")
-
-	// Register Phase 6 read-heavy plugin shadow state providers
-	shadowTracker.RegisterPluginProvider("energy", func() shadowstate.PluginShadowState {
-		return energyManager.GetShadowState()
-	})
-	logger.Info("Registered energy shadow state with tracker")
+	tvManager := tv.NewManager(pluginClient("tv"), stateManager, logger, writeScopes.ReadOnly("tv"), subscriptionRegistry)
+	tvManager.SetEntities(entityRegistry)
+	addPlugin("tv", tvManager, nil)
 
+	// Register shadow state for the always-on plugins started above
 	shadowTracker.RegisterPluginProvider("statetracking", func() shadowstate.PluginShadowState {
 		return stateTrackingManager.GetShadowState()
 	})
@@ -280,25 +605,248 @@ func main() {
 		return dayPhaseManager.GetShadowState()
 	})
 	logger.Info("Registered dayphase shadow state with tracker")
+	apiServer.AddHealthChecker("statetracking", stateTrackingManager)
+	apiServer.AddHealthChecker("dayphase", dayPhaseManager)
+
+	// Start Grow Lights Manager (supplements natural day length from the day phase calculator)
+	growLightsManager, err := newGrowLightsManager(pluginClient("growlights"), stateManager, logger, writeScopes.ReadOnly("growlights"), configDir, dayPhaseCalc, timezone, subscriptionRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create Grow Lights Manager", zap.Error(err))
+	}
+	addPlugin("growlights", growLightsManager, func() shadowstate.PluginShadowState {
+		return growLightsManager.GetShadowState()
+	})
+
+	// Start Bedroom Comfort Manager (overnight fan/thermostat adjustments while asleep)
+	bedroomComfortManager, err := newBedroomComfortManager(pluginClient("bedroomcomfort"), stateManager, logger, writeScopes.ReadOnly("bedroomcomfort"), configDir, subscriptionRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create Bedroom Comfort Manager", zap.Error(err))
+	}
+	bedroomComfortManager.SetKidMode(kidGuard)
+	bedroomComfortManager.SetSafeMode(safeModeSwitch)
+	addPlugin("bedroomcomfort", bedroomComfortManager, func() shadowstate.PluginShadowState {
+		return bedroomComfortManager.GetShadowState()
+	})
+
+	// Start Sleep Fan Manager (bedroom fan speed from room temperature while asleep)
+	sleepFanManager, err := newSleepFanManager(pluginClient("sleepfan"), stateManager, logger, writeScopes.ReadOnly("sleepfan"), configDir, subscriptionRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create Sleep Fan Manager", zap.Error(err))
+	}
+	if sleepFanManager != nil {
+		addPlugin("sleepfan", sleepFanManager, func() shadowstate.PluginShadowState {
+			return sleepFanManager.GetShadowState()
+		})
+	}
+
+	// Start Freeze Protection Manager (heaters and dampers in unconditioned spaces near freezing)
+	freezeProtectionManager, err := newFreezeProtectionManager(pluginClient("freezeprotection"), stateManager, logger, writeScopes.ReadOnly("freezeprotection"), configDir, subscriptionRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create Freeze Protection Manager", zap.Error(err))
+	}
+	if freezeProtectionManager != nil {
+		addPlugin("freezeprotection", freezeProtectionManager, func() shadowstate.PluginShadowState {
+			return freezeProtectionManager.GetShadowState()
+		})
+	}
+
+	// Start Climate Manager (thermostat comfort schedule and open window pause; load shedding wins)
+	climateManager, err := newClimateManager(pluginClient("climate"), stateManager, logger, writeScopes.ReadOnly("climate"), configDir, subscriptionRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create Climate Manager", zap.Error(err))
+	}
+	if climateManager != nil {
+		climateManager.SetLoadShedding(loadSheddingManager)
+		climateManager.SetKidMode(kidGuard)
+		climateManager.SetAnnouncer(announcer)
+		addPlugin("climate", climateManager, func() shadowstate.PluginShadowState {
+			return climateManager.GetShadowState()
+		})
+	}
+
+	// Start Emergency Manager (smoke/CO response; suspends the other plugins while active)
+	emergencyManager, err := newEmergencyManager(pluginClient("emergency"), stateManager, logger, writeScopes.ReadOnly("emergency"), configDir, subscriptionRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create Emergency Manager", zap.Error(err))
+	}
+	if emergencyManager != nil {
+		emergencyManager.SetAnnouncer(announcer)
+		addPlugin("emergency", emergencyManager, func() shadowstate.PluginShadowState {
+			return emergencyManager.GetShadowState()
+		})
+	}
+
+	// Start Open Reminder Manager (doors/windows left open when asleep or away)
+	openReminderManager, err := newOpenReminderManager(pluginClient("openreminder"), stateManager, logger, writeScopes.ReadOnly("openreminder"), configDir, subscriptionRegistry, dndGuard, notificationRouter, focusGuard)
+	if err != nil {
+		logger.Fatal("Failed to create Open Reminder Manager", zap.Error(err))
+	}
+	addPlugin("openreminder", openReminderManager, func() shadowstate.PluginShadowState {
+		return openReminderManager.GetShadowState()
+	})
+	apiServer.SetOpenReminderAcknowledger(openReminderManager)
+
+	// Start Low Battery Manager (daily battery sweep, weekly consolidated notification)
+	lowBatteryManager, err := newLowBatteryManager(pluginClient("lowbattery"), stateManager, logger, writeScopes.ReadOnly("lowbattery"), configDir, timezone)
+	if err != nil {
+		logger.Fatal("Failed to create Low Battery Manager", zap.Error(err))
+	}
+	addPlugin("lowbattery", lowBatteryManager, func() shadowstate.PluginShadowState {
+		return lowBatteryManager.GetShadowState()
+	})
+
+	// Start Hot Water Manager (recirculation pump scheduled from learned hot water use)
+	hotWaterManager, err := newHotWaterManager(pluginClient("hotwater"), stateManager, logger, writeScopes.ReadOnly("hotwater"), configDir, timezone)
+	if err != nil {
+		logger.Fatal("Failed to create Hot Water Manager", zap.Error(err))
+	}
+	if hotWaterManager != nil {
+		hotWaterManager.SetSafeMode(safeModeSwitch)
+		addPlugin("hotwater", hotWaterManager, func() shadowstate.PluginShadowState {
+			return hotWaterManager.GetShadowState()
+		})
+		apiServer.SetHotWaterScheduleProvider(hotWaterManager)
+	}
+
+	// Start Rules Manager (YAML "when X and Y then Z" automations)
+	rulesManager, err := newRulesManager(pluginClient("rules"), stateManager, logger, writeScopes.ReadOnly("rules"), configDir, timezone, announcer, jobScheduler)
+	if err != nil {
+		logger.Fatal("Failed to create Rules Manager", zap.Error(err))
+	}
+	if rulesManager != nil {
+		addPlugin("rules", rulesManager, func() shadowstate.PluginShadowState {
+			return rulesManager.GetShadowState()
+		})
+	}
+
+	// Start Scene Scheduler (scenes on cron schedules or at sunrise/sunset offsets)
+	sceneScheduler, err := newSceneScheduler(pluginClient("scenescheduler"), stateManager, logger, writeScopes.ReadOnly("scenescheduler"), configDir, timezone, jobScheduler)
+	if err != nil {
+		logger.Fatal("Failed to create Scene Scheduler", zap.Error(err))
+	}
+	if sceneScheduler != nil {
+		addPlugin("scenescheduler", sceneScheduler, func() shadowstate.PluginShadowState {
+			return sceneScheduler.GetShadowState()
+		})
+	}
+
+	// Start Focus Mode Manager (office focus window from the toggle or a calendar event)
+	var focusModeManager *focusmode.Manager
+	if focusModeConfig != nil {
+		focusModeManager = focusmode.NewManager(pluginClient("focusmode"), stateManager, focusModeConfig, logger, writeScopes.ReadOnly("focusmode"))
+		addPlugin("focusmode", focusModeManager, func() shadowstate.PluginShadowState {
+			return focusModeManager.GetShadowState()
+		})
+	}
+
+	// Start Kid Mode Manager (household and room kid mode from toggles and the schedule)
+	var kidModeManager *kidmode.Manager
+	if kidModeConfig != nil {
+		kidModeManager = kidmode.NewManager(pluginClient("kidmode"), stateManager, kidModeConfig, logger, writeScopes.ReadOnly("kidmode"))
+		kidModeManager.SetTimezone(timezone)
+		kidModeManager.SetScheduler(jobScheduler)
+		addPlugin("kidmode", kidModeManager, func() shadowstate.PluginShadowState {
+			return kidModeManager.GetShadowState()
+		})
+	}
+
+	// Start Report Manager (weekly automation digest, counts plugin actions as they're recorded)
+	reportManager, err := newReportManager(pluginClient("reports"), stateManager, shadowTracker, logger, writeScopes.ReadOnly("reports"), configDir, timezone)
+	if err != nil {
+		logger.Fatal("Failed to create Report Manager", zap.Error(err))
+	}
+	reportManager.SetEntities(entityRegistry)
+	addPlugin("reports", reportManager, nil)
+	apiServer.SetWeeklyReportProvider(reportManager)
+	apiServer.SetOccupancyHeatmapProvider(reportManager)
+
+	// Suspend non-essential plugins in battery safe mode; registered once
+	// every plugin has been added so all of them can be suspended
+	suspendPluginsInSafeMode(pluginLifecycle, safeModeSwitch, logger)
+
+	// A smoke/CO emergency takes priority over every other plugin; registered
+	// here for the same reason
+	if emergencyManager != nil {
+		suspendPluginsInEmergency(pluginLifecycle, emergencyManager, logger)
+	}
+
+	// Start plugins for each namespace against its scoped state
+	namespacePlugins, stopNamespacePlugins, err := startNamespacePlugins(ctx, pluginClient, writeScopes, namespaces, logger, configDir, timezone, shadowTracker)
+	if err != nil {
+		logger.Fatal("Failed to start namespace plugins", zap.Error(err))
+	}
+	defer stopNamespacePlugins()
 
 	// Start Reset Coordinator (must be last - after all plugins are started)
-	resetCoordinator := reset.NewCoordinator(stateManager, logger, readOnly, []reset.PluginWithName{
+	resetPlugins := []reset.PluginWithName{
 		{Name: "State Tracking", Plugin: stateTrackingManager},
 		{Name: "Day Phase", Plugin: dayPhaseManager},
-		{Name: "Energy", Plugin: energyManager},
-		{Name: "Load Shedding", Plugin: loadSheddingManager},
-		{Name: "Lighting", Plugin: lightingManager},
-		{Name: "Music", Plugin: musicManager},
-		{Name: "Security", Plugin: securityManager},
-		{Name: "Sleep Hygiene", Plugin: sleepHygieneManager},
-	})
+		{Name: "Bedroom Comfort", Plugin: pluginLifecycle.Resettable("bedroomcomfort", bedroomComfortManager)},
+		{Name: "Energy", Plugin: pluginLifecycle.Resettable("energy", energyManager)},
+		{Name: "Grow Lights", Plugin: pluginLifecycle.Resettable("growlights", growLightsManager)},
+		{Name: "Load Shedding", Plugin: pluginLifecycle.Resettable("loadshedding", loadSheddingManager)},
+		{Name: "Lighting", Plugin: pluginLifecycle.Resettable("lighting", lightingManager)},
+		{Name: "Low Battery", Plugin: pluginLifecycle.Resettable("lowbattery", lowBatteryManager)},
+		{Name: "Music", Plugin: pluginLifecycle.Resettable("music", musicManager)},
+		{Name: "Open Reminder", Plugin: pluginLifecycle.Resettable("openreminder", openReminderManager)},
+		{Name: "Security", Plugin: pluginLifecycle.Resettable("security", securityManager)},
+		{Name: "Sleep Hygiene", Plugin: pluginLifecycle.Resettable("sleephygiene", sleepHygieneManager)},
+	}
+	if focusModeManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Focus Mode", Plugin: pluginLifecycle.Resettable("focusmode", focusModeManager)})
+	}
+	if kidModeManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Kid Mode", Plugin: pluginLifecycle.Resettable("kidmode", kidModeManager)})
+	}
+	if hotWaterManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Hot Water", Plugin: pluginLifecycle.Resettable("hotwater", hotWaterManager)})
+	}
+	if rulesManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Rules", Plugin: pluginLifecycle.Resettable("rules", rulesManager)})
+	}
+	if sceneScheduler != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Scene Scheduler", Plugin: pluginLifecycle.Resettable("scenescheduler", sceneScheduler)})
+	}
+	if sleepFanManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Sleep Fan", Plugin: pluginLifecycle.Resettable("sleepfan", sleepFanManager)})
+	}
+	if freezeProtectionManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Freeze Protection", Plugin: pluginLifecycle.Resettable("freezeprotection", freezeProtectionManager)})
+	}
+	if climateManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Climate", Plugin: pluginLifecycle.Resettable("climate", climateManager)})
+	}
+	if emergencyManager != nil {
+		resetPlugins = append(resetPlugins, reset.PluginWithName{Name: "Emergency", Plugin: pluginLifecycle.Resettable("emergency", emergencyManager)})
+	}
+	resetCoordinator := reset.NewCoordinator(stateManager, logger, writeScopes.ReadOnly("state"), append(resetPlugins, namespacePlugins...))
 	if err := resetCoordinator.Start(); err != nil {
 		logger.Fatal("Failed to start Reset Coordinator", zap.Error(err))
 	}
 	defer resetCoordinator.Stop()
 
+	// Every plugin has subscribed, so the subscription graph is complete
+	stateManager.CheckCascades(maxCascadeDepth)
+
+	// Confirm state sync now that every plugin is subscribed, ending warm-up
+	// early; if it fails the warm-up windows run out instead
+	if err := stateManager.SyncFromHA(); err != nil {
+		logger.Warn("Failed to confirm state sync, warm-up runs its full windows", zap.Error(err))
+	} else {
+		warmupGate.MarkSynced()
+	}
+
+	// Replay recorded state changes now that every plugin is listening
+	if virtualClient != nil {
+		stopReplay, err := startReplay(virtualClient, logger)
+		if err != nil {
+			logger.Fatal("Failed to start replay", zap.Error(err))
+		}
+		defer stopReplay()
+	}
+
 	// Demonstrate setting values (only in read-write mode)
-	if !readOnly {
+	if !writeScopes.ReadOnly("state") {
 		demonstrateStateChanges(stateManager, logger)
 	} else {
 		logger.Info("Running in READ-ONLY mode - state monitoring active")
@@ -309,7 +857,7 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	logger.Info("Application running. Press Ctrl+C to exit.")
-	if readOnly {
+	if writeScopes.ReadOnly("state") {
 		logger.Info("Monitoring state changes in READ-ONLY mode...")
 	} else {
 		logger.Info("Monitoring state changes...")
@@ -319,6 +867,71 @@ func main() {
 	<-sigChan
 
 	logger.Info("Shutting down gracefully...")
+	cancel()
+}
+
+// newVirtualClient creates the in-memory Home Assistant used in SIMULATION
+// mode, seeded from the SIMULATION_SNAPSHOT states file
+func newVirtualClient(logger *zap.Logger) (*ha.VirtualClient, error) {
+	snapshotPath := os.Getenv("SIMULATION_SNAPSHOT")
+	if snapshotPath == "" {
+		return nil, fmt.Errorf("SIMULATION_SNAPSHOT must be set in simulation mode")
+	}
+
+	snapshot, err := ha.LoadStates(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("SIMULATION mode: using a virtual Home Assistant, no service calls leave this process",
+		zap.String("snapshot", snapshotPath),
+		zap.Int("entities", len(snapshot)))
+
+	return ha.NewVirtualClient(snapshot, logger), nil
+}
+
+// startReplay replays the SIMULATION_REPLAY recording, if set, in the
+// background at SIMULATION_SPEED times the recorded pace
+func startReplay(client *ha.VirtualClient, logger *zap.Logger) (func(), error) {
+	replayPath := os.Getenv("SIMULATION_REPLAY")
+	if replayPath == "" {
+		return func() {}, nil
+	}
+
+	recording, err := ha.LoadStates(replayPath)
+	if err != nil {
+		return nil, err
+	}
+
+	speed := 1.0
+	if speedStr := os.Getenv("SIMULATION_SPEED"); speedStr != "" {
+		if parsed, err := strconv.ParseFloat(speedStr, 64); err == nil && parsed > 0 {
+			speed = parsed
+		} else {
+			logger.Warn("Invalid SIMULATION_SPEED value, using default", zap.String("value", speedStr), zap.Float64("default", 1))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		if err := client.Replay(ctx, recording, speed); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("Replay failed", zap.Error(err))
+		}
+	}()
+	return cancel, nil
+}
+
+// resolveConfigDir determines the config directory path
+// Priority: CONFIG_DIR env var > ./configs (container) > ../configs (local dev)
+func resolveConfigDir() string {
+	if configDir := os.Getenv("CONFIG_DIR"); configDir != "" {
+		return configDir
+	}
+	// Auto-detect: prefer ./configs if it exists (container), otherwise ../configs (local dev)
+	if _, err := os.Stat("./configs"); err == nil {
+		return "./configs"
+	}
+	return "../configs"
 }
 
 func displayState(manager *state.Manager, logger *zap.Logger) {
@@ -437,7 +1050,7 @@ func demonstrateStateChanges(manager *state.Manager, logger *zap.Logger) {
 	logger.Info("===================================")
 }
 
-func startEnergyManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, registry *shadowstate.SubscriptionRegistry) (*energy.Manager, error) {
+func newEnergyManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, registry *shadowstate.SubscriptionRegistry) (*energy.Manager, error) {
 	// Load energy configuration
 	configPath := filepath.Join(configDir, "energy_config.yaml")
 	energyConfig, err := energy.LoadConfig(configPath)
@@ -450,92 +1063,1129 @@ func startEnergyManager(client ha.HAClient, stateManager *state.Manager, logger
 		zap.String("free_energy_start", energyConfig.Energy.FreeEnergyTime.Start),
 		zap.String("free_energy_end", energyConfig.Energy.FreeEnergyTime.End))
 
-	// Create and start energy manager
+	// Create energy manager
 	energyManager := energy.NewManager(client, stateManager, energyConfig, logger, readOnly, timezone, registry)
-	if err := energyManager.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start energy manager: %w", err)
-	}
-
 	return energyManager, nil
 }
 
-func startMusicManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string) (*music.Manager, error) {
-	// Load music configuration
-	configPath := filepath.Join(configDir, "music_config.yaml")
-	musicConfig, err := music.LoadConfig(configPath)
+func newGrowLightsManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, calculator *dayphaselib.Calculator, timezone *time.Location, registry *shadowstate.SubscriptionRegistry) (*growlights.Manager, error) {
+	// Load grow light configuration
+	configPath := filepath.Join(configDir, "growlight_config.yaml")
+	growLightConfig, err := growlights.LoadConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load music config: %w", err)
+		return nil, fmt.Errorf("failed to load grow light config: %w", err)
 	}
 
-	// Count total music modes
-	logger.Info("Loaded music configuration",
-		zap.Int("music_modes", len(musicConfig.Music)))
-
-	// Create and start music manager
-	musicManager := music.NewManager(client, stateManager, musicConfig, logger, readOnly, nil)
-	if err := musicManager.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start music manager: %w", err)
-	}
+	logger.Info("Loaded grow light configuration",
+		zap.Int("fixtures", len(growLightConfig.GrowLights.Fixtures)),
+		zap.Float64("default_photoperiod_hours", growLightConfig.GrowLights.DefaultPhotoperiodHours))
 
-	logger.Info("Music Manager started successfully")
-	return musicManager, nil
+	// Create grow lights manager
+	growLightsManager := growlights.NewManager(client, stateManager, growLightConfig, calculator, logger, readOnly, timezone, registry)
+	return growLightsManager, nil
 }
 
-func startLightingManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry) (*lighting.Manager, error) {
-	// Load lighting configuration
-	configPath := filepath.Join(configDir, "hue_config.yaml")
-	lightingConfig, err := lighting.LoadConfig(configPath)
+func newBedroomComfortManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry) (*bedroomcomfort.Manager, error) {
+	// Load bedroom comfort configuration
+	configPath := filepath.Join(configDir, "bedroom_comfort_config.yaml")
+	comfortConfig, err := bedroomcomfort.LoadConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load lighting config: %w", err)
+		return nil, fmt.Errorf("failed to load bedroom comfort config: %w", err)
 	}
 
-	logger.Info("Loaded lighting configuration",
-		zap.Int("rooms", len(lightingConfig.Rooms)))
-
-	// Create and start lighting manager
-	lightingManager := lighting.NewManager(client, stateManager, lightingConfig, logger, readOnly, registry)
-	if err := lightingManager.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start lighting manager: %w", err)
-	}
+	logger.Info("Loaded bedroom comfort configuration",
+		zap.String("climate_entity", comfortConfig.BedroomComfort.ClimateEntity),
+		zap.Float64("min_temperature", comfortConfig.BedroomComfort.MinTemperature),
+		zap.Float64("max_temperature", comfortConfig.BedroomComfort.MaxTemperature),
+		zap.Int("max_adjustments_per_night", comfortConfig.BedroomComfort.MaxAdjustmentsPerNight))
 
-	logger.Info("Lighting Manager started successfully")
-	return lightingManager, nil
+	// Create bedroom comfort manager
+	bedroomComfortManager := bedroomcomfort.NewManager(client, stateManager, comfortConfig, logger, readOnly, registry)
+	return bedroomComfortManager, nil
 }
 
-func startSleepHygieneManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string) (*sleephygiene.Manager, error) {
-	// Load schedule configuration
-	configLoader := config.NewLoader(configDir, logger)
-	if err := configLoader.LoadScheduleConfig(); err != nil {
-		return nil, fmt.Errorf("failed to load schedule config: %w", err)
+func newSleepFanManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry) (*sleepfan.Manager, error) {
+	// Load sleep fan configuration (optional: no bedroom fans without it)
+	configPath := filepath.Join(configDir, "sleep_fan_config.yaml")
+	fanConfig, err := sleepfan.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No sleep fan config found, sleep fan control disabled", zap.String("path", configPath))
+		return nil, nil
 	}
-
-	logger.Info("Loaded schedule configuration for Sleep Hygiene")
-
-	// Create and start sleep hygiene manager
-	sleepHygieneManager := sleephygiene.NewManager(client, stateManager, configLoader, logger, readOnly, nil)
-	if err := sleepHygieneManager.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start sleep hygiene manager: %w", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sleep fan config: %w", err)
+	}
+	if len(fanConfig.SleepFan.Bedrooms) == 0 {
+		logger.Info("No bedroom fans configured, sleep fan control disabled", zap.String("path", configPath))
+		return nil, nil
 	}
 
-	logger.Info("Sleep Hygiene Manager started successfully")
-	return sleepHygieneManager, nil
+	logger.Info("Loaded sleep fan configuration", zap.Int("bedrooms", len(fanConfig.SleepFan.Bedrooms)))
+
+	// Create sleep fan manager
+	sleepFanManager := sleepfan.NewManager(client, stateManager, fanConfig, logger, readOnly, registry)
+	return sleepFanManager, nil
 }
 
-func startDayPhaseManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, calculator *dayphaselib.Calculator) (*dayphase.Manager, error) {
-	// Load schedule configuration (needed for day phase calculation)
+func newFreezeProtectionManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry) (*freezeprotection.Manager, error) {
+	// Load freeze protection configuration (optional: no spaces protected without it)
+	configPath := filepath.Join(configDir, "freeze_protection_config.yaml")
+	freezeConfig, err := freezeprotection.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No freeze protection config found, freeze protection disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load freeze protection config: %w", err)
+	}
+	if len(freezeConfig.FreezeProtection.Spaces) == 0 {
+		logger.Info("No spaces configured, freeze protection disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+
+	logger.Info("Loaded freeze protection configuration", zap.Int("spaces", len(freezeConfig.FreezeProtection.Spaces)))
+
+	// Create freeze protection manager
+	freezeProtectionManager := freezeprotection.NewManager(client, stateManager, freezeConfig, logger, readOnly, registry)
+	return freezeProtectionManager, nil
+}
+
+func newClimateManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry) (*climate.Manager, error) {
+	// Load climate configuration (optional: thermostats keep their own schedule without it)
+	configPath := filepath.Join(configDir, "climate_config.yaml")
+	climateConfig, err := climate.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No climate config found, thermostat schedule disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load climate config: %w", err)
+	}
+	if len(climateConfig.Climate.Thermostats) == 0 && climateConfig.Climate.OpenPause == nil {
+		logger.Info("No thermostats or open window pause configured, climate disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+
+	logger.Info("Loaded climate configuration",
+		zap.Int("thermostats", len(climateConfig.Climate.Thermostats)),
+		zap.Bool("open_pause", climateConfig.Climate.OpenPause != nil))
+
+	// Create climate manager
+	climateManager := climate.NewManager(client, stateManager, climateConfig, logger, readOnly, registry)
+	return climateManager, nil
+}
+
+func newEmergencyManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry) (*emergency.Manager, error) {
+	// Load emergency configuration (optional: no smoke/CO response without it)
+	configPath := filepath.Join(configDir, "emergency_config.yaml")
+	emergencyConfig, err := emergency.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No emergency config found, smoke/CO response disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load emergency config: %w", err)
+	}
+
+	logger.Info("Loaded emergency configuration",
+		zap.Int("detectors", len(emergencyConfig.Emergency.Detectors)),
+		zap.Strings("keep_running", emergencyConfig.Emergency.KeepRunning))
+
+	// Create emergency manager
+	emergencyManager := emergency.NewManager(client, stateManager, emergencyConfig, logger, readOnly, registry)
+	return emergencyManager, nil
+}
+
+func newOpenReminderManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, registry *shadowstate.SubscriptionRegistry, dndGuard *donotdisturb.Guard, notificationRouter *notifyrouter.Router, focusGuard *focusmode.Guard) (*openreminder.Manager, error) {
+	// Load open reminder configuration
+	configPath := filepath.Join(configDir, "open_reminder_config.yaml")
+	reminderConfig, err := openreminder.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open reminder config: %w", err)
+	}
+
+	logger.Info("Loaded open reminder configuration",
+		zap.Int("sensors", len(reminderConfig.OpenReminder.Sensors)),
+		zap.Int("repeat_interval_minutes", reminderConfig.OpenReminder.RepeatIntervalMinutes),
+		zap.Int("max_reminders", reminderConfig.OpenReminder.MaxReminders))
+
+	// Create open reminder manager
+	openReminderManager := openreminder.NewManager(client, stateManager, reminderConfig, logger, readOnly, registry)
+	openReminderManager.SetDoNotDisturb(dndGuard)
+	openReminderManager.SetNotificationRouter(notificationRouter)
+	openReminderManager.SetFocusMode(focusGuard)
+	return openReminderManager, nil
+}
+
+func newLowBatteryManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location) (*lowbattery.Manager, error) {
+	// Load low-battery configuration
+	configPath := filepath.Join(configDir, "low_battery_config.yaml")
+	lowBatteryConfig, err := lowbattery.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load low battery config: %w", err)
+	}
+
+	logger.Info("Loaded low battery configuration",
+		zap.String("sweep_time", lowBatteryConfig.LowBattery.SweepTime),
+		zap.Int("threshold_overrides", len(lowBatteryConfig.LowBattery.Thresholds)))
+
+	// Create low-battery manager
+	lowBatteryManager := lowbattery.NewManager(client, stateManager, lowBatteryConfig, logger, readOnly, timezone)
+	return lowBatteryManager, nil
+}
+
+func newHotWaterManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location) (*hotwater.Manager, error) {
+	// Load hot water configuration (optional: not every house has a recirculation pump)
+	configPath := filepath.Join(configDir, "hot_water_config.yaml")
+	hotWaterConfig, err := hotwater.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No hot water config found, recirculation pump scheduling disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hot water config: %w", err)
+	}
+
+	logger.Info("Loaded hot water configuration",
+		zap.String("pump_entity", hotWaterConfig.HotWater.PumpEntity),
+		zap.Int("history_days", hotWaterConfig.HotWater.History()))
+
+	// Create hot water manager
+	hotWaterManager := hotwater.NewManager(client, stateManager, hotWaterConfig, logger, readOnly, timezone)
+	return hotWaterManager, nil
+}
+
+func startMQTTBridge(stateManager *state.Manager, logger *zap.Logger, readOnly, simulation bool, configDir string) (*mqtt.Bridge, error) {
+	// Load MQTT configuration (optional: no bridge without a broker)
+	configPath := filepath.Join(configDir, "mqtt_config.yaml")
+	mqttConfig, err := mqtt.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No MQTT config found, MQTT bridge disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MQTT config: %w", err)
+	}
+	if !mqttConfig.MQTT.Enabled {
+		logger.Info("MQTT bridge disabled in config")
+		return nil, nil
+	}
+	// Simulated state must not reach real devices on the broker
+	if simulation {
+		logger.Info("MQTT bridge disabled in simulation mode")
+		return nil, nil
+	}
+
+	client := mqtt.NewPahoClient(mqttConfig.MQTT, os.Getenv("MQTT_USERNAME"), os.Getenv("MQTT_PASSWORD"), logger)
+	bridge := mqtt.NewBridge(client, stateManager, mqttConfig, logger, readOnly)
+	if err := bridge.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MQTT bridge: %w", err)
+	}
+
+	return bridge, nil
+}
+
+func startSolarForecast(stateManager *state.Manager, logger *zap.Logger, simulation bool, configDir string) (*forecast.Client, error) {
+	// Load solar forecast configuration (optional: no look-ahead without a forecast)
+	configPath := filepath.Join(configDir, "solar_forecast_config.yaml")
+	forecastConfig, err := forecast.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No solar forecast config found, solar forecast disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load solar forecast config: %w", err)
+	}
+	// Forecast services limit free calls per day; simulation runs don't spend them
+	if simulation {
+		logger.Info("Solar forecast disabled in simulation mode")
+		return nil, nil
+	}
+
+	provider, err := forecast.NewProvider(forecastConfig, os.Getenv("SOLAR_FORECAST_API_KEY"))
+	if err != nil {
+		return nil, err
+	}
+	client := forecast.NewClient(provider, stateManager, forecastConfig, logger)
+	client.Start()
+
+	return client, nil
+}
+
+func newRulesManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, announcer *tts.Announcer, jobScheduler *scheduler.Scheduler) (*rules.Manager, error) {
+	// Load rules configuration (optional: every automation may live in a plugin)
+	configPath := filepath.Join(configDir, "rules_config.yaml")
+	rulesConfig, err := rules.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No rules config found, rules engine disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules config: %w", err)
+	}
+
+	logger.Info("Loaded rules configuration", zap.Int("rules", len(rulesConfig.Rules)))
+
+	// Create rules manager
+	rulesManager := rules.NewManager(client, stateManager, rulesConfig, logger, readOnly, timezone)
+	rulesManager.SetScheduler(jobScheduler)
+	rulesManager.SetAnnouncer(announcer)
+	return rulesManager, nil
+}
+
+func newSceneScheduler(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, jobScheduler *scheduler.Scheduler) (*scenescheduler.Manager, error) {
+	// Load scene schedules (optional section of the schedule configuration)
+	configPath := filepath.Join(configDir, "schedule_config.yaml")
+	sceneConfig, err := scenescheduler.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scene schedules: %w", err)
+	}
+	if len(sceneConfig.SceneSchedules) == 0 {
+		logger.Info("No scene schedules configured, scene scheduler disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+
+	logger.Info("Loaded scene schedules", zap.Int("schedules", len(sceneConfig.SceneSchedules)))
+
+	// Create scene scheduler
+	sceneScheduler := scenescheduler.NewManager(client, stateManager, sceneConfig, logger, readOnly, timezone)
+	sceneScheduler.SetScheduler(jobScheduler)
+	return sceneScheduler, nil
+}
+
+func newReportManager(client ha.HAClient, stateManager *state.Manager, shadowTracker *shadowstate.Tracker, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location) (*reports.Manager, error) {
+	// Load report configuration
+	configPath := filepath.Join(configDir, "report_config.yaml")
+	reportConfig, err := reports.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report config: %w", err)
+	}
+
+	logger.Info("Loaded report configuration",
+		zap.String("weekly_report_day", reportConfig.WeeklyReport.Day),
+		zap.String("weekly_report_time", reportConfig.WeeklyReport.Time))
+
+	// Create report manager
+	reportManager := reports.NewManager(client, stateManager, shadowTracker, reportConfig, logger, readOnly, timezone)
+	return reportManager, nil
+}
+
+func newMusicManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, dndGuard *donotdisturb.Guard, jobScheduler *scheduler.Scheduler) (*music.Manager, error) {
+	// Load music configuration
+	configPath := filepath.Join(configDir, "music_config.yaml")
+	musicConfig, err := music.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load music config: %w", err)
+	}
+
+	// Count total music modes
+	logger.Info("Loaded music configuration",
+		zap.Int("music_modes", len(musicConfig.Music)))
+
+	// Create music manager
+	musicManager := music.NewManager(client, stateManager, musicConfig, logger, readOnly, nil)
+	musicManager.SetTimezone(timezone)
+	musicManager.SetDoNotDisturb(dndGuard)
+	if jobScheduler != nil {
+		musicManager.SetScheduler(jobScheduler)
+	}
+	return musicManager, nil
+}
+
+// registerMusicZones registers each music zone's state variable with the
+// state manager. Must run before SyncFromHA.
+func registerMusicZones(stateManager *state.Manager, logger *zap.Logger, configDir string) error {
+	musicConfig, err := music.LoadConfig(filepath.Join(configDir, "music_config.yaml"))
+	if err != nil {
+		return err
+	}
+	if err := music.RegisterZoneVariables(stateManager, musicConfig); err != nil {
+		return err
+	}
+	for _, zone := range slices.Sorted(maps.Keys(musicConfig.Zones)) {
+		logger.Info("Registered music zone",
+			zap.String("zone", zone),
+			zap.String("variable", music.ZoneVariable(zone)))
+	}
+	return nil
+}
+
+func newLightingManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, registry *shadowstate.SubscriptionRegistry, focusGuard *focusmode.Guard, jobScheduler *scheduler.Scheduler) (*lighting.Manager, error) {
+	// Load lighting configuration
+	configPath := filepath.Join(configDir, "hue_config.yaml")
+	lightingConfig, err := lighting.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lighting config: %w", err)
+	}
+
+	logger.Info("Loaded lighting configuration",
+		zap.Int("rooms", len(lightingConfig.Rooms)))
+
+	// Create lighting manager
+	lightingManager := lighting.NewManager(client, stateManager, lightingConfig, logger, readOnly, registry)
+	lightingManager.SetTimezone(timezone)
+	lightingManager.SetFocusMode(focusGuard)
+	if jobScheduler != nil {
+		lightingManager.SetScheduler(jobScheduler)
+	}
+	return lightingManager, nil
+}
+
+// scopedNamespace is a namespace from namespace_config.yaml and its scoped state manager
+type scopedNamespace struct {
+	config namespace.Namespace
+	state  *state.Manager
+}
+
+// registerNamespaces loads the optional namespace config and registers each
+// namespace's variables with the state manager. Must run before SyncFromHA.
+func registerNamespaces(stateManager *state.Manager, logger *zap.Logger, configDir string) ([]scopedNamespace, error) {
+	configPath := filepath.Join(configDir, "namespace_config.yaml")
+	nsConfig, err := namespace.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No namespace config found, running without namespaces", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]scopedNamespace, 0, len(nsConfig.Namespaces))
+	for _, ns := range nsConfig.Namespaces {
+		scoped, err := stateManager.RegisterNamespace(ns.Name, ns.Variables)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, scopedNamespace{config: ns, state: scoped})
+
+		logger.Info("Registered state namespace",
+			zap.String("namespace", ns.Name),
+			zap.Strings("variables", ns.Variables),
+			zap.Strings("plugins", ns.Plugins))
+	}
+	return namespaces, nil
+}
+
+// startNamespacePlugins starts each namespace's plugins against its scoped
+// state, with configs from the namespace's config directory. Shadow state is
+// registered as "<namespace>.<plugin>". The returned func stops every plugin;
+// plugins already started are stopped if a later one fails.
+func startNamespacePlugins(ctx context.Context, pluginClient func(plugin string) ha.HAClient, writeScopes *writescope.Scopes, namespaces []scopedNamespace, logger *zap.Logger, configDir string, timezone *time.Location, shadowTracker *shadowstate.Tracker) ([]reset.PluginWithName, func(), error) {
+	var started []reset.PluginWithName
+	var stops []func()
+	stopAll := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	fail := func(err error) ([]reset.PluginWithName, func(), error) {
+		stopAll()
+		return nil, nil, err
+	}
+
+	for _, ns := range namespaces {
+		nsLogger := logger.Named(ns.config.Name)
+		nsConfigDir := ns.config.ConfigPath(configDir)
+
+		if ns.config.HasPlugin(namespace.PluginLighting) {
+			lightingManager, err := newLightingManager(pluginClient("lighting"), ns.state, nsLogger, writeScopes.ReadOnly("lighting"), nsConfigDir, timezone, nil, nil, nil)
+			if err != nil {
+				return fail(fmt.Errorf("namespace %s: %w", ns.config.Name, err))
+			}
+			if err := lightingManager.Start(ctx); err != nil {
+				return fail(fmt.Errorf("namespace %s: failed to start lighting manager: %w", ns.config.Name, err))
+			}
+			started = append(started, reset.PluginWithName{Name: ns.config.Name + " Lighting", Plugin: lightingManager})
+			stops = append(stops, lightingManager.Stop)
+			key := state.QualifiedKey(ns.config.Name, "lighting")
+			shadowTracker.RegisterPluginProvider(key, func() shadowstate.PluginShadowState {
+				return lightingManager.GetShadowState()
+			})
+			lightingManager.OnAction(func(at time.Time, reason string) {
+				shadowTracker.RecordAction(key, at, reason)
+			})
+		}
+
+		if ns.config.HasPlugin(namespace.PluginMusic) {
+			// Do-not-disturb bedrooms are in the main house, so the guard isn't
+			// shared; group checks run on the manager's own scheduler so job
+			// names don't collide with the main house's
+			musicManager, err := newMusicManager(pluginClient("music"), ns.state, nsLogger, writeScopes.ReadOnly("music"), nsConfigDir, timezone, nil, nil)
+			if err != nil {
+				return fail(fmt.Errorf("namespace %s: %w", ns.config.Name, err))
+			}
+			if err := musicManager.Start(ctx); err != nil {
+				return fail(fmt.Errorf("namespace %s: failed to start music manager: %w", ns.config.Name, err))
+			}
+			started = append(started, reset.PluginWithName{Name: ns.config.Name + " Music", Plugin: musicManager})
+			stops = append(stops, musicManager.Stop)
+			key := state.QualifiedKey(ns.config.Name, "music")
+			shadowTracker.RegisterPluginProvider(key, func() shadowstate.PluginShadowState {
+				return musicManager.GetShadowState()
+			})
+			musicManager.OnAction(func(at time.Time, reason string) {
+				shadowTracker.RecordAction(key, at, reason)
+			})
+		}
+
+		logger.Info("Namespace plugins started",
+			zap.String("namespace", ns.config.Name),
+			zap.Strings("plugins", ns.config.Plugins))
+	}
+	return started, stopAll, nil
+}
+
+// watchHAConnection keeps isHomeAssistantConnected current and re-syncs all
+// state variables after a reconnect, so changes made in Home Assistant while
+// disconnected reach subscribers. Clients that don't reconnect on their own
+// (the simulation's virtual client) are left alone.
+func watchHAConnection(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger) {
+	notifier, ok := client.(ha.ConnectionNotifier)
+	if !ok {
+		return
+	}
+
+	notifier.SetConnectionHandler(func(connected bool) {
+		if connected {
+			// Backfill before announcing the connection so observers see fresh state
+			if err := stateManager.SyncFromHA(); err != nil {
+				logger.Error("Failed to re-sync state after reconnect", zap.Error(err))
+			}
+		}
+		if err := stateManager.SetBool("isHomeAssistantConnected", connected); err != nil {
+			logger.Error("Failed to update isHomeAssistantConnected", zap.Error(err))
+		}
+	})
+}
+
+// loadEntityGroups loads the named entity groups plugins refer to with entitygroups.Group
+func loadEntityGroups(logger *zap.Logger, configDir string) (*entitygroups.Config, error) {
+	configPath := filepath.Join(configDir, "entity_groups_config.yaml")
+	groupsConfig, err := entitygroups.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded entity groups configuration",
+		zap.Int("groups", len(groupsConfig.EntityGroups)))
+	return groupsConfig, nil
+}
+
+// loadEntities loads the optional entities config. Without it every entity
+// has its default ID.
+func loadEntities(logger *zap.Logger, configDir string) (*entities.Registry, error) {
+	configPath := filepath.Join(configDir, "entities_config.yaml")
+	entitiesConfig, err := entities.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No entities config found, using the default entity IDs", zap.String("path", configPath))
+		return entities.NewRegistry(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded entities configuration",
+		zap.Int("entities", len(entitiesConfig.Entities)))
+	return entities.NewRegistry(entitiesConfig), nil
+}
+
+// loadWarmupGate loads the optional warm-up config. A missing file turns
+// warm-up off (the returned gate is nil).
+func loadWarmupGate(logger *zap.Logger, configDir string) (*warmup.Gate, error) {
+	configPath := filepath.Join(configDir, "warmup_config.yaml")
+	warmupConfig, err := warmup.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No warm-up config found, plugins act immediately after startup", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded warm-up configuration",
+		zap.Int("default_seconds", warmupConfig.Warmup.DefaultSeconds),
+		zap.Int("plugin_overrides", len(warmupConfig.Warmup.Plugins)))
+	return warmup.NewGate(warmupConfig, logger), nil
+}
+
+// loadRateLimiter loads the optional rate limits config. Without it service
+// calls aren't limited.
+func loadRateLimiter(logger *zap.Logger, configDir string) (*ratelimit.Limiter, error) {
+	configPath := filepath.Join(configDir, "rate_limits_config.yaml")
+	rateLimitsConfig, err := ratelimit.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No rate limits config found, plugin service calls aren't limited", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded rate limits configuration",
+		zap.Int("default_domains", len(rateLimitsConfig.RateLimits.Default.Domains)),
+		zap.Int("plugin_overrides", len(rateLimitsConfig.RateLimits.Plugins)))
+	return ratelimit.NewLimiter(rateLimitsConfig, logger), nil
+}
+
+// loadWriteScopes loads the optional write scopes config and applies the
+// READ_ONLY, WRITE_PLUGINS, READ_ONLY_PLUGINS and READ_ONLY_DOMAINS
+// overrides. Without the file every plugin is read-write unless READ_ONLY=true.
+func loadWriteScopes(logger *zap.Logger, configDir string) (*writescope.Scopes, error) {
+	configPath := filepath.Join(configDir, "write_scopes_config.yaml")
+	scopesConfig, err := writescope.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No write scopes config found, using READ_ONLY for every plugin", zap.String("path", configPath))
+		scopesConfig = writescope.DefaultConfig(false)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := scopesConfig.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	return writescope.New(scopesConfig, logger), nil
+}
+
+// suspendPluginsInSafeMode stops the plugins listed in safe_mode while battery
+// safe mode is on and resumes them when it ends. Suspensions aren't saved, so
+// a restart during safe mode starts every enabled plugin until the energy
+// plugin enters safe mode again.
+func suspendPluginsInSafeMode(pluginLifecycle *lifecycle.Manager, safeModeSwitch *safemode.Switch, logger *zap.Logger) {
+	var suspended []string
+	var mu sync.Mutex
+
+	apply := func(status safemode.Status) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !status.Active {
+			for _, name := range suspended {
+				if err := pluginLifecycle.Resume(name); err != nil {
+					logger.Error("Failed to resume plugin after safe mode", zap.String("plugin", name), zap.Error(err))
+				}
+			}
+			suspended = nil
+			return
+		}
+		for _, name := range status.SuspendPlugins {
+			if err := pluginLifecycle.Suspend(name); err != nil {
+				logger.Warn("Failed to suspend plugin for safe mode", zap.String("plugin", name), zap.Error(err))
+				continue
+			}
+			suspended = append(suspended, name)
+		}
+	}
+
+	safeModeSwitch.OnChange(apply)
+	// Safe mode may have started while plugins were still being added
+	if status := safeModeSwitch.Status(); status.Active {
+		apply(status)
+	}
+}
+
+// suspendPluginsInEmergency stops every running plugin except the emergency
+// plugin and those it keeps running while a smoke/CO emergency is active, and
+// resumes them when it ends. Plugins already suspended, e.g. by safe mode, are
+// left to whatever suspended them.
+func suspendPluginsInEmergency(pluginLifecycle *lifecycle.Manager, emergencyManager *emergency.Manager, logger *zap.Logger) {
+	var suspended []string
+	var mu sync.Mutex
+
+	apply := func(status emergency.Status) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !status.Active {
+			for _, name := range suspended {
+				if err := pluginLifecycle.Resume(name); err != nil {
+					logger.Error("Failed to resume plugin after emergency", zap.String("plugin", name), zap.Error(err))
+				}
+			}
+			suspended = nil
+			emergencyManager.RecordSuspendedPlugins(nil)
+			return
+		}
+		for _, plugin := range pluginLifecycle.Statuses() {
+			if plugin.Name == "emergency" || !plugin.Enabled || plugin.Suspended || slices.Contains(status.KeepRunning, plugin.Name) {
+				continue
+			}
+			if err := pluginLifecycle.Suspend(plugin.Name); err != nil {
+				logger.Warn("Failed to suspend plugin for emergency", zap.String("plugin", plugin.Name), zap.Error(err))
+				continue
+			}
+			suspended = append(suspended, plugin.Name)
+		}
+		emergencyManager.RecordSuspendedPlugins(suspended)
+	}
+
+	emergencyManager.OnChange(apply)
+	// A detector may have been on when the emergency plugin started
+	if status := emergencyManager.Status(); status.Active {
+		apply(status)
+	}
+}
+
+// newPluginLifecycle loads the optional plugins config into a lifecycle
+// manager. A missing file enables every plugin; the file is created the first
+// time a plugin is disabled.
+func newPluginLifecycle(ctx context.Context, logger *zap.Logger, configDir string, shadowTracker *shadowstate.Tracker) (*lifecycle.Manager, error) {
+	configPath := filepath.Join(configDir, "plugins_config.yaml")
+	pluginsConfig, err := lifecycle.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No plugins config found, all plugins enabled", zap.String("path", configPath))
+		pluginsConfig = &lifecycle.Config{}
+	} else if err != nil {
+		return nil, err
+	} else {
+		logger.Info("Loaded plugins configuration", zap.Strings("disabled", pluginsConfig.Plugins.Disabled))
+	}
+	return lifecycle.NewManager(ctx, pluginsConfig, configPath, shadowTracker, logger), nil
+}
+
+// loadConsistencyCheckConfig loads the optional consistency check config. A
+// missing file disables the nightly check (the returned config is nil).
+func loadConsistencyCheckConfig(logger *zap.Logger, configDir string) (*statetracking.ConsistencyCheckConfig, error) {
+	configPath := filepath.Join(configDir, "consistency_check_config.yaml")
+	consistencyConfig, err := statetracking.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No consistency check config found, nightly consistency check disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded consistency check configuration",
+		zap.String("time", consistencyConfig.ConsistencyCheck.Time),
+		zap.String("notify_service", string(consistencyConfig.ConsistencyCheck.NotifyService)))
+	return consistencyConfig, nil
+}
+
+// loadWinddownTemperatureConfig loads the optional winddown temperature config.
+// A missing file leaves the evening phases unshifted (the returned config is nil).
+func loadWinddownTemperatureConfig(logger *zap.Logger, configDir string) (*dayphase.TemperatureShiftConfig, error) {
+	configPath := filepath.Join(configDir, "winddown_temperature_config.yaml")
+	shiftConfig, err := dayphase.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No winddown temperature config found, evening phases not shifted", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded winddown temperature configuration",
+		zap.String("sensor", shiftConfig.WinddownTemperature.OutdoorTemperatureSensor))
+	return shiftConfig, nil
+}
+
+// loadDoNotDisturbGuard loads the optional do-not-disturb config. A missing
+// file disables do-not-disturb (the returned guard is nil).
+func loadDoNotDisturbGuard(stateManager *state.Manager, logger *zap.Logger, configDir string) (*donotdisturb.Guard, error) {
+	configPath := filepath.Join(configDir, "do_not_disturb_config.yaml")
+	dndConfig, err := donotdisturb.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No do-not-disturb config found, do-not-disturb disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded do-not-disturb configuration",
+		zap.Int("bedrooms", len(dndConfig.DoNotDisturb.Bedrooms)))
+	return donotdisturb.NewGuard(dndConfig, stateManager), nil
+}
+
+func loadFocusModeConfig(logger *zap.Logger, configDir string) (*focusmode.Config, error) {
+	configPath := filepath.Join(configDir, "focus_mode_config.yaml")
+	focusModeConfig, err := focusmode.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No focus mode config found, focus mode disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded focus mode configuration",
+		zap.String("toggle_variable", focusModeConfig.FocusMode.ToggleVariable),
+		zap.String("calendar_entity", focusModeConfig.FocusMode.CalendarEntity))
+	return focusModeConfig, nil
+}
+
+func loadKidModeConfig(logger *zap.Logger, configDir string) (*kidmode.Config, error) {
+	configPath := filepath.Join(configDir, "kid_mode_config.yaml")
+	kidModeConfig, err := kidmode.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No kid mode config found, kid mode disabled", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded kid mode configuration",
+		zap.String("toggle_variable", kidModeConfig.KidMode.ToggleVariable),
+		zap.Int("schedule_windows", len(kidModeConfig.KidMode.Schedule)),
+		zap.Int("rooms", len(kidModeConfig.KidMode.Rooms)))
+	return kidModeConfig, nil
+}
+
+func loadNotificationRouter(stateManager *state.Manager, logger *zap.Logger, configDir string) (*notifyrouter.Router, error) {
+	configPath := filepath.Join(configDir, "notification_router_config.yaml")
+	routerConfig, err := notifyrouter.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No notification router config found, announcements are always spoken", zap.String("path", configPath))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Loaded notification router configuration",
+		zap.Int("schedule_steps", len(routerConfig.NotificationRouter.Schedule)))
+	return notifyrouter.NewRouter(routerConfig, stateManager), nil
+}
+
+// loadAnnouncer creates the TTS announcer, with the default speakers and no
+// quiet hours if tts_config.yaml is missing
+func loadAnnouncer(stateManager *state.Manager, router *notifyrouter.Router, logger *zap.Logger, configDir string) (*tts.Announcer, error) {
+	configPath := filepath.Join(configDir, "tts_config.yaml")
+	ttsConfig, err := tts.LoadConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No TTS config found, using the default speakers without quiet hours", zap.String("path", configPath))
+		ttsConfig = tts.DefaultConfig()
+	} else if err != nil {
+		return nil, err
+	} else {
+		logger.Info("Loaded TTS configuration",
+			zap.Int("targets", len(ttsConfig.TTS.Speakers)),
+			zap.Strings("quiet_day_phases", ttsConfig.TTS.QuietHours.DayPhases),
+			zap.Bool("quiet_when_anyone_asleep", ttsConfig.TTS.QuietHours.WhenAnyoneAsleep))
+	}
+
+	announcer := tts.New(ttsConfig, stateManager, logger)
+	announcer.SetNotificationRouter(router)
+	return announcer, nil
+}
+
+func newSleepHygieneManager(client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, dndGuard *donotdisturb.Guard, announcer *tts.Announcer, jobScheduler *scheduler.Scheduler) (*sleephygiene.Manager, error) {
+	// Load schedule configuration
 	configLoader := config.NewLoader(configDir, logger)
+	configLoader.SetTimezone(timezone)
+	if err := configLoader.LoadScheduleConfig(); err != nil {
+		return nil, fmt.Errorf("failed to load schedule config: %w", err)
+	}
+
+	logger.Info("Loaded schedule configuration for Sleep Hygiene")
+
+	// Load optional adaptive wake configuration
+	adaptiveWakePath := filepath.Join(configDir, "adaptive_wake_config.yaml")
+	adaptiveWakeConfig, err := sleephygiene.LoadConfig(adaptiveWakePath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No adaptive wake config found, alarm follows the schedule", zap.String("path", adaptiveWakePath))
+		adaptiveWakeConfig = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load adaptive wake config: %w", err)
+	} else {
+		logger.Info("Loaded adaptive wake configuration",
+			zap.Bool("enabled", adaptiveWakeConfig.AdaptiveWake.Enabled),
+			zap.Int("preparation_buffer_minutes", adaptiveWakeConfig.AdaptiveWake.PreparationBufferMinutes),
+			zap.String("floor_time", adaptiveWakeConfig.AdaptiveWake.FloorTime),
+			zap.Int("people", len(adaptiveWakeConfig.AdaptiveWake.People)))
+	}
+
+	// Load optional wake light configuration
+	wakeLightPath := filepath.Join(configDir, "wake_light_config.yaml")
+	wakeLightConfig, err := sleephygiene.LoadWakeLightConfig(wakeLightPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No wake light config found, wake light uses the default ramp", zap.String("path", wakeLightPath))
+		wakeLightConfig = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load wake light config: %w", err)
+	} else {
+		logger.Info("Loaded wake light configuration",
+			zap.Bool("enabled", wakeLightConfig.WakeLight.Enabled),
+			zap.String("lux_entity", wakeLightConfig.WakeLight.LuxEntity),
+			zap.String("weather_entity", wakeLightConfig.WakeLight.WeatherEntity),
+			zap.Int("profiles", len(wakeLightConfig.WakeLight.Profiles)))
+	}
+
+	// Load optional Sonos alarm configuration
+	sonosAlarmPath := filepath.Join(configDir, "sonos_alarm_config.yaml")
+	sonosAlarmConfig, err := sleephygiene.LoadSonosAlarmConfig(sonosAlarmPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No Sonos alarm config found, no backup alarm is kept on Sonos", zap.String("path", sonosAlarmPath))
+		sonosAlarmConfig = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load Sonos alarm config: %w", err)
+	} else {
+		logger.Info("Loaded Sonos alarm configuration",
+			zap.Bool("enabled", sonosAlarmConfig.SonosAlarm.Enabled),
+			zap.String("speaker", sonosAlarmConfig.SonosAlarm.Speaker),
+			zap.Int("alarm_id", sonosAlarmConfig.SonosAlarm.AlarmID),
+			zap.Int("delay_minutes", sonosAlarmConfig.SonosAlarm.DelayMinutes))
+	}
+
+	// Create sleep hygiene manager
+	sleepHygieneManager := sleephygiene.NewManager(client, stateManager, configLoader, logger, readOnly, nil)
+	sleepHygieneManager.SetTimezone(timezone)
+	sleepHygieneManager.SetDoNotDisturb(dndGuard)
+	sleepHygieneManager.SetAnnouncer(announcer)
+	sleepHygieneManager.SetAdaptiveWake(adaptiveWakeConfig)
+	sleepHygieneManager.SetWakeLight(wakeLightConfig)
+	sleepHygieneManager.SetSonosAlarm(sonosAlarmConfig)
+	sleepHygieneManager.SetScheduler(jobScheduler)
+	return sleepHygieneManager, nil
+}
+
+func startDayPhaseManager(ctx context.Context, client ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, configDir string, timezone *time.Location, calculator *dayphaselib.Calculator) (*dayphase.Manager, error) {
+	// Load schedule configuration (needed for day phase calculation)
+	configLoader := config.NewLoader(configDir, logger)
+	configLoader.SetTimezone(timezone)
 	if err := configLoader.LoadScheduleConfig(); err != nil {
 		return nil, fmt.Errorf("failed to load schedule config: %w", err)
 	}
 
 	logger.Info("Loaded schedule configuration for Day Phase")
 
+	temperatureShift, err := loadWinddownTemperatureConfig(logger, configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load winddown temperature config: %w", err)
+	}
+
 	// Create and start day phase manager
 	dayPhaseManager := dayphase.NewManager(client, stateManager, configLoader, calculator, logger, readOnly)
-	if err := dayPhaseManager.Start(); err != nil {
+	if temperatureShift != nil {
+		dayPhaseManager.SetTemperatureShift(temperatureShift)
+	}
+	if err := dayPhaseManager.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start day phase manager: %w", err)
 	}
 
 	logger.Info("Day Phase Manager started successfully")
 	return dayPhaseManager, nil
 }
+
+// stateReadThroughTimeout bounds a state read that misses the cache and goes to HA
+const stateReadThroughTimeout = 2 * time.Second
+
+// Exit codes for the validate-config subcommand
+const (
+	validateExitOK      = 0
+	validateExitInvalid = 1
+	validateExitUsage   = 2
+)
+
+// runValidateConfig implements the validate-config subcommand. It prints a JSON
+// result to stdout and returns the process exit code.
+func runValidateConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configDir := fs.String("config-dir", "", "config directory (default: CONFIG_DIR, ./configs or ../configs)")
+	entityCache := fs.String("entities", "", "cached HA entity list (JSON array of entity IDs or /api/states output); entity checks are skipped if empty")
+	refresh := fs.Bool("refresh-entities", false, "fetch the entity list from Home Assistant (HA_URL/HA_TOKEN) and write it to -entities before validating")
+	if err := fs.Parse(args); err != nil {
+		return validateExitUsage
+	}
+
+	if *configDir == "" {
+		*configDir = resolveConfigDir()
+	}
+
+	if *refresh {
+		if *entityCache == "" {
+			fmt.Fprintln(stderr, "-refresh-entities requires -entities")
+			return validateExitUsage
+		}
+		if err := refreshEntityCache(*entityCache); err != nil {
+			fmt.Fprintf(stderr, "Failed to refresh entity cache: %v\n", err)
+			return validateExitUsage
+		}
+	}
+
+	var entities configcheck.EntitySet
+	if *entityCache != "" {
+		var err error
+		entities, err = configcheck.LoadEntityCache(*entityCache)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return validateExitUsage
+		}
+	}
+
+	result := configcheck.Validate(*configDir, entities)
+	result.EntityCache = *entityCache
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fmt.Fprintf(stderr, "Failed to encode result: %v\n", err)
+		return validateExitUsage
+	}
+
+	if !result.Valid {
+		return validateExitInvalid
+	}
+	return validateExitOK
+}
+
+// runLightingSim implements the lighting-sim subcommand. It runs the lighting
+// manager against a scenario's virtual rooms and scripted inputs and writes a
+// timeline of scene decisions per room, without connecting to Home Assistant.
+func runLightingSim(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lighting-sim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	scenarioPath := fs.String("scenario", "", "scenario YAML with optional virtual rooms, initial state, and scripted steps (required)")
+	configDir := fs.String("config-dir", "", "config directory for hue_config.yaml when the scenario has no rooms (default: CONFIG_DIR, ./configs or ../configs)")
+	format := fs.String("format", "json", "output format: json or html")
+	out := fs.String("out", "", "output file (default: stdout)")
+	verbose := fs.Bool("v", false, "log lighting manager decisions to stderr")
+	if err := fs.Parse(args); err != nil {
+		return validateExitUsage
+	}
+
+	if *scenarioPath == "" {
+		fmt.Fprintln(stderr, "-scenario is required")
+		return validateExitUsage
+	}
+	if *format != "json" && *format != "html" {
+		fmt.Fprintf(stderr, "Unknown format %q (expected json or html)\n", *format)
+		return validateExitUsage
+	}
+
+	scenario, err := lighting.LoadScenario(*scenarioPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load scenario: %v\n", err)
+		return validateExitUsage
+	}
+
+	var rooms []lighting.RoomConfig
+	if len(scenario.Rooms) == 0 {
+		if *configDir == "" {
+			*configDir = resolveConfigDir()
+		}
+		hueConfig, err := lighting.LoadConfig(filepath.Join(*configDir, "hue_config.yaml"))
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to load lighting config: %v\n", err)
+			return validateExitUsage
+		}
+		rooms = hueConfig.Rooms
+	}
+
+	logger := zap.NewNop()
+	if *verbose {
+		cfg := zap.NewDevelopmentConfig()
+		cfg.OutputPaths = []string{"stderr"}
+		if l, err := cfg.Build(); err == nil {
+			logger = l
+		}
+	}
+
+	timeline, err := lighting.Simulate(scenario, rooms, logger)
+	if err != nil {
+		fmt.Fprintf(stderr, "Simulation failed: %v\n", err)
+		return validateExitInvalid
+	}
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to create output file: %v\n", err)
+			return validateExitUsage
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "html" {
+		err = timeline.WriteHTML(w)
+	} else {
+		err = timeline.WriteJSON(w)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to write timeline: %v\n", err)
+		return validateExitUsage
+	}
+	return validateExitOK
+}
+
+// runStateDiff implements the state-diff subcommand. It fetches /api/state
+// and /api/shadow from two running instances and prints a JSON diff of their
+// state variables and plugin shadow outputs.
+func runStateDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("state-diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	baseURL := fs.String("base", "", "base URL of the reference instance, e.g. http://blue:8080 (required)")
+	candidateURL := fs.String("candidate", "http://localhost:8080", "base URL of the instance being checked")
+	timestamps := fs.Bool("timestamps", false, "report differing timestamps, which are ignored by default")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout for fetching both instances")
+	if err := fs.Parse(args); err != nil {
+		return validateExitUsage
+	}
+
+	if *baseURL == "" {
+		fmt.Fprintln(stderr, "-base is required")
+		return validateExitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := &http.Client{}
+	base, err := statediff.Fetch(ctx, client, *baseURL)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to fetch base: %v\n", err)
+		return validateExitUsage
+	}
+	candidate, err := statediff.Fetch(ctx, client, *candidateURL)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to fetch candidate: %v\n", err)
+		return validateExitUsage
+	}
+
+	diff := statediff.Compare(base, candidate, statediff.Options{IgnoreTimestamps: !*timestamps})
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(diff); err != nil {
+		fmt.Fprintf(stderr, "Failed to encode diff: %v\n", err)
+		return validateExitUsage
+	}
+
+	if !diff.Identical {
+		return validateExitInvalid
+	}
+	return validateExitOK
+}
+
+// refreshEntityCache fetches all entity states from Home Assistant and writes their IDs to path
+func refreshEntityCache(path string) error {
+	// Load environment variables from .env file if present
+	_ = godotenv.Load()
+
+	haURL := os.Getenv("HA_URL")
+	haToken := os.Getenv("HA_TOKEN")
+	if haURL == "" || haToken == "" {
+		return fmt.Errorf("HA_URL and HA_TOKEN environment variables must be set")
+	}
+
+	// A full state dump can be large, so allow longer than the default request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := ha.NewClient(haURL, haToken, zap.NewNop())
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to Home Assistant: %w", err)
+	}
+	defer client.Disconnect()
+
+	states, err := client.GetAllStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get states: %w", err)
+	}
+
+	return configcheck.WriteEntityCache(path, states)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"homeautomation/internal/statediff"
+
+	"go.uber.org/zap"
+)
+
+// diffPeerTimeout bounds the requests to the peer for /api/diff
+const diffPeerTimeout = 10 * time.Second
+
+// SetDiffPeer sets the base URL of the instance /api/diff compares this one
+// against, e.g. "http://homeautomation-blue:8080". With no peer the endpoint
+// is disabled.
+func (s *Server) SetDiffPeer(baseURL string) {
+	s.diffPeerURL = baseURL
+}
+
+// handleGetDiff compares the peer's state variables and shadow outputs (the
+// base) with this instance's (the candidate)
+func (s *Server) handleGetDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.diffPeerURL == "" {
+		http.Error(w, "No peer configured (set PEER_URL)", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Timestamps are ignored unless ?timestamps=true
+	opts := statediff.Options{IgnoreTimestamps: r.URL.Query().Get("timestamps") != "true"}
+
+	base, err := statediff.Fetch(r.Context(), s.diffClient, s.diffPeerURL)
+	if err != nil {
+		s.logger.Warn("Failed to fetch peer snapshot",
+			zap.String("peer", s.diffPeerURL),
+			zap.Error(err))
+		http.Error(w, "Failed to fetch peer: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	candidate, err := s.localSnapshot()
+	if err != nil {
+		s.logger.Error("Failed to build local snapshot", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	diff := statediff.Compare(base, candidate, opts)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		s.logger.Error("Failed to encode diff response", zap.Error(err))
+		return
+	}
+
+	s.logger.Debug("Diff request served",
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.String("peer", s.diffPeerURL),
+		zap.Bool("identical", diff.Identical))
+}
+
+// localSnapshot builds this instance's snapshot from the same documents
+// /api/state and /api/shadow serve
+func (s *Server) localSnapshot() (*statediff.Snapshot, error) {
+	stateDoc, err := json.Marshal(s.collectState())
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := make(map[string]interface{})
+	for name, state := range s.shadowTracker.GetAllPluginStates() {
+		plugins[name] = state
+	}
+	shadowDoc, err := json.Marshal(AllShadowStatesResponse{Plugins: plugins})
+	if err != nil {
+		return nil, err
+	}
+
+	return statediff.Parse(stateDoc, shadowDoc)
+}
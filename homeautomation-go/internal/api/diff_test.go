@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+	"homeautomation/internal/statediff"
+
+	"go.uber.org/zap"
+)
+
+// newDiffTestServer returns a server whose lighting shadow state and dayPhase
+// can be set independently of another instance's
+func newDiffTestServer(t *testing.T, dayPhase string) *Server {
+	t.Helper()
+	logger := zap.NewNop()
+
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("input_text.day_phase", dayPhase, nil)
+	stateManager := state.NewManager(mockClient, logger, false)
+	if err := stateManager.SyncFromHA(); err != nil {
+		t.Fatalf("Failed to sync state: %v", err)
+	}
+
+	shadowTracker := shadowstate.NewTracker()
+	lightingState := shadowstate.NewLightingShadowState()
+	lightingState.Outputs.LastActionTime = time.Now()
+	shadowTracker.RegisterPlugin("lighting", lightingState)
+
+	return NewServer(stateManager, shadowTracker, logger, 8080, time.UTC)
+}
+
+func getDiff(t *testing.T, server *Server, query string) (*httptest.ResponseRecorder, statediff.Diff) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/diff"+query, nil)
+	w := httptest.NewRecorder()
+	server.handleGetDiff(w, req)
+
+	var diff statediff.Diff
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return w, diff
+}
+
+func TestHandleGetDiff(t *testing.T) {
+	blue := newDiffTestServer(t, "evening")
+	peer := httptest.NewServer(blue.server.Handler)
+	defer peer.Close()
+
+	green := newDiffTestServer(t, "evening")
+	green.SetDiffPeer(peer.URL)
+
+	w, diff := getDiff(t, green, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !diff.Identical {
+		t.Errorf("Expected identical instances, got state %+v, shadow %+v", diff.State, diff.Shadow)
+	}
+
+	// Lighting acted at different instants on each instance
+	_, diff = getDiff(t, green, "?timestamps=true")
+	if len(diff.Shadow) != 1 || diff.Shadow[0].Path != "lighting.lastActionTime" {
+		t.Errorf("Expected only the lighting action time to differ, got %+v", diff.Shadow)
+	}
+}
+
+func TestHandleGetDiff_ReportsChangedState(t *testing.T) {
+	blue := newDiffTestServer(t, "evening")
+	peer := httptest.NewServer(blue.server.Handler)
+	defer peer.Close()
+
+	green := newDiffTestServer(t, "night")
+	green.SetDiffPeer(peer.URL)
+
+	_, diff := getDiff(t, green, "")
+	if diff.Identical || len(diff.State) != 1 {
+		t.Fatalf("Expected one state difference, got %+v", diff.State)
+	}
+	got := diff.State[0]
+	if got.Path != "dayPhase" || got.Kind != statediff.KindChanged || got.Base != "evening" || got.Candidate != "night" {
+		t.Errorf("Unexpected difference %+v", got)
+	}
+}
+
+func TestHandleGetDiff_NoPeer(t *testing.T) {
+	server := newDiffTestServer(t, "evening")
+
+	w, _ := getDiff(t, server, "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+func TestHandleGetDiff_PeerUnavailable(t *testing.T) {
+	peer := httptest.NewServer(http.NotFoundHandler())
+	peer.Close()
+
+	server := newDiffTestServer(t, "evening")
+	server.SetDiffPeer(peer.URL)
+
+	w, _ := getDiff(t, server, "")
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", w.Code)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// handleGetStateFanOut serves the state variable subscription graph: how many
+// subscribers each variable has, how long they take and how far a change
+// cascades through the writes they declare
+func (s *Server) handleGetStateFanOut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := s.stateManager.FanOut()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		s.logger.Error("Failed to encode state fan-out response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("State fan-out request served",
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.Int("variable_count", len(report.Variables)),
+		zap.Int("cycle_count", len(report.Cycles)))
+}
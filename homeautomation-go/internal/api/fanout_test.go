@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func TestHandleGetStateFanOut(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	if err := stateManager.SetupComputedState(); err != nil {
+		t.Fatalf("SetupComputedState failed: %v", err)
+	}
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/state/fanout", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var report state.FanOutReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	var found bool
+	for _, variable := range report.Variables {
+		if variable.Key != "isAnyoneHome" {
+			continue
+		}
+		found = true
+		if variable.Subscribers != 1 {
+			t.Errorf("Expected 1 subscriber to isAnyoneHome, got %d", variable.Subscribers)
+		}
+		if len(variable.Writes) != 1 || variable.Writes[0] != "isAnyoneHomeAndAwake" {
+			t.Errorf("Expected isAnyoneHome to write isAnyoneHomeAndAwake, got %v", variable.Writes)
+		}
+		if variable.CascadeDepth != 1 {
+			t.Errorf("Expected cascade depth 1, got %d", variable.CascadeDepth)
+		}
+	}
+	if !found {
+		t.Errorf("Expected isAnyoneHome in the report, got %+v", report.Variables)
+	}
+}
+
+func TestHandleGetStateFanOut_MethodNotAllowed(t *testing.T) {
+	server := newMetricsTestServer(zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/state/fanout", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
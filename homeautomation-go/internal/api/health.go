@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"homeautomation/internal/health"
+
+	"go.uber.org/zap"
+)
+
+// statusDisabled marks a plugin that was disabled at runtime; it doesn't count
+// towards the overall status
+const statusDisabled = "disabled"
+
+// HealthResponse represents the response for /health
+type HealthResponse struct {
+	Status  string                   `json:"status"`
+	Plugins map[string]health.Report `json:"plugins,omitempty"`
+}
+
+// AddHealthChecker registers a plugin's health with /health
+func (s *Server) AddHealthChecker(name string, checker health.Checker) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	if s.healthCheckers == nil {
+		s.healthCheckers = make(map[string]health.Checker)
+	}
+	s.healthCheckers[name] = checker
+}
+
+// handleHealth reports each plugin's health and the worst of them overall.
+// It returns 503 when a plugin is unhealthy, so container health checks and
+// uptime monitors see a stuck plugin; degraded still returns 200.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	disabled := make(map[string]bool)
+	if s.pluginController != nil {
+		for _, status := range s.pluginController.Statuses() {
+			disabled[status.Name] = !status.Enabled
+		}
+	}
+
+	s.healthMu.RLock()
+	defer s.healthMu.RUnlock()
+
+	response := HealthResponse{Status: health.StatusOK}
+	if len(s.healthCheckers) > 0 {
+		response.Plugins = make(map[string]health.Report, len(s.healthCheckers))
+	}
+	for name, checker := range s.healthCheckers {
+		if disabled[name] {
+			response.Plugins[name] = health.Report{Status: statusDisabled}
+			continue
+		}
+		report := checker.Health()
+		response.Plugins[name] = report
+		if health.Worse(report.Status, response.Status) {
+			response.Status = report.Status
+		}
+	}
+
+	code := http.StatusOK
+	if response.Status == health.StatusUnhealthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode health response", zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// fakeHealthChecker returns a fixed report
+type fakeHealthChecker struct {
+	report health.Report
+}
+
+func (f *fakeHealthChecker) Health() health.Report {
+	return f.report
+}
+
+func getHealth(t *testing.T, server *Server) (int, HealthResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	server.handleHealth(w, req)
+
+	var response HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w.Code, response
+}
+
+func newHealthTestServer() *Server {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	return NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+}
+
+func TestHandleHealth_AggregatesPlugins(t *testing.T) {
+	server := newHealthTestServer()
+	server.AddHealthChecker("lighting", &fakeHealthChecker{health.Report{Status: health.StatusOK, Running: true}})
+	server.AddHealthChecker("music", &fakeHealthChecker{health.Report{Status: health.StatusDegraded, Running: true, Errors: 2}})
+
+	code, response := getHealth(t, server)
+	if code != http.StatusOK {
+		t.Errorf("Expected status 200 while degraded, got %d", code)
+	}
+	if response.Status != health.StatusDegraded {
+		t.Errorf("Expected overall status degraded, got %q", response.Status)
+	}
+	if response.Plugins["music"].Errors != 2 {
+		t.Errorf("Expected music to report 2 errors, got %+v", response.Plugins["music"])
+	}
+	if response.Plugins["lighting"].Status != health.StatusOK {
+		t.Errorf("Expected lighting ok, got %+v", response.Plugins["lighting"])
+	}
+}
+
+func TestHandleHealth_UnhealthyReturns503(t *testing.T) {
+	server := newHealthTestServer()
+	server.AddHealthChecker("lighting", &fakeHealthChecker{health.Report{Status: health.StatusOK, Running: true}})
+	server.AddHealthChecker("hotwater", &fakeHealthChecker{health.Report{Status: health.StatusUnhealthy, Reason: "no evaluation for 1h0m0s"}})
+	server.AddHealthChecker("music", &fakeHealthChecker{health.Report{Status: health.StatusDegraded, Running: true}})
+
+	code, response := getHealth(t, server)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", code)
+	}
+	if response.Status != health.StatusUnhealthy {
+		t.Errorf("Expected overall status unhealthy, got %q", response.Status)
+	}
+}
+
+func TestHandleHealth_IgnoresDisabledPlugins(t *testing.T) {
+	server := newHealthTestServer()
+	server.SetPluginController(&fakePluginController{enabled: map[string]bool{"music": false}})
+	server.AddHealthChecker("music", &fakeHealthChecker{health.Report{Status: health.StatusUnhealthy, Reason: "not running"}})
+
+	code, response := getHealth(t, server)
+	if code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+	if response.Status != health.StatusOK {
+		t.Errorf("Expected overall status ok, got %q", response.Status)
+	}
+	if response.Plugins["music"].Status != "disabled" {
+		t.Errorf("Expected music reported as disabled, got %+v", response.Plugins["music"])
+	}
+}
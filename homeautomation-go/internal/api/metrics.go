@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultSlowRequestThreshold is how long a request may take before it is
+// logged as slow, unless overridden with SetSlowRequestThreshold
+const DefaultSlowRequestThreshold = 1 * time.Second
+
+// requestDurationBuckets are the upper bounds (inclusive) of the request
+// duration histogram. Requests slower than the last bucket are only counted
+// in the route total.
+var requestDurationBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// DurationBucket is one cumulative bucket of a request duration histogram
+type DurationBucket struct {
+	LeMs  float64 `json:"leMs"`
+	Count int64   `json:"count"`
+}
+
+// RouteMetrics summarizes the requests served by a single route
+type RouteMetrics struct {
+	Count           int64            `json:"count"`
+	SlowCount       int64            `json:"slowCount"`
+	StatusCodes     map[string]int64 `json:"statusCodes"`
+	TotalDurationMs float64          `json:"totalDurationMs"`
+	MaxDurationMs   float64          `json:"maxDurationMs"`
+	DurationBuckets []DurationBucket `json:"durationBuckets"`
+}
+
+// MetricsResponse represents the JSON response for the metrics endpoint
+type MetricsResponse struct {
+	Since                  time.Time               `json:"since"`
+	SlowRequestThresholdMs float64                 `json:"slowRequestThresholdMs"`
+	Routes                 map[string]RouteMetrics `json:"routes"`
+}
+
+// routeStats accumulates the metrics for a single route
+type routeStats struct {
+	count         int64
+	slowCount     int64
+	statusCodes   map[int]int64
+	totalDuration time.Duration
+	maxDuration   time.Duration
+	buckets       []int64 // non-cumulative count per requestDurationBuckets entry
+}
+
+// requestMetrics records per-route request durations and status codes
+type requestMetrics struct {
+	mu     sync.Mutex
+	since  time.Time
+	routes map[string]*routeStats
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{
+		since:  time.Now(),
+		routes: make(map[string]*routeStats),
+	}
+}
+
+// record adds one served request to the route's metrics
+func (m *requestMetrics) record(route string, status int, duration time.Duration, slow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.routes[route]
+	if !ok {
+		stats = &routeStats{
+			statusCodes: make(map[int]int64),
+			buckets:     make([]int64, len(requestDurationBuckets)),
+		}
+		m.routes[route] = stats
+	}
+
+	stats.count++
+	stats.statusCodes[status]++
+	stats.totalDuration += duration
+	if duration > stats.maxDuration {
+		stats.maxDuration = duration
+	}
+	if slow {
+		stats.slowCount++
+	}
+	for i, bound := range requestDurationBuckets {
+		if duration <= bound {
+			stats.buckets[i]++
+			break
+		}
+	}
+}
+
+// snapshot returns a copy of the current metrics with cumulative buckets
+func (m *requestMetrics) snapshot() map[string]RouteMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]RouteMetrics, len(m.routes))
+	for route, stats := range m.routes {
+		codes := make(map[string]int64, len(stats.statusCodes))
+		for code, count := range stats.statusCodes {
+			codes[strconv.Itoa(code)] = count
+		}
+
+		buckets := make([]DurationBucket, len(requestDurationBuckets))
+		var cumulative int64
+		for i, bound := range requestDurationBuckets {
+			cumulative += stats.buckets[i]
+			buckets[i] = DurationBucket{LeMs: durationMs(bound), Count: cumulative}
+		}
+
+		result[route] = RouteMetrics{
+			Count:           stats.count,
+			SlowCount:       stats.slowCount,
+			StatusCodes:     codes,
+			TotalDurationMs: durationMs(stats.totalDuration),
+			MaxDurationMs:   durationMs(stats.maxDuration),
+			DurationBuckets: buckets,
+		}
+	}
+	return result
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// instrument wraps a handler so its duration and status code are recorded
+// under the given route, and requests slower than the threshold are logged
+func (s *Server) instrument(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		handler(rec, r)
+
+		duration := time.Since(start)
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		threshold := s.getSlowRequestThreshold()
+		slow := threshold > 0 && duration >= threshold
+		s.metrics.record(route, status, duration, slow)
+
+		if slow {
+			s.logger.Warn("Slow HTTP request",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("route", route),
+				zap.Int("status", status),
+				zap.Duration("duration", duration),
+				zap.Duration("threshold", threshold),
+				zap.String("remote_addr", r.RemoteAddr))
+		}
+	}
+}
+
+// SetSlowRequestThreshold sets how long a request may take before it is
+// logged as slow. Zero disables slow-request logging.
+func (s *Server) SetSlowRequestThreshold(threshold time.Duration) {
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	s.slowRequestThreshold = threshold
+}
+
+func (s *Server) getSlowRequestThreshold() time.Duration {
+	s.metricsMu.RLock()
+	defer s.metricsMu.RUnlock()
+	return s.slowRequestThreshold
+}
+
+// handleGetMetrics returns request counts, status codes and duration
+// histograms for every API route since the server started
+func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := MetricsResponse{
+		Since:                  s.metrics.since,
+		SlowRequestThresholdMs: durationMs(s.getSlowRequestThreshold()),
+		Routes:                 s.metrics.snapshot(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode metrics response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Metrics request served",
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.Int("route_count", len(response.Routes)))
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newMetricsTestServer(logger *zap.Logger) *Server {
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	return NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+}
+
+func TestInstrumentRecordsStatusAndDuration(t *testing.T) {
+	server := newMetricsTestServer(zap.NewNop())
+
+	for _, method := range []string{http.MethodGet, http.MethodGet, http.MethodPost} {
+		req := httptest.NewRequest(method, "/health", nil)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+	}
+
+	routes := server.metrics.snapshot()
+	health, ok := routes["/health"]
+	if !ok {
+		t.Fatalf("Expected metrics for /health, got routes %v", routes)
+	}
+	if health.Count != 3 {
+		t.Errorf("Expected 3 requests, got %d", health.Count)
+	}
+	if health.StatusCodes["200"] != 2 {
+		t.Errorf("Expected 2 responses with status 200, got %d", health.StatusCodes["200"])
+	}
+	if health.StatusCodes["405"] != 1 {
+		t.Errorf("Expected 1 response with status 405, got %d", health.StatusCodes["405"])
+	}
+	if health.SlowCount != 0 {
+		t.Errorf("Expected no slow requests, got %d", health.SlowCount)
+	}
+
+	last := health.DurationBuckets[len(health.DurationBuckets)-1]
+	if last.Count != 3 {
+		t.Errorf("Expected all 3 requests in the largest bucket, got %d", last.Count)
+	}
+}
+
+func TestRequestMetricsBuckets(t *testing.T) {
+	metrics := newRequestMetrics()
+	metrics.record("/api/shadow", http.StatusOK, 3*time.Millisecond, false)
+	metrics.record("/api/shadow", http.StatusOK, 40*time.Millisecond, false)
+	metrics.record("/api/shadow", http.StatusInternalServerError, 2*time.Second, true)
+	metrics.record("/api/shadow", http.StatusOK, 30*time.Second, true)
+
+	route := metrics.snapshot()["/api/shadow"]
+
+	expected := map[float64]int64{
+		5:     1,
+		50:    2,
+		1000:  2,
+		2500:  3,
+		10000: 3,
+	}
+	for _, bucket := range route.DurationBuckets {
+		if want, ok := expected[bucket.LeMs]; ok && bucket.Count != want {
+			t.Errorf("Expected %d requests in bucket le=%vms, got %d", want, bucket.LeMs, bucket.Count)
+		}
+	}
+
+	if route.Count != 4 {
+		t.Errorf("Expected 4 requests, got %d", route.Count)
+	}
+	if route.SlowCount != 2 {
+		t.Errorf("Expected 2 slow requests, got %d", route.SlowCount)
+	}
+	if route.MaxDurationMs != 30000 {
+		t.Errorf("Expected max duration 30000ms, got %v", route.MaxDurationMs)
+	}
+	if route.StatusCodes["500"] != 1 {
+		t.Errorf("Expected 1 response with status 500, got %d", route.StatusCodes["500"])
+	}
+}
+
+func TestInstrumentLogsSlowRequests(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	server := newMetricsTestServer(zap.New(core))
+
+	slowHandler := server.instrument("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+	})
+
+	// Disabled threshold never logs
+	server.SetSlowRequestThreshold(0)
+	slowHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if logs.Len() != 0 {
+		t.Errorf("Expected no slow request logs with threshold disabled, got %d", logs.Len())
+	}
+
+	server.SetSlowRequestThreshold(time.Millisecond)
+	slowHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	entries := logs.FilterMessage("Slow HTTP request").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 slow request log, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["route"] != "/slow" {
+		t.Errorf("Expected route /slow, got %v", fields["route"])
+	}
+	if fields["status"] != int64(http.StatusServiceUnavailable) {
+		t.Errorf("Expected status 503, got %v", fields["status"])
+	}
+
+	route := server.metrics.snapshot()["/slow"]
+	if route.Count != 2 || route.SlowCount != 1 {
+		t.Errorf("Expected 2 requests with 1 slow, got %d with %d slow", route.Count, route.SlowCount)
+	}
+}
+
+func TestHandleGetMetrics(t *testing.T) {
+	server := newMetricsTestServer(zap.NewNop())
+	server.SetSlowRequestThreshold(250 * time.Millisecond)
+
+	server.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/version", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+	w := httptest.NewRecorder()
+	server.handleGetMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response MetricsResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.SlowRequestThresholdMs != 250 {
+		t.Errorf("Expected slow request threshold 250ms, got %v", response.SlowRequestThresholdMs)
+	}
+	if response.Routes["/api/version"].Count != 1 {
+		t.Errorf("Expected 1 request for /api/version, got %d", response.Routes["/api/version"].Count)
+	}
+	if len(response.Routes["/api/version"].DurationBuckets) != len(requestDurationBuckets) {
+		t.Errorf("Expected %d duration buckets, got %d", len(requestDurationBuckets), len(response.Routes["/api/version"].DurationBuckets))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/metrics", nil)
+	w = httptest.NewRecorder()
+	server.handleGetMetrics(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
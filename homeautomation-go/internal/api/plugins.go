@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"homeautomation/internal/lifecycle"
+
+	"go.uber.org/zap"
+)
+
+// PluginController enables and disables plugins at runtime
+type PluginController interface {
+	Statuses() []lifecycle.Status
+	Enable(name string) error
+	Disable(name string) error
+}
+
+// PluginsResponse represents the response for /api/plugins
+type PluginsResponse struct {
+	Plugins []lifecycle.Status `json:"plugins"`
+}
+
+// SetPluginController sets the controller for the plugin enable/disable endpoints
+func (s *Server) SetPluginController(controller PluginController) {
+	s.pluginController = controller
+}
+
+// handleGetPlugins lists the plugins that can be enabled and disabled
+func (s *Server) handleGetPlugins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.pluginController == nil {
+		http.Error(w, "Plugin control not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(PluginsResponse{Plugins: s.pluginController.Statuses()}); err != nil {
+		s.logger.Error("Failed to encode plugins response", zap.Error(err))
+	}
+}
+
+// handleEnablePlugin starts a disabled plugin, /api/plugins/{name}/enable
+func (s *Server) handleEnablePlugin(w http.ResponseWriter, r *http.Request) {
+	s.handleSetPluginEnabled(w, r, true)
+}
+
+// handleDisablePlugin stops a plugin, /api/plugins/{name}/disable
+func (s *Server) handleDisablePlugin(w http.ResponseWriter, r *http.Request) {
+	s.handleSetPluginEnabled(w, r, false)
+}
+
+// handleSetPluginEnabled enables or disables the named plugin. Like state
+// writes, it requires the API token.
+func (s *Server) handleSetPluginEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.pluginController == nil {
+		http.Error(w, "Plugin control not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !s.authorizeWrite(w, r) {
+		return
+	}
+
+	name := r.PathValue("name")
+	var err error
+	if enabled {
+		err = s.pluginController.Enable(name)
+	} else {
+		err = s.pluginController.Disable(name)
+	}
+	if err != nil {
+		if errors.Is(err, lifecycle.ErrUnknownPlugin) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, lifecycle.ErrPluginBusy) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.logger.Error("Failed to change plugin state",
+			zap.String("plugin", name),
+			zap.Bool("enabled", enabled),
+			zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Plugin state changed via API",
+		zap.String("plugin", name),
+		zap.Bool("enabled", enabled),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(lifecycle.Status{Name: name, Enabled: enabled}); err != nil {
+		s.logger.Error("Failed to encode plugin response", zap.Error(err))
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/lifecycle"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// fakePluginController tracks enabled plugins in a map
+type fakePluginController struct {
+	enabled map[string]bool
+	busy    map[string]bool // Refuse to be disabled
+}
+
+func (f *fakePluginController) Statuses() []lifecycle.Status {
+	return []lifecycle.Status{{Name: "music", Enabled: f.enabled["music"]}}
+}
+
+func (f *fakePluginController) Enable(name string) error {
+	if _, ok := f.enabled[name]; !ok {
+		return fmt.Errorf("%w: %s", lifecycle.ErrUnknownPlugin, name)
+	}
+	f.enabled[name] = true
+	return nil
+}
+
+func (f *fakePluginController) Disable(name string) error {
+	if _, ok := f.enabled[name]; !ok {
+		return fmt.Errorf("%w: %s", lifecycle.ErrUnknownPlugin, name)
+	}
+	if f.busy[name] {
+		return fmt.Errorf("%w: %s", lifecycle.ErrPluginBusy, name)
+	}
+	f.enabled[name] = false
+	return nil
+}
+
+func sendPluginRequest(server *Server, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+
+	// Through the mux so the {name} path value is set
+	server.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func newPluginTestServer() *Server {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	return NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+}
+
+func TestPlugins_UnavailableWithoutController(t *testing.T) {
+	server := newPluginTestServer()
+	server.SetWriteToken(testWriteToken)
+
+	if w := sendPluginRequest(server, http.MethodGet, "/api/plugins", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 listing plugins, got %d", w.Code)
+	}
+	if w := sendPluginRequest(server, http.MethodPost, "/api/plugins/music/disable", testWriteToken); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 disabling a plugin, got %d", w.Code)
+	}
+}
+
+func TestPlugins_EnableDisable(t *testing.T) {
+	server := newPluginTestServer()
+	server.SetWriteToken(testWriteToken)
+	controller := &fakePluginController{enabled: map[string]bool{"music": true}}
+	server.SetPluginController(controller)
+
+	w := sendPluginRequest(server, http.MethodPost, "/api/plugins/music/disable", testWriteToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if controller.enabled["music"] {
+		t.Error("Expected music to be disabled")
+	}
+
+	w = sendPluginRequest(server, http.MethodGet, "/api/plugins", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response PluginsResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Plugins) != 1 || response.Plugins[0].Enabled {
+		t.Errorf("Expected music listed as disabled, got %+v", response.Plugins)
+	}
+
+	w = sendPluginRequest(server, http.MethodPost, "/api/plugins/music/enable", testWriteToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !controller.enabled["music"] {
+		t.Error("Expected music to be enabled")
+	}
+}
+
+func TestPlugins_Errors(t *testing.T) {
+	server := newPluginTestServer()
+	server.SetWriteToken(testWriteToken)
+	server.SetPluginController(&fakePluginController{
+		enabled: map[string]bool{"music": true, "emergency": true},
+		busy:    map[string]bool{"emergency": true},
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"unknown plugin", http.MethodPost, "/api/plugins/jukebox/disable", testWriteToken, http.StatusNotFound},
+		{"busy plugin", http.MethodPost, "/api/plugins/emergency/disable", testWriteToken, http.StatusConflict},
+		{"missing token", http.MethodPost, "/api/plugins/music/disable", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/api/plugins/music/enable", "nope", http.StatusUnauthorized},
+		{"method not allowed", http.MethodGet, "/api/plugins/music/enable", testWriteToken, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendPluginRequest(server, tt.method, tt.path, tt.token)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"homeautomation/internal/metrics"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// SetMetrics serves the registry on /metrics, adding gauges for numeric state
+// variables and for how long ago each plugin last acted
+func (s *Server) SetMetrics(registry *metrics.Registry) {
+	registry.GaugeFunc("state_number",
+		"Current value of each numeric state variable",
+		[]string{"key"}, s.collectNumberSamples)
+	registry.GaugeFunc("plugin_last_action_age_seconds",
+		"Seconds since each plugin last acted, from its shadow state; plugins that have not acted since startup are omitted",
+		[]string{"plugin"}, s.collectLastActionAgeSamples)
+
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	s.registry = registry
+}
+
+func (s *Server) getRegistry() *metrics.Registry {
+	s.metricsMu.RLock()
+	defer s.metricsMu.RUnlock()
+	return s.registry
+}
+
+// collectNumberSamples reads every numeric state variable
+func (s *Server) collectNumberSamples() []metrics.Sample {
+	var samples []metrics.Sample
+	for _, variable := range state.AllVariables {
+		if variable.Type != state.TypeNumber {
+			continue
+		}
+		value, err := s.stateManager.GetNumber(variable.Key)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, metrics.Sample{LabelValues: []string{variable.Key}, Value: value})
+	}
+	return samples
+}
+
+// collectLastActionAgeSamples reads each plugin's last action time
+func (s *Server) collectLastActionAgeSamples() []metrics.Sample {
+	now := time.Now()
+	var samples []metrics.Sample
+	for name, pluginState := range s.shadowTracker.GetAllPluginStates() {
+		provider, ok := pluginState.(shadowstate.ActionTimeProvider)
+		if !ok {
+			continue
+		}
+		lastAction := provider.GetLastActionTime()
+		if lastAction.IsZero() {
+			continue
+		}
+		samples = append(samples, metrics.Sample{
+			LabelValues: []string{name},
+			Value:       now.Sub(lastAction).Seconds(),
+		})
+	}
+	return samples
+}
+
+// handlePrometheusMetrics serves the metrics registry in the Prometheus text format
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registry := s.getRegistry()
+	if registry == nil {
+		http.Error(w, "Metrics not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := registry.WriteText(w); err != nil {
+		s.logger.Error("Failed to write metrics", zap.Error(err))
+		return
+	}
+
+	s.logger.Debug("Prometheus metrics request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/metrics"
+	"homeautomation/internal/shadowstate"
+)
+
+func TestHandlePrometheusMetrics(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+	if err := stateManager.SetNumber("alarmTime", 1700000000000); err != nil {
+		t.Fatalf("SetNumber failed: %v", err)
+	}
+
+	focus := shadowstate.NewFocusModeTracker()
+	focus.RecordChange(true, "toggle", time.Now().Add(-90*time.Second), "Focus mode on by toggle")
+	server.shadowTracker.RegisterPluginProvider("focusmode", func() shadowstate.PluginShadowState {
+		return focus.GetState()
+	})
+	idle := shadowstate.NewFocusModeTracker()
+	server.shadowTracker.RegisterPluginProvider("idle", func() shadowstate.PluginShadowState {
+		return idle.GetState()
+	})
+
+	registry := metrics.NewRegistry()
+	registry.Counter("ha_service_calls_total", "Service calls", "domain", "service", "result").Inc("light", "turn_on", "success")
+	server.SetMetrics(registry)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.handlePrometheusMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus text format, got %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`homeautomation_ha_service_calls_total{domain="light",service="turn_on",result="success"} 1`,
+		`homeautomation_state_number{key="alarmTime"} 1.7e+12`,
+		"# TYPE homeautomation_plugin_last_action_age_seconds gauge",
+		`homeautomation_plugin_last_action_age_seconds{plugin="focusmode"} 9`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `plugin="idle"`) {
+		t.Errorf("A plugin that has not acted should be omitted, got:\n%s", body)
+	}
+}
+
+func TestHandlePrometheusMetrics_NotConfigured(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.handlePrometheusMetrics(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", w.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Assets that make the dashboard an installable progressive web app
+
+//go:embed templates/manifest.webmanifest
+var manifestJSON string
+
+//go:embed templates/sw.js
+var serviceWorkerJS string
+
+//go:embed templates/icon.svg
+var iconSVG string
+
+// handlePWAAsset serves the web app manifest, service worker and icon
+func (s *Server) handlePWAAsset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body string
+	switch r.URL.Path {
+	case "/manifest.webmanifest":
+		w.Header().Set("Content-Type", "application/manifest+json")
+		body = manifestJSON
+	case "/sw.js":
+		// Browsers check for a new service worker on every navigation
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("Cache-Control", "no-cache")
+		body = serviceWorkerJS
+	case "/icon.svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		body = iconSVG
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	_, _ = w.Write([]byte(body))
+}
@@ -4,10 +4,23 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
+	"homeautomation/internal/buildinfo"
+	"homeautomation/internal/health"
+	"homeautomation/internal/journal"
+	"homeautomation/internal/metrics"
+	"homeautomation/internal/plugins/music"
+	"homeautomation/internal/plugins/openreminder"
+	"homeautomation/internal/plugins/security"
+	"homeautomation/internal/reports"
+	"homeautomation/internal/scheduler"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -17,40 +30,161 @@ import (
 //go:embed templates/dashboard.html
 var dashboardHTML string
 
+// WeeklyReportProvider supplies the latest weekly automation report
+type WeeklyReportProvider interface {
+	GetWeeklyReport() *reports.WeeklyReport
+}
+
+// OccupancyHeatmapProvider supplies hourly room occupancy percentages
+type OccupancyHeatmapProvider interface {
+	GetOccupancyHeatmap() *reports.OccupancyHeatmap
+}
+
+// SpeakerGroupController lists and activates speaker group presets
+type SpeakerGroupController interface {
+	GetSpeakerGroupPresets() map[string][]string
+	GetActiveSpeakerGroupPreset() string
+	ActivateSpeakerGroupPreset(name string) error
+}
+
+// NowPlayingProvider supplies the track the music lead player reports
+type NowPlayingProvider interface {
+	GetNowPlaying() *shadowstate.NowPlaying
+}
+
+// OpenReminderAcknowledger stops the active door/window left-open reminder
+type OpenReminderAcknowledger interface {
+	Acknowledge() error
+}
+
+// SecurityDrillRunner starts a supervised security drill
+type SecurityDrillRunner interface {
+	StartDrill() error
+}
+
+// SecurityAlarmController reads and sets the security alarm mode
+type SecurityAlarmController interface {
+	GetAlarmState() shadowstate.AlarmState
+	SetAlarmMode(mode string) error
+}
+
+// SecurityLocksProvider supplies the door locks and their audit trail
+type SecurityLocksProvider interface {
+	GetDoorLocks() shadowstate.DoorLocksState
+}
+
+// HotWaterScheduleProvider supplies the recirculation schedule learned from hot water use
+type HotWaterScheduleProvider interface {
+	GetLearnedSchedule() shadowstate.HotWaterSchedule
+}
+
+// JobScheduler lists the jobs scheduled by every plugin
+type JobScheduler interface {
+	Jobs() []scheduler.Job
+}
+
+// HistoryProvider supplies recent state changes and plugin actions from the event journal
+type HistoryProvider interface {
+	GetHistory(filter journal.Filter) []journal.Event
+}
+
 // Server provides HTTP API endpoints for the home automation system
 type Server struct {
-	stateManager  *state.Manager
-	shadowTracker *shadowstate.Tracker
-	logger        *zap.Logger
-	server        *http.Server
-	timezone      *time.Location
+	stateManager           *state.Manager
+	shadowTracker          *shadowstate.Tracker
+	logger                 *zap.Logger
+	server                 *http.Server
+	timezone               *time.Location
+	reportProvider         WeeklyReportProvider
+	occupancyProvider      OccupancyHeatmapProvider
+	speakerGroupController SpeakerGroupController
+	nowPlaying             NowPlayingProvider
+	openReminderAck        OpenReminderAcknowledger
+	securityDrill          SecurityDrillRunner
+	securityAlarm          SecurityAlarmController
+	securityLocks          SecurityLocksProvider
+	hotWaterSchedule       HotWaterScheduleProvider
+	jobScheduler           JobScheduler
+	pluginController       PluginController
+	healthCheckers         map[string]health.Checker
+	healthMu               sync.RWMutex // Protects healthCheckers, registered while serving
+	diffPeerURL            string
+	diffClient             *http.Client
+	historyProvider        HistoryProvider
+	metrics                *requestMetrics
+	metricsMu              sync.RWMutex // Protects slowRequestThreshold and registry
+	registry               *metrics.Registry
+	slowRequestThreshold   time.Duration
+	writeToken             string
 }
 
 // NewServer creates a new API server
 func NewServer(stateManager *state.Manager, shadowTracker *shadowstate.Tracker, logger *zap.Logger, port int, timezone *time.Location) *Server {
 	s := &Server{
-		stateManager:  stateManager,
-		shadowTracker: shadowTracker,
-		logger:        logger,
-		timezone:      timezone,
+		stateManager:         stateManager,
+		shadowTracker:        shadowTracker,
+		logger:               logger,
+		timezone:             timezone,
+		metrics:              newRequestMetrics(),
+		slowRequestThreshold: DefaultSlowRequestThreshold,
+		diffClient:           &http.Client{Timeout: diffPeerTimeout},
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleSitemap)
-	mux.HandleFunc("/api/state", s.handleGetState)
-	mux.HandleFunc("/api/states", s.handleGetStatesByPlugin)
-	mux.HandleFunc("/api/shadow", s.handleGetAllShadowStates)
-	mux.HandleFunc("/api/shadow/lighting", s.handleGetLightingShadowState)
-	mux.HandleFunc("/api/shadow/music", s.handleGetMusicShadowState)
-	mux.HandleFunc("/api/shadow/security", s.handleGetSecurityShadowState)
-	mux.HandleFunc("/api/shadow/loadshedding", s.handleGetLoadSheddingShadowState)
-	mux.HandleFunc("/api/shadow/sleephygiene", s.handleGetSleepHygieneShadowState)
-	mux.HandleFunc("/api/shadow/energy", s.handleGetEnergyShadowState)
-	mux.HandleFunc("/api/shadow/statetracking", s.handleGetStateTrackingShadowState)
-	mux.HandleFunc("/api/shadow/dayphase", s.handleGetDayPhaseShadowState)
-	mux.HandleFunc("/api/shadow/tv", s.handleGetTVShadowState)
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/dashboard", s.handleDashboard)
+	mux.HandleFunc("/", s.instrument("/", s.handleSitemap))
+	mux.HandleFunc("/api/state", s.instrument("/api/state", s.handleState))
+	mux.HandleFunc("/api/state/{key}", s.instrument("/api/state/{key}", s.handleStateVariable))
+	// Not instrumented: a stream stays open far longer than the slow request threshold
+	mux.HandleFunc("/api/state/fanout", s.instrument("/api/state/fanout", s.handleGetStateFanOut))
+	mux.HandleFunc("/api/state/stream", s.handleStateStream)
+	mux.HandleFunc("/api/ws", s.handleWebSocket)
+	mux.HandleFunc("/api/states", s.instrument("/api/states", s.handleGetStatesByPlugin))
+	mux.HandleFunc("/api/shadow", s.instrument("/api/shadow", s.handleGetAllShadowStates))
+	mux.HandleFunc("/api/shadow/lighting", s.instrument("/api/shadow/lighting", s.handleGetLightingShadowState))
+	mux.HandleFunc("/api/shadow/music", s.instrument("/api/shadow/music", s.handleGetMusicShadowState))
+	mux.HandleFunc("/api/shadow/security", s.instrument("/api/shadow/security", s.handleGetSecurityShadowState))
+	mux.HandleFunc("/api/shadow/loadshedding", s.instrument("/api/shadow/loadshedding", s.handleGetLoadSheddingShadowState))
+	mux.HandleFunc("/api/shadow/sleephygiene", s.instrument("/api/shadow/sleephygiene", s.handleGetSleepHygieneShadowState))
+	mux.HandleFunc("/api/shadow/energy", s.instrument("/api/shadow/energy", s.handleGetEnergyShadowState))
+	mux.HandleFunc("/api/shadow/statetracking", s.instrument("/api/shadow/statetracking", s.handleGetStateTrackingShadowState))
+	mux.HandleFunc("/api/shadow/dayphase", s.instrument("/api/shadow/dayphase", s.handleGetDayPhaseShadowState))
+	mux.HandleFunc("/api/shadow/tv", s.instrument("/api/shadow/tv", s.handleGetTVShadowState))
+	mux.HandleFunc("/api/shadow/growlights", s.instrument("/api/shadow/growlights", s.handleGetGrowLightsShadowState))
+	mux.HandleFunc("/api/shadow/bedroomcomfort", s.instrument("/api/shadow/bedroomcomfort", s.handleGetBedroomComfortShadowState))
+	mux.HandleFunc("/api/shadow/openreminder", s.instrument("/api/shadow/openreminder", s.handleGetOpenReminderShadowState))
+	mux.HandleFunc("/api/shadow/lowbattery", s.instrument("/api/shadow/lowbattery", s.handleGetLowBatteryShadowState))
+	mux.HandleFunc("/api/shadow/focusmode", s.instrument("/api/shadow/focusmode", s.handleGetFocusModeShadowState))
+	mux.HandleFunc("/api/shadow/kidmode", s.instrument("/api/shadow/kidmode", s.handleGetKidModeShadowState))
+	mux.HandleFunc("/api/shadow/hotwater", s.instrument("/api/shadow/hotwater", s.handleGetHotWaterShadowState))
+	mux.HandleFunc("/api/shadow/rules", s.instrument("/api/shadow/rules", s.handleGetRulesShadowState))
+	mux.HandleFunc("/api/shadow/scenescheduler", s.instrument("/api/shadow/scenescheduler", s.handleGetSceneSchedulerShadowState))
+	mux.HandleFunc("/api/shadow/sleepfan", s.instrument("/api/shadow/sleepfan", s.handleGetSleepFanShadowState))
+	mux.HandleFunc("/api/shadow/freezeprotection", s.instrument("/api/shadow/freezeprotection", s.handleGetFreezeProtectionShadowState))
+	mux.HandleFunc("/api/shadow/climate", s.instrument("/api/shadow/climate", s.handleGetClimateShadowState))
+	mux.HandleFunc("/api/shadow/emergency", s.instrument("/api/shadow/emergency", s.handleGetEmergencyShadowState))
+	mux.HandleFunc("/api/reports/weekly", s.instrument("/api/reports/weekly", s.handleGetWeeklyReport))
+	mux.HandleFunc("/api/occupancy/heatmap", s.instrument("/api/occupancy/heatmap", s.handleGetOccupancyHeatmap))
+	mux.HandleFunc("/api/music/speaker-group", s.instrument("/api/music/speaker-group", s.handleSpeakerGroup))
+	mux.HandleFunc("/api/music/nowplaying", s.instrument("/api/music/nowplaying", s.handleGetNowPlaying))
+	mux.HandleFunc("/api/open-reminder/acknowledge", s.instrument("/api/open-reminder/acknowledge", s.handleAcknowledgeOpenReminder))
+	mux.HandleFunc("/api/security/drill", s.instrument("/api/security/drill", s.handleStartSecurityDrill))
+	mux.HandleFunc("/api/security/alarm", s.instrument("/api/security/alarm", s.handleSecurityAlarm))
+	mux.HandleFunc("/api/security/locks", s.instrument("/api/security/locks", s.handleGetSecurityLocks))
+	mux.HandleFunc("/api/hotwater/schedule", s.instrument("/api/hotwater/schedule", s.handleGetHotWaterSchedule))
+	mux.HandleFunc("/api/schedule", s.instrument("/api/schedule", s.handleGetSchedule))
+	mux.HandleFunc("/api/plugins", s.instrument("/api/plugins", s.handleGetPlugins))
+	mux.HandleFunc("/api/plugins/{name}/enable", s.instrument("/api/plugins/{name}/enable", s.handleEnablePlugin))
+	mux.HandleFunc("/api/plugins/{name}/disable", s.instrument("/api/plugins/{name}/disable", s.handleDisablePlugin))
+	mux.HandleFunc("/api/history", s.instrument("/api/history", s.handleGetHistory))
+	mux.HandleFunc("/api/diff", s.instrument("/api/diff", s.handleGetDiff))
+	mux.HandleFunc("/health", s.instrument("/health", s.handleHealth))
+	mux.HandleFunc("/api/metrics", s.instrument("/api/metrics", s.handleGetMetrics))
+	mux.HandleFunc("/metrics", s.instrument("/metrics", s.handlePrometheusMetrics))
+	mux.HandleFunc("/api/version", s.instrument("/api/version", s.handleVersion))
+	mux.HandleFunc("/dashboard", s.instrument("/dashboard", s.handleDashboard))
+	mux.HandleFunc("/manifest.webmanifest", s.instrument("/manifest.webmanifest", s.handlePWAAsset))
+	mux.HandleFunc("/sw.js", s.instrument("/sw.js", s.handlePWAAsset))
+	mux.HandleFunc("/icon.svg", s.instrument("/icon.svg", s.handlePWAAsset))
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
@@ -78,6 +212,21 @@ func (s *Server) handleGetState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	response := s.collectState()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("State request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// collectState reads every state variable into the /api/state document
+func (s *Server) collectState() StateResponse {
 	response := StateResponse{
 		Booleans: make(map[string]bool),
 		Numbers:  make(map[string]float64),
@@ -119,7 +268,8 @@ func (s *Server) handleGetState(w http.ResponseWriter, r *http.Request) {
 			response.Strings[variable.Key] = value
 
 		case state.TypeJSON:
-			var value map[string]interface{}
+			// Objects or arrays (e.g. lowBatteryDevices)
+			var value interface{}
 			if err := s.stateManager.GetJSON(variable.Key, &value); err != nil {
 				s.logger.Error("Failed to get JSON variable",
 					zap.String("key", variable.Key),
@@ -129,16 +279,7 @@ func (s *Server) handleGetState(w http.ResponseWriter, r *http.Request) {
 			response.JSONs[variable.Key] = value
 		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		s.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	s.logger.Debug("State request served",
-		zap.String("remote_addr", r.RemoteAddr))
+	return response
 }
 
 // PluginMetadata describes which state variables a plugin uses
@@ -177,8 +318,8 @@ var pluginRegistry = []PluginMetadata{
 	{
 		Name:        "music",
 		Description: "Manages music playback mode and Sonos control",
-		Reads:       []string{"dayPhase", "isAnyoneAsleep", "isAnyoneHome", "musicPlaybackType"},
-		Writes:      []string{"musicPlaybackType", "currentlyPlayingMusicUri"},
+		Reads:       []string{"dayPhase", "isAnyoneAsleep", "isAnyoneHome", "musicPlaybackType", "speakerGroupPreset", "isPrimaryBedroomDoNotDisturb", "isGuestBedroomDoNotDisturb", "isOfficeFocusActive", "isKidModeActive", "isGuestBedroomKidMode"},
+		Writes:      []string{"musicPlaybackType", "currentlyPlayingMusicUri", "speakerGroupPreset"},
 	},
 	{
 		Name:        "lighting",
@@ -194,28 +335,100 @@ var pluginRegistry = []PluginMetadata{
 	},
 	{
 		Name:        "energy",
-		Description: "Monitors battery, solar production, and grid availability",
-		Reads:       []string{"isGridAvailable", "batteryEnergyLevel", "solarProductionEnergyLevel", "isFreeEnergyAvailable"},
-		Writes:      []string{"batteryEnergyLevel", "thisHourSolarGeneration", "remainingSolarGeneration", "solarProductionEnergyLevel", "currentEnergyLevel", "isFreeEnergyAvailable"},
+		Description: "Monitors battery, solar production, and grid availability, switches the inverter to backup reserve ahead of outage risk, sets the battery reserve by policy (raised during a storm watch), and publishes time-of-use grid pricing",
+		Reads:       []string{"isGridAvailable", "batteryEnergyLevel", "solarProductionEnergyLevel", "isFreeEnergyAvailable", "solarForecast", "isStormWatch"},
+		Writes:      []string{"batteryEnergyLevel", "thisHourSolarGeneration", "remainingSolarGeneration", "solarProductionEnergyLevel", "currentEnergyLevel", "isFreeEnergyAvailable", "isPeakPricing", "currentGridPrice"},
 	},
 	{
 		Name:        "loadshedding",
-		Description: "Controls thermostat based on available energy",
+		Description: "Controls thermostat based on available energy and sheds tiered devices as energy drops",
 		Reads:       []string{"currentEnergyLevel"},
-		Writes:      []string{},
+		Writes:      []string{"loadSheddingPlan"},
 	},
 	{
 		Name:        "sleephygiene",
 		Description: "Manages wake-up sequences and bedtime routines",
-		Reads:       []string{"alarmTime"},
-		Writes:      []string{"isFadeOutInProgress", "currentlyPlayingMusic", "musicPlaybackType"},
+		Reads:       []string{"alarmTime", "isPrimaryBedroomDoNotDisturb", "isGuestBedroomDoNotDisturb"},
+		Writes:      []string{"isFadeOutInProgress", "currentlyPlayingMusic", "musicPlaybackType", "isPrimaryBedroomDoNotDisturb", "isGuestBedroomDoNotDisturb", "alarmTime", "sleepHygieneTriggers"},
 	},
 	{
 		Name:        "security",
 		Description: "Manages security automation based on presence and sleep",
-		Reads:       []string{"isEveryoneAsleep", "isAnyoneHome", "didOwnerJustReturnHome", "isExpectingSomeone"},
+		Reads:       []string{"isEveryoneAsleep", "isAnyoneHome", "isAnyOwnerHome", "didOwnerJustReturnHome", "isExpectingSomeone", "isKitchenOccupied", "isNickOfficeOccupied", "isExpectingDelivery"},
+		Writes:      []string{"lockdownActive", "isPersonAtFrontDoor", "isPersonInDriveway"},
+	},
+	{
+		Name:        "growlights",
+		Description: "Schedules supplemental grow lighting to complement natural day length",
+		Reads:       []string{"currentEnergyLevel"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "bedroomcomfort",
+		Description: "Makes small overnight fan and thermostat adjustments to keep the bedroom comfortable",
+		Reads:       []string{"isMasterAsleep", "currentEnergyLevel"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "openreminder",
+		Description: "Reminds about doors and windows left open when everyone is asleep or away",
+		Reads:       []string{"isEveryoneAsleep", "isAnyoneHome"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "lowbattery",
+		Description: "Sweeps battery levels daily and sends a weekly low-battery notification",
+		Reads:       []string{},
+		Writes:      []string{"lowBatteryDevices"},
+	},
+	{
+		Name:        "focusmode",
+		Description: "Holds the office on a concentration scene and keeps its speakers out of music and non-critical TTS during focus windows",
+		Reads:       []string{"isOfficeFocusMode"},
+		Writes:      []string{"isOfficeFocusActive"},
+	},
+	{
+		Name:        "kidmode",
+		Description: "Turns kid mode on by toggle or schedule, household-wide or per room, and holds the rooms' thermostats at their setpoint",
+		Reads:       []string{"isKidMode", "isGuestBedroomKidMode"},
+		Writes:      []string{"isKidModeActive"},
+	},
+	{
+		Name:        "hotwater",
+		Description: "Runs the hot water recirculation pump ahead of learned hot water use and the wake-up alarm while someone is home",
+		Reads:       []string{"isAnyoneHome", "isEveryoneAsleep", "isMasterAsleep", "alarmTime"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "scenescheduler",
+		Description: "Activates scenes on cron schedules or at offsets from sunrise and sunset, optionally only while someone is home",
+		Reads:       []string{"isAnyoneHome"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "sleepfan",
+		Description: "Steps bedroom fan speeds with room temperature while each bedroom is asleep, and turns fans off while a window is open",
+		Reads:       []string{"isMasterAsleep", "isGuestAsleep"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "freezeprotection",
+		Description: "Switches space heaters and opens HVAC dampers in unconditioned spaces near freezing, with heater limits by energy level, and alerts if the temperature keeps dropping",
+		Reads:       []string{"currentEnergyLevel"},
+		Writes:      []string{},
+	},
+	{
+		Name:        "climate",
+		Description: "Sets thermostat setpoints from occupancy, sleep and the day phase, with a setback while away or asleep, and pauses HVAC while a window or door stays open; thermostats shed by load shedding keep their shed setpoints",
+		Reads:       []string{"isAnyoneHome", "isEveryoneAsleep", "dayPhase", "loadSheddingPlan"},
 		Writes:      []string{},
 	},
+	{
+		Name:        "emergency",
+		Description: "Responds to smoke/CO detectors: lights to full white, doors unlocked, music stopped, the garage opened, and repeated evacuation announcements until acknowledged; every other plugin is suspended while it lasts",
+		Reads:       []string{"isEmergencyAcknowledged"},
+		Writes:      []string{"isEmergencyActive", "isEmergencyAcknowledged"},
+	},
 	{
 		Name:        "reset",
 		Description: "Coordinates system-wide state resets",
@@ -328,18 +541,17 @@ func (s *Server) getStateVariableValue(key string) (interface{}, string) {
 	return nil, ""
 }
 
-// handleHealth returns a simple health check response
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+// handleVersion returns the build metadata of the running binary
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ok",
-	})
+	if err := json.NewEncoder(w).Encode(buildinfo.Get()); err != nil {
+		s.logger.Error("Failed to encode version response", zap.Error(err))
+	}
 }
 
 // Endpoint represents an API endpoint with its documentation
@@ -373,6 +585,31 @@ func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
 			Method:      "GET",
 			Description: "Get all state variables grouped by type (booleans, numbers, strings, jsons)",
 		},
+		{
+			Path:        "/api/state",
+			Method:      "PATCH",
+			Description: "Bulk update state variables all or nothing - body: JSON Patch (application/json-patch+json, e.g. [{\"op\": \"replace\", \"path\": \"/booleans/isHaveGuests\", \"value\": true}]) or merge patch (application/merge-patch+json) shaped like GET /api/state; returns a result per change. Requires Authorization: Bearer <API_TOKEN> when API_TOKEN is set",
+		},
+		{
+			Path:        "/api/state/stream",
+			Method:      "GET",
+			Description: "Server-Sent Events stream of state changes - a \"snapshot\" event shaped like GET /api/state, then a \"change\" event ({key, group, value}) per update",
+		},
+		{
+			Path:        "/api/ws",
+			Method:      "GET",
+			Description: "WebSocket push channel used by the dashboard - a \"snapshot\" message ({state, plugins}) shaped like GET /api/state and GET /api/shadow, then a \"state\" message ({key, group, value}) per state change and a \"shadow\" message ({plugins}) with each plugin whose shadow state changed",
+		},
+		{
+			Path:        "/api/state/{key}",
+			Method:      "GET",
+			Description: "Get one state variable - returns {key, group, value}",
+		},
+		{
+			Path:        "/api/state/{key}",
+			Method:      "POST, PUT",
+			Description: "Set one state variable - body: {\"value\": ...} matching the variable's type; requires Authorization: Bearer <API_TOKEN> (disabled when API_TOKEN is unset); when the state write scope is read-only only local-only variables can be set",
+		},
 		{
 			Path:        "/api/states",
 			Method:      "GET",
@@ -396,7 +633,7 @@ func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
 		{
 			Path:        "/api/shadow/security",
 			Method:      "GET",
-			Description: "Get shadow state for security plugin - shows lockdown status, doorbell events, and garage actions",
+			Description: "Get shadow state for security plugin - shows lockdown status, alarm state, door locks, doorbell events, garage actions, and person detections",
 		},
 		{
 			Path:        "/api/shadow/loadshedding",
@@ -406,7 +643,7 @@ func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
 		{
 			Path:        "/api/shadow/sleephygiene",
 			Method:      "GET",
-			Description: "Get shadow state for sleep hygiene plugin - shows wake sequence status, fade-out progress, TTS announcements, and reminders",
+			Description: "Get shadow state for sleep hygiene plugin - shows wake sequence status, fade-out progress, TTS announcements, reminders, and the adaptive wake alarm computation",
 		},
 		{
 			Path:        "/api/shadow/energy",
@@ -428,15 +665,185 @@ func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
 			Method:      "GET",
 			Description: "Get shadow state for TV plugin - shows Apple TV state, TV power, HDMI input, and playback status",
 		},
+		{
+			Path:        "/api/shadow/growlights",
+			Method:      "GET",
+			Description: "Get shadow state for grow lights plugin - shows natural day length, energy deferral, and per-fixture lighting windows",
+		},
+		{
+			Path:        "/api/shadow/bedroomcomfort",
+			Method:      "GET",
+			Description: "Get shadow state for bedroom comfort plugin - shows tonight's fan/thermostat adjustments against the nightly cap",
+		},
+		{
+			Path:        "/api/shadow/openreminder",
+			Method:      "GET",
+			Description: "Get shadow state for open reminder plugin - shows open doors/windows, the trigger, and reminders sent",
+		},
+		{
+			Path:        "/api/shadow/lowbattery",
+			Method:      "GET",
+			Description: "Get shadow state for low battery plugin - shows batteries under their threshold from the last daily sweep and the last weekly notification",
+		},
+		{
+			Path:        "/api/shadow/focusmode",
+			Method:      "GET",
+			Description: "Get shadow state for office focus mode - shows whether it is on, whether the toggle or a calendar event started it, and since when",
+		},
+		{
+			Path:        "/api/shadow/kidmode",
+			Method:      "GET",
+			Description: "Get shadow state for kid mode - shows whether it is on household-wide and why, which rooms are in kid mode, and the thermostat setpoints held",
+		},
+		{
+			Path:        "/api/shadow/hotwater",
+			Method:      "GET",
+			Description: "Get shadow state for hot water recirculation - shows whether the pump is running and why, the next run, today's draws and the learned schedule",
+		},
+		{
+			Path:        "/api/shadow/rules",
+			Method:      "GET",
+			Description: "Get shadow state for YAML automation rules - shows each rule's last trigger, whether its conditions held, the actions it ran and its next cron time",
+		},
+		{
+			Path:        "/api/shadow/scenescheduler",
+			Method:      "GET",
+			Description: "Get shadow state for the scene scheduler - shows each scheduled scene's cron or sun event, next run and last result",
+		},
+		{
+			Path:        "/api/shadow/sleepfan",
+			Method:      "GET",
+			Description: "Get shadow state for sleep fan control - shows each bedroom's temperature, window, band speed and the fan speed last set",
+		},
+		{
+			Path:        "/api/shadow/freezeprotection",
+			Method:      "GET",
+			Description: "Get shadow state for freeze protection - shows each space's temperature, heater limits at the energy level, heaters and dampers, and the last alert",
+		},
+		{
+			Path:        "/api/shadow/climate",
+			Method:      "GET",
+			Description: "Get shadow state for the thermostat schedule - shows each thermostat's comfort mode, target vs. actual setpoints, what holds it, open window pauses, and the reason for the last change",
+		},
+		{
+			Path:        "/api/shadow/emergency",
+			Method:      "GET",
+			Description: "Get shadow state for the smoke/CO emergency - shows each detector, whether an emergency is active or acknowledged, the suspended plugins, announcements, and the response actions taken",
+		},
+		{
+			Path:        "/api/reports/weekly",
+			Method:      "GET",
+			Description: "Get the weekly automation report - automations per plugin, energy self-consumption, average sleep/wake times, and doorbell events",
+		},
+		{
+			Path:        "/api/occupancy/heatmap",
+			Method:      "GET",
+			Description: "Get each room's occupancy percentage per hour of day, overall and by weekday, over the last weeks - null for hours without samples",
+		},
+		{
+			Path:        "/api/music/speaker-group",
+			Method:      "GET",
+			Description: "Get the speaker group presets and the active preset",
+		},
+		{
+			Path:        "/api/music/speaker-group",
+			Method:      "POST",
+			Description: "Activate a speaker group preset with the current music mode's playlist - body: {\"preset\": \"dinner\"}, empty preset returns to the mode's speakers",
+		},
+		{
+			Path:        "/api/music/nowplaying",
+			Method:      "GET",
+			Description: "Get the track the lead player is playing - title, artist and album with the music mode and playlist URI; playing is false when no music is on",
+		},
+		{
+			Path:        "/api/open-reminder/acknowledge",
+			Method:      "POST",
+			Description: "Acknowledge the active door/window left-open reminder so it stops repeating",
+		},
+		{
+			Path:        "/api/security/drill",
+			Method:      "POST",
+			Description: "Start a security drill - notification, light flashes, low-volume TTS and valve relay click without lockdown; the checklist appears in /api/shadow/security as lastDrill",
+		},
+		{
+			Path:        "/api/security/alarm",
+			Method:      "GET",
+			Description: "Get the alarm mode (disarmed, home, away, night), status (arming, armed, pending, triggered), bypassed sensors and recent transitions",
+		},
+		{
+			Path:        "/api/security/alarm",
+			Method:      "POST",
+			Description: "Set the alarm mode - body: {\"mode\": \"away\"}; arming starts the exit delay, disarming silences the siren (requires Authorization: Bearer <API_TOKEN>)",
+		},
+		{
+			Path:        "/api/security/locks",
+			Method:      "GET",
+			Description: "Get each door lock's state, whether its door is open and when it auto-locks, plus the audit trail of lock/unlock events and who or what caused them",
+		},
+		{
+			Path:        "/api/hotwater/schedule",
+			Method:      "GET",
+			Description: "Get the hot water recirculation schedule learned from flow/temperature sensors - weekday and weekend slots with how often hot water was drawn in each",
+		},
+		{
+			Path:        "/api/schedule",
+			Method:      "GET",
+			Description: "Get every job on the shared scheduler - cron, sun event and one-shot jobs from all plugins, soonest first",
+		},
+		{
+			Path:        "/api/plugins",
+			Method:      "GET",
+			Description: "List the plugins that can be disabled at runtime and whether each is enabled",
+		},
+		{
+			Path:        "/api/plugins/{name}/enable",
+			Method:      "POST",
+			Description: "Start a disabled plugin and re-register its shadow state; saved to plugins_config.yaml (requires Authorization: Bearer <API_TOKEN>)",
+		},
+		{
+			Path:        "/api/plugins/{name}/disable",
+			Method:      "POST",
+			Description: "Stop a plugin and remove its shadow state; saved to plugins_config.yaml so it stays off after a restart (requires Authorization: Bearer <API_TOKEN>)",
+		},
+		{
+			Path:        "/api/history",
+			Method:      "GET",
+			Description: "Get recent state variable changes and plugin actions (with reason), oldest first - filters: ?plugin=, ?variable=, ?since= and ?until= (RFC3339, or a duration like 1h for since), ?limit=",
+		},
+		{
+			Path:        "/api/diff",
+			Method:      "GET",
+			Description: "Compare state variables and plugin shadow outputs with the instance at PEER_URL (the base) - lists changed, missing and added values; timestamps are ignored unless ?timestamps=true",
+		},
 		{
 			Path:        "/health",
 			Method:      "GET",
-			Description: "Health check endpoint - returns {\"status\": \"ok\"}",
+			Description: "Health check - overall status plus each plugin's health; 503 when any enabled plugin is unhealthy",
+		},
+		{
+			Path:        "/api/version",
+			Method:      "GET",
+			Description: "Build metadata for the running binary - version, git commit, build time, and Go version",
+		},
+		{
+			Path:        "/api/state/fanout",
+			Method:      "GET",
+			Description: "State variable fan-out - per variable subscriber count, average callback time, declared writes and cascade depth, plus any cycles of writes",
+		},
+		{
+			Path:        "/api/metrics",
+			Method:      "GET",
+			Description: "HTTP API request metrics - per-route request counts, status codes, duration histograms, and slow requests over the threshold",
+		},
+		{
+			Path:        "/metrics",
+			Method:      "GET",
+			Description: "Prometheus metrics - HA service calls per domain/service, HA reconnects, numeric state variable values, seconds since each plugin last acted, and state change callback latency",
 		},
 		{
 			Path:        "/dashboard",
 			Method:      "GET",
-			Description: "Shadow State Dashboard - web UI to visualize plugin states",
+			Description: "Shadow State Dashboard - installable web app with pinned music, expecting-someone and energy controls, and plugin states",
 		},
 	}
 
@@ -720,16 +1127,16 @@ func (s *Server) handleGetDayPhaseShadowState(w http.ResponseWriter, r *http.Req
 		zap.String("remote_addr", r.RemoteAddr))
 }
 
-// handleGetTVShadowState returns the TV plugin shadow state
-func (s *Server) handleGetTVShadowState(w http.ResponseWriter, r *http.Request) {
+// handleGetGrowLightsShadowState returns the grow lights plugin shadow state
+func (s *Server) handleGetGrowLightsShadowState(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	state, ok := s.shadowTracker.GetPluginState("tv")
+	state, ok := s.shadowTracker.GetPluginState("growlights")
 	if !ok {
-		http.Error(w, "TV shadow state not found", http.StatusNotFound)
+		http.Error(w, "Grow lights shadow state not found", http.StatusNotFound)
 		return
 	}
 
@@ -740,68 +1147,867 @@ func (s *Server) handleGetTVShadowState(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	s.logger.Debug("TV shadow state request served",
+	s.logger.Debug("Grow lights shadow state request served",
 		zap.String("remote_addr", r.RemoteAddr))
 }
 
-// AllShadowStatesResponse represents the response for /api/shadow endpoint
-type AllShadowStatesResponse struct {
-	Plugins  map[string]interface{} `json:"plugins"`
-	Metadata ShadowMetadata         `json:"metadata"`
-}
-
-// ShadowMetadata contains metadata about the shadow state response
-type ShadowMetadata struct {
-	Timestamp time.Time `json:"timestamp"`
-	Version   string    `json:"version"`
-}
-
-// handleGetAllShadowStates returns shadow states for all plugins
-func (s *Server) handleGetAllShadowStates(w http.ResponseWriter, r *http.Request) {
+// handleGetBedroomComfortShadowState returns the bedroom comfort plugin shadow state
+func (s *Server) handleGetBedroomComfortShadowState(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	allStates := s.shadowTracker.GetAllPluginStates()
-
-	// Convert to map[string]interface{} for JSON encoding
-	pluginsData := make(map[string]interface{})
-	for name, state := range allStates {
-		pluginsData[name] = state
-	}
-
-	response := AllShadowStatesResponse{
-		Plugins: pluginsData,
-		Metadata: ShadowMetadata{
-			Timestamp: time.Now(),
-			Version:   "1.0.0",
-		},
+	state, ok := s.shadowTracker.GetPluginState("bedroomcomfort")
+	if !ok {
+		http.Error(w, "Bedroom comfort shadow state not found", http.StatusNotFound)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := s.writeJSONWithLocalTimestamps(w, response); err != nil {
-		s.logger.Error("Failed to encode shadow states response", zap.Error(err))
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	s.logger.Debug("All shadow states request served",
-		zap.String("remote_addr", r.RemoteAddr),
-		zap.Int("plugin_count", len(allStates)))
+	s.logger.Debug("Bedroom comfort shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
 }
 
-// Start begins serving HTTP requests
-func (s *Server) Start() error {
-	s.logger.Info("Starting HTTP API server", zap.String("addr", s.server.Addr))
+// handleGetOpenReminderShadowState returns the open reminder plugin shadow state
+func (s *Server) handleGetOpenReminderShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.logger.Error("HTTP server error", zap.Error(err))
-		}
-	}()
+	state, ok := s.shadowTracker.GetPluginState("openreminder")
+	if !ok {
+		http.Error(w, "Open reminder shadow state not found", http.StatusNotFound)
+		return
+	}
 
-	return nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Open reminder shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetLowBatteryShadowState returns the low battery plugin shadow state
+func (s *Server) handleGetLowBatteryShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("lowbattery")
+	if !ok {
+		http.Error(w, "Low battery shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Low battery shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetFocusModeShadowState returns the focus mode shadow state
+func (s *Server) handleGetFocusModeShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("focusmode")
+	if !ok {
+		http.Error(w, "Focus mode shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Focus mode shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetKidModeShadowState returns the kid mode shadow state
+func (s *Server) handleGetKidModeShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("kidmode")
+	if !ok {
+		http.Error(w, "Kid mode shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Kid mode shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetHotWaterShadowState returns the hot water plugin shadow state
+func (s *Server) handleGetHotWaterShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("hotwater")
+	if !ok {
+		http.Error(w, "Hot water shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Hot water shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetSceneSchedulerShadowState returns the scene scheduler plugin shadow state
+func (s *Server) handleGetSceneSchedulerShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("scenescheduler")
+	if !ok {
+		http.Error(w, "Scene scheduler shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Scene scheduler shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetSleepFanShadowState returns the sleep fan plugin shadow state
+func (s *Server) handleGetSleepFanShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("sleepfan")
+	if !ok {
+		http.Error(w, "Sleep fan shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Sleep fan shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetFreezeProtectionShadowState returns the freeze protection plugin shadow state
+func (s *Server) handleGetFreezeProtectionShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("freezeprotection")
+	if !ok {
+		http.Error(w, "Freeze protection shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Freeze protection shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetClimateShadowState returns the climate plugin shadow state
+func (s *Server) handleGetClimateShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("climate")
+	if !ok {
+		http.Error(w, "Climate shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Climate shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetEmergencyShadowState returns the emergency plugin shadow state
+func (s *Server) handleGetEmergencyShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("emergency")
+	if !ok {
+		http.Error(w, "Emergency shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Emergency shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetRulesShadowState returns the rules plugin shadow state
+func (s *Server) handleGetRulesShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("rules")
+	if !ok {
+		http.Error(w, "Rules shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Rules shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// handleGetTVShadowState returns the TV plugin shadow state
+func (s *Server) handleGetTVShadowState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := s.shadowTracker.GetPluginState("tv")
+	if !ok {
+		http.Error(w, "TV shadow state not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, state); err != nil {
+		s.logger.Error("Failed to encode shadow state response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("TV shadow state request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// AllShadowStatesResponse represents the response for /api/shadow endpoint
+type AllShadowStatesResponse struct {
+	Plugins  map[string]interface{} `json:"plugins"`
+	Metadata ShadowMetadata         `json:"metadata"`
+}
+
+// ShadowMetadata contains metadata about the shadow state response
+type ShadowMetadata struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Version   string         `json:"version"`
+	Build     buildinfo.Info `json:"build"`
+}
+
+// SetWeeklyReportProvider sets the source for the weekly report endpoint.
+// The report manager starts after the API server, so it is attached here.
+func (s *Server) SetWeeklyReportProvider(provider WeeklyReportProvider) {
+	s.reportProvider = provider
+}
+
+// handleGetWeeklyReport returns the latest weekly automation report
+func (s *Server) handleGetWeeklyReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.reportProvider == nil {
+		http.Error(w, "Weekly report not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, s.reportProvider.GetWeeklyReport()); err != nil {
+		s.logger.Error("Failed to encode weekly report response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Weekly report request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// SetOccupancyHeatmapProvider sets the source for the occupancy heatmap endpoint
+func (s *Server) SetOccupancyHeatmapProvider(provider OccupancyHeatmapProvider) {
+	s.occupancyProvider = provider
+}
+
+// handleGetOccupancyHeatmap returns hourly occupancy percentages per room
+func (s *Server) handleGetOccupancyHeatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.occupancyProvider == nil {
+		http.Error(w, "Occupancy heatmap not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, s.occupancyProvider.GetOccupancyHeatmap()); err != nil {
+		s.logger.Error("Failed to encode occupancy heatmap response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Occupancy heatmap request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// SpeakerGroupResponse represents the response for /api/music/speaker-group
+type SpeakerGroupResponse struct {
+	Active  string              `json:"active"`
+	Presets map[string][]string `json:"presets"`
+}
+
+// SpeakerGroupRequest is the body for activating a speaker group preset
+type SpeakerGroupRequest struct {
+	Preset string `json:"preset"`
+}
+
+// SetSpeakerGroupController sets the controller for the speaker group endpoint.
+// The music manager starts after the API server, so it is attached here.
+func (s *Server) SetSpeakerGroupController(controller SpeakerGroupController) {
+	s.speakerGroupController = controller
+}
+
+// handleSpeakerGroup lists speaker group presets (GET) or activates one
+// (POST). Activating a preset requires the API token.
+func (s *Server) handleSpeakerGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.speakerGroupController == nil {
+		http.Error(w, "Speaker groups not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if !s.authorizeWrite(w, r) {
+			return
+		}
+
+		var req SpeakerGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.speakerGroupController.ActivateSpeakerGroupPreset(req.Preset); err != nil {
+			switch {
+			case errors.Is(err, music.ErrUnknownSpeakerGroupPreset):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, music.ErrNoMusicPlaying):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				s.logger.Error("Failed to activate speaker group preset",
+					zap.String("preset", req.Preset),
+					zap.Error(err))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		s.logger.Info("Speaker group preset requested via API",
+			zap.String("preset", req.Preset),
+			zap.String("remote_addr", r.RemoteAddr))
+	}
+
+	response := SpeakerGroupResponse{
+		Active:  s.speakerGroupController.GetActiveSpeakerGroupPreset(),
+		Presets: s.speakerGroupController.GetSpeakerGroupPresets(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode speaker group response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// NowPlayingResponse represents the response for /api/music/nowplaying
+type NowPlayingResponse struct {
+	Playing    bool                    `json:"playing"`
+	NowPlaying *shadowstate.NowPlaying `json:"nowPlaying,omitempty"`
+}
+
+// SetNowPlayingProvider sets the source for the now playing endpoint.
+// The music manager starts after the API server, so it is attached here.
+func (s *Server) SetNowPlayingProvider(provider NowPlayingProvider) {
+	s.nowPlaying = provider
+}
+
+// handleGetNowPlaying returns the track the music lead player is playing
+func (s *Server) handleGetNowPlaying(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.nowPlaying == nil {
+		http.Error(w, "Now playing not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	nowPlaying := s.nowPlaying.GetNowPlaying()
+	response := NowPlayingResponse{
+		Playing:    nowPlaying != nil,
+		NowPlaying: nowPlaying,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, response); err != nil {
+		s.logger.Error("Failed to encode now playing response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Now playing request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// SetOpenReminderAcknowledger sets the acknowledger for the open reminder endpoint.
+// The open reminder manager starts after the API server, so it is attached here.
+func (s *Server) SetOpenReminderAcknowledger(ack OpenReminderAcknowledger) {
+	s.openReminderAck = ack
+}
+
+// handleAcknowledgeOpenReminder stops the active left-open reminder. It
+// requires the API token.
+func (s *Server) handleAcknowledgeOpenReminder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.openReminderAck == nil {
+		http.Error(w, "Open reminders not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !s.authorizeWrite(w, r) {
+		return
+	}
+
+	if err := s.openReminderAck.Acknowledge(); err != nil {
+		if errors.Is(err, openreminder.ErrNoActiveReminder) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.logger.Error("Failed to acknowledge open reminder", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Open reminder acknowledged via API",
+		zap.String("remote_addr", r.RemoteAddr))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "acknowledged"}); err != nil {
+		s.logger.Error("Failed to encode acknowledge response", zap.Error(err))
+	}
+}
+
+// SetSecurityDrillRunner sets the runner for the security drill endpoint
+func (s *Server) SetSecurityDrillRunner(runner SecurityDrillRunner) {
+	s.securityDrill = runner
+}
+
+// handleStartSecurityDrill starts a security drill. The drill runs in the
+// background; its checklist is reported in the security shadow state. It
+// requires the API token.
+func (s *Server) handleStartSecurityDrill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.securityDrill == nil {
+		http.Error(w, "Security drills not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !s.authorizeWrite(w, r) {
+		return
+	}
+
+	if err := s.securityDrill.StartDrill(); err != nil {
+		if errors.Is(err, security.ErrDrillInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.logger.Error("Failed to start security drill", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Security drill started via API",
+		zap.String("remote_addr", r.RemoteAddr))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "started"}); err != nil {
+		s.logger.Error("Failed to encode drill response", zap.Error(err))
+	}
+}
+
+// SecurityAlarmRequest is the body for setting the alarm mode
+type SecurityAlarmRequest struct {
+	Mode string `json:"mode"`
+}
+
+// SetSecurityAlarmController sets the controller for the alarm endpoint
+func (s *Server) SetSecurityAlarmController(controller SecurityAlarmController) {
+	s.securityAlarm = controller
+}
+
+// handleSecurityAlarm returns the alarm state (GET) or sets the alarm mode
+// (POST). Setting the mode requires the API token.
+func (s *Server) handleSecurityAlarm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.securityAlarm == nil {
+		http.Error(w, "Security alarm not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if !s.authorizeWrite(w, r) {
+			return
+		}
+
+		var req SecurityAlarmRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.securityAlarm.SetAlarmMode(req.Mode); err != nil {
+			switch {
+			case errors.Is(err, security.ErrUnknownAlarmMode):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, security.ErrAlarmNotConfigured):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				s.logger.Error("Failed to set alarm mode",
+					zap.String("mode", req.Mode),
+					zap.Error(err))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		s.logger.Info("Alarm mode set via API",
+			zap.String("mode", req.Mode),
+			zap.String("remote_addr", r.RemoteAddr))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.securityAlarm.GetAlarmState()); err != nil {
+		s.logger.Error("Failed to encode alarm response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// SetSecurityLocksProvider sets the provider for the door locks endpoint
+func (s *Server) SetSecurityLocksProvider(provider SecurityLocksProvider) {
+	s.securityLocks = provider
+}
+
+// handleGetSecurityLocks returns the door locks and their audit trail
+func (s *Server) handleGetSecurityLocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.securityLocks == nil {
+		http.Error(w, "Door locks not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.securityLocks.GetDoorLocks()); err != nil {
+		s.logger.Error("Failed to encode door locks response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// SetHotWaterScheduleProvider sets the source for the hot water schedule endpoint
+func (s *Server) SetHotWaterScheduleProvider(provider HotWaterScheduleProvider) {
+	s.hotWaterSchedule = provider
+}
+
+// handleGetHotWaterSchedule returns the learned hot water recirculation schedule
+func (s *Server) handleGetHotWaterSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.hotWaterSchedule == nil {
+		http.Error(w, "Hot water schedule not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, s.hotWaterSchedule.GetLearnedSchedule()); err != nil {
+		s.logger.Error("Failed to encode hot water schedule response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Hot water schedule request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// ScheduleResponse represents the response for /api/schedule
+type ScheduleResponse struct {
+	Jobs []scheduler.Job `json:"jobs"`
+}
+
+// SetJobScheduler sets the scheduler whose jobs the schedule endpoint lists
+func (s *Server) SetJobScheduler(jobScheduler JobScheduler) {
+	s.jobScheduler = jobScheduler
+}
+
+// handleGetSchedule returns every scheduled job, soonest first
+func (s *Server) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.jobScheduler == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, ScheduleResponse{Jobs: s.jobScheduler.Jobs()}); err != nil {
+		s.logger.Error("Failed to encode schedule response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("Schedule request served",
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// HistoryResponse represents the response for /api/history
+type HistoryResponse struct {
+	Events []journal.Event `json:"events"`
+	Count  int             `json:"count"`
+}
+
+// defaultHistoryLimit is how many events /api/history returns without ?limit=
+const defaultHistoryLimit = 500
+
+// SetHistoryProvider sets the source for the history endpoint
+func (s *Server) SetHistoryProvider(provider HistoryProvider) {
+	s.historyProvider = provider
+}
+
+// handleGetHistory returns journal events matching the query filters
+func (s *Server) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.historyProvider == nil {
+		http.Error(w, "History not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := parseHistoryFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events := s.historyProvider.GetHistory(filter)
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, HistoryResponse{Events: events, Count: len(events)}); err != nil {
+		s.logger.Error("Failed to encode history response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("History request served",
+		zap.Int("events", len(events)),
+		zap.String("remote_addr", r.RemoteAddr))
+}
+
+// parseHistoryFilter reads the /api/history query parameters. since may also
+// be a duration, meaning that long before now.
+func parseHistoryFilter(query url.Values, now time.Time) (journal.Filter, error) {
+	filter := journal.Filter{
+		Plugin:   query.Get("plugin"),
+		Variable: query.Get("variable"),
+		Limit:    defaultHistoryLimit,
+	}
+
+	if since := query.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			filter.Since = now.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else {
+			return filter, fmt.Errorf("invalid since %q: use RFC3339 or a duration like 1h", since)
+		}
+	}
+
+	if until := query.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return filter, fmt.Errorf("invalid until %q: use RFC3339", until)
+		}
+		filter.Until = t
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid limit %q: must be a positive integer", limit)
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// handleGetAllShadowStates returns shadow states for all plugins
+func (s *Server) handleGetAllShadowStates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	allStates := s.shadowTracker.GetAllPluginStates()
+
+	// Convert to map[string]interface{} for JSON encoding
+	pluginsData := make(map[string]interface{})
+	for name, state := range allStates {
+		pluginsData[name] = state
+	}
+
+	response := AllShadowStatesResponse{
+		Plugins: pluginsData,
+		Metadata: ShadowMetadata{
+			Timestamp: time.Now(),
+			Version:   "1.0.0",
+			Build:     buildinfo.Get(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.writeJSONWithLocalTimestamps(w, response); err != nil {
+		s.logger.Error("Failed to encode shadow states response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Debug("All shadow states request served",
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.Int("plugin_count", len(allStates)))
+}
+
+// Start begins serving HTTP requests
+func (s *Server) Start() error {
+	s.logger.Info("Starting HTTP API server", zap.String("addr", s.server.Addr))
+
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("HTTP server error", zap.Error(err))
+		}
+	}()
+
+	return nil
 }
 
 // Stop gracefully shuts down the HTTP server
@@ -865,22 +2071,31 @@ func (s *Server) addLocalTimestamps(data interface{}) interface{} {
 // writeJSONWithLocalTimestamps encodes the given data as JSON, adding local
 // timestamp fields for any RFC3339 timestamps found in the structure.
 func (s *Server) writeJSONWithLocalTimestamps(w http.ResponseWriter, data interface{}) error {
+	transformed, err := s.withLocalTimestamps(data)
+	if err != nil {
+		return err
+	}
+
+	// Encode the transformed data
+	return json.NewEncoder(w).Encode(transformed)
+}
+
+// withLocalTimestamps converts data to its generic JSON form with local
+// timestamps added, as served by the shadow endpoints
+func (s *Server) withLocalTimestamps(data interface{}) (interface{}, error) {
 	// First marshal to JSON, then unmarshal to map so we can transform
 	jsonBytes, err := json.Marshal(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var genericData interface{}
 	if err := json.Unmarshal(jsonBytes, &genericData); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Add local timestamps
-	transformed := s.addLocalTimestamps(genericData)
-
-	// Encode the transformed data
-	return json.NewEncoder(w).Encode(transformed)
+	return s.addLocalTimestamps(genericData), nil
 }
 
 // handleDashboard serves a web UI for visualizing shadow state
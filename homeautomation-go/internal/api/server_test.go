@@ -2,12 +2,22 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"homeautomation/internal/buildinfo"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/journal"
+	"homeautomation/internal/plugins/music"
+	"homeautomation/internal/plugins/openreminder"
+	"homeautomation/internal/plugins/security"
+	"homeautomation/internal/reports"
+	"homeautomation/internal/scheduler"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -101,6 +111,11 @@ func TestHandleGetState(t *testing.T) {
 			t.Errorf("Expected string key %s to be present", key)
 		}
 	}
+
+	// JSON variables may hold arrays as well as objects
+	if _, ok := response.JSONs["lowBatteryDevices"].([]interface{}); !ok {
+		t.Errorf("Expected lowBatteryDevices to be an array, got %#v", response.JSONs["lowBatteryDevices"])
+	}
 }
 
 func TestHandleGetStateMethodNotAllowed(t *testing.T) {
@@ -121,6 +136,47 @@ func TestHandleGetStateMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestHandleVersion(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	shadowTracker := shadowstate.NewTracker()
+	server := NewServer(stateManager, shadowTracker, logger, 8080, time.UTC)
+
+	oldVersion, oldCommit := buildinfo.Version, buildinfo.Commit
+	defer func() { buildinfo.Version, buildinfo.Commit = oldVersion, oldCommit }()
+	buildinfo.Version = "v1.2.3"
+	buildinfo.Commit = "abc123"
+
+	req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
+	w := httptest.NewRecorder()
+
+	server.handleVersion(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var response buildinfo.Info
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Version != "v1.2.3" || response.Commit != "abc123" {
+		t.Errorf("Expected version v1.2.3 at abc123, got %+v", response)
+	}
+	if response.GoVersion == "" || response.BuildTime == "" {
+		t.Errorf("Expected Go version and build time, got %+v", response)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/version", nil)
+	w = httptest.NewRecorder()
+	server.handleVersion(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", w.Code)
+	}
+}
+
 func TestHandleHealth(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
@@ -739,6 +795,766 @@ func TestHandleGetSecurityShadowState_NotFound(t *testing.T) {
 	}
 }
 
+// fakeReportProvider returns a fixed weekly report
+type fakeReportProvider struct {
+	report *reports.WeeklyReport
+}
+
+func (f *fakeReportProvider) GetWeeklyReport() *reports.WeeklyReport {
+	return f.report
+}
+
+func TestHandleGetWeeklyReport(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	server.SetWeeklyReportProvider(&fakeReportProvider{report: &reports.WeeklyReport{
+		PeriodEnd:           time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC),
+		AutomationsByPlugin: map[string]int{"lighting": 12},
+		TotalAutomations:    12,
+		DoorbellEvents:      3,
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reports/weekly", nil)
+	w := httptest.NewRecorder()
+	server.handleGetWeeklyReport(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["totalAutomations"] != float64(12) {
+		t.Errorf("Expected totalAutomations 12, got %v", response["totalAutomations"])
+	}
+	if response["doorbellEvents"] != float64(3) {
+		t.Errorf("Expected doorbellEvents 3, got %v", response["doorbellEvents"])
+	}
+	if _, ok := response["periodEndLocal"]; !ok {
+		t.Error("Expected local timestamp for periodEnd")
+	}
+}
+
+func TestHandleGetWeeklyReport_NoProvider(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reports/weekly", nil)
+	w := httptest.NewRecorder()
+	server.handleGetWeeklyReport(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+func TestHandleGetWeeklyReportMethodNotAllowed(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/reports/weekly", nil)
+	w := httptest.NewRecorder()
+	server.handleGetWeeklyReport(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+// fakeOccupancyHeatmapProvider returns a fixed heatmap
+type fakeOccupancyHeatmapProvider struct {
+	heatmap *reports.OccupancyHeatmap
+}
+
+func (f *fakeOccupancyHeatmapProvider) GetOccupancyHeatmap() *reports.OccupancyHeatmap {
+	return f.heatmap
+}
+
+func TestHandleGetOccupancyHeatmap(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/occupancy/heatmap", nil)
+	w := httptest.NewRecorder()
+	server.handleGetOccupancyHeatmap(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without provider, got %d", w.Code)
+	}
+
+	pct := 75.0
+	hourly := make([]*float64, 24)
+	hourly[12] = &pct
+	server.SetOccupancyHeatmapProvider(&fakeOccupancyHeatmapProvider{heatmap: &reports.OccupancyHeatmap{
+		PeriodEnd: time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC),
+		Rooms: map[string]*reports.RoomOccupancy{
+			"kitchen": {Variable: "isKitchenOccupied", Samples: 4, Hourly: hourly},
+		},
+	}})
+
+	req = httptest.NewRequest(http.MethodPost, "/api/occupancy/heatmap", nil)
+	w = httptest.NewRecorder()
+	server.handleGetOccupancyHeatmap(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/occupancy/heatmap", nil)
+	w = httptest.NewRecorder()
+	server.handleGetOccupancyHeatmap(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Rooms map[string]struct {
+			Hourly []*float64 `json:"hourly"`
+		} `json:"rooms"`
+		PeriodEndLocal string `json:"periodEndLocal"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	kitchen := response.Rooms["kitchen"].Hourly
+	if len(kitchen) != 24 || kitchen[12] == nil || *kitchen[12] != 75 || kitchen[11] != nil {
+		t.Errorf("Expected 75%% at noon and null elsewhere, got %v", kitchen)
+	}
+	if response.PeriodEndLocal == "" {
+		t.Error("Expected local timestamp for periodEnd")
+	}
+}
+
+// fakeHotWaterSchedule returns a fixed learned schedule
+type fakeHotWaterSchedule struct {
+	schedule shadowstate.HotWaterSchedule
+}
+
+func (f *fakeHotWaterSchedule) GetLearnedSchedule() shadowstate.HotWaterSchedule {
+	return f.schedule
+}
+
+func TestHandleGetHotWaterSchedule(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	server.SetHotWaterScheduleProvider(&fakeHotWaterSchedule{schedule: shadowstate.HotWaterSchedule{
+		SlotMinutes: 30,
+		Weekday: shadowstate.HotWaterDayProfile{
+			DaysObserved: 5,
+			Active:       true,
+			Slots:        []shadowstate.HotWaterSlot{{Start: "07:00", DrawDays: 4, Probability: 0.8}},
+		},
+		LearnedAt: time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC),
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/hotwater/schedule", nil)
+	w := httptest.NewRecorder()
+	server.handleGetHotWaterSchedule(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		SlotMinutes int `json:"slotMinutes"`
+		Weekday     struct {
+			Active bool `json:"active"`
+			Slots  []struct {
+				Start       string  `json:"start"`
+				Probability float64 `json:"probability"`
+			} `json:"slots"`
+		} `json:"weekday"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.SlotMinutes != 30 || !response.Weekday.Active {
+		t.Errorf("Unexpected schedule: %+v", response)
+	}
+	if len(response.Weekday.Slots) != 1 || response.Weekday.Slots[0].Start != "07:00" || response.Weekday.Slots[0].Probability != 0.8 {
+		t.Errorf("Unexpected weekday slots: %+v", response.Weekday.Slots)
+	}
+}
+
+func TestHandleGetHotWaterSchedule_NoProvider(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/hotwater/schedule", nil)
+	w := httptest.NewRecorder()
+	server.handleGetHotWaterSchedule(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+// fakeJobScheduler returns a fixed list of jobs
+type fakeJobScheduler struct {
+	jobs []scheduler.Job
+}
+
+func (f *fakeJobScheduler) Jobs() []scheduler.Job {
+	return f.jobs
+}
+
+func TestHandleGetSchedule(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	server.SetJobScheduler(&fakeJobScheduler{jobs: []scheduler.Job{
+		{Name: "sleephygiene/time_triggers", Kind: scheduler.KindCron, Spec: "* * * * *", Next: time.Date(2025, 6, 16, 10, 8, 0, 0, time.UTC)},
+		{Name: "scenescheduler/porch", Kind: scheduler.KindSun, Spec: "sunset-30m0s", Next: time.Date(2025, 6, 17, 0, 59, 0, 0, time.UTC)},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schedule", nil)
+	w := httptest.NewRecorder()
+	server.handleGetSchedule(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Jobs []struct {
+			Name string `json:"name"`
+			Kind string `json:"kind"`
+			Spec string `json:"spec"`
+		} `json:"jobs"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Jobs) != 2 || response.Jobs[1].Name != "scenescheduler/porch" || response.Jobs[1].Kind != "sun" {
+		t.Errorf("Unexpected jobs: %+v", response.Jobs)
+	}
+}
+
+func TestHandleGetSchedule_NoScheduler(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schedule", nil)
+	w := httptest.NewRecorder()
+	server.handleGetSchedule(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+// fakeHistory records the filter it was queried with
+type fakeHistory struct {
+	filter journal.Filter
+	events []journal.Event
+}
+
+func (f *fakeHistory) GetHistory(filter journal.Filter) []journal.Event {
+	f.filter = filter
+	return f.events
+}
+
+func TestHandleGetHistory(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	history := &fakeHistory{events: []journal.Event{
+		{Timestamp: time.Date(2025, 6, 15, 7, 0, 0, 0, time.UTC), Kind: journal.KindPluginAction, Plugin: "hotwater", Reason: "Someone woke up"},
+	}}
+	server.SetHistoryProvider(history)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/history?plugin=hotwater&since=2025-06-15T06:00:00Z&until=2025-06-15T08:00:00Z&limit=20", nil)
+	w := httptest.NewRecorder()
+	server.handleGetHistory(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	expected := journal.Filter{
+		Plugin: "hotwater",
+		Since:  time.Date(2025, 6, 15, 6, 0, 0, 0, time.UTC),
+		Until:  time.Date(2025, 6, 15, 8, 0, 0, 0, time.UTC),
+		Limit:  20,
+	}
+	if history.filter != expected {
+		t.Errorf("Expected filter %+v, got %+v", expected, history.filter)
+	}
+
+	var response struct {
+		Count  int `json:"count"`
+		Events []struct {
+			Kind   string `json:"kind"`
+			Plugin string `json:"plugin"`
+			Reason string `json:"reason"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Count != 1 || len(response.Events) != 1 || response.Events[0].Reason != "Someone woke up" {
+		t.Errorf("Unexpected history response: %+v", response)
+	}
+}
+
+func TestParseHistoryFilter(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	filter, err := parseHistoryFilter(url.Values{"since": {"1h"}, "variable": {"isAnyoneHome"}}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !filter.Since.Equal(now.Add(-time.Hour)) || filter.Variable != "isAnyoneHome" || filter.Limit != defaultHistoryLimit {
+		t.Errorf("Unexpected filter: %+v", filter)
+	}
+
+	for _, query := range []url.Values{
+		{"since": {"yesterday"}},
+		{"until": {"1h"}},
+		{"limit": {"0"}},
+	} {
+		if _, err := parseHistoryFilter(query, now); err == nil {
+			t.Errorf("Expected error for %v", query)
+		}
+	}
+}
+
+func TestHandleGetHistory_NoProvider(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/history", nil)
+	w := httptest.NewRecorder()
+	server.handleGetHistory(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+// fakeSpeakerGroupController records preset activations
+type fakeSpeakerGroupController struct {
+	active string
+}
+
+func (f *fakeSpeakerGroupController) GetSpeakerGroupPresets() map[string][]string {
+	return map[string][]string{"dinner": {"Kitchen", "Dining Room"}}
+}
+
+func (f *fakeSpeakerGroupController) GetActiveSpeakerGroupPreset() string {
+	return f.active
+}
+
+func (f *fakeSpeakerGroupController) ActivateSpeakerGroupPreset(name string) error {
+	switch name {
+	case "", "dinner":
+		f.active = name
+		return nil
+	case "party":
+		return music.ErrNoMusicPlaying
+	default:
+		return music.ErrUnknownSpeakerGroupPreset
+	}
+}
+
+func TestHandleSpeakerGroup(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+	server.SetWriteToken(testWriteToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/music/speaker-group", nil)
+	w := httptest.NewRecorder()
+	server.handleSpeakerGroup(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without controller, got %d", w.Code)
+	}
+
+	controller := &fakeSpeakerGroupController{}
+	server.SetSpeakerGroupController(controller)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		token          string
+		expectedStatus int
+		expectedActive string
+	}{
+		{"list presets", http.MethodGet, "", "", http.StatusOK, ""},
+		{"activate without token", http.MethodPost, `{"preset": "dinner"}`, "", http.StatusUnauthorized, ""},
+		{"activate preset", http.MethodPost, `{"preset": "dinner"}`, testWriteToken, http.StatusOK, "dinner"},
+		{"unknown preset", http.MethodPost, `{"preset": "disco"}`, testWriteToken, http.StatusNotFound, ""},
+		{"no music playing", http.MethodPost, `{"preset": "party"}`, testWriteToken, http.StatusConflict, ""},
+		{"invalid body", http.MethodPost, `not json`, testWriteToken, http.StatusBadRequest, ""},
+		{"clear preset", http.MethodPost, `{"preset": ""}`, testWriteToken, http.StatusOK, ""},
+		{"method not allowed", http.MethodDelete, "", "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/music/speaker-group", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.handleSpeakerGroup(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response SpeakerGroupResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Active != tt.expectedActive {
+				t.Errorf("Expected active preset %q, got %q", tt.expectedActive, response.Active)
+			}
+			if len(response.Presets["dinner"]) != 2 {
+				t.Errorf("Expected dinner preset in response, got %v", response.Presets)
+			}
+		})
+	}
+}
+
+// fakeNowPlayingProvider returns a fixed track
+type fakeNowPlayingProvider struct {
+	nowPlaying *shadowstate.NowPlaying
+}
+
+func (f *fakeNowPlayingProvider) GetNowPlaying() *shadowstate.NowPlaying {
+	return f.nowPlaying
+}
+
+func TestHandleGetNowPlaying(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/music/nowplaying", nil)
+	w := httptest.NewRecorder()
+	server.handleGetNowPlaying(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without provider, got %d", w.Code)
+	}
+
+	provider := &fakeNowPlayingProvider{}
+	server.SetNowPlayingProvider(provider)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/music/nowplaying", nil)
+	w = httptest.NewRecorder()
+	server.handleGetNowPlaying(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/music/nowplaying", nil)
+	w = httptest.NewRecorder()
+	server.handleGetNowPlaying(w, req)
+	var response NowPlayingResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Playing || response.NowPlaying != nil {
+		t.Errorf("Expected nothing playing, got %+v", response)
+	}
+
+	provider.nowPlaying = &shadowstate.NowPlaying{
+		MusicType:  "day",
+		LeadPlayer: "media_player.kitchen",
+		Title:      "Opening Track",
+		Artist:     "First Artist",
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/music/nowplaying", nil)
+	w = httptest.NewRecorder()
+	server.handleGetNowPlaying(w, req)
+	response = NowPlayingResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Playing || response.NowPlaying == nil || response.NowPlaying.Title != "Opening Track" {
+		t.Errorf("Expected the track in the response, got %+v", response)
+	}
+}
+
+// fakeOpenReminderAcknowledger acknowledges a single active reminder
+type fakeOpenReminderAcknowledger struct {
+	active bool
+}
+
+func (f *fakeOpenReminderAcknowledger) Acknowledge() error {
+	if !f.active {
+		return openreminder.ErrNoActiveReminder
+	}
+	f.active = false
+	return nil
+}
+
+func TestHandleAcknowledgeOpenReminder(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+	server.SetWriteToken(testWriteToken)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/open-reminder/acknowledge", nil)
+	w := httptest.NewRecorder()
+	server.handleAcknowledgeOpenReminder(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without acknowledger, got %d", w.Code)
+	}
+
+	server.SetOpenReminderAcknowledger(&fakeOpenReminderAcknowledger{active: true})
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		expectedStatus int
+	}{
+		{"acknowledge without token", http.MethodPost, "", http.StatusUnauthorized},
+		{"acknowledge active reminder", http.MethodPost, testWriteToken, http.StatusOK},
+		{"no active reminder", http.MethodPost, testWriteToken, http.StatusConflict},
+		{"method not allowed", http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/open-reminder/acknowledge", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.handleAcknowledgeOpenReminder(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+// fakeSecurityDrillRunner runs one drill at a time
+type fakeSecurityDrillRunner struct {
+	running bool
+}
+
+func (f *fakeSecurityDrillRunner) StartDrill() error {
+	if f.running {
+		return security.ErrDrillInProgress
+	}
+	f.running = true
+	return nil
+}
+
+func TestHandleStartSecurityDrill(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+	server.SetWriteToken(testWriteToken)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/security/drill", nil)
+	w := httptest.NewRecorder()
+	server.handleStartSecurityDrill(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without drill runner, got %d", w.Code)
+	}
+
+	server.SetSecurityDrillRunner(&fakeSecurityDrillRunner{})
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		expectedStatus int
+	}{
+		{"start without token", http.MethodPost, "", http.StatusUnauthorized},
+		{"start drill", http.MethodPost, testWriteToken, http.StatusAccepted},
+		{"drill already running", http.MethodPost, testWriteToken, http.StatusConflict},
+		{"method not allowed", http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/security/drill", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.handleStartSecurityDrill(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+// fakeSecurityAlarmController accepts the four alarm modes
+type fakeSecurityAlarmController struct {
+	mode string
+}
+
+func (f *fakeSecurityAlarmController) GetAlarmState() shadowstate.AlarmState {
+	return shadowstate.AlarmState{Enabled: true, Mode: f.mode, Status: f.mode}
+}
+
+func (f *fakeSecurityAlarmController) SetAlarmMode(mode string) error {
+	switch mode {
+	case security.AlarmDisarmed, security.AlarmHome, security.AlarmAway, security.AlarmNight:
+		f.mode = mode
+		return nil
+	}
+	return fmt.Errorf("%w: %q", security.ErrUnknownAlarmMode, mode)
+}
+
+func TestHandleSecurityAlarm(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+	server.SetWriteToken(testWriteToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/security/alarm", nil)
+	w := httptest.NewRecorder()
+	server.handleSecurityAlarm(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a controller, got %d", w.Code)
+	}
+
+	controller := &fakeSecurityAlarmController{mode: security.AlarmDisarmed}
+	server.SetSecurityAlarmController(controller)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		token          string
+		expectedStatus int
+	}{
+		{"get state", http.MethodGet, "", "", http.StatusOK},
+		{"set without token", http.MethodPost, `{"mode":"away"}`, "", http.StatusUnauthorized},
+		{"set away", http.MethodPost, `{"mode":"away"}`, testWriteToken, http.StatusOK},
+		{"unknown mode", http.MethodPost, `{"mode":"vacation"}`, testWriteToken, http.StatusBadRequest},
+		{"invalid body", http.MethodPost, `not json`, testWriteToken, http.StatusBadRequest},
+		{"method not allowed", http.MethodDelete, "", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/security/alarm", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.handleSecurityAlarm(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if controller.mode != security.AlarmAway {
+		t.Errorf("Expected alarm mode away, got %q", controller.mode)
+	}
+}
+
+// fakeSecurityLocksProvider reports one locked door
+type fakeSecurityLocksProvider struct{}
+
+func (fakeSecurityLocksProvider) GetDoorLocks() shadowstate.DoorLocksState {
+	return shadowstate.DoorLocksState{
+		Locks:  []shadowstate.DoorLockStatus{{EntityID: "lock.front_door", Name: "Front door", State: "locked"}},
+		Events: []shadowstate.LockEvent{{EntityID: "lock.front_door", Event: "locked", Source: "auto_lock", Success: true}},
+	}
+}
+
+func TestHandleGetSecurityLocks(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/security/locks", nil)
+	w := httptest.NewRecorder()
+	server.handleGetSecurityLocks(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a provider, got %d", w.Code)
+	}
+
+	server.SetSecurityLocksProvider(fakeSecurityLocksProvider{})
+
+	w = httptest.NewRecorder()
+	server.handleGetSecurityLocks(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response shadowstate.DoorLocksState
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Locks) != 1 || len(response.Events) != 1 || response.Events[0].Source != "auto_lock" {
+		t.Errorf("Unexpected door locks response: %+v", response)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleGetSecurityLocks(w, httptest.NewRequest(http.MethodPost, "/api/security/locks", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleGetOpenReminderShadowState(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	shadowTracker := shadowstate.NewTracker()
+	server := NewServer(stateManager, shadowTracker, logger, 8080, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/shadow/openreminder", nil)
+	w := httptest.NewRecorder()
+	server.handleGetOpenReminderShadowState(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 before registration, got %d", w.Code)
+	}
+
+	reminderState := shadowstate.NewOpenReminderShadowState()
+	reminderState.Outputs.Active = true
+	reminderState.Outputs.RemindersSent = 2
+	shadowTracker.RegisterPlugin("openreminder", reminderState)
+
+	w = httptest.NewRecorder()
+	server.handleGetOpenReminderShadowState(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	outputs, ok := response["outputs"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected outputs in response")
+	}
+	if outputs["remindersSent"] != float64(2) {
+		t.Errorf("Expected remindersSent 2, got %v", outputs["remindersSent"])
+	}
+}
+
 func TestHandleGetAllShadowStates(t *testing.T) {
 	// Create logger
 	logger, _ := zap.NewDevelopment()
@@ -807,6 +1623,9 @@ func TestHandleGetAllShadowStates(t *testing.T) {
 	if response.Metadata.Version == "" {
 		t.Error("Expected metadata version to be set")
 	}
+	if response.Metadata.Build.Version != buildinfo.Version || response.Metadata.Build.Commit == "" {
+		t.Errorf("Expected build metadata to be set, got %+v", response.Metadata.Build)
+	}
 }
 
 func TestAddLocalTimestamps(t *testing.T) {
@@ -993,6 +1812,9 @@ func TestHandleDashboard(t *testing.T) {
 		"autoRefresh",
 		"plugins-grid",
 		"#1a1a2e", // dark mode background color
+		"/api/history",
+		"timelinePlugin",
+		"timelineVariable",
 	}
 
 	for _, expected := range expectedElements {
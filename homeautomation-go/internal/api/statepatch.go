@@ -0,0 +1,361 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// Patch document media types accepted by PATCH /api/state
+const (
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+)
+
+// maxStatePatchBytes bounds the size of a PATCH /api/state body
+const maxStatePatchBytes = 1 << 20
+
+// Per-change statuses in a StatePatchResponse
+const (
+	PatchStatusApplied    = "applied"     // Written
+	PatchStatusInvalid    = "invalid"     // Failed validation
+	PatchStatusNotApplied = "not_applied" // Valid, but the patch was rejected or rolled back
+	PatchStatusFailed     = "failed"      // The write to Home Assistant failed
+)
+
+// stateGroups are the groups of the /api/state response, in response order
+var stateGroups = []struct {
+	name string
+	typ  state.StateType
+}{
+	{"booleans", state.TypeBool},
+	{"numbers", state.TypeNumber},
+	{"strings", state.TypeString},
+	{"jsons", state.TypeJSON},
+}
+
+// StatePatchResult reports the outcome of one change in a state patch
+type StatePatchResult struct {
+	Path   string `json:"path"` // JSON Pointer into the /api/state document, e.g. "/booleans/isHaveGuests"
+	Key    string `json:"key,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// StatePatchResponse is the response for PATCH /api/state. Applied is true
+// only when every change was written.
+type StatePatchResponse struct {
+	Applied bool               `json:"applied"`
+	Results []StatePatchResult `json:"results"`
+}
+
+// jsonPatchOperation is one RFC 6902 operation
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// statePatchChange is one parsed change; err is set when it can't be applied
+type statePatchChange struct {
+	path   string
+	update state.Update
+	err    error
+}
+
+// handleState serves GET (read all variables) and PATCH (bulk update) on
+// /api/state. PATCH needs the write token.
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPatch {
+		if s.authorizeWrite(w, r) {
+			s.handlePatchState(w, r)
+		}
+		return
+	}
+	s.handleGetState(w, r)
+}
+
+// handlePatchState applies a JSON Patch (RFC 6902) or JSON Merge Patch
+// (RFC 7396) document against the /api/state document. The changes are
+// applied all or nothing: if any is invalid nothing is written.
+func (s *Server) handlePatchState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != contentTypeJSONPatch && mediaType != contentTypeMergePatch {
+		w.Header().Set("Accept-Patch", contentTypeJSONPatch+", "+contentTypeMergePatch)
+		http.Error(w, "Unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var changes []statePatchChange
+	var err error
+	body := http.MaxBytesReader(w, r.Body, maxStatePatchBytes)
+	if mediaType == contentTypeJSONPatch {
+		changes, err = parseJSONPatch(body)
+	} else {
+		changes, err = s.parseMergePatch(body)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid patch document: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response, status := s.applyStatePatch(changes)
+
+	s.logger.Info("State patch requested via API",
+		zap.String("format", mediaType),
+		zap.Int("changes", len(changes)),
+		zap.Bool("applied", response.Applied),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode state patch response", zap.Error(err))
+	}
+}
+
+// applyStatePatch validates and writes the changes and returns the response
+// with its HTTP status
+func (s *Server) applyStatePatch(changes []statePatchChange) (StatePatchResponse, int) {
+	response := StatePatchResponse{Results: make([]StatePatchResult, len(changes))}
+
+	updates := make([]state.Update, 0, len(changes))
+	indexes := make([]int, 0, len(changes)) // Change index of each update
+	malformed := false
+	for i, change := range changes {
+		response.Results[i] = StatePatchResult{Path: change.path, Key: change.update.Key}
+		if change.err != nil {
+			response.Results[i].Status = PatchStatusInvalid
+			response.Results[i].Error = change.err.Error()
+			malformed = true
+			continue
+		}
+		updates = append(updates, change.update)
+		indexes = append(indexes, i)
+	}
+
+	var results []state.UpdateResult
+	var err error
+	if malformed {
+		// Still report which of the remaining changes are valid
+		results, err = s.stateManager.ValidateBatch(updates)
+		if err == nil {
+			err = state.ErrBatchInvalid
+		}
+	} else {
+		results, err = s.stateManager.SetBatch(updates)
+	}
+
+	for j, result := range results {
+		item := &response.Results[indexes[j]]
+		switch {
+		case result.Applied:
+			item.Status = PatchStatusApplied
+		case result.Err == nil:
+			item.Status = PatchStatusNotApplied
+		case errors.Is(err, state.ErrBatchRolledBack):
+			item.Status = PatchStatusFailed
+			item.Error = result.Err.Error()
+		default:
+			item.Status = PatchStatusInvalid
+			item.Error = result.Err.Error()
+		}
+	}
+
+	switch {
+	case errors.Is(err, state.ErrBatchInvalid):
+		return response, http.StatusUnprocessableEntity
+	case err != nil:
+		return response, http.StatusBadGateway
+	}
+	response.Applied = true
+	return response, http.StatusOK
+}
+
+// parseJSONPatch reads an RFC 6902 document. State variables always exist,
+// so "add" and "replace" both set a variable; other operations are invalid.
+func parseJSONPatch(body io.Reader) ([]statePatchChange, error) {
+	var operations []jsonPatchOperation
+	if err := json.NewDecoder(body).Decode(&operations); err != nil {
+		return nil, err
+	}
+
+	changes := make([]statePatchChange, 0, len(operations))
+	for _, op := range operations {
+		change := statePatchChange{path: op.Path}
+		group, key, err := parseStatePointer(op.Path)
+		change.update.Key = key
+		change.update.Type, _ = groupType(group)
+		switch {
+		case err != nil:
+			change.err = err
+		case op.Op != "add" && op.Op != "replace":
+			change.err = fmt.Errorf("unsupported operation %q (use add or replace)", op.Op)
+		case len(op.Value) == 0:
+			change.err = fmt.Errorf("missing value")
+		default:
+			change.update.Value, change.err = decodeValue(op.Value)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// parseMergePatch reads an RFC 7396 document shaped like the /api/state
+// response. JSON variables are merged into their current value.
+func (s *Server) parseMergePatch(body io.Reader) ([]statePatchChange, error) {
+	var document map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	changes := make([]statePatchChange, 0)
+	known := make(map[string]bool, len(stateGroups))
+	for _, group := range stateGroups {
+		known[group.name] = true
+		raw, ok := document[group.name]
+		if !ok {
+			continue
+		}
+
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil || values == nil {
+			changes = append(changes, statePatchChange{
+				path: "/" + group.name,
+				err:  fmt.Errorf("%s must be an object of variable values", group.name),
+			})
+			continue
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			change := statePatchChange{
+				path:   "/" + group.name + "/" + escapePointerToken(key),
+				update: state.Update{Key: key, Type: group.typ},
+			}
+			if string(values[key]) == "null" {
+				change.err = fmt.Errorf("state variables cannot be removed")
+			} else {
+				change.update.Value, change.err = decodeValue(values[key])
+			}
+			if change.err == nil && group.typ == state.TypeJSON {
+				change.update.Value, change.err = s.mergeJSONVariable(key, change.update.Value)
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	unknown := make([]string, 0)
+	for name := range document {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		changes = append(changes, statePatchChange{
+			path: "/" + escapePointerToken(name),
+			err:  fmt.Errorf("unknown group %q", name),
+		})
+	}
+
+	return changes, nil
+}
+
+// mergeJSONVariable applies a merge patch to a JSON variable's current value
+func (s *Server) mergeJSONVariable(key string, patch interface{}) (interface{}, error) {
+	var current interface{}
+	if err := s.stateManager.GetJSON(key, &current); err != nil {
+		return nil, err
+	}
+	return mergePatch(current, patch), nil
+}
+
+// mergePatch implements the RFC 7396 MergePatch algorithm
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	merged := make(map[string]interface{}, len(targetObject))
+	for name, value := range targetObject {
+		merged[name] = value
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = mergePatch(merged[name], value)
+	}
+	return merged
+}
+
+// parseStatePointer splits a JSON Pointer like "/booleans/isHaveGuests" into
+// its group and variable key
+func parseStatePointer(pointer string) (string, string, error) {
+	tokens := strings.Split(pointer, "/")
+	if len(tokens) != 3 || tokens[0] != "" {
+		return "", "", fmt.Errorf("path must be /<group>/<variable>")
+	}
+	group := unescapePointerToken(tokens[1])
+	key := unescapePointerToken(tokens[2])
+	if _, ok := groupType(group); !ok {
+		return "", key, fmt.Errorf("unknown group %q", group)
+	}
+	return group, key, nil
+}
+
+// decodeValue decodes a patch value. The state manager checks it against the
+// variable's type.
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// groupType returns the variable type of an /api/state group
+func groupType(name string) (state.StateType, bool) {
+	for _, group := range stateGroups {
+		if group.name == name {
+			return group.typ, true
+		}
+	}
+	return "", false
+}
+
+// escapePointerToken escapes a JSON Pointer reference token (RFC 6901)
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// unescapePointerToken reverses escapePointerToken
+func unescapePointerToken(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newStatePatchTestServer(t *testing.T, readOnly bool) (*Server, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, readOnly)
+	server := NewServer(stateManager, shadowstate.NewTracker(), logger, 8080, time.UTC)
+	server.SetWriteToken(testWriteToken)
+	return server, stateManager
+}
+
+func patchState(t *testing.T, server *Server, contentType, body string) (*httptest.ResponseRecorder, StatePatchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/api/state", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+testWriteToken)
+	w := httptest.NewRecorder()
+
+	server.handleState(w, req)
+
+	var response StatePatchResponse
+	if w.Header().Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return w, response
+}
+
+func TestHandlePatchState_JSONPatch(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+
+	w, response := patchState(t, server, "application/json-patch+json", `[
+		{"op": "replace", "path": "/booleans/isHaveGuests", "value": true},
+		{"op": "add", "path": "/strings/dayPhase", "value": "winddown"},
+		{"op": "replace", "path": "/numbers/alarmTime", "value": 1700000000000}
+	]`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !response.Applied || len(response.Results) != 3 {
+		t.Fatalf("Expected 3 applied changes, got %+v", response)
+	}
+	for _, result := range response.Results {
+		if result.Status != PatchStatusApplied {
+			t.Errorf("Expected %s applied, got %+v", result.Path, result)
+		}
+	}
+
+	if isHaveGuests, _ := stateManager.GetBool("isHaveGuests"); !isHaveGuests {
+		t.Error("Expected isHaveGuests to be true")
+	}
+	if dayPhase, _ := stateManager.GetString("dayPhase"); dayPhase != "winddown" {
+		t.Errorf("Expected dayPhase winddown, got %q", dayPhase)
+	}
+}
+
+func TestHandlePatchState_InvalidChangeRejectsPatch(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+
+	w, response := patchState(t, server, "application/json-patch+json", `[
+		{"op": "replace", "path": "/booleans/isHaveGuests", "value": true},
+		{"op": "replace", "path": "/booleans/dayPhase", "value": true},
+		{"op": "remove", "path": "/booleans/isTVPlaying"},
+		{"op": "replace", "path": "/widgets/isTVPlaying", "value": true}
+	]`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	if response.Applied {
+		t.Error("Expected patch not to be applied")
+	}
+
+	expected := []string{PatchStatusNotApplied, PatchStatusInvalid, PatchStatusInvalid, PatchStatusInvalid}
+	for i, status := range expected {
+		if response.Results[i].Status != status {
+			t.Errorf("Result %d: expected status %s, got %+v", i, status, response.Results[i])
+		}
+	}
+	if !strings.Contains(response.Results[1].Error, "not a bool") {
+		t.Errorf("Expected a type error for dayPhase, got %q", response.Results[1].Error)
+	}
+
+	if isHaveGuests, _ := stateManager.GetBool("isHaveGuests"); isHaveGuests {
+		t.Error("Expected isHaveGuests unchanged when the patch is rejected")
+	}
+}
+
+func TestHandlePatchState_MergePatch(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+	if err := stateManager.SetJSON("currentlyPlayingMusic", map[string]interface{}{"title": "Song", "artist": "Band"}); err != nil {
+		t.Fatalf("Failed to set currentlyPlayingMusic: %v", err)
+	}
+
+	w, response := patchState(t, server, "application/merge-patch+json", `{
+		"booleans": {"isHaveGuests": true},
+		"jsons": {"currentlyPlayingMusic": {"artist": null, "album": "Live"}}
+	}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %+v", w.Code, response)
+	}
+	if len(response.Results) != 2 || response.Results[1].Path != "/jsons/currentlyPlayingMusic" {
+		t.Errorf("Expected results in group order, got %+v", response.Results)
+	}
+
+	var music map[string]interface{}
+	if err := stateManager.GetJSON("currentlyPlayingMusic", &music); err != nil {
+		t.Fatalf("Failed to get currentlyPlayingMusic: %v", err)
+	}
+	if music["title"] != "Song" || music["album"] != "Live" {
+		t.Errorf("Expected merged fields, got %v", music)
+	}
+	if _, ok := music["artist"]; ok {
+		t.Errorf("Expected artist removed by the null in the merge patch, got %v", music)
+	}
+}
+
+func TestHandlePatchState_MergePatchRejectsRemoval(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	w, response := patchState(t, server, "application/merge-patch+json", `{"booleans": {"isHaveGuests": null}}`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	if response.Results[0].Status != PatchStatusInvalid {
+		t.Errorf("Expected removal to be invalid, got %+v", response.Results[0])
+	}
+}
+
+func TestHandlePatchState_ReadOnlyMode(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, true)
+
+	w, response := patchState(t, server, "application/json-patch+json",
+		`[{"op": "replace", "path": "/booleans/isHaveGuests", "value": true}]`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	if !strings.Contains(response.Results[0].Error, "read-only") {
+		t.Errorf("Expected a read-only error, got %+v", response.Results[0])
+	}
+}
+
+func TestHandlePatchState_BadRequests(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	w, _ := patchState(t, server, "application/json", `{"booleans": {"isHaveGuests": true}}`)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415 for plain JSON, got %d", w.Code)
+	}
+	if !strings.Contains(w.Header().Get("Accept-Patch"), "application/merge-patch+json") {
+		t.Errorf("Expected Accept-Patch header, got %q", w.Header().Get("Accept-Patch"))
+	}
+
+	w, _ = patchState(t, server, "application/json-patch+json", `{"op": "replace"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed JSON Patch, got %d", w.Code)
+	}
+}
+
+func TestHandleState_MethodNotAllowed(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/state", nil)
+	w := httptest.NewRecorder()
+	server.handleState(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// stateStreamHeartbeat is how often an idle stream sends a comment so
+// proxies and mobile networks keep the connection open
+const stateStreamHeartbeat = 25 * time.Second
+
+// stateStreamBuffer is how many changes may queue for a slow client before
+// its stream or socket is closed; the browser reconnects and gets a fresh snapshot
+const stateStreamBuffer = 64
+
+// StateChangeEvent is the data of a "change" event on /api/state/stream
+type StateChangeEvent struct {
+	Key   string      `json:"key"`
+	Group string      `json:"group"` // booleans, numbers, strings or jsons, as in /api/state
+	Value interface{} `json:"value"`
+}
+
+// handleStateStream streams state as Server-Sent Events: a "snapshot" event
+// with the /api/state document, then a "change" event for every update
+func (s *Server) handleStateStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// The server's write timeout would otherwise end the stream
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Debug("Could not clear write deadline for state stream", zap.Error(err))
+	}
+
+	// Subscribe before taking the snapshot so no change is missed in between
+	changes, overflow, unsubscribe := s.subscribeAllState()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeServerSentEvent(w, "snapshot", s.collectState()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	s.logger.Debug("State stream opened", zap.String("remote_addr", r.RemoteAddr))
+	defer s.logger.Debug("State stream closed", zap.String("remote_addr", r.RemoteAddr))
+
+	heartbeat := time.NewTicker(stateStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			s.logger.Warn("State stream client fell behind, closing stream",
+				zap.String("remote_addr", r.RemoteAddr))
+			return
+		case change := <-changes:
+			if err := writeServerSentEvent(w, "change", change); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// subscribeAllState subscribes to every state variable. Changes are queued
+// on the returned channel; if a slow client lets stateStreamBuffer changes
+// pile up, overflow is closed and the caller should drop the connection.
+func (s *Server) subscribeAllState() (<-chan StateChangeEvent, <-chan struct{}, func()) {
+	changes := make(chan StateChangeEvent, stateStreamBuffer)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+
+	subscriptions := make([]state.Subscription, 0, len(state.AllVariables))
+	for _, variable := range state.AllVariables {
+		group := stateGroupName(variable.Type)
+		sub, err := s.stateManager.Subscribe(variable.Key, func(key string, _, newValue interface{}) {
+			select {
+			case changes <- StateChangeEvent{Key: key, Group: group, Value: newValue}:
+			default:
+				overflowOnce.Do(func() { close(overflow) })
+			}
+		})
+		if err != nil {
+			s.logger.Warn("Failed to subscribe to state variable",
+				zap.String("key", variable.Key),
+				zap.Error(err))
+			continue
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	unsubscribe := func() {
+		for _, sub := range subscriptions {
+			sub.Unsubscribe()
+		}
+	}
+	return changes, overflow, unsubscribe
+}
+
+// writeServerSentEvent writes one named event with JSON data
+func writeServerSentEvent(w io.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// stateGroupName returns the /api/state group holding variables of a type
+func stateGroupName(typ state.StateType) string {
+	for _, group := range stateGroups {
+		if group.typ == typ {
+			return group.name
+		}
+	}
+	return ""
+}
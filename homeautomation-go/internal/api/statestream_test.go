@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readServerSentEvent reads the next named event, skipping comments
+func readServerSentEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestHandleStateStream_SnapshotThenChanges(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+	if err := stateManager.SetBool("isExpectingSomeone", false); err != nil {
+		t.Fatalf("SetBool failed: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(server.handleStateStream))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	event, data := readServerSentEvent(t, reader)
+	if event != "snapshot" {
+		t.Fatalf("Expected snapshot event first, got %q", event)
+	}
+	var snapshot StateResponse
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if snapshot.Booleans["isExpectingSomeone"] != false {
+		t.Errorf("Expected isExpectingSomeone=false in snapshot, got %v", snapshot.Booleans["isExpectingSomeone"])
+	}
+
+	if err := stateManager.SetBool("isExpectingSomeone", true); err != nil {
+		t.Fatalf("SetBool failed: %v", err)
+	}
+
+	event, data = readServerSentEvent(t, reader)
+	if event != "change" {
+		t.Fatalf("Expected change event, got %q", event)
+	}
+	var change StateChangeEvent
+	if err := json.Unmarshal([]byte(data), &change); err != nil {
+		t.Fatalf("Failed to decode change: %v", err)
+	}
+	if change.Key != "isExpectingSomeone" || change.Group != "booleans" || change.Value != true {
+		t.Errorf("Unexpected change event: %+v", change)
+	}
+}
+
+func TestHandleStateStream_MethodNotAllowed(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/state/stream", nil)
+	w := httptest.NewRecorder()
+	server.handleStateStream(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandlePWAAsset(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{"/manifest.webmanifest", "application/manifest+json", `"start_url": "/dashboard"`},
+		{"/sw.js", "application/javascript", "caches.open"},
+		{"/icon.svg", "image/svg+xml", "<svg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			server.handlePWAAsset(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.contentType, ct)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Expected body to contain %q", tt.contains)
+			}
+		})
+	}
+}
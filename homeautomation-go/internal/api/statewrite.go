@@ -0,0 +1,161 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// maxStateWriteBytes bounds the size of a POST/PUT /api/state/{key} body
+const maxStateWriteBytes = 1 << 20
+
+// StateVariableResponse is the response for /api/state/{key}
+type StateVariableResponse struct {
+	Key   string      `json:"key"`
+	Group string      `json:"group"` // booleans, numbers, strings or jsons, as in /api/state
+	Value interface{} `json:"value"`
+}
+
+// stateWriteRequest is the body of POST/PUT /api/state/{key}
+type stateWriteRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// SetWriteToken sets the bearer token every mutating endpoint requires.
+// With no token, those endpoints are disabled.
+func (s *Server) SetWriteToken(token string) {
+	s.writeToken = token
+}
+
+// authorizeWrite checks the request's bearer token and writes the error
+// response if it is missing or wrong, or if no token is configured.
+func (s *Server) authorizeWrite(w http.ResponseWriter, r *http.Request) bool {
+	if s.writeToken == "" {
+		http.Error(w, "Writes are disabled: API_TOKEN is not set", http.StatusForbidden)
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.writeToken)) != 1 {
+		s.logger.Warn("Rejected unauthorized write",
+			zap.String("path", r.URL.Path),
+			zap.String("remote_addr", r.RemoteAddr))
+		w.Header().Set("WWW-Authenticate", `Bearer realm="homeautomation"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// handleStateVariable serves GET (read) and POST/PUT (write) on a single
+// variable, /api/state/{key}
+func (s *Server) handleStateVariable(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	variable, ok := state.VariablesByKey()[key]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown state variable %q", key), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeStateVariable(w, variable)
+	case http.MethodPost, http.MethodPut:
+		if !s.authorizeWrite(w, r) {
+			return
+		}
+		s.handleSetStateVariable(w, r, variable)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSetStateVariable sets a variable from a {"value": ...} body. The
+// value must match the variable's type; in read-only mode only local-only
+// and computed variables can be written.
+func (s *Server) handleSetStateVariable(w http.ResponseWriter, r *http.Request, variable state.StateVariable) {
+	var request stateWriteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStateWriteBytes)).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(request.Value) == 0 || string(request.Value) == "null" {
+		http.Error(w, `Request body must be {"value": ...}`, http.StatusBadRequest)
+		return
+	}
+	value, err := decodeValue(request.Value)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid value: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.stateManager.SetBatch([]state.Update{{Key: variable.Key, Value: value, Type: variable.Type}})
+	if err != nil {
+		status := http.StatusBadGateway
+		message := err.Error()
+		if len(results) == 1 && results[0].Err != nil {
+			message = results[0].Err.Error()
+		}
+		switch {
+		case len(results) == 1 && errors.Is(results[0].Err, state.ErrReadOnlyMode):
+			status = http.StatusForbidden
+		case errors.Is(err, state.ErrBatchInvalid):
+			status = http.StatusUnprocessableEntity
+		}
+		s.logger.Warn("State write via API failed",
+			zap.String("key", variable.Key),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err))
+		http.Error(w, message, status)
+		return
+	}
+
+	s.logger.Info("State variable set via API",
+		zap.String("key", variable.Key),
+		zap.Any("value", value),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	s.writeStateVariable(w, variable)
+}
+
+// writeStateVariable responds with a variable's current value
+func (s *Server) writeStateVariable(w http.ResponseWriter, variable state.StateVariable) {
+	var value interface{}
+	var err error
+	switch variable.Type {
+	case state.TypeBool:
+		value, err = s.stateManager.GetBool(variable.Key)
+	case state.TypeNumber:
+		value, err = s.stateManager.GetNumber(variable.Key)
+	case state.TypeString:
+		value, err = s.stateManager.GetString(variable.Key)
+	case state.TypeJSON:
+		var v interface{}
+		err = s.stateManager.GetJSON(variable.Key, &v)
+		value = v
+	}
+	if err != nil {
+		s.logger.Error("Failed to read state variable",
+			zap.String("key", variable.Key),
+			zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(StateVariableResponse{
+		Key:   variable.Key,
+		Group: stateGroupName(variable.Type),
+		Value: value,
+	}); err != nil {
+		s.logger.Error("Failed to encode state variable response", zap.Error(err))
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testWriteToken = "s3cret"
+
+func writeStateVariable(t *testing.T, server *Server, method, key, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/state/"+key, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+
+	// Through the mux so the {key} path value is set
+	server.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func decodeStateVariable(t *testing.T, w *httptest.ResponseRecorder) StateVariableResponse {
+	t.Helper()
+	var response StateVariableResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestHandleStateVariable_SetEachType(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+
+	tests := []struct {
+		method string
+		key    string
+		body   string
+		group  string
+	}{
+		{http.MethodPost, "isHaveGuests", `{"value": true}`, "booleans"},
+		{http.MethodPut, "alarmTime", `{"value": 1700000000000}`, "numbers"},
+		{http.MethodPost, "dayPhase", `{"value": "winddown"}`, "strings"},
+		{http.MethodPut, "currentlyPlayingMusic", `{"value": {"type": "day"}}`, "jsons"},
+	}
+	for _, tt := range tests {
+		w := writeStateVariable(t, server, tt.method, tt.key, testWriteToken, tt.body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status 200, got %d: %s", tt.method, tt.key, w.Code, w.Body.String())
+		}
+		response := decodeStateVariable(t, w)
+		if response.Key != tt.key || response.Group != tt.group {
+			t.Errorf("%s: unexpected response %+v", tt.key, response)
+		}
+	}
+
+	if isHaveGuests, _ := stateManager.GetBool("isHaveGuests"); !isHaveGuests {
+		t.Error("Expected isHaveGuests to be true")
+	}
+	if alarmTime, _ := stateManager.GetNumber("alarmTime"); alarmTime != 1700000000000 {
+		t.Errorf("Expected alarmTime 1700000000000, got %v", alarmTime)
+	}
+	if dayPhase, _ := stateManager.GetString("dayPhase"); dayPhase != "winddown" {
+		t.Errorf("Expected dayPhase winddown, got %q", dayPhase)
+	}
+	var music map[string]interface{}
+	if err := stateManager.GetJSON("currentlyPlayingMusic", &music); err != nil || music["type"] != "day" {
+		t.Errorf("Expected currentlyPlayingMusic type day, got %v (%v)", music, err)
+	}
+}
+
+func TestHandleStateVariable_Get(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+	if err := stateManager.SetString("dayPhase", "evening"); err != nil {
+		t.Fatalf("Failed to set dayPhase: %v", err)
+	}
+
+	// Reads don't need the token
+	w := writeStateVariable(t, server, http.MethodGet, "dayPhase", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	response := decodeStateVariable(t, w)
+	if response.Value != "evening" || response.Group != "strings" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
+
+func TestHandleStateVariable_Auth(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+	server.SetWriteToken("")
+
+	w := writeStateVariable(t, server, http.MethodPost, "isHaveGuests", "anything", `{"value": true}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 with no token configured, got %d", w.Code)
+	}
+
+	server.SetWriteToken(testWriteToken)
+	w = writeStateVariable(t, server, http.MethodPost, "isHaveGuests", "", `{"value": true}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected a WWW-Authenticate header")
+	}
+	w = writeStateVariable(t, server, http.MethodPost, "isHaveGuests", "wrong", `{"value": true}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 with the wrong token, got %d", w.Code)
+	}
+
+	if isHaveGuests, _ := stateManager.GetBool("isHaveGuests"); isHaveGuests {
+		t.Error("Expected unauthorized writes to be rejected")
+	}
+}
+
+func TestHandleStateVariable_Errors(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		body   string
+		status int
+	}{
+		{"unknown key", http.MethodPost, "noSuchVariable", `{"value": true}`, http.StatusNotFound},
+		{"type mismatch", http.MethodPost, "isHaveGuests", `{"value": "yes"}`, http.StatusUnprocessableEntity},
+		{"missing value", http.MethodPost, "isHaveGuests", `{}`, http.StatusBadRequest},
+		{"malformed body", http.MethodPost, "isHaveGuests", `{"value":`, http.StatusBadRequest},
+		{"method", http.MethodDelete, "isHaveGuests", ``, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := writeStateVariable(t, server, tt.method, tt.key, testWriteToken, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleStateVariable_ReadOnlyMode(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, true)
+
+	w := writeStateVariable(t, server, http.MethodPost, "isHaveGuests", testWriteToken, `{"value": true}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a synced variable, got %d", w.Code)
+	}
+
+	w = writeStateVariable(t, server, http.MethodPost, "lockdownActive", testWriteToken, `{"value": true}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected local-only variables to stay writable, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandlePatchState_RequiresToken(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+
+	patch := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/state", strings.NewReader(`{"booleans": {"isHaveGuests": true}}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.handleState(w, req)
+		return w
+	}
+
+	if w := patch(""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 without a token, got %d", w.Code)
+	}
+
+	server.SetWriteToken("")
+	if w := patch("anything"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 with no token configured, got %d", w.Code)
+	}
+	if isHaveGuests, _ := stateManager.GetBool("isHaveGuests"); isHaveGuests {
+		t.Fatal("Expected unauthorized patches to be rejected")
+	}
+
+	server.SetWriteToken(testWriteToken)
+	if w := patch(testWriteToken); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with the token, got %d: %s", w.Code, w.Body.String())
+	}
+}
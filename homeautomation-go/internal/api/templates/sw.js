@@ -0,0 +1,38 @@
+// Service worker for the dashboard PWA. Caches the app shell so the
+// dashboard opens offline; API requests always go to the network.
+const CACHE = 'dashboard-v1';
+const SHELL = ['/dashboard', '/manifest.webmanifest', '/icon.svg'];
+
+self.addEventListener('install', event => {
+    event.waitUntil(caches.open(CACHE).then(cache => cache.addAll(SHELL)));
+    self.skipWaiting();
+});
+
+self.addEventListener('activate', event => {
+    event.waitUntil(
+        caches.keys().then(keys => Promise.all(
+            keys.filter(key => key !== CACHE).map(key => caches.delete(key))
+        ))
+    );
+    self.clients.claim();
+});
+
+self.addEventListener('fetch', event => {
+    const url = new URL(event.request.url);
+    if (event.request.method !== 'GET' || url.origin !== self.location.origin || url.pathname.startsWith('/api/')) {
+        return;
+    }
+
+    // Network first so the dashboard is always current when online
+    event.respondWith(
+        fetch(event.request)
+            .then(response => {
+                if (response.ok && SHELL.includes(url.pathname)) {
+                    const copy = response.clone();
+                    caches.open(CACHE).then(cache => cache.put(event.request, copy));
+                }
+                return response;
+            })
+            .catch(() => caches.match(event.request))
+    );
+});
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// wsShadowInterval is how often shadow state is checked for changes to push.
+// Plugins don't publish shadow changes, so they are found by comparison.
+const wsShadowInterval = time.Second
+
+// wsPingInterval is how often an idle socket is pinged so proxies and mobile
+// networks keep the connection open
+const wsPingInterval = 25 * time.Second
+
+// wsPongTimeout is how long the socket may go without hearing from the
+// client before it is considered dead
+const wsPongTimeout = 2 * wsPingInterval
+
+// wsWriteTimeout bounds each message write
+const wsWriteTimeout = 10 * time.Second
+
+// wsUpgrader keeps the default same-origin check, so only the dashboard
+// served by this process can open a socket from a browser
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// WebSocketMessage is a message pushed on /api/ws
+type WebSocketMessage struct {
+	Type string `json:"type"` // snapshot, state or shadow
+
+	// State is the /api/state document (snapshot only)
+	State *StateResponse `json:"state,omitempty"`
+
+	// Plugins holds shadow state by plugin as in /api/shadow: all of them in
+	// a snapshot, only those that changed in a shadow message
+	Plugins map[string]interface{} `json:"plugins,omitempty"`
+
+	// The changed variable (state only)
+	*StateChangeEvent
+}
+
+// handleWebSocket pushes state and shadow state to the dashboard as it
+// changes: a "snapshot" message, then a "state" message per state variable
+// change and a "shadow" message whenever plugin shadow state changes
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error status
+		s.logger.Debug("WebSocket upgrade failed",
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	s.logger.Debug("WebSocket opened", zap.String("remote_addr", r.RemoteAddr))
+	defer s.logger.Debug("WebSocket closed", zap.String("remote_addr", r.RemoteAddr))
+
+	// The client sends nothing but control frames; reading handles pongs and
+	// notices when the socket goes away
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Subscribe before taking the snapshot so no change is missed in between
+	changes, overflow, unsubscribe := s.subscribeAllState()
+	defer unsubscribe()
+
+	sent := make(map[string]string)
+	current := s.changedShadowStates(sent)
+	state := s.collectState()
+	if err := s.writeWebSocketMessage(conn, WebSocketMessage{Type: "snapshot", State: &state, Plugins: current}); err != nil {
+		return
+	}
+
+	shadowTicker := time.NewTicker(wsShadowInterval)
+	defer shadowTicker.Stop()
+	pingTicker := time.NewTicker(wsPingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		var message *WebSocketMessage
+		select {
+		case <-closed:
+			return
+		case <-overflow:
+			s.logger.Warn("WebSocket client fell behind, closing socket",
+				zap.String("remote_addr", r.RemoteAddr))
+			return
+		case change := <-changes:
+			message = &WebSocketMessage{Type: "state", StateChangeEvent: &change}
+		case <-shadowTicker.C:
+			if changed := s.changedShadowStates(sent); len(changed) > 0 {
+				message = &WebSocketMessage{Type: "shadow", Plugins: changed}
+			}
+		case <-pingTicker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+
+		if message != nil {
+			if err := s.writeWebSocketMessage(conn, *message); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// changedShadowStates returns the shadow state of each plugin whose JSON
+// differs from sent, with local timestamps added, and records it in sent
+func (s *Server) changedShadowStates(sent map[string]string) map[string]interface{} {
+	changed := make(map[string]interface{})
+	for name, pluginState := range s.shadowTracker.GetAllPluginStates() {
+		encoded, err := json.Marshal(pluginState)
+		if err != nil {
+			s.logger.Error("Failed to encode shadow state", zap.String("plugin", name), zap.Error(err))
+			continue
+		}
+		if sent[name] == string(encoded) {
+			continue
+		}
+
+		transformed, err := s.withLocalTimestamps(pluginState)
+		if err != nil {
+			s.logger.Error("Failed to encode shadow state", zap.String("plugin", name), zap.Error(err))
+			continue
+		}
+		sent[name] = string(encoded)
+		changed[name] = transformed
+	}
+	return changed
+}
+
+// writeWebSocketMessage sends one JSON message
+func (s *Server) writeWebSocketMessage(conn *websocket.Conn, message WebSocketMessage) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(message)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWebSocket opens a socket to a test server running handleWebSocket
+func dialWebSocket(t *testing.T, server *Server) *websocket.Conn {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	t.Cleanup(ts.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readWebSocketMessage reads the next message, failing after a few seconds
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline failed: %v", err)
+	}
+	var message map[string]interface{}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return message
+}
+
+func TestHandleWebSocket_SnapshotThenStateChanges(t *testing.T) {
+	server, stateManager := newStatePatchTestServer(t, false)
+	if err := stateManager.SetBool("isExpectingSomeone", false); err != nil {
+		t.Fatalf("SetBool failed: %v", err)
+	}
+	conn := dialWebSocket(t, server)
+
+	snapshot := readWebSocketMessage(t, conn)
+	if snapshot["type"] != "snapshot" {
+		t.Fatalf("Expected snapshot message first, got %v", snapshot["type"])
+	}
+	booleans := snapshot["state"].(map[string]interface{})["booleans"].(map[string]interface{})
+	if booleans["isExpectingSomeone"] != false {
+		t.Errorf("Expected isExpectingSomeone=false in snapshot, got %v", booleans["isExpectingSomeone"])
+	}
+
+	if err := stateManager.SetString("musicPlaybackType", "evening"); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+
+	change := readWebSocketMessage(t, conn)
+	if change["type"] != "state" || change["key"] != "musicPlaybackType" ||
+		change["group"] != "strings" || change["value"] != "evening" {
+		t.Errorf("Unexpected state message: %v", change)
+	}
+}
+
+func TestHandleWebSocket_PushesChangedShadowState(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+	tracker := shadowstate.NewFocusModeTracker()
+	tvTracker := shadowstate.NewTVTracker()
+	server.shadowTracker.RegisterPluginProvider("focusmode", func() shadowstate.PluginShadowState {
+		return tracker.GetState()
+	})
+	server.shadowTracker.RegisterPluginProvider("tv", func() shadowstate.PluginShadowState {
+		return tvTracker.GetState()
+	})
+	conn := dialWebSocket(t, server)
+
+	snapshot := readWebSocketMessage(t, conn)
+	plugins := snapshot["plugins"].(map[string]interface{})
+	if len(plugins) != 2 {
+		t.Fatalf("Expected both plugins in snapshot, got %v", plugins)
+	}
+
+	tracker.RecordChange(true, "toggle", time.Now(), "Focus mode on by toggle")
+
+	message := readWebSocketMessage(t, conn)
+	if message["type"] != "shadow" {
+		t.Fatalf("Expected shadow message, got %v", message)
+	}
+	plugins = message["plugins"].(map[string]interface{})
+	if len(plugins) != 1 {
+		t.Fatalf("Expected only the changed plugin, got %v", plugins)
+	}
+	outputs := plugins["focusmode"].(map[string]interface{})["outputs"].(map[string]interface{})
+	if outputs["active"] != true {
+		t.Errorf("Expected focus mode active, got %v", outputs)
+	}
+	if _, ok := outputs["lastActionTimeLocal"]; !ok {
+		t.Errorf("Expected local timestamps to be added, got %v", outputs)
+	}
+}
+
+func TestHandleWebSocket_RejectsCrossOrigin(t *testing.T) {
+	server, _ := newStatePatchTestServer(t, false)
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+
+	header := http.Header{"Origin": []string{"https://example.com"}}
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), header)
+	if err == nil {
+		t.Fatal("Expected cross-origin dial to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403, got %v", resp)
+	}
+}
@@ -0,0 +1,283 @@
+// Package areas caches Home Assistant's area registry and maps entities to
+// areas, so configs can refer to an HA area instead of repeating the
+// entities in it. The cache is refreshed periodically and subscribers are
+// told which areas changed.
+package areas
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// DefaultRefreshInterval is how often the registries are re-read when no
+// interval is configured
+const DefaultRefreshInterval = 30 * time.Minute
+
+// Change describes how the area mapping changed in a refresh
+type Change struct {
+	AddedAreas   []string // Areas that appeared
+	RemovedAreas []string // Areas that disappeared
+	ChangedAreas []string // Existing areas whose entities or name changed
+}
+
+// Empty reports whether nothing changed
+func (c Change) Empty() bool {
+	return len(c.AddedAreas) == 0 && len(c.RemovedAreas) == 0 && len(c.ChangedAreas) == 0
+}
+
+// snapshot is one read of the registries
+type snapshot struct {
+	areas    map[string]ha.Area
+	entities map[string][]string // Area ID -> sorted entity IDs
+	areaOf   map[string]string   // Entity ID -> area ID
+}
+
+// Registry is a cache of the area registry and the entities in each area
+type Registry struct {
+	client ha.RegistryClient
+	logger *zap.Logger
+
+	mu          sync.RWMutex
+	current     *snapshot // nil until the first successful refresh
+	refreshedAt time.Time
+	onChange    []func(Change)
+
+	// Cancelled by Stop to end the refresh loop
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRegistry creates an empty registry. Call Refresh or Start to load it.
+func NewRegistry(client ha.RegistryClient, logger *zap.Logger) *Registry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{
+		client: client,
+		logger: logger.Named("areas"),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// OnChange registers a callback run after a refresh that changed the mapping.
+// It is not called for the first load.
+func (r *Registry) OnChange(callback func(Change)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, callback)
+}
+
+// Start loads the registries and refreshes them every interval (or
+// DefaultRefreshInterval if interval is not positive) until Stop
+func (r *Registry) Start(interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	if _, err := r.Refresh(r.ctx); err != nil {
+		return err
+	}
+
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Refresh(r.ctx); err != nil {
+					r.logger.Warn("Failed to refresh area registry, keeping the cached areas", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	r.logger.Info("Area registry loaded",
+		zap.Int("areas", len(r.Areas())),
+		zap.Duration("refresh_interval", interval))
+	return nil
+}
+
+// Stop ends the refresh loop
+func (r *Registry) Stop() {
+	r.cancel()
+	if r.done != nil {
+		<-r.done
+	}
+}
+
+// Refresh re-reads the area, entity and device registries and returns what
+// changed since the previous refresh
+func (r *Registry) Refresh(ctx context.Context) (Change, error) {
+	areas, err := r.client.ListAreas(ctx)
+	if err != nil {
+		return Change{}, fmt.Errorf("failed to list areas: %w", err)
+	}
+	entities, err := r.client.ListEntityRegistry(ctx)
+	if err != nil {
+		return Change{}, fmt.Errorf("failed to list entity registry: %w", err)
+	}
+	devices, err := r.client.ListDeviceRegistry(ctx)
+	if err != nil {
+		return Change{}, fmt.Errorf("failed to list device registry: %w", err)
+	}
+
+	next := buildSnapshot(areas, entities, devices)
+
+	r.mu.Lock()
+	previous := r.current
+	r.current = next
+	r.refreshedAt = time.Now()
+	callbacks := append([]func(Change){}, r.onChange...)
+	r.mu.Unlock()
+
+	if previous == nil {
+		return Change{}, nil
+	}
+
+	change := diff(previous, next)
+	if change.Empty() {
+		return change, nil
+	}
+
+	r.logger.Info("Area registry changed",
+		zap.Strings("added", change.AddedAreas),
+		zap.Strings("removed", change.RemovedAreas),
+		zap.Strings("changed", change.ChangedAreas))
+	for _, callback := range callbacks {
+		callback(change)
+	}
+	return change, nil
+}
+
+// Entities returns the entities in an area, optionally only those in one
+// domain (e.g. "light"). It fails if the registry hasn't loaded or the area
+// doesn't exist.
+func (r *Registry) Entities(areaID, domain string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.current == nil {
+		return nil, fmt.Errorf("area registry not loaded")
+	}
+	if _, ok := r.current.areas[areaID]; !ok {
+		return nil, fmt.Errorf("unknown area %q", areaID)
+	}
+
+	entities := make([]string, 0, len(r.current.entities[areaID]))
+	for _, entityID := range r.current.entities[areaID] {
+		if domain == "" || strings.HasPrefix(entityID, domain+".") {
+			entities = append(entities, entityID)
+		}
+	}
+	return entities, nil
+}
+
+// AreaOf returns the area an entity is in
+func (r *Registry) AreaOf(entityID string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.current == nil {
+		return "", false
+	}
+	areaID, ok := r.current.areaOf[entityID]
+	return areaID, ok
+}
+
+// Areas returns every area, sorted by ID
+func (r *Registry) Areas() []ha.Area {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.current == nil {
+		return nil
+	}
+	areas := make([]ha.Area, 0, len(r.current.areas))
+	for _, area := range r.current.areas {
+		areas = append(areas, area)
+	}
+	sort.Slice(areas, func(i, j int) bool { return areas[i].AreaID < areas[j].AreaID })
+	return areas
+}
+
+// RefreshedAt returns when the registries were last read, zero if never
+func (r *Registry) RefreshedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.refreshedAt
+}
+
+// buildSnapshot maps each enabled entity to its own area, or its device's
+// area if the entity has none
+func buildSnapshot(areas []ha.Area, entities []ha.EntityRegistryEntry, devices []ha.DeviceRegistryEntry) *snapshot {
+	s := &snapshot{
+		areas:    make(map[string]ha.Area, len(areas)),
+		entities: make(map[string][]string, len(areas)),
+		areaOf:   make(map[string]string),
+	}
+	for _, area := range areas {
+		s.areas[area.AreaID] = area
+	}
+
+	deviceArea := make(map[string]string, len(devices))
+	for _, device := range devices {
+		deviceArea[device.ID] = device.AreaID
+	}
+
+	for _, entity := range entities {
+		if entity.DisabledBy != "" {
+			continue
+		}
+		areaID := entity.AreaID
+		if areaID == "" {
+			areaID = deviceArea[entity.DeviceID]
+		}
+		if _, ok := s.areas[areaID]; !ok {
+			continue
+		}
+		s.areaOf[entity.EntityID] = areaID
+		s.entities[areaID] = append(s.entities[areaID], entity.EntityID)
+	}
+
+	for areaID := range s.entities {
+		sort.Strings(s.entities[areaID])
+	}
+	return s
+}
+
+// diff compares two snapshots area by area
+func diff(previous, next *snapshot) Change {
+	var change Change
+	for areaID, area := range next.areas {
+		old, ok := previous.areas[areaID]
+		switch {
+		case !ok:
+			change.AddedAreas = append(change.AddedAreas, areaID)
+		case old.Name != area.Name || !slices.Equal(previous.entities[areaID], next.entities[areaID]):
+			change.ChangedAreas = append(change.ChangedAreas, areaID)
+		}
+	}
+	for areaID := range previous.areas {
+		if _, ok := next.areas[areaID]; !ok {
+			change.RemovedAreas = append(change.RemovedAreas, areaID)
+		}
+	}
+
+	sort.Strings(change.AddedAreas)
+	sort.Strings(change.RemovedAreas)
+	sort.Strings(change.ChangedAreas)
+	return change
+}
@@ -0,0 +1,103 @@
+package areas
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testRegistries(mockHA *ha.MockClient) {
+	mockHA.SetRegistries(
+		[]ha.Area{{AreaID: "kitchen", Name: "Kitchen"}, {AreaID: "patio", Name: "Patio"}},
+		[]ha.EntityRegistryEntry{
+			{EntityID: "light.kitchen", AreaID: "kitchen"},
+			{EntityID: "media_player.kitchen", DeviceID: "sonos_kitchen"},
+			{EntityID: "light.patio", DeviceID: "patio_switch"},
+			{EntityID: "light.old_patio", AreaID: "patio", DisabledBy: "user"},
+			{EntityID: "sensor.unassigned"},
+		},
+		[]ha.DeviceRegistryEntry{
+			{ID: "sonos_kitchen", AreaID: "kitchen"},
+			{ID: "patio_switch", AreaID: "patio"},
+		},
+	)
+}
+
+func TestRegistry_Refresh(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	testRegistries(mockHA)
+	registry := NewRegistry(mockHA, zap.NewNop())
+
+	_, err := registry.Entities("kitchen", "")
+	assert.ErrorContains(t, err, "not loaded")
+
+	change, err := registry.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.True(t, change.Empty(), "the first load is not a change")
+
+	kitchen, err := registry.Entities("kitchen", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.kitchen", "media_player.kitchen"}, kitchen, "device area applies when the entity has none")
+
+	lights, err := registry.Entities("kitchen", "light")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.kitchen"}, lights)
+
+	patio, err := registry.Entities("patio", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.patio"}, patio, "disabled entities are skipped")
+
+	areaID, ok := registry.AreaOf("media_player.kitchen")
+	assert.True(t, ok)
+	assert.Equal(t, "kitchen", areaID)
+	_, ok = registry.AreaOf("sensor.unassigned")
+	assert.False(t, ok)
+
+	_, err = registry.Entities("attic", "")
+	assert.ErrorContains(t, err, `unknown area "attic"`)
+	assert.Len(t, registry.Areas(), 2)
+}
+
+func TestRegistry_ChangeDetection(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	testRegistries(mockHA)
+	registry := NewRegistry(mockHA, zap.NewNop())
+
+	var notified []Change
+	registry.OnChange(func(change Change) { notified = append(notified, change) })
+
+	_, err := registry.Refresh(context.Background())
+	require.NoError(t, err)
+
+	// Unchanged registries don't notify
+	_, err = registry.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, notified)
+
+	// Move the kitchen speaker to a new dining room and drop the patio
+	mockHA.SetRegistries(
+		[]ha.Area{{AreaID: "kitchen", Name: "Kitchen"}, {AreaID: "dining_room", Name: "Dining Room"}},
+		[]ha.EntityRegistryEntry{
+			{EntityID: "light.kitchen", AreaID: "kitchen"},
+			{EntityID: "media_player.kitchen", AreaID: "dining_room", DeviceID: "sonos_kitchen"},
+		},
+		[]ha.DeviceRegistryEntry{{ID: "sonos_kitchen", AreaID: "kitchen"}},
+	)
+
+	change, err := registry.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dining_room"}, change.AddedAreas)
+	assert.Equal(t, []string{"patio"}, change.RemovedAreas)
+	assert.Equal(t, []string{"kitchen"}, change.ChangedAreas)
+	require.Len(t, notified, 1)
+	assert.Equal(t, change, notified[0])
+
+	dining, err := registry.Entities("dining_room", "media_player")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"media_player.kitchen"}, dining, "an entity's own area overrides its device's")
+}
@@ -0,0 +1,69 @@
+// Package buildinfo exposes version metadata embedded at build time.
+//
+// Set the values with -ldflags, e.g.
+//
+//	go build -ldflags "-X homeautomation/internal/buildinfo.Version=v1.2.3 \
+//	  -X homeautomation/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X homeautomation/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Populated via -ldflags at build time
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+}
+
+// Get returns the build metadata. When the commit wasn't set with -ldflags,
+// the VCS stamp Go embeds in module builds is used instead.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		applySettings(&info, bi.Settings)
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// applySettings fills the commit from the Go VCS build settings if it wasn't set via -ldflags
+func applySettings(info *Info, settings []debug.BuildSetting) {
+	ldflagsCommit := info.Commit != ""
+	for _, s := range settings {
+		switch s.Key {
+		case "vcs.revision":
+			if !ldflagsCommit {
+				info.Commit = s.Value
+			}
+		case "vcs.modified":
+			if !ldflagsCommit {
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGetDefaults(t *testing.T) {
+	info := Get()
+
+	if info.Version != Version {
+		t.Errorf("Expected version %q, got %q", Version, info.Version)
+	}
+	if info.Commit == "" || info.BuildTime == "" {
+		t.Errorf("Expected commit and build time to be filled in, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %q, got %q", runtime.Version(), info.GoVersion)
+	}
+}
+
+func TestGetUsesLdflags(t *testing.T) {
+	oldVersion, oldCommit, oldBuildTime := Version, Commit, BuildTime
+	defer func() { Version, Commit, BuildTime = oldVersion, oldCommit, oldBuildTime }()
+
+	Version = "v1.2.3"
+	Commit = "0123456789abcdef0123"
+	BuildTime = "2025-01-06T09:00:00Z"
+
+	info := Get()
+	if info.Version != "v1.2.3" || info.Commit != Commit || info.BuildTime != BuildTime {
+		t.Errorf("Expected ldflags values, got %+v", info)
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "abc123"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+
+	t.Run("fills missing values", func(t *testing.T) {
+		info := Info{}
+		applySettings(&info, settings)
+		if info.Commit != "abc123" || !info.Modified {
+			t.Errorf("Expected VCS values, got %+v", info)
+		}
+	})
+
+	t.Run("ldflags take precedence", func(t *testing.T) {
+		info := Info{Commit: "def456"}
+		applySettings(&info, settings)
+		if info.Commit != "def456" || info.Modified {
+			t.Errorf("Expected ldflags values to be kept, got %+v", info)
+		}
+	})
+}
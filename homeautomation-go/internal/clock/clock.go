@@ -3,6 +3,7 @@
 package clock
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -19,9 +20,15 @@ type Clock interface {
 	// It returns a Timer that can be used to cancel the call using its Stop method.
 	AfterFunc(d time.Duration, f func()) Timer
 
+	// NewTicker returns a Ticker that sends the time every d
+	NewTicker(d time.Duration) Ticker
+
 	// Sleep pauses the current goroutine for at least the duration d
 	Sleep(d time.Duration)
 
+	// SleepContext is Sleep that returns ctx's error early once ctx is done
+	SleepContext(ctx context.Context, d time.Duration) error
+
 	// Since returns the time elapsed since t
 	Since(t time.Time) time.Duration
 }
@@ -37,6 +44,19 @@ type Timer interface {
 	Reset(d time.Duration) bool
 }
 
+// Ticker delivers the time at intervals
+type Ticker interface {
+	// C returns the channel ticks are sent on. Like time.Ticker, a tick
+	// is dropped if the last one hasn't been received.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. No more ticks are sent.
+	Stop()
+
+	// Reset stops the ticker and restarts it with period d
+	Reset(d time.Duration)
+}
+
 // RealClock implements Clock using the standard time package
 type RealClock struct{}
 
@@ -45,6 +65,11 @@ type realTimer struct {
 	timer *time.Timer
 }
 
+// realTicker wraps time.Ticker to implement our Ticker interface
+type realTicker struct {
+	ticker *time.Ticker
+}
+
 // NewRealClock creates a new RealClock instance
 func NewRealClock() *RealClock {
 	return &RealClock{}
@@ -65,11 +90,28 @@ func (c *RealClock) AfterFunc(d time.Duration, f func()) Timer {
 	return &realTimer{timer: time.AfterFunc(d, f)}
 }
 
+// NewTicker returns a Ticker that sends the time every d
+func (c *RealClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
 // Sleep pauses the current goroutine for at least the duration d
 func (c *RealClock) Sleep(d time.Duration) {
 	time.Sleep(d)
 }
 
+// SleepContext pauses for d or until ctx is done, whichever comes first
+func (c *RealClock) SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Since returns the time elapsed since t
 func (c *RealClock) Since(t time.Time) time.Duration {
 	return time.Since(t)
@@ -85,6 +127,21 @@ func (t *realTimer) Reset(d time.Duration) bool {
 	return t.timer.Reset(d)
 }
 
+// C returns the channel ticks are sent on
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop turns off the ticker
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// Reset restarts the ticker with period d
+func (t *realTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
+
 // MockClock is a Clock implementation for testing that allows manual time control
 type MockClock struct {
 	mu      sync.Mutex
@@ -100,6 +157,16 @@ type mockTimer struct {
 	mu       sync.Mutex
 }
 
+// mockTicker re-arms a mock timer after each tick. It ticks at most once per
+// Advance, however far the clock moves.
+type mockTicker struct {
+	clock  *MockClock
+	c      chan time.Time
+	mu     sync.Mutex
+	period time.Duration
+	timer  Timer
+}
+
 // NewMockClock creates a new MockClock starting at the given time
 func NewMockClock(start time.Time) *MockClock {
 	return &MockClock{
@@ -142,12 +209,27 @@ func (c *MockClock) AfterFunc(d time.Duration, f func()) Timer {
 	return timer
 }
 
+// NewTicker returns a Ticker that sends the mock time every d of Advance
+func (c *MockClock) NewTicker(d time.Duration) Ticker {
+	t := &mockTicker{clock: c, c: make(chan time.Time, 1), period: d}
+	t.mu.Lock()
+	t.timer = c.AfterFunc(d, t.tick)
+	t.mu.Unlock()
+	return t
+}
+
 // Sleep does nothing immediately in MockClock - time only advances via Advance()
 func (c *MockClock) Sleep(d time.Duration) {
 	// In mock mode, Sleep is a no-op. Use Advance() to move time forward.
 	// This allows tests to control exactly when time passes.
 }
 
+// SleepContext doesn't wait, like Sleep, but reports a done ctx so callers
+// stop as they would in production
+func (c *MockClock) SleepContext(ctx context.Context, d time.Duration) error {
+	return ctx.Err()
+}
+
 // Since returns the time elapsed since t using the mock current time
 func (c *MockClock) Since(t time.Time) time.Duration {
 	c.mu.Lock()
@@ -235,3 +317,43 @@ func (t *mockTimer) Reset(d time.Duration) bool {
 
 	return wasActive
 }
+
+// C returns the channel ticks are sent on
+func (t *mockTicker) C() <-chan time.Time {
+	return t.c
+}
+
+// tick sends the time, dropping it if the last tick is unread, and re-arms
+func (t *mockTicker) tick() {
+	select {
+	case t.c <- t.clock.Now():
+	default:
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer = t.clock.AfterFunc(t.period, t.tick)
+	}
+}
+
+// Stop turns off the ticker
+func (t *mockTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
+
+// Reset restarts the ticker with period d
+func (t *mockTicker) Reset(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.period = d
+	t.timer = t.clock.AfterFunc(d, t.tick)
+}
@@ -0,0 +1,43 @@
+package clock
+
+import "time"
+
+// WallClock returns hour:minute on day's date, in day's location. On a
+// daylight saving change a wall time that is skipped resolves to the moment
+// clocks jump forward, and a wall time that happens twice resolves to its
+// first occurrence, so a daily trigger fires exactly once either way.
+func WallClock(day time.Time, hour, minute int) time.Time {
+	year, month, date := day.Date()
+	loc := day.Location()
+	t := time.Date(year, month, date, hour, minute, 0, 0, loc)
+
+	if t.Hour() != hour || t.Minute() != minute {
+		// Skipped: time.Date normalized into one side of the gap
+		start, end := t.ZoneBounds()
+		if t.Hour()*60+t.Minute() > hour*60+minute {
+			return start
+		}
+		return end
+	}
+
+	// Repeated: prefer the occurrence before clocks went back
+	start, _ := t.ZoneBounds()
+	if start.IsZero() {
+		return t
+	}
+	_, offset := t.Zone()
+	_, previousOffset := start.Add(-time.Second).Zone()
+	if previousOffset > offset {
+		earlier := t.Add(-time.Duration(previousOffset-offset) * time.Second)
+		if earlier.Before(start) {
+			return earlier
+		}
+	}
+	return t
+}
+
+// DayAt returns the time on day's date at the hour and minute of clockTime,
+// as WallClock. Schedules parse "HH:MM" into a time.Time, which this places on a date.
+func DayAt(day, clockTime time.Time) time.Time {
+	return WallClock(day, clockTime.Hour(), clockTime.Minute())
+}
@@ -0,0 +1,95 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestWallClock(t *testing.T) {
+	chicago := mustLoadLocation(t, "America/Chicago")
+
+	tests := []struct {
+		name     string
+		day      time.Time
+		hour     int
+		minute   int
+		expected time.Time
+	}{
+		{
+			name:     "ordinary day",
+			day:      time.Date(2024, 6, 1, 12, 0, 0, 0, chicago),
+			hour:     22,
+			minute:   30,
+			expected: time.Date(2024, 6, 2, 3, 30, 0, 0, time.UTC),
+		},
+		{
+			// 02:00 CST jumps to 03:00 CDT, so 02:30 never happens
+			name:     "skipped hour resolves to the jump",
+			day:      time.Date(2024, 3, 10, 0, 0, 0, 0, chicago),
+			hour:     2,
+			minute:   30,
+			expected: time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "just after the skipped hour",
+			day:      time.Date(2024, 3, 10, 0, 0, 0, 0, chicago),
+			hour:     3,
+			minute:   0,
+			expected: time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			// 02:00 CDT falls back to 01:00 CST, so 01:30 happens twice
+			name:     "repeated hour resolves to the first occurrence",
+			day:      time.Date(2024, 11, 3, 12, 0, 0, 0, chicago),
+			hour:     1,
+			minute:   30,
+			expected: time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "after the repeated hour",
+			day:      time.Date(2024, 11, 3, 12, 0, 0, 0, chicago),
+			hour:     2,
+			minute:   30,
+			expected: time.Date(2024, 11, 3, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "UTC",
+			day:      time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+			hour:     2,
+			minute:   30,
+			expected: time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WallClock(tt.day, tt.hour, tt.minute)
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got.UTC())
+			}
+			if got.Location() != tt.day.Location() {
+				t.Errorf("Expected location %v, got %v", tt.day.Location(), got.Location())
+			}
+		})
+	}
+}
+
+func TestDayAt(t *testing.T) {
+	chicago := mustLoadLocation(t, "America/Chicago")
+
+	clockTime, _ := time.Parse("15:04", "02:15")
+	got := DayAt(time.Date(2024, 3, 10, 12, 0, 0, 0, chicago), clockTime)
+
+	if expected := time.Date(2024, 3, 10, 3, 0, 0, 0, chicago); !got.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"time"
 
+	"homeautomation/internal/clock"
+
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
@@ -62,6 +64,7 @@ type Loader struct {
 	musicConfig    *MusicConfig
 	hueConfig      *HueConfig
 	scheduleConfig *ScheduleConfig
+	timezone       *time.Location
 	stopChan       chan struct{}
 }
 
@@ -70,10 +73,18 @@ func NewLoader(configDir string, logger *zap.Logger) *Loader {
 	return &Loader{
 		configDir: configDir,
 		logger:    logger,
+		timezone:  time.Local,
 		stopChan:  make(chan struct{}),
 	}
 }
 
+// SetTimezone sets the time zone schedule times are local to
+func (l *Loader) SetTimezone(timezone *time.Location) {
+	if timezone != nil {
+		l.timezone = timezone
+	}
+}
+
 // LoadAll loads all configuration files
 func (l *Loader) LoadAll() error {
 	l.logger.Info("Loading configuration files", zap.String("dir", l.configDir))
@@ -175,11 +186,18 @@ func (l *Loader) GetScheduleConfig() *ScheduleConfig {
 
 // GetTodaysSchedule parses and returns today's schedule with actual timestamps
 func (l *Loader) GetTodaysSchedule() (*ParsedSchedule, error) {
+	return l.GetScheduleFor(time.Now())
+}
+
+// GetScheduleFor returns the schedule for the day containing now, in the
+// loader's time zone. Times skipped or repeated by a daylight saving change
+// resolve as clock.WallClock does.
+func (l *Loader) GetScheduleFor(now time.Time) (*ParsedSchedule, error) {
 	if l.scheduleConfig == nil {
 		return nil, fmt.Errorf("schedule config not loaded")
 	}
 
-	now := time.Now()
+	now = now.In(l.timezone)
 	weekday := int(now.Weekday())
 
 	if weekday >= len(l.scheduleConfig.Schedule) {
@@ -196,8 +214,7 @@ func (l *Loader) GetTodaysSchedule() (*ParsedSchedule, error) {
 		}
 
 		// Combine with today's date
-		year, month, day := now.Date()
-		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+		return clock.DayAt(now, t), nil
 	}
 
 	beginWake, err := parseTime(entry.BeginWake)
@@ -250,15 +267,10 @@ func (l *Loader) GetTodaysSchedule() (*ParsedSchedule, error) {
 func (l *Loader) StartAutoReload() {
 	l.logger.Info("Starting auto-reload scheduler (daily at 00:01)")
 
-	// Calculate time until next 00:01
-	now := time.Now()
-	next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 1, 0, 0, now.Location())
-	duration := next.Sub(now)
-
 	// Start a goroutine that reloads configs daily
 	go func() {
 		// Wait for first reload time
-		timer := time.NewTimer(duration)
+		timer := time.NewTimer(time.Until(l.nextReload(time.Now())))
 		defer timer.Stop()
 
 		for {
@@ -269,8 +281,9 @@ func (l *Loader) StartAutoReload() {
 					l.logger.Error("Failed to auto-reload configs", zap.Error(err))
 				}
 
-				// Schedule next reload in 24 hours
-				timer.Reset(24 * time.Hour)
+				// Schedule the next reload; not simply 24 hours, as days
+				// with a daylight saving change are 23 or 25 hours long
+				timer.Reset(time.Until(l.nextReload(time.Now())))
 
 			case <-l.stopChan:
 				l.logger.Info("Stopping auto-reload scheduler")
@@ -280,6 +293,16 @@ func (l *Loader) StartAutoReload() {
 	}()
 }
 
+// nextReload returns the next 00:01 after now in the loader's time zone
+func (l *Loader) nextReload(now time.Time) time.Time {
+	now = now.In(l.timezone)
+	next := clock.WallClock(now, 0, 1)
+	if !next.After(now) {
+		next = clock.WallClock(now.AddDate(0, 0, 1), 0, 1)
+	}
+	return next
+}
+
 // Stop stops the auto-reload scheduler
 func (l *Loader) Stop() {
 	close(l.stopChan)
@@ -448,3 +448,80 @@ func TestLoader_GetTodaysSchedule_ParseErrors(t *testing.T) {
 		})
 	}
 }
+
+// writeDailySchedule writes a schedule_config.yaml with the same entry every day
+func writeDailySchedule(t *testing.T, entry string) string {
+	t.Helper()
+	dir := t.TempDir()
+	scheduleYAML := "schedule:\n"
+	for i := 0; i < 7; i++ {
+		scheduleYAML += entry
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schedule_config.yaml"), []byte(scheduleYAML), 0644))
+	return dir
+}
+
+func TestLoader_GetScheduleFor_UsesTimezone(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+
+	dir := writeDailySchedule(t, `  - begin_wake: "05:00"
+    wake: "07:00"
+    dusk: "18:00"
+    winddown: "21:00"
+    stop_screens: "22:00"
+    go_to_bed: "22:30"
+    night: "23:00"
+`)
+	loader := NewLoader(dir, zap.NewNop())
+	loader.SetTimezone(chicago)
+	require.NoError(t, loader.LoadScheduleConfig())
+
+	// 03:00 UTC on Tuesday is still Monday evening in Chicago
+	schedule, err := loader.GetScheduleFor(time.Date(2024, 6, 4, 3, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 3, 22, 0, 0, 0, chicago), schedule.StopScreens)
+	assert.Equal(t, chicago, schedule.StopScreens.Location())
+}
+
+func TestLoader_GetScheduleFor_DaylightSavingChanges(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+
+	// begin_wake falls in the hour skipped in March, dusk in the hour repeated in November
+	dir := writeDailySchedule(t, `  - begin_wake: "02:30"
+    wake: "07:00"
+    dusk: "01:30"
+    winddown: "21:00"
+    stop_screens: "22:00"
+    go_to_bed: "22:30"
+    night: "23:00"
+`)
+	loader := NewLoader(dir, zap.NewNop())
+	loader.SetTimezone(chicago)
+	require.NoError(t, loader.LoadScheduleConfig())
+
+	springForward, err := loader.GetScheduleFor(time.Date(2024, 3, 10, 12, 0, 0, 0, chicago))
+	require.NoError(t, err)
+	assert.True(t, springForward.BeginWake.Equal(time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)), "skipped 02:30 runs when clocks reach 03:00, got %v", springForward.BeginWake)
+	assert.True(t, springForward.Wake.Equal(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)), "07:00 CDT, got %v", springForward.Wake)
+
+	fallBack, err := loader.GetScheduleFor(time.Date(2024, 11, 3, 12, 0, 0, 0, chicago))
+	require.NoError(t, err)
+	assert.True(t, fallBack.Dusk.Equal(time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC)), "repeated 01:30 runs the first time, got %v", fallBack.Dusk)
+	assert.True(t, fallBack.Wake.Equal(time.Date(2024, 11, 3, 13, 0, 0, 0, time.UTC)), "07:00 CST, got %v", fallBack.Wake)
+}
+
+func TestLoader_NextReload(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+
+	loader := NewLoader(t.TempDir(), zap.NewNop())
+	loader.SetTimezone(chicago)
+
+	// Later the same night, and across the 25-hour day in November
+	assert.Equal(t, time.Date(2024, 6, 3, 0, 1, 0, 0, chicago), loader.nextReload(time.Date(2024, 6, 3, 0, 0, 30, 0, chicago)))
+	next := loader.nextReload(time.Date(2024, 11, 3, 0, 1, 0, 0, chicago))
+	assert.Equal(t, time.Date(2024, 11, 4, 0, 1, 0, 0, chicago), next)
+	assert.Equal(t, 25*time.Hour, next.Sub(time.Date(2024, 11, 3, 0, 1, 0, 0, chicago)))
+}
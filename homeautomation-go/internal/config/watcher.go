@@ -0,0 +1,141 @@
+package config
+
+import (
+	"crypto/sha256"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultWatchInterval is how often watched config files are checked for changes
+const DefaultWatchInterval = 5 * time.Second
+
+// Watcher polls config files and calls back when their contents change.
+// Changes are detected by checksum, so editors that replace the file and
+// touches that don't change the contents are handled alike.
+type Watcher struct {
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu    sync.Mutex
+	files []*watchedFile
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// watchedFile is one file and the checksum it had when last seen
+type watchedFile struct {
+	path     string
+	sum      [sha256.Size]byte
+	exists   bool
+	onChange func()
+}
+
+// NewWatcher creates a watcher that checks its files every interval
+func NewWatcher(interval time.Duration, logger *zap.Logger) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &Watcher{
+		interval: interval,
+		logger:   logger.Named("config-watcher"),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Watch calls onChange whenever the file at path changes. The current
+// contents are the baseline, so onChange is not called for them.
+func (w *Watcher) Watch(path string, onChange func()) {
+	f := &watchedFile{path: path, onChange: onChange}
+	f.sum, f.exists = checksum(path)
+
+	w.mu.Lock()
+	w.files = append(w.files, f)
+	w.mu.Unlock()
+}
+
+// WatchFile reloads the file at path with load whenever it changes and hands
+// the result to apply. If the file fails to load or apply, the error is
+// logged and the component keeps its current configuration until the next
+// edit.
+func WatchFile[T any](w *Watcher, path string, load func(string) (T, error), apply func(T) error) {
+	w.Watch(path, func() {
+		config, err := load(path)
+		if err != nil {
+			w.logger.Error("Failed to reload config, keeping the current one",
+				zap.String("path", path),
+				zap.Error(err))
+			return
+		}
+		if err := apply(config); err != nil {
+			w.logger.Error("Failed to apply reloaded config, keeping the current one",
+				zap.String("path", path),
+				zap.Error(err))
+			return
+		}
+		w.logger.Info("Applied reloaded config", zap.String("path", path))
+	})
+}
+
+// Check compares every watched file with its last checksum and calls back
+// for those that changed. A deleted file is not reported, but its return is.
+func (w *Watcher) Check() {
+	w.mu.Lock()
+	var changed []*watchedFile
+	for _, f := range w.files {
+		sum, exists := checksum(f.path)
+		if exists == f.exists && sum == f.sum {
+			continue
+		}
+		f.sum, f.exists = sum, exists
+		if exists {
+			changed = append(changed, f)
+		} else {
+			w.logger.Warn("Watched config file is missing", zap.String("path", f.path))
+		}
+	}
+	w.mu.Unlock()
+
+	for _, f := range changed {
+		w.logger.Info("Config file changed", zap.String("path", f.path))
+		f.onChange()
+	}
+}
+
+// Start checks the watched files every interval until Stop
+func (w *Watcher) Start() {
+	w.logger.Info("Watching config files for changes", zap.Duration("interval", w.interval))
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops checking for changes. It is safe to call more than once.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopChan)
+	})
+}
+
+// checksum returns the SHA-256 of a file's contents and whether it could be read
+func checksum(path string) ([sha256.Size]byte, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	return sha256.Sum256(data), true
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestWatcher_CallsBackOnContentChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "energy_config.yaml")
+	writeFile(t, path, "a: 1\n")
+
+	watcher := NewWatcher(time.Hour, zap.NewNop())
+	var changes int
+	watcher.Watch(path, func() { changes++ })
+
+	watcher.Check()
+	assert.Zero(t, changes, "the contents at Watch are the baseline")
+
+	writeFile(t, path, "a: 1\n")
+	watcher.Check()
+	assert.Zero(t, changes, "rewriting the same contents is not a change")
+
+	writeFile(t, path, "a: 2\n")
+	watcher.Check()
+	watcher.Check()
+	assert.Equal(t, 1, changes)
+}
+
+func TestWatcher_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hue_config.yaml")
+	writeFile(t, path, "a: 1\n")
+
+	watcher := NewWatcher(time.Hour, zap.NewNop())
+	var changes int
+	watcher.Watch(path, func() { changes++ })
+
+	require.NoError(t, os.Remove(path))
+	watcher.Check()
+	assert.Zero(t, changes, "a deleted file is not reported")
+
+	writeFile(t, path, "a: 1\n")
+	watcher.Check()
+	assert.Equal(t, 1, changes, "the file coming back is")
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "music_config.yaml")
+	writeFile(t, path, "ok\n")
+
+	load := func(p string) (string, error) {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		if string(data) == "broken\n" {
+			return "", errors.New("parse error")
+		}
+		return string(data), nil
+	}
+	var applied []string
+	apply := func(config string) error {
+		applied = append(applied, config)
+		return nil
+	}
+
+	watcher := NewWatcher(time.Hour, zap.NewNop())
+	WatchFile(watcher, path, load, apply)
+
+	writeFile(t, path, "broken\n")
+	watcher.Check()
+	assert.Empty(t, applied, "a config that fails to load is not applied")
+
+	writeFile(t, path, "fixed\n")
+	watcher.Check()
+	assert.Equal(t, []string{"fixed\n"}, applied)
+}
+
+func TestWatcher_StartStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "energy_config.yaml")
+	writeFile(t, path, "a: 1\n")
+
+	watcher := NewWatcher(10*time.Millisecond, zap.NewNop())
+	var changes atomic.Int32
+	watcher.Watch(path, func() { changes.Add(1) })
+	watcher.Start()
+
+	writeFile(t, path, "a: 2\n")
+	assert.Eventually(t, func() bool { return changes.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	watcher.Stop()
+	watcher.Stop()
+}
@@ -0,0 +1,1337 @@
+// Package configcheck validates the YAML configuration files before they are
+// loaded by the running application.
+package configcheck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"homeautomation/internal/config"
+	"homeautomation/internal/dayphase"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/entities"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/focusmode"
+	"homeautomation/internal/forecast"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/lifecycle"
+	"homeautomation/internal/mqtt"
+	"homeautomation/internal/namespace"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/plugins/bedroomcomfort"
+	"homeautomation/internal/plugins/climate"
+	dayphaseplugin "homeautomation/internal/plugins/dayphase"
+	"homeautomation/internal/plugins/emergency"
+	"homeautomation/internal/plugins/energy"
+	"homeautomation/internal/plugins/freezeprotection"
+	"homeautomation/internal/plugins/growlights"
+	"homeautomation/internal/plugins/hotwater"
+	"homeautomation/internal/plugins/lighting"
+	"homeautomation/internal/plugins/loadshedding"
+	"homeautomation/internal/plugins/lowbattery"
+	"homeautomation/internal/plugins/music"
+	"homeautomation/internal/plugins/openreminder"
+	"homeautomation/internal/plugins/rules"
+	"homeautomation/internal/plugins/scenescheduler"
+	"homeautomation/internal/plugins/security"
+	"homeautomation/internal/plugins/sleepfan"
+	"homeautomation/internal/plugins/sleephygiene"
+	"homeautomation/internal/plugins/statetracking"
+	"homeautomation/internal/ratelimit"
+	"homeautomation/internal/reports"
+	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
+	"homeautomation/internal/warmup"
+	"homeautomation/internal/writescope"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity indicates whether a finding blocks a config change
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single problem found in a config file
+type Finding struct {
+	File     string   `json:"file"`
+	Field    string   `json:"field,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Result is the machine-readable outcome of validating a config directory
+type Result struct {
+	Valid              bool      `json:"valid"`
+	ConfigDir          string    `json:"configDir"`
+	FilesChecked       []string  `json:"filesChecked"`
+	EntityCache        string    `json:"entityCache,omitempty"`
+	EntitiesChecked    int       `json:"entitiesChecked"`
+	EntityCheckSkipped bool      `json:"entityCheckSkipped"`
+	Findings           []Finding `json:"findings"`
+}
+
+// checker accumulates findings while validating each config file
+type checker struct {
+	configDir string
+	entities  EntitySet
+	variables map[string]state.StateVariable
+	result    *Result
+}
+
+// Validate loads every config file in configDir, runs schema validation and,
+// if entities is non-nil, checks that referenced entity IDs exist in Home Assistant.
+func Validate(configDir string, entities EntitySet) *Result {
+	c := &checker{
+		configDir: configDir,
+		entities:  entities,
+		variables: state.VariablesByKey(),
+		result: &Result{
+			ConfigDir:          configDir,
+			FilesChecked:       []string{},
+			EntityCheckSkipped: entities == nil,
+			Findings:           []Finding{},
+		},
+	}
+
+	c.checkHueConfig()
+	c.checkMusicConfig()
+	c.checkEnergyConfig()
+	c.checkScheduleConfig()
+	c.checkGrowLightConfig()
+	c.checkReportConfig()
+	c.checkBedroomComfortConfig()
+	c.checkSleepFanConfig()
+	c.checkFreezeProtectionConfig()
+	c.checkLoadSheddingConfig()
+	c.checkClimateConfig()
+	c.checkEmergencyConfig()
+	c.checkOpenReminderConfig()
+	c.checkLowBatteryConfig()
+	c.checkSecurityConfig()
+	c.checkDoNotDisturbConfig()
+	c.checkNotificationRouterConfig()
+	c.checkTTSConfig()
+	c.checkFocusModeConfig()
+	c.checkKidModeConfig()
+	c.checkHotWaterConfig()
+	c.checkRulesConfig()
+	c.checkEntityGroupsConfig()
+	c.checkAdaptiveWakeConfig()
+	c.checkWakeLightConfig()
+	c.checkSonosAlarmConfig()
+	c.checkConsistencyCheckConfig()
+	c.checkWinddownTemperatureConfig()
+	c.checkNamespaceConfig()
+	c.checkMQTTConfig()
+	c.checkSolarForecastConfig()
+	c.checkWarmupConfig()
+	c.checkPluginsConfig()
+	c.checkWriteScopesConfig()
+	c.checkRateLimitsConfig()
+	c.checkEntitiesConfig()
+
+	c.result.Valid = true
+	for _, f := range c.result.Findings {
+		if f.Severity == SeverityError {
+			c.result.Valid = false
+			break
+		}
+	}
+
+	return c.result
+}
+
+func (c *checker) addError(file, field, format string, args ...interface{}) {
+	c.result.Findings = append(c.result.Findings, Finding{
+		File:     file,
+		Field:    field,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) addWarning(file, field, format string, args ...interface{}) {
+	c.result.Findings = append(c.result.Findings, Finding{
+		File:     file,
+		Field:    field,
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// path returns the full path of a config file and records it as checked
+func (c *checker) path(file string) string {
+	c.result.FilesChecked = append(c.result.FilesChecked, file)
+	return filepath.Join(c.configDir, file)
+}
+
+// checkEntity reports an entity ID that is missing from the entity cache
+func (c *checker) checkEntity(file, field, entityID string) {
+	if c.entities == nil || entityID == "" {
+		return
+	}
+	c.result.EntitiesChecked++
+	if !c.entities[entityID] {
+		c.addError(file, field, "entity %s not found in Home Assistant", entityID)
+	}
+}
+
+// checkVariable reports a reference to an unknown state variable
+func (c *checker) checkVariable(file, field, key string) {
+	if _, ok := c.variables[key]; !ok {
+		c.addError(file, field, "unknown state variable %q", key)
+	}
+}
+
+func (c *checker) checkTime(file, field, value string) {
+	if _, err := time.Parse("15:04", value); err != nil {
+		c.addError(file, field, "invalid time %q, expected HH:MM", value)
+	}
+}
+
+func (c *checker) checkHueConfig() {
+	c.checkHueConfigFile("hue_config.yaml")
+}
+
+// checkHueConfigFile checks a hue config, relative to the config directory
+func (c *checker) checkHueConfigFile(file string) {
+	cfg, err := lighting.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	if len(cfg.Rooms) == 0 {
+		c.addWarning(file, "rooms", "no rooms configured")
+	}
+
+	for i, room := range cfg.Rooms {
+		prefix := fmt.Sprintf("rooms[%d]", i)
+		if room.HueGroup == "" {
+			c.addError(file, prefix+".hue_group", "hue_group is required")
+		}
+		if room.HASSAreaID == "" {
+			c.addError(file, prefix+".hass_area_id", "hass_area_id is required")
+		}
+		if room.TransitionSeconds != nil && *room.TransitionSeconds < 0 {
+			c.addError(file, prefix+".transition_seconds", "transition_seconds must not be negative")
+		}
+
+		conditions := []struct {
+			field string
+			keys  []string
+		}{
+			{"on_if_true", room.GetOnIfTrueConditions()},
+			{"on_if_false", room.GetOnIfFalseConditions()},
+			{"off_if_true", room.GetOffIfTrueConditions()},
+			{"off_if_false", room.GetOffIfFalseConditions()},
+			{"increase_brightness_if_true", room.GetIncreaseBrightnessIfTrueConditions()},
+		}
+		for _, cond := range conditions {
+			for _, key := range cond.keys {
+				c.checkVariable(file, prefix+"."+cond.field, key)
+			}
+		}
+
+		c.checkTransitionDayPhases(file, prefix+".day_phase_transitions", room.DayPhaseTransitions)
+
+		if room.OnBudget != nil {
+			if room.OnBudget.DailyMinutes <= 0 {
+				c.addError(file, prefix+".on_budget.daily_minutes", "daily_minutes must be positive")
+			}
+			c.checkEntity(file, prefix+".on_budget.light_entity", room.OnBudgetLightEntity())
+		}
+
+		if room.AdaptiveBrightness != nil {
+			c.checkEntity(file, prefix+".adaptive_brightness.lux_entity", room.AdaptiveBrightness.LuxEntity)
+		}
+
+		if room.Motion != nil {
+			for j, sensor := range room.Motion.Sensors {
+				c.checkEntity(file, fmt.Sprintf("%s.motion.sensors[%d]", prefix, j), sensor)
+			}
+			for _, key := range room.Motion.GetIgnoreIfTrueConditions() {
+				c.checkVariable(file, prefix+".motion.ignore_if_true", key)
+			}
+		}
+	}
+
+	c.checkTransitionDayPhases(file, "day_phase_transitions", cfg.DayPhaseTransitions)
+
+	if cfg.OnBudgetNotifyService != "" {
+		if _, _, ok := cfg.OnBudgetNotifyService.DomainService(); !ok {
+			c.addError(file, "on_budget_notify_service", "invalid service %q, expected domain.service", cfg.OnBudgetNotifyService)
+		}
+	}
+
+	if outage := cfg.GridOutage; outage != nil {
+		if outage.BrightnessCapPct < 0 || outage.BrightnessCapPct > 100 {
+			c.addError(file, "grid_outage.brightness_cap_pct", "brightness_cap_pct must be between 0 and 100")
+		}
+		for i, phase := range outage.DayPhases {
+			if !validDayPhases[dayphase.DayPhase(phase)] {
+				c.addError(file, fmt.Sprintf("grid_outage.day_phases[%d]", i), "unknown day phase %q", phase)
+			}
+		}
+	}
+}
+
+// checkTransitionDayPhases checks that day phase fades are keyed by known day phases
+func (c *checker) checkTransitionDayPhases(file, field string, transitions map[string]lighting.PhaseTransition) {
+	phases := make([]string, 0, len(transitions))
+	for phase := range transitions {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	for _, phase := range phases {
+		if !validDayPhases[dayphase.DayPhase(phase)] {
+			c.addError(file, field+"."+phase, "unknown day phase %q", phase)
+		}
+	}
+}
+
+// validDayPhases are the values the dayPhase variable takes
+var validDayPhases = map[dayphase.DayPhase]bool{
+	dayphase.DayPhaseMorning:  true,
+	dayphase.DayPhaseDay:      true,
+	dayphase.DayPhaseSunset:   true,
+	dayphase.DayPhaseDusk:     true,
+	dayphase.DayPhaseWinddown: true,
+	dayphase.DayPhaseNight:    true,
+}
+
+func (c *checker) checkMusicConfig() {
+	c.checkMusicConfigFile("music_config.yaml")
+}
+
+// checkMusicConfigFile checks a music config, relative to the config directory
+func (c *checker) checkMusicConfigFile(file string) {
+	cfg, err := music.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for _, mode := range sortedKeys(cfg.Music) {
+		musicMode := cfg.Music[mode]
+		prefix := "music." + mode
+
+		if len(musicMode.PlaybackOptions) == 0 {
+			c.addError(file, prefix+".playback_options", "at least one playback option is required")
+		}
+		for i, option := range musicMode.PlaybackOptions {
+			if option.URI == "" {
+				c.addError(file, fmt.Sprintf("%s.playback_options[%d].uri", prefix, i), "uri is required")
+			}
+			if option.VolumeMultiplier < 0 {
+				c.addError(file, fmt.Sprintf("%s.playback_options[%d].volume_multiplier", prefix, i), "volume_multiplier must not be negative")
+			}
+		}
+
+		for i, participant := range musicMode.Participants {
+			field := fmt.Sprintf("%s.participants[%d]", prefix, i)
+			if participant.PlayerName == "" {
+				c.addError(file, field+".player_name", "player_name is required")
+				continue
+			}
+			if participant.BaseVolume < 0 || participant.BaseVolume > 100 {
+				c.addError(file, field+".base_volume", "base_volume %d must be between 0 and 100", participant.BaseVolume)
+			}
+			for j, cond := range participant.LeaveMutedIf {
+				c.checkVariable(file, fmt.Sprintf("%s.leave_muted_if[%d].variable", field, j), cond.Variable)
+			}
+			if participant.EntityID != "" {
+				c.checkEntity(file, field+".entity_id", participant.EntityID)
+			} else {
+				c.checkEntity(file, field+".player_name", cfg.SpeakerEntityID(participant.PlayerName))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.SpeakerGroups) {
+		for i, speaker := range cfg.SpeakerGroups[name].Speakers {
+			c.checkEntity(file, fmt.Sprintf("speaker_groups.%s.speakers[%d]", name, i), cfg.SpeakerEntityID(speaker))
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.Zones) {
+		zone := cfg.Zones[name]
+		prefix := "zones." + name
+		variable := state.ZoneVariable(c.variables["musicPlaybackType"], name)
+		c.checkEntity(file, prefix, variable.EntityID)
+		for i, speaker := range zone.Speakers {
+			c.checkEntity(file, fmt.Sprintf("%s.speakers[%d]", prefix, i), cfg.SpeakerEntityID(speaker))
+		}
+		for i, cond := range zone.LeaveMutedIf {
+			c.checkVariable(file, fmt.Sprintf("%s.leave_muted_if[%d].variable", prefix, i), cond.Variable)
+		}
+	}
+
+	sets := make(map[string]bool)
+	for _, mode := range cfg.Music {
+		for _, option := range mode.PlaybackOptions {
+			for _, set := range option.Sets {
+				sets[set] = true
+			}
+		}
+	}
+	for i, profile := range cfg.TasteProfiles {
+		prefix := fmt.Sprintf("taste_profiles[%d]", i)
+		for j, variable := range profile.Present {
+			c.checkVariable(file, fmt.Sprintf("%s.present[%d]", prefix, j), variable)
+		}
+		for j, variable := range profile.Absent {
+			c.checkVariable(file, fmt.Sprintf("%s.absent[%d]", prefix, j), variable)
+		}
+		for _, set := range sortedKeys(profile.SetWeights) {
+			if !sets[set] {
+				c.addError(file, prefix+".set_weights."+set, "no playback option is in set %q", set)
+			}
+		}
+	}
+
+	if v := cfg.PlaybackVerification; v != nil && v.WakeFallback != nil {
+		c.checkEntity(file, "playback_verification.wake_fallback.speaker", cfg.SpeakerEntityID(v.WakeFallback.Speaker))
+		c.checkEntity(file, "playback_verification.wake_fallback.tts_entity", v.WakeFallback.TTSEntity)
+	}
+}
+
+func (c *checker) checkEnergyConfig() {
+	const file = "energy_config.yaml"
+	cfg, err := energy.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	c.checkTime(file, "energy.free_energy_time.start", cfg.Energy.FreeEnergyTime.Start)
+	c.checkTime(file, "energy.free_energy_time.end", cfg.Energy.FreeEnergyTime.End)
+
+	if len(cfg.Energy.EnergyStates) == 0 {
+		c.addError(file, "energy.energy_states", "at least one energy state is required")
+	}
+
+	if announcements := cfg.FreeEnergyAnnouncements; announcements != nil {
+		if _, _, ok := announcements.NotifyService.DomainService(); !ok {
+			c.addError(file, "free_energy_announcements.notify_service", "invalid notify_service %q (expected domain.service)", announcements.NotifyService)
+		}
+		if announcements.StartLeadMinutes < 0 || announcements.StartLeadMinutes > 180 {
+			c.addError(file, "free_energy_announcements.start_lead_minutes", "start_lead_minutes must be between 0 and 180")
+		}
+		if announcements.EndLeadMinutes < 0 || announcements.EndLeadMinutes > 180 {
+			c.addError(file, "free_energy_announcements.end_lead_minutes", "end_lead_minutes must be between 0 and 180")
+		}
+		if quiet := announcements.QuietHours; quiet != nil {
+			c.checkTime(file, "free_energy_announcements.quiet_hours.start", quiet.Start)
+			c.checkTime(file, "free_energy_announcements.quiet_hours.end", quiet.End)
+		}
+	}
+
+	if reserve := cfg.BackupReserve; reserve != nil {
+		c.checkBackupReserve(file, reserve)
+	}
+
+	if announcements := cfg.LevelAnnouncements; announcements != nil {
+		c.checkLevelAnnouncements(file, announcements, cfg.Energy.EnergyStates)
+	}
+
+	if rates := cfg.RateSchedule; rates != nil {
+		c.checkEntity(file, "rate_schedule.price_sensor", rates.PriceSensor)
+		if len(rates.Windows) == 0 && rates.PriceSensor == "" {
+			c.addWarning(file, "rate_schedule", "no windows or price_sensor, so the price never changes")
+		}
+	}
+
+	if policy := cfg.ReservePolicy; policy != nil {
+		c.checkEntity(file, "reserve_policy.reserve_entity", policy.ReserveEntity)
+		if variable := policy.StormWatchVariable; variable != "" {
+			if v, ok := c.variables[variable]; !ok {
+				c.addError(file, "reserve_policy.storm_watch_variable", "unknown state variable %q", variable)
+			} else if v.Type != state.TypeBool {
+				c.addError(file, "reserve_policy.storm_watch_variable", "state variable %q is not a boolean", variable)
+			}
+		}
+	}
+
+	if cfg.ForecastLookAhead != nil {
+		if _, err := os.Stat(filepath.Join(c.configDir, "solar_forecast_config.yaml")); errors.Is(err, os.ErrNotExist) {
+			c.addWarning(file, "forecast_look_ahead", "no solar_forecast_config.yaml, so there is no forecast to look ahead at")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, energyState := range cfg.Energy.EnergyStates {
+		prefix := fmt.Sprintf("energy.energy_states[%d]", i)
+		if energyState.ConditionName == "" {
+			c.addError(file, prefix+".condition_name", "condition_name is required")
+		} else if seen[energyState.ConditionName] {
+			c.addError(file, prefix+".condition_name", "duplicate condition_name %q", energyState.ConditionName)
+		}
+		seen[energyState.ConditionName] = true
+
+		if energyState.BatteryMinimumPercentage < 0 || energyState.BatteryMinimumPercentage > 100 {
+			c.addError(file, prefix+".battery_minimum_percentage", "battery_minimum_percentage must be between 0 and 100")
+		}
+
+		light := energyState.LightConfig
+		for _, channel := range []struct {
+			name  string
+			value int
+		}{{"red", light.Red}, {"green", light.Green}, {"blue", light.Blue}} {
+			if channel.value < 0 || channel.value > 255 {
+				c.addError(file, prefix+".light_config."+channel.name, "%s must be between 0 and 255", channel.name)
+			}
+		}
+		if light.BrightnessPct < 0 || light.BrightnessPct > 100 {
+			c.addError(file, prefix+".light_config.brightness_pct", "brightness_pct must be between 0 and 100")
+		}
+	}
+}
+
+func (c *checker) checkLevelAnnouncements(file string, announcements *energy.LevelAnnouncements, states []energy.EnergyState) {
+	const prefix = "level_announcements."
+	levels := map[string]bool{"*": true}
+	for _, energyState := range states {
+		levels[energyState.ConditionName] = true
+	}
+
+	needsNotify := false
+	for i, transition := range announcements.Transitions {
+		field := fmt.Sprintf("%stransitions[%d]", prefix, i)
+		if !levels[transition.From] {
+			c.addError(file, field+".from", "unknown energy level %q", transition.From)
+		}
+		if !levels[transition.To] {
+			c.addError(file, field+".to", "unknown energy level %q", transition.To)
+		}
+		if !transition.Notify && len(transition.Speakers) == 0 {
+			c.addError(file, field, "transition must notify, name speakers, or both")
+		}
+		needsNotify = needsNotify || transition.Notify
+		for j, speaker := range transition.Speakers {
+			c.checkEntity(file, fmt.Sprintf("%s.speakers[%d]", field, j), speaker)
+		}
+	}
+	if _, _, ok := announcements.NotifyService.DomainService(); needsNotify && !ok {
+		c.addError(file, prefix+"notify_service", "invalid notify_service %q (expected domain.service)", announcements.NotifyService)
+	}
+	if quiet := announcements.QuietHours; quiet != nil {
+		c.checkTime(file, prefix+"quiet_hours.start", quiet.Start)
+		c.checkTime(file, prefix+"quiet_hours.end", quiet.End)
+	}
+}
+
+func (c *checker) checkBackupReserve(file string, reserve *energy.BackupReserve) {
+	const prefix = "backup_reserve."
+	if domain := ha.EntityDomain(reserve.InverterModeEntity); domain != "select" && domain != "input_select" {
+		c.addError(file, prefix+"inverter_mode_entity", "inverter_mode_entity must be a select or input_select, got %q", reserve.InverterModeEntity)
+	}
+	c.checkEntity(file, prefix+"inverter_mode_entity", reserve.InverterModeEntity)
+	if reserve.BackupMode == "" || reserve.NormalMode == "" {
+		c.addError(file, prefix+"backup_mode", "backup_mode and normal_mode are required")
+	} else if reserve.BackupMode == reserve.NormalMode {
+		c.addError(file, prefix+"backup_mode", "backup_mode and normal_mode must differ")
+	}
+
+	if reserve.RiskSensor == "" && reserve.WeatherEntity == "" {
+		c.addError(file, prefix+"risk_sensor", "at least one of risk_sensor and weather_entity is required")
+	}
+	if reserve.RiskSensor != "" && reserve.RiskThreshold <= 0 {
+		c.addError(file, prefix+"risk_threshold", "risk_threshold must be positive")
+	}
+	c.checkEntity(file, prefix+"risk_sensor", reserve.RiskSensor)
+	if reserve.WeatherEntity != "" {
+		if !strings.HasPrefix(reserve.WeatherEntity, "weather.") {
+			c.addError(file, prefix+"weather_entity", "weather_entity must be a weather entity, got %q", reserve.WeatherEntity)
+		}
+		if len(reserve.RiskConditions) == 0 && reserve.WindSpeedThreshold <= 0 {
+			c.addWarning(file, prefix+"risk_conditions", "weather_entity is set but no risk_conditions or wind_speed_threshold, so the forecast never raises risk")
+		}
+	}
+	c.checkEntity(file, prefix+"weather_entity", reserve.WeatherEntity)
+
+	if reserve.WindSpeedThreshold < 0 {
+		c.addError(file, prefix+"wind_speed_threshold", "wind_speed_threshold must not be negative")
+	}
+	if reserve.LeadHours < 0 || reserve.LeadHours > 48 {
+		c.addError(file, prefix+"lead_hours", "lead_hours must be between 0 and 48")
+	}
+	if reserve.RestoreAfterMinutes < 0 {
+		c.addError(file, prefix+"restore_after_minutes", "restore_after_minutes must not be negative")
+	}
+}
+
+func (c *checker) checkScheduleConfig() {
+	const file = "schedule_config.yaml"
+	data, err := os.ReadFile(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	var cfg config.ScheduleConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	// The schedule is indexed by weekday, Sunday first
+	if len(cfg.Schedule) != 7 {
+		c.addError(file, "schedule", "expected 7 entries (Sunday through Saturday), found %d", len(cfg.Schedule))
+	}
+
+	for i, entry := range cfg.Schedule {
+		prefix := fmt.Sprintf("schedule[%d]", i)
+		c.checkTime(file, prefix+".begin_wake", entry.BeginWake)
+		c.checkTime(file, prefix+".wake", entry.Wake)
+		c.checkTime(file, prefix+".dusk", entry.Dusk)
+		c.checkTime(file, prefix+".winddown", entry.Winddown)
+		c.checkTime(file, prefix+".stop_screens", entry.StopScreens)
+		c.checkTime(file, prefix+".go_to_bed", entry.GoToBed)
+		c.checkTime(file, prefix+".night", entry.Night)
+	}
+
+	// The file was already counted above, so it is not passed through c.path again
+	scenes, err := scenescheduler.LoadConfig(filepath.Join(c.configDir, file))
+	if err != nil {
+		c.addError(file, "scene_schedules", "%v", err)
+		return
+	}
+	for i, s := range scenes.SceneSchedules {
+		c.checkEntity(file, fmt.Sprintf("scene_schedules[%d].scene", i), s.Scene)
+	}
+}
+
+func (c *checker) checkGrowLightConfig() {
+	const file = "growlight_config.yaml"
+	cfg, err := growlights.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, fixture := range cfg.GrowLights.Fixtures {
+		c.checkEntity(file, fmt.Sprintf("grow_lights.fixtures[%d].entity_id", i), fixture.EntityID)
+	}
+}
+
+func (c *checker) checkReportConfig() {
+	const file = "report_config.yaml"
+	cfg, err := reports.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	c.checkEntity(file, "weekly_report.solar_production_sensor", cfg.WeeklyReport.SolarProductionSensor)
+	c.checkEntity(file, "weekly_report.grid_export_sensor", cfg.WeeklyReport.GridExportSensor)
+
+	rooms := make([]string, 0, len(cfg.OccupancyHeatmap.Rooms))
+	for room := range cfg.OccupancyHeatmap.Rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	for _, room := range rooms {
+		field := fmt.Sprintf("occupancy_heatmap.rooms.%s", room)
+		variable := cfg.OccupancyHeatmap.Rooms[room]
+		if v, ok := c.variables[variable]; !ok {
+			c.addError(file, field, "unknown state variable %q", variable)
+		} else if v.Type != state.TypeBool {
+			c.addError(file, field, "state variable %q is not a boolean", variable)
+		}
+	}
+}
+
+func (c *checker) checkBedroomComfortConfig() {
+	const file = "bedroom_comfort_config.yaml"
+	cfg, err := bedroomcomfort.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	c.checkEntity(file, "bedroom_comfort.climate_entity", cfg.BedroomComfort.ClimateEntity)
+	c.checkEntity(file, "bedroom_comfort.temperature_sensor", cfg.BedroomComfort.TemperatureSensor)
+	c.checkEntity(file, "bedroom_comfort.humidity_sensor", cfg.BedroomComfort.HumiditySensor)
+	c.checkEntity(file, "bedroom_comfort.fan_entity", cfg.BedroomComfort.FanEntity)
+}
+
+func (c *checker) checkSleepFanConfig() {
+	const file = "sleep_fan_config.yaml"
+	// Optional: no bedroom fan control when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := sleepfan.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	// Bedroom comfort switches its fan on and off, which would fight the speed steps
+	var comfortFan string
+	if comfort, err := bedroomcomfort.LoadConfig(filepath.Join(c.configDir, "bedroom_comfort_config.yaml")); err == nil {
+		comfortFan = comfort.BedroomComfort.FanEntity
+	}
+
+	for i, b := range cfg.SleepFan.Bedrooms {
+		field := fmt.Sprintf("sleep_fan.bedrooms[%d]", i)
+		c.checkEntity(file, field+".fan_entity", b.FanEntity)
+		c.checkEntity(file, field+".temperature_sensor", b.TemperatureSensor)
+		c.checkEntity(file, field+".window_sensor", b.WindowSensor)
+		if comfortFan != "" && b.FanEntity == comfortFan {
+			c.addWarning(file, field+".fan_entity", "%s is also bedroom_comfort.fan_entity; both plugins would control it", b.FanEntity)
+		}
+	}
+}
+
+func (c *checker) checkLoadSheddingConfig() {
+	const file = "loadshedding_config.yaml"
+	// Optional: only the thermostat is shed when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := loadshedding.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	if len(cfg.LoadShedding.Devices) > 0 && len(cfg.LoadShedding.ShedFromTier) == 0 {
+		c.addWarning(file, "load_shedding.shed_from_tier", "no energy level sheds a tier, so no device is ever shed")
+	}
+
+	// Shedding a freeze protection heater would leave the space to freeze
+	heaters := make(map[string]bool)
+	if freeze, err := freezeprotection.LoadConfig(filepath.Join(c.configDir, "freeze_protection_config.yaml")); err == nil {
+		for _, space := range freeze.FreezeProtection.Spaces {
+			for _, heater := range space.Heaters {
+				heaters[heater] = true
+			}
+		}
+	}
+
+	for i, device := range cfg.LoadShedding.Devices {
+		field := fmt.Sprintf("load_shedding.devices[%d].entity_id", i)
+		c.checkEntity(file, field, device.EntityID)
+		if heaters[device.EntityID] {
+			c.addWarning(file, field, "%s is a freeze protection heater; freeze protection limits it by energy level already", device.EntityID)
+		}
+	}
+}
+
+func (c *checker) checkClimateConfig() {
+	const file = "climate_config.yaml"
+	// Optional: thermostats keep their own schedule when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := climate.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	// Bedroom comfort adjusts its thermostat overnight, which would fight the asleep setpoints
+	var comfortClimate string
+	if comfort, err := bedroomcomfort.LoadConfig(filepath.Join(c.configDir, "bedroom_comfort_config.yaml")); err == nil {
+		comfortClimate = comfort.BedroomComfort.ClimateEntity
+	}
+
+	for i, t := range cfg.Climate.Thermostats {
+		field := fmt.Sprintf("climate.thermostats[%d]", i)
+		c.checkEntity(file, field+".entity_id", t.EntityID)
+		if comfortClimate != "" && t.EntityID == comfortClimate {
+			c.addWarning(file, field+".entity_id", "%s is also bedroom_comfort.climate_entity; both plugins would control it", t.EntityID)
+		}
+		for _, phase := range sortedKeys(t.DayPhases) {
+			if !validDayPhases[dayphase.DayPhase(phase)] {
+				c.addError(file, field+".day_phases."+phase, "unknown day phase %q", phase)
+			}
+		}
+	}
+
+	if openPause := cfg.Climate.OpenPause; openPause != nil {
+		for i, sensor := range openPause.Sensors {
+			c.checkEntity(file, fmt.Sprintf("climate.open_pause.sensors[%d]", i), sensor)
+		}
+		for i, thermostat := range openPause.Thermostats {
+			c.checkEntity(file, fmt.Sprintf("climate.open_pause.thermostats[%d]", i), thermostat)
+		}
+	}
+}
+
+func (c *checker) checkEmergencyConfig() {
+	const file = "emergency_config.yaml"
+	// Optional: no smoke/CO response when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := emergency.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	s := cfg.Emergency
+	for i, d := range s.Detectors {
+		c.checkEntity(file, fmt.Sprintf("emergency.detectors[%d].entity_id", i), d.EntityID)
+	}
+	for _, list := range []struct {
+		field    string
+		entities []string
+	}{
+		{"lights", s.Lights},
+		{"unlock", s.Unlock},
+		{"media_players", s.MediaPlayers},
+		{"speakers", s.Speakers},
+	} {
+		for i, entityID := range list.entities {
+			if entitygroups.IsReference(entityID) {
+				continue
+			}
+			c.checkEntity(file, fmt.Sprintf("emergency.%s[%d]", list.field, i), entityID)
+		}
+	}
+	c.checkEntity(file, "emergency.garage_door", s.GarageDoor)
+	c.checkEntity(file, "emergency.tts_entity", s.TTSEntity)
+
+	key := s.AcknowledgeKey()
+	c.checkVariable(file, "emergency.acknowledge_variable", key)
+	if variable, ok := c.variables[key]; ok && variable.Type != state.TypeBool {
+		c.addError(file, "emergency.acknowledge_variable", "state variable %q is not a boolean", key)
+	}
+
+	// A misspelled plugin would be suspended after all
+	for i, name := range s.KeepRunning {
+		if !slices.Contains(warmup.Plugins, name) {
+			c.addWarning(file, fmt.Sprintf("emergency.keep_running[%d]", i), "unknown plugin %q", name)
+		}
+	}
+}
+
+func (c *checker) checkFreezeProtectionConfig() {
+	const file = "freeze_protection_config.yaml"
+	// Optional: no spaces are protected when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := freezeprotection.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	// A limit for a level the energy plugin never reports would never apply
+	var levels map[string]bool
+	if energyConfig, err := energy.LoadConfig(filepath.Join(c.configDir, "energy_config.yaml")); err == nil {
+		levels = make(map[string]bool)
+		for _, energyState := range energyConfig.Energy.EnergyStates {
+			levels[energyState.ConditionName] = true
+		}
+	}
+
+	for i, space := range cfg.FreezeProtection.Spaces {
+		field := fmt.Sprintf("freeze_protection.spaces[%d]", i)
+		c.checkEntity(file, field+".temperature_sensor", space.TemperatureSensor)
+		for j, heater := range space.Heaters {
+			c.checkEntity(file, fmt.Sprintf("%s.heaters[%d]", field, j), heater)
+		}
+		for j, damper := range space.Dampers {
+			c.checkEntity(file, fmt.Sprintf("%s.dampers[%d]", field, j), damper)
+		}
+		if levels == nil {
+			continue
+		}
+		for _, level := range sortedKeys(space.EnergyLimits) {
+			if !levels[level] {
+				c.addError(file, field+".energy_limits."+level, "unknown energy level %q", level)
+			}
+		}
+	}
+}
+
+func (c *checker) checkOpenReminderConfig() {
+	const file = "open_reminder_config.yaml"
+	cfg, err := openreminder.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, sensor := range cfg.OpenReminder.Sensors {
+		c.checkEntity(file, fmt.Sprintf("open_reminder.sensors[%d].entity_id", i), sensor.EntityID)
+	}
+	c.checkEntity(file, "open_reminder.acknowledge_entity", cfg.OpenReminder.AcknowledgeEntity)
+	c.checkEntity(file, "open_reminder.tts_entity", cfg.OpenReminder.TTSEntity)
+	for i, speaker := range cfg.OpenReminder.AnnounceSpeakers {
+		c.checkEntity(file, fmt.Sprintf("open_reminder.announce_speakers[%d]", i), speaker)
+	}
+}
+
+func (c *checker) checkLowBatteryConfig() {
+	const file = "low_battery_config.yaml"
+	cfg, err := lowbattery.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	// Prefix patterns can't be checked against the entity list
+	for i, override := range cfg.LowBattery.Thresholds {
+		if !strings.HasSuffix(override.Match, "*") {
+			c.checkEntity(file, fmt.Sprintf("low_battery.thresholds[%d].match", i), override.Match)
+		}
+	}
+	for i, pattern := range cfg.LowBattery.Ignore {
+		if !strings.HasSuffix(pattern, "*") {
+			c.checkEntity(file, fmt.Sprintf("low_battery.ignore[%d]", i), pattern)
+		}
+	}
+}
+
+func (c *checker) checkSecurityConfig() {
+	const file = "security_config.yaml"
+	cfg, err := security.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, entityID := range cfg.Security.CameraPrivacy.PrivacySwitches {
+		c.checkEntity(file, fmt.Sprintf("security.camera_privacy.privacy_switches[%d]", i), entityID)
+	}
+	c.checkEntity(file, "security.drill.valve_relay", cfg.Security.Drill.ValveRelay)
+	for i, door := range cfg.Security.DoorHeldOpen.Doors {
+		c.checkEntity(file, fmt.Sprintf("security.door_held_open.doors[%d].entity_id", i), door.EntityID)
+		for j, light := range door.Lights {
+			c.checkEntity(file, fmt.Sprintf("security.door_held_open.doors[%d].lights[%d]", i, j), light)
+		}
+	}
+	for i, camera := range cfg.Security.PersonDetection.Cameras {
+		c.checkEntity(file, fmt.Sprintf("security.person_detection.cameras[%d].sensor", i), camera.Sensor)
+		c.checkEntity(file, fmt.Sprintf("security.person_detection.cameras[%d].camera_entity", i), camera.CameraEntity)
+	}
+	c.checkEntity(file, "security.alarm.mode_entity", cfg.Security.Alarm.ModeEntity)
+	c.checkEntity(file, "security.alarm.siren", cfg.Security.Alarm.Siren)
+	for i, sensor := range cfg.Security.Alarm.Sensors {
+		c.checkEntity(file, fmt.Sprintf("security.alarm.sensors[%d].entity_id", i), sensor.EntityID)
+	}
+	for i, door := range cfg.Security.DoorLocks.Doors {
+		c.checkEntity(file, fmt.Sprintf("security.door_locks.doors[%d].lock", i), door.Lock)
+		c.checkEntity(file, fmt.Sprintf("security.door_locks.doors[%d].door_sensor", i), door.DoorSensor)
+	}
+}
+
+func (c *checker) checkDoNotDisturbConfig() {
+	const file = "do_not_disturb_config.yaml"
+	// Optional: do-not-disturb is disabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := donotdisturb.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, bedroom := range cfg.DoNotDisturb.Bedrooms {
+		for j, entityID := range bedroom.Entities {
+			c.checkEntity(file, fmt.Sprintf("do_not_disturb.bedrooms[%d].entities[%d]", i, j), entityID)
+		}
+	}
+}
+
+func (c *checker) checkNotificationRouterConfig() {
+	const file = "notification_router_config.yaml"
+	// Optional: announcements are always spoken when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	if _, err := notifyrouter.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkTTSConfig() {
+	const file = "tts_config.yaml"
+	// Optional: announcements use the default speakers when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := tts.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	s := cfg.TTS
+	c.checkEntity(file, "tts.tts_entity", s.TTSEntity)
+	for _, target := range sortedKeys(s.Speakers) {
+		for i, entityID := range s.Speakers[target] {
+			if entitygroups.IsReference(entityID) {
+				continue
+			}
+			c.checkEntity(file, fmt.Sprintf("tts.speakers.%s[%d]", target, i), entityID)
+		}
+	}
+}
+
+func (c *checker) checkFocusModeConfig() {
+	const file = "focus_mode_config.yaml"
+	// Optional: focus mode is disabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := focusmode.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	s := cfg.FocusMode
+	c.checkEntity(file, "focus_mode.calendar_entity", s.CalendarEntity)
+	c.checkEntity(file, "focus_mode.scene", s.Scene)
+	for i, entityID := range s.Speakers {
+		c.checkEntity(file, fmt.Sprintf("focus_mode.speakers[%d]", i), entityID)
+	}
+	for i, entityID := range s.Lights {
+		c.checkEntity(file, fmt.Sprintf("focus_mode.lights[%d]", i), entityID)
+	}
+}
+
+func (c *checker) checkKidModeConfig() {
+	const file = "kid_mode_config.yaml"
+	// Optional: kid mode is disabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := kidmode.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, room := range cfg.KidMode.Rooms {
+		for j, entityID := range room.Speakers {
+			c.checkEntity(file, fmt.Sprintf("kid_mode.rooms[%d].speakers[%d]", i, j), entityID)
+		}
+		for j, entityID := range room.Lights {
+			c.checkEntity(file, fmt.Sprintf("kid_mode.rooms[%d].lights[%d]", i, j), entityID)
+		}
+		for j, entityID := range room.Thermostats {
+			c.checkEntity(file, fmt.Sprintf("kid_mode.rooms[%d].thermostats[%d]", i, j), entityID)
+		}
+	}
+}
+
+func (c *checker) checkHotWaterConfig() {
+	const file = "hot_water_config.yaml"
+	// Optional: recirculation pump scheduling is disabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := hotwater.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	s := cfg.HotWater
+	c.checkEntity(file, "hot_water.pump_entity", s.PumpEntity)
+	c.checkEntity(file, "hot_water.flow_sensor", s.FlowSensor)
+	c.checkEntity(file, "hot_water.temperature_sensor", s.TemperatureSensor)
+}
+
+func (c *checker) checkRulesConfig() {
+	const file = "rules_config.yaml"
+	// Optional: the rules engine is disabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := rules.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for _, rule := range cfg.Rules {
+		for i, trigger := range rule.Triggers {
+			if trigger.Entity != "" {
+				c.checkEntity(file, fmt.Sprintf("rules.%s.triggers[%d].entity", rule.Name, i), trigger.Entity)
+			}
+		}
+		for i, action := range rule.Actions {
+			field := fmt.Sprintf("rules.%s.actions[%d].data.entity_id", rule.Name, i)
+			switch entityID := action.Data["entity_id"].(type) {
+			case string:
+				c.checkEntity(file, field, entityID)
+			case []interface{}:
+				for j, id := range entityID {
+					if s, ok := id.(string); ok {
+						c.checkEntity(file, fmt.Sprintf("%s[%d]", field, j), s)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c *checker) checkEntityGroupsConfig() {
+	const file = "entity_groups_config.yaml"
+	cfg, err := entitygroups.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for _, name := range sortedKeys(cfg.EntityGroups) {
+		for i, entityID := range cfg.EntityGroups[name] {
+			// Area references resolve from the area registry at runtime
+			if entitygroups.IsReference(entityID) {
+				continue
+			}
+			c.checkEntity(file, fmt.Sprintf("entity_groups.%s[%d]", name, i), entityID)
+		}
+	}
+}
+
+func (c *checker) checkAdaptiveWakeConfig() {
+	const file = "adaptive_wake_config.yaml"
+	// Optional: the alarm follows the schedule when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := sleephygiene.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, person := range cfg.AdaptiveWake.People {
+		c.checkEntity(file, fmt.Sprintf("adaptive_wake.people[%d].calendar_entity", i), person.CalendarEntity)
+	}
+}
+
+func (c *checker) checkWakeLightConfig() {
+	const file = "wake_light_config.yaml"
+	// Optional: the wake light uses the default ramp when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := sleephygiene.LoadWakeLightConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	c.checkEntity(file, "wake_light.lux_entity", cfg.WakeLight.LuxEntity)
+	c.checkEntity(file, "wake_light.weather_entity", cfg.WakeLight.WeatherEntity)
+}
+
+func (c *checker) checkSonosAlarmConfig() {
+	const file = "sonos_alarm_config.yaml"
+	// Optional: no backup alarm is kept on Sonos when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := sleephygiene.LoadSonosAlarmConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	c.checkEntity(file, "sonos_alarm.speaker", cfg.SonosAlarm.Speaker)
+	c.checkEntity(file, "sonos_alarm.alarm_entity", cfg.Entity())
+}
+
+func (c *checker) checkConsistencyCheckConfig() {
+	const file = "consistency_check_config.yaml"
+	// Optional: the nightly consistency check is disabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	if _, err := statetracking.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkWinddownTemperatureConfig() {
+	const file = "winddown_temperature_config.yaml"
+	// Optional: evening phases are not shifted when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := dayphaseplugin.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	c.checkEntity(file, "winddown_temperature.outdoor_temperature_sensor", cfg.WinddownTemperature.OutdoorTemperatureSensor)
+}
+
+func (c *checker) checkNamespaceConfig() {
+	const file = "namespace_config.yaml"
+	// Optional: no namespaces when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := namespace.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	for i, ns := range cfg.Namespaces {
+		for j, key := range ns.Variables {
+			variable := state.NamespacedVariable(ns.Name, c.variables[key])
+			c.checkEntity(file, fmt.Sprintf("namespaces[%d].variables[%d]", i, j), variable.EntityID)
+		}
+
+		// Plugin configs live in the namespace's config directory
+		dir := ns.ConfigPath("")
+		if ns.HasPlugin(namespace.PluginLighting) {
+			c.checkHueConfigFile(filepath.Join(dir, "hue_config.yaml"))
+		}
+		if ns.HasPlugin(namespace.PluginMusic) {
+			c.checkMusicConfigFile(filepath.Join(dir, "music_config.yaml"))
+		}
+	}
+}
+
+func (c *checker) checkMQTTConfig() {
+	const file = "mqtt_config.yaml"
+	// Optional: no MQTT bridge when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// Validation checks the broker URL, variables and topics
+	if _, err := mqtt.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkSolarForecastConfig() {
+	const file = "solar_forecast_config.yaml"
+	// Optional: no solar forecast when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// Validation checks the provider and its settings
+	if _, err := forecast.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkWarmupConfig() {
+	const file = "warmup_config.yaml"
+	// Optional: plugins act immediately after startup when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// Validation checks the windows and plugin names
+	if _, err := warmup.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkPluginsConfig() {
+	const file = "plugins_config.yaml"
+	// Optional: every plugin is enabled when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	if _, err := lifecycle.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkWriteScopesConfig() {
+	const file = "write_scopes_config.yaml"
+	// Optional: READ_ONLY alone decides when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// Validation checks the scope values and plugin names
+	if _, err := writescope.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkRateLimitsConfig() {
+	const file = "rate_limits_config.yaml"
+	// Optional: service calls aren't limited when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// Validation checks the limits, domains and plugin names
+	if _, err := ratelimit.LoadConfig(c.path(file)); err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+	}
+}
+
+func (c *checker) checkEntitiesConfig() {
+	const file = "entities_config.yaml"
+	// Optional: every entity has its default ID when the file is missing
+	if _, err := os.Stat(filepath.Join(c.configDir, file)); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	cfg, err := entities.LoadConfig(c.path(file))
+	if err != nil {
+		c.addError(file, "", "failed to load: %v", err)
+		return
+	}
+
+	// Startup refuses to run without every entity, named here or defaulted
+	registry := entities.NewRegistry(cfg)
+	for _, name := range entities.Names() {
+		c.checkEntity(file, "entities."+string(name), registry.ID(name))
+	}
+}
+
+// sortedKeys returns the keys of a map in sorted order so findings are deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,658 @@
+package configcheck
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const productionConfigDir = "../../../configs"
+
+// copyProductionConfigs copies the production config files into a temp directory
+func copyProductionConfigs(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	entries, err := os.ReadDir(productionConfigDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(productionConfigDir, entry.Name()))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644))
+	}
+	return dir
+}
+
+func writeConfig(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func findingFor(result *Result, file, field string) *Finding {
+	for i := range result.Findings {
+		if result.Findings[i].File == file && result.Findings[i].Field == field {
+			return &result.Findings[i]
+		}
+	}
+	return nil
+}
+
+func TestValidate_ProductionConfigs(t *testing.T) {
+	result := Validate(productionConfigDir, nil)
+
+	assert.True(t, result.Valid, "production configs should be valid: %+v", result.Findings)
+	assert.True(t, result.EntityCheckSkipped)
+	assert.Len(t, result.FilesChecked, 36)
+}
+
+func TestValidate_MissingFile(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	require.NoError(t, os.Remove(filepath.Join(dir, "energy_config.yaml")))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "energy_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Equal(t, SeverityError, finding.Severity)
+}
+
+func TestValidate_OptionalDoNotDisturbConfig(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	require.NoError(t, os.Remove(filepath.Join(dir, "do_not_disturb_config.yaml")))
+
+	result := Validate(dir, nil)
+
+	assert.True(t, result.Valid, "missing do-not-disturb config should be allowed: %+v", result.Findings)
+	assert.NotContains(t, result.FilesChecked, "do_not_disturb_config.yaml")
+}
+
+func TestValidate_DoNotDisturbVariable(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "do_not_disturb_config.yaml", `
+do_not_disturb:
+  bedrooms:
+    - name: primary
+      variable: dayPhase
+      entities: [media_player.bedroom]
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "do_not_disturb_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "must be a boolean")
+}
+
+func TestValidate_EntityGroupsRequired(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "entity_groups_config.yaml", `
+entity_groups:
+  common_speakers: [media_player.kitchen]
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "entity_groups_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "is required")
+}
+
+func TestValidate_AdaptiveWakeFloorTime(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "adaptive_wake_config.yaml", `
+adaptive_wake:
+  enabled: true
+  preparation_buffer_minutes: 60
+  floor_time: "6am"
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "adaptive_wake_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "floor_time")
+}
+
+func TestValidate_SonosAlarmVolume(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "sonos_alarm_config.yaml", `
+sonos_alarm:
+  enabled: true
+  speaker: media_player.bedroom
+  alarm_id: 1
+  volume: 40
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "sonos_alarm_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "volume")
+}
+
+func TestValidate_WakeLightProfileNeedsEntity(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "wake_light_config.yaml", `
+wake_light:
+  enabled: true
+  default:
+    final_brightness_pct: 80
+    transition_minutes: 30
+  profiles:
+    - name: dark
+      max_lux: 400
+      final_brightness_pct: 100
+      transition_minutes: 40
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "wake_light_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "lux_entity")
+}
+
+func TestValidate_TTSUnknownTarget(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "tts_config.yaml", `
+tts:
+  speakers:
+    doorbell:
+      - media_player.kitchen
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "tts_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "unknown target")
+}
+
+func TestValidate_ConsistencyCheckNotifyService(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "consistency_check_config.yaml", `
+consistency_check:
+  time: "04:00"
+  notify_service: "mobile_app_phone"
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "consistency_check_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "notify_service")
+}
+
+func TestValidate_WinddownTemperatureCurveOrder(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "winddown_temperature_config.yaml", `
+winddown_temperature:
+  outdoor_temperature_sensor: "sensor.weather_station_temperature"
+  winddown_curve:
+    - temperature: 40
+      offset_minutes: 0
+    - temperature: 20
+      offset_minutes: -45
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "winddown_temperature_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "strictly increasing")
+}
+
+func TestValidate_FreeEnergyAnnouncementQuietHours(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	data, err := os.ReadFile(filepath.Join(dir, "energy_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "energy_config.yaml", strings.Replace(string(data), `start: "22:30"`, `start: "late"`, 1))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "energy_config.yaml", "free_energy_announcements.quiet_hours.start"))
+}
+
+func TestValidate_UnknownStateVariable(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", `---
+rooms:
+  - hue_group: Living Room
+    hass_area_id: living_room
+    on_if_true: isAnyoneHomeAndAwake
+    off_if_true: isEveryoneAsleeep
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "hue_config.yaml", "rooms[0].off_if_true")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "isEveryoneAsleeep")
+	assert.Nil(t, findingFor(result, "hue_config.yaml", "rooms[0].on_if_true"))
+}
+
+func TestValidate_OccupancyHeatmapRooms(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "report_config.yaml", `---
+weekly_report:
+  day: sunday
+  time: "18:00"
+occupancy_heatmap:
+  rooms:
+    kitchen: isKitchenOccupied
+    hallway: isHallwayOccupied
+    living_room: dayPhase
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.Nil(t, findingFor(result, "report_config.yaml", "occupancy_heatmap.rooms.kitchen"))
+	unknown := findingFor(result, "report_config.yaml", "occupancy_heatmap.rooms.hallway")
+	require.NotNil(t, unknown)
+	assert.Contains(t, unknown.Message, "isHallwayOccupied")
+	notBool := findingFor(result, "report_config.yaml", "occupancy_heatmap.rooms.living_room")
+	require.NotNil(t, notBool)
+	assert.Contains(t, notBool.Message, "not a boolean")
+}
+
+func TestValidate_ScheduleTimes(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "schedule_config.yaml", `schedule:
+- begin_wake: "08:50"
+  wake: "9am"
+  dusk: "20:00"
+  winddown: "22:15"
+  stop_screens: "22:30"
+  go_to_bed: "23:30"
+  night: "23:00"
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "schedule_config.yaml", "schedule"), "should require one entry per weekday")
+	assert.NotNil(t, findingFor(result, "schedule_config.yaml", "schedule[0].wake"))
+}
+
+func TestValidate_SceneSchedules(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	schedule, err := os.ReadFile(filepath.Join(dir, "schedule_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "schedule_config.yaml", strings.Replace(string(schedule), "sun: sunset", "sun: moonrise", 1))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "schedule_config.yaml", "scene_schedules")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "moonrise")
+}
+
+func TestValidate_SleepFanSharedWithBedroomComfort(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	comfort, err := os.ReadFile(filepath.Join(dir, "bedroom_comfort_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "bedroom_comfort_config.yaml", strings.Replace(string(comfort), `fan_entity: ""`, "fan_entity: fan.primary_suite_ceiling_fan", 1))
+
+	result := Validate(dir, nil)
+
+	assert.True(t, result.Valid)
+	finding := findingFor(result, "sleep_fan_config.yaml", "sleep_fan.bedrooms[0].fan_entity")
+	require.NotNil(t, finding)
+	assert.Equal(t, SeverityWarning, finding.Severity)
+}
+
+func TestValidate_FreezeProtectionUnknownEnergyLevel(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	freeze, err := os.ReadFile(filepath.Join(dir, "freeze_protection_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "freeze_protection_config.yaml", strings.Replace(string(freeze), "        red:", "        crimson:", 1))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "freeze_protection_config.yaml", "freeze_protection.spaces[0].energy_limits.crimson")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "crimson")
+}
+
+func TestValidate_BackupReserve(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	energyConfig, err := os.ReadFile(filepath.Join(dir, "energy_config.yaml"))
+	require.NoError(t, err)
+	modified := strings.Replace(string(energyConfig), "select.inverter_operating_mode", "switch.inverter_backup", 1)
+	modified = strings.Replace(modified, `normal_mode: "Self-Consumption"`, `normal_mode: "Backup"`, 1)
+	writeConfig(t, dir, "energy_config.yaml", modified)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "energy_config.yaml", "backup_reserve.inverter_mode_entity"))
+	assert.NotNil(t, findingFor(result, "energy_config.yaml", "backup_reserve.backup_mode"))
+}
+
+func TestValidate_RateSchedulePriceSensor(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	energyConfig, err := os.ReadFile(filepath.Join(dir, "energy_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "energy_config.yaml", strings.Replace(string(energyConfig), "  # price_sensor: sensor.electricity_price\n", "  price_sensor: sensor.electricity_price\n", 1))
+
+	result := Validate(dir, EntitySet{})
+
+	finding := findingFor(result, "energy_config.yaml", "rate_schedule.price_sensor")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "sensor.electricity_price not found")
+}
+
+func TestValidate_ReservePolicyStormWatchVariable(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	energyConfig, err := os.ReadFile(filepath.Join(dir, "energy_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "energy_config.yaml", strings.Replace(string(energyConfig), "storm_watch_variable: isStormWatch", "storm_watch_variable: currentEnergyLevel", 1))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "energy_config.yaml", "reserve_policy.storm_watch_variable")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "not a boolean")
+}
+
+func TestValidate_LoadSheddingFreezeHeater(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	loadShedding, err := os.ReadFile(filepath.Join(dir, "loadshedding_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "loadshedding_config.yaml", strings.Replace(string(loadShedding), "switch.ev_charger", "switch.garage_heater", 1))
+
+	result := Validate(dir, nil)
+
+	finding := findingFor(result, "loadshedding_config.yaml", "load_shedding.devices[1].entity_id")
+	require.NotNil(t, finding)
+	assert.Equal(t, SeverityWarning, finding.Severity)
+	assert.Contains(t, finding.Message, "freeze protection heater")
+}
+
+func TestValidate_ClimateDayPhaseAndComfortThermostat(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	climateConfig, err := os.ReadFile(filepath.Join(dir, "climate_config.yaml"))
+	require.NoError(t, err)
+	climateConfig = []byte(strings.Replace(string(climateConfig), "winddown:", "bedtime:", 1))
+	climateConfig = []byte(strings.Replace(string(climateConfig), "climate.most_of_house_thermostat", "climate.primary_suite_thermostat", 1))
+	writeConfig(t, dir, "climate_config.yaml", string(climateConfig))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "climate_config.yaml", "climate.thermostats[0].day_phases.bedtime")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, `unknown day phase "bedtime"`)
+
+	finding = findingFor(result, "climate_config.yaml", "climate.thermostats[0].entity_id")
+	require.NotNil(t, finding)
+	assert.Equal(t, SeverityWarning, finding.Severity)
+	assert.Contains(t, finding.Message, "bedroom_comfort.climate_entity")
+}
+
+func TestValidate_LevelAnnouncementUnknownLevel(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	energyConfig, err := os.ReadFile(filepath.Join(dir, "energy_config.yaml"))
+	require.NoError(t, err)
+	writeConfig(t, dir, "energy_config.yaml", strings.Replace(string(energyConfig), "      to: black\n", "      to: low\n", 1))
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "energy_config.yaml", "level_announcements.transitions[1].to")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, `unknown energy level "low"`)
+}
+
+func TestValidate_WarmupUnknownPlugin(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "warmup_config.yaml", "warmup:\n  default_seconds: 30\n  plugins:\n    lights: 60\n")
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "warmup_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, `unknown plugin "lights"`)
+}
+
+func TestValidate_WriteScopesBadScope(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "write_scopes_config.yaml", "write_scopes:\n  default: read_write\n  plugins:\n    lighting: write\n")
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	finding := findingFor(result, "write_scopes_config.yaml", "")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "lighting must be read_only or read_write")
+}
+
+func TestValidate_EnergyRanges(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "energy_config.yaml", `---
+energy:
+  free_energy_time:
+    start: "21:00"
+    end: "07:00"
+  energy_states:
+    - condition_name: black
+      battery_minimum_percentage: 0
+      light_config:
+        red: 300
+        green: 0
+        blue: 0
+        brightness_pct: 50
+    - condition_name: black
+      battery_minimum_percentage: 40
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "energy_config.yaml", "energy.energy_states[0].light_config.red"))
+	assert.NotNil(t, findingFor(result, "energy_config.yaml", "energy.energy_states[1].condition_name"))
+}
+
+func TestValidate_EntityCrossCheck(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "growlight_config.yaml", `---
+grow_lights:
+  default_photoperiod_hours: 14
+  fixtures:
+    - name: Herbs
+      entity_id: switch.herbs
+      earliest_on: "06:00"
+      latest_off: "21:00"
+    - name: Seedlings
+      entity_id: switch.seedlings
+      earliest_on: "06:00"
+      latest_off: "21:00"
+`)
+
+	result := Validate(dir, EntitySet{"switch.herbs": true, "media_player.kitchen": true})
+
+	assert.False(t, result.Valid)
+	assert.False(t, result.EntityCheckSkipped)
+	assert.Greater(t, result.EntitiesChecked, 2)
+	assert.Nil(t, findingFor(result, "growlight_config.yaml", "grow_lights.fixtures[0].entity_id"))
+	finding := findingFor(result, "growlight_config.yaml", "grow_lights.fixtures[1].entity_id")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "switch.seedlings")
+
+	// Speakers are resolved to media_player entities
+	assert.Nil(t, findingFor(result, "music_config.yaml", "music.morning.participants[0].player_name"))
+	assert.NotNil(t, findingFor(result, "music_config.yaml", "music.morning.participants[1].player_name"))
+}
+
+func TestValidate_WarningsDoNotInvalidate(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", "---\nrooms: []\n")
+
+	result := Validate(dir, nil)
+
+	assert.True(t, result.Valid)
+	finding := findingFor(result, "hue_config.yaml", "rooms")
+	require.NotNil(t, finding)
+	assert.Equal(t, SeverityWarning, finding.Severity)
+}
+
+func TestValidate_HueOnBudget(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", `---
+on_budget_notify_service: mobile_app_phone
+rooms:
+  - hue_group: Closet
+    hass_area_id: closet
+    on_budget:
+      daily_minutes: 0
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "rooms[0].on_budget.daily_minutes"))
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "on_budget_notify_service"))
+}
+
+func TestValidate_HueGridOutage(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", `---
+grid_outage:
+  brightness_cap_pct: 150
+  day_phases: [night, midnight]
+rooms:
+  - hue_group: Porch
+    hass_area_id: porch
+    decorative: true
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "grid_outage.brightness_cap_pct"))
+	assert.Nil(t, findingFor(result, "hue_config.yaml", "grid_outage.day_phases[0]"))
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "grid_outage.day_phases[1]"))
+}
+
+func TestValidate_HueDayPhaseTransitions(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", `---
+day_phase_transitions:
+  winddown: {minutes: 10}
+rooms:
+  - hue_group: Porch
+    hass_area_id: porch
+    day_phase_transitions:
+      bedtime: {minutes: 5}
+`)
+
+	result := Validate(dir, nil)
+
+	assert.False(t, result.Valid)
+	assert.Nil(t, findingFor(result, "hue_config.yaml", "day_phase_transitions.winddown"))
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "rooms[0].day_phase_transitions.bedtime"))
+}
+
+func TestValidate_HueAdaptiveBrightness(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", `---
+rooms:
+  - hue_group: Porch
+    hass_area_id: porch
+    adaptive_brightness:
+      lux_entity: sensor.porch_illuminance
+`)
+
+	result := Validate(dir, EntitySet{"sensor.living_room_illuminance": true})
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "rooms[0].adaptive_brightness.lux_entity"))
+}
+
+func TestValidate_HueMotion(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "hue_config.yaml", `---
+rooms:
+  - hue_group: Hallway
+    hass_area_id: hallway
+    motion:
+      sensors: [binary_sensor.hallway_motion]
+      ignore_if_true: isEveryoneAsleeep
+`)
+
+	result := Validate(dir, EntitySet{})
+
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "rooms[0].motion.sensors[0]"))
+	assert.NotNil(t, findingFor(result, "hue_config.yaml", "rooms[0].motion.ignore_if_true"))
+}
+
+func TestValidate_NamespaceConfig(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "namespace_config.yaml", `---
+namespaces:
+  - name: suite
+    variables: [isAnyoneHome]
+    plugins: [lighting]
+`)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "suite"), 0755))
+	writeConfig(t, dir, filepath.Join("suite", "hue_config.yaml"), `---
+rooms:
+  - hue_group: Suite
+    hass_area_id: suite
+    on_if_true: isAnyoneHome
+    off_if_false: isSuiteHome
+`)
+
+	result := Validate(dir, EntitySet{"input_boolean.anyone_home": true})
+
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.FilesChecked, filepath.Join("suite", "hue_config.yaml"))
+	assert.NotNil(t, findingFor(result, filepath.Join("suite", "hue_config.yaml"), "rooms[0].off_if_false"))
+	assert.NotNil(t, findingFor(result, "namespace_config.yaml", "namespaces[0].variables[0]"), "suite entity should be checked")
+}
+
+func TestValidate_EntitiesConfig(t *testing.T) {
+	dir := copyProductionConfigs(t)
+	writeConfig(t, dir, "entities_config.yaml", `---
+entities:
+  master_bedroom_light: light.bedroom_ceiling
+`)
+
+	result := Validate(dir, EntitySet{"light.primary_suite": true})
+
+	assert.False(t, result.Valid)
+	assert.Nil(t, findingFor(result, "entities_config.yaml", "entities.primary_suite_light"))
+	finding := findingFor(result, "entities_config.yaml", "entities.master_bedroom_light")
+	require.NotNil(t, finding)
+	assert.Contains(t, finding.Message, "light.bedroom_ceiling")
+	assert.NotNil(t, findingFor(result, "entities_config.yaml", "entities.doorbell"), "defaulted names are checked too")
+
+	writeConfig(t, dir, "entities_config.yaml", "---\nentities:\n  bedroom_speaker: light.bedroom\n")
+	result = Validate(dir, nil)
+	assert.False(t, result.Valid)
+	assert.NotNil(t, findingFor(result, "entities_config.yaml", ""))
+}
@@ -0,0 +1,66 @@
+package configcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"homeautomation/internal/ha"
+)
+
+// EntitySet is a set of known Home Assistant entity IDs
+type EntitySet map[string]bool
+
+// LoadEntityCache loads a cached entity list from a JSON file. The file may be
+// either a plain array of entity IDs (as written by WriteEntityCache) or the raw
+// output of Home Assistant's /api/states endpoint.
+func LoadEntityCache(path string) (EntitySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity cache: %w", err)
+	}
+
+	entities := make(EntitySet)
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err == nil {
+		for _, id := range ids {
+			entities[id] = true
+		}
+		return entities, nil
+	}
+
+	var states []ha.State
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse entity cache: expected an array of entity IDs or HA states: %w", err)
+	}
+	for _, s := range states {
+		if s.EntityID != "" {
+			entities[s.EntityID] = true
+		}
+	}
+
+	return entities, nil
+}
+
+// WriteEntityCache writes the entity IDs of the given states to path as a sorted JSON array
+func WriteEntityCache(path string, states []*ha.State) error {
+	ids := make([]string, 0, len(states))
+	for _, s := range states {
+		if s != nil && s.EntityID != "" {
+			ids = append(ids, s.EntityID)
+		}
+	}
+	sort.Strings(ids)
+
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode entity cache: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write entity cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package configcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityCache_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entities.json")
+
+	states := []*ha.State{
+		{EntityID: "switch.b"},
+		{EntityID: "light.a"},
+		nil,
+	}
+	require.NoError(t, WriteEntityCache(path, states))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[\n  \"light.a\",\n  \"switch.b\"\n]\n", string(data))
+
+	entities, err := LoadEntityCache(path)
+	require.NoError(t, err)
+	assert.Equal(t, EntitySet{"light.a": true, "switch.b": true}, entities)
+}
+
+func TestLoadEntityCache_HAStatesDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "states.json")
+	content := `[
+  {"entity_id": "light.kitchen", "state": "on", "attributes": {}},
+  {"entity_id": "media_player.kitchen", "state": "idle", "attributes": {"volume_level": 0.2}}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	entities, err := LoadEntityCache(path)
+	require.NoError(t, err)
+	assert.Equal(t, EntitySet{"light.kitchen": true, "media_player.kitchen": true}, entities)
+}
+
+func TestLoadEntityCache_Errors(t *testing.T) {
+	_, err := LoadEntityCache("/nonexistent/entities.json")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"entity_id": "light.a"}`), 0644))
+	_, err = LoadEntityCache(path)
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"homeautomation/internal/clock"
 	"homeautomation/internal/config"
 
 	"github.com/sixdouglas/suncalc"
@@ -38,6 +39,8 @@ type Calculator struct {
 	latitude  float64
 	longitude float64
 	logger    *zap.Logger
+	timezone  *time.Location
+	clock     clock.Clock
 
 	// Cached sun times from suncalc (updated every 6 hours)
 	// These match Node-RED's suncalc exactly
@@ -52,14 +55,34 @@ func NewCalculator(latitude, longitude float64, logger *zap.Logger) *Calculator
 		latitude:  latitude,
 		longitude: longitude,
 		logger:    logger,
+		timezone:  time.Local,
+		clock:     clock.NewRealClock(),
 		sunTimes:  make(map[string]time.Time),
 	}
 }
 
+// SetTimezone sets the time zone the day phase's clock times (the 06:00
+// morning cutoff, the scheduled night time) are local to
+func (c *Calculator) SetTimezone(timezone *time.Location) {
+	if timezone != nil {
+		c.timezone = timezone
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (c *Calculator) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// now returns the current time in the calculator's time zone
+func (c *Calculator) now() time.Time {
+	return c.clock.Now().In(c.timezone)
+}
+
 // UpdateSunTimes calculates sun event times for today using suncalc
 // This uses the same algorithm as Node-RED's suncalc library
 func (c *Calculator) UpdateSunTimes() error {
-	now := time.Now()
+	now := c.now()
 
 	// Get sun times using suncalc - this matches Node-RED exactly
 	// The library uses the same sun angle calculations:
@@ -114,10 +137,10 @@ func (c *Calculator) UpdateSunTimes() error {
 //   - goldenHourEnd -> "day"
 //   - everything else -> "day"
 func (c *Calculator) GetSunEvent() SunEvent {
-	now := time.Now()
+	now := c.now()
 
 	// Ensure we have recent sun times
-	if c.lastUpdate.IsZero() || time.Since(c.lastUpdate) > 6*time.Hour {
+	if c.lastUpdate.IsZero() || c.clock.Since(c.lastUpdate) > 6*time.Hour {
 		c.UpdateSunTimes()
 	}
 
@@ -159,12 +182,33 @@ func (c *Calculator) GetSunTimes() map[string]time.Time {
 // CalculateDayPhase determines the current day phase based on sun event and schedule
 // This implements the logic from Node-RED's Configuration tab
 func (c *Calculator) CalculateDayPhase(schedule *config.ParsedSchedule) DayPhase {
+	return c.CalculateDayPhaseShifted(schedule, 0, 0)
+}
+
+// CalculateDayPhaseShifted is CalculateDayPhase with the evening boundaries
+// moved: winddownShift moves the start of winddown (astronomical night) and
+// nightShift moves the scheduled night time. Negative shifts are earlier.
+func (c *Calculator) CalculateDayPhaseShifted(schedule *config.ParsedSchedule, winddownShift, nightShift time.Duration) DayPhase {
 	sunEvent := c.GetSunEvent()
-	now := time.Now()
+	now := c.now()
 
 	c.logger.Debug("Calculating day phase",
 		zap.String("sun_event", string(sunEvent)),
-		zap.Time("now", now))
+		zap.Time("now", now),
+		zap.Duration("winddown_shift", winddownShift),
+		zap.Duration("night_shift", nightShift))
+
+	// Only the evening boundary moves; before goldenHour a night sun event is
+	// the pre-dawn night, which stays night
+	if winddownShift != 0 && !now.Before(c.sunTimes["goldenHour"]) {
+		winddownStart := c.sunTimes["night"].Add(winddownShift)
+		switch {
+		case sunEvent == SunEventNight && now.Before(winddownStart):
+			return DayPhaseDusk
+		case (sunEvent == SunEventSunset || sunEvent == SunEventDusk) && !now.Before(winddownStart):
+			sunEvent = SunEventNight
+		}
+	}
 
 	switch sunEvent {
 	case SunEventMorning:
@@ -182,13 +226,13 @@ func (c *Calculator) CalculateDayPhase(schedule *config.ParsedSchedule) DayPhase
 	case SunEventNight:
 		// Check if we're past the scheduled "night" time
 		if schedule != nil {
-			if now.After(schedule.Night) || now.Hour() < 6 {
+			if now.After(schedule.Night.Add(nightShift)) || now.Hour() < 6 {
 				return DayPhaseNight
 			}
 			return DayPhaseWinddown
 		}
 		// No schedule available, use simple logic
-		if now.Hour() >= 23 || now.Hour() < 6 {
+		if now.Add(-nightShift).Hour() >= 23 || now.Hour() < 6 {
 			return DayPhaseNight
 		}
 		return DayPhaseWinddown
@@ -208,12 +252,12 @@ func (c *Calculator) StartPeriodicUpdate() chan struct{} {
 	c.UpdateSunTimes()
 
 	go func() {
-		ticker := time.NewTicker(6 * time.Hour)
+		ticker := c.clock.NewTicker(6 * time.Hour)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-ticker.C:
+			case <-ticker.C():
 				c.logger.Debug("Periodic sun time update")
 				if err := c.UpdateSunTimes(); err != nil {
 					c.logger.Error("Failed to update sun times", zap.Error(err))
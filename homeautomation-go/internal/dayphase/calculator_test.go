@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"homeautomation/internal/clock"
 	"homeautomation/internal/config"
 
 	"github.com/stretchr/testify/assert"
@@ -487,6 +488,114 @@ func TestCalculator_CalculateDayPhaseEdgeCases(t *testing.T) {
 	assert.Equal(t, DayPhaseDay, phase)
 }
 
+func TestCalculator_CalculateDayPhaseShifted(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	now := time.Date(2026, time.June, 21, 21, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		night         time.Duration // astronomical night relative to now
+		scheduleNight time.Duration // scheduled night relative to now
+		winddownShift time.Duration
+		nightShift    time.Duration
+		expected      DayPhase
+	}{
+		{
+			name:          "cold night starts winddown during dusk",
+			night:         20 * time.Minute,
+			scheduleNight: 2 * time.Hour,
+			winddownShift: -30 * time.Minute,
+			expected:      DayPhaseWinddown,
+		},
+		{
+			name:          "hot evening stays in dusk after astronomical night",
+			night:         -10 * time.Minute,
+			scheduleNight: 2 * time.Hour,
+			winddownShift: 30 * time.Minute,
+			expected:      DayPhaseDusk,
+		},
+		{
+			name:          "hot evening reaches winddown after the shifted start",
+			night:         -40 * time.Minute,
+			scheduleNight: 2 * time.Hour,
+			winddownShift: 30 * time.Minute,
+			expected:      DayPhaseWinddown,
+		},
+		{
+			name:          "cold night starts night before the schedule",
+			night:         -1 * time.Hour,
+			scheduleNight: 20 * time.Minute,
+			nightShift:    -30 * time.Minute,
+			expected:      DayPhaseNight,
+		},
+		{
+			name:          "hot evening delays night past the schedule",
+			night:         -1 * time.Hour,
+			scheduleNight: -10 * time.Minute,
+			nightShift:    30 * time.Minute,
+			expected:      DayPhaseWinddown,
+		},
+		{
+			name:          "no shift matches the unshifted phase",
+			night:         20 * time.Minute,
+			scheduleNight: 2 * time.Hour,
+			expected:      DayPhaseDusk,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := NewCalculator(32.85486, -97.50515, logger)
+			calc.SetTimezone(time.UTC)
+			calc.SetClock(clock.NewMockClock(now))
+			night := now.Add(tt.night)
+			setSunTimesForTest(calc, now,
+				now.Add(-12*time.Hour),     // dawn
+				now.Add(-11*time.Hour),     // sunrise
+				now.Add(-11*time.Hour),     // sunriseEnd
+				now.Add(-10*time.Hour),     // goldenHourEnd
+				night.Add(-3*time.Hour),    // goldenHour
+				night.Add(-2*time.Hour),    // sunsetStart
+				night.Add(-90*time.Minute), // sunset
+				night.Add(-1*time.Hour),    // dusk
+				night.Add(-30*time.Minute), // nauticalDusk
+				night,                      // night
+			)
+			schedule := &config.ParsedSchedule{Night: now.Add(tt.scheduleNight)}
+
+			phase := calc.CalculateDayPhaseShifted(schedule, tt.winddownShift, tt.nightShift)
+			assert.Equal(t, tt.expected, phase)
+		})
+	}
+}
+
+func TestCalculator_CalculateDayPhaseShiftedBeforeDawn(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	// Shifts move the evening boundaries only; the pre-dawn night stays night
+	now := time.Date(2026, time.June, 21, 3, 0, 0, 0, time.UTC)
+	calc := NewCalculator(32.85486, -97.50515, logger)
+	calc.SetTimezone(time.UTC)
+	calc.SetClock(clock.NewMockClock(now))
+	setSunTimesForTest(calc, now,
+		now.Add(2*time.Hour),  // dawn
+		now.Add(3*time.Hour),  // sunrise
+		now.Add(3*time.Hour),  // sunriseEnd
+		now.Add(4*time.Hour),  // goldenHourEnd
+		now.Add(16*time.Hour), // goldenHour
+		now.Add(17*time.Hour), // sunsetStart
+		now.Add(17*time.Hour), // sunset
+		now.Add(18*time.Hour), // dusk
+		now.Add(18*time.Hour), // nauticalDusk
+		now.Add(19*time.Hour), // night
+	)
+	schedule := &config.ParsedSchedule{Night: now.Add(19 * time.Hour)}
+
+	assert.Equal(t, DayPhaseNight, calc.CalculateDayPhaseShifted(schedule, 30*time.Minute, 30*time.Minute))
+	assert.Equal(t, DayPhaseNight, calc.CalculateDayPhaseShifted(schedule, -30*time.Minute, -30*time.Minute))
+}
+
 func TestCalculator_AutoUpdateSunTimes(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	calc := NewCalculator(32.85486, -97.50515, logger)
@@ -0,0 +1,107 @@
+package donotdisturb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bedroom is a room whose do-not-disturb toggle suppresses automations
+// targeting its entities
+type Bedroom struct {
+	Name      string   `yaml:"name"`
+	Variable  string   `yaml:"variable"`   // Boolean state variable holding the toggle
+	ExpiresAt string   `yaml:"expires_at"` // Optional "HH:MM"; the toggle is cleared the next time this passes
+	Entities  []string `yaml:"entities"`   // Speakers, lights, etc. in the room
+}
+
+// Settings holds the do-not-disturb bedrooms
+type Settings struct {
+	Bedrooms []Bedroom `yaml:"bedrooms"`
+}
+
+// Config represents the do_not_disturb_config.yaml structure
+type Config struct {
+	DoNotDisturb Settings `yaml:"do_not_disturb"`
+}
+
+// ExpiryAfter returns the first time the bedroom's expires_at passes after
+// enabledAt, in enabledAt's location. It returns false if the bedroom has no
+// expiry and do-not-disturb stays on until cleared.
+func (b Bedroom) ExpiryAfter(enabledAt time.Time) (time.Time, bool) {
+	if b.ExpiresAt == "" {
+		return time.Time{}, false
+	}
+	at, err := time.Parse("15:04", b.ExpiresAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	expiry := time.Date(enabledAt.Year(), enabledAt.Month(), enabledAt.Day(),
+		at.Hour(), at.Minute(), 0, 0, enabledAt.Location())
+	if !expiry.After(enabledAt) {
+		expiry = expiry.AddDate(0, 0, 1)
+	}
+	return expiry, true
+}
+
+// Validate checks that every bedroom has a unique boolean variable and a valid expiry
+func (c *Config) Validate() error {
+	variables := state.VariablesByKey()
+	names := make(map[string]bool)
+
+	for i, b := range c.DoNotDisturb.Bedrooms {
+		if b.Name == "" {
+			return fmt.Errorf("bedrooms[%d]: name is required", i)
+		}
+		if names[b.Name] {
+			return fmt.Errorf("bedrooms[%d]: duplicate bedroom %q", i, b.Name)
+		}
+		names[b.Name] = true
+
+		variable, ok := variables[b.Variable]
+		if !ok {
+			return fmt.Errorf("bedroom %q: unknown state variable %q", b.Name, b.Variable)
+		}
+		if variable.Type != state.TypeBool {
+			return fmt.Errorf("bedroom %q: variable %q must be a boolean", b.Name, b.Variable)
+		}
+
+		if b.ExpiresAt != "" {
+			if _, err := time.Parse("15:04", b.ExpiresAt); err != nil {
+				return fmt.Errorf("bedroom %q: invalid expires_at %q, expected HH:MM", b.Name, b.ExpiresAt)
+			}
+		}
+
+		for _, entityID := range b.Entities {
+			if !strings.Contains(entityID, ".") {
+				return fmt.Errorf("bedroom %q: invalid entity ID %q", b.Name, entityID)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the do-not-disturb configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,95 @@
+package donotdisturb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../configs/do_not_disturb_config.yaml")
+	require.NoError(t, err)
+
+	require.NotEmpty(t, config.DoNotDisturb.Bedrooms)
+	primary := config.DoNotDisturb.Bedrooms[0]
+	assert.Equal(t, "primary", primary.Name)
+	assert.Equal(t, "isPrimaryBedroomDoNotDisturb", primary.Variable)
+	assert.Contains(t, primary.Entities, "media_player.bedroom")
+}
+
+func TestValidate(t *testing.T) {
+	valid := Bedroom{Name: "primary", Variable: "isPrimaryBedroomDoNotDisturb", ExpiresAt: "10:00", Entities: []string{"media_player.bedroom"}}
+
+	tests := []struct {
+		name     string
+		bedrooms []Bedroom
+		wantErr  string
+	}{
+		{"valid", []Bedroom{valid}, ""},
+		{"no expiry", []Bedroom{{Name: "guest", Variable: "isGuestBedroomDoNotDisturb"}}, ""},
+		{"missing name", []Bedroom{{Variable: "isPrimaryBedroomDoNotDisturb"}}, "name is required"},
+		{"duplicate name", []Bedroom{valid, valid}, "duplicate bedroom"},
+		{"unknown variable", []Bedroom{{Name: "primary", Variable: "isNotAVariable"}}, "unknown state variable"},
+		{"non-boolean variable", []Bedroom{{Name: "primary", Variable: "dayPhase"}}, "must be a boolean"},
+		{"invalid expiry", []Bedroom{{Name: "primary", Variable: "isPrimaryBedroomDoNotDisturb", ExpiresAt: "10am"}}, "invalid expires_at"},
+		{"invalid entity", []Bedroom{{Name: "primary", Variable: "isPrimaryBedroomDoNotDisturb", Entities: []string{"bedroom"}}}, "invalid entity ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{DoNotDisturb: Settings{Bedrooms: tt.bedrooms}}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "do_not_disturb_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+do_not_disturb:
+  bedrooms:
+    - name: primary
+      variable: isPrimaryBedroomDoNotDisturb
+      expires_at: "25:00"
+`), 0644))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestExpiryAfter(t *testing.T) {
+	bedroom := Bedroom{Name: "primary", ExpiresAt: "10:00"}
+
+	t.Run("later the same day", func(t *testing.T) {
+		expiry, ok := bedroom.ExpiryAfter(time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC))
+		require.True(t, ok)
+		assert.Equal(t, time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC), expiry)
+	})
+
+	t.Run("enabled after expiry time rolls to the next day", func(t *testing.T) {
+		expiry, ok := bedroom.ExpiryAfter(time.Date(2025, 1, 6, 22, 30, 0, 0, time.UTC))
+		require.True(t, ok)
+		assert.Equal(t, time.Date(2025, 1, 7, 10, 0, 0, 0, time.UTC), expiry)
+	})
+
+	t.Run("enabled exactly at expiry time rolls to the next day", func(t *testing.T) {
+		expiry, ok := bedroom.ExpiryAfter(time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC))
+		require.True(t, ok)
+		assert.Equal(t, time.Date(2025, 1, 7, 10, 0, 0, 0, time.UTC), expiry)
+	})
+
+	t.Run("no expiry", func(t *testing.T) {
+		_, ok := Bedroom{Name: "guest"}.ExpiryAfter(time.Now())
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,76 @@
+// Package donotdisturb implements hotel-style "do not disturb" toggles per
+// bedroom. While a bedroom's toggle is on, automations skip any action that
+// targets the bedroom's entities (wake sequences, music, reminders, and TTS).
+package donotdisturb
+
+import (
+	"homeautomation/internal/state"
+)
+
+// Guard reports which entities are in bedrooms with do-not-disturb on.
+// All methods are safe to call on a nil Guard, which suppresses nothing.
+type Guard struct {
+	config       *Config
+	stateManager *state.Manager
+	byEntity     map[string][]Bedroom
+}
+
+// NewGuard creates a guard reading the bedroom toggles from the state manager
+func NewGuard(config *Config, stateManager *state.Manager) *Guard {
+	byEntity := make(map[string][]Bedroom)
+	for _, b := range config.DoNotDisturb.Bedrooms {
+		for _, entityID := range b.Entities {
+			byEntity[entityID] = append(byEntity[entityID], b)
+		}
+	}
+
+	return &Guard{
+		config:       config,
+		stateManager: stateManager,
+		byEntity:     byEntity,
+	}
+}
+
+// Bedrooms returns the configured bedrooms
+func (g *Guard) Bedrooms() []Bedroom {
+	if g == nil {
+		return nil
+	}
+	return g.config.DoNotDisturb.Bedrooms
+}
+
+// IsActive reports whether the bedroom's toggle is on
+func (g *Guard) IsActive(b Bedroom) bool {
+	if g == nil {
+		return false
+	}
+	active, err := g.stateManager.GetBool(b.Variable)
+	return err == nil && active
+}
+
+// Suppresses reports whether the entity is in a bedroom with do-not-disturb
+// on, returning that bedroom's name
+func (g *Guard) Suppresses(entityID string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	for _, b := range g.byEntity[entityID] {
+		if g.IsActive(b) {
+			return b.Name, true
+		}
+	}
+	return "", false
+}
+
+// Filter splits entity IDs into those that may be targeted and those that
+// are suppressed by do-not-disturb, preserving order
+func (g *Guard) Filter(entityIDs []string) (allowed []string, suppressed []string) {
+	for _, entityID := range entityIDs {
+		if _, ok := g.Suppresses(entityID); ok {
+			suppressed = append(suppressed, entityID)
+		} else {
+			allowed = append(allowed, entityID)
+		}
+	}
+	return allowed, suppressed
+}
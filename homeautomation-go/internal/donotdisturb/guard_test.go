@@ -0,0 +1,67 @@
+package donotdisturb
+
+import (
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestGuard(t *testing.T) (*Guard, *state.Manager) {
+	t.Helper()
+
+	stateManager := state.NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	config := &Config{DoNotDisturb: Settings{Bedrooms: []Bedroom{
+		{Name: "primary", Variable: "isPrimaryBedroomDoNotDisturb", Entities: []string{"media_player.bedroom", "light.master_bedroom"}},
+		{Name: "guest", Variable: "isGuestBedroomDoNotDisturb", Entities: []string{"media_player.guest_room"}},
+	}}}
+	require.NoError(t, config.Validate())
+
+	return NewGuard(config, stateManager), stateManager
+}
+
+func TestGuard_Suppresses(t *testing.T) {
+	guard, stateManager := newTestGuard(t)
+
+	_, ok := guard.Suppresses("media_player.bedroom")
+	assert.False(t, ok, "nothing is suppressed while toggles are off")
+
+	require.NoError(t, stateManager.SetBool("isPrimaryBedroomDoNotDisturb", true))
+
+	bedroom, ok := guard.Suppresses("media_player.bedroom")
+	assert.True(t, ok)
+	assert.Equal(t, "primary", bedroom)
+
+	_, ok = guard.Suppresses("media_player.guest_room")
+	assert.False(t, ok, "other bedrooms are unaffected")
+
+	_, ok = guard.Suppresses("media_player.kitchen")
+	assert.False(t, ok, "entities outside bedrooms are never suppressed")
+}
+
+func TestGuard_Filter(t *testing.T) {
+	guard, stateManager := newTestGuard(t)
+	require.NoError(t, stateManager.SetBool("isGuestBedroomDoNotDisturb", true))
+
+	allowed, suppressed := guard.Filter([]string{"media_player.kitchen", "media_player.guest_room", "media_player.bedroom"})
+
+	assert.Equal(t, []string{"media_player.kitchen", "media_player.bedroom"}, allowed)
+	assert.Equal(t, []string{"media_player.guest_room"}, suppressed)
+}
+
+func TestGuard_Nil(t *testing.T) {
+	var guard *Guard
+
+	assert.Empty(t, guard.Bedrooms())
+	assert.False(t, guard.IsActive(Bedroom{Name: "primary"}))
+	_, ok := guard.Suppresses("media_player.bedroom")
+	assert.False(t, ok)
+
+	allowed, suppressed := guard.Filter([]string{"media_player.bedroom"})
+	assert.Equal(t, []string{"media_player.bedroom"}, allowed)
+	assert.Empty(t, suppressed)
+}
@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"homeautomation/internal/ha"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the entities_config.yaml structure
+type Config struct {
+	Entities map[Name]string `yaml:"entities"` // Symbolic name to entity ID, overriding the default
+}
+
+// Validate checks that every name is known and every entity ID is in the
+// same domain as its default, so a light can't be swapped for a sensor
+func (c *Config) Validate() error {
+	for name, entityID := range c.Entities {
+		def, ok := defaults[name]
+		if !ok {
+			return fmt.Errorf("entities: unknown entity name %q", name)
+		}
+		domain, objectID, ok := strings.Cut(entityID, ".")
+		if !ok || domain == "" || objectID == "" {
+			return fmt.Errorf("entities: %s: %q is not an entity ID", name, entityID)
+		}
+		if want := ha.EntityDomain(def); domain != want {
+			return fmt.Errorf("entities: %s must be a %s entity, got %q", name, want, entityID)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the entity names from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
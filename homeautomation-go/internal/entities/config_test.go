@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "valid", config: Config{Entities: map[Name]string{MasterBedroomLight: "light.bedroom_ceiling"}}},
+		{name: "empty", config: Config{}},
+		{name: "unknown name", config: Config{Entities: map[Name]string{"bedroom_light": "light.bedroom"}}, wantErr: `unknown entity name "bedroom_light"`},
+		{name: "not an entity ID", config: Config{Entities: map[Name]string{Doorbell: "doorbell"}}, wantErr: `"doorbell" is not an entity ID`},
+		{name: "empty object ID", config: Config{Entities: map[Name]string{Doorbell: "input_button."}}, wantErr: "is not an entity ID"},
+		{name: "wrong domain", config: Config{Entities: map[Name]string{BedroomSpeaker: "light.bedroom"}}, wantErr: "bedroom_speaker must be a media_player entity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadConfig_RepositoryConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join("..", "..", "..", "configs", "entities_config.yaml"))
+	require.NoError(t, err)
+
+	// The repository config names every entity, so it is the one place to look
+	for _, name := range Names() {
+		assert.Contains(t, config.Entities, name)
+	}
+}
+
+func TestLoadConfig_Missing(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "entities_config.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,184 @@
+// Package entities names the Home Assistant entities plugin code refers to
+// directly, so a renamed device is changed in entities_config.yaml rather
+// than in Go. Plugins look entities up by symbolic name through a Registry,
+// and startup checks that every one of them exists in Home Assistant.
+//
+// Input helpers behind state variables are named in internal/state, and
+// entities a plugin's own config file lists stay there.
+package entities
+
+import (
+	"sort"
+)
+
+// Name is the symbolic name of an entity, its key in entities_config.yaml
+type Name string
+
+const (
+	// Sleep hygiene and state tracking
+	PrimarySuiteLight    Name = "primary_suite_light"    // Turning off signals bedtime
+	MasterBedroomLight   Name = "master_bedroom_light"   // Turned on at wake
+	PrimaryBathroomLight Name = "primary_bathroom_light" // Turned off when the wake sequence is cancelled
+	BedroomSpeaker       Name = "bedroom_speaker"        // Faded out at wake when no bedroom speaker is playing
+	NickEightSleep       Name = "nick_eight_sleep"       // Bed state sensor whose alarm begins the wake sequence
+	CarolineEightSleep   Name = "caroline_eight_sleep"
+
+	// TV
+	AppleTV      Name = "apple_tv"
+	SyncBoxPower Name = "sync_box_power"
+	SyncBoxInput Name = "sync_box_input"
+
+	// Security
+	Doorbell              Name = "doorbell"
+	VehicleArriving       Name = "vehicle_arriving"
+	GarageDoor            Name = "garage_door"
+	GarageVehicleDetected Name = "garage_vehicle_detected"
+	DrillTTS              Name = "drill_tts" // Speaks the security drill announcement
+
+	// Energy
+	BatteryLevel        Name = "battery_level"
+	SolarNextHour       Name = "solar_next_hour"
+	SolarRemainingToday Name = "solar_remaining_today"
+
+	// Load shedding
+	HouseThermostat     Name = "house_thermostat"
+	SuiteThermostat     Name = "suite_thermostat"
+	HouseThermostatHold Name = "house_thermostat_hold"
+	SuiteThermostatHold Name = "suite_thermostat_hold"
+)
+
+// defaults are the entity IDs used when entities_config.yaml doesn't name one
+var defaults = map[Name]string{
+	PrimarySuiteLight:    "light.primary_suite",
+	MasterBedroomLight:   "light.master_bedroom",
+	PrimaryBathroomLight: "light.primary_bathroom_main_lights",
+	BedroomSpeaker:       "media_player.bedroom",
+	NickEightSleep:       "sensor.nick_s_eight_sleep_side_bed_state_type",
+	CarolineEightSleep:   "sensor.caroline_s_eight_sleep_side_bed_state_type",
+
+	AppleTV:      "media_player.big_beautiful_oled",
+	SyncBoxPower: "switch.sync_box_power",
+	SyncBoxInput: "select.sync_box_hdmi_input",
+
+	Doorbell:              "input_button.doorbell",
+	VehicleArriving:       "input_button.vehicle_arriving",
+	GarageDoor:            "cover.garage_door_door",
+	GarageVehicleDetected: "binary_sensor.garage_door_vehicle_detected",
+	DrillTTS:              "tts.google_translate_en_com",
+
+	BatteryLevel:        "sensor.span_panel_span_storage_battery_percentage_2",
+	SolarNextHour:       "sensor.energy_next_hour",
+	SolarRemainingToday: "sensor.energy_production_today_remaining",
+
+	HouseThermostat:     "climate.most_of_house_thermostat",
+	SuiteThermostat:     "climate.primary_suite_thermostat",
+	HouseThermostatHold: "switch.most_of_house_thermostat_hold",
+	SuiteThermostatHold: "switch.primary_suite_thermostat_hold",
+}
+
+// Names returns every symbolic name, sorted
+func Names() []Name {
+	names := make([]Name, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// Default returns a name's entity ID when entities_config.yaml doesn't set one
+func Default(name Name) string {
+	return defaults[name]
+}
+
+// Registry maps symbolic names to entity IDs. A nil Registry uses the
+// defaults, so plugins not given one behave as before.
+type Registry struct {
+	ids map[Name]string
+}
+
+// NewRegistry creates a registry from a config, falling back to the defaults
+// for names it doesn't set. A nil config gives the defaults.
+func NewRegistry(config *Config) *Registry {
+	ids := make(map[Name]string, len(defaults))
+	for name, entityID := range defaults {
+		ids[name] = entityID
+	}
+	if config != nil {
+		for name, entityID := range config.Entities {
+			ids[name] = entityID
+		}
+	}
+	return &Registry{ids: ids}
+}
+
+// ID returns the entity ID for a name
+func (r *Registry) ID(name Name) string {
+	if r == nil {
+		return Default(name)
+	}
+	return r.ids[name]
+}
+
+// PrimarySuiteLight is the primary suite's lights; turning them off signals bedtime
+func (r *Registry) PrimarySuiteLight() string { return r.ID(PrimarySuiteLight) }
+
+// MasterBedroomLight is the master bedroom light turned on at wake
+func (r *Registry) MasterBedroomLight() string { return r.ID(MasterBedroomLight) }
+
+// PrimaryBathroomLight is the primary bathroom light turned off when the wake sequence is cancelled
+func (r *Registry) PrimaryBathroomLight() string { return r.ID(PrimaryBathroomLight) }
+
+// BedroomSpeaker is the speaker faded out at wake when no bedroom speaker is playing
+func (r *Registry) BedroomSpeaker() string { return r.ID(BedroomSpeaker) }
+
+// NickEightSleep is Nick's Eight Sleep bed state sensor
+func (r *Registry) NickEightSleep() string { return r.ID(NickEightSleep) }
+
+// CarolineEightSleep is Caroline's Eight Sleep bed state sensor
+func (r *Registry) CarolineEightSleep() string { return r.ID(CarolineEightSleep) }
+
+// AppleTV is the media player whose playback sets isAppleTVPlaying
+func (r *Registry) AppleTV() string { return r.ID(AppleTV) }
+
+// SyncBoxPower is the HDMI sync box power switch that sets isTVon
+func (r *Registry) SyncBoxPower() string { return r.ID(SyncBoxPower) }
+
+// SyncBoxInput is the HDMI sync box input selector that isTVPlaying follows
+func (r *Registry) SyncBoxInput() string { return r.ID(SyncBoxInput) }
+
+// Doorbell is the doorbell press button
+func (r *Registry) Doorbell() string { return r.ID(Doorbell) }
+
+// VehicleArriving is the button pressed when a vehicle is arriving
+func (r *Registry) VehicleArriving() string { return r.ID(VehicleArriving) }
+
+// GarageDoor is the garage door cover
+func (r *Registry) GarageDoor() string { return r.ID(GarageDoor) }
+
+// GarageVehicleDetected is the garage's vehicle presence sensor
+func (r *Registry) GarageVehicleDetected() string { return r.ID(GarageVehicleDetected) }
+
+// DrillTTS is the TTS entity the security drill speaks through
+func (r *Registry) DrillTTS() string { return r.ID(DrillTTS) }
+
+// BatteryLevel is the home battery's charge percentage sensor
+func (r *Registry) BatteryLevel() string { return r.ID(BatteryLevel) }
+
+// SolarNextHour is the solar production forecast for the next hour
+func (r *Registry) SolarNextHour() string { return r.ID(SolarNextHour) }
+
+// SolarRemainingToday is the solar production forecast for the rest of today
+func (r *Registry) SolarRemainingToday() string { return r.ID(SolarRemainingToday) }
+
+// HouseThermostat is the thermostat for most of the house
+func (r *Registry) HouseThermostat() string { return r.ID(HouseThermostat) }
+
+// SuiteThermostat is the primary suite's thermostat
+func (r *Registry) SuiteThermostat() string { return r.ID(SuiteThermostat) }
+
+// HouseThermostatHold is the hold switch of the house thermostat
+func (r *Registry) HouseThermostatHold() string { return r.ID(HouseThermostatHold) }
+
+// SuiteThermostatHold is the hold switch of the primary suite's thermostat
+func (r *Registry) SuiteThermostatHold() string { return r.ID(SuiteThermostatHold) }
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Defaults(t *testing.T) {
+	var nilRegistry *Registry
+	registry := NewRegistry(nil)
+
+	for _, name := range Names() {
+		assert.NotEmpty(t, registry.ID(name), name)
+		assert.Equal(t, registry.ID(name), nilRegistry.ID(name), "a nil registry uses the defaults")
+	}
+	assert.Equal(t, "light.master_bedroom", registry.MasterBedroomLight())
+	assert.Equal(t, "media_player.bedroom", nilRegistry.BedroomSpeaker())
+}
+
+func TestRegistry_Overrides(t *testing.T) {
+	registry := NewRegistry(&Config{Entities: map[Name]string{
+		MasterBedroomLight: "light.bedroom_ceiling",
+	}})
+
+	assert.Equal(t, "light.bedroom_ceiling", registry.MasterBedroomLight())
+	assert.Equal(t, "light.primary_suite", registry.PrimarySuiteLight(), "names not in the config keep their default")
+}
+
+func TestRegistry_Verify(t *testing.T) {
+	registry := NewRegistry(&Config{Entities: map[Name]string{
+		MasterBedroomLight: "light.bedroom_ceiling",
+	}})
+
+	client := ha.NewMockClient()
+	for _, name := range Names() {
+		client.SetState(registry.ID(name), "on", nil)
+	}
+	require.NoError(t, registry.Verify(context.Background(), client))
+
+	// Only the defaults exist, so the override and one removed entity are missing
+	client = ha.NewMockClient()
+	for _, name := range Names() {
+		if name != Doorbell {
+			client.SetState(NewRegistry(nil).ID(name), "on", nil)
+		}
+	}
+	err := registry.Verify(context.Background(), client)
+
+	var missingErr *MissingError
+	require.True(t, errors.As(err, &missingErr))
+	assert.Equal(t, []Missing{
+		{Name: Doorbell, EntityID: "input_button.doorbell"},
+		{Name: MasterBedroomLight, EntityID: "light.bedroom_ceiling"},
+	}, missingErr.Missing)
+	assert.Contains(t, err.Error(), "2 entities not found")
+	assert.Contains(t, err.Error(), "light.bedroom_ceiling (master_bedroom_light)")
+}
+
+func TestRegistry_VerifyStatesError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewRegistry(nil).Verify(ctx, ha.NewMockClient())
+	require.ErrorIs(t, err, context.Canceled)
+	var missingErr *MissingError
+	assert.False(t, errors.As(err, &missingErr))
+}
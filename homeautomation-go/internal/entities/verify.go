@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"homeautomation/internal/ha"
+)
+
+// Missing is a registry entity Home Assistant doesn't have
+type Missing struct {
+	Name     Name
+	EntityID string
+}
+
+// MissingError reports every registry entity Home Assistant doesn't have
+type MissingError struct {
+	Missing []Missing
+}
+
+func (e *MissingError) Error() string {
+	parts := make([]string, len(e.Missing))
+	for i, m := range e.Missing {
+		parts[i] = fmt.Sprintf("%s (%s)", m.EntityID, m.Name)
+	}
+	return fmt.Sprintf("%d entities not found in Home Assistant: %s", len(e.Missing), strings.Join(parts, ", "))
+}
+
+// Verify checks that every entity in the registry exists in Home Assistant.
+// A *MissingError lists all the missing ones at once, so a rename is fixed in
+// one pass rather than one restart per entity.
+func (r *Registry) Verify(ctx context.Context, client ha.HAClient) error {
+	states, err := client.GetAllStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get states: %w", err)
+	}
+
+	existing := make(map[string]bool, len(states))
+	for _, s := range states {
+		existing[s.EntityID] = true
+	}
+
+	var missing []Missing
+	for _, name := range Names() {
+		if entityID := r.ID(name); !existing[entityID] {
+			missing = append(missing, Missing{Name: name, EntityID: entityID})
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingError{Missing: missing}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package entitygroups
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"homeautomation/internal/ha"
+)
+
+// Client wraps an HA client and expands group and area references in the
+// entity ID fields of service call data ("entity_id" and any "*_entity_id" key)
+type Client struct {
+	ha.HAClient
+	config *Config
+}
+
+// NewClient wraps client so service calls can target named entity groups
+func NewClient(client ha.HAClient, config *Config) *Client {
+	return &Client{HAClient: client, config: config}
+}
+
+// Expand replaces group and area references in ids with their entities
+func (c *Client) Expand(ids []string) ([]string, error) {
+	return c.config.Expand(ids)
+}
+
+// CallService expands group and area references in the data and calls the wrapped client
+func (c *Client) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	expanded, err := c.expandData(data)
+	if err != nil {
+		return fmt.Errorf("%s.%s: %w", domain, service, err)
+	}
+	return c.HAClient.CallService(ctx, domain, service, expanded)
+}
+
+// expandData returns a copy of data with group references expanded. Data
+// without any references is returned unchanged.
+func (c *Client) expandData(data map[string]interface{}) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	for key, value := range data {
+		if key != "entity_id" && !strings.HasSuffix(key, "_entity_id") {
+			continue
+		}
+
+		ids, single, ok := entityIDs(value)
+		if !ok || !hasReference(ids) {
+			continue
+		}
+
+		expanded, err := c.config.Expand(ids)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		if result == nil {
+			result = make(map[string]interface{}, len(data))
+			for k, v := range data {
+				result[k] = v
+			}
+		}
+		if single && len(expanded) == 1 {
+			result[key] = expanded[0]
+		} else {
+			result[key] = expanded
+		}
+	}
+
+	if result == nil {
+		return data, nil
+	}
+	return result, nil
+}
+
+// entityIDs converts an entity ID field to a list, reporting whether it was a single string
+func entityIDs(value interface{}) ([]string, bool, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true, true
+	case []string:
+		return v, false, true
+	case []interface{}:
+		ids := make([]string, 0, len(v))
+		for _, item := range v {
+			id, ok := item.(string)
+			if !ok {
+				return nil, false, false
+			}
+			ids = append(ids, id)
+		}
+		return ids, false, true
+	default:
+		return nil, false, false
+	}
+}
+
+func hasReference(ids []string) bool {
+	for _, id := range ids {
+		if IsReference(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand replaces group and area references in ids using client if it
+// supports entity groups. Plugins use this when they need the entities before the service
+// call, e.g. to filter out do-not-disturb bedrooms. Without group support the
+// ids are returned unchanged.
+func Expand(client ha.HAClient, ids []string) ([]string, error) {
+	expander, ok := client.(interface {
+		Expand(ids []string) ([]string, error)
+	})
+	if !ok || !hasReference(ids) {
+		return ids, nil
+	}
+	return expander.Expand(ids)
+}
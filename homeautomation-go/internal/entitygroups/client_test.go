@@ -0,0 +1,72 @@
+package entitygroups
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CallServiceExpandsGroups(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	client := NewClient(mockHA, testConfig())
+
+	require.NoError(t, client.CallService(context.Background(), "tts", "speak", map[string]interface{}{
+		"entity_id":              "tts.google_translate_en_com",
+		"media_player_entity_id": []string{"media_player.bedroom", Group(CommonSpeakers)},
+		"message":                "Hello",
+	}))
+	require.NoError(t, client.CallService(context.Background(), "media_player", "media_pause", map[string]interface{}{
+		"entity_id": Group(patioSpeakers),
+	}))
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{
+		"entity_id": []interface{}{Group(CommonAreaLights)},
+		"flash":     "short",
+	}))
+
+	calls := mockHA.GetServiceCalls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "tts.google_translate_en_com", calls[0].Data["entity_id"])
+	assert.Equal(t, []string{"media_player.bedroom", "media_player.kitchen", "media_player.dining_room"}, calls[0].Data["media_player_entity_id"])
+	assert.Equal(t, "Hello", calls[0].Data["message"])
+	assert.Equal(t, "media_player.patio", calls[1].Data["entity_id"])
+	assert.Equal(t, []string{"light.living_room", "light.kitchen"}, calls[2].Data["entity_id"])
+	assert.Equal(t, "short", calls[2].Data["flash"])
+}
+
+func TestClient_CallServiceUnknownGroup(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	client := NewClient(mockHA, testConfig())
+
+	err := client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{
+		"entity_id": Group("garage_lights"),
+	})
+
+	assert.ErrorContains(t, err, "unknown entity group")
+	assert.Empty(t, mockHA.GetServiceCalls(), "service must not be called with an unexpanded group")
+}
+
+func TestClient_CallServiceLeavesDataUntouched(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	client := NewClient(mockHA, testConfig())
+	data := map[string]interface{}{"entity_id": []string{Group(patioSpeakers)}}
+
+	require.NoError(t, client.CallService(context.Background(), "media_player", "media_pause", data))
+
+	assert.Equal(t, []string{Group(patioSpeakers)}, data["entity_id"], "caller's data must not be modified")
+}
+
+func TestExpand_WithAndWithoutGroupSupport(t *testing.T) {
+	ids := []string{Group(patioSpeakers), "media_player.bedroom"}
+
+	expanded, err := Expand(NewClient(ha.NewMockClient(), testConfig()), ids)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"media_player.patio", "media_player.bedroom"}, expanded)
+
+	unchanged, err := Expand(ha.NewMockClient(), ids)
+	require.NoError(t, err)
+	assert.Equal(t, ids, unchanged)
+}
@@ -0,0 +1,212 @@
+// Package entitygroups defines named lists of entities (e.g. the common-area
+// speakers) in one config file. Plugins refer to a list with Group("name") and
+// the reference is expanded to its entity IDs when the service call is made.
+// Groups and plugins can also refer to a Home Assistant area with
+// Area("kitchen", "light"), expanded from the cached area registry.
+package entitygroups
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// groupPrefix marks an entity ID as a reference to a named group. HA entity
+// IDs never contain a colon, so references can't collide with real entities.
+const groupPrefix = "group:"
+
+// areaPrefix marks an entity ID as a reference to the entities in an HA area,
+// optionally limited to one domain: "area:kitchen" or "area:kitchen/light"
+const areaPrefix = "area:"
+
+// Groups referenced by plugins; the config must define each of them
+const (
+	CommonSpeakers   = "common_speakers"
+	CommonAreaLights = "common_area_lights"
+	DoorbellLights   = "doorbell_lights"
+)
+
+// requiredGroups are the groups plugins expect to exist
+var requiredGroups = []string{CommonSpeakers, CommonAreaLights, DoorbellLights}
+
+// Group returns a reference to the named group that can be used wherever an
+// entity ID is expected in service call data
+func Group(name string) string {
+	return groupPrefix + name
+}
+
+// groupName returns the group name if id is a group reference
+func groupName(id string) (string, bool) {
+	if !strings.HasPrefix(id, groupPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(id, groupPrefix), true
+}
+
+// Area returns a reference to the entities in an HA area, limited to domain
+// unless it is empty
+func Area(areaID, domain string) string {
+	if domain == "" {
+		return areaPrefix + areaID
+	}
+	return areaPrefix + areaID + "/" + domain
+}
+
+// parseArea returns the area ID and domain if id is an area reference
+func parseArea(id string) (string, string, bool) {
+	if !strings.HasPrefix(id, areaPrefix) {
+		return "", "", false
+	}
+	areaID, domain, _ := strings.Cut(strings.TrimPrefix(id, areaPrefix), "/")
+	return areaID, domain, true
+}
+
+// IsReference reports whether id is a group or area reference rather than an entity ID
+func IsReference(id string) bool {
+	_, isGroup := groupName(id)
+	_, _, isArea := parseArea(id)
+	return isGroup || isArea
+}
+
+// AreaResolver looks up the entities in an HA area, e.g. *areas.Registry
+type AreaResolver interface {
+	Entities(areaID, domain string) ([]string, error)
+}
+
+// Config represents the entity_groups_config.yaml structure
+type Config struct {
+	EntityGroups map[string][]string `yaml:"entity_groups"`
+	// AreaRefreshMinutes is how often the HA area registry is re-read for
+	// area references, default 30
+	AreaRefreshMinutes int `yaml:"area_refresh_minutes"`
+
+	// areas resolves area references, nil until SetAreaResolver
+	areas AreaResolver
+}
+
+// SetAreaResolver sets the area registry used to expand area references
+func (c *Config) SetAreaResolver(resolver AreaResolver) {
+	c.areas = resolver
+}
+
+// Validate checks that every group is non-empty, lists only entity IDs and
+// area references, and that the groups plugins refer to are defined
+func (c *Config) Validate() error {
+	if c.AreaRefreshMinutes < 0 {
+		return fmt.Errorf("area_refresh_minutes must not be negative")
+	}
+
+	for name, entities := range c.EntityGroups {
+		if name == "" || strings.ContainsAny(name, ":. ") {
+			return fmt.Errorf("invalid group name %q", name)
+		}
+		if len(entities) == 0 {
+			return fmt.Errorf("group %q: at least one entity is required", name)
+		}
+		for _, entityID := range entities {
+			if _, ok := groupName(entityID); ok {
+				return fmt.Errorf("group %q: groups cannot contain other groups (%q)", name, entityID)
+			}
+			if areaID, domain, ok := parseArea(entityID); ok {
+				if areaID == "" || strings.ContainsAny(domain, ". /") {
+					return fmt.Errorf("group %q: invalid area reference %q", name, entityID)
+				}
+				continue
+			}
+			if !strings.Contains(entityID, ".") {
+				return fmt.Errorf("group %q: invalid entity ID %q", name, entityID)
+			}
+		}
+	}
+
+	for _, name := range requiredGroups {
+		if _, ok := c.EntityGroups[name]; !ok {
+			return fmt.Errorf("group %q is required", name)
+		}
+	}
+	return nil
+}
+
+// Expand replaces group and area references in ids with their entities,
+// keeping order and dropping duplicates. Plain entity IDs are passed through.
+func (c *Config) Expand(ids []string) ([]string, error) {
+	expanded := make([]string, 0, len(ids))
+	seen := make(map[string]bool)
+	add := func(entityID string) {
+		if !seen[entityID] {
+			seen[entityID] = true
+			expanded = append(expanded, entityID)
+		}
+	}
+	addArea := func(id string) (bool, error) {
+		areaID, domain, ok := parseArea(id)
+		if !ok {
+			return false, nil
+		}
+		entities, err := c.areaEntities(areaID, domain)
+		if err != nil {
+			return true, err
+		}
+		for _, entityID := range entities {
+			add(entityID)
+		}
+		return true, nil
+	}
+
+	for _, id := range ids {
+		if isArea, err := addArea(id); isArea {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		name, ok := groupName(id)
+		if !ok {
+			add(id)
+			continue
+		}
+		entities, ok := c.EntityGroups[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown entity group %q", name)
+		}
+		for _, entityID := range entities {
+			if isArea, err := addArea(entityID); isArea {
+				if err != nil {
+					return nil, fmt.Errorf("entity group %q: %w", name, err)
+				}
+				continue
+			}
+			add(entityID)
+		}
+	}
+	return expanded, nil
+}
+
+// areaEntities returns the entities in an area from the area resolver
+func (c *Config) areaEntities(areaID, domain string) ([]string, error) {
+	if c.areas == nil {
+		return nil, fmt.Errorf("area %q: the Home Assistant area registry is not available", areaID)
+	}
+	return c.areas.Entities(areaID, domain)
+}
+
+// LoadConfig loads the entity group configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,113 @@
+package entitygroups
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patioSpeakers is an optional group, not one plugins require
+const patioSpeakers = "patio_speakers"
+
+func testConfig() *Config {
+	return &Config{EntityGroups: map[string][]string{
+		CommonSpeakers:   {"media_player.kitchen", "media_player.dining_room"},
+		CommonAreaLights: {"light.living_room", "light.kitchen"},
+		DoorbellLights:   {"light.primary_suite", "light.living_room"},
+		patioSpeakers:    {"media_player.patio"},
+	}}
+}
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../configs/entity_groups_config.yaml")
+	require.NoError(t, err)
+
+	assert.Contains(t, config.EntityGroups[CommonSpeakers], "media_player.kitchen")
+	assert.Contains(t, config.EntityGroups[DoorbellLights], "light.primary_suite")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"extra group", func(c *Config) { c.EntityGroups["bedroom_lights"] = []string{"light.master_bedroom"} }, ""},
+		{"missing required group", func(c *Config) { delete(c.EntityGroups, DoorbellLights) }, "is required"},
+		{"empty group", func(c *Config) { c.EntityGroups[patioSpeakers] = nil }, "at least one entity"},
+		{"invalid entity", func(c *Config) { c.EntityGroups[patioSpeakers] = []string{"patio"} }, "invalid entity ID"},
+		{"nested group", func(c *Config) { c.EntityGroups[patioSpeakers] = []string{Group(CommonSpeakers)} }, "cannot contain other groups"},
+		{"invalid name", func(c *Config) { c.EntityGroups["media_player.patio"] = []string{"media_player.patio"} }, "invalid group name"},
+		{"area reference", func(c *Config) { c.EntityGroups[patioSpeakers] = []string{Area("patio", "media_player")} }, ""},
+		{"empty area", func(c *Config) { c.EntityGroups[patioSpeakers] = []string{"area:/media_player"} }, "invalid area reference"},
+		{"invalid area domain", func(c *Config) { c.EntityGroups[patioSpeakers] = []string{"area:patio/media_player.patio"} }, "invalid area reference"},
+		{"negative refresh", func(c *Config) { c.AreaRefreshMinutes = -1 }, "area_refresh_minutes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			tt.mutate(config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	config := testConfig()
+
+	expanded, err := config.Expand([]string{"media_player.bedroom", Group(CommonSpeakers), "media_player.kitchen", "media_player.office"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"media_player.bedroom", "media_player.kitchen", "media_player.dining_room", "media_player.office"}, expanded)
+
+	expanded, err = config.Expand([]string{Group(CommonAreaLights), Group(DoorbellLights)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.living_room", "light.kitchen", "light.primary_suite"}, expanded)
+
+	_, err = config.Expand([]string{Group("garage_lights")})
+	assert.ErrorContains(t, err, `unknown entity group "garage_lights"`)
+}
+
+// fakeAreas resolves areas from a fixed map
+type fakeAreas map[string][]string
+
+func (f fakeAreas) Entities(areaID, domain string) ([]string, error) {
+	entities, ok := f[areaID]
+	if !ok {
+		return nil, fmt.Errorf("unknown area %q", areaID)
+	}
+	filtered := make([]string, 0, len(entities))
+	for _, entityID := range entities {
+		if domain == "" || strings.HasPrefix(entityID, domain+".") {
+			filtered = append(filtered, entityID)
+		}
+	}
+	return filtered, nil
+}
+
+func TestExpand_AreaReferences(t *testing.T) {
+	config := testConfig()
+	config.EntityGroups[patioSpeakers] = []string{Area("patio", "media_player"), "media_player.garage"}
+
+	_, err := config.Expand([]string{Area("patio", "")})
+	assert.ErrorContains(t, err, "area registry is not available")
+
+	config.SetAreaResolver(fakeAreas{"patio": {"light.patio", "media_player.patio", "media_player.pool"}})
+
+	expanded, err := config.Expand([]string{Area("patio", "light"), Group(patioSpeakers)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.patio", "media_player.patio", "media_player.pool", "media_player.garage"}, expanded)
+
+	_, err = config.Expand([]string{Area("attic", "")})
+	assert.ErrorContains(t, err, `unknown area "attic"`)
+}
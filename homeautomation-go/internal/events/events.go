@@ -0,0 +1,252 @@
+// Package events is a typed event bus. State variable changes arrive as
+// BoolChanged, StringChanged or NumberChanged depending on the variable's
+// type, so handlers get values of the right type instead of asserting
+// interface{} values themselves. Plugins publish PluginAction events when they
+// act. Subscriptions take a key (or plugin name) or a glob pattern such as
+// "is*Home" or "*".
+package events
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Kind is the type of value a state change event carries
+type Kind string
+
+// Kinds of state change events
+const (
+	KindBool   Kind = "bool"
+	KindString Kind = "string"
+	KindNumber Kind = "number"
+)
+
+// BoolChanged is published when a boolean state variable changes
+type BoolChanged struct {
+	Key     string
+	Old     bool
+	New     bool
+	Initial bool // No value was known before, so Old is false rather than a real value
+}
+
+// StringChanged is published when a string state variable changes
+type StringChanged struct {
+	Key     string
+	Old     string
+	New     string
+	Initial bool // No value was known before, so Old is "" rather than a real value
+}
+
+// NumberChanged is published when a number state variable changes
+type NumberChanged struct {
+	Key     string
+	Old     float64
+	New     float64
+	Initial bool // No value was known before, so Old is 0 rather than a real value
+}
+
+// PluginAction is published when a plugin acts on the house
+type PluginAction struct {
+	Plugin string
+	Action string // e.g. "play", "announce"
+	Reason string
+	Time   time.Time
+}
+
+// Source delivers state changes to the bus. Watch is called for each state
+// change subscription: it checks that the variables matching pattern hold
+// values of kind and makes sure their changes are published until release is
+// called.
+type Source interface {
+	Watch(pattern string, kind Kind) (release func(), err error)
+}
+
+// Subscription represents an active event subscription
+type Subscription interface {
+	Unsubscribe()
+}
+
+// topic groups subscribers by event type
+type topic string
+
+const (
+	topicBool         topic = topic(KindBool)
+	topicString       topic = topic(KindString)
+	topicNumber       topic = topic(KindNumber)
+	topicPluginAction topic = "plugin_action"
+)
+
+type subscriber struct {
+	pattern string
+	handler func(event interface{})
+}
+
+// Bus delivers events to subscribers in the order they subscribed, on the
+// publisher's goroutine. Publishing on a nil Bus does nothing.
+type Bus struct {
+	logger *zap.Logger
+	source Source
+
+	// Subscribers per topic by ID (protected by mu)
+	subscribers map[topic]map[uint64]subscriber
+	nextID      uint64
+	mu          sync.RWMutex
+}
+
+// NewBus creates an event bus
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		logger:      logger.Named("events"),
+		subscribers: make(map[topic]map[uint64]subscriber),
+	}
+}
+
+// SetSource sets where state change events come from. Without a source, state
+// change subscriptions see only what is published directly.
+func (b *Bus) SetSource(source Source) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.source = source
+}
+
+// Match reports whether key matches a subscription pattern
+func Match(pattern, key string) bool {
+	matched, err := path.Match(pattern, key)
+	return err == nil && matched
+}
+
+// OnBool calls handler for changes to boolean variables matching pattern
+func (b *Bus) OnBool(pattern string, handler func(BoolChanged)) (Subscription, error) {
+	return b.subscribe(topicBool, pattern, func(event interface{}) {
+		handler(event.(BoolChanged))
+	})
+}
+
+// OnString calls handler for changes to string variables matching pattern
+func (b *Bus) OnString(pattern string, handler func(StringChanged)) (Subscription, error) {
+	return b.subscribe(topicString, pattern, func(event interface{}) {
+		handler(event.(StringChanged))
+	})
+}
+
+// OnNumber calls handler for changes to number variables matching pattern
+func (b *Bus) OnNumber(pattern string, handler func(NumberChanged)) (Subscription, error) {
+	return b.subscribe(topicNumber, pattern, func(event interface{}) {
+		handler(event.(NumberChanged))
+	})
+}
+
+// OnPluginAction calls handler for actions of plugins matching pattern
+func (b *Bus) OnPluginAction(pattern string, handler func(PluginAction)) (Subscription, error) {
+	return b.subscribe(topicPluginAction, pattern, func(event interface{}) {
+		handler(event.(PluginAction))
+	})
+}
+
+func (b *Bus) subscribe(t topic, pattern string, handler func(interface{})) (Subscription, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	b.mu.RLock()
+	source := b.source
+	b.mu.RUnlock()
+
+	release := func() {}
+	if source != nil && t != topicPluginAction {
+		var err error
+		if release, err = source.Watch(pattern, Kind(t)); err != nil {
+			return nil, err
+		}
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	if b.subscribers[t] == nil {
+		b.subscribers[t] = make(map[uint64]subscriber)
+	}
+	b.subscribers[t][id] = subscriber{pattern: pattern, handler: handler}
+	b.mu.Unlock()
+
+	return &subscription{bus: b, topic: t, id: id, release: release}, nil
+}
+
+type subscription struct {
+	bus     *Bus
+	topic   topic
+	id      uint64
+	release func()
+	once    sync.Once
+}
+
+func (s *subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscribers[s.topic], s.id)
+		s.bus.mu.Unlock()
+		s.release()
+	})
+}
+
+// PublishBool delivers a boolean change
+func (b *Bus) PublishBool(event BoolChanged) {
+	b.publish(topicBool, event.Key, event)
+}
+
+// PublishString delivers a string change
+func (b *Bus) PublishString(event StringChanged) {
+	b.publish(topicString, event.Key, event)
+}
+
+// PublishNumber delivers a number change
+func (b *Bus) PublishNumber(event NumberChanged) {
+	b.publish(topicNumber, event.Key, event)
+}
+
+// PublishPluginAction delivers a plugin action
+func (b *Bus) PublishPluginAction(event PluginAction) {
+	b.publish(topicPluginAction, event.Plugin, event)
+}
+
+// publish calls the handlers subscribed to key, recovering and logging panics
+// so one handler can't stop the others
+func (b *Bus) publish(t topic, key string, event interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	ids := make([]uint64, 0, len(b.subscribers[t]))
+	for id, sub := range b.subscribers[t] {
+		if Match(sub.pattern, key) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	handlers := make([]func(interface{}), 0, len(ids))
+	for _, id := range ids {
+		handlers = append(handlers, b.subscribers[t][id].handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					b.logger.Warn("Event handler panicked",
+						zap.String("topic", string(t)),
+						zap.String("key", key),
+						zap.Any("panic", r),
+						zap.Stack("stack"))
+				}
+			}()
+			handler(event)
+		}()
+	}
+}
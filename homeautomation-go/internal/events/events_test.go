@@ -0,0 +1,118 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSource records watched patterns and fails for unknown keys
+type fakeSource struct {
+	watched  []string
+	released []string
+}
+
+func (s *fakeSource) Watch(pattern string, kind Kind) (func(), error) {
+	if pattern == "unknown" {
+		return nil, errors.New("no variables match")
+	}
+	s.watched = append(s.watched, string(kind)+":"+pattern)
+	return func() { s.released = append(s.released, pattern) }, nil
+}
+
+func TestBus_TypedTopics(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+
+	var bools []BoolChanged
+	var strings []StringChanged
+	_, err := bus.OnBool("isNickHome", func(e BoolChanged) { bools = append(bools, e) })
+	require.NoError(t, err)
+	_, err = bus.OnString("isNickHome", func(e StringChanged) { strings = append(strings, e) })
+	require.NoError(t, err)
+
+	bus.PublishBool(BoolChanged{Key: "isNickHome", Old: false, New: true})
+	bus.PublishBool(BoolChanged{Key: "isToriHere", New: true})
+
+	assert.Equal(t, []BoolChanged{{Key: "isNickHome", Old: false, New: true}}, bools)
+	assert.Empty(t, strings, "a bool change doesn't reach string subscribers of the same key")
+}
+
+func TestBus_Wildcards(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+
+	var keys []string
+	_, err := bus.OnBool("is*Home", func(e BoolChanged) { keys = append(keys, e.Key) })
+	require.NoError(t, err)
+	var all []float64
+	_, err = bus.OnNumber("*", func(e NumberChanged) { all = append(all, e.New) })
+	require.NoError(t, err)
+
+	bus.PublishBool(BoolChanged{Key: "isNickHome", New: true})
+	bus.PublishBool(BoolChanged{Key: "isCarolineHome", New: true})
+	bus.PublishBool(BoolChanged{Key: "isToriHere", New: true})
+	bus.PublishNumber(NumberChanged{Key: "alarmTime", New: 7})
+
+	assert.Equal(t, []string{"isNickHome", "isCarolineHome"}, keys)
+	assert.Equal(t, []float64{7}, all)
+
+	_, err = bus.OnBool("[", func(BoolChanged) {})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
+
+func TestBus_PluginActions(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+
+	var actions []PluginAction
+	_, err := bus.OnPluginAction("music", func(e PluginAction) { actions = append(actions, e) })
+	require.NoError(t, err)
+
+	bus.PublishPluginAction(PluginAction{Plugin: "music", Action: "play"})
+	bus.PublishPluginAction(PluginAction{Plugin: "lighting", Action: "scene"})
+
+	require.Len(t, actions, 1)
+	assert.Equal(t, "play", actions[0].Action)
+
+	var none *Bus
+	none.PublishPluginAction(PluginAction{Plugin: "music"})
+}
+
+func TestBus_OrderAndPanics(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+
+	var order []int
+	_, err := bus.OnString("dayPhase", func(StringChanged) { order = append(order, 1) })
+	require.NoError(t, err)
+	_, err = bus.OnString("dayPhase", func(StringChanged) { panic("boom") })
+	require.NoError(t, err)
+	_, err = bus.OnString("dayPhase", func(StringChanged) { order = append(order, 3) })
+	require.NoError(t, err)
+
+	bus.PublishString(StringChanged{Key: "dayPhase", New: "night"})
+	assert.Equal(t, []int{1, 3}, order, "a panicking handler doesn't stop the rest")
+}
+
+func TestBus_SourceWatchAndRelease(t *testing.T) {
+	source := &fakeSource{}
+	bus := NewBus(zap.NewNop())
+	bus.SetSource(source)
+
+	calls := 0
+	sub, err := bus.OnBool("isNickHome", func(BoolChanged) { calls++ })
+	require.NoError(t, err)
+	_, err = bus.OnPluginAction("*", func(PluginAction) {})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bool:isNickHome"}, source.watched, "plugin actions don't come from the source")
+
+	_, err = bus.OnBool("unknown", func(BoolChanged) {})
+	assert.Error(t, err)
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	assert.Equal(t, []string{"isNickHome"}, source.released)
+
+	bus.PublishBool(BoolChanged{Key: "isNickHome", New: true})
+	assert.Zero(t, calls)
+}
@@ -0,0 +1,163 @@
+// Package fade ramps speaker volumes up or down in steps. Music fades
+// speakers in after starting playback and sleep hygiene fades the bedroom out
+// at wake time; both describe the fade (targets, curve, step interval) and let
+// Run step every speaker together until the fade finishes, a speaker's
+// Continue check fails, or the context is cancelled.
+package fade
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+)
+
+// Curve shapes how the volume moves between the start and end of a fade
+type Curve string
+
+const (
+	// Linear changes the volume by the same amount each step
+	Linear Curve = "linear"
+
+	// Exponential changes the volume slowly near the quiet end and quickly
+	// near the loud end, which sounds more even since loudness is heard
+	// logarithmically
+	Exponential Curve = "exponential"
+)
+
+// exponentialRate sets how strongly the exponential curve bends
+const exponentialRate = 4.0
+
+// Target is one speaker's fade
+type Target struct {
+	EntityID string
+	From     ha.Volume
+	To       ha.Volume
+	Steps    int // Volume changes from From to To; fewer than 1 jumps straight to To
+}
+
+// Interval returns how long to wait after setting a speaker to volume
+type Interval func(volume ha.Volume) time.Duration
+
+// Fixed waits the same time after every step
+func Fixed(d time.Duration) Interval {
+	return func(ha.Volume) time.Duration {
+		return d
+	}
+}
+
+// Adaptive waits perPoint for each percent the volume is below ceiling, and
+// at least min. Quieter volumes wait longer, so a fade-in starts slowly and a
+// fade-out lingers near silence.
+func Adaptive(ceiling int, perPoint, min time.Duration) Interval {
+	return func(volume ha.Volume) time.Duration {
+		d := time.Duration(ceiling-volume.Percent()) * perPoint
+		if d < min {
+			return min
+		}
+		return d
+	}
+}
+
+// Options configures a fade
+type Options struct {
+	Curve    Curve    // Defaults to Linear
+	Interval Interval // Wait between steps; defaults to one second
+
+	// Resolution rounds each step to a scale the speaker is set on, e.g.
+	// ha.SonosSteps or 100 for percent; 0 doesn't round
+	Resolution int
+
+	// SetVolume sets a speaker's volume; callers log their own errors, and a
+	// failed step doesn't stop the fade
+	SetVolume func(entityID string, volume ha.Volume)
+
+	// Continue is checked before each step; returning false stops that
+	// speaker's fade. Nil always continues.
+	Continue func(entityID string) bool
+
+	// OnStep is called after each volume change
+	OnStep func(entityID string, volume ha.Volume)
+}
+
+// Run fades every target concurrently and returns once all have finished or
+// stopped. It returns the context's error if the context was cancelled.
+func Run(ctx context.Context, c clock.Clock, targets []Target, options Options) error {
+	if options.Curve == "" {
+		options.Curve = Linear
+	}
+	if options.Interval == nil {
+		options.Interval = Fixed(time.Second)
+	}
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		if target.From == target.To {
+			continue
+		}
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			run(ctx, c, target, options)
+		}(target)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// run steps one target, waiting the interval between steps
+func run(ctx context.Context, c clock.Clock, target Target, options Options) {
+	steps := target.Steps
+	if steps < 1 {
+		steps = 1
+	}
+
+	for i := 1; i <= steps; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		if options.Continue != nil && !options.Continue(target.EntityID) {
+			return
+		}
+
+		volume := At(options.Curve, target.From, target.To, float64(i)/float64(steps))
+		if options.Resolution > 0 {
+			volume = ha.VolumeFromLevel(math.Round(volume.Level()*float64(options.Resolution)) / float64(options.Resolution))
+		}
+		options.SetVolume(target.EntityID, volume)
+		if options.OnStep != nil {
+			options.OnStep(target.EntityID, volume)
+		}
+
+		if i == steps {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.After(options.Interval(volume)):
+		}
+	}
+}
+
+// At returns the volume a fraction (0-1) of the way from one volume to another
+// along a curve
+func At(curve Curve, from, to ha.Volume, fraction float64) ha.Volume {
+	fraction = math.Max(0, math.Min(1, fraction))
+	if curve != Exponential {
+		return ha.VolumeFromLevel(from.Level() + (to.Level()-from.Level())*fraction)
+	}
+
+	// Bend the curve from the quiet end in either direction
+	low, high := from.Level(), to.Level()
+	position := fraction
+	if low > high {
+		low, high = high, low
+		position = 1 - fraction
+	}
+	bent := (math.Exp(exponentialRate*position) - 1) / (math.Exp(exponentialRate) - 1)
+	return ha.VolumeFromLevel(low + (high-low)*bent)
+}
@@ -0,0 +1,172 @@
+package fade
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder collects the volumes set per speaker
+type recorder struct {
+	volumes map[string][]int
+	mu      sync.Mutex
+}
+
+func newRecorder() *recorder {
+	return &recorder{volumes: make(map[string][]int)}
+}
+
+func (r *recorder) set(entityID string, volume ha.Volume) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.volumes[entityID] = append(r.volumes[entityID], volume.Percent())
+}
+
+func (r *recorder) get(entityID string) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.volumes[entityID]...)
+}
+
+func TestRun_LinearMultiSpeaker(t *testing.T) {
+	r := newRecorder()
+	var steps int
+	var mu sync.Mutex
+
+	err := Run(context.Background(), clock.NewRealClock(), []Target{
+		{EntityID: "media_player.kitchen", To: ha.VolumeFromSonos(3), Steps: 3},
+		{EntityID: "media_player.bedroom", From: ha.VolumeFromPercent(4), Steps: 4},
+		{EntityID: "media_player.office", From: ha.VolumeFromPercent(20), To: ha.VolumeFromPercent(20), Steps: 5},
+	}, Options{
+		Interval:  Fixed(time.Millisecond),
+		SetVolume: r.set,
+		OnStep: func(string, ha.Volume) {
+			mu.Lock()
+			steps++
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{7, 13, 20}, r.get("media_player.kitchen"))
+	assert.Equal(t, []int{3, 2, 1, 0}, r.get("media_player.bedroom"))
+	assert.Empty(t, r.get("media_player.office"), "a speaker already at its target isn't touched")
+	assert.Equal(t, 7, steps)
+}
+
+func TestRun_Resolution(t *testing.T) {
+	var levels []float64
+	err := Run(context.Background(), clock.NewRealClock(), []Target{
+		{EntityID: "media_player.bedroom", From: ha.VolumeFromPercent(60), Steps: 60},
+	}, Options{
+		Resolution: 100,
+		Interval:   Fixed(0),
+		SetVolume:  func(_ string, v ha.Volume) { levels = append(levels, v.Level()) },
+	})
+	require.NoError(t, err)
+
+	require.Len(t, levels, 60)
+	assert.Equal(t, 0.59, levels[0], "steps land exactly on whole percents")
+	assert.Equal(t, 0.0, levels[59])
+}
+
+func TestRun_ContinueStopsOneSpeaker(t *testing.T) {
+	r := newRecorder()
+	err := Run(context.Background(), clock.NewRealClock(), []Target{
+		{EntityID: "media_player.kitchen", To: ha.VolumeFromPercent(5), Steps: 5},
+		{EntityID: "media_player.office", To: ha.VolumeFromPercent(5), Steps: 5},
+	}, Options{
+		Interval:  Fixed(time.Millisecond),
+		SetVolume: r.set,
+		Continue: func(entityID string) bool {
+			return entityID != "media_player.office" || len(r.get(entityID)) < 2
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, r.get("media_player.kitchen"), 5)
+	assert.Equal(t, []int{1, 2}, r.get("media_player.office"))
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newRecorder()
+
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, clock.NewRealClock(), []Target{
+			{EntityID: "media_player.bedroom", From: ha.VolumeFromPercent(50), Steps: 50},
+		}, Options{
+			Interval:  Fixed(time.Hour),
+			SetVolume: r.set,
+		})
+	}()
+
+	require.Eventually(t, func() bool { return len(r.get("media_player.bedroom")) == 1 }, time.Second, time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("fade did not stop when cancelled")
+	}
+	assert.Equal(t, []int{49}, r.get("media_player.bedroom"))
+}
+
+func TestRun_WaitsTheInterval(t *testing.T) {
+	start := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	mockClock := clock.NewMockClock(start)
+	r := newRecorder()
+
+	done := make(chan error)
+	go func() {
+		done <- Run(context.Background(), mockClock, []Target{
+			{EntityID: "media_player.bedroom", From: ha.VolumeFromPercent(2), Steps: 2},
+		}, Options{
+			Interval:  Adaptive(60, time.Second, time.Second),
+			SetVolume: r.set,
+		})
+	}()
+
+	require.Eventually(t, func() bool { return len(r.get("media_player.bedroom")) == 1 }, time.Second, time.Millisecond)
+
+	// At 1% the adaptive interval is 59 seconds
+	mockClock.Advance(58 * time.Second)
+	assert.Len(t, r.get("media_player.bedroom"), 1)
+	mockClock.Advance(time.Second)
+
+	require.NoError(t, <-done)
+	assert.Equal(t, []int{1, 0}, r.get("media_player.bedroom"))
+}
+
+func TestAdaptive(t *testing.T) {
+	interval := Adaptive(100, 2*time.Millisecond, 100*time.Millisecond)
+	assert.Equal(t, 200*time.Millisecond, interval(ha.Volume{}))
+	assert.Equal(t, 100*time.Millisecond, interval(ha.VolumeFromPercent(60)), "never below the minimum")
+	assert.Equal(t, 100*time.Millisecond, interval(ha.VolumeFromPercent(100)))
+}
+
+func TestAt(t *testing.T) {
+	quiet, loud := ha.Volume{}, ha.VolumeFromPercent(100)
+
+	assert.Equal(t, 50, At(Linear, quiet, loud, 0.5).Percent())
+	assert.Equal(t, 50, At(Linear, loud, quiet, 0.5).Percent())
+	assert.Equal(t, 100, At(Linear, quiet, loud, 2).Percent(), "fractions past the end clamp")
+
+	// Exponential spends most of the fade near silence whichever way it goes:
+	// halfway through, a fade-in has barely started and a fade-out is nearly done
+	assert.Less(t, At(Exponential, quiet, loud, 0.5).Percent(), 20)
+	assert.Less(t, At(Exponential, loud, quiet, 0.5).Percent(), 20)
+	assert.Equal(t, At(Exponential, quiet, loud, 0.25), At(Exponential, loud, quiet, 0.75))
+	assert.Equal(t, 0, At(Exponential, quiet, loud, 0).Percent())
+	assert.Equal(t, 100, At(Exponential, quiet, loud, 1).Percent())
+	assert.Equal(t, 0, At(Exponential, loud, quiet, 1).Percent())
+}
@@ -0,0 +1,94 @@
+package focusmode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEventKeyword starts focus mode for calendar events with it in the title
+const DefaultEventKeyword = "Focus"
+
+// Settings holds the focus mode triggers and the office's speakers and lights
+type Settings struct {
+	ToggleVariable string   `yaml:"toggle_variable"` // Boolean state variable that turns focus mode on by hand
+	CalendarEntity string   `yaml:"calendar_entity"` // Optional calendar.* entity; focus mode follows its matching events
+	EventKeyword   string   `yaml:"event_keyword"`   // Case-insensitive; defaults to DefaultEventKeyword
+	Speakers       []string `yaml:"speakers"`        // Left out of house music and non-critical TTS
+	Scene          string   `yaml:"scene"`           // Optional concentration scene, scene.*
+	Lights         []string `yaml:"lights"`          // Held on the scene, then restored when focus mode ends
+}
+
+// Config represents the focus_mode_config.yaml structure
+type Config struct {
+	FocusMode Settings `yaml:"focus_mode"`
+}
+
+// Keyword returns the calendar event keyword
+func (s *Settings) Keyword() string {
+	if s.EventKeyword == "" {
+		return DefaultEventKeyword
+	}
+	return s.EventKeyword
+}
+
+// Validate checks the toggle variable, calendar, scene and entity IDs
+func (c *Config) Validate() error {
+	s := c.FocusMode
+	if s.ToggleVariable == "" && s.CalendarEntity == "" {
+		return fmt.Errorf("focus_mode: toggle_variable or calendar_entity is required")
+	}
+
+	if s.ToggleVariable != "" {
+		variable, ok := state.VariablesByKey()[s.ToggleVariable]
+		if !ok {
+			return fmt.Errorf("focus_mode: unknown toggle_variable %q", s.ToggleVariable)
+		}
+		if variable.Type != state.TypeBool {
+			return fmt.Errorf("focus_mode: toggle_variable %q must be a boolean", s.ToggleVariable)
+		}
+	}
+
+	if s.CalendarEntity != "" && !strings.HasPrefix(s.CalendarEntity, "calendar.") {
+		return fmt.Errorf("focus_mode: calendar_entity must be a calendar.* entity, got %q", s.CalendarEntity)
+	}
+
+	if s.Scene != "" {
+		if !strings.HasPrefix(s.Scene, "scene.") {
+			return fmt.Errorf("focus_mode: scene must be a scene.* entity, got %q", s.Scene)
+		}
+		if len(s.Lights) == 0 {
+			return fmt.Errorf("focus_mode: lights are required with a scene, so they can be restored")
+		}
+	}
+
+	for _, entityID := range append(append([]string{}, s.Speakers...), s.Lights...) {
+		if !strings.Contains(entityID, ".") {
+			return fmt.Errorf("focus_mode: invalid entity ID %q", entityID)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the focus mode configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,51 @@
+package focusmode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../configs/focus_mode_config.yaml")
+	require.NoError(t, err)
+
+	s := config.FocusMode
+	assert.Equal(t, "isOfficeFocusMode", s.ToggleVariable)
+	assert.Equal(t, "Focus", s.Keyword())
+	assert.NotEmpty(t, s.Speakers)
+	assert.NotEmpty(t, s.Lights)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  string
+	}{
+		{"toggle only", Settings{ToggleVariable: "isOfficeFocusMode"}, ""},
+		{"calendar only", Settings{CalendarEntity: "calendar.work"}, ""},
+		{"scene with lights", Settings{ToggleVariable: "isOfficeFocusMode", Scene: "scene.focus", Lights: []string{"light.office"}}, ""},
+		{"no trigger", Settings{Speakers: []string{"media_player.office"}}, "toggle_variable or calendar_entity is required"},
+		{"unknown variable", Settings{ToggleVariable: "isFocus"}, "unknown toggle_variable"},
+		{"non-boolean variable", Settings{ToggleVariable: "dayPhase"}, "must be a boolean"},
+		{"not a calendar", Settings{CalendarEntity: "sensor.work"}, "calendar.* entity"},
+		{"not a scene", Settings{ToggleVariable: "isOfficeFocusMode", Scene: "light.office", Lights: []string{"light.office"}}, "scene.* entity"},
+		{"scene without lights", Settings{ToggleVariable: "isOfficeFocusMode", Scene: "scene.focus"}, "lights are required"},
+		{"invalid speaker", Settings{ToggleVariable: "isOfficeFocusMode", Speakers: []string{"office"}}, "invalid entity ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{FocusMode: tt.settings}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package focusmode
+
+import (
+	"homeautomation/internal/state"
+)
+
+// ActiveVariable is the local-only variable that is true while focus mode is
+// on, from either trigger. Music participants can list it in leave_muted_if.
+const ActiveVariable = "isOfficeFocusActive"
+
+// Guard reports which office entities focus mode holds.
+// All methods are safe to call on a nil Guard, which holds nothing.
+type Guard struct {
+	stateManager *state.Manager
+	entities     map[string]bool
+}
+
+// NewGuard creates a guard reading ActiveVariable from the state manager
+func NewGuard(config *Config, stateManager *state.Manager) *Guard {
+	entities := make(map[string]bool)
+	for _, entityID := range config.FocusMode.Speakers {
+		entities[entityID] = true
+	}
+	for _, entityID := range config.FocusMode.Lights {
+		entities[entityID] = true
+	}
+
+	return &Guard{
+		stateManager: stateManager,
+		entities:     entities,
+	}
+}
+
+// IsActive reports whether focus mode is on
+func (g *Guard) IsActive() bool {
+	if g == nil {
+		return false
+	}
+	active, err := g.stateManager.GetBool(ActiveVariable)
+	return err == nil && active
+}
+
+// Suppresses reports whether focus mode is on and holds the entity, so
+// non-critical automations should leave it alone
+func (g *Guard) Suppresses(entityID string) bool {
+	if g == nil || !g.entities[entityID] {
+		return false
+	}
+	return g.IsActive()
+}
+
+// Filter splits entity IDs into those that may be targeted and those held by
+// focus mode, preserving order
+func (g *Guard) Filter(entityIDs []string) (allowed []string, suppressed []string) {
+	for _, entityID := range entityIDs {
+		if g.Suppresses(entityID) {
+			suppressed = append(suppressed, entityID)
+		} else {
+			allowed = append(allowed, entityID)
+		}
+	}
+	return allowed, suppressed
+}
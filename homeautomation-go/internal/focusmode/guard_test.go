@@ -0,0 +1,39 @@
+package focusmode
+
+import (
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGuard_Filter(t *testing.T) {
+	stateManager := state.NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	guard := NewGuard(createTestConfig(), stateManager)
+
+	speakers := []string{"media_player.kitchen", "media_player.office"}
+	allowed, suppressed := guard.Filter(speakers)
+	assert.Equal(t, speakers, allowed, "nothing is held while focus mode is off")
+	assert.Empty(t, suppressed)
+
+	require.NoError(t, stateManager.SetBool(ActiveVariable, true))
+
+	allowed, suppressed = guard.Filter(speakers)
+	assert.Equal(t, []string{"media_player.kitchen"}, allowed)
+	assert.Equal(t, []string{"media_player.office"}, suppressed)
+	assert.True(t, guard.Suppresses("light.n_office"), "the office lights are held too")
+}
+
+func TestGuard_Nil(t *testing.T) {
+	var guard *Guard
+
+	assert.False(t, guard.IsActive())
+	assert.False(t, guard.Suppresses("media_player.office"))
+	allowed, suppressed := guard.Filter([]string{"media_player.office"})
+	assert.Equal(t, []string{"media_player.office"}, allowed)
+	assert.Empty(t, suppressed)
+}
@@ -0,0 +1,303 @@
+// Package focusmode implements office focus mode. While it is on, turned on
+// by hand or by a "Focus" calendar event, the office speakers are left out
+// of house music and non-critical TTS, and the office lights are held on a
+// concentration scene. The lights are restored when the window ends.
+package focusmode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// snapshotSceneID is the temporary HA scene holding the office lights captured before focus mode
+const snapshotSceneID = "office_focus_snapshot"
+
+// Manager turns focus mode on and off from the toggle and the calendar
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *Config
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+
+	// Whether focus mode is on, and the matching calendar event (protected by mu)
+	active        bool
+	calendarEvent string
+	mu            sync.Mutex
+
+	// Subscriptions for cleanup
+	subscriptions   []state.Subscription
+	haSubscriptions []ha.Subscription
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.FocusModeTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new focus mode manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Config, logger *zap.Logger, readOnly bool) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("focusmode")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowstate.NewFocusModeTracker(),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.FocusModeShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start subscribes to the toggle and calendar and applies the current state
+func (m *Manager) Start(ctx context.Context) error {
+	// Focus mode's work runs under ctx until Stop cancels it
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	s := m.config.FocusMode
+	m.logger.Info("Starting Focus Mode Manager",
+		zap.String("toggle_variable", s.ToggleVariable),
+		zap.String("calendar_entity", s.CalendarEntity),
+		zap.String("scene", s.Scene))
+
+	if s.ToggleVariable != "" {
+		sub, err := m.stateManager.Subscribe(s.ToggleVariable, func(key string, oldValue, newValue interface{}) {
+			m.evaluate(key)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", s.ToggleVariable, err)
+		}
+		m.subscriptions = append(m.subscriptions, sub)
+	}
+
+	if s.CalendarEntity != "" {
+		sub, err := m.haClient.SubscribeStateChanges(s.CalendarEntity, func(entityID string, oldState, newState *ha.State) {
+			m.setCalendarEvent(m.focusEvent(newState))
+			m.evaluate(entityID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", s.CalendarEntity, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, sub)
+
+		calendar, err := m.haClient.GetState(m.ctx, s.CalendarEntity)
+		if err != nil {
+			m.logger.Warn("Failed to read focus calendar", zap.Error(err))
+		} else {
+			m.setCalendarEvent(m.focusEvent(calendar))
+		}
+	}
+
+	m.evaluate("startup")
+
+	m.health.Started(len(m.subscriptions) + len(m.haSubscriptions))
+	m.logger.Info("Focus Mode Manager started successfully")
+	return nil
+}
+
+// Stop unsubscribes from the toggle and calendar
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Focus Mode Manager")
+
+	m.cancel()
+
+	for _, sub := range m.subscriptions {
+		sub.Unsubscribe()
+	}
+	m.subscriptions = nil
+
+	for _, sub := range m.haSubscriptions {
+		if err := sub.Unsubscribe(); err != nil {
+			m.logger.Warn("Failed to unsubscribe from calendar", zap.Error(err))
+		}
+	}
+	m.haSubscriptions = nil
+
+	m.logger.Info("Focus Mode Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-applies the concentration scene if focus mode is on
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Focus Mode - re-applying focus state")
+
+	m.mu.Lock()
+	active := m.active
+	m.mu.Unlock()
+
+	if active {
+		m.activateScene()
+	}
+
+	m.logger.Info("Successfully reset Focus Mode")
+	return nil
+}
+
+// IsActive reports whether focus mode is on
+func (m *Manager) IsActive() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// focusEvent returns the title of the calendar's current event if it is a
+// focus event, or "" if not
+func (m *Manager) focusEvent(calendar *ha.State) string {
+	if calendar == nil || calendar.State != "on" {
+		return ""
+	}
+	title, _ := calendar.Attributes["message"].(string)
+	if !strings.Contains(strings.ToLower(title), strings.ToLower(m.config.FocusMode.Keyword())) {
+		return ""
+	}
+	return title
+}
+
+// setCalendarEvent records the current focus event
+func (m *Manager) setCalendarEvent(title string) {
+	m.mu.Lock()
+	m.calendarEvent = title
+	m.mu.Unlock()
+}
+
+// evaluate turns focus mode on or off when the toggle or calendar changed it
+func (m *Manager) evaluate(trigger string) {
+	m.health.Tick()
+	toggle := false
+	if m.config.FocusMode.ToggleVariable != "" {
+		toggle, _ = m.stateManager.GetBool(m.config.FocusMode.ToggleVariable)
+	}
+
+	m.mu.Lock()
+	event := m.calendarEvent
+	wasActive := m.active
+	active := toggle || event != ""
+	m.active = active
+	m.mu.Unlock()
+
+	source := "toggle"
+	if !toggle && event != "" {
+		source = "calendar"
+	}
+	m.shadowTracker.UpdateInputs(toggle, event)
+
+	if active == wasActive {
+		return
+	}
+
+	now := m.clock.Now()
+	if active {
+		reason := "Focus mode on by toggle"
+		if source == "calendar" {
+			reason = fmt.Sprintf("Focus mode on for calendar event %q", event)
+		}
+		m.logger.Info("Focus mode on", zap.String("source", source), zap.String("trigger", trigger))
+		m.setActiveVariable(true)
+		m.snapshotLights()
+		m.activateScene()
+		m.shadowTracker.RecordChange(true, source, now, reason)
+		return
+	}
+
+	m.logger.Info("Focus mode off", zap.String("trigger", trigger))
+	m.setActiveVariable(false)
+	m.restoreLights()
+	m.shadowTracker.RecordChange(false, "", now, "Focus window ended")
+}
+
+// setActiveVariable publishes ActiveVariable. It is local-only, so it is set
+// in read-only mode too.
+func (m *Manager) setActiveVariable(active bool) {
+	if err := m.stateManager.SetBool(ActiveVariable, active); err != nil {
+		m.logger.Error("Failed to set "+ActiveVariable, zap.Error(err))
+	}
+}
+
+// snapshotLights captures the office lights so they can be restored
+func (m *Manager) snapshotLights() {
+	s := m.config.FocusMode
+	if s.Scene == "" {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would snapshot office lights", zap.Strings("lights", s.Lights))
+		return
+	}
+	if err := ha.SnapshotScene(m.ctx, m.haClient, snapshotSceneID, s.Lights); err != nil {
+		m.logger.Error("Failed to snapshot office lights", zap.Error(err))
+	}
+}
+
+// activateScene turns on the concentration scene
+func (m *Manager) activateScene() {
+	s := m.config.FocusMode
+	if s.Scene == "" {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would activate focus scene", zap.String("scene", s.Scene))
+		return
+	}
+	if err := m.haClient.CallService(m.ctx, "scene", "turn_on", map[string]interface{}{
+		"entity_id": s.Scene,
+	}); err != nil {
+		m.logger.Error("Failed to activate focus scene", zap.String("scene", s.Scene), zap.Error(err))
+	}
+}
+
+// restoreLights re-applies the lights captured when focus mode started
+func (m *Manager) restoreLights() {
+	if m.config.FocusMode.Scene == "" {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would restore office lights")
+		return
+	}
+	if err := ha.RestoreScene(m.ctx, m.haClient, snapshotSceneID); err != nil {
+		m.logger.Error("Failed to restore office lights", zap.Error(err))
+	}
+}
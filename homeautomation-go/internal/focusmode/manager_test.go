@@ -0,0 +1,129 @@
+package focusmode
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig() *Config {
+	return &Config{
+		FocusMode: Settings{
+			ToggleVariable: "isOfficeFocusMode",
+			CalendarEntity: "calendar.work",
+			Speakers:       []string{"media_player.office"},
+			Scene:          "scene.office_concentrate",
+			Lights:         []string{"light.n_office"},
+		},
+	}
+}
+
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("calendar.work", "off", map[string]interface{}{"message": "Standup"})
+
+	stateManager := state.NewManager(mockHA, logger, readOnly)
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, readOnly)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager
+}
+
+func sceneCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "scene" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestToggleStartsAndEndsFocus(t *testing.T) {
+	manager, mockHA, stateManager := setupTest(t, false)
+	assert.False(t, manager.IsActive())
+
+	require.NoError(t, stateManager.SetBool("isOfficeFocusMode", true))
+
+	active, _ := stateManager.GetBool(ActiveVariable)
+	assert.True(t, active)
+	calls := sceneCalls(mockHA)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "create", calls[0].Service, "the office lights are snapshotted first")
+	assert.Equal(t, []string{"light.n_office"}, calls[0].Data["snapshot_entities"])
+	assert.Equal(t, "turn_on", calls[1].Service)
+	assert.Equal(t, "scene.office_concentrate", calls[1].Data["entity_id"])
+
+	shadow := manager.GetShadowState()
+	assert.True(t, shadow.Outputs.Active)
+	assert.Equal(t, "toggle", shadow.Outputs.Source)
+
+	mockHA.ClearServiceCalls()
+	require.NoError(t, stateManager.SetBool("isOfficeFocusMode", false))
+
+	active, _ = stateManager.GetBool(ActiveVariable)
+	assert.False(t, active)
+	calls = sceneCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, "scene."+snapshotSceneID, calls[0].Data["entity_id"], "the lights are restored")
+	assert.False(t, manager.GetShadowState().Outputs.Active)
+}
+
+func TestCalendarFocusEvent(t *testing.T) {
+	manager, mockHA, stateManager := setupTest(t, false)
+
+	// Another event doesn't start focus mode
+	mockHA.SimulateStateChange("calendar.work", "on")
+	assert.False(t, manager.IsActive())
+
+	mockHA.SetState("calendar.work", "on", map[string]interface{}{"message": "Deep focus block"})
+	assert.True(t, manager.IsActive())
+	assert.Equal(t, "calendar", manager.GetShadowState().Outputs.Source)
+
+	// The toggle keeps focus mode on after the event ends
+	require.NoError(t, stateManager.SetBool("isOfficeFocusMode", true))
+	mockHA.SetState("calendar.work", "off", map[string]interface{}{"message": "Deep focus block"})
+	assert.True(t, manager.IsActive())
+
+	require.NoError(t, stateManager.SetBool("isOfficeFocusMode", false))
+	assert.False(t, manager.IsActive())
+}
+
+func TestStartDuringFocusEvent(t *testing.T) {
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("calendar.work", "on", map[string]interface{}{"message": "FOCUS"})
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, false)
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	assert.True(t, manager.IsActive(), "the keyword match is case-insensitive")
+	active, _ := stateManager.GetBool(ActiveVariable)
+	assert.True(t, active)
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	manager, mockHA, stateManager := setupTest(t, true)
+
+	// The toggle is synced, so flip it from Home Assistant
+	mockHA.SetState("input_boolean.office_focus_mode", "on", nil)
+
+	assert.True(t, manager.IsActive())
+	active, _ := stateManager.GetBool(ActiveVariable)
+	assert.True(t, active, "the local-only variable is still set")
+	assert.Empty(t, sceneCalls(mockHA))
+}
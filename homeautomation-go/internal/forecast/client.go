@@ -0,0 +1,129 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// StateKey is the local-only state variable the forecast is published to
+const StateKey = "solarForecast"
+
+// Client fetches the solar forecast on an interval and publishes it, summed
+// into hours, to the solarForecast state variable. A failed fetch keeps the
+// previous forecast, whose fetchedAt lets readers judge how stale it is.
+type Client struct {
+	provider     Provider
+	stateManager *state.Manager
+	interval     time.Duration
+	logger       *zap.Logger
+	clock        clock.Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewClient creates a new solar forecast client
+func NewClient(provider Provider, stateManager *state.Manager, config *Config, logger *zap.Logger) *Client {
+	return &Client{
+		provider:     provider,
+		stateManager: stateManager,
+		interval:     config.RefreshInterval(),
+		logger:       logger.Named("forecast"),
+		clock:        clock.NewRealClock(),
+	}
+}
+
+// SetClock sets the clock used to stamp fetched forecasts (for testing)
+func (c *Client) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Start fetches the forecast in the background, straight away and then every
+// refresh interval. Startup isn't held up by a slow or unreachable service.
+func (c *Client) Start() {
+	c.logger.Info("Starting solar forecast client",
+		zap.String("provider", c.provider.Name()),
+		zap.Duration("refresh_interval", c.interval))
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.wg.Add(1)
+	go c.run(c.ctx)
+}
+
+// Stop stops fetching and waits for an in-flight fetch to be abandoned
+func (c *Client) Stop() {
+	c.logger.Info("Stopping solar forecast client")
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+}
+
+// run refreshes the forecast until ctx is cancelled
+func (c *Client) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Warn("Failed to refresh solar forecast, keeping the previous one", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches the forecast once and publishes it
+func (c *Client) Refresh(ctx context.Context) error {
+	periods, err := c.provider.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	forecast := Forecast{
+		Provider:  c.provider.Name(),
+		FetchedAt: c.clock.Now().UTC(),
+		Periods:   Hourly(periods),
+	}
+	if err := Publish(c.stateManager, &forecast); err != nil {
+		return fmt.Errorf("failed to publish solar forecast: %w", err)
+	}
+
+	now := c.clock.Now()
+	c.logger.Info("Solar forecast refreshed",
+		zap.String("provider", forecast.Provider),
+		zap.Int("hours", len(forecast.Periods)),
+		zap.Float64("next_24h_kwh", forecast.EnergyBetween(now, now.Add(24*time.Hour))))
+	return nil
+}
+
+// Publish writes a forecast to the solarForecast state variable
+func Publish(stateManager *state.Manager, forecast *Forecast) error {
+	return stateManager.SetJSON(StateKey, *forecast)
+}
+
+// Load reads the forecast from the solarForecast state variable. It returns
+// nil if no forecast has been fetched yet.
+func Load(stateManager *state.Manager) (*Forecast, error) {
+	var forecast Forecast
+	if err := stateManager.GetJSON(StateKey, &forecast); err != nil {
+		return nil, err
+	}
+	if forecast.FetchedAt.IsZero() {
+		return nil, nil
+	}
+	return &forecast, nil
+}
@@ -0,0 +1,107 @@
+package forecast
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported forecast providers
+const (
+	ProviderSolcast       = "solcast"
+	ProviderForecastSolar = "forecast_solar"
+)
+
+// defaultRefreshMinutes keeps well inside the free tiers of both services
+// (Solcast allows 10 calls a day, forecast.solar 12 an hour)
+const defaultRefreshMinutes = 180
+
+// SolcastConfig identifies the rooftop site registered with Solcast
+type SolcastConfig struct {
+	ResourceID string `yaml:"resource_id"`
+}
+
+// ForecastSolarConfig describes the array to forecast.solar
+type ForecastSolarConfig struct {
+	Latitude    float64 `yaml:"latitude"`
+	Longitude   float64 `yaml:"longitude"`
+	Declination float64 `yaml:"declination"` // Panel tilt, 0 (horizontal) to 90 (vertical)
+	Azimuth     float64 `yaml:"azimuth"`     // -180 to 180, 0 is south, -90 east, 90 west
+	KWp         float64 `yaml:"kwp"`         // Installed peak power
+}
+
+// Config represents the solar_forecast_config.yaml structure. The API key,
+// required by Solcast and optional for forecast.solar, comes from the
+// SOLAR_FORECAST_API_KEY environment variable.
+type Config struct {
+	Provider       string               `yaml:"provider"` // "solcast" or "forecast_solar"
+	RefreshMinutes int                  `yaml:"refresh_minutes"`
+	Solcast        *SolcastConfig       `yaml:"solcast"`
+	ForecastSolar  *ForecastSolarConfig `yaml:"forecast_solar"`
+}
+
+// RefreshInterval returns how often the forecast is fetched
+func (c *Config) RefreshInterval() time.Duration {
+	if c.RefreshMinutes <= 0 {
+		return defaultRefreshMinutes * time.Minute
+	}
+	return time.Duration(c.RefreshMinutes) * time.Minute
+}
+
+// Validate checks the provider has the settings it needs
+func (c *Config) Validate() error {
+	if c.RefreshMinutes < 0 {
+		return fmt.Errorf("refresh_minutes must not be negative")
+	}
+
+	switch c.Provider {
+	case ProviderSolcast:
+		if c.Solcast == nil || c.Solcast.ResourceID == "" {
+			return fmt.Errorf("solcast.resource_id is required for the solcast provider")
+		}
+	case ProviderForecastSolar:
+		fs := c.ForecastSolar
+		if fs == nil {
+			return fmt.Errorf("forecast_solar settings are required for the forecast_solar provider")
+		}
+		if fs.Latitude < -90 || fs.Latitude > 90 {
+			return fmt.Errorf("forecast_solar.latitude must be between -90 and 90")
+		}
+		if fs.Longitude < -180 || fs.Longitude > 180 {
+			return fmt.Errorf("forecast_solar.longitude must be between -180 and 180")
+		}
+		if fs.Declination < 0 || fs.Declination > 90 {
+			return fmt.Errorf("forecast_solar.declination must be between 0 and 90")
+		}
+		if fs.Azimuth < -180 || fs.Azimuth > 180 {
+			return fmt.Errorf("forecast_solar.azimuth must be between -180 and 180")
+		}
+		if fs.KWp <= 0 {
+			return fmt.Errorf("forecast_solar.kwp must be positive")
+		}
+	default:
+		return fmt.Errorf("provider must be %q or %q, got %q", ProviderSolcast, ProviderForecastSolar, c.Provider)
+	}
+	return nil
+}
+
+// LoadConfig loads the solar forecast configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,79 @@
+// Package forecast fetches hourly solar production forecasts from Solcast or
+// forecast.solar and publishes them to the solarForecast state variable, so
+// the energy plugin can look ahead at the sun still to come.
+package forecast
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Period is the solar energy forecast to be produced between Start and End
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	KWh   float64   `json:"kwh"`
+}
+
+// Forecast is one fetched solar production forecast
+type Forecast struct {
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Periods   []Period  `json:"periods"` // Ordered by start, not overlapping
+}
+
+// Provider fetches a solar production forecast from a forecast service
+type Provider interface {
+	Name() string
+	Fetch(ctx context.Context) ([]Period, error)
+}
+
+// EnergyBetween returns the energy forecast to be produced between from and
+// to, counting the overlapping share of periods that only partly fall within
+func (f *Forecast) EnergyBetween(from, to time.Time) float64 {
+	var total float64
+	for _, p := range f.Periods {
+		start, end := p.Start, p.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		length := p.End.Sub(p.Start)
+		if !end.After(start) || length <= 0 {
+			continue
+		}
+		total += p.KWh * float64(end.Sub(start)) / float64(length)
+	}
+	return total
+}
+
+// Hourly sums periods into clock hours, splitting periods that cross an hour
+// boundary. Hours without any forecast period are left out.
+func Hourly(periods []Period) []Period {
+	byHour := make(map[time.Time]float64)
+	for _, p := range periods {
+		length := p.End.Sub(p.Start)
+		if length <= 0 {
+			continue
+		}
+		for start := p.Start; start.Before(p.End); {
+			hour := start.Truncate(time.Hour)
+			end := hour.Add(time.Hour)
+			if end.After(p.End) {
+				end = p.End
+			}
+			byHour[hour] += p.KWh * float64(end.Sub(start)) / float64(length)
+			start = end
+		}
+	}
+
+	hourly := make([]Period, 0, len(byHour))
+	for hour, kwh := range byHour {
+		hourly = append(hourly, Period{Start: hour.UTC(), End: hour.Add(time.Hour).UTC(), KWh: kwh})
+	}
+	sort.Slice(hourly, func(i, j int) bool { return hourly[i].Start.Before(hourly[j].Start) })
+	return hourly
+}
@@ -0,0 +1,222 @@
+package forecast
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var noon = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEnergyBetween_ProratesPartialPeriods(t *testing.T) {
+	f := &Forecast{Periods: []Period{
+		{Start: noon, End: noon.Add(time.Hour), KWh: 4},
+		{Start: noon.Add(time.Hour), End: noon.Add(2 * time.Hour), KWh: 2},
+	}}
+
+	assert.InDelta(t, 6, f.EnergyBetween(noon.Add(-time.Hour), noon.Add(3*time.Hour)), 1e-9)
+	assert.InDelta(t, 3, f.EnergyBetween(noon.Add(30*time.Minute), noon.Add(90*time.Minute)), 1e-9)
+	assert.Zero(t, f.EnergyBetween(noon.Add(2*time.Hour), noon.Add(3*time.Hour)))
+}
+
+func TestHourly_SplitsAndSumsPeriods(t *testing.T) {
+	hourly := Hourly([]Period{
+		{Start: noon, End: noon.Add(30 * time.Minute), KWh: 1},
+		{Start: noon.Add(30 * time.Minute), End: noon.Add(90 * time.Minute), KWh: 2},
+	})
+
+	require.Len(t, hourly, 2)
+	assert.Equal(t, Period{Start: noon, End: noon.Add(time.Hour), KWh: 2}, hourly[0])
+	assert.Equal(t, Period{Start: noon.Add(time.Hour), End: noon.Add(2 * time.Hour), KWh: 1}, hourly[1])
+}
+
+func TestSolcastProvider_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rooftop_sites/abcd-1234/forecasts", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"forecasts":[
+			{"pv_estimate":4.0,"period_end":"2026-06-01T13:00:00.0000000Z","period":"PT30M"},
+			{"pv_estimate":2.0,"period_end":"2026-06-01T12:30:00.0000000Z","period":"PT30M"}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider := &solcastProvider{config: SolcastConfig{ResourceID: "abcd-1234"}, apiKey: "secret", baseURL: server.URL, httpClient: server.Client()}
+	periods, err := provider.Fetch(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []Period{
+		{Start: noon, End: noon.Add(30 * time.Minute), KWh: 1},
+		{Start: noon.Add(30 * time.Minute), End: noon.Add(time.Hour), KWh: 2},
+	}, periods)
+}
+
+func TestSolcastProvider_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider := &solcastProvider{config: SolcastConfig{ResourceID: "abcd"}, baseURL: server.URL, httpClient: server.Client()}
+	_, err := provider.Fetch(context.Background())
+	assert.EqualError(t, err, "solcast: unexpected status 429: rate limit exceeded")
+}
+
+func TestForecastSolarProvider_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/key/estimate/watthours/period/32.85/-97.5/25/0/7.6", r.URL.Path)
+		_, _ = w.Write([]byte(`{"result":{
+			"2026-06-01 06:30:00":0,
+			"2026-06-01 07:00:00":150,
+			"2026-06-01 08:00:00":900,
+			"2026-06-02 06:31:00":0,
+			"2026-06-02 07:00:00":120
+		},"message":{"code":0,"type":"success","info":{"timezone":"America/Chicago"}}}`))
+	}))
+	defer server.Close()
+
+	provider := &forecastSolarProvider{
+		config:     ForecastSolarConfig{Latitude: 32.85, Longitude: -97.5, Declination: 25, Azimuth: 0, KWp: 7.6},
+		apiKey:     "key",
+		baseURL:    server.URL,
+		httpClient: server.Client(),
+	}
+	periods, err := provider.Fetch(context.Background())
+	require.NoError(t, err)
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 6, day, hour, minute, 0, 0, chicago).UTC()
+	}
+
+	// Overnight gaps aren't periods
+	require.Len(t, periods, 3)
+	assert.Equal(t, Period{Start: at(1, 6, 30), End: at(1, 7, 0), KWh: 0.15}, periods[0])
+	assert.Equal(t, Period{Start: at(1, 7, 0), End: at(1, 8, 0), KWh: 0.9}, periods[1])
+	assert.Equal(t, Period{Start: at(2, 6, 31), End: at(2, 7, 0), KWh: 0.12}, periods[2])
+}
+
+func TestParsePeriod(t *testing.T) {
+	d, err := parsePeriod("PT15M")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, d)
+
+	d, err = parsePeriod("PT1H")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, d)
+
+	_, err = parsePeriod("P1D")
+	assert.Error(t, err)
+}
+
+// stubProvider returns fixed periods, or an error
+type stubProvider struct {
+	periods []Period
+	err     error
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+func (p *stubProvider) Fetch(ctx context.Context) ([]Period, error) { return p.periods, p.err }
+
+func TestClient_RefreshPublishesHourlyForecast(t *testing.T) {
+	stateManager := state.NewManager(ha.NewMockClient(), zap.NewNop(), false)
+
+	forecast, err := Load(stateManager)
+	require.NoError(t, err)
+	assert.Nil(t, forecast, "nothing fetched yet")
+
+	provider := &stubProvider{periods: []Period{
+		{Start: noon, End: noon.Add(30 * time.Minute), KWh: 1.5},
+		{Start: noon.Add(30 * time.Minute), End: noon.Add(time.Hour), KWh: 2.5},
+	}}
+	client := NewClient(provider, stateManager, &Config{}, zap.NewNop())
+	client.SetClock(clock.NewMockClock(noon))
+
+	require.NoError(t, client.Refresh(context.Background()))
+
+	forecast, err = Load(stateManager)
+	require.NoError(t, err)
+	require.NotNil(t, forecast)
+	assert.Equal(t, "stub", forecast.Provider)
+	assert.True(t, forecast.FetchedAt.Equal(noon))
+	require.Len(t, forecast.Periods, 1)
+	assert.InDelta(t, 4, forecast.Periods[0].KWh, 1e-9)
+
+	// A failed fetch keeps the previous forecast
+	provider.err = assert.AnError
+	assert.ErrorIs(t, client.Refresh(context.Background()), assert.AnError)
+	forecast, err = Load(stateManager)
+	require.NoError(t, err)
+	assert.True(t, forecast.FetchedAt.Equal(noon))
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("../../../configs/solar_forecast_config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderForecastSolar, config.Provider)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "solcast",
+			yaml: "provider: solcast\nsolcast:\n  resource_id: abcd\n",
+		},
+		{
+			name:    "unknown provider",
+			yaml:    "provider: pvgis\n",
+			wantErr: `provider must be "solcast" or "forecast_solar", got "pvgis"`,
+		},
+		{
+			name:    "solcast without site",
+			yaml:    "provider: solcast\n",
+			wantErr: "solcast.resource_id is required for the solcast provider",
+		},
+		{
+			name:    "forecast.solar without kwp",
+			yaml:    "provider: forecast_solar\nforecast_solar:\n  latitude: 32.8\n  longitude: -97.5\n",
+			wantErr: "forecast_solar.kwp must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "solar_forecast_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewProvider_SolcastNeedsAPIKey(t *testing.T) {
+	config := &Config{Provider: ProviderSolcast, Solcast: &SolcastConfig{ResourceID: "abcd"}}
+
+	_, err := NewProvider(config, "")
+	assert.Error(t, err)
+
+	provider, err := NewProvider(config, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderSolcast, provider.Name())
+}
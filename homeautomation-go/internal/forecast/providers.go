@@ -0,0 +1,208 @@
+package forecast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default API endpoints
+const (
+	solcastBaseURL       = "https://api.solcast.com.au"
+	forecastSolarBaseURL = "https://api.forecast.solar"
+)
+
+// requestTimeout bounds one forecast request
+const requestTimeout = 30 * time.Second
+
+// NewProvider creates the provider the config selects
+func NewProvider(config *Config, apiKey string) (Provider, error) {
+	httpClient := &http.Client{Timeout: requestTimeout}
+	switch config.Provider {
+	case ProviderSolcast:
+		if apiKey == "" {
+			return nil, fmt.Errorf("the solcast provider needs an API key in SOLAR_FORECAST_API_KEY")
+		}
+		return &solcastProvider{config: *config.Solcast, apiKey: apiKey, baseURL: solcastBaseURL, httpClient: httpClient}, nil
+	case ProviderForecastSolar:
+		return &forecastSolarProvider{config: *config.ForecastSolar, apiKey: apiKey, baseURL: forecastSolarBaseURL, httpClient: httpClient}, nil
+	}
+	return nil, fmt.Errorf("unknown forecast provider %q", config.Provider)
+}
+
+// getJSON fetches url and decodes its JSON body into target
+func getJSON(ctx context.Context, httpClient *http.Client, url string, header http.Header, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// solcastProvider fetches the rooftop site forecast from Solcast
+type solcastProvider struct {
+	config     SolcastConfig
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (p *solcastProvider) Name() string { return ProviderSolcast }
+
+// solcastResponse is the body of a rooftop site forecast. Each estimate is
+// the average power in kW over the period ending at period_end.
+type solcastResponse struct {
+	Forecasts []struct {
+		PVEstimate float64 `json:"pv_estimate"`
+		PeriodEnd  string  `json:"period_end"`
+		Period     string  `json:"period"` // ISO 8601 duration, e.g. "PT30M"
+	} `json:"forecasts"`
+}
+
+// Fetch returns the next 48 hours of the site's forecast
+func (p *solcastProvider) Fetch(ctx context.Context) ([]Period, error) {
+	endpoint := fmt.Sprintf("%s/rooftop_sites/%s/forecasts?format=json&hours=48", p.baseURL, url.PathEscape(p.config.ResourceID))
+	header := http.Header{"Authorization": []string{"Bearer " + p.apiKey}}
+
+	var body solcastResponse
+	if err := getJSON(ctx, p.httpClient, endpoint, header, &body); err != nil {
+		return nil, fmt.Errorf("solcast: %w", err)
+	}
+
+	periods := make([]Period, 0, len(body.Forecasts))
+	for _, f := range body.Forecasts {
+		end, err := time.Parse(time.RFC3339, f.PeriodEnd)
+		if err != nil {
+			return nil, fmt.Errorf("solcast: invalid period_end %q: %w", f.PeriodEnd, err)
+		}
+		end = end.UTC()
+		length, err := parsePeriod(f.Period)
+		if err != nil {
+			return nil, fmt.Errorf("solcast: %w", err)
+		}
+		periods = append(periods, Period{
+			Start: end.Add(-length),
+			End:   end,
+			KWh:   f.PVEstimate * length.Hours(),
+		})
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+	return periods, nil
+}
+
+// parsePeriod parses the minute and hour ISO 8601 durations Solcast uses
+func parsePeriod(period string) (time.Duration, error) {
+	value, ok := strings.CutPrefix(period, "PT")
+	if ok && len(value) > 1 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err == nil && n > 0 {
+			switch value[len(value)-1] {
+			case 'M':
+				return time.Duration(n) * time.Minute, nil
+			case 'H':
+				return time.Duration(n) * time.Hour, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unsupported period %q", period)
+}
+
+// forecastSolarProvider fetches the array's estimate from forecast.solar
+type forecastSolarProvider struct {
+	config     ForecastSolarConfig
+	apiKey     string // Optional; the public API works without one
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (p *forecastSolarProvider) Name() string { return ProviderForecastSolar }
+
+// forecastSolarResponse is the body of a watthours/period estimate. Result
+// maps the local time each period ends to the Wh produced since the previous
+// one; each day's first entry is sunrise.
+type forecastSolarResponse struct {
+	Result  map[string]float64 `json:"result"`
+	Message struct {
+		Info struct {
+			Timezone string `json:"timezone"`
+		} `json:"info"`
+	} `json:"message"`
+}
+
+// Fetch returns the estimate for today and tomorrow
+func (p *forecastSolarProvider) Fetch(ctx context.Context) ([]Period, error) {
+	base := p.baseURL
+	if p.apiKey != "" {
+		base += "/" + url.PathEscape(p.apiKey)
+	}
+	c := p.config
+	endpoint := fmt.Sprintf("%s/estimate/watthours/period/%s/%s/%s/%s/%s", base,
+		formatFloat(c.Latitude), formatFloat(c.Longitude), formatFloat(c.Declination), formatFloat(c.Azimuth), formatFloat(c.KWp))
+
+	var body forecastSolarResponse
+	if err := getJSON(ctx, p.httpClient, endpoint, nil, &body); err != nil {
+		return nil, fmt.Errorf("forecast.solar: %w", err)
+	}
+
+	loc := time.UTC
+	if body.Message.Info.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(body.Message.Info.Timezone); err != nil {
+			return nil, fmt.Errorf("forecast.solar: unknown timezone %q", body.Message.Info.Timezone)
+		}
+	}
+
+	ends := make([]time.Time, 0, len(body.Result))
+	wh := make(map[time.Time]float64, len(body.Result))
+	for stamp, value := range body.Result {
+		end, err := time.ParseInLocation(time.DateTime, stamp, loc)
+		if err != nil {
+			return nil, fmt.Errorf("forecast.solar: invalid timestamp %q: %w", stamp, err)
+		}
+		end = end.UTC()
+		ends = append(ends, end)
+		wh[end] = value
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i].Before(ends[j]) })
+
+	periods := make([]Period, 0, len(ends))
+	for i := 1; i < len(ends); i++ {
+		start, end := ends[i-1], ends[i]
+		// The first entry of a day is sunrise, not the end of a period
+		if start.In(loc).YearDay() != end.In(loc).YearDay() {
+			continue
+		}
+		periods = append(periods, Period{Start: start, End: end, KWh: wh[end] / 1000})
+	}
+	return periods, nil
+}
+
+// formatFloat formats a coordinate or array setting for the request path
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,256 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CommandPriority ranks plugins whose service calls target the same entity.
+// A higher priority plugin's call holds the entity for a while, and lower
+// priority calls to it are suppressed until the hold ends.
+type CommandPriority int
+
+// Priority classes, lowest first
+const (
+	PriorityAmbiance  CommandPriority = iota + 1 // Scenes, music and everything not listed
+	PrioritySleep                                // Sleep routines, e.g. a wake-up fade
+	PrioritySecurity                             // Locks, lockdown and the alarm
+	PriorityEmergency                            // Smoke/CO response
+)
+
+// String returns the priority class name
+func (p CommandPriority) String() string {
+	switch p {
+	case PriorityAmbiance:
+		return "ambiance"
+	case PrioritySleep:
+		return "sleep"
+	case PrioritySecurity:
+		return "security"
+	case PriorityEmergency:
+		return "emergency"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// DefaultArbiterHold is how long an entity stays held by the last plugin to
+// command it
+const DefaultArbiterHold = 10 * time.Second
+
+// DefaultCommandPriorities are the priority classes of the plugins that
+// outrank ambiance. Plugins not listed are ambiance.
+var DefaultCommandPriorities = map[string]CommandPriority{
+	"emergency":      PriorityEmergency,
+	"security":       PrioritySecurity,
+	"sleephygiene":   PrioritySleep,
+	"sleepfan":       PrioritySleep,
+	"bedroomcomfort": PrioritySleep,
+}
+
+// entityOwner is the plugin that last commanded an entity
+type entityOwner struct {
+	plugin   string
+	priority CommandPriority
+	service  string
+	until    time.Time
+}
+
+// Arbiter serializes service calls per entity across plugins and resolves
+// conflicting calls by priority class. Calls to the same entity run one at a
+// time in arrival order. A call holds its entities for the hold window;
+// within it, another plugin's call to one of them is dropped if its priority
+// is lower and overrides the holder's otherwise. Both are logged.
+// A plugin never conflicts with itself.
+type Arbiter struct {
+	priorities map[string]CommandPriority
+	hold       time.Duration
+	logger     *zap.Logger
+	now        func() time.Time
+
+	// Per-entity call locks and holders (protected by mu)
+	lanes  map[string]*sync.Mutex
+	owners map[string]entityOwner
+	mu     sync.Mutex
+}
+
+// NewArbiter creates an arbiter with the plugins' priority classes and the
+// hold window. A hold of zero or less only serializes calls.
+func NewArbiter(priorities map[string]CommandPriority, hold time.Duration, logger *zap.Logger) *Arbiter {
+	return &Arbiter{
+		priorities: priorities,
+		hold:       hold,
+		logger:     logger.Named("arbiter"),
+		now:        time.Now,
+		lanes:      make(map[string]*sync.Mutex),
+		owners:     make(map[string]entityOwner),
+	}
+}
+
+// Priority returns a plugin's priority class
+func (a *Arbiter) Priority(plugin string) CommandPriority {
+	if priority, ok := a.priorities[plugin]; ok {
+		return priority
+	}
+	return PriorityAmbiance
+}
+
+// Client wraps a plugin's HA client so its service calls are arbitrated.
+// Everything else passes through.
+func (a *Arbiter) Client(plugin string, client HAClient) HAClient {
+	if a == nil {
+		return client
+	}
+	return &arbitratedClient{HAClient: client, arbiter: a, plugin: plugin}
+}
+
+// call runs send once no other call to the same entities is in flight. If a
+// higher priority plugin holds one of them, the call is dropped and logged
+// like a warm-up or read-only write: it returns nil, since the plugin did
+// nothing wrong.
+func (a *Arbiter) call(plugin, service string, entities []string, send func() error) error {
+	if len(entities) == 0 {
+		return send()
+	}
+
+	// Lock in a fixed order so calls sharing several entities can't deadlock
+	lanes := a.entityLanes(entities)
+	for _, lane := range lanes {
+		lane.Lock()
+	}
+	defer func() {
+		for _, lane := range lanes {
+			lane.Unlock()
+		}
+	}()
+
+	if !a.claim(plugin, service, entities) {
+		return nil
+	}
+	return send()
+}
+
+// entityLanes returns the call locks for the entities, sorted by entity ID
+func (a *Arbiter) entityLanes(entities []string) []*sync.Mutex {
+	sorted := append([]string(nil), entities...)
+	sort.Strings(sorted)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	lanes := make([]*sync.Mutex, 0, len(sorted))
+	for i, entityID := range sorted {
+		if i > 0 && sorted[i-1] == entityID {
+			continue
+		}
+		lane, ok := a.lanes[entityID]
+		if !ok {
+			lane = &sync.Mutex{}
+			a.lanes[entityID] = lane
+		}
+		lanes = append(lanes, lane)
+	}
+	return lanes
+}
+
+// claim makes the plugin the holder of the entities. It returns false if a
+// higher priority plugin holds any of them.
+func (a *Arbiter) claim(plugin, service string, entities []string) bool {
+	priority := a.Priority(plugin)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for _, entityID := range entities {
+		owner, ok := a.owners[entityID]
+		if !ok || owner.plugin == plugin || !now.Before(owner.until) || owner.priority <= priority {
+			continue
+		}
+		a.logger.Warn("ARBITER: Suppressed service call",
+			zap.String("plugin", plugin),
+			zap.Stringer("priority", priority),
+			zap.String("service", service),
+			zap.String("entity_id", entityID),
+			zap.String("held_by", owner.plugin),
+			zap.Stringer("held_priority", owner.priority),
+			zap.String("held_service", owner.service),
+			zap.Duration("remaining", owner.until.Sub(now).Round(time.Second)))
+		return false
+	}
+
+	for _, entityID := range entities {
+		if owner, ok := a.owners[entityID]; ok && owner.plugin != plugin && now.Before(owner.until) {
+			a.logger.Info("ARBITER: Overrode another plugin's call",
+				zap.String("plugin", plugin),
+				zap.Stringer("priority", priority),
+				zap.String("service", service),
+				zap.String("entity_id", entityID),
+				zap.String("overridden", owner.plugin),
+				zap.Stringer("overridden_priority", owner.priority),
+				zap.String("overridden_service", owner.service))
+		}
+		a.owners[entityID] = entityOwner{plugin: plugin, priority: priority, service: service, until: now.Add(a.hold)}
+	}
+	return true
+}
+
+// arbitratedClient routes a plugin's service calls through the arbiter
+type arbitratedClient struct {
+	HAClient
+	arbiter *Arbiter
+	plugin  string
+}
+
+// CallService runs the call once the arbiter allows it
+func (c *arbitratedClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	entities, err := c.targets(data)
+	if err != nil {
+		return err
+	}
+	return c.arbiter.call(c.plugin, domain+"."+service, entities, func() error {
+		return c.HAClient.CallService(ctx, domain, service, data)
+	})
+}
+
+// Expand keeps entity group references working through the wrapper
+func (c *arbitratedClient) Expand(ids []string) ([]string, error) {
+	if expander, ok := c.HAClient.(interface {
+		Expand(ids []string) ([]string, error)
+	}); ok {
+		return expander.Expand(ids)
+	}
+	return ids, nil
+}
+
+// targets returns the entities a call acts on: the speakers of a TTS call,
+// the entity_id of anything else, with entity group references expanded
+func (c *arbitratedClient) targets(data map[string]interface{}) ([]string, error) {
+	value, ok := data["media_player_entity_id"]
+	if !ok {
+		value = data["entity_id"]
+	}
+
+	var ids []string
+	switch v := value.(type) {
+	case string:
+		ids = []string{v}
+	case []string:
+		ids = v
+	case []interface{}:
+		for _, item := range v {
+			if id, ok := item.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return c.Expand(ids)
+}
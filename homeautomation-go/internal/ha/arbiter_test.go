@@ -0,0 +1,135 @@
+package ha
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestArbiter(hold time.Duration) (*Arbiter, *time.Time) {
+	now := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+	a := NewArbiter(DefaultCommandPriorities, hold, zap.NewNop())
+	a.now = func() time.Time { return now }
+	return a, &now
+}
+
+func TestArbiter_Priority(t *testing.T) {
+	a, _ := newTestArbiter(DefaultArbiterHold)
+
+	assert.Equal(t, PriorityEmergency, a.Priority("emergency"))
+	assert.Equal(t, PrioritySecurity, a.Priority("security"))
+	assert.Equal(t, PrioritySleep, a.Priority("sleephygiene"))
+	assert.Equal(t, PriorityAmbiance, a.Priority("music"), "unlisted plugins are ambiance")
+	assert.Equal(t, "sleep", PrioritySleep.String())
+}
+
+// TestArbiter_SuppressesLowerPriority tests that a lower priority plugin's
+// call to an entity held by a higher priority plugin is dropped until the
+// hold ends
+func TestArbiter_SuppressesLowerPriority(t *testing.T) {
+	a, now := newTestArbiter(10 * time.Second)
+	mock := NewMockClient()
+	ctx := context.Background()
+
+	sleep := a.Client("sleephygiene", mock)
+	music := a.Client("music", mock)
+
+	require.NoError(t, sleep.CallService(ctx, "media_player", "volume_set", map[string]interface{}{
+		"entity_id": "media_player.bedroom", "volume_level": 0.2,
+	}))
+
+	require.NoError(t, music.CallService(ctx, "media_player", "volume_mute", map[string]interface{}{
+		"entity_id": []string{"media_player.kitchen", "media_player.bedroom"}, "is_volume_muted": true,
+	}))
+	assert.Len(t, mock.GetServiceCalls(), 1, "the suppressed call is not sent")
+
+	require.NoError(t, music.CallService(ctx, "media_player", "volume_mute", map[string]interface{}{
+		"entity_id": "media_player.kitchen", "is_volume_muted": true,
+	}), "other entities are not held")
+
+	*now = now.Add(10 * time.Second)
+	require.NoError(t, music.CallService(ctx, "media_player", "volume_mute", map[string]interface{}{
+		"entity_id": "media_player.bedroom", "is_volume_muted": true,
+	}), "the hold has ended")
+	assert.Len(t, mock.GetServiceCalls(), 3)
+}
+
+// TestArbiter_Overrides tests that an equal or higher priority plugin takes
+// an entity over, and that a plugin never conflicts with itself
+func TestArbiter_Overrides(t *testing.T) {
+	a, _ := newTestArbiter(10 * time.Second)
+	mock := NewMockClient()
+	ctx := context.Background()
+
+	music := a.Client("music", mock)
+	lighting := a.Client("lighting", mock)
+	emergency := a.Client("emergency", mock)
+	security := a.Client("security", mock)
+
+	require.NoError(t, music.CallService(ctx, "media_player", "media_play", map[string]interface{}{"entity_id": "media_player.kitchen"}))
+	require.NoError(t, music.CallService(ctx, "media_player", "volume_set", map[string]interface{}{"entity_id": "media_player.kitchen"}))
+	require.NoError(t, lighting.CallService(ctx, "media_player", "media_pause", map[string]interface{}{"entity_id": "media_player.kitchen"}), "equal priority overrides")
+	require.NoError(t, emergency.CallService(ctx, "tts", "speak", map[string]interface{}{
+		"entity_id":              "tts.google_en_com",
+		"media_player_entity_id": []interface{}{"media_player.kitchen"},
+	}), "a TTS call acts on its speakers")
+
+	require.NoError(t, security.CallService(ctx, "media_player", "volume_set", map[string]interface{}{"entity_id": "media_player.kitchen"}))
+	assert.Len(t, mock.GetServiceCalls(), 4, "the emergency now holds the speaker")
+
+	require.NoError(t, security.CallService(ctx, "tts", "speak", map[string]interface{}{"entity_id": "tts.google_en_com"}))
+	require.NoError(t, music.CallService(ctx, "notify", "notify", map[string]interface{}{"message": "hi"}))
+	assert.Len(t, mock.GetServiceCalls(), 6, "the TTS entity itself is not held, and calls without a target pass through")
+}
+
+// TestArbiter_SerializesPerEntity tests that calls to the same entity don't
+// run concurrently
+func TestArbiter_SerializesPerEntity(t *testing.T) {
+	a, _ := newTestArbiter(0)
+	client := &concurrencyClient{MockClient: NewMockClient()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(plugin string) {
+			defer wg.Done()
+			_ = a.Client(plugin, client).CallService(context.Background(), "light", "turn_on", map[string]interface{}{
+				"entity_id": []string{"light.kitchen", "light.hallway"},
+			})
+		}([]string{"lighting", "sleephygiene"}[i%2])
+	}
+	wg.Wait()
+
+	assert.Equal(t, 20, len(client.GetServiceCalls()), "a hold of zero only serializes")
+	assert.Equal(t, int32(1), client.maxInFlight)
+}
+
+// concurrencyClient records the most service calls it has seen in flight
+type concurrencyClient struct {
+	*MockClient
+	mu          sync.Mutex
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (c *concurrencyClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+	err := c.MockClient.CallService(ctx, domain, service, data)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return err
+}
@@ -0,0 +1,77 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MaxBatchParallelism is how many calls of a CallServices batch are in flight
+// at once
+const MaxBatchParallelism = 5
+
+// CallError is a failed call of a CallServices batch
+type CallError struct {
+	Index int // Position of the call in the batch
+	Call  ServiceCall
+	Err   error
+}
+
+func (e *CallError) Error() string {
+	if entityID, ok := e.Call.Data["entity_id"]; ok {
+		return fmt.Sprintf("%s.%s %v: %v", e.Call.Domain, e.Call.Service, entityID, e.Err)
+	}
+	return fmt.Sprintf("%s.%s: %v", e.Call.Domain, e.Call.Service, e.Err)
+}
+
+func (e *CallError) Unwrap() error { return e.Err }
+
+// CallServices makes a batch of service calls through client concurrently,
+// at most MaxBatchParallelism at a time, so commanding several speakers or
+// lights takes about as long as the slowest call instead of the sum.
+// It waits for every call it started and returns the failures joined, each a
+// *CallError. Once ctx is cancelled no more calls are started, in-flight calls
+// are abandoned through ctx, and the calls never sent are reported with
+// ctx's error. Calls have no order; a batch is for independent calls.
+func CallServices(ctx context.Context, client HAClient, calls []ServiceCall) error {
+	var (
+		errs []error
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+	slots := make(chan struct{}, MaxBatchParallelism)
+
+	started := 0
+	for i, call := range calls {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		started++
+		wg.Add(1)
+		go func(i int, call ServiceCall) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := client.CallService(ctx, call.Domain, call.Service, call.Data); err != nil {
+				mu.Lock()
+				errs = append(errs, &CallError{Index: i, Call: call, Err: err})
+				mu.Unlock()
+			}
+		}(i, call)
+	}
+	wg.Wait()
+
+	if started < len(calls) {
+		errs = append(errs, fmt.Errorf("%d of %d service calls not sent: %w", len(calls)-started, len(calls), ctx.Err()))
+	}
+	return errors.Join(errs...)
+}
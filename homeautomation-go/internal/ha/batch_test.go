@@ -0,0 +1,108 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchTestClient is a MockClient whose calls block until released and fail
+// for entities in fail
+type batchTestClient struct {
+	*MockClient
+	release  chan struct{}
+	fail     map[string]bool
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (c *batchTestClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	c.mu.Lock()
+	c.inFlight++
+	c.peak = max(c.peak, c.inFlight)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c.fail[data["entity_id"].(string)] {
+		return errors.New("HA error: not_found")
+	}
+	return c.MockClient.CallService(ctx, domain, service, data)
+}
+
+func muteCalls(n int) []ServiceCall {
+	calls := make([]ServiceCall, n)
+	for i := range calls {
+		calls[i] = ServiceCall{Domain: "media_player", Service: "volume_set", Data: Volume{}.ServiceData(fmt.Sprintf("media_player.speaker_%d", i))}
+	}
+	return calls
+}
+
+func TestCallServices(t *testing.T) {
+	client := &batchTestClient{MockClient: NewMockClient(), release: make(chan struct{}), fail: map[string]bool{"media_player.speaker_3": true}}
+	close(client.release)
+
+	err := CallServices(context.Background(), client, muteCalls(8))
+
+	var callErr *CallError
+	require.ErrorAs(t, err, &callErr)
+	assert.Equal(t, 3, callErr.Index)
+	assert.Equal(t, "media_player.speaker_3", callErr.Call.Data["entity_id"])
+	assert.EqualError(t, err, "media_player.volume_set media_player.speaker_3: HA error: not_found")
+	assert.Len(t, client.GetServiceCalls(), 7, "the other calls still run")
+
+	assert.NoError(t, CallServices(context.Background(), client, nil))
+}
+
+func TestCallServices_BoundedParallelism(t *testing.T) {
+	client := &batchTestClient{MockClient: NewMockClient(), release: make(chan struct{})}
+
+	done := make(chan error)
+	go func() { done <- CallServices(context.Background(), client, muteCalls(12)) }()
+
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.inFlight == MaxBatchParallelism
+	}, time.Second, time.Millisecond)
+	close(client.release)
+
+	require.NoError(t, <-done)
+	assert.Len(t, client.GetServiceCalls(), 12)
+	assert.Equal(t, MaxBatchParallelism, client.peak)
+}
+
+func TestCallServices_Cancellation(t *testing.T) {
+	client := &batchTestClient{MockClient: NewMockClient(), release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() { done <- CallServices(ctx, client, muteCalls(12)) }()
+
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.inFlight == MaxBatchParallelism
+	}, time.Second, time.Millisecond)
+	cancel()
+
+	err := <-done
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "7 of 12 service calls not sent")
+	assert.Empty(t, client.GetServiceCalls(), "in-flight calls are abandoned")
+}
@@ -11,18 +11,48 @@ import (
 	"go.uber.org/zap"
 )
 
-// HAClient defines the interface for Home Assistant WebSocket client
+// DefaultRequestTimeout bounds a request to Home Assistant when the caller's
+// context has no deadline of its own
+const DefaultRequestTimeout = 10 * time.Second
+
+// HAClient defines the interface for Home Assistant WebSocket client.
+// Methods that talk to Home Assistant take a context so callers can enforce
+// timeouts and cancel in-flight requests during shutdown.
 type HAClient interface {
-	Connect() error
+	Connect(ctx context.Context) error
 	Disconnect() error
 	IsConnected() bool
-	GetState(entityID string) (*State, error)
-	GetAllStates() ([]*State, error)
-	CallService(domain, service string, data map[string]interface{}) error
+	GetState(ctx context.Context, entityID string) (*State, error)
+	GetAllStates(ctx context.Context) ([]*State, error)
+	CallService(ctx context.Context, domain, service string, data map[string]interface{}) error
 	SubscribeStateChanges(entityID string, handler StateChangeHandler) (Subscription, error)
-	SetInputBoolean(name string, value bool) error
-	SetInputNumber(name string, value float64) error
-	SetInputText(name string, value string) error
+	SetInputBoolean(ctx context.Context, name string, value bool) error
+	SetInputNumber(ctx context.Context, name string, value float64) error
+	SetInputText(ctx context.Context, name string, value string) error
+}
+
+// ConnectionHandler is called when the connection to Home Assistant is lost
+// (connected false) and when it is re-established (connected true)
+type ConnectionHandler func(connected bool)
+
+// ConnectionNotifier is implemented by clients that reconnect on their own and
+// can report connection changes
+type ConnectionNotifier interface {
+	SetConnectionHandler(handler ConnectionHandler)
+}
+
+// Reconnect backoff; vars so tests can shorten them
+var (
+	reconnectInitialBackoff = time.Second
+	reconnectMaxBackoff     = 30 * time.Second
+)
+
+// withDefaultTimeout applies DefaultRequestTimeout if ctx has no deadline
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultRequestTimeout)
 }
 
 // subscriberEntry holds a handler with its unique subscription ID
@@ -61,6 +91,12 @@ type Client struct {
 	ctxMu       sync.RWMutex // Protects ctx and cancel
 	reconnect   bool
 	writeMu     sync.Mutex // Protects websocket writes
+	dedupe      *dedupeCache
+	dedupeMu    sync.RWMutex // Protects dedupe
+	metrics     clientMetrics
+	metricsMu   sync.RWMutex // Protects metrics
+	onConn      ConnectionHandler
+	onConnMu    sync.RWMutex // Protects onConn
 }
 
 func (c *Client) clearSubscribers() {
@@ -99,11 +135,17 @@ func NewClient(url, token string, logger *zap.Logger) *Client {
 		ctx:         ctx,
 		cancel:      cancel,
 		reconnect:   true,
+		dedupe:      newDedupeCache(DefaultIdempotencyWindow, DefaultIdempotentServices),
 	}
 }
 
-// Connect establishes WebSocket connection and authenticates
-func (c *Client) Connect() error {
+// Connect establishes WebSocket connection and authenticates.
+// The context bounds the dial and authentication handshake only; it does not
+// affect the lifetime of the established connection.
+func (c *Client) Connect(ctx context.Context) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
 	c.connMu.Lock()
 
 	if c.connected {
@@ -118,13 +160,17 @@ func (c *Client) Connect() error {
 	c.msgIDMu.Unlock()
 
 	// Connect to WebSocket
-	conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.url, nil)
 	if err != nil {
 		c.connMu.Unlock()
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 	c.conn = conn
 
+	// Bound the handshake reads by the context deadline
+	deadline, _ := ctx.Deadline()
+	c.conn.SetReadDeadline(deadline)
+
 	// Receive auth_required message
 	var authRequired Message
 	if err := c.conn.ReadJSON(&authRequired); err != nil {
@@ -174,6 +220,9 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("expected auth_ok, got %s", authResponse.Type)
 	}
 
+	// Clear the handshake deadline; receiveMessages blocks on reads indefinitely
+	c.conn.SetReadDeadline(time.Time{})
+
 	c.resetContext()
 	c.connected = true
 	c.reconnect = true
@@ -186,7 +235,7 @@ func (c *Client) Connect() error {
 	c.connMu.Unlock()
 
 	// Subscribe to state_changed events
-	if err := c.subscribeToStateChanges(); err != nil {
+	if err := c.subscribeToStateChanges(ctx); err != nil {
 		c.logger.Warn("Failed to subscribe to state changes", zap.Error(err))
 	}
 
@@ -198,10 +247,7 @@ func (c *Client) Disconnect() error {
 	c.connMu.Lock()
 	defer c.connMu.Unlock()
 
-	if !c.connected {
-		return nil
-	}
-
+	// Stop reconnect attempts too, which run while not connected
 	c.reconnect = false
 
 	// Cancel context
@@ -209,6 +255,10 @@ func (c *Client) Disconnect() error {
 	c.cancel()
 	c.ctxMu.Unlock()
 
+	if !c.connected {
+		return nil
+	}
+
 	c.connected = false
 
 	if c.conn != nil {
@@ -233,6 +283,27 @@ func (c *Client) IsConnected() bool {
 	return c.connected
 }
 
+// SetConnectionHandler registers a handler called when the connection is lost
+// and after each successful reconnect. Entity subscriptions survive a
+// reconnect, but events sent while disconnected are missed, so the handler is
+// the place to re-read state.
+func (c *Client) SetConnectionHandler(handler ConnectionHandler) {
+	c.onConnMu.Lock()
+	defer c.onConnMu.Unlock()
+	c.onConn = handler
+}
+
+// notifyConnection calls the connection handler, if any
+func (c *Client) notifyConnection(connected bool) {
+	c.onConnMu.RLock()
+	handler := c.onConn
+	c.onConnMu.RUnlock()
+
+	if handler != nil {
+		handler(connected)
+	}
+}
+
 // nextMsgID returns the next message ID
 func (c *Client) nextMsgID() int {
 	c.msgIDMu.Lock()
@@ -241,21 +312,28 @@ func (c *Client) nextMsgID() int {
 	return c.msgID
 }
 
-// sendMessage sends a message and waits for response
-func (c *Client) sendMessage(msg interface{}) (*Message, error) {
+// sendMessage sends a message and waits for response until ctx is done,
+// DefaultRequestTimeout elapses (if ctx has no deadline), or the client disconnects
+func (c *Client) sendMessage(ctx context.Context, msg interface{}) (*Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, notSentError{err}
+	}
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
 	// Capture connection reference while holding the lock to prevent race with Disconnect().
 	// Invariant: connected == true implies conn != nil (enforced by Connect/Disconnect).
 	c.connMu.RLock()
 	if !c.connected {
 		c.connMu.RUnlock()
-		return nil, fmt.Errorf("not connected")
+		return nil, notSentError{fmt.Errorf("not connected")}
 	}
 	conn := c.conn
 	c.connMu.RUnlock()
 
-	// Get context for cancellation check
+	// Get client context for disconnect check
 	c.ctxMu.RLock()
-	ctx := c.ctx
+	clientCtx := c.ctx
 	c.ctxMu.RUnlock()
 
 	// Get message ID
@@ -267,8 +345,10 @@ func (c *Client) sendMessage(msg interface{}) (*Message, error) {
 		msgID = m.ID
 	case *SubscribeEventsRequest:
 		msgID = m.ID
+	case *CommandRequest:
+		msgID = m.ID
 	default:
-		return nil, fmt.Errorf("unsupported message type")
+		return nil, notSentError{fmt.Errorf("unsupported message type")}
 	}
 
 	// Create response channel
@@ -291,7 +371,7 @@ func (c *Client) sendMessage(msg interface{}) (*Message, error) {
 	c.writeMu.Unlock()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %w", err)
+		return nil, notSentError{fmt.Errorf("failed to send message: %w", err)}
 	}
 
 	// Wait for response with timeout
@@ -299,14 +379,17 @@ func (c *Client) sendMessage(msg interface{}) (*Message, error) {
 	case resp := <-respChan:
 		if resp.Success != nil && !*resp.Success {
 			if resp.Error != nil {
-				return nil, fmt.Errorf("HA error: %s - %s", resp.Error.Code, resp.Error.Message)
+				return nil, rejectedError{fmt.Errorf("HA error: %s - %s", resp.Error.Code, resp.Error.Message)}
 			}
-			return nil, fmt.Errorf("request failed")
+			return nil, rejectedError{fmt.Errorf("request failed")}
 		}
 		return &resp, nil
-	case <-time.After(10 * time.Second):
-		return nil, fmt.Errorf("timeout waiting for response")
 	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timeout waiting for response: %w", ctx.Err())
+		}
+		return nil, ctx.Err()
+	case <-clientCtx.Done():
 		return nil, fmt.Errorf("client disconnected")
 	}
 }
@@ -386,8 +469,13 @@ func (c *Client) handleEvent(msg *Message) {
 
 	// Call handlers in separate goroutines to avoid blocking receiveMessages
 	// This prevents deadlocks when handlers try to send messages back to HA
+	callbackDuration := c.getMetrics().callbackDuration
 	for _, entry := range entries {
-		go entry.handler(eventData.EntityID, eventData.OldState, eventData.NewState)
+		go func(handler StateChangeHandler) {
+			start := time.Now()
+			handler(eventData.EntityID, eventData.OldState, eventData.NewState)
+			callbackDuration.Observe(time.Since(start).Seconds(), "ha")
+		}(entry.handler)
 	}
 }
 
@@ -395,22 +483,27 @@ func (c *Client) handleEvent(msg *Message) {
 func (c *Client) handleDisconnect() {
 	c.connMu.Lock()
 	c.connected = false
+	reconnect := c.reconnect
 	c.connMu.Unlock()
 
-	c.logger.Warn("Connection lost")
-
-	if !c.reconnect {
+	if !reconnect {
+		// Disconnect was called; the read error is the connection closing
 		return
 	}
 
+	c.logger.Warn("Connection lost")
+	c.notifyConnection(false)
+
 	// Attempt to reconnect with exponential backoff
 	go c.attemptReconnect()
 }
 
-// attemptReconnect tries to reconnect with exponential backoff
+// attemptReconnect tries to reconnect with exponential backoff. Connect
+// re-subscribes to state_changed events; entity subscribers are kept, so
+// handlers registered before the drop keep receiving events.
 func (c *Client) attemptReconnect() {
-	backoff := time.Second
-	maxBackoff := 30 * time.Second
+	backoff := reconnectInitialBackoff
+	maxBackoff := reconnectMaxBackoff
 
 	for {
 		// Get context for cancellation check
@@ -426,7 +519,8 @@ func (c *Client) attemptReconnect() {
 
 		c.logger.Info("Attempting to reconnect...")
 
-		if err := c.Connect(); err != nil {
+		// Connect replaces ctx, so the handshake gets its own context
+		if err := c.Connect(context.Background()); err != nil {
 			c.logger.Error("Reconnection failed", zap.Error(err))
 			backoff *= 2
 			if backoff > maxBackoff {
@@ -436,12 +530,14 @@ func (c *Client) attemptReconnect() {
 		}
 
 		c.logger.Info("Reconnected successfully")
+		c.getMetrics().reconnects.Inc()
+		c.notifyConnection(true)
 		return
 	}
 }
 
 // subscribeToStateChanges subscribes to all state_changed events
-func (c *Client) subscribeToStateChanges() error {
+func (c *Client) subscribeToStateChanges(ctx context.Context) error {
 	msgID := c.nextMsgID()
 	req := &SubscribeEventsRequest{
 		ID:        msgID,
@@ -449,13 +545,13 @@ func (c *Client) subscribeToStateChanges() error {
 		EventType: "state_changed",
 	}
 
-	_, err := c.sendMessage(req)
+	_, err := c.sendMessage(ctx, req)
 	return err
 }
 
 // GetState retrieves the state of an entity
-func (c *Client) GetState(entityID string) (*State, error) {
-	states, err := c.GetAllStates()
+func (c *Client) GetState(ctx context.Context, entityID string) (*State, error) {
+	states, err := c.GetAllStates(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -470,14 +566,14 @@ func (c *Client) GetState(entityID string) (*State, error) {
 }
 
 // GetAllStates retrieves all entity states
-func (c *Client) GetAllStates() ([]*State, error) {
+func (c *Client) GetAllStates(ctx context.Context) ([]*State, error) {
 	msgID := c.nextMsgID()
 	req := &GetStatesRequest{
 		ID:   msgID,
 		Type: "get_states",
 	}
 
-	resp, err := c.sendMessage(req)
+	resp, err := c.sendMessage(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -491,17 +587,26 @@ func (c *Client) GetAllStates() ([]*State, error) {
 }
 
 // CallService calls a Home Assistant service
-func (c *Client) CallService(domain, service string, data map[string]interface{}) error {
-	msgID := c.nextMsgID()
-	req := &CallServiceRequest{
-		ID:          msgID,
-		Type:        "call_service",
-		Domain:      domain,
-		Service:     service,
-		ServiceData: data,
-	}
+func (c *Client) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	err := c.callServiceOnce(ctx, domain, service, data, func() error {
+		msgID := c.nextMsgID()
+		req := &CallServiceRequest{
+			ID:          msgID,
+			Type:        "call_service",
+			Domain:      domain,
+			Service:     service,
+			ServiceData: data,
+		}
+
+		_, err := c.sendMessage(ctx, req)
+		return err
+	})
 
-	_, err := c.sendMessage(req)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	c.getMetrics().serviceCalls.Inc(domain, service, result)
 	return err
 }
 
@@ -556,28 +661,28 @@ func (c *Client) unsubscribe(entityID string, subID int) error {
 }
 
 // SetInputBoolean sets the value of an input_boolean
-func (c *Client) SetInputBoolean(name string, value bool) error {
+func (c *Client) SetInputBoolean(ctx context.Context, name string, value bool) error {
 	service := "turn_off"
 	if value {
 		service = "turn_on"
 	}
 
-	return c.CallService("input_boolean", service, map[string]interface{}{
+	return c.CallService(ctx, "input_boolean", service, map[string]interface{}{
 		"entity_id": fmt.Sprintf("input_boolean.%s", name),
 	})
 }
 
 // SetInputNumber sets the value of an input_number
-func (c *Client) SetInputNumber(name string, value float64) error {
-	return c.CallService("input_number", "set_value", map[string]interface{}{
+func (c *Client) SetInputNumber(ctx context.Context, name string, value float64) error {
+	return c.CallService(ctx, "input_number", "set_value", map[string]interface{}{
 		"entity_id": fmt.Sprintf("input_number.%s", name),
 		"value":     value,
 	})
 }
 
 // SetInputText sets the value of an input_text
-func (c *Client) SetInputText(name string, value string) error {
-	return c.CallService("input_text", "set_value", map[string]interface{}{
+func (c *Client) SetInputText(ctx context.Context, name string, value string) error {
+	return c.CallService(ctx, "input_text", "set_value", map[string]interface{}{
 		"entity_id": fmt.Sprintf("input_text.%s", name),
 		"value":     value,
 	})
@@ -1,6 +1,7 @@
 package ha
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -78,7 +79,7 @@ func TestClient_Connect(t *testing.T) {
 		url := "ws" + strings.TrimPrefix(server.URL, "http")
 		client := NewClient(url, token, logger)
 
-		err := client.Connect()
+		err := client.Connect(context.Background())
 		assert.NoError(t, err)
 		assert.True(t, client.IsConnected())
 
@@ -102,7 +103,7 @@ func TestClient_Connect(t *testing.T) {
 		url := "ws" + strings.TrimPrefix(server.URL, "http")
 		client := NewClient(url, "wrong_token", logger)
 
-		err := client.Connect()
+		err := client.Connect(context.Background())
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "authentication failed")
 		assert.False(t, client.IsConnected())
@@ -129,10 +130,10 @@ func TestClient_Connect(t *testing.T) {
 		url := "ws" + strings.TrimPrefix(server.URL, "http")
 		client := NewClient(url, token, logger)
 
-		err := client.Connect()
+		err := client.Connect(context.Background())
 		require.NoError(t, err)
 
-		err = client.Connect()
+		err = client.Connect(context.Background())
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "already connected")
 
@@ -193,11 +194,11 @@ func TestClient_GetAllStates(t *testing.T) {
 	url := "ws" + strings.TrimPrefix(server.URL, "http")
 	client := NewClient(url, token, logger)
 
-	err := client.Connect()
+	err := client.Connect(context.Background())
 	require.NoError(t, err)
 	defer client.Disconnect()
 
-	states, err := client.GetAllStates()
+	states, err := client.GetAllStates(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, states, 2)
 	assert.Equal(t, "input_boolean.test", states[0].EntityID)
@@ -247,16 +248,16 @@ func TestClient_GetState(t *testing.T) {
 	url := "ws" + strings.TrimPrefix(server.URL, "http")
 	client := NewClient(url, token, logger)
 
-	err := client.Connect()
+	err := client.Connect(context.Background())
 	require.NoError(t, err)
 	defer client.Disconnect()
 
-	state, err := client.GetState("input_boolean.test")
+	state, err := client.GetState(context.Background(), "input_boolean.test")
 	assert.NoError(t, err)
 	assert.Equal(t, "input_boolean.test", state.EntityID)
 	assert.Equal(t, "on", state.State)
 
-	_, err = client.GetState("nonexistent")
+	_, err = client.GetState(context.Background(), "nonexistent")
 	assert.Error(t, err)
 }
 
@@ -298,16 +299,79 @@ func TestClient_CallService(t *testing.T) {
 	url := "ws" + strings.TrimPrefix(server.URL, "http")
 	client := NewClient(url, token, logger)
 
-	err := client.Connect()
+	err := client.Connect(context.Background())
 	require.NoError(t, err)
 	defer client.Disconnect()
 
-	err = client.CallService("input_boolean", "turn_on", map[string]interface{}{
+	err = client.CallService(context.Background(), "input_boolean", "turn_on", map[string]interface{}{
 		"entity_id": "input_boolean.test",
 	})
 	assert.NoError(t, err)
 }
 
+func TestClient_CallServiceContext(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	token := "test_token"
+
+	// Server acknowledges the subscription but never answers service calls
+	server := mockHAServer(t, func(conn *websocket.Conn) {
+		standardAuthFlow(t, conn, token)
+
+		var subMsg SubscribeEventsRequest
+		conn.ReadJSON(&subMsg)
+		success := true
+		conn.WriteJSON(Message{
+			ID:      subMsg.ID,
+			Type:    "result",
+			Success: &success,
+		})
+
+		for {
+			var req CallServiceRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client := NewClient(url, token, logger)
+
+	err := client.Connect(context.Background())
+	require.NoError(t, err)
+	defer client.Disconnect()
+
+	data := map[string]interface{}{"entity_id": "input_boolean.test"}
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := client.CallService(ctx, "input_boolean", "turn_on", data)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), DefaultRequestTimeout)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		err := client.CallService(ctx, "input_boolean", "turn_on", data)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := client.CallService(ctx, "input_boolean", "turn_on", data)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
 func TestClient_SetInputBoolean(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	token := "test_token"
@@ -356,11 +420,11 @@ func TestClient_SetInputBoolean(t *testing.T) {
 			url := "ws" + strings.TrimPrefix(server.URL, "http")
 			client := NewClient(url, token, logger)
 
-			err := client.Connect()
+			err := client.Connect(context.Background())
 			require.NoError(t, err)
 			defer client.Disconnect()
 
-			err = client.SetInputBoolean("test", tc.value)
+			err = client.SetInputBoolean(context.Background(), "test", tc.value)
 			assert.NoError(t, err)
 		})
 	}
@@ -404,11 +468,11 @@ func TestClient_SetInputNumber(t *testing.T) {
 	url := "ws" + strings.TrimPrefix(server.URL, "http")
 	client := NewClient(url, token, logger)
 
-	err := client.Connect()
+	err := client.Connect(context.Background())
 	require.NoError(t, err)
 	defer client.Disconnect()
 
-	err = client.SetInputNumber("test", 42.5)
+	err = client.SetInputNumber(context.Background(), "test", 42.5)
 	assert.NoError(t, err)
 }
 
@@ -450,11 +514,11 @@ func TestClient_SetInputText(t *testing.T) {
 	url := "ws" + strings.TrimPrefix(server.URL, "http")
 	client := NewClient(url, token, logger)
 
-	err := client.Connect()
+	err := client.Connect(context.Background())
 	require.NoError(t, err)
 	defer client.Disconnect()
 
-	err = client.SetInputText("test", "test_value")
+	err = client.SetInputText(context.Background(), "test", "test_value")
 	assert.NoError(t, err)
 }
 
@@ -464,11 +528,11 @@ func TestMockClient(t *testing.T) {
 	t.Run("connection", func(t *testing.T) {
 		assert.False(t, mock.IsConnected())
 
-		err := mock.Connect()
+		err := mock.Connect(context.Background())
 		assert.NoError(t, err)
 		assert.True(t, mock.IsConnected())
 
-		err = mock.Connect()
+		err = mock.Connect(context.Background())
 		assert.Error(t, err)
 
 		err = mock.Disconnect()
@@ -481,18 +545,18 @@ func TestMockClient(t *testing.T) {
 			"friendly_name": "Test",
 		})
 
-		state, err := mock.GetState("input_boolean.test")
+		state, err := mock.GetState(context.Background(), "input_boolean.test")
 		assert.NoError(t, err)
 		assert.Equal(t, "on", state.State)
 
-		_, err = mock.GetState("nonexistent")
+		_, err = mock.GetState(context.Background(), "nonexistent")
 		assert.Error(t, err)
 	})
 
 	t.Run("service calls", func(t *testing.T) {
 		mock.ClearServiceCalls()
 
-		err := mock.SetInputBoolean("test", true)
+		err := mock.SetInputBoolean(context.Background(), "test", true)
 		assert.NoError(t, err)
 
 		calls := mock.GetServiceCalls()
@@ -501,6 +565,21 @@ func TestMockClient(t *testing.T) {
 		assert.Equal(t, "turn_on", calls[0].Service)
 	})
 
+	t.Run("cancelled context", func(t *testing.T) {
+		mock.ClearServiceCalls()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := mock.CallService(ctx, "input_boolean", "turn_on", map[string]interface{}{
+			"entity_id": "input_boolean.test",
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, mock.GetServiceCalls())
+
+		_, err = mock.GetState(ctx, "input_boolean.test")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
 	t.Run("subscriptions", func(t *testing.T) {
 		callCount := 0
 		handler := func(entityID string, oldState, newState *State) {
@@ -538,6 +617,101 @@ func TestClient_DisconnectClearsSubscribers(t *testing.T) {
 	assert.Empty(t, client.subscribers)
 }
 
+func TestClient_ReconnectResubscribes(t *testing.T) {
+	reconnectInitialBackoff = 10 * time.Millisecond
+	defer func() { reconnectInitialBackoff = time.Second }()
+
+	token := "test_token"
+	var connections int32
+
+	server := mockHAServer(t, func(conn *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		standardAuthFlow(t, conn, token)
+
+		// Every connection must subscribe to state_changed again
+		var subMsg SubscribeEventsRequest
+		require.NoError(t, conn.ReadJSON(&subMsg))
+		assert.Equal(t, "state_changed", subMsg.EventType)
+		success := true
+		conn.WriteJSON(Message{ID: subMsg.ID, Type: "result", Success: &success})
+
+		if n == 1 {
+			// Home Assistant restarts
+			return
+		}
+
+		data, _ := json.Marshal(StateChangedEvent{
+			EntityID: "input_boolean.test",
+			NewState: &State{EntityID: "input_boolean.test", State: "on"},
+		})
+		conn.WriteJSON(Message{Type: "event", Event: &Event{EventType: "state_changed", Data: data}})
+
+		// Hold the connection until the client disconnects
+		var msg Message
+		for conn.ReadJSON(&msg) == nil {
+		}
+	})
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client := NewClient(url, token, zap.NewNop())
+
+	transitions := make(chan bool, 4)
+	client.SetConnectionHandler(func(connected bool) { transitions <- connected })
+
+	events := make(chan string, 1)
+	_, err := client.SubscribeStateChanges("input_boolean.test", func(entityID string, oldState, newState *State) {
+		events <- newState.State
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Connect(context.Background()))
+	defer client.Disconnect()
+
+	for _, want := range []bool{false, true} {
+		select {
+		case got := <-transitions:
+			assert.Equal(t, want, got)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no connection change to %v", want)
+		}
+	}
+	assert.True(t, client.IsConnected())
+
+	// The subscription made before the drop still receives events
+	select {
+	case state := <-events:
+		assert.Equal(t, "on", state)
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscriber did not receive an event after reconnecting")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+func TestClient_DisconnectStopsReconnecting(t *testing.T) {
+	client := NewClient("ws://127.0.0.1:1", "token", zap.NewNop())
+	client.reconnect = true
+
+	var calls int32
+	client.SetConnectionHandler(func(bool) { atomic.AddInt32(&calls, 1) })
+
+	done := make(chan struct{})
+	go func() {
+		client.attemptReconnect()
+		close(done)
+	}()
+
+	require.NoError(t, client.Disconnect())
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("reconnect loop kept running after Disconnect")
+	}
+	assert.False(t, client.IsConnected())
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
 func TestClient_HandleEventBackpressuresHandlers(t *testing.T) {
 	client := &Client{
 		logger:      zap.NewNop(),
@@ -585,3 +759,51 @@ func TestClient_HandleEventBackpressuresHandlers(t *testing.T) {
 		t.Fatal("handlers did not complete in time")
 	}
 }
+
+func TestClient_ListAreas(t *testing.T) {
+	logger := zap.NewNop()
+	token := "test_token"
+
+	server := mockHAServer(t, func(conn *websocket.Conn) {
+		standardAuthFlow(t, conn, token)
+
+		// Handle subscribe_events
+		var subMsg SubscribeEventsRequest
+		conn.ReadJSON(&subMsg)
+		success := true
+		conn.WriteJSON(Message{
+			ID:      subMsg.ID,
+			Type:    "result",
+			Success: &success,
+		})
+
+		// Handle config/area_registry/list request
+		var req CommandRequest
+		conn.ReadJSON(&req)
+		assert.Equal(t, "config/area_registry/list", req.Type)
+
+		conn.WriteJSON(Message{
+			ID:      req.ID,
+			Type:    "result",
+			Success: &success,
+			Result:  json.RawMessage(`[{"area_id":"kitchen","name":"Kitchen","aliases":["Cookhouse"],"picture":null}]`),
+		})
+
+		time.Sleep(100 * time.Millisecond)
+	})
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client := NewClient(url, token, logger)
+
+	err := client.Connect(context.Background())
+	require.NoError(t, err)
+	defer client.Disconnect()
+
+	areas, err := client.ListAreas(context.Background())
+	require.NoError(t, err)
+	require.Len(t, areas, 1)
+	assert.Equal(t, "kitchen", areas[0].AreaID)
+	assert.Equal(t, "Kitchen", areas[0].Name)
+	assert.Equal(t, []string{"Cookhouse"}, areas[0].Aliases)
+}
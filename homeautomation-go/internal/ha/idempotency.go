@@ -0,0 +1,227 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultIdempotencyWindow is how long a sensitive command is remembered, so
+// a retry within it is not sent again
+const DefaultIdempotencyWindow = 30 * time.Second
+
+// DefaultIdempotentServices are the services where running a command twice
+// does harm (a garage door opened twice reverses, a lock unlocked twice
+// defeats a re-lock in between). A retry of one of them whose earlier
+// identical call has an unknown outcome is not re-sent, even without an
+// explicit idempotency key; a repeat after a confirmed success always is.
+var DefaultIdempotentServices = []string{
+	"cover.open_cover",
+	"cover.toggle",
+	"lock.unlock",
+	"lock.open",
+}
+
+// ErrDuplicateCommand is returned for a retry of a command that was already
+// sent under the same idempotency key when the first attempt's outcome is
+// unknown (it timed out or the connection dropped after sending). The
+// command is not sent again.
+var ErrDuplicateCommand = errors.New("command already sent with this idempotency key")
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey attaches an idempotency key to service calls made with
+// the returned context. Calls with the same key within the idempotency window
+// are sent to Home Assistant at most once, whatever the service.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key attached to ctx, if any
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// notSentError marks a failure where the request never reached Home Assistant
+type notSentError struct{ err error }
+
+func (e notSentError) Error() string { return e.err.Error() }
+func (e notSentError) Unwrap() error { return e.err }
+
+// rejectedError marks a request Home Assistant answered with a failure
+type rejectedError struct{ err error }
+
+func (e rejectedError) Error() string { return e.err.Error() }
+func (e rejectedError) Unwrap() error { return e.err }
+
+// mayHaveExecuted reports whether a request that returned err might have
+// been carried out by Home Assistant
+func mayHaveExecuted(err error) bool {
+	var notSent notSentError
+	var rejected rejectedError
+	return err == nil || !(errors.As(err, &notSent) || errors.As(err, &rejected))
+}
+
+// dedupeEntry is one remembered command. done is closed when the first
+// attempt finishes.
+type dedupeEntry struct {
+	done     chan struct{}
+	err      error
+	expires  time.Time
+	explicit bool // Keyed with WithIdempotencyKey rather than derived from the call
+}
+
+// dedupeCache remembers recently sent commands by idempotency key
+type dedupeCache struct {
+	mu       sync.Mutex
+	window   time.Duration
+	services map[string]bool
+	entries  map[string]*dedupeEntry
+	now      func() time.Time
+}
+
+func newDedupeCache(window time.Duration, services []string) *dedupeCache {
+	d := &dedupeCache{
+		window:   window,
+		services: make(map[string]bool, len(services)),
+		entries:  make(map[string]*dedupeEntry),
+		now:      time.Now,
+	}
+	for _, s := range services {
+		d.services[s] = true
+	}
+	return d
+}
+
+// key returns the idempotency key for a call and whether it is explicit: the
+// key from ctx, or one derived from the service and its data for sensitive
+// services. ok is false for calls that aren't deduplicated.
+func (d *dedupeCache) key(ctx context.Context, domain, service string, data map[string]interface{}) (key string, explicit, ok bool) {
+	if d.window <= 0 {
+		return "", false, false
+	}
+	if key, ok := IdempotencyKey(ctx); ok {
+		return key, true, true
+	}
+	name := domain + "." + service
+	if !d.services[name] {
+		return "", false, false
+	}
+	// encoding/json sorts map keys, so equal data gives an equal key
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", false, false
+	}
+	return name + ":" + string(encoded), false, true
+}
+
+// begin returns the entry for key and whether the caller is the first
+// attempt, which must send the command and call finish
+func (d *dedupeCache) begin(key string, explicit bool) (*dedupeEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for k, e := range d.entries {
+		if isClosed(e.done) && now.After(e.expires) {
+			delete(d.entries, k)
+		}
+	}
+
+	if e, ok := d.entries[key]; ok {
+		return e, false
+	}
+	e := &dedupeEntry{done: make(chan struct{}), explicit: explicit}
+	d.entries[key] = e
+	return e, true
+}
+
+// finish records the first attempt's result. A command that can't have been
+// carried out is forgotten so a retry sends it, and so is a derived-key
+// command that succeeded, since repeating it is a new command.
+func (d *dedupeCache) finish(key string, e *dedupeEntry, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e.err = err
+	e.expires = d.now().Add(d.window)
+	if e.resendable() {
+		delete(d.entries, key)
+	}
+	close(e.done)
+}
+
+// resendable reports whether a finished entry leaves a repeat of its command
+// free to be sent
+func (e *dedupeEntry) resendable() bool {
+	return !mayHaveExecuted(e.err) || (e.err == nil && !e.explicit)
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetIdempotency sets how long sent commands are remembered and which
+// services ("domain.service") are deduplicated without an explicit key.
+// A window of zero or less turns deduplication off.
+func (c *Client) SetIdempotency(window time.Duration, services []string) {
+	c.dedupeMu.Lock()
+	defer c.dedupeMu.Unlock()
+	c.dedupe = newDedupeCache(window, services)
+}
+
+// callServiceOnce sends a service call unless one with the same idempotency
+// key was sent within the window. A duplicate returns the first attempt's
+// result: nil if it succeeded under an explicit key, ErrDuplicateCommand if
+// its outcome is unknown. A call with a derived key is only held back while
+// an identical call is in flight or after one with an unknown outcome.
+func (c *Client) callServiceOnce(ctx context.Context, domain, service string, data map[string]interface{}, send func() error) error {
+	c.dedupeMu.RLock()
+	dedupe := c.dedupe
+	c.dedupeMu.RUnlock()
+
+	key, explicit, ok := dedupe.key(ctx, domain, service, data)
+	if !ok {
+		return send()
+	}
+
+	for {
+		entry, first := dedupe.begin(key, explicit)
+		if first {
+			err := send()
+			dedupe.finish(key, entry, err)
+			return err
+		}
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if entry.resendable() {
+			continue // The first attempt can't have run, or succeeded and this is a new command
+		}
+
+		c.logger.Info("Skipping duplicate service call",
+			zap.String("domain", domain),
+			zap.String("service", service),
+			zap.String("idempotency_key", key),
+			zap.NamedError("first_attempt_error", entry.err))
+		if entry.err != nil {
+			return fmt.Errorf("%w: %s.%s, first attempt: %v", ErrDuplicateCommand, domain, service, entry.err)
+		}
+		return nil
+	}
+}
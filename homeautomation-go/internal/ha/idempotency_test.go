@@ -0,0 +1,195 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDedupeCache_Key(t *testing.T) {
+	d := newDedupeCache(time.Minute, DefaultIdempotentServices)
+	ctx := context.Background()
+
+	key1, explicit, ok := d.key(ctx, "cover", "open_cover", map[string]interface{}{"entity_id": "cover.garage", "x": 1})
+	require.True(t, ok)
+	assert.False(t, explicit)
+	key2, _, _ := d.key(ctx, "cover", "open_cover", map[string]interface{}{"x": 1, "entity_id": "cover.garage"})
+	assert.Equal(t, key1, key2, "equal data gives an equal key")
+
+	_, _, ok = d.key(ctx, "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"})
+	assert.False(t, ok, "services that aren't sensitive are not deduplicated")
+
+	key, explicit, ok := d.key(WithIdempotencyKey(ctx, "wake-2025-01-06"), "light", "turn_on", nil)
+	require.True(t, ok, "an explicit key applies to any service")
+	assert.True(t, explicit)
+	assert.Equal(t, "wake-2025-01-06", key)
+
+	disabled := newDedupeCache(0, DefaultIdempotentServices)
+	_, _, ok = disabled.key(ctx, "cover", "open_cover", nil)
+	assert.False(t, ok)
+}
+
+func TestDedupeCache_Expiry(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	d := newDedupeCache(30*time.Second, nil)
+	d.now = func() time.Time { return now }
+
+	entry, first := d.begin("k", true)
+	require.True(t, first)
+	d.finish("k", entry, nil)
+
+	_, first = d.begin("k", true)
+	assert.False(t, first, "remembered within the window")
+
+	now = now.Add(31 * time.Second)
+	_, first = d.begin("k", true)
+	assert.True(t, first, "forgotten after the window")
+}
+
+func TestDedupeCache_ForgetsCommandsThatCannotHaveRun(t *testing.T) {
+	d := newDedupeCache(time.Minute, nil)
+
+	for _, err := range []error{
+		notSentError{fmt.Errorf("not connected")},
+		rejectedError{fmt.Errorf("HA error: not_found - Service not found")},
+	} {
+		entry, first := d.begin("k", false)
+		require.True(t, first)
+		d.finish("k", entry, err)
+	}
+
+	// A derived key is forgotten after a success: a repeat is a new command
+	entry, first := d.begin("k", false)
+	require.True(t, first)
+	d.finish("k", entry, nil)
+
+	entry, first = d.begin("k", false)
+	require.True(t, first)
+	d.finish("k", entry, fmt.Errorf("timeout waiting for response: %w", context.DeadlineExceeded))
+
+	_, first = d.begin("k", false)
+	assert.False(t, first, "a timed out command might have run and is remembered")
+}
+
+// idempotencyTestClient connects to a server that counts call_service
+// requests and answers them with respond; a nil response is never sent
+func idempotencyTestClient(t *testing.T, respond func(n int32) *Message) (*Client, *atomic.Int32) {
+	t.Helper()
+	token := "test_token"
+	var calls atomic.Int32
+
+	server := mockHAServer(t, func(conn *websocket.Conn) {
+		standardAuthFlow(t, conn, token)
+
+		var subMsg SubscribeEventsRequest
+		conn.ReadJSON(&subMsg)
+		success := true
+		conn.WriteJSON(Message{ID: subMsg.ID, Type: "result", Success: &success})
+
+		for {
+			var req CallServiceRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			n := calls.Add(1)
+			if resp := respond(n); resp != nil {
+				resp.ID = req.ID
+				conn.WriteJSON(resp)
+			}
+		}
+	})
+	t.Cleanup(server.Close)
+
+	client := NewClient("ws"+strings.TrimPrefix(server.URL, "http"), token, zap.NewNop())
+	require.NoError(t, client.Connect(context.Background()))
+	t.Cleanup(func() { client.Disconnect() })
+	return client, &calls
+}
+
+func TestClient_CallServiceRetryAfterTimeoutIsNotResent(t *testing.T) {
+	// The server never answers, as when HA runs the command but the reply is lost
+	client, calls := idempotencyTestClient(t, func(int32) *Message { return nil })
+	data := map[string]interface{}{"entity_id": "cover.garage_door_door"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.CallService(ctx, "cover", "open_cover", data)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = client.CallService(context.Background(), "cover", "open_cover", data)
+	assert.ErrorIs(t, err, ErrDuplicateCommand)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load(), "the retry must not reach Home Assistant")
+}
+
+func TestClient_CallServiceDuplicateAfterSuccess(t *testing.T) {
+	success := true
+	client, calls := idempotencyTestClient(t, func(int32) *Message {
+		return &Message{Type: "result", Success: &success}
+	})
+	ctx := WithIdempotencyKey(context.Background(), "unlock-front-door-1")
+	data := map[string]interface{}{"entity_id": "lock.front_door"}
+
+	require.NoError(t, client.CallService(ctx, "lock", "unlock", data))
+	require.NoError(t, client.CallService(ctx, "lock", "unlock", data), "a duplicate of a successful command succeeds")
+	assert.Equal(t, int32(1), calls.Load())
+
+	// A different key is a new command
+	require.NoError(t, client.CallService(WithIdempotencyKey(context.Background(), "unlock-front-door-2"), "lock", "unlock", data))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_CallServiceRepeatAfterSuccessIsSent(t *testing.T) {
+	success := true
+	client, calls := idempotencyTestClient(t, func(int32) *Message {
+		return &Message{Type: "result", Success: &success}
+	})
+	data := map[string]interface{}{"entity_id": "cover.garage_door_door"}
+
+	// The owner leaves and comes back: two garage opens, each confirmed
+	require.NoError(t, client.CallService(context.Background(), "cover", "open_cover", data))
+	require.NoError(t, client.CallService(context.Background(), "cover", "open_cover", data))
+	assert.Equal(t, int32(2), calls.Load(), "a confirmed command never holds back the next one")
+}
+
+func TestClient_CallServiceRetryAfterRejectionIsSent(t *testing.T) {
+	success, failure := true, false
+	client, calls := idempotencyTestClient(t, func(n int32) *Message {
+		if n == 1 {
+			return &Message{Type: "result", Success: &failure, Error: &Error{Code: "home_assistant_error", Message: "busy"}}
+		}
+		return &Message{Type: "result", Success: &success}
+	})
+	data := map[string]interface{}{"entity_id": "cover.garage_door_door"}
+
+	err := client.CallService(context.Background(), "cover", "open_cover", data)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrDuplicateCommand))
+
+	require.NoError(t, client.CallService(context.Background(), "cover", "open_cover", data))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_SetIdempotencyDisabled(t *testing.T) {
+	success := true
+	client, calls := idempotencyTestClient(t, func(int32) *Message {
+		return &Message{Type: "result", Success: &success}
+	})
+	client.SetIdempotency(0, nil)
+	ctx := WithIdempotencyKey(context.Background(), "open-garage-1")
+	data := map[string]interface{}{"entity_id": "cover.garage_door_door"}
+
+	require.NoError(t, client.CallService(ctx, "cover", "open_cover", data))
+	require.NoError(t, client.CallService(ctx, "cover", "open_cover", data))
+	assert.Equal(t, int32(2), calls.Load())
+}
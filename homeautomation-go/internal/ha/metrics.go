@@ -0,0 +1,36 @@
+package ha
+
+import (
+	"homeautomation/internal/metrics"
+)
+
+// clientMetrics are the metrics the client records. The zero value records
+// nothing, since metrics methods are nil-safe.
+type clientMetrics struct {
+	serviceCalls     *metrics.Counter
+	reconnects       *metrics.Counter
+	callbackDuration *metrics.Histogram
+}
+
+// SetMetrics records service calls, reconnects and subscription callback
+// durations in the registry
+func (c *Client) SetMetrics(registry *metrics.Registry) {
+	m := clientMetrics{
+		serviceCalls: registry.Counter("ha_service_calls_total",
+			"Home Assistant service calls by domain, service and result (success or error)",
+			"domain", "service", "result"),
+		reconnects: registry.Counter("ha_reconnects_total",
+			"Successful reconnections to the Home Assistant WebSocket API after a lost connection"),
+		callbackDuration: registry.SubscriptionCallbackDuration(),
+	}
+
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+	c.metrics = m
+}
+
+func (c *Client) getMetrics() clientMetrics {
+	c.metricsMu.RLock()
+	defer c.metricsMu.RUnlock()
+	return c.metrics
+}
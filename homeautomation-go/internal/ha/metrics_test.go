@@ -0,0 +1,50 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_Metrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	client := NewClient("ws://unused", "token", zap.NewNop())
+	client.SetMetrics(registry)
+
+	// Not connected, so the call fails
+	err := client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"})
+	require.Error(t, err)
+
+	done := make(chan struct{})
+	client.subscribers["sensor.test"] = []subscriberEntry{
+		{subID: 1, handler: func(entityID string, oldState, newState *State) { close(done) }},
+	}
+	data, err := json.Marshal(StateChangedEvent{EntityID: "sensor.test"})
+	require.NoError(t, err)
+	client.handleEvent(&Message{Type: "event", Event: &Event{EventType: "state_changed", Data: data}})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	// The duration is observed just after the handler returns
+	assert.Eventually(t, func() bool {
+		var b strings.Builder
+		require.NoError(t, registry.WriteText(&b))
+		return strings.Contains(b.String(), `homeautomation_subscription_callback_duration_seconds_count{source="ha"} 1`)
+	}, time.Second, 10*time.Millisecond)
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+	assert.Contains(t, b.String(), `homeautomation_ha_service_calls_total{domain="light",service="turn_on",result="error"} 1`)
+}
@@ -1,6 +1,7 @@
 package ha
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -20,6 +21,12 @@ type MockClient struct {
 	callsMu        sync.Mutex
 	getStateCalls  map[string]int // Track GetState calls per entity
 	getStateCallMu sync.Mutex
+
+	// Area, entity and device registries returned by the List* methods
+	areas          []Area
+	entityRegistry []EntityRegistryEntry
+	deviceRegistry []DeviceRegistryEntry
+	registryMu     sync.RWMutex
 }
 
 func (m *MockClient) clearSubscribers() {
@@ -29,14 +36,6 @@ func (m *MockClient) clearSubscribers() {
 	m.subscribers = make(map[string][]subscriberEntry)
 }
 
-// ServiceCall records a service call for testing
-type ServiceCall struct {
-	Domain  string
-	Service string
-	Data    map[string]interface{}
-	Time    time.Time
-}
-
 // mockSubscription implements Subscription interface for MockClient
 type mockSubscription struct {
 	entityID string
@@ -60,7 +59,7 @@ func NewMockClient() *MockClient {
 }
 
 // Connect simulates connecting to Home Assistant
-func (m *MockClient) Connect() error {
+func (m *MockClient) Connect(ctx context.Context) error {
 	m.connMu.Lock()
 	defer m.connMu.Unlock()
 
@@ -90,7 +89,11 @@ func (m *MockClient) IsConnected() bool {
 }
 
 // GetState retrieves a mock state
-func (m *MockClient) GetState(entityID string) (*State, error) {
+func (m *MockClient) GetState(ctx context.Context, entityID string) (*State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Track the GetState call
 	m.getStateCallMu.Lock()
 	m.getStateCalls[entityID]++
@@ -108,7 +111,11 @@ func (m *MockClient) GetState(entityID string) (*State, error) {
 }
 
 // GetAllStates retrieves all mock states
-func (m *MockClient) GetAllStates() ([]*State, error) {
+func (m *MockClient) GetAllStates(ctx context.Context) ([]*State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.statesMu.RLock()
 	defer m.statesMu.RUnlock()
 
@@ -120,8 +127,13 @@ func (m *MockClient) GetAllStates() ([]*State, error) {
 	return states, nil
 }
 
-// CallService records a service call
-func (m *MockClient) CallService(domain, service string, data map[string]interface{}) error {
+// CallService records a service call. Calls made with an already-cancelled
+// context fail without being recorded, as they would never reach Home Assistant.
+func (m *MockClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.callsMu.Lock()
 	m.serviceCalls = append(m.serviceCalls, ServiceCall{
 		Domain:  domain,
@@ -195,28 +207,28 @@ func (m *MockClient) unsubscribe(entityID string, subID int) error {
 }
 
 // SetInputBoolean sets a mock input_boolean
-func (m *MockClient) SetInputBoolean(name string, value bool) error {
+func (m *MockClient) SetInputBoolean(ctx context.Context, name string, value bool) error {
 	service := "turn_off"
 	if value {
 		service = "turn_on"
 	}
 
-	return m.CallService("input_boolean", service, map[string]interface{}{
+	return m.CallService(ctx, "input_boolean", service, map[string]interface{}{
 		"entity_id": fmt.Sprintf("input_boolean.%s", name),
 	})
 }
 
 // SetInputNumber sets a mock input_number
-func (m *MockClient) SetInputNumber(name string, value float64) error {
-	return m.CallService("input_number", "set_value", map[string]interface{}{
+func (m *MockClient) SetInputNumber(ctx context.Context, name string, value float64) error {
+	return m.CallService(ctx, "input_number", "set_value", map[string]interface{}{
 		"entity_id": fmt.Sprintf("input_number.%s", name),
 		"value":     value,
 	})
 }
 
 // SetInputText sets a mock input_text
-func (m *MockClient) SetInputText(name string, value string) error {
-	return m.CallService("input_text", "set_value", map[string]interface{}{
+func (m *MockClient) SetInputText(ctx context.Context, name string, value string) error {
+	return m.CallService(ctx, "input_text", "set_value", map[string]interface{}{
 		"entity_id": fmt.Sprintf("input_text.%s", name),
 		"value":     value,
 	})
@@ -399,3 +411,34 @@ func (m *MockClient) GetSubscribedEntities() []string {
 	}
 	return entities
 }
+
+// SetRegistries sets the area, entity and device registries returned by the List* methods
+func (m *MockClient) SetRegistries(areas []Area, entities []EntityRegistryEntry, devices []DeviceRegistryEntry) {
+	m.registryMu.Lock()
+	defer m.registryMu.Unlock()
+
+	m.areas = append([]Area(nil), areas...)
+	m.entityRegistry = append([]EntityRegistryEntry(nil), entities...)
+	m.deviceRegistry = append([]DeviceRegistryEntry(nil), devices...)
+}
+
+// ListAreas returns the mock area registry
+func (m *MockClient) ListAreas(ctx context.Context) ([]Area, error) {
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	return append([]Area(nil), m.areas...), nil
+}
+
+// ListEntityRegistry returns the mock entity registry
+func (m *MockClient) ListEntityRegistry(ctx context.Context) ([]EntityRegistryEntry, error) {
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	return append([]EntityRegistryEntry(nil), m.entityRegistry...), nil
+}
+
+// ListDeviceRegistry returns the mock device registry
+func (m *MockClient) ListDeviceRegistry(ctx context.Context) ([]DeviceRegistryEntry, error) {
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	return append([]DeviceRegistryEntry(nil), m.deviceRegistry...), nil
+}
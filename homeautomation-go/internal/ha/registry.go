@@ -0,0 +1,89 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RegistryClient lists Home Assistant's area, entity and device registries.
+// Client and MockClient implement it; wrappers of HAClient may not.
+type RegistryClient interface {
+	ListAreas(ctx context.Context) ([]Area, error)
+	ListEntityRegistry(ctx context.Context) ([]EntityRegistryEntry, error)
+	ListDeviceRegistry(ctx context.Context) ([]DeviceRegistryEntry, error)
+}
+
+// CommandRequest is a WebSocket command that takes no arguments, such as
+// config/area_registry/list
+type CommandRequest struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+}
+
+// Area is an entry in the area registry
+type Area struct {
+	AreaID  string   `json:"area_id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	FloorID string   `json:"floor_id,omitempty"`
+}
+
+// EntityRegistryEntry is an entry in the entity registry. AreaID is empty
+// when the entity inherits its area from its device.
+type EntityRegistryEntry struct {
+	EntityID   string `json:"entity_id"`
+	AreaID     string `json:"area_id,omitempty"`
+	DeviceID   string `json:"device_id,omitempty"`
+	DisabledBy string `json:"disabled_by,omitempty"`
+}
+
+// DeviceRegistryEntry is an entry in the device registry
+type DeviceRegistryEntry struct {
+	ID     string `json:"id"`
+	AreaID string `json:"area_id,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// ListAreas retrieves the area registry
+func (c *Client) ListAreas(ctx context.Context) ([]Area, error) {
+	var areas []Area
+	if err := c.listRegistry(ctx, "config/area_registry/list", &areas); err != nil {
+		return nil, err
+	}
+	return areas, nil
+}
+
+// ListEntityRegistry retrieves the entity registry
+func (c *Client) ListEntityRegistry(ctx context.Context) ([]EntityRegistryEntry, error) {
+	var entries []EntityRegistryEntry
+	if err := c.listRegistry(ctx, "config/entity_registry/list", &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListDeviceRegistry retrieves the device registry
+func (c *Client) ListDeviceRegistry(ctx context.Context) ([]DeviceRegistryEntry, error) {
+	var entries []DeviceRegistryEntry
+	if err := c.listRegistry(ctx, "config/device_registry/list", &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// listRegistry sends a registry list command and decodes its result into out
+func (c *Client) listRegistry(ctx context.Context, command string, out interface{}) error {
+	resp, err := c.sendMessage(ctx, &CommandRequest{
+		ID:   c.nextMsgID(),
+		Type: command,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result: %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SnapshotScene captures the current state of the given entities into a
+// temporary Home Assistant scene (scene.create with snapshot_entities) so
+// it can later be re-applied with RestoreScene. Creating a scene with an
+// existing ID overwrites the previous snapshot.
+func SnapshotScene(ctx context.Context, client HAClient, sceneID string, entityIDs []string) error {
+	if sceneID == "" {
+		return fmt.Errorf("scene ID is required")
+	}
+	if len(entityIDs) == 0 {
+		return fmt.Errorf("at least one entity is required to snapshot scene %s", sceneID)
+	}
+
+	return client.CallService(ctx, "scene", "create", map[string]interface{}{
+		"scene_id":          sceneID,
+		"snapshot_entities": entityIDs,
+	})
+}
+
+// RestoreScene re-applies a scene previously captured with SnapshotScene
+func RestoreScene(ctx context.Context, client HAClient, sceneID string) error {
+	if sceneID == "" {
+		return fmt.Errorf("scene ID is required")
+	}
+
+	return client.CallService(ctx, "scene", "turn_on", map[string]interface{}{
+		"entity_id": sceneEntityID(sceneID),
+	})
+}
+
+// sceneEntityID converts a scene ID into its entity ID
+// e.g., "lockdown_snapshot" -> "scene.lockdown_snapshot"
+func sceneEntityID(sceneID string) string {
+	if strings.HasPrefix(sceneID, "scene.") {
+		return sceneID
+	}
+	return "scene." + sceneID
+}
@@ -0,0 +1,63 @@
+package ha
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotScene(t *testing.T) {
+	client := NewMockClient()
+
+	err := SnapshotScene(context.Background(), client, "lockdown_snapshot", []string{"light.kitchen", "light.n_office"})
+	require.NoError(t, err)
+
+	calls := client.GetServiceCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene", calls[0].Domain)
+	assert.Equal(t, "create", calls[0].Service)
+	assert.Equal(t, "lockdown_snapshot", calls[0].Data["scene_id"])
+	assert.Equal(t, []string{"light.kitchen", "light.n_office"}, calls[0].Data["snapshot_entities"])
+}
+
+func TestSnapshotScene_Validation(t *testing.T) {
+	client := NewMockClient()
+
+	assert.Error(t, SnapshotScene(context.Background(), client, "", []string{"light.kitchen"}))
+	assert.Error(t, SnapshotScene(context.Background(), client, "lockdown_snapshot", nil))
+	assert.Empty(t, client.GetServiceCalls())
+}
+
+func TestRestoreScene(t *testing.T) {
+	tests := []struct {
+		name     string
+		sceneID  string
+		expected string
+	}{
+		{"bare scene ID", "lockdown_snapshot", "scene.lockdown_snapshot"},
+		{"full entity ID", "scene.lockdown_snapshot", "scene.lockdown_snapshot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient()
+
+			require.NoError(t, RestoreScene(context.Background(), client, tt.sceneID))
+
+			calls := client.GetServiceCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, "scene", calls[0].Domain)
+			assert.Equal(t, "turn_on", calls[0].Service)
+			assert.Equal(t, tt.expected, calls[0].Data["entity_id"])
+		})
+	}
+}
+
+func TestRestoreScene_Validation(t *testing.T) {
+	client := NewMockClient()
+
+	assert.Error(t, RestoreScene(context.Background(), client, ""))
+	assert.Empty(t, client.GetServiceCalls())
+}
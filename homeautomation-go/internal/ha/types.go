@@ -75,6 +75,15 @@ type CallServiceRequest struct {
 	Target      *ServiceTarget         `json:"target,omitempty"`
 }
 
+// ServiceCall is a service call: one call of a CallServices batch, or one
+// recorded by MockClient, which also sets Time
+type ServiceCall struct {
+	Domain  string
+	Service string
+	Data    map[string]interface{}
+	Time    time.Time
+}
+
 // ServiceTarget represents service call target
 type ServiceTarget struct {
 	EntityID []string `json:"entity_id,omitempty"`
@@ -0,0 +1,32 @@
+package ha
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EntityDomain returns the domain of an entity ID, e.g. "fan.bedroom" ->
+// "fan". An ID without one gives "homeassistant", whose turn_on and turn_off
+// services work for any entity.
+func EntityDomain(entityID string) string {
+	if domain, _, ok := strings.Cut(entityID, "."); ok && domain != "" {
+		return domain
+	}
+	return "homeassistant"
+}
+
+// ToFloat converts a state or attribute value, which may be reported as a
+// number or a string, to a float
+func ToFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected value type %T", value)
+	}
+}
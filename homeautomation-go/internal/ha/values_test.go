@@ -0,0 +1,42 @@
+package ha
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityDomain(t *testing.T) {
+	assert.Equal(t, "fan", EntityDomain("fan.bedroom"))
+	assert.Equal(t, "input_number", EntityDomain("input_number.backup_reserve"))
+	assert.Equal(t, "homeassistant", EntityDomain("bedroom"))
+	assert.Equal(t, "homeassistant", EntityDomain(".bedroom"))
+}
+
+func TestToFloat(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected float64
+		wantErr  bool
+	}{
+		{"float", 21.5, 21.5, false},
+		{"int", 40, 40, false},
+		{"string", "68.5", 68.5, false},
+		{"unavailable", "unavailable", 0, true},
+		{"nil", nil, 0, true},
+		{"bool", true, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := ToFloat(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
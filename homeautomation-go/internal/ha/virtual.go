@@ -0,0 +1,173 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// VirtualClient is an in-memory Home Assistant for SIMULATION mode. It starts
+// from a snapshot of entity states, applies service calls to its own states
+// instead of sending them anywhere, and can replay recorded state changes.
+type VirtualClient struct {
+	*MockClient
+	logger *zap.Logger
+}
+
+// NewVirtualClient creates a virtual client seeded with the given states
+func NewVirtualClient(snapshot []*State, logger *zap.Logger) *VirtualClient {
+	mock := NewMockClient()
+	for _, s := range snapshot {
+		mock.SetMockState(s.EntityID, s)
+	}
+	return &VirtualClient{MockClient: mock, logger: logger.Named("virtual-ha")}
+}
+
+// LoadStates reads a JSON array of entity states, such as the output of Home
+// Assistant's /api/states endpoint. Entries without an entity_id are skipped
+// and the rest are sorted by last_changed, so the same file format serves as a
+// snapshot and as a recording to replay.
+func LoadStates(path string) ([]*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read states file: %w", err)
+	}
+
+	var raw []*State
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse states file: expected an array of HA states: %w", err)
+	}
+
+	states := make([]*State, 0, len(raw))
+	for _, s := range raw {
+		if s != nil && s.EntityID != "" {
+			states = append(states, s)
+		}
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].LastChanged.Before(states[j].LastChanged)
+	})
+	return states, nil
+}
+
+// CallService logs the call a plugin would have made and applies it to the
+// virtual states. Lights and fans are switched too, which the test mock leaves alone.
+func (v *VirtualClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	v.logger.Info("SIMULATION: service call",
+		zap.String("domain", domain),
+		zap.String("service", service),
+		zap.Any("data", data))
+
+	if err := v.MockClient.CallService(ctx, domain, service, data); err != nil {
+		return err
+	}
+
+	if domain != "light" && domain != "fan" {
+		return nil
+	}
+	var value string
+	switch service {
+	case "turn_on":
+		value = "on"
+	case "turn_off":
+		value = "off"
+	default:
+		return nil
+	}
+
+	var entityIDs []string
+	if entityID, ok := data["entity_id"].(string); ok {
+		entityIDs = []string{entityID}
+	} else if ids, ok := data["entity_id"].([]string); ok {
+		entityIDs = ids
+	}
+	for _, entityID := range entityIDs {
+		v.switchEntity(entityID, value)
+	}
+	return nil
+}
+
+// switchEntity sets an entity on or off, keeping its attributes
+func (v *VirtualClient) switchEntity(entityID, value string) {
+	v.statesMu.RLock()
+	current := v.states[entityID]
+	v.statesMu.RUnlock()
+
+	var attributes map[string]interface{}
+	if current != nil {
+		if current.State == value {
+			return
+		}
+		attributes = current.Attributes
+	}
+	v.SetState(entityID, value, attributes)
+}
+
+// SetInputBoolean routes through CallService so the write is logged
+func (v *VirtualClient) SetInputBoolean(ctx context.Context, name string, value bool) error {
+	service := "turn_off"
+	if value {
+		service = "turn_on"
+	}
+	return v.CallService(ctx, "input_boolean", service, map[string]interface{}{
+		"entity_id": fmt.Sprintf("input_boolean.%s", name),
+	})
+}
+
+// SetInputNumber routes through CallService so the write is logged
+func (v *VirtualClient) SetInputNumber(ctx context.Context, name string, value float64) error {
+	return v.CallService(ctx, "input_number", "set_value", map[string]interface{}{
+		"entity_id": fmt.Sprintf("input_number.%s", name),
+		"value":     value,
+	})
+}
+
+// SetInputText routes through CallService so the write is logged
+func (v *VirtualClient) SetInputText(ctx context.Context, name string, value string) error {
+	return v.CallService(ctx, "input_text", "set_value", map[string]interface{}{
+		"entity_id": fmt.Sprintf("input_text.%s", name),
+		"value":     value,
+	})
+}
+
+// Replay applies recorded state changes in order, waiting the recorded gap
+// between consecutive changes divided by speed. It returns when the recording
+// ends or ctx is cancelled.
+func (v *VirtualClient) Replay(ctx context.Context, recording []*State, speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("replay speed must be positive, got %v", speed)
+	}
+
+	v.logger.Info("Replaying recorded state changes",
+		zap.Int("changes", len(recording)),
+		zap.Float64("speed", speed))
+
+	for i, change := range recording {
+		if i > 0 {
+			gap := time.Duration(float64(change.LastChanged.Sub(recording[i-1].LastChanged)) / speed)
+			if gap > 0 {
+				timer := time.NewTimer(gap)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				}
+			}
+		}
+
+		v.logger.Info("SIMULATION: replaying state change",
+			zap.String("entity_id", change.EntityID),
+			zap.String("state", change.State),
+			zap.Time("recorded_at", change.LastChanged))
+		v.SetState(change.EntityID, change.State, change.Attributes)
+	}
+
+	v.logger.Info("Replay finished")
+	return nil
+}
@@ -0,0 +1,87 @@
+package ha
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLoadStates_SortsByLastChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "states.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"entity_id": "input_boolean.anyone_home", "state": "off", "last_changed": "2025-06-15T08:00:00Z"},
+		{"state": "ignored"},
+		{"entity_id": "light.kitchen", "state": "on", "attributes": {"brightness": 200}, "last_changed": "2025-06-15T07:00:00Z"}
+	]`), 0644))
+
+	states, err := LoadStates(path)
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, "light.kitchen", states[0].EntityID)
+	assert.Equal(t, float64(200), states[0].Attributes["brightness"])
+	assert.Equal(t, "input_boolean.anyone_home", states[1].EntityID)
+
+	_, err = LoadStates(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestVirtualClient_AppliesServiceCalls(t *testing.T) {
+	client := NewVirtualClient([]*State{
+		{EntityID: "input_boolean.anyone_home", State: "off"},
+		{EntityID: "light.kitchen", State: "off", Attributes: map[string]interface{}{"friendly_name": "Kitchen"}},
+	}, zap.NewNop())
+	require.NoError(t, client.Connect(context.Background()))
+
+	var changes []string
+	_, err := client.SubscribeStateChanges("light.kitchen", func(entityID string, oldState, newState *State) {
+		changes = append(changes, oldState.State+"->"+newState.State)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.SetInputBoolean(context.Background(), "anyone_home", true))
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}))
+
+	anyoneHome, err := client.GetState(context.Background(), "input_boolean.anyone_home")
+	require.NoError(t, err)
+	assert.Equal(t, "on", anyoneHome.State)
+
+	kitchen, err := client.GetState(context.Background(), "light.kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, "on", kitchen.State)
+	assert.Equal(t, "Kitchen", kitchen.Attributes["friendly_name"])
+	assert.Equal(t, []string{"off->on"}, changes)
+	assert.Len(t, client.GetServiceCalls(), 2)
+}
+
+func TestVirtualClient_Replay(t *testing.T) {
+	client := NewVirtualClient([]*State{{EntityID: "sensor.hw_flow", State: "0"}}, zap.NewNop())
+	require.NoError(t, client.Connect(context.Background()))
+
+	var seen []string
+	_, err := client.SubscribeStateChanges("sensor.hw_flow", func(entityID string, oldState, newState *State) {
+		seen = append(seen, newState.State)
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2025, 6, 15, 7, 0, 0, 0, time.UTC)
+	recording := []*State{
+		{EntityID: "sensor.hw_flow", State: "2.5", LastChanged: start},
+		{EntityID: "sensor.hw_flow", State: "0", LastChanged: start.Add(time.Hour)},
+	}
+
+	// An hour between changes at 3.6 million times speed is a millisecond
+	require.NoError(t, client.Replay(context.Background(), recording, 3600*1000))
+	assert.Equal(t, []string{"2.5", "0"}, seen)
+
+	assert.Error(t, client.Replay(context.Background(), recording, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, client.Replay(ctx, recording, 1), context.Canceled)
+}
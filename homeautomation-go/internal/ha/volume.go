@@ -0,0 +1,93 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// SonosSteps is the top of the Sonos volume scale used by the music config:
+// base_volume and the wake-up fallback volume run 0-15
+const SonosSteps = 15
+
+// Volume is a speaker volume. Home Assistant's media_player.volume_set takes a
+// 0.0-1.0 level, the music config uses the 0-15 Sonos scale and sleep hygiene
+// fades in percent; mixing them up (dividing a Sonos volume by 100, say) plays
+// at the wrong loudness without any error, so every volume_set goes through a
+// Volume built from the scale the value came from. The zero Volume is silent.
+type Volume struct {
+	level float64 // 0.0-1.0
+}
+
+// VolumeFromLevel creates a volume from a Home Assistant 0.0-1.0 level,
+// clamping values out of range
+func VolumeFromLevel(level float64) Volume {
+	if math.IsNaN(level) || level < 0 {
+		return Volume{}
+	}
+	return Volume{level: math.Min(level, 1)}
+}
+
+// VolumeFromPercent creates a volume from 0-100 percent
+func VolumeFromPercent(percent int) Volume {
+	return VolumeFromLevel(float64(percent) / 100)
+}
+
+// VolumeFromSonos creates a volume from the 0-15 Sonos scale
+func VolumeFromSonos(steps int) Volume {
+	return VolumeFromLevel(float64(steps) / SonosSteps)
+}
+
+// Level returns the 0.0-1.0 level Home Assistant uses
+func (v Volume) Level() float64 {
+	return v.level
+}
+
+// Percent returns the volume rounded to 0-100 percent
+func (v Volume) Percent() int {
+	return int(math.Round(v.level * 100))
+}
+
+// Sonos returns the volume rounded to the 0-15 Sonos scale
+func (v Volume) Sonos() int {
+	return int(math.Round(v.level * SonosSteps))
+}
+
+// IsZero reports whether the volume is silent
+func (v Volume) IsZero() bool {
+	return v.level == 0
+}
+
+func (v Volume) String() string {
+	return fmt.Sprintf("%d%%", v.Percent())
+}
+
+// ServiceData returns the media_player.volume_set data setting entityID (one
+// entity ID or a list) to v, for callers that wrap CallService themselves
+func (v Volume) ServiceData(entityID interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"entity_id":    entityID,
+		"volume_level": v.level,
+	}
+}
+
+// SetVolume sets a media player (or list of them) to v
+func SetVolume(ctx context.Context, client HAClient, entityID interface{}, v Volume) error {
+	return client.CallService(ctx, "media_player", "volume_set", v.ServiceData(entityID))
+}
+
+// VolumeOf reads a media player's volume from its volume_level attribute. It
+// returns false if the state is missing or has no numeric volume_level.
+func VolumeOf(state *State) (Volume, bool) {
+	if state == nil {
+		return Volume{}, false
+	}
+	switch level := state.Attributes["volume_level"].(type) {
+	case float64:
+		return VolumeFromLevel(level), true
+	case int:
+		return VolumeFromLevel(float64(level)), true
+	default:
+		return Volume{}, false
+	}
+}
@@ -0,0 +1,90 @@
+package ha
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolume_Conversions(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  Volume
+		level   float64
+		percent int
+		sonos   int
+	}{
+		{"zero value", Volume{}, 0, 0, 0},
+		{"sonos 0", VolumeFromSonos(0), 0, 0, 0},
+		{"sonos 6", VolumeFromSonos(6), 0.4, 40, 6},
+		{"sonos max", VolumeFromSonos(SonosSteps), 1, 100, 15},
+		{"sonos above max clamps", VolumeFromSonos(16), 1, 100, 15},
+		{"negative sonos clamps", VolumeFromSonos(-1), 0, 0, 0},
+		{"percent 58", VolumeFromPercent(58), 0.58, 58, 9},
+		{"percent 100", VolumeFromPercent(100), 1, 100, 15},
+		{"percent above 100 clamps", VolumeFromPercent(150), 1, 100, 15},
+		{"level 0.2", VolumeFromLevel(0.2), 0.2, 20, 3},
+		{"level above 1 clamps", VolumeFromLevel(1.5), 1, 100, 15},
+		{"negative level clamps", VolumeFromLevel(-0.1), 0, 0, 0},
+		{"NaN is silent", VolumeFromLevel(math.NaN()), 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.level, tt.volume.Level(), 1e-9)
+			assert.Equal(t, tt.percent, tt.volume.Percent())
+			assert.Equal(t, tt.sonos, tt.volume.Sonos())
+		})
+	}
+}
+
+func TestVolume_RoundTrips(t *testing.T) {
+	for steps := 0; steps <= SonosSteps; steps++ {
+		assert.Equal(t, steps, VolumeFromSonos(steps).Sonos())
+	}
+	for percent := 0; percent <= 100; percent++ {
+		assert.Equal(t, percent, VolumeFromPercent(percent).Percent(), "0.58*100 must not truncate to 57")
+	}
+}
+
+func TestVolume_IsZeroAndString(t *testing.T) {
+	assert.True(t, Volume{}.IsZero())
+	assert.False(t, VolumeFromSonos(1).IsZero())
+	assert.Equal(t, "40%", VolumeFromSonos(6).String())
+}
+
+func TestSetVolume(t *testing.T) {
+	client := NewMockClient()
+
+	err := SetVolume(context.Background(), client, []string{"media_player.kitchen", "media_player.office"}, VolumeFromSonos(6))
+	require.NoError(t, err)
+
+	calls := client.GetServiceCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "media_player", calls[0].Domain)
+	assert.Equal(t, "volume_set", calls[0].Service)
+	assert.Equal(t, []string{"media_player.kitchen", "media_player.office"}, calls[0].Data["entity_id"])
+	assert.InDelta(t, 0.4, calls[0].Data["volume_level"], 1e-9)
+}
+
+func TestVolumeOf(t *testing.T) {
+	volume, ok := VolumeOf(&State{Attributes: map[string]interface{}{"volume_level": 0.75}})
+	require.True(t, ok)
+	assert.Equal(t, 75, volume.Percent())
+
+	volume, ok = VolumeOf(&State{Attributes: map[string]interface{}{"volume_level": 1}})
+	require.True(t, ok)
+	assert.Equal(t, 100, volume.Percent())
+
+	_, ok = VolumeOf(&State{Attributes: map[string]interface{}{}})
+	assert.False(t, ok)
+
+	_, ok = VolumeOf(&State{Attributes: map[string]interface{}{"volume_level": "loud"}})
+	assert.False(t, ok)
+
+	_, ok = VolumeOf(nil)
+	assert.False(t, ok)
+}
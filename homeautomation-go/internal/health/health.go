@@ -0,0 +1,235 @@
+// Package health lets plugin managers report whether they are working. Each
+// manager keeps a Recorder: it marks a tick after every successful evaluation,
+// records its subscription count when it starts, and counts the errors it logs.
+// The API aggregates the reports at /health.
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Status values, from best to worst
+const (
+	StatusOK        = "ok"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
+)
+
+// RecentErrorWindow is how long a logged error keeps a plugin degraded
+const RecentErrorWindow = 15 * time.Minute
+
+// staleTicks is how many tick intervals may pass without a tick before a
+// plugin is considered stuck
+const staleTicks = 3
+
+// Report is a plugin's health
+type Report struct {
+	Status        string     `json:"status"`
+	Reason        string     `json:"reason,omitempty"`
+	Running       bool       `json:"running"`
+	LastTick      *time.Time `json:"lastTick,omitempty"`
+	Subscriptions int        `json:"subscriptions"`
+	Errors        int        `json:"errors"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorAt   *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Checker is implemented by managers that report their health
+type Checker interface {
+	Health() Report
+}
+
+// Worse reports whether status a is worse than status b
+func Worse(a, b string) bool {
+	return rank(a) > rank(b)
+}
+
+func rank(status string) int {
+	switch status {
+	case StatusOK:
+		return 0
+	case StatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Recorder tracks one manager's health.
+// All methods are safe to call on a nil Recorder.
+type Recorder struct {
+	interval time.Duration
+	clock    clock.Clock
+
+	// Running state, ticks and errors (protected by mu)
+	running       bool
+	startedAt     time.Time
+	lastTick      time.Time
+	subscriptions int
+	errors        int
+	lastError     string
+	lastErrorAt   time.Time
+	mu            sync.Mutex
+}
+
+// NewRecorder creates a recorder. interval is how often the manager ticks on
+// its own; a manager that only reacts to changes passes 0 and is never
+// considered stuck.
+func NewRecorder(interval time.Duration) *Recorder {
+	return &Recorder{interval: interval, clock: clock.NewRealClock()}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (r *Recorder) SetClock(c clock.Clock) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = c
+}
+
+// Logger returns logger with every error-level entry counted as an error,
+// whatever level logger itself writes at
+func (r *Recorder) Logger(logger *zap.Logger) *zap.Logger {
+	if r == nil {
+		return logger
+	}
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, errorCounter{r})
+	}))
+}
+
+// errorCounter is a zap core that counts error-level entries instead of
+// writing them
+type errorCounter struct {
+	recorder *Recorder
+}
+
+func (c errorCounter) Enabled(level zapcore.Level) bool {
+	return level >= zapcore.ErrorLevel
+}
+
+func (c errorCounter) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c errorCounter) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c errorCounter) Write(entry zapcore.Entry, _ []zapcore.Field) error {
+	c.recorder.Error(entry.Message)
+	return nil
+}
+
+func (c errorCounter) Sync() error {
+	return nil
+}
+
+// Started marks the manager running with its subscription count
+func (r *Recorder) Started(subscriptions int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = true
+	r.startedAt = r.clock.Now()
+	r.subscriptions = subscriptions
+}
+
+// Stopped marks the manager stopped
+func (r *Recorder) Stopped() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = false
+	r.subscriptions = 0
+}
+
+// Tick marks a successful evaluation
+func (r *Recorder) Tick() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastTick = r.clock.Now()
+}
+
+// Error counts an error
+func (r *Recorder) Error(message string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors++
+	r.lastError = message
+	r.lastErrorAt = r.clock.Now()
+}
+
+// Report returns the manager's health. A manager that isn't running, or that
+// ticks on its own but hasn't for three intervals, is unhealthy; one that
+// logged an error in the last RecentErrorWindow is degraded.
+func (r *Recorder) Report() Report {
+	if r == nil {
+		return Report{Status: StatusOK}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{
+		Status:        StatusOK,
+		Running:       r.running,
+		LastTick:      optionalTime(r.lastTick),
+		Subscriptions: r.subscriptions,
+		Errors:        r.errors,
+		LastError:     r.lastError,
+		LastErrorAt:   optionalTime(r.lastErrorAt),
+	}
+	now := r.clock.Now()
+
+	switch {
+	case !r.running:
+		report.Status = StatusUnhealthy
+		report.Reason = "not running"
+	case r.interval > 0 && now.Sub(r.lastActivity()) > staleTicks*r.interval:
+		report.Status = StatusUnhealthy
+		report.Reason = fmt.Sprintf("no evaluation for %s", now.Sub(r.lastActivity()).Round(time.Second))
+	case !r.lastErrorAt.IsZero() && now.Sub(r.lastErrorAt) < RecentErrorWindow:
+		report.Status = StatusDegraded
+		report.Reason = "recent error: " + r.lastError
+	}
+	return report
+}
+
+// lastActivity is the last tick, or the start if there hasn't been one since;
+// callers must hold mu
+func (r *Recorder) lastActivity() time.Time {
+	if r.lastTick.After(r.startedAt) {
+		return r.lastTick
+	}
+	return r.startedAt
+}
+
+// optionalTime returns nil for the zero time, so it's left out of the JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
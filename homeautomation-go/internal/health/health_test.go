@@ -0,0 +1,95 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestRecorder(interval time.Duration) (*Recorder, *clock.MockClock) {
+	mockClock := clock.NewMockClock(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	recorder := NewRecorder(interval)
+	recorder.SetClock(mockClock)
+	return recorder, mockClock
+}
+
+func TestRecorder_NotRunningIsUnhealthy(t *testing.T) {
+	recorder, _ := newTestRecorder(0)
+	report := recorder.Report()
+	assert.Equal(t, StatusUnhealthy, report.Status)
+	assert.Equal(t, "not running", report.Reason)
+
+	recorder.Started(3)
+	report = recorder.Report()
+	assert.Equal(t, StatusOK, report.Status)
+	assert.True(t, report.Running)
+	assert.Equal(t, 3, report.Subscriptions)
+
+	recorder.Stopped()
+	assert.Equal(t, StatusUnhealthy, recorder.Report().Status)
+}
+
+func TestRecorder_StaleTicksAreUnhealthy(t *testing.T) {
+	recorder, mockClock := newTestRecorder(time.Minute)
+	recorder.Started(1)
+
+	mockClock.Advance(2 * time.Minute)
+	assert.Equal(t, StatusOK, recorder.Report().Status, "within three intervals of starting")
+
+	recorder.Tick()
+	mockClock.Advance(3 * time.Minute)
+	assert.Equal(t, StatusOK, recorder.Report().Status)
+
+	mockClock.Advance(time.Second)
+	report := recorder.Report()
+	assert.Equal(t, StatusUnhealthy, report.Status)
+	assert.Equal(t, "no evaluation for 3m1s", report.Reason)
+}
+
+func TestRecorder_EventDrivenNeverStale(t *testing.T) {
+	recorder, mockClock := newTestRecorder(0)
+	recorder.Started(2)
+	mockClock.Advance(48 * time.Hour)
+	assert.Equal(t, StatusOK, recorder.Report().Status)
+}
+
+func TestRecorder_LoggedErrorsDegrade(t *testing.T) {
+	recorder, mockClock := newTestRecorder(0)
+	recorder.Started(1)
+	logger := recorder.Logger(zap.NewNop())
+
+	logger.Warn("Not counted")
+	assert.Equal(t, StatusOK, recorder.Report().Status)
+
+	logger.Error("Failed to call service")
+	report := recorder.Report()
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, "Failed to call service", report.LastError)
+
+	mockClock.Advance(RecentErrorWindow)
+	report = recorder.Report()
+	assert.Equal(t, StatusOK, report.Status, "old errors are still counted but no longer degrade")
+	assert.Equal(t, 1, report.Errors)
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var recorder *Recorder
+	recorder.Started(1)
+	recorder.Tick()
+	recorder.Error("ignored")
+	recorder.Stopped()
+	assert.Equal(t, StatusOK, recorder.Report().Status)
+	assert.NotNil(t, recorder.Logger(zap.NewNop()))
+}
+
+func TestWorse(t *testing.T) {
+	assert.True(t, Worse(StatusUnhealthy, StatusDegraded))
+	assert.True(t, Worse(StatusDegraded, StatusOK))
+	assert.False(t, Worse(StatusOK, StatusDegraded))
+	assert.False(t, Worse(StatusDegraded, StatusDegraded))
+}
@@ -0,0 +1,193 @@
+package journal
+
+import (
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// Capacity is how many events the journal keeps; the oldest are dropped first
+const Capacity = 5000
+
+// Event kinds
+const (
+	KindStateChange  = "state_change"
+	KindPluginAction = "plugin_action"
+)
+
+// Event is one entry in the journal: a state variable change or a plugin action
+type Event struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Kind      string      `json:"kind"`
+	Variable  string      `json:"variable,omitempty"` // State changes only
+	OldValue  interface{} `json:"oldValue,omitempty"`
+	NewValue  interface{} `json:"newValue,omitempty"`
+	Plugin    string      `json:"plugin,omitempty"` // Plugin actions only
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// Filter selects events from the journal. Zero fields match everything.
+type Filter struct {
+	Plugin   string    // Only actions by this plugin
+	Variable string    // Only changes to this state variable
+	Since    time.Time // Events at or after this time
+	Until    time.Time // Events at or before this time
+	Limit    int       // Keep only the newest Limit matches
+}
+
+// matches reports whether an event passes the filter
+func (f Filter) matches(e Event) bool {
+	if f.Plugin != "" && e.Plugin != f.Plugin {
+		return false
+	}
+	if f.Variable != "" && e.Variable != f.Variable {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Journal records every state variable change and plugin action in a bounded
+// ring buffer. It is in memory only, so history starts at the last restart.
+type Journal struct {
+	stateManager  *state.Manager
+	shadowTracker *shadowstate.Tracker
+	logger        *zap.Logger
+	clock         clock.Clock
+
+	mu     sync.Mutex
+	events []Event // Ring buffer of up to capacity events
+	next   int     // Index the next event is written to
+	full   bool    // Whether the buffer has wrapped
+
+	subscriptions      []state.Subscription
+	unsubscribeActions func()
+}
+
+// New creates a journal that keeps up to capacity events
+func New(stateManager *state.Manager, shadowTracker *shadowstate.Tracker, logger *zap.Logger, capacity int) *Journal {
+	if capacity <= 0 {
+		capacity = Capacity
+	}
+
+	return &Journal{
+		stateManager:  stateManager,
+		shadowTracker: shadowTracker,
+		logger:        logger.Named("journal"),
+		clock:         clock.NewRealClock(),
+		events:        make([]Event, capacity),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (j *Journal) SetClock(c clock.Clock) {
+	j.clock = c
+}
+
+// Start subscribes to every state variable and to plugin actions
+func (j *Journal) Start() error {
+	j.logger.Info("Starting event journal", zap.Int("capacity", len(j.events)))
+
+	for _, variable := range state.AllVariables {
+		sub, err := j.stateManager.Subscribe(variable.Key, j.handleStateChange)
+		if err != nil {
+			j.logger.Warn("Failed to subscribe to state variable",
+				zap.String("key", variable.Key),
+				zap.Error(err))
+			continue
+		}
+		j.subscriptions = append(j.subscriptions, sub)
+	}
+
+	j.unsubscribeActions = j.shadowTracker.SubscribeActions(j.handleAction)
+
+	return nil
+}
+
+// Stop unsubscribes from state changes and plugin actions
+func (j *Journal) Stop() {
+	j.logger.Info("Stopping event journal")
+
+	if j.unsubscribeActions != nil {
+		j.unsubscribeActions()
+		j.unsubscribeActions = nil
+	}
+
+	for _, sub := range j.subscriptions {
+		sub.Unsubscribe()
+	}
+	j.subscriptions = nil
+}
+
+// GetHistory returns the events matching the filter, oldest first
+func (j *Journal) GetHistory(filter Filter) []Event {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	events := make([]Event, 0)
+	j.each(func(e Event) {
+		if filter.matches(e) {
+			events = append(events, e)
+		}
+	})
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events
+}
+
+// handleStateChange records a state variable change
+func (j *Journal) handleStateChange(key string, oldValue, newValue interface{}) {
+	j.record(Event{
+		Timestamp: j.clock.Now(),
+		Kind:      KindStateChange,
+		Variable:  key,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+	})
+}
+
+// handleAction records a plugin action
+func (j *Journal) handleAction(action shadowstate.ActionEvent) {
+	j.record(Event{
+		Timestamp: action.Timestamp,
+		Kind:      KindPluginAction,
+		Plugin:    action.Plugin,
+		Reason:    action.Reason,
+	})
+}
+
+// record appends an event, overwriting the oldest once the buffer is full
+func (j *Journal) record(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.events[j.next] = e
+	j.next = (j.next + 1) % len(j.events)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// each calls fn for every buffered event, oldest first. Callers hold mu.
+func (j *Journal) each(fn func(Event)) {
+	if j.full {
+		for _, e := range j.events[j.next:] {
+			fn(e)
+		}
+	}
+	for _, e := range j.events[:j.next] {
+		fn(e)
+	}
+}
@@ -0,0 +1,108 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testStart = time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+
+func setupTest(t *testing.T, capacity int) (*Journal, *ha.MockClient, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SyncFromHA())
+	journal := New(stateManager, shadowstate.NewTracker(), logger, capacity)
+	mockClock := clock.NewMockClock(testStart)
+	journal.SetClock(mockClock)
+
+	require.NoError(t, journal.Start())
+	t.Cleanup(journal.Stop)
+
+	return journal, mockHA, mockClock
+}
+
+func TestRecordsStateChanges(t *testing.T) {
+	journal, mockHA, mockClock := setupTest(t, 10)
+
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+	mockClock.Advance(time.Minute)
+	mockHA.SetState("input_boolean.anyone_home", "off", nil)
+
+	events := journal.GetHistory(Filter{Variable: "isAnyoneHome"})
+	require.Len(t, events, 2)
+	assert.Equal(t, Event{
+		Timestamp: testStart.Add(time.Minute),
+		Kind:      KindStateChange,
+		Variable:  "isAnyoneHome",
+		OldValue:  true,
+		NewValue:  false,
+	}, events[1])
+}
+
+func TestRecordsEveryPluginActionWithReason(t *testing.T) {
+	logger := zap.NewNop()
+	stateManager := state.NewManager(ha.NewMockClient(), logger, false)
+	tracker := shadowstate.NewTracker()
+	journal := New(stateManager, tracker, logger, 10)
+	require.NoError(t, journal.Start())
+
+	// Actions in the same instant are each recorded
+	tracker.RecordAction("hotwater", testStart, "Someone woke up")
+	tracker.RecordAction("hotwater", testStart, "Someone woke up")
+	tracker.RecordAction("hotwater", testStart.Add(time.Minute), "Nobody home")
+
+	journal.Stop()
+	tracker.RecordAction("hotwater", testStart.Add(2*time.Minute), "Someone woke up")
+
+	events := journal.GetHistory(Filter{Plugin: "hotwater"})
+	require.Len(t, events, 3, "actions after Stop aren't recorded")
+	assert.Equal(t, "Someone woke up", events[1].Reason)
+	assert.Equal(t, Event{
+		Timestamp: testStart.Add(time.Minute),
+		Kind:      KindPluginAction,
+		Plugin:    "hotwater",
+		Reason:    "Nobody home",
+	}, events[2])
+}
+
+func TestDropsOldestWhenFull(t *testing.T) {
+	journal, _, _ := setupTest(t, 3)
+
+	for i := 0; i < 5; i++ {
+		journal.record(Event{Timestamp: testStart.Add(time.Duration(i) * time.Minute), Kind: KindStateChange, Variable: "alarmTime"})
+	}
+
+	events := journal.GetHistory(Filter{})
+	require.Len(t, events, 3)
+	assert.Equal(t, testStart.Add(2*time.Minute), events[0].Timestamp)
+	assert.Equal(t, testStart.Add(4*time.Minute), events[2].Timestamp)
+}
+
+func TestFilterByTimeAndLimit(t *testing.T) {
+	journal, _, _ := setupTest(t, 10)
+
+	for i := 0; i < 6; i++ {
+		journal.record(Event{Timestamp: testStart.Add(time.Duration(i) * time.Minute), Kind: KindStateChange, Variable: "alarmTime"})
+	}
+
+	events := journal.GetHistory(Filter{
+		Since: testStart.Add(time.Minute),
+		Until: testStart.Add(4 * time.Minute),
+		Limit: 2,
+	})
+	require.Len(t, events, 2)
+	assert.Equal(t, testStart.Add(3*time.Minute), events[0].Timestamp)
+	assert.Equal(t, testStart.Add(4*time.Minute), events[1].Timestamp)
+}
@@ -0,0 +1,223 @@
+package kidmode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Room is a set of entities kid mode restricts, on while household kid mode
+// is on or while the room's own toggle is
+type Room struct {
+	Name           string   `yaml:"name"`
+	ToggleVariable string   `yaml:"toggle_variable"` // Optional boolean state variable turning kid mode on for just this room
+	Speakers       []string `yaml:"speakers"`        // Volume capped; left out of blocked music modes
+	Lights         []string `yaml:"lights"`          // Flashes softened to a dim glow
+	Thermostats    []string `yaml:"thermostats"`     // Setpoint held when lock_thermostats is on
+}
+
+// Window is a time of day range, "HH:MM", on the listed days (every day when
+// none are listed). A window whose end is before its start spans midnight
+// and belongs to the day it starts on.
+type Window struct {
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+	Days  []string `yaml:"days"` // e.g. ["saturday", "sunday"]
+}
+
+// Settings holds what turns kid mode on and what it restricts
+type Settings struct {
+	ToggleVariable      string   `yaml:"toggle_variable"`       // Boolean state variable turning household kid mode on
+	Schedule            []Window `yaml:"schedule"`              // Optional windows when household kid mode is on
+	Rooms               []Room   `yaml:"rooms"`                 // Entities restricted while their room is in kid mode
+	MaxVolume           int      `yaml:"max_volume"`            // Speaker cap on the Sonos 0-15 scale; 0 doesn't cap
+	BlockedMusicModes   []string `yaml:"blocked_music_modes"`   // Music modes not played while household kid mode is on
+	LockThermostats     bool     `yaml:"lock_thermostats"`      // Hold the rooms' thermostats at their setpoint
+	SoftFlashBrightness int      `yaml:"soft_flash_brightness"` // Brightness percent a softened flash glows at; 0 leaves flashes alone
+}
+
+// Config represents the kid_mode_config.yaml structure
+type Config struct {
+	KidMode Settings `yaml:"kid_mode"`
+}
+
+// weekdays maps day names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Contains reports whether t falls within the window. Invalid windows contain nothing.
+func (w Window) Contains(t time.Time) bool {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	day := t.Weekday()
+	switch {
+	case endMinute > startMinute:
+		if minute < startMinute || minute >= endMinute {
+			return false
+		}
+	case minute >= startMinute:
+	case minute < endMinute:
+		// After midnight, the window started the day before
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return w.onDay(day)
+}
+
+// onDay reports whether the window runs on the weekday
+func (w Window) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the times and days
+func (w Window) Validate() error {
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("invalid start %q, expected HH:MM", w.Start)
+	}
+	if _, err := time.Parse("15:04", w.End); err != nil {
+		return fmt.Errorf("invalid end %q, expected HH:MM", w.End)
+	}
+	if w.Start == w.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	for _, name := range w.Days {
+		if _, ok := weekdays[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown day %q", name)
+		}
+	}
+	return nil
+}
+
+// Blocks reports whether the music mode is blocked
+func (s *Settings) Blocks(musicType string) bool {
+	for _, blocked := range s.BlockedMusicModes {
+		if blocked == musicType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the toggles, schedule, rooms and limits
+func (c *Config) Validate() error {
+	s := c.KidMode
+	if s.ToggleVariable == "" && len(s.Schedule) == 0 {
+		return fmt.Errorf("kid_mode: toggle_variable or schedule is required")
+	}
+	if s.ToggleVariable != "" {
+		if err := validateToggle(s.ToggleVariable); err != nil {
+			return fmt.Errorf("kid_mode: %w", err)
+		}
+	}
+	for i, w := range s.Schedule {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("kid_mode: schedule[%d]: %w", i, err)
+		}
+	}
+
+	if len(s.Rooms) == 0 {
+		return fmt.Errorf("kid_mode: at least one room is required")
+	}
+	names := make(map[string]bool)
+	for i, r := range s.Rooms {
+		if r.Name == "" {
+			return fmt.Errorf("kid_mode: rooms[%d]: name is required", i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("kid_mode: rooms[%d]: duplicate room %q", i, r.Name)
+		}
+		names[r.Name] = true
+
+		if r.ToggleVariable != "" {
+			if err := validateToggle(r.ToggleVariable); err != nil {
+				return fmt.Errorf("kid_mode: room %q: %w", r.Name, err)
+			}
+		}
+		for _, group := range []struct {
+			domain   string
+			entities []string
+		}{
+			{"media_player", r.Speakers},
+			{"light", r.Lights},
+			{"climate", r.Thermostats},
+		} {
+			for _, entityID := range group.entities {
+				if !strings.HasPrefix(entityID, group.domain+".") {
+					return fmt.Errorf("kid_mode: room %q: %q must be a %s.* entity", r.Name, entityID, group.domain)
+				}
+			}
+		}
+	}
+
+	if s.MaxVolume < 0 || s.MaxVolume > ha.SonosSteps {
+		return fmt.Errorf("kid_mode: max_volume must be between 0 and %d, got %d", ha.SonosSteps, s.MaxVolume)
+	}
+	if s.SoftFlashBrightness < 0 || s.SoftFlashBrightness > 100 {
+		return fmt.Errorf("kid_mode: soft_flash_brightness must be between 0 and 100, got %d", s.SoftFlashBrightness)
+	}
+	return nil
+}
+
+// validateToggle checks that a toggle is a known boolean state variable
+func validateToggle(key string) error {
+	variable, ok := state.VariablesByKey()[key]
+	if !ok {
+		return fmt.Errorf("unknown toggle_variable %q", key)
+	}
+	if variable.Type != state.TypeBool {
+		return fmt.Errorf("toggle_variable %q must be a boolean", key)
+	}
+	return nil
+}
+
+// LoadConfig loads the kid mode configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,75 @@
+package kidmode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../configs/kid_mode_config.yaml")
+	require.NoError(t, err)
+
+	s := config.KidMode
+	assert.Equal(t, "isKidMode", s.ToggleVariable)
+	assert.NotEmpty(t, s.Rooms)
+	assert.True(t, s.Blocks("sex"))
+	assert.False(t, s.Blocks("day"))
+}
+
+func TestValidate(t *testing.T) {
+	room := Room{Name: "living", Speakers: []string{"media_player.kitchen"}}
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  string
+	}{
+		{"toggle only", Settings{ToggleVariable: "isKidMode", Rooms: []Room{room}}, ""},
+		{"schedule only", Settings{Schedule: []Window{{Start: "08:00", End: "19:00"}}, Rooms: []Room{room}}, ""},
+		{"no trigger", Settings{Rooms: []Room{room}}, "toggle_variable or schedule is required"},
+		{"unknown variable", Settings{ToggleVariable: "isKids", Rooms: []Room{room}}, "unknown toggle_variable"},
+		{"non-boolean room toggle", Settings{ToggleVariable: "isKidMode", Rooms: []Room{{Name: "guest", ToggleVariable: "dayPhase"}}}, "must be a boolean"},
+		{"bad window", Settings{Schedule: []Window{{Start: "8am", End: "19:00"}}, Rooms: []Room{room}}, "schedule[0]"},
+		{"unknown day", Settings{Schedule: []Window{{Start: "08:00", End: "19:00", Days: []string{"funday"}}}, Rooms: []Room{room}}, "unknown day"},
+		{"no rooms", Settings{ToggleVariable: "isKidMode"}, "at least one room"},
+		{"duplicate room", Settings{ToggleVariable: "isKidMode", Rooms: []Room{room, room}}, "duplicate room"},
+		{"light as speaker", Settings{ToggleVariable: "isKidMode", Rooms: []Room{{Name: "x", Speakers: []string{"light.kitchen"}}}}, "media_player.* entity"},
+		{"sensor as thermostat", Settings{ToggleVariable: "isKidMode", Rooms: []Room{{Name: "x", Thermostats: []string{"sensor.temp"}}}}, "climate.* entity"},
+		{"volume on the wrong scale", Settings{ToggleVariable: "isKidMode", Rooms: []Room{room}, MaxVolume: 40}, "max_volume"},
+		{"brightness over 100", Settings{ToggleVariable: "isKidMode", Rooms: []Room{room}, SoftFlashBrightness: 150}, "soft_flash_brightness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{KidMode: tt.settings}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	// 2024-01-13 is a Saturday
+	saturday := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 13, hour, minute, 0, 0, time.UTC)
+	}
+	weekend := Window{Start: "08:00", End: "19:30", Days: []string{"Saturday", "sunday"}}
+	overnight := Window{Start: "20:00", End: "07:00", Days: []string{"friday"}}
+
+	assert.True(t, weekend.Contains(saturday(8, 0)))
+	assert.True(t, weekend.Contains(saturday(19, 29)))
+	assert.False(t, weekend.Contains(saturday(19, 30)), "the end is exclusive")
+	assert.False(t, weekend.Contains(saturday(7, 59)))
+	assert.False(t, weekend.Contains(saturday(12, 0).AddDate(0, 0, 2)), "Monday isn't listed")
+
+	assert.True(t, overnight.Contains(saturday(6, 0)), "early Saturday belongs to Friday's window")
+	assert.False(t, overnight.Contains(saturday(21, 0)))
+	assert.True(t, overnight.Contains(saturday(21, 0).AddDate(0, 0, -1)))
+}
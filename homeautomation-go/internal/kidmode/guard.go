@@ -0,0 +1,159 @@
+package kidmode
+
+import (
+	"homeautomation/internal/state"
+)
+
+// ActiveVariable is the local-only variable that is true while household kid
+// mode is on, from the toggle or the schedule. Music participants can list
+// it in leave_muted_if.
+const ActiveVariable = "isKidModeActive"
+
+// softFlashTransition is how many seconds a softened flash fades up over
+const softFlashTransition = 2
+
+// Guard reports which entities kid mode restricts and how. Plugins ask it
+// before acting, so the limits apply across music, lights and climate.
+// All methods are safe to call on a nil Guard, which restricts nothing.
+type Guard struct {
+	config       *Config
+	stateManager *state.Manager
+	byEntity     map[string][]Room
+}
+
+// NewGuard creates a guard reading the toggles and ActiveVariable from the state manager
+func NewGuard(config *Config, stateManager *state.Manager) *Guard {
+	byEntity := make(map[string][]Room)
+	for _, r := range config.KidMode.Rooms {
+		for _, entities := range [][]string{r.Speakers, r.Lights, r.Thermostats} {
+			for _, entityID := range entities {
+				byEntity[entityID] = append(byEntity[entityID], r)
+			}
+		}
+	}
+
+	return &Guard{
+		config:       config,
+		stateManager: stateManager,
+		byEntity:     byEntity,
+	}
+}
+
+// Rooms returns the configured rooms
+func (g *Guard) Rooms() []Room {
+	if g == nil {
+		return nil
+	}
+	return g.config.KidMode.Rooms
+}
+
+// IsActive reports whether household kid mode is on
+func (g *Guard) IsActive() bool {
+	if g == nil {
+		return false
+	}
+	active, err := g.stateManager.GetBool(ActiveVariable)
+	return err == nil && active
+}
+
+// RoomActive reports whether kid mode is on for the room, household-wide or by its own toggle
+func (g *Guard) RoomActive(r Room) bool {
+	if g == nil {
+		return false
+	}
+	if g.IsActive() {
+		return true
+	}
+	if r.ToggleVariable == "" {
+		return false
+	}
+	active, err := g.stateManager.GetBool(r.ToggleVariable)
+	return err == nil && active
+}
+
+// Restricts reports whether the entity is in a room with kid mode on,
+// returning that room's name
+func (g *Guard) Restricts(entityID string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	for _, r := range g.byEntity[entityID] {
+		if g.RoomActive(r) {
+			return r.Name, true
+		}
+	}
+	return "", false
+}
+
+// CapVolume returns the speaker's volume (Sonos 0-15 scale) lowered to
+// max_volume when kid mode restricts it
+func (g *Guard) CapVolume(entityID string, volume int) int {
+	if g == nil || g.config.KidMode.MaxVolume == 0 || volume <= g.config.KidMode.MaxVolume {
+		return volume
+	}
+	if _, ok := g.Restricts(entityID); !ok {
+		return volume
+	}
+	return g.config.KidMode.MaxVolume
+}
+
+// BlocksMusicMode reports whether household kid mode is on and the music
+// mode is one it blocks
+func (g *Guard) BlocksMusicMode(musicType string) bool {
+	return g != nil && g.config.KidMode.Blocks(musicType) && g.IsActive()
+}
+
+// BlocksMusicModeOn reports whether the speaker is in a room with kid mode on
+// and the music mode is one it blocks, returning the room's name
+func (g *Guard) BlocksMusicModeOn(entityID, musicType string) (string, bool) {
+	if g == nil || !g.config.KidMode.Blocks(musicType) {
+		return "", false
+	}
+	return g.Restricts(entityID)
+}
+
+// LocksThermostat reports whether the thermostat's setpoint is held by kid
+// mode, so automations should leave it alone
+func (g *Guard) LocksThermostat(entityID string) bool {
+	if g == nil || !g.config.KidMode.LockThermostats {
+		return false
+	}
+	for _, r := range g.byEntity[entityID] {
+		for _, thermostat := range r.Thermostats {
+			if thermostat == entityID && g.RoomActive(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FlashCalls returns the light.turn_on service data for flashing the lights:
+// lights kid mode restricts glow at soft_flash_brightness instead of
+// flashing. The lights' order is kept within each call.
+func (g *Guard) FlashCalls(lights []string) []map[string]interface{} {
+	var flash, soften []string
+	for _, entityID := range lights {
+		if _, ok := g.Restricts(entityID); ok && g.config.KidMode.SoftFlashBrightness > 0 {
+			soften = append(soften, entityID)
+		} else {
+			flash = append(flash, entityID)
+		}
+	}
+
+	var calls []map[string]interface{}
+	if len(flash) > 0 {
+		calls = append(calls, map[string]interface{}{
+			"entity_id": flash,
+			"flash":     "short",
+		})
+	}
+	if len(soften) > 0 {
+		calls = append(calls, map[string]interface{}{
+			"entity_id":      soften,
+			"brightness_pct": g.config.KidMode.SoftFlashBrightness,
+			"transition":     softFlashTransition,
+		})
+	}
+	return calls
+}
@@ -0,0 +1,80 @@
+package kidmode
+
+import (
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGuard_Household(t *testing.T) {
+	stateManager := state.NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	guard := NewGuard(createTestConfig(), stateManager)
+
+	assert.Equal(t, 12, guard.CapVolume("media_player.kitchen", 12), "nothing is capped while kid mode is off")
+	assert.False(t, guard.BlocksMusicMode("sex"))
+	assert.False(t, guard.LocksThermostat("climate.most_of_house_thermostat"))
+
+	require.NoError(t, stateManager.SetBool(ActiveVariable, true))
+
+	assert.Equal(t, 8, guard.CapVolume("media_player.kitchen", 12))
+	assert.Equal(t, 5, guard.CapVolume("media_player.kitchen", 5), "quieter volumes are kept")
+	assert.Equal(t, 12, guard.CapVolume("media_player.bedroom", 12), "speakers outside the rooms aren't capped")
+	assert.True(t, guard.BlocksMusicMode("sex"))
+	assert.False(t, guard.BlocksMusicMode("day"))
+	assert.True(t, guard.LocksThermostat("climate.most_of_house_thermostat"))
+	assert.False(t, guard.LocksThermostat("climate.primary_suite_thermostat"))
+}
+
+func TestGuard_RoomToggle(t *testing.T) {
+	stateManager := state.NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	guard := NewGuard(createTestConfig(), stateManager)
+
+	require.NoError(t, stateManager.SetBool("isGuestBedroomKidMode", true))
+
+	room, ok := guard.Restricts("media_player.guest_room")
+	assert.True(t, ok)
+	assert.Equal(t, "guest_bedroom", room)
+	_, ok = guard.Restricts("media_player.kitchen")
+	assert.False(t, ok, "other rooms follow household kid mode")
+
+	assert.False(t, guard.BlocksMusicMode("sex"), "a room toggle doesn't block modes for the house")
+	room, ok = guard.BlocksMusicModeOn("media_player.guest_room", "sex")
+	assert.True(t, ok, "but leaves the room's speakers out")
+	assert.Equal(t, "guest_bedroom", room)
+	_, ok = guard.BlocksMusicModeOn("media_player.kitchen", "sex")
+	assert.False(t, ok)
+}
+
+func TestGuard_FlashCalls(t *testing.T) {
+	stateManager := state.NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	guard := NewGuard(createTestConfig(), stateManager)
+	lights := []string{"light.living_room", "light.primary_suite"}
+
+	assert.Equal(t, []map[string]interface{}{
+		{"entity_id": lights, "flash": "short"},
+	}, guard.FlashCalls(lights))
+
+	require.NoError(t, stateManager.SetBool(ActiveVariable, true))
+
+	assert.Equal(t, []map[string]interface{}{
+		{"entity_id": []string{"light.primary_suite"}, "flash": "short"},
+		{"entity_id": []string{"light.living_room"}, "brightness_pct": 30, "transition": softFlashTransition},
+	}, guard.FlashCalls(lights))
+}
+
+func TestGuard_Nil(t *testing.T) {
+	var guard *Guard
+
+	assert.False(t, guard.IsActive())
+	assert.Equal(t, 15, guard.CapVolume("media_player.kitchen", 15))
+	assert.False(t, guard.BlocksMusicMode("sex"))
+	assert.False(t, guard.LocksThermostat("climate.most_of_house_thermostat"))
+	assert.Equal(t, []map[string]interface{}{
+		{"entity_id": []string{"light.kitchen"}, "flash": "short"},
+	}, guard.FlashCalls([]string{"light.kitchen"}))
+}
@@ -0,0 +1,458 @@
+// Package kidmode implements kid mode, a set of limits on what the house
+// does while children are around. It is on household-wide by toggle or
+// schedule, or in single rooms by their own toggles. Music, lighting and
+// climate plugins ask the Guard before acting: speaker volumes are capped,
+// some music modes aren't played, light flashes become a dim glow, and the
+// rooms' thermostats are held at the setpoint they had when kid mode began.
+package kidmode
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// scheduleJob is the scheduler job checking the schedule windows each minute
+const scheduleJob = "kidmode/schedule"
+
+// Manager turns kid mode on and off and holds the locked thermostats
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *Config
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+	timezone     *time.Location // nil keeps the clock's location
+	scheduler    *scheduler.Scheduler
+
+	// Serializes evaluate, which runs from subscriptions and the schedule
+	evalMu sync.Mutex
+
+	// Household state, rooms in kid mode, and held setpoints (protected by mu)
+	active      bool
+	activeRooms map[string]bool
+	held        map[string]float64
+	mu          sync.Mutex
+
+	// Subscriptions for cleanup; thermostat subscriptions come and go with their rooms
+	subscriptions           []state.Subscription
+	thermostatSubscriptions map[string]ha.Subscription
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.KidModeTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new kid mode manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Config, logger *zap.Logger, readOnly bool) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+	logger = recorder.Logger(logger.Named("kidmode"))
+
+	return &Manager{
+		ctx:                     ctx,
+		cancel:                  cancel,
+		haClient:                haClient,
+		stateManager:            stateManager,
+		config:                  config,
+		logger:                  logger,
+		health:                  recorder,
+		readOnly:                readOnly,
+		clock:                   clock.NewRealClock(),
+		scheduler:               scheduler.New(logger, nil),
+		activeRooms:             make(map[string]bool),
+		held:                    make(map[string]float64),
+		thermostatSubscriptions: make(map[string]ha.Subscription),
+		shadowTracker:           shadowstate.NewKidModeTracker(),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetTimezone sets the time zone the schedule windows are in
+func (m *Manager) SetTimezone(timezone *time.Location) {
+	m.timezone = timezone
+}
+
+// SetScheduler sets the shared scheduler the schedule check runs on
+func (m *Manager) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.KidModeShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start subscribes to the toggles, schedules the window check and applies the current state
+func (m *Manager) Start(ctx context.Context) error {
+	// Kid mode's timers and calls run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	s := m.config.KidMode
+	m.logger.Info("Starting Kid Mode Manager",
+		zap.String("toggle_variable", s.ToggleVariable),
+		zap.Int("schedule_windows", len(s.Schedule)),
+		zap.Int("rooms", len(s.Rooms)))
+
+	toggles := []string{s.ToggleVariable}
+	for _, r := range s.Rooms {
+		toggles = append(toggles, r.ToggleVariable)
+	}
+	subscribed := make(map[string]bool)
+	for _, key := range toggles {
+		if key == "" || subscribed[key] {
+			continue
+		}
+		subscribed[key] = true
+		sub, err := m.stateManager.Subscribe(key, func(key string, oldValue, newValue interface{}) {
+			m.evaluate(key)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", key, err)
+		}
+		m.subscriptions = append(m.subscriptions, sub)
+	}
+
+	if len(s.Schedule) > 0 {
+		if err := m.scheduler.Cron(scheduleJob, "* * * * *", func() { m.evaluate("schedule") }); err != nil {
+			return fmt.Errorf("failed to schedule kid mode windows: %w", err)
+		}
+	}
+
+	m.evaluate("startup")
+
+	m.health.Started(len(m.subscriptions))
+	m.logger.Info("Kid Mode Manager started successfully")
+	return nil
+}
+
+// Stop unsubscribes from the toggles and thermostats and cancels the schedule check
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Kid Mode Manager")
+
+	m.cancel()
+	m.scheduler.Cancel(scheduleJob)
+
+	for _, sub := range m.subscriptions {
+		sub.Unsubscribe()
+	}
+	m.subscriptions = nil
+
+	m.evalMu.Lock()
+	for entityID := range m.thermostatSubscriptions {
+		m.releaseThermostat(entityID)
+	}
+	m.evalMu.Unlock()
+
+	m.logger.Info("Kid Mode Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset puts the held thermostats back to their held setpoints
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Kid Mode - re-applying held thermostat setpoints")
+
+	m.mu.Lock()
+	held := make(map[string]float64, len(m.held))
+	for entityID, setpoint := range m.held {
+		held[entityID] = setpoint
+	}
+	m.mu.Unlock()
+
+	for entityID, setpoint := range held {
+		m.setTemperature(entityID, setpoint)
+	}
+
+	m.logger.Info("Successfully reset Kid Mode")
+	return nil
+}
+
+// IsActive reports whether household kid mode is on
+func (m *Manager) IsActive() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// inSchedule reports whether a schedule window is open now
+func (m *Manager) inSchedule() bool {
+	now := m.clock.Now()
+	if m.timezone != nil {
+		now = now.In(m.timezone)
+	}
+	for _, w := range m.config.KidMode.Schedule {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate turns kid mode on or off, household-wide and per room, when a
+// toggle or the schedule changed it
+func (m *Manager) evaluate(trigger string) {
+	m.evalMu.Lock()
+	defer m.evalMu.Unlock()
+
+	m.health.Tick()
+	s := m.config.KidMode
+
+	toggle := false
+	if s.ToggleVariable != "" {
+		toggle, _ = m.stateManager.GetBool(s.ToggleVariable)
+	}
+	scheduled := m.inSchedule()
+	active := toggle || scheduled
+
+	roomToggles := make(map[string]bool)
+	rooms := make(map[string]bool)
+	for _, r := range s.Rooms {
+		on := false
+		if r.ToggleVariable != "" {
+			on, _ = m.stateManager.GetBool(r.ToggleVariable)
+			roomToggles[r.Name] = on
+		}
+		if active || on {
+			rooms[r.Name] = true
+		}
+	}
+	m.shadowTracker.UpdateInputs(toggle, scheduled, roomToggles)
+
+	m.mu.Lock()
+	wasActive := m.active
+	previousRooms := m.activeRooms
+	m.active = active
+	m.activeRooms = rooms
+	m.mu.Unlock()
+
+	var started, ended []string
+	for _, r := range s.Rooms {
+		switch {
+		case rooms[r.Name] && !previousRooms[r.Name]:
+			started = append(started, r.Name)
+		case !rooms[r.Name] && previousRooms[r.Name]:
+			ended = append(ended, r.Name)
+		}
+	}
+	if active == wasActive && len(started) == 0 && len(ended) == 0 {
+		return
+	}
+
+	if active != wasActive {
+		m.setActiveVariable(active)
+	}
+	m.updateThermostatHolds(rooms)
+
+	source := ""
+	if active {
+		source = "toggle"
+		if !toggle {
+			source = "schedule"
+		}
+	}
+	var reasons []string
+	switch {
+	case active && !wasActive:
+		reasons = append(reasons, "Kid mode on by "+source)
+	case !active && wasActive:
+		reasons = append(reasons, "Kid mode off")
+	}
+	if len(started) > 0 {
+		reasons = append(reasons, "on in "+strings.Join(started, ", "))
+	}
+	if len(ended) > 0 {
+		reasons = append(reasons, "off in "+strings.Join(ended, ", "))
+	}
+	reason := strings.Join(reasons, "; ")
+
+	m.logger.Info("Kid mode changed",
+		zap.Bool("active", active),
+		zap.String("source", source),
+		zap.Strings("rooms_on", started),
+		zap.Strings("rooms_off", ended),
+		zap.String("trigger", trigger))
+
+	activeRooms := make([]string, 0, len(rooms))
+	for _, r := range s.Rooms {
+		if rooms[r.Name] {
+			activeRooms = append(activeRooms, r.Name)
+		}
+	}
+	m.shadowTracker.RecordChange(active, source, activeRooms, m.clock.Now(), reason)
+}
+
+// setActiveVariable publishes ActiveVariable. It is local-only, so it is set
+// in read-only mode too.
+func (m *Manager) setActiveVariable(active bool) {
+	if err := m.stateManager.SetBool(ActiveVariable, active); err != nil {
+		m.logger.Error("Failed to set "+ActiveVariable, zap.Error(err))
+	}
+}
+
+// updateThermostatHolds holds the thermostats of rooms in kid mode and
+// releases the rest. Called with evalMu held.
+func (m *Manager) updateThermostatHolds(rooms map[string]bool) {
+	locked := make(map[string]bool)
+	if m.config.KidMode.LockThermostats {
+		for _, r := range m.config.KidMode.Rooms {
+			if !rooms[r.Name] {
+				continue
+			}
+			for _, entityID := range r.Thermostats {
+				locked[entityID] = true
+			}
+		}
+	}
+
+	for entityID := range m.thermostatSubscriptions {
+		if !locked[entityID] {
+			m.releaseThermostat(entityID)
+		}
+	}
+	for entityID := range locked {
+		if _, ok := m.thermostatSubscriptions[entityID]; !ok {
+			m.holdThermostat(entityID)
+		}
+	}
+
+	m.mu.Lock()
+	m.shadowTracker.RecordHeldThermostats(m.held)
+	m.mu.Unlock()
+}
+
+// holdThermostat records the thermostat's setpoint and watches for it changing
+func (m *Manager) holdThermostat(entityID string) {
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil || st == nil {
+		m.logger.Warn("Failed to read thermostat to hold", zap.String("entity_id", entityID), zap.Error(err))
+		return
+	}
+	setpoint, ok := targetTemperature(st)
+	if !ok {
+		m.logger.Warn("Thermostat has no setpoint to hold", zap.String("entity_id", entityID))
+		return
+	}
+
+	sub, err := m.haClient.SubscribeStateChanges(entityID, m.handleThermostatChange)
+	if err != nil {
+		m.logger.Error("Failed to subscribe to thermostat", zap.String("entity_id", entityID), zap.Error(err))
+		return
+	}
+	m.thermostatSubscriptions[entityID] = sub
+
+	m.mu.Lock()
+	m.held[entityID] = setpoint
+	m.mu.Unlock()
+	m.logger.Info("Holding thermostat setpoint", zap.String("entity_id", entityID), zap.Float64("temperature", setpoint))
+}
+
+// releaseThermostat stops holding the thermostat
+func (m *Manager) releaseThermostat(entityID string) {
+	if sub, ok := m.thermostatSubscriptions[entityID]; ok {
+		if err := sub.Unsubscribe(); err != nil {
+			m.logger.Warn("Failed to unsubscribe from thermostat", zap.String("entity_id", entityID), zap.Error(err))
+		}
+		delete(m.thermostatSubscriptions, entityID)
+	}
+
+	m.mu.Lock()
+	delete(m.held, entityID)
+	m.mu.Unlock()
+	m.logger.Info("Released thermostat", zap.String("entity_id", entityID))
+}
+
+// handleThermostatChange puts a held thermostat back when its setpoint is changed
+func (m *Manager) handleThermostatChange(entityID string, oldState, newState *ha.State) {
+	m.health.Tick()
+	setpoint, ok := targetTemperature(newState)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	held, holding := m.held[entityID]
+	m.mu.Unlock()
+	if !holding || math.Abs(setpoint-held) < 0.05 {
+		return
+	}
+
+	m.logger.Info("Reverting thermostat change while kid mode holds it",
+		zap.String("entity_id", entityID),
+		zap.Float64("changed_to", setpoint),
+		zap.Float64("held", held))
+	m.setTemperature(entityID, held)
+	m.shadowTracker.RecordAction(m.clock.Now(),
+		fmt.Sprintf("Reverted %s from %.1f to held %.1f", entityID, setpoint, held))
+}
+
+// setTemperature sets a thermostat's target temperature
+func (m *Manager) setTemperature(entityID string, temperature float64) {
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set thermostat target",
+			zap.String("entity_id", entityID),
+			zap.Float64("temperature", temperature))
+		return
+	}
+	if err := m.haClient.CallService(m.ctx, "climate", "set_temperature", map[string]interface{}{
+		"entity_id":   entityID,
+		"temperature": temperature,
+	}); err != nil {
+		m.logger.Error("Failed to set thermostat target",
+			zap.String("entity_id", entityID),
+			zap.Float64("temperature", temperature),
+			zap.Error(err))
+	}
+}
+
+// targetTemperature returns a climate entity's setpoint
+func targetTemperature(st *ha.State) (float64, bool) {
+	if st == nil {
+		return 0, false
+	}
+	switch v := st.Attributes["temperature"].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,179 @@
+package kidmode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig() *Config {
+	return &Config{
+		KidMode: Settings{
+			ToggleVariable: "isKidMode",
+			Rooms: []Room{
+				{
+					Name:        "common_areas",
+					Speakers:    []string{"media_player.kitchen"},
+					Lights:      []string{"light.living_room"},
+					Thermostats: []string{"climate.most_of_house_thermostat"},
+				},
+				{
+					Name:           "guest_bedroom",
+					ToggleVariable: "isGuestBedroomKidMode",
+					Speakers:       []string{"media_player.guest_room"},
+				},
+			},
+			MaxVolume:           8,
+			BlockedMusicModes:   []string{"sex"},
+			LockThermostats:     true,
+			SoftFlashBrightness: 30,
+		},
+	}
+}
+
+func setupTest(t *testing.T, config *Config, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("climate.most_of_house_thermostat", "heat", map[string]interface{}{"temperature": 20.0})
+
+	stateManager := state.NewManager(mockHA, logger, readOnly)
+	manager := NewManager(mockHA, stateManager, config, logger, readOnly)
+	manager.SetClock(clock.NewMockClock(time.Date(2024, 1, 13, 12, 0, 0, 0, time.UTC)))
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager
+}
+
+func climateCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "climate" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestToggleStartsAndEndsKidMode(t *testing.T) {
+	manager, _, stateManager := setupTest(t, createTestConfig(), false)
+	assert.False(t, manager.IsActive())
+
+	require.NoError(t, stateManager.SetBool("isKidMode", true))
+
+	active, _ := stateManager.GetBool(ActiveVariable)
+	assert.True(t, active)
+	shadow := manager.GetShadowState()
+	assert.True(t, shadow.Outputs.Active)
+	assert.Equal(t, "toggle", shadow.Outputs.Source)
+	assert.Equal(t, []string{"common_areas", "guest_bedroom"}, shadow.Outputs.ActiveRooms)
+	assert.Equal(t, map[string]float64{"climate.most_of_house_thermostat": 20.0}, shadow.Outputs.HeldThermostats)
+
+	require.NoError(t, stateManager.SetBool("isKidMode", false))
+
+	active, _ = stateManager.GetBool(ActiveVariable)
+	assert.False(t, active)
+	shadow = manager.GetShadowState()
+	assert.False(t, shadow.Outputs.Active)
+	assert.Empty(t, shadow.Outputs.ActiveRooms)
+	assert.Empty(t, shadow.Outputs.HeldThermostats)
+}
+
+func TestRoomToggle(t *testing.T) {
+	manager, _, stateManager := setupTest(t, createTestConfig(), false)
+
+	require.NoError(t, stateManager.SetBool("isGuestBedroomKidMode", true))
+
+	active, _ := stateManager.GetBool(ActiveVariable)
+	assert.False(t, active, "a room toggle doesn't turn on household kid mode")
+	shadow := manager.GetShadowState()
+	assert.Equal(t, []string{"guest_bedroom"}, shadow.Outputs.ActiveRooms)
+	assert.Empty(t, shadow.Outputs.HeldThermostats, "the common areas' thermostat isn't held")
+}
+
+func TestThermostatHeld(t *testing.T) {
+	manager, mockHA, stateManager := setupTest(t, createTestConfig(), false)
+	require.NoError(t, stateManager.SetBool("isKidMode", true))
+
+	// Turned up at the thermostat
+	mockHA.SetState("climate.most_of_house_thermostat", "heat", map[string]interface{}{"temperature": 24.0})
+
+	calls := climateCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "set_temperature", calls[0].Service)
+	assert.Equal(t, "climate.most_of_house_thermostat", calls[0].Data["entity_id"])
+	assert.Equal(t, 20.0, calls[0].Data["temperature"])
+	assert.Contains(t, manager.GetShadowState().Outputs.LastActionReason, "Reverted climate.most_of_house_thermostat")
+
+	// Released once kid mode ends
+	require.NoError(t, stateManager.SetBool("isKidMode", false))
+	mockHA.ClearServiceCalls()
+	mockHA.SetState("climate.most_of_house_thermostat", "heat", map[string]interface{}{"temperature": 23.0})
+	assert.Empty(t, climateCalls(mockHA))
+}
+
+func TestThermostatNotLocked(t *testing.T) {
+	config := createTestConfig()
+	config.KidMode.LockThermostats = false
+	_, mockHA, stateManager := setupTest(t, config, false)
+	require.NoError(t, stateManager.SetBool("isKidMode", true))
+
+	mockHA.SetState("climate.most_of_house_thermostat", "heat", map[string]interface{}{"temperature": 24.0})
+	assert.Empty(t, climateCalls(mockHA))
+}
+
+func TestSchedule(t *testing.T) {
+	config := createTestConfig()
+	config.KidMode.ToggleVariable = ""
+	config.KidMode.Schedule = []Window{{Start: "13:00", End: "14:00"}}
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 13, 12, 0, 0, 0, time.UTC))
+	jobs := scheduler.New(logger, time.UTC)
+	jobs.SetClock(mockClock)
+	defer jobs.Stop()
+
+	manager := NewManager(mockHA, stateManager, config, logger, false)
+	manager.SetClock(mockClock)
+	manager.SetTimezone(time.UTC)
+	manager.SetScheduler(jobs)
+	require.NoError(t, manager.Start(context.Background()))
+
+	_, ok := jobs.Next(scheduleJob)
+	require.True(t, ok, "the windows are checked on the shared scheduler")
+	assert.False(t, manager.IsActive())
+
+	mockClock.Set(time.Date(2024, 1, 13, 13, 30, 0, 0, time.UTC))
+	manager.evaluate("schedule")
+	assert.True(t, manager.IsActive())
+	assert.Equal(t, "schedule", manager.GetShadowState().Outputs.Source)
+
+	manager.Stop()
+	_, ok = jobs.Next(scheduleJob)
+	assert.False(t, ok, "Stop cancels the window check")
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	manager, mockHA, _ := setupTest(t, createTestConfig(), true)
+
+	// The toggle is synced, so flip it from Home Assistant
+	mockHA.SetState("input_boolean.kid_mode", "on", nil)
+	assert.True(t, manager.IsActive())
+
+	mockHA.SetState("climate.most_of_house_thermostat", "heat", map[string]interface{}{"temperature": 24.0})
+	assert.Empty(t, climateCalls(mockHA))
+}
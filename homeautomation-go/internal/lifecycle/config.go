@@ -0,0 +1,86 @@
+package lifecycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configHeader is written above the plugin list when the file is saved
+const configHeader = `# Plugins turned off at runtime. Managed by POST /api/plugins/{name}/enable
+# and /disable; a listed plugin isn't started at startup.
+`
+
+// Settings lists the plugins that are disabled
+type Settings struct {
+	Disabled []string `yaml:"disabled"`
+}
+
+// Config represents the plugins_config.yaml structure
+type Config struct {
+	Plugins Settings `yaml:"plugins"`
+}
+
+// Validate checks that disabled plugin names are set and listed once
+func (c *Config) Validate() error {
+	seen := make(map[string]bool)
+	for i, name := range c.Plugins.Disabled {
+		if name == "" {
+			return fmt.Errorf("disabled[%d]: plugin name is required", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("disabled[%d]: duplicate plugin %q", i, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// LoadConfig loads the plugin configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Save writes the configuration to path. The file is replaced atomically so
+// a crash mid-write can't leave it truncated.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".plugins_config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(configHeader); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,40 @@
+package lifecycle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.NoError(t, (&Config{Plugins: Settings{Disabled: []string{"music", "tv"}}}).Validate())
+
+	err := (&Config{Plugins: Settings{Disabled: []string{"music", ""}}}).Validate()
+	assert.ErrorContains(t, err, "plugin name is required")
+
+	err = (&Config{Plugins: Settings{Disabled: []string{"music", "music"}}}).Validate()
+	assert.ErrorContains(t, err, "duplicate plugin")
+}
+
+func TestConfig_SaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugins_config.yaml")
+	config := &Config{Plugins: Settings{Disabled: []string{"lighting", "music"}}}
+	require.NoError(t, config.Save(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "# "), "saved file keeps its header comment")
+
+	loaded, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, config.Plugins.Disabled, loaded.Plugins.Disabled)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files are left behind")
+}
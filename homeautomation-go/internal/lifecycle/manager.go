@@ -0,0 +1,341 @@
+// Package lifecycle lets plugins be disabled and re-enabled while the service
+// runs. Disabling a plugin stops it and removes its shadow state; enabling it
+// starts it again. The disabled set is saved to plugins_config.yaml so it
+// survives a restart. Plugins can also be suspended, e.g. by battery safe
+// mode, which stops them like disabling does but isn't saved.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// ErrUnknownPlugin is returned for a plugin name that was never added
+var ErrUnknownPlugin = errors.New("unknown plugin")
+
+// ErrPluginBusy is returned when a plugin refuses to be disabled or suspended
+var ErrPluginBusy = errors.New("plugin busy")
+
+// Plugin is a manager that can be started and stopped repeatedly. Its work
+// runs under the context it is started with, and Stop returns once that work
+// has ended.
+type Plugin interface {
+	Start(ctx context.Context) error
+	Stop()
+}
+
+// Resettable is a plugin that takes part in system-wide resets
+type Resettable interface {
+	Reset() error
+}
+
+// Busy is a plugin that can refuse to be disabled or suspended, e.g. while
+// it is responding to an emergency. StopAll stops it regardless.
+type Busy interface {
+	Busy() error
+}
+
+// Status reports whether a plugin is enabled
+type Status struct {
+	Name      string `json:"name"`
+	Enabled   bool   `json:"enabled"`
+	Suspended bool   `json:"suspended,omitempty"` // Stopped until resumed, whether enabled or not
+}
+
+// managedPlugin is a plugin with its shadow state provider
+type managedPlugin struct {
+	plugin   Plugin
+	provider func() shadowstate.PluginShadowState
+	running  bool
+}
+
+// Manager starts, stops and tracks plugins
+type Manager struct {
+	ctx        context.Context // Plugins are started with it
+	config     *Config
+	configPath string
+	tracker    *shadowstate.Tracker
+	logger     *zap.Logger
+
+	// Added plugins in start order, the disabled set and the suspended set
+	// (protected by mu)
+	plugins   map[string]*managedPlugin
+	order     []string
+	disabled  map[string]bool
+	suspended map[string]bool
+	mu        sync.Mutex
+}
+
+// NewManager creates a lifecycle manager that starts plugins with ctx, so
+// cancelling it ends every plugin's work. Changes are saved to configPath;
+// with an empty path they last until restart.
+func NewManager(ctx context.Context, config *Config, configPath string, tracker *shadowstate.Tracker, logger *zap.Logger) *Manager {
+	if config == nil {
+		config = &Config{}
+	}
+	disabled := make(map[string]bool, len(config.Plugins.Disabled))
+	for _, name := range config.Plugins.Disabled {
+		disabled[name] = true
+	}
+	return &Manager{
+		ctx:        ctx,
+		config:     config,
+		configPath: configPath,
+		tracker:    tracker,
+		logger:     logger.Named("lifecycle"),
+		plugins:    make(map[string]*managedPlugin),
+		disabled:   disabled,
+		suspended:  make(map[string]bool),
+	}
+}
+
+// Add registers a plugin under name and, unless it is disabled, starts it and
+// registers its shadow state provider (which may be nil). Actions of a plugin
+// that is a shadowstate.ActionSource are passed to the tracker under name.
+func (m *Manager) Add(name string, plugin Plugin, provider func() shadowstate.PluginShadowState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.plugins[name]; exists {
+		return fmt.Errorf("plugin %s already added", name)
+	}
+	p := &managedPlugin{plugin: plugin, provider: provider}
+	m.plugins[name] = p
+	m.order = append(m.order, name)
+
+	// Actions are passed on once here; a stopped plugin records none
+	if source, ok := plugin.(shadowstate.ActionSource); ok && m.tracker != nil {
+		source.OnAction(func(at time.Time, reason string) {
+			m.tracker.RecordAction(name, at, reason)
+		})
+	}
+
+	if m.disabled[name] {
+		m.logger.Info("Plugin disabled, not starting", zap.String("plugin", name))
+		return nil
+	}
+	if m.suspended[name] {
+		m.logger.Info("Plugin suspended, not starting", zap.String("plugin", name))
+		return nil
+	}
+	return m.start(name, p)
+}
+
+// Enable starts a disabled plugin and saves the change
+func (m *Manager) Enable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlugin, name)
+	}
+	if !m.disabled[name] {
+		return nil
+	}
+
+	// A suspended plugin starts once it is resumed
+	if !m.suspended[name] {
+		if err := m.start(name, p); err != nil {
+			return err
+		}
+	}
+	delete(m.disabled, name)
+	m.logger.Info("Plugin enabled", zap.String("plugin", name), zap.Bool("suspended", m.suspended[name]))
+	return m.save()
+}
+
+// Disable stops a plugin, removes its shadow state and saves the change.
+// Returns ErrPluginBusy if the plugin refuses to stop.
+func (m *Manager) Disable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlugin, name)
+	}
+	if m.disabled[name] {
+		return nil
+	}
+	if err := busy(name, p); err != nil {
+		return err
+	}
+
+	if p.running {
+		m.stop(name, p)
+	}
+	m.disabled[name] = true
+	m.logger.Info("Plugin disabled", zap.String("plugin", name))
+	return m.save()
+}
+
+// Enabled reports whether a plugin is enabled
+func (m *Manager) Enabled(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.disabled[name]
+}
+
+// Suspend stops a plugin until Resume without saving the change. A plugin
+// that is disabled meanwhile stays stopped when resumed. Returns
+// ErrPluginBusy if the plugin refuses to stop.
+func (m *Manager) Suspend(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlugin, name)
+	}
+	if m.suspended[name] {
+		return nil
+	}
+	if err := busy(name, p); err != nil {
+		return err
+	}
+
+	if p.running {
+		m.stop(name, p)
+	}
+	m.suspended[name] = true
+	m.logger.Info("Plugin suspended", zap.String("plugin", name))
+	return nil
+}
+
+// Resume starts a suspended plugin again, unless it has been disabled
+func (m *Manager) Resume(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlugin, name)
+	}
+	if !m.suspended[name] {
+		return nil
+	}
+
+	delete(m.suspended, name)
+	m.logger.Info("Plugin resumed", zap.String("plugin", name), zap.Bool("disabled", m.disabled[name]))
+	if m.disabled[name] {
+		return nil
+	}
+	return m.start(name, p)
+}
+
+// Running reports whether a plugin is enabled and not suspended
+func (m *Manager) Running(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.disabled[name] && !m.suspended[name]
+}
+
+// Statuses returns every added plugin sorted by name
+func (m *Manager) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.plugins))
+	for name := range m.plugins {
+		statuses = append(statuses, Status{Name: name, Enabled: !m.disabled[name], Suspended: m.suspended[name]})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// StopAll stops running plugins in reverse start order
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.order) - 1; i >= 0; i-- {
+		name := m.order[i]
+		if p := m.plugins[name]; p.running {
+			m.stop(name, p)
+		}
+	}
+}
+
+// Resettable wraps a plugin's reset so it is skipped while the plugin is
+// disabled or suspended; a stopped plugin shouldn't act on a system-wide reset
+func (m *Manager) Resettable(name string, plugin Resettable) Resettable {
+	return &guardedReset{manager: m, name: name, plugin: plugin}
+}
+
+// guardedReset resets a plugin only while it is enabled
+type guardedReset struct {
+	manager *Manager
+	name    string
+	plugin  Resettable
+}
+
+// Reset forwards to the plugin unless it is disabled or suspended
+func (g *guardedReset) Reset() error {
+	if !g.manager.Running(g.name) {
+		g.manager.logger.Info("Skipping reset of stopped plugin", zap.String("plugin", g.name))
+		return nil
+	}
+	return g.plugin.Reset()
+}
+
+// start starts a plugin and registers its provider; callers must hold mu
+func (m *Manager) start(name string, p *managedPlugin) error {
+	if err := p.plugin.Start(m.ctx); err != nil {
+		return fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	p.running = true
+	if p.provider != nil && m.tracker != nil {
+		m.tracker.RegisterPluginProvider(name, p.provider)
+	}
+	return nil
+}
+
+// stop stops a plugin and unregisters its provider; callers must hold mu
+func (m *Manager) stop(name string, p *managedPlugin) {
+	p.plugin.Stop()
+	p.running = false
+	if p.provider != nil && m.tracker != nil {
+		m.tracker.UnregisterPluginProvider(name)
+	}
+}
+
+// busy returns ErrPluginBusy if a running plugin refuses to be stopped
+func busy(name string, p *managedPlugin) error {
+	b, ok := p.plugin.(Busy)
+	if !ok || !p.running {
+		return nil
+	}
+	if err := b.Busy(); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPluginBusy, name, err)
+	}
+	return nil
+}
+
+// save writes the disabled set to the config file; callers must hold mu
+func (m *Manager) save() error {
+	disabled := make([]string, 0, len(m.disabled))
+	for name := range m.disabled {
+		disabled = append(disabled, name)
+	}
+	sort.Strings(disabled)
+	m.config.Plugins.Disabled = disabled
+
+	if m.configPath == "" {
+		return nil
+	}
+	if err := m.config.Save(m.configPath); err != nil {
+		return fmt.Errorf("failed to save plugins config: %w", err)
+	}
+	return nil
+}
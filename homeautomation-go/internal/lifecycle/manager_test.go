@@ -0,0 +1,251 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakePlugin counts starts, stops and resets
+type fakePlugin struct {
+	name   string
+	events *[]string
+	starts int
+	stops  int
+	resets int
+	err    error
+}
+
+func (f *fakePlugin) Start(ctx context.Context) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.starts++
+	if f.events != nil {
+		*f.events = append(*f.events, "start "+f.name)
+	}
+	return nil
+}
+
+func (f *fakePlugin) Stop() {
+	f.stops++
+	if f.events != nil {
+		*f.events = append(*f.events, "stop "+f.name)
+	}
+}
+
+func (f *fakePlugin) Reset() error {
+	f.resets++
+	return nil
+}
+
+// actionPlugin is a fakePlugin that reports its actions
+type actionPlugin struct {
+	fakePlugin
+	shadowstate.ActionNotifier
+}
+
+// busyPlugin is a fakePlugin that refuses to stop while err is set
+type busyPlugin struct {
+	fakePlugin
+	busyErr error
+}
+
+func (b *busyPlugin) Busy() error {
+	return b.busyErr
+}
+
+func fakeProvider() shadowstate.PluginShadowState {
+	return shadowstate.NewTVShadowState()
+}
+
+func TestManager_AddStartsEnabledPlugins(t *testing.T) {
+	tracker := shadowstate.NewTracker()
+	config := &Config{Plugins: Settings{Disabled: []string{"music"}}}
+	m := NewManager(context.Background(), config, "", tracker, zap.NewNop())
+
+	lighting := &fakePlugin{}
+	music := &fakePlugin{}
+	require.NoError(t, m.Add("lighting", lighting, fakeProvider))
+	require.NoError(t, m.Add("music", music, fakeProvider))
+
+	assert.Equal(t, 1, lighting.starts)
+	assert.Equal(t, 0, music.starts, "disabled plugins aren't started")
+
+	_, ok := tracker.GetPluginState("lighting")
+	assert.True(t, ok)
+	_, ok = tracker.GetPluginState("music")
+	assert.False(t, ok, "disabled plugins have no shadow state")
+
+	assert.Equal(t, []Status{{Name: "lighting", Enabled: true}, {Name: "music", Enabled: false}}, m.Statuses())
+	assert.Error(t, m.Add("lighting", &fakePlugin{}, nil), "names must be unique")
+}
+
+func TestManager_AddReportsStartFailure(t *testing.T) {
+	m := NewManager(context.Background(), nil, "", nil, zap.NewNop())
+	assert.Error(t, m.Add("energy", &fakePlugin{err: errors.New("boom")}, nil))
+}
+
+func TestManager_DisableEnable(t *testing.T) {
+	tracker := shadowstate.NewTracker()
+	configPath := filepath.Join(t.TempDir(), "plugins_config.yaml")
+	m := NewManager(context.Background(), &Config{}, configPath, tracker, zap.NewNop())
+
+	music := &fakePlugin{}
+	require.NoError(t, m.Add("music", music, fakeProvider))
+
+	require.NoError(t, m.Disable("music"))
+	assert.Equal(t, 1, music.stops)
+	assert.False(t, m.Enabled("music"))
+	_, ok := tracker.GetPluginState("music")
+	assert.False(t, ok, "shadow provider is unregistered")
+
+	saved, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"music"}, saved.Plugins.Disabled)
+
+	require.NoError(t, m.Disable("music"), "disabling twice is a no-op")
+	assert.Equal(t, 1, music.stops)
+
+	require.NoError(t, m.Enable("music"))
+	assert.Equal(t, 2, music.starts)
+	assert.True(t, m.Enabled("music"))
+	_, ok = tracker.GetPluginState("music")
+	assert.True(t, ok, "shadow provider is registered again")
+
+	saved, err = LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Empty(t, saved.Plugins.Disabled)
+}
+
+func TestManager_SuspendResume(t *testing.T) {
+	tracker := shadowstate.NewTracker()
+	configPath := filepath.Join(t.TempDir(), "plugins_config.yaml")
+	m := NewManager(context.Background(), &Config{}, configPath, tracker, zap.NewNop())
+
+	music := &fakePlugin{}
+	require.NoError(t, m.Add("music", music, fakeProvider))
+
+	require.NoError(t, m.Suspend("music"))
+	assert.Equal(t, 1, music.stops)
+	assert.True(t, m.Enabled("music"), "suspending isn't disabling")
+	assert.False(t, m.Running("music"))
+	assert.Equal(t, []Status{{Name: "music", Enabled: true, Suspended: true}}, m.Statuses())
+	_, ok := tracker.GetPluginState("music")
+	assert.False(t, ok)
+	assert.NoFileExists(t, configPath, "suspensions aren't saved")
+
+	require.NoError(t, m.Suspend("music"), "suspending twice is a no-op")
+	assert.Equal(t, 1, music.stops)
+
+	require.NoError(t, m.Resume("music"))
+	assert.Equal(t, 2, music.starts)
+	assert.True(t, m.Running("music"))
+	_, ok = tracker.GetPluginState("music")
+	assert.True(t, ok)
+}
+
+func TestManager_SuspendedPluginKeepsDisableAndEnable(t *testing.T) {
+	m := NewManager(context.Background(), nil, "", nil, zap.NewNop())
+	music := &fakePlugin{}
+	require.NoError(t, m.Add("music", music, nil))
+	require.NoError(t, m.Suspend("music"))
+
+	// Disabled while suspended: stays stopped when resumed
+	require.NoError(t, m.Disable("music"))
+	assert.Equal(t, 1, music.stops, "already stopped")
+	require.NoError(t, m.Resume("music"))
+	assert.Equal(t, 1, music.starts)
+	assert.False(t, m.Running("music"))
+
+	// Enabled while suspended: starts once resumed
+	require.NoError(t, m.Suspend("music"))
+	require.NoError(t, m.Enable("music"))
+	assert.Equal(t, 1, music.starts)
+	require.NoError(t, m.Resume("music"))
+	assert.Equal(t, 2, music.starts)
+	assert.True(t, m.Running("music"))
+}
+
+func TestManager_BusyPluginRefusesToStop(t *testing.T) {
+	m := NewManager(context.Background(), nil, "", nil, zap.NewNop())
+	emergency := &busyPlugin{busyErr: errors.New("emergency active")}
+	require.NoError(t, m.Add("emergency", emergency, nil))
+
+	assert.ErrorIs(t, m.Disable("emergency"), ErrPluginBusy)
+	assert.ErrorIs(t, m.Suspend("emergency"), ErrPluginBusy)
+	assert.Equal(t, 0, emergency.stops)
+	assert.True(t, m.Running("emergency"))
+
+	// StopAll stops it regardless
+	m.StopAll()
+	assert.Equal(t, 1, emergency.stops)
+
+	emergency.busyErr = nil
+	require.NoError(t, m.Disable("emergency"))
+	assert.False(t, m.Enabled("emergency"))
+}
+
+func TestManager_UnknownPlugin(t *testing.T) {
+	m := NewManager(context.Background(), nil, "", nil, zap.NewNop())
+	assert.ErrorIs(t, m.Enable("jukebox"), ErrUnknownPlugin)
+	assert.ErrorIs(t, m.Disable("jukebox"), ErrUnknownPlugin)
+	assert.ErrorIs(t, m.Suspend("jukebox"), ErrUnknownPlugin)
+	assert.ErrorIs(t, m.Resume("jukebox"), ErrUnknownPlugin)
+}
+
+func TestManager_AddPassesActionsToTracker(t *testing.T) {
+	tracker := shadowstate.NewTracker()
+	var events []shadowstate.ActionEvent
+	tracker.SubscribeActions(func(e shadowstate.ActionEvent) {
+		events = append(events, e)
+	})
+
+	m := NewManager(context.Background(), nil, "", tracker, zap.NewNop())
+	rules := &actionPlugin{}
+	require.NoError(t, m.Add("rules", rules, nil))
+
+	at := time.Date(2026, time.October, 17, 8, 0, 0, 0, time.UTC)
+	rules.NotifyAction(at, "Porch light (sunset)")
+	rules.NotifyAction(at, "Porch light (sunset)")
+
+	expected := shadowstate.ActionEvent{Plugin: "rules", Timestamp: at, Reason: "Porch light (sunset)"}
+	assert.Equal(t, []shadowstate.ActionEvent{expected, expected}, events, "every action is passed on, even with the same time")
+}
+
+func TestManager_StopAllStopsRunningPluginsInReverse(t *testing.T) {
+	var events []string
+	m := NewManager(context.Background(), nil, "", nil, zap.NewNop())
+	require.NoError(t, m.Add("energy", &fakePlugin{name: "energy", events: &events}, nil))
+	require.NoError(t, m.Add("music", &fakePlugin{name: "music", events: &events}, nil))
+	require.NoError(t, m.Add("lighting", &fakePlugin{name: "lighting", events: &events}, nil))
+	require.NoError(t, m.Disable("music"))
+
+	m.StopAll()
+	assert.Equal(t, []string{
+		"start energy", "start music", "start lighting",
+		"stop music", "stop lighting", "stop energy",
+	}, events, "disabled plugins aren't stopped twice")
+}
+
+func TestManager_ResettableSkipsDisabledPlugins(t *testing.T) {
+	m := NewManager(context.Background(), nil, "", nil, zap.NewNop())
+	music := &fakePlugin{}
+	require.NoError(t, m.Add("music", music, nil))
+	resettable := m.Resettable("music", music)
+
+	require.NoError(t, resettable.Reset())
+	assert.Equal(t, 1, music.resets)
+
+	require.NoError(t, m.Disable("music"))
+	require.NoError(t, resettable.Reset())
+	assert.Equal(t, 1, music.resets)
+}
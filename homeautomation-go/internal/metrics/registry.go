@@ -0,0 +1,370 @@
+// Package metrics collects counters, histograms and scrape-time gauges and
+// writes them in the Prometheus text exposition format, so /metrics can be
+// scraped without pulling in the Prometheus client library.
+//
+// All methods are safe to call on a nil *Registry, *Counter or *Histogram,
+// which record nothing. Components hold a nil registry until one is set.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "homeautomation"
+
+// DefaultLatencyBuckets are histogram upper bounds in seconds, suited to
+// callbacks and Home Assistant round trips
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample is one value of a gauge, with label values in the order the gauge
+// declared its label names
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// Registry holds every metric served on /metrics
+type Registry struct {
+	mu         sync.Mutex
+	counters   map[string]*Counter
+	histograms map[string]*Histogram
+	gauges     map[string]*gaugeFunc
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]*Counter),
+		histograms: make(map[string]*Histogram),
+		gauges:     make(map[string]*gaugeFunc),
+	}
+}
+
+// Counter is a monotonically increasing value per label combination
+type Counter struct {
+	desc   desc
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// Histogram counts observations into cumulative buckets per label combination
+type Histogram struct {
+	desc    desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // non-cumulative count per bucket
+	count       uint64
+	sum         float64
+}
+
+// gaugeFunc reports its values when the registry is written
+type gaugeFunc struct {
+	desc    desc
+	collect func() []Sample
+}
+
+// desc is the name, help text and label names shared by a metric's series
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+// Counter returns the counter with the given name, creating it on first use.
+// The name is prefixed with Namespace.
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	if r == nil {
+		return nil
+	}
+	name = Namespace + "_" + name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.counters[name]; ok {
+		return c
+	}
+	c := &Counter{
+		desc:   desc{name: name, help: help, labelNames: labelNames},
+		values: make(map[string]*counterSeries),
+	}
+	r.counters[name] = c
+	return c
+}
+
+// Histogram returns the histogram with the given name, creating it on first
+// use. Buckets are upper bounds in ascending order; the name is prefixed with
+// Namespace.
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if r == nil {
+		return nil
+	}
+	name = Namespace + "_" + name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.histograms[name]; ok {
+		return h
+	}
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		buckets: buckets,
+		values:  make(map[string]*histogramSeries),
+	}
+	r.histograms[name] = h
+	return h
+}
+
+// GaugeFunc registers a gauge whose samples are read from collect each time
+// the registry is written. Registering a name again replaces the function.
+func (r *Registry) GaugeFunc(name, help string, labelNames []string, collect func() []Sample) {
+	if r == nil {
+		return
+	}
+	name = Namespace + "_" + name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[name] = &gaugeFunc{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		collect: collect,
+	}
+}
+
+// SubscriptionCallbackDuration returns the histogram of state change
+// callback durations shared by the Home Assistant client (source "ha") and
+// the state manager (source "state")
+func (r *Registry) SubscriptionCallbackDuration() *Histogram {
+	return r.Histogram("subscription_callback_duration_seconds",
+		"Time spent in state change callbacks, by source (ha or state)",
+		DefaultLatencyBuckets, "source")
+}
+
+// Inc adds one to the series with the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the series with the given
+// label values
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if c == nil || delta < 0 {
+		return
+	}
+	key := seriesKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	series, ok := c.values[key]
+	if !ok {
+		series = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = series
+	}
+	series.value += delta
+}
+
+// Observe records one value, in seconds for latency histograms
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+	key := seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = series
+	}
+	series.count++
+	series.sum += value
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+			break
+		}
+	}
+}
+
+// WriteText writes every metric in the Prometheus text exposition format
+// (version 0.0.4), sorted by name
+func (r *Registry) WriteText(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	writers := make(map[string]func(io.Writer) error, len(r.counters)+len(r.histograms)+len(r.gauges))
+	for name, c := range r.counters {
+		writers[name] = c.writeText
+	}
+	for name, h := range r.histograms {
+		writers[name] = h.writeText
+	}
+	for name, g := range r.gauges {
+		writers[name] = g.writeText
+	}
+	r.mu.Unlock()
+
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := writers[name](w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Counter) writeText(w io.Writer) error {
+	c.mu.Lock()
+	series := make([]*counterSeries, 0, len(c.values))
+	for _, s := range c.values {
+		series = append(series, &counterSeries{labelValues: s.labelValues, value: s.value})
+	}
+	c.mu.Unlock()
+	sort.Slice(series, func(i, j int) bool {
+		return seriesKey(series[i].labelValues) < seriesKey(series[j].labelValues)
+	})
+
+	if err := c.desc.writeHeader(w, "counter"); err != nil {
+		return err
+	}
+	for _, s := range series {
+		if err := writeSample(w, c.desc.name, c.desc.labelNames, s.labelValues, "", "", s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Histogram) writeText(w io.Writer) error {
+	h.mu.Lock()
+	series := make([]histogramSeries, 0, len(h.values))
+	for _, s := range h.values {
+		copied := *s
+		copied.counts = append([]uint64(nil), s.counts...)
+		series = append(series, copied)
+	}
+	h.mu.Unlock()
+	sort.Slice(series, func(i, j int) bool {
+		return seriesKey(series[i].labelValues) < seriesKey(series[j].labelValues)
+	})
+
+	if err := h.desc.writeHeader(w, "histogram"); err != nil {
+		return err
+	}
+	for _, s := range series {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			if err := writeSample(w, h.desc.name+"_bucket", h.desc.labelNames, s.labelValues, "le", formatValue(bound), float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		if err := writeSample(w, h.desc.name+"_bucket", h.desc.labelNames, s.labelValues, "le", "+Inf", float64(s.count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.desc.name+"_sum", h.desc.labelNames, s.labelValues, "", "", s.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.desc.name+"_count", h.desc.labelNames, s.labelValues, "", "", float64(s.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *gaugeFunc) writeText(w io.Writer) error {
+	samples := g.collect()
+	sort.Slice(samples, func(i, j int) bool {
+		return seriesKey(samples[i].LabelValues) < seriesKey(samples[j].LabelValues)
+	})
+
+	if err := g.desc.writeHeader(w, "gauge"); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if err := writeSample(w, g.desc.name, g.desc.labelNames, s.LabelValues, "", "", s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the HELP and TYPE lines
+func (d desc) writeHeader(w io.Writer, typ string) error {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help)
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, help, d.name, typ)
+	return err
+}
+
+// writeSample writes one sample line; extraName/extraValue add the le label
+// of histogram buckets
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) error {
+	var labels []string
+	for i, labelName := range labelNames {
+		labelValue := ""
+		if i < len(labelValues) {
+			labelValue = labelValues[i]
+		}
+		labels = append(labels, labelName+`="`+escapeLabelValue(labelValue)+`"`)
+	}
+	if extraName != "" {
+		labels = append(labels, extraName+`="`+extraValue+`"`)
+	}
+
+	if len(labels) == 0 {
+		_, err := fmt.Fprintf(w, "%s %s\n", name, formatValue(value))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(labels, ","), formatValue(value))
+	return err
+}
+
+// escapeLabelValue escapes backslashes, quotes and newlines
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatValue formats a sample value as Prometheus expects
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// seriesKey identifies a label combination
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
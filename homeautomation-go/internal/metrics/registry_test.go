@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeText(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	calls := r.Counter("ha_service_calls_total", "Service calls", "domain", "service")
+	calls.Inc("light", "turn_on")
+	calls.Inc("light", "turn_on")
+	calls.Add(3, "scene", "turn_on")
+	calls.Add(-1, "scene", "turn_on") // counters never go down
+
+	assert.Same(t, calls, r.Counter("ha_service_calls_total", "Service calls", "domain", "service"))
+	assert.Equal(t, `# HELP homeautomation_ha_service_calls_total Service calls
+# TYPE homeautomation_ha_service_calls_total counter
+homeautomation_ha_service_calls_total{domain="light",service="turn_on"} 2
+homeautomation_ha_service_calls_total{domain="scene",service="turn_on"} 3
+`, writeText(t, r))
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	latency := r.Histogram("callback_duration_seconds", "Callback latency", []float64{0.1, 1}, "source")
+	latency.Observe(0.05, "state")
+	latency.Observe(0.5, "state")
+	latency.Observe(2, "state")
+
+	assert.Equal(t, `# HELP homeautomation_callback_duration_seconds Callback latency
+# TYPE homeautomation_callback_duration_seconds histogram
+homeautomation_callback_duration_seconds_bucket{source="state",le="0.1"} 1
+homeautomation_callback_duration_seconds_bucket{source="state",le="1"} 2
+homeautomation_callback_duration_seconds_bucket{source="state",le="+Inf"} 3
+homeautomation_callback_duration_seconds_sum{source="state"} 2.55
+homeautomation_callback_duration_seconds_count{source="state"} 3
+`, writeText(t, r))
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	r.GaugeFunc("state_number", "Numeric state", []string{"key"}, func() []Sample {
+		return []Sample{
+			{LabelValues: []string{"tempF"}, Value: 71.5},
+			{LabelValues: []string{"a\"b"}, Value: 1},
+		}
+	})
+	r.GaugeFunc("up", "Always one", nil, func() []Sample {
+		return []Sample{{Value: 1}}
+	})
+
+	assert.Equal(t, `# HELP homeautomation_state_number Numeric state
+# TYPE homeautomation_state_number gauge
+homeautomation_state_number{key="a\"b"} 1
+homeautomation_state_number{key="tempF"} 71.5
+# HELP homeautomation_up Always one
+# TYPE homeautomation_up gauge
+homeautomation_up 1
+`, writeText(t, r))
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+
+	r.Counter("calls_total", "Calls").Inc()
+	r.Histogram("latency_seconds", "Latency", DefaultLatencyBuckets).Observe(1)
+	r.GaugeFunc("up", "Up", nil, func() []Sample { return nil })
+	assert.Empty(t, writeText(t, r))
+}
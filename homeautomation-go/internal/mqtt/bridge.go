@@ -0,0 +1,293 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// Bridge publishes state variable changes to MQTT and writes messages from
+// subscribed topics to state variables
+type Bridge struct {
+	client       Client
+	stateManager *state.Manager
+	config       *BridgeConfig
+	variables    map[string]state.StateVariable
+	logger       *zap.Logger
+	readOnly     bool
+
+	subscriptions []state.Subscription
+
+	// Last payload published to each topic, so echoes of unchanged values
+	// aren't republished (protected by mu)
+	lastPublished map[string]string
+	mu            sync.Mutex
+}
+
+// NewBridge creates a new MQTT bridge
+func NewBridge(client Client, stateManager *state.Manager, config *Config, logger *zap.Logger, readOnly bool) *Bridge {
+	return &Bridge{
+		client:        client,
+		stateManager:  stateManager,
+		config:        &config.MQTT,
+		variables:     state.VariablesByKey(),
+		logger:        logger.Named("mqtt"),
+		readOnly:      readOnly,
+		lastPublished: make(map[string]string),
+	}
+}
+
+// Start subscribes to the configured topics and state variables and connects
+// to the broker. The broker need not be reachable yet; the client keeps
+// reconnecting and every published variable is republished on connect.
+func (b *Bridge) Start() error {
+	b.logger.Info("Starting MQTT bridge",
+		zap.String("broker", b.config.Broker),
+		zap.Int("publish", len(b.config.Publish)),
+		zap.Int("subscribe", len(b.config.Subscribe)))
+
+	for i := range b.config.Subscribe {
+		mapping := &b.config.Subscribe[i]
+		err := b.client.Subscribe(mapping.Topic, b.config.QoS, func(topic string, payload []byte) {
+			b.handleMessage(mapping, topic, payload)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", mapping.Topic, err)
+		}
+	}
+
+	for i := range b.config.Publish {
+		mapping := &b.config.Publish[i]
+		sub, err := b.stateManager.Subscribe(mapping.Variable, func(_ string, _, newValue interface{}) {
+			b.publish(mapping, newValue, false)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", mapping.Variable, err)
+		}
+		b.subscriptions = append(b.subscriptions, sub)
+	}
+
+	if err := b.client.Connect(b.publishAll); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	b.logger.Info("MQTT bridge started")
+	return nil
+}
+
+// Stop unsubscribes from state variables and disconnects from the broker
+func (b *Bridge) Stop() {
+	b.logger.Info("Stopping MQTT bridge")
+
+	for _, sub := range b.subscriptions {
+		sub.Unsubscribe()
+	}
+	b.subscriptions = nil
+
+	b.client.Disconnect()
+
+	b.logger.Info("MQTT bridge stopped")
+}
+
+// publishAll publishes the current value of every published variable, so the
+// broker catches up after a (re)connect
+func (b *Bridge) publishAll() {
+	for i := range b.config.Publish {
+		mapping := &b.config.Publish[i]
+		value, err := b.readValue(mapping.Variable)
+		if err != nil {
+			b.logger.Error("Failed to read state variable",
+				zap.String("variable", mapping.Variable),
+				zap.Error(err))
+			continue
+		}
+		b.publish(mapping, value, true)
+	}
+}
+
+// publish sends a variable's value to its topic unless the same payload was
+// the last one published there. force republishes regardless.
+func (b *Bridge) publish(mapping *PublishMapping, value interface{}, force bool) {
+	payload, err := FormatPayload(value)
+	if err != nil {
+		b.logger.Error("Failed to format MQTT payload",
+			zap.String("variable", mapping.Variable),
+			zap.Error(err))
+		return
+	}
+
+	b.mu.Lock()
+	last, seen := b.lastPublished[mapping.Topic]
+	b.mu.Unlock()
+	if seen && last == payload && !force {
+		return
+	}
+
+	if b.readOnly {
+		b.logger.Info("READ-ONLY: Would publish to MQTT",
+			zap.String("topic", mapping.Topic),
+			zap.String("payload", payload))
+		return
+	}
+
+	if err := b.client.Publish(mapping.Topic, b.config.QoS, mapping.Retain, []byte(payload)); err != nil {
+		// Republished on reconnect
+		b.logger.Warn("Failed to publish to MQTT",
+			zap.String("topic", mapping.Topic),
+			zap.Error(err))
+		return
+	}
+
+	b.mu.Lock()
+	b.lastPublished[mapping.Topic] = payload
+	b.mu.Unlock()
+
+	b.logger.Debug("Published state variable to MQTT",
+		zap.String("variable", mapping.Variable),
+		zap.String("topic", mapping.Topic),
+		zap.String("payload", payload))
+}
+
+// handleMessage writes a message from a subscribed topic to its variable
+func (b *Bridge) handleMessage(mapping *SubscribeMapping, topic string, payload []byte) {
+	value, ok, err := b.parseMessage(mapping, payload)
+	if err != nil {
+		b.logger.Warn("Ignoring MQTT message",
+			zap.String("topic", topic),
+			zap.String("variable", mapping.Variable),
+			zap.String("payload", string(payload)),
+			zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if b.readOnly {
+		b.logger.Info("READ-ONLY: Would set state variable from MQTT",
+			zap.String("topic", topic),
+			zap.String("variable", mapping.Variable),
+			zap.Any("value", value))
+		return
+	}
+
+	results, err := b.stateManager.SetBatch([]state.Update{{Key: mapping.Variable, Value: value}})
+	if err != nil && len(results) == 1 && results[0].Err != nil {
+		err = results[0].Err
+	}
+	if err != nil {
+		b.logger.Error("Failed to set state variable from MQTT",
+			zap.String("topic", topic),
+			zap.String("variable", mapping.Variable),
+			zap.Error(err))
+		return
+	}
+
+	b.logger.Debug("Set state variable from MQTT",
+		zap.String("topic", topic),
+		zap.String("variable", mapping.Variable),
+		zap.Any("value", value))
+}
+
+// parseMessage extracts the configured field from a payload and converts it
+// to the variable's type. ok is false for payloads the mapping's values
+// don't list.
+func (b *Bridge) parseMessage(mapping *SubscribeMapping, payload []byte) (interface{}, bool, error) {
+	raw := strings.TrimSpace(string(payload))
+
+	if mapping.JSONField != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, false, fmt.Errorf("payload is not a JSON object: %w", err)
+		}
+		field, ok := fields[mapping.JSONField]
+		if !ok {
+			// e.g. a Zigbee2MQTT state update without an action
+			return nil, false, nil
+		}
+		if s, isString := field.(string); isString {
+			raw = s
+		} else {
+			data, _ := json.Marshal(field)
+			raw = string(data)
+		}
+	}
+
+	if mapping.Values != nil {
+		value, ok := mapping.Values[raw]
+		return value, ok, nil
+	}
+
+	value, err := ParsePayload(b.variables[mapping.Variable], raw)
+	return value, err == nil, err
+}
+
+// FormatPayload formats a state value as an MQTT payload: true/false for
+// booleans, the number, the string as is, or JSON
+func FormatPayload(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case string:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
+
+// ParsePayload converts an MQTT payload to a variable's type. Booleans also
+// accept on/off and 1/0 in any case, as sent by Zigbee2MQTT and Tasmota.
+func ParsePayload(variable state.StateVariable, payload string) (interface{}, error) {
+	switch variable.Type {
+	case state.TypeBool:
+		switch strings.ToLower(payload) {
+		case "true", "on", "1":
+			return true, nil
+		case "false", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%s is a boolean, got %q", variable.Key, payload)
+
+	case state.TypeNumber:
+		number, err := strconv.ParseFloat(payload, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is a number, got %q", variable.Key, payload)
+		}
+		return number, nil
+
+	case state.TypeString:
+		return payload, nil
+
+	default:
+		var value interface{}
+		if err := json.Unmarshal([]byte(payload), &value); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON: %w", variable.Key, err)
+		}
+		return value, nil
+	}
+}
+
+// readValue returns a state variable's value as its subscribers see it
+func (b *Bridge) readValue(key string) (interface{}, error) {
+	switch b.variables[key].Type {
+	case state.TypeBool:
+		return b.stateManager.GetBool(key)
+	case state.TypeNumber:
+		return b.stateManager.GetNumber(key)
+	case state.TypeString:
+		return b.stateManager.GetString(key)
+	default:
+		var value interface{}
+		err := b.stateManager.GetJSON(key, &value)
+		return value, err
+	}
+}
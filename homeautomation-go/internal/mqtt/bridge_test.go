@@ -0,0 +1,171 @@
+package mqtt
+
+import (
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig(t *testing.T) *Config {
+	t.Helper()
+
+	config := &Config{MQTT: BridgeConfig{
+		Enabled: true,
+		Broker:  "tcp://localhost:1883",
+		Publish: []PublishMapping{
+			{Variable: "dayPhase", Topic: "homeautomation/state/dayPhase", Retain: true},
+			{Variable: "isExpectingSomeone", Topic: "homeautomation/state/isExpectingSomeone", Retain: true},
+		},
+		Subscribe: []SubscribeMapping{
+			{Topic: "homeautomation/set/isExpectingSomeone", Variable: "isExpectingSomeone"},
+			{Topic: "zigbee2mqtt/+/office_button", Variable: "isOfficeFocusMode", JSONField: "action", Values: map[string]interface{}{"single": true, "double": false}},
+			{Topic: "sensors/alarm", Variable: "alarmTime"},
+		},
+	}}
+	require.NoError(t, config.Validate())
+	return config
+}
+
+func setupTest(t *testing.T, readOnly bool) (*Bridge, *MockClient, *state.Manager) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_text.day_phase", "evening", nil)
+
+	stateManager := state.NewManager(mockHA, logger, readOnly)
+	require.NoError(t, stateManager.SyncFromHA())
+
+	client := NewMockClient()
+	bridge := NewBridge(client, stateManager, createTestConfig(t), logger, readOnly)
+	require.NoError(t, bridge.Start())
+	t.Cleanup(bridge.Stop)
+
+	return bridge, client, stateManager
+}
+
+func TestStart_PublishesCurrentValues(t *testing.T) {
+	_, client, _ := setupTest(t, false)
+
+	assert.Equal(t, []Message{
+		{Topic: "homeautomation/state/dayPhase", Payload: "evening", Retain: true},
+		{Topic: "homeautomation/state/isExpectingSomeone", Payload: "false", Retain: true},
+	}, client.GetPublished())
+}
+
+func TestPublishesOnChange(t *testing.T) {
+	_, client, stateManager := setupTest(t, false)
+	client.ClearPublished()
+
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+	// An unchanged value is not republished
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+
+	assert.Equal(t, []Message{{Topic: "homeautomation/state/dayPhase", Payload: "night", Retain: true}}, client.GetPublished())
+}
+
+func TestSubscribe_SetsVariable(t *testing.T) {
+	_, client, stateManager := setupTest(t, false)
+
+	client.Deliver("homeautomation/set/isExpectingSomeone", "ON")
+	value, err := stateManager.GetBool("isExpectingSomeone")
+	require.NoError(t, err)
+	assert.True(t, value)
+
+	client.Deliver("sensors/alarm", "1718000000000")
+	alarm, err := stateManager.GetNumber("alarmTime")
+	require.NoError(t, err)
+	assert.Equal(t, 1718000000000.0, alarm)
+}
+
+func TestSubscribe_JSONFieldAndValues(t *testing.T) {
+	_, client, stateManager := setupTest(t, false)
+
+	client.Deliver("zigbee2mqtt/office/office_button", `{"action": "single", "battery": 90}`)
+	focus, err := stateManager.GetBool("isOfficeFocusMode")
+	require.NoError(t, err)
+	assert.True(t, focus)
+
+	// Unlisted actions and updates without an action are ignored
+	client.Deliver("zigbee2mqtt/office/office_button", `{"action": "hold"}`)
+	client.Deliver("zigbee2mqtt/office/office_button", `{"battery": 89}`)
+	focus, _ = stateManager.GetBool("isOfficeFocusMode")
+	assert.True(t, focus)
+
+	client.Deliver("zigbee2mqtt/office/office_button", `{"action": "double"}`)
+	focus, _ = stateManager.GetBool("isOfficeFocusMode")
+	assert.False(t, focus)
+}
+
+func TestSubscribe_InvalidPayloadIgnored(t *testing.T) {
+	_, client, stateManager := setupTest(t, false)
+
+	client.Deliver("sensors/alarm", "soon")
+	alarm, err := stateManager.GetNumber("alarmTime")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, alarm)
+}
+
+func TestSubscribe_RepublishesChangedVariable(t *testing.T) {
+	_, client, _ := setupTest(t, false)
+	client.ClearPublished()
+
+	client.Deliver("homeautomation/set/isExpectingSomeone", "true")
+
+	assert.Equal(t, []Message{{Topic: "homeautomation/state/isExpectingSomeone", Payload: "true", Retain: true}}, client.GetPublished())
+}
+
+func TestReadOnly(t *testing.T) {
+	_, client, stateManager := setupTest(t, true)
+
+	client.Deliver("homeautomation/set/isExpectingSomeone", "true")
+
+	assert.Empty(t, client.GetPublished())
+	value, err := stateManager.GetBool("isExpectingSomeone")
+	require.NoError(t, err)
+	assert.False(t, value)
+}
+
+func TestPublish_RetriedOnReconnect(t *testing.T) {
+	bridge, client, stateManager := setupTest(t, false)
+	client.Disconnect()
+	client.ClearPublished()
+
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+	assert.Empty(t, client.GetPublished())
+
+	require.NoError(t, client.Connect(bridge.publishAll))
+	assert.Contains(t, client.GetPublished(), Message{Topic: "homeautomation/state/dayPhase", Payload: "night", Retain: true})
+}
+
+func TestFormatAndParsePayload(t *testing.T) {
+	for _, tt := range []struct {
+		value   interface{}
+		payload string
+	}{
+		{true, "true"},
+		{21.5, "21.5"},
+		{1718000000000.0, "1718000000000"},
+		{"evening", "evening"},
+		{[]interface{}{"sensor.a"}, `["sensor.a"]`},
+	} {
+		payload, err := FormatPayload(tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.payload, payload)
+	}
+
+	variables := state.VariablesByKey()
+	value, err := ParsePayload(variables["isAnyoneHome"], "Off")
+	require.NoError(t, err)
+	assert.Equal(t, false, value)
+	_, err = ParsePayload(variables["isAnyoneHome"], "maybe")
+	assert.Error(t, err)
+	value, err = ParsePayload(variables["lowBatteryDevices"], `[{"entity_id": "sensor.a"}]`)
+	require.NoError(t, err)
+	assert.Len(t, value, 1)
+}
@@ -0,0 +1,143 @@
+// Package mqtt bridges state variables and an MQTT broker: selected variables
+// are published to topics when they change, and messages on subscribed topics
+// update variables, as configured in mqtt_config.yaml.
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// ErrNotConnected is returned when publishing while the broker is unreachable
+var ErrNotConnected = errors.New("not connected to MQTT broker")
+
+// operationTimeout bounds waiting for the broker to acknowledge a publish or subscribe
+const operationTimeout = 5 * time.Second
+
+// MessageHandler receives messages on a subscribed topic
+type MessageHandler func(topic string, payload []byte)
+
+// Client is the subset of an MQTT client the bridge uses
+type Client interface {
+	// Connect starts connecting in the background and keeps reconnecting.
+	// onConnect runs after every (re)connection, once subscriptions are restored.
+	Connect(onConnect func()) error
+	Subscribe(topic string, qos byte, handler MessageHandler) error
+	Publish(topic string, qos byte, retain bool, payload []byte) error
+	Disconnect()
+}
+
+// PahoClient is a Client backed by the Eclipse Paho MQTT library
+type PahoClient struct {
+	options *paho.ClientOptions
+	client  paho.Client
+	logger  *zap.Logger
+
+	// Subscriptions restored on every reconnect (protected by mu)
+	handlers map[string]subscription
+	mu       sync.Mutex
+}
+
+type subscription struct {
+	qos     byte
+	handler MessageHandler
+}
+
+// NewPahoClient creates a client for the configured broker. username and
+// password may be empty for brokers without authentication.
+func NewPahoClient(config BridgeConfig, username, password string, logger *zap.Logger) *PahoClient {
+	c := &PahoClient{
+		logger:   logger.Named("mqtt"),
+		handlers: make(map[string]subscription),
+	}
+
+	c.options = paho.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			c.logger.Warn("Lost connection to MQTT broker, reconnecting", zap.Error(err))
+		})
+	return c
+}
+
+// Connect starts connecting to the broker
+func (c *PahoClient) Connect(onConnect func()) error {
+	c.options.SetOnConnectHandler(func(client paho.Client) {
+		c.logger.Info("Connected to MQTT broker")
+
+		c.mu.Lock()
+		handlers := make(map[string]subscription, len(c.handlers))
+		for topic, sub := range c.handlers {
+			handlers[topic] = sub
+		}
+		c.mu.Unlock()
+
+		for topic, sub := range handlers {
+			if err := c.subscribe(client, topic, sub); err != nil {
+				c.logger.Error("Failed to subscribe", zap.String("topic", topic), zap.Error(err))
+			}
+		}
+		if onConnect != nil {
+			// Not on paho's callback goroutine, which must not block on publishes
+			go onConnect()
+		}
+	})
+
+	c.client = paho.NewClient(c.options)
+	// With connect retry on, the token only completes once connected
+	c.client.Connect()
+	return nil
+}
+
+// Subscribe registers a handler for a topic, subscribing now if connected
+func (c *PahoClient) Subscribe(topic string, qos byte, handler MessageHandler) error {
+	sub := subscription{qos: qos, handler: handler}
+
+	c.mu.Lock()
+	c.handlers[topic] = sub
+	c.mu.Unlock()
+
+	if c.client == nil || !c.client.IsConnectionOpen() {
+		return nil
+	}
+	return c.subscribe(c.client, topic, sub)
+}
+
+func (c *PahoClient) subscribe(client paho.Client, topic string, sub subscription) error {
+	token := client.Subscribe(topic, sub.qos, func(_ paho.Client, msg paho.Message) {
+		sub.handler(msg.Topic(), msg.Payload())
+	})
+	return wait(token)
+}
+
+// Publish sends a message, failing fast while the broker is unreachable
+func (c *PahoClient) Publish(topic string, qos byte, retain bool, payload []byte) error {
+	if c.client == nil || !c.client.IsConnectionOpen() {
+		return ErrNotConnected
+	}
+	return wait(c.client.Publish(topic, qos, retain, payload))
+}
+
+// Disconnect closes the connection, waiting briefly for in-flight messages
+func (c *PahoClient) Disconnect() {
+	if c.client != nil {
+		c.client.Disconnect(250)
+	}
+}
+
+func wait(token paho.Token) error {
+	if !token.WaitTimeout(operationTimeout) {
+		return fmt.Errorf("timed out after %v", operationTimeout)
+	}
+	return token.Error()
+}
@@ -0,0 +1,169 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PublishMapping mirrors a state variable to an MQTT topic
+type PublishMapping struct {
+	Variable string `yaml:"variable"`
+	Topic    string `yaml:"topic"`
+	Retain   bool   `yaml:"retain"` // Retained so new subscribers see the current value
+}
+
+// SubscribeMapping updates a state variable from messages on an MQTT topic
+type SubscribeMapping struct {
+	Topic     string `yaml:"topic"` // May contain + and # wildcards
+	Variable  string `yaml:"variable"`
+	JSONField string `yaml:"json_field"` // Optional: read this top-level field of a JSON payload, e.g. "action"
+	// Optional: payload (or field) -> value; payloads not listed are ignored
+	Values map[string]interface{} `yaml:"values"`
+}
+
+// BridgeConfig holds the broker connection and topic mappings
+type BridgeConfig struct {
+	Enabled   bool               `yaml:"enabled"`
+	Broker    string             `yaml:"broker"`    // e.g. "tcp://mosquitto:1883"
+	ClientID  string             `yaml:"client_id"` // Defaults to "homeautomation"
+	QoS       byte               `yaml:"qos"`
+	Publish   []PublishMapping   `yaml:"publish"`
+	Subscribe []SubscribeMapping `yaml:"subscribe"`
+}
+
+// Config represents the mqtt_config.yaml structure
+type Config struct {
+	MQTT BridgeConfig `yaml:"mqtt"`
+}
+
+// DefaultClientID is the MQTT client ID used when none is configured
+const DefaultClientID = "homeautomation"
+
+// brokerSchemes are the broker URL schemes the client supports
+var brokerSchemes = map[string]bool{"tcp": true, "mqtt": true, "ssl": true, "tls": true, "mqtts": true, "ws": true, "wss": true}
+
+// Validate checks the broker URL and topic mappings and converts configured
+// values to the type of the state variable they are written to
+func (c *Config) Validate() error {
+	cfg := &c.MQTT
+	if cfg.ClientID == "" {
+		cfg.ClientID = DefaultClientID
+	}
+	if cfg.QoS > 2 {
+		return fmt.Errorf("qos must be 0, 1 or 2, got %d", cfg.QoS)
+	}
+
+	if cfg.Enabled {
+		broker, err := url.Parse(cfg.Broker)
+		if err != nil || broker.Host == "" || !brokerSchemes[broker.Scheme] {
+			return fmt.Errorf("broker must be a URL like tcp://host:1883, got %q", cfg.Broker)
+		}
+	}
+
+	variables := state.VariablesByKey()
+	published := make(map[string]bool)
+
+	for i, p := range cfg.Publish {
+		if _, ok := variables[p.Variable]; !ok {
+			return fmt.Errorf("publish[%d]: unknown state variable %q", i, p.Variable)
+		}
+		if p.Topic == "" {
+			return fmt.Errorf("publish[%d]: topic is required", i)
+		}
+		if strings.ContainsAny(p.Topic, "+#") {
+			return fmt.Errorf("publish[%d]: topic %q must not contain wildcards", i, p.Topic)
+		}
+		published[p.Topic] = true
+	}
+
+	for i := range cfg.Subscribe {
+		s := &cfg.Subscribe[i]
+		variable, ok := variables[s.Variable]
+		if !ok {
+			return fmt.Errorf("subscribe[%d]: unknown state variable %q", i, s.Variable)
+		}
+		if variable.ReadOnly {
+			return fmt.Errorf("subscribe[%d]: state variable %s is read-only", i, s.Variable)
+		}
+		if s.Topic == "" {
+			return fmt.Errorf("subscribe[%d]: topic is required", i)
+		}
+		// A value published to the topic it is read from would echo forever
+		if published[s.Topic] {
+			return fmt.Errorf("subscribe[%d]: topic %q is also published to", i, s.Topic)
+		}
+		for payload, value := range s.Values {
+			converted, err := convertValue(variable, value)
+			if err != nil {
+				return fmt.Errorf("subscribe[%d]: values[%s]: %w", i, payload, err)
+			}
+			s.Values[payload] = converted
+		}
+	}
+	return nil
+}
+
+// convertValue converts a YAML value to the Go type the state manager uses
+// for the variable
+func convertValue(variable state.StateVariable, value interface{}) (interface{}, error) {
+	switch variable.Type {
+	case state.TypeBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s is a boolean, got %v", variable.Key, value)
+
+	case state.TypeNumber:
+		switch n := value.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+		return nil, fmt.Errorf("%s is a number, got %v", variable.Key, value)
+
+	case state.TypeString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("%s is a string, got %v", variable.Key, value)
+
+	default:
+		// Round-trip through JSON so values match ones read from state
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", variable.Key, err)
+		}
+		var converted interface{}
+		if err := json.Unmarshal(data, &converted); err != nil {
+			return nil, fmt.Errorf("%s: %w", variable.Key, err)
+		}
+		return converted, nil
+	}
+}
+
+// LoadConfig loads the MQTT bridge configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,110 @@
+package mqtt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_RealConfig(t *testing.T) {
+	config, err := LoadConfig("../../../configs/mqtt_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.MQTT.Publish)
+	assert.NotEmpty(t, config.MQTT.Subscribe)
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mqtt_config.yaml")
+	content := `mqtt:
+  enabled: true
+  broker: tcp://localhost:1883
+  subscribe:
+    - topic: zigbee2mqtt/office_button
+      variable: isOfficeFocusMode
+      json_field: action
+      values:
+        single: true
+        double: false
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultClientID, config.MQTT.ClientID)
+	assert.Equal(t, map[string]interface{}{"single": true, "double": false}, config.MQTT.Subscribe[0].Values)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BridgeConfig
+		errMsg string
+	}{
+		{
+			name:   "disabled without broker",
+			config: BridgeConfig{Publish: []PublishMapping{{Variable: "dayPhase", Topic: "home/dayPhase"}}},
+		},
+		{
+			name:   "invalid broker",
+			config: BridgeConfig{Enabled: true, Broker: "localhost:1883"},
+			errMsg: "broker must be a URL",
+		},
+		{
+			name:   "invalid qos",
+			config: BridgeConfig{Enabled: true, Broker: "tcp://localhost:1883", QoS: 3},
+			errMsg: "qos",
+		},
+		{
+			name:   "unknown publish variable",
+			config: BridgeConfig{Publish: []PublishMapping{{Variable: "dayPhaze", Topic: "home/dayPhase"}}},
+			errMsg: "unknown state variable",
+		},
+		{
+			name:   "publish topic with wildcard",
+			config: BridgeConfig{Publish: []PublishMapping{{Variable: "dayPhase", Topic: "home/+"}}},
+			errMsg: "wildcards",
+		},
+		{
+			name:   "subscribe topic missing",
+			config: BridgeConfig{Subscribe: []SubscribeMapping{{Variable: "isExpectingSomeone"}}},
+			errMsg: "topic is required",
+		},
+		{
+			name: "subscribe to a published topic",
+			config: BridgeConfig{
+				Publish:   []PublishMapping{{Variable: "isExpectingSomeone", Topic: "home/expecting"}},
+				Subscribe: []SubscribeMapping{{Topic: "home/expecting", Variable: "isExpectingSomeone"}},
+			},
+			errMsg: "also published",
+		},
+		{
+			name:   "value of the wrong type",
+			config: BridgeConfig{Subscribe: []SubscribeMapping{{Topic: "button", Variable: "isExpectingSomeone", Values: map[string]interface{}{"single": "yes"}}}},
+			errMsg: "boolean",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{MQTT: tt.config}
+			err := config.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	assert.True(t, topicMatches("zigbee2mqtt/button", "zigbee2mqtt/button"))
+	assert.True(t, topicMatches("zigbee2mqtt/+", "zigbee2mqtt/button"))
+	assert.True(t, topicMatches("zigbee2mqtt/#", "zigbee2mqtt/button/action"))
+	assert.False(t, topicMatches("zigbee2mqtt/+", "zigbee2mqtt/button/action"))
+	assert.False(t, topicMatches("zigbee2mqtt/button/action", "zigbee2mqtt/button"))
+}
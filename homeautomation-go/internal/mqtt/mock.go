@@ -0,0 +1,121 @@
+package mqtt
+
+import (
+	"strings"
+	"sync"
+)
+
+// Message is a message published through a MockClient
+type Message struct {
+	Topic   string
+	Payload string
+	Retain  bool
+}
+
+// MockClient implements Client for testing. Published messages are recorded
+// and Deliver simulates a message from the broker.
+type MockClient struct {
+	handlers  map[string]MessageHandler
+	published []Message
+	connected bool
+	mu        sync.Mutex
+}
+
+// NewMockClient creates a new mock MQTT client
+func NewMockClient() *MockClient {
+	return &MockClient{handlers: make(map[string]MessageHandler)}
+}
+
+// Connect marks the client connected and runs onConnect immediately
+func (m *MockClient) Connect(onConnect func()) error {
+	m.mu.Lock()
+	m.connected = true
+	m.mu.Unlock()
+
+	if onConnect != nil {
+		onConnect()
+	}
+	return nil
+}
+
+// Subscribe records the handler for a topic
+func (m *MockClient) Subscribe(topic string, _ byte, handler MessageHandler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers[topic] = handler
+	return nil
+}
+
+// Publish records the message, or fails if the client is disconnected
+func (m *MockClient) Publish(topic string, _ byte, retain bool, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.connected {
+		return ErrNotConnected
+	}
+	m.published = append(m.published, Message{Topic: topic, Payload: string(payload), Retain: retain})
+	return nil
+}
+
+// Disconnect marks the client disconnected
+func (m *MockClient) Disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connected = false
+}
+
+// Deliver simulates a message from the broker, calling every handler whose
+// topic filter matches
+func (m *MockClient) Deliver(topic, payload string) {
+	m.mu.Lock()
+	var handlers []MessageHandler
+	for filter, handler := range m.handlers {
+		if topicMatches(filter, topic) {
+			handlers = append(handlers, handler)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(topic, []byte(payload))
+	}
+}
+
+// GetPublished returns every message published so far
+func (m *MockClient) GetPublished() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message{}, m.published...)
+}
+
+// ClearPublished forgets the messages published so far
+func (m *MockClient) ClearPublished() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.published = nil
+}
+
+// topicMatches reports whether a topic matches a subscription filter with
+// + (one level) and # (all remaining levels) wildcards
+func topicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
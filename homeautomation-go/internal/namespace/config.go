@@ -0,0 +1,124 @@
+package namespace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plugins that can run inside a namespace
+const (
+	PluginLighting = "lighting"
+	PluginMusic    = "music"
+)
+
+// supportedPlugins are the plugins that can run against a namespace's state
+var supportedPlugins = map[string]bool{
+	PluginLighting: true,
+	PluginMusic:    true,
+}
+
+// Namespace is a separately automated part of the house, such as a rental
+// suite. Its plugins run against namespaced copies of the listed variables
+// (suite.isAnyoneHome, backed by input_boolean.suite_anyone_home) and see
+// every other variable, such as the energy level, as the global value.
+type Namespace struct {
+	Name      string   `yaml:"name"`
+	ConfigDir string   `yaml:"config_dir"` // Relative to the main config directory, defaults to name
+	Variables []string `yaml:"variables"`  // State variables scoped to the namespace
+	Plugins   []string `yaml:"plugins"`    // Plugins to run for the namespace (lighting, music)
+}
+
+// Config represents the namespace_config.yaml structure
+type Config struct {
+	Namespaces []Namespace `yaml:"namespaces"`
+}
+
+// ConfigPath returns the directory holding the namespace's plugin configs
+func (n Namespace) ConfigPath(configDir string) string {
+	dir := n.ConfigDir
+	if dir == "" {
+		dir = n.Name
+	}
+	return filepath.Join(configDir, dir)
+}
+
+// HasVariable reports whether the namespace scopes the named state variable
+func (n Namespace) HasVariable(key string) bool {
+	for _, v := range n.Variables {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPlugin reports whether the namespace runs the named plugin
+func (n Namespace) HasPlugin(name string) bool {
+	for _, p := range n.Plugins {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks namespace names, scoped variables and plugins
+func (c *Config) Validate() error {
+	variables := state.VariablesByKey()
+	names := make(map[string]bool)
+
+	for i, n := range c.Namespaces {
+		if !state.ValidNamespace(n.Name) {
+			return fmt.Errorf("namespaces[%d]: invalid name %q, expected lowercase letters, digits and underscores", i, n.Name)
+		}
+		if names[n.Name] {
+			return fmt.Errorf("namespaces[%d]: duplicate namespace %q", i, n.Name)
+		}
+		names[n.Name] = true
+
+		if len(n.Variables) == 0 {
+			return fmt.Errorf("namespace %q: no variables scoped to the namespace", n.Name)
+		}
+		seen := make(map[string]bool)
+		for _, key := range n.Variables {
+			if _, ok := variables[key]; !ok {
+				return fmt.Errorf("namespace %q: unknown state variable %q", n.Name, key)
+			}
+			if seen[key] {
+				return fmt.Errorf("namespace %q: duplicate variable %q", n.Name, key)
+			}
+			seen[key] = true
+		}
+
+		for _, p := range n.Plugins {
+			if !supportedPlugins[p] {
+				return fmt.Errorf("namespace %q: plugin %q cannot run in a namespace", n.Name, p)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the namespace configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
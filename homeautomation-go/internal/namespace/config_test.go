@@ -0,0 +1,69 @@
+package namespace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	_, err := LoadConfig("../../../configs/namespace_config.yaml")
+	require.NoError(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "namespace_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`---
+namespaces:
+  - name: suite
+    variables: [isAnyoneHome, isAnyoneAsleep]
+    plugins: [lighting, music]
+`), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, config.Namespaces, 1)
+
+	suite := config.Namespaces[0]
+	assert.Equal(t, []string{"isAnyoneHome", "isAnyoneAsleep"}, suite.Variables)
+	assert.True(t, suite.HasPlugin(PluginLighting))
+	assert.Equal(t, filepath.Join("configs", "suite"), suite.ConfigPath("configs"))
+
+	suite.ConfigDir = "basement"
+	assert.Equal(t, filepath.Join("configs", "basement"), suite.ConfigPath("configs"))
+}
+
+func TestValidate(t *testing.T) {
+	valid := Namespace{Name: "suite", Variables: []string{"isAnyoneHome"}, Plugins: []string{"lighting"}}
+
+	tests := []struct {
+		name       string
+		namespaces []Namespace
+		wantErr    string
+	}{
+		{"valid", []Namespace{valid}, ""},
+		{"no plugins", []Namespace{{Name: "suite", Variables: []string{"isAnyoneHome"}}}, ""},
+		{"invalid name", []Namespace{{Name: "Suite", Variables: []string{"isAnyoneHome"}}}, "invalid name"},
+		{"duplicate name", []Namespace{valid, valid}, "duplicate namespace"},
+		{"no variables", []Namespace{{Name: "suite"}}, "no variables"},
+		{"unknown variable", []Namespace{{Name: "suite", Variables: []string{"isNotAVariable"}}}, "unknown state variable"},
+		{"duplicate variable", []Namespace{{Name: "suite", Variables: []string{"isAnyoneHome", "isAnyoneHome"}}}, "duplicate variable"},
+		{"unsupported plugin", []Namespace{{Name: "suite", Variables: []string{"isAnyoneHome"}, Plugins: []string{"energy"}}}, "cannot run in a namespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Namespaces: tt.namespaces}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
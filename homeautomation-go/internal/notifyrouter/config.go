@@ -0,0 +1,124 @@
+package notifyrouter
+
+import (
+	"fmt"
+	"os"
+
+	"homeautomation/internal/dayphase"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Level is how intrusively an announcement is delivered
+type Level string
+
+const (
+	LevelFull  Level = "full"  // Spoken on the speakers with TTS
+	LevelChime Level = "chime" // A chime on the speakers, no speech
+	LevelPush  Level = "push"  // A mobile push notification only
+)
+
+// valid reports whether the level is known
+func (l Level) valid() bool {
+	return l == LevelFull || l == LevelChime || l == LevelPush
+}
+
+// ScheduleStep sets the delivery level while dayPhase has a value
+type ScheduleStep struct {
+	DayPhase string `yaml:"day_phase"`
+	Level    Level  `yaml:"level"`
+}
+
+// Chime is the sound played on the speakers at the chime level
+type Chime struct {
+	MediaContentID   string `yaml:"media_content_id"`
+	MediaContentType string `yaml:"media_content_type"` // Defaults to "music"
+}
+
+// Settings holds the silencing schedule and how each level is delivered
+type Settings struct {
+	Chime       Chime          `yaml:"chime"`
+	PushService Target         `yaml:"push_service"`
+	Schedule    []ScheduleStep `yaml:"schedule"`
+}
+
+// Config represents the notification_router_config.yaml structure
+type Config struct {
+	NotificationRouter Settings `yaml:"notification_router"`
+}
+
+// ChimeContentType returns the chime's media type, defaulting to "music"
+func (s Settings) ChimeContentType() string {
+	if s.Chime.MediaContentType == "" {
+		return "music"
+	}
+	return s.Chime.MediaContentType
+}
+
+// LevelFor returns the level scheduled for a day phase, LevelFull if the
+// phase has no step
+func (s Settings) LevelFor(dayPhase string) Level {
+	for _, step := range s.Schedule {
+		if step.DayPhase == dayPhase {
+			return step.Level
+		}
+	}
+	return LevelFull
+}
+
+// Validate checks the schedule and that every level it uses can be delivered
+func (c *Config) Validate() error {
+	s := c.NotificationRouter
+	phases := make(map[string]bool)
+
+	for i, step := range s.Schedule {
+		switch dayphase.DayPhase(step.DayPhase) {
+		case dayphase.DayPhaseMorning, dayphase.DayPhaseDay, dayphase.DayPhaseSunset,
+			dayphase.DayPhaseDusk, dayphase.DayPhaseWinddown, dayphase.DayPhaseNight:
+		default:
+			return fmt.Errorf("schedule[%d]: unknown day_phase %q", i, step.DayPhase)
+		}
+		if phases[step.DayPhase] {
+			return fmt.Errorf("schedule[%d]: duplicate day_phase %q", i, step.DayPhase)
+		}
+		phases[step.DayPhase] = true
+
+		if !step.Level.valid() {
+			return fmt.Errorf("schedule[%d]: unknown level %q, expected full, chime or push", i, step.Level)
+		}
+		if step.Level == LevelChime && s.Chime.MediaContentID == "" {
+			return fmt.Errorf("schedule[%d]: level chime requires chime.media_content_id", i)
+		}
+		if step.Level == LevelPush {
+			if _, _, ok := s.PushService.DomainService(); !ok {
+				return fmt.Errorf("schedule[%d]: level push requires push_service as domain.service", i)
+			}
+		}
+	}
+
+	if s.PushService != "" {
+		if _, _, ok := s.PushService.DomainService(); !ok {
+			return fmt.Errorf("invalid push_service %q, expected domain.service", s.PushService)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the notification router configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,67 @@
+package notifyrouter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../configs/notification_router_config.yaml")
+	require.NoError(t, err)
+
+	s := config.NotificationRouter
+	assert.Equal(t, LevelFull, s.LevelFor("day"))
+	assert.Equal(t, LevelChime, s.LevelFor("winddown"))
+	assert.Equal(t, LevelPush, s.LevelFor("night"))
+}
+
+func TestValidate(t *testing.T) {
+	chime := Chime{MediaContentID: "media-source://media_source/local/chime.mp3"}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  string
+	}{
+		{"valid", Settings{Chime: chime, PushService: "notify.notify", Schedule: []ScheduleStep{
+			{DayPhase: "winddown", Level: LevelChime}, {DayPhase: "night", Level: LevelPush}}}, ""},
+		{"empty", Settings{}, ""},
+		{"unknown day phase", Settings{Schedule: []ScheduleStep{{DayPhase: "evening", Level: LevelFull}}}, "unknown day_phase"},
+		{"duplicate day phase", Settings{Schedule: []ScheduleStep{
+			{DayPhase: "night", Level: LevelFull}, {DayPhase: "night", Level: LevelFull}}}, "duplicate day_phase"},
+		{"unknown level", Settings{Schedule: []ScheduleStep{{DayPhase: "night", Level: "whisper"}}}, "unknown level"},
+		{"chime without media", Settings{Schedule: []ScheduleStep{{DayPhase: "night", Level: LevelChime}}}, "requires chime.media_content_id"},
+		{"push without service", Settings{Schedule: []ScheduleStep{{DayPhase: "night", Level: LevelPush}}}, "requires push_service"},
+		{"invalid push service", Settings{PushService: "notify"}, "invalid push_service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{NotificationRouter: tt.settings}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification_router_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+notification_router:
+  schedule:
+    - day_phase: night
+      level: push
+`), 0644))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+}
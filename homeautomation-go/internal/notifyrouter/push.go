@@ -0,0 +1,47 @@
+package notifyrouter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"homeautomation/internal/ha"
+)
+
+// Target is a notify service as "domain.service", e.g. notify.mobile_app_phone.
+// Plugin configs use it for their notify_service settings.
+type Target string
+
+// DomainService splits the target into its Home Assistant domain and service
+func (t Target) DomainService() (string, string, bool) {
+	domain, service, ok := strings.Cut(string(t), ".")
+	if !ok || domain == "" || service == "" {
+		return "", "", false
+	}
+	return domain, service, true
+}
+
+// Push is a mobile push notification
+type Push struct {
+	Title   string
+	Message string
+	Image   string // URL of an image to attach, optional
+}
+
+// SendPush sends a push notification to the target. Pushes aren't lowered by
+// the schedule; they are already its quietest level.
+func SendPush(ctx context.Context, client ha.HAClient, target Target, p Push) error {
+	domain, service, ok := target.DomainService()
+	if !ok {
+		return fmt.Errorf("invalid notify service %q, expected domain.service", target)
+	}
+
+	data := map[string]interface{}{"message": p.Message}
+	if p.Title != "" {
+		data["title"] = p.Title
+	}
+	if p.Image != "" {
+		data["data"] = map[string]interface{}{"image": p.Image}
+	}
+	return client.CallService(ctx, domain, service, data)
+}
@@ -0,0 +1,93 @@
+// Package notifyrouter delivers spoken announcements at a verbosity that
+// follows the evening: full TTS during the day, then a chime, then only a
+// mobile push as dayPhase moves through winddown to night. Plugins hand their
+// announcements to the router instead of calling tts.speak themselves, so the
+// silencing schedule lives in one place, and send their pushes through
+// SendPush rather than calling notify services directly.
+package notifyrouter
+
+import (
+	"context"
+	"fmt"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+)
+
+// DefaultTTSEntity speaks announcements that don't name a TTS entity
+const DefaultTTSEntity = "tts.google_translate_en_com"
+
+// Announcement is a message meant to be spoken on some speakers
+type Announcement struct {
+	Title     string   // Push notification title
+	Message   string   // Spoken text, or the push notification body
+	Speakers  []string // Media players to speak or chime on
+	TTSEntity string   // Defaults to DefaultTTSEntity
+	PushSent  bool     // The caller sends its own push, so nothing is sent at the push level
+}
+
+// Router picks the delivery level from dayPhase and sends announcements.
+// All methods are safe to call on a nil Router, which always speaks in full.
+type Router struct {
+	config       *Config
+	stateManager *state.Manager
+}
+
+// NewRouter creates a router reading dayPhase from the state manager
+func NewRouter(config *Config, stateManager *state.Manager) *Router {
+	return &Router{
+		config:       config,
+		stateManager: stateManager,
+	}
+}
+
+// Level returns the delivery level for the current day phase
+func (r *Router) Level() Level {
+	if r == nil {
+		return LevelFull
+	}
+	dayPhase, err := r.stateManager.GetString("dayPhase")
+	if err != nil {
+		return LevelFull
+	}
+	return r.config.NotificationRouter.LevelFor(dayPhase)
+}
+
+// Announce delivers the announcement at the current level and returns the
+// level used
+func (r *Router) Announce(ctx context.Context, client ha.HAClient, a Announcement) (Level, error) {
+	level := r.Level()
+
+	switch level {
+	case LevelChime:
+		s := r.config.NotificationRouter
+		return level, client.CallService(ctx, "media_player", "play_media", map[string]interface{}{
+			"entity_id":          a.Speakers,
+			"media_content_id":   s.Chime.MediaContentID,
+			"media_content_type": s.ChimeContentType(),
+			"announce":           true,
+		})
+
+	case LevelPush:
+		if a.PushSent {
+			return level, nil
+		}
+		target := r.config.NotificationRouter.PushService
+		if target == "" {
+			return level, fmt.Errorf("no push_service configured")
+		}
+		return level, SendPush(ctx, client, target, Push{Title: a.Title, Message: a.Message})
+
+	default:
+		ttsEntity := a.TTSEntity
+		if ttsEntity == "" {
+			ttsEntity = DefaultTTSEntity
+		}
+		return level, client.CallService(ctx, "tts", "speak", map[string]interface{}{
+			"entity_id":              ttsEntity,
+			"media_player_entity_id": a.Speakers,
+			"message":                a.Message,
+			"cache":                  true,
+		})
+	}
+}
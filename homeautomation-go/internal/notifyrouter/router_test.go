@@ -0,0 +1,127 @@
+package notifyrouter
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestRouter(t *testing.T) (*Router, *state.Manager, *ha.MockClient) {
+	t.Helper()
+
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, zap.NewNop(), false)
+	config := &Config{NotificationRouter: Settings{
+		Chime:       Chime{MediaContentID: "media-source://media_source/local/chime.mp3"},
+		PushService: "notify.mobile_app_phone",
+		Schedule: []ScheduleStep{
+			{DayPhase: "winddown", Level: LevelChime},
+			{DayPhase: "night", Level: LevelPush},
+		},
+	}}
+	require.NoError(t, config.Validate())
+
+	return NewRouter(config, stateManager), stateManager, mockClient
+}
+
+func announceAt(t *testing.T, router *Router, stateManager *state.Manager, mockClient *ha.MockClient, dayPhase string) (Level, ha.ServiceCall) {
+	t.Helper()
+	require.NoError(t, stateManager.SetString("dayPhase", dayPhase))
+	mockClient.ClearServiceCalls()
+
+	level, err := router.Announce(context.Background(), mockClient, Announcement{
+		Title:    "Arrival",
+		Message:  "Tori is here",
+		Speakers: []string{"media_player.kitchen"},
+	})
+	require.NoError(t, err)
+
+	calls := mockClient.GetServiceCalls()
+	require.Len(t, calls, 1)
+	return level, calls[0]
+}
+
+func TestRouter_ProgressesThroughTheEvening(t *testing.T) {
+	router, stateManager, mockClient := newTestRouter(t)
+
+	level, call := announceAt(t, router, stateManager, mockClient, "dusk")
+	assert.Equal(t, LevelFull, level)
+	assert.Equal(t, "tts", call.Domain)
+	assert.Equal(t, "speak", call.Service)
+	assert.Equal(t, DefaultTTSEntity, call.Data["entity_id"])
+	assert.Equal(t, "Tori is here", call.Data["message"])
+
+	level, call = announceAt(t, router, stateManager, mockClient, "winddown")
+	assert.Equal(t, LevelChime, level)
+	assert.Equal(t, "media_player", call.Domain)
+	assert.Equal(t, "play_media", call.Service)
+	assert.Equal(t, []string{"media_player.kitchen"}, call.Data["entity_id"])
+	assert.Equal(t, "music", call.Data["media_content_type"])
+	assert.NotContains(t, call.Data, "message")
+
+	level, call = announceAt(t, router, stateManager, mockClient, "night")
+	assert.Equal(t, LevelPush, level)
+	assert.Equal(t, "notify", call.Domain)
+	assert.Equal(t, "mobile_app_phone", call.Service)
+	assert.Equal(t, "Arrival", call.Data["title"])
+	assert.Equal(t, "Tori is here", call.Data["message"])
+}
+
+func TestRouter_Nil(t *testing.T) {
+	var router *Router
+	mockClient := ha.NewMockClient()
+
+	assert.Equal(t, LevelFull, router.Level())
+
+	level, err := router.Announce(context.Background(), mockClient, Announcement{
+		Message:   "Garage door is still open",
+		Speakers:  []string{"media_player.kitchen"},
+		TTSEntity: "tts.cloud",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, LevelFull, level)
+
+	calls := mockClient.GetServiceCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "tts", calls[0].Domain)
+	assert.Equal(t, "tts.cloud", calls[0].Data["entity_id"])
+}
+
+func TestTarget_DomainService(t *testing.T) {
+	domain, service, ok := Target("notify.mobile_app_phone").DomainService()
+	assert.True(t, ok)
+	assert.Equal(t, "notify", domain)
+	assert.Equal(t, "mobile_app_phone", service)
+
+	for _, target := range []Target{"", "notify", "notify.", ".mobile_app_phone"} {
+		_, _, ok := target.DomainService()
+		assert.False(t, ok, "target %q", target)
+	}
+}
+
+func TestSendPush(t *testing.T) {
+	mockClient := ha.NewMockClient()
+
+	require.NoError(t, SendPush(context.Background(), mockClient, "notify.mobile_app_phone", Push{
+		Title:   "Person detected",
+		Message: "Someone is at the front door",
+		Image:   "/local/snapshots/front.jpg",
+	}))
+	require.NoError(t, SendPush(context.Background(), mockClient, "notify.notify", Push{Message: "Weekly report"}))
+	assert.Error(t, SendPush(context.Background(), mockClient, "notify", Push{Message: "Lost"}))
+
+	calls := mockClient.GetServiceCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "mobile_app_phone", calls[0].Service)
+	assert.Equal(t, "Person detected", calls[0].Data["title"])
+	assert.Equal(t, map[string]interface{}{"image": "/local/snapshots/front.jpg"}, calls[0].Data["data"])
+	assert.Equal(t, "notify", calls[1].Service)
+	assert.NotContains(t, calls[1].Data, "title")
+	assert.NotContains(t, calls[1].Data, "data")
+}
@@ -0,0 +1,87 @@
+package bedroomcomfort
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComfortSettings holds the bedroom comfort band and adjustment limits
+type ComfortSettings struct {
+	ClimateEntity                string   `yaml:"climate_entity"`
+	TemperatureSensor            string   `yaml:"temperature_sensor"` // Optional, defaults to the climate entity's current_temperature
+	HumiditySensor               string   `yaml:"humidity_sensor"`    // Optional, defaults to the climate entity's current_humidity
+	FanEntity                    string   `yaml:"fan_entity"`         // Optional fan.* or switch.*
+	MinTemperature               float64  `yaml:"min_temperature"`
+	MaxTemperature               float64  `yaml:"max_temperature"`
+	MaxHumidity                  float64  `yaml:"max_humidity"` // 0 = ignore humidity
+	SetpointStep                 float64  `yaml:"setpoint_step"`
+	MaxAdjustmentsPerNight       int      `yaml:"max_adjustments_per_night"`
+	MinAdjustmentIntervalMinutes int      `yaml:"min_adjustment_interval_minutes"`
+	DeferEnergyLevels            []string `yaml:"defer_energy_levels"`
+}
+
+// BedroomComfortConfig represents the bedroom_comfort_config.yaml structure
+type BedroomComfortConfig struct {
+	BedroomComfort ComfortSettings `yaml:"bedroom_comfort"`
+}
+
+// MinAdjustmentInterval returns the minimum time between two adjustments
+func (c *BedroomComfortConfig) MinAdjustmentInterval() time.Duration {
+	return time.Duration(c.BedroomComfort.MinAdjustmentIntervalMinutes) * time.Minute
+}
+
+// ShouldDefer returns true if thermostat adjustments should be skipped at the given energy level
+func (c *BedroomComfortConfig) ShouldDefer(energyLevel string) bool {
+	for _, level := range c.BedroomComfort.DeferEnergyLevels {
+		if level == energyLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the comfort band and limits are usable
+func (c *BedroomComfortConfig) Validate() error {
+	s := c.BedroomComfort
+	if s.ClimateEntity == "" {
+		return fmt.Errorf("climate_entity is required")
+	}
+	if s.MinTemperature >= s.MaxTemperature {
+		return fmt.Errorf("min_temperature (%.1f) must be below max_temperature (%.1f)", s.MinTemperature, s.MaxTemperature)
+	}
+	if s.MaxHumidity < 0 || s.MaxHumidity > 100 {
+		return fmt.Errorf("max_humidity must be between 0 and 100")
+	}
+	if s.SetpointStep <= 0 {
+		return fmt.Errorf("setpoint_step must be positive")
+	}
+	if s.MaxAdjustmentsPerNight <= 0 {
+		return fmt.Errorf("max_adjustments_per_night must be positive")
+	}
+	if s.MinAdjustmentIntervalMinutes < 0 {
+		return fmt.Errorf("min_adjustment_interval_minutes must not be negative")
+	}
+	return nil
+}
+
+// LoadConfig loads the bedroom comfort configuration from a YAML file
+func LoadConfig(path string) (*BedroomComfortConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config BedroomComfortConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
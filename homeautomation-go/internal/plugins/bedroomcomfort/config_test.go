@@ -0,0 +1,88 @@
+package bedroomcomfort
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "bedroom_comfort_config.yaml")
+
+	configContent := `---
+bedroom_comfort:
+  climate_entity: climate.primary_suite_thermostat
+  fan_entity: fan.bedroom
+  min_temperature: 66
+  max_temperature: 70
+  max_humidity: 60
+  setpoint_step: 1
+  max_adjustments_per_night: 4
+  min_adjustment_interval_minutes: 30
+  defer_energy_levels:
+    - black
+    - red
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "climate.primary_suite_thermostat", config.BedroomComfort.ClimateEntity)
+	assert.Equal(t, "fan.bedroom", config.BedroomComfort.FanEntity)
+	assert.Equal(t, 66.0, config.BedroomComfort.MinTemperature)
+	assert.Equal(t, 70.0, config.BedroomComfort.MaxTemperature)
+	assert.Equal(t, 4, config.BedroomComfort.MaxAdjustmentsPerNight)
+	assert.Equal(t, 30*time.Minute, config.MinAdjustmentInterval())
+	assert.True(t, config.ShouldDefer("red"))
+	assert.False(t, config.ShouldDefer("green"))
+}
+
+func TestLoadConfig_FileNotFound(t *testing.T) {
+	_, err := LoadConfig("/nonexistent/bedroom_comfort_config.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_RepoConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/bedroom_comfort_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.BedroomComfort.ClimateEntity)
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *BedroomComfortConfig {
+		return &BedroomComfortConfig{BedroomComfort: ComfortSettings{
+			ClimateEntity:          "climate.bedroom",
+			MinTemperature:         66,
+			MaxTemperature:         70,
+			SetpointStep:           1,
+			MaxAdjustmentsPerNight: 4,
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*BedroomComfortConfig)
+	}{
+		{"missing climate entity", func(c *BedroomComfortConfig) { c.BedroomComfort.ClimateEntity = "" }},
+		{"inverted band", func(c *BedroomComfortConfig) { c.BedroomComfort.MinTemperature = 72 }},
+		{"humidity out of range", func(c *BedroomComfortConfig) { c.BedroomComfort.MaxHumidity = 120 }},
+		{"zero step", func(c *BedroomComfortConfig) { c.BedroomComfort.SetpointStep = 0 }},
+		{"zero cap", func(c *BedroomComfortConfig) { c.BedroomComfort.MaxAdjustmentsPerNight = 0 }},
+		{"negative interval", func(c *BedroomComfortConfig) { c.BedroomComfort.MinAdjustmentIntervalMinutes = -1 }},
+	}
+
+	require.NoError(t, valid().Validate())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			assert.Error(t, config.Validate())
+		})
+	}
+}
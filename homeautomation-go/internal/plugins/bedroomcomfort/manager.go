@@ -0,0 +1,581 @@
+package bedroomcomfort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// EvaluationInterval is how often bedroom conditions are checked while asleep
+const EvaluationInterval = 5 * time.Minute
+
+// errThermostatHeld is returned when kid mode holds the thermostat's setpoint
+var errThermostatHeld = errors.New("thermostat held by kid mode")
+
+// Adjustment actions recorded in the shadow state
+const (
+	ActionFanOn         = "fan_on"
+	ActionFanOff        = "fan_off"
+	ActionLowerSetpoint = "lower_setpoint"
+	ActionRaiseSetpoint = "raise_setpoint"
+	ActionRestore       = "restore"
+)
+
+// night tracks the adjustments made since isMasterAsleep became true
+type night struct {
+	started        time.Time
+	adjustments    int
+	lastAdjustment time.Time
+	fanOn          bool     // fan was turned on by this plugin
+	originalTarget *float64 // thermostat target before the first adjustment
+	target         *float64 // thermostat target last commanded by this plugin
+}
+
+// reading is a snapshot of bedroom conditions
+type reading struct {
+	temperature float64
+	humidity    *float64
+}
+
+// Manager makes small fan and thermostat adjustments overnight to keep the
+// bedroom within a comfort band
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *BedroomComfortConfig
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+	kid          *kidmode.Guard // Leaves the thermostat alone while kid mode holds it, nil if not configured
+
+	// Current night, nil while awake
+	night *night
+	mu    sync.Mutex
+
+	// Control for the periodic evaluation loop
+	stopChan chan struct{}
+
+	// Battery safe mode slows the evaluation loop, nil if not configured
+	safeMode *safemode.Switch
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.BedroomComfortTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Bedroom Comfort manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *BedroomComfortConfig, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	shadowTracker := shadowstate.NewBedroomComfortTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(EvaluationInterval)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("bedroomcomfort")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		stopChan:      make(chan struct{}),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "bedroomcomfort", logger.Named("bedroomcomfort")),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetKidMode sets the kid mode guard. Setpoint changes are skipped while
+// kid mode holds the thermostat; fan adjustments carry on.
+func (m *Manager) SetKidMode(guard *kidmode.Guard) {
+	m.kid = guard
+}
+
+// SetSafeMode sets the battery safe mode switch. While safe mode is on the
+// timer evaluates only every slowdown_factor-th interval.
+func (m *Manager) SetSafeMode(s *safemode.Switch) {
+	m.safeMode = s
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.BedroomComfortShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start begins monitoring bedroom comfort
+func (m *Manager) Start(ctx context.Context) error {
+	// Run under ctx; after Stop stopChan is closed and needs renewing
+	if m.ctx.Err() != nil {
+		m.stopChan = make(chan struct{})
+	}
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Bedroom Comfort Manager",
+		zap.String("climate_entity", m.config.BedroomComfort.ClimateEntity),
+		zap.String("fan_entity", m.config.BedroomComfort.FanEntity),
+		zap.Float64("min_temperature", m.config.BedroomComfort.MinTemperature),
+		zap.Float64("max_temperature", m.config.BedroomComfort.MaxTemperature))
+
+	if err := m.subHelper.SubscribeToState("isMasterAsleep", m.handleMasterAsleepChange); err != nil {
+		return fmt.Errorf("failed to subscribe to isMasterAsleep: %w", err)
+	}
+
+	m.subHelper.CaptureInitialInputs()
+
+	// Pick up a night already in progress (e.g. after a restart)
+	if asleep, err := m.stateManager.GetBool("isMasterAsleep"); err == nil && asleep {
+		m.startNight()
+	}
+
+	go m.runEvaluationLoop(m.stopChan)
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Bedroom Comfort Manager started successfully")
+	return nil
+}
+
+// Stop stops the Bedroom Comfort Manager and cleans up subscriptions
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Bedroom Comfort Manager")
+
+	m.cancel()
+
+	close(m.stopChan)
+	m.subHelper.UnsubscribeAll()
+
+	m.logger.Info("Bedroom Comfort Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-evaluates bedroom conditions immediately, ignoring the minimum
+// interval but not the nightly adjustment cap
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Bedroom Comfort - re-evaluating conditions")
+
+	m.mu.Lock()
+	if m.night != nil {
+		m.night.lastAdjustment = time.Time{}
+	}
+	m.mu.Unlock()
+
+	m.evaluate("reset")
+
+	m.logger.Info("Successfully reset Bedroom Comfort")
+	return nil
+}
+
+// runEvaluationLoop re-evaluates conditions every EvaluationInterval
+func (m *Manager) runEvaluationLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(EvaluationInterval)
+	defer ticker.Stop()
+
+	tick := 0
+	for {
+		select {
+		case <-ticker.C:
+			tick++
+			if !m.safeMode.ShouldEvaluate(tick) {
+				// Skipped on purpose, so /health shouldn't report the loop as stale
+				m.health.Tick()
+				continue
+			}
+			m.evaluate("timer")
+		case <-stop:
+			m.logger.Info("Stopping bedroom comfort evaluation loop")
+			return
+		}
+	}
+}
+
+// handleMasterAsleepChange starts a night when the master bedroom goes to
+// sleep and restores the original settings on wake.
+// HA echoes our own writes with old == new, so compare against the night
+// state rather than oldValue.
+func (m *Manager) handleMasterAsleepChange(key string, oldValue, newValue interface{}) {
+	asleep, ok := newValue.(bool)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	active := m.night != nil
+	m.mu.Unlock()
+
+	switch {
+	case asleep && !active:
+		m.startNight()
+	case !asleep && active:
+		m.endNight()
+	}
+}
+
+// startNight resets the nightly counters and evaluates conditions
+func (m *Manager) startNight() {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	if m.night != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.night = &night{started: now}
+	m.mu.Unlock()
+
+	m.logger.Info("Master asleep, monitoring bedroom comfort",
+		zap.Int("max_adjustments", m.config.BedroomComfort.MaxAdjustmentsPerNight))
+	m.shadowTracker.StartNight(now, m.config.BedroomComfort.MaxAdjustmentsPerNight)
+
+	m.evaluate("isMasterAsleep")
+}
+
+// endNight turns off any fan this plugin turned on and restores the original
+// thermostat target
+func (m *Manager) endNight() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.night
+	m.night = nil
+	if n == nil {
+		return
+	}
+
+	defer m.shadowTracker.EndNight()
+
+	m.logger.Info("Master awake, ending bedroom comfort monitoring",
+		zap.Int("adjustments", n.adjustments))
+
+	if !n.fanOn && (n.originalTarget == nil || n.target == nil || *n.target == *n.originalTarget) {
+		return
+	}
+
+	r, _ := m.readConditions()
+	reason := "Master awake, restoring original settings"
+
+	if n.fanOn {
+		if err := m.setFan(false, reason); err == nil {
+			n.fanOn = false
+		}
+	}
+	if n.originalTarget != nil && n.target != nil && *n.target != *n.originalTarget {
+		if err := m.setTargetTemperature(*n.originalTarget, reason); err == nil {
+			n.target = n.originalTarget
+		}
+	}
+
+	m.recordAdjustment(n, ActionRestore, r, reason, false)
+}
+
+// evaluate reads bedroom conditions and makes at most one adjustment
+func (m *Manager) evaluate(trigger string) {
+	m.health.Tick()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.night
+	if n == nil {
+		return
+	}
+
+	s := m.config.BedroomComfort
+	now := m.clock.Now()
+
+	r, err := m.readConditions()
+	if err != nil {
+		m.logger.Warn("Failed to read bedroom conditions", zap.Error(err))
+		return
+	}
+
+	energyLevel, err := m.stateManager.GetString("currentEnergyLevel")
+	if err != nil {
+		m.logger.Warn("Failed to get currentEnergyLevel, not deferring", zap.Error(err))
+		energyLevel = ""
+	}
+	deferred := m.config.ShouldDefer(energyLevel)
+
+	inputs := map[string]interface{}{
+		"temperature":        r.temperature,
+		"currentEnergyLevel": energyLevel,
+		"trigger":            trigger,
+	}
+	if r.humidity != nil {
+		inputs["humidity"] = *r.humidity
+	}
+	m.shadowTracker.UpdateCurrentInputs(inputs)
+
+	if n.adjustments >= s.MaxAdjustmentsPerNight {
+		m.logger.Debug("Nightly adjustment cap reached",
+			zap.Int("adjustments", n.adjustments))
+		return
+	}
+	if !n.lastAdjustment.IsZero() && now.Sub(n.lastAdjustment) < m.config.MinAdjustmentInterval() {
+		return
+	}
+
+	hasFan := s.FanEntity != ""
+	humid := s.MaxHumidity > 0 && r.humidity != nil && *r.humidity > s.MaxHumidity
+	midpoint := (s.MinTemperature + s.MaxTemperature) / 2
+
+	switch {
+	case r.temperature > s.MaxTemperature:
+		reason := fmt.Sprintf("Temperature %.1f above comfort band (max %.1f)", r.temperature, s.MaxTemperature)
+		if hasFan && !n.fanOn {
+			m.adjustFan(n, true, r, reason)
+		} else {
+			m.adjustSetpoint(n, -s.SetpointStep, r, reason, deferred, energyLevel)
+		}
+
+	case r.temperature < s.MinTemperature:
+		reason := fmt.Sprintf("Temperature %.1f below comfort band (min %.1f)", r.temperature, s.MinTemperature)
+		if n.fanOn {
+			m.adjustFan(n, false, r, reason)
+		} else {
+			m.adjustSetpoint(n, s.SetpointStep, r, reason, deferred, energyLevel)
+		}
+
+	case humid && hasFan && !n.fanOn:
+		m.adjustFan(n, true, r, fmt.Sprintf("Humidity %.0f%% above %.0f%%", *r.humidity, s.MaxHumidity))
+
+	case n.fanOn && !humid && r.temperature <= midpoint:
+		m.adjustFan(n, false, r, fmt.Sprintf("Temperature %.1f back within comfort band", r.temperature))
+	}
+}
+
+// adjustFan switches the bedroom fan and records the adjustment
+func (m *Manager) adjustFan(n *night, on bool, r reading, reason string) {
+	if err := m.setFan(on, reason); err != nil {
+		return
+	}
+	n.fanOn = on
+
+	action := ActionFanOff
+	if on {
+		action = ActionFanOn
+	}
+	m.recordAdjustment(n, action, r, reason, true)
+}
+
+// adjustSetpoint moves the thermostat target by delta, staying within the
+// comfort band, and records the adjustment
+func (m *Manager) adjustSetpoint(n *night, delta float64, r reading, reason string, deferred bool, energyLevel string) {
+	s := m.config.BedroomComfort
+
+	if deferred {
+		m.logger.Debug("Skipping thermostat adjustment due to energy level",
+			zap.String("energy_level", energyLevel),
+			zap.String("reason", reason))
+		return
+	}
+
+	current := n.target
+	if current == nil {
+		target, err := m.readTargetTemperature()
+		if err != nil {
+			m.logger.Warn("Failed to read thermostat target, skipping adjustment", zap.Error(err))
+			return
+		}
+		current = &target
+	}
+
+	target := *current + delta
+	if target < s.MinTemperature {
+		target = s.MinTemperature
+	}
+	if target > s.MaxTemperature {
+		target = s.MaxTemperature
+	}
+	if target == *current {
+		m.logger.Debug("Thermostat target already at comfort band limit",
+			zap.Float64("target", target))
+		return
+	}
+
+	if err := m.setTargetTemperature(target, reason); err != nil {
+		return
+	}
+	if n.originalTarget == nil {
+		original := *current
+		n.originalTarget = &original
+	}
+	n.target = &target
+
+	action := ActionRaiseSetpoint
+	if delta < 0 {
+		action = ActionLowerSetpoint
+	}
+	m.recordAdjustment(n, action, r, reason, true)
+}
+
+// recordAdjustment updates the night counters and logs the adjustment to the shadow state
+func (m *Manager) recordAdjustment(n *night, action string, r reading, reason string, counted bool) {
+	now := m.clock.Now()
+	if counted {
+		n.adjustments++
+		n.lastAdjustment = now
+	}
+
+	adjustment := shadowstate.BedroomComfortAdjustment{
+		Time:        now,
+		Action:      action,
+		Temperature: r.temperature,
+		Humidity:    r.humidity,
+		FanOn:       n.fanOn,
+		Reason:      reason,
+	}
+	if n.target != nil {
+		target := *n.target
+		adjustment.TargetTemperature = &target
+	}
+	m.shadowTracker.RecordAdjustment(adjustment, counted)
+
+	m.logger.Info("Bedroom comfort adjustment",
+		zap.String("action", action),
+		zap.Float64("temperature", r.temperature),
+		zap.Int("adjustments_tonight", n.adjustments),
+		zap.String("reason", reason))
+}
+
+// setFan turns the configured fan on or off
+func (m *Manager) setFan(on bool, reason string) error {
+	entityID := m.config.BedroomComfort.FanEntity
+	service := "turn_off"
+	if on {
+		service = "turn_on"
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would switch bedroom fan",
+			zap.String("entity_id", entityID),
+			zap.String("service", service),
+			zap.String("reason", reason))
+		return nil
+	}
+
+	if err := m.haClient.CallService(m.ctx, ha.EntityDomain(entityID), service, map[string]interface{}{
+		"entity_id": entityID,
+	}); err != nil {
+		m.logger.Error("Failed to switch bedroom fan",
+			zap.String("entity_id", entityID),
+			zap.String("service", service),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// setTargetTemperature sets the thermostat target temperature
+func (m *Manager) setTargetTemperature(target float64, reason string) error {
+	entityID := m.config.BedroomComfort.ClimateEntity
+
+	if m.kid.LocksThermostat(entityID) {
+		m.logger.Info("Skipping bedroom thermostat target: kid mode holds the thermostat",
+			zap.String("entity_id", entityID),
+			zap.Float64("temperature", target),
+			zap.String("reason", reason))
+		return errThermostatHeld
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set bedroom thermostat target",
+			zap.String("entity_id", entityID),
+			zap.Float64("temperature", target),
+			zap.String("reason", reason))
+		return nil
+	}
+
+	if err := m.haClient.CallService(m.ctx, "climate", "set_temperature", map[string]interface{}{
+		"entity_id":   entityID,
+		"temperature": target,
+	}); err != nil {
+		m.logger.Error("Failed to set bedroom thermostat target",
+			zap.String("entity_id", entityID),
+			zap.Float64("temperature", target),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// readConditions reads the bedroom temperature and, if available, humidity
+func (m *Manager) readConditions() (reading, error) {
+	s := m.config.BedroomComfort
+
+	var r reading
+	temperature, err := m.readValue(s.TemperatureSensor, "current_temperature")
+	if err != nil {
+		return r, fmt.Errorf("temperature: %w", err)
+	}
+	r.temperature = temperature
+
+	if humidity, err := m.readValue(s.HumiditySensor, "current_humidity"); err == nil {
+		r.humidity = &humidity
+	}
+
+	return r, nil
+}
+
+// readTargetTemperature reads the thermostat's current target temperature
+func (m *Manager) readTargetTemperature() (float64, error) {
+	return m.readValue("", "temperature")
+}
+
+// readValue reads a numeric sensor state, or the given attribute of the
+// climate entity if no sensor is configured
+func (m *Manager) readValue(sensor, climateAttribute string) (float64, error) {
+	if sensor != "" {
+		st, err := m.haClient.GetState(m.ctx, sensor)
+		if err != nil {
+			return 0, err
+		}
+		return ha.ToFloat(st.State)
+	}
+
+	st, err := m.haClient.GetState(m.ctx, m.config.BedroomComfort.ClimateEntity)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := st.Attributes[climateAttribute]
+	if !ok {
+		return 0, fmt.Errorf("%s has no %s attribute", st.EntityID, climateAttribute)
+	}
+	return ha.ToFloat(value)
+}
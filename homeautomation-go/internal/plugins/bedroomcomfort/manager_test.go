@@ -0,0 +1,253 @@
+package bedroomcomfort
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testClimate = "climate.primary_suite_thermostat"
+	testFan     = "fan.test_bedroom_fan"
+)
+
+func createTestConfig(fanEntity string) *BedroomComfortConfig {
+	return &BedroomComfortConfig{
+		BedroomComfort: ComfortSettings{
+			ClimateEntity:                testClimate,
+			FanEntity:                    fanEntity,
+			MinTemperature:               66,
+			MaxTemperature:               70,
+			MaxHumidity:                  60,
+			SetpointStep:                 1,
+			MaxAdjustmentsPerNight:       2,
+			MinAdjustmentIntervalMinutes: 30,
+			DeferEnergyLevels:            []string{"black", "red"},
+		},
+	}
+}
+
+func setupTest(t *testing.T, config *BedroomComfortConfig, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	manager := NewManager(mockHA, stateManager, config, logger, readOnly, nil)
+	mockClock := clock.NewMockClock(time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	return manager, mockHA, stateManager, mockClock
+}
+
+// setConditions sets the thermostat's current temperature, humidity and target
+func setConditions(mockHA *ha.MockClient, temperature, humidity, target float64) {
+	mockHA.SetState(testClimate, "heat_cool", map[string]interface{}{
+		"current_temperature": temperature,
+		"current_humidity":    humidity,
+		"temperature":         target,
+	})
+}
+
+// setAsleep sets isMasterAsleep and discards the resulting input_boolean service call
+func setAsleep(t *testing.T, mockHA *ha.MockClient, stateManager *state.Manager, asleep bool) {
+	t.Helper()
+	mockHA.ClearServiceCalls()
+	require.NoError(t, stateManager.SetBool("isMasterAsleep", asleep))
+}
+
+// comfortCalls returns service calls made to the fan or thermostat
+func comfortCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var result []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "climate" || call.Domain == "fan" {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+func TestLowersSetpointWhenTooWarm(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(""), false)
+	setConditions(mockHA, 72, 45, 69)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	calls := comfortCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "set_temperature", calls[0].Service)
+	assert.Equal(t, 68.0, calls[0].Data["temperature"])
+
+	shadow := manager.GetShadowState()
+	assert.True(t, shadow.Outputs.NightActive)
+	assert.Equal(t, 1, shadow.Outputs.AdjustmentsTonight)
+	require.Len(t, shadow.Outputs.Adjustments, 1)
+	assert.Equal(t, ActionLowerSetpoint, shadow.Outputs.Adjustments[0].Action)
+}
+
+func TestSetpointStaysWithinComfortBand(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, createTestConfig(""), false)
+	setConditions(mockHA, 72, 45, 66)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	assert.Empty(t, comfortCalls(mockHA), "should not lower the target below min_temperature")
+}
+
+func TestFanPreferredOverThermostat(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(testFan), false)
+	setConditions(mockHA, 72, 45, 69)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	calls := comfortCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "fan", calls[0].Domain)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.True(t, manager.GetShadowState().Outputs.FanOn)
+}
+
+func TestHumidityTurnsFanOn(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, createTestConfig(testFan), false)
+	setConditions(mockHA, 68, 70, 68)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	calls := comfortCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, testFan, calls[0].Data["entity_id"])
+}
+
+func TestMinimumIntervalAndNightlyCap(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(""), false)
+	setConditions(mockHA, 75, 45, 70)
+	setAsleep(t, mockHA, stateManager, true)
+	require.Len(t, comfortCalls(mockHA), 1)
+
+	// Too soon for another adjustment
+	mockClock.Advance(10 * time.Minute)
+	manager.evaluate("timer")
+	assert.Len(t, comfortCalls(mockHA), 1)
+
+	mockClock.Advance(30 * time.Minute)
+	manager.evaluate("timer")
+	calls := comfortCalls(mockHA)
+	require.Len(t, calls, 2)
+	assert.Equal(t, 68.0, calls[1].Data["temperature"])
+
+	// Cap of 2 reached
+	mockClock.Advance(time.Hour)
+	manager.evaluate("timer")
+	assert.Len(t, comfortCalls(mockHA), 2)
+	assert.Equal(t, 2, manager.GetShadowState().Outputs.AdjustmentsTonight)
+}
+
+func TestEnergyDeferralSkipsThermostat(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, createTestConfig(""), false)
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "red"))
+	setConditions(mockHA, 72, 45, 69)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	assert.Empty(t, comfortCalls(mockHA))
+}
+
+func TestKidModeHeldThermostatSkipsSetpoint(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(""), false)
+	manager.SetKidMode(kidmode.NewGuard(&kidmode.Config{KidMode: kidmode.Settings{
+		ToggleVariable:  "isKidMode",
+		Rooms:           []kidmode.Room{{Name: "suite", Thermostats: []string{testClimate}}},
+		LockThermostats: true,
+	}}, stateManager))
+	require.NoError(t, stateManager.SetBool(kidmode.ActiveVariable, true))
+	setConditions(mockHA, 72, 45, 69)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	assert.Empty(t, comfortCalls(mockHA))
+	assert.Equal(t, 0, manager.GetShadowState().Outputs.AdjustmentsTonight)
+}
+
+func TestWakeRestoresOriginalSettings(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(testFan), false)
+	setConditions(mockHA, 72, 45, 69)
+	setAsleep(t, mockHA, stateManager, true)
+
+	// Fan is on; still too warm so the thermostat is lowered next
+	mockClock.Advance(time.Hour)
+	manager.evaluate("timer")
+	require.Len(t, comfortCalls(mockHA), 2)
+
+	mockClock.Advance(6 * time.Hour)
+	setAsleep(t, mockHA, stateManager, false)
+
+	calls := comfortCalls(mockHA)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "turn_off", calls[0].Service)
+	assert.Equal(t, "set_temperature", calls[1].Service)
+	assert.Equal(t, 69.0, calls[1].Data["temperature"])
+
+	shadow := manager.GetShadowState()
+	assert.False(t, shadow.Outputs.NightActive)
+	assert.False(t, shadow.Outputs.FanOn)
+	assert.Equal(t, 2, shadow.Outputs.AdjustmentsTonight)
+	require.Len(t, shadow.Outputs.Adjustments, 3)
+	assert.Equal(t, ActionRestore, shadow.Outputs.Adjustments[2].Action)
+}
+
+func TestNoAdjustmentsWhileAwake(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(testFan), false)
+	setConditions(mockHA, 75, 80, 70)
+	setAsleep(t, mockHA, stateManager, false)
+
+	manager.evaluate("timer")
+
+	assert.Empty(t, comfortCalls(mockHA))
+	assert.False(t, manager.GetShadowState().Outputs.NightActive)
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(""), true)
+	setConditions(mockHA, 72, 45, 69)
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	assert.Empty(t, comfortCalls(mockHA))
+	// Adjustments are still logged so the shadow state shows what would happen
+	assert.Equal(t, 1, manager.GetShadowState().Outputs.AdjustmentsTonight)
+}
+
+func TestNewNightResetsCounters(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(""), false)
+	setConditions(mockHA, 72, 45, 69)
+	setAsleep(t, mockHA, stateManager, true)
+	setAsleep(t, mockHA, stateManager, false)
+
+	mockClock.Advance(24 * time.Hour)
+	setConditions(mockHA, 72, 45, 69)
+	setAsleep(t, mockHA, stateManager, true)
+
+	shadow := manager.GetShadowState()
+	assert.Equal(t, 1, shadow.Outputs.AdjustmentsTonight)
+	require.Len(t, shadow.Outputs.Adjustments, 1)
+}
+
+func TestShadowStateImplementsActionTimeProvider(t *testing.T) {
+	manager, _, _, _ := setupTest(t, createTestConfig(""), false)
+	var _ shadowstate.ActionTimeProvider = manager.GetShadowState()
+}
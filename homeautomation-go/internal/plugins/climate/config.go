@@ -0,0 +1,183 @@
+package climate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPauseAfter is how long a window or door stays open before HVAC is
+// paused when after_minutes isn't set
+const DefaultPauseAfter = 5 * time.Minute
+
+// Comfort modes a thermostat's setpoints are chosen by
+const (
+	ModeHome   = "home"   // Someone home and awake
+	ModeAsleep = "asleep" // Everyone home asleep
+	ModeAway   = "away"   // Nobody home
+)
+
+// Setpoints is the heat/cool range of a heat_cool thermostat
+type Setpoints struct {
+	Low  float64 `yaml:"low"`  // Heats below this temperature
+	High float64 `yaml:"high"` // Cools above this temperature
+}
+
+// Validate checks the range isn't empty
+func (s Setpoints) Validate() error {
+	if s.Low >= s.High {
+		return fmt.Errorf("low must be below high")
+	}
+	return nil
+}
+
+// Thermostat is one thermostat's comfort schedule
+type Thermostat struct {
+	EntityID string    `yaml:"entity_id"`
+	Home     Setpoints `yaml:"home"`
+	Asleep   Setpoints `yaml:"asleep"`
+	Away     Setpoints `yaml:"away"`
+
+	// Replace home for a dayPhase, e.g. a cooler winddown
+	DayPhases map[string]Setpoints `yaml:"day_phases"`
+}
+
+// Target returns the comfort mode and setpoints for the occupancy, sleep and
+// day phase. Away wins over asleep, so an empty house is always set back.
+func (t *Thermostat) Target(anyoneHome, everyoneAsleep bool, dayPhase string) (string, Setpoints) {
+	switch {
+	case !anyoneHome:
+		return ModeAway, t.Away
+	case everyoneAsleep:
+		return ModeAsleep, t.Asleep
+	}
+	if setpoints, ok := t.DayPhases[dayPhase]; ok {
+		return ModeHome, setpoints
+	}
+	return ModeHome, t.Home
+}
+
+// OpenPause pauses HVAC while a window or door stays open
+type OpenPause struct {
+	Sensors      []string `yaml:"sensors"`       // Contact binary sensors, "on" while open
+	Thermostats  []string `yaml:"thermostats"`   // Paused thermostats; the scheduled ones if empty
+	AfterMinutes int      `yaml:"after_minutes"` // How long a sensor is open before pausing
+	Announce     bool     `yaml:"announce"`      // Announce pauses on the owners' speakers
+}
+
+// PauseAfter returns how long a sensor is open before HVAC is paused
+func (p *OpenPause) PauseAfter() time.Duration {
+	if p.AfterMinutes <= 0 {
+		return DefaultPauseAfter
+	}
+	return time.Duration(p.AfterMinutes) * time.Minute
+}
+
+// Settings lists the scheduled thermostats and the open window pause
+type Settings struct {
+	Thermostats []Thermostat `yaml:"thermostats"`
+	OpenPause   *OpenPause   `yaml:"open_pause"` // nil disables pausing
+}
+
+// PausedThermostats returns the thermostats paused while a window or door is
+// open, nil if pausing is off
+func (s *Settings) PausedThermostats() []string {
+	if s.OpenPause == nil {
+		return nil
+	}
+	if len(s.OpenPause.Thermostats) > 0 {
+		return s.OpenPause.Thermostats
+	}
+	thermostats := make([]string, 0, len(s.Thermostats))
+	for _, t := range s.Thermostats {
+		thermostats = append(thermostats, t.EntityID)
+	}
+	return thermostats
+}
+
+// Config represents the climate_config.yaml structure
+type Config struct {
+	Climate Settings `yaml:"climate"`
+}
+
+// Validate checks each thermostat's entity and setpoints, and the open
+// window pause
+func (c *Config) Validate() error {
+	seen := make(map[string]bool)
+	for i, t := range c.Climate.Thermostats {
+		if !strings.HasPrefix(t.EntityID, "climate.") {
+			return fmt.Errorf("thermostats[%d]: entity_id must be a climate entity, got %q", i, t.EntityID)
+		}
+		if seen[t.EntityID] {
+			return fmt.Errorf("thermostats[%d]: duplicate entity_id %q", i, t.EntityID)
+		}
+		seen[t.EntityID] = true
+
+		for _, mode := range []struct {
+			name      string
+			setpoints Setpoints
+		}{{ModeHome, t.Home}, {ModeAsleep, t.Asleep}, {ModeAway, t.Away}} {
+			if err := mode.setpoints.Validate(); err != nil {
+				return fmt.Errorf("thermostat %s: %s: %w", t.EntityID, mode.name, err)
+			}
+		}
+		for phase, setpoints := range t.DayPhases {
+			if err := setpoints.Validate(); err != nil {
+				return fmt.Errorf("thermostat %s: day_phases.%s: %w", t.EntityID, phase, err)
+			}
+		}
+	}
+
+	if c.Climate.OpenPause != nil {
+		if err := c.Climate.OpenPause.validate(); err != nil {
+			return fmt.Errorf("open_pause: %w", err)
+		}
+		if len(c.Climate.PausedThermostats()) == 0 {
+			return fmt.Errorf("open_pause: no thermostats to pause")
+		}
+	}
+	return nil
+}
+
+// validate checks the sensor and thermostat entities
+func (p *OpenPause) validate() error {
+	if len(p.Sensors) == 0 {
+		return fmt.Errorf("sensors is required")
+	}
+	for i, sensor := range p.Sensors {
+		if !strings.HasPrefix(sensor, "binary_sensor.") {
+			return fmt.Errorf("sensors[%d]: must be a binary_sensor entity, got %q", i, sensor)
+		}
+	}
+	for i, thermostat := range p.Thermostats {
+		if !strings.HasPrefix(thermostat, "climate.") {
+			return fmt.Errorf("thermostats[%d]: must be a climate entity, got %q", i, thermostat)
+		}
+	}
+	if p.AfterMinutes < 0 {
+		return fmt.Errorf("after_minutes must not be negative")
+	}
+	return nil
+}
+
+// LoadConfig loads the climate configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
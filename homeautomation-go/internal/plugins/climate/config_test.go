@@ -0,0 +1,130 @@
+package climate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/climate_config.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, config.Climate.Thermostats)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "not a climate entity",
+			yaml:    "climate:\n  thermostats:\n    - entity_id: switch.thermostat\n",
+			wantErr: `thermostats[0]: entity_id must be a climate entity, got "switch.thermostat"`,
+		},
+		{
+			name: "duplicate thermostat",
+			yaml: "climate:\n  thermostats:\n" +
+				"    - {entity_id: climate.house, home: {low: 68, high: 76}, asleep: {low: 64, high: 78}, away: {low: 60, high: 82}}\n" +
+				"    - {entity_id: climate.house, home: {low: 68, high: 76}, asleep: {low: 64, high: 78}, away: {low: 60, high: 82}}\n",
+			wantErr: `thermostats[1]: duplicate entity_id "climate.house"`,
+		},
+		{
+			name:    "missing mode",
+			yaml:    "climate:\n  thermostats:\n    - {entity_id: climate.house, home: {low: 68, high: 76}}\n",
+			wantErr: "thermostat climate.house: asleep: low must be below high",
+		},
+		{
+			name: "empty day phase range",
+			yaml: "climate:\n  thermostats:\n" +
+				"    - {entity_id: climate.house, home: {low: 68, high: 76}, asleep: {low: 64, high: 78}, away: {low: 60, high: 82}, day_phases: {winddown: {low: 74, high: 70}}}\n",
+			wantErr: "thermostat climate.house: day_phases.winddown: low must be below high",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "climate_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTarget(t *testing.T) {
+	thermostat := Thermostat{
+		Home:      Setpoints{Low: 68, High: 76},
+		Asleep:    Setpoints{Low: 64, High: 78},
+		Away:      Setpoints{Low: 60, High: 82},
+		DayPhases: map[string]Setpoints{"winddown": {Low: 67, High: 74}},
+	}
+
+	tests := []struct {
+		name           string
+		anyoneHome     bool
+		everyoneAsleep bool
+		dayPhase       string
+		wantMode       string
+		wantSetpoints  Setpoints
+	}{
+		{"home", true, false, "day", ModeHome, thermostat.Home},
+		{"day phase override", true, false, "winddown", ModeHome, Setpoints{Low: 67, High: 74}},
+		{"asleep wins over day phase", true, true, "winddown", ModeAsleep, thermostat.Asleep},
+		{"away wins over asleep", false, true, "night", ModeAway, thermostat.Away},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, setpoints := thermostat.Target(tt.anyoneHome, tt.everyoneAsleep, tt.dayPhase)
+			assert.Equal(t, tt.wantMode, mode)
+			assert.Equal(t, tt.wantSetpoints, setpoints)
+		})
+	}
+}
+
+func TestOpenPause(t *testing.T) {
+	valid := func() *Config {
+		config := &Config{Climate: Settings{Thermostats: []Thermostat{{
+			EntityID: "climate.house",
+			Home:     Setpoints{Low: 68, High: 76},
+			Asleep:   Setpoints{Low: 64, High: 78},
+			Away:     Setpoints{Low: 60, High: 82},
+		}}}}
+		config.Climate.OpenPause = &OpenPause{Sensors: []string{"binary_sensor.window"}}
+		return config
+	}
+
+	config := valid()
+	require.NoError(t, config.Validate())
+	assert.Equal(t, []string{"climate.house"}, config.Climate.PausedThermostats())
+	assert.Equal(t, DefaultPauseAfter, config.Climate.OpenPause.PauseAfter())
+
+	config.Climate.OpenPause.Thermostats = []string{"climate.suite"}
+	config.Climate.OpenPause.AfterMinutes = 10
+	assert.Equal(t, []string{"climate.suite"}, config.Climate.PausedThermostats())
+	assert.Equal(t, 10*time.Minute, config.Climate.OpenPause.PauseAfter())
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"no sensors", func(c *Config) { c.Climate.OpenPause.Sensors = nil }, "open_pause: sensors is required"},
+		{"not a binary sensor", func(c *Config) { c.Climate.OpenPause.Sensors = []string{"cover.garage_door"} }, `open_pause: sensors[0]: must be a binary_sensor entity, got "cover.garage_door"`},
+		{"not a climate entity", func(c *Config) { c.Climate.OpenPause.Thermostats = []string{"switch.heater"} }, `open_pause: thermostats[0]: must be a climate entity, got "switch.heater"`},
+		{"nothing to pause", func(c *Config) { c.Climate.Thermostats = nil }, "open_pause: no thermostats to pause"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			assert.EqualError(t, config.Validate(), tt.wantErr)
+		})
+	}
+}
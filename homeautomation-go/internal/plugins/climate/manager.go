@@ -0,0 +1,414 @@
+// Package climate runs a comfort schedule on the thermostats. Each
+// thermostat's heat/cool setpoints follow occupancy and sleep: the home
+// setpoints while someone is home and awake, optionally replaced for a day
+// phase, the asleep setpoints once everyone is asleep, and a setback while
+// nobody is home. Load shedding always wins: a thermostat it restricts keeps
+// the shed setpoints until it is released, and the schedule is applied again
+// then. Thermostats held by kid mode are likewise left alone. Setpoints are
+// only written when the scheduled target changes, so a manual adjustment
+// lasts until the next mode or day phase change.
+//
+// Optionally, HVAC is paused while a window or door stays open: once a
+// contact sensor has been open for a few minutes, running thermostats are
+// switched off, with an announcement, and returned to their HVAC mode when
+// every sensor has closed.
+package climate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// Plugins that can hold a thermostat's setpoints
+const (
+	heldByLoadShedding = "loadshedding"
+	heldByKidMode      = "kidmode"
+	heldByOpenPause    = "openpause"
+)
+
+// Shedder reports whether load shedding currently owns a device
+type Shedder interface {
+	Sheds(entityID string) bool
+}
+
+// thermostat is a configured thermostat and the target last applied to it
+type thermostat struct {
+	config  *Thermostat
+	applied *Setpoints // nil until applied, and again while held
+	heldBy  string
+}
+
+// Manager sets thermostat setpoints from occupancy, sleep and the day phase
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *Config
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+	shedder      Shedder        // nil if load shedding isn't wired in
+	kid          *kidmode.Guard // Thermostats held by kid mode keep their setpoints, nil if not configured
+	announcer    *tts.Announcer // Speaks open window pauses, nil speaks on the default speakers
+
+	// Thermostats in config order, and the open window pause: sensors open
+	// now, paused thermostats, and thermostats turned back on while paused,
+	// left alone until every sensor closes (protected by mu)
+	thermostats []*thermostat
+	openSince   map[string]time.Time
+	paused      map[string]*pause
+	overridden  map[string]bool
+	pauseTimer  clock.Timer
+	mu          sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.ClimateTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Climate manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Config, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	shadowTracker := shadowstate.NewClimateTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("climate")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "climate", logger.Named("climate")),
+		openSince:     make(map[string]time.Time),
+		paused:        make(map[string]*pause),
+		overridden:    make(map[string]bool),
+	}
+
+	for i := range config.Climate.Thermostats {
+		t := &config.Climate.Thermostats[i]
+		m.thermostats = append(m.thermostats, &thermostat{config: t})
+		shadowTracker.AddThermostat(t.EntityID)
+	}
+	// Thermostats only paused for open windows are listed after the scheduled ones
+	for _, entityID := range config.Climate.PausedThermostats() {
+		if !m.scheduled(entityID) {
+			shadowTracker.AddThermostat(entityID)
+		}
+	}
+
+	return m
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetLoadShedding sets what reports the thermostats load shedding owns.
+// Those keep their shed setpoints until released.
+func (m *Manager) SetLoadShedding(shedder Shedder) {
+	m.shedder = shedder
+}
+
+// SetKidMode sets the kid mode guard. Thermostats it holds keep their
+// setpoints.
+func (m *Manager) SetKidMode(guard *kidmode.Guard) {
+	m.kid = guard
+}
+
+// SetAnnouncer sets the announcer that speaks open window pauses
+func (m *Manager) SetAnnouncer(announcer *tts.Announcer) {
+	m.announcer = announcer
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.ClimateShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start subscribes to occupancy, sleep, the day phase and the load shedding
+// plan, to each thermostat to track its actual setpoints, and to the open
+// window pause's sensors
+func (m *Manager) Start(ctx context.Context) error {
+	// Thermostat commands run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Climate Manager", zap.Int("thermostats", len(m.thermostats)))
+
+	// The load shedding plan is republished whenever HVAC is restricted or
+	// released, so it doubles as the signal to re-check holds
+	for _, key := range []string{"isAnyoneHome", "isEveryoneAsleep", "dayPhase", "loadSheddingPlan"} {
+		if err := m.subHelper.SubscribeToState(key, m.handleInputChange); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", key, err)
+		}
+	}
+	for _, t := range m.thermostats {
+		entityID := t.config.EntityID
+		if err := m.subHelper.SubscribeToEntity(entityID, func(_ string, _, newState *ha.State) {
+			m.recordActual(entityID, newState)
+			m.handleThermostatChange(entityID, newState)
+		}); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+	}
+
+	m.subHelper.CaptureInitialInputs()
+	m.evaluateAll("startup")
+	if err := m.startOpenPause(); err != nil {
+		return err
+	}
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Climate Manager started successfully")
+	return nil
+}
+
+// Stop unsubscribes. Setpoints and paused thermostats are left as they are.
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Climate Manager")
+
+	m.stopOpenPause()
+	m.cancel()
+	m.subHelper.UnsubscribeAll()
+
+	m.logger.Info("Climate Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset forgets the applied targets and applies the schedule again, and
+// re-reads the open window sensors
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Climate - re-applying thermostat schedule")
+
+	m.mu.Lock()
+	for _, t := range m.thermostats {
+		t.applied = nil
+	}
+	if m.config.Climate.OpenPause != nil {
+		m.readSensors()
+		if len(m.openSince) == 0 {
+			m.resumeAll("reset")
+		} else {
+			m.checkOpen("reset")
+		}
+	}
+	m.mu.Unlock()
+
+	m.evaluateAll("reset")
+
+	m.logger.Info("Successfully reset Climate")
+	return nil
+}
+
+// handleInputChange re-evaluates every thermostat
+func (m *Manager) handleInputChange(key string, _, _ interface{}) {
+	m.evaluateAll(key)
+}
+
+// evaluateAll re-evaluates every thermostat with the current inputs
+func (m *Manager) evaluateAll(trigger string) {
+	m.health.Tick()
+
+	anyoneHome := m.readBool("isAnyoneHome", true)
+	everyoneAsleep := m.readBool("isEveryoneAsleep", false)
+	dayPhase, err := m.stateManager.GetString("dayPhase")
+	if err != nil {
+		m.logger.Warn("Failed to get dayPhase, using home setpoints", zap.Error(err))
+		dayPhase = ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.thermostats {
+		m.evaluate(t, anyoneHome, everyoneAsleep, dayPhase, trigger)
+	}
+}
+
+// evaluate applies the thermostat's scheduled setpoints unless another
+// plugin holds it. Callers must hold mu.
+func (m *Manager) evaluate(t *thermostat, anyoneHome, everyoneAsleep bool, dayPhase, trigger string) {
+	entityID := t.config.EntityID
+	mode, target := t.config.Target(anyoneHome, everyoneAsleep, dayPhase)
+
+	heldBy := m.heldBy(entityID)
+	m.shadowTracker.UpdateTarget(entityID, mode, target.Low, target.High, heldBy)
+
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		m.logger.Warn("Failed to read thermostat",
+			zap.String("entity_id", entityID),
+			zap.Error(err))
+	} else {
+		m.recordActual(entityID, st)
+	}
+
+	if heldBy != "" {
+		if t.heldBy != heldBy {
+			m.logger.Info("⏭  Leaving thermostat setpoints alone",
+				zap.String("entity_id", entityID),
+				zap.String("held_by", heldBy))
+		}
+		// Applied again once released
+		t.heldBy, t.applied = heldBy, nil
+		return
+	}
+	t.heldBy = ""
+
+	if t.applied != nil && *t.applied == target {
+		return
+	}
+
+	reason := fmt.Sprintf("%s: %s (%s)", mode, modeReason(mode, dayPhase, t.config), trigger)
+
+	if st != nil {
+		low, lowOK := numericAttribute(st.Attributes["target_temp_low"])
+		high, highOK := numericAttribute(st.Attributes["target_temp_high"])
+		if lowOK && highOK && low == target.Low && high == target.High {
+			m.logger.Debug("Thermostat already at target",
+				zap.String("entity_id", entityID),
+				zap.String("mode", mode))
+			t.applied = &target
+			return
+		}
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set thermostat setpoints",
+			zap.String("entity_id", entityID),
+			zap.Float64("target_temp_low", target.Low),
+			zap.Float64("target_temp_high", target.High),
+			zap.String("reason", reason))
+	} else {
+		if err := m.haClient.CallService(m.ctx, "climate", "set_temperature", map[string]interface{}{
+			"entity_id":        entityID,
+			"target_temp_low":  target.Low,
+			"target_temp_high": target.High,
+		}); err != nil {
+			m.logger.Error("Failed to set thermostat setpoints",
+				zap.String("entity_id", entityID),
+				zap.Error(err))
+			return
+		}
+		m.logger.Info("✓ Set thermostat setpoints",
+			zap.String("entity_id", entityID),
+			zap.Float64("target_temp_low", target.Low),
+			zap.Float64("target_temp_high", target.High),
+			zap.String("reason", reason))
+	}
+
+	t.applied = &target
+	m.shadowTracker.RecordChange(entityID, reason, m.clock.Now())
+}
+
+// heldBy returns what holds a thermostat's setpoints, "" if nothing. Load
+// shedding wins over kid mode, and both over an open window pause. Callers
+// must hold mu.
+func (m *Manager) heldBy(entityID string) string {
+	_, paused := m.paused[entityID]
+	switch {
+	case m.shedder != nil && m.shedder.Sheds(entityID):
+		return heldByLoadShedding
+	case m.kid.LocksThermostat(entityID):
+		return heldByKidMode
+	case paused:
+		return heldByOpenPause
+	}
+	return ""
+}
+
+// modeReason describes why a comfort mode applies
+func modeReason(mode, dayPhase string, t *Thermostat) string {
+	switch mode {
+	case ModeAway:
+		return "nobody home"
+	case ModeAsleep:
+		return "everyone asleep"
+	}
+	if _, ok := t.DayPhases[dayPhase]; ok {
+		return "someone home, " + dayPhase
+	}
+	return "someone home"
+}
+
+// recordActual records the setpoints a thermostat reports
+func (m *Manager) recordActual(entityID string, st *ha.State) {
+	if st == nil {
+		return
+	}
+	var low, high *float64
+	if value, ok := numericAttribute(st.Attributes["target_temp_low"]); ok {
+		low = &value
+	}
+	if value, ok := numericAttribute(st.Attributes["target_temp_high"]); ok {
+		high = &value
+	}
+	m.shadowTracker.UpdateActual(entityID, low, high)
+}
+
+// readBool reads a boolean state variable, fallback if it can't be read
+func (m *Manager) readBool(key string, fallback bool) bool {
+	value, err := m.stateManager.GetBool(key)
+	if err != nil {
+		m.logger.Warn("Failed to get "+key+", assuming default",
+			zap.Bool("default", fallback),
+			zap.Error(err))
+		return fallback
+	}
+	return value
+}
+
+// numericAttribute reads a numeric attribute, which may be reported as a
+// number or a string
+func numericAttribute(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,171 @@
+package climate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testThermostat = "climate.most_of_house_thermostat"
+
+// fakeShedder sheds the thermostats it is told to
+type fakeShedder struct {
+	mu   sync.Mutex
+	shed map[string]bool
+}
+
+func (f *fakeShedder) Sheds(entityID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.shed[entityID]
+}
+
+func (f *fakeShedder) set(entityID string, shed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shed[entityID] = shed
+}
+
+func createTestConfig() *Config {
+	return &Config{Climate: Settings{Thermostats: []Thermostat{{
+		EntityID:  testThermostat,
+		Home:      Setpoints{Low: 68, High: 76},
+		Asleep:    Setpoints{Low: 64, High: 78},
+		Away:      Setpoints{Low: 60, High: 82},
+		DayPhases: map[string]Setpoints{"winddown": {Low: 67, High: 74}},
+	}}}}
+}
+
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *fakeShedder) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", false))
+	require.NoError(t, stateManager.SetString("dayPhase", "day"))
+
+	mockHA.SetState(testThermostat, "heat_cool", map[string]interface{}{
+		"target_temp_low":  70.0,
+		"target_temp_high": 75.0,
+	})
+
+	shedder := &fakeShedder{shed: make(map[string]bool)}
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, readOnly, nil)
+	manager.SetLoadShedding(shedder)
+
+	mockHA.ClearServiceCalls()
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	return manager, mockHA, stateManager, shedder
+}
+
+// setpointCalls describes the set_temperature calls made, in order
+func setpointCalls(mockHA *ha.MockClient) []string {
+	var calls []string
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "climate" && call.Service == "set_temperature" {
+			calls = append(calls, fmt.Sprintf("%v-%v", call.Data["target_temp_low"], call.Data["target_temp_high"]))
+		}
+	}
+	return calls
+}
+
+func TestFollowsOccupancyAndSleep(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false)
+	assert.Equal(t, []string{"68-76"}, setpointCalls(mockHA))
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+	assert.Equal(t, []string{"68-76", "67-74", "64-78", "60-82"}, setpointCalls(mockHA))
+
+	status := manager.GetShadowState().Outputs.Thermostats[0]
+	assert.Equal(t, ModeAway, status.Mode)
+	assert.Equal(t, 60.0, status.TargetLow)
+	assert.Equal(t, 82.0, status.TargetHigh)
+	assert.Equal(t, "away: nobody home (isAnyoneHome)", status.LastReason)
+	assert.Equal(t, testThermostat+": away: nobody home (isAnyoneHome)", manager.GetShadowState().Outputs.LastActionReason)
+}
+
+func TestManualChangeLastsUntilTargetChanges(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false)
+	mockHA.ClearServiceCalls()
+
+	mockHA.SetState(testThermostat, "heat_cool", map[string]interface{}{
+		"target_temp_low":  71.0,
+		"target_temp_high": 75.0,
+	})
+	// An unrelated day phase change keeps the same target
+	require.NoError(t, stateManager.SetString("dayPhase", "sunset"))
+	assert.Empty(t, setpointCalls(mockHA))
+
+	status := manager.GetShadowState().Outputs.Thermostats[0]
+	require.NotNil(t, status.ActualLow)
+	assert.Equal(t, 71.0, *status.ActualLow)
+	assert.Equal(t, 68.0, status.TargetLow)
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+	assert.Equal(t, []string{"64-78"}, setpointCalls(mockHA))
+}
+
+func TestAlreadyAtTarget(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, false)
+	mockHA.SetState(testThermostat, "heat_cool", map[string]interface{}{
+		"target_temp_low":  64.0,
+		"target_temp_high": 78.0,
+	})
+	mockHA.ClearServiceCalls()
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	assert.Empty(t, setpointCalls(mockHA))
+}
+
+func TestLoadSheddingWins(t *testing.T) {
+	manager, mockHA, stateManager, shedder := setupTest(t, false)
+	mockHA.ClearServiceCalls()
+
+	shedder.set(testThermostat, true)
+	require.NoError(t, stateManager.SetJSON("loadSheddingPlan", map[string]interface{}{"tier": "hvac_restricted"}))
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+	assert.Empty(t, setpointCalls(mockHA))
+
+	status := manager.GetShadowState().Outputs.Thermostats[0]
+	assert.Equal(t, "loadshedding", status.HeldBy)
+	assert.Equal(t, ModeAway, status.Mode)
+
+	// Released: the schedule is applied again
+	shedder.set(testThermostat, false)
+	require.NoError(t, stateManager.SetJSON("loadSheddingPlan", map[string]interface{}{"tier": "normal"}))
+	assert.Equal(t, []string{"60-82"}, setpointCalls(mockHA))
+	assert.Empty(t, manager.GetShadowState().Outputs.Thermostats[0].HeldBy)
+}
+
+func TestReadOnly(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, true)
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	assert.Empty(t, setpointCalls(mockHA))
+	assert.Equal(t, "asleep: everyone asleep (isEveryoneAsleep)", manager.GetShadowState().Outputs.Thermostats[0].LastReason)
+}
+
+func TestReset(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false)
+	mockHA.ClearServiceCalls()
+
+	require.NoError(t, manager.Reset())
+
+	assert.Equal(t, []string{"68-76"}, setpointCalls(mockHA))
+}
@@ -0,0 +1,393 @@
+package climate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// pause is a thermostat switched off for an open window or door
+type pause struct {
+	mode  string // HVAC mode restored on resume
+	since time.Time
+}
+
+// startOpenPause watches the window and door sensors and the thermostats
+// paused for them that aren't scheduled, then pauses for anything already
+// open long enough
+func (m *Manager) startOpenPause() error {
+	openPause := m.config.Climate.OpenPause
+	if openPause == nil {
+		return nil
+	}
+
+	for _, sensor := range openPause.Sensors {
+		if err := m.subHelper.SubscribeToEntity(sensor, m.handleSensorChange); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", sensor, err)
+		}
+	}
+	for _, entityID := range m.config.Climate.PausedThermostats() {
+		if m.scheduled(entityID) {
+			continue
+		}
+		if err := m.subHelper.SubscribeToEntity(entityID, func(entityID string, _, newState *ha.State) {
+			m.handleThermostatChange(entityID, newState)
+		}); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.readSensors()
+	changed := m.checkOpen("startup")
+	m.mu.Unlock()
+
+	if changed {
+		m.evaluateAll("startup")
+	}
+	return nil
+}
+
+// stopOpenPause cancels the pending open sensor check
+func (m *Manager) stopOpenPause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pauseTimer != nil {
+		m.pauseTimer.Stop()
+		m.pauseTimer = nil
+	}
+}
+
+// scheduled reports whether a thermostat has a comfort schedule
+func (m *Manager) scheduled(entityID string) bool {
+	for _, t := range m.thermostats {
+		if t.config.EntityID == entityID {
+			return true
+		}
+	}
+	return false
+}
+
+// readSensors reads which sensors are open, keeping the open time of those
+// already known to be open. Callers must hold mu.
+func (m *Manager) readSensors() {
+	now := m.clock.Now()
+	openSince := make(map[string]time.Time)
+	for _, sensor := range m.config.Climate.OpenPause.Sensors {
+		st, err := m.haClient.GetState(m.ctx, sensor)
+		if err != nil {
+			m.logger.Warn("Failed to read window sensor",
+				zap.String("entity_id", sensor),
+				zap.Error(err))
+			continue
+		}
+		if st.State != "on" {
+			continue
+		}
+		switch since, ok := m.openSince[sensor]; {
+		case ok:
+			openSince[sensor] = since
+		case !st.LastChanged.IsZero() && st.LastChanged.Before(now):
+			openSince[sensor] = st.LastChanged
+		default:
+			openSince[sensor] = now
+		}
+	}
+	m.openSince = openSince
+	m.recordOpenSensors()
+}
+
+// handleSensorChange tracks a window or door opening and closing. Paused
+// thermostats resume once every sensor is closed.
+func (m *Manager) handleSensorChange(entityID string, _, newState *ha.State) {
+	open := newState != nil && newState.State == "on"
+
+	m.mu.Lock()
+	_, wasOpen := m.openSince[entityID]
+	if open == wasOpen {
+		m.mu.Unlock()
+		return
+	}
+
+	var changed bool
+	if open {
+		m.openSince[entityID] = m.clock.Now()
+		m.recordOpenSensors()
+		changed = m.checkOpen(entityID)
+	} else {
+		delete(m.openSince, entityID)
+		m.recordOpenSensors()
+		if len(m.openSince) == 0 {
+			changed = m.resumeAll(entityID)
+		} else {
+			changed = m.checkOpen(entityID)
+		}
+	}
+	m.mu.Unlock()
+
+	if changed {
+		m.evaluateAll(entityID)
+	}
+}
+
+// handleThermostatChange pauses a thermostat that starts running while a
+// window is open. One turned back on while paused was turned on by someone,
+// so it is left alone until every sensor closes.
+func (m *Manager) handleThermostatChange(entityID string, newState *ha.State) {
+	if m.config.Climate.OpenPause == nil || !m.pausable(entityID) || newState == nil {
+		return
+	}
+
+	m.mu.Lock()
+	var changed bool
+	if _, ok := m.paused[entityID]; !ok {
+		changed = m.checkOpen(entityID)
+	} else if newState.State != "off" {
+		delete(m.paused, entityID)
+		m.overridden[entityID] = true
+		m.logger.Info("Paused thermostat turned back on, leaving it on",
+			zap.String("entity_id", entityID),
+			zap.String("hvac_mode", newState.State))
+		m.shadowTracker.RecordPause(entityID, "", time.Time{}, "turned back on while paused", m.clock.Now())
+		changed = true
+	}
+	m.mu.Unlock()
+
+	if changed {
+		m.evaluateAll(entityID)
+	}
+}
+
+// pausable reports whether a thermostat is paused for open windows
+func (m *Manager) pausable(entityID string) bool {
+	for _, thermostat := range m.config.Climate.PausedThermostats() {
+		if thermostat == entityID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOpen pauses the running thermostats once a sensor has been open long
+// enough, or schedules a check for when one will have been. It reports
+// whether a thermostat was paused. Callers must hold mu.
+func (m *Manager) checkOpen(trigger string) bool {
+	if m.pauseTimer != nil {
+		m.pauseTimer.Stop()
+		m.pauseTimer = nil
+	}
+
+	after := m.config.Climate.OpenPause.PauseAfter()
+	now := m.clock.Now()
+
+	// Sensors are sorted longest open first, so the first one is the one
+	// to pause for, or the next to become due
+	sensors := m.sortedOpenSensors()
+	if len(sensors) == 0 {
+		return false
+	}
+	opened := sensors[0]
+	if since := m.openSince[opened]; now.Sub(since) < after {
+		m.pauseTimer = m.clock.AfterFunc(since.Add(after).Sub(now), m.handlePauseTimer)
+		return false
+	}
+
+	var changed bool
+	for _, entityID := range m.config.Climate.PausedThermostats() {
+		if _, ok := m.paused[entityID]; ok || m.overridden[entityID] {
+			continue
+		}
+		if m.pauseThermostat(entityID, opened, trigger) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// handlePauseTimer checks the open sensors once one has been open long enough
+func (m *Manager) handlePauseTimer() {
+	m.mu.Lock()
+	m.pauseTimer = nil
+	changed := m.checkOpen("open_timer")
+	m.mu.Unlock()
+
+	if changed {
+		m.evaluateAll("open_timer")
+	}
+}
+
+// pauseThermostat switches a running thermostat off, remembering its HVAC
+// mode, and announces why. Thermostats load shedding owns are left to it.
+// Callers must hold mu.
+func (m *Manager) pauseThermostat(entityID, sensor, trigger string) bool {
+	if m.shedder != nil && m.shedder.Sheds(entityID) {
+		m.logger.Debug("Not pausing thermostat: owned by load shedding",
+			zap.String("entity_id", entityID))
+		return false
+	}
+
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		m.logger.Warn("Failed to read thermostat, not pausing it",
+			zap.String("entity_id", entityID),
+			zap.Error(err))
+		return false
+	}
+	if !running(st) {
+		return false
+	}
+
+	name := m.friendlyName(sensor)
+	reason := fmt.Sprintf("paused: %s open (%s)", name, trigger)
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would pause thermostat",
+			zap.String("entity_id", entityID),
+			zap.String("sensor", sensor),
+			zap.String("hvac_mode", st.State))
+	} else {
+		if err := m.setHVACMode(entityID, "off"); err != nil {
+			m.logger.Error("Failed to pause thermostat",
+				zap.String("entity_id", entityID),
+				zap.Error(err))
+			return false
+		}
+		m.logger.Info("✓ Paused thermostat for open window",
+			zap.String("entity_id", entityID),
+			zap.String("sensor", sensor),
+			zap.String("hvac_mode", st.State))
+	}
+
+	now := m.clock.Now()
+	m.paused[entityID] = &pause{mode: st.State, since: now}
+	m.shadowTracker.RecordPause(entityID, st.State, now, reason, now)
+	m.announcePause(name)
+	return true
+}
+
+// resumeAll returns every paused thermostat to its HVAC mode. It reports
+// whether any was resumed. Callers must hold mu.
+func (m *Manager) resumeAll(trigger string) bool {
+	m.overridden = make(map[string]bool)
+
+	entityIDs := make([]string, 0, len(m.paused))
+	for entityID := range m.paused {
+		entityIDs = append(entityIDs, entityID)
+	}
+	sort.Strings(entityIDs)
+
+	for _, entityID := range entityIDs {
+		p := m.paused[entityID]
+		if m.readOnly {
+			m.logger.Info("READ-ONLY: Would resume thermostat",
+				zap.String("entity_id", entityID),
+				zap.String("hvac_mode", p.mode))
+		} else {
+			if err := m.setHVACMode(entityID, p.mode); err != nil {
+				m.logger.Error("Failed to resume thermostat",
+					zap.String("entity_id", entityID),
+					zap.Error(err))
+				continue
+			}
+			m.logger.Info("✓ Resumed thermostat, windows closed",
+				zap.String("entity_id", entityID),
+				zap.String("hvac_mode", p.mode),
+				zap.Duration("paused_for", m.clock.Since(p.since)))
+		}
+		delete(m.paused, entityID)
+		m.shadowTracker.RecordPause(entityID, "", time.Time{}, fmt.Sprintf("resumed %s: windows closed (%s)", p.mode, trigger), m.clock.Now())
+	}
+	return len(entityIDs) > 0
+}
+
+// setHVACMode sets a thermostat's HVAC mode
+func (m *Manager) setHVACMode(entityID, mode string) error {
+	return m.haClient.CallService(m.ctx, "climate", "set_hvac_mode", map[string]interface{}{
+		"entity_id": entityID,
+		"hvac_mode": mode,
+	})
+}
+
+// announcePause tells the owners HVAC is paused. Announcements skipped for
+// quiet hours don't matter; the pause happens anyway.
+func (m *Manager) announcePause(sensorName string) {
+	if !m.config.Climate.OpenPause.Announce {
+		return
+	}
+	message := fmt.Sprintf("Pausing the heating and cooling: %s is open.", sensorName)
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce HVAC pause", zap.String("message", message))
+		return
+	}
+
+	speakers, err := m.announcer.Speakers(m.haClient, tts.TargetOwners)
+	if err != nil {
+		m.logger.Warn("Failed to resolve speakers for HVAC pause", zap.Error(err))
+		return
+	}
+	err = m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Title:    "HVAC paused",
+		Message:  message,
+		Speakers: speakers,
+	})
+	if err != nil && !errors.Is(err, tts.ErrQuietHours) {
+		m.logger.Warn("Failed to announce HVAC pause", zap.Error(err))
+	}
+}
+
+// recordOpenSensors records the open sensors, longest open first. Callers
+// must hold mu.
+func (m *Manager) recordOpenSensors() {
+	sensors := make([]shadowstate.ClimateOpenSensor, 0, len(m.openSince))
+	for _, sensor := range m.sortedOpenSensors() {
+		sensors = append(sensors, shadowstate.ClimateOpenSensor{EntityID: sensor, OpenSince: m.openSince[sensor]})
+	}
+	m.shadowTracker.UpdateOpenSensors(sensors)
+}
+
+// sortedOpenSensors returns the open sensors, longest open first. Callers
+// must hold mu.
+func (m *Manager) sortedOpenSensors() []string {
+	sensors := make([]string, 0, len(m.openSince))
+	for sensor := range m.openSince {
+		sensors = append(sensors, sensor)
+	}
+	sort.Slice(sensors, func(i, j int) bool {
+		a, b := m.openSince[sensors[i]], m.openSince[sensors[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return sensors[i] < sensors[j]
+	})
+	return sensors
+}
+
+// friendlyName returns a sensor's friendly name, or its entity ID
+func (m *Manager) friendlyName(entityID string) string {
+	if st, err := m.haClient.GetState(m.ctx, entityID); err == nil {
+		if name, ok := st.Attributes["friendly_name"].(string); ok && name != "" {
+			return name
+		}
+	}
+	return entityID
+}
+
+// running reports whether a thermostat's HVAC is on and, if it reports what
+// it's doing, not idle
+func running(st *ha.State) bool {
+	switch st.State {
+	case "off", "unavailable", "unknown", "":
+		return false
+	}
+	if action, ok := st.Attributes["hvac_action"].(string); ok {
+		return action != "idle" && action != "off"
+	}
+	return true
+}
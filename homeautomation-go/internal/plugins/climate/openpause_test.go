@@ -0,0 +1,209 @@
+package climate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testWindow = "binary_sensor.primary_suite_window"
+	testDoor   = "binary_sensor.back_door"
+)
+
+func setupOpenPauseTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *clock.MockClock, *fakeShedder) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", false))
+
+	setThermostat(mockHA, "heat_cool", "heating")
+	setSensor(mockHA, testWindow, "off")
+	setSensor(mockHA, testDoor, "off")
+
+	config := createTestConfig()
+	config.Climate.OpenPause = &OpenPause{Sensors: []string{testWindow, testDoor}, AfterMinutes: 5}
+
+	shedder := &fakeShedder{shed: make(map[string]bool)}
+	manager := NewManager(mockHA, stateManager, config, logger, readOnly, nil)
+	manager.SetLoadShedding(shedder)
+	mockClock := clock.NewMockClock(time.Date(2025, 7, 1, 14, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+	mockHA.ClearServiceCalls()
+
+	return manager, mockHA, mockClock, shedder
+}
+
+// setSensor opens or closes a window sensor
+func setSensor(mockHA *ha.MockClient, entityID, state string) {
+	mockHA.SetState(entityID, state, map[string]interface{}{"friendly_name": map[string]string{
+		testWindow: "Primary suite window",
+		testDoor:   "Back door",
+	}[entityID]})
+}
+
+// setThermostat sets the thermostat's HVAC mode and action, at the home
+// setpoints so the schedule leaves it alone
+func setThermostat(mockHA *ha.MockClient, mode, action string) {
+	mockHA.SetState(testThermostat, mode, map[string]interface{}{
+		"target_temp_low":  68.0,
+		"target_temp_high": 76.0,
+		"hvac_action":      action,
+	})
+}
+
+// hvacModeCalls returns the HVAC modes set, in order
+func hvacModeCalls(mockHA *ha.MockClient) []string {
+	var calls []string
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "climate" && call.Service == "set_hvac_mode" {
+			calls = append(calls, call.Data["hvac_mode"].(string))
+		}
+	}
+	return calls
+}
+
+func TestOpenPause_PausesAfterDelayAndResumesWhenAllClosed(t *testing.T) {
+	manager, mockHA, mockClock, _ := setupOpenPauseTest(t, false)
+
+	setSensor(mockHA, testWindow, "on")
+	mockClock.Advance(4 * time.Minute)
+	setSensor(mockHA, testDoor, "on")
+	assert.Empty(t, hvacModeCalls(mockHA))
+
+	mockClock.Advance(time.Minute)
+	assert.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+	setThermostat(mockHA, "off", "off")
+
+	shadow := manager.GetShadowState()
+	status := shadow.Outputs.Thermostats[0]
+	assert.Equal(t, "openpause", status.HeldBy)
+	assert.Equal(t, "heat_cool", status.PausedMode)
+	assert.Equal(t, mockClock.Now(), status.PausedSince)
+	assert.Equal(t, "paused: Primary suite window open (open_timer)", status.LastReason)
+	require.Len(t, shadow.Outputs.OpenSensors, 2)
+	assert.Equal(t, testWindow, shadow.Outputs.OpenSensors[0].EntityID)
+
+	// Still paused while the door is open
+	setSensor(mockHA, testWindow, "off")
+	assert.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+
+	setSensor(mockHA, testDoor, "off")
+	assert.Equal(t, []string{"off", "heat_cool"}, hvacModeCalls(mockHA))
+	status = manager.GetShadowState().Outputs.Thermostats[0]
+	assert.Empty(t, status.HeldBy)
+	assert.True(t, status.PausedSince.IsZero())
+	assert.Equal(t, "resumed heat_cool: windows closed ("+testDoor+")", status.LastReason)
+}
+
+func TestOpenPause_OpenedBriefly(t *testing.T) {
+	_, mockHA, mockClock, _ := setupOpenPauseTest(t, false)
+
+	setSensor(mockHA, testWindow, "on")
+	mockClock.Advance(2 * time.Minute)
+	setSensor(mockHA, testWindow, "off")
+	mockClock.Advance(10 * time.Minute)
+
+	assert.Empty(t, hvacModeCalls(mockHA))
+}
+
+func TestOpenPause_IdleThermostatPausedWhenItStarts(t *testing.T) {
+	_, mockHA, mockClock, _ := setupOpenPauseTest(t, false)
+	setThermostat(mockHA, "heat_cool", "idle")
+
+	setSensor(mockHA, testWindow, "on")
+	mockClock.Advance(10 * time.Minute)
+	assert.Empty(t, hvacModeCalls(mockHA))
+
+	setThermostat(mockHA, "heat_cool", "cooling")
+	assert.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+}
+
+func TestOpenPause_TurnedBackOnIsLeftAlone(t *testing.T) {
+	manager, mockHA, mockClock, _ := setupOpenPauseTest(t, false)
+
+	setSensor(mockHA, testWindow, "on")
+	mockClock.Advance(5 * time.Minute)
+	require.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+
+	setThermostat(mockHA, "heat", "heating")
+	setSensor(mockHA, testDoor, "on")
+	mockClock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+	assert.Equal(t, "turned back on while paused", manager.GetShadowState().Outputs.Thermostats[0].LastReason)
+
+	// Nothing to resume once everything closes
+	setSensor(mockHA, testWindow, "off")
+	setSensor(mockHA, testDoor, "off")
+	assert.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+}
+
+func TestOpenPause_LoadSheddingWins(t *testing.T) {
+	_, mockHA, mockClock, shedder := setupOpenPauseTest(t, false)
+	shedder.set(testThermostat, true)
+
+	setSensor(mockHA, testWindow, "on")
+	mockClock.Advance(5 * time.Minute)
+
+	assert.Empty(t, hvacModeCalls(mockHA))
+}
+
+func TestOpenPause_ReadOnly(t *testing.T) {
+	manager, mockHA, mockClock, _ := setupOpenPauseTest(t, true)
+
+	setSensor(mockHA, testWindow, "on")
+	mockClock.Advance(5 * time.Minute)
+
+	assert.Empty(t, hvacModeCalls(mockHA))
+	assert.Equal(t, "openpause", manager.GetShadowState().Outputs.Thermostats[0].HeldBy)
+}
+
+func TestOpenPause_OpenAtStartup(t *testing.T) {
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	setThermostat(mockHA, "cool", "cooling")
+	setSensor(mockHA, testWindow, "on")
+
+	config := createTestConfig()
+	config.Climate.OpenPause = &OpenPause{Sensors: []string{testWindow}, AfterMinutes: 5}
+	manager := NewManager(mockHA, stateManager, config, logger, false, nil)
+	mockClock := clock.NewMockClock(time.Now().Add(time.Hour))
+	manager.SetClock(mockClock)
+
+	// Open for an hour by the mock clock already
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	assert.Equal(t, []string{"off"}, hvacModeCalls(mockHA))
+}
+
+func TestOpenPause_Announces(t *testing.T) {
+	manager, mockHA, mockClock, _ := setupOpenPauseTest(t, false)
+	manager.config.Climate.OpenPause.Announce = true
+
+	setSensor(mockHA, testDoor, "on")
+	mockClock.Advance(5 * time.Minute)
+
+	var messages []string
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "tts" && call.Service == "speak" {
+			messages = append(messages, call.Data["message"].(string))
+		}
+	}
+	assert.Equal(t, []string{"Pausing the heating and cooling: Back door is open."}, messages)
+}
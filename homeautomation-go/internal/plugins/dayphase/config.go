@@ -0,0 +1,110 @@
+package dayphase
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxOffsetMinutes bounds curve offsets so a shifted night can't cross midnight
+// or a shifted winddown land in the afternoon
+const maxOffsetMinutes = 120
+
+// CurvePoint maps an outdoor temperature to a timing offset
+type CurvePoint struct {
+	Temperature   float64 `yaml:"temperature"`
+	OffsetMinutes float64 `yaml:"offset_minutes"` // Negative = earlier, positive = later
+}
+
+// TemperatureCurve is a piecewise-linear temperature -> offset curve. Points
+// must be in increasing temperature order; temperatures outside the curve use
+// the nearest end point's offset.
+type TemperatureCurve []CurvePoint
+
+// Offset returns the interpolated offset for the given temperature, rounded
+// to the minute. An empty curve gives no offset.
+func (c TemperatureCurve) Offset(temperature float64) time.Duration {
+	if len(c) == 0 {
+		return 0
+	}
+
+	minutes := c[len(c)-1].OffsetMinutes
+	if temperature <= c[0].Temperature {
+		minutes = c[0].OffsetMinutes
+	} else {
+		for i := 1; i < len(c); i++ {
+			if temperature > c[i].Temperature {
+				continue
+			}
+			lo, hi := c[i-1], c[i]
+			fraction := (temperature - lo.Temperature) / (hi.Temperature - lo.Temperature)
+			minutes = lo.OffsetMinutes + fraction*(hi.OffsetMinutes-lo.OffsetMinutes)
+			break
+		}
+	}
+
+	return time.Duration(math.Round(minutes)) * time.Minute
+}
+
+// validate checks point order and offset bounds
+func (c TemperatureCurve) validate(name string) error {
+	for i, point := range c {
+		if math.Abs(point.OffsetMinutes) > maxOffsetMinutes {
+			return fmt.Errorf("winddown_temperature: %s point %d offset_minutes %.0f exceeds ±%d", name, i, point.OffsetMinutes, maxOffsetMinutes)
+		}
+		if i > 0 && point.Temperature <= c[i-1].Temperature {
+			return fmt.Errorf("winddown_temperature: %s temperatures must be strictly increasing (point %d)", name, i)
+		}
+	}
+	return nil
+}
+
+// WinddownTemperatureSettings shifts evening phase boundaries by outdoor temperature
+type WinddownTemperatureSettings struct {
+	OutdoorTemperatureSensor string           `yaml:"outdoor_temperature_sensor"`
+	WinddownCurve            TemperatureCurve `yaml:"winddown_curve"` // Shifts the dusk -> winddown boundary
+	NightCurve               TemperatureCurve `yaml:"night_curve"`    // Shifts the winddown -> night boundary
+}
+
+// TemperatureShiftConfig represents the winddown_temperature_config.yaml structure
+type TemperatureShiftConfig struct {
+	WinddownTemperature WinddownTemperatureSettings `yaml:"winddown_temperature"`
+}
+
+// Validate checks the sensor entity and both curves
+func (c *TemperatureShiftConfig) Validate() error {
+	settings := c.WinddownTemperature
+	if !strings.HasPrefix(settings.OutdoorTemperatureSensor, "sensor.") {
+		return fmt.Errorf("winddown_temperature: outdoor_temperature_sensor %q must be a sensor entity", settings.OutdoorTemperatureSensor)
+	}
+	if len(settings.WinddownCurve) == 0 && len(settings.NightCurve) == 0 {
+		return fmt.Errorf("winddown_temperature: at least one of winddown_curve or night_curve is required")
+	}
+	if err := settings.WinddownCurve.validate("winddown_curve"); err != nil {
+		return err
+	}
+	return settings.NightCurve.validate("night_curve")
+}
+
+// LoadConfig loads the winddown temperature configuration from a YAML file
+func LoadConfig(path string) (*TemperatureShiftConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config TemperatureShiftConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -1,12 +1,14 @@
 package dayphase
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"homeautomation/internal/config"
 	dayphaselib "homeautomation/internal/dayphase"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -35,6 +37,13 @@ type Manager struct {
 
 	// Shadow state tracking
 	shadowTracker *shadowstate.DayPhaseTracker
+
+	// Optional outdoor-temperature shift of the evening phases
+	temperatureShift *TemperatureShiftConfig
+	heldShift        *shadowstate.TemperatureShift
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new Day Phase manager
@@ -46,12 +55,15 @@ func NewManager(
 	logger *zap.Logger,
 	readOnly bool,
 ) *Manager {
+	recorder := health.NewRecorder(5 * time.Minute)
+
 	return &Manager{
 		haClient:      haClient,
 		stateManager:  stateManager,
 		configLoader:  configLoader,
 		calculator:    calculator,
-		logger:        logger.Named("dayphase"),
+		logger:        recorder.Logger(logger.Named("dayphase")),
+		health:        recorder,
 		readOnly:      readOnly,
 		stopChan:      make(chan struct{}),
 		stoppedChan:   make(chan struct{}),
@@ -66,7 +78,7 @@ func (m *Manager) GetShadowState() *shadowstate.DayPhaseShadowState {
 }
 
 // Start begins monitoring and updating day phase variables
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
 	m.logger.Info("Starting Day Phase Manager")
 
 	// Start periodic sun time updates (every 6 hours)
@@ -77,18 +89,20 @@ func (m *Manager) Start() error {
 		return fmt.Errorf("failed to do initial day phase update: %w", err)
 	}
 
-	// Start periodic update goroutine (every 5 minutes)
-	go m.periodicUpdate()
+	// Start periodic update goroutine (every 5 minutes), until Stop or ctx ends it
+	go m.periodicUpdate(ctx)
 
 	// Mark as started
 	m.started = true
 
+	m.health.Started(len(m.subscriptions))
 	m.logger.Info("Day Phase Manager started successfully")
 	return nil
 }
 
 // Stop stops the Day Phase Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping Day Phase Manager")
 
 	// Only stop if started
@@ -117,8 +131,13 @@ func (m *Manager) Stop() {
 	m.logger.Info("Day Phase Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // periodicUpdate runs every 5 minutes to update sun event and day phase
-func (m *Manager) periodicUpdate() {
+func (m *Manager) periodicUpdate(ctx context.Context) {
 	defer close(m.stoppedChan)
 
 	ticker := time.NewTicker(5 * time.Minute)
@@ -129,11 +148,17 @@ func (m *Manager) periodicUpdate() {
 		case <-ticker.C:
 			if err := m.updateSunEventAndDayPhase(); err != nil {
 				m.logger.Error("Failed to update sun event and day phase", zap.Error(err))
+			} else {
+				m.health.Tick()
 			}
 
 		case <-m.stopChan:
 			m.logger.Info("Stopping periodic day phase updates")
 			return
+
+		case <-ctx.Done():
+			m.logger.Info("Stopping periodic day phase updates")
+			return
 		}
 	}
 }
@@ -180,9 +205,6 @@ func (m *Manager) updateSunEventAndDayPhase() error {
 		schedule = nil
 	}
 
-	dayPhase := m.calculator.CalculateDayPhase(schedule)
-	dayPhaseStr := string(dayPhase)
-
 	// Get current dayPhase value from state
 	currentDayPhase, err := m.stateManager.GetString("dayPhase")
 	if err != nil {
@@ -190,6 +212,10 @@ func (m *Manager) updateSunEventAndDayPhase() error {
 		currentDayPhase = ""
 	}
 
+	winddownShift, nightShift := m.currentTemperatureShift(currentDayPhase)
+	dayPhase := m.calculator.CalculateDayPhaseShifted(schedule, winddownShift, nightShift)
+	dayPhaseStr := string(dayPhase)
+
 	// Update dayPhase if it changed
 	if currentDayPhase != dayPhaseStr {
 		m.logger.Info("Day phase changed",
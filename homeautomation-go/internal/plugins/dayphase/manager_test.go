@@ -1,6 +1,7 @@
 package dayphase
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -40,7 +41,7 @@ func TestManagerStartStop(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, configLoader, calculator, logger, false)
 
 	// Start the manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Give it a moment to start
@@ -122,7 +123,7 @@ func TestManagerPeriodicUpdate(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, configLoader, calculator, logger, false)
 
 	// Start the manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Let it run for a short time
@@ -152,7 +153,7 @@ func TestManagerWithDifferentCoordinates(t *testing.T) {
 
 	manager := NewManager(mockClient, stateManager, configLoader, calculator, logger, false)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Give it time to calculate
@@ -245,7 +246,7 @@ func TestManagerReset(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, configLoader, calculator, logger, false)
 
 	// Start the manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
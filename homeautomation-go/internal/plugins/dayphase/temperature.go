@@ -0,0 +1,74 @@
+package dayphase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	dayphaselib "homeautomation/internal/dayphase"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// temperatureReadTimeout bounds the outdoor temperature read on each update
+const temperatureReadTimeout = 5 * time.Second
+
+// SetTemperatureShift enables shifting the winddown and night boundaries by
+// the outdoor temperature: earlier on cold nights, later on hot evenings
+func (m *Manager) SetTemperatureShift(config *TemperatureShiftConfig) {
+	m.temperatureShift = config
+}
+
+// currentTemperatureShift returns the winddown and night offsets to apply.
+// Once the evening has reached winddown the offsets are held until morning,
+// so a temperature change can't move the phase back to dusk or winddown.
+func (m *Manager) currentTemperatureShift(currentDayPhase string) (time.Duration, time.Duration) {
+	if m.temperatureShift == nil {
+		return 0, 0
+	}
+
+	evening := currentDayPhase == string(dayphaselib.DayPhaseWinddown) || currentDayPhase == string(dayphaselib.DayPhaseNight)
+	if evening && m.heldShift != nil {
+		held := *m.heldShift
+		held.Held = true
+		m.shadowTracker.UpdateTemperatureShift(held)
+		return time.Duration(held.WinddownOffsetMinutes) * time.Minute, time.Duration(held.NightOffsetMinutes) * time.Minute
+	}
+
+	settings := m.temperatureShift.WinddownTemperature
+	shift := shadowstate.TemperatureShift{ComputedAt: time.Now()}
+
+	temperature, err := m.readOutdoorTemperature(settings.OutdoorTemperatureSensor)
+	if err != nil {
+		m.logger.Warn("Outdoor temperature unavailable, not shifting evening phases",
+			zap.String("sensor", settings.OutdoorTemperatureSensor),
+			zap.Error(err))
+	} else {
+		shift.OutdoorTemperature = temperature
+		shift.TemperatureAvailable = true
+		shift.WinddownOffsetMinutes = int(settings.WinddownCurve.Offset(temperature) / time.Minute)
+		shift.NightOffsetMinutes = int(settings.NightCurve.Offset(temperature) / time.Minute)
+	}
+
+	m.logger.Debug("Outdoor temperature phase shift",
+		zap.Float64("temperature", shift.OutdoorTemperature),
+		zap.Int("winddown_offset_minutes", shift.WinddownOffsetMinutes),
+		zap.Int("night_offset_minutes", shift.NightOffsetMinutes))
+	m.heldShift = &shift
+	m.shadowTracker.UpdateTemperatureShift(shift)
+
+	return time.Duration(shift.WinddownOffsetMinutes) * time.Minute, time.Duration(shift.NightOffsetMinutes) * time.Minute
+}
+
+// readOutdoorTemperature reads the sensor's numeric state from Home Assistant
+func (m *Manager) readOutdoorTemperature(entityID string) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), temperatureReadTimeout)
+	defer cancel()
+
+	sensorState, err := m.haClient.GetState(ctx, entityID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(sensorState.State, 64)
+}
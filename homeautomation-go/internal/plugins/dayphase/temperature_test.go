@@ -0,0 +1,158 @@
+package dayphase
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/config"
+	dayphaselib "homeautomation/internal/dayphase"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTemperatureShiftTestConfig() *TemperatureShiftConfig {
+	return &TemperatureShiftConfig{
+		WinddownTemperature: WinddownTemperatureSettings{
+			OutdoorTemperatureSensor: "sensor.weather_station_temperature",
+			WinddownCurve: TemperatureCurve{
+				{Temperature: 20, OffsetMinutes: -45},
+				{Temperature: 40, OffsetMinutes: 0},
+				{Temperature: 85, OffsetMinutes: 0},
+				{Temperature: 100, OffsetMinutes: 30},
+			},
+			NightCurve: TemperatureCurve{
+				{Temperature: 20, OffsetMinutes: -30},
+				{Temperature: 40, OffsetMinutes: 0},
+			},
+		},
+	}
+}
+
+func newTemperatureShiftTestManager(t *testing.T) (*Manager, *ha.MockClient) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	configLoader := config.NewLoader("../../../configs", logger)
+	calculator := dayphaselib.NewCalculator(32.85486, -97.50515, logger)
+
+	manager := NewManager(mockClient, stateManager, configLoader, calculator, logger, false)
+	manager.SetTemperatureShift(newTemperatureShiftTestConfig())
+	return manager, mockClient
+}
+
+func TestTemperatureCurve_Offset(t *testing.T) {
+	curve := newTemperatureShiftTestConfig().WinddownTemperature.WinddownCurve
+
+	tests := []struct {
+		name        string
+		temperature float64
+		expected    time.Duration
+	}{
+		{name: "Below the curve uses the first point", temperature: 5, expected: -45 * time.Minute},
+		{name: "Interpolates between points", temperature: 30, expected: -23 * time.Minute},
+		{name: "Flat mild range", temperature: 60, expected: 0},
+		{name: "Hot evening is later", temperature: 95, expected: 20 * time.Minute},
+		{name: "Above the curve uses the last point", temperature: 110, expected: 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, curve.Offset(tt.temperature))
+		})
+	}
+
+	assert.Equal(t, time.Duration(0), TemperatureCurve(nil).Offset(10), "empty curve should not shift")
+}
+
+func TestTemperatureShiftConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *WinddownTemperatureSettings)
+		wantErr bool
+	}{
+		{name: "Valid", modify: func(s *WinddownTemperatureSettings) {}},
+		{name: "Night curve only", modify: func(s *WinddownTemperatureSettings) { s.WinddownCurve = nil }},
+		{name: "Not a sensor", modify: func(s *WinddownTemperatureSettings) { s.OutdoorTemperatureSensor = "weather.home" }, wantErr: true},
+		{name: "No curves", modify: func(s *WinddownTemperatureSettings) { s.WinddownCurve, s.NightCurve = nil, nil }, wantErr: true},
+		{name: "Unordered points", modify: func(s *WinddownTemperatureSettings) { s.NightCurve[1].Temperature = 10 }, wantErr: true},
+		{name: "Offset too large", modify: func(s *WinddownTemperatureSettings) { s.WinddownCurve[0].OffsetMinutes = -180 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTemperatureShiftTestConfig()
+			tt.modify(&config.WinddownTemperature)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCurrentTemperatureShift_ColdNight(t *testing.T) {
+	manager, mockClient := newTemperatureShiftTestManager(t)
+	mockClient.SetState("sensor.weather_station_temperature", "15", nil)
+
+	winddown, night := manager.currentTemperatureShift("dusk")
+
+	assert.Equal(t, -45*time.Minute, winddown)
+	assert.Equal(t, -30*time.Minute, night)
+
+	shift := manager.GetShadowState().Outputs.TemperatureShift
+	if assert.NotNil(t, shift) {
+		assert.True(t, shift.TemperatureAvailable)
+		assert.Equal(t, 15.0, shift.OutdoorTemperature)
+		assert.Equal(t, -45, shift.WinddownOffsetMinutes)
+		assert.Equal(t, -30, shift.NightOffsetMinutes)
+		assert.False(t, shift.Held)
+	}
+}
+
+func TestCurrentTemperatureShift_HeldAfterWinddown(t *testing.T) {
+	manager, mockClient := newTemperatureShiftTestManager(t)
+	mockClient.SetState("sensor.weather_station_temperature", "15", nil)
+	manager.currentTemperatureShift("dusk")
+
+	// Warming after winddown started must not move the phase back to dusk
+	mockClient.SetState("sensor.weather_station_temperature", "60", nil)
+	winddown, night := manager.currentTemperatureShift("winddown")
+
+	assert.Equal(t, -45*time.Minute, winddown)
+	assert.Equal(t, -30*time.Minute, night)
+	assert.True(t, manager.GetShadowState().Outputs.TemperatureShift.Held)
+
+	// The next morning recomputes from the current temperature
+	winddown, night = manager.currentTemperatureShift("morning")
+	assert.Equal(t, time.Duration(0), winddown)
+	assert.Equal(t, time.Duration(0), night)
+}
+
+func TestCurrentTemperatureShift_SensorUnavailable(t *testing.T) {
+	manager, mockClient := newTemperatureShiftTestManager(t)
+	mockClient.SetState("sensor.weather_station_temperature", "unavailable", nil)
+
+	winddown, night := manager.currentTemperatureShift("dusk")
+
+	assert.Equal(t, time.Duration(0), winddown)
+	assert.Equal(t, time.Duration(0), night)
+	shift := manager.GetShadowState().Outputs.TemperatureShift
+	if assert.NotNil(t, shift) {
+		assert.False(t, shift.TemperatureAvailable)
+	}
+}
+
+func TestCurrentTemperatureShift_NotConfigured(t *testing.T) {
+	manager, _ := newTemperatureShiftTestManager(t)
+	manager.SetTemperatureShift(nil)
+
+	winddown, night := manager.currentTemperatureShift("dusk")
+
+	assert.Equal(t, time.Duration(0), winddown)
+	assert.Equal(t, time.Duration(0), night)
+	assert.Nil(t, manager.GetShadowState().Outputs.TemperatureShift)
+}
@@ -0,0 +1,163 @@
+package emergency
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/notifyrouter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Detector kinds
+const (
+	KindSmoke = "smoke"
+	KindCO    = "co"
+)
+
+// Defaults for settings left out of the config
+const (
+	DefaultAnnounceInterval     = 30 * time.Second
+	DefaultAcknowledgeVariable  = "isEmergencyAcknowledged"
+	DefaultLightColorTempKelvin = 5000
+)
+
+// Detector is a smoke or carbon monoxide alarm, "on" while it detects
+type Detector struct {
+	EntityID string `yaml:"entity_id"`
+	Name     string `yaml:"name"` // Spoken in the announcement, e.g. "kitchen"
+	Kind     string `yaml:"kind"` // "smoke" or "co"
+}
+
+// Label returns the detector's name, falling back to its entity ID
+func (d *Detector) Label() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.EntityID
+}
+
+// Hazard returns how the detector's kind is spoken
+func (d *Detector) Hazard() string {
+	if d.Kind == KindCO {
+		return "carbon monoxide"
+	}
+	return "smoke"
+}
+
+// Settings lists the detectors and the response to them
+type Settings struct {
+	Detectors []Detector `yaml:"detectors"`
+
+	// Response actions, entity IDs or entity group references. Lights and
+	// media players turn on or stop every entity in the domain when empty.
+	Lights       []string `yaml:"lights"`        // Turned on at full brightness, white
+	Unlock       []string `yaml:"unlock"`        // Locks opened so nobody is trapped
+	MediaPlayers []string `yaml:"media_players"` // Music stopped so the announcements can be heard
+	GarageDoor   string   `yaml:"garage_door"`   // Cover opened as a way out; none if empty
+
+	// Repeated evacuation announcement
+	Speakers                []string            `yaml:"speakers"`                  // Media players or entity group references; the security speakers if empty
+	TTSEntity               string              `yaml:"tts_entity"`                // The announcer's TTS entity if empty
+	Volume                  float64             `yaml:"volume"`                    // 0-1, raised before each announcement; unchanged if 0
+	AnnounceIntervalSeconds int                 `yaml:"announce_interval_seconds"` // Between announcements
+	AcknowledgeVariable     string              `yaml:"acknowledge_variable"`      // Boolean state variable that stops the announcements
+	NotifyService           notifyrouter.Target `yaml:"notify_service"`            // e.g. "notify.notify"; empty sends no push
+
+	KeepRunning []string `yaml:"keep_running"` // Plugins left running during an emergency; every other plugin is suspended
+}
+
+// AnnounceInterval returns the time between announcements
+func (s *Settings) AnnounceInterval() time.Duration {
+	if s.AnnounceIntervalSeconds <= 0 {
+		return DefaultAnnounceInterval
+	}
+	return time.Duration(s.AnnounceIntervalSeconds) * time.Second
+}
+
+// AcknowledgeKey returns the state variable that stops the announcements
+func (s *Settings) AcknowledgeKey() string {
+	if s.AcknowledgeVariable == "" {
+		return DefaultAcknowledgeVariable
+	}
+	return s.AcknowledgeVariable
+}
+
+// Config represents the emergency_config.yaml structure
+type Config struct {
+	Emergency Settings `yaml:"emergency"`
+}
+
+// Validate checks the detectors and the response entities
+func (c *Config) Validate() error {
+	s := &c.Emergency
+	if len(s.Detectors) == 0 {
+		return fmt.Errorf("detectors is required")
+	}
+	seen := make(map[string]bool)
+	for i, d := range s.Detectors {
+		if !strings.HasPrefix(d.EntityID, "binary_sensor.") {
+			return fmt.Errorf("detectors[%d]: entity_id must be a binary_sensor entity, got %q", i, d.EntityID)
+		}
+		if seen[d.EntityID] {
+			return fmt.Errorf("detectors[%d]: duplicate entity_id %q", i, d.EntityID)
+		}
+		seen[d.EntityID] = true
+		if d.Kind != KindSmoke && d.Kind != KindCO {
+			return fmt.Errorf("detectors[%d]: kind must be %q or %q, got %q", i, KindSmoke, KindCO, d.Kind)
+		}
+	}
+
+	for _, list := range []struct {
+		name, domain string
+		entities     []string
+	}{
+		{"lights", "light", s.Lights},
+		{"unlock", "lock", s.Unlock},
+		{"media_players", "media_player", s.MediaPlayers},
+		{"speakers", "media_player", s.Speakers},
+	} {
+		for i, entityID := range list.entities {
+			if !strings.HasPrefix(entityID, list.domain+".") && !entitygroups.IsReference(entityID) {
+				return fmt.Errorf("%s[%d]: must be a %s entity or entity group reference, got %q", list.name, i, list.domain, entityID)
+			}
+		}
+	}
+	if s.GarageDoor != "" && !strings.HasPrefix(s.GarageDoor, "cover.") {
+		return fmt.Errorf("garage_door must be a cover entity, got %q", s.GarageDoor)
+	}
+	if s.Volume < 0 || s.Volume > 1 {
+		return fmt.Errorf("volume must be between 0 and 1")
+	}
+	if s.AnnounceIntervalSeconds < 0 {
+		return fmt.Errorf("announce_interval_seconds must not be negative")
+	}
+	if s.NotifyService != "" {
+		if _, _, ok := s.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("invalid notify_service %q (expected domain.service)", s.NotifyService)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the emergency configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
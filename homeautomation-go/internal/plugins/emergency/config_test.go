@@ -0,0 +1,86 @@
+package emergency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/emergency_config.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, config.Emergency.Detectors)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "no detectors",
+			yaml:    "emergency:\n  unlock: [lock.front_door]\n",
+			wantErr: "detectors is required",
+		},
+		{
+			name:    "not a binary sensor",
+			yaml:    "emergency:\n  detectors:\n    - {entity_id: sensor.kitchen_smoke, kind: smoke}\n",
+			wantErr: `detectors[0]: entity_id must be a binary_sensor entity, got "sensor.kitchen_smoke"`,
+		},
+		{
+			name: "duplicate detector",
+			yaml: "emergency:\n  detectors:\n" +
+				"    - {entity_id: binary_sensor.kitchen_smoke, kind: smoke}\n" +
+				"    - {entity_id: binary_sensor.kitchen_smoke, kind: co}\n",
+			wantErr: `detectors[1]: duplicate entity_id "binary_sensor.kitchen_smoke"`,
+		},
+		{
+			name:    "unknown kind",
+			yaml:    "emergency:\n  detectors:\n    - {entity_id: binary_sensor.kitchen_smoke, kind: fire}\n",
+			wantErr: `detectors[0]: kind must be "smoke" or "co", got "fire"`,
+		},
+		{
+			name:    "not a lock",
+			yaml:    "emergency:\n  detectors:\n    - {entity_id: binary_sensor.kitchen_smoke, kind: smoke}\n  unlock: [switch.front_door]\n",
+			wantErr: `unlock[0]: must be a lock entity or entity group reference, got "switch.front_door"`,
+		},
+		{
+			name:    "not a cover",
+			yaml:    "emergency:\n  detectors:\n    - {entity_id: binary_sensor.kitchen_smoke, kind: smoke}\n  garage_door: switch.garage\n",
+			wantErr: `garage_door must be a cover entity, got "switch.garage"`,
+		},
+		{
+			name:    "volume out of range",
+			yaml:    "emergency:\n  detectors:\n    - {entity_id: binary_sensor.kitchen_smoke, kind: smoke}\n  volume: 1.5\n",
+			wantErr: "volume must be between 0 and 1",
+		},
+		{
+			name:    "invalid notify service",
+			yaml:    "emergency:\n  detectors:\n    - {entity_id: binary_sensor.kitchen_smoke, kind: smoke}\n  notify_service: notify\n",
+			wantErr: `invalid notify_service "notify" (expected domain.service)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "emergency_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSettingsDefaults(t *testing.T) {
+	var s Settings
+	assert.Equal(t, DefaultAnnounceInterval, s.AnnounceInterval())
+	assert.Equal(t, DefaultAcknowledgeVariable, s.AcknowledgeKey())
+
+	s = Settings{AnnounceIntervalSeconds: 10, AcknowledgeVariable: "isStormWatch"}
+	assert.Equal(t, 10*time.Second, s.AnnounceInterval())
+	assert.Equal(t, "isStormWatch", s.AcknowledgeKey())
+}
@@ -0,0 +1,661 @@
+// Package emergency responds to smoke and carbon monoxide alarms. When the
+// first detector trips, every light goes to full white brightness, the
+// configured doors are unlocked and the garage opened, music stops, and an
+// evacuation announcement repeats until the detectors clear or someone sets
+// the acknowledge variable. Another detector tripping re-arms the
+// announcements.
+//
+// The emergency takes priority over every other plugin: listeners registered
+// with OnChange (wired in main) suspend the other plugins while it lasts, so
+// none of them dims the lights, locks the doors or restarts the music.
+// Announcements are spoken with tts.speak directly, bypassing the
+// announcement queue, quiet hours and the notification router's night-time
+// chime/push downgrade.
+package emergency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// ActiveVariable is the local-only variable that is true during an emergency
+const ActiveVariable = "isEmergencyActive"
+
+// NotificationTitle is the title of the emergency push notifications
+const NotificationTitle = "Emergency"
+
+// Response actions recorded in shadow state
+const (
+	actionLights   = "lights"
+	actionUnlock   = "unlock"
+	actionMusic    = "stop_music"
+	actionGarage   = "open_garage"
+	actionNotify   = "notify"
+	actionAnnounce = "announce"
+)
+
+// Status describes the current emergency
+type Status struct {
+	Active      bool
+	Reason      string   // Detectors that are on, e.g. "smoke in kitchen"
+	KeepRunning []string // Lifecycle plugin names left running while active
+	Since       time.Time
+}
+
+// detector is a configured detector and what it last reported
+type detector struct {
+	config   *Detector
+	detected bool
+	since    time.Time
+}
+
+// Manager runs the emergency response while any detector is on
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *Config
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+	announcer    *tts.Announcer // Supplies the security speakers and TTS entity, nil uses the defaults
+
+	// Detectors in config order and the current emergency (protected by mu)
+	detectors     []*detector
+	active        bool
+	since         time.Time
+	reason        string
+	acknowledged  bool
+	announceTimer clock.Timer
+	listeners     []func(Status)
+	mu            sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.EmergencyTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests (replaced
+	// by Start under mu)
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Goroutines started by Start, waited for by Stop
+	background sync.WaitGroup
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Emergency manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Config, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	shadowTracker := shadowstate.NewEmergencyTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("emergency")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "emergency", logger.Named("emergency")),
+	}
+
+	for i := range config.Emergency.Detectors {
+		m.detectors = append(m.detectors, &detector{config: &config.Emergency.Detectors[i]})
+	}
+	m.publishDetectorsLocked()
+
+	return m
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetAnnouncer sets the announcer whose security speakers and TTS entity are
+// used when the config doesn't name its own
+func (m *Manager) SetAnnouncer(announcer *tts.Announcer) {
+	m.announcer = announcer
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.EmergencyShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Status returns the current emergency
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.statusLocked()
+}
+
+// OnChange registers fn to be called after an emergency starts or ends.
+// Listeners are called without the manager's lock held, so they may read it.
+func (m *Manager) OnChange(fn func(Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// RecordSuspendedPlugins records the plugins suspended for the emergency in
+// shadow state
+func (m *Manager) RecordSuspendedPlugins(plugins []string) {
+	m.shadowTracker.UpdateSuspendedPlugins(plugins)
+}
+
+// Start subscribes to the detectors and the acknowledge variable, and starts
+// the response if a detector is already on
+func (m *Manager) Start(ctx context.Context) error {
+	// The response runs under ctx until Stop cancels it
+	m.mu.Lock()
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.mu.Unlock()
+
+	m.logger.Info("Starting Emergency Manager", zap.Int("detectors", len(m.detectors)))
+
+	for _, d := range m.detectors {
+		entityID := d.config.EntityID
+		if err := m.subHelper.SubscribeToEntity(entityID, func(_ string, _, newState *ha.State) {
+			m.handleDetectorChange(entityID, newState)
+		}); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+	}
+	acknowledgeKey := m.config.Emergency.AcknowledgeKey()
+	if err := m.subHelper.SubscribeToState(acknowledgeKey, m.handleAcknowledgeChange); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", acknowledgeKey, err)
+	}
+
+	m.subHelper.CaptureInitialInputs()
+	m.readDetectors()
+	if m.evaluate("startup", false) {
+		// Start runs under the plugin lifecycle's lock, which the listeners
+		// take to suspend and resume plugins
+		m.goBackground(m.notify)
+	} else {
+		// Announcements stopped by Stop pick up again
+		m.announce()
+	}
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Emergency Manager started successfully")
+	return nil
+}
+
+// Stop stops the announcements and unsubscribes. The plugin lifecycle won't
+// disable or suspend the manager while an emergency is active (see Busy), so
+// only shutdown stops it with other plugins still suspended.
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Emergency Manager")
+
+	m.mu.Lock()
+	m.stopAnnouncementsLocked()
+	m.cancel()
+	m.mu.Unlock()
+
+	m.subHelper.UnsubscribeAll()
+	m.background.Wait()
+
+	m.logger.Info("Emergency Manager stopped")
+}
+
+// Busy refuses to let the plugin be disabled or suspended while an emergency
+// is active, since the other plugins it suspended would stay suspended
+// (implements lifecycle.Busy)
+func (m *Manager) Busy() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active {
+		return fmt.Errorf("emergency active: %s", m.reason)
+	}
+	return nil
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// goBackground runs f in a goroutine that Stop waits for
+func (m *Manager) goBackground(f func()) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		f()
+	}()
+}
+
+// Reset re-reads the detectors, starting or ending the emergency to match
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Emergency - re-reading detectors")
+
+	m.readDetectors()
+	if m.evaluate("reset", false) {
+		m.notify()
+	}
+
+	m.logger.Info("Successfully reset Emergency")
+	return nil
+}
+
+// handleDetectorChange records a detector's new state and starts, re-arms or
+// ends the emergency
+func (m *Manager) handleDetectorChange(entityID string, newState *ha.State) {
+	m.health.Tick()
+
+	detected := newState != nil && newState.State == "on"
+
+	m.mu.Lock()
+	d := m.detector(entityID)
+	if d == nil || d.detected == detected {
+		m.mu.Unlock()
+		return
+	}
+	d.detected = detected
+	if detected {
+		d.since = m.clock.Now()
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Detector changed",
+		zap.String("entity_id", entityID),
+		zap.String("kind", d.config.Kind),
+		zap.Bool("detected", detected))
+
+	if m.evaluate(entityID, detected) {
+		m.notify()
+	}
+}
+
+// handleAcknowledgeChange stops the announcements when the acknowledge
+// variable is set, and restarts them if it is cleared while still active
+func (m *Manager) handleAcknowledgeChange(key string, _, newValue interface{}) {
+	acknowledged, ok := newValue.(bool)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	if !m.active || m.acknowledged == acknowledged {
+		m.mu.Unlock()
+		return
+	}
+	m.acknowledged = acknowledged
+	if acknowledged {
+		m.stopAnnouncementsLocked()
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Emergency announcements acknowledged",
+		zap.String("variable", key),
+		zap.Bool("acknowledged", acknowledged))
+	m.shadowTracker.RecordAcknowledged(acknowledged, m.clock.Now())
+
+	if !acknowledged {
+		m.announce()
+	}
+}
+
+// readDetectors reads each detector's state from Home Assistant
+func (m *Manager) readDetectors() {
+	for _, d := range m.detectors {
+		st, err := m.haClient.GetState(m.ctx, d.config.EntityID)
+		if err != nil {
+			m.logger.Warn("Failed to read detector",
+				zap.String("entity_id", d.config.EntityID),
+				zap.Error(err))
+			continue
+		}
+		detected := st != nil && st.State == "on"
+
+		m.mu.Lock()
+		if detected && !d.detected {
+			d.since = m.clock.Now()
+		}
+		d.detected = detected
+		m.mu.Unlock()
+	}
+}
+
+// evaluate starts the emergency when a detector is on and it isn't active,
+// and ends it when every detector is clear. A detector tripping during an
+// emergency re-arms acknowledged announcements. Reports whether the
+// emergency started or ended, in which case the caller notifies listeners.
+func (m *Manager) evaluate(trigger string, tripped bool) bool {
+	m.mu.Lock()
+	m.publishDetectorsLocked()
+	reason := m.reasonLocked()
+	now := m.clock.Now()
+
+	switch {
+	case reason != "" && !m.active:
+		m.active, m.since, m.reason, m.acknowledged = true, now, reason, false
+		m.mu.Unlock()
+		m.startResponse(reason, trigger, now)
+		return true
+
+	case reason != "" && tripped:
+		m.reason = reason
+		rearmed := m.acknowledged
+		m.acknowledged = false
+		m.mu.Unlock()
+		m.logger.Warn("🔥 Another detector tripped during the emergency", zap.String("reason", reason))
+		if rearmed {
+			m.shadowTracker.RecordAcknowledged(false, now)
+			m.setBool(m.config.Emergency.AcknowledgeKey(), false)
+		}
+		m.announce()
+		return false
+
+	case reason == "" && m.active:
+		m.active, m.since, m.reason, m.acknowledged = false, now, "", false
+		m.stopAnnouncementsLocked()
+		m.mu.Unlock()
+		m.endResponse(trigger, now)
+		return true
+	}
+
+	m.mu.Unlock()
+	return false
+}
+
+// startResponse publishes the emergency and runs the response actions
+func (m *Manager) startResponse(reason, trigger string, now time.Time) {
+	m.logger.Error("🔥 EMERGENCY: detector tripped, starting emergency response",
+		zap.String("reason", reason),
+		zap.String("trigger", trigger))
+
+	m.setBool(ActiveVariable, true)
+	m.setBool(m.config.Emergency.AcknowledgeKey(), false)
+	m.shadowTracker.RecordActivation(true, "emergency: "+reason, now)
+
+	s := &m.config.Emergency
+	m.callService(actionLights, "light", "turn_on", map[string]interface{}{
+		"entity_id":         entitiesOrAll(s.Lights),
+		"brightness_pct":    100,
+		"color_temp_kelvin": DefaultLightColorTempKelvin,
+	})
+	for _, lock := range s.Unlock {
+		m.callService(actionUnlock, "lock", "unlock", map[string]interface{}{"entity_id": lock})
+	}
+	m.callService(actionMusic, "media_player", "media_stop", map[string]interface{}{
+		"entity_id": entitiesOrAll(s.MediaPlayers),
+	})
+	if s.GarageDoor != "" {
+		m.callService(actionGarage, "cover", "open_cover", map[string]interface{}{"entity_id": s.GarageDoor})
+	}
+	m.sendNotification(capitalize(reason) + " detected. Evacuate the house.")
+
+	m.announce()
+}
+
+// endResponse publishes the all-clear. Lights, locks and the garage are left
+// as they are; the resumed plugins take them over again.
+func (m *Manager) endResponse(trigger string, now time.Time) {
+	m.logger.Warn("Emergency over, all detectors clear", zap.String("trigger", trigger))
+
+	m.setBool(ActiveVariable, false)
+	m.setBool(m.config.Emergency.AcknowledgeKey(), false)
+	m.shadowTracker.RecordActivation(false, "all detectors clear", now)
+
+	m.sendNotification("All smoke and carbon monoxide detectors have cleared.")
+}
+
+// announce speaks the evacuation announcement now and again every interval
+// until the emergency ends or is acknowledged
+func (m *Manager) announce() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopAnnouncementsLocked()
+	if !m.active || m.acknowledged || m.ctx.Err() != nil {
+		return
+	}
+
+	m.speakLocked(fmt.Sprintf("Emergency. %s detected. Leave the house now.", capitalize(m.reason)))
+	m.announceTimer = m.clock.AfterFunc(m.config.Emergency.AnnounceInterval(), m.announce)
+}
+
+// speakLocked raises the speakers' volume if configured and speaks the
+// message. Callers must hold mu.
+func (m *Manager) speakLocked(message string) {
+	speakers, err := m.speakers()
+	if err != nil {
+		m.logger.Error("Failed to resolve emergency speakers", zap.Error(err))
+		m.recordAction(actionAnnounce, "", err)
+		return
+	}
+
+	if volume := m.config.Emergency.Volume; volume > 0 {
+		m.act(actionAnnounce, "media_player.volume_set", fmt.Sprint(speakers), func() error {
+			return ha.SetVolume(m.ctx, m.haClient, speakers, ha.VolumeFromLevel(volume))
+		})
+	}
+
+	ttsEntity := m.config.Emergency.TTSEntity
+	if ttsEntity == "" {
+		ttsEntity = m.announcer.TTSEntity()
+	}
+	if m.callService(actionAnnounce, "tts", "speak", map[string]interface{}{
+		"entity_id":              ttsEntity,
+		"media_player_entity_id": speakers,
+		"message":                message,
+		"cache":                  true,
+	}) {
+		m.shadowTracker.RecordAnnouncement(m.clock.Now())
+	}
+}
+
+// speakers returns the configured speakers, or the announcer's security
+// speakers, with entity group references expanded
+func (m *Manager) speakers() ([]string, error) {
+	if speakers := m.config.Emergency.Speakers; len(speakers) > 0 {
+		return entitygroups.Expand(m.haClient, speakers)
+	}
+	return m.announcer.Speakers(m.haClient, tts.TargetSecurity)
+}
+
+// sendNotification sends a push notification if notify_service is set
+func (m *Manager) sendNotification(message string) {
+	domain, service, ok := m.config.Emergency.NotifyService.DomainService()
+	if !ok {
+		return
+	}
+	m.callService(actionNotify, domain, service, map[string]interface{}{
+		"title":   NotificationTitle,
+		"message": message,
+	})
+}
+
+// callService calls a response service, or logs it in read-only mode, and
+// records the action. Reports whether the call was sent.
+func (m *Manager) callService(action, domain, service string, data map[string]interface{}) bool {
+	return m.act(action, domain+"."+service, fmt.Sprint(data["entity_id"]), func() error {
+		return m.haClient.CallService(m.ctx, domain, service, data)
+	})
+}
+
+// act runs call, which sends service to target, or logs it in read-only mode,
+// and records the action. Reports whether the call was sent.
+func (m *Manager) act(action, service, target string, call func() error) bool {
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would call emergency service",
+			zap.String("action", action),
+			zap.String("service", service),
+			zap.String("target", target))
+		m.recordAction(action, target, fmt.Errorf("skipped: read-only mode"))
+		return false
+	}
+
+	if err := call(); err != nil {
+		m.logger.Error("Failed to call emergency service",
+			zap.String("action", action),
+			zap.String("service", service),
+			zap.String("target", target),
+			zap.Error(err))
+		m.recordAction(action, target, err)
+		return false
+	}
+	m.logger.Info("✓ Emergency action",
+		zap.String("action", action),
+		zap.String("service", service),
+		zap.String("target", target))
+	m.recordAction(action, target, nil)
+	return true
+}
+
+// recordAction records a response action in shadow state, failed if err is set
+func (m *Manager) recordAction(action, target string, err error) {
+	event := shadowstate.EmergencyAction{
+		Timestamp: m.clock.Now(),
+		Action:    action,
+		Target:    target,
+		Success:   err == nil,
+	}
+	if err != nil {
+		event.Detail = err.Error()
+	}
+	m.shadowTracker.RecordAction(event)
+}
+
+// setBool writes a state variable, logging failures
+func (m *Manager) setBool(key string, value bool) {
+	err := m.stateManager.SetBool(key, value)
+	switch {
+	case errors.Is(err, state.ErrReadOnlyMode):
+		m.logger.Info("READ-ONLY: Would set "+key, zap.Bool("value", value))
+	case err != nil:
+		m.logger.Error("Failed to set "+key, zap.Bool("value", value), zap.Error(err))
+	}
+}
+
+// notify calls the listeners with the current status
+func (m *Manager) notify() {
+	m.mu.Lock()
+	status := m.statusLocked()
+	listeners := slices.Clone(m.listeners)
+	m.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(status)
+	}
+}
+
+// statusLocked returns the current status. Callers must hold mu.
+func (m *Manager) statusLocked() Status {
+	return Status{
+		Active:      m.active,
+		Reason:      m.reason,
+		KeepRunning: slices.Clone(m.config.Emergency.KeepRunning),
+		Since:       m.since,
+	}
+}
+
+// reasonLocked describes the detectors that are on, e.g. "smoke in kitchen,
+// hallway; carbon monoxide in garage", or "" if none. Callers must hold mu.
+func (m *Manager) reasonLocked() string {
+	var hazards []string
+	byHazard := make(map[string][]string)
+	for _, d := range m.detectors {
+		if !d.detected {
+			continue
+		}
+		hazard := d.config.Hazard()
+		if _, ok := byHazard[hazard]; !ok {
+			hazards = append(hazards, hazard)
+		}
+		byHazard[hazard] = append(byHazard[hazard], d.config.Label())
+	}
+
+	parts := make([]string, 0, len(hazards))
+	for _, hazard := range hazards {
+		parts = append(parts, hazard+" in "+strings.Join(byHazard[hazard], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// publishDetectorsLocked records the detectors in shadow state. Callers must
+// hold mu.
+func (m *Manager) publishDetectorsLocked() {
+	statuses := make([]shadowstate.EmergencyDetectorStatus, 0, len(m.detectors))
+	for _, d := range m.detectors {
+		status := shadowstate.EmergencyDetectorStatus{
+			EntityID: d.config.EntityID,
+			Name:     d.config.Label(),
+			Kind:     d.config.Kind,
+			Detected: d.detected,
+		}
+		if d.detected {
+			status.Since = d.since
+		}
+		statuses = append(statuses, status)
+	}
+	m.shadowTracker.UpdateDetectors(statuses)
+}
+
+// stopAnnouncementsLocked cancels the next announcement. Callers must hold mu.
+func (m *Manager) stopAnnouncementsLocked() {
+	if m.announceTimer != nil {
+		m.announceTimer.Stop()
+		m.announceTimer = nil
+	}
+}
+
+// detector returns a configured detector. Callers must hold mu.
+func (m *Manager) detector(entityID string) *detector {
+	for _, d := range m.detectors {
+		if d.config.EntityID == entityID {
+			return d
+		}
+	}
+	return nil
+}
+
+// entitiesOrAll returns the entities, or "all" for every entity in the domain
+func entitiesOrAll(entities []string) interface{} {
+	if len(entities) == 0 {
+		return "all"
+	}
+	return entities
+}
+
+// capitalize upper-cases the first letter of a spoken sentence
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,235 @@
+package emergency
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig() *Config {
+	return &Config{Emergency: Settings{
+		Detectors: []Detector{
+			{EntityID: "binary_sensor.kitchen_smoke", Name: "the kitchen", Kind: KindSmoke},
+			{EntityID: "binary_sensor.garage_co", Name: "the garage", Kind: KindCO},
+		},
+		Unlock:                  []string{"lock.front_door"},
+		GarageDoor:              "cover.garage_door",
+		Speakers:                []string{"media_player.kitchen"},
+		AnnounceIntervalSeconds: 30,
+		KeepRunning:             []string{"energy"},
+	}}
+}
+
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("binary_sensor.kitchen_smoke", "off", nil)
+	mockHA.SetState("binary_sensor.garage_co", "off", nil)
+	mockHA.SetState("input_boolean.emergency_acknowledged", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SyncFromHA())
+
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC))
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, readOnly, nil)
+	manager.SetClock(mockClock)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+// countCalls counts the service calls made to a domain.service
+func countCalls(mockHA *ha.MockClient, domain, service string) int {
+	n := 0
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == domain && call.Service == service {
+			n++
+		}
+	}
+	return n
+}
+
+// TestEmergencyResponse tests that a tripped detector runs every response
+// action, notifies listeners and repeats the announcement until the detector
+// clears
+func TestEmergencyResponse(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+
+	var statuses []Status
+	manager.OnChange(func(status Status) {
+		statuses = append(statuses, status)
+	})
+
+	mockHA.SimulateStateChange("binary_sensor.kitchen_smoke", "on")
+
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Active)
+	assert.Equal(t, "smoke in the kitchen", statuses[0].Reason)
+	assert.Equal(t, []string{"energy"}, statuses[0].KeepRunning)
+
+	active, err := stateManager.GetBool(ActiveVariable)
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	for _, call := range mockHA.GetServiceCalls() {
+		switch call.Domain + "." + call.Service {
+		case "light.turn_on":
+			assert.Equal(t, "all", call.Data["entity_id"])
+			assert.Equal(t, 100, call.Data["brightness_pct"])
+		case "lock.unlock":
+			assert.Equal(t, "lock.front_door", call.Data["entity_id"])
+		case "media_player.media_stop":
+			assert.Equal(t, "all", call.Data["entity_id"])
+		case "cover.open_cover":
+			assert.Equal(t, "cover.garage_door", call.Data["entity_id"])
+		case "tts.speak":
+			assert.Equal(t, []string{"media_player.kitchen"}, call.Data["media_player_entity_id"])
+			assert.Equal(t, "Emergency. Smoke in the kitchen detected. Leave the house now.", call.Data["message"])
+		}
+	}
+	for _, service := range []string{"light.turn_on", "lock.unlock", "media_player.media_stop", "cover.open_cover", "tts.speak"} {
+		domain, name, _ := strings.Cut(service, ".")
+		assert.Equal(t, 1, countCalls(mockHA, domain, name), service)
+	}
+
+	mockClock.Advance(30 * time.Second)
+	mockClock.Advance(30 * time.Second)
+	assert.Equal(t, 3, countCalls(mockHA, "tts", "speak"))
+	assert.Equal(t, 3, manager.GetShadowState().Outputs.Announcements)
+
+	mockHA.SimulateStateChange("binary_sensor.kitchen_smoke", "off")
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[1].Active)
+
+	mockClock.Advance(time.Minute)
+	assert.Equal(t, 3, countCalls(mockHA, "tts", "speak"), "announcements should stop once the detector clears")
+
+	active, err = stateManager.GetBool(ActiveVariable)
+	require.NoError(t, err)
+	assert.False(t, active)
+	assert.False(t, manager.GetShadowState().Outputs.Active)
+}
+
+// TestEmergencyAcknowledge tests that the acknowledge variable stops the
+// announcements, and that another detector tripping re-arms them
+func TestEmergencyAcknowledge(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+
+	mockHA.SimulateStateChange("binary_sensor.kitchen_smoke", "on")
+	require.NoError(t, stateManager.SetBool("isEmergencyAcknowledged", true))
+
+	mockClock.Advance(2 * time.Minute)
+	assert.Equal(t, 1, countCalls(mockHA, "tts", "speak"))
+	assert.True(t, manager.GetShadowState().Outputs.Acknowledged)
+	assert.True(t, manager.Status().Active, "acknowledging stops the announcements, not the emergency")
+
+	mockHA.SimulateStateChange("binary_sensor.garage_co", "on")
+	assert.Equal(t, 2, countCalls(mockHA, "tts", "speak"))
+	assert.Equal(t, "smoke in the kitchen; carbon monoxide in the garage", manager.Status().Reason)
+
+	acknowledged, err := stateManager.GetBool("isEmergencyAcknowledged")
+	require.NoError(t, err)
+	assert.False(t, acknowledged, "a new detector re-arms the announcements")
+
+	mockClock.Advance(30 * time.Second)
+	assert.Equal(t, 3, countCalls(mockHA, "tts", "speak"))
+}
+
+// TestEmergencyReadOnly tests that read-only mode records the response it
+// would have run without calling any services
+func TestEmergencyReadOnly(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, true)
+
+	mockHA.SimulateStateChange("binary_sensor.garage_co", "on")
+
+	assert.Empty(t, mockHA.GetServiceCalls())
+	outputs := manager.GetShadowState().Outputs
+	assert.True(t, outputs.Active)
+	require.NotEmpty(t, outputs.Actions)
+	for _, action := range outputs.Actions {
+		assert.False(t, action.Success)
+		assert.Equal(t, "skipped: read-only mode", action.Detail)
+	}
+}
+
+// TestEmergencyActiveAtStartup tests that a detector already on when the
+// manager starts starts the emergency
+func TestEmergencyActiveAtStartup(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("binary_sensor.kitchen_smoke", "on", nil)
+	mockHA.SetState("binary_sensor.garage_co", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, false, nil)
+	manager.SetClock(clock.NewMockClock(time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)))
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	assert.True(t, manager.Status().Active)
+	assert.Equal(t, 1, countCalls(mockHA, "tts", "speak"))
+}
+
+// TestEmergencyVolume tests that the speakers are raised to the configured
+// volume before each announcement
+func TestEmergencyVolume(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false)
+	manager.config.Emergency.Volume = 0.8
+
+	mockHA.SimulateStateChange("binary_sensor.kitchen_smoke", "on")
+
+	require.Equal(t, 1, countCalls(mockHA, "media_player", "volume_set"))
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Service == "volume_set" {
+			assert.Equal(t, []string{"media_player.kitchen"}, call.Data["entity_id"])
+			assert.Equal(t, 0.8, call.Data["volume_level"])
+		}
+	}
+}
+
+// TestEmergencyBusyWhileActive tests that the manager refuses to be stopped
+// by the plugin lifecycle while an emergency is active, and that Stop waits
+// for the startup notification
+func TestEmergencyBusyWhileActive(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("binary_sensor.kitchen_smoke", "on", nil)
+	mockHA.SetState("binary_sensor.garage_co", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, false, nil)
+	manager.SetClock(clock.NewMockClock(time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)))
+
+	var notified atomic.Bool
+	manager.OnChange(func(status Status) {
+		notified.Store(status.Active)
+	})
+	require.NoError(t, manager.Start(context.Background()))
+
+	assert.ErrorContains(t, manager.Busy(), "smoke in the kitchen")
+
+	manager.Stop()
+	assert.True(t, notified.Load(), "Stop should wait for the startup notification")
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+	mockHA.SimulateStateChange("binary_sensor.kitchen_smoke", "off")
+	assert.NoError(t, manager.Busy())
+}
@@ -0,0 +1,130 @@
+package energy
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// Reasons a free energy announcement was not sent
+const (
+	AnnouncementSuppressedQuietHours = "quiet_hours"
+	AnnouncementSuppressedNobodyHome = "nobody_home"
+)
+
+// checkFreeEnergyAnnouncements announces the free energy window once per
+// boundary when now is within the configured lead time before it begins or ends
+func (m *Manager) checkFreeEnergyAnnouncements(now time.Time) {
+	config := m.currentConfig()
+	announcements := config.FreeEnergyAnnouncements
+	if announcements == nil {
+		return
+	}
+
+	boundaries := []struct {
+		name string
+		at   string
+		lead int
+	}{
+		{"start", config.Energy.FreeEnergyTime.Start, announcements.StartLeadMinutes},
+		{"end", config.Energy.FreeEnergyTime.End, announcements.EndLeadMinutes},
+	}
+
+	for _, b := range boundaries {
+		if b.lead <= 0 {
+			continue
+		}
+		boundary, ok := nextDailyTime(now, b.at, m.timezone)
+		if !ok || boundary.Sub(now) > time.Duration(b.lead)*time.Minute {
+			continue
+		}
+
+		m.announceMu.Lock()
+		already := m.lastAnnounced[b.name].Equal(boundary)
+		m.lastAnnounced[b.name] = boundary
+		m.announceMu.Unlock()
+		if already {
+			continue
+		}
+
+		m.announceFreeEnergy(b.name, boundary, now)
+	}
+}
+
+// announceFreeEnergy sends one announcement unless it falls in quiet hours or
+// nobody is home, and records the outcome in the shadow state
+func (m *Manager) announceFreeEnergy(boundary string, at, now time.Time) {
+	announcements := m.currentConfig().FreeEnergyAnnouncements
+	minutes := int(math.Round(at.Sub(now).Minutes()))
+	clock := at.In(m.timezone).Format("15:04")
+
+	message := fmt.Sprintf("Free energy starts in %d minutes (%s) - a good time to start laundry or charging", minutes, clock)
+	if boundary == "end" {
+		message = fmt.Sprintf("Free energy ends in %d minutes (%s) - finish up laundry and charging", minutes, clock)
+	}
+
+	record := shadowstate.FreeEnergyAnnouncement{
+		Boundary:    boundary,
+		WindowTime:  at,
+		Message:     message,
+		EvaluatedAt: now,
+	}
+
+	isAnyoneHome, err := m.stateManager.GetBool("isAnyoneHome")
+	if err != nil {
+		m.logger.Warn("Failed to get isAnyoneHome for free energy announcement", zap.Error(err))
+	}
+
+	switch {
+	case announcements.QuietHours != nil && announcements.QuietHours.Contains(now, m.timezone):
+		record.SuppressedReason = AnnouncementSuppressedQuietHours
+	case err == nil && !isAnyoneHome:
+		record.SuppressedReason = AnnouncementSuppressedNobodyHome
+	}
+
+	if record.SuppressedReason != "" {
+		m.logger.Info("Skipping free energy announcement",
+			zap.String("boundary", boundary),
+			zap.String("reason", record.SuppressedReason))
+		m.shadowTracker.RecordFreeEnergyAnnouncement(record)
+		return
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send free energy announcement",
+			zap.String("service", string(announcements.NotifyService)),
+			zap.String("message", message))
+	} else if err := notifyrouter.SendPush(m.ctx, m.haClient, announcements.NotifyService, notifyrouter.Push{
+		Title:   "Free energy",
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send free energy announcement", zap.Error(err))
+	} else {
+		record.Sent = true
+		m.logger.Info("Sent free energy announcement",
+			zap.String("boundary", boundary),
+			zap.Time("window_time", at))
+	}
+
+	m.shadowTracker.RecordFreeEnergyAnnouncement(record)
+}
+
+// nextDailyTime returns the first occurrence of an "HH:MM" time in loc
+// strictly after now
+func nextDailyTime(now time.Time, hhmm string, loc *time.Location) (time.Time, bool) {
+	parsed, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, false
+	}
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), parsed.Hour(), parsed.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, parsed.Hour(), parsed.Minute(), 0, 0, loc)
+	}
+	return next, true
+}
@@ -0,0 +1,155 @@
+package energy
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newAnnouncementTestManager returns a manager with the 21:00-07:00 window
+// announced 15 minutes ahead, quiet from 22:30 to 06:30, with someone home
+func newAnnouncementTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	mockClient.ClearServiceCalls()
+
+	config := createTestConfig()
+	config.FreeEnergyAnnouncements = &FreeEnergyAnnouncements{
+		NotifyService:    "notify.notify",
+		StartLeadMinutes: 15,
+		EndLeadMinutes:   15,
+		QuietHours:       &QuietHours{Start: "22:30", End: "06:30"},
+	}
+
+	return NewManager(mockClient, stateManager, config, logger, readOnly, time.UTC, nil), mockClient
+}
+
+func notifyCalls(mockClient *ha.MockClient) []ha.ServiceCall {
+	calls := make([]ha.ServiceCall, 0)
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "notify" && call.Service == "notify" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestFreeEnergyAnnouncements_StartAndEnd(t *testing.T) {
+	manager, mockClient := newAnnouncementTestManager(t, false)
+
+	// 20:44 - too early
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 10, 20, 44, 0, 0, time.UTC))
+	assert.Empty(t, notifyCalls(mockClient))
+
+	// 20:45 - 15 minutes before the window begins
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 10, 20, 45, 0, 0, time.UTC))
+	calls := notifyCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Contains(t, calls[0].Data["message"], "starts in 15 minutes (21:00)")
+
+	// Later checks before the window don't repeat it
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 10, 20, 50, 0, 0, time.UTC))
+	assert.Len(t, notifyCalls(mockClient), 1)
+
+	// 06:45 - 15 minutes before the window ends
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 11, 6, 45, 0, 0, time.UTC))
+	calls = notifyCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[1].Data["message"], "ends in 15 minutes (07:00)")
+
+	announcement := manager.GetShadowState().Outputs.LastFreeEnergyAnnouncement
+	require.NotNil(t, announcement)
+	assert.Equal(t, "end", announcement.Boundary)
+	assert.True(t, announcement.Sent)
+	assert.Equal(t, time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC), announcement.WindowTime)
+
+	// The next evening is announced again
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 11, 20, 46, 0, 0, time.UTC))
+	assert.Len(t, notifyCalls(mockClient), 3)
+}
+
+func TestFreeEnergyAnnouncements_QuietHours(t *testing.T) {
+	manager, mockClient := newAnnouncementTestManager(t, false)
+	manager.currentConfig().FreeEnergyAnnouncements.QuietHours = &QuietHours{Start: "22:00", End: "07:00"}
+
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 11, 6, 45, 0, 0, time.UTC))
+
+	assert.Empty(t, notifyCalls(mockClient))
+	announcement := manager.GetShadowState().Outputs.LastFreeEnergyAnnouncement
+	require.NotNil(t, announcement)
+	assert.Equal(t, AnnouncementSuppressedQuietHours, announcement.SuppressedReason)
+	assert.False(t, announcement.Sent)
+}
+
+func TestFreeEnergyAnnouncements_NobodyHome(t *testing.T) {
+	manager, mockClient := newAnnouncementTestManager(t, false)
+	require.NoError(t, manager.stateManager.SetBool("isAnyoneHome", false))
+	mockClient.ClearServiceCalls()
+
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 10, 20, 45, 0, 0, time.UTC))
+
+	assert.Empty(t, notifyCalls(mockClient))
+	announcement := manager.GetShadowState().Outputs.LastFreeEnergyAnnouncement
+	require.NotNil(t, announcement)
+	assert.Equal(t, AnnouncementSuppressedNobodyHome, announcement.SuppressedReason)
+}
+
+func TestFreeEnergyAnnouncements_DisabledBoundary(t *testing.T) {
+	manager, mockClient := newAnnouncementTestManager(t, false)
+	manager.currentConfig().FreeEnergyAnnouncements.StartLeadMinutes = 0
+	manager.currentConfig().FreeEnergyAnnouncements.EndLeadMinutes = 30
+
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 10, 20, 50, 0, 0, time.UTC))
+	assert.Empty(t, notifyCalls(mockClient), "start announcement is disabled")
+
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 11, 6, 31, 0, 0, time.UTC))
+	calls := notifyCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Contains(t, calls[0].Data["message"], "ends in 29 minutes")
+}
+
+func TestFreeEnergyAnnouncements_ReadOnlyMode(t *testing.T) {
+	manager, mockClient := newAnnouncementTestManager(t, true)
+
+	manager.checkFreeEnergyAnnouncements(time.Date(2026, 3, 10, 20, 45, 0, 0, time.UTC))
+
+	assert.Empty(t, notifyCalls(mockClient))
+	announcement := manager.GetShadowState().Outputs.LastFreeEnergyAnnouncement
+	require.NotNil(t, announcement)
+	assert.False(t, announcement.Sent)
+	assert.Empty(t, announcement.SuppressedReason)
+}
+
+func TestQuietHours_Contains(t *testing.T) {
+	overnight := &QuietHours{Start: "22:00", End: "07:00"}
+	daytime := &QuietHours{Start: "13:00", End: "15:00"}
+
+	tests := []struct {
+		name     string
+		quiet    *QuietHours
+		at       time.Time
+		expected bool
+	}{
+		{"Overnight before start", overnight, time.Date(2026, 3, 10, 21, 59, 0, 0, time.UTC), false},
+		{"Overnight at start", overnight, time.Date(2026, 3, 10, 22, 0, 0, 0, time.UTC), true},
+		{"Overnight after midnight", overnight, time.Date(2026, 3, 11, 3, 0, 0, 0, time.UTC), true},
+		{"Overnight at end", overnight, time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC), false},
+		{"Daytime inside", daytime, time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC), true},
+		{"Daytime outside", daytime, time.Date(2026, 3, 10, 16, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.quiet.Contains(tt.at, time.UTC))
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package energy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// Inverter modes recorded in the shadow state
+const (
+	InverterModeBackup = "backup"
+	InverterModeNormal = "normal"
+)
+
+// forecastPeriod is how long an hourly forecast entry lasts
+const forecastPeriod = time.Hour
+
+// backupReserveState tracks whether this plugin put the inverter in backup mode
+type backupReserveState struct {
+	active       bool      // Inverter was switched to (or found in) backup mode for high risk
+	lowRiskSince time.Time // When risk dropped while active, zero while risk is high
+	lastSwitch   time.Time
+}
+
+// checkBackupReserve assesses grid outage risk and switches the inverter to
+// backup mode while it is high. Normal mode is restored once risk has stayed
+// low for restore_after_minutes and the grid is up; a backup mode this plugin
+// didn't choose is left alone.
+func (m *Manager) checkBackupReserve(now time.Time) {
+	config := m.currentConfig().BackupReserve
+	if config == nil {
+		return
+	}
+
+	decision := m.assessOutageRisk(config, now)
+
+	gridAvailable, err := m.stateManager.GetBool("isGridAvailable")
+	if err != nil {
+		m.logger.Warn("Failed to get isGridAvailable for backup reserve, assuming available", zap.Error(err))
+		gridAvailable = true
+	}
+	decision.GridAvailable = gridAvailable
+
+	m.reserveMu.Lock()
+	defer m.reserveMu.Unlock()
+
+	r := &m.reserve
+	switch {
+	case decision.HighRisk && !r.active:
+		r.lowRiskSince = time.Time{}
+		if m.currentInverterMode(config) == config.BackupMode {
+			r.active = true
+			decision.Decision = "Inverter already in backup mode: " + strings.Join(decision.Reasons, "; ")
+			break
+		}
+		reason := "High outage risk: " + strings.Join(decision.Reasons, "; ")
+		if err := m.setInverterMode(config, config.BackupMode, reason); err != nil {
+			decision.Decision = fmt.Sprintf("Failed to switch to backup mode: %v", err)
+			break
+		}
+		r.active = true
+		r.lastSwitch = now
+		decision.Decision = "Switched to backup mode. " + reason
+
+	case decision.HighRisk:
+		r.lowRiskSince = time.Time{}
+		decision.Decision = "Holding backup mode: " + strings.Join(decision.Reasons, "; ")
+
+	case r.active && !gridAvailable:
+		r.lowRiskSince = time.Time{}
+		decision.Decision = "Holding backup mode until the grid returns"
+
+	case r.active:
+		if r.lowRiskSince.IsZero() {
+			r.lowRiskSince = now
+		}
+		if remaining := r.lowRiskSince.Add(config.RestoreAfter()).Sub(now); remaining > 0 {
+			decision.Decision = fmt.Sprintf("Outage risk low, restoring normal mode in %d minutes", int(remaining.Round(time.Minute).Minutes()))
+			break
+		}
+		reason := fmt.Sprintf("Outage risk low for %d minutes", config.RestoreAfterMinutes)
+		if err := m.setInverterMode(config, config.NormalMode, reason); err != nil {
+			decision.Decision = fmt.Sprintf("Failed to restore normal mode: %v", err)
+			break
+		}
+		r.active = false
+		r.lowRiskSince = time.Time{}
+		r.lastSwitch = now
+		decision.Decision = "Restored normal mode. " + reason
+
+	default:
+		decision.Decision = "No outage risk"
+	}
+
+	decision.Mode = InverterModeNormal
+	if r.active {
+		decision.Mode = InverterModeBackup
+	}
+	decision.LowRiskSince = r.lowRiskSince
+	decision.LastSwitch = r.lastSwitch
+
+	m.shadowTracker.RecordBackupReserveDecision(decision)
+}
+
+// assessOutageRisk reads the utility risk sensor and the weather forecast.
+// Risk is high when the sensor is at or above its threshold, the current
+// weather is a risk condition, or a forecast period within lead_hours has a
+// risk condition or high wind.
+func (m *Manager) assessOutageRisk(config *BackupReserve, now time.Time) shadowstate.BackupReserveDecision {
+	decision := shadowstate.BackupReserveDecision{EvaluatedAt: now}
+
+	if config.RiskSensor != "" {
+		if st, err := m.haClient.GetState(m.ctx, config.RiskSensor); err != nil {
+			m.logger.Warn("Failed to read outage risk sensor",
+				zap.String("entity_id", config.RiskSensor),
+				zap.Error(err))
+		} else if value, err := strconv.ParseFloat(st.State, 64); err != nil {
+			m.logger.Warn("Outage risk sensor is not a number",
+				zap.String("entity_id", config.RiskSensor),
+				zap.String("value", st.State))
+		} else {
+			decision.RiskSensorValue = &value
+			if value >= config.RiskThreshold {
+				decision.Reasons = append(decision.Reasons, fmt.Sprintf("outage risk %g at or above %g", value, config.RiskThreshold))
+			}
+		}
+	}
+
+	if config.WeatherEntity != "" {
+		st, err := m.haClient.GetState(m.ctx, config.WeatherEntity)
+		if err != nil {
+			m.logger.Warn("Failed to read weather for outage risk",
+				zap.String("entity_id", config.WeatherEntity),
+				zap.Error(err))
+		} else {
+			decision.WeatherCondition = st.State
+			if config.IsRiskCondition(st.State) {
+				decision.Reasons = append(decision.Reasons, "weather "+st.State+" now")
+			}
+
+			forecast, _ := st.Attributes["forecast"].([]interface{})
+			for _, entry := range forecast {
+				period, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				reason, at, risky := m.riskyForecastPeriod(config, period, now)
+				if !risky {
+					continue
+				}
+				if decision.NextRiskyPeriod.IsZero() || at.Before(decision.NextRiskyPeriod) {
+					decision.NextRiskyPeriod = at
+				}
+				decision.Reasons = append(decision.Reasons, reason)
+			}
+		}
+	}
+
+	decision.HighRisk = len(decision.Reasons) > 0
+	return decision
+}
+
+// riskyForecastPeriod reports whether an hourly forecast entry still in
+// progress or starting within the lead time has a risk condition or high wind
+func (m *Manager) riskyForecastPeriod(config *BackupReserve, period map[string]interface{}, now time.Time) (string, time.Time, bool) {
+	datetime, _ := period["datetime"].(string)
+	at, err := time.Parse(time.RFC3339, datetime)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	if !at.Add(forecastPeriod).After(now) || at.After(now.Add(config.Lead())) {
+		return "", time.Time{}, false
+	}
+
+	clock := at.In(m.timezone).Format("15:04")
+	if condition, _ := period["condition"].(string); config.IsRiskCondition(condition) {
+		return fmt.Sprintf("forecast %s at %s", condition, clock), at, true
+	}
+	if wind, ok := period["wind_speed"].(float64); ok && config.WindSpeedThreshold > 0 && wind >= config.WindSpeedThreshold {
+		return fmt.Sprintf("forecast wind %g at %s", wind, clock), at, true
+	}
+	return "", time.Time{}, false
+}
+
+// currentInverterMode reads the inverter mode entity's selected option
+func (m *Manager) currentInverterMode(config *BackupReserve) string {
+	st, err := m.haClient.GetState(m.ctx, config.InverterModeEntity)
+	if err != nil {
+		m.logger.Warn("Failed to read inverter mode",
+			zap.String("entity_id", config.InverterModeEntity),
+			zap.Error(err))
+		return ""
+	}
+	return st.State
+}
+
+// setInverterMode selects an inverter mode option
+func (m *Manager) setInverterMode(config *BackupReserve, mode, reason string) error {
+	entityID := config.InverterModeEntity
+	domain := ha.EntityDomain(entityID)
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would switch inverter mode",
+			zap.String("entity_id", entityID),
+			zap.String("mode", mode),
+			zap.String("reason", reason))
+		return nil
+	}
+
+	if err := m.haClient.CallService(m.ctx, domain, "select_option", map[string]interface{}{
+		"entity_id": entityID,
+		"option":    mode,
+	}); err != nil {
+		m.logger.Error("Failed to switch inverter mode",
+			zap.String("entity_id", entityID),
+			zap.String("mode", mode),
+			zap.Error(err))
+		return err
+	}
+
+	m.logger.Info("Switched inverter mode",
+		zap.String("entity_id", entityID),
+		zap.String("mode", mode),
+		zap.String("reason", reason))
+	m.shadowTracker.RecordInverterCommand(time.Now(), "Inverter mode "+mode+": "+reason)
+	return nil
+}
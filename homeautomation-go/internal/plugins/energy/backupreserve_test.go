@@ -0,0 +1,224 @@
+package energy
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testInverterMode = "select.inverter_operating_mode"
+	testRiskSensor   = "sensor.utility_outage_risk"
+	testWeather      = "weather.home"
+)
+
+// newBackupReserveTestManager returns a manager that switches to backup mode
+// at a risk of 50 or forecast lightning within 3 hours, restoring after an hour
+func newBackupReserveTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetBool("isGridAvailable", true))
+
+	mockClient.SetState(testInverterMode, "Self-Consumption", map[string]interface{}{})
+	mockClient.SetState(testRiskSensor, "10", map[string]interface{}{})
+	mockClient.SetState(testWeather, "cloudy", map[string]interface{}{})
+	mockClient.ClearServiceCalls()
+
+	config := createTestConfig()
+	config.BackupReserve = &BackupReserve{
+		InverterModeEntity:  testInverterMode,
+		BackupMode:          "Backup",
+		NormalMode:          "Self-Consumption",
+		RiskSensor:          testRiskSensor,
+		RiskThreshold:       50,
+		WeatherEntity:       testWeather,
+		RiskConditions:      []string{"lightning", "lightning-rainy"},
+		WindSpeedThreshold:  40,
+		LeadHours:           3,
+		RestoreAfterMinutes: 60,
+	}
+
+	return NewManager(mockClient, stateManager, config, logger, readOnly, time.UTC, nil), mockClient, stateManager
+}
+
+// setForecast sets the weather entity's hourly forecast
+func setForecast(mockClient *ha.MockClient, condition string, periods ...map[string]interface{}) {
+	forecast := make([]interface{}, 0, len(periods))
+	for _, p := range periods {
+		forecast = append(forecast, p)
+	}
+	mockClient.SetState(testWeather, condition, map[string]interface{}{"forecast": forecast})
+}
+
+func inverterCalls(mockClient *ha.MockClient) []ha.ServiceCall {
+	calls := make([]ha.ServiceCall, 0)
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "select_option" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestBackupReserve_NoRisk(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, false)
+
+	manager.checkBackupReserve(time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	assert.Empty(t, inverterCalls(mockClient))
+	decision := manager.GetShadowState().Outputs.BackupReserve
+	require.NotNil(t, decision)
+	assert.False(t, decision.HighRisk)
+	assert.Equal(t, InverterModeNormal, decision.Mode)
+	require.NotNil(t, decision.RiskSensorValue)
+	assert.Equal(t, 10.0, *decision.RiskSensorValue)
+}
+
+func TestBackupReserve_RiskSensorSwitchesAndRestores(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, false)
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	mockClient.SetState(testRiskSensor, "70", map[string]interface{}{})
+	manager.checkBackupReserve(now)
+
+	calls := inverterCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "select", calls[0].Domain)
+	assert.Equal(t, "Backup", calls[0].Data["option"])
+	decision := manager.GetShadowState().Outputs.BackupReserve
+	assert.True(t, decision.HighRisk)
+	assert.Equal(t, InverterModeBackup, decision.Mode)
+	assert.Contains(t, decision.Reasons[0], "outage risk 70")
+
+	// Held while risk stays high
+	manager.checkBackupReserve(now.Add(time.Minute))
+	assert.Len(t, inverterCalls(mockClient), 1)
+
+	// Risk drops; normal mode waits for restore_after_minutes
+	mockClient.SetState(testRiskSensor, "20", map[string]interface{}{})
+	manager.checkBackupReserve(now.Add(2 * time.Hour))
+	manager.checkBackupReserve(now.Add(2*time.Hour + 30*time.Minute))
+	assert.Len(t, inverterCalls(mockClient), 1)
+	assert.Equal(t, InverterModeBackup, manager.GetShadowState().Outputs.BackupReserve.Mode)
+
+	manager.checkBackupReserve(now.Add(3 * time.Hour))
+	calls = inverterCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "Self-Consumption", calls[1].Data["option"])
+	decision = manager.GetShadowState().Outputs.BackupReserve
+	assert.Equal(t, InverterModeNormal, decision.Mode)
+	assert.Equal(t, now.Add(3*time.Hour), decision.LastSwitch)
+}
+
+func TestBackupReserve_ForecastAheadOfRiskyWindow(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, false)
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	// Lightning at 16:00 is beyond the 3 hour lead time
+	setForecast(mockClient, "cloudy",
+		map[string]interface{}{"datetime": "2026-06-10T13:00:00Z", "condition": "cloudy", "wind_speed": 15.0},
+		map[string]interface{}{"datetime": "2026-06-10T16:00:00Z", "condition": "lightning", "wind_speed": 20.0},
+	)
+	manager.checkBackupReserve(now)
+	assert.Empty(t, inverterCalls(mockClient))
+
+	// An hour later it is within the lead time
+	manager.checkBackupReserve(now.Add(time.Hour))
+	require.Len(t, inverterCalls(mockClient), 1)
+	decision := manager.GetShadowState().Outputs.BackupReserve
+	assert.Equal(t, time.Date(2026, 6, 10, 16, 0, 0, 0, time.UTC), decision.NextRiskyPeriod)
+	assert.Equal(t, []string{"forecast lightning at 16:00"}, decision.Reasons)
+}
+
+func TestBackupReserve_ForecastWind(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, false)
+
+	setForecast(mockClient, "windy",
+		map[string]interface{}{"datetime": "2026-06-10T14:00:00Z", "condition": "windy", "wind_speed": 45.0},
+	)
+	manager.checkBackupReserve(time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	require.Len(t, inverterCalls(mockClient), 1)
+	assert.Equal(t, []string{"forecast wind 45 at 14:00"}, manager.GetShadowState().Outputs.BackupReserve.Reasons)
+}
+
+func TestBackupReserve_HeldWhileGridDown(t *testing.T) {
+	manager, mockClient, stateManager := newBackupReserveTestManager(t, false)
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	mockClient.SetState(testRiskSensor, "90", map[string]interface{}{})
+	manager.checkBackupReserve(now)
+	require.Len(t, inverterCalls(mockClient), 1)
+
+	// The outage happens, then the risk signal clears
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+	mockClient.SetState(testRiskSensor, "0", map[string]interface{}{})
+	manager.checkBackupReserve(now.Add(4 * time.Hour))
+	assert.Len(t, inverterCalls(mockClient), 1)
+	assert.Contains(t, manager.GetShadowState().Outputs.BackupReserve.Decision, "until the grid returns")
+
+	// Grid back: the restore delay starts from here
+	require.NoError(t, stateManager.SetBool("isGridAvailable", true))
+	manager.checkBackupReserve(now.Add(5 * time.Hour))
+	assert.Len(t, inverterCalls(mockClient), 1)
+	manager.checkBackupReserve(now.Add(6 * time.Hour))
+	assert.Len(t, inverterCalls(mockClient), 2)
+}
+
+func TestBackupReserve_AdoptsExistingBackupMode(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, false)
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	mockClient.SetState(testInverterMode, "Backup", map[string]interface{}{})
+	mockClient.SetState(testWeather, "lightning", map[string]interface{}{})
+	manager.checkBackupReserve(now)
+
+	assert.Empty(t, inverterCalls(mockClient))
+	assert.Equal(t, InverterModeBackup, manager.GetShadowState().Outputs.BackupReserve.Mode)
+
+	// Restored after the risk passes, as if this plugin had switched it
+	mockClient.SetState(testWeather, "sunny", map[string]interface{}{})
+	manager.checkBackupReserve(now.Add(time.Hour))
+	manager.checkBackupReserve(now.Add(2 * time.Hour))
+	require.Len(t, inverterCalls(mockClient), 1)
+}
+
+func TestBackupReserve_ManualBackupModeLeftAlone(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, false)
+
+	mockClient.SetState(testInverterMode, "Backup", map[string]interface{}{})
+	manager.checkBackupReserve(time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+	manager.checkBackupReserve(time.Date(2026, 6, 10, 14, 0, 0, 0, time.UTC))
+
+	assert.Empty(t, inverterCalls(mockClient))
+}
+
+func TestBackupReserve_ReadOnly(t *testing.T) {
+	manager, mockClient, _ := newBackupReserveTestManager(t, true)
+
+	mockClient.SetState(testRiskSensor, "70", map[string]interface{}{})
+	manager.checkBackupReserve(time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	assert.Empty(t, inverterCalls(mockClient))
+	// The decision is still recorded so the shadow state shows what would happen
+	decision := manager.GetShadowState().Outputs.BackupReserve
+	assert.Equal(t, InverterModeBackup, decision.Mode)
+	assert.Contains(t, decision.Decision, "Switched to backup mode")
+}
+
+func TestBackupReserve_NotConfigured(t *testing.T) {
+	manager, mockClient := newAnnouncementTestManager(t, false)
+
+	manager.checkBackupReserve(time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	assert.Empty(t, inverterCalls(mockClient))
+	assert.Nil(t, manager.GetShadowState().Outputs.BackupReserve)
+}
@@ -1,7 +1,13 @@
 package energy
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,6 +18,380 @@ type FreeEnergyTime struct {
 	End   string `yaml:"end"`   // Format: "07:00"
 }
 
+// FreeEnergyAnnouncements announces the free energy window shortly before it
+// begins and ends, so laundry and charging can be started at the right time
+type FreeEnergyAnnouncements struct {
+	NotifyService    notifyrouter.Target `yaml:"notify_service"`     // e.g. "notify.notify"
+	StartLeadMinutes int                 `yaml:"start_lead_minutes"` // Minutes before the window begins; 0 disables
+	EndLeadMinutes   int                 `yaml:"end_lead_minutes"`   // Minutes before the window ends; 0 disables
+	QuietHours       *QuietHours         `yaml:"quiet_hours"`        // Announcements due in quiet hours are skipped
+}
+
+// QuietHours is a daily time range that may span midnight
+type QuietHours struct {
+	Start string `yaml:"start"` // Format: "22:00"
+	End   string `yaml:"end"`   // Format: "07:00"
+}
+
+// Contains reports whether t falls within the quiet hours in loc
+func (q *QuietHours) Contains(t time.Time, loc *time.Location) bool {
+	start, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", q.End)
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	// Spans midnight
+	return minute >= startMinute || minute < endMinute
+}
+
+// LevelAnnouncements announces selected currentEnergyLevel changes, e.g. when
+// the house drops into load shedding or recovers from it
+type LevelAnnouncements struct {
+	NotifyService notifyrouter.Target `yaml:"notify_service"` // e.g. "notify.notify"; used by transitions with notify
+	QuietHours    *QuietHours         `yaml:"quiet_hours"`    // Changes during quiet hours aren't announced
+	Transitions   []LevelTransition   `yaml:"transitions"`    // First match wins
+}
+
+// LevelTransition is a level change to announce. From and To are level names,
+// or "*" for any level.
+type LevelTransition struct {
+	From     string   `yaml:"from"`
+	To       string   `yaml:"to"`
+	Notify   bool     `yaml:"notify"`   // Send a push through notify_service
+	Speakers []string `yaml:"speakers"` // Speak on these media players through the notification router
+}
+
+// Matches reports whether the transition covers a change from one level to another
+func (t LevelTransition) Matches(from, to string) bool {
+	return (t.From == "*" || t.From == from) && (t.To == "*" || t.To == to)
+}
+
+// Find returns the first transition covering a change, if any
+func (a *LevelAnnouncements) Find(from, to string) (LevelTransition, bool) {
+	for _, transition := range a.Transitions {
+		if transition.Matches(from, to) {
+			return transition, true
+		}
+	}
+	return LevelTransition{}, false
+}
+
+// BackupReserve switches the inverter to a backup-reserve mode, which keeps
+// the battery charged for an outage, ahead of periods with a high risk of
+// losing the grid, and back to normal once the risk has passed
+type BackupReserve struct {
+	InverterModeEntity  string   `yaml:"inverter_mode_entity"` // select.* or input_select.* holding the inverter mode
+	BackupMode          string   `yaml:"backup_mode"`          // Option that holds the battery in reserve
+	NormalMode          string   `yaml:"normal_mode"`          // Option restored afterwards
+	RiskSensor          string   `yaml:"risk_sensor"`          // Optional utility outage-risk sensor, e.g. 0-100
+	RiskThreshold       float64  `yaml:"risk_threshold"`       // Risk sensor value at or above which risk is high
+	WeatherEntity       string   `yaml:"weather_entity"`       // Optional weather.* entity with a forecast attribute
+	RiskConditions      []string `yaml:"risk_conditions"`      // Weather conditions that count as high risk, e.g. "lightning"
+	WindSpeedThreshold  float64  `yaml:"wind_speed_threshold"` // Forecast wind speed at or above which risk is high; 0 ignores wind
+	LeadHours           int      `yaml:"lead_hours"`           // How far ahead the forecast is checked
+	RestoreAfterMinutes int      `yaml:"restore_after_minutes"`
+}
+
+// Lead returns how far ahead of a risky forecast period backup mode starts
+func (b *BackupReserve) Lead() time.Duration {
+	return time.Duration(b.LeadHours) * time.Hour
+}
+
+// RestoreAfter returns how long risk must stay low before normal mode is restored
+func (b *BackupReserve) RestoreAfter() time.Duration {
+	return time.Duration(b.RestoreAfterMinutes) * time.Minute
+}
+
+// IsRiskCondition reports whether a weather condition counts as high outage risk
+func (b *BackupReserve) IsRiskCondition(condition string) bool {
+	for _, c := range b.RiskConditions {
+		if c == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservePolicy sets the inverter's battery backup reserve, the charge held
+// back for an outage, by policy: a normal reserve, a higher one while a storm
+// watch state variable is on, and optionally a different one while the grid
+// is down. The reserve is only set when the policy changes, so a manual
+// adjustment holds until the next change.
+type ReservePolicy struct {
+	ReserveEntity      string   `yaml:"reserve_entity"`       // number.* or input_number.* holding the reserve percentage
+	NormalReservePct   float64  `yaml:"normal_reserve_pct"`   // Reserve in normal conditions
+	StormWatchVariable string   `yaml:"storm_watch_variable"` // Boolean state variable set during severe weather, e.g. isStormWatch
+	StormReservePct    float64  `yaml:"storm_reserve_pct"`    // Reserve while the storm watch is on
+	OutageReservePct   *float64 `yaml:"outage_reserve_pct"`   // Optional reserve while the grid is down; unset keeps the reserve in effect
+}
+
+// Validate checks the reserve entity and percentages
+func (r *ReservePolicy) Validate() error {
+	if domain := ha.EntityDomain(r.ReserveEntity); domain != "number" && domain != "input_number" {
+		return fmt.Errorf("reserve_policy.reserve_entity must be a number or input_number, got %q", r.ReserveEntity)
+	}
+	if r.NormalReservePct < 0 || r.NormalReservePct > 100 {
+		return fmt.Errorf("reserve_policy.normal_reserve_pct must be between 0 and 100")
+	}
+	if r.StormWatchVariable != "" && (r.StormReservePct < r.NormalReservePct || r.StormReservePct > 100) {
+		return fmt.Errorf("reserve_policy.storm_reserve_pct must be between normal_reserve_pct and 100")
+	}
+	if r.StormWatchVariable == "" && r.StormReservePct != 0 {
+		return fmt.Errorf("reserve_policy.storm_reserve_pct needs a storm_watch_variable")
+	}
+	if r.OutageReservePct != nil && (*r.OutageReservePct < 0 || *r.OutageReservePct > 100) {
+		return fmt.Errorf("reserve_policy.outage_reserve_pct must be between 0 and 100")
+	}
+	return nil
+}
+
+// Default battery safe mode settings
+const defaultSafeModeSlowdownFactor = 5
+
+// SafeMode suspends non-essential plugins and slows periodic evaluation while
+// the house runs on a low battery with the grid down. It turns on once the
+// battery drops below EnterBelowPct during an outage and off once the grid
+// returns or the battery recovers to ExitAbovePct.
+type SafeMode struct {
+	EnterBelowPct  float64  `yaml:"enter_below_pct"`
+	ExitAbovePct   float64  `yaml:"exit_above_pct"`
+	SuspendPlugins []string `yaml:"suspend_plugins"` // Plugin names as used by /api/plugins
+	SlowdownFactor int      `yaml:"slowdown_factor"` // Evaluation loops run every Nth tick; default 5
+}
+
+// SlowdownFactorOrDefault returns the slowdown factor, or the default if unset
+func (s *SafeMode) SlowdownFactorOrDefault() int {
+	if s.SlowdownFactor <= 0 {
+		return defaultSafeModeSlowdownFactor
+	}
+	return s.SlowdownFactor
+}
+
+// Validate checks the thresholds leave a gap between entering and leaving
+// safe mode, so it doesn't flap on a battery reading near one threshold
+func (s *SafeMode) Validate() error {
+	if s.EnterBelowPct <= 0 || s.EnterBelowPct >= 100 {
+		return fmt.Errorf("safe_mode.enter_below_pct must be between 0 and 100")
+	}
+	if s.ExitAbovePct <= s.EnterBelowPct || s.ExitAbovePct > 100 {
+		return fmt.Errorf("safe_mode.exit_above_pct must be above enter_below_pct and at most 100")
+	}
+	if s.SlowdownFactor < 0 {
+		return fmt.Errorf("safe_mode.slowdown_factor must not be negative")
+	}
+	for i, name := range s.SuspendPlugins {
+		if name == "" {
+			return fmt.Errorf("safe_mode.suspend_plugins[%d]: plugin name is required", i)
+		}
+		// The energy plugin is what ends safe mode
+		if name == "energy" {
+			return fmt.Errorf("safe_mode.suspend_plugins[%d]: the energy plugin can't be suspended", i)
+		}
+	}
+	return nil
+}
+
+// Default forecast look-ahead settings
+const (
+	defaultLookAheadRaiseLevels   = 1
+	defaultLookAheadMaxAgeMinutes = 360
+)
+
+// ForecastLookAhead raises currentEnergyLevel while the battery is low but the
+// solar forecast promises enough energy soon, e.g. 6 kWh in the next 2 hours,
+// so loads aren't shed only to have the battery refilled shortly after
+type ForecastLookAhead struct {
+	Hours         float64 `yaml:"hours"`           // How far ahead forecast energy is counted
+	MinKWh        float64 `yaml:"min_kwh"`         // Forecast energy within hours needed to raise the level
+	RaiseLevels   int     `yaml:"raise_levels"`    // Levels to raise by; default 1
+	MinBatteryPct float64 `yaml:"min_battery_pct"` // Never raise with the battery below this
+	MaxAgeMinutes int     `yaml:"max_age_minutes"` // Older forecasts are ignored; default 360
+}
+
+// Window returns how far ahead forecast energy is counted
+func (f *ForecastLookAhead) Window() time.Duration {
+	return time.Duration(f.Hours * float64(time.Hour))
+}
+
+// RaiseLevelsOrDefault returns the levels to raise by, or the default if unset
+func (f *ForecastLookAhead) RaiseLevelsOrDefault() int {
+	if f.RaiseLevels <= 0 {
+		return defaultLookAheadRaiseLevels
+	}
+	return f.RaiseLevels
+}
+
+// MaxAge returns the age beyond which a forecast is ignored
+func (f *ForecastLookAhead) MaxAge() time.Duration {
+	if f.MaxAgeMinutes <= 0 {
+		return defaultLookAheadMaxAgeMinutes * time.Minute
+	}
+	return time.Duration(f.MaxAgeMinutes) * time.Minute
+}
+
+// Validate checks the window and thresholds
+func (f *ForecastLookAhead) Validate() error {
+	if f.Hours <= 0 || f.Hours > 48 {
+		return fmt.Errorf("forecast_look_ahead.hours must be between 0 and 48")
+	}
+	if f.MinKWh <= 0 {
+		return fmt.Errorf("forecast_look_ahead.min_kwh must be positive")
+	}
+	if f.RaiseLevels < 0 {
+		return fmt.Errorf("forecast_look_ahead.raise_levels must not be negative")
+	}
+	if f.MinBatteryPct < 0 || f.MinBatteryPct >= 100 {
+		return fmt.Errorf("forecast_look_ahead.min_battery_pct must be between 0 and 100")
+	}
+	if f.MaxAgeMinutes < 0 {
+		return fmt.Errorf("forecast_look_ahead.max_age_minutes must not be negative")
+	}
+	return nil
+}
+
+// RateSchedule is a time-of-use electricity tariff. The current window sets
+// currentGridPrice and isPeakPricing, so plugins can avoid expensive hours
+// as well as low-battery ones.
+type RateSchedule struct {
+	Windows        []RateWindow `yaml:"windows"`          // First match wins
+	DefaultPrice   float64      `yaml:"default_price"`    // Price per kWh outside every window
+	PriceSensor    string       `yaml:"price_sensor"`     // Optional live price per kWh, e.g. from a dynamic tariff; overrides the scheduled price
+	PeakAbovePrice float64      `yaml:"peak_above_price"` // Optional: a live price at or above this is peak pricing; 0 only uses peak windows
+}
+
+// RateWindow is a priced time of day range, "HH:MM", on the listed days
+// (every day when none are listed). A window whose end is before its start
+// spans midnight and belongs to the day it starts on.
+type RateWindow struct {
+	Name  string   `yaml:"name"` // e.g. "peak", "off_peak"
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+	Days  []string `yaml:"days"` // e.g. ["monday", "friday"]
+	Price float64  `yaml:"price"`
+	Peak  bool     `yaml:"peak"`
+}
+
+// weekdays maps day names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Current returns the first window t falls within in loc, if any
+func (r *RateSchedule) Current(t time.Time, loc *time.Location) (RateWindow, bool) {
+	local := t.In(loc)
+	for _, window := range r.Windows {
+		if window.Contains(local) {
+			return window, true
+		}
+	}
+	return RateWindow{}, false
+}
+
+// Validate checks every window, and that prices aren't negative
+func (r *RateSchedule) Validate() error {
+	if r.DefaultPrice < 0 {
+		return fmt.Errorf("rate_schedule.default_price must not be negative")
+	}
+	if r.PeakAbovePrice < 0 {
+		return fmt.Errorf("rate_schedule.peak_above_price must not be negative")
+	}
+	if r.PeakAbovePrice > 0 && r.PriceSensor == "" {
+		return fmt.Errorf("rate_schedule.peak_above_price needs a price_sensor")
+	}
+	for i, window := range r.Windows {
+		if err := window.Validate(); err != nil {
+			return fmt.Errorf("rate_schedule.windows[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Contains reports whether t falls within the window. Invalid windows contain nothing.
+func (w RateWindow) Contains(t time.Time) bool {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	day := t.Weekday()
+	switch {
+	case endMinute > startMinute:
+		if minute < startMinute || minute >= endMinute {
+			return false
+		}
+	case minute >= startMinute:
+	case minute < endMinute:
+		// After midnight, the window started the day before
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return w.onDay(day)
+}
+
+// onDay reports whether the window runs on the weekday
+func (w RateWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the name, times, days and price
+func (w RateWindow) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("invalid start %q, expected HH:MM", w.Start)
+	}
+	if _, err := time.Parse("15:04", w.End); err != nil {
+		return fmt.Errorf("invalid end %q, expected HH:MM", w.End)
+	}
+	if w.Start == w.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	for _, name := range w.Days {
+		if _, ok := weekdays[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown day %q", name)
+		}
+	}
+	if w.Price < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+	return nil
+}
+
 // EnergyState represents a single energy state level
 type EnergyState struct {
 	ConditionName                       string      `yaml:"condition_name"`
@@ -35,6 +415,27 @@ type EnergyConfig struct {
 		FreeEnergyTime FreeEnergyTime `yaml:"free_energy_time"`
 		EnergyStates   []EnergyState  `yaml:"energy_states"`
 	} `yaml:"energy"`
+
+	// Optional announcements ahead of the free energy window
+	FreeEnergyAnnouncements *FreeEnergyAnnouncements `yaml:"free_energy_announcements"`
+
+	// Optional inverter backup-reserve switching ahead of outage risk
+	BackupReserve *BackupReserve `yaml:"backup_reserve"`
+
+	// Optional announcements of currentEnergyLevel changes
+	LevelAnnouncements *LevelAnnouncements `yaml:"level_announcements"`
+
+	// Optional battery safe mode during grid outages
+	SafeMode *SafeMode `yaml:"safe_mode"`
+
+	// Optional raise of currentEnergyLevel ahead of forecast solar production
+	ForecastLookAhead *ForecastLookAhead `yaml:"forecast_look_ahead"`
+
+	// Optional time-of-use electricity pricing
+	RateSchedule *RateSchedule `yaml:"rate_schedule"`
+
+	// Optional battery backup reserve policies
+	ReservePolicy *ReservePolicy `yaml:"reserve_policy"`
 }
 
 // LoadConfig loads the energy configuration from a YAML file
@@ -49,5 +450,29 @@ func LoadConfig(path string) (*EnergyConfig, error) {
 		return nil, err
 	}
 
+	if config.SafeMode != nil {
+		if err := config.SafeMode.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.ForecastLookAhead != nil {
+		if err := config.ForecastLookAhead.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.RateSchedule != nil {
+		if err := config.RateSchedule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.ReservePolicy != nil {
+		if err := config.ReservePolicy.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
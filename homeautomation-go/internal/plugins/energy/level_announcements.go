@@ -0,0 +1,154 @@
+package energy
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// SetNotificationRouter sets the router that speaks level announcements on
+// speakers at the verbosity the day phase allows. A nil router speaks in full.
+func (m *Manager) SetNotificationRouter(router *notifyrouter.Router) {
+	m.notifications = router
+}
+
+// checkLevelTransition announces a change of currentEnergyLevel if a
+// configured transition covers it. The first level seen after startup is only
+// remembered, so restarts don't announce.
+func (m *Manager) checkLevelTransition(level string, now time.Time) {
+	m.announceMu.Lock()
+	previous := m.lastLevel
+	m.lastLevel = level
+	m.announceMu.Unlock()
+
+	if previous == "" || previous == level {
+		return
+	}
+
+	announcements := m.currentConfig().LevelAnnouncements
+	if announcements == nil {
+		return
+	}
+	transition, ok := announcements.Find(previous, level)
+	if !ok {
+		return
+	}
+
+	m.announceLevelTransition(announcements, transition, previous, level, now)
+}
+
+// announceLevelTransition notifies and speaks one level change unless it falls
+// in quiet hours, and records the outcome in the shadow state
+func (m *Manager) announceLevelTransition(announcements *LevelAnnouncements, transition LevelTransition, from, to string, now time.Time) {
+	message := m.levelTransitionMessage(from, to, now)
+	record := shadowstate.EnergyLevelAnnouncement{
+		From:        from,
+		To:          to,
+		Message:     message,
+		EvaluatedAt: now,
+	}
+
+	if announcements.QuietHours != nil && announcements.QuietHours.Contains(now, m.timezone) {
+		record.SuppressedReason = AnnouncementSuppressedQuietHours
+		m.logger.Info("Skipping energy level announcement in quiet hours",
+			zap.String("from", from),
+			zap.String("to", to))
+		m.shadowTracker.RecordLevelAnnouncement(record)
+		return
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce energy level change",
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Bool("notify", transition.Notify),
+			zap.Strings("speakers", transition.Speakers),
+			zap.String("message", message))
+		m.shadowTracker.RecordLevelAnnouncement(record)
+		return
+	}
+
+	if transition.Notify {
+		if err := notifyrouter.SendPush(m.ctx, m.haClient, announcements.NotifyService, notifyrouter.Push{
+			Title:   "Energy level",
+			Message: message,
+		}); err != nil {
+			m.logger.Error("Failed to send energy level notification", zap.Error(err))
+		} else {
+			record.Notified = true
+		}
+	}
+
+	if len(transition.Speakers) > 0 {
+		level, err := m.notifications.Announce(m.ctx, m.haClient, notifyrouter.Announcement{
+			Title:    "Energy level",
+			Message:  message,
+			Speakers: transition.Speakers,
+			PushSent: record.Notified,
+		})
+		if err != nil {
+			m.logger.Error("Failed to announce energy level change", zap.Error(err))
+		} else {
+			record.SpokenLevel = string(level)
+		}
+	}
+
+	m.logger.Info("Announced energy level change",
+		zap.String("from", from),
+		zap.String("to", to),
+		zap.Bool("notified", record.Notified),
+		zap.String("spoken_level", record.SpokenLevel))
+	m.shadowTracker.RecordLevelAnnouncement(record)
+}
+
+// levelTransitionMessage describes a level change with the battery charge and
+// when free energy next starts (or ends, during the window)
+func (m *Manager) levelTransitionMessage(from, to string, now time.Time) string {
+	direction := "changed"
+	levels := m.levelNames()
+	if fromIndex, toIndex := slices.Index(levels, from), slices.Index(levels, to); fromIndex >= 0 && toIndex >= 0 {
+		if toIndex < fromIndex {
+			direction = "dropped"
+		} else {
+			direction = "rose"
+		}
+	}
+	message := fmt.Sprintf("Energy level %s from %s to %s", direction, from, to)
+
+	if readings := m.shadowTracker.GetState().Outputs.SensorReadings; !readings.LastUpdate.IsZero() {
+		message += fmt.Sprintf(", battery at %.0f%%", readings.BatteryPercentage)
+	}
+
+	window := m.currentConfig().Energy.FreeEnergyTime
+	if isFreeEnergy, err := m.stateManager.GetBool("isFreeEnergyAvailable"); err == nil && isFreeEnergy {
+		if end, ok := nextDailyTime(now, window.End, m.timezone); ok {
+			message += fmt.Sprintf(", free energy until %s", end.Format("15:04"))
+		}
+	} else if start, ok := nextDailyTime(now, window.Start, m.timezone); ok {
+		message += fmt.Sprintf(", free energy in %s (%s)", formatWait(start.Sub(now)), start.Format("15:04"))
+	}
+	return message
+}
+
+// levelNames returns the configured energy levels, lowest first
+func (m *Manager) levelNames() []string {
+	var names []string
+	for _, energyState := range m.currentConfig().Energy.EnergyStates {
+		names = append(names, energyState.ConditionName)
+	}
+	return names
+}
+
+// formatWait formats a wait as e.g. "5h 20m" or "45m"
+func formatWait(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
@@ -0,0 +1,138 @@
+package energy
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newLevelAnnouncementTestManager returns a manager announcing drops into red
+// by push and speaker and recoveries from red by push, quiet 22:30-06:30
+func newLevelAnnouncementTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetBool("isFreeEnergyAvailable", false))
+	mockClient.ClearServiceCalls()
+
+	config := createTestConfig()
+	config.LevelAnnouncements = &LevelAnnouncements{
+		NotifyService: "notify.notify",
+		QuietHours:    &QuietHours{Start: "22:30", End: "06:30"},
+		Transitions: []LevelTransition{
+			{From: "*", To: "red", Notify: true, Speakers: []string{"media_player.kitchen"}},
+			{From: "red", To: "green", Notify: true},
+		},
+	}
+
+	return NewManager(mockClient, stateManager, config, logger, readOnly, time.UTC, nil), mockClient
+}
+
+func ttsCalls(mockClient *ha.MockClient) []ha.ServiceCall {
+	calls := make([]ha.ServiceCall, 0)
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "tts" && call.Service == "speak" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestLevelAnnouncements_DropAndRecovery(t *testing.T) {
+	manager, mockClient := newLevelAnnouncementTestManager(t, false)
+	manager.handleBatteryChange(38)
+	mockClient.ClearServiceCalls()
+	afternoon := time.Date(2026, 3, 10, 15, 40, 0, 0, time.UTC)
+
+	// The first level after startup is only remembered
+	manager.checkLevelTransition("yellow", afternoon)
+	assert.Empty(t, notifyCalls(mockClient))
+
+	manager.checkLevelTransition("red", afternoon)
+	calls := notifyCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "Energy level dropped from yellow to red, battery at 38%, free energy in 5h 20m (21:00)", calls[0].Data["message"])
+	assert.Len(t, ttsCalls(mockClient), 1, "speakers announce through the notification router")
+
+	// Unchanged levels don't repeat
+	manager.checkLevelTransition("red", afternoon.Add(time.Minute))
+	assert.Len(t, notifyCalls(mockClient), 1)
+
+	manager.checkLevelTransition("green", afternoon.Add(2*time.Hour))
+	calls = notifyCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[1].Data["message"], "Energy level rose from red to green")
+
+	announcement := manager.GetShadowState().Outputs.LastLevelAnnouncement
+	require.NotNil(t, announcement)
+	assert.Equal(t, "red", announcement.From)
+	assert.Equal(t, "green", announcement.To)
+	assert.True(t, announcement.Notified)
+	assert.Empty(t, announcement.SpokenLevel)
+}
+
+func TestLevelAnnouncements_UnconfiguredTransition(t *testing.T) {
+	manager, mockClient := newLevelAnnouncementTestManager(t, false)
+	afternoon := time.Date(2026, 3, 10, 15, 40, 0, 0, time.UTC)
+
+	manager.checkLevelTransition("green", afternoon)
+	manager.checkLevelTransition("yellow", afternoon)
+	manager.checkLevelTransition("white", afternoon)
+
+	assert.Empty(t, mockClient.GetServiceCalls())
+	assert.Nil(t, manager.GetShadowState().Outputs.LastLevelAnnouncement)
+}
+
+func TestLevelAnnouncements_QuietHours(t *testing.T) {
+	manager, mockClient := newLevelAnnouncementTestManager(t, false)
+	night := time.Date(2026, 3, 10, 23, 15, 0, 0, time.UTC)
+
+	manager.checkLevelTransition("yellow", night)
+	manager.checkLevelTransition("red", night)
+
+	assert.Empty(t, mockClient.GetServiceCalls())
+	announcement := manager.GetShadowState().Outputs.LastLevelAnnouncement
+	require.NotNil(t, announcement)
+	assert.Equal(t, AnnouncementSuppressedQuietHours, announcement.SuppressedReason)
+	assert.False(t, announcement.Notified)
+}
+
+func TestLevelAnnouncements_DuringFreeEnergy(t *testing.T) {
+	manager, mockClient := newLevelAnnouncementTestManager(t, false)
+	require.NoError(t, manager.stateManager.SetBool("isFreeEnergyAvailable", true))
+	mockClient.ClearServiceCalls()
+	morning := time.Date(2026, 3, 10, 6, 40, 0, 0, time.UTC)
+
+	manager.checkLevelTransition("red", morning)
+	manager.checkLevelTransition("green", morning)
+
+	calls := notifyCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Contains(t, calls[0].Data["message"], "free energy until 07:00")
+}
+
+func TestLevelAnnouncements_ReadOnly(t *testing.T) {
+	manager, mockClient := newLevelAnnouncementTestManager(t, true)
+	afternoon := time.Date(2026, 3, 10, 15, 40, 0, 0, time.UTC)
+
+	manager.checkLevelTransition("yellow", afternoon)
+	manager.checkLevelTransition("red", afternoon)
+
+	assert.Empty(t, mockClient.GetServiceCalls())
+	announcement := manager.GetShadowState().Outputs.LastLevelAnnouncement
+	require.NotNil(t, announcement)
+	assert.False(t, announcement.Notified)
+}
+
+func TestLevelTransition_Matches(t *testing.T) {
+	assert.True(t, LevelTransition{From: "*", To: "red"}.Matches("yellow", "red"))
+	assert.True(t, LevelTransition{From: "red", To: "*"}.Matches("red", "green"))
+	assert.False(t, LevelTransition{From: "red", To: "green"}.Matches("black", "green"))
+}
@@ -0,0 +1,137 @@
+package energy
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"homeautomation/internal/forecast"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// lookAheadResult is one evaluation of the forecast look-ahead
+type lookAheadResult struct {
+	raise     bool
+	reason    string
+	kwh       float64
+	fetchedAt time.Time
+}
+
+// evaluateLookAhead reports whether the solar forecast promises enough energy
+// within the look-ahead window to raise the energy level
+func (m *Manager) evaluateLookAhead(config *ForecastLookAhead, now time.Time) lookAheadResult {
+	fc, err := forecast.Load(m.stateManager)
+	if err != nil {
+		m.logger.Warn("Failed to read solar forecast", zap.Error(err))
+		return lookAheadResult{reason: "solar forecast unreadable"}
+	}
+	if fc == nil {
+		return lookAheadResult{reason: "no solar forecast yet"}
+	}
+
+	result := lookAheadResult{
+		kwh:       fc.EnergyBetween(now, now.Add(config.Window())),
+		fetchedAt: fc.FetchedAt,
+	}
+	if age := now.Sub(fc.FetchedAt); age > config.MaxAge() {
+		result.reason = fmt.Sprintf("solar forecast is %s old, older than %s", age.Round(time.Minute), config.MaxAge())
+		return result
+	}
+
+	if config.MinBatteryPct > 0 {
+		m.safeModeMu.Lock()
+		battery := m.safeModeBattery
+		m.safeModeMu.Unlock()
+		switch {
+		case battery == nil:
+			result.reason = "battery level unknown"
+			return result
+		case *battery < config.MinBatteryPct:
+			result.reason = fmt.Sprintf("battery at %.0f%%, below %.0f%%", *battery, config.MinBatteryPct)
+			return result
+		}
+	}
+
+	if result.kwh < config.MinKWh {
+		result.reason = fmt.Sprintf("%.1f kWh forecast in the next %gh, below %.1f kWh", result.kwh, config.Hours, config.MinKWh)
+		return result
+	}
+	result.raise = true
+	result.reason = fmt.Sprintf("%.1f kWh forecast in the next %gh", result.kwh, config.Hours)
+	return result
+}
+
+// applyForecastLookAhead raises the overall level by raise_levels while the
+// forecast promises enough solar energy soon. The highest level is never
+// reached this way, as it stands for free grid energy.
+func (m *Manager) applyForecastLookAhead(level string, now time.Time) string {
+	config := m.currentConfig()
+	lookAhead := config.ForecastLookAhead
+	if lookAhead == nil {
+		m.setLookAheadMet(false)
+		return level
+	}
+
+	result := m.evaluateLookAhead(lookAhead, now)
+	decision := shadowstate.ForecastLookAheadDecision{
+		Reason:            result.reason,
+		ForecastKWh:       result.kwh,
+		WindowHours:       lookAhead.Hours,
+		FromLevel:         level,
+		ToLevel:           level,
+		ForecastFetchedAt: result.fetchedAt,
+		EvaluatedAt:       now,
+	}
+
+	if result.raise {
+		var levelNames []string
+		for _, state := range config.Energy.EnergyStates {
+			levelNames = append(levelNames, state.ConditionName)
+		}
+		index := slices.Index(levelNames, level)
+		target := min(index+lookAhead.RaiseLevelsOrDefault(), len(levelNames)-2)
+		if index >= 0 && target > index {
+			decision.Raised = true
+			decision.ToLevel = levelNames[target]
+		}
+	}
+
+	m.setLookAheadMet(result.raise)
+	m.shadowTracker.RecordForecastLookAheadDecision(decision)
+
+	if decision.Raised {
+		m.logger.Info("Raising energy level ahead of forecast solar production",
+			zap.String("from", decision.FromLevel),
+			zap.String("to", decision.ToLevel),
+			zap.String("reason", decision.Reason))
+	}
+	return decision.ToLevel
+}
+
+// checkForecastLookAhead recalculates the overall level when the look-ahead
+// decision changes as the window moves through the forecast
+func (m *Manager) checkForecastLookAhead(now time.Time) {
+	lookAhead := m.currentConfig().ForecastLookAhead
+	if lookAhead == nil {
+		return
+	}
+
+	met := m.evaluateLookAhead(lookAhead, now).raise
+	m.lookAheadMu.Lock()
+	changed := met != m.lookAheadMet
+	m.lookAheadMet = met
+	m.lookAheadMu.Unlock()
+	if changed {
+		m.recalculateOverallEnergyLevel()
+	}
+}
+
+// setLookAheadMet records whether the forecast met the look-ahead threshold
+// when the level was last calculated
+func (m *Manager) setLookAheadMet(met bool) {
+	m.lookAheadMu.Lock()
+	m.lookAheadMet = met
+	m.lookAheadMu.Unlock()
+}
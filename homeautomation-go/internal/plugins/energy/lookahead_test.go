@@ -0,0 +1,140 @@
+package energy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/forecast"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newLookAheadTestManager returns a manager raising the level by one while
+// 6 kWh are forecast in the next 2 hours, with battery and solar both red
+func newLookAheadTestManager(t *testing.T) (*Manager, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetString("batteryEnergyLevel", "red"))
+	require.NoError(t, stateManager.SetString("solarProductionEnergyLevel", "red"))
+
+	config := createTestConfig()
+	config.ForecastLookAhead = &ForecastLookAhead{Hours: 2, MinKWh: 6, MinBatteryPct: 25}
+
+	manager := NewManager(mockClient, stateManager, config, logger, false, time.UTC, nil)
+	manager.recordSafeModeBattery(45)
+	return manager, stateManager
+}
+
+// publishForecast publishes a forecast of kwh in the hour starting after
+// offset, fetched at fetchedAt
+func publishForecast(t *testing.T, stateManager *state.Manager, offset time.Duration, kwh float64, fetchedAt time.Time) {
+	t.Helper()
+	start := time.Now().Add(offset)
+	require.NoError(t, forecast.Publish(stateManager, &forecast.Forecast{
+		Provider:  "test",
+		FetchedAt: fetchedAt,
+		Periods:   []forecast.Period{{Start: start, End: start.Add(time.Hour), KWh: kwh}},
+	}))
+}
+
+func TestForecastLookAhead_RaisesLevelAheadOfSun(t *testing.T) {
+	manager, stateManager := newLookAheadTestManager(t)
+
+	manager.recalculateOverallEnergyLevel()
+	level, _ := stateManager.GetString("currentEnergyLevel")
+	assert.Equal(t, "red", level, "no forecast yet")
+
+	publishForecast(t, stateManager, 30*time.Minute, 8, time.Now())
+	manager.recalculateOverallEnergyLevel()
+
+	level, _ = stateManager.GetString("currentEnergyLevel")
+	assert.Equal(t, "yellow", level)
+
+	decision := manager.GetShadowState().Outputs.ForecastLookAhead
+	require.NotNil(t, decision)
+	assert.True(t, decision.Raised)
+	assert.Equal(t, "red", decision.FromLevel)
+	assert.Equal(t, "yellow", decision.ToLevel)
+	assert.InDelta(t, 8, decision.ForecastKWh, 0.01)
+}
+
+func TestForecastLookAhead_DoesNotRaise(t *testing.T) {
+	tests := []struct {
+		name    string
+		offset  time.Duration
+		kwh     float64
+		age     time.Duration
+		battery float64
+		reason  string
+	}{
+		{name: "too little sun", offset: 30 * time.Minute, kwh: 4, battery: 45, reason: "below 6.0 kWh"},
+		{name: "sun too late", offset: 3 * time.Hour, kwh: 8, battery: 45, reason: "0.0 kWh forecast"},
+		{name: "stale forecast", offset: 30 * time.Minute, kwh: 8, age: 7 * time.Hour, battery: 45, reason: "old"},
+		{name: "battery too low", offset: 30 * time.Minute, kwh: 8, battery: 20, reason: "battery at 20%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, stateManager := newLookAheadTestManager(t)
+			manager.recordSafeModeBattery(tt.battery)
+			publishForecast(t, stateManager, tt.offset, tt.kwh, time.Now().Add(-tt.age))
+
+			manager.recalculateOverallEnergyLevel()
+
+			level, _ := stateManager.GetString("currentEnergyLevel")
+			assert.Equal(t, "red", level)
+			decision := manager.GetShadowState().Outputs.ForecastLookAhead
+			require.NotNil(t, decision)
+			assert.False(t, decision.Raised)
+			assert.Contains(t, decision.Reason, tt.reason)
+		})
+	}
+}
+
+func TestForecastLookAhead_NeverRaisesToHighestLevel(t *testing.T) {
+	manager, stateManager := newLookAheadTestManager(t)
+	require.NoError(t, stateManager.SetString("batteryEnergyLevel", "green"))
+	require.NoError(t, stateManager.SetString("solarProductionEnergyLevel", "green"))
+	publishForecast(t, stateManager, 0, 8, time.Now())
+
+	manager.recalculateOverallEnergyLevel()
+
+	level, _ := stateManager.GetString("currentEnergyLevel")
+	assert.Equal(t, "green", level, "white is left to free energy")
+	assert.False(t, manager.GetShadowState().Outputs.ForecastLookAhead.Raised)
+}
+
+func TestForecastLookAhead_CheckRecalculatesWhenForecastChanges(t *testing.T) {
+	manager, stateManager := newLookAheadTestManager(t)
+	manager.recalculateOverallEnergyLevel()
+
+	// Not subscribed, so only the periodic check picks the forecast up
+	publishForecast(t, stateManager, 0, 8, time.Now())
+	manager.checkForecastLookAhead(time.Now())
+
+	level, _ := stateManager.GetString("currentEnergyLevel")
+	assert.Equal(t, "yellow", level)
+}
+
+func TestLoadConfig_ForecastLookAhead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "energy_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("forecast_look_ahead:\n  hours: 2\n  min_kwh: 6\n"), 0o644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, config.ForecastLookAhead.Window())
+	assert.Equal(t, defaultLookAheadRaiseLevels, config.ForecastLookAhead.RaiseLevelsOrDefault())
+	assert.Equal(t, defaultLookAheadMaxAgeMinutes*time.Minute, config.ForecastLookAhead.MaxAge())
+
+	require.NoError(t, os.WriteFile(path, []byte("forecast_look_ahead:\n  hours: 2\n"), 0o644))
+	_, err = LoadConfig(path)
+	assert.EqualError(t, err, "forecast_look_ahead.min_kwh must be positive")
+}
@@ -1,13 +1,21 @@
 package energy
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"homeautomation/internal/entities"
+	"homeautomation/internal/forecast"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/safemode"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -18,19 +26,56 @@ import (
 type Manager struct {
 	haClient     ha.HAClient
 	stateManager *state.Manager
-	config       *EnergyConfig
+	config       atomic.Pointer[EnergyConfig] // Swapped by Reload
 	logger       *zap.Logger
 	readOnly     bool
 	timezone     *time.Location
+	entities     *entities.Registry // Battery and solar forecast sensors; nil uses the defaults
 
 	// Control for free energy checker
 	stopChecker chan struct{}
 
+	// Window boundary each free energy announcement was last made for, and
+	// the last currentEnergyLevel seen for level announcements
+	lastAnnounced map[string]time.Time
+	lastLevel     string
+	announceMu    sync.Mutex
+
+	// Speaks level announcements, nil speaks in full
+	notifications *notifyrouter.Router
+
+	// Inverter backup-reserve switching
+	reserve   backupReserveState
+	reserveMu sync.Mutex
+
+	// Battery safe mode, published on a switch shared with other plugins.
+	// The latest battery reading is protected by safeModeMu.
+	safeMode        *safemode.Switch
+	safeModeBattery *float64
+	safeModeMu      sync.Mutex
+
+	// Whether the solar forecast met the look-ahead threshold when the level
+	// was last calculated (protected by lookAheadMu)
+	lookAheadMet bool
+	lookAheadMu  sync.Mutex
+
+	// Reserve policy the inverter's backup reserve was last set for, empty
+	// until the first check and after a reload (protected by reservePolicyMu)
+	reservePolicy   string
+	reservePolicyMu sync.Mutex
+
 	// Shadow state tracking
 	shadowTracker *shadowstate.EnergyTracker
 
 	// Subscription helper for automatic shadow state input capture
 	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new Energy State manager
@@ -42,17 +87,25 @@ func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Energ
 
 	shadowTracker := shadowstate.NewEnergyTracker()
 
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(time.Minute)
+
 	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
 		haClient:      haClient,
 		stateManager:  stateManager,
-		config:        config,
-		logger:        logger.Named("energy"),
+		logger:        recorder.Logger(logger.Named("energy")),
+		health:        recorder,
 		readOnly:      readOnly,
 		timezone:      timezone,
 		stopChecker:   make(chan struct{}),
+		lastAnnounced: make(map[string]time.Time),
 		shadowTracker: shadowTracker,
 		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "energy", logger.Named("energy")),
 	}
+	m.config.Store(config)
 
 	return m
 }
@@ -62,22 +115,40 @@ func (m *Manager) GetShadowState() *shadowstate.EnergyShadowState {
 	return m.shadowTracker.GetState()
 }
 
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// SetEntities sets the registry the battery and solar forecast sensors are
+// looked up in. It must be called before Start.
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
 // Start begins monitoring energy state
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
 	m.logger.Info("Starting Energy State Manager")
 
+	// Run under ctx; a re-enabled plugin also needs a new checker channel
+	if m.ctx.Err() != nil {
+		m.stopChecker = make(chan struct{})
+	}
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	// Subscribe to battery level changes (shadow inputs captured automatically)
-	if err := m.subHelper.SubscribeToSensor("sensor.span_panel_span_storage_battery_percentage_2", m.handleBatteryChange); err != nil {
+	if err := m.subHelper.SubscribeToSensor(m.entities.BatteryLevel(), m.handleBatteryChange); err != nil {
 		return fmt.Errorf("failed to subscribe to battery sensor: %w", err)
 	}
 
 	// Subscribe to this hour solar generation
-	if err := m.subHelper.SubscribeToSensor("sensor.energy_next_hour", m.handleThisHourSolarChange); err != nil {
+	if err := m.subHelper.SubscribeToSensor(m.entities.SolarNextHour(), m.handleThisHourSolarChange); err != nil {
 		return fmt.Errorf("failed to subscribe to this hour solar sensor: %w", err)
 	}
 
 	// Subscribe to remaining solar generation
-	if err := m.subHelper.SubscribeToSensor("sensor.energy_production_today_remaining", m.handleRemainingSolarChange); err != nil {
+	if err := m.subHelper.SubscribeToSensor(m.entities.SolarRemainingToday(), m.handleRemainingSolarChange); err != nil {
 		return fmt.Errorf("failed to subscribe to remaining solar sensor: %w", err)
 	}
 
@@ -99,12 +170,28 @@ func (m *Manager) Start() error {
 		return fmt.Errorf("failed to subscribe to free energy available: %w", err)
 	}
 
+	if err := m.subHelper.SubscribeToState(forecast.StateKey, m.handleIntermediateLevelChange); err != nil {
+		return fmt.Errorf("failed to subscribe to solar forecast: %w", err)
+	}
+
+	// Re-check the reserve policy as soon as the storm watch changes
+	if policy := m.currentConfig().ReservePolicy; policy != nil && policy.StormWatchVariable != "" {
+		if err := m.subHelper.SubscribeToState(policy.StormWatchVariable, m.handleReservePolicyInputChange); err != nil {
+			m.logger.Warn("Failed to subscribe to storm watch variable",
+				zap.String("variable", policy.StormWatchVariable),
+				zap.Error(err))
+		}
+	}
+
 	// Start free energy check timer (check every minute)
-	go m.runFreeEnergyChecker()
+	go m.runFreeEnergyChecker(m.stopChecker)
 
 	// Capture initial shadow state inputs after all subscriptions are registered
 	m.captureInitialInputs()
 
+	m.evaluateSafeMode(time.Now())
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
 	m.logger.Info("Energy State Manager started successfully")
 	return nil
 }
@@ -123,17 +210,30 @@ func (m *Manager) captureInitialInputs() {
 
 // Stop stops the Energy State Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping Energy State Manager")
 
+	m.cancel()
+
 	// Stop the free energy checker goroutine
 	close(m.stopChecker)
 
 	// Unsubscribe from all subscriptions via helper
 	m.subHelper.UnsubscribeAll()
 
+	// Nothing would end safe mode while the plugin is stopped
+	if m.safeMode.Exit("energy plugin stopped", time.Now()) {
+		m.logger.Info("Left battery safe mode: energy plugin stopped")
+	}
+
 	m.logger.Info("Energy State Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handleBatteryChange processes battery percentage changes
 func (m *Manager) handleBatteryChange(percentage float64) {
 	m.logger.Info("Battery level changed",
@@ -149,6 +249,9 @@ func (m *Manager) handleBatteryChange(percentage float64) {
 	// Update shadow state sensor reading for battery
 	m.shadowTracker.UpdateBatteryPercentage(percentage)
 
+	m.recordSafeModeBattery(percentage)
+	m.evaluateSafeMode(time.Now())
+
 	// Determine battery energy level
 	level := m.determineBatteryEnergyLevel(percentage)
 	if level == "" {
@@ -257,7 +360,7 @@ func (m *Manager) handleGridAvailabilityChange(key string, oldValue, newValue in
 			zap.Bool("grid_available", gridAvailable))
 	} else {
 		// Explicitly sync to Home Assistant to ensure bidirectional consistency
-		if err := m.haClient.SetInputBoolean("grid_available", gridAvailable); err != nil {
+		if err := m.haClient.SetInputBoolean(m.ctx, "grid_available", gridAvailable); err != nil {
 			m.logger.Error("Failed to sync grid availability to Home Assistant",
 				zap.Bool("grid_available", gridAvailable),
 				zap.Error(err))
@@ -270,6 +373,9 @@ func (m *Manager) handleGridAvailabilityChange(key string, oldValue, newValue in
 
 	// Trigger free energy recalculation
 	m.checkFreeEnergy()
+
+	m.evaluateSafeMode(time.Now())
+	m.checkReservePolicy(time.Now())
 }
 
 // handleIntermediateLevelChange recalculates overall energy level when intermediate levels change
@@ -292,7 +398,7 @@ func (m *Manager) determineBatteryEnergyLevel(percentage float64) string {
 	}
 
 	var levels []levelThreshold
-	for _, state := range m.config.Energy.EnergyStates {
+	for _, state := range m.currentConfig().Energy.EnergyStates {
 		if !math.IsNaN(state.BatteryMinimumPercentage) && !math.IsInf(state.BatteryMinimumPercentage, 0) {
 			levels = append(levels, levelThreshold{
 				name:      state.ConditionName,
@@ -362,7 +468,7 @@ func (m *Manager) determineSolarEnergyLevel(thisHourKW, remainingKWH float64) st
 	level := "black"
 
 	// Check each energy state in order (they should already be ordered in config)
-	for _, state := range m.config.Energy.EnergyStates {
+	for _, state := range m.currentConfig().Energy.EnergyStates {
 		// Both conditions must be met
 		if thisHourKW >= state.EnergyProductionMinimumKW &&
 			remainingKWH >= state.RemainingEnergyProductionMinimumKWH {
@@ -399,6 +505,7 @@ func (m *Manager) recalculateOverallEnergyLevel() {
 		}
 		// Update shadow state
 		m.shadowTracker.UpdateOverallLevel("white")
+		m.checkLevelTransition("white", time.Now())
 		return
 	}
 
@@ -416,6 +523,7 @@ func (m *Manager) recalculateOverallEnergyLevel() {
 	}
 
 	overallLevel := m.determineOverallEnergyLevel(batteryLevel, solarLevel)
+	overallLevel = m.applyForecastLookAhead(overallLevel, time.Now())
 
 	m.logger.Info("Determined overall energy level",
 		zap.String("battery_level", batteryLevel),
@@ -434,13 +542,14 @@ func (m *Manager) recalculateOverallEnergyLevel() {
 
 	// Update shadow state
 	m.shadowTracker.UpdateOverallLevel(overallLevel)
+	m.checkLevelTransition(overallLevel, time.Now())
 }
 
 // determineOverallEnergyLevel combines battery and solar levels
 func (m *Manager) determineOverallEnergyLevel(batteryLevel, solarLevel string) string {
 	// Extract ordered list of level names
 	var levelNames []string
-	for _, state := range m.config.Energy.EnergyStates {
+	for _, state := range m.currentConfig().Energy.EnergyStates {
 		levelNames = append(levelNames, state.ConditionName)
 	}
 
@@ -503,19 +612,31 @@ func (m *Manager) determineOverallEnergyLevel(batteryLevel, solarLevel string) s
 	return result
 }
 
-// runFreeEnergyChecker runs the free energy checker every minute
-func (m *Manager) runFreeEnergyChecker() {
+// runFreeEnergyChecker runs the free energy checker every minute until stop is closed
+func (m *Manager) runFreeEnergyChecker(stop <-chan struct{}) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
 	// Check immediately on start
 	m.checkFreeEnergy()
+	m.checkFreeEnergyAnnouncements(time.Now())
+	m.checkBackupReserve(time.Now())
+	m.checkForecastLookAhead(time.Now())
+	m.checkGridPricing(time.Now())
+	m.checkReservePolicy(time.Now())
+	m.health.Tick()
 
 	for {
 		select {
 		case <-ticker.C:
 			m.checkFreeEnergy()
-		case <-m.stopChecker:
+			m.checkFreeEnergyAnnouncements(time.Now())
+			m.checkBackupReserve(time.Now())
+			m.checkForecastLookAhead(time.Now())
+			m.checkGridPricing(time.Now())
+			m.checkReservePolicy(time.Now())
+			m.health.Tick()
+		case <-stop:
 			m.logger.Info("Stopping free energy checker")
 			return
 		}
@@ -562,6 +683,7 @@ func (m *Manager) checkFreeEnergy() {
 
 // isFreeEnergyTime checks if current time is within free energy window
 func (m *Manager) isFreeEnergyTime(isGridAvailable bool) bool {
+	config := m.currentConfig()
 	if !isGridAvailable {
 		m.logger.Debug("Grid is not available, no free energy")
 		return false
@@ -571,13 +693,13 @@ func (m *Manager) isFreeEnergyTime(isGridAvailable bool) bool {
 	now := time.Now().In(m.timezone)
 
 	// Parse times (format: "21:00")
-	startTime, err := time.Parse("15:04", m.config.Energy.FreeEnergyTime.Start)
+	startTime, err := time.Parse("15:04", config.Energy.FreeEnergyTime.Start)
 	if err != nil {
 		m.logger.Error("Failed to parse free energy start time", zap.Error(err))
 		return false
 	}
 
-	endTime, err := time.Parse("15:04", m.config.Energy.FreeEnergyTime.End)
+	endTime, err := time.Parse("15:04", config.Energy.FreeEnergyTime.End)
 	if err != nil {
 		m.logger.Error("Failed to parse free energy end time", zap.Error(err))
 		return false
@@ -625,6 +747,16 @@ func (m *Manager) Reset() error {
 	// Recalculate overall energy level based on current battery and solar levels
 	m.recalculateOverallEnergyLevel()
 
+	// Re-assess outage risk and the inverter mode
+	m.checkBackupReserve(time.Now())
+
+	// Re-publish the grid price
+	m.checkGridPricing(time.Now())
+
+	// Re-apply the battery reserve policy
+	m.resetReservePolicy()
+	m.checkReservePolicy(time.Now())
+
 	m.logger.Info("Successfully reset Energy State")
 	return nil
 }
@@ -1,6 +1,7 @@
 package energy
 
 import (
+	"context"
 	"math"
 	"testing"
 	"time"
@@ -280,7 +281,7 @@ func TestManagerStartAndHandlers(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, config, logger, false, nil, nil)
 
 	// Test Start method
-	err = manager.Start()
+	err = manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Give goroutines time to start
@@ -442,12 +443,12 @@ func TestEnergyManager_Stop(t *testing.T) {
 	_ = stateManager.SetBool("isFreeEnergyAvailable", false)
 
 	// Start manager (creates subscriptions and goroutine)
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Verify subscriptions were created via subHelper
 	assert.Equal(t, 3, len(manager.subHelper.GetHASubscriptions()), "Should have 3 HA subscriptions")
-	assert.Equal(t, 4, len(manager.subHelper.GetStateSubscriptions()), "Should have 4 state subscriptions")
+	assert.Equal(t, 5, len(manager.subHelper.GetStateSubscriptions()), "Should have 5 state subscriptions")
 
 	// Stop manager
 	manager.Stop()
@@ -552,14 +553,14 @@ func TestManagerReset(t *testing.T) {
 	// Set up initial state
 	mockClient.SetState("sensor.battery_energy_level", "50", map[string]interface{}{})
 	mockClient.SetState("sensor.solar_production_energy_level", "75", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	err := stateManager.SyncFromHA()
 	assert.NoError(t, err)
 
 	manager := NewManager(mockClient, stateManager, config, logger, false, nil, nil)
 
-	err = manager.Start()
+	err = manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -0,0 +1,107 @@
+package energy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// Sources of the published grid price
+const (
+	PriceSourceSchedule = "schedule"
+	PriceSourceSensor   = "sensor"
+)
+
+// checkGridPricing publishes the current grid price and whether it is peak
+// pricing to currentGridPrice and isPeakPricing. The scheduled window sets
+// both; a live price sensor, when readable, replaces the price and can raise
+// peak pricing at or above peak_above_price.
+func (m *Manager) checkGridPricing(now time.Time) {
+	config := m.currentConfig().RateSchedule
+	if config == nil {
+		return
+	}
+
+	decision := m.assessGridPrice(config, now)
+
+	wasPeak, err := m.stateManager.GetBool("isPeakPricing")
+	if err != nil {
+		m.logger.Error("Failed to get isPeakPricing", zap.Error(err))
+		return
+	}
+	if wasPeak != decision.Peak {
+		m.logger.Info("Peak pricing changed",
+			zap.Bool("peak", decision.Peak),
+			zap.String("window", decision.Window),
+			zap.Float64("price", decision.Price),
+			zap.String("reason", decision.Reason))
+	}
+
+	if err := m.stateManager.SetNumber("currentGridPrice", decision.Price); err != nil {
+		m.logger.Error("Failed to set currentGridPrice", zap.Error(err))
+	}
+	if err := m.stateManager.SetBool("isPeakPricing", decision.Peak); err != nil {
+		m.logger.Error("Failed to set isPeakPricing", zap.Error(err))
+	}
+
+	m.shadowTracker.RecordGridPricingDecision(decision)
+}
+
+// assessGridPrice works out the price and peak pricing in effect at now
+func (m *Manager) assessGridPrice(config *RateSchedule, now time.Time) shadowstate.GridPricingDecision {
+	decision := shadowstate.GridPricingDecision{
+		Window:      "default",
+		Price:       config.DefaultPrice,
+		Source:      PriceSourceSchedule,
+		Reason:      "outside every rate window",
+		EvaluatedAt: now,
+	}
+	if window, ok := config.Current(now, m.timezone); ok {
+		decision.Window = window.Name
+		decision.Price = window.Price
+		decision.Peak = window.Peak
+		decision.Reason = fmt.Sprintf("in the %s window (%s-%s)", window.Name, window.Start, window.End)
+	}
+
+	if config.PriceSensor == "" {
+		return decision
+	}
+
+	price, ok := m.readPriceSensor(config.PriceSensor)
+	if !ok {
+		decision.Reason += ", price sensor unavailable"
+		return decision
+	}
+	decision.SensorPrice = &price
+	decision.Price = price
+	decision.Source = PriceSourceSensor
+	if config.PeakAbovePrice > 0 && price >= config.PeakAbovePrice && !decision.Peak {
+		decision.Peak = true
+		decision.Reason = fmt.Sprintf("live price %g at or above %g", price, config.PeakAbovePrice)
+	}
+	return decision
+}
+
+// readPriceSensor reads the live price, reporting whether it is a usable number
+func (m *Manager) readPriceSensor(entityID string) (float64, bool) {
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		m.logger.Warn("Failed to read price sensor",
+			zap.String("entity_id", entityID),
+			zap.Error(err))
+		return 0, false
+	}
+	price, err := strconv.ParseFloat(st.State, 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		m.logger.Warn("Price sensor is not a number",
+			zap.String("entity_id", entityID),
+			zap.String("value", st.State))
+		return 0, false
+	}
+	return price, true
+}
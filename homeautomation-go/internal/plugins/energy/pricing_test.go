@@ -0,0 +1,135 @@
+package energy
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const priceSensor = "sensor.electricity_price"
+
+// newPricingTestManager returns a manager with a weekday 15:00-20:00 peak
+// window and free nights, and the price sensor if withSensor
+func newPricingTestManager(t *testing.T, withSensor bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	config := createTestConfig()
+	config.RateSchedule = &RateSchedule{
+		DefaultPrice: 0.16,
+		Windows: []RateWindow{
+			{Name: "free_nights", Start: "21:00", End: "07:00", Price: 0},
+			{Name: "peak", Start: "15:00", End: "20:00", Days: []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, Price: 0.28, Peak: true},
+		},
+	}
+	if withSensor {
+		config.RateSchedule.PriceSensor = priceSensor
+		config.RateSchedule.PeakAbovePrice = 0.30
+	}
+
+	return NewManager(mockClient, stateManager, config, logger, false, time.UTC, nil), mockClient, stateManager
+}
+
+func assertPricing(t *testing.T, stateManager *state.Manager, price float64, peak bool) {
+	t.Helper()
+	gotPrice, err := stateManager.GetNumber("currentGridPrice")
+	require.NoError(t, err)
+	assert.InDelta(t, price, gotPrice, 1e-9)
+	gotPeak, err := stateManager.GetBool("isPeakPricing")
+	require.NoError(t, err)
+	assert.Equal(t, peak, gotPeak)
+}
+
+func TestGridPricing_FollowsSchedule(t *testing.T) {
+	manager, _, stateManager := newPricingTestManager(t, false)
+	wednesday := time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	manager.checkGridPricing(wednesday.Add(16 * time.Hour))
+	assertPricing(t, stateManager, 0.28, true)
+	decision := manager.GetShadowState().Outputs.GridPricing
+	require.NotNil(t, decision)
+	assert.Equal(t, "peak", decision.Window)
+	assert.Equal(t, PriceSourceSchedule, decision.Source)
+
+	manager.checkGridPricing(wednesday.Add(20 * time.Hour))
+	assertPricing(t, stateManager, 0.16, false)
+	assert.Equal(t, "default", manager.GetShadowState().Outputs.GridPricing.Window)
+
+	// The free nights window started the evening before
+	manager.checkGridPricing(wednesday.Add(6 * time.Hour))
+	assertPricing(t, stateManager, 0, false)
+
+	// No peak window at the weekend
+	saturday := time.Date(2026, 6, 6, 16, 0, 0, 0, time.UTC)
+	manager.checkGridPricing(saturday)
+	assertPricing(t, stateManager, 0.16, false)
+}
+
+func TestGridPricing_LivePriceSensor(t *testing.T) {
+	manager, mockClient, stateManager := newPricingTestManager(t, true)
+	saturday := time.Date(2026, 6, 6, 16, 0, 0, 0, time.UTC)
+
+	mockClient.SetMockState(priceSensor, &ha.State{EntityID: priceSensor, State: "0.22"})
+	manager.checkGridPricing(saturday)
+	assertPricing(t, stateManager, 0.22, false)
+	assert.Equal(t, PriceSourceSensor, manager.GetShadowState().Outputs.GridPricing.Source)
+
+	// A price spike is peak pricing outside the peak window
+	mockClient.SetMockState(priceSensor, &ha.State{EntityID: priceSensor, State: "0.45"})
+	manager.checkGridPricing(saturday)
+	assertPricing(t, stateManager, 0.45, true)
+	assert.Contains(t, manager.GetShadowState().Outputs.GridPricing.Reason, "live price 0.45")
+
+	// An unreadable sensor falls back to the schedule
+	mockClient.SetMockState(priceSensor, &ha.State{EntityID: priceSensor, State: "unavailable"})
+	manager.checkGridPricing(saturday)
+	assertPricing(t, stateManager, 0.16, false)
+	assert.Nil(t, manager.GetShadowState().Outputs.GridPricing.SensorPrice)
+}
+
+func TestRateSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule RateSchedule
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			schedule: RateSchedule{Windows: []RateWindow{{Name: "peak", Start: "15:00", End: "20:00", Days: []string{"Monday"}, Price: 0.3, Peak: true}}},
+		},
+		{
+			name:     "bad time",
+			schedule: RateSchedule{Windows: []RateWindow{{Name: "peak", Start: "3pm", End: "20:00"}}},
+			wantErr:  `rate_schedule.windows[0]: invalid start "3pm", expected HH:MM`,
+		},
+		{
+			name:     "unknown day",
+			schedule: RateSchedule{Windows: []RateWindow{{Name: "peak", Start: "15:00", End: "20:00", Days: []string{"weekday"}}}},
+			wantErr:  `rate_schedule.windows[0]: unknown day "weekday"`,
+		},
+		{
+			name:     "peak price without sensor",
+			schedule: RateSchedule{PeakAbovePrice: 0.3},
+			wantErr:  "rate_schedule.peak_above_price needs a price_sensor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package energy
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// currentConfig returns the configuration in effect
+func (m *Manager) currentConfig() *EnergyConfig {
+	return m.config.Load()
+}
+
+// Reload applies an edited energy_config.yaml and re-derives the energy
+// levels from the current readings, so new thresholds and the free energy
+// window take effect without a restart. A config without energy states is
+// rejected and the current one kept.
+func (m *Manager) Reload(config *EnergyConfig) error {
+	if config == nil || len(config.Energy.EnergyStates) == 0 {
+		return fmt.Errorf("energy config has no energy_states")
+	}
+
+	m.config.Store(config)
+	m.logger.Info("Energy config reloaded",
+		zap.Int("energy_states", len(config.Energy.EnergyStates)),
+		zap.String("free_energy_start", config.Energy.FreeEnergyTime.Start),
+		zap.String("free_energy_end", config.Energy.FreeEnergyTime.End))
+
+	if battery, err := m.haClient.GetState(m.ctx, m.entities.BatteryLevel()); err != nil {
+		m.logger.Warn("Failed to read battery level after reload", zap.Error(err))
+	} else if percentage, err := strconv.ParseFloat(battery.State, 64); err == nil {
+		m.handleBatteryChange(percentage)
+	}
+	m.recalculateSolarProductionLevel()
+	m.checkFreeEnergy()
+	m.recalculateOverallEnergyLevel()
+	m.evaluateSafeMode(time.Now())
+	m.checkGridPricing(time.Now())
+	m.resetReservePolicy()
+	m.checkReservePolicy(time.Now())
+	return nil
+}
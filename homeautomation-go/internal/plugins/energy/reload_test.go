@@ -0,0 +1,47 @@
+package energy
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReload_AppliesNewThresholds(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("sensor.span_panel_span_storage_battery_percentage_2", "50", nil)
+	require.NoError(t, mockClient.Connect(context.Background()))
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	manager := NewManager(mockClient, stateManager, createTestConfig(), logger, false, nil, nil)
+	manager.handleBatteryChange(50)
+	level, _ := stateManager.GetString("batteryEnergyLevel")
+	assert.Equal(t, "red", level)
+
+	// Raise the red threshold above the current charge
+	config := createTestConfig()
+	config.Energy.EnergyStates[1].BatteryMinimumPercentage = 55
+	require.NoError(t, manager.Reload(config))
+
+	assert.Same(t, config, manager.currentConfig())
+	level, _ = stateManager.GetString("batteryEnergyLevel")
+	assert.Equal(t, "black", level, "the battery level is re-derived with the new thresholds")
+}
+
+func TestReload_RejectsEmptyConfig(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	original := createTestConfig()
+	manager := NewManager(mockClient, stateManager, original, logger, false, nil, nil)
+
+	assert.Error(t, manager.Reload(&EnergyConfig{}))
+	assert.Error(t, manager.Reload(nil))
+	assert.Same(t, original, manager.currentConfig(), "the current config is kept")
+}
@@ -0,0 +1,138 @@
+package energy
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// Battery reserve policies
+const (
+	ReservePolicyNormal     = "normal"
+	ReservePolicyStormWatch = "storm_watch"
+	ReservePolicyGridOutage = "grid_outage"
+)
+
+// checkReservePolicy works out the reserve policy in effect and, when it has
+// changed since the reserve was last set, sets the inverter's backup reserve
+// to match. A failed set is retried on the next check.
+func (m *Manager) checkReservePolicy(now time.Time) {
+	config := m.currentConfig().ReservePolicy
+	if config == nil {
+		return
+	}
+
+	policy, reservePct, reason := m.selectReservePolicy(config)
+
+	m.reservePolicyMu.Lock()
+	defer m.reservePolicyMu.Unlock()
+
+	if policy == m.reservePolicy {
+		m.shadowTracker.RecordReservePolicyEvaluation(policy, reservePct, reason, now)
+		return
+	}
+
+	m.logger.Info("Battery reserve policy changed",
+		zap.String("from", m.reservePolicy),
+		zap.String("to", policy),
+		zap.Float64("reserve_pct", reservePct),
+		zap.String("reason", reason))
+
+	transition := shadowstate.ReservePolicyTransition{
+		From:       m.reservePolicy,
+		To:         policy,
+		ReservePct: reservePct,
+		Reason:     reason,
+		At:         now,
+	}
+	applied, err := m.setBackupReservePct(config.ReserveEntity, reservePct, policy)
+	transition.Applied = applied
+	if err != nil {
+		transition.Error = err.Error()
+	} else {
+		m.reservePolicy = policy
+	}
+	m.shadowTracker.RecordReservePolicyTransition(transition)
+}
+
+// selectReservePolicy picks the policy in effect: grid outage while the grid
+// is down (when an outage reserve is configured), then storm watch, then normal
+func (m *Manager) selectReservePolicy(config *ReservePolicy) (string, float64, string) {
+	if config.OutageReservePct != nil {
+		gridAvailable, err := m.stateManager.GetBool("isGridAvailable")
+		if err != nil {
+			m.logger.Error("Failed to get isGridAvailable", zap.Error(err))
+		} else if !gridAvailable {
+			return ReservePolicyGridOutage, *config.OutageReservePct, "grid is down"
+		}
+	}
+
+	if config.StormWatchVariable != "" {
+		stormWatch, err := m.stateManager.GetBool(config.StormWatchVariable)
+		if err != nil {
+			m.logger.Warn("Failed to read storm watch variable",
+				zap.String("variable", config.StormWatchVariable),
+				zap.Error(err))
+		} else if stormWatch {
+			return ReservePolicyStormWatch, config.StormReservePct, fmt.Sprintf("%s is on", config.StormWatchVariable)
+		}
+	}
+
+	return ReservePolicyNormal, config.NormalReservePct, "no storm watch or grid outage"
+}
+
+// resetReservePolicy forgets the policy last applied, so the next check sets
+// the reserve again even when the policy hasn't changed
+func (m *Manager) resetReservePolicy() {
+	m.reservePolicyMu.Lock()
+	defer m.reservePolicyMu.Unlock()
+	m.reservePolicy = ""
+}
+
+// handleReservePolicyInputChange re-checks the reserve policy when the storm
+// watch variable changes
+func (m *Manager) handleReservePolicyInputChange(key string, oldValue, newValue interface{}) {
+	m.checkReservePolicy(time.Now())
+}
+
+// setBackupReservePct sets the reserve number entity, reporting whether the
+// inverter now holds reservePct. Nothing is sent when it already does.
+func (m *Manager) setBackupReservePct(entityID string, reservePct float64, policy string) (bool, error) {
+	if st, err := m.haClient.GetState(m.ctx, entityID); err == nil {
+		if current, err := strconv.ParseFloat(st.State, 64); err == nil && current == reservePct {
+			return true, nil
+		}
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set battery backup reserve",
+			zap.String("entity_id", entityID),
+			zap.Float64("reserve_pct", reservePct),
+			zap.String("policy", policy))
+		return false, nil
+	}
+
+	domain := ha.EntityDomain(entityID)
+	if err := m.haClient.CallService(m.ctx, domain, "set_value", map[string]interface{}{
+		"entity_id": entityID,
+		"value":     reservePct,
+	}); err != nil {
+		m.logger.Error("Failed to set battery backup reserve",
+			zap.String("entity_id", entityID),
+			zap.Float64("reserve_pct", reservePct),
+			zap.Error(err))
+		return false, err
+	}
+
+	m.logger.Info("Set battery backup reserve",
+		zap.String("entity_id", entityID),
+		zap.Float64("reserve_pct", reservePct),
+		zap.String("policy", policy))
+	m.shadowTracker.RecordInverterCommand(time.Now(), fmt.Sprintf("Backup reserve %g%% (%s)", reservePct, policy))
+	return true, nil
+}
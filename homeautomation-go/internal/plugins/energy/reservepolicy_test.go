@@ -0,0 +1,146 @@
+package energy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const reserveEntity = "number.inverter_backup_reserve"
+
+// newReservePolicyTestManager returns a manager holding a 20% reserve, 80%
+// during a storm watch and 5% while the grid is down
+func newReservePolicyTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	mockClient.SetMockState(reserveEntity, &ha.State{EntityID: reserveEntity, State: "20"})
+
+	outagePct := 5.0
+	config := createTestConfig()
+	config.ReservePolicy = &ReservePolicy{
+		ReserveEntity:      reserveEntity,
+		NormalReservePct:   20,
+		StormWatchVariable: "isStormWatch",
+		StormReservePct:    80,
+		OutageReservePct:   &outagePct,
+	}
+
+	return NewManager(mockClient, stateManager, config, logger, readOnly, time.UTC, nil), mockClient, stateManager
+}
+
+// reserveValues returns the reserve percentages set, in order
+func reserveValues(mockClient *ha.MockClient) []interface{} {
+	var values []interface{}
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "number" && call.Service == "set_value" && call.Data["entity_id"] == reserveEntity {
+			values = append(values, call.Data["value"])
+		}
+	}
+	return values
+}
+
+func TestReservePolicy_Transitions(t *testing.T) {
+	manager, mockClient, stateManager := newReservePolicyTestManager(t, false)
+	mockClient.SetMockState(reserveEntity, &ha.State{EntityID: reserveEntity, State: "10"})
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	manager.checkReservePolicy(now)
+
+	require.NoError(t, stateManager.SetBool("isStormWatch", true))
+	manager.checkReservePolicy(now.Add(time.Minute))
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+	manager.checkReservePolicy(now.Add(2 * time.Minute))
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", true))
+	require.NoError(t, stateManager.SetBool("isStormWatch", false))
+	manager.checkReservePolicy(now.Add(3 * time.Minute))
+
+	assert.Equal(t, []interface{}{20.0, 80.0, 5.0, 20.0}, reserveValues(mockClient))
+
+	policy := manager.GetShadowState().Outputs.ReservePolicy
+	require.NotNil(t, policy)
+	assert.Equal(t, ReservePolicyNormal, policy.Policy)
+	assert.Equal(t, 20.0, policy.ReservePct)
+	assert.True(t, policy.Since.Equal(now.Add(3*time.Minute)))
+
+	require.Len(t, policy.Transitions, 4)
+	var path []string
+	for _, transition := range policy.Transitions {
+		assert.True(t, transition.Applied)
+		path = append(path, transition.From+"->"+transition.To)
+	}
+	assert.Equal(t, []string{"->normal", "normal->storm_watch", "storm_watch->grid_outage", "grid_outage->normal"}, path)
+	assert.Equal(t, "isStormWatch is on", policy.Transitions[1].Reason)
+}
+
+func TestReservePolicy_ManualChangeHoldsUntilPolicyChanges(t *testing.T) {
+	manager, mockClient, stateManager := newReservePolicyTestManager(t, false)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Already at the normal reserve, so nothing is sent
+	manager.checkReservePolicy(now)
+	assert.Empty(t, reserveValues(mockClient))
+	assert.True(t, manager.GetShadowState().Outputs.ReservePolicy.Transitions[0].Applied)
+
+	mockClient.SetMockState(reserveEntity, &ha.State{EntityID: reserveEntity, State: "50"})
+	manager.checkReservePolicy(now.Add(time.Minute))
+	assert.Empty(t, reserveValues(mockClient), "same policy, so the manual reserve holds")
+	assert.True(t, manager.GetShadowState().Outputs.ReservePolicy.EvaluatedAt.Equal(now.Add(time.Minute)))
+
+	require.NoError(t, stateManager.SetBool("isStormWatch", true))
+	manager.checkReservePolicy(now.Add(2 * time.Minute))
+	assert.Equal(t, []interface{}{80.0}, reserveValues(mockClient))
+}
+
+func TestReservePolicy_ReadOnly(t *testing.T) {
+	manager, mockClient, stateManager := newReservePolicyTestManager(t, true)
+	require.NoError(t, stateManager.SetBool("isStormWatch", true))
+
+	manager.checkReservePolicy(time.Now())
+
+	assert.Empty(t, reserveValues(mockClient))
+	policy := manager.GetShadowState().Outputs.ReservePolicy
+	require.NotNil(t, policy)
+	assert.Equal(t, ReservePolicyStormWatch, policy.Policy)
+	require.Len(t, policy.Transitions, 1)
+	assert.False(t, policy.Transitions[0].Applied)
+}
+
+func TestReservePolicy_OutageWithoutOutageReserve(t *testing.T) {
+	manager, mockClient, stateManager := newReservePolicyTestManager(t, false)
+	manager.currentConfig().ReservePolicy.OutageReservePct = nil
+	require.NoError(t, stateManager.SetBool("isStormWatch", true))
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	manager.checkReservePolicy(time.Now())
+
+	assert.Equal(t, []interface{}{80.0}, reserveValues(mockClient), "the storm reserve holds through the outage")
+}
+
+func TestLoadConfig_ReservePolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "energy_config.yaml")
+	write := func(yaml string) error {
+		require.NoError(t, os.WriteFile(path, []byte(yaml), 0o644))
+		_, err := LoadConfig(path)
+		return err
+	}
+
+	assert.NoError(t, write("reserve_policy:\n  reserve_entity: number.reserve\n  normal_reserve_pct: 20\n  storm_watch_variable: isStormWatch\n  storm_reserve_pct: 80\n"))
+	assert.EqualError(t, write("reserve_policy:\n  reserve_entity: sensor.reserve\n"),
+		`reserve_policy.reserve_entity must be a number or input_number, got "sensor.reserve"`)
+	assert.EqualError(t, write("reserve_policy:\n  reserve_entity: number.reserve\n  normal_reserve_pct: 20\n  storm_watch_variable: isStormWatch\n  storm_reserve_pct: 10\n"),
+		"reserve_policy.storm_reserve_pct must be between normal_reserve_pct and 100")
+	assert.EqualError(t, write("reserve_policy:\n  reserve_entity: number.reserve\n  outage_reserve_pct: 120\n"),
+		"reserve_policy.outage_reserve_pct must be between 0 and 100")
+}
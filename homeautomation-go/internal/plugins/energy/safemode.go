@@ -0,0 +1,99 @@
+package energy
+
+import (
+	"fmt"
+	"time"
+
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// SetSafeMode sets the switch battery safe mode is published on, shared with
+// the plugins that change their behavior in safe mode
+func (m *Manager) SetSafeMode(s *safemode.Switch) {
+	m.safeMode = s
+}
+
+// evaluateSafeMode turns safe mode on once the battery drops below
+// enter_below_pct with the grid down, and off once the grid returns or the
+// battery recovers to exit_above_pct
+func (m *Manager) evaluateSafeMode(now time.Time) {
+	config := m.currentConfig().SafeMode
+	if config == nil {
+		if m.safeMode.Exit("safe_mode removed from the energy config", now) {
+			m.logger.Info("Left battery safe mode: no longer configured")
+		}
+		return
+	}
+
+	gridAvailable, err := m.stateManager.GetBool("isGridAvailable")
+	if err != nil {
+		m.logger.Warn("Failed to get isGridAvailable for safe mode, assuming available", zap.Error(err))
+		gridAvailable = true
+	}
+
+	m.safeModeMu.Lock()
+	battery := m.safeModeBattery
+	m.safeModeMu.Unlock()
+
+	active := m.safeMode.Active()
+	enter, exit := false, false
+	var reason string
+	switch {
+	case gridAvailable:
+		exit = active
+		reason = "grid available"
+	case battery == nil:
+		reason = "battery level unknown"
+	case !active && *battery < config.EnterBelowPct:
+		enter = true
+		reason = fmt.Sprintf("battery at %.0f%% with the grid down, below %.0f%%", *battery, config.EnterBelowPct)
+	case active && *battery >= config.ExitAbovePct:
+		exit = true
+		reason = fmt.Sprintf("battery recovered to %.0f%%, at or above %.0f%%", *battery, config.ExitAbovePct)
+	case active:
+		reason = fmt.Sprintf("battery at %.0f%%, below %.0f%% needed to leave safe mode", *battery, config.ExitAbovePct)
+	default:
+		reason = fmt.Sprintf("battery at %.0f%%, at or above %.0f%%", *battery, config.EnterBelowPct)
+	}
+
+	switch {
+	case (enter || exit) && m.readOnly:
+		m.logger.Info("READ-ONLY: Would change battery safe mode",
+			zap.Bool("active", enter),
+			zap.String("reason", reason))
+	case enter:
+		m.safeMode.Enter(reason, config.SuspendPlugins, config.SlowdownFactorOrDefault(), now)
+		m.logger.Warn("Entered battery safe mode",
+			zap.String("reason", reason),
+			zap.Strings("suspend_plugins", config.SuspendPlugins),
+			zap.Int("slowdown_factor", config.SlowdownFactorOrDefault()))
+	case exit:
+		m.safeMode.Exit(reason, now)
+		m.logger.Info("Left battery safe mode", zap.String("reason", reason))
+	}
+
+	status := m.safeMode.Status()
+	decision := shadowstate.SafeModeDecision{
+		Active:        status.Active,
+		Reason:        reason,
+		BatteryPct:    battery,
+		GridAvailable: gridAvailable,
+		EvaluatedAt:   now,
+	}
+	if status.Active {
+		decision.SuspendedPlugins = status.SuspendPlugins
+		decision.SlowdownFactor = status.SlowdownFactor
+		decision.Since = status.Since
+	}
+	m.shadowTracker.RecordSafeModeDecision(decision)
+}
+
+// recordSafeModeBattery keeps the latest battery reading for safe mode
+func (m *Manager) recordSafeModeBattery(percentage float64) {
+	m.safeModeMu.Lock()
+	defer m.safeModeMu.Unlock()
+	m.safeModeBattery = &percentage
+}
@@ -0,0 +1,118 @@
+package energy
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newSafeModeTestManager returns a manager entering safe mode below 20% and
+// leaving it at 35%, with the grid down
+func newSafeModeTestManager(t *testing.T, readOnly bool) (*Manager, *state.Manager, *safemode.Switch) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	config := createTestConfig()
+	config.SafeMode = &SafeMode{
+		EnterBelowPct:  20,
+		ExitAbovePct:   35,
+		SuspendPlugins: []string{"music", "growlights"},
+	}
+
+	manager := NewManager(mockClient, stateManager, config, logger, readOnly, time.UTC, nil)
+	safeModeSwitch := safemode.NewSwitch()
+	manager.SetSafeMode(safeModeSwitch)
+	return manager, stateManager, safeModeSwitch
+}
+
+func TestSafeMode_EntersAndLeavesWithHysteresis(t *testing.T) {
+	manager, _, safeModeSwitch := newSafeModeTestManager(t, false)
+
+	manager.handleBatteryChange(25)
+	assert.False(t, safeModeSwitch.Active(), "above enter_below_pct")
+
+	manager.handleBatteryChange(18)
+	require.True(t, safeModeSwitch.Active())
+	status := safeModeSwitch.Status()
+	assert.Equal(t, []string{"music", "growlights"}, status.SuspendPlugins)
+	assert.Equal(t, defaultSafeModeSlowdownFactor, status.SlowdownFactor)
+
+	manager.handleBatteryChange(30)
+	assert.True(t, safeModeSwitch.Active(), "stays on until exit_above_pct")
+
+	manager.handleBatteryChange(36)
+	assert.False(t, safeModeSwitch.Active())
+
+	decision := manager.GetShadowState().Outputs.SafeMode
+	require.NotNil(t, decision)
+	assert.False(t, decision.Active)
+	assert.Contains(t, decision.Reason, "recovered to 36%")
+	require.NotNil(t, decision.BatteryPct)
+	assert.Equal(t, 36.0, *decision.BatteryPct)
+}
+
+func TestSafeMode_LeavesWhenGridReturns(t *testing.T) {
+	manager, stateManager, safeModeSwitch := newSafeModeTestManager(t, false)
+
+	manager.handleBatteryChange(10)
+	require.True(t, safeModeSwitch.Active())
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", true))
+	manager.handleGridAvailabilityChange("isGridAvailable", false, true)
+	assert.False(t, safeModeSwitch.Active())
+	assert.Equal(t, "grid available", manager.GetShadowState().Outputs.SafeMode.Reason)
+}
+
+func TestSafeMode_StaysOffWithGridAvailable(t *testing.T) {
+	manager, stateManager, safeModeSwitch := newSafeModeTestManager(t, false)
+	require.NoError(t, stateManager.SetBool("isGridAvailable", true))
+
+	manager.handleBatteryChange(5)
+	assert.False(t, safeModeSwitch.Active(), "a low battery alone isn't an outage")
+}
+
+func TestSafeMode_ReadOnly(t *testing.T) {
+	manager, _, safeModeSwitch := newSafeModeTestManager(t, true)
+
+	manager.handleBatteryChange(10)
+	assert.False(t, safeModeSwitch.Active())
+	decision := manager.GetShadowState().Outputs.SafeMode
+	require.NotNil(t, decision)
+	assert.Contains(t, decision.Reason, "below 20%")
+}
+
+func TestSafeMode_ValidateThresholds(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SafeMode
+		wantErr bool
+	}{
+		{"valid", SafeMode{EnterBelowPct: 20, ExitAbovePct: 35, SuspendPlugins: []string{"music"}}, false},
+		{"missing enter", SafeMode{ExitAbovePct: 35}, true},
+		{"no gap", SafeMode{EnterBelowPct: 20, ExitAbovePct: 20}, true},
+		{"exit above 100", SafeMode{EnterBelowPct: 20, ExitAbovePct: 120}, true},
+		{"negative slowdown", SafeMode{EnterBelowPct: 20, ExitAbovePct: 35, SlowdownFactor: -1}, true},
+		{"suspends energy", SafeMode{EnterBelowPct: 20, ExitAbovePct: 35, SuspendPlugins: []string{"energy"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package freezeprotection
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults for the dropping-temperature alert
+const (
+	DefaultAlertAfterMinutes = 30
+	DefaultAlertDrop         = 2.0
+)
+
+// EnergyLimit changes when a space's heaters run at one currentEnergyLevel.
+// Dampers only move heat the house already has, so they aren't limited.
+type EnergyLimit struct {
+	OnBelow    *float64 `yaml:"on_below"`    // Heaters start at this temperature instead
+	OffAbove   *float64 `yaml:"off_above"`   // Heaters stop at this temperature instead
+	HeatersOff bool     `yaml:"heaters_off"` // Heaters don't run at all at this level
+}
+
+// Space configures one unconditioned space, e.g. the garage
+type Space struct {
+	Name              string   `yaml:"name"`
+	TemperatureSensor string   `yaml:"temperature_sensor"`
+	Heaters           []string `yaml:"heaters"` // switch.* space heaters
+	Dampers           []string `yaml:"dampers"` // cover.* HVAC dampers, opened to let house heat in
+	OnBelow           float64  `yaml:"on_below"`
+	OffAbove          float64  `yaml:"off_above"`
+
+	// Heater limits by currentEnergyLevel, e.g. a lower on_below on red
+	EnergyLimits map[string]EnergyLimit `yaml:"energy_limits"`
+}
+
+// HeaterThresholds returns the temperatures heaters start below and stop at
+// or above at an energy level, and whether they may run at all
+func (s *Space) HeaterThresholds(energyLevel string) (onBelow, offAbove float64, allowed bool) {
+	onBelow, offAbove = s.OnBelow, s.OffAbove
+	limit, ok := s.EnergyLimits[energyLevel]
+	if !ok {
+		return onBelow, offAbove, len(s.Heaters) > 0
+	}
+	if limit.OnBelow != nil {
+		onBelow = *limit.OnBelow
+	}
+	if limit.OffAbove != nil {
+		offAbove = *limit.OffAbove
+	}
+	return onBelow, offAbove, len(s.Heaters) > 0 && !limit.HeatersOff
+}
+
+// FreezeProtectionSettings lists the protected spaces and how alerts are sent
+type FreezeProtectionSettings struct {
+	NotifyService     notifyrouter.Target `yaml:"notify_service"`      // e.g. "notify.notify"; empty disables alerts
+	AlertAfterMinutes int                 `yaml:"alert_after_minutes"` // Default 30
+	AlertDrop         float64             `yaml:"alert_drop"`          // Default 2 degrees
+	Spaces            []Space             `yaml:"spaces"`
+}
+
+// FreezeProtectionConfig represents the freeze_protection_config.yaml structure
+type FreezeProtectionConfig struct {
+	FreezeProtection FreezeProtectionSettings `yaml:"freeze_protection"`
+}
+
+// AlertAfter returns how long mitigation runs before a falling temperature
+// is alerted
+func (s *FreezeProtectionSettings) AlertAfter() time.Duration {
+	if s.AlertAfterMinutes <= 0 {
+		return DefaultAlertAfterMinutes * time.Minute
+	}
+	return time.Duration(s.AlertAfterMinutes) * time.Minute
+}
+
+// AlertDropOrDefault returns how far below the temperature mitigation started
+// at a space must fall to alert
+func (s *FreezeProtectionSettings) AlertDropOrDefault() float64 {
+	if s.AlertDrop <= 0 {
+		return DefaultAlertDrop
+	}
+	return s.AlertDrop
+}
+
+// Validate checks every space's sensor, devices and thresholds
+func (c *FreezeProtectionConfig) Validate() error {
+	settings := &c.FreezeProtection
+	if settings.NotifyService != "" {
+		if _, _, ok := settings.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("notify_service must be domain.service, got %q", settings.NotifyService)
+		}
+	}
+	if settings.AlertAfterMinutes < 0 || settings.AlertDrop < 0 {
+		return fmt.Errorf("alert_after_minutes and alert_drop must not be negative")
+	}
+
+	names := make(map[string]bool)
+	devices := make(map[string]string)
+	for i, s := range settings.Spaces {
+		if s.Name == "" {
+			return fmt.Errorf("spaces[%d]: name is required", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("spaces[%d]: duplicate space %q", i, s.Name)
+		}
+		names[s.Name] = true
+
+		if s.TemperatureSensor == "" {
+			return fmt.Errorf("space %q: temperature_sensor is required", s.Name)
+		}
+		if len(s.Heaters) == 0 && len(s.Dampers) == 0 {
+			return fmt.Errorf("space %q: at least one heater or damper is required", s.Name)
+		}
+		for _, entityID := range s.Heaters {
+			if !strings.HasPrefix(entityID, "switch.") {
+				return fmt.Errorf("space %q: heaters must be switch.* entities, got %q", s.Name, entityID)
+			}
+		}
+		for _, entityID := range s.Dampers {
+			if !strings.HasPrefix(entityID, "cover.") {
+				return fmt.Errorf("space %q: dampers must be cover.* entities, got %q", s.Name, entityID)
+			}
+		}
+		for _, entityID := range append(append([]string(nil), s.Heaters...), s.Dampers...) {
+			if other, ok := devices[entityID]; ok {
+				return fmt.Errorf("space %q: %s is already used by space %q", s.Name, entityID, other)
+			}
+			devices[entityID] = s.Name
+		}
+
+		if s.OnBelow >= s.OffAbove {
+			return fmt.Errorf("space %q: on_below must be below off_above", s.Name)
+		}
+		for level := range s.EnergyLimits {
+			onBelow, offAbove, _ := s.HeaterThresholds(level)
+			if onBelow >= offAbove {
+				return fmt.Errorf("space %q: energy_limits.%s: on_below must be below off_above", s.Name, level)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the freeze protection configuration from a YAML file
+func LoadConfig(path string) (*FreezeProtectionConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config FreezeProtectionConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
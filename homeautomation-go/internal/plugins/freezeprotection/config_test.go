@@ -0,0 +1,120 @@
+package freezeprotection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "freeze_protection_config.yaml")
+
+	configContent := `---
+freeze_protection:
+  notify_service: notify.notify
+  alert_after_minutes: 45
+  spaces:
+    - name: garage
+      temperature_sensor: sensor.garage_temperature
+      heaters: [switch.garage_space_heater]
+      dampers: [cover.garage_hvac_damper]
+      on_below: 38
+      off_above: 42
+      energy_limits:
+        red: {on_below: 34, off_above: 36}
+        black: {heaters_off: true}
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	settings := config.FreezeProtection
+	assert.Equal(t, 45*time.Minute, settings.AlertAfter())
+	assert.Equal(t, DefaultAlertDrop, settings.AlertDropOrDefault())
+	require.Len(t, settings.Spaces, 1)
+
+	garage := settings.Spaces[0]
+	onBelow, offAbove, allowed := garage.HeaterThresholds("green")
+	assert.Equal(t, []interface{}{38.0, 42.0, true}, []interface{}{onBelow, offAbove, allowed})
+	onBelow, offAbove, allowed = garage.HeaterThresholds("red")
+	assert.Equal(t, []interface{}{34.0, 36.0, true}, []interface{}{onBelow, offAbove, allowed})
+	_, _, allowed = garage.HeaterThresholds("black")
+	assert.False(t, allowed)
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *FreezeProtectionConfig {
+		return &FreezeProtectionConfig{FreezeProtection: FreezeProtectionSettings{Spaces: []Space{{
+			Name:              "garage",
+			TemperatureSensor: "sensor.garage_temperature",
+			Heaters:           []string{"switch.garage_space_heater"},
+			OnBelow:           38,
+			OffAbove:          42,
+		}}}}
+	}
+	low := 44.0
+
+	tests := []struct {
+		name    string
+		modify  func(c *FreezeProtectionConfig)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*FreezeProtectionConfig) {}},
+		{
+			name:    "bad notify service",
+			modify:  func(c *FreezeProtectionConfig) { c.FreezeProtection.NotifyService = "notify" },
+			wantErr: `notify_service must be domain.service, got "notify"`,
+		},
+		{
+			name:    "no devices",
+			modify:  func(c *FreezeProtectionConfig) { c.FreezeProtection.Spaces[0].Heaters = nil },
+			wantErr: `space "garage": at least one heater or damper is required`,
+		},
+		{
+			name:    "heater not a switch",
+			modify:  func(c *FreezeProtectionConfig) { c.FreezeProtection.Spaces[0].Heaters = []string{"climate.garage"} },
+			wantErr: `space "garage": heaters must be switch.* entities, got "climate.garage"`,
+		},
+		{
+			name: "device in two spaces",
+			modify: func(c *FreezeProtectionConfig) {
+				laundry := c.FreezeProtection.Spaces[0]
+				laundry.Name = "laundry"
+				laundry.TemperatureSensor = "sensor.laundry_temperature"
+				c.FreezeProtection.Spaces = append(c.FreezeProtection.Spaces, laundry)
+			},
+			wantErr: `space "laundry": switch.garage_space_heater is already used by space "garage"`,
+		},
+		{
+			name:    "thresholds reversed",
+			modify:  func(c *FreezeProtectionConfig) { c.FreezeProtection.Spaces[0].OffAbove = 38 },
+			wantErr: `space "garage": on_below must be below off_above`,
+		},
+		{
+			name: "energy limit thresholds reversed",
+			modify: func(c *FreezeProtectionConfig) {
+				c.FreezeProtection.Spaces[0].EnergyLimits = map[string]EnergyLimit{"red": {OnBelow: &low}}
+			},
+			wantErr: `space "garage": energy_limits.red: on_below must be below off_above`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
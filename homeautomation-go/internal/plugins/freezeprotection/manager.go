@@ -0,0 +1,473 @@
+// Package freezeprotection keeps unconditioned spaces such as the laundry room
+// and garage above freezing. When a space's temperature falls below its
+// threshold its space heaters are switched on and its HVAC dampers opened,
+// and both are switched back once it has warmed up again. Heaters draw a lot
+// of power, so their thresholds can be lowered or heaters left off at low
+// energy levels. If the temperature keeps dropping despite the mitigation an
+// alert is sent.
+package freezeprotection
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// NotificationTitle is the title of dropping-temperature alerts
+const NotificationTitle = "Freeze protection"
+
+// space is a configured space and the mitigation this plugin is running
+type space struct {
+	config *Space
+
+	temperature *float64
+	heatersOn   bool
+	dampersOpen bool
+
+	// Set while heaters or dampers are on
+	since            time.Time
+	startTemperature *float64
+	alerted          bool // A dropping-temperature alert was sent for this mitigation
+}
+
+// mitigating reports whether the space's heaters or dampers are on
+func (s *space) mitigating() bool {
+	return s.heatersOn || s.dampersOpen
+}
+
+// Manager switches heaters and dampers in unconditioned spaces with their
+// temperature and the energy level
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *FreezeProtectionConfig
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+
+	// Spaces in config order and the last energy level (protected by mu)
+	spaces      []*space
+	energyLevel string
+	mu          sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.FreezeProtectionTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Freeze Protection manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *FreezeProtectionConfig, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	shadowTracker := shadowstate.NewFreezeProtectionTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("freezeprotection")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "freezeprotection", logger.Named("freezeprotection")),
+	}
+
+	for i := range config.FreezeProtection.Spaces {
+		s := &config.FreezeProtection.Spaces[i]
+		m.spaces = append(m.spaces, &space{config: s})
+		shadowTracker.AddSpace(s.Name)
+	}
+
+	return m
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.FreezeProtectionShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start subscribes to each space's temperature and the energy level
+func (m *Manager) Start(ctx context.Context) error {
+	// Heater and damper commands run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Freeze Protection Manager", zap.Int("spaces", len(m.spaces)))
+
+	if err := m.subHelper.SubscribeToState("currentEnergyLevel", m.handleEnergyLevelChange); err != nil {
+		return fmt.Errorf("failed to subscribe to currentEnergyLevel: %w", err)
+	}
+	for _, s := range m.spaces {
+		if err := m.subscribe(s); err != nil {
+			return err
+		}
+	}
+
+	m.subHelper.CaptureInitialInputs()
+
+	m.mu.Lock()
+	m.energyLevel = m.readEnergyLevel()
+	for _, s := range m.spaces {
+		m.adopt(s)
+		m.evaluate(s, "startup")
+	}
+	m.mu.Unlock()
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Freeze Protection Manager started successfully")
+	return nil
+}
+
+// Stop unsubscribes. Heaters and dampers are left as they are so a restart
+// doesn't leave a space unprotected.
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Freeze Protection Manager")
+
+	m.cancel()
+	m.subHelper.UnsubscribeAll()
+
+	m.logger.Info("Freeze Protection Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-reads each space's devices and re-evaluates it
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Freeze Protection - re-evaluating spaces")
+
+	m.mu.Lock()
+	m.energyLevel = m.readEnergyLevel()
+	for _, s := range m.spaces {
+		m.adopt(s)
+		m.evaluate(s, "reset")
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Successfully reset Freeze Protection")
+	return nil
+}
+
+// subscribe watches a space's temperature
+func (m *Manager) subscribe(s *space) error {
+	return m.subHelper.SubscribeToSensor(s.config.TemperatureSensor, func(value float64) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		s.temperature = &value
+		m.evaluate(s, s.config.TemperatureSensor)
+	})
+}
+
+// handleEnergyLevelChange re-evaluates every space with the new heater limits
+func (m *Manager) handleEnergyLevelChange(key string, _, newValue interface{}) {
+	level, ok := newValue.(string)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if level == m.energyLevel {
+		return
+	}
+	m.energyLevel = level
+	for _, s := range m.spaces {
+		m.evaluate(s, key)
+	}
+}
+
+// adopt reads the space's temperature and takes over heaters and dampers
+// that are already on while the space is below off_above, e.g. after a
+// restart mid-mitigation. Devices on above off_above are someone else's and
+// left alone. Callers must hold mu.
+func (m *Manager) adopt(s *space) {
+	c := s.config
+	if temperature, err := m.readNumber(c.TemperatureSensor); err == nil {
+		s.temperature = &temperature
+	}
+
+	s.heatersOn, s.dampersOpen = false, false
+	if s.temperature != nil && *s.temperature < c.OffAbove {
+		s.heatersOn = m.anyInState(c.Heaters, "on")
+		s.dampersOpen = m.anyInState(c.Dampers, "open")
+	}
+	switch {
+	case !s.mitigating():
+		s.since, s.startTemperature, s.alerted = time.Time{}, nil, false
+	case s.since.IsZero():
+		s.since = m.clock.Now()
+		s.startTemperature = copyTemperature(s.temperature)
+		m.shadowTracker.RecordMitigation(c.Name, s.heatersOn, s.dampersOpen, s.since, s.startTemperature,
+			fmt.Sprintf("Took over %s already on at %.1f", s.mitigationName(), *s.temperature), s.since)
+	}
+}
+
+// evaluate switches the space's heaters and dampers with its temperature and
+// alerts if the temperature keeps dropping despite them. Dampers use the
+// space's thresholds; heaters use the energy level's. Callers must hold mu.
+func (m *Manager) evaluate(s *space, trigger string) {
+	m.health.Tick()
+	c := s.config
+
+	onBelow, offAbove, heatersAllowed := c.HeaterThresholds(m.energyLevel)
+	m.shadowTracker.UpdateConditions(c.Name, s.temperature, m.energyLevel, onBelow, offAbove, heatersAllowed)
+
+	if s.temperature == nil {
+		m.logger.Debug("No temperature yet, leaving space unchanged",
+			zap.String("space", c.Name))
+		return
+	}
+	temperature := *s.temperature
+
+	wantDampers := s.dampersOpen
+	switch {
+	case len(c.Dampers) == 0:
+		wantDampers = false
+	case temperature < c.OnBelow:
+		wantDampers = true
+	case temperature >= c.OffAbove:
+		wantDampers = false
+	}
+
+	wantHeaters := s.heatersOn
+	switch {
+	case !heatersAllowed:
+		wantHeaters = false
+	case temperature < onBelow:
+		wantHeaters = true
+	case temperature >= offAbove:
+		wantHeaters = false
+	}
+
+	if wantDampers != s.dampersOpen || wantHeaters != s.heatersOn {
+		reason := m.changeReason(s, temperature, wantHeaters, wantDampers, heatersAllowed, trigger)
+		m.switchDevices(s, wantHeaters, wantDampers, reason)
+	}
+
+	m.checkStillDropping(s, temperature)
+}
+
+// changeReason describes why a space's heaters or dampers are being switched
+func (m *Manager) changeReason(s *space, temperature float64, heaters, dampers, heatersAllowed bool, trigger string) string {
+	switch {
+	case s.heatersOn && !heatersAllowed:
+		return fmt.Sprintf("Heaters not allowed at energy level %s, temperature %.1f (%s)", m.energyLevel, temperature, trigger)
+	case (heaters && !s.heatersOn) || (dampers && !s.dampersOpen):
+		return fmt.Sprintf("Temperature %.1f near freezing (%s)", temperature, trigger)
+	default:
+		return fmt.Sprintf("Temperature %.1f recovered (%s)", temperature, trigger)
+	}
+}
+
+// switchDevices turns the space's heaters and opens its dampers, or the
+// reverse, and records the change; callers must hold mu
+func (m *Manager) switchDevices(s *space, heaters, dampers bool, reason string) {
+	c := s.config
+
+	if heaters != s.heatersOn {
+		service := "turn_off"
+		if heaters {
+			service = "turn_on"
+		}
+		if err := m.callService(c.Name, "switch", service, c.Heaters); err != nil {
+			heaters = s.heatersOn
+		}
+	}
+	if dampers != s.dampersOpen {
+		service := "close_cover"
+		if dampers {
+			service = "open_cover"
+		}
+		if err := m.callService(c.Name, "cover", service, c.Dampers); err != nil {
+			dampers = s.dampersOpen
+		}
+	}
+	if heaters == s.heatersOn && dampers == s.dampersOpen {
+		return
+	}
+
+	wasMitigating := s.mitigating()
+	s.heatersOn, s.dampersOpen = heaters, dampers
+	now := m.clock.Now()
+	switch {
+	case s.mitigating() && !wasMitigating:
+		s.since = now
+		s.startTemperature = copyTemperature(s.temperature)
+		s.alerted = false
+	case !s.mitigating():
+		s.since = time.Time{}
+		s.startTemperature = nil
+	}
+
+	m.logger.Info("Freeze protection changed",
+		zap.String("space", c.Name),
+		zap.Bool("heaters_on", heaters),
+		zap.Bool("dampers_open", dampers),
+		zap.String("reason", reason))
+	m.shadowTracker.RecordMitigation(c.Name, heaters, dampers, s.since, s.startTemperature, reason, now)
+}
+
+// checkStillDropping alerts once per mitigation when the space has kept
+// getting colder for alert_after_minutes despite heaters or dampers;
+// callers must hold mu
+func (m *Manager) checkStillDropping(s *space, temperature float64) {
+	settings := &m.config.FreezeProtection
+	if !s.mitigating() || s.alerted || s.startTemperature == nil {
+		return
+	}
+	now := m.clock.Now()
+	if now.Sub(s.since) < settings.AlertAfter() {
+		return
+	}
+	if temperature > *s.startTemperature-settings.AlertDropOrDefault() {
+		return
+	}
+
+	s.alerted = true
+	message := fmt.Sprintf("%s is at %.1f°, down from %.1f° when %s started %s ago",
+		s.config.Name, temperature, *s.startTemperature, s.mitigationName(), now.Sub(s.since).Round(time.Minute))
+	m.logger.Warn("Temperature still dropping despite freeze protection",
+		zap.String("space", s.config.Name),
+		zap.Float64("temperature", temperature),
+		zap.Float64("start_temperature", *s.startTemperature))
+	m.shadowTracker.RecordAlert(s.config.Name, message, now)
+	m.sendAlert(message)
+}
+
+// mitigationName names the devices running, for alerts
+func (s *space) mitigationName() string {
+	var names []string
+	if s.heatersOn {
+		names = append(names, "heaters")
+	}
+	if s.dampersOpen {
+		names = append(names, "dampers")
+	}
+	return strings.Join(names, " and ")
+}
+
+// sendAlert notifies notify_service, if set
+func (m *Manager) sendAlert(message string) {
+	target := m.config.FreezeProtection.NotifyService
+	if target == "" {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send freeze protection alert",
+			zap.String("service", string(target)),
+			zap.String("message", message))
+		return
+	}
+
+	if err := notifyrouter.SendPush(m.ctx, m.haClient, target, notifyrouter.Push{
+		Title:   NotificationTitle,
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send freeze protection alert", zap.Error(err))
+	}
+}
+
+// callService calls a switch or cover service on a space's entities
+func (m *Manager) callService(spaceName, domain, service string, entityIDs []string) error {
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would switch freeze protection devices",
+			zap.String("space", spaceName),
+			zap.String("service", domain+"."+service),
+			zap.Strings("entity_ids", entityIDs))
+		return nil
+	}
+
+	if err := m.haClient.CallService(m.ctx, domain, service, map[string]interface{}{
+		"entity_id": entityIDs,
+	}); err != nil {
+		m.logger.Error("Failed to switch freeze protection devices",
+			zap.String("space", spaceName),
+			zap.String("service", domain+"."+service),
+			zap.Strings("entity_ids", entityIDs),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// readEnergyLevel reads currentEnergyLevel, "" if it can't be read
+func (m *Manager) readEnergyLevel() string {
+	level, err := m.stateManager.GetString("currentEnergyLevel")
+	if err != nil {
+		m.logger.Warn("Failed to get currentEnergyLevel, using default heater limits", zap.Error(err))
+		return ""
+	}
+	return level
+}
+
+// anyInState reports whether any of the entities is in the given state
+func (m *Manager) anyInState(entityIDs []string, want string) bool {
+	for _, entityID := range entityIDs {
+		if st, err := m.haClient.GetState(m.ctx, entityID); err == nil && st != nil && st.State == want {
+			return true
+		}
+	}
+	return false
+}
+
+// readNumber reads a numeric sensor state
+func (m *Manager) readNumber(entityID string) (float64, error) {
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		return 0, err
+	}
+	if st == nil {
+		return 0, fmt.Errorf("no state for %s", entityID)
+	}
+	return strconv.ParseFloat(st.State, 64)
+}
+
+// copyTemperature copies a temperature reading
+func copyTemperature(t *float64) *float64 {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
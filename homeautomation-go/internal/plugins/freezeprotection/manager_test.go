@@ -0,0 +1,187 @@
+package freezeprotection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testSensor = "sensor.garage_temperature"
+	testHeater = "switch.garage_space_heater"
+	testDamper = "cover.garage_hvac_damper"
+)
+
+func createTestConfig() *FreezeProtectionConfig {
+	redOnBelow, redOffAbove := 34.0, 36.0
+	return &FreezeProtectionConfig{FreezeProtection: FreezeProtectionSettings{
+		NotifyService: "notify.notify",
+		Spaces: []Space{{
+			Name:              "garage",
+			TemperatureSensor: testSensor,
+			Heaters:           []string{testHeater},
+			Dampers:           []string{testDamper},
+			OnBelow:           38,
+			OffAbove:          42,
+			EnergyLimits: map[string]EnergyLimit{
+				"red":   {OnBelow: &redOnBelow, OffAbove: &redOffAbove},
+				"black": {HeatersOff: true},
+			},
+		}},
+	}}
+}
+
+func setupTest(t *testing.T, temperature string) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "green"))
+
+	mockHA.SetState(testSensor, temperature, map[string]interface{}{})
+	mockHA.SetState(testHeater, "off", map[string]interface{}{})
+	mockHA.SetState(testDamper, "closed", map[string]interface{}{})
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, false, nil)
+	mockClock := clock.NewMockClock(time.Date(2025, 1, 15, 2, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+
+	mockHA.ClearServiceCalls()
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	return manager, mockHA, stateManager, mockClock
+}
+
+// deviceCalls returns the heater, damper and notify calls, leaving out state
+// variable writes
+func deviceCalls(mockHA *ha.MockClient) []string {
+	var calls []string
+	for _, call := range mockHA.GetServiceCalls() {
+		switch call.Domain {
+		case "switch", "cover", "notify":
+			calls = append(calls, call.Domain+"."+call.Service)
+		}
+	}
+	return calls
+}
+
+func TestMitigatesNearFreezingAndRecovers(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, "45")
+	assert.Empty(t, deviceCalls(mockHA))
+
+	mockHA.SetState(testSensor, "37.5", map[string]interface{}{})
+	assert.Equal(t, []string{"switch.turn_on", "cover.open_cover"}, deviceCalls(mockHA))
+
+	status := manager.GetShadowState().Outputs.Spaces[0]
+	assert.True(t, status.HeatersOn)
+	assert.True(t, status.DampersOpen)
+	require.NotNil(t, status.StartTemperature)
+	assert.Equal(t, 37.5, *status.StartTemperature)
+
+	// Between the thresholds nothing changes
+	mockHA.ClearServiceCalls()
+	mockHA.SetState(testSensor, "40", map[string]interface{}{})
+	assert.Empty(t, deviceCalls(mockHA))
+
+	mockHA.SetState(testSensor, "42", map[string]interface{}{})
+	assert.Equal(t, []string{"switch.turn_off", "cover.close_cover"}, deviceCalls(mockHA))
+
+	status = manager.GetShadowState().Outputs.Spaces[0]
+	assert.False(t, status.HeatersOn)
+	assert.Nil(t, status.StartTemperature)
+	assert.Contains(t, status.LastReason, "recovered")
+}
+
+func TestEnergyLevelLimitsHeaters(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, "37")
+	assert.Equal(t, []string{"switch.turn_on", "cover.open_cover"}, deviceCalls(mockHA))
+
+	// Red stops the heaters at 36; the dampers keep the house's heat coming
+	mockHA.ClearServiceCalls()
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "red"))
+	assert.Equal(t, []string{"switch.turn_off"}, deviceCalls(mockHA))
+
+	mockHA.ClearServiceCalls()
+	mockHA.SetState(testSensor, "35", map[string]interface{}{})
+	assert.Empty(t, deviceCalls(mockHA), "above red's on_below")
+
+	mockHA.SetState(testSensor, "33.5", map[string]interface{}{})
+	assert.Equal(t, []string{"switch.turn_on"}, deviceCalls(mockHA))
+
+	// Black doesn't allow heaters at all
+	mockHA.ClearServiceCalls()
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "black"))
+	assert.Equal(t, []string{"switch.turn_off"}, deviceCalls(mockHA))
+
+	status := manager.GetShadowState().Outputs.Spaces[0]
+	assert.False(t, status.HeatersAllowed)
+	assert.True(t, status.DampersOpen)
+	assert.Contains(t, status.LastReason, "energy level black")
+}
+
+func TestAlertsWhenStillDropping(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, "37")
+	mockHA.ClearServiceCalls()
+
+	// Dropping, but not for long enough yet
+	mockClock.Advance(10 * time.Minute)
+	mockHA.SetState(testSensor, "34", map[string]interface{}{})
+	assert.Empty(t, deviceCalls(mockHA))
+
+	mockClock.Advance(25 * time.Minute)
+	mockHA.SetState(testSensor, "33.5", map[string]interface{}{})
+	require.Equal(t, []string{"notify.notify"}, deviceCalls(mockHA))
+	message := mockHA.GetServiceCalls()[0].Data["message"]
+	assert.Equal(t, "garage is at 33.5°, down from 37.0° when heaters and dampers started 35m0s ago", message)
+
+	// Only once per mitigation
+	mockHA.ClearServiceCalls()
+	mockClock.Advance(30 * time.Minute)
+	mockHA.SetState(testSensor, "32", map[string]interface{}{})
+	assert.Empty(t, deviceCalls(mockHA))
+
+	assert.False(t, manager.GetShadowState().Outputs.Spaces[0].LastAlert.IsZero())
+}
+
+func TestAdoptsHeatersOnAfterRestart(t *testing.T) {
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	mockHA.SetState(testSensor, "40", map[string]interface{}{})
+	mockHA.SetState(testHeater, "on", map[string]interface{}{})
+	mockHA.SetState(testDamper, "closed", map[string]interface{}{})
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, false, nil)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	assert.True(t, manager.GetShadowState().Outputs.Spaces[0].HeatersOn)
+
+	mockHA.ClearServiceCalls()
+	mockHA.SetState(testSensor, "43", map[string]interface{}{})
+	assert.Equal(t, []string{"switch.turn_off"}, deviceCalls(mockHA), "adopted heaters are turned off once warm")
+}
+
+func TestReadOnlyDoesNotSwitch(t *testing.T) {
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	mockHA.SetState(testSensor, "30", map[string]interface{}{})
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, true, nil)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	assert.Empty(t, deviceCalls(mockHA))
+	assert.True(t, manager.GetShadowState().Outputs.Spaces[0].HeatersOn)
+}
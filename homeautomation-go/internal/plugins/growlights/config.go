@@ -0,0 +1,97 @@
+package growlights
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureConfig represents the schedule for a single grow light fixture
+type FixtureConfig struct {
+	Name             string  `yaml:"name"`
+	EntityID         string  `yaml:"entity_id"`         // switch.* or light.*
+	PhotoperiodHours float64 `yaml:"photoperiod_hours"` // Total daily light target, 0 = use default
+	EarliestOn       string  `yaml:"earliest_on"`       // Format: "06:00"
+	LatestOff        string  `yaml:"latest_off"`        // Format: "21:30"
+	BrightnessPct    *int    `yaml:"brightness_pct"`    // Only applied to light.* entities
+}
+
+// GrowLightSettings holds the plugin-wide grow light settings
+type GrowLightSettings struct {
+	DefaultPhotoperiodHours float64         `yaml:"default_photoperiod_hours"`
+	DeferEnergyLevels       []string        `yaml:"defer_energy_levels"`
+	Fixtures                []FixtureConfig `yaml:"fixtures"`
+}
+
+// GrowLightConfig represents the growlight_config.yaml structure
+type GrowLightConfig struct {
+	GrowLights GrowLightSettings `yaml:"grow_lights"`
+}
+
+// PhotoperiodFor returns the photoperiod target for a fixture, falling back to the default
+func (c *GrowLightConfig) PhotoperiodFor(fixture FixtureConfig) time.Duration {
+	hours := fixture.PhotoperiodHours
+	if hours <= 0 {
+		hours = c.GrowLights.DefaultPhotoperiodHours
+	}
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// ShouldDefer returns true if supplemental lighting should be deferred at the given energy level
+func (c *GrowLightConfig) ShouldDefer(energyLevel string) bool {
+	for _, level := range c.GrowLights.DeferEnergyLevels {
+		if level == energyLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every fixture has the fields needed to schedule it
+func (c *GrowLightConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i, fixture := range c.GrowLights.Fixtures {
+		if fixture.Name == "" {
+			return fmt.Errorf("fixture %d: name is required", i)
+		}
+		if seen[fixture.Name] {
+			return fmt.Errorf("fixture %q: duplicate name", fixture.Name)
+		}
+		seen[fixture.Name] = true
+
+		if fixture.EntityID == "" {
+			return fmt.Errorf("fixture %q: entity_id is required", fixture.Name)
+		}
+		if c.PhotoperiodFor(fixture) <= 0 {
+			return fmt.Errorf("fixture %q: photoperiod_hours must be positive", fixture.Name)
+		}
+		if _, err := time.Parse("15:04", fixture.EarliestOn); err != nil {
+			return fmt.Errorf("fixture %q: invalid earliest_on: %w", fixture.Name, err)
+		}
+		if _, err := time.Parse("15:04", fixture.LatestOff); err != nil {
+			return fmt.Errorf("fixture %q: invalid latest_off: %w", fixture.Name, err)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the grow light configuration from a YAML file
+func LoadConfig(path string) (*GrowLightConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config GrowLightConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,119 @@
+package growlights
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "growlight_config.yaml")
+
+	configContent := `---
+grow_lights:
+  default_photoperiod_hours: 14
+  defer_energy_levels:
+    - black
+  fixtures:
+    - name: Herbs
+      entity_id: switch.herb_light
+      earliest_on: "06:00"
+      latest_off: "21:30"
+    - name: Seedlings
+      entity_id: light.seedling_light
+      photoperiod_hours: 16
+      earliest_on: "05:30"
+      latest_off: "22:00"
+      brightness_pct: 80
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, 14.0, config.GrowLights.DefaultPhotoperiodHours)
+	assert.Equal(t, []string{"black"}, config.GrowLights.DeferEnergyLevels)
+	require.Len(t, config.GrowLights.Fixtures, 2)
+
+	assert.Equal(t, "switch.herb_light", config.GrowLights.Fixtures[0].EntityID)
+	assert.Nil(t, config.GrowLights.Fixtures[0].BrightnessPct)
+	require.NotNil(t, config.GrowLights.Fixtures[1].BrightnessPct)
+	assert.Equal(t, 80, *config.GrowLights.Fixtures[1].BrightnessPct)
+}
+
+func TestLoadConfig_FileNotFound(t *testing.T) {
+	_, err := LoadConfig("/nonexistent/growlight_config.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_InvalidYAML(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "growlight_config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("grow_lights: [unclosed"), 0644))
+
+	_, err := LoadConfig(configPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_ProductionConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/growlight_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.GrowLights.Fixtures)
+}
+
+func TestValidate(t *testing.T) {
+	valid := FixtureConfig{Name: "Herbs", EntityID: "switch.herb_light", EarliestOn: "06:00", LatestOff: "21:30"}
+
+	tests := []struct {
+		name     string
+		fixtures []FixtureConfig
+		wantErr  bool
+	}{
+		{"valid", []FixtureConfig{valid}, false},
+		{"missing name", []FixtureConfig{{EntityID: "switch.x", EarliestOn: "06:00", LatestOff: "21:30"}}, true},
+		{"missing entity", []FixtureConfig{{Name: "X", EarliestOn: "06:00", LatestOff: "21:30"}}, true},
+		{"duplicate name", []FixtureConfig{valid, valid}, true},
+		{"bad earliest_on", []FixtureConfig{{Name: "X", EntityID: "switch.x", EarliestOn: "6am", LatestOff: "21:30"}}, true},
+		{"bad latest_off", []FixtureConfig{{Name: "X", EntityID: "switch.x", EarliestOn: "06:00", LatestOff: "25:00"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &GrowLightConfig{GrowLights: GrowLightSettings{DefaultPhotoperiodHours: 14, Fixtures: tt.fixtures}}
+			err := config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RequiresPhotoperiod(t *testing.T) {
+	config := &GrowLightConfig{GrowLights: GrowLightSettings{
+		Fixtures: []FixtureConfig{{Name: "Herbs", EntityID: "switch.herb_light", EarliestOn: "06:00", LatestOff: "21:30"}},
+	}}
+	assert.Error(t, config.Validate(), "fixture without photoperiod and no default should be rejected")
+}
+
+func TestPhotoperiodFor(t *testing.T) {
+	config := &GrowLightConfig{GrowLights: GrowLightSettings{DefaultPhotoperiodHours: 14}}
+
+	assert.Equal(t, 14*time.Hour, config.PhotoperiodFor(FixtureConfig{}))
+	assert.Equal(t, 16*time.Hour+30*time.Minute, config.PhotoperiodFor(FixtureConfig{PhotoperiodHours: 16.5}))
+}
+
+func TestShouldDefer(t *testing.T) {
+	config := &GrowLightConfig{GrowLights: GrowLightSettings{DeferEnergyLevels: []string{"black", "red"}}}
+
+	assert.True(t, config.ShouldDefer("black"))
+	assert.True(t, config.ShouldDefer("red"))
+	assert.False(t, config.ShouldDefer("green"))
+	assert.False(t, config.ShouldDefer(""))
+}
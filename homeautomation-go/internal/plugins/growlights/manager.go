@@ -0,0 +1,390 @@
+package growlights
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// EvaluationInterval is how often fixture schedules are re-evaluated
+const EvaluationInterval = 1 * time.Minute
+
+// SunTimesProvider supplies today's sun event times (implemented by dayphase.Calculator)
+type SunTimesProvider interface {
+	GetSunTimes() map[string]time.Time
+}
+
+// Window is a period during which a fixture should be on
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains returns true if t falls within the window
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Manager schedules supplemental grow lighting to complement natural daylight
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *GrowLightConfig
+	sunTimes     SunTimesProvider
+	logger       *zap.Logger
+	readOnly     bool
+	timezone     *time.Location
+	clock        clock.Clock
+
+	// Last commanded on/off state per fixture, to avoid repeating service calls
+	commanded map[string]bool
+	mu        sync.Mutex
+
+	// Control for the periodic evaluation loop
+	stopChan chan struct{}
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.GrowLightsTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Grow Lights manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *GrowLightConfig, sunTimes SunTimesProvider, logger *zap.Logger, readOnly bool, timezone *time.Location, registry *shadowstate.SubscriptionRegistry) *Manager {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	shadowTracker := shadowstate.NewGrowLightsTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(EvaluationInterval)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		sunTimes:      sunTimes,
+		logger:        recorder.Logger(logger.Named("growlights")),
+		health:        recorder,
+		readOnly:      readOnly,
+		timezone:      timezone,
+		clock:         clock.NewRealClock(),
+		commanded:     make(map[string]bool),
+		stopChan:      make(chan struct{}),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "growlights", logger.Named("growlights")),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.GrowLightsShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start begins scheduling grow lights
+func (m *Manager) Start(ctx context.Context) error {
+	// Run under ctx; restarting after Stop also needs an open stopChan
+	if m.ctx.Err() != nil {
+		m.stopChan = make(chan struct{})
+	}
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Grow Lights Manager",
+		zap.Int("fixtures", len(m.config.GrowLights.Fixtures)))
+
+	// Re-evaluate immediately when the energy level changes so deferral takes effect promptly
+	if err := m.subHelper.SubscribeToState("currentEnergyLevel", m.handleEnergyLevelChange); err != nil {
+		return fmt.Errorf("failed to subscribe to currentEnergyLevel: %w", err)
+	}
+
+	m.subHelper.CaptureInitialInputs()
+
+	go m.runEvaluationLoop(m.stopChan)
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Grow Lights Manager started successfully")
+	return nil
+}
+
+// Stop stops the Grow Lights Manager and cleans up subscriptions
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Grow Lights Manager")
+
+	m.cancel()
+
+	close(m.stopChan)
+	m.subHelper.UnsubscribeAll()
+
+	m.logger.Info("Grow Lights Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset forgets commanded fixture states and re-applies the current schedule
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Grow Lights - re-applying fixture schedules")
+
+	m.mu.Lock()
+	m.commanded = make(map[string]bool)
+	m.mu.Unlock()
+
+	m.evaluate("reset")
+
+	m.logger.Info("Successfully reset Grow Lights")
+	return nil
+}
+
+// runEvaluationLoop re-evaluates fixture schedules every EvaluationInterval
+func (m *Manager) runEvaluationLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(EvaluationInterval)
+	defer ticker.Stop()
+
+	// Evaluate immediately on start
+	m.evaluate("startup")
+
+	for {
+		select {
+		case <-ticker.C:
+			m.evaluate("timer")
+		case <-stop:
+			m.logger.Info("Stopping grow light evaluation loop")
+			return
+		}
+	}
+}
+
+// handleEnergyLevelChange re-evaluates fixtures when the energy level changes
+func (m *Manager) handleEnergyLevelChange(key string, oldValue, newValue interface{}) {
+	m.logger.Debug("Energy level changed, re-evaluating grow lights",
+		zap.Any("old", oldValue),
+		zap.Any("new", newValue))
+	m.evaluate(key)
+}
+
+// evaluate computes each fixture's desired state and switches it if needed
+func (m *Manager) evaluate(trigger string) {
+	m.health.Tick()
+	now := m.clock.Now().In(m.timezone)
+
+	sunTimes := m.sunTimes.GetSunTimes()
+	sunrise, sunset := sunTimes["sunrise"], sunTimes["sunset"]
+	if sunrise.IsZero() || sunset.IsZero() || !sunset.After(sunrise) {
+		m.logger.Warn("Sun times unavailable, skipping grow light evaluation",
+			zap.Time("sunrise", sunrise),
+			zap.Time("sunset", sunset))
+		return
+	}
+	sunrise, sunset = sunrise.In(m.timezone), sunset.In(m.timezone)
+	naturalDayLength := sunset.Sub(sunrise)
+
+	energyLevel, err := m.stateManager.GetString("currentEnergyLevel")
+	if err != nil {
+		m.logger.Warn("Failed to get currentEnergyLevel, not deferring", zap.Error(err))
+		energyLevel = ""
+	}
+	deferred := m.config.ShouldDefer(energyLevel)
+
+	m.shadowTracker.UpdateCurrentInputs(map[string]interface{}{
+		"sunrise":            sunrise.Format(time.RFC3339),
+		"sunset":             sunset.Format(time.RFC3339),
+		"currentEnergyLevel": energyLevel,
+		"trigger":            trigger,
+	})
+	m.shadowTracker.UpdateEvaluation(naturalDayLength.Hours(), deferred)
+
+	for _, fixture := range m.config.GrowLights.Fixtures {
+		supplemental := m.config.PhotoperiodFor(fixture) - naturalDayLength
+		windows := ComputeWindows(fixture, supplemental, sunrise, sunset)
+
+		inWindow := false
+		for _, w := range windows {
+			if w.Contains(now) {
+				inWindow = true
+				break
+			}
+		}
+
+		shouldBeOn := inWindow && !deferred
+		reason := describeDecision(inWindow, deferred, supplemental, energyLevel)
+
+		m.applyFixtureState(fixture, shouldBeOn, reason, now)
+
+		fixtureState := shadowstate.GrowLightFixtureState{
+			EntityID:          fixture.EntityID,
+			On:                shouldBeOn,
+			Deferred:          inWindow && deferred,
+			SupplementalHours: maxDuration(supplemental, 0).Hours(),
+			Windows:           make([]shadowstate.GrowLightWindow, 0, len(windows)),
+			Reason:            reason,
+		}
+		for _, w := range windows {
+			fixtureState.Windows = append(fixtureState.Windows, shadowstate.GrowLightWindow{Start: w.Start, End: w.End})
+		}
+		if previous, ok := m.GetShadowState().Outputs.Fixtures[fixture.Name]; ok && previous.On == shouldBeOn {
+			fixtureState.LastChanged = previous.LastChanged
+		} else {
+			fixtureState.LastChanged = now
+		}
+		m.shadowTracker.UpdateFixture(fixture.Name, fixtureState)
+	}
+}
+
+// applyFixtureState turns a fixture on or off if it differs from the last commanded state
+func (m *Manager) applyFixtureState(fixture FixtureConfig, on bool, reason string, now time.Time) {
+	m.mu.Lock()
+	previous, known := m.commanded[fixture.Name]
+	if known && previous == on {
+		m.mu.Unlock()
+		return
+	}
+	m.commanded[fixture.Name] = on
+	m.mu.Unlock()
+
+	domain := ha.EntityDomain(fixture.EntityID)
+	service := "turn_off"
+	if on {
+		service = "turn_on"
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would switch grow light",
+			zap.String("fixture", fixture.Name),
+			zap.String("entity_id", fixture.EntityID),
+			zap.String("service", service),
+			zap.String("reason", reason))
+		return
+	}
+
+	data := map[string]interface{}{
+		"entity_id": fixture.EntityID,
+	}
+	if on && domain == "light" && fixture.BrightnessPct != nil {
+		data["brightness_pct"] = *fixture.BrightnessPct
+	}
+
+	if err := m.haClient.CallService(m.ctx, domain, service, data); err != nil {
+		m.logger.Error("Failed to switch grow light",
+			zap.String("fixture", fixture.Name),
+			zap.String("entity_id", fixture.EntityID),
+			zap.String("service", service),
+			zap.Error(err))
+		// Forget the commanded state so the next evaluation retries
+		m.mu.Lock()
+		delete(m.commanded, fixture.Name)
+		m.mu.Unlock()
+		return
+	}
+
+	m.logger.Info("Grow light switched",
+		zap.String("fixture", fixture.Name),
+		zap.String("entity_id", fixture.EntityID),
+		zap.String("service", service),
+		zap.String("reason", reason))
+	m.shadowTracker.RecordFixtureSwitch(fixture.Name, on, reason, now)
+}
+
+// ComputeWindows returns the periods a fixture should be on to add the given
+// supplemental light to the natural day. Light is added after sunset first
+// (up to latest_off), and any remainder is added before sunrise (no earlier
+// than earliest_on).
+func ComputeWindows(fixture FixtureConfig, supplemental time.Duration, sunrise, sunset time.Time) []Window {
+	if supplemental <= 0 {
+		return nil
+	}
+
+	earliestOn := clockTimeOn(sunrise, fixture.EarliestOn)
+	latestOff := clockTimeOn(sunset, fixture.LatestOff)
+
+	windows := make([]Window, 0, 2)
+
+	// Evening extension
+	eveningEnd := sunset.Add(supplemental)
+	if eveningEnd.After(latestOff) {
+		eveningEnd = latestOff
+	}
+	if eveningEnd.After(sunset) {
+		windows = append(windows, Window{Start: sunset, End: eveningEnd})
+		supplemental -= eveningEnd.Sub(sunset)
+	}
+
+	// Morning extension for whatever the evening could not cover
+	if supplemental > 0 {
+		morningStart := sunrise.Add(-supplemental)
+		if morningStart.Before(earliestOn) {
+			morningStart = earliestOn
+		}
+		if sunrise.After(morningStart) {
+			windows = append([]Window{{Start: morningStart, End: sunrise}}, windows...)
+		}
+	}
+
+	return windows
+}
+
+// clockTimeOn combines an "HH:MM" string with the date of ref.
+// Config is validated at load time, so a parse failure falls back to ref itself.
+func clockTimeOn(ref time.Time, hhmm string) time.Time {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return ref
+	}
+	return time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), 0, 0, ref.Location())
+}
+
+// describeDecision explains why a fixture is in its desired state
+func describeDecision(inWindow, deferred bool, supplemental time.Duration, energyLevel string) string {
+	switch {
+	case supplemental <= 0:
+		return "Natural day length meets photoperiod"
+	case inWindow && deferred:
+		return fmt.Sprintf("Deferred due to energy level '%s'", energyLevel)
+	case inWindow:
+		return "Within supplemental lighting window"
+	default:
+		return "Outside supplemental lighting window"
+	}
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,308 @@
+package growlights
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSunTimes returns fixed sunrise/sunset times
+type fakeSunTimes struct {
+	sunrise time.Time
+	sunset  time.Time
+}
+
+func (f *fakeSunTimes) GetSunTimes() map[string]time.Time {
+	return map[string]time.Time{
+		"sunrise": f.sunrise,
+		"sunset":  f.sunset,
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// winterDay has 10 hours of natural light (07:00 - 17:00)
+func winterDay() *fakeSunTimes {
+	return &fakeSunTimes{
+		sunrise: time.Date(2025, 1, 15, 7, 0, 0, 0, time.UTC),
+		sunset:  time.Date(2025, 1, 15, 17, 0, 0, 0, time.UTC),
+	}
+}
+
+func createTestConfig() *GrowLightConfig {
+	return &GrowLightConfig{
+		GrowLights: GrowLightSettings{
+			DefaultPhotoperiodHours: 14,
+			DeferEnergyLevels:       []string{"black", "red"},
+			Fixtures: []FixtureConfig{
+				{
+					Name:       "Herbs",
+					EntityID:   "switch.herb_light",
+					EarliestOn: "06:00",
+					LatestOff:  "21:30",
+				},
+				{
+					Name:             "Seedlings",
+					EntityID:         "light.seedling_light",
+					PhotoperiodHours: 16,
+					EarliestOn:       "05:30",
+					LatestOff:        "19:00",
+					BrightnessPct:    intPtr(80),
+				},
+			},
+		},
+	}
+}
+
+func setupTest(t *testing.T, readOnly bool, now time.Time) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(), winterDay(), logger, readOnly, time.UTC, nil)
+	mockClock := clock.NewMockClock(now)
+	manager.SetClock(mockClock)
+
+	return manager, mockHA, stateManager, mockClock
+}
+
+// setEnergyLevel sets currentEnergyLevel and discards the resulting input_text service call
+func setEnergyLevel(t *testing.T, mockHA *ha.MockClient, stateManager *state.Manager, level string) {
+	t.Helper()
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", level))
+	mockHA.ClearServiceCalls()
+}
+
+func callsFor(calls []ha.ServiceCall, entityID string) []ha.ServiceCall {
+	var result []ha.ServiceCall
+	for _, call := range calls {
+		if call.Data["entity_id"] == entityID {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+func TestComputeWindows(t *testing.T) {
+	sun := winterDay()
+	fixture := FixtureConfig{EarliestOn: "06:00", LatestOff: "21:30"}
+
+	tests := []struct {
+		name         string
+		supplemental time.Duration
+		expected     []Window
+	}{
+		{
+			name:         "no supplemental light needed",
+			supplemental: 0,
+			expected:     nil,
+		},
+		{
+			name:         "evening only",
+			supplemental: 4 * time.Hour,
+			expected: []Window{
+				{Start: sun.sunset, End: sun.sunset.Add(4 * time.Hour)},
+			},
+		},
+		{
+			name:         "evening capped at latest_off, remainder in morning",
+			supplemental: 5 * time.Hour,
+			expected: []Window{
+				{Start: time.Date(2025, 1, 15, 6, 30, 0, 0, time.UTC), End: sun.sunrise},
+				{Start: sun.sunset, End: time.Date(2025, 1, 15, 21, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:         "morning capped at earliest_on",
+			supplemental: 8 * time.Hour,
+			expected: []Window{
+				{Start: time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC), End: sun.sunrise},
+				{Start: sun.sunset, End: time.Date(2025, 1, 15, 21, 30, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows := ComputeWindows(fixture, tt.supplemental, sun.sunrise, sun.sunset)
+			assert.Equal(t, tt.expected, windows)
+		})
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	start := time.Date(2025, 1, 15, 17, 0, 0, 0, time.UTC)
+	w := Window{Start: start, End: start.Add(time.Hour)}
+
+	assert.True(t, w.Contains(start), "start is inclusive")
+	assert.True(t, w.Contains(start.Add(30*time.Minute)))
+	assert.False(t, w.Contains(start.Add(time.Hour)), "end is exclusive")
+	assert.False(t, w.Contains(start.Add(-time.Minute)))
+}
+
+func TestEvaluate_TurnsOnDuringEveningWindow(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	manager.evaluate("test")
+
+	calls := mockHA.GetServiceCalls()
+	herbCalls := callsFor(calls, "switch.herb_light")
+	require.Len(t, herbCalls, 1)
+	assert.Equal(t, "switch", herbCalls[0].Domain)
+	assert.Equal(t, "turn_on", herbCalls[0].Service)
+
+	seedlingCalls := callsFor(calls, "light.seedling_light")
+	require.Len(t, seedlingCalls, 1)
+	assert.Equal(t, "light", seedlingCalls[0].Domain)
+	assert.Equal(t, "turn_on", seedlingCalls[0].Service)
+	assert.Equal(t, 80, seedlingCalls[0].Data["brightness_pct"])
+
+	shadow := manager.GetShadowState()
+	assert.InDelta(t, 10.0, shadow.Outputs.NaturalDayLengthHours, 0.001)
+	assert.False(t, shadow.Outputs.Deferred)
+	assert.True(t, shadow.Outputs.Fixtures["Herbs"].On)
+	assert.InDelta(t, 4.0, shadow.Outputs.Fixtures["Herbs"].SupplementalHours, 0.001)
+	assert.InDelta(t, 6.0, shadow.Outputs.Fixtures["Seedlings"].SupplementalHours, 0.001)
+}
+
+func TestStop_CancelsServiceCalls(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	manager.Stop()
+	manager.evaluate("test")
+
+	assert.Empty(t, mockHA.GetServiceCalls(), "no service calls should be made after Stop")
+
+	// Failed calls are forgotten so a restarted manager would retry them
+	manager.mu.Lock()
+	assert.Empty(t, manager.commanded)
+	manager.mu.Unlock()
+}
+
+func TestStart_AfterStopResumes(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	require.NoError(t, manager.Start(context.Background()))
+	manager.Stop()
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	mockHA.ClearServiceCalls()
+	manager.evaluate("test")
+	assert.NotEmpty(t, mockHA.GetServiceCalls(), "a restarted manager acts again")
+}
+
+func TestEvaluate_TurnsOffOutsideWindow(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	manager.evaluate("test")
+	mockHA.ClearServiceCalls()
+
+	// Herbs window ends at 21:00 (14h - 10h natural = 4h after sunset)
+	mockClock.Set(time.Date(2025, 1, 15, 21, 15, 0, 0, time.UTC))
+	manager.evaluate("test")
+
+	herbCalls := callsFor(mockHA.GetServiceCalls(), "switch.herb_light")
+	require.Len(t, herbCalls, 1)
+	assert.Equal(t, "turn_off", herbCalls[0].Service)
+	assert.False(t, manager.GetShadowState().Outputs.Fixtures["Herbs"].On)
+}
+
+func TestEvaluate_DoesNotRepeatServiceCalls(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	manager.evaluate("test")
+	mockHA.ClearServiceCalls()
+
+	mockClock.Advance(time.Minute)
+	manager.evaluate("test")
+
+	assert.Empty(t, mockHA.GetServiceCalls(), "unchanged fixtures should not be re-commanded")
+}
+
+func TestEvaluate_DefersOnLowEnergy(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "red")
+
+	manager.evaluate("test")
+
+	for _, call := range mockHA.GetServiceCalls() {
+		assert.Equal(t, "turn_off", call.Service, "fixtures should stay off while deferred")
+	}
+
+	shadow := manager.GetShadowState()
+	assert.True(t, shadow.Outputs.Deferred)
+	assert.True(t, shadow.Outputs.Fixtures["Herbs"].Deferred)
+	assert.False(t, shadow.Outputs.Fixtures["Herbs"].On)
+	assert.Contains(t, shadow.Outputs.Fixtures["Herbs"].Reason, "red")
+}
+
+func TestEnergyLevelChange_ResumesLighting(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "black")
+
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	// Startup evaluation runs asynchronously
+	assert.Eventually(t, func() bool {
+		return len(callsFor(mockHA.GetServiceCalls(), "switch.herb_light")) == 1
+	}, time.Second, 10*time.Millisecond)
+	mockHA.ClearServiceCalls()
+
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "green"))
+
+	assert.Eventually(t, func() bool {
+		calls := callsFor(mockHA.GetServiceCalls(), "switch.herb_light")
+		return len(calls) == 1 && calls[0].Service == "turn_on"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestEvaluate_ReadOnlyMode(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, true, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	manager.evaluate("test")
+
+	assert.Empty(t, mockHA.GetServiceCalls(), "read-only mode should not call services")
+	assert.True(t, manager.GetShadowState().Outputs.Fixtures["Herbs"].On, "shadow state should still be recorded")
+}
+
+func TestEvaluate_SkipsWithoutSunTimes(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	manager.sunTimes = &fakeSunTimes{}
+
+	manager.evaluate("test")
+
+	assert.Empty(t, mockHA.GetServiceCalls())
+	assert.Empty(t, manager.GetShadowState().Outputs.Fixtures)
+}
+
+func TestReset_ReappliesCommandedState(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false, time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC))
+	setEnergyLevel(t, mockHA, stateManager, "green")
+
+	manager.evaluate("test")
+	mockHA.ClearServiceCalls()
+
+	require.NoError(t, manager.Reset())
+
+	assert.Len(t, callsFor(mockHA.GetServiceCalls(), "switch.herb_light"), 1, "reset should re-command fixtures")
+}
@@ -0,0 +1,136 @@
+package hotwater
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults for optional schedule settings
+const (
+	DefaultSlotMinutes    = 30
+	DefaultHistoryDays    = 14
+	DefaultMinDays        = 3
+	DefaultMinProbability = 0.3
+	DefaultLeadMinutes    = 10
+	DefaultRunMinutes     = 10
+)
+
+// HotWaterSettings holds the pump, usage sensors and scheduling settings
+type HotWaterSettings struct {
+	PumpEntity        string  `yaml:"pump_entity"`        // switch.*
+	FlowSensor        string  `yaml:"flow_sensor"`        // Optional hot water flow rate sensor
+	FlowThreshold     float64 `yaml:"flow_threshold"`     // Flow at or above this counts as a draw
+	TemperatureSensor string  `yaml:"temperature_sensor"` // Optional hot water line temperature near the taps
+	DrawTemperature   float64 `yaml:"draw_temperature"`   // Without a flow sensor, the line rising past this while the pump is off counts as a draw
+	ReadyTemperature  float64 `yaml:"ready_temperature"`  // Pump stops early once the line reaches this; 0 = run the full time
+	SlotMinutes       int     `yaml:"slot_minutes"`       // Width of a schedule slot; must divide a day
+	HistoryDays       int     `yaml:"history_days"`       // Days of usage history the schedule is learned from
+	MinDays           int     `yaml:"min_days"`           // Days observed before a weekday/weekend schedule is used
+	MinProbability    float64 `yaml:"min_probability"`    // Share of days with a draw in a slot for it to be scheduled
+	LeadMinutes       int     `yaml:"lead_minutes"`       // Start the pump this long before a scheduled slot or the alarm
+	RunMinutes        int     `yaml:"run_minutes"`        // Longest the pump runs at a time
+}
+
+// HotWaterConfig represents the hot_water_config.yaml structure
+type HotWaterConfig struct {
+	HotWater HotWaterSettings `yaml:"hot_water"`
+}
+
+// Slot returns the slot width
+func (s *HotWaterSettings) Slot() time.Duration {
+	return time.Duration(orDefault(s.SlotMinutes, DefaultSlotMinutes)) * time.Minute
+}
+
+// History returns how many days of usage are kept
+func (s *HotWaterSettings) History() int {
+	return orDefault(s.HistoryDays, DefaultHistoryDays)
+}
+
+// MinimumDays returns how many days must be observed before a schedule is used
+func (s *HotWaterSettings) MinimumDays() int {
+	return orDefault(s.MinDays, DefaultMinDays)
+}
+
+// Threshold returns the share of days a slot needs draws on to be scheduled
+func (s *HotWaterSettings) Threshold() float64 {
+	if s.MinProbability > 0 {
+		return s.MinProbability
+	}
+	return DefaultMinProbability
+}
+
+// Lead returns how long before a scheduled slot the pump starts
+func (s *HotWaterSettings) Lead() time.Duration {
+	return time.Duration(orDefault(s.LeadMinutes, DefaultLeadMinutes)) * time.Minute
+}
+
+// Run returns how long the pump runs at a time
+func (s *HotWaterSettings) Run() time.Duration {
+	return time.Duration(orDefault(s.RunMinutes, DefaultRunMinutes)) * time.Minute
+}
+
+// Validate checks the pump, sensors and schedule settings
+func (c *HotWaterConfig) Validate() error {
+	s := c.HotWater
+	if !strings.HasPrefix(s.PumpEntity, "switch.") {
+		return fmt.Errorf("hot_water: pump_entity must be a switch.* entity, got %q", s.PumpEntity)
+	}
+	if s.FlowSensor == "" && s.TemperatureSensor == "" {
+		return fmt.Errorf("hot_water: at least one of flow_sensor or temperature_sensor is required")
+	}
+	if s.FlowSensor != "" && s.FlowThreshold <= 0 {
+		return fmt.Errorf("hot_water: flow_threshold must be positive when flow_sensor is set")
+	}
+	if s.TemperatureSensor != "" && s.DrawTemperature <= 0 && s.FlowSensor == "" {
+		return fmt.Errorf("hot_water: draw_temperature is required when temperature_sensor is the only usage sensor")
+	}
+	if s.ReadyTemperature < 0 || s.DrawTemperature < 0 {
+		return fmt.Errorf("hot_water: temperatures must not be negative")
+	}
+	if s.ReadyTemperature > 0 && s.TemperatureSensor == "" {
+		return fmt.Errorf("hot_water: ready_temperature requires temperature_sensor")
+	}
+	if s.SlotMinutes < 0 || (24*60)%orDefault(s.SlotMinutes, DefaultSlotMinutes) != 0 {
+		return fmt.Errorf("hot_water: slot_minutes must divide a day evenly, got %d", s.SlotMinutes)
+	}
+	if s.HistoryDays < 0 || s.MinDays < 0 || s.LeadMinutes < 0 || s.RunMinutes < 0 {
+		return fmt.Errorf("hot_water: history_days, min_days, lead_minutes and run_minutes must not be negative")
+	}
+	if s.MinimumDays() > s.History() {
+		return fmt.Errorf("hot_water: min_days (%d) exceeds history_days (%d)", s.MinimumDays(), s.History())
+	}
+	if s.MinProbability < 0 || s.MinProbability > 1 {
+		return fmt.Errorf("hot_water: min_probability must be between 0 and 1")
+	}
+	return nil
+}
+
+// LoadConfig loads the hot water configuration from a YAML file
+func LoadConfig(path string) (*HotWaterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config HotWaterConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func orDefault(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
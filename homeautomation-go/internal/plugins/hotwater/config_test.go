@@ -0,0 +1,77 @@
+package hotwater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/hot_water_config.yaml")
+	require.NoError(t, err)
+
+	s := config.HotWater
+	assert.Equal(t, "switch.hot_water_recirculation", s.PumpEntity)
+	assert.Equal(t, 30*time.Minute, s.Slot())
+	assert.Equal(t, 10*time.Minute, s.Lead())
+	assert.Equal(t, 10*time.Minute, s.Run())
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hot_water_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+hot_water:
+  pump_entity: switch.recirc
+  flow_sensor: sensor.flow
+  flow_threshold: 1
+`), 0o644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	s := config.HotWater
+	assert.Equal(t, DefaultSlotMinutes*time.Minute, s.Slot())
+	assert.Equal(t, DefaultHistoryDays, s.History())
+	assert.Equal(t, DefaultMinDays, s.MinimumDays())
+	assert.Equal(t, DefaultMinProbability, s.Threshold())
+}
+
+func TestValidate(t *testing.T) {
+	valid := createTestConfig().HotWater
+
+	tests := []struct {
+		name    string
+		modify  func(s *HotWaterSettings)
+		wantErr string
+	}{
+		{"valid", func(s *HotWaterSettings) {}, ""},
+		{"temperature only", func(s *HotWaterSettings) { s.FlowSensor = ""; s.DrawTemperature = 30 }, ""},
+		{"pump not a switch", func(s *HotWaterSettings) { s.PumpEntity = "light.pump" }, "pump_entity"},
+		{"no usage sensor", func(s *HotWaterSettings) { s.FlowSensor = ""; s.TemperatureSensor = "" }, "at least one"},
+		{"flow without threshold", func(s *HotWaterSettings) { s.FlowThreshold = 0 }, "flow_threshold"},
+		{"temperature only without draw temperature", func(s *HotWaterSettings) { s.FlowSensor = "" }, "draw_temperature"},
+		{"ready without temperature sensor", func(s *HotWaterSettings) { s.TemperatureSensor = "" }, "ready_temperature"},
+		{"slot does not divide a day", func(s *HotWaterSettings) { s.SlotMinutes = 25 }, "slot_minutes"},
+		{"min days exceeds history", func(s *HotWaterSettings) { s.MinDays = 20 }, "min_days"},
+		{"probability too high", func(s *HotWaterSettings) { s.MinProbability = 1.5 }, "min_probability"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.modify(&s)
+			config := &HotWaterConfig{HotWater: s}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package hotwater
+
+import (
+	"time"
+
+	"homeautomation/internal/shadowstate"
+)
+
+// dateLayout keys usage history by local date
+const dateLayout = "2006-01-02"
+
+// usageHistory records which slots of each day had a hot water draw
+type usageHistory struct {
+	start time.Time               // Midnight of the first observed day
+	days  map[string]map[int]bool // Local date -> slots with a draw
+}
+
+// newUsageHistory starts observing on the day of now
+func newUsageHistory(now time.Time) *usageHistory {
+	return &usageHistory{
+		start: midnight(now),
+		days:  make(map[string]map[int]bool),
+	}
+}
+
+// record marks the slot containing at as having a draw, and forgets days
+// older than the history window
+func (h *usageHistory) record(at time.Time, s *HotWaterSettings) {
+	day := at.Format(dateLayout)
+	if h.days[day] == nil {
+		h.days[day] = make(map[int]bool)
+	}
+	h.days[day][slotIndex(at, s.Slot())] = true
+
+	oldest := midnight(at).AddDate(0, 0, -s.History()).Format(dateLayout)
+	for d := range h.days {
+		if d < oldest {
+			delete(h.days, d)
+		}
+	}
+}
+
+// learn builds the weekday and weekend schedules from the complete days in
+// the history window. A slot is scheduled when at least min_probability of
+// the observed days had a draw in it.
+func (h *usageHistory) learn(now time.Time, s *HotWaterSettings) shadowstate.HotWaterSchedule {
+	slot := s.Slot()
+	slotsPerDay := int(24 * time.Hour / slot)
+
+	today := midnight(now)
+	from := today.AddDate(0, 0, -s.History())
+	if h.start.After(from) {
+		from = h.start
+	}
+
+	var observed [2]int
+	counts := [2][]int{make([]int, slotsPerDay), make([]int, slotsPerDay)}
+	for d := from; d.Before(today); d = d.AddDate(0, 0, 1) {
+		kind := dayKind(d)
+		observed[kind]++
+		for i := range h.days[d.Format(dateLayout)] {
+			counts[kind][i]++
+		}
+	}
+
+	schedule := shadowstate.HotWaterSchedule{
+		SlotMinutes: int(slot / time.Minute),
+		LearnedAt:   now,
+	}
+	for kind, profile := range []*shadowstate.HotWaterDayProfile{&schedule.Weekday, &schedule.Weekend} {
+		profile.DaysObserved = observed[kind]
+		profile.Active = observed[kind] >= s.MinimumDays()
+		profile.Slots = []shadowstate.HotWaterSlot{}
+		if observed[kind] == 0 {
+			continue
+		}
+		for i, drawDays := range counts[kind] {
+			probability := float64(drawDays) / float64(observed[kind])
+			if drawDays == 0 || probability < s.Threshold() {
+				continue
+			}
+			profile.Slots = append(profile.Slots, shadowstate.HotWaterSlot{
+				Start:       slotStart(today, i, slot).Format("15:04"),
+				DrawDays:    drawDays,
+				Probability: probability,
+			})
+		}
+	}
+	return schedule
+}
+
+// profileFor returns the weekday or weekend profile for the day of t
+func profileFor(schedule shadowstate.HotWaterSchedule, t time.Time) shadowstate.HotWaterDayProfile {
+	if dayKind(t) == weekend {
+		return schedule.Weekend
+	}
+	return schedule.Weekday
+}
+
+// Day kinds, used as indexes into the learned counts
+const (
+	weekday = 0
+	weekend = 1
+)
+
+func dayKind(t time.Time) int {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return weekend
+	}
+	return weekday
+}
+
+// slotIndex returns which slot of its day t falls in, by wall clock time
+func slotIndex(t time.Time, slot time.Duration) int {
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes / int(slot/time.Minute)
+}
+
+// slotStart returns the wall clock start of slot i on the day of ref
+func slotStart(ref time.Time, i int, slot time.Duration) time.Time {
+	return time.Date(ref.Year(), ref.Month(), ref.Day(), 0, i*int(slot/time.Minute), 0, 0, ref.Location())
+}
+
+// midnight returns the start of the day of t, in t's location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
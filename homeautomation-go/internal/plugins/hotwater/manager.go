@@ -0,0 +1,561 @@
+// Package hotwater runs the hot water recirculation pump ahead of the times
+// hot water is usually drawn, learned from flow and line temperature
+// sensors, and ahead of the wake-up alarm, so hot water is at the tap
+// without running the pump around the clock. The pump only runs while
+// someone is home.
+package hotwater
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// EvaluationInterval is how often the pump schedule is re-evaluated
+const EvaluationInterval = 1 * time.Minute
+
+// runWindow is a period the pump should run
+type runWindow struct {
+	start  time.Time
+	end    time.Time
+	alarm  bool // Preheating for the wake-up alarm rather than a learned slot
+	reason string
+}
+
+// Manager learns when hot water is used and schedules the recirculation pump
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *HotWaterConfig
+	logger       *zap.Logger
+	readOnly     bool
+	timezone     *time.Location
+	clock        clock.Clock
+
+	// Usage history, sensor readings and pump state (protected by mu)
+	history      *usageHistory
+	schedule     shadowstate.HotWaterSchedule
+	flowing      bool
+	lineTemp     float64
+	hasLineTemp  bool
+	pumpOn       bool // Last commanded pump state
+	pumpKnown    bool
+	wakeRunUntil time.Time // End of the run started by someone waking up
+	readyUntil   time.Time // The line reached ready_temperature; the pump stays off until this run would have ended
+	drawDay      string
+	drawsToday   int
+	mu           sync.Mutex
+
+	// Control for the periodic evaluation loop
+	stopChan chan struct{}
+
+	// Battery safe mode slows the evaluation loop, nil if not configured
+	safeMode *safemode.Switch
+
+	// Subscriptions for cleanup
+	subscriptions   []state.Subscription
+	haSubscriptions []ha.Subscription
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.HotWaterTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new hot water recirculation manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *HotWaterConfig, logger *zap.Logger, readOnly bool, timezone *time.Location) *Manager {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(EvaluationInterval)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("hotwater")),
+		health:        recorder,
+		readOnly:      readOnly,
+		timezone:      timezone,
+		clock:         clock.NewRealClock(),
+		stopChan:      make(chan struct{}),
+		shadowTracker: shadowstate.NewHotWaterTracker(),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetSafeMode sets the battery safe mode switch. While safe mode is on the
+// timer evaluates only every slowdown_factor-th interval.
+func (m *Manager) SetSafeMode(s *safemode.Switch) {
+	m.safeMode = s
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.HotWaterShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// GetLearnedSchedule returns the schedule learned at the last evaluation
+func (m *Manager) GetLearnedSchedule() shadowstate.HotWaterSchedule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule := m.schedule
+	schedule.Weekday.Slots = append([]shadowstate.HotWaterSlot{}, m.schedule.Weekday.Slots...)
+	schedule.Weekend.Slots = append([]shadowstate.HotWaterSlot{}, m.schedule.Weekend.Slots...)
+	return schedule
+}
+
+// Start subscribes to presence, sleep, the alarm and the usage sensors, then
+// evaluates the pump every EvaluationInterval
+func (m *Manager) Start(ctx context.Context) error {
+	// Run under ctx; Stop cancels it and closes stopChan, so renew both
+	if m.ctx.Err() != nil {
+		m.stopChan = make(chan struct{})
+	}
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	s := &m.config.HotWater
+	m.logger.Info("Starting Hot Water Manager",
+		zap.String("pump_entity", s.PumpEntity),
+		zap.String("flow_sensor", s.FlowSensor),
+		zap.String("temperature_sensor", s.TemperatureSensor))
+
+	m.mu.Lock()
+	m.history = newUsageHistory(m.clock.Now().In(m.timezone))
+	m.mu.Unlock()
+
+	for _, key := range []string{"isAnyoneHome", "isEveryoneAsleep", "alarmTime"} {
+		sub, err := m.stateManager.Subscribe(key, func(key string, oldValue, newValue interface{}) {
+			m.evaluate(key)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", key, err)
+		}
+		m.subscriptions = append(m.subscriptions, sub)
+	}
+
+	sub, err := m.stateManager.Subscribe("isMasterAsleep", m.handleSleepChange)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to isMasterAsleep: %w", err)
+	}
+	m.subscriptions = append(m.subscriptions, sub)
+
+	if s.FlowSensor != "" {
+		if err := m.subscribeSensor(s.FlowSensor, m.handleFlowChange); err != nil {
+			return err
+		}
+	}
+	if s.TemperatureSensor != "" {
+		if err := m.subscribeSensor(s.TemperatureSensor, m.handleTemperatureChange); err != nil {
+			return err
+		}
+	}
+
+	m.evaluate("startup")
+	go m.runEvaluationLoop(m.stopChan)
+
+	m.health.Started(len(m.subscriptions) + len(m.haSubscriptions))
+	m.logger.Info("Hot Water Manager started successfully")
+	return nil
+}
+
+// subscribeSensor subscribes to a usage sensor and reads its current value
+// as the baseline, so the reading at startup is not counted as a draw
+func (m *Manager) subscribeSensor(entityID string, handler ha.StateChangeHandler) error {
+	sub, err := m.haClient.SubscribeStateChanges(entityID, handler)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+	}
+	m.haSubscriptions = append(m.haSubscriptions, sub)
+
+	current, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		m.logger.Warn("Failed to read hot water sensor", zap.String("entity_id", entityID), zap.Error(err))
+		return nil
+	}
+	value, ok := sensorValue(current)
+	if !ok {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entityID == m.config.HotWater.FlowSensor {
+		m.flowing = value >= m.config.HotWater.FlowThreshold
+	} else {
+		m.lineTemp, m.hasLineTemp = value, true
+	}
+	return nil
+}
+
+// Stop stops the evaluation loop and unsubscribes
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Hot Water Manager")
+
+	m.cancel()
+
+	close(m.stopChan)
+
+	for _, sub := range m.subscriptions {
+		sub.Unsubscribe()
+	}
+	m.subscriptions = nil
+
+	for _, sub := range m.haSubscriptions {
+		if err := sub.Unsubscribe(); err != nil {
+			m.logger.Warn("Failed to unsubscribe from hot water sensor", zap.Error(err))
+		}
+	}
+	m.haSubscriptions = nil
+
+	m.logger.Info("Hot Water Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset forgets the commanded pump state and re-applies the schedule. The
+// learned usage history is kept.
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Hot Water - re-applying pump schedule")
+
+	m.mu.Lock()
+	m.pumpKnown = false
+	m.wakeRunUntil = time.Time{}
+	m.readyUntil = time.Time{}
+	m.mu.Unlock()
+
+	m.evaluate("reset")
+
+	m.logger.Info("Successfully reset Hot Water")
+	return nil
+}
+
+// runEvaluationLoop re-evaluates the pump every EvaluationInterval
+func (m *Manager) runEvaluationLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(EvaluationInterval)
+	defer ticker.Stop()
+
+	tick := 0
+	for {
+		select {
+		case <-ticker.C:
+			tick++
+			if !m.safeMode.ShouldEvaluate(tick) {
+				// Skipped on purpose, so /health shouldn't report the loop as stale
+				m.health.Tick()
+				continue
+			}
+			m.evaluate("timer")
+		case <-stop:
+			m.logger.Info("Stopping hot water evaluation loop")
+			return
+		}
+	}
+}
+
+// handleSleepChange runs the pump when someone wakes up
+func (m *Manager) handleSleepChange(key string, oldValue, newValue interface{}) {
+	wasAsleep, _ := oldValue.(bool)
+	asleep, ok := newValue.(bool)
+	if !ok || asleep || !wasAsleep {
+		return
+	}
+
+	m.mu.Lock()
+	m.wakeRunUntil = m.clock.Now().In(m.timezone).Add(m.config.HotWater.Run())
+	m.mu.Unlock()
+
+	m.logger.Info("Someone woke up, running the recirculation pump")
+	m.evaluate(key)
+}
+
+// handleFlowChange records a draw when hot water starts flowing
+func (m *Manager) handleFlowChange(entityID string, oldState, newState *ha.State) {
+	flow, ok := sensorValue(newState)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	started := !m.flowing && flow >= m.config.HotWater.FlowThreshold
+	m.flowing = flow >= m.config.HotWater.FlowThreshold
+	m.mu.Unlock()
+
+	if started {
+		m.recordDraw("flow")
+	}
+}
+
+// handleTemperatureChange records a draw when the line heats up while the
+// pump is off (only without a flow sensor), and stops the pump once the line
+// is hot
+func (m *Manager) handleTemperatureChange(entityID string, oldState, newState *ha.State) {
+	temperature, ok := sensorValue(newState)
+	if !ok {
+		return
+	}
+	s := &m.config.HotWater
+
+	m.mu.Lock()
+	previous, hadPrevious := m.lineTemp, m.hasLineTemp
+	m.lineTemp, m.hasLineTemp = temperature, true
+	pumpOn := m.pumpOn
+	m.mu.Unlock()
+
+	if s.FlowSensor == "" && !pumpOn && hadPrevious && previous < s.DrawTemperature && temperature >= s.DrawTemperature {
+		m.recordDraw("temperature")
+	}
+	if pumpOn && s.ReadyTemperature > 0 && temperature >= s.ReadyTemperature {
+		m.evaluate(entityID)
+	}
+}
+
+// recordDraw adds a hot water draw to the usage history
+func (m *Manager) recordDraw(source string) {
+	now := m.clock.Now().In(m.timezone)
+
+	m.mu.Lock()
+	m.history.record(now, &m.config.HotWater)
+	if day := now.Format(dateLayout); day != m.drawDay {
+		m.drawDay = day
+		m.drawsToday = 0
+	}
+	m.drawsToday++
+	draws := m.drawsToday
+	m.mu.Unlock()
+
+	m.logger.Debug("Hot water draw",
+		zap.String("source", source),
+		zap.Int("draws_today", draws))
+	m.shadowTracker.RecordDraw(now, draws)
+}
+
+// evaluate relearns the schedule, decides whether the pump should run and
+// switches it if needed
+func (m *Manager) evaluate(trigger string) {
+	m.health.Tick()
+	now := m.clock.Now().In(m.timezone)
+	s := &m.config.HotWater
+
+	anyoneHome, err := m.stateManager.GetBool("isAnyoneHome")
+	if err != nil {
+		m.logger.Warn("Failed to get isAnyoneHome", zap.Error(err))
+	}
+	everyoneAsleep, err := m.stateManager.GetBool("isEveryoneAsleep")
+	if err != nil {
+		m.logger.Warn("Failed to get isEveryoneAsleep", zap.Error(err))
+	}
+	var alarm time.Time
+	if alarmMillis, err := m.stateManager.GetNumber("alarmTime"); err == nil && alarmMillis > 0 {
+		alarm = time.UnixMilli(int64(alarmMillis)).In(m.timezone)
+	}
+
+	m.mu.Lock()
+	m.schedule = m.history.learn(now, s)
+	schedule := m.schedule
+	wakeRunUntil := m.wakeRunUntil
+	lineHot := s.ReadyTemperature > 0 && m.hasLineTemp && m.lineTemp >= s.ReadyTemperature
+	flowing, lineTemp, hasLineTemp := m.flowing, m.lineTemp, m.hasLineTemp
+	m.mu.Unlock()
+
+	inputs := map[string]interface{}{
+		"isAnyoneHome":     anyoneHome,
+		"isEveryoneAsleep": everyoneAsleep,
+		"flowing":          flowing,
+		"trigger":          trigger,
+	}
+	if !alarm.IsZero() {
+		inputs["alarmTime"] = alarm.Format(time.RFC3339)
+	}
+	if hasLineTemp {
+		inputs["lineTemperature"] = lineTemp
+	}
+	m.shadowTracker.UpdateCurrentInputs(inputs)
+
+	windows := m.runWindows(now, schedule, alarm)
+	on, reason, runUntil := decide(now, anyoneHome, everyoneAsleep, wakeRunUntil, windows)
+
+	m.mu.Lock()
+	if on && lineHot {
+		m.readyUntil = runUntil
+	}
+	if now.Before(m.readyUntil) {
+		on, reason, runUntil = false, "Hot water is already at the taps", time.Time{}
+	}
+	m.mu.Unlock()
+
+	nextRun := time.Time{}
+	for _, w := range windows {
+		if w.start.After(now) {
+			nextRun = w.start
+			break
+		}
+	}
+
+	m.shadowTracker.UpdateEvaluation(on, reason, runUntil, nextRun, schedule)
+	m.applyPumpState(on, reason)
+}
+
+// runWindows returns the pump runs around now, earliest first: lead_minutes
+// before each learned slot on days with enough history, and before the alarm
+func (m *Manager) runWindows(now time.Time, schedule shadowstate.HotWaterSchedule, alarm time.Time) []runWindow {
+	s := &m.config.HotWater
+	var windows []runWindow
+
+	today := midnight(now)
+	for offset := -1; offset <= 1; offset++ {
+		day := today.AddDate(0, 0, offset)
+		profile := profileFor(schedule, day)
+		if !profile.Active {
+			continue
+		}
+		for _, slot := range profile.Slots {
+			start := clockTimeOn(day, slot.Start).Add(-s.Lead())
+			windows = append(windows, runWindow{
+				start:  start,
+				end:    start.Add(s.Run()),
+				reason: fmt.Sprintf("Usual hot water use at %s", slot.Start),
+			})
+		}
+	}
+
+	if !alarm.IsZero() {
+		start := alarm.Add(-s.Lead())
+		windows = append(windows, runWindow{
+			start:  start,
+			end:    start.Add(s.Run()),
+			alarm:  true,
+			reason: fmt.Sprintf("Wake-up alarm at %s", alarm.Format("15:04")),
+		})
+	}
+
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].start.Before(windows[j].start) })
+	return windows
+}
+
+// decide returns whether the pump should run, why, and until when
+func decide(now time.Time, anyoneHome, everyoneAsleep bool, wakeRunUntil time.Time, windows []runWindow) (bool, string, time.Time) {
+	if !anyoneHome {
+		return false, "Nobody home", time.Time{}
+	}
+	if now.Before(wakeRunUntil) {
+		return true, "Someone woke up", wakeRunUntil
+	}
+
+	asleep := false
+	for _, w := range windows {
+		if now.Before(w.start) || !now.Before(w.end) {
+			continue
+		}
+		if w.alarm || !everyoneAsleep {
+			return true, w.reason, w.end
+		}
+		asleep = true
+	}
+	if asleep {
+		return false, "Everyone asleep", time.Time{}
+	}
+	return false, "No hot water use expected", time.Time{}
+}
+
+// applyPumpState switches the pump if it differs from the last commanded state
+func (m *Manager) applyPumpState(on bool, reason string) {
+	pump := m.config.HotWater.PumpEntity
+
+	m.mu.Lock()
+	if m.pumpKnown && m.pumpOn == on {
+		m.mu.Unlock()
+		return
+	}
+	m.pumpOn, m.pumpKnown = on, true
+	m.mu.Unlock()
+
+	service := "turn_off"
+	if on {
+		service = "turn_on"
+	}
+	m.shadowTracker.RecordPumpChange(on, reason, m.clock.Now())
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would switch recirculation pump",
+			zap.String("entity_id", pump),
+			zap.String("service", service),
+			zap.String("reason", reason))
+		return
+	}
+
+	if err := m.haClient.CallService(m.ctx, "switch", service, map[string]interface{}{
+		"entity_id": pump,
+	}); err != nil {
+		m.logger.Error("Failed to switch recirculation pump",
+			zap.String("entity_id", pump),
+			zap.String("service", service),
+			zap.Error(err))
+		// Forget the commanded state so the next evaluation retries
+		m.mu.Lock()
+		m.pumpKnown = false
+		m.mu.Unlock()
+		return
+	}
+
+	m.logger.Info("Recirculation pump switched",
+		zap.String("entity_id", pump),
+		zap.String("service", service),
+		zap.String("reason", reason))
+}
+
+// sensorValue parses a numeric sensor state
+func sensorValue(st *ha.State) (float64, bool) {
+	if st == nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(st.State, 64)
+	return value, err == nil
+}
+
+// clockTimeOn combines an "HH:MM" string with the date of ref
+func clockTimeOn(ref time.Time, hhmm string) time.Time {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return ref
+	}
+	return time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), 0, 0, ref.Location())
+}
@@ -0,0 +1,239 @@
+package hotwater
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig() *HotWaterConfig {
+	return &HotWaterConfig{
+		HotWater: HotWaterSettings{
+			PumpEntity:        "switch.recirc",
+			FlowSensor:        "sensor.hw_flow",
+			FlowThreshold:     0.5,
+			TemperatureSensor: "sensor.hw_line",
+			ReadyTemperature:  40,
+			SlotMinutes:       30,
+			HistoryDays:       14,
+			MinDays:           3,
+			MinProbability:    0.5,
+			LeadMinutes:       10,
+			RunMinutes:        10,
+		},
+	}
+}
+
+// monday is 05:00 UTC on Monday 2025-01-06
+var monday = time.Date(2025, 1, 6, 5, 0, 0, 0, time.UTC)
+
+// setupTest starts a manager on Monday morning with someone home and awake
+func setupTest(t *testing.T, config *HotWaterConfig, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("sensor.hw_flow", "0", nil)
+	mockHA.SetState("sensor.hw_line", "20", nil)
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+	mockHA.SetState("input_boolean.everyone_asleep", "off", nil)
+	mockHA.SetState("input_boolean.master_asleep", "off", nil)
+	mockHA.Connect(context.Background())
+
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SyncFromHA())
+
+	mockClock := clock.NewMockClock(monday)
+	manager := NewManager(mockHA, stateManager, config, logger, readOnly, time.UTC)
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+// pumpCalls returns the services called on the pump
+func pumpCalls(mockHA *ha.MockClient) []string {
+	var services []string
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "switch" && call.Data["entity_id"] == "switch.recirc" {
+			services = append(services, call.Service)
+		}
+	}
+	return services
+}
+
+// drawAt simulates a hot water draw at the given time on day offset from monday
+func drawAt(mockHA *ha.MockClient, mockClock *clock.MockClock, days, hour, minute int) {
+	mockClock.Set(time.Date(2025, 1, 6+days, hour, minute, 0, 0, time.UTC))
+	mockHA.SimulateStateChange("sensor.hw_flow", "2.5")
+	mockHA.SimulateStateChange("sensor.hw_flow", "0")
+}
+
+func TestLearnsScheduleFromDraws(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, createTestConfig(), false)
+
+	// Showers around 07:05 Monday to Wednesday, and one 21:00 bath
+	for day := 0; day < 3; day++ {
+		drawAt(mockHA, mockClock, day, 7, 5)
+	}
+	drawAt(mockHA, mockClock, 1, 21, 0)
+
+	// Thursday 06:50 is lead_minutes before the 07:00 slot
+	mockClock.Set(time.Date(2025, 1, 9, 6, 50, 0, 0, time.UTC))
+	manager.evaluate("timer")
+
+	schedule := manager.GetLearnedSchedule()
+	assert.Equal(t, 30, schedule.SlotMinutes)
+	assert.True(t, schedule.Weekday.Active)
+	assert.Equal(t, 3, schedule.Weekday.DaysObserved)
+	require.Len(t, schedule.Weekday.Slots, 1, "a single bath is below min_probability")
+	assert.Equal(t, "07:00", schedule.Weekday.Slots[0].Start)
+	assert.Equal(t, 3, schedule.Weekday.Slots[0].DrawDays)
+	assert.Equal(t, 1.0, schedule.Weekday.Slots[0].Probability)
+	assert.False(t, schedule.Weekend.Active)
+
+	assert.Equal(t, []string{"turn_on"}, pumpCalls(mockHA))
+	shadow := manager.GetShadowState().Outputs
+	assert.True(t, shadow.PumpOn)
+	assert.Equal(t, "Usual hot water use at 07:00", shadow.Reason)
+	assert.Equal(t, time.Date(2025, 1, 9, 7, 0, 0, 0, time.UTC), shadow.RunUntil)
+
+	// The run ends after run_minutes
+	mockHA.ClearServiceCalls()
+	mockClock.Set(time.Date(2025, 1, 9, 7, 0, 0, 0, time.UTC))
+	manager.evaluate("timer")
+	assert.Equal(t, []string{"turn_off"}, pumpCalls(mockHA))
+	assert.Equal(t, time.Date(2025, 1, 10, 6, 50, 0, 0, time.UTC), manager.GetShadowState().Outputs.NextRun)
+
+	// The weekday schedule isn't used on the weekend
+	mockHA.ClearServiceCalls()
+	mockClock.Set(time.Date(2025, 1, 11, 6, 50, 0, 0, time.UTC))
+	manager.evaluate("timer")
+	assert.Empty(t, pumpCalls(mockHA))
+}
+
+func TestScheduleNeedsMinimumDays(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, createTestConfig(), false)
+
+	drawAt(mockHA, mockClock, 0, 7, 5)
+	drawAt(mockHA, mockClock, 1, 7, 5)
+
+	// Only Monday and Tuesday are complete days
+	mockClock.Set(time.Date(2025, 1, 8, 6, 50, 0, 0, time.UTC))
+	manager.evaluate("timer")
+
+	schedule := manager.GetLearnedSchedule()
+	assert.False(t, schedule.Weekday.Active)
+	assert.Len(t, schedule.Weekday.Slots, 1)
+	assert.Empty(t, pumpCalls(mockHA))
+}
+
+func TestNobodyHomeKeepsPumpOff(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+
+	for day := 0; day < 3; day++ {
+		drawAt(mockHA, mockClock, day, 7, 5)
+	}
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+	mockHA.ClearServiceCalls()
+
+	mockClock.Set(time.Date(2025, 1, 9, 6, 50, 0, 0, time.UTC))
+	manager.evaluate("timer")
+
+	assert.Empty(t, pumpCalls(mockHA))
+	assert.Equal(t, "Nobody home", manager.GetShadowState().Outputs.Reason)
+}
+
+func TestPreheatsForAlarm(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+	alarm := time.Date(2025, 1, 6, 6, 30, 0, 0, time.UTC)
+	require.NoError(t, stateManager.SetNumber("alarmTime", float64(alarm.UnixMilli())))
+	mockHA.ClearServiceCalls()
+
+	mockClock.Set(time.Date(2025, 1, 6, 6, 20, 0, 0, time.UTC))
+	manager.evaluate("timer")
+
+	assert.Equal(t, []string{"turn_on"}, pumpCalls(mockHA))
+	assert.Equal(t, "Wake-up alarm at 06:30", manager.GetShadowState().Outputs.Reason)
+}
+
+func TestWakingUpRunsPumpUntilLineIsHot(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, createTestConfig(), false)
+
+	mockHA.SetState("input_boolean.master_asleep", "on", nil)
+	mockHA.ClearServiceCalls()
+
+	mockHA.SetState("input_boolean.master_asleep", "off", nil)
+	assert.Equal(t, []string{"turn_on"}, pumpCalls(mockHA))
+	assert.Equal(t, "Someone woke up", manager.GetShadowState().Outputs.Reason)
+
+	// The line reaching ready_temperature ends the run early
+	mockHA.ClearServiceCalls()
+	mockClock.Advance(3 * time.Minute)
+	mockHA.SimulateStateChange("sensor.hw_line", "42")
+	assert.Equal(t, []string{"turn_off"}, pumpCalls(mockHA))
+	assert.Equal(t, "Hot water is already at the taps", manager.GetShadowState().Outputs.Reason)
+
+	// It stays off for the rest of the run even as the line cools
+	mockHA.ClearServiceCalls()
+	mockHA.SimulateStateChange("sensor.hw_line", "35")
+	mockClock.Advance(time.Minute)
+	manager.evaluate("timer")
+	assert.Empty(t, pumpCalls(mockHA))
+
+	// Heating from the pump isn't counted as a draw
+	assert.Zero(t, manager.GetShadowState().Outputs.DrawsToday)
+}
+
+func TestTemperatureDrawsWithoutFlowSensor(t *testing.T) {
+	config := createTestConfig()
+	config.HotWater.FlowSensor = ""
+	config.HotWater.DrawTemperature = 30
+	manager, mockHA, _, mockClock := setupTest(t, config, false)
+
+	mockClock.Set(time.Date(2025, 1, 6, 7, 5, 0, 0, time.UTC))
+	mockHA.SimulateStateChange("sensor.hw_line", "36")
+	mockHA.SimulateStateChange("sensor.hw_line", "38")
+
+	shadow := manager.GetShadowState().Outputs
+	assert.Equal(t, 1, shadow.DrawsToday, "only rising past draw_temperature is a draw")
+	assert.Equal(t, mockClock.Now(), shadow.LastDraw)
+}
+
+func TestReadOnlyDoesNotSwitchPump(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, createTestConfig(), true)
+
+	mockHA.SetState("input_boolean.master_asleep", "on", nil)
+	mockHA.SetState("input_boolean.master_asleep", "off", nil)
+
+	assert.Empty(t, pumpCalls(mockHA))
+	assert.True(t, manager.GetShadowState().Outputs.PumpOn)
+}
+
+func TestHistoryForgetsOldDays(t *testing.T) {
+	s := &createTestConfig().HotWater
+	history := newUsageHistory(monday)
+
+	for day := 0; day < 20; day++ {
+		history.record(time.Date(2025, 1, 6+day, 7, 5, 0, 0, time.UTC), s)
+	}
+
+	assert.Len(t, history.days, s.History()+1, fmt.Sprintf("%d days of history plus today", s.History()))
+	schedule := history.learn(time.Date(2025, 1, 26, 12, 0, 0, 0, time.UTC), s)
+	assert.Equal(t, 10, schedule.Weekday.DaysObserved)
+	assert.Equal(t, 4, schedule.Weekend.DaysObserved)
+}
@@ -0,0 +1,81 @@
+package lighting
+
+import (
+	"math"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// adaptiveScale is a room's adaptive brightness as worked out for a scene
+type adaptiveScale struct {
+	lux        float64
+	scalePct   int
+	brightness int // The scene's brightness (0-255) scaled by scalePct
+}
+
+// adaptiveBrightness works out the brightness to set after a room's scene
+// from its lux sensor. It returns false when the room has no adaptive
+// brightness, the sensor or scene brightness can't be read, or the scene is
+// used as is.
+func (m *Manager) adaptiveBrightness(room *RoomConfig, sceneValues map[string]interface{}) (adaptiveScale, bool) {
+	if room.AdaptiveBrightness == nil {
+		return adaptiveScale{}, false
+	}
+	lux := m.readRoomLux(room)
+	if lux == nil {
+		return adaptiveScale{}, false
+	}
+	sceneBrightness, ok := brightnessValue(sceneValues["brightness"])
+	if !ok {
+		m.logger.Debug("Scene brightness unknown, skipping adaptive brightness",
+			zap.String("room", room.HueGroup))
+		return adaptiveScale{}, false
+	}
+
+	scalePct := room.AdaptiveBrightness.ScalePct(*lux)
+	if scalePct == 100 {
+		return adaptiveScale{}, false
+	}
+	brightness := int(math.Round(sceneBrightness * float64(scalePct) / 100))
+	return adaptiveScale{
+		lux:        *lux,
+		scalePct:   scalePct,
+		brightness: max(1, min(255, brightness)),
+	}, true
+}
+
+// applyAdaptiveBrightness sets the room's lights to the scaled brightness
+// after its scene, with the same transition as the scene
+func (m *Manager) applyAdaptiveBrightness(room *RoomConfig, scale adaptiveScale, transitionSeconds *int) error {
+	serviceData := map[string]interface{}{
+		"area_id":    room.HASSAreaID,
+		"brightness": scale.brightness,
+	}
+	if transitionSeconds != nil {
+		serviceData["transition"] = *transitionSeconds
+	}
+	return m.haClient.CallService(m.ctx, "light", "turn_on", serviceData)
+}
+
+// readRoomLux returns the room's ambient light level, or nil if it can't be read
+func (m *Manager) readRoomLux(room *RoomConfig) *float64 {
+	entityID := room.AdaptiveBrightness.LuxEntity
+	sensor, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil || sensor == nil {
+		m.logger.Warn("Failed to read lux sensor",
+			zap.String("room", room.HueGroup),
+			zap.String("entity_id", entityID),
+			zap.Error(err))
+		return nil
+	}
+	lux, err := strconv.ParseFloat(sensor.State, 64)
+	if err != nil {
+		m.logger.Debug("Lux sensor has no reading",
+			zap.String("room", room.HueGroup),
+			zap.String("entity_id", entityID),
+			zap.String("state", sensor.State))
+		return nil
+	}
+	return &lux
+}
@@ -0,0 +1,142 @@
+package lighting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func adaptiveTestConfig() *HueConfig {
+	return &HueConfig{
+		Rooms: []RoomConfig{
+			{
+				HueGroup:           "Living Room",
+				HASSAreaID:         "living_room",
+				OnIfTrue:           "isAnyoneHomeAndAwake",
+				OffIfFalse:         "isAnyoneHomeAndAwake",
+				AdaptiveBrightness: &AdaptiveBrightness{LuxEntity: "sensor.living_room_illuminance"},
+			},
+		},
+	}
+}
+
+func seedLux(mockClient *ha.MockClient, lux string) {
+	mockClient.SetMockState("sensor.living_room_illuminance", &ha.State{
+		EntityID: "sensor.living_room_illuminance",
+		State:    lux,
+	})
+}
+
+func TestAdaptiveBrightness_ScalePct(t *testing.T) {
+	adaptive := &AdaptiveBrightness{LuxEntity: "sensor.lux"}
+
+	assert.Equal(t, 60, adaptive.ScalePct(0))
+	assert.Equal(t, 60, adaptive.ScalePct(10))
+	assert.Equal(t, 90, adaptive.ScalePct(205))
+	assert.Equal(t, 120, adaptive.ScalePct(400))
+	assert.Equal(t, 120, adaptive.ScalePct(20000))
+
+	custom := &AdaptiveBrightness{LuxEntity: "sensor.lux", DarkLux: 100, BrightLux: 200, MinPct: 50, MaxPct: 100}
+	assert.Equal(t, 75, custom.ScalePct(150))
+}
+
+func TestLoadConfig_AdaptiveBrightness(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: "rooms:\n  - hue_group: Kitchen\n    adaptive_brightness:\n      lux_entity: sensor.kitchen_lux\n      dark_lux: 5\n",
+		},
+		{
+			name:    "missing sensor",
+			yaml:    "rooms:\n  - hue_group: Kitchen\n    adaptive_brightness:\n      min_pct: 50\n",
+			wantErr: `room "Kitchen" adaptive_brightness: lux_entity is required`,
+		},
+		{
+			name:    "empty lux range",
+			yaml:    "rooms:\n  - hue_group: Kitchen\n    adaptive_brightness:\n      lux_entity: sensor.kitchen_lux\n      dark_lux: 500\n",
+			wantErr: `room "Kitchen" adaptive_brightness: dark_lux must be below bright_lux`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hue_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAdaptiveBrightness_DimsSceneInTheDark(t *testing.T) {
+	manager, mockClient, stateManager, _ := newFadeTestManager(t, adaptiveTestConfig())
+	seedBrightness(mockClient, 0, 200)
+	seedLux(mockClient, "4.5")
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "scene", calls[0].Domain)
+	assert.Equal(t, "light", calls[1].Domain)
+	assert.Equal(t, "living_room", calls[1].Data["area_id"])
+	assert.Equal(t, 120, calls[1].Data["brightness"])
+
+	room := manager.GetShadowState().Outputs.Rooms["Living Room"]
+	require.NotNil(t, room.AmbientLux)
+	assert.Equal(t, 4.5, *room.AmbientLux)
+	assert.Equal(t, 60, room.BrightnessScalePct)
+}
+
+func TestAdaptiveBrightness_CapsAtFullBrightness(t *testing.T) {
+	_, mockClient, stateManager, _ := newFadeTestManager(t, adaptiveTestConfig())
+	seedBrightness(mockClient, 0, 240)
+	seedLux(mockClient, "1200")
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Equal(t, 255, calls[1].Data["brightness"])
+}
+
+func TestAdaptiveBrightness_SkippedWithoutReading(t *testing.T) {
+	manager, mockClient, stateManager, _ := newFadeTestManager(t, adaptiveTestConfig())
+	seedBrightness(mockClient, 0, 200)
+	seedLux(mockClient, "unavailable")
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1, "the scene is used as is")
+	assert.Equal(t, "scene", calls[0].Domain)
+	assert.Nil(t, manager.GetShadowState().Outputs.Rooms["Living Room"].AmbientLux)
+}
+
+func TestAdaptiveBrightness_FadeEndsAtScaledBrightness(t *testing.T) {
+	config := adaptiveTestConfig()
+	config.DayPhaseTransitions = map[string]PhaseTransition{"winddown": {Minutes: 4, Steps: 2}}
+	_, mockClient, stateManager, _ := newFadeTestManager(t, config)
+	seedBrightness(mockClient, 200, 200)
+	seedLux(mockClient, "0")
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, 160, calls[0].Data["brightness"], "halfway to 60% of the scene")
+}
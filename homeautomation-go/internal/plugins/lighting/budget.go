@@ -0,0 +1,312 @@
+package lighting
+
+import (
+	"fmt"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// onBudgetCheckInterval is how often rooms with an on-time budget are checked
+const onBudgetCheckInterval = time.Minute
+
+// onBudgetNotificationTitle is the title of the notification sent when a budget runs out
+const onBudgetNotificationTitle = "Lights left on"
+
+// roomBudget tracks how long a room's lights have been on today
+type roomBudget struct {
+	room        *RoomConfig
+	entityID    string
+	limit       time.Duration
+	day         string        // Local date consumed applies to (YYYY-MM-DD)
+	consumed    time.Duration // On-time before onSince
+	onSince     time.Time     // Zero while the lights are off
+	exhausted   bool          // Budget ran out today and the room was turned off
+	exhaustedAt time.Time
+}
+
+// used returns the on-time consumed today as of now
+func (b *roomBudget) used(now time.Time) time.Duration {
+	if b.onSince.IsZero() {
+		return b.consumed
+	}
+	return b.consumed + now.Sub(b.onSince)
+}
+
+// startOnBudgets starts tracking rooms that have a daily on-time budget
+func (m *Manager) startOnBudgets() error {
+	config := m.currentConfig()
+	now := m.clock.Now()
+
+	for i := range config.Rooms {
+		room := &config.Rooms[i]
+		if !hasOnBudget(room) {
+			if room.OnBudget != nil {
+				m.logger.Warn("Ignoring on-time budget without daily_minutes",
+					zap.String("room", room.HueGroup))
+			}
+			continue
+		}
+		if err := m.trackOnBudget(room, now); err != nil {
+			return err
+		}
+	}
+
+	m.startBudgetLoop()
+	return nil
+}
+
+// hasOnBudget reports whether a room has a usable daily on-time budget
+func hasOnBudget(room *RoomConfig) bool {
+	return room.OnBudget != nil && room.OnBudget.DailyMinutes > 0
+}
+
+// trackOnBudget starts tracking one room's on-time budget, subscribing to its
+// light unless an earlier config already did
+func (m *Manager) trackOnBudget(room *RoomConfig, now time.Time) error {
+	budget := &roomBudget{
+		room:     room,
+		entityID: room.OnBudgetLightEntity(),
+		limit:    time.Duration(room.OnBudget.DailyMinutes) * time.Minute,
+		day:      m.localDay(now),
+	}
+
+	// Lights already on count from now
+	if st, err := m.haClient.GetState(m.ctx, budget.entityID); err == nil && st.State == "on" {
+		budget.onSince = now
+	}
+
+	m.budgetMu.Lock()
+	subscribed := m.budgetSubscribed[budget.entityID]
+	m.budgetMu.Unlock()
+	if !subscribed {
+		sub, err := m.haClient.SubscribeStateChanges(budget.entityID, m.handleBudgetLightChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", budget.entityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, sub)
+		if m.registry != nil {
+			m.registry.RegisterHASubscription(m.pluginName, budget.entityID)
+		}
+	}
+
+	m.budgetMu.Lock()
+	m.budgetSubscribed[budget.entityID] = true
+	m.budgets[budget.entityID] = budget
+	m.budgetMu.Unlock()
+	m.updateBudgetShadow(budget, now)
+
+	m.logger.Info("Tracking daily on-time budget",
+		zap.String("room", room.HueGroup),
+		zap.String("entity_id", budget.entityID),
+		zap.Int("daily_minutes", room.OnBudget.DailyMinutes))
+	return nil
+}
+
+// startBudgetLoop starts the budget check loop once there are budgets to check
+func (m *Manager) startBudgetLoop() {
+	m.budgetMu.Lock()
+	defer m.budgetMu.Unlock()
+
+	if m.budgetLoopStarted || len(m.budgets) == 0 {
+		return
+	}
+	m.budgetLoopStarted = true
+	go m.runBudgetLoop()
+}
+
+// reloadOnBudgets brings the tracked budgets in line with a reloaded config.
+// Budgets that remain keep today's usage with the new limit; removed ones
+// stop being enforced and new ones start counting from now.
+func (m *Manager) reloadOnBudgets(config *HueConfig) error {
+	now := m.clock.Now()
+	wanted := make(map[string]*RoomConfig)
+	for i := range config.Rooms {
+		room := &config.Rooms[i]
+		if hasOnBudget(room) {
+			wanted[room.OnBudgetLightEntity()] = room
+		}
+	}
+
+	m.budgetMu.Lock()
+	var kept, removed []*roomBudget
+	for entityID, budget := range m.budgets {
+		room, ok := wanted[entityID]
+		if !ok {
+			delete(m.budgets, entityID)
+			removed = append(removed, budget)
+			continue
+		}
+		budget.room = room
+		budget.limit = time.Duration(room.OnBudget.DailyMinutes) * time.Minute
+		kept = append(kept, budget)
+		delete(wanted, entityID)
+	}
+	m.budgetMu.Unlock()
+
+	for _, budget := range removed {
+		m.shadowTracker.RemoveOnBudget(budget.room.HueGroup)
+		m.logger.Info("Stopped tracking daily on-time budget", zap.String("room", budget.room.HueGroup))
+	}
+	for _, budget := range kept {
+		m.updateBudgetShadow(budget, now)
+	}
+	for _, room := range wanted {
+		if err := m.trackOnBudget(room, now); err != nil {
+			return err
+		}
+	}
+
+	m.startBudgetLoop()
+	return nil
+}
+
+// runBudgetLoop checks on-time budgets every onBudgetCheckInterval until Stop
+func (m *Manager) runBudgetLoop() {
+	ticker := time.NewTicker(onBudgetCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.checkBudgets()
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// handleBudgetLightChange accumulates on-time as a budgeted room's lights turn on and off
+func (m *Manager) handleBudgetLightChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+	now := m.clock.Now()
+
+	m.budgetMu.Lock()
+	budget, ok := m.budgets[entityID]
+	if !ok {
+		m.budgetMu.Unlock()
+		return
+	}
+	m.rolloverBudget(budget, now)
+
+	on := newState.State == "on"
+	switch {
+	case on && budget.onSince.IsZero():
+		budget.onSince = now
+	case !on && !budget.onSince.IsZero():
+		budget.consumed += now.Sub(budget.onSince)
+		budget.onSince = time.Time{}
+	}
+	m.budgetMu.Unlock()
+
+	m.updateBudgetShadow(budget, now)
+}
+
+// checkBudgets turns off rooms whose on-time budget has run out today
+func (m *Manager) checkBudgets() {
+	now := m.clock.Now()
+
+	m.budgetMu.Lock()
+	var exhausted []*roomBudget
+	for _, budget := range m.budgets {
+		m.rolloverBudget(budget, now)
+		if !budget.exhausted && !budget.onSince.IsZero() && budget.used(now) >= budget.limit {
+			budget.exhausted = true
+			budget.exhaustedAt = now
+			exhausted = append(exhausted, budget)
+		}
+	}
+	budgets := make([]*roomBudget, 0, len(m.budgets))
+	for _, budget := range m.budgets {
+		budgets = append(budgets, budget)
+	}
+	m.budgetMu.Unlock()
+
+	for _, budget := range budgets {
+		m.updateBudgetShadow(budget, now)
+	}
+
+	for _, budget := range exhausted {
+		m.logger.Info("Daily on-time budget used up, turning off room",
+			zap.String("room", budget.room.HueGroup),
+			zap.Duration("budget", budget.limit))
+		m.turnOffRoom(budget.room, "on_budget")
+		m.notifyBudgetExhausted(budget)
+	}
+}
+
+// rolloverBudget starts a new day's budget. On-time before midnight belongs to
+// the previous day. Callers must hold budgetMu.
+func (m *Manager) rolloverBudget(budget *roomBudget, now time.Time) {
+	today := m.localDay(now)
+	if budget.day == today {
+		return
+	}
+
+	budget.day = today
+	budget.consumed = 0
+	budget.exhausted = false
+	budget.exhaustedAt = time.Time{}
+	if !budget.onSince.IsZero() {
+		local := now.In(m.timezone)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, m.timezone)
+		if budget.onSince.Before(midnight) {
+			budget.onSince = midnight
+		}
+	}
+}
+
+// notifyBudgetExhausted tells the household a room was turned off for exceeding its budget
+func (m *Manager) notifyBudgetExhausted(budget *roomBudget) {
+	config := m.currentConfig()
+	if config.OnBudgetNotifyService == "" {
+		return
+	}
+
+	message := fmt.Sprintf("%s lights were on for more than %d minutes today and have been turned off.",
+		budget.room.HueGroup, budget.room.OnBudget.DailyMinutes)
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send on-time budget notification",
+			zap.String("service", string(config.OnBudgetNotifyService)),
+			zap.String("message", message))
+		return
+	}
+
+	if err := notifyrouter.SendPush(m.ctx, m.haClient, config.OnBudgetNotifyService, notifyrouter.Push{
+		Title:   onBudgetNotificationTitle,
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send on-time budget notification",
+			zap.String("room", budget.room.HueGroup),
+			zap.Error(err))
+	}
+}
+
+// updateBudgetShadow publishes a room's budget usage to the shadow state
+func (m *Manager) updateBudgetShadow(budget *roomBudget, now time.Time) {
+	m.budgetMu.Lock()
+	state := shadowstate.OnBudgetState{
+		LightEntity:     budget.entityID,
+		Day:             budget.day,
+		BudgetMinutes:   budget.limit.Minutes(),
+		ConsumedMinutes: budget.used(now).Minutes(),
+		LightsOn:        !budget.onSince.IsZero(),
+		Exhausted:       budget.exhausted,
+		ExhaustedAt:     budget.exhaustedAt,
+	}
+	m.budgetMu.Unlock()
+
+	m.shadowTracker.UpdateOnBudget(budget.room.HueGroup, state)
+}
+
+// localDay returns the local date of t as YYYY-MM-DD
+func (m *Manager) localDay(t time.Time) string {
+	return t.In(m.timezone).Format("2006-01-02")
+}
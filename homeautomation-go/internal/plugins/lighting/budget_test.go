@@ -0,0 +1,178 @@
+package lighting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newBudgetTestManager starts a manager with a 30 minute budget on the closet
+func newBudgetTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	config := &HueConfig{
+		OnBudgetNotifyService: "notify.mobile_app_phone",
+		Rooms: []RoomConfig{
+			{
+				HueGroup:   "Closet",
+				HASSAreaID: "closet",
+				OnBudget:   &OnBudget{DailyMinutes: 30},
+			},
+		},
+	}
+
+	mockClock := clock.NewMockClock(time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC))
+	manager := NewManager(mockClient, stateManager, config, logger, readOnly, nil)
+	manager.SetClock(mockClock)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	return manager, mockClient, mockClock
+}
+
+func budgetCalls(calls []ha.ServiceCall) (turnOffs, notifications int) {
+	for _, call := range calls {
+		switch {
+		case call.Domain == "light" && call.Service == "turn_off":
+			turnOffs++
+		case call.Domain == "notify":
+			notifications++
+		}
+	}
+	return turnOffs, notifications
+}
+
+func TestOnBudgetLightEntity(t *testing.T) {
+	room := RoomConfig{HueGroup: "Utility Room"}
+	room.OnBudget = &OnBudget{DailyMinutes: 10}
+	assert.Equal(t, "light.utility_room", room.OnBudgetLightEntity())
+
+	room.OnBudget.LightEntity = "light.laundry_ceiling"
+	assert.Equal(t, "light.laundry_ceiling", room.OnBudgetLightEntity())
+}
+
+func TestOnBudget_AccumulatesOnTime(t *testing.T) {
+	manager, mockClient, mockClock := newBudgetTestManager(t, false)
+
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Advance(10 * time.Minute)
+	mockClient.SimulateStateChange("light.closet", "off")
+	mockClock.Advance(time.Hour)
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Advance(5 * time.Minute)
+	manager.checkBudgets()
+
+	budget := manager.GetShadowState().Outputs.OnBudgets["Closet"]
+	assert.Equal(t, "light.closet", budget.LightEntity)
+	assert.Equal(t, "2024-03-10", budget.Day)
+	assert.Equal(t, 30.0, budget.BudgetMinutes)
+	assert.Equal(t, 15.0, budget.ConsumedMinutes)
+	assert.True(t, budget.LightsOn)
+	assert.False(t, budget.Exhausted)
+
+	turnOffs, notifications := budgetCalls(mockClient.GetServiceCalls())
+	assert.Zero(t, turnOffs)
+	assert.Zero(t, notifications)
+}
+
+func TestOnBudget_ExhaustedTurnsOffAndNotifies(t *testing.T) {
+	manager, mockClient, mockClock := newBudgetTestManager(t, false)
+
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Advance(30 * time.Minute)
+	manager.checkBudgets()
+
+	turnOffs, notifications := budgetCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, 1, turnOffs)
+	assert.Equal(t, 1, notifications)
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "notify" {
+			assert.Equal(t, "mobile_app_phone", call.Service)
+			assert.Contains(t, call.Data["message"], "Closet")
+		}
+	}
+
+	budget := manager.GetShadowState().Outputs.OnBudgets["Closet"]
+	assert.True(t, budget.Exhausted)
+	assert.Equal(t, mockClock.Now(), budget.ExhaustedAt)
+
+	// Lights turned back on later the same day are left alone but still counted
+	mockClient.SimulateStateChange("light.closet", "off")
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Advance(10 * time.Minute)
+	manager.checkBudgets()
+
+	turnOffs, notifications = budgetCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, 1, turnOffs)
+	assert.Equal(t, 1, notifications)
+	assert.Equal(t, 40.0, manager.GetShadowState().Outputs.OnBudgets["Closet"].ConsumedMinutes)
+}
+
+func TestOnBudget_ResetsAtMidnight(t *testing.T) {
+	manager, mockClient, mockClock := newBudgetTestManager(t, false)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	manager.SetTimezone(newYork)
+
+	// 20:00 UTC is 16:00 in New York; midnight local is 04:00 UTC
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Set(time.Date(2024, 3, 11, 3, 50, 0, 0, time.UTC))
+	manager.checkBudgets()
+	assert.True(t, manager.GetShadowState().Outputs.OnBudgets["Closet"].Exhausted)
+
+	mockClock.Set(time.Date(2024, 3, 11, 4, 20, 0, 0, time.UTC))
+	manager.checkBudgets()
+
+	budget := manager.GetShadowState().Outputs.OnBudgets["Closet"]
+	assert.Equal(t, "2024-03-11", budget.Day)
+	assert.Equal(t, 20.0, budget.ConsumedMinutes, "only time since local midnight counts toward the new day")
+	assert.False(t, budget.Exhausted)
+}
+
+func TestOnBudget_ReadOnly(t *testing.T) {
+	manager, mockClient, mockClock := newBudgetTestManager(t, true)
+
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Advance(45 * time.Minute)
+	manager.checkBudgets()
+
+	assert.Empty(t, mockClient.GetServiceCalls())
+	assert.True(t, manager.GetShadowState().Outputs.OnBudgets["Closet"].Exhausted)
+}
+
+func TestOnBudget_LightsOnAtStartup(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("light.closet", "on", nil)
+	stateManager := state.NewManager(mockClient, logger, false)
+	config := &HueConfig{
+		Rooms: []RoomConfig{
+			{HueGroup: "Closet", HASSAreaID: "closet", OnBudget: &OnBudget{DailyMinutes: 30}},
+		},
+	}
+
+	mockClock := clock.NewMockClock(time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC))
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	manager.SetClock(mockClock)
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	mockClock.Advance(30 * time.Minute)
+	manager.checkBudgets()
+
+	turnOffs, notifications := budgetCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, 1, turnOffs)
+	assert.Zero(t, notifications, "no notify service configured")
+}
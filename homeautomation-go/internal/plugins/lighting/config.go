@@ -1,7 +1,14 @@
 package lighting
 
 import (
+	"fmt"
+	"maps"
+	"math"
 	"os"
+	"slices"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,6 +23,209 @@ type RoomConfig struct {
 	OffIfFalse               interface{} `yaml:"off_if_false"`                // Can be string or []string
 	IncreaseBrightnessIfTrue interface{} `yaml:"increase_brightness_if_true"` // Can be string or []string
 	TransitionSeconds        *int        `yaml:"transition_seconds"`          // Pointer to handle nil/~ values
+	OnBudget                 *OnBudget   `yaml:"on_budget"`                   // Optional daily on-time limit
+	Decorative               bool        `yaml:"decorative"`                  // Kept off during grid outage dimming
+
+	// Fades into a day phase's scene, keyed by day phase, overriding the
+	// defaults in HueConfig.DayPhaseTransitions
+	DayPhaseTransitions map[string]PhaseTransition `yaml:"day_phase_transitions"`
+
+	// Scales scene brightness by the room's ambient light, nil to disable
+	AdaptiveBrightness *AdaptiveBrightness `yaml:"adaptive_brightness"`
+
+	// Raises the room to an occupied scene on motion, nil to disable
+	Motion *MotionConfig `yaml:"motion"`
+
+	// Overrides HueConfig.ManualOverrideMinutes for the room, 0 to disable
+	ManualOverrideMinutes *int `yaml:"manual_override_minutes"`
+}
+
+// Default motion override settings
+const (
+	defaultMotionTimeoutMinutes = 5
+	defaultMotionScene          = "occupied"
+)
+
+// MotionConfig raises a room to its occupied scene while its motion sensors
+// see someone. Once every sensor has been clear for the timeout the room goes
+// back to what it was before, or to whatever the day phase logic decided for
+// it in the meantime.
+type MotionConfig struct {
+	Sensors        []string    `yaml:"sensors"`         // binary_sensor.* reporting "on" while there is motion
+	TimeoutMinutes int         `yaml:"timeout_minutes"` // Default 5
+	Scene          string      `yaml:"scene"`           // Scene name in place of the day phase, default "occupied"
+	IgnoreIfTrue   interface{} `yaml:"ignore_if_true"`  // Can be string or []string
+}
+
+// TimeoutOrDefault returns how long the sensors must be clear before the
+// override ends
+func (c *MotionConfig) TimeoutOrDefault() time.Duration {
+	if c.TimeoutMinutes <= 0 {
+		return defaultMotionTimeoutMinutes * time.Minute
+	}
+	return time.Duration(c.TimeoutMinutes) * time.Minute
+}
+
+// SceneOrDefault returns the scene name activated on motion
+func (c *MotionConfig) SceneOrDefault() string {
+	if c.Scene == "" {
+		return defaultMotionScene
+	}
+	return c.Scene
+}
+
+// GetIgnoreIfTrueConditions returns the variables that, while true, stop
+// motion from raising the room
+func (c *MotionConfig) GetIgnoreIfTrueConditions() []string {
+	return interfaceToStringSlice(c.IgnoreIfTrue)
+}
+
+// validate checks the sensors are set
+func (c *MotionConfig) validate() error {
+	if len(c.Sensors) == 0 {
+		return fmt.Errorf("at least one sensor is required")
+	}
+	for _, sensor := range c.Sensors {
+		if sensor == "" {
+			return fmt.Errorf("sensors must not be empty")
+		}
+	}
+	if c.TimeoutMinutes < 0 {
+		return fmt.Errorf("timeout_minutes must not be negative")
+	}
+	return nil
+}
+
+// Default adaptive brightness settings
+const (
+	defaultAdaptiveDarkLux   = 10
+	defaultAdaptiveBrightLux = 400
+	defaultAdaptiveMinPct    = 60
+	defaultAdaptiveMaxPct    = 120
+)
+
+// AdaptiveBrightness scales a scene's brightness after it is activated by the
+// ambient light a lux sensor reads: MinPct of the scene's brightness at or
+// below DarkLux, MaxPct at or above BrightLux, and linearly in between. Scenes
+// stay gentle on dark evenings and visible on bright days.
+type AdaptiveBrightness struct {
+	LuxEntity string  `yaml:"lux_entity"` // sensor.* reporting illuminance in lux
+	DarkLux   float64 `yaml:"dark_lux"`   // Default 10
+	BrightLux float64 `yaml:"bright_lux"` // Default 400
+	MinPct    int     `yaml:"min_pct"`    // Default 60
+	MaxPct    int     `yaml:"max_pct"`    // Default 120
+}
+
+// DarkLuxOrDefault returns the lux at or below which MinPct applies
+func (a *AdaptiveBrightness) DarkLuxOrDefault() float64 {
+	if a.DarkLux <= 0 {
+		return defaultAdaptiveDarkLux
+	}
+	return a.DarkLux
+}
+
+// BrightLuxOrDefault returns the lux at or above which MaxPct applies
+func (a *AdaptiveBrightness) BrightLuxOrDefault() float64 {
+	if a.BrightLux <= 0 {
+		return defaultAdaptiveBrightLux
+	}
+	return a.BrightLux
+}
+
+// MinPctOrDefault returns the scale applied in the dark
+func (a *AdaptiveBrightness) MinPctOrDefault() int {
+	if a.MinPct <= 0 {
+		return defaultAdaptiveMinPct
+	}
+	return a.MinPct
+}
+
+// MaxPctOrDefault returns the scale applied in bright ambient light
+func (a *AdaptiveBrightness) MaxPctOrDefault() int {
+	if a.MaxPct <= 0 {
+		return defaultAdaptiveMaxPct
+	}
+	return a.MaxPct
+}
+
+// ScalePct returns the percentage of the scene's brightness to use at the
+// given ambient light
+func (a *AdaptiveBrightness) ScalePct(lux float64) int {
+	dark, bright := a.DarkLuxOrDefault(), a.BrightLuxOrDefault()
+	minPct, maxPct := float64(a.MinPctOrDefault()), float64(a.MaxPctOrDefault())
+	switch {
+	case lux <= dark:
+		return int(minPct)
+	case lux >= bright:
+		return int(maxPct)
+	}
+	return int(math.Round(minPct + (maxPct-minPct)*(lux-dark)/(bright-dark)))
+}
+
+// validate checks the sensor is set and the lux range isn't empty
+func (a *AdaptiveBrightness) validate() error {
+	if a.LuxEntity == "" {
+		return fmt.Errorf("lux_entity is required")
+	}
+	if a.DarkLux < 0 || a.BrightLux < 0 || a.MinPct < 0 || a.MaxPct < 0 {
+		return fmt.Errorf("lux and percentages must not be negative")
+	}
+	if a.DarkLuxOrDefault() >= a.BrightLuxOrDefault() {
+		return fmt.Errorf("dark_lux must be below bright_lux")
+	}
+	return nil
+}
+
+// PhaseTransition fades a room into its scene when the day phase changes,
+// instead of using transition_seconds. A fade of more than one step ramps the
+// room's brightness towards the scene's in equal steps, then activates the
+// scene for the last step, so long fades don't rely on the bulbs' own
+// transition limits.
+type PhaseTransition struct {
+	Minutes int `yaml:"minutes"`
+	Steps   int `yaml:"steps"` // Default 1
+}
+
+// StepsOrDefault returns the number of steps, or 1 if unset
+func (t PhaseTransition) StepsOrDefault() int {
+	if t.Steps <= 0 {
+		return 1
+	}
+	return t.Steps
+}
+
+// StepDuration returns how long each step of the fade takes
+func (t PhaseTransition) StepDuration() time.Duration {
+	return time.Duration(t.Minutes) * time.Minute / time.Duration(t.StepsOrDefault())
+}
+
+// validate checks the fade's duration and steps
+func (t PhaseTransition) validate() error {
+	if t.Minutes <= 0 {
+		return fmt.Errorf("minutes must be positive")
+	}
+	if t.Steps < 0 {
+		return fmt.Errorf("steps must not be negative")
+	}
+	if t.StepDuration() < time.Second {
+		return fmt.Errorf("each of the %d steps must last at least a second", t.StepsOrDefault())
+	}
+	return nil
+}
+
+// OnBudget limits how long a room's lights may be on each day. Once the
+// budget is used up the room is turned off and a notification is sent.
+type OnBudget struct {
+	DailyMinutes int    `yaml:"daily_minutes"`
+	LightEntity  string `yaml:"light_entity"` // Defaults to light.<snake_case(hue_group)>
+}
+
+// OnBudgetLightEntity returns the light entity watched for the room's on-time budget
+func (r *RoomConfig) OnBudgetLightEntity() string {
+	if r.OnBudget != nil && r.OnBudget.LightEntity != "" {
+		return r.OnBudget.LightEntity
+	}
+	return "light." + toSnakeCase(r.HueGroup)
 }
 
 // GetOnIfTrueConditions returns the list of on_if_true conditions
@@ -73,6 +283,70 @@ func interfaceToStringSlice(val interface{}) []string {
 // HueConfig represents the Hue lighting configuration
 type HueConfig struct {
 	Rooms []RoomConfig `yaml:"rooms"`
+
+	// Notify service (domain.service) used when a room's on-time budget runs out
+	OnBudgetNotifyService notifyrouter.Target `yaml:"on_budget_notify_service"`
+
+	// Optional night-time dimming while the grid is down, nil to disable
+	GridOutage *GridOutageConfig `yaml:"grid_outage"`
+
+	// Fades into day phases for every room, keyed by day phase. Rooms can
+	// override a phase's fade with their own day_phase_transitions.
+	DayPhaseTransitions map[string]PhaseTransition `yaml:"day_phase_transitions"`
+
+	// How long a room is left alone after someone changes its lights outside
+	// this service (e.g. from the Hue app), 0 to not watch for manual changes.
+	// Rooms can override it with their own manual_override_minutes.
+	ManualOverrideMinutes int `yaml:"manual_override_minutes"`
+}
+
+// ManualOverrideFor returns how long a manual change to the room's lights is
+// respected, 0 if manual changes aren't watched for
+func (c *HueConfig) ManualOverrideFor(room *RoomConfig) time.Duration {
+	minutes := c.ManualOverrideMinutes
+	if room.ManualOverrideMinutes != nil {
+		minutes = *room.ManualOverrideMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// PhaseTransitionFor returns the fade into the day phase for a room, if any
+func (c *HueConfig) PhaseTransitionFor(room *RoomConfig, dayPhase string) (PhaseTransition, bool) {
+	if transition, ok := room.DayPhaseTransitions[dayPhase]; ok {
+		return transition, true
+	}
+	transition, ok := c.DayPhaseTransitions[dayPhase]
+	return transition, ok
+}
+
+// Default grid outage dimming settings
+const (
+	defaultOutageBrightnessCapPct = 30
+)
+
+var defaultOutageDayPhases = []string{"dusk", "winddown", "night"}
+
+// GridOutageConfig caps brightness and turns off decorative rooms when
+// isGridAvailable is false during the listed day phases, to extend the battery
+type GridOutageConfig struct {
+	BrightnessCapPct int      `yaml:"brightness_cap_pct"` // Default 30
+	DayPhases        []string `yaml:"day_phases"`         // Default dusk, winddown, night
+}
+
+// BrightnessCapPctOrDefault returns the brightness cap, or the default if unset
+func (g *GridOutageConfig) BrightnessCapPctOrDefault() int {
+	if g.BrightnessCapPct <= 0 {
+		return defaultOutageBrightnessCapPct
+	}
+	return g.BrightnessCapPct
+}
+
+// DayPhasesOrDefault returns the day phases dimming applies to, or the defaults if unset
+func (g *GridOutageConfig) DayPhasesOrDefault() []string {
+	if len(g.DayPhases) == 0 {
+		return defaultOutageDayPhases
+	}
+	return g.DayPhases
 }
 
 // LoadConfig loads the Hue configuration from a YAML file
@@ -87,5 +361,77 @@ func LoadConfig(path string) (*HueConfig, error) {
 		return nil, err
 	}
 
+	if err := validatePhaseTransitions(&config); err != nil {
+		return nil, err
+	}
+
+	if err := validateAdaptiveBrightness(&config); err != nil {
+		return nil, err
+	}
+
+	if err := validateMotion(&config); err != nil {
+		return nil, err
+	}
+
+	if err := validateManualOverride(&config); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
+
+// validateAdaptiveBrightness checks each room's adaptive brightness
+func validateAdaptiveBrightness(config *HueConfig) error {
+	for _, room := range config.Rooms {
+		if room.AdaptiveBrightness == nil {
+			continue
+		}
+		if err := room.AdaptiveBrightness.validate(); err != nil {
+			return fmt.Errorf("room %q adaptive_brightness: %w", room.HueGroup, err)
+		}
+	}
+	return nil
+}
+
+// validateMotion checks each room's motion override
+func validateMotion(config *HueConfig) error {
+	for _, room := range config.Rooms {
+		if room.Motion == nil {
+			continue
+		}
+		if err := room.Motion.validate(); err != nil {
+			return fmt.Errorf("room %q motion: %w", room.HueGroup, err)
+		}
+	}
+	return nil
+}
+
+// validateManualOverride checks the respect windows aren't negative
+func validateManualOverride(config *HueConfig) error {
+	if config.ManualOverrideMinutes < 0 {
+		return fmt.Errorf("manual_override_minutes must not be negative")
+	}
+	for _, room := range config.Rooms {
+		if room.ManualOverrideMinutes != nil && *room.ManualOverrideMinutes < 0 {
+			return fmt.Errorf("room %q manual_override_minutes must not be negative", room.HueGroup)
+		}
+	}
+	return nil
+}
+
+// validatePhaseTransitions checks the default and per-room day phase fades
+func validatePhaseTransitions(config *HueConfig) error {
+	for _, phase := range slices.Sorted(maps.Keys(config.DayPhaseTransitions)) {
+		if err := config.DayPhaseTransitions[phase].validate(); err != nil {
+			return fmt.Errorf("day_phase_transitions.%s: %w", phase, err)
+		}
+	}
+	for _, room := range config.Rooms {
+		for _, phase := range slices.Sorted(maps.Keys(room.DayPhaseTransitions)) {
+			if err := room.DayPhaseTransitions[phase].validate(); err != nil {
+				return fmt.Errorf("room %q day_phase_transitions.%s: %w", room.HueGroup, phase, err)
+			}
+		}
+	}
+	return nil
+}
@@ -1,11 +1,22 @@
 package lighting
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"homeautomation/internal/clock"
+	"homeautomation/internal/focusmode"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/scheduler"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -16,33 +27,98 @@ import (
 type Manager struct {
 	haClient      ha.HAClient
 	stateManager  *state.Manager
-	config        *HueConfig
+	config        atomic.Pointer[HueConfig] // Swapped by Reload
 	logger        *zap.Logger
 	readOnly      bool
 	shadowTracker *shadowstate.LightingTracker
 
 	// Subscriptions for cleanup
-	subscriptions []state.Subscription
+	subscriptions   []state.Subscription
+	haSubscriptions []ha.Subscription
+
+	// Daily on-time budgets, keyed by light entity
+	clock    clock.Clock
+	timezone *time.Location
+	budgets  map[string]*roomBudget
+	budgetMu sync.Mutex
+	// Budget lights already subscribed to, and whether the check loop runs (protected by budgetMu)
+	budgetSubscribed  map[string]bool
+	budgetLoopStarted bool
+
+	// Grid outage dimming (protected by outageMu)
+	outageActive bool
+	outageSince  time.Time
+	outageMu     sync.Mutex
+
+	// Rooms raised to their occupied scene by motion, keyed by hue group, and
+	// the motion sensors already subscribed to (protected by motionMu)
+	motionOverrides  map[string]*motionOverride
+	motionSubscribed map[string]bool
+	motionMu         sync.Mutex
+
+	// Rooms whose lights were changed outside this service, keyed by hue
+	// group, when our own commands to each room settle, and the lights
+	// already watched (protected by manualMu)
+	manualOverrides  map[string]*manualOverride
+	ownCommandUntil  map[string]time.Time
+	manualSubscribed map[string]bool
+	manualMu         sync.Mutex
+
+	// Rooms whose lights are held by office focus mode are left alone, nil if not configured
+	focus *focusmode.Guard
+
+	// Runs the steps of day phase fades, shared with other plugins
+	scheduler *scheduler.Scheduler
+
+	// Battery safe mode keeps decorative rooms off, nil if not configured
+	safeMode *safemode.Switch
 
 	// Automatic input capture for shadow state
 	pluginName  string
 	registry    *shadowstate.SubscriptionRegistry
 	inputHelper *shadowstate.InputCaptureHelper
+
+	// Optional observer of scene decisions, used by the simulator
+	onDecision func(SceneDecision)
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new Lighting Control manager
 func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *HueConfig, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
 	m := &Manager{
-		haClient:      haClient,
-		stateManager:  stateManager,
-		config:        config,
-		logger:        logger.Named("lighting"),
-		readOnly:      readOnly,
-		shadowTracker: shadowstate.NewLightingTracker(),
-		subscriptions: make([]state.Subscription, 0),
-		pluginName:    "lighting",
-		registry:      registry,
+		ctx:              ctx,
+		cancel:           cancel,
+		haClient:         haClient,
+		stateManager:     stateManager,
+		logger:           recorder.Logger(logger.Named("lighting")),
+		health:           recorder,
+		readOnly:         readOnly,
+		shadowTracker:    shadowstate.NewLightingTracker(),
+		subscriptions:    make([]state.Subscription, 0),
+		clock:            clock.NewRealClock(),
+		timezone:         time.UTC,
+		budgets:          make(map[string]*roomBudget),
+		budgetSubscribed: make(map[string]bool),
+		motionOverrides:  make(map[string]*motionOverride),
+		motionSubscribed: make(map[string]bool),
+		manualOverrides:  make(map[string]*manualOverride),
+		ownCommandUntil:  make(map[string]time.Time),
+		manualSubscribed: make(map[string]bool),
+		scheduler:        scheduler.New(logger, nil),
+		pluginName:       "lighting",
+		registry:         registry,
 	}
+	m.config.Store(config)
 
 	// Create input helper if registry provided
 	if registry != nil {
@@ -52,8 +128,42 @@ func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *HueCo
 	return m
 }
 
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetTimezone sets the timezone used to decide when daily on-time budgets reset
+func (m *Manager) SetTimezone(tz *time.Location) {
+	if tz != nil {
+		m.timezone = tz
+	}
+}
+
+// SetFocusMode sets the office focus mode guard. Rooms whose light is held
+// by focus mode keep the concentration scene until focus mode ends.
+func (m *Manager) SetFocusMode(guard *focusmode.Guard) {
+	m.focus = guard
+}
+
+// SetScheduler sets the scheduler day phase fades step on, shared with other plugins
+func (m *Manager) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+// SetSafeMode sets the battery safe mode switch. Decorative rooms are kept
+// off while safe mode is on and every room is re-evaluated when it changes.
+func (m *Manager) SetSafeMode(s *safemode.Switch) {
+	m.safeMode = s
+	s.OnChange(m.handleSafeModeChange)
+}
+
 // Start begins monitoring lighting state and triggers
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
+	// Lighting's calls and fades run under ctx, cancelled again by Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	m.logger.Info("Starting Lighting Control Manager")
 
 	// Subscribe to day phase changes
@@ -145,26 +255,71 @@ func (m *Manager) Start() error {
 			zap.String("variable", varNameCopy))
 	}
 
+	// Track rooms with a daily on-time budget
+	if err := m.startOnBudgets(); err != nil {
+		return err
+	}
+
+	// Dim at night while the grid is down
+	if err := m.startGridOutageDimming(); err != nil {
+		return err
+	}
+
+	// Raise rooms to their occupied scene on motion
+	if err := m.subscribeMotionSensors(m.currentConfig()); err != nil {
+		return err
+	}
+
+	// Leave rooms alone for a while after their lights are changed by hand
+	if err := m.subscribeManualLights(m.currentConfig()); err != nil {
+		return err
+	}
+
 	// Initialize shadow state with current input values (after all subscriptions registered)
 	m.updateShadowInputs()
 
+	m.health.Started(len(m.subscriptions) + len(m.haSubscriptions))
 	m.logger.Info("Lighting Control Manager started successfully")
 	return nil
 }
 
 // Stop stops the Lighting Control Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping Lighting Control Manager")
 
+	m.cancel()
+	m.cancelFades()
+	m.dropMotionOverrides()
+	m.dropManualOverrides()
+
 	// Unsubscribe from all subscriptions
 	for _, sub := range m.subscriptions {
 		sub.Unsubscribe()
 	}
 	m.subscriptions = nil
 
+	for _, sub := range m.haSubscriptions {
+		sub.Unsubscribe()
+	}
+	m.haSubscriptions = nil
+
+	m.motionMu.Lock()
+	m.motionSubscribed = make(map[string]bool)
+	m.motionMu.Unlock()
+
+	m.manualMu.Lock()
+	m.manualSubscribed = make(map[string]bool)
+	m.manualMu.Unlock()
+
 	m.logger.Info("Lighting Control Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handleDayPhaseChange processes day phase changes and activates scenes
 func (m *Manager) handleDayPhaseChange(key string, oldValue, newValue interface{}) {
 	newPhase, ok := newValue.(string)
@@ -180,6 +335,9 @@ func (m *Manager) handleDayPhaseChange(key string, oldValue, newValue interface{
 		zap.Any("old", oldValue),
 		zap.String("new", newPhase))
 
+	// Grid outage dimming only applies during some day phases
+	m.updateGridOutageDimming(newPhase)
+
 	// Activate scenes for all rooms based on new day phase
 	// dayPhase changes always affect all rooms (like "reset" in Node-RED)
 	m.activateScenesForAllRooms(newPhase, key)
@@ -288,7 +446,7 @@ func (m *Manager) collectConditionVariables() []string {
 		"isHaveGuests":     true,
 	}
 
-	for _, room := range m.config.Rooms {
+	for _, room := range m.currentConfig().Rooms {
 		// Collect from all condition types
 		for _, condition := range room.GetOnIfTrueConditions() {
 			if condition != "" && !alreadySubscribed[condition] {
@@ -342,36 +500,113 @@ func (m *Manager) handleOccupancyChange(key string, oldValue, newValue interface
 	m.evaluateAllRooms(dayPhase, key)
 }
 
-// activateScenesForAllRooms activates scenes for all configured rooms
+// roomCommand is the service call a room evaluation decided on, and what to
+// do with its result once it has been sent
+type roomCommand struct {
+	call ha.ServiceCall
+	done func(err error)
+}
+
+// activateScenesForAllRooms activates scenes for all configured rooms,
+// sending the rooms' service calls as one batch
 func (m *Manager) activateScenesForAllRooms(dayPhase string, trigger string) {
-	for _, room := range m.config.Rooms {
-		m.evaluateAndActivateRoom(&room, dayPhase, trigger)
+	var commands []*roomCommand
+	for _, room := range m.currentConfig().Rooms {
+		commands = append(commands, m.evaluateRoom(&room, dayPhase, trigger))
 	}
+	m.sendRoomCommands(commands)
 }
 
 // evaluateAllRooms re-evaluates all rooms and activates scenes as needed
 // Only evaluates rooms where the trigger variable is relevant (matches Node-RED)
 func (m *Manager) evaluateAllRooms(dayPhase string, trigger string) {
-	for _, room := range m.config.Rooms {
+	var commands []*roomCommand
+	for _, room := range m.currentConfig().Rooms {
 		// Check if this trigger is relevant to this room
 		if m.isTopicRelevant(&room, trigger) {
-			m.evaluateAndActivateRoom(&room, dayPhase, trigger)
+			commands = append(commands, m.evaluateRoom(&room, dayPhase, trigger))
 		} else {
 			m.logger.Debug("Skipping room evaluation - trigger not relevant",
 				zap.String("room", room.HueGroup),
 				zap.String("trigger", trigger))
 		}
 	}
+	m.sendRoomCommands(commands)
+}
+
+// sendRoomCommand sends a room's service call, if any, and finishes the room
+// with its result
+func (m *Manager) sendRoomCommand(command *roomCommand) {
+	if command == nil {
+		return
+	}
+	command.done(m.haClient.CallService(m.ctx, command.call.Domain, command.call.Service, command.call.Data))
+}
+
+// sendRoomCommands sends the rooms' service calls concurrently with
+// ha.CallServices, then finishes each room with its call's result. Nothing is
+// finished once Stop has abandoned the batch.
+func (m *Manager) sendRoomCommands(commands []*roomCommand) {
+	commands = slices.DeleteFunc(commands, func(c *roomCommand) bool { return c == nil })
+	if len(commands) <= 1 {
+		for _, command := range commands {
+			m.sendRoomCommand(command)
+		}
+		return
+	}
+
+	calls := make([]ha.ServiceCall, len(commands))
+	for i, command := range commands {
+		calls[i] = command.call
+	}
+	err := ha.CallServices(m.ctx, m.haClient, calls)
+	if m.ctx.Err() != nil {
+		m.logger.Info("Room commands abandoned, lighting is stopping", zap.Error(err))
+		return
+	}
+
+	results := make([]error, len(commands))
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			var callErr *ha.CallError
+			if errors.As(e, &callErr) {
+				results[callErr.Index] = callErr.Err
+			}
+		}
+	}
+	for i, command := range commands {
+		command.done(results[i])
+	}
 }
 
 // evaluateAndActivateRoom evaluates a room's conditions and activates the appropriate scene
 func (m *Manager) evaluateAndActivateRoom(room *RoomConfig, dayPhase string, trigger string) {
+	m.sendRoomCommand(m.evaluateRoom(room, dayPhase, trigger))
+}
+
+// evaluateRoom evaluates a room's conditions and returns the command that
+// activates its scene or turns it off, or nil if nothing is left to send
+func (m *Manager) evaluateRoom(room *RoomConfig, dayPhase string, trigger string) *roomCommand {
 	m.logger.Debug("Evaluating room",
 		zap.String("room", room.HueGroup),
 		zap.String("area_id", room.HASSAreaID),
 		zap.String("day_phase", dayPhase),
 		zap.String("trigger", trigger))
 
+	if m.focus.Suppresses(room.OnBudgetLightEntity()) {
+		m.logger.Info("Leaving room on its focus mode scene",
+			zap.String("room", room.HueGroup),
+			zap.String("trigger", trigger))
+		return nil
+	}
+
+	if m.manualOverrideActive(room, trigger) {
+		m.logger.Info("Leaving manually changed room alone",
+			zap.String("room", room.HueGroup),
+			zap.String("trigger", trigger))
+		return nil
+	}
+
 	// Evaluate on/off conditions
 	shouldTurnOn := m.evaluateOnConditions(room)
 	shouldTurnOff := m.evaluateOffConditions(room)
@@ -381,6 +616,21 @@ func (m *Manager) evaluateAndActivateRoom(room *RoomConfig, dayPhase string, tri
 		zap.Bool("should_turn_on", shouldTurnOn),
 		zap.Bool("should_turn_off", shouldTurnOff))
 
+	// Decorative rooms stay off during grid outage dimming and battery safe
+	// mode, whatever their conditions
+	if room.Decorative && (m.outageBrightnessCap() > 0 || m.safeMode.Active()) {
+		m.logger.Info("Keeping decorative room off during grid outage",
+			zap.String("room", room.HueGroup),
+			zap.Bool("safe_mode", m.safeMode.Active()))
+		m.dropMotionOverride(room.HueGroup)
+		return m.turnOffCommand(room, trigger)
+	}
+
+	// Motion holds the room in its occupied scene; the decision applies once it ends
+	if m.deferToMotionOverride(room, shouldTurnOn, shouldTurnOff, trigger) {
+		return nil
+	}
+
 	// If both are true, prioritize turning ON (matches Node-RED behavior)
 	if shouldTurnOn {
 		m.logger.Info("Room should be turned on with scene",
@@ -390,19 +640,18 @@ func (m *Manager) evaluateAndActivateRoom(room *RoomConfig, dayPhase string, tri
 			m.logger.Debug("ON takes precedence over OFF",
 				zap.String("room", room.HueGroup))
 		}
-		m.activateScene(room, dayPhase, trigger)
-		return
+		return m.activateSceneCommand(room, dayPhase, trigger)
 	}
 
 	if shouldTurnOff {
 		m.logger.Info("Room should be turned off",
 			zap.String("room", room.HueGroup))
-		m.turnOffRoom(room, trigger)
-		return
+		return m.turnOffCommand(room, trigger)
 	}
 
 	m.logger.Debug("No action needed for room",
 		zap.String("room", room.HueGroup))
+	return nil
 }
 
 // isTopicRelevant checks if a state variable change is relevant to a room's conditions
@@ -500,6 +749,40 @@ func (m *Manager) evaluateCondition(condition string) bool {
 	return value
 }
 
+// conditionEvaluations evaluates every condition of a room the way
+// evaluateOnConditions and evaluateOffConditions do, for the shadow state
+func (m *Manager) conditionEvaluations(room *RoomConfig) []shadowstate.ConditionEvaluation {
+	kinds := []struct {
+		kind       string
+		conditions []string
+		metWhen    bool
+	}{
+		{"on_if_true", room.GetOnIfTrueConditions(), true},
+		{"on_if_false", room.GetOnIfFalseConditions(), false},
+		{"off_if_true", room.GetOffIfTrueConditions(), true},
+		{"off_if_false", room.GetOffIfFalseConditions(), false},
+	}
+
+	var evaluations []shadowstate.ConditionEvaluation
+	for _, k := range kinds {
+		for _, condition := range k.conditions {
+			if condition == "" {
+				continue
+			}
+			evaluation := shadowstate.ConditionEvaluation{Kind: k.kind, Variable: condition}
+			// An unreadable condition counts as false, as in evaluateCondition
+			value, err := m.stateManager.GetBool(condition)
+			if err != nil {
+				evaluation.Error = err.Error()
+			}
+			evaluation.Value = err == nil && value
+			evaluation.Met = evaluation.Value == k.metWhen
+			evaluations = append(evaluations, evaluation)
+		}
+	}
+	return evaluations
+}
+
 // toSnakeCase converts a string to snake_case format
 // Matches the Node-RED implementation that converts "Primary Suite evening" to "primary_suite_evening"
 func toSnakeCase(str string) string {
@@ -515,11 +798,84 @@ func toSnakeCase(str string) string {
 	return strings.Trim(result, "_")
 }
 
-// activateScene activates a Hue scene for a room
+// activateScene activates a Hue scene for a room, fading into it when the
+// day phase changed and a fade is configured for the new phase
 func (m *Manager) activateScene(room *RoomConfig, dayPhase string, trigger string) {
-	// Construct scene entity ID: scene.{snake_case(hue_group + " " + day_phase)}
-	sceneName := room.HueGroup + " " + dayPhase
-	sceneEntityID := "scene." + toSnakeCase(sceneName)
+	m.sendRoomCommand(m.activateSceneCommand(room, dayPhase, trigger))
+}
+
+// activateSceneCommand returns the command activating a room's scene, or
+// starts a fade into it and returns nil
+func (m *Manager) activateSceneCommand(room *RoomConfig, dayPhase string, trigger string) *roomCommand {
+	// Whatever happens to the room now replaces a fade still in progress
+	m.cancelFade(room)
+
+	if trigger == "dayPhase" {
+		if transition, ok := m.currentConfig().PhaseTransitionFor(room, dayPhase); ok {
+			m.fadeIntoScene(room, dayPhase, trigger, transition)
+			return nil
+		}
+	}
+
+	return m.sceneCommand(room, dayPhase, trigger, room.TransitionSeconds)
+}
+
+// roomSceneEntityID returns the Hue scene for a room and day phase:
+// scene.{snake_case(hue_group + " " + day_phase)}
+func roomSceneEntityID(room *RoomConfig, dayPhase string) string {
+	return "scene." + toSnakeCase(room.HueGroup+" "+dayPhase)
+}
+
+// turnOnScene calls scene.turn_on for a room with the given transition in
+// seconds, nil for the bulbs' default
+func (m *Manager) turnOnScene(room *RoomConfig, dayPhase string, trigger string, transitionSeconds *int) {
+	m.sendRoomCommand(m.sceneCommand(room, dayPhase, trigger, transitionSeconds))
+}
+
+// sceneCommand returns the scene.turn_on command for a room, which caps or
+// scales the scene's brightness once sent. In read-only mode it records what
+// it would do and returns nil.
+func (m *Manager) sceneCommand(room *RoomConfig, dayPhase string, trigger string, transitionSeconds *int) *roomCommand {
+	sceneEntityID := roomSceneEntityID(room, dayPhase)
+	capPct := m.outageBrightnessCap()
+
+	// Call Home Assistant scene.turn_on service (matches Node-RED)
+	serviceData := map[string]interface{}{
+		"entity_id": sceneEntityID,
+		"area_id":   room.HASSAreaID,
+	}
+
+	// Add transition if specified
+	if transitionSeconds != nil {
+		serviceData["transition"] = *transitionSeconds
+	}
+
+	// The Nook doesn't do well with dynamics because of its lights
+	if room.HueGroup == "Nook" {
+		serviceData["dynamic"] = false
+	}
+
+	targets, values := m.resolveScene(sceneEntityID)
+	detail := shadowstate.RoomActionDetail{
+		Service:          "scene.turn_on",
+		ServiceData:      serviceData,
+		SceneEntityID:    sceneEntityID,
+		TargetEntities:   targets,
+		SceneValues:      values,
+		BrightnessCapPct: capPct,
+		Conditions:       m.conditionEvaluations(room),
+	}
+
+	// The grid outage cap takes precedence over the ambient light
+	var adaptive adaptiveScale
+	adaptiveOK := false
+	if capPct == 0 {
+		adaptive, adaptiveOK = m.adaptiveBrightness(room, values)
+	}
+	if adaptiveOK {
+		detail.AmbientLux = &adaptive.lux
+		detail.BrightnessScalePct = adaptive.scalePct
+	}
 
 	if m.readOnly {
 		m.logger.Info("READ-ONLY: Would activate scene",
@@ -527,12 +883,14 @@ func (m *Manager) activateScene(room *RoomConfig, dayPhase string, trigger strin
 			zap.String("area_id", room.HASSAreaID),
 			zap.String("scene", dayPhase),
 			zap.String("entity_id", sceneEntityID),
-			zap.String("trigger", trigger))
+			zap.String("trigger", trigger),
+			zap.Int("brightness_cap_pct", capPct),
+			zap.Int("brightness_scale_pct", detail.BrightnessScalePct))
 		// Record shadow state even in read-only mode for consistency with music plugin
 		m.recordAction(room.HueGroup, "activate_scene",
-			fmt.Sprintf("Would activate scene '%s'", dayPhase),
-			dayPhase, false, trigger)
-		return
+			sceneReason("Would activate", dayPhase, capPct),
+			dayPhase, false, trigger, detail)
+		return nil
 	}
 
 	m.logger.Info("Activating scene",
@@ -541,62 +899,119 @@ func (m *Manager) activateScene(room *RoomConfig, dayPhase string, trigger strin
 		zap.String("scene", dayPhase),
 		zap.String("entity_id", sceneEntityID),
 		zap.String("trigger", trigger),
-		zap.Any("transition_seconds", room.TransitionSeconds))
+		zap.Any("transition_seconds", transitionSeconds))
+
+	m.markOwnCommand(room, transitionSeconds)
+	return &roomCommand{
+		call: ha.ServiceCall{Domain: "scene", Service: "turn_on", Data: serviceData},
+		done: func(err error) {
+			if err != nil {
+				m.logger.Error("Failed to activate scene",
+					zap.String("room", room.HueGroup),
+					zap.String("scene", dayPhase),
+					zap.String("entity_id", sceneEntityID),
+					zap.Error(err))
+				return
+			}
 
-	// Call Home Assistant scene.turn_on service (matches Node-RED)
-	serviceData := map[string]interface{}{
-		"entity_id": sceneEntityID,
-		"area_id":   room.HASSAreaID,
-	}
+			m.logger.Info("Scene activated successfully",
+				zap.String("room", room.HueGroup),
+				zap.String("scene", dayPhase),
+				zap.String("entity_id", sceneEntityID))
+
+			// Dim to the grid outage brightness cap
+			if capPct > 0 {
+				if err := m.applyBrightnessCap(room, capPct, targets, values, transitionSeconds); err != nil {
+					m.logger.Error("Failed to apply grid outage brightness cap",
+						zap.String("room", room.HueGroup),
+						zap.Int("brightness_cap_pct", capPct),
+						zap.Error(err))
+				}
+			}
 
-	// Add transition if specified
-	if room.TransitionSeconds != nil {
-		serviceData["transition"] = *room.TransitionSeconds
-	}
+			// Scale to the room's ambient light
+			if adaptiveOK {
+				if err := m.applyAdaptiveBrightness(room, adaptive, transitionSeconds); err != nil {
+					m.logger.Error("Failed to apply adaptive brightness",
+						zap.String("room", room.HueGroup),
+						zap.Int("brightness_scale_pct", adaptive.scalePct),
+						zap.Error(err))
+				} else {
+					m.logger.Info("Scaled scene brightness to ambient light",
+						zap.String("room", room.HueGroup),
+						zap.Float64("lux", adaptive.lux),
+						zap.Int("brightness_scale_pct", adaptive.scalePct),
+						zap.Int("brightness", adaptive.brightness))
+				}
+			}
 
-	// The Nook doesn't do well with dynamics because of its lights
-	if room.HueGroup == "Nook" {
-		serviceData["dynamic"] = false
+			// Record action in shadow state
+			m.recordAction(room.HueGroup, "activate_scene",
+				sceneReason("Activated", dayPhase, capPct),
+				dayPhase, false, trigger, detail)
+		},
 	}
+}
 
-	// Call the service with the constructed entity ID
-	err := m.haClient.CallService("scene", "turn_on", serviceData)
-	if err != nil {
-		m.logger.Error("Failed to activate scene",
-			zap.String("room", room.HueGroup),
-			zap.String("scene", dayPhase),
+// sceneValueAttributes are the scene attributes recorded as its brightness
+// and color values
+var sceneValueAttributes = []string{
+	"brightness", "color_mode", "color_temp", "color_temp_kelvin",
+	"hs_color", "rgb_color", "xy_color", "is_dynamic", "speed",
+}
+
+// resolveScene reads the lights a scene sets and its brightness and color
+// values from the scene entity's attributes. It returns nothing if the scene
+// can't be read.
+func (m *Manager) resolveScene(sceneEntityID string) ([]string, map[string]interface{}) {
+	st, err := m.haClient.GetState(m.ctx, sceneEntityID)
+	if err != nil || st == nil {
+		m.logger.Debug("Could not read scene attributes",
 			zap.String("entity_id", sceneEntityID),
 			zap.Error(err))
-		return
+		return nil, nil
 	}
 
-	m.logger.Info("Scene activated successfully",
-		zap.String("room", room.HueGroup),
-		zap.String("scene", dayPhase),
-		zap.String("entity_id", sceneEntityID))
+	var targets []string
+	switch ids := st.Attributes["entity_id"].(type) {
+	case []interface{}:
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				targets = append(targets, s)
+			}
+		}
+	case []string:
+		targets = append(targets, ids...)
+	case string:
+		targets = []string{ids}
+	}
+
+	values := make(map[string]interface{})
+	for _, attr := range sceneValueAttributes {
+		if v, ok := st.Attributes[attr]; ok {
+			values[attr] = v
+		}
+	}
+	return targets, values
+}
 
-	// Record action in shadow state
-	m.recordAction(room.HueGroup, "activate_scene",
-		fmt.Sprintf("Activated scene '%s'", dayPhase),
-		dayPhase, false, trigger)
+// sceneReason describes a scene activation for the shadow state
+func sceneReason(verb string, dayPhase string, capPct int) string {
+	if capPct > 0 {
+		return fmt.Sprintf("%s scene '%s' capped at %d%% brightness (grid outage)", verb, dayPhase, capPct)
+	}
+	return fmt.Sprintf("%s scene '%s'", verb, dayPhase)
 }
 
 // turnOffRoom turns off lights in a room
 func (m *Manager) turnOffRoom(room *RoomConfig, trigger string) {
-	if m.readOnly {
-		m.logger.Info("READ-ONLY: Would turn off room",
-			zap.String("room", room.HueGroup),
-			zap.String("area_id", room.HASSAreaID),
-			zap.String("trigger", trigger))
-		// Record shadow state even in read-only mode for consistency with music plugin
-		m.recordAction(room.HueGroup, "turn_off", "Would turn off room", "", true, trigger)
-		return
-	}
+	m.sendRoomCommand(m.turnOffCommand(room, trigger))
+}
 
-	m.logger.Info("Turning off room",
-		zap.String("room", room.HueGroup),
-		zap.String("area_id", room.HASSAreaID),
-		zap.String("trigger", trigger))
+// turnOffCommand returns the light.turn_off command for a room. In read-only
+// mode it records what it would do and returns nil.
+func (m *Manager) turnOffCommand(room *RoomConfig, trigger string) *roomCommand {
+	m.cancelFade(room)
 
 	// Use light.turn_off with area_id
 	serviceData := map[string]interface{}{
@@ -608,20 +1023,46 @@ func (m *Manager) turnOffRoom(room *RoomConfig, trigger string) {
 		serviceData["transition"] = *room.TransitionSeconds
 	}
 
-	err := m.haClient.CallService("light", "turn_off", serviceData)
-	if err != nil {
-		m.logger.Error("Failed to turn off room",
+	detail := shadowstate.RoomActionDetail{
+		Service:     "light.turn_off",
+		ServiceData: serviceData,
+		Conditions:  m.conditionEvaluations(room),
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would turn off room",
 			zap.String("room", room.HueGroup),
 			zap.String("area_id", room.HASSAreaID),
-			zap.Error(err))
-		return
+			zap.String("trigger", trigger))
+		// Record shadow state even in read-only mode for consistency with music plugin
+		m.recordAction(room.HueGroup, "turn_off", "Would turn off room", "", true, trigger, detail)
+		return nil
 	}
 
-	m.logger.Info("Room turned off successfully",
-		zap.String("room", room.HueGroup))
+	m.logger.Info("Turning off room",
+		zap.String("room", room.HueGroup),
+		zap.String("area_id", room.HASSAreaID),
+		zap.String("trigger", trigger))
+
+	m.markOwnCommand(room, room.TransitionSeconds)
+	return &roomCommand{
+		call: ha.ServiceCall{Domain: "light", Service: "turn_off", Data: serviceData},
+		done: func(err error) {
+			if err != nil {
+				m.logger.Error("Failed to turn off room",
+					zap.String("room", room.HueGroup),
+					zap.String("area_id", room.HASSAreaID),
+					zap.Error(err))
+				return
+			}
+
+			m.logger.Info("Room turned off successfully",
+				zap.String("room", room.HueGroup))
 
-	// Record action in shadow state
-	m.recordAction(room.HueGroup, "turn_off", "Turned off room", "", true, trigger)
+			// Record action in shadow state
+			m.recordAction(room.HueGroup, "turn_off", "Turned off room", "", true, trigger, detail)
+		},
+	}
 }
 
 // Reset re-applies lighting scenes for all rooms based on current day phase
@@ -637,6 +1078,11 @@ func (m *Manager) Reset() error {
 	m.logger.Info("Re-activating scenes for current day phase",
 		zap.String("day_phase", dayPhase))
 
+	// Reset puts every room back on its day phase scene, whether motion or
+	// a manual change is holding it
+	m.dropMotionOverrides()
+	m.dropManualOverrides()
+
 	// Re-apply scenes for all rooms (like the comment says: "like reset in Node-RED")
 	m.activateScenesForAllRooms(dayPhase, "reset")
 
@@ -681,6 +1127,7 @@ func (m *Manager) updateShadowInputs() {
 
 // updateShadowInputsWithTrigger updates the current shadow state inputs including the trigger
 func (m *Manager) updateShadowInputsWithTrigger(trigger string) {
+	m.health.Tick()
 	inputs := make(map[string]interface{})
 
 	// Get all subscribed variables
@@ -713,7 +1160,7 @@ func (m *Manager) updateShadowInputsWithTrigger(trigger string) {
 }
 
 // recordAction captures the current inputs and records an action in shadow state
-func (m *Manager) recordAction(roomName string, actionType string, reason string, activeScene string, turnedOff bool, trigger string) {
+func (m *Manager) recordAction(roomName string, actionType string, reason string, activeScene string, turnedOff bool, trigger string, detail shadowstate.RoomActionDetail) {
 	// First, update current inputs (includes trigger field)
 	m.updateShadowInputsWithTrigger(trigger)
 
@@ -721,10 +1168,25 @@ func (m *Manager) recordAction(roomName string, actionType string, reason string
 	m.shadowTracker.SnapshotInputsForAction()
 
 	// Record the action
-	m.shadowTracker.RecordRoomAction(roomName, actionType, reason, activeScene, turnedOff)
+	m.shadowTracker.RecordRoomActionDetail(roomName, actionType, reason, activeScene, turnedOff, detail)
+
+	if m.onDecision != nil {
+		m.onDecision(SceneDecision{
+			Room:    roomName,
+			Action:  actionType,
+			Scene:   activeScene,
+			Trigger: trigger,
+			Reason:  reason,
+		})
+	}
 }
 
 // GetShadowState returns the current shadow state
 func (m *Manager) GetShadowState() *shadowstate.LightingShadowState {
 	return m.shadowTracker.GetState()
 }
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
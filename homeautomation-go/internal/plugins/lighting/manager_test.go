@@ -1,12 +1,17 @@
 package lighting
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"homeautomation/internal/focusmode"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -53,7 +58,7 @@ func TestNewManager(t *testing.T) {
 	assert.NotNil(t, manager)
 	assert.Equal(t, mockClient, manager.haClient)
 	assert.Equal(t, stateManager, manager.stateManager)
-	assert.Equal(t, config, manager.config)
+	assert.Equal(t, config, manager.currentConfig())
 	assert.False(t, manager.readOnly)
 }
 
@@ -269,6 +274,52 @@ func TestActivateScene(t *testing.T) {
 	assert.Equal(t, 30, call.Data["transition"])
 }
 
+func TestActivateScene_RecordsResolvedDetail(t *testing.T) {
+	logger := zap.NewNop()
+	config := createTestConfig()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	require.NoError(t, stateManager.SetBool("isTVPlaying", true))
+	mockClient.SetState("scene.living_room_evening", "scening", map[string]interface{}{
+		"entity_id":  []interface{}{"light.sofa", "light.floor_lamp"},
+		"brightness": 180.0,
+		"is_dynamic": false,
+		"group_name": "Living Room",
+	})
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+
+	manager.activateScene(&config.Rooms[0], "evening", "dayPhase")
+
+	room := manager.GetShadowState().Outputs.Rooms["Living Room"]
+	assert.Equal(t, "scene.turn_on", room.Service)
+	assert.Equal(t, "scene.living_room_evening", room.SceneEntityID)
+	assert.Equal(t, "living_room_2", room.ServiceData["area_id"])
+	assert.Equal(t, 30, room.ServiceData["transition"])
+	assert.Equal(t, []string{"light.sofa", "light.floor_lamp"}, room.TargetEntities)
+	assert.Equal(t, map[string]interface{}{"brightness": 180.0, "is_dynamic": false}, room.SceneValues)
+
+	require.Len(t, room.Conditions, 4)
+	assert.Equal(t, shadowstate.ConditionEvaluation{Kind: "on_if_true", Variable: "isAnyoneHome", Value: true, Met: true}, room.Conditions[0])
+	assert.Equal(t, shadowstate.ConditionEvaluation{Kind: "on_if_false", Variable: "isTVPlaying", Value: true, Met: false}, room.Conditions[1])
+	assert.Equal(t, shadowstate.ConditionEvaluation{Kind: "off_if_false", Variable: "isAnyoneHome", Value: true, Met: false}, room.Conditions[3])
+}
+
+func TestActivateSceneReadOnly_RecordsServiceData(t *testing.T) {
+	logger := zap.NewNop()
+	config := createTestConfig()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	manager := NewManager(mockClient, stateManager, config, logger, true, nil)
+
+	manager.activateScene(&config.Rooms[0], "night", "dayPhase")
+
+	room := manager.GetShadowState().Outputs.Rooms["Living Room"]
+	assert.Equal(t, "scene.living_room_night", room.ServiceData["entity_id"])
+	assert.Empty(t, room.TargetEntities, "unknown scene has no resolved targets")
+	assert.Empty(t, mockClient.GetServiceCalls())
+}
+
 func TestTurnOffRoomReadOnly(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	config := createTestConfig()
@@ -381,6 +432,66 @@ func TestEvaluateAndActivateRoom(t *testing.T) {
 	}
 }
 
+// failingSceneClient fails scene.turn_on for one scene
+type failingSceneClient struct {
+	ha.HAClient
+	scene string
+}
+
+func (c *failingSceneClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	if domain == "scene" && data["entity_id"] == c.scene {
+		return errors.New("HA error: not_found")
+	}
+	return c.HAClient.CallService(ctx, domain, service, data)
+}
+
+func TestActivateScenesForAllRooms_Batch(t *testing.T) {
+	logger := zap.NewNop()
+	config := createTestConfig()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	client := &failingSceneClient{HAClient: mockClient, scene: "scene.primary_suite_evening"}
+	manager := NewManager(client, stateManager, config, logger, false, nil)
+
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	require.NoError(t, stateManager.SetBool("isMasterAsleep", false))
+	require.NoError(t, stateManager.SetBool("isNickHome", true))
+	mockClient.ClearServiceCalls()
+
+	manager.activateScenesForAllRooms("evening", "reset")
+
+	calls := mockClient.GetServiceCalls()
+	require.Len(t, calls, 1, "the failed room's call isn't recorded by the mock")
+	assert.Equal(t, "scene.living_room_evening", calls[0].Data["entity_id"])
+
+	rooms := manager.GetShadowState().Outputs.Rooms
+	assert.Equal(t, "evening", rooms["Living Room"].ActiveScene)
+	assert.NotContains(t, rooms, "Primary Suite", "a room whose call failed records no action")
+}
+
+func TestEvaluateAndActivateRoom_FocusMode(t *testing.T) {
+	logger := zap.NewNop()
+	config := createTestConfig()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	manager.SetFocusMode(focusmode.NewGuard(&focusmode.Config{FocusMode: focusmode.Settings{
+		ToggleVariable: "isOfficeFocusMode",
+		Lights:         []string{"light.living_room"},
+	}}, stateManager))
+
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	require.NoError(t, stateManager.SetBool(focusmode.ActiveVariable, true))
+	mockClient.ClearServiceCalls()
+
+	manager.evaluateAndActivateRoom(&config.Rooms[0], "day", "isAnyoneHome")
+	assert.Empty(t, mockClient.GetServiceCalls(), "a room held by focus mode keeps its scene")
+
+	require.NoError(t, stateManager.SetBool(focusmode.ActiveVariable, false))
+	manager.evaluateAndActivateRoom(&config.Rooms[0], "day", "isAnyoneHome")
+	assert.NotEmpty(t, mockClient.GetServiceCalls())
+}
+
 func TestStart(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	config := createTestConfig()
@@ -389,7 +500,7 @@ func TestStart(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
 
 	// Start manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Verify subscriptions were created
@@ -440,7 +551,7 @@ func TestLightingManager_Stop(t *testing.T) {
 	_ = stateManager.SetBool("isHaveGuests", false)
 
 	// Start manager (creates subscriptions)
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 
 	// Verify subscriptions were created (7 subscriptions)
@@ -464,7 +575,7 @@ func TestManagerReset(t *testing.T) {
 
 	manager := NewManager(mockClient, stateManager, hueConfig, logger, false, nil)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -0,0 +1,248 @@
+package lighting
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// manualSettleTime is how long after one of our own commands, on top of its
+// transition, changes to the room's light are still put down to that command
+const manualSettleTime = 10 * time.Second
+
+// manualAttributes are the light attributes whose change counts as a manual
+// adjustment, along with turning the light on or off
+var manualAttributes = []string{
+	"brightness", "color_temp", "color_temp_kelvin", "hs_color", "rgb_color", "xy_color",
+}
+
+// manualJobName is the scheduler job ending a room's manual override
+func manualJobName(roomName string) string {
+	return "lighting/manual/" + toSnakeCase(roomName)
+}
+
+// manualOverride is a room whose lights were changed outside this service.
+// Scene decisions for it are skipped until the respect window ends.
+type manualOverride struct {
+	entityID      string
+	lightState    string
+	since         time.Time
+	until         time.Time
+	skipped       int
+	lastSkipped   string
+	lastSkippedAt time.Time
+}
+
+// subscribeManualLights subscribes to the light of every room that watches
+// for manual changes, skipping lights already subscribed to
+func (m *Manager) subscribeManualLights(config *HueConfig) error {
+	for i := range config.Rooms {
+		room := &config.Rooms[i]
+		if config.ManualOverrideFor(room) <= 0 {
+			continue
+		}
+		entityID := room.OnBudgetLightEntity()
+
+		m.manualMu.Lock()
+		subscribed := m.manualSubscribed[entityID]
+		m.manualSubscribed[entityID] = true
+		m.manualMu.Unlock()
+		if subscribed {
+			continue
+		}
+
+		sub, err := m.haClient.SubscribeStateChanges(entityID, m.handleManualLightChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, sub)
+		if m.registry != nil {
+			m.registry.RegisterHASubscription(m.pluginName, entityID)
+		}
+		m.logger.Debug("Watching for manual light changes",
+			zap.String("room", room.HueGroup),
+			zap.String("entity_id", entityID))
+	}
+	return nil
+}
+
+// markOwnCommand notes that we are about to change the room's lights, so the
+// state changes that follow aren't taken for a manual change
+func (m *Manager) markOwnCommand(room *RoomConfig, transitionSeconds *int) {
+	settle := manualSettleTime
+	if transitionSeconds != nil {
+		settle += time.Duration(*transitionSeconds) * time.Second
+	}
+	until := m.clock.Now().Add(settle)
+
+	m.manualMu.Lock()
+	defer m.manualMu.Unlock()
+	if until.After(m.ownCommandUntil[room.HueGroup]) {
+		m.ownCommandUntil[room.HueGroup] = until
+	}
+}
+
+// handleManualLightChange starts or extends the manual override of each room
+// the light belongs to, unless the change followed one of our own commands
+func (m *Manager) handleManualLightChange(entityID string, oldState, newState *ha.State) {
+	if !lightAdjusted(oldState, newState) {
+		return
+	}
+
+	now := m.clock.Now()
+	config := m.currentConfig()
+	for i := range config.Rooms {
+		room := &config.Rooms[i]
+		respect := config.ManualOverrideFor(room)
+		if respect <= 0 || room.OnBudgetLightEntity() != entityID {
+			continue
+		}
+
+		m.manualMu.Lock()
+		ours := now.Before(m.ownCommandUntil[room.HueGroup])
+		m.manualMu.Unlock()
+		if ours {
+			continue
+		}
+
+		m.startManualOverride(room, newState.State, now, respect)
+	}
+}
+
+// lightAdjusted reports whether a light was turned on or off or had its
+// brightness or color changed. Changes to or from unavailable don't count.
+func lightAdjusted(oldState, newState *ha.State) bool {
+	if oldState == nil || newState == nil {
+		return false
+	}
+	for _, st := range []string{oldState.State, newState.State} {
+		if st != "on" && st != "off" {
+			return false
+		}
+	}
+	if oldState.State != newState.State {
+		return true
+	}
+	if newState.State == "off" {
+		return false
+	}
+	for _, attr := range manualAttributes {
+		if !reflect.DeepEqual(oldState.Attributes[attr], newState.Attributes[attr]) {
+			return true
+		}
+	}
+	return false
+}
+
+// startManualOverride leaves the room alone for the respect window from now,
+// stopping anything we were still doing to it
+func (m *Manager) startManualOverride(room *RoomConfig, lightState string, now time.Time, respect time.Duration) {
+	m.manualMu.Lock()
+	override, extended := m.manualOverrides[room.HueGroup]
+	if !extended {
+		override = &manualOverride{entityID: room.OnBudgetLightEntity(), since: now}
+		m.manualOverrides[room.HueGroup] = override
+	}
+	override.lightState = lightState
+	override.until = now.Add(respect)
+	until := override.until
+	state := override.shadow()
+	m.manualMu.Unlock()
+	m.shadowTracker.UpdateManualOverride(room.HueGroup, state)
+
+	m.cancelFade(room)
+	m.dropMotionOverride(room.HueGroup)
+
+	name := room.HueGroup
+	if err := m.scheduler.Once(manualJobName(name), until, func() { m.endManualOverride(name) }); err != nil {
+		m.logger.Error("Failed to schedule end of manual override",
+			zap.String("room", room.HueGroup),
+			zap.Error(err))
+	}
+
+	m.logger.Info("Lights changed manually, leaving room alone",
+		zap.String("room", room.HueGroup),
+		zap.String("entity_id", override.entityID),
+		zap.String("light_state", lightState),
+		zap.Bool("extended", extended),
+		zap.Time("until", until))
+}
+
+// manualOverrideActive reports whether the room's lights are being left
+// alone, counting the decision the caller is skipping
+func (m *Manager) manualOverrideActive(room *RoomConfig, trigger string) bool {
+	now := m.clock.Now()
+
+	m.manualMu.Lock()
+	override, active := m.manualOverrides[room.HueGroup]
+	if !active {
+		m.manualMu.Unlock()
+		return false
+	}
+	override.skipped++
+	override.lastSkipped = trigger
+	override.lastSkippedAt = now
+	state := override.shadow()
+	m.manualMu.Unlock()
+	m.shadowTracker.UpdateManualOverride(room.HueGroup, state)
+	return true
+}
+
+// endManualOverride lets the room follow its conditions again from the next
+// lighting change. The room isn't re-applied straight away, so lights someone
+// turned off don't come back on by themselves.
+func (m *Manager) endManualOverride(roomName string) {
+	if !m.dropManualOverride(roomName) {
+		return
+	}
+	m.logger.Info("Manual override ended, room follows its conditions again",
+		zap.String("room", roomName))
+}
+
+// dropManualOverride removes a room's manual override and its pending end,
+// reporting whether there was one
+func (m *Manager) dropManualOverride(roomName string) bool {
+	m.manualMu.Lock()
+	_, active := m.manualOverrides[roomName]
+	delete(m.manualOverrides, roomName)
+	m.manualMu.Unlock()
+	if !active {
+		return false
+	}
+
+	m.scheduler.Cancel(manualJobName(roomName))
+	m.shadowTracker.RemoveManualOverride(roomName)
+	return true
+}
+
+// dropManualOverrides removes every room's manual override
+func (m *Manager) dropManualOverrides() {
+	m.manualMu.Lock()
+	names := make([]string, 0, len(m.manualOverrides))
+	for name := range m.manualOverrides {
+		names = append(names, name)
+	}
+	m.manualMu.Unlock()
+
+	for _, name := range names {
+		m.dropManualOverride(name)
+	}
+}
+
+// shadow returns the override for the shadow state. The caller holds manualMu.
+func (o *manualOverride) shadow() shadowstate.ManualOverrideState {
+	return shadowstate.ManualOverrideState{
+		LightEntity:    o.entityID,
+		LightState:     o.lightState,
+		Since:          o.since,
+		Until:          o.until,
+		SkippedActions: o.skipped,
+		LastSkipped:    o.lastSkipped,
+		LastSkippedAt:  o.lastSkippedAt,
+	}
+}
@@ -0,0 +1,137 @@
+package lighting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func manualTestConfig(minutes int) *HueConfig {
+	config := fadeTestConfig(PhaseTransition{Minutes: 10})
+	config.ManualOverrideMinutes = minutes
+	return config
+}
+
+// adjustLivingRoom changes the living room light as the Hue app would
+func adjustLivingRoom(mockClient *ha.MockClient, state string, brightness float64) {
+	mockClient.SetState("light.living_room", state, map[string]interface{}{"brightness": brightness})
+}
+
+func TestManualOverride_SkipsDayPhaseChange(t *testing.T) {
+	manager, mockClient, stateManager, mockClock := newFadeTestManager(t, manualTestConfig(60))
+	seedLivingRoom(mockClient, true)
+
+	adjustLivingRoom(mockClient, "on", 40)
+
+	override, ok := manager.GetShadowState().Outputs.ManualOverrides["Living Room"]
+	require.True(t, ok)
+	assert.Equal(t, "light.living_room", override.LightEntity)
+	assert.Equal(t, mockClock.Now().Add(time.Hour), override.Until)
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+	assert.Empty(t, lightingCalls(mockClient), "manual change is respected")
+
+	override = manager.GetShadowState().Outputs.ManualOverrides["Living Room"]
+	assert.Equal(t, 1, override.SkippedActions)
+	assert.Equal(t, "dayPhase", override.LastSkipped)
+
+	// Once the window ends the room isn't touched until the next change
+	mockClock.Advance(time.Hour)
+	assert.Empty(t, manager.GetShadowState().Outputs.ManualOverrides)
+	assert.Empty(t, lightingCalls(mockClient))
+
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene.living_room_night", calls[0].Data["entity_id"])
+}
+
+func TestManualOverride_IgnoresOwnCommands(t *testing.T) {
+	manager, mockClient, stateManager, mockClock := newFadeTestManager(t, manualTestConfig(60))
+	seedLivingRoom(mockClient, false)
+
+	// The scene turning the light on is ours
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+	adjustLivingRoom(mockClient, "on", 120)
+	assert.Empty(t, manager.GetShadowState().Outputs.ManualOverrides)
+
+	// A change long after it isn't
+	mockClock.Advance(time.Minute)
+	adjustLivingRoom(mockClient, "off", 0)
+	assert.Contains(t, manager.GetShadowState().Outputs.ManualOverrides, "Living Room")
+}
+
+func TestManualOverride_RoomOverridesDefault(t *testing.T) {
+	disabled := 0
+	config := manualTestConfig(60)
+	config.Rooms[0].ManualOverrideMinutes = &disabled
+	manager, mockClient, stateManager, _ := newFadeTestManager(t, config)
+	seedLivingRoom(mockClient, true)
+
+	adjustLivingRoom(mockClient, "on", 40)
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	assert.Empty(t, manager.GetShadowState().Outputs.ManualOverrides)
+	assert.Len(t, lightingCalls(mockClient), 1)
+}
+
+func TestManualOverride_StopsMotionAndReset(t *testing.T) {
+	config := manualTestConfig(30)
+	config.Rooms[0].Motion = &MotionConfig{Sensors: []string{motionSensor}}
+	manager, mockClient, _, mockClock := newFadeTestManager(t, config)
+	seedLivingRoom(mockClient, false)
+
+	mockClient.SetState(motionSensor, "on", nil)
+	mockClock.Advance(time.Minute)
+	adjustLivingRoom(mockClient, "on", 40)
+
+	state := manager.GetShadowState()
+	assert.Empty(t, state.Outputs.MotionOverrides, "manual change takes the room from motion")
+	assert.Contains(t, state.Outputs.ManualOverrides, "Living Room")
+
+	// Motion doesn't raise a manually changed room
+	mockClient.SetState(motionSensor, "off", nil)
+	mockClient.ClearServiceCalls()
+	mockClient.SetState(motionSensor, "on", nil)
+	assert.Empty(t, lightingCalls(mockClient))
+
+	// Reset re-applies the room regardless
+	require.NoError(t, manager.Reset())
+	assert.Empty(t, manager.GetShadowState().Outputs.ManualOverrides)
+	assert.Len(t, lightingCalls(mockClient), 1)
+}
+
+func TestLightAdjusted(t *testing.T) {
+	on := func(brightness float64) *ha.State {
+		return &ha.State{State: "on", Attributes: map[string]interface{}{"brightness": brightness}}
+	}
+	off := &ha.State{State: "off"}
+
+	assert.True(t, lightAdjusted(off, on(100)))
+	assert.True(t, lightAdjusted(on(100), off))
+	assert.True(t, lightAdjusted(on(100), on(80)))
+	assert.False(t, lightAdjusted(on(100), on(100)))
+	assert.False(t, lightAdjusted(off, &ha.State{State: "off", Attributes: map[string]interface{}{"friendly_name": "x"}}))
+	assert.False(t, lightAdjusted(&ha.State{State: "unavailable"}, on(100)))
+	assert.False(t, lightAdjusted(nil, on(100)))
+}
+
+func TestLoadConfig_ManualOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hue_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("manual_override_minutes: 90\nrooms:\n  - hue_group: Kitchen\n  - hue_group: Nook\n    manual_override_minutes: 15\n"), 0o644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, config.ManualOverrideFor(&config.Rooms[0]))
+	assert.Equal(t, 15*time.Minute, config.ManualOverrideFor(&config.Rooms[1]))
+
+	require.NoError(t, os.WriteFile(path, []byte("rooms:\n  - hue_group: Kitchen\n    manual_override_minutes: -5\n"), 0o644))
+	_, err = LoadConfig(path)
+	assert.EqualError(t, err, `room "Kitchen" manual_override_minutes must not be negative`)
+}
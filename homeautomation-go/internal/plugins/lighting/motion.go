@@ -0,0 +1,321 @@
+package lighting
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// motionRevertTrigger is the trigger recorded when a motion override ends
+const motionRevertTrigger = "motion_timeout"
+
+// motionJobName is the scheduler job ending a room's motion override
+func motionJobName(room *RoomConfig) string {
+	return "lighting/motion/" + toSnakeCase(room.HueGroup)
+}
+
+// motionOverride is a room raised to its occupied scene by motion. While it
+// holds the room, day phase decisions for it are kept in deferred instead of
+// being applied, so the two don't fight over the lights.
+type motionOverride struct {
+	scene      string
+	sensor     string
+	since      time.Time
+	priorOn    bool      // Whether the room's lights were on before the override
+	clearSince time.Time // Zero while any sensor sees motion
+	revertAt   time.Time
+	deferred   string // "activate_scene", "turn_off", or "" if the day phase logic hasn't acted
+}
+
+// subscribeMotionSensors subscribes to the motion sensors of every room that
+// has a motion override, skipping sensors already subscribed to
+func (m *Manager) subscribeMotionSensors(config *HueConfig) error {
+	for _, room := range config.Rooms {
+		if room.Motion == nil {
+			continue
+		}
+		for _, sensor := range room.Motion.Sensors {
+			m.motionMu.Lock()
+			subscribed := m.motionSubscribed[sensor]
+			m.motionSubscribed[sensor] = true
+			m.motionMu.Unlock()
+			if subscribed {
+				continue
+			}
+
+			sub, err := m.haClient.SubscribeStateChanges(sensor, m.handleMotionChange)
+			if err != nil {
+				return fmt.Errorf("failed to subscribe to %s: %w", sensor, err)
+			}
+			m.haSubscriptions = append(m.haSubscriptions, sub)
+			if m.registry != nil {
+				m.registry.RegisterHASubscription(m.pluginName, sensor)
+			}
+			m.logger.Debug("Subscribed to motion sensor",
+				zap.String("room", room.HueGroup),
+				zap.String("sensor", sensor))
+		}
+	}
+	return nil
+}
+
+// handleMotionChange starts or extends the override of each room the sensor
+// belongs to, and starts the timeout once all of a room's sensors are clear
+func (m *Manager) handleMotionChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+
+	config := m.currentConfig()
+	for i := range config.Rooms {
+		room := &config.Rooms[i]
+		if room.Motion == nil || !slices.Contains(room.Motion.Sensors, entityID) {
+			continue
+		}
+		if newState.State == "on" {
+			m.motionDetected(room, entityID)
+		} else if !m.anyMotion(room) {
+			m.motionCleared(room)
+		}
+	}
+}
+
+// motionDetected raises the room to its occupied scene, or keeps an override
+// already holding it from timing out
+func (m *Manager) motionDetected(room *RoomConfig, sensor string) {
+	now := m.clock.Now()
+
+	m.motionMu.Lock()
+	override, active := m.motionOverrides[room.HueGroup]
+	if active {
+		override.clearSince = time.Time{}
+		override.revertAt = time.Time{}
+		state := override.shadow()
+		m.motionMu.Unlock()
+
+		m.scheduler.Cancel(motionJobName(room))
+		m.shadowTracker.UpdateMotionOverride(room.HueGroup, state)
+		m.logger.Debug("Motion extended override",
+			zap.String("room", room.HueGroup),
+			zap.String("sensor", sensor))
+		return
+	}
+	m.motionMu.Unlock()
+
+	if reason, ignored := m.motionIgnored(room); ignored {
+		m.logger.Debug("Ignoring motion",
+			zap.String("room", room.HueGroup),
+			zap.String("sensor", sensor),
+			zap.String("reason", reason))
+		return
+	}
+
+	// A room whose brightness can't be read counts as on, so it returns to
+	// its scene rather than going dark
+	brightness, ok := m.currentBrightness(room)
+	override = &motionOverride{
+		scene:   room.Motion.SceneOrDefault(),
+		sensor:  sensor,
+		since:   now,
+		priorOn: !ok || brightness > 0,
+	}
+
+	m.motionMu.Lock()
+	m.motionOverrides[room.HueGroup] = override
+	state := override.shadow()
+	m.motionMu.Unlock()
+	m.shadowTracker.UpdateMotionOverride(room.HueGroup, state)
+
+	m.logger.Info("Motion detected, raising room to occupied scene",
+		zap.String("room", room.HueGroup),
+		zap.String("sensor", sensor),
+		zap.String("scene", override.scene),
+		zap.Bool("prior_on", override.priorOn))
+
+	m.cancelFade(room)
+	m.turnOnScene(room, override.scene, sensor, room.TransitionSeconds)
+}
+
+// motionIgnored reports why motion shouldn't raise the room, if it shouldn't
+func (m *Manager) motionIgnored(room *RoomConfig) (string, bool) {
+	if m.focus.Suppresses(room.OnBudgetLightEntity()) {
+		return "room held by focus mode", true
+	}
+	m.manualMu.Lock()
+	_, manual := m.manualOverrides[room.HueGroup]
+	m.manualMu.Unlock()
+	if manual {
+		return "lights changed manually", true
+	}
+	if room.Decorative && (m.outageBrightnessCap() > 0 || m.safeMode.Active()) {
+		return "decorative room kept off", true
+	}
+	for _, condition := range room.Motion.GetIgnoreIfTrueConditions() {
+		if m.evaluateCondition(condition) {
+			return condition + " is true", true
+		}
+	}
+	return "", false
+}
+
+// anyMotion reports whether any of the room's sensors still sees motion
+func (m *Manager) anyMotion(room *RoomConfig) bool {
+	for _, sensor := range room.Motion.Sensors {
+		st, err := m.haClient.GetState(m.ctx, sensor)
+		if err == nil && st != nil && st.State == "on" {
+			return true
+		}
+	}
+	return false
+}
+
+// motionCleared schedules the end of the room's override once its timeout
+// has passed without motion
+func (m *Manager) motionCleared(room *RoomConfig) {
+	now := m.clock.Now()
+
+	m.motionMu.Lock()
+	override, active := m.motionOverrides[room.HueGroup]
+	if !active {
+		m.motionMu.Unlock()
+		return
+	}
+	override.clearSince = now
+	override.revertAt = now.Add(room.Motion.TimeoutOrDefault())
+	revertAt := override.revertAt
+	state := override.shadow()
+	m.motionMu.Unlock()
+	m.shadowTracker.UpdateMotionOverride(room.HueGroup, state)
+
+	name := room.HueGroup
+	if err := m.scheduler.Once(motionJobName(room), revertAt, func() { m.endMotionOverride(name) }); err != nil {
+		m.logger.Error("Failed to schedule end of motion override",
+			zap.String("room", room.HueGroup),
+			zap.Error(err))
+		return
+	}
+	m.logger.Debug("Motion cleared, override ends after timeout",
+		zap.String("room", room.HueGroup),
+		zap.Time("revert_at", revertAt))
+}
+
+// endMotionOverride hands the room back: it gets whatever the day phase
+// logic decided while it was held, or otherwise its day phase scene if its
+// lights were on before the override and off if they weren't
+func (m *Manager) endMotionOverride(roomName string) {
+	override := m.dropMotionOverride(roomName)
+	if override == nil {
+		return
+	}
+
+	config := m.currentConfig()
+	idx := slices.IndexFunc(config.Rooms, func(r RoomConfig) bool { return r.HueGroup == roomName })
+	if idx < 0 {
+		m.logger.Info("Motion override ended for a room no longer configured", zap.String("room", roomName))
+		return
+	}
+	room := &config.Rooms[idx]
+
+	dayPhase, err := m.stateManager.GetString("dayPhase")
+	if err != nil {
+		m.logger.Error("Failed to get dayPhase", zap.Error(err))
+		return
+	}
+
+	action := override.deferred
+	if action == "" {
+		action = "turn_off"
+		if override.priorOn {
+			action = "activate_scene"
+		}
+	}
+
+	m.logger.Info("Motion override ended, restoring room",
+		zap.String("room", roomName),
+		zap.String("action", action),
+		zap.String("deferred", override.deferred),
+		zap.Bool("prior_on", override.priorOn),
+		zap.String("day_phase", dayPhase))
+
+	if action == "activate_scene" {
+		m.activateScene(room, dayPhase, motionRevertTrigger)
+	} else {
+		m.turnOffRoom(room, motionRevertTrigger)
+	}
+}
+
+// deferToMotionOverride keeps a day phase decision for a room held by a
+// motion override, reporting whether the room is held. shouldTurnOn takes
+// precedence as it does in evaluateAndActivateRoom.
+func (m *Manager) deferToMotionOverride(room *RoomConfig, shouldTurnOn, shouldTurnOff bool, trigger string) bool {
+	m.motionMu.Lock()
+	override, active := m.motionOverrides[room.HueGroup]
+	if !active {
+		m.motionMu.Unlock()
+		return false
+	}
+	switch {
+	case shouldTurnOn:
+		override.deferred = "activate_scene"
+	case shouldTurnOff:
+		override.deferred = "turn_off"
+	}
+	deferred := override.deferred
+	state := override.shadow()
+	m.motionMu.Unlock()
+	m.shadowTracker.UpdateMotionOverride(room.HueGroup, state)
+
+	m.logger.Info("Room held by motion override, applying day phase decision when it ends",
+		zap.String("room", room.HueGroup),
+		zap.String("trigger", trigger),
+		zap.String("deferred", deferred))
+	return true
+}
+
+// dropMotionOverride removes a room's override and its pending timeout,
+// returning the override if there was one
+func (m *Manager) dropMotionOverride(roomName string) *motionOverride {
+	m.motionMu.Lock()
+	override, active := m.motionOverrides[roomName]
+	delete(m.motionOverrides, roomName)
+	m.motionMu.Unlock()
+	if !active {
+		return nil
+	}
+
+	m.scheduler.Cancel(motionJobName(&RoomConfig{HueGroup: roomName}))
+	m.shadowTracker.RemoveMotionOverride(roomName)
+	return override
+}
+
+// dropMotionOverrides removes every room's override without restoring it
+func (m *Manager) dropMotionOverrides() {
+	m.motionMu.Lock()
+	names := make([]string, 0, len(m.motionOverrides))
+	for name := range m.motionOverrides {
+		names = append(names, name)
+	}
+	m.motionMu.Unlock()
+
+	for _, name := range names {
+		m.dropMotionOverride(name)
+	}
+}
+
+// shadow returns the override for the shadow state. The caller holds motionMu.
+func (o *motionOverride) shadow() shadowstate.MotionOverrideState {
+	return shadowstate.MotionOverrideState{
+		Scene:      o.scene,
+		Sensor:     o.sensor,
+		Since:      o.since,
+		PriorOn:    o.priorOn,
+		ClearSince: o.clearSince,
+		RevertAt:   o.revertAt,
+		Deferred:   o.deferred,
+	}
+}
@@ -0,0 +1,190 @@
+package lighting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const motionSensor = "binary_sensor.living_room_motion"
+
+func motionTestConfig(motion *MotionConfig) *HueConfig {
+	return &HueConfig{
+		Rooms: []RoomConfig{
+			{HueGroup: "Living Room", HASSAreaID: "living_room", OnIfTrue: "isAnyoneHomeAndAwake", OffIfFalse: "isAnyoneHomeAndAwake", Motion: motion},
+		},
+	}
+}
+
+// seedLivingRoom sets whether the living room's lights are on before motion
+func seedLivingRoom(mockClient *ha.MockClient, on bool) {
+	st := &ha.State{EntityID: "light.living_room", State: "off"}
+	if on {
+		st.State = "on"
+		st.Attributes = map[string]interface{}{"brightness": 120.0}
+	}
+	mockClient.SetMockState("light.living_room", st)
+}
+
+func TestMotionOverride_RestoresRoomThatWasOff(t *testing.T) {
+	manager, mockClient, _, mockClock := newFadeTestManager(t, motionTestConfig(&MotionConfig{Sensors: []string{motionSensor}, TimeoutMinutes: 5}))
+	seedLivingRoom(mockClient, false)
+
+	mockClient.SetState(motionSensor, "on", nil)
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene", calls[0].Domain)
+	assert.Equal(t, "scene.living_room_occupied", calls[0].Data["entity_id"])
+
+	override := manager.GetShadowState().Outputs.MotionOverrides["Living Room"]
+	assert.Equal(t, "occupied", override.Scene)
+	assert.False(t, override.PriorOn)
+
+	// The timeout only starts once the sensor is clear
+	mockClock.Advance(10 * time.Minute)
+	assert.Len(t, lightingCalls(mockClient), 1)
+
+	mockClient.SetState(motionSensor, "off", nil)
+	override = manager.GetShadowState().Outputs.MotionOverrides["Living Room"]
+	assert.Equal(t, mockClock.Now().Add(5*time.Minute), override.RevertAt)
+
+	mockClock.Advance(5 * time.Minute)
+	calls = lightingCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "light", calls[1].Domain)
+	assert.Equal(t, "turn_off", calls[1].Service)
+
+	state := manager.GetShadowState()
+	assert.Empty(t, state.Outputs.MotionOverrides)
+	assert.True(t, state.Outputs.Rooms["Living Room"].TurnedOff)
+}
+
+func TestMotionOverride_RestoresDayPhaseScene(t *testing.T) {
+	_, mockClient, _, mockClock := newFadeTestManager(t, motionTestConfig(&MotionConfig{Sensors: []string{motionSensor}}))
+	seedLivingRoom(mockClient, true)
+
+	mockClient.SetState(motionSensor, "on", nil)
+	mockClient.SetState(motionSensor, "off", nil)
+	mockClock.Advance(defaultMotionTimeoutMinutes * time.Minute)
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "scene.living_room_occupied", calls[0].Data["entity_id"])
+	assert.Equal(t, "scene.living_room_dusk", calls[1].Data["entity_id"])
+}
+
+func TestMotionOverride_DefersDayPhaseDecisions(t *testing.T) {
+	manager, mockClient, stateManager, mockClock := newFadeTestManager(t, motionTestConfig(&MotionConfig{Sensors: []string{motionSensor}}))
+	seedLivingRoom(mockClient, false)
+
+	mockClient.SetState(motionSensor, "on", nil)
+	mockClient.ClearServiceCalls()
+
+	// The day phase changing doesn't replace the occupied scene
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+	assert.Empty(t, lightingCalls(mockClient))
+	assert.Equal(t, "activate_scene", manager.GetShadowState().Outputs.MotionOverrides["Living Room"].Deferred)
+
+	// Motion again before the timeout keeps the override
+	mockClient.SetState(motionSensor, "off", nil)
+	mockClock.Advance(3 * time.Minute)
+	mockClient.SetState(motionSensor, "on", nil)
+	mockClient.SetState(motionSensor, "off", nil)
+	mockClock.Advance(3 * time.Minute)
+	assert.Empty(t, lightingCalls(mockClient))
+
+	// The room was off before, but the day phase logic turned it on since
+	mockClock.Advance(2 * time.Minute)
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene.living_room_winddown", calls[0].Data["entity_id"])
+}
+
+func TestMotionOverride_WaitsForEverySensor(t *testing.T) {
+	const hallway = "binary_sensor.living_room_doorway_motion"
+	_, mockClient, _, mockClock := newFadeTestManager(t, motionTestConfig(&MotionConfig{Sensors: []string{motionSensor, hallway}, TimeoutMinutes: 1}))
+	seedLivingRoom(mockClient, false)
+
+	mockClient.SetState(motionSensor, "on", nil)
+	mockClient.SetState(hallway, "on", nil)
+	mockClient.SetState(motionSensor, "off", nil)
+	mockClock.Advance(5 * time.Minute)
+	assert.Len(t, lightingCalls(mockClient), 1, "doorway sensor still sees motion")
+
+	mockClient.SetState(hallway, "off", nil)
+	mockClock.Advance(time.Minute)
+	assert.Len(t, lightingCalls(mockClient), 2)
+}
+
+func TestMotionOverride_IgnoreIfTrue(t *testing.T) {
+	manager, mockClient, stateManager, _ := newFadeTestManager(t, motionTestConfig(&MotionConfig{Sensors: []string{motionSensor}, IgnoreIfTrue: "isEveryoneAsleep"}))
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+	mockClient.ClearServiceCalls()
+
+	mockClient.SetState(motionSensor, "on", nil)
+
+	assert.Empty(t, lightingCalls(mockClient))
+	assert.Empty(t, manager.GetShadowState().Outputs.MotionOverrides)
+}
+
+func TestMotionOverride_ResetDropsOverride(t *testing.T) {
+	manager, mockClient, _, mockClock := newFadeTestManager(t, motionTestConfig(&MotionConfig{Sensors: []string{motionSensor}, Scene: "bright"}))
+	seedLivingRoom(mockClient, false)
+
+	mockClient.SetState(motionSensor, "on", nil)
+	require.NoError(t, manager.Reset())
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "scene.living_room_bright", calls[0].Data["entity_id"])
+	assert.Equal(t, "scene.living_room_dusk", calls[1].Data["entity_id"])
+	assert.Empty(t, manager.GetShadowState().Outputs.MotionOverrides)
+
+	mockClient.SetState(motionSensor, "off", nil)
+	mockClock.Advance(time.Hour)
+	assert.Len(t, lightingCalls(mockClient), 2)
+}
+
+func TestLoadConfig_Motion(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: "rooms:\n  - hue_group: Kitchen\n    motion:\n      sensors: [binary_sensor.kitchen_motion]\n      ignore_if_true: [isEveryoneAsleep]\n",
+		},
+		{
+			name:    "no sensors",
+			yaml:    "rooms:\n  - hue_group: Kitchen\n    motion:\n      timeout_minutes: 5\n",
+			wantErr: `room "Kitchen" motion: at least one sensor is required`,
+		},
+		{
+			name:    "negative timeout",
+			yaml:    "rooms:\n  - hue_group: Kitchen\n    motion:\n      sensors: [binary_sensor.kitchen_motion]\n      timeout_minutes: -1\n",
+			wantErr: `room "Kitchen" motion: timeout_minutes must not be negative`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hue_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
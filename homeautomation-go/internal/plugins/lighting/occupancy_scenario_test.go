@@ -50,6 +50,7 @@ package lighting
 // =============================================================================
 
 import (
+	"context"
 	"testing"
 
 	"homeautomation/internal/ha"
@@ -133,7 +134,7 @@ func TestScenario_NickOfficeOccupied_TurnsOnLights(t *testing.T) {
 	_ = stateManager.SetBool("isAnyoneHomeAndAwake", true)
 
 	// Start manager - this is where subscriptions should be set up
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -210,7 +211,7 @@ func TestScenario_NickOfficeUnoccupied_TurnsOffLights(t *testing.T) {
 	_ = stateManager.SetBool("isAnyoneHomeAndAwake", true)
 
 	// Start manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -286,7 +287,7 @@ func TestScenario_KitchenOccupied_TurnsOnLights(t *testing.T) {
 	_ = stateManager.SetBool("isAnyoneHomeAndAwake", true)
 
 	// Start manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -362,7 +363,7 @@ func TestScenario_OccupancyChangeOnlyAffectsRelevantRoom(t *testing.T) {
 	_ = stateManager.SetBool("isAnyoneHomeAndAwake", true)
 
 	// Start manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
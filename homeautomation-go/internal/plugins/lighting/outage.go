@@ -0,0 +1,203 @@
+package lighting
+
+import (
+	"fmt"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// startGridOutageDimming subscribes to grid availability if grid outage
+// dimming is configured. The energy plugin only reports isGridAvailable; the
+// lighting plugin owns every light command, so the outage is applied as a
+// constraint on its own scene decisions rather than as competing commands.
+func (m *Manager) startGridOutageDimming() error {
+	if m.currentConfig().GridOutage == nil {
+		return nil
+	}
+
+	sub, err := m.stateManager.Subscribe("isGridAvailable", m.handleGridAvailabilityChange)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to isGridAvailable: %w", err)
+	}
+	m.subscriptions = append(m.subscriptions, sub)
+	if m.registry != nil {
+		m.registry.RegisterStateSubscription(m.pluginName, "isGridAvailable")
+	}
+
+	dayPhase, err := m.stateManager.GetString("dayPhase")
+	if err != nil {
+		return fmt.Errorf("failed to get dayPhase: %w", err)
+	}
+	m.updateGridOutageDimming(dayPhase)
+	return nil
+}
+
+// handleGridAvailabilityChange re-applies every room when dimming turns on or off
+func (m *Manager) handleGridAvailabilityChange(key string, oldValue, newValue interface{}) {
+	// Update shadow state current inputs immediately
+	m.updateShadowInputs()
+
+	dayPhase, err := m.stateManager.GetString("dayPhase")
+	if err != nil {
+		m.logger.Error("Failed to get dayPhase", zap.Error(err))
+		return
+	}
+
+	if !m.updateGridOutageDimming(dayPhase) {
+		return
+	}
+
+	// Re-apply scenes so rooms pick up (or drop) the brightness cap and
+	// decorative rooms turn off (or return to their normal conditions)
+	m.activateScenesForAllRooms(dayPhase, key)
+}
+
+// gridOutageDimmingWanted reports whether dimming applies during the given day phase
+func (m *Manager) gridOutageDimmingWanted(dayPhase string) bool {
+	config := m.currentConfig()
+	if config.GridOutage == nil {
+		return false
+	}
+
+	gridAvailable, err := m.stateManager.GetBool("isGridAvailable")
+	if err != nil {
+		m.logger.Warn("Failed to get isGridAvailable", zap.Error(err))
+		return false
+	}
+	if gridAvailable {
+		return false
+	}
+
+	for _, phase := range config.GridOutage.DayPhasesOrDefault() {
+		if phase == dayPhase {
+			return true
+		}
+	}
+	return false
+}
+
+// updateGridOutageDimming recomputes whether dimming is active for the day
+// phase and reports whether it changed
+func (m *Manager) updateGridOutageDimming(dayPhase string) bool {
+	config := m.currentConfig()
+	if config.GridOutage == nil {
+		return false
+	}
+	active := m.gridOutageDimmingWanted(dayPhase)
+
+	m.outageMu.Lock()
+	changed := active != m.outageActive
+	m.outageActive = active
+	if !active {
+		m.outageSince = time.Time{}
+	} else if changed {
+		m.outageSince = m.clock.Now()
+	}
+	since := m.outageSince
+	m.outageMu.Unlock()
+
+	var decorative []string
+	for _, room := range config.Rooms {
+		if room.Decorative {
+			decorative = append(decorative, room.HueGroup)
+		}
+	}
+	m.shadowTracker.UpdateGridOutage(shadowstate.GridOutageDimmingState{
+		Active:           active,
+		BrightnessCapPct: config.GridOutage.BrightnessCapPctOrDefault(),
+		DecorativeRooms:  decorative,
+		Since:            since,
+	})
+
+	if changed {
+		m.logger.Info("Grid outage dimming changed",
+			zap.Bool("active", active),
+			zap.String("day_phase", dayPhase),
+			zap.Int("brightness_cap_pct", config.GridOutage.BrightnessCapPctOrDefault()),
+			zap.Strings("decorative_rooms", decorative))
+	}
+	return changed
+}
+
+// outageBrightnessCap returns the brightness cap while dimming is active, or 0
+func (m *Manager) outageBrightnessCap() int {
+	m.outageMu.Lock()
+	defer m.outageMu.Unlock()
+
+	if !m.outageActive {
+		return 0
+	}
+	return m.currentConfig().GridOutage.BrightnessCapPctOrDefault()
+}
+
+// applyBrightnessCap dims a room's lights to the cap after its scene is
+// activated. Each light goes to the lower of the scene's brightness and the
+// cap, so lights the scene already sets at or below the cap are left alone.
+// When the scene doesn't record a brightness, a light's own brightness is
+// used. Lights are commanded by entity so the calls are arbitrated.
+func (m *Manager) applyBrightnessCap(room *RoomConfig, capPct int, targets []string, sceneValues map[string]interface{}, transitionSeconds *int) error {
+	if len(targets) == 0 {
+		m.logger.Warn("Scene lights unknown, not applying grid outage brightness cap",
+			zap.String("room", room.HueGroup))
+		return nil
+	}
+
+	capBrightness := float64(capPct) * 255 / 100
+	sceneBrightness, sceneKnown := brightnessValue(sceneValues["brightness"])
+	if sceneKnown && sceneBrightness <= capBrightness {
+		return nil
+	}
+
+	var calls []ha.ServiceCall
+	for _, entityID := range targets {
+		if ha.EntityDomain(entityID) != "light" {
+			continue
+		}
+		if !sceneKnown && !m.lightAboveBrightness(entityID, capBrightness) {
+			continue
+		}
+		serviceData := map[string]interface{}{
+			"entity_id":      entityID,
+			"brightness_pct": capPct,
+		}
+		if transitionSeconds != nil {
+			serviceData["transition"] = *transitionSeconds
+		}
+		calls = append(calls, ha.ServiceCall{Domain: "light", Service: "turn_on", Data: serviceData})
+	}
+	return ha.CallServices(m.ctx, m.haClient, calls)
+}
+
+// lightAboveBrightness reports whether a light is on brighter than brightness (0-255)
+func (m *Manager) lightAboveBrightness(entityID string, brightness float64) bool {
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil || st == nil || st.State != "on" {
+		return false
+	}
+	current, ok := brightnessValue(st.Attributes["brightness"])
+	return ok && current > brightness
+}
+
+// handleSafeModeChange re-applies every room so decorative rooms turn off
+// when battery safe mode starts and return to their conditions when it ends
+func (m *Manager) handleSafeModeChange(status safemode.Status) {
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	dayPhase, err := m.stateManager.GetString("dayPhase")
+	if err != nil {
+		m.logger.Error("Failed to get dayPhase", zap.Error(err))
+		return
+	}
+
+	m.logger.Info("Battery safe mode changed, re-applying rooms",
+		zap.Bool("active", status.Active),
+		zap.String("reason", status.Reason))
+	m.activateScenesForAllRooms(dayPhase, "safeMode")
+}
@@ -0,0 +1,249 @@
+package lighting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/safemode"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// livingRoomLights are the lights the living room's scenes set
+var livingRoomLights = []interface{}{"light.living_room_lamp", "light.living_room_ceiling"}
+
+// seedLivingRoomScene sets the living room's scene for a day phase, with its
+// brightness (0-255) or none if negative
+func seedLivingRoomScene(mockClient *ha.MockClient, dayPhase string, brightness float64) {
+	entityID := "scene.living_room_" + dayPhase
+	attributes := map[string]interface{}{"entity_id": livingRoomLights}
+	if brightness >= 0 {
+		attributes["brightness"] = brightness
+	}
+	mockClient.SetMockState(entityID, &ha.State{EntityID: entityID, State: "scening", Attributes: attributes})
+}
+
+// newOutageTestManager starts a manager with a living room and decorative
+// outdoor lights, both on while someone is awake. The living room's night
+// scene is at 80% brightness.
+func newOutageTestManager(t *testing.T, dayPhase string, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+	return newOutageTestManagerWithClient(t, ha.NewMockClient(), nil, dayPhase, readOnly)
+}
+
+// newOutageTestManagerWithClient is newOutageTestManager with the manager's
+// HA client wrapped by wrap, if not nil
+func newOutageTestManagerWithClient(t *testing.T, mockClient *ha.MockClient, wrap func(ha.HAClient) ha.HAClient, dayPhase string, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	seedLivingRoomScene(mockClient, "night", 204)
+	var client ha.HAClient = mockClient
+	if wrap != nil {
+		client = wrap(mockClient)
+	}
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetString("dayPhase", dayPhase))
+	require.NoError(t, stateManager.SetBool("isAnyoneHomeAndAwake", true))
+
+	config := &HueConfig{
+		GridOutage: &GridOutageConfig{BrightnessCapPct: 25},
+		Rooms: []RoomConfig{
+			{HueGroup: "Living Room", HASSAreaID: "living_room", OnIfTrue: "isAnyoneHomeAndAwake"},
+			{HueGroup: "Decorative Outdoor Lights", HASSAreaID: "front_of_house", OnIfTrue: "isAnyoneHomeAndAwake", Decorative: true},
+		},
+	}
+
+	manager := NewManager(client, stateManager, config, logger, readOnly, nil)
+	manager.SetClock(clock.NewMockClock(time.Date(2024, 3, 10, 22, 0, 0, 0, time.UTC)))
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockClient.ClearServiceCalls()
+	return manager, mockClient, stateManager
+}
+
+// outageCalls summarises light commands: capped lights by entity, turned
+// off rooms and scenes by area
+func outageCalls(calls []ha.ServiceCall) (capped map[string]interface{}, turnedOff map[string]bool, scenes map[string]bool) {
+	capped = make(map[string]interface{})
+	turnedOff = make(map[string]bool)
+	scenes = make(map[string]bool)
+	for _, call := range calls {
+		area, _ := call.Data["area_id"].(string)
+		switch {
+		case call.Domain == "light" && call.Service == "turn_on":
+			entityID, _ := call.Data["entity_id"].(string)
+			capped[entityID] = call.Data["brightness_pct"]
+		case call.Domain == "light" && call.Service == "turn_off":
+			turnedOff[area] = true
+		case call.Domain == "scene":
+			scenes[area] = true
+		}
+	}
+	return capped, turnedOff, scenes
+}
+
+func TestGridOutageConfigDefaults(t *testing.T) {
+	config := &GridOutageConfig{}
+	assert.Equal(t, 30, config.BrightnessCapPctOrDefault())
+	assert.Equal(t, []string{"dusk", "winddown", "night"}, config.DayPhasesOrDefault())
+}
+
+func TestGridOutageDimming_AtNight(t *testing.T) {
+	manager, mockClient, stateManager := newOutageTestManager(t, "night", false)
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	capped, turnedOff, scenes := outageCalls(mockClient.GetServiceCalls())
+	assert.True(t, scenes["living_room"], "living room scene re-applied")
+	assert.Equal(t, map[string]interface{}{"light.living_room_lamp": 25, "light.living_room_ceiling": 25}, capped, "each living room light capped")
+	assert.True(t, turnedOff["front_of_house"], "decorative lights turned off")
+	assert.False(t, scenes["front_of_house"], "decorative scene not activated")
+
+	outage := manager.GetShadowState().Outputs.GridOutage
+	require.NotNil(t, outage)
+	assert.True(t, outage.Active)
+	assert.Equal(t, 25, outage.BrightnessCapPct)
+	assert.Equal(t, []string{"Decorative Outdoor Lights"}, outage.DecorativeRooms)
+	assert.False(t, outage.Since.IsZero())
+
+	// Grid returns: scenes restored at full brightness, decorative lights follow their conditions again
+	mockClient.ClearServiceCalls()
+	require.NoError(t, stateManager.SetBool("isGridAvailable", true))
+
+	capped, turnedOff, scenes = outageCalls(mockClient.GetServiceCalls())
+	assert.True(t, scenes["living_room"])
+	assert.True(t, scenes["front_of_house"])
+	assert.Empty(t, capped)
+	assert.Empty(t, turnedOff)
+	assert.False(t, manager.GetShadowState().Outputs.GridOutage.Active)
+}
+
+func TestGridOutageDimming_DaytimeOutage(t *testing.T) {
+	manager, mockClient, stateManager := newOutageTestManager(t, "day", false)
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+	capped, turnedOff, scenes := outageCalls(mockClient.GetServiceCalls())
+	assert.Empty(t, capped, "no dimming during the day")
+	assert.Empty(t, turnedOff)
+	assert.Empty(t, scenes)
+	assert.False(t, manager.GetShadowState().Outputs.GridOutage.Active)
+
+	// Nightfall during the outage starts dimming with the new day phase's scenes
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+
+	capped, turnedOff, _ = outageCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, 25, capped["light.living_room_lamp"])
+	assert.True(t, turnedOff["front_of_house"])
+	assert.True(t, manager.GetShadowState().Outputs.GridOutage.Active)
+}
+
+func TestGridOutageDimming_LeavesDimSceneAlone(t *testing.T) {
+	manager, mockClient, stateManager := newOutageTestManager(t, "night", false)
+	seedLivingRoomScene(mockClient, "night", 25) // 10%
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	capped, _, scenes := outageCalls(mockClient.GetServiceCalls())
+	assert.True(t, scenes["living_room"])
+	assert.Empty(t, capped, "a scene below the cap isn't brightened")
+	assert.True(t, manager.GetShadowState().Outputs.GridOutage.Active)
+}
+
+func TestGridOutageDimming_SceneBrightnessUnknown(t *testing.T) {
+	_, mockClient, stateManager := newOutageTestManager(t, "night", false)
+	seedLivingRoomScene(mockClient, "night", -1)
+	mockClient.SetMockState("light.living_room_lamp", &ha.State{
+		EntityID: "light.living_room_lamp", State: "on", Attributes: map[string]interface{}{"brightness": 200.0},
+	})
+	mockClient.SetMockState("light.living_room_ceiling", &ha.State{
+		EntityID: "light.living_room_ceiling", State: "on", Attributes: map[string]interface{}{"brightness": 40.0},
+	})
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	capped, _, _ := outageCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, map[string]interface{}{"light.living_room_lamp": 25}, capped, "only the light above the cap is dimmed")
+}
+
+func TestGridOutageDimming_Arbitrated(t *testing.T) {
+	arbiter := ha.NewArbiter(ha.DefaultCommandPriorities, time.Minute, zap.NewNop())
+	mockClient := ha.NewMockClient()
+	_, _, stateManager := newOutageTestManagerWithClient(t, mockClient, func(client ha.HAClient) ha.HAClient {
+		return arbiter.Client("lighting", client)
+	}, "night", false)
+
+	// Security holds the lamp, e.g. pulsing it red for a lockdown
+	security := arbiter.Client("security", mockClient)
+	require.NoError(t, security.CallService(context.Background(), "light", "turn_on", map[string]interface{}{
+		"entity_id": "light.living_room_lamp",
+		"rgb_color": []int{255, 0, 0},
+	}))
+	mockClient.ClearServiceCalls()
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	capped, _, _ := outageCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, map[string]interface{}{"light.living_room_ceiling": 25}, capped, "the held lamp isn't capped over security")
+}
+
+func TestGridOutageDimming_ReadOnly(t *testing.T) {
+	manager, mockClient, stateManager := newOutageTestManager(t, "night", true)
+
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	capped, turnedOff, scenes := outageCalls(mockClient.GetServiceCalls())
+	assert.Empty(t, capped)
+	assert.Empty(t, turnedOff)
+	assert.Empty(t, scenes)
+	room := manager.GetShadowState().Outputs.Rooms["Living Room"]
+	assert.Contains(t, room.Reason, "capped at 25%")
+}
+
+func TestSafeMode_KeepsDecorativeRoomsOff(t *testing.T) {
+	manager, mockClient, _ := newOutageTestManager(t, "day", false)
+	safeModeSwitch := safemode.NewSwitch()
+	manager.SetSafeMode(safeModeSwitch)
+
+	safeModeSwitch.Enter("battery low", nil, 5, time.Now())
+
+	_, turnedOff, scenes := outageCalls(mockClient.GetServiceCalls())
+	assert.True(t, scenes["living_room"], "other rooms keep their scenes")
+	assert.True(t, turnedOff["front_of_house"], "decorative lights turned off")
+	assert.False(t, scenes["front_of_house"])
+
+	mockClient.ClearServiceCalls()
+	safeModeSwitch.Exit("grid available", time.Now())
+
+	_, turnedOff, scenes = outageCalls(mockClient.GetServiceCalls())
+	assert.True(t, scenes["front_of_house"], "decorative lights follow their conditions again")
+	assert.Empty(t, turnedOff)
+}
+
+func TestGridOutageDimming_NotConfigured(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+
+	config := &HueConfig{Rooms: []RoomConfig{{HueGroup: "Living Room", HASSAreaID: "living_room"}}}
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	mockClient.ClearServiceCalls()
+	require.NoError(t, stateManager.SetBool("isGridAvailable", false))
+
+	capped, turnedOff, scenes := outageCalls(mockClient.GetServiceCalls())
+	assert.Empty(t, capped)
+	assert.Empty(t, turnedOff)
+	assert.Empty(t, scenes)
+	assert.Nil(t, manager.GetShadowState().Outputs.GridOutage)
+}
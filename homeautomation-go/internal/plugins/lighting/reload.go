@@ -0,0 +1,36 @@
+package lighting
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// currentConfig returns the configuration in effect
+func (m *Manager) currentConfig() *HueConfig {
+	return m.config.Load()
+}
+
+// Reload applies an edited hue_config.yaml. Room scenes and conditions apply
+// from the next lighting change, on-time budgets are added, removed or
+// resized in place without losing today's usage, and newly listed motion
+// sensors and lights watched for manual changes are subscribed to.
+func (m *Manager) Reload(config *HueConfig) error {
+	if config == nil {
+		return fmt.Errorf("hue config is nil")
+	}
+
+	m.config.Store(config)
+	if err := m.reloadOnBudgets(config); err != nil {
+		return err
+	}
+	if err := m.subscribeMotionSensors(config); err != nil {
+		return err
+	}
+	if err := m.subscribeManualLights(config); err != nil {
+		return err
+	}
+
+	m.logger.Info("Hue config reloaded", zap.Int("rooms", len(config.Rooms)))
+	return nil
+}
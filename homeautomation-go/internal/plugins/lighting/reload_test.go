@@ -0,0 +1,69 @@
+package lighting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload_ResizesBudgetAndKeepsUsage(t *testing.T) {
+	manager, mockClient, mockClock := newBudgetTestManager(t, false)
+
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClock.Advance(20 * time.Minute)
+
+	require.NoError(t, manager.Reload(&HueConfig{
+		Rooms: []RoomConfig{
+			{HueGroup: "Closet", HASSAreaID: "closet", OnBudget: &OnBudget{DailyMinutes: 15}},
+		},
+	}))
+
+	budget := manager.GetShadowState().Outputs.OnBudgets["Closet"]
+	assert.Equal(t, 15.0, budget.BudgetMinutes)
+	assert.Equal(t, 20.0, budget.ConsumedMinutes, "today's usage carries over")
+
+	manager.checkBudgets()
+	turnOffs, _ := budgetCalls(mockClient.GetServiceCalls())
+	assert.Equal(t, 1, turnOffs, "the smaller budget is enforced right away")
+}
+
+func TestReload_AddsAndRemovesBudgets(t *testing.T) {
+	manager, mockClient, mockClock := newBudgetTestManager(t, false)
+
+	require.NoError(t, manager.Reload(&HueConfig{
+		Rooms: []RoomConfig{
+			{HueGroup: "Closet", HASSAreaID: "closet"},
+			{HueGroup: "Pantry", HASSAreaID: "pantry", OnBudget: &OnBudget{DailyMinutes: 10}},
+		},
+	}))
+
+	budgets := manager.GetShadowState().Outputs.OnBudgets
+	assert.NotContains(t, budgets, "Closet")
+	assert.Contains(t, budgets, "Pantry")
+
+	// The removed budget is no longer enforced
+	mockClient.SimulateStateChange("light.closet", "on")
+	mockClient.SimulateStateChange("light.pantry", "on")
+	mockClock.Advance(45 * time.Minute)
+	manager.checkBudgets()
+
+	var turnedOff []interface{}
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "light" && call.Service == "turn_off" {
+			turnedOff = append(turnedOff, call.Data["entity_id"])
+		}
+	}
+	assert.Len(t, turnedOff, 1)
+	assert.True(t, manager.GetShadowState().Outputs.OnBudgets["Pantry"].Exhausted)
+
+	// Restoring the budget tracks the room again
+	require.NoError(t, manager.Reload(&HueConfig{
+		Rooms: []RoomConfig{
+			{HueGroup: "Closet", HASSAreaID: "closet", OnBudget: &OnBudget{DailyMinutes: 30}},
+		},
+	}))
+	assert.Contains(t, manager.GetShadowState().Outputs.OnBudgets, "Closet")
+	assert.Error(t, manager.Reload(nil))
+}
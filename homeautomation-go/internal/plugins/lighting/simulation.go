@@ -0,0 +1,299 @@
+package lighting
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/simulation.html
+var simulationHTML string
+
+var simulationTemplate = template.Must(template.New("simulation").Parse(simulationHTML))
+
+// Scenario describes virtual rooms and a scripted sequence of state changes
+// to run the lighting manager against without touching real lights
+type Scenario struct {
+	Name    string                 `yaml:"name"`
+	Rooms   []RoomConfig           `yaml:"rooms"`   // Optional, defaults to the rooms in hue_config.yaml
+	Initial map[string]interface{} `yaml:"initial"` // State variable values before the manager starts
+	Steps   []ScenarioStep         `yaml:"steps"`
+}
+
+// ScenarioStep is one scripted change to state variables
+type ScenarioStep struct {
+	At          string                 `yaml:"at"` // Optional label, e.g. "19:30"
+	Description string                 `yaml:"description"`
+	Set         map[string]interface{} `yaml:"set"`
+}
+
+// SceneDecision is a scene activation or turn-off made by the manager
+type SceneDecision struct {
+	Room    string `json:"room"`
+	Action  string `json:"action"` // "activate_scene" or "turn_off"
+	Scene   string `json:"scene,omitempty"`
+	Trigger string `json:"trigger"`
+	Reason  string `json:"reason"`
+}
+
+// StepResult records the decisions made in response to one scenario step
+type StepResult struct {
+	Index       int                    `json:"index"`
+	At          string                 `json:"at,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Changes     map[string]interface{} `json:"changes"`
+	Decisions   []SceneDecision        `json:"decisions"`
+	// RoomStates is each room's scene (or "off") after the step; rooms the
+	// manager hasn't touched yet are omitted
+	RoomStates map[string]string `json:"roomStates"`
+}
+
+// Timeline is the result of running a scenario
+type Timeline struct {
+	Scenario string       `json:"scenario"`
+	Rooms    []string     `json:"rooms"`
+	Steps    []StepResult `json:"steps"`
+}
+
+// LoadScenario loads a simulation scenario from a YAML file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, err
+	}
+
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &scenario, nil
+}
+
+// Validate checks that every scripted value refers to a known state variable
+// of the right type
+func (s *Scenario) Validate() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario has no steps")
+	}
+
+	variables := state.VariablesByKey()
+	check := func(where string, values map[string]interface{}) error {
+		for key, value := range values {
+			variable, ok := variables[key]
+			if !ok {
+				return fmt.Errorf("%s: unknown state variable %q", where, key)
+			}
+			if _, err := convertValue(variable, value); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+		}
+		return nil
+	}
+
+	if err := check("initial", s.Initial); err != nil {
+		return err
+	}
+	for i, step := range s.Steps {
+		if len(step.Set) == 0 {
+			return fmt.Errorf("steps[%d]: set is required", i)
+		}
+		if err := check(fmt.Sprintf("steps[%d]", i), step.Set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Simulate runs the lighting manager against the scenario's rooms using a mock
+// Home Assistant client and returns the scene decisions made at each step.
+// If the scenario defines no rooms, the given rooms are used instead.
+func Simulate(scenario *Scenario, rooms []RoomConfig, logger *zap.Logger) (*Timeline, error) {
+	if len(scenario.Rooms) > 0 {
+		rooms = scenario.Rooms
+	}
+	if len(rooms) == 0 {
+		return nil, fmt.Errorf("no rooms to simulate")
+	}
+
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	if err := applyValues(stateManager, scenario.Initial); err != nil {
+		return nil, fmt.Errorf("initial: %w", err)
+	}
+
+	var decisions []SceneDecision
+	manager := NewManager(mockHA, stateManager, &HueConfig{Rooms: rooms}, logger, false, nil)
+	manager.onDecision = func(d SceneDecision) {
+		decisions = append(decisions, d)
+	}
+
+	if err := manager.Start(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to start lighting manager: %w", err)
+	}
+	defer manager.Stop()
+
+	timeline := &Timeline{
+		Scenario: scenario.Name,
+		Rooms:    make([]string, 0, len(rooms)),
+		Steps:    make([]StepResult, 0, len(scenario.Steps)),
+	}
+	for _, room := range rooms {
+		timeline.Rooms = append(timeline.Rooms, room.HueGroup)
+	}
+
+	roomStates := make(map[string]string)
+	for i, step := range scenario.Steps {
+		decisions = nil
+		if err := applyValues(stateManager, step.Set); err != nil {
+			return nil, fmt.Errorf("steps[%d]: %w", i, err)
+		}
+
+		for _, d := range decisions {
+			if d.Action == "turn_off" {
+				roomStates[d.Room] = "off"
+			} else {
+				roomStates[d.Room] = d.Scene
+			}
+		}
+
+		result := StepResult{
+			Index:       i,
+			At:          step.At,
+			Description: step.Description,
+			Changes:     step.Set,
+			Decisions:   append([]SceneDecision{}, decisions...),
+			RoomStates:  make(map[string]string, len(roomStates)),
+		}
+		for room, scene := range roomStates {
+			result.RoomStates[room] = scene
+		}
+		timeline.Steps = append(timeline.Steps, result)
+	}
+
+	return timeline, nil
+}
+
+// WriteJSON writes the timeline as indented JSON
+func (t *Timeline) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// WriteHTML writes the timeline as a standalone HTML page with one column per room
+func (t *Timeline) WriteHTML(w io.Writer) error {
+	type cell struct {
+		State   string
+		Changed bool
+		Reason  string
+	}
+	type row struct {
+		StepResult
+		ChangeList []string
+		Cells      []cell
+	}
+
+	rows := make([]row, 0, len(t.Steps))
+	for _, step := range t.Steps {
+		r := row{StepResult: step}
+
+		for _, key := range sortedKeys(step.Changes) {
+			r.ChangeList = append(r.ChangeList, fmt.Sprintf("%s = %v", key, step.Changes[key]))
+		}
+
+		changed := make(map[string]SceneDecision)
+		for _, d := range step.Decisions {
+			changed[d.Room] = d
+		}
+		for _, room := range t.Rooms {
+			d, ok := changed[room]
+			r.Cells = append(r.Cells, cell{State: step.RoomStates[room], Changed: ok, Reason: d.Reason})
+		}
+		rows = append(rows, r)
+	}
+
+	return simulationTemplate.Execute(w, struct {
+		Scenario string
+		Rooms    []string
+		Rows     []row
+	}{t.Scenario, t.Rooms, rows})
+}
+
+// applyValues sets state variables in key order so runs are deterministic
+func applyValues(stateManager *state.Manager, values map[string]interface{}) error {
+	variables := state.VariablesByKey()
+	for _, key := range sortedKeys(values) {
+		variable, ok := variables[key]
+		if !ok {
+			return fmt.Errorf("unknown state variable %q", key)
+		}
+		value, err := convertValue(variable, values[key])
+		if err != nil {
+			return err
+		}
+
+		switch v := value.(type) {
+		case bool:
+			err = stateManager.SetBool(key, v)
+		case string:
+			err = stateManager.SetString(key, v)
+		case float64:
+			err = stateManager.SetNumber(key, v)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// convertValue converts a YAML value to the Go type of the state variable
+func convertValue(variable state.StateVariable, value interface{}) (interface{}, error) {
+	switch variable.Type {
+	case state.TypeBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case state.TypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case state.TypeNumber:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	default:
+		return nil, fmt.Errorf("%s: %s variables are not supported", variable.Key, variable.Type)
+	}
+	return nil, fmt.Errorf("%s: expected %s value, got %T", variable.Key, variable.Type, value)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
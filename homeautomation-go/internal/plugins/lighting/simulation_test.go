@@ -0,0 +1,132 @@
+package lighting
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario("testdata/simulation_scenario.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Evening in the office and kitchen", scenario.Name)
+	assert.Len(t, scenario.Rooms, 2)
+	assert.Equal(t, "day", scenario.Initial["dayPhase"])
+	require.Len(t, scenario.Steps, 5)
+	assert.Equal(t, "17:00", scenario.Steps[0].At)
+	assert.Equal(t, true, scenario.Steps[0].Set["isNickOfficeOccupied"])
+}
+
+func TestScenarioValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"no steps", "name: empty\n", "no steps"},
+		{"empty set", "steps:\n  - at: \"10:00\"\n", "set is required"},
+		{"unknown variable", "steps:\n  - set:\n      isNotAThing: true\n", "unknown state variable"},
+		{"wrong type", "steps:\n  - set:\n      dayPhase: true\n", "expected string"},
+		{"bad initial", "initial:\n  isAnyoneHome: yes please\nsteps:\n  - set:\n      dayPhase: day\n", "initial"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0644))
+
+			_, err := LoadScenario(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	scenario, err := LoadScenario("testdata/simulation_scenario.yaml")
+	require.NoError(t, err)
+
+	timeline, err := Simulate(scenario, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"N Office", "Kitchen"}, timeline.Rooms)
+	require.Len(t, timeline.Steps, 5)
+
+	// Office occupied: only the office turns on
+	step := timeline.Steps[0]
+	require.Len(t, step.Decisions, 1)
+	assert.Equal(t, SceneDecision{
+		Room:    "N Office",
+		Action:  "activate_scene",
+		Scene:   "day",
+		Trigger: "isNickOfficeOccupied",
+		Reason:  "Activated scene 'day'",
+	}, step.Decisions[0])
+	assert.Equal(t, map[string]string{"N Office": "day"}, step.RoomStates)
+
+	// Kitchen occupied: kitchen turns on, office carries over
+	step = timeline.Steps[1]
+	require.Len(t, step.Decisions, 1)
+	assert.Equal(t, "Kitchen", step.Decisions[0].Room)
+	assert.Equal(t, map[string]string{"N Office": "day", "Kitchen": "day"}, step.RoomStates)
+
+	// Day phase change re-evaluates every room
+	step = timeline.Steps[2]
+	assert.Len(t, step.Decisions, 2)
+	assert.Equal(t, map[string]string{"N Office": "evening", "Kitchen": "evening"}, step.RoomStates)
+
+	// Leaving the office turns it off
+	step = timeline.Steps[3]
+	require.Len(t, step.Decisions, 1)
+	assert.Equal(t, "turn_off", step.Decisions[0].Action)
+	assert.Equal(t, "off", step.RoomStates["N Office"])
+
+	// Bedtime: kitchen still occupied, so on_if_true takes precedence over off_if_false
+	step = timeline.Steps[4]
+	require.Len(t, step.Decisions, 1)
+	assert.Equal(t, "Kitchen", step.Decisions[0].Room)
+	assert.Equal(t, "activate_scene", step.Decisions[0].Action)
+}
+
+func TestSimulate_UsesFallbackRooms(t *testing.T) {
+	scenario := &Scenario{
+		Steps: []ScenarioStep{{Set: map[string]interface{}{"dayPhase": "morning"}}},
+	}
+	rooms := createOccupancyTestConfig().Rooms
+
+	timeline, err := Simulate(scenario, rooms, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"N Office", "Kitchen"}, timeline.Rooms)
+
+	_, err = Simulate(scenario, nil, zap.NewNop())
+	assert.Error(t, err, "Should fail with no rooms to simulate")
+}
+
+func TestTimelineOutput(t *testing.T) {
+	scenario, err := LoadScenario("testdata/simulation_scenario.yaml")
+	require.NoError(t, err)
+	timeline, err := Simulate(scenario, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, timeline.WriteJSON(&jsonBuf))
+	var decoded Timeline
+	require.NoError(t, json.Unmarshal(jsonBuf.Bytes(), &decoded))
+	assert.Len(t, decoded.Steps, 5)
+
+	var htmlBuf bytes.Buffer
+	require.NoError(t, timeline.WriteHTML(&htmlBuf))
+	html := htmlBuf.String()
+	assert.True(t, strings.HasPrefix(html, "<!DOCTYPE html>"))
+	assert.Contains(t, html, "<th>N Office</th>")
+	assert.Contains(t, html, "isNickOfficeOccupied = true")
+	assert.Contains(t, html, `title="Turned off room"`)
+}
@@ -0,0 +1,195 @@
+package lighting
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// fadeJobName is the scheduler job running a room's next fade step
+func fadeJobName(room *RoomConfig) string {
+	return "lighting/fade/" + toSnakeCase(room.HueGroup)
+}
+
+// fade is a multi-step fade into a day phase's scene in progress. The room is
+// copied so a config reload mid-fade doesn't change it.
+type fade struct {
+	room     RoomConfig
+	dayPhase string
+	trigger  string
+
+	step         int // The step to run next, from 1
+	steps        int
+	from         float64 // Brightness (0-255) when the fade started
+	to           float64 // The scene's brightness, capped during a grid outage or scaled to ambient light
+	stepDuration time.Duration
+	stepSeconds  int // stepDuration as a light transition
+}
+
+// brightnessAt returns the brightness the room reaches at the end of a step
+func (f *fade) brightnessAt(step int) int {
+	return int(math.Round(f.from + (f.to-f.from)*float64(step)/float64(f.steps)))
+}
+
+// fadeIntoScene activates a room's scene over the configured fade. A single
+// step is one scene transition; more steps ramp the brightness on the
+// scheduler and activate the scene for the last step. When either end's
+// brightness is unknown the fade falls back to a single scene transition.
+func (m *Manager) fadeIntoScene(room *RoomConfig, dayPhase string, trigger string, transition PhaseTransition) {
+	steps := transition.StepsOrDefault()
+	if steps == 1 {
+		seconds := transition.Minutes * 60
+		m.turnOnScene(room, dayPhase, trigger, &seconds)
+		return
+	}
+
+	from, fromOK := m.currentBrightness(room)
+	_, values := m.resolveScene(roomSceneEntityID(room, dayPhase))
+	to, toOK := brightnessValue(values["brightness"])
+	if !fromOK || !toOK {
+		m.logger.Info("Room or scene brightness unknown, fading in a single transition",
+			zap.String("room", room.HueGroup),
+			zap.String("scene", dayPhase))
+		seconds := transition.Minutes * 60
+		m.turnOnScene(room, dayPhase, trigger, &seconds)
+		return
+	}
+
+	capPct := m.outageBrightnessCap()
+	if capPct > 0 {
+		to = math.Min(to, float64(capPct)*255/100)
+	} else if adaptive, ok := m.adaptiveBrightness(room, values); ok {
+		to = float64(adaptive.brightness)
+	}
+
+	f := &fade{
+		room:         *room,
+		dayPhase:     dayPhase,
+		trigger:      trigger,
+		step:         1,
+		steps:        steps,
+		from:         from,
+		to:           to,
+		stepDuration: transition.StepDuration(),
+		stepSeconds:  int(transition.StepDuration().Seconds()),
+	}
+
+	detail := shadowstate.RoomActionDetail{
+		Service:          "light.turn_on",
+		ServiceData:      f.stepServiceData(1),
+		SceneEntityID:    roomSceneEntityID(room, dayPhase),
+		BrightnessCapPct: capPct,
+		Conditions:       m.conditionEvaluations(room),
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would fade into scene",
+			zap.String("room", room.HueGroup),
+			zap.String("scene", dayPhase),
+			zap.Int("minutes", transition.Minutes),
+			zap.Int("steps", steps))
+		m.recordAction(room.HueGroup, "activate_scene",
+			fadeReason("Would fade", dayPhase, transition), dayPhase, false, trigger, detail)
+		return
+	}
+
+	m.logger.Info("Fading into scene",
+		zap.String("room", room.HueGroup),
+		zap.String("scene", dayPhase),
+		zap.Int("minutes", transition.Minutes),
+		zap.Int("steps", steps),
+		zap.Float64("from_brightness", from),
+		zap.Float64("to_brightness", to))
+	m.recordAction(room.HueGroup, "activate_scene",
+		fadeReason("Fading", dayPhase, transition), dayPhase, false, trigger, detail)
+
+	m.runFadeStep(f)
+}
+
+// runFadeStep runs the fade's next step and schedules the one after it. The
+// last step activates the scene itself so the room ends on the scene's
+// colors, not just its brightness.
+func (m *Manager) runFadeStep(f *fade) {
+	if f.step >= f.steps {
+		m.turnOnScene(&f.room, f.dayPhase, f.trigger, &f.stepSeconds)
+		return
+	}
+
+	m.markOwnCommand(&f.room, &f.stepSeconds)
+	if err := m.haClient.CallService(m.ctx, "light", "turn_on", f.stepServiceData(f.step)); err != nil {
+		m.logger.Error("Failed to run fade step, activating the scene instead",
+			zap.String("room", f.room.HueGroup),
+			zap.Int("step", f.step),
+			zap.Error(err))
+		m.turnOnScene(&f.room, f.dayPhase, f.trigger, &f.stepSeconds)
+		return
+	}
+
+	next := *f
+	next.step++
+	at := m.clock.Now().Add(f.stepDuration)
+	if err := m.scheduler.Once(fadeJobName(&f.room), at, func() { m.runFadeStep(&next) }); err != nil {
+		m.logger.Error("Failed to schedule fade step",
+			zap.String("room", f.room.HueGroup),
+			zap.Error(err))
+	}
+}
+
+// fadeReason describes the start of a multi-step fade for the shadow state
+func fadeReason(verb string, dayPhase string, transition PhaseTransition) string {
+	return fmt.Sprintf("%s into scene '%s' over %d minutes in %d steps",
+		verb, dayPhase, transition.Minutes, transition.StepsOrDefault())
+}
+
+// stepServiceData is the light.turn_on call ramping the room's brightness
+// for a step
+func (f *fade) stepServiceData(step int) map[string]interface{} {
+	return map[string]interface{}{
+		"area_id":    f.room.HASSAreaID,
+		"brightness": f.brightnessAt(step),
+		"transition": f.stepSeconds,
+	}
+}
+
+// cancelFade stops a room's fade in progress, if any
+func (m *Manager) cancelFade(room *RoomConfig) {
+	if m.scheduler.Cancel(fadeJobName(room)) {
+		m.logger.Debug("Cancelled fade in progress", zap.String("room", room.HueGroup))
+	}
+}
+
+// cancelFades stops every room's fade in progress
+func (m *Manager) cancelFades() {
+	for _, room := range m.currentConfig().Rooms {
+		m.cancelFade(&room)
+	}
+}
+
+// currentBrightness reads the brightness (0-255) of the room's group light,
+// 0 while it is off
+func (m *Manager) currentBrightness(room *RoomConfig) (float64, bool) {
+	st, err := m.haClient.GetState(m.ctx, room.OnBudgetLightEntity())
+	if err != nil || st == nil {
+		return 0, false
+	}
+	if st.State == "off" {
+		return 0, true
+	}
+	return brightnessValue(st.Attributes["brightness"])
+}
+
+// brightnessValue converts a brightness attribute to a number
+func brightnessValue(v interface{}) (float64, bool) {
+	switch b := v.(type) {
+	case float64:
+		return b, true
+	case int:
+		return float64(b), true
+	default:
+		return 0, false
+	}
+}
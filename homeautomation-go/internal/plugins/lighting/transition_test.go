@@ -0,0 +1,228 @@
+package lighting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newFadeTestManager starts a manager with a living room that is on while
+// someone is awake, stepping fades on a scheduler driven by the returned clock
+func newFadeTestManager(t *testing.T, config *HueConfig) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetString("dayPhase", "dusk"))
+	require.NoError(t, stateManager.SetBool("isAnyoneHomeAndAwake", true))
+
+	mockClock := clock.NewMockClock(time.Date(2024, 3, 10, 21, 0, 0, 0, time.UTC))
+	jobs := scheduler.New(logger, time.UTC)
+	jobs.SetClock(mockClock)
+	t.Cleanup(jobs.Stop)
+
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	manager.SetClock(mockClock)
+	manager.SetScheduler(jobs)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockClient.ClearServiceCalls()
+	return manager, mockClient, stateManager, mockClock
+}
+
+func fadeTestConfig(transition PhaseTransition) *HueConfig {
+	return &HueConfig{
+		DayPhaseTransitions: map[string]PhaseTransition{"winddown": transition},
+		Rooms: []RoomConfig{
+			{HueGroup: "Living Room", HASSAreaID: "living_room", OnIfTrue: "isAnyoneHomeAndAwake", OffIfFalse: "isAnyoneHomeAndAwake"},
+		},
+	}
+}
+
+// seedBrightness sets the living room's current brightness and its winddown scene's brightness
+func seedBrightness(mockClient *ha.MockClient, current, scene float64) {
+	mockClient.SetMockState("light.living_room", &ha.State{
+		EntityID:   "light.living_room",
+		State:      "on",
+		Attributes: map[string]interface{}{"brightness": current},
+	})
+	mockClient.SetMockState("scene.living_room_winddown", &ha.State{
+		EntityID:   "scene.living_room_winddown",
+		State:      "scening",
+		Attributes: map[string]interface{}{"brightness": scene},
+	})
+}
+
+// lightingCalls returns the light and scene calls, leaving out state variable writes
+func lightingCalls(mockClient *ha.MockClient) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "light" || call.Domain == "scene" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestPhaseTransitionFor(t *testing.T) {
+	config := &HueConfig{
+		DayPhaseTransitions: map[string]PhaseTransition{"winddown": {Minutes: 10}},
+	}
+	room := &RoomConfig{
+		HueGroup:            "Primary Suite",
+		DayPhaseTransitions: map[string]PhaseTransition{"winddown": {Minutes: 20, Steps: 4}},
+	}
+
+	transition, ok := config.PhaseTransitionFor(room, "winddown")
+	assert.True(t, ok)
+	assert.Equal(t, PhaseTransition{Minutes: 20, Steps: 4}, transition, "room overrides the default")
+	assert.Equal(t, 5*time.Minute, transition.StepDuration())
+
+	transition, ok = config.PhaseTransitionFor(&RoomConfig{HueGroup: "Kitchen"}, "winddown")
+	assert.True(t, ok)
+	assert.Equal(t, 1, transition.StepsOrDefault())
+
+	_, ok = config.PhaseTransitionFor(room, "night")
+	assert.False(t, ok)
+}
+
+func TestLoadConfig_PhaseTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: "day_phase_transitions:\n  winddown: {minutes: 10, steps: 5}\nrooms:\n  - hue_group: Kitchen\n    day_phase_transitions:\n      night: {minutes: 2}\n",
+		},
+		{
+			name:    "missing minutes",
+			yaml:    "day_phase_transitions:\n  winddown: {steps: 5}\n",
+			wantErr: "day_phase_transitions.winddown: minutes must be positive",
+		},
+		{
+			name:    "steps too short",
+			yaml:    "rooms:\n  - hue_group: Kitchen\n    day_phase_transitions:\n      night: {minutes: 1, steps: 120}\n",
+			wantErr: `room "Kitchen" day_phase_transitions.night: each of the 120 steps must last at least a second`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hue_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDayPhaseFade_SingleStep(t *testing.T) {
+	_, mockClient, stateManager, _ := newFadeTestManager(t, fadeTestConfig(PhaseTransition{Minutes: 10}))
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene", calls[0].Domain)
+	assert.Equal(t, "scene.living_room_winddown", calls[0].Data["entity_id"])
+	assert.Equal(t, 600, calls[0].Data["transition"])
+}
+
+func TestDayPhaseFade_MultiStep(t *testing.T) {
+	manager, mockClient, stateManager, mockClock := newFadeTestManager(t, fadeTestConfig(PhaseTransition{Minutes: 8, Steps: 4}))
+	seedBrightness(mockClient, 200, 40)
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	// The first step starts straight away
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "light", calls[0].Domain)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, 160, calls[0].Data["brightness"])
+	assert.Equal(t, 120, calls[0].Data["transition"])
+
+	room := manager.GetShadowState().Outputs.Rooms["Living Room"]
+	assert.Equal(t, "winddown", room.ActiveScene)
+	assert.Contains(t, room.Reason, "over 8 minutes in 4 steps")
+
+	// Each following step runs once the previous one has finished
+	mockClock.Advance(2 * time.Minute)
+	mockClock.Advance(2 * time.Minute)
+	calls = lightingCalls(mockClient)
+	require.Len(t, calls, 3)
+	assert.Equal(t, 120, calls[1].Data["brightness"])
+	assert.Equal(t, 80, calls[2].Data["brightness"])
+
+	// The last step activates the scene itself
+	mockClock.Advance(2 * time.Minute)
+	calls = lightingCalls(mockClient)
+	require.Len(t, calls, 4)
+	assert.Equal(t, "scene", calls[3].Domain)
+	assert.Equal(t, "scene.living_room_winddown", calls[3].Data["entity_id"])
+	assert.Equal(t, 120, calls[3].Data["transition"])
+
+	_, pending := manager.scheduler.Next(fadeJobName(&manager.currentConfig().Rooms[0]))
+	assert.False(t, pending, "fade finished")
+}
+
+func TestDayPhaseFade_CancelledByTurnOff(t *testing.T) {
+	manager, mockClient, stateManager, mockClock := newFadeTestManager(t, fadeTestConfig(PhaseTransition{Minutes: 8, Steps: 4}))
+	seedBrightness(mockClient, 200, 40)
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+	require.NoError(t, stateManager.SetBool("isAnyoneHomeAndAwake", false))
+	mockClient.ClearServiceCalls()
+
+	_, pending := manager.scheduler.Next(fadeJobName(&manager.currentConfig().Rooms[0]))
+	assert.False(t, pending, "turning the room off cancels the fade")
+
+	mockClock.Advance(10 * time.Minute)
+	assert.Empty(t, lightingCalls(mockClient))
+}
+
+func TestDayPhaseFade_UnknownBrightness(t *testing.T) {
+	_, mockClient, stateManager, _ := newFadeTestManager(t, fadeTestConfig(PhaseTransition{Minutes: 8, Steps: 4}))
+
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene", calls[0].Domain, "falls back to a single scene transition")
+	assert.Equal(t, 480, calls[0].Data["transition"])
+}
+
+func TestDayPhaseFade_OnlyOnDayPhaseChange(t *testing.T) {
+	_, mockClient, stateManager, _ := newFadeTestManager(t, fadeTestConfig(PhaseTransition{Minutes: 10}))
+	require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+	require.NoError(t, stateManager.SetBool("isAnyoneHomeAndAwake", false))
+	mockClient.ClearServiceCalls()
+
+	// Someone waking up during winddown gets the scene without the fade
+	require.NoError(t, stateManager.SetBool("isAnyoneHomeAndAwake", true))
+
+	calls := lightingCalls(mockClient)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene", calls[0].Domain)
+	assert.NotContains(t, calls[0].Data, "transition")
+}
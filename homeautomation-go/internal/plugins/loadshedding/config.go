@@ -0,0 +1,132 @@
+package loadshedding
+
+import (
+	"fmt"
+	"os"
+
+	"homeautomation/internal/entities"
+	"homeautomation/internal/ha"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tiers a device can be shed in: tier 3 is shed first and restored last,
+// tier 1 is shed last and restored first
+const (
+	MinTier = 1
+	MaxTier = 3
+)
+
+// Restore rules for shed devices
+const (
+	RestorePrevious = "previous" // Return to the state it was shed from (default)
+	RestoreManual   = "manual"   // Left shed until someone turns it back on
+)
+
+// defaultShedMode is the HVAC or operation mode climate and water heater
+// devices are shed to
+const defaultShedMode = "off"
+
+// Device is a load shed by tier
+type Device struct {
+	EntityID string `yaml:"entity_id"` // switch.*, input_boolean.*, climate.* or water_heater.*
+	Tier     int    `yaml:"tier"`
+	ShedMode string `yaml:"shed_mode"` // Climate HVAC mode or water heater operation mode to shed to, default "off"
+	Restore  string `yaml:"restore"`   // "previous" (default) or "manual"
+}
+
+// Domain returns the device's Home Assistant domain
+func (d *Device) Domain() string {
+	return ha.EntityDomain(d.EntityID)
+}
+
+// ShedModeOrDefault returns the mode a climate or water heater device is shed to
+func (d *Device) ShedModeOrDefault() string {
+	if d.ShedMode == "" {
+		return defaultShedMode
+	}
+	return d.ShedMode
+}
+
+// RestoreOrDefault returns the device's restore rule
+func (d *Device) RestoreOrDefault() string {
+	if d.Restore == "" {
+		return RestorePrevious
+	}
+	return d.Restore
+}
+
+// LoadSheddingSettings lists the tiered devices and the tiers shed at each
+// energy level
+type LoadSheddingSettings struct {
+	// Energy level -> lowest tier shed there; that tier and every higher one
+	// are shed. Levels not listed restore every tier, except yellow, which
+	// holds the current tiers unless listed.
+	ShedFromTier map[string]int `yaml:"shed_from_tier"`
+	Devices      []Device       `yaml:"devices"`
+}
+
+// LoadSheddingConfig represents the loadshedding_config.yaml structure
+type LoadSheddingConfig struct {
+	LoadShedding LoadSheddingSettings `yaml:"load_shedding"`
+}
+
+// Validate checks the energy levels, tiers and devices
+func (c *LoadSheddingConfig) Validate() error {
+	settings := &c.LoadShedding
+	levels := map[string]bool{energyStateBlack: true, energyStateRed: true, energyStateYellow: true, energyStateGreen: true, energyStateWhite: true}
+	for level, tier := range settings.ShedFromTier {
+		if !levels[level] {
+			return fmt.Errorf("shed_from_tier: unknown energy level %q", level)
+		}
+		if tier < MinTier || tier > MaxTier {
+			return fmt.Errorf("shed_from_tier.%s must be between %d and %d", level, MinTier, MaxTier)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, d := range settings.Devices {
+		switch d.Domain() {
+		case "switch", "input_boolean", "climate", "water_heater":
+		default:
+			return fmt.Errorf("devices[%d]: entity_id must be a switch, input_boolean, climate or water_heater, got %q", i, d.EntityID)
+		}
+		if d.EntityID == entities.Default(entities.HouseThermostat) || d.EntityID == entities.Default(entities.SuiteThermostat) {
+			return fmt.Errorf("devices[%d]: %s is already restricted by the thermostat hold", i, d.EntityID)
+		}
+		if seen[d.EntityID] {
+			return fmt.Errorf("devices[%d]: duplicate entity_id %q", i, d.EntityID)
+		}
+		seen[d.EntityID] = true
+
+		if d.Tier < MinTier || d.Tier > MaxTier {
+			return fmt.Errorf("devices[%d]: tier must be between %d and %d", i, MinTier, MaxTier)
+		}
+		if d.ShedMode != "" && d.Domain() != "climate" && d.Domain() != "water_heater" {
+			return fmt.Errorf("devices[%d]: shed_mode only applies to climate and water_heater devices", i)
+		}
+		if restore := d.RestoreOrDefault(); restore != RestorePrevious && restore != RestoreManual {
+			return fmt.Errorf("devices[%d]: restore must be %q or %q, got %q", i, RestorePrevious, RestoreManual, d.Restore)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the load shedding configuration from a YAML file
+func LoadConfig(path string) (*LoadSheddingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config LoadSheddingConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -1,11 +1,15 @@
 package loadshedding
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
+	"homeautomation/internal/entities"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/kidmode"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -23,12 +27,6 @@ const (
 	energyStateGreen  = "green"
 	energyStateWhite  = "white"
 
-	// Thermostat entities
-	thermostatHoldHouse = "switch.most_of_house_thermostat_hold"
-	thermostatHoldSuite = "switch.primary_suite_thermostat_hold"
-	climateHouse        = "climate.most_of_house_thermostat"
-	climateSuite        = "climate.primary_suite_thermostat"
-
 	// Temperature ranges
 	tempLowRestricted  = 65.0
 	tempHighRestricted = 80.0
@@ -47,23 +45,47 @@ type Manager struct {
 	loadSheddingOn bool
 	stateMu        sync.Mutex
 	shadowTracker  *shadowstate.LoadSheddingTracker
+	kid            *kidmode.Guard     // Thermostats held by kid mode keep their setpoint, nil if not configured
+	entities       *entities.Registry // Thermostats and their hold switches, nil uses the defaults
+
+	// Tiered devices shed alongside the thermostat, nil if not configured.
+	// Shed devices are keyed by entity ID (protected by tiersMu).
+	config    *LoadSheddingConfig
+	shed      map[string]shadowstate.ShedDevice
+	shedOrder []string
+	tiersMu   sync.Mutex
 
 	// Automatic shadow state input tracking
 	pluginName  string
 	registry    *shadowstate.SubscriptionRegistry
 	inputHelper *shadowstate.InputCaptureHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new Load Shedding manager
 func NewManager(haClient ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
 	const pluginName = "loadshedding"
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
 	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
 		haClient:      haClient,
 		stateManager:  stateManager,
-		logger:        logger.Named("loadshedding"),
+		logger:        recorder.Logger(logger.Named("loadshedding")),
+		health:        recorder,
 		readOnly:      readOnly,
 		enabled:       false,
 		shadowTracker: shadowstate.NewLoadSheddingTracker(),
+		shed:          make(map[string]shadowstate.ShedDevice),
 		pluginName:    pluginName,
 		registry:      registry,
 	}
@@ -76,8 +98,50 @@ func NewManager(haClient ha.HAClient, stateManager *state.Manager, logger *zap.L
 	return m
 }
 
+// SetKidMode sets the kid mode guard. Thermostats it holds are left out of
+// the wider temperature range; the hold switches are still turned on.
+func (m *Manager) SetKidMode(guard *kidmode.Guard) {
+	m.kid = guard
+}
+
+// SetEntities sets the registry the thermostats and their hold switches are
+// looked up in
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
+// holdSwitches returns the thermostat hold switches turned on while HVAC is restricted
+func (m *Manager) holdSwitches() []string {
+	return []string{m.entities.HouseThermostatHold(), m.entities.SuiteThermostatHold()}
+}
+
+// thermostats returns the thermostats given the wider range while HVAC is restricted
+func (m *Manager) thermostats() []string {
+	return []string{m.entities.HouseThermostat(), m.entities.SuiteThermostat()}
+}
+
+// Sheds reports whether load shedding currently owns a device: the
+// thermostats while HVAC is restricted, and any tiered device it has shed
+func (m *Manager) Sheds(entityID string) bool {
+	m.stateMu.Lock()
+	restricted := m.loadSheddingOn
+	m.stateMu.Unlock()
+	if restricted && (entityID == m.entities.HouseThermostat() || entityID == m.entities.SuiteThermostat()) {
+		return true
+	}
+
+	m.tiersMu.Lock()
+	defer m.tiersMu.Unlock()
+	_, shed := m.shed[entityID]
+	return shed
+}
+
 // Start begins monitoring energy state and controlling thermostats
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
+	// Shedding and restoring run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	if m.enabled {
 		return fmt.Errorf("load shedding already started")
 	}
@@ -106,12 +170,16 @@ func (m *Manager) Start() error {
 	}
 
 	m.enabled = true
+	m.health.Started(1)
 	m.logger.Info("Load Shedding Manager started successfully")
 	return nil
 }
 
 // Stop stops the Load Shedding Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.cancel()
+
 	if !m.enabled {
 		return
 	}
@@ -125,6 +193,11 @@ func (m *Manager) Stop() {
 	m.logger.Info("Load Shedding Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handleEnergyChange is called when currentEnergyLevel changes
 func (m *Manager) handleEnergyChange(key string, oldValue, newValue interface{}) {
 	m.handleEnergyChangeWithTrigger(key, oldValue, newValue, key)
@@ -169,6 +242,9 @@ func (m *Manager) handleEnergyChangeWithTrigger(key string, oldValue, newValue i
 		m.logger.Warn("Unknown energy state",
 			zap.String("state", newLevel))
 	}
+
+	m.applyTiers(newLevel)
+	m.publishPlan(newLevel)
 }
 
 // enableLoadShedding activates load shedding (energy state red/black)
@@ -211,7 +287,7 @@ func (m *Manager) enableLoadShedding(energyLevel string, trigger string) {
 
 	if m.readOnly {
 		m.logger.Info("READ-ONLY: Would enable thermostat hold mode",
-			zap.Strings("entities", []string{thermostatHoldHouse, thermostatHoldSuite}))
+			zap.Strings("entities", m.holdSwitches()))
 		// Record shadow state even in read-only mode for consistency
 		reason := fmt.Sprintf("Energy state is %s (low battery) - would restrict HVAC", energyLevel)
 		m.recordAction(true, "enable", reason, true, tempLowRestricted, tempHighRestricted, trigger)
@@ -220,10 +296,10 @@ func (m *Manager) enableLoadShedding(energyLevel string, trigger string) {
 
 	// Turn on thermostat hold mode
 	m.logger.Info("Executing: Enable thermostat hold mode",
-		zap.Strings("entities", []string{thermostatHoldHouse, thermostatHoldSuite}))
+		zap.Strings("entities", m.holdSwitches()))
 
-	if err := m.haClient.CallService("switch", "turn_on", map[string]interface{}{
-		"entity_id": []string{thermostatHoldHouse, thermostatHoldSuite},
+	if err := m.haClient.CallService(m.ctx, "switch", "turn_on", map[string]interface{}{
+		"entity_id": m.holdSwitches(),
 	}); err != nil {
 		m.logger.Error("Failed to enable thermostat hold mode",
 			zap.Error(err))
@@ -233,22 +309,34 @@ func (m *Manager) enableLoadShedding(energyLevel string, trigger string) {
 	m.logger.Info("✓ Successfully enabled thermostat hold mode")
 
 	// Set wider temperature range
-	m.logger.Info("Executing: Set wider temperature range",
-		zap.Float64("temp_low", tempLowRestricted),
-		zap.Float64("temp_high", tempHighRestricted),
-		zap.Strings("entities", []string{climateHouse, climateSuite}))
-
-	if err := m.haClient.CallService("climate", "set_temperature", map[string]interface{}{
-		"entity_id":        []string{climateHouse, climateSuite},
-		"target_temp_low":  tempLowRestricted,
-		"target_temp_high": tempHighRestricted,
-	}); err != nil {
-		m.logger.Error("Failed to set thermostat temperature range",
-			zap.Error(err))
-		return
+	var climates []string
+	for _, entityID := range m.thermostats() {
+		if m.kid.LocksThermostat(entityID) {
+			m.logger.Info("⏭  Leaving thermostat setpoint alone: held by kid mode",
+				zap.String("entity_id", entityID))
+			continue
+		}
+		climates = append(climates, entityID)
 	}
 
-	m.logger.Info("✓ Successfully set wider temperature range")
+	if len(climates) > 0 {
+		m.logger.Info("Executing: Set wider temperature range",
+			zap.Float64("temp_low", tempLowRestricted),
+			zap.Float64("temp_high", tempHighRestricted),
+			zap.Strings("entities", climates))
+
+		if err := m.haClient.CallService(m.ctx, "climate", "set_temperature", map[string]interface{}{
+			"entity_id":        climates,
+			"target_temp_low":  tempLowRestricted,
+			"target_temp_high": tempHighRestricted,
+		}); err != nil {
+			m.logger.Error("Failed to set thermostat temperature range",
+				zap.Error(err))
+			return
+		}
+
+		m.logger.Info("✓ Successfully set wider temperature range")
+	}
 	m.logger.Info("=== LOAD SHEDDING ACTIVATED ===",
 		zap.String("action", "HVAC restricted to conserve battery"))
 
@@ -306,7 +394,7 @@ func (m *Manager) disableLoadShedding(energyLevel string, trigger string) {
 
 	if m.readOnly {
 		m.logger.Info("READ-ONLY: Would disable thermostat hold mode (restore schedule)",
-			zap.Strings("entities", []string{thermostatHoldHouse, thermostatHoldSuite}))
+			zap.Strings("entities", m.holdSwitches()))
 		// Record shadow state even in read-only mode for consistency
 		reason := fmt.Sprintf("Energy state is %s (battery restored) - would return to normal HVAC", energyLevel)
 		m.recordAction(false, "disable", reason, false, 0, 0, trigger)
@@ -315,10 +403,10 @@ func (m *Manager) disableLoadShedding(energyLevel string, trigger string) {
 
 	// Turn off thermostat hold mode (return to schedule)
 	m.logger.Info("Executing: Disable thermostat hold mode (restore schedule)",
-		zap.Strings("entities", []string{thermostatHoldHouse, thermostatHoldSuite}))
+		zap.Strings("entities", m.holdSwitches()))
 
-	if err := m.haClient.CallService("switch", "turn_off", map[string]interface{}{
-		"entity_id": []string{thermostatHoldHouse, thermostatHoldSuite},
+	if err := m.haClient.CallService(m.ctx, "switch", "turn_off", map[string]interface{}{
+		"entity_id": m.holdSwitches(),
 	}); err != nil {
 		m.logger.Error("Failed to disable thermostat hold mode",
 			zap.Error(err))
@@ -370,12 +458,12 @@ func (m *Manager) checkRateLimit() bool {
 // Returns true if at least one hold is on, false otherwise
 func (m *Manager) checkThermostatHoldState() (bool, error) {
 	// Get state of both thermostat hold switches
-	houseState, err := m.haClient.GetState(thermostatHoldHouse)
+	houseState, err := m.haClient.GetState(m.ctx, m.entities.HouseThermostatHold())
 	if err != nil {
 		return false, fmt.Errorf("failed to get house thermostat hold state: %w", err)
 	}
 
-	suiteState, err := m.haClient.GetState(thermostatHoldSuite)
+	suiteState, err := m.haClient.GetState(m.ctx, m.entities.SuiteThermostatHold())
 	if err != nil {
 		return false, fmt.Errorf("failed to get suite thermostat hold state: %w", err)
 	}
@@ -433,6 +521,7 @@ func (m *Manager) updateShadowInputs() {
 
 // updateShadowInputsWithTrigger updates the current input values in shadow state including trigger
 func (m *Manager) updateShadowInputsWithTrigger(trigger string) {
+	m.health.Tick()
 	additional := map[string]interface{}{"trigger": trigger}
 
 	// Use automatic input capture with additional fields if available
@@ -477,3 +566,8 @@ func (m *Manager) recordAction(active bool, actionType string, reason string, ho
 func (m *Manager) GetShadowState() *shadowstate.LoadSheddingShadowState {
 	return m.shadowTracker.GetState()
 }
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
@@ -1,6 +1,7 @@
 package loadshedding
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -344,7 +345,7 @@ func TestLoadSheddingShadowState_HandleEnergyChange(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, zap.NewNop(), true, nil)
 
 	// Start the manager to enable subscriptions
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
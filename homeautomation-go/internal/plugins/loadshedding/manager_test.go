@@ -1,16 +1,38 @@
 package loadshedding
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"homeautomation/internal/ha"
+	"homeautomation/internal/kidmode"
 	"homeautomation/internal/state"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
 
+// Default thermostat entities
+const (
+	thermostatHoldHouse = "switch.most_of_house_thermostat_hold"
+	thermostatHoldSuite = "switch.primary_suite_thermostat_hold"
+	climateHouse        = "climate.most_of_house_thermostat"
+	climateSuite        = "climate.primary_suite_thermostat"
+)
+
+// withoutPlanUpdates drops the loadSheddingPlan writes published after every energy change
+func withoutPlanUpdates(calls []ha.ServiceCall) []ha.ServiceCall {
+	filtered := make([]ha.ServiceCall, 0, len(calls))
+	for _, call := range calls {
+		if call.Data["entity_id"] == "input_text.load_shedding_plan" {
+			continue
+		}
+		filtered = append(filtered, call)
+	}
+	return filtered
+}
+
 func TestLoadShedding_EnergyStateRed(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
@@ -26,7 +48,7 @@ func TestLoadShedding_EnergyStateRed(t *testing.T) {
 	assert.NoError(t, err)
 
 	ls := NewManager(mockClient, stateManager, logger, false, nil)
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -70,6 +92,38 @@ func TestLoadShedding_EnergyStateRed(t *testing.T) {
 	assert.True(t, foundSetTemp, "Expected climate.set_temperature service call")
 }
 
+func TestLoadShedding_KidModeHeldThermostat(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState(thermostatHoldHouse, "off", nil)
+	mockClient.SetState(thermostatHoldSuite, "off", nil)
+
+	stateManager := state.NewManager(mockClient, logger, false)
+	assert.NoError(t, stateManager.SyncFromHA())
+	assert.NoError(t, stateManager.SetBool(kidmode.ActiveVariable, true))
+
+	ls := NewManager(mockClient, stateManager, logger, false, nil)
+	ls.SetKidMode(kidmode.NewGuard(&kidmode.Config{KidMode: kidmode.Settings{
+		ToggleVariable:  "isKidMode",
+		Rooms:           []kidmode.Room{{Name: "house", Thermostats: []string{climateHouse}}},
+		LockThermostats: true,
+	}}, stateManager))
+	assert.NoError(t, ls.Start(context.Background()))
+	defer ls.Stop()
+
+	assert.NoError(t, stateManager.SetString("currentEnergyLevel", "red"))
+	time.Sleep(100 * time.Millisecond)
+
+	foundSetTemp := false
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "climate" && call.Service == "set_temperature" {
+			foundSetTemp = true
+			assert.Equal(t, []string{climateSuite}, call.Data["entity_id"], "the held thermostat is left alone")
+		}
+	}
+	assert.True(t, foundSetTemp, "Expected climate.set_temperature for the suite")
+}
+
 func TestLoadShedding_EnergyStateBlack(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
@@ -84,7 +138,7 @@ func TestLoadShedding_EnergyStateBlack(t *testing.T) {
 	assert.NoError(t, err)
 
 	ls := NewManager(mockClient, stateManager, logger, false, nil)
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -124,7 +178,7 @@ func TestLoadShedding_EnergyStateGreen(t *testing.T) {
 	// Manually set loadSheddingOn to true to simulate that load shedding was previously enabled
 	ls.loadSheddingOn = true
 
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -169,7 +223,7 @@ func TestLoadShedding_EnergyStateWhite(t *testing.T) {
 	// Manually set loadSheddingOn to true to simulate that load shedding was previously enabled
 	ls.loadSheddingOn = true
 
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -209,7 +263,7 @@ func TestLoadShedding_RateLimiting(t *testing.T) {
 
 	// Override minimum action interval for testing
 	// (In production, we'd use dependency injection for the time source)
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -230,7 +284,7 @@ func TestLoadShedding_RateLimiting(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Should only have the SetString call, not the load shedding action (rate limited)
-	finalCallCount := len(mockClient.GetServiceCalls())
+	finalCallCount := len(withoutPlanUpdates(mockClient.GetServiceCalls()))
 	assert.Equal(t, 1, finalCallCount,
 		"Should only have SetString call, load shedding action should be rate limited")
 }
@@ -251,12 +305,12 @@ func TestLoadShedding_StartStop(t *testing.T) {
 	ls := NewManager(mockClient, stateManager, logger, false, nil)
 
 	// Start
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	assert.True(t, ls.enabled)
 
 	// Try starting again (should fail)
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.Error(t, err)
 
 	// Stop
@@ -282,7 +336,7 @@ func TestLoadShedding_UnknownState(t *testing.T) {
 	assert.NoError(t, err)
 
 	ls := NewManager(mockClient, stateManager, logger, false, nil)
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -292,7 +346,7 @@ func TestLoadShedding_UnknownState(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Should only have the SetString call, no load shedding actions for unknown state
-	calls := mockClient.GetServiceCalls()
+	calls := withoutPlanUpdates(mockClient.GetServiceCalls())
 	assert.Equal(t, 1, len(calls), "Unknown state should only have SetString call, no load shedding actions")
 	// Verify it's the SetString call
 	assert.Equal(t, "input_text", calls[0].Domain)
@@ -317,7 +371,7 @@ func TestLoadShedding_RedToGreenTransition(t *testing.T) {
 	// Manually set last action to past to avoid rate limiting
 	ls.lastAction = time.Now().Add(-2 * time.Hour)
 
-	err = ls.Start()
+	err = ls.Start(context.Background())
 	assert.NoError(t, err)
 	defer ls.Stop()
 
@@ -363,7 +417,7 @@ func TestManagerReset(t *testing.T) {
 
 	manager := NewManager(mockClient, stateManager, logger, false, nil)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -0,0 +1,128 @@
+package loadshedding
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// Shedding tiers reported in the plan
+	tierNormal         = "normal"
+	tierHVACRestricted = "hvac_restricted"
+
+	// Home Assistant input_text values are limited to 255 characters
+	maxPlanLength = 255
+)
+
+// Plan describes the current and next load shedding tier for the Home
+// Assistant dashboard card. It is published as JSON to
+// input_text.load_shedding_plan, so field names are kept short.
+type Plan struct {
+	Tier         string   `json:"tier"`
+	Level        string   `json:"level"`
+	Next         string   `json:"next"`
+	NextOn       []string `json:"nextOn"`                 // Energy levels that move to the next tier
+	RestoreAfter string   `json:"restoreAfter,omitempty"` // RFC3339; earliest time HVAC can be restored
+	ShedTiers    []int    `json:"shedTiers,omitempty"`    // Device tiers shed, lowest first
+	Reasons      []string `json:"reasons"`
+}
+
+// buildPlan describes the current shedding tier, what moves it to the next
+// tier, and why a tier change the energy level calls for hasn't happened yet
+func buildPlan(active bool, level string, lastAction time.Time, now time.Time) Plan {
+	plan := Plan{Level: level}
+
+	if active {
+		plan.Tier = tierHVACRestricted
+		plan.Next = tierNormal
+		plan.NextOn = []string{energyStateGreen, energyStateWhite}
+		plan.Reasons = []string{"HVAC restricted to conserve battery"}
+	} else {
+		plan.Tier = tierNormal
+		plan.Next = tierHVACRestricted
+		plan.NextOn = []string{energyStateRed, energyStateBlack}
+		plan.Reasons = []string{"HVAC on normal schedule"}
+	}
+
+	var restoreAfter time.Time
+	if !lastAction.IsZero() {
+		restoreAfter = lastAction.Add(minActionInterval)
+	}
+	rateLimited := restoreAfter.After(now)
+
+	if active && rateLimited {
+		plan.RestoreAfter = restoreAfter.UTC().Format(time.RFC3339)
+	}
+
+	switch level {
+	case energyStateYellow:
+		plan.Reasons = append(plan.Reasons, "Yellow holds the current tier")
+	case energyStateRed, energyStateBlack:
+		if !active && rateLimited {
+			plan.Reasons = append(plan.Reasons, "Shedding rate limited")
+		}
+	case energyStateGreen, energyStateWhite:
+		if active && rateLimited {
+			plan.Reasons = append(plan.Reasons, "Restore rate limited")
+		}
+	case "":
+		plan.Reasons = append(plan.Reasons, "Energy level unknown")
+	}
+
+	return plan
+}
+
+// marshalPlan encodes the plan, dropping trailing reasons if needed to fit
+// within Home Assistant's input_text limit
+func marshalPlan(plan Plan) ([]byte, error) {
+	for {
+		data, err := json.Marshal(plan)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) <= maxPlanLength {
+			return data, nil
+		}
+		if len(plan.Reasons) <= 1 {
+			return nil, fmt.Errorf("load shedding plan is %d characters, limit is %d", len(data), maxPlanLength)
+		}
+		plan.Reasons = plan.Reasons[:len(plan.Reasons)-1]
+	}
+}
+
+// publishPlan updates loadSheddingPlan to reflect the current shedding tier
+func (m *Manager) publishPlan(level string) {
+	m.stateMu.Lock()
+	active := m.loadSheddingOn
+	m.stateMu.Unlock()
+
+	m.lastActionMu.Lock()
+	lastAction := m.lastAction
+	m.lastActionMu.Unlock()
+
+	plan := buildPlan(active, level, lastAction, time.Now())
+	plan.ShedTiers = m.shedTiers()
+	data, err := marshalPlan(plan)
+	if err != nil {
+		m.logger.Error("Failed to encode load shedding plan", zap.Error(err))
+		return
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would publish load shedding plan", zap.ByteString("plan", data))
+		return
+	}
+
+	// Store the decoded form so unchanged plans compare equal to the cached value
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		m.logger.Error("Failed to encode load shedding plan", zap.Error(err))
+		return
+	}
+	if err := m.stateManager.SetJSON("loadSheddingPlan", value); err != nil {
+		m.logger.Error("Failed to publish load shedding plan", zap.Error(err))
+	}
+}
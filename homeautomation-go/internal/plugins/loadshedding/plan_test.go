@@ -0,0 +1,109 @@
+package loadshedding
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBuildPlan(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	recent := now.Add(-15 * time.Minute)
+
+	tests := []struct {
+		name         string
+		active       bool
+		level        string
+		lastAction   time.Time
+		wantTier     string
+		wantNext     string
+		wantRestore  string
+		wantReasonOf string
+	}{
+		{"normal on green", false, energyStateGreen, time.Time{}, tierNormal, tierHVACRestricted, "", "normal schedule"},
+		{"restricted on red", true, energyStateRed, recent, tierHVACRestricted, tierNormal, "2025-01-06T09:45:00Z", "conserve battery"},
+		{"restricted after rate limit", true, energyStateRed, now.Add(-2 * time.Hour), tierHVACRestricted, tierNormal, "", "conserve battery"},
+		{"yellow holds tier", true, energyStateYellow, time.Time{}, tierHVACRestricted, tierNormal, "", "Yellow holds"},
+		{"restore rate limited", true, energyStateGreen, recent, tierHVACRestricted, tierNormal, "2025-01-06T09:45:00Z", "Restore rate limited"},
+		{"shedding rate limited", false, energyStateBlack, recent, tierNormal, tierHVACRestricted, "", "Shedding rate limited"},
+		{"unknown level", false, "", time.Time{}, tierNormal, tierHVACRestricted, "", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := buildPlan(tt.active, tt.level, tt.lastAction, now)
+
+			assert.Equal(t, tt.wantTier, plan.Tier)
+			assert.Equal(t, tt.wantNext, plan.Next)
+			assert.Equal(t, tt.level, plan.Level)
+			assert.Equal(t, tt.wantRestore, plan.RestoreAfter)
+			assert.Contains(t, strings.Join(plan.Reasons, "; "), tt.wantReasonOf)
+		})
+	}
+}
+
+func TestMarshalPlan_FitsInputText(t *testing.T) {
+	plan := buildPlan(true, energyStateGreen, time.Now(), time.Now())
+	plan.Reasons = append(plan.Reasons, strings.Repeat("x", 200))
+
+	data, err := marshalPlan(plan)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(data), maxPlanLength)
+	assert.NotContains(t, string(data), "xxx", "Trailing reasons should be dropped to fit")
+	assert.Contains(t, string(data), "conserve battery")
+}
+
+func TestLoadShedding_PublishesPlan(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState(thermostatHoldHouse, "off", nil)
+	mockClient.SetState(thermostatHoldSuite, "off", nil)
+
+	stateManager := state.NewManager(mockClient, logger, false)
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "green"))
+
+	ls := NewManager(mockClient, stateManager, logger, false, nil)
+	require.NoError(t, ls.Start(context.Background()))
+	defer ls.Stop()
+
+	var plan Plan
+	require.NoError(t, stateManager.GetJSON("loadSheddingPlan", &plan))
+	assert.Equal(t, tierNormal, plan.Tier)
+	assert.Equal(t, []string{energyStateRed, energyStateBlack}, plan.NextOn)
+
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", "red"))
+
+	require.NoError(t, stateManager.GetJSON("loadSheddingPlan", &plan))
+	assert.Equal(t, tierHVACRestricted, plan.Tier)
+	assert.Equal(t, energyStateRed, plan.Level)
+	assert.NotEmpty(t, plan.RestoreAfter, "Restore is rate limited right after shedding starts")
+
+	foundInputText := false
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "input_text" && call.Data["entity_id"] == "input_text.load_shedding_plan" {
+			foundInputText = true
+		}
+	}
+	assert.True(t, foundInputText, "Expected the plan to be written to input_text.load_shedding_plan")
+}
+
+func TestLoadShedding_PlanReadOnly(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, true)
+
+	ls := NewManager(mockClient, stateManager, logger, true, nil)
+	ls.publishPlan(energyStateRed)
+
+	var plan map[string]interface{}
+	require.NoError(t, stateManager.GetJSON("loadSheddingPlan", &plan))
+	assert.Empty(t, plan, "Plan should not be published in read-only mode")
+}
@@ -0,0 +1,215 @@
+package loadshedding
+
+import (
+	"sort"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// SetConfig sets the tiered devices shed alongside the thermostat. Without
+// one only the thermostat is shed.
+func (m *Manager) SetConfig(config *LoadSheddingConfig) {
+	m.config = config
+}
+
+// shedFromTier returns the lowest tier shed at an energy level, MaxTier+1 when
+// nothing is, and whether the level changes the shed tiers at all
+func (m *Manager) shedFromTier(level string) (int, bool) {
+	if tier, ok := m.config.LoadShedding.ShedFromTier[level]; ok {
+		return tier, true
+	}
+	if level == energyStateYellow || level == "" {
+		return 0, false
+	}
+	return MaxTier + 1, true
+}
+
+// applyTiers sheds the tiered devices the energy level calls for, highest
+// tier first, and restores the rest in reverse order, lowest tier first
+func (m *Manager) applyTiers(level string) {
+	if m.config == nil || len(m.config.LoadShedding.Devices) == 0 {
+		return
+	}
+
+	fromTier, ok := m.shedFromTier(level)
+	if !ok {
+		m.logger.Debug("Energy level holds the shed tiers", zap.String("energy_level", level))
+		return
+	}
+
+	m.tiersMu.Lock()
+	defer m.tiersMu.Unlock()
+
+	var toShed, toRestore []Device
+	for _, device := range m.config.LoadShedding.Devices {
+		_, isShed := m.shed[device.EntityID]
+		switch {
+		case device.Tier >= fromTier && !isShed:
+			toShed = append(toShed, device)
+		case device.Tier < fromTier && isShed:
+			toRestore = append(toRestore, device)
+		}
+	}
+	sort.SliceStable(toShed, func(i, j int) bool { return toShed[i].Tier > toShed[j].Tier })
+	sort.SliceStable(toRestore, func(i, j int) bool { return toRestore[i].Tier < toRestore[j].Tier })
+
+	for _, device := range toShed {
+		m.shedDevice(device, level)
+	}
+	for _, device := range toRestore {
+		m.restoreDevice(device, level)
+	}
+
+	if len(toShed) > 0 || len(toRestore) > 0 {
+		m.recordShedDevices()
+	}
+}
+
+// shedDevice turns a device off, or to its shed mode, remembering the state
+// to restore. Devices already off are left out.
+func (m *Manager) shedDevice(device Device, level string) {
+	if device.Domain() == "climate" && m.kid.LocksThermostat(device.EntityID) {
+		m.logger.Info("⏭  Not shedding thermostat: held by kid mode",
+			zap.String("entity_id", device.EntityID))
+		return
+	}
+
+	st, err := m.haClient.GetState(m.ctx, device.EntityID)
+	if err != nil {
+		m.logger.Warn("Failed to read device state, not shedding it",
+			zap.String("entity_id", device.EntityID),
+			zap.Error(err))
+		return
+	}
+	previous := st.State
+	if previous == "unavailable" || previous == "unknown" {
+		m.logger.Warn("Device unavailable, not shedding it",
+			zap.String("entity_id", device.EntityID))
+		return
+	}
+	if previous == "off" || previous == device.ShedModeOrDefault() {
+		m.logger.Debug("Device already shed",
+			zap.String("entity_id", device.EntityID),
+			zap.String("state", previous))
+		return
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would shed device",
+			zap.String("entity_id", device.EntityID),
+			zap.Int("tier", device.Tier),
+			zap.String("energy_level", level))
+	} else {
+		if err := m.setDeviceState(device, device.ShedModeOrDefault()); err != nil {
+			m.logger.Error("Failed to shed device",
+				zap.String("entity_id", device.EntityID),
+				zap.Error(err))
+			return
+		}
+		m.logger.Info("✓ Shed device",
+			zap.String("entity_id", device.EntityID),
+			zap.Int("tier", device.Tier),
+			zap.String("energy_level", level))
+	}
+
+	m.shed[device.EntityID] = shadowstate.ShedDevice{
+		EntityID: device.EntityID,
+		Tier:     device.Tier,
+		Previous: previous,
+		Restore:  device.RestoreOrDefault(),
+		ShedAt:   time.Now(),
+	}
+	m.shedOrder = append(m.shedOrder, device.EntityID)
+}
+
+// restoreDevice returns a shed device to its previous state, unless it is
+// restored manually
+func (m *Manager) restoreDevice(device Device, level string) {
+	shed := m.shed[device.EntityID]
+
+	switch {
+	case shed.Restore == RestoreManual:
+		m.logger.Info("Leaving shed device for manual restore",
+			zap.String("entity_id", device.EntityID),
+			zap.Int("tier", device.Tier))
+	case m.readOnly:
+		m.logger.Info("READ-ONLY: Would restore device",
+			zap.String("entity_id", device.EntityID),
+			zap.String("state", shed.Previous),
+			zap.String("energy_level", level))
+	default:
+		if err := m.setDeviceState(device, shed.Previous); err != nil {
+			m.logger.Error("Failed to restore device",
+				zap.String("entity_id", device.EntityID),
+				zap.Error(err))
+			return
+		}
+		m.logger.Info("✓ Restored device",
+			zap.String("entity_id", device.EntityID),
+			zap.String("state", shed.Previous),
+			zap.Int("tier", device.Tier),
+			zap.String("energy_level", level))
+	}
+
+	delete(m.shed, device.EntityID)
+	for i, entityID := range m.shedOrder {
+		if entityID == device.EntityID {
+			m.shedOrder = append(m.shedOrder[:i], m.shedOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// setDeviceState turns a switch "on" or "off", or sets a climate or water
+// heater device's mode
+func (m *Manager) setDeviceState(device Device, mode string) error {
+	switch device.Domain() {
+	case "climate":
+		return m.haClient.CallService(m.ctx, "climate", "set_hvac_mode", map[string]interface{}{
+			"entity_id": device.EntityID,
+			"hvac_mode": mode,
+		})
+	case "water_heater":
+		return m.haClient.CallService(m.ctx, "water_heater", "set_operation_mode", map[string]interface{}{
+			"entity_id":      device.EntityID,
+			"operation_mode": mode,
+		})
+	}
+	service := "turn_off"
+	if mode == "on" {
+		service = "turn_on"
+	}
+	return m.haClient.CallService(m.ctx, device.Domain(), service, map[string]interface{}{
+		"entity_id": device.EntityID,
+	})
+}
+
+// shedTiers returns the tiers with a device shed, lowest first
+func (m *Manager) shedTiers() []int {
+	m.tiersMu.Lock()
+	defer m.tiersMu.Unlock()
+
+	seen := make(map[int]bool)
+	var tiers []int
+	for _, shed := range m.shed {
+		if !seen[shed.Tier] {
+			seen[shed.Tier] = true
+			tiers = append(tiers, shed.Tier)
+		}
+	}
+	sort.Ints(tiers)
+	return tiers
+}
+
+// recordShedDevices records the shed devices in shadow state, in the order
+// they were shed. Called with tiersMu held.
+func (m *Manager) recordShedDevices() {
+	devices := make([]shadowstate.ShedDevice, 0, len(m.shedOrder))
+	for _, entityID := range m.shedOrder {
+		devices = append(devices, m.shed[entityID])
+	}
+	m.shadowTracker.RecordShedDevices(devices)
+}
@@ -0,0 +1,190 @@
+package loadshedding
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTieredManager returns a manager shedding tier 3 on red and tiers 2-3 on
+// black, with every device on
+func newTieredManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient) {
+	t.Helper()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState(thermostatHoldHouse, "off", nil)
+	mockClient.SetState(thermostatHoldSuite, "off", nil)
+	mockClient.SetState("switch.pool_pump", "on", nil)
+	mockClient.SetState("switch.ev_charger", "on", nil)
+	mockClient.SetState("water_heater.tank", "heat_pump", nil)
+	mockClient.SetState("switch.dehumidifier", "on", nil)
+
+	manager := NewManager(mockClient, state.NewManager(mockClient, zap.NewNop(), false), zap.NewNop(), readOnly, nil)
+	manager.SetConfig(&LoadSheddingConfig{LoadShedding: LoadSheddingSettings{
+		ShedFromTier: map[string]int{energyStateRed: 3, energyStateBlack: 2},
+		Devices: []Device{
+			{EntityID: "water_heater.tank", Tier: 2, ShedMode: "eco"},
+			{EntityID: "switch.pool_pump", Tier: 3},
+			{EntityID: "switch.dehumidifier", Tier: 2, Restore: RestoreManual},
+			{EntityID: "switch.ev_charger", Tier: 3},
+		},
+	}})
+	return manager, mockClient
+}
+
+// tierCalls describes the tiered device service calls made, in order
+func tierCalls(mockClient *ha.MockClient) []string {
+	var calls []string
+	for _, call := range mockClient.GetServiceCalls() {
+		entityID, ok := call.Data["entity_id"].(string)
+		if !ok || entityID == "input_text.load_shedding_plan" {
+			continue
+		}
+		description := call.Service + " " + entityID
+		if mode, ok := call.Data["operation_mode"].(string); ok {
+			description += " " + mode
+		}
+		calls = append(calls, description)
+	}
+	return calls
+}
+
+func TestTiers_ShedAndRestoreInReverseOrder(t *testing.T) {
+	manager, mockClient := newTieredManager(t, false)
+
+	manager.applyTiers(energyStateRed)
+	assert.Equal(t, []string{"turn_off switch.pool_pump", "turn_off switch.ev_charger"}, tierCalls(mockClient))
+
+	mockClient.ClearServiceCalls()
+	manager.applyTiers(energyStateBlack)
+	assert.Equal(t, []string{"set_operation_mode water_heater.tank eco", "turn_off switch.dehumidifier"}, tierCalls(mockClient))
+	assert.Equal(t, []int{2, 3}, manager.shedTiers())
+
+	// Yellow holds every tier
+	mockClient.ClearServiceCalls()
+	manager.applyTiers(energyStateYellow)
+	assert.Empty(t, tierCalls(mockClient))
+
+	// Back to red restores tier 2 only; the dehumidifier waits for someone
+	manager.applyTiers(energyStateRed)
+	assert.Equal(t, []string{"set_operation_mode water_heater.tank heat_pump"}, tierCalls(mockClient))
+	assert.Equal(t, []int{3}, manager.shedTiers())
+
+	mockClient.ClearServiceCalls()
+	manager.applyTiers(energyStateGreen)
+	assert.Equal(t, []string{"turn_on switch.pool_pump", "turn_on switch.ev_charger"}, tierCalls(mockClient))
+	assert.Empty(t, manager.shedTiers())
+	assert.Empty(t, manager.GetShadowState().Outputs.ShedDevices)
+}
+
+func TestTiers_DevicesAlreadyOffStayOff(t *testing.T) {
+	manager, mockClient := newTieredManager(t, false)
+	mockClient.SetState("switch.ev_charger", "off", nil)
+	mockClient.ClearServiceCalls()
+
+	manager.applyTiers(energyStateRed)
+	manager.applyTiers(energyStateWhite)
+
+	assert.Equal(t, []string{"turn_off switch.pool_pump", "turn_on switch.pool_pump"}, tierCalls(mockClient))
+}
+
+func TestTiers_ShadowStateAndPlan(t *testing.T) {
+	manager, mockClient := newTieredManager(t, false)
+	stateManager := manager.stateManager
+	require.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop()
+
+	require.NoError(t, stateManager.SetString("currentEnergyLevel", energyStateRed))
+
+	shed := manager.GetShadowState().Outputs.ShedDevices
+	require.Len(t, shed, 2)
+	assert.Equal(t, "switch.pool_pump", shed[0].EntityID)
+	assert.Equal(t, 3, shed[0].Tier)
+	assert.Equal(t, "on", shed[0].Previous)
+	assert.Equal(t, RestorePrevious, shed[0].Restore)
+
+	var plan Plan
+	require.NoError(t, stateManager.GetJSON("loadSheddingPlan", &plan))
+	assert.Equal(t, []int{3}, plan.ShedTiers)
+	assert.Contains(t, tierCalls(mockClient), "turn_off switch.ev_charger")
+}
+
+func TestSheds(t *testing.T) {
+	manager, _ := newTieredManager(t, false)
+	assert.False(t, manager.Sheds(climateHouse))
+
+	manager.applyTiers(energyStateRed)
+	assert.True(t, manager.Sheds("switch.pool_pump"))
+	assert.False(t, manager.Sheds("water_heater.tank"))
+
+	manager.stateMu.Lock()
+	manager.loadSheddingOn = true
+	manager.stateMu.Unlock()
+	assert.True(t, manager.Sheds(climateHouse))
+	assert.True(t, manager.Sheds(climateSuite))
+}
+
+func TestTiers_ReadOnly(t *testing.T) {
+	manager, mockClient := newTieredManager(t, true)
+	mockClient.ClearServiceCalls()
+
+	manager.applyTiers(energyStateBlack)
+
+	assert.Empty(t, tierCalls(mockClient))
+	assert.Len(t, manager.GetShadowState().Outputs.ShedDevices, 4)
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/loadshedding_config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, 3, config.LoadShedding.ShedFromTier[energyStateRed])
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "unknown level",
+			yaml:    "load_shedding:\n  shed_from_tier:\n    low: 3\n",
+			wantErr: `shed_from_tier: unknown energy level "low"`,
+		},
+		{
+			name:    "tier out of range",
+			yaml:    "load_shedding:\n  devices:\n    - entity_id: switch.pool_pump\n      tier: 4\n",
+			wantErr: "devices[0]: tier must be between 1 and 3",
+		},
+		{
+			name:    "unsupported domain",
+			yaml:    "load_shedding:\n  devices:\n    - entity_id: light.porch\n      tier: 3\n",
+			wantErr: `devices[0]: entity_id must be a switch, input_boolean, climate or water_heater, got "light.porch"`,
+		},
+		{
+			name:    "thermostat already held",
+			yaml:    "load_shedding:\n  devices:\n    - entity_id: " + climateHouse + "\n      tier: 1\n",
+			wantErr: "devices[0]: " + climateHouse + " is already restricted by the thermostat hold",
+		},
+		{
+			name:    "shed mode on a switch",
+			yaml:    "load_shedding:\n  devices:\n    - entity_id: switch.pool_pump\n      tier: 3\n      shed_mode: eco\n",
+			wantErr: "devices[0]: shed_mode only applies to climate and water_heater devices",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "loadshedding_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package lowbattery
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultThresholdPercent is the low-battery threshold when none is configured
+const DefaultThresholdPercent = 20.0
+
+// ThresholdOverride sets a different threshold for matching entities
+type ThresholdOverride struct {
+	Match     string  `yaml:"match"`     // Entity ID, or a prefix ending in "*" (e.g. "lock.*")
+	Threshold float64 `yaml:"threshold"` // Percent
+}
+
+// Matches reports whether the override applies to an entity
+func (o ThresholdOverride) Matches(entityID string) bool {
+	if prefix, ok := strings.CutSuffix(o.Match, "*"); ok {
+		return strings.HasPrefix(entityID, prefix)
+	}
+	return o.Match == entityID
+}
+
+// LowBatterySettings holds the sweep schedule, thresholds and weekly report
+type LowBatterySettings struct {
+	SweepTime        string              `yaml:"sweep_time"`        // Daily sweep, format: "09:00"
+	DefaultThreshold float64             `yaml:"default_threshold"` // Percent; 0 uses DefaultThresholdPercent
+	Thresholds       []ThresholdOverride `yaml:"thresholds"`        // First match wins
+	Ignore           []string            `yaml:"ignore"`            // Entity IDs or "*" prefixes never reported
+	ReportDay        string              `yaml:"report_day"`        // Day of week for the consolidated notification, e.g. "saturday"
+	ReportTime       string              `yaml:"report_time"`       // Format: "10:00"
+	NotifyService    notifyrouter.Target `yaml:"notify_service"`    // e.g. "notify.notify"; empty disables notifications
+}
+
+// LowBatteryConfig represents the low_battery_config.yaml structure
+type LowBatteryConfig struct {
+	LowBattery LowBatterySettings `yaml:"low_battery"`
+}
+
+// ThresholdFor returns the low-battery threshold for an entity
+func (s *LowBatterySettings) ThresholdFor(entityID string) float64 {
+	for _, override := range s.Thresholds {
+		if override.Matches(entityID) {
+			return override.Threshold
+		}
+	}
+	if s.DefaultThreshold > 0 {
+		return s.DefaultThreshold
+	}
+	return DefaultThresholdPercent
+}
+
+// Ignored reports whether an entity is excluded from the sweep
+func (s *LowBatterySettings) Ignored(entityID string) bool {
+	for _, pattern := range s.Ignore {
+		if (ThresholdOverride{Match: pattern}).Matches(entityID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportWeekday returns the configured report day
+func (s *LowBatterySettings) ReportWeekday() (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s.ReportDay) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid report_day %q", s.ReportDay)
+}
+
+// NextSweep returns the first daily sweep time strictly after now, in loc
+func (c *LowBatteryConfig) NextSweep(now time.Time, loc *time.Location) time.Time {
+	at, _ := time.Parse("15:04", c.LowBattery.SweepTime)
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// NextReport returns the first weekly report time strictly after now, in loc
+func (c *LowBatteryConfig) NextReport(now time.Time, loc *time.Location) time.Time {
+	weekday, _ := c.LowBattery.ReportWeekday()
+	at, _ := time.Parse("15:04", c.LowBattery.ReportTime)
+	local := now.In(loc)
+	daysAhead := (int(weekday) - int(local.Weekday()) + 7) % 7
+	next := time.Date(local.Year(), local.Month(), local.Day()+daysAhead, at.Hour(), at.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// Validate checks the schedule, thresholds and notify service
+func (c *LowBatteryConfig) Validate() error {
+	s := c.LowBattery
+	if _, err := time.Parse("15:04", s.SweepTime); err != nil {
+		return fmt.Errorf("low_battery: invalid sweep_time %q, expected HH:MM", s.SweepTime)
+	}
+	if s.DefaultThreshold < 0 || s.DefaultThreshold > 100 {
+		return fmt.Errorf("low_battery: default_threshold must be between 0 and 100")
+	}
+	for i, override := range s.Thresholds {
+		if override.Match == "" {
+			return fmt.Errorf("low_battery: thresholds[%d]: match is required", i)
+		}
+		if override.Threshold <= 0 || override.Threshold > 100 {
+			return fmt.Errorf("low_battery: thresholds[%d]: threshold must be between 0 and 100", i)
+		}
+	}
+	if _, err := s.ReportWeekday(); err != nil {
+		return fmt.Errorf("low_battery: %w", err)
+	}
+	if _, err := time.Parse("15:04", s.ReportTime); err != nil {
+		return fmt.Errorf("low_battery: invalid report_time %q, expected HH:MM", s.ReportTime)
+	}
+	if s.NotifyService != "" {
+		if _, _, ok := s.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("low_battery: invalid notify_service %q (expected domain.service)", s.NotifyService)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the low-battery configuration from a YAML file
+func LoadConfig(path string) (*LowBatteryConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config LowBatteryConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,93 @@
+package lowbattery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/low_battery_config.yaml")
+	require.NoError(t, err)
+
+	s := config.LowBattery
+	assert.Equal(t, 40.0, s.ThresholdFor("lock.front_door"))
+	assert.Equal(t, 20.0, s.ThresholdFor("sensor.hall_motion_battery"))
+	assert.True(t, s.Ignored("sensor.nick_phone_battery_level"))
+	assert.False(t, s.Ignored("sensor.hall_motion_battery"))
+}
+
+func TestValidate(t *testing.T) {
+	valid := createTestConfig().LowBattery
+
+	tests := []struct {
+		name    string
+		modify  func(s *LowBatterySettings)
+		wantErr string
+	}{
+		{"valid", func(s *LowBatterySettings) {}, ""},
+		{"invalid sweep time", func(s *LowBatterySettings) { s.SweepTime = "9am" }, "invalid sweep_time"},
+		{"default threshold too high", func(s *LowBatterySettings) { s.DefaultThreshold = 120 }, "default_threshold"},
+		{"override without match", func(s *LowBatterySettings) { s.Thresholds = []ThresholdOverride{{Threshold: 30}} }, "match is required"},
+		{"override without threshold", func(s *LowBatterySettings) { s.Thresholds = []ThresholdOverride{{Match: "lock.*"}} }, "threshold must be between"},
+		{"invalid report day", func(s *LowBatterySettings) { s.ReportDay = "someday" }, "invalid report_day"},
+		{"invalid report time", func(s *LowBatterySettings) { s.ReportTime = "25:00" }, "invalid report_time"},
+		{"invalid notify service", func(s *LowBatterySettings) { s.NotifyService = "notify" }, "invalid notify_service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			s.Thresholds = append([]ThresholdOverride(nil), valid.Thresholds...)
+			tt.modify(&s)
+			config := &LowBatteryConfig{LowBattery: s}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "low_battery_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+low_battery:
+  sweep_time: "09:00"
+  report_day: caturday
+  report_time: "10:00"
+`), 0644))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestThresholdFor(t *testing.T) {
+	s := LowBatterySettings{Thresholds: []ThresholdOverride{
+		{Match: "lock.front_door", Threshold: 50},
+		{Match: "lock.*", Threshold: 40},
+	}}
+
+	assert.Equal(t, 50.0, s.ThresholdFor("lock.front_door"), "first match wins")
+	assert.Equal(t, 40.0, s.ThresholdFor("lock.back_door"))
+	assert.Equal(t, DefaultThresholdPercent, s.ThresholdFor("sensor.hall_motion_battery"))
+}
+
+func TestNextReport(t *testing.T) {
+	config := createTestConfig()
+
+	// Friday 08:00 -> Saturday 10:00
+	next := config.NextReport(time.Date(2025, 1, 17, 8, 0, 0, 0, time.UTC), time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 18, 10, 0, 0, 0, time.UTC), next)
+
+	// Exactly at the report time -> next week
+	next = config.NextReport(time.Date(2025, 1, 18, 10, 0, 0, 0, time.UTC), time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 25, 10, 0, 0, 0, time.UTC), next)
+}
@@ -0,0 +1,353 @@
+// Package lowbattery sweeps every Home Assistant entity that reports a
+// battery level once a day, publishes the ones under their threshold to the
+// lowBatteryDevices state variable, and sends one consolidated notification a
+// week in place of HA's per-device alerts.
+package lowbattery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// NotificationTitle is the title of the weekly notification
+const NotificationTitle = "Low batteries"
+
+// Manager runs the daily battery sweep and the weekly notification
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *LowBatteryConfig
+	logger       *zap.Logger
+	readOnly     bool
+	timezone     *time.Location
+	clock        clock.Clock
+
+	// Pending sweep and report timers, and the latest low devices (protected by mu)
+	sweepTimer  clock.Timer
+	reportTimer clock.Timer
+	devices     []shadowstate.LowBatteryDevice
+	swept       bool
+	mu          sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.LowBatteryTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new low-battery manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *LowBatteryConfig, logger *zap.Logger, readOnly bool, timezone *time.Location) *Manager {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(24 * time.Hour)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("lowbattery")),
+		health:        recorder,
+		readOnly:      readOnly,
+		timezone:      timezone,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowstate.NewLowBatteryTracker(),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.LowBatteryShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start sweeps once so lowBatteryDevices is populated, then schedules the
+// daily sweep and the weekly report
+func (m *Manager) Start(ctx context.Context) error {
+	// Stop cancels the context derived from ctx
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Low Battery Manager",
+		zap.String("sweep_time", m.config.LowBattery.SweepTime),
+		zap.String("report_day", m.config.LowBattery.ReportDay),
+		zap.String("report_time", m.config.LowBattery.ReportTime))
+
+	if _, err := m.Sweep(); err != nil {
+		m.logger.Warn("Initial battery sweep failed, retrying at the next scheduled sweep", zap.Error(err))
+	}
+
+	m.scheduleSweep()
+	m.scheduleReport()
+
+	m.health.Started(0)
+	m.logger.Info("Low Battery Manager started successfully")
+	return nil
+}
+
+// Stop cancels the scheduled sweep and report
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Low Battery Manager")
+
+	m.cancel()
+
+	m.mu.Lock()
+	if m.sweepTimer != nil {
+		m.sweepTimer.Stop()
+	}
+	if m.reportTimer != nil {
+		m.reportTimer.Stop()
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Low Battery Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-runs the sweep
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Low Battery - re-sweeping batteries")
+
+	if _, err := m.Sweep(); err != nil {
+		return err
+	}
+
+	m.logger.Info("Successfully reset Low Battery")
+	return nil
+}
+
+// Sweep reads every entity, publishes those with a battery under their
+// threshold to lowBatteryDevices, and returns them lowest first
+func (m *Manager) Sweep() ([]shadowstate.LowBatteryDevice, error) {
+	now := m.clock.Now()
+
+	states, err := m.haClient.GetAllStates(m.ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to read entity states: %w", err)
+		m.shadowTracker.RecordSweep(nil, 0, now, err)
+		return nil, err
+	}
+
+	devices, scanned := m.findLowBatteries(states)
+
+	m.mu.Lock()
+	m.devices = devices
+	m.swept = true
+	m.mu.Unlock()
+
+	if err := m.stateManager.SetJSON("lowBatteryDevices", devices); err != nil {
+		m.logger.Error("Failed to publish lowBatteryDevices", zap.Error(err))
+	}
+	m.shadowTracker.RecordSweep(devices, scanned, now, nil)
+	m.health.Tick()
+
+	m.logger.Info("Battery sweep complete",
+		zap.Int("batteries", scanned),
+		zap.Int("low", len(devices)))
+	return devices, nil
+}
+
+// SendReport sends the consolidated notification for the latest sweep. Nothing
+// is sent when no battery is low.
+func (m *Manager) SendReport() {
+	m.mu.Lock()
+	devices := append([]shadowstate.LowBatteryDevice(nil), m.devices...)
+	swept := m.swept
+	m.mu.Unlock()
+
+	if !swept {
+		m.logger.Warn("Skipping weekly low-battery report, no sweep has succeeded yet")
+		return
+	}
+	if len(devices) == 0 {
+		m.logger.Info("No low batteries this week, skipping notification")
+		return
+	}
+
+	message := formatMessage(devices)
+	m.shadowTracker.RecordReport(message, m.clock.Now())
+
+	target := m.config.LowBattery.NotifyService
+	if target == "" {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send low-battery notification",
+			zap.String("service", string(target)),
+			zap.String("message", message))
+		return
+	}
+
+	if err := notifyrouter.SendPush(m.ctx, m.haClient, target, notifyrouter.Push{
+		Title:   NotificationTitle,
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send low-battery notification", zap.Error(err))
+	} else {
+		m.logger.Info("Low-battery notification sent", zap.Int("devices", len(devices)))
+	}
+}
+
+// scheduleSweep arms the timer for the next daily sweep
+func (m *Manager) scheduleSweep() {
+	now := m.clock.Now()
+	next := m.config.NextSweep(now, m.timezone)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return
+	}
+	m.sweepTimer = m.clock.AfterFunc(next.Sub(now), func() {
+		if _, err := m.Sweep(); err != nil {
+			m.logger.Error("Battery sweep failed", zap.Error(err))
+		}
+		m.scheduleSweep()
+	})
+}
+
+// scheduleReport arms the timer for the next weekly report
+func (m *Manager) scheduleReport() {
+	now := m.clock.Now()
+	next := m.config.NextReport(now, m.timezone)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return
+	}
+	m.reportTimer = m.clock.AfterFunc(next.Sub(now), func() {
+		m.SendReport()
+		m.scheduleReport()
+	})
+}
+
+// findLowBatteries returns the entities under their threshold, lowest first,
+// and how many battery readings were found
+func (m *Manager) findLowBatteries(states []*ha.State) ([]shadowstate.LowBatteryDevice, int) {
+	s := &m.config.LowBattery
+	devices := []shadowstate.LowBatteryDevice{}
+	scanned := 0
+
+	for _, st := range states {
+		if st == nil || s.Ignored(st.EntityID) {
+			continue
+		}
+		level, ok := batteryLevel(st)
+		if !ok {
+			continue
+		}
+		scanned++
+
+		threshold := s.ThresholdFor(st.EntityID)
+		if level >= threshold {
+			continue
+		}
+		devices = append(devices, shadowstate.LowBatteryDevice{
+			EntityID:  st.EntityID,
+			Name:      friendlyName(st),
+			Level:     level,
+			Threshold: threshold,
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Level != devices[j].Level {
+			return devices[i].Level < devices[j].Level
+		}
+		return devices[i].EntityID < devices[j].EntityID
+	})
+	return devices, scanned
+}
+
+// batteryLevel returns the battery percentage an entity reports, either as
+// a battery sensor's state or a battery_level/battery attribute
+func batteryLevel(st *ha.State) (float64, bool) {
+	if deviceClass, _ := st.Attributes["device_class"].(string); deviceClass == "battery" && strings.HasPrefix(st.EntityID, "sensor.") {
+		level, err := strconv.ParseFloat(st.State, 64)
+		return level, err == nil
+	}
+	for _, attribute := range []string{"battery_level", "battery"} {
+		if level, ok := numericAttribute(st.Attributes[attribute]); ok {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// numericAttribute converts an attribute value to a number
+func numericAttribute(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		level, err := strconv.ParseFloat(v, 64)
+		return level, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// friendlyName returns the entity's friendly_name, or its ID
+func friendlyName(st *ha.State) string {
+	if name, ok := st.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return st.EntityID
+}
+
+// formatMessage builds the weekly notification, e.g.
+// "3 batteries are low: Front door lock 12%, Hall motion 15%, Patio sensor 18%"
+func formatMessage(devices []shadowstate.LowBatteryDevice) string {
+	parts := make([]string, len(devices))
+	for i, device := range devices {
+		parts[i] = fmt.Sprintf("%s %.0f%%", device.Name, device.Level)
+	}
+	if len(devices) == 1 {
+		return fmt.Sprintf("1 battery is low: %s", parts[0])
+	}
+	return fmt.Sprintf("%d batteries are low: %s", len(devices), strings.Join(parts, ", "))
+}
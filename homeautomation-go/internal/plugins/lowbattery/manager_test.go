@@ -0,0 +1,159 @@
+package lowbattery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig() *LowBatteryConfig {
+	return &LowBatteryConfig{
+		LowBattery: LowBatterySettings{
+			SweepTime:        "09:00",
+			DefaultThreshold: 20,
+			Thresholds:       []ThresholdOverride{{Match: "lock.*", Threshold: 40}},
+			Ignore:           []string{"sensor.phone_battery"},
+			ReportDay:        "saturday",
+			ReportTime:       "10:00",
+			NotifyService:    "notify.notify",
+		},
+	}
+}
+
+// setupTest starts a manager on Friday 2025-01-17 at 08:00 UTC with a mix of
+// battery entities
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("sensor.hall_motion_battery", "15", map[string]interface{}{
+		"device_class": "battery", "friendly_name": "Hall motion battery"})
+	mockHA.SetState("sensor.patio_battery", "85", map[string]interface{}{"device_class": "battery"})
+	mockHA.SetState("sensor.phone_battery", "5", map[string]interface{}{"device_class": "battery"})
+	mockHA.SetState("sensor.offline_battery", "unavailable", map[string]interface{}{"device_class": "battery"})
+	mockHA.SetState("lock.front_door", "locked", map[string]interface{}{
+		"battery_level": 35.0, "friendly_name": "Front door lock"})
+	mockHA.SetState("binary_sensor.back_door", "off", map[string]interface{}{"battery": "9"})
+	mockHA.SetState("light.kitchen", "on", nil)
+
+	stateManager := state.NewManager(mockHA, logger, readOnly)
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, readOnly, time.UTC)
+	mockClock := clock.NewMockClock(time.Date(2025, 1, 17, 8, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+func notifyCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "notify" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestSweepPublishesLowBatteries(t *testing.T) {
+	manager, _, stateManager, _ := setupTest(t, false)
+
+	var published []shadowstate.LowBatteryDevice
+	require.NoError(t, stateManager.GetJSON("lowBatteryDevices", &published))
+
+	require.Len(t, published, 3)
+	assert.Equal(t, shadowstate.LowBatteryDevice{EntityID: "binary_sensor.back_door", Name: "binary_sensor.back_door", Level: 9, Threshold: 20}, published[0])
+	assert.Equal(t, shadowstate.LowBatteryDevice{EntityID: "sensor.hall_motion_battery", Name: "Hall motion battery", Level: 15, Threshold: 20}, published[1])
+	assert.Equal(t, shadowstate.LowBatteryDevice{EntityID: "lock.front_door", Name: "Front door lock", Level: 35, Threshold: 40}, published[2],
+		"locks use their own higher threshold")
+
+	shadow := manager.GetShadowState()
+	assert.Equal(t, 4, shadow.Outputs.BatteriesScanned, "ignored and unavailable batteries are not counted")
+	assert.Len(t, shadow.Outputs.Devices, 3)
+	assert.Empty(t, shadow.Outputs.LastSweepError)
+}
+
+func TestDailySweepPicksUpChanges(t *testing.T) {
+	_, mockHA, stateManager, mockClock := setupTest(t, false)
+
+	mockHA.SetState("sensor.patio_battery", "12", map[string]interface{}{"device_class": "battery"})
+	mockHA.SetState("binary_sensor.back_door", "off", map[string]interface{}{"battery": "100"})
+
+	mockClock.Advance(59 * time.Minute)
+	var published []shadowstate.LowBatteryDevice
+	require.NoError(t, stateManager.GetJSON("lowBatteryDevices", &published))
+	assert.Len(t, published, 3, "nothing changes before the sweep time")
+
+	mockClock.Advance(time.Minute)
+	require.NoError(t, stateManager.GetJSON("lowBatteryDevices", &published))
+	require.Len(t, published, 3)
+	assert.Equal(t, "sensor.patio_battery", published[0].EntityID)
+	for _, device := range published {
+		assert.NotEqual(t, "binary_sensor.back_door", device.EntityID, "replaced battery drops off the list")
+	}
+}
+
+func TestWeeklyReport(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, false)
+
+	// Saturday 09:59 - not yet
+	mockClock.Advance(25*time.Hour + 59*time.Minute)
+	assert.Empty(t, notifyCalls(mockHA))
+
+	// Saturday 10:00
+	mockClock.Advance(time.Minute)
+	calls := notifyCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "notify", calls[0].Service)
+	assert.Equal(t, NotificationTitle, calls[0].Data["title"])
+	assert.Equal(t, "3 batteries are low: binary_sensor.back_door 9%, Hall motion battery 15%, Front door lock 35%", calls[0].Data["message"])
+
+	assert.Equal(t, calls[0].Data["message"], manager.GetShadowState().Outputs.LastReportMessage)
+
+	// Only once a week, despite the daily sweeps
+	mockClock.Advance(6 * 24 * time.Hour)
+	assert.Len(t, notifyCalls(mockHA), 1)
+	mockClock.Advance(24 * time.Hour)
+	assert.Len(t, notifyCalls(mockHA), 2)
+}
+
+func TestWeeklyReport_NothingLow(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false)
+	manager.config.LowBattery.DefaultThreshold = 1
+	manager.config.LowBattery.Thresholds = nil
+	_, err := manager.Sweep()
+	require.NoError(t, err)
+
+	manager.SendReport()
+	assert.Empty(t, notifyCalls(mockHA))
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, true)
+
+	manager.SendReport()
+	assert.Empty(t, notifyCalls(mockHA))
+
+	var published []shadowstate.LowBatteryDevice
+	require.NoError(t, stateManager.GetJSON("lowBatteryDevices", &published))
+	assert.Len(t, published, 3, "the local-only variable is still published")
+	assert.NotEmpty(t, manager.GetShadowState().Outputs.LastReportMessage)
+}
+
+func TestFormatMessage(t *testing.T) {
+	assert.Equal(t, "1 battery is low: Front door lock 35%",
+		formatMessage([]shadowstate.LowBatteryDevice{{Name: "Front door lock", Level: 35}}))
+}
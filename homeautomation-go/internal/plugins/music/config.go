@@ -2,14 +2,240 @@ package music
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
 
 	"gopkg.in/yaml.v3"
 )
 
 // MusicConfig represents the music configuration structure
 type MusicConfig struct {
-	Music map[string]MusicMode `yaml:"music"`
+	Music                map[string]MusicMode          `yaml:"music"`
+	SpeakerGroups        map[string]SpeakerGroupPreset `yaml:"speaker_groups"`
+	PlaybackVerification *PlaybackVerification         `yaml:"playback_verification"` // Optional, playback starts are not checked when unset
+	TasteProfiles        []TasteProfile                `yaml:"taste_profiles"`        // Optional, checked in order; the first match weights playlist selection
+	GroupReconciliation  *GroupReconciliation          `yaml:"group_reconciliation"`  // Optional, the Sonos group is not checked when unset
+	Zones                map[string]Zone               `yaml:"zones"`                 // Optional areas that can play a music mode of their own
+}
+
+// Zone is an area of the house whose speakers can play a music mode of their
+// own, set through the zone's musicPlaybackType.<zone> variable. While the
+// variable is empty the speakers follow the whole-house mode.
+type Zone struct {
+	Speakers     []string        `yaml:"speakers"`       // Player names; the first leads the zone's group
+	BaseVolume   int             `yaml:"base_volume"`    // Used for speakers the zone's mode does not configure
+	LeaveMutedIf []MuteCondition `yaml:"leave_muted_if"` // Added to each speaker's own conditions while the zone plays its mode
+}
+
+// Validate checks the zone's name, speakers and volume
+func (z Zone) Validate(name string) error {
+	if !state.ValidNamespace(name) {
+		return fmt.Errorf("invalid zone name, must be lowercase letters, digits and underscores")
+	}
+	if len(z.Speakers) == 0 {
+		return fmt.Errorf("at least one speaker is required")
+	}
+	if z.BaseVolume < 0 || z.BaseVolume > ha.SonosSteps {
+		return fmt.Errorf("base_volume must be between 0 and %d", ha.SonosSteps)
+	}
+	for i, condition := range z.LeaveMutedIf {
+		if condition.Variable == "" {
+			return fmt.Errorf("leave_muted_if[%d]: variable is required", i)
+		}
+	}
+	return nil
+}
+
+// Group reconciliation policies
+const (
+	// GroupPolicyRejoin joins speakers that dropped out of the group back to the lead
+	GroupPolicyRejoin = "rejoin"
+
+	// GroupPolicyFollow accepts the group as regrouped in the Sonos app,
+	// adopting its speakers as the current participants
+	GroupPolicyFollow = "follow"
+)
+
+// GroupReconciliation periodically compares the lead player's Sonos group
+// with the current participants, catching speakers regrouped from the Sonos app
+type GroupReconciliation struct {
+	Cron   string `yaml:"cron"`   // When to check, e.g. "*/5 * * * *"
+	Policy string `yaml:"policy"` // "rejoin" (default) or "follow"
+}
+
+// EffectivePolicy returns the policy, defaulting to rejoin
+func (r *GroupReconciliation) EffectivePolicy() string {
+	if r.Policy == "" {
+		return GroupPolicyRejoin
+	}
+	return r.Policy
+}
+
+// Validate checks the cron expression and policy
+func (r *GroupReconciliation) Validate() error {
+	if _, err := scheduler.ParseCron(r.Cron); err != nil {
+		return err
+	}
+	switch r.EffectivePolicy() {
+	case GroupPolicyRejoin, GroupPolicyFollow:
+		return nil
+	}
+	return fmt.Errorf("policy must be %q or %q, got %q", GroupPolicyRejoin, GroupPolicyFollow, r.Policy)
+}
+
+// TasteProfile weights playback options by set while a particular combination
+// of people is home, e.g. leaning one set of playlists when only one person
+// is home in the afternoon and another when both are
+type TasteProfile struct {
+	Name        string             `yaml:"name"`
+	Present     []string           `yaml:"present"`      // Presence variables that must be true, e.g. isNickHome
+	Absent      []string           `yaml:"absent"`       // Presence variables that must be false
+	MusicTypes  []string           `yaml:"music_types"`  // Optional, every music mode when empty
+	TimeWindows []TimeWindow       `yaml:"time_windows"` // Optional, any time of day when empty
+	SetWeights  map[string]float64 `yaml:"set_weights"`  // Playback option set -> weight multiplier; 0 leaves the set out
+}
+
+// AppliesTo reports whether the profile covers the music type at t
+func (p TasteProfile) AppliesTo(musicType string, t time.Time) bool {
+	if len(p.MusicTypes) > 0 && !slices.Contains(p.MusicTypes, musicType) {
+		return false
+	}
+	if len(p.TimeWindows) == 0 {
+		return true
+	}
+	for _, window := range p.TimeWindows {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// WeightFor returns the multiplier for an option: the largest weight among
+// the option's sets, or 1 if the profile weights none of them
+func (p TasteProfile) WeightFor(option PlaybackOption) float64 {
+	weight, found := 0.0, false
+	for _, set := range option.Sets {
+		if w, ok := p.SetWeights[set]; ok && (!found || w > weight) {
+			weight, found = w, true
+		}
+	}
+	if !found {
+		return 1
+	}
+	return weight
+}
+
+// Validate checks the profile's people, modes, windows and weights
+func (p TasteProfile) Validate(modes map[string]MusicMode) error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.Present) == 0 && len(p.Absent) == 0 {
+		return fmt.Errorf("at least one present or absent variable is required")
+	}
+	for _, t := range p.MusicTypes {
+		if _, ok := modes[t]; !ok {
+			return fmt.Errorf("unknown music type %q", t)
+		}
+	}
+	for i, window := range p.TimeWindows {
+		if err := window.Validate(); err != nil {
+			return fmt.Errorf("time_windows[%d]: %w", i, err)
+		}
+	}
+	if len(p.SetWeights) == 0 {
+		return fmt.Errorf("at least one set weight is required")
+	}
+	for set, weight := range p.SetWeights {
+		if weight < 0 {
+			return fmt.Errorf("set_weights.%s must not be negative", set)
+		}
+	}
+	return nil
+}
+
+// PlaybackVerification checks that the lead player is playing after a start,
+// retrying the start until FailuresBeforeFallback failed checks in a row
+type PlaybackVerification struct {
+	CheckDelaySeconds      int           `yaml:"check_delay_seconds"`      // Time to wait after a start before checking
+	FailuresBeforeFallback int           `yaml:"failures_before_fallback"` // Consecutive failed checks before moving on to the next playback option
+	WakeFallback           *WakeFallback `yaml:"wake_fallback"`            // Optional TTS alarm when wake music fails to start
+}
+
+// WakeFallback plays a TTS alarm when music for a wake sequence can't be
+// started, so the wake still happens when the music service is down
+type WakeFallback struct {
+	MusicTypes []string `yaml:"music_types"` // Music modes that wake someone up, e.g. "wakeup"
+	Speaker    string   `yaml:"speaker"`     // Player name, e.g. "Bedroom"
+	Volume     int      `yaml:"volume"`      // Same 0-15 scale as base_volume
+	TTSEntity  string   `yaml:"tts_entity"`  // e.g. "tts.google_translate_en_com"
+	Message    string   `yaml:"message"`
+}
+
+// CheckDelay returns how long to wait after a start before checking the lead player
+func (v *PlaybackVerification) CheckDelay() time.Duration {
+	return time.Duration(v.CheckDelaySeconds) * time.Second
+}
+
+// Applies reports whether the fallback covers the music type
+func (f *WakeFallback) Applies(musicType string) bool {
+	for _, t := range f.MusicTypes {
+		if t == musicType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the verification timing and the wake fallback
+func (v *PlaybackVerification) Validate(modes map[string]MusicMode) error {
+	if v.CheckDelaySeconds <= 0 {
+		return fmt.Errorf("check_delay_seconds must be positive")
+	}
+	if v.FailuresBeforeFallback <= 0 {
+		return fmt.Errorf("failures_before_fallback must be positive")
+	}
+
+	f := v.WakeFallback
+	if f == nil {
+		return nil
+	}
+	if len(f.MusicTypes) == 0 {
+		return fmt.Errorf("wake_fallback: at least one music type is required")
+	}
+	for _, t := range f.MusicTypes {
+		if _, ok := modes[t]; !ok {
+			return fmt.Errorf("wake_fallback: unknown music type %q", t)
+		}
+	}
+	if f.Speaker == "" {
+		return fmt.Errorf("wake_fallback: speaker is required")
+	}
+	if f.Volume <= 0 || f.Volume > 15 {
+		return fmt.Errorf("wake_fallback: volume must be between 1 and 15")
+	}
+	if !strings.HasPrefix(f.TTSEntity, "tts.") {
+		return fmt.Errorf("wake_fallback: tts_entity must be a tts.* entity, got %q", f.TTSEntity)
+	}
+	if f.Message == "" {
+		return fmt.Errorf("wake_fallback: message is required")
+	}
+	return nil
+}
+
+// SpeakerGroupPreset is a named set of speakers that can replace the current
+// music mode's participants (e.g., "dinner" plays only in the kitchen and dining room)
+type SpeakerGroupPreset struct {
+	Speakers   []string `yaml:"speakers"`    // Player names; the first is the group leader
+	BaseVolume int      `yaml:"base_volume"` // Used for speakers the current music mode does not configure
 }
 
 // MusicMode represents a specific music mode (morning, day, evening, etc.)
@@ -21,10 +247,52 @@ type MusicMode struct {
 // Participant represents a Sonos speaker configuration for a music mode
 type Participant struct {
 	PlayerName   string          `yaml:"player_name"`
+	EntityID     string          `yaml:"entity_id"` // Optional, derived from player_name when unset; applies wherever the player name is used
 	BaseVolume   int             `yaml:"base_volume"`
 	LeaveMutedIf []MuteCondition `yaml:"leave_muted_if"`
 }
 
+// SpeakerEntityID returns the media player entity ID for a player name: the
+// entity_id configured for the player on any participant, falling back to
+// the name-derived ID when none is set
+func (c *MusicConfig) SpeakerEntityID(speakerName string) string {
+	for _, mode := range c.Music {
+		for _, p := range mode.Participants {
+			if p.PlayerName == speakerName && p.EntityID != "" {
+				return p.EntityID
+			}
+		}
+	}
+	return SpeakerEntityID(speakerName)
+}
+
+// validateSpeakerEntities checks that explicit entity IDs are media players
+// and that each player name and entity ID pair up one to one
+func validateSpeakerEntities(modes map[string]MusicMode) error {
+	entityOf := make(map[string]string)
+	playerOf := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(modes)) {
+		for i, p := range modes[name].Participants {
+			if p.EntityID == "" {
+				continue
+			}
+			field := fmt.Sprintf("music.%s.participants[%d].entity_id", name, i)
+			if !strings.HasPrefix(p.EntityID, "media_player.") || p.EntityID == "media_player." {
+				return fmt.Errorf("%s: must be a media_player.* entity, got %q", field, p.EntityID)
+			}
+			if entity, ok := entityOf[p.PlayerName]; ok && entity != p.EntityID {
+				return fmt.Errorf("%s: player %q is already mapped to %s", field, p.PlayerName, entity)
+			}
+			if player, ok := playerOf[p.EntityID]; ok && player != p.PlayerName {
+				return fmt.Errorf("%s: %s is already used by player %q", field, p.EntityID, player)
+			}
+			entityOf[p.PlayerName] = p.EntityID
+			playerOf[p.EntityID] = p.PlayerName
+		}
+	}
+	return nil
+}
+
 // MuteCondition represents a condition under which a speaker should be muted
 type MuteCondition struct {
 	Variable string      `yaml:"variable"`
@@ -33,9 +301,63 @@ type MuteCondition struct {
 
 // PlaybackOption represents a specific playlist or media to play
 type PlaybackOption struct {
-	URI              string  `yaml:"uri"`
-	MediaType        string  `yaml:"media_type"`
-	VolumeMultiplier float64 `yaml:"volume_multiplier"`
+	URI              string       `yaml:"uri"`
+	MediaType        string       `yaml:"media_type"`
+	VolumeMultiplier float64      `yaml:"volume_multiplier"`
+	TimeWindows      []TimeWindow `yaml:"time_windows"` // Optional, preferred over plain rotation during these windows
+	Weight           float64      `yaml:"weight"`       // Optional, relative share among options in the same window (default 1)
+	Sets             []string     `yaml:"sets"`         // Optional, named sets that taste profiles weight
+}
+
+// TimeWindow is a local time of day range in "HH:MM" format.
+// A window whose end is before its start spans midnight.
+type TimeWindow struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Contains reports whether t's time of day falls within the window
+// (start inclusive, end exclusive). Invalid windows contain nothing.
+func (w TimeWindow) Contains(t time.Time) bool {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if endMinute < startMinute {
+		return minute >= startMinute || minute < endMinute
+	}
+	return minute >= startMinute && minute < endMinute
+}
+
+// Validate checks that both ends are HH:MM and differ
+func (w TimeWindow) Validate() error {
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("invalid start %q, expected HH:MM", w.Start)
+	}
+	if _, err := time.Parse("15:04", w.End); err != nil {
+		return fmt.Errorf("invalid end %q, expected HH:MM", w.End)
+	}
+	if w.Start == w.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	return nil
+}
+
+// EffectiveWeight returns the option's weight, defaulting to 1 when unset
+func (o PlaybackOption) EffectiveWeight() float64 {
+	if o.Weight == 0 {
+		return 1
+	}
+	return o.Weight
 }
 
 // LoadConfig loads the music configuration from a YAML file
@@ -58,5 +380,60 @@ func LoadConfig(path string) (*MusicConfig, error) {
 		}
 	}
 
+	for name, mode := range config.Music {
+		for i, option := range mode.PlaybackOptions {
+			if option.Weight < 0 {
+				return nil, fmt.Errorf("music.%s.playback_options[%d]: weight must not be negative", name, i)
+			}
+			for j, window := range option.TimeWindows {
+				if err := window.Validate(); err != nil {
+					return nil, fmt.Errorf("music.%s.playback_options[%d].time_windows[%d]: %w", name, i, j, err)
+				}
+			}
+		}
+	}
+
+	if err := validateSpeakerEntities(config.Music); err != nil {
+		return nil, err
+	}
+
+	for name, preset := range config.SpeakerGroups {
+		if len(preset.Speakers) == 0 {
+			return nil, fmt.Errorf("speaker group %q has no speakers", name)
+		}
+	}
+
+	if config.PlaybackVerification != nil {
+		if err := config.PlaybackVerification.Validate(config.Music); err != nil {
+			return nil, fmt.Errorf("playback_verification: %w", err)
+		}
+	}
+
+	if config.GroupReconciliation != nil {
+		if err := config.GroupReconciliation.Validate(); err != nil {
+			return nil, fmt.Errorf("group_reconciliation: %w", err)
+		}
+	}
+
+	zoneOf := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(config.Zones)) {
+		zone := config.Zones[name]
+		if err := zone.Validate(name); err != nil {
+			return nil, fmt.Errorf("zones.%s: %w", name, err)
+		}
+		for _, speaker := range zone.Speakers {
+			if other, ok := zoneOf[speaker]; ok {
+				return nil, fmt.Errorf("zones.%s: speaker %q is already in zone %s", name, speaker, other)
+			}
+			zoneOf[speaker] = name
+		}
+	}
+
+	for i, profile := range config.TasteProfiles {
+		if err := profile.Validate(config.Music); err != nil {
+			return nil, fmt.Errorf("taste_profiles[%d]: %w", i, err)
+		}
+	}
+
 	return &config, nil
 }
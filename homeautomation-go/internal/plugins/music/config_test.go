@@ -3,7 +3,9 @@ package music
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -230,3 +232,226 @@ music:
 		t.Errorf("Expected error about missing 'wakeup' mode, got: %v", err)
 	}
 }
+
+func TestLoadConfigSpeakerGroups(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production config: %v", err)
+	}
+
+	dinner, ok := config.SpeakerGroups["dinner"]
+	if !ok {
+		t.Fatal("Expected dinner speaker group preset")
+	}
+	if len(dinner.Speakers) != 2 || dinner.Speakers[0] != "Kitchen" || dinner.Speakers[1] != "Dining Room" {
+		t.Errorf("Expected dinner speakers [Kitchen Dining Room], got %v", dinner.Speakers)
+	}
+
+	focus := config.SpeakerGroups["focus"]
+	if len(focus.Speakers) != 1 || focus.Speakers[0] != "Office" {
+		t.Errorf("Expected focus speakers [Office], got %v", focus.Speakers)
+	}
+
+	// Party includes every speaker used by any music mode
+	party := make(map[string]bool)
+	for _, name := range config.SpeakerGroups["party"].Speakers {
+		party[name] = true
+	}
+	for modeName, mode := range config.Music {
+		for _, p := range mode.Participants {
+			if !party[p.PlayerName] {
+				t.Errorf("Party preset is missing speaker %q from mode %q", p.PlayerName, modeName)
+			}
+		}
+	}
+}
+
+func TestLoadConfigEmptySpeakerGroup(t *testing.T) {
+	data, err := os.ReadFile("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read production config: %v", err)
+	}
+
+	content := string(data) + `
+  empty:
+    speakers: []
+`
+	configPath := filepath.Join(t.TempDir(), "music_config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for speaker group without speakers, got nil")
+	}
+}
+
+func TestSpeakerEntityID(t *testing.T) {
+	config := &MusicConfig{Music: map[string]MusicMode{
+		"morning": {Participants: []Participant{
+			{PlayerName: "Kids Bathroom", EntityID: "media_player.kids_bathroom_2"},
+			{PlayerName: "Kitchen"},
+		}},
+		// The mapping applies in modes that don't repeat it
+		"day": {Participants: []Participant{{PlayerName: "Kids Bathroom"}}},
+	}}
+
+	tests := []struct {
+		speaker string
+		want    string
+	}{
+		{"Kids Bathroom", "media_player.kids_bathroom_2"},
+		{"Kitchen", "media_player.kitchen"},
+		{"Dining Room", "media_player.dining_room"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.speaker, func(t *testing.T) {
+			if got := config.SpeakerEntityID(tt.speaker); got != tt.want {
+				t.Errorf("SpeakerEntityID(%q) = %q, want %q", tt.speaker, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSpeakerEntityIDs(t *testing.T) {
+	data, err := os.ReadFile("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read production config: %v", err)
+	}
+
+	config, err := LoadConfig("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production config: %v", err)
+	}
+	if got := config.SpeakerEntityID("Kids Bathroom"); got != "media_player.kids_bathroom" {
+		t.Errorf("Expected the configured Kids Bathroom entity, got %q", got)
+	}
+
+	const mapped = "        entity_id: media_player.kids_bathroom\n"
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"not a media player", mapped, "        entity_id: light.kids_bathroom\n"},
+		{"player mapped twice", `      - player_name: "Kitchen"
+        base_volume: 9
+`, `      - player_name: "Kitchen"
+        entity_id: media_player.kitchen
+        base_volume: 9
+      - player_name: "Kids Bathroom"
+        entity_id: media_player.kids_bathroom_2
+        base_volume: 7
+`},
+		{"entity shared by players", `      - player_name: "Kitchen"
+        base_volume: 9
+`, `      - player_name: "Kitchen"
+        entity_id: media_player.kids_bathroom
+        base_volume: 9
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Replace(string(data), tt.from, tt.to, 1)
+			if content == string(data) {
+				t.Fatalf("Production config no longer contains %q", tt.from)
+			}
+			configPath := filepath.Join(t.TempDir(), "music_config.yaml")
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			if _, err := LoadConfig(configPath); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 6, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window TimeWindow
+		time   time.Time
+		want   bool
+	}{
+		{"inside", TimeWindow{Start: "08:00", End: "12:00"}, at(9, 30), true},
+		{"start is inclusive", TimeWindow{Start: "08:00", End: "12:00"}, at(8, 0), true},
+		{"end is exclusive", TimeWindow{Start: "08:00", End: "12:00"}, at(12, 0), false},
+		{"before", TimeWindow{Start: "08:00", End: "12:00"}, at(7, 59), false},
+		{"spans midnight late", TimeWindow{Start: "22:00", End: "02:00"}, at(23, 0), true},
+		{"spans midnight early", TimeWindow{Start: "22:00", End: "02:00"}, at(1, 0), true},
+		{"spans midnight outside", TimeWindow{Start: "22:00", End: "02:00"}, at(12, 0), false},
+		{"invalid", TimeWindow{Start: "8am", End: "12:00"}, at(9, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.time); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigTimeWindows(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production config: %v", err)
+	}
+
+	windowed := 0
+	for _, option := range config.Music["day"].PlaybackOptions {
+		if len(option.TimeWindows) > 0 {
+			windowed++
+		}
+	}
+	if windowed == 0 {
+		t.Error("Expected day mode to have playback options with time windows")
+	}
+
+	if w := (PlaybackOption{}).EffectiveWeight(); w != 1 {
+		t.Errorf("Expected default weight 1, got %v", w)
+	}
+}
+
+func TestLoadConfigInvalidTimeWindows(t *testing.T) {
+	data, err := os.ReadFile("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read production config: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"bad start", `start: "08:00"`, `start: "8am"`},
+		{"bad end", `end: "12:00"`, `end: "noon"`},
+		{"empty window", `end: "12:00"`, `end: "08:00"`},
+		{"negative weight", `weight: 2`, `weight: -1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Replace(string(data), tt.from, tt.to, 1)
+			if content == string(data) {
+				t.Fatalf("Production config no longer contains %q", tt.from)
+			}
+			configPath := filepath.Join(t.TempDir(), "music_config.yaml")
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			if _, err := LoadConfig(configPath); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
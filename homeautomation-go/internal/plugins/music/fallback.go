@@ -0,0 +1,106 @@
+package music
+
+import (
+	"errors"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// maxPlaybackFailures bounds the failures kept in the shadow state
+const maxPlaybackFailures = 10
+
+// errPlayMediaFailed marks a play_media call on the lead player that failed,
+// as opposed to failures grouping or muting the speakers
+var errPlayMediaFailed = errors.New("failed to start playback")
+
+// playOption starts whole-house playback of one of the mode's options. When
+// the play_media call fails, the next option in rotation is tried instead.
+func (m *Manager) playOption(musicType string, mode MusicMode, option PlaybackOption, trigger string) error {
+	participants := m.buildParticipants(musicType, mode, option)
+	if len(participants) == 0 && len(mode.Participants) > 0 {
+		m.logger.Info("Skipping playback: all speakers are in do-not-disturb bedrooms or kid mode",
+			zap.String("type", musicType))
+		return nil
+	}
+
+	m.setCurrentlyPlayingURI(option.URI)
+
+	err := m.startPlayback(musicType, option, participants, trigger)
+	if errors.Is(err, errPlayMediaFailed) {
+		if fellBack, fallbackErr := m.fallBackFrom(musicType, option, err.Error()); fellBack {
+			return fallbackErr
+		}
+	}
+	return err
+}
+
+// fallBackFrom records that the option failed to start and plays the next
+// option in the mode's rotation that hasn't failed since the playback was
+// selected, returning that playback's error. fellBack is false once every
+// option has failed.
+func (m *Manager) fallBackFrom(musicType string, failed PlaybackOption, reason string) (fellBack bool, err error) {
+	mode, ok := m.currentConfig().Music[musicType]
+	if !ok {
+		m.recordPlaybackFailure(musicType, failed.URI, reason, "")
+		return false, nil
+	}
+
+	m.mu.Lock()
+	m.failedURIs[failed.URI] = true
+	next, ok := nextUntriedOption(mode.PlaybackOptions, failed.URI, m.failedURIs)
+	m.mu.Unlock()
+
+	if !ok {
+		m.recordPlaybackFailure(musicType, failed.URI, reason, "")
+		return false, nil
+	}
+	m.recordPlaybackFailure(musicType, failed.URI, reason, next.URI)
+
+	m.logger.Warn("Playlist failed to start, falling back to the next playback option",
+		zap.String("type", musicType),
+		zap.String("failed_uri", failed.URI),
+		zap.String("next_uri", next.URI),
+		zap.String("reason", reason))
+
+	return true, m.playOption(musicType, mode, next, "playback_fallback")
+}
+
+// nextUntriedOption returns the first option after the failed one, wrapping
+// around, whose URI isn't in failed
+func nextUntriedOption(options []PlaybackOption, failedURI string, failed map[string]bool) (PlaybackOption, bool) {
+	start := 0
+	for i, option := range options {
+		if option.URI == failedURI {
+			start = i + 1
+			break
+		}
+	}
+	for n := 0; n < len(options); n++ {
+		option := options[(start+n)%len(options)]
+		if !failed[option.URI] {
+			return option, true
+		}
+	}
+	return PlaybackOption{}, false
+}
+
+// recordPlaybackFailure adds the failure to the shadow state, keeping the
+// most recent maxPlaybackFailures
+func (m *Manager) recordPlaybackFailure(musicType, uri, reason, fellBackTo string) {
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	failures := append(m.shadowState.Outputs.PlaybackFailures, shadowstate.PlaybackFailure{
+		MusicType:  musicType,
+		URI:        uri,
+		Reason:     reason,
+		FellBackTo: fellBackTo,
+		FailedAt:   m.timeProvider.Now(),
+	})
+	if len(failures) > maxPlaybackFailures {
+		failures = failures[len(failures)-maxPlaybackFailures:]
+	}
+	m.shadowState.Outputs.PlaybackFailures = failures
+}
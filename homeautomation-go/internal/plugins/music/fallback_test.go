@@ -0,0 +1,164 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+	"homeautomation/internal/testclock"
+
+	"go.uber.org/zap"
+)
+
+// rejectingPlaylistClient fails play_media for the given URIs, like Spotify
+// rejecting a playlist that no longer exists
+type rejectingPlaylistClient struct {
+	*ha.MockClient
+	rejected map[string]bool
+}
+
+func (c *rejectingPlaylistClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	if service == "play_media" {
+		if uri, _ := data["media_content_id"].(string); c.rejected[uri] {
+			return errors.New("playlist not found")
+		}
+	}
+	return c.MockClient.CallService(ctx, domain, service, data)
+}
+
+// createFallbackTestConfig returns day music with three playlists rotated in order
+func createFallbackTestConfig() *MusicConfig {
+	return &MusicConfig{
+		Music: map[string]MusicMode{
+			"day": {
+				Participants: []Participant{
+					{PlayerName: "Kitchen", BaseVolume: 9, LeaveMutedIf: []MuteCondition{}},
+				},
+				PlaybackOptions: []PlaybackOption{
+					{URI: "spotify:playlist:day1", MediaType: "playlist", VolumeMultiplier: 1.0},
+					{URI: "spotify:playlist:day2", MediaType: "playlist", VolumeMultiplier: 1.0},
+					{URI: "spotify:playlist:day3", MediaType: "playlist", VolumeMultiplier: 1.0},
+				},
+			},
+		},
+	}
+}
+
+func TestPlayMediaFailure_FallsBackToNextOption(t *testing.T) {
+	logger := zap.NewNop()
+	client := &rejectingPlaylistClient{
+		MockClient: ha.NewMockClient(),
+		rejected:   map[string]bool{"spotify:playlist:day1": true},
+	}
+	stateManager := state.NewManager(client, logger, false)
+	manager := NewManager(client, stateManager, createFallbackTestConfig(), logger, false, nil)
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+
+	if !manager.isCurrentPlayback("day", "spotify:playlist:day2") {
+		t.Errorf("Expected the next playlist to play, got %+v", manager.currentlyPlaying)
+	}
+
+	failures := manager.GetShadowState().Outputs.PlaybackFailures
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 recorded failure, got %+v", failures)
+	}
+	if failures[0].URI != "spotify:playlist:day1" || failures[0].FellBackTo != "spotify:playlist:day2" || failures[0].MusicType != "day" {
+		t.Errorf("Unexpected failure record: %+v", failures[0])
+	}
+}
+
+func TestPlayMediaFailure_EveryOptionFails(t *testing.T) {
+	logger := zap.NewNop()
+	client := &rejectingPlaylistClient{
+		MockClient: ha.NewMockClient(),
+		rejected: map[string]bool{
+			"spotify:playlist:day1": true,
+			"spotify:playlist:day2": true,
+			"spotify:playlist:day3": true,
+		},
+	}
+	stateManager := state.NewManager(client, logger, false)
+	manager := NewManager(client, stateManager, createFallbackTestConfig(), logger, false, nil)
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err == nil {
+		t.Error("Expected an error once every playlist failed")
+	}
+
+	failures := manager.GetShadowState().Outputs.PlaybackFailures
+	if len(failures) != 3 {
+		t.Fatalf("Expected each playlist's failure recorded, got %+v", failures)
+	}
+	if last := failures[2]; last.URI != "spotify:playlist:day3" || last.FellBackTo != "" {
+		t.Errorf("Expected the last failure without a fallback, got %+v", last)
+	}
+}
+
+func TestVerifyPlaybackStart_FallsBackToNextOption(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	config := createFallbackTestConfig()
+	config.PlaybackVerification = &PlaybackVerification{CheckDelaySeconds: 15, FailuresBeforeFallback: 1}
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	clk := testclock.New(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	manager.SetClock(clk)
+	mockClient.SetState("media_player.kitchen", "idle", nil)
+
+	option := config.Music["day"].PlaybackOptions[0]
+	manager.currentlyPlaying = &CurrentlyPlayingMusic{Type: "day", URI: option.URI, LeadPlayer: "Kitchen"}
+	done := make(chan struct{})
+	go func() {
+		manager.verifyPlaybackStart("day", option, "Kitchen")
+		close(done)
+	}()
+
+	// Fire just the one check; the fallback's own verification is left waiting
+	clk.BlockUntil(1)
+	clk.AdvanceToNext()
+	<-done
+
+	if !manager.isCurrentPlayback("day", "spotify:playlist:day2") {
+		t.Errorf("Expected the next playlist to play, got %+v", manager.currentlyPlaying)
+	}
+	failures := manager.GetShadowState().Outputs.PlaybackFailures
+	if len(failures) != 1 || failures[0].FellBackTo != "spotify:playlist:day2" {
+		t.Errorf("Expected the failure recorded with its fallback, got %+v", failures)
+	}
+}
+
+func TestNextUntriedOption(t *testing.T) {
+	options := createFallbackTestConfig().Music["day"].PlaybackOptions
+
+	tests := []struct {
+		name   string
+		failed []string
+		from   string
+		want   string
+		wantOK bool
+	}{
+		{"next in rotation", []string{"spotify:playlist:day1"}, "spotify:playlist:day1", "spotify:playlist:day2", true},
+		{"wraps around", []string{"spotify:playlist:day3"}, "spotify:playlist:day3", "spotify:playlist:day1", true},
+		{"skips failed", []string{"spotify:playlist:day1", "spotify:playlist:day3"}, "spotify:playlist:day3", "spotify:playlist:day2", true},
+		{"all failed", []string{"spotify:playlist:day1", "spotify:playlist:day2", "spotify:playlist:day3"}, "spotify:playlist:day2", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed := make(map[string]bool)
+			for _, uri := range tt.failed {
+				failed[uri] = true
+			}
+			got, ok := nextUntriedOption(options, tt.from, failed)
+			if ok != tt.wantOK || got.URI != tt.want {
+				t.Errorf("nextUntriedOption() = %q, %v, want %q, %v", got.URI, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package music
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// groupReconciliationJob is the scheduler job comparing the Sonos group with the participants
+const groupReconciliationJob = "music/group_reconciliation"
+
+// groupSettleTime is how long after playback builds a group before it's
+// checked, so joins still in flight aren't mistaken for dropped speakers
+const groupSettleTime = time.Minute
+
+// scheduleGroupReconciliation (re)schedules the group check from the current
+// config, cancelling it when the config no longer has one
+func (m *Manager) scheduleGroupReconciliation() {
+	m.scheduler.Cancel(groupReconciliationJob)
+
+	reconciliation := m.currentConfig().GroupReconciliation
+	if reconciliation == nil {
+		return
+	}
+	if err := m.scheduler.Cron(groupReconciliationJob, reconciliation.Cron, m.ReconcileSpeakerGroup); err != nil {
+		m.logger.Error("Failed to schedule speaker group reconciliation", zap.Error(err))
+		return
+	}
+	m.logger.Info("Scheduled speaker group reconciliation",
+		zap.String("cron", reconciliation.Cron),
+		zap.String("policy", reconciliation.EffectivePolicy()))
+}
+
+// ReconcileSpeakerGroup compares the lead player's Sonos group with the
+// participants of the current playback. Under the rejoin policy speakers
+// that dropped out are joined back; under the follow policy the group as
+// found becomes the participants. Speakers added from the Sonos app are left
+// playing either way. The result is published as groupCheck in the shadow state.
+func (m *Manager) ReconcileSpeakerGroup() {
+	reconciliation := m.currentConfig().GroupReconciliation
+	if reconciliation == nil || m.ctx.Err() != nil {
+		return
+	}
+
+	m.mu.RLock()
+	playing := m.currentlyPlaying
+	groupedAt := m.groupedAt
+	m.mu.RUnlock()
+	if playing == nil {
+		return
+	}
+	now := m.timeProvider.Now()
+	if now.Sub(groupedAt) < groupSettleTime {
+		m.logger.Debug("Skipping speaker group check: group was just built")
+		return
+	}
+
+	check := &shadowstate.GroupCheck{CheckedAt: now, LeadPlayer: playing.LeadPlayer}
+	defer m.recordGroupCheck(check)
+
+	leadEntityID := m.getSpeakerEntityID(playing.LeadPlayer)
+	leadState, err := m.haClient.GetState(m.ctx, leadEntityID)
+	if err != nil || leadState == nil {
+		check.Problem = fmt.Sprintf("failed to read %s", leadEntityID)
+		m.logger.Warn("Failed to read lead player for speaker group check",
+			zap.String("entity_id", leadEntityID),
+			zap.Error(err))
+		return
+	}
+
+	members := groupMembers(leadState)
+	if len(members) > 0 && members[0] != leadEntityID {
+		// Joining the others back would pull them into someone else's group
+		check.Problem = fmt.Sprintf("%s joined the group led by %s", leadEntityID, members[0])
+		m.logger.Warn("Lead player joined another speaker group, leaving it alone",
+			zap.String("lead_player", playing.LeadPlayer),
+			zap.String("group_leader", members[0]))
+		return
+	}
+
+	inGroup := make(map[string]bool, len(members)+1)
+	inGroup[leadEntityID] = true
+	for _, entityID := range members {
+		inGroup[entityID] = true
+	}
+	participating := make(map[string]bool, len(playing.Participants))
+	var missing []ParticipantWithVolume
+	for _, p := range playing.Participants {
+		entityID := m.getSpeakerEntityID(p.PlayerName)
+		participating[entityID] = true
+		if !inGroup[entityID] {
+			missing = append(missing, p)
+			check.Missing = append(check.Missing, p.PlayerName)
+		}
+	}
+	var extra []string
+	for _, entityID := range members {
+		if !participating[entityID] {
+			extra = append(extra, entityID)
+			check.Extra = append(check.Extra, m.speakerNameFor(entityID))
+		}
+	}
+
+	if len(missing) == 0 && len(extra) == 0 {
+		check.InSync = true
+		return
+	}
+
+	m.logger.Warn("Speaker group differs from the participants",
+		zap.String("type", playing.Type),
+		zap.Strings("missing", check.Missing),
+		zap.Strings("extra", check.Extra),
+		zap.String("policy", reconciliation.EffectivePolicy()))
+
+	if reconciliation.EffectivePolicy() == GroupPolicyFollow {
+		m.followSpeakerGroup(playing, missing, extra)
+		check.Action = "followed"
+		m.updateShadowState("follow_group",
+			fmt.Sprintf("Adopted the Sonos group as regrouped outside the music plugin (missing: %s, added: %s)",
+				listOrNone(check.Missing), listOrNone(check.Extra)),
+			"group_reconciliation")
+		return
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+	m.rejoinSpeakers(missing, leadEntityID)
+	check.Action = "rejoined"
+	m.updateShadowState("rejoin_speakers",
+		fmt.Sprintf("Rejoined %s to the group led by %s", strings.Join(check.Missing, ", "), playing.LeadPlayer),
+		"group_reconciliation")
+}
+
+// rejoinSpeakers joins speakers back to the lead and restores their volumes,
+// leaving muted the ones whose mute conditions hold
+func (m *Manager) rejoinSpeakers(participants []ParticipantWithVolume, leadEntityID string) {
+	for _, p := range participants {
+		entityID := m.getSpeakerEntityID(p.PlayerName)
+		m.logger.Info("Rejoining speaker to group", zap.String("speaker", p.PlayerName))
+
+		if err := m.callService("media_player", "join", map[string]interface{}{
+			"entity_id":     entityID,
+			"group_members": []string{leadEntityID},
+		}); err != nil {
+			m.logger.Error("Failed to rejoin speaker to group",
+				zap.String("speaker", p.PlayerName),
+				zap.Error(err))
+			continue
+		}
+
+		volume := ha.Volume{}
+		if m.shouldUnmuteSpeaker(p) {
+			volume = ha.VolumeFromSonos(p.Volume)
+		}
+		if err := m.callService("media_player", "volume_set", volume.ServiceData(entityID)); err != nil {
+			m.logger.Error("Failed to restore rejoined speaker volume",
+				zap.String("speaker", p.PlayerName),
+				zap.Error(err))
+		}
+	}
+}
+
+// followSpeakerGroup makes the group as found the current participants:
+// missing speakers are dropped and extra ones added at their current volume
+func (m *Manager) followSpeakerGroup(playing *CurrentlyPlayingMusic, missing []ParticipantWithVolume, extra []string) {
+	dropped := make(map[string]bool, len(missing))
+	for _, p := range missing {
+		dropped[p.PlayerName] = true
+	}
+
+	participants := make([]ParticipantWithVolume, 0, len(playing.Participants)+len(extra))
+	for _, p := range playing.Participants {
+		if !dropped[p.PlayerName] {
+			participants = append(participants, p)
+		}
+	}
+	for _, entityID := range extra {
+		var volume int
+		if speakerState, err := m.haClient.GetState(m.ctx, entityID); err == nil {
+			if v, ok := ha.VolumeOf(speakerState); ok {
+				volume = v.Sonos()
+			}
+		}
+		participants = append(participants, ParticipantWithVolume{
+			PlayerName:    m.speakerNameFor(entityID),
+			BaseVolume:    volume,
+			Volume:        volume,
+			DefaultVolume: volume,
+		})
+	}
+
+	m.mu.Lock()
+	if m.currentlyPlaying != playing {
+		// Playback changed while the group was being read
+		m.mu.Unlock()
+		return
+	}
+	followed := *playing
+	followed.Participants = participants
+	m.currentlyPlaying = &followed
+	m.mu.Unlock()
+
+	speakers := make([]shadowstate.SpeakerState, 0, len(participants))
+	for _, p := range participants {
+		speakers = append(speakers, shadowstate.SpeakerState{
+			PlayerName:    p.PlayerName,
+			Volume:        p.Volume,
+			BaseVolume:    p.BaseVolume,
+			DefaultVolume: p.DefaultVolume,
+			IsLeader:      p.PlayerName == playing.LeadPlayer,
+		})
+	}
+	m.updateShadowOutputs("", nil, speakers)
+}
+
+// recordGroupCheck publishes the group check in the shadow state
+func (m *Manager) recordGroupCheck(check *shadowstate.GroupCheck) {
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+	m.shadowState.Outputs.GroupCheck = check
+	m.shadowState.Metadata.LastUpdated = check.CheckedAt
+}
+
+// speakerNameFor returns the configured player name for a media player
+// entity, or the entity's object ID for speakers the config doesn't mention
+func (m *Manager) speakerNameFor(entityID string) string {
+	config := m.currentConfig()
+	for _, mode := range config.Music {
+		for _, p := range mode.Participants {
+			if m.getSpeakerEntityID(p.PlayerName) == entityID {
+				return p.PlayerName
+			}
+		}
+	}
+	for _, preset := range config.SpeakerGroups {
+		for _, name := range preset.Speakers {
+			if m.getSpeakerEntityID(name) == entityID {
+				return name
+			}
+		}
+	}
+	return strings.TrimPrefix(entityID, "media_player.")
+}
+
+// groupMembers returns a media player's Sonos group, leader first. A
+// speaker that isn't grouped reports just itself or nothing.
+func groupMembers(s *ha.State) []string {
+	switch members := s.Attributes["group_members"].(type) {
+	case []string:
+		return members
+	case []interface{}:
+		result := make([]string, 0, len(members))
+		for _, member := range members {
+			if entityID, ok := member.(string); ok {
+				result = append(result, entityID)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,233 @@
+package music
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// setupGroupTest returns a manager playing day music on Kitchen (lead),
+// Living Room and Office, grouped well before now
+func setupGroupTest(t *testing.T, policy string) (*Manager, *ha.MockClient) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	config := createSpeakerGroupTestConfig()
+	config.GroupReconciliation = &GroupReconciliation{Cron: "*/5 * * * *", Policy: policy}
+	now := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	manager := NewManager(mockClient, stateManager, config, logger, false, FixedTimeProvider{FixedTime: now})
+
+	manager.currentlyPlaying = &CurrentlyPlayingMusic{
+		Type:       "day",
+		URI:        "spotify:playlist:day1",
+		MediaType:  "playlist",
+		LeadPlayer: "Kitchen",
+		Participants: []ParticipantWithVolume{
+			{PlayerName: "Kitchen", Volume: 9},
+			{PlayerName: "Living Room", Volume: 10},
+			{PlayerName: "Office", Volume: 8},
+		},
+	}
+	manager.groupedAt = now.Add(-10 * time.Minute)
+	return manager, mockClient
+}
+
+// setGroup reports the given Sonos group on the kitchen lead player
+func setGroup(mockClient *ha.MockClient, members ...string) {
+	mockClient.SetMockState("media_player.kitchen", &ha.State{
+		EntityID:   "media_player.kitchen",
+		State:      "playing",
+		Attributes: map[string]interface{}{"group_members": members},
+	})
+}
+
+// joinCalls returns the entities joined to a group
+func joinCalls(mockClient *ha.MockClient) []string {
+	var joined []string
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "media_player" && call.Service == "join" {
+			joined = append(joined, call.Data["entity_id"].(string))
+		}
+	}
+	return joined
+}
+
+func TestReconcileSpeakerGroup_InSync(t *testing.T) {
+	manager, mockClient := setupGroupTest(t, GroupPolicyRejoin)
+	setGroup(mockClient, "media_player.kitchen", "media_player.living_room", "media_player.office")
+
+	manager.ReconcileSpeakerGroup()
+
+	if calls := mockClient.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls for a group in sync, got %+v", calls)
+	}
+	check := manager.GetShadowState().Outputs.GroupCheck
+	if check == nil || !check.InSync || check.Action != "" {
+		t.Errorf("Expected an in-sync group check, got %+v", check)
+	}
+}
+
+func TestReconcileSpeakerGroup_RejoinsDroppedSpeaker(t *testing.T) {
+	manager, mockClient := setupGroupTest(t, "")
+	// Office was pulled out in the Sonos app and Bedroom added
+	setGroup(mockClient, "media_player.kitchen", "media_player.living_room", "media_player.bedroom")
+
+	manager.ReconcileSpeakerGroup()
+
+	if joined := joinCalls(mockClient); len(joined) != 1 || joined[0] != "media_player.office" {
+		t.Fatalf("Expected Office to be rejoined, got %v", joined)
+	}
+	var volume interface{}
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "volume_set" && call.Data["entity_id"] == "media_player.office" {
+			volume = call.Data["volume_level"]
+		}
+	}
+	if volume != ha.VolumeFromSonos(8).Level() {
+		t.Errorf("Expected Office restored to volume 8, got %v", volume)
+	}
+
+	check := manager.GetShadowState().Outputs.GroupCheck
+	if check == nil || check.InSync || check.Action != "rejoined" {
+		t.Fatalf("Expected a rejoined group check, got %+v", check)
+	}
+	if len(check.Missing) != 1 || check.Missing[0] != "Office" || len(check.Extra) != 1 || check.Extra[0] != "bedroom" {
+		t.Errorf("Expected Office missing and bedroom extra, got %+v", check)
+	}
+	if !manager.isPlayingOn("Office") {
+		t.Error("Expected Office to remain a participant under the rejoin policy")
+	}
+}
+
+func TestReconcileSpeakerGroup_FollowsRegrouping(t *testing.T) {
+	manager, mockClient := setupGroupTest(t, GroupPolicyFollow)
+	setGroup(mockClient, "media_player.kitchen", "media_player.living_room", "media_player.dining_room")
+	mockClient.SetMockState("media_player.dining_room", &ha.State{
+		EntityID:   "media_player.dining_room",
+		State:      "playing",
+		Attributes: map[string]interface{}{"volume_level": ha.VolumeFromSonos(5).Level()},
+	})
+
+	manager.ReconcileSpeakerGroup()
+
+	if joined := joinCalls(mockClient); len(joined) != 0 {
+		t.Errorf("Expected no joins under the follow policy, got %v", joined)
+	}
+	if manager.isPlayingOn("Office") {
+		t.Error("Expected Office to be dropped from the participants")
+	}
+	if !manager.isPlayingOn("Dining Room") {
+		t.Error("Expected Dining Room (named from the dinner preset) to be adopted")
+	}
+
+	shadow := manager.GetShadowState()
+	if shadow.Outputs.GroupCheck == nil || shadow.Outputs.GroupCheck.Action != "followed" {
+		t.Fatalf("Expected a followed group check, got %+v", shadow.Outputs.GroupCheck)
+	}
+	if shadow.Outputs.LastActionType != "follow_group" {
+		t.Errorf("Expected follow_group action, got %q", shadow.Outputs.LastActionType)
+	}
+	var adopted bool
+	for _, speaker := range shadow.Outputs.SpeakerGroup {
+		if speaker.PlayerName == "Dining Room" && speaker.Volume == 5 {
+			adopted = true
+		}
+	}
+	if !adopted {
+		t.Errorf("Expected Dining Room at volume 5 in the shadow speaker group, got %+v", shadow.Outputs.SpeakerGroup)
+	}
+}
+
+func TestReconcileSpeakerGroup_Skips(t *testing.T) {
+	t.Run("group just built", func(t *testing.T) {
+		manager, mockClient := setupGroupTest(t, GroupPolicyRejoin)
+		manager.groupedAt = manager.timeProvider.Now().Add(-10 * time.Second)
+		setGroup(mockClient, "media_player.kitchen")
+
+		manager.ReconcileSpeakerGroup()
+
+		if joined := joinCalls(mockClient); len(joined) != 0 {
+			t.Errorf("Expected no joins while the group settles, got %v", joined)
+		}
+		if check := manager.GetShadowState().Outputs.GroupCheck; check != nil {
+			t.Errorf("Expected no group check, got %+v", check)
+		}
+	})
+
+	t.Run("lead joined another group", func(t *testing.T) {
+		manager, mockClient := setupGroupTest(t, GroupPolicyRejoin)
+		setGroup(mockClient, "media_player.bedroom", "media_player.kitchen")
+
+		manager.ReconcileSpeakerGroup()
+
+		if joined := joinCalls(mockClient); len(joined) != 0 {
+			t.Errorf("Expected no joins into another group, got %v", joined)
+		}
+		if check := manager.GetShadowState().Outputs.GroupCheck; check == nil || check.Problem == "" {
+			t.Errorf("Expected the problem to be recorded, got %+v", check)
+		}
+	})
+
+	t.Run("nothing playing", func(t *testing.T) {
+		manager, mockClient := setupGroupTest(t, GroupPolicyRejoin)
+		manager.currentlyPlaying = nil
+
+		manager.ReconcileSpeakerGroup()
+
+		if mockClient.WasGetStateCalled("media_player.kitchen") {
+			t.Error("Expected the group not to be read when nothing is playing")
+		}
+	})
+}
+
+func TestGroupReconciliation_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  GroupReconciliation
+		wantErr bool
+	}{
+		{"default policy", GroupReconciliation{Cron: "*/5 * * * *"}, false},
+		{"follow", GroupReconciliation{Cron: "*/5 * * * *", Policy: GroupPolicyFollow}, false},
+		{"bad cron", GroupReconciliation{Cron: "every 5 minutes"}, true},
+		{"unknown policy", GroupReconciliation{Cron: "*/5 * * * *", Policy: "kick"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGroupReconciliation_Scheduling(t *testing.T) {
+	manager, _ := setupGroupTest(t, GroupPolicyRejoin)
+	manager.currentlyPlaying = nil
+	jobs := scheduler.New(zap.NewNop(), time.UTC)
+	defer jobs.Stop()
+	manager.SetScheduler(jobs)
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if _, ok := jobs.Next(groupReconciliationJob); !ok {
+		t.Fatal("Expected the group reconciliation to be scheduled on start")
+	}
+
+	if err := manager.Reload(createSpeakerGroupTestConfig()); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if _, ok := jobs.Next(groupReconciliationJob); ok {
+		t.Error("Expected reloading without group_reconciliation to cancel the job")
+	}
+
+	manager.Stop()
+}
@@ -0,0 +1,131 @@
+package music
+
+import (
+	"fmt"
+	"strings"
+
+	"homeautomation/internal/events"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// SetKidMode sets the kid mode guard. Speakers in rooms in kid mode play no
+// louder than its cap and sit out the music modes it blocks; modes blocked
+// household-wide aren't played at all.
+func (m *Manager) SetKidMode(guard *kidmode.Guard) {
+	m.kid = guard
+}
+
+// subscribeKidMode re-applies the kid mode limits to the current playback
+// whenever kid mode turns on or off, household-wide or in a room
+func (m *Manager) subscribeKidMode(bus *events.Bus) ([]state.Subscription, error) {
+	if m.kid == nil {
+		return nil, nil
+	}
+
+	variables := []string{kidmode.ActiveVariable}
+	seen := map[string]bool{kidmode.ActiveVariable: true}
+	for _, room := range m.kid.Rooms() {
+		if room.ToggleVariable != "" && !seen[room.ToggleVariable] {
+			seen[room.ToggleVariable] = true
+			variables = append(variables, room.ToggleVariable)
+		}
+	}
+
+	subs := make([]state.Subscription, 0, len(variables))
+	for _, variable := range variables {
+		sub, err := bus.OnBool(variable, func(e events.BoolChanged) { m.handleKidModeChange(e.Key) })
+		if err != nil {
+			for _, s := range subs {
+				s.Unsubscribe()
+			}
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", variable, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// handleKidModeChange moves off a music mode kid mode now blocks, or else
+// brings the playing speakers to their kid mode volumes, in the whole house
+// and then in each zone
+func (m *Manager) handleKidModeChange(key string) {
+	m.applyKidModeToHouse(key)
+	m.applyKidModeToZones(key)
+}
+
+// applyKidModeToHouse applies kid mode to the whole-house playback
+func (m *Manager) applyKidModeToHouse(key string) {
+	m.mu.RLock()
+	playing := m.currentlyPlaying
+	m.mu.RUnlock()
+	if playing == nil || playing.Type == "" {
+		return
+	}
+
+	if m.kid.BlocksMusicMode(playing.Type) {
+		m.logger.Info("Kid mode blocks the playing music mode, re-selecting",
+			zap.String("type", playing.Type),
+			zap.String("trigger", key))
+		m.selectAppropriateMusicModeWithContext(key, false)
+		return
+	}
+
+	participants, adjusted := m.applyKidModeVolumes(playing.Type, playing.Participants)
+	if len(adjusted) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if m.currentlyPlaying != playing {
+		// Playback changed while the volumes were being set
+		m.mu.Unlock()
+		return
+	}
+	updated := *playing
+	updated.Participants = participants
+	m.currentlyPlaying = &updated
+	m.mu.Unlock()
+
+	m.updateShadowOutputs("", nil, speakerStates(participants, playing.LeadPlayer))
+	m.updateShadowState("kid_mode",
+		"Kid mode changed speaker volumes: "+strings.Join(adjusted, ", "), key)
+}
+
+// applyKidModeVolumes sets each participant to its kid mode volume, returning
+// the updated participants and a description of each change
+func (m *Manager) applyKidModeVolumes(musicType string, current []ParticipantWithVolume) ([]ParticipantWithVolume, []string) {
+	participants := append([]ParticipantWithVolume(nil), current...)
+	var adjusted []string
+	for i, p := range participants {
+		volume := m.kidModeVolume(musicType, p)
+		if volume == p.Volume {
+			continue
+		}
+		participants[i].Volume = volume
+		adjusted = append(adjusted, fmt.Sprintf("%s %d→%d", p.PlayerName, p.Volume, volume))
+
+		switch {
+		case !m.shouldUnmuteSpeaker(participants[i]):
+			// Left muted by its own conditions; the new volume applies when they clear
+		case volume == 0:
+			m.muteSpeaker(participants[i])
+		default:
+			m.unmuteSpeaker(participants[i])
+		}
+	}
+	return participants, adjusted
+}
+
+// kidModeVolume returns the volume a participant plays at under kid mode:
+// its default volume, capped in rooms in kid mode, or 0 where kid mode
+// blocks the music mode on the speaker
+func (m *Manager) kidModeVolume(musicType string, p ParticipantWithVolume) int {
+	entityID := m.getSpeakerEntityID(p.PlayerName)
+	if _, ok := m.kid.BlocksMusicModeOn(entityID, musicType); ok {
+		return 0
+	}
+	return m.kid.CapVolume(entityID, p.DefaultVolume)
+}
@@ -0,0 +1,128 @@
+package music
+
+import (
+	"testing"
+
+	"homeautomation/internal/events"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// setupKidModeTest returns a manager with kid mode capping Kitchen and
+// Living Room at 8, and the guest bedroom (Office) in its own room
+func setupKidModeTest(t *testing.T, blocked ...string) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	kidConfig := &kidmode.Config{KidMode: kidmode.Settings{
+		ToggleVariable: "isKidMode",
+		Rooms: []kidmode.Room{
+			{Name: "common_areas", Speakers: []string{"media_player.kitchen", "media_player.living_room"}},
+			{Name: "guest_bedroom", ToggleVariable: "isGuestBedroomKidMode", Speakers: []string{"media_player.office"}},
+		},
+		MaxVolume:         8,
+		BlockedMusicModes: blocked,
+	}}
+
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+	manager.SetKidMode(kidmode.NewGuard(kidConfig, stateManager))
+	return manager, mockClient, stateManager
+}
+
+// volumeSetTo returns the last volume level set on the entity, or -1
+func volumeSetTo(mockClient *ha.MockClient, entityID string) float64 {
+	level := -1.0
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "volume_set" && call.Data["entity_id"] == entityID {
+			level = call.Data["volume_level"].(float64)
+		}
+	}
+	return level
+}
+
+func TestKidMode_CapsVolumes(t *testing.T) {
+	manager, _, stateManager := setupKidModeTest(t)
+	stateManager.SetBool(kidmode.ActiveVariable, true)
+
+	participants := manager.buildParticipants("day", createSpeakerGroupTestConfig().Music["day"], PlaybackOption{VolumeMultiplier: 1.0})
+
+	want := map[string][2]int{
+		"Kitchen":     {8, 9},
+		"Living Room": {8, 10},
+		"Office":      {8, 8},
+	}
+	for _, p := range participants {
+		if got := [2]int{p.Volume, p.DefaultVolume}; got != want[p.PlayerName] {
+			t.Errorf("%s: expected volume/default %v, got %v", p.PlayerName, want[p.PlayerName], got)
+		}
+	}
+}
+
+func TestKidMode_RoomLeavesSpeakerOutOfBlockedMode(t *testing.T) {
+	manager, _, stateManager := setupKidModeTest(t, "day")
+	stateManager.SetBool("isGuestBedroomKidMode", true)
+
+	participants := manager.buildParticipants("day", createSpeakerGroupTestConfig().Music["day"], PlaybackOption{VolumeMultiplier: 1.0})
+
+	if len(participants) != 2 {
+		t.Fatalf("Expected Office to be left out, got %+v", participants)
+	}
+	for _, p := range participants {
+		if p.PlayerName == "Office" {
+			t.Error("Expected Office to sit out day music while the guest bedroom is in kid mode")
+		}
+		if p.Volume != p.DefaultVolume {
+			t.Errorf("Expected %s uncapped outside kid mode, got %d", p.PlayerName, p.Volume)
+		}
+	}
+}
+
+func TestKidMode_AdjustsCurrentPlayback(t *testing.T) {
+	manager, mockClient, stateManager := setupKidModeTest(t)
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	mockClient.ClearServiceCalls()
+
+	stateManager.SetBool(kidmode.ActiveVariable, true)
+	manager.handleKidModeChange(kidmode.ActiveVariable)
+
+	if level := volumeSetTo(mockClient, "media_player.living_room"); level != ha.VolumeFromSonos(8).Level() {
+		t.Errorf("Expected Living Room turned down to 8, got level %v", level)
+	}
+	if level := volumeSetTo(mockClient, "media_player.office"); level != -1 {
+		t.Errorf("Expected Office, already at 8, to be left alone, got level %v", level)
+	}
+	if action := manager.GetShadowState().Outputs.LastActionType; action != "kid_mode" {
+		t.Errorf("Expected kid_mode action, got %q", action)
+	}
+
+	mockClient.ClearServiceCalls()
+	stateManager.SetBool(kidmode.ActiveVariable, false)
+	manager.handleKidModeChange(kidmode.ActiveVariable)
+
+	if level := volumeSetTo(mockClient, "media_player.living_room"); level != ha.VolumeFromSonos(10).Level() {
+		t.Errorf("Expected Living Room restored to 10, got level %v", level)
+	}
+}
+
+func TestKidMode_BlocksRequestedMode(t *testing.T) {
+	manager, mockClient, stateManager := setupKidModeTest(t, "evening")
+	stateManager.SetBool(kidmode.ActiveVariable, true)
+
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "", New: "evening"})
+
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "play_media" {
+			t.Fatalf("Expected blocked evening music not to play, got %+v", call.Data)
+		}
+	}
+	if action := manager.GetShadowState().Outputs.LastActionType; action != "kid_mode_blocked" {
+		t.Errorf("Expected kid_mode_blocked action, got %q", action)
+	}
+}
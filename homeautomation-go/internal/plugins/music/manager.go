@@ -1,19 +1,36 @@
 package music
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"homeautomation/internal/clock"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/events"
+	"homeautomation/internal/fade"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/scheduler"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrUnknownSpeakerGroupPreset is returned when activating a preset that is not configured
+	ErrUnknownSpeakerGroupPreset = errors.New("unknown speaker group preset")
+
+	// ErrNoMusicPlaying is returned when activating a preset while no music mode is active
+	ErrNoMusicPlaying = errors.New("no music mode is active")
+)
+
 // CurrentlyPlayingMusic represents the currently active music playback
 type CurrentlyPlayingMusic struct {
 	Type         string                  `json:"type"`
@@ -34,6 +51,7 @@ type ParticipantWithVolume struct {
 
 // TimeProvider is an interface for getting the current time
 // This allows tests to inject a fixed time instead of using time.Now()
+// Every clock.Clock is one; tests that need time to pass use SetClock instead
 type TimeProvider interface {
 	Now() time.Time
 }
@@ -58,24 +76,52 @@ func (f FixedTimeProvider) Now() time.Time {
 type Manager struct {
 	haClient     ha.HAClient
 	stateManager *state.Manager
-	config       *MusicConfig
+	config       atomic.Pointer[MusicConfig] // Swapped by Reload
 	logger       *zap.Logger
 	readOnly     bool
 	timeProvider TimeProvider
+	clock        clock.Clock         // Times fades, group settling and playback checks
+	timezone     *time.Location      // Used to evaluate playback option time windows
+	dnd          *donotdisturb.Guard // Speakers in do-not-disturb bedrooms are left out, nil if not configured
+	kid          *kidmode.Guard      // Caps volumes and blocks music modes in kid mode, nil if not configured
 
 	// Playback state
-	playlistNumbers    map[string]int // Tracks playlist rotation per music type
+	playlistNumbers    map[string]int             // Tracks playlist rotation per music type
+	failedURIs         map[string]bool            // Options that failed to start since the playback was selected
+	windowCredits      map[string]map[int]float64 // Weighted selection credit per music type and option index
 	currentlyPlaying   *CurrentlyPlayingMusic
 	lastPlaybackTime   time.Time
 	playbackInProgress bool
-	mu                 sync.RWMutex // Protects playback state
+	activePreset       string                            // Speaker group preset replacing the mode's participants, "" for none
+	zonePlaying        map[string]*CurrentlyPlayingMusic // Zones playing a mode of their own
+	groupedAt          time.Time                         // When playback last (re)built the speaker group
+	mu                 sync.RWMutex                      // Protects playback state
 
 	// Shadow state tracking
 	shadowState *shadowstate.MusicShadowState
 	shadowMu    sync.RWMutex // Protects shadow state
+	actions     shadowstate.ActionNotifier
+
+	// Lead player followed for the track now playing
+	trackSub    ha.Subscription
+	trackEntity string
+	trackMu     sync.Mutex // Protects trackSub and trackEntity
 
 	// Subscriptions for cleanup
 	subscriptions []state.Subscription
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Fade-ins and playback verifications in progress; Stop waits for them
+	background sync.WaitGroup
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+
+	scheduler *scheduler.Scheduler // Runs the group reconciliation, shared with other plugins
+	running   atomic.Bool          // Between Start and Stop, so Reload knows whether to reschedule
 }
 
 // NewManager creates a new Music manager
@@ -84,113 +130,204 @@ func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Music
 	if timeProvider == nil {
 		timeProvider = RealTimeProvider{}
 	}
-	return &Manager{
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:                ctx,
+		cancel:             cancel,
 		haClient:           haClient,
 		stateManager:       stateManager,
-		config:             config,
-		logger:             logger.Named("music"),
+		logger:             recorder.Logger(logger.Named("music")),
+		health:             recorder,
 		readOnly:           readOnly,
 		timeProvider:       timeProvider,
+		clock:              clock.NewRealClock(),
+		timezone:           time.Local,
 		playlistNumbers:    make(map[string]int),
+		failedURIs:         make(map[string]bool),
+		windowCredits:      make(map[string]map[int]float64),
+		zonePlaying:        make(map[string]*CurrentlyPlayingMusic),
 		shadowState:        shadowstate.NewMusicShadowState(),
+		scheduler:          scheduler.New(logger, nil),
 		subscriptions:      make([]state.Subscription, 0),
 		playbackInProgress: false,
 	}
+	m.config.Store(config)
+	return m
+}
+
+// SetClock sets the clock fades, group settling and playback checks wait on,
+// and makes it the time provider too, so a test's virtual clock drives both
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+	m.timeProvider = c
+}
+
+// SetTimezone sets the timezone used to evaluate playback option time windows
+func (m *Manager) SetTimezone(tz *time.Location) {
+	if tz != nil {
+		m.timezone = tz
+	}
+}
+
+// SetScheduler sets the scheduler the group reconciliation runs on, shared
+// with other plugins
+func (m *Manager) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+// SetDoNotDisturb sets the per-bedroom do-not-disturb guard. Speakers in
+// bedrooms with the toggle on are left out of playback started afterwards.
+func (m *Manager) SetDoNotDisturb(guard *donotdisturb.Guard) {
+	m.dnd = guard
 }
 
 // Start begins monitoring state changes and managing music playback
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
+	// Playback, fades and verification run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	m.logger.Info("Starting Music Manager")
 
+	bus := m.stateManager.Events()
+
 	// Subscribe to dayPhase changes
-	sub, err := m.stateManager.Subscribe("dayPhase", m.handleStateChange)
+	sub, err := bus.OnString("dayPhase", func(e events.StringChanged) {
+		m.handleStateChange(e.Key, false)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to dayPhase: %w", err)
 	}
 	m.subscriptions = append(m.subscriptions, sub)
 
 	// Subscribe to isAnyoneAsleep changes
-	sub, err = m.stateManager.Subscribe("isAnyoneAsleep", m.handleStateChange)
+	sub, err = bus.OnBool("isAnyoneAsleep", m.handleAnyoneAsleepChange)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to isAnyoneAsleep: %w", err)
 	}
 	m.subscriptions = append(m.subscriptions, sub)
 
 	// Subscribe to isAnyoneHome changes
-	sub, err = m.stateManager.Subscribe("isAnyoneHome", m.handleStateChange)
+	sub, err = bus.OnBool("isAnyoneHome", func(e events.BoolChanged) {
+		m.handleStateChange(e.Key, false)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to isAnyoneHome: %w", err)
 	}
 	m.subscriptions = append(m.subscriptions, sub)
 
 	// Subscribe to musicPlaybackType changes to trigger actual playback
-	sub, err = m.stateManager.Subscribe("musicPlaybackType", m.handleMusicPlaybackTypeChange)
+	sub, err = bus.OnString("musicPlaybackType", m.handleMusicPlaybackTypeChange)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to musicPlaybackType: %w", err)
 	}
 	m.subscriptions = append(m.subscriptions, sub)
 
+	// Subscribe to speakerGroupPreset changes to regroup active playback
+	sub, err = bus.OnString("speakerGroupPreset", m.handleSpeakerGroupPresetChange)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to speakerGroupPreset: %w", err)
+	}
+	m.subscriptions = append(m.subscriptions, sub)
+
 	// Subscribe to all mute condition variables from participant configs
-	muteConditionVars := m.collectMuteConditionVariables()
-	for _, varName := range muteConditionVars {
-		varNameCopy := varName // Capture loop variable
-		sub, err = m.stateManager.Subscribe(varNameCopy, m.handleMuteConditionChange)
+	for varName, value := range m.collectMuteConditionVariables() {
+		sub, err = m.subscribeMuteCondition(bus, varName, value)
 		if err != nil {
 			// Log warning but don't fail - variable might not exist yet
 			m.logger.Warn("Failed to subscribe to mute condition variable",
-				zap.String("variable", varNameCopy),
+				zap.String("variable", varName),
 				zap.Error(err))
 			continue
 		}
 		m.subscriptions = append(m.subscriptions, sub)
 		m.logger.Debug("Subscribed to mute condition variable",
-			zap.String("variable", varNameCopy))
+			zap.String("variable", varName))
+	}
+
+	kidSubs, err := m.subscribeKidMode(bus)
+	if err != nil {
+		return err
 	}
+	m.subscriptions = append(m.subscriptions, kidSubs...)
+
+	zoneSubs, err := m.subscribeZones(bus)
+	if err != nil {
+		return err
+	}
+	m.subscriptions = append(m.subscriptions, zoneSubs...)
+
+	m.running.Store(true)
+	m.scheduleGroupReconciliation()
 
 	// Perform initial music mode selection
 	m.selectAppropriateMusicMode()
 
+	m.health.Started(len(m.subscriptions))
 	m.logger.Info("Music Manager started successfully")
 	return nil
 }
 
 // Stop stops the Music Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping Music Manager")
 
+	m.cancel()
+	m.running.Store(false)
+	m.scheduler.Cancel(groupReconciliationJob)
+	m.unwatchLeadPlayer()
+
 	// Unsubscribe from all subscriptions
 	for _, sub := range m.subscriptions {
 		sub.Unsubscribe()
 	}
 	m.subscriptions = nil
 
+	// Fades and verifications end once they see the cancelled context
+	m.background.Wait()
+
 	m.logger.Info("Music Manager stopped")
 }
 
+// goBackground runs f in a goroutine that Stop waits for
+func (m *Manager) goBackground(f func()) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		f()
+	}()
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handleStateChange processes state changes that should trigger music mode re-evaluation
-func (m *Manager) handleStateChange(key string, oldValue, newValue interface{}) {
-	m.logger.Debug("State change detected",
-		zap.String("key", key),
-		zap.Any("old", oldValue),
-		zap.Any("new", newValue))
-
-	// Detect wake-up event: isAnyoneAsleep changed from true to false
-	// This matches Node-RED behavior where msg.topic and msg.payload are checked:
-	//   if (msg.topic == "isAnyoneAsleep" && msg.payload == false) { ... }
-	isWakeUpEvent := false
-	if key == "isAnyoneAsleep" {
-		oldBool, oldOk := oldValue.(bool)
-		newBool, newOk := newValue.(bool)
-		if oldOk && newOk && oldBool && !newBool {
-			isWakeUpEvent = true
-			m.logger.Info("Wake-up event detected: isAnyoneAsleep changed from true to false")
-		}
-	}
+func (m *Manager) handleStateChange(key string, isWakeUpEvent bool) {
+	m.logger.Debug("State change detected", zap.String("key", key))
 
 	// Re-evaluate music mode with context
 	m.selectAppropriateMusicModeWithContext(key, isWakeUpEvent)
 }
 
+// handleAnyoneAsleepChange re-evaluates music mode, flagging a wake-up when
+// isAnyoneAsleep goes from true to false. This matches Node-RED behavior where
+// msg.topic and msg.payload are checked:
+//
+//	if (msg.topic == "isAnyoneAsleep" && msg.payload == false) { ... }
+func (m *Manager) handleAnyoneAsleepChange(e events.BoolChanged) {
+	isWakeUpEvent := !e.Initial && e.Old && !e.New
+	if isWakeUpEvent {
+		m.logger.Info("Wake-up event detected: isAnyoneAsleep changed from true to false")
+	}
+	m.handleStateChange(e.Key, isWakeUpEvent)
+}
+
 // selectAppropriateMusicMode determines which music mode should be active (without trigger context)
 func (m *Manager) selectAppropriateMusicMode() {
 	m.selectAppropriateMusicModeWithContext("", false)
@@ -198,6 +335,7 @@ func (m *Manager) selectAppropriateMusicMode() {
 
 // selectAppropriateMusicModeWithContext determines which music mode should be active with trigger context
 func (m *Manager) selectAppropriateMusicModeWithContext(triggerKey string, isWakeUpEvent bool) {
+	m.health.Tick()
 	m.logger.Debug("Selecting appropriate music mode",
 		zap.String("trigger_key", triggerKey),
 		zap.Bool("is_wake_up_event", isWakeUpEvent))
@@ -259,6 +397,11 @@ func (m *Manager) selectAppropriateMusicModeWithContext(triggerKey string, isWak
 
 	// Determine music mode based on day phase and trigger context
 	musicMode := m.determineMusicModeFromDayPhase(dayPhase, currentMusicType, triggerKey, isWakeUpEvent)
+	if m.kid.BlocksMusicMode(musicMode) {
+		m.logger.Info("Kid mode blocks the selected music mode, leaving music off",
+			zap.String("music_mode", musicMode))
+		musicMode = ""
+	}
 
 	m.logger.Info("Selected music mode",
 		zap.String("day_phase", dayPhase),
@@ -348,14 +491,20 @@ func (m *Manager) setMusicPlaybackType(musicType string) error {
 
 // handleMusicPlaybackTypeChange is called when musicPlaybackType changes
 // This triggers actual music playback orchestration
-func (m *Manager) handleMusicPlaybackTypeChange(key string, oldValue, newValue interface{}) {
+func (m *Manager) handleMusicPlaybackTypeChange(e events.StringChanged) {
 	m.logger.Info("Music playback type changed, initiating playback",
-		zap.Any("old", oldValue),
-		zap.Any("new", newValue))
+		zap.String("old", e.Old),
+		zap.String("new", e.New))
 
-	newType, ok := newValue.(string)
-	if !ok {
-		m.logger.Error("Invalid musicPlaybackType value type")
+	newType := e.New
+
+	// A blocked mode set by hand falls back to the automatic selection
+	if m.kid.BlocksMusicMode(newType) {
+		m.logger.Info("Kid mode blocks the requested music mode, re-selecting",
+			zap.String("type", newType))
+		m.updateShadowState("kid_mode_blocked",
+			fmt.Sprintf("Kid mode blocks %s music", newType), "musicPlaybackType")
+		m.selectAppropriateMusicMode()
 		return
 	}
 
@@ -381,6 +530,9 @@ func (m *Manager) handleMusicPlaybackTypeChange(key string, oldValue, newValue i
 	}
 	m.mu.RUnlock()
 
+	// A speaker group preset only applies to the mode it was activated in
+	m.clearSpeakerGroupPreset()
+
 	// If empty string, stop playback
 	if newType == "" {
 		m.logger.Info("Stopping music playback")
@@ -396,11 +548,11 @@ func (m *Manager) handleMusicPlaybackTypeChange(key string, oldValue, newValue i
 	}
 }
 
-// collectMuteConditionVariables collects all unique variables from participant mute conditions
+// collectMuteConditionVariables collects all unique variables from participant mute conditions,
+// each with the value its first condition compares against
 // These are variables like isNickOfficeOccupied that need subscriptions for dynamic speaker unmuting
-func (m *Manager) collectMuteConditionVariables() []string {
-	// Use a map to collect unique variables
-	varMap := make(map[string]bool)
+func (m *Manager) collectMuteConditionVariables() map[string]interface{} {
+	varMap := make(map[string]interface{})
 
 	// Standard variables that are already subscribed to via explicit handlers
 	alreadySubscribed := map[string]bool{
@@ -410,50 +562,83 @@ func (m *Manager) collectMuteConditionVariables() []string {
 		"musicPlaybackType": true,
 	}
 
-	for _, mode := range m.config.Music {
-		for _, participant := range mode.Participants {
-			for _, condition := range participant.LeaveMutedIf {
-				if condition.Variable != "" && !alreadySubscribed[condition.Variable] {
-					varMap[condition.Variable] = true
-				}
+	add := func(conditions []MuteCondition) {
+		for _, condition := range conditions {
+			if condition.Variable == "" || alreadySubscribed[condition.Variable] {
+				continue
+			}
+			if _, ok := varMap[condition.Variable]; !ok {
+				varMap[condition.Variable] = condition.Value
 			}
 		}
 	}
 
-	// Convert map to slice
-	result := make([]string, 0, len(varMap))
-	for varName := range varMap {
-		result = append(result, varName)
+	config := m.currentConfig()
+	for _, mode := range config.Music {
+		for _, participant := range mode.Participants {
+			add(participant.LeaveMutedIf)
+		}
+	}
+	for _, zone := range config.Zones {
+		add(zone.LeaveMutedIf)
 	}
 
-	return result
+	return varMap
+}
+
+// subscribeMuteCondition subscribes to a mute condition variable with the event
+// type of the value the condition compares against, so a condition whose value
+// can never match the variable fails here instead of never unmuting
+func (m *Manager) subscribeMuteCondition(bus *events.Bus, variable string, value interface{}) (state.Subscription, error) {
+	switch value.(type) {
+	case bool:
+		return bus.OnBool(variable, func(e events.BoolChanged) { m.handleMuteConditionChange(e.Key) })
+	case string:
+		return bus.OnString(variable, func(e events.StringChanged) { m.handleMuteConditionChange(e.Key) })
+	case int, float64:
+		return bus.OnNumber(variable, func(e events.NumberChanged) { m.handleMuteConditionChange(e.Key) })
+	default:
+		return nil, fmt.Errorf("unsupported mute condition value %v (%T)", value, value)
+	}
 }
 
 // handleMuteConditionChange processes changes to variables used in speaker mute conditions
 // This re-evaluates speaker states during active playback
-func (m *Manager) handleMuteConditionChange(key string, oldValue, newValue interface{}) {
+func (m *Manager) handleMuteConditionChange(key string) {
 	m.logger.Debug("Mute condition variable changed",
-		zap.String("key", key),
-		zap.Any("old", oldValue),
-		zap.Any("new", newValue))
+		zap.String("key", key))
 
-	// Check if music is currently playing
+	// Check if music is currently playing, in the whole house or a zone
 	m.mu.RLock()
-	currentlyPlaying := m.currentlyPlaying
+	playing := make([]*CurrentlyPlayingMusic, 0, 1+len(m.zonePlaying))
+	if m.currentlyPlaying != nil && m.currentlyPlaying.Type != "" {
+		playing = append(playing, m.currentlyPlaying)
+	}
+	for _, zone := range m.zoneNames() {
+		if p := m.zonePlaying[zone]; p != nil {
+			playing = append(playing, p)
+		}
+	}
 	m.mu.RUnlock()
 
-	if currentlyPlaying == nil || currentlyPlaying.Type == "" {
+	if len(playing) == 0 {
 		m.logger.Debug("No music currently playing, ignoring mute condition change",
 			zap.String("key", key))
 		return
 	}
 
-	m.logger.Info("Re-evaluating speaker mute conditions during active playback",
-		zap.String("key", key),
-		zap.String("music_type", currentlyPlaying.Type))
+	for _, p := range playing {
+		m.logger.Info("Re-evaluating speaker mute conditions during active playback",
+			zap.String("key", key),
+			zap.String("music_type", p.Type))
+		m.reevaluateMuteConditions(key, p.Participants)
+	}
+}
 
-	// Re-evaluate each participant's mute conditions
-	for _, participant := range currentlyPlaying.Participants {
+// reevaluateMuteConditions mutes or unmutes the participants whose mute
+// conditions use the changed variable
+func (m *Manager) reevaluateMuteConditions(key string, participants []ParticipantWithVolume) {
+	for _, participant := range participants {
 		// Check if this participant uses the changed variable in their mute conditions
 		usesVariable := false
 		for _, condition := range participant.LeaveMutedIf {
@@ -495,17 +680,14 @@ func (m *Manager) unmuteSpeaker(participant ParticipantWithVolume) {
 	}
 
 	entityID := m.getSpeakerEntityID(participant.PlayerName)
-	volumeLevel := float64(participant.Volume) / 100.0 // Convert to 0.0-1.0 scale
+	volume := ha.VolumeFromSonos(participant.Volume)
 
 	m.logger.Info("Unmuting speaker",
 		zap.String("speaker", participant.PlayerName),
 		zap.Int("target_volume", participant.Volume),
-		zap.Float64("volume_level", volumeLevel))
+		zap.Float64("volume_level", volume.Level()))
 
-	if err := m.callService("media_player", "volume_set", map[string]interface{}{
-		"entity_id":    entityID,
-		"volume_level": volumeLevel,
-	}); err != nil {
+	if err := m.callService("media_player", "volume_set", volume.ServiceData(entityID)); err != nil {
 		m.logger.Error("Failed to unmute speaker",
 			zap.String("speaker", participant.PlayerName),
 			zap.Error(err))
@@ -525,10 +707,7 @@ func (m *Manager) muteSpeaker(participant ParticipantWithVolume) {
 	m.logger.Info("Muting speaker",
 		zap.String("speaker", participant.PlayerName))
 
-	if err := m.callService("media_player", "volume_set", map[string]interface{}{
-		"entity_id":    entityID,
-		"volume_level": 0,
-	}); err != nil {
+	if err := m.callService("media_player", "volume_set", ha.Volume{}.ServiceData(entityID)); err != nil {
 		m.logger.Error("Failed to mute speaker",
 			zap.String("speaker", participant.PlayerName),
 			zap.Error(err))
@@ -540,6 +719,7 @@ func (m *Manager) stopPlayback() {
 	m.mu.Lock()
 	m.currentlyPlaying = nil
 	m.mu.Unlock()
+	m.unwatchLeadPlayer()
 
 	// Clear the currently playing music URI in Home Assistant
 	if err := m.stateManager.SetString("currentlyPlayingMusicUri", ""); err != nil {
@@ -553,22 +733,27 @@ func (m *Manager) stopPlayback() {
 		return
 	}
 
-	// Set all speakers to volume 0
-	for _, mode := range m.config.Music {
+	// Set all speakers to volume 0, except those playing a zone's own mode
+	var mutes []ha.ServiceCall
+	for _, mode := range m.currentConfig().Music {
 		for _, participant := range mode.Participants {
-			entityID := m.getSpeakerEntityID(participant.PlayerName)
-			if err := m.callService("media_player", "volume_set", map[string]interface{}{
-				"entity_id":    entityID,
-				"volume_level": 0,
-			}); err != nil {
-				m.logger.Error("Failed to set speaker volume to 0",
-					zap.String("speaker", participant.PlayerName),
-					zap.Error(err))
+			if _, ok := m.claimingZone(participant.PlayerName); ok {
+				continue
 			}
+			entityID := m.getSpeakerEntityID(participant.PlayerName)
+			mutes = append(mutes, ha.ServiceCall{Domain: "media_player", Service: "volume_set", Data: ha.Volume{}.ServiceData(entityID)})
 		}
 	}
+	if err := m.callServices(mutes); err != nil {
+		m.logger.Error("Failed to set speaker volume to 0", zap.Error(err))
+	}
 
 	m.logger.Info("Music playback stopped")
+	m.stateManager.Events().PublishPluginAction(events.PluginAction{
+		Plugin: "music",
+		Action: "stop",
+		Time:   m.timeProvider.Now(),
+	})
 }
 
 // orchestratePlayback coordinates the complete playback flow
@@ -576,13 +761,13 @@ func (m *Manager) orchestratePlayback(musicType string, trigger string) error {
 	m.logger.Info("Orchestrating playback", zap.String("type", musicType), zap.String("trigger", trigger))
 
 	// Get the music mode configuration
-	mode, ok := m.config.Music[musicType]
+	mode, ok := m.currentConfig().Music[musicType]
 	if !ok {
 		return fmt.Errorf("unknown music type: %s", musicType)
 	}
 
-	// Select playlist with rotation
-	playlistIndex := m.getNextPlaylistIndex(musicType, len(mode.PlaybackOptions))
+	// Select playlist by time window, falling back to rotation
+	playlistIndex := m.selectPlaylistIndex(musicType, mode.PlaybackOptions)
 	playbackOption := mode.PlaybackOptions[playlistIndex]
 
 	m.logger.Info("Selected playlist",
@@ -591,31 +776,101 @@ func (m *Manager) orchestratePlayback(musicType string, trigger string) error {
 		zap.String("uri", playbackOption.URI),
 		zap.Float64("volume_multiplier", playbackOption.VolumeMultiplier))
 
-	// Set the currently playing music URI in Home Assistant
-	if err := m.stateManager.SetString("currentlyPlayingMusicUri", playbackOption.URI); err != nil {
+	m.mu.Lock()
+	m.failedURIs = make(map[string]bool)
+	m.mu.Unlock()
+
+	return m.playOption(musicType, mode, playbackOption, trigger)
+}
+
+// setCurrentlyPlayingURI sets the currently playing music URI in Home Assistant
+func (m *Manager) setCurrentlyPlayingURI(uri string) {
+	if err := m.stateManager.SetString("currentlyPlayingMusicUri", uri); err != nil {
 		if errors.Is(err, state.ErrReadOnlyMode) {
 			m.logger.Debug("Skipping URI update in read-only mode",
-				zap.String("uri", playbackOption.URI))
+				zap.String("uri", uri))
 		} else {
 			m.logger.Error("Failed to set currently playing music URI",
-				zap.String("uri", playbackOption.URI),
+				zap.String("uri", uri),
 				zap.Error(err))
 		}
 	}
+}
+
+// buildParticipants builds the speakers for a playback option with calculated volumes.
+// If a speaker group preset is active, its speakers replace the mode's participants;
+// speakers the mode also configures keep their base volume and mute conditions.
+// Speakers playing a zone's own mode are left to the zone.
+func (m *Manager) buildParticipants(musicType string, mode MusicMode, option PlaybackOption) []ParticipantWithVolume {
+	m.mu.RLock()
+	preset, usePreset := m.currentConfig().SpeakerGroups[m.activePreset]
+	m.mu.RUnlock()
+
+	sources := mode.Participants
+	if usePreset {
+		configured := make(map[string]Participant, len(mode.Participants))
+		for _, p := range mode.Participants {
+			configured[p.PlayerName] = p
+		}
+
+		sources = make([]Participant, 0, len(preset.Speakers))
+		for _, name := range preset.Speakers {
+			p, ok := configured[name]
+			if !ok {
+				p = Participant{PlayerName: name, BaseVolume: preset.BaseVolume}
+			}
+			sources = append(sources, p)
+		}
+	}
+
+	house := make([]Participant, 0, len(sources))
+	for _, p := range sources {
+		if zone, ok := m.claimingZone(p.PlayerName); ok {
+			m.logger.Info("Leaving out speaker playing its zone's own mode",
+				zap.String("speaker", p.PlayerName),
+				zap.String("zone", zone))
+			continue
+		}
+		house = append(house, p)
+	}
+	return m.participantsWithVolume(musicType, house, option)
+}
 
-	// Build participants with calculated volumes
-	participants := make([]ParticipantWithVolume, 0, len(mode.Participants))
-	for _, p := range mode.Participants {
-		volume := m.calculateVolume(p.BaseVolume, playbackOption.VolumeMultiplier)
+// participantsWithVolume calculates the speakers' volumes for a playback
+// option, leaving out speakers in do-not-disturb bedrooms and in kid mode
+// rooms that block the music mode. In kid mode, Volume is capped while
+// DefaultVolume keeps the uncapped volume to return to when kid mode ends.
+func (m *Manager) participantsWithVolume(musicType string, sources []Participant, option PlaybackOption) []ParticipantWithVolume {
+	participants := make([]ParticipantWithVolume, 0, len(sources))
+	for _, p := range sources {
+		entityID := m.getSpeakerEntityID(p.PlayerName)
+		if bedroom, ok := m.dnd.Suppresses(entityID); ok {
+			m.logger.Info("Leaving out speaker in do-not-disturb bedroom",
+				zap.String("speaker", p.PlayerName),
+				zap.String("bedroom", bedroom))
+			continue
+		}
+		if room, ok := m.kid.BlocksMusicModeOn(entityID, musicType); ok {
+			m.logger.Info("Leaving out speaker in kid mode room",
+				zap.String("speaker", p.PlayerName),
+				zap.String("room", room),
+				zap.String("type", musicType))
+			continue
+		}
+		volume := m.calculateVolume(p.BaseVolume, option.VolumeMultiplier)
 		participants = append(participants, ParticipantWithVolume{
 			PlayerName:    p.PlayerName,
 			BaseVolume:    p.BaseVolume,
-			Volume:        volume,
+			Volume:        m.kid.CapVolume(entityID, volume),
 			DefaultVolume: volume,
 			LeaveMutedIf:  p.LeaveMutedIf,
 		})
 	}
+	return participants
+}
 
+// startPlayback records the new playback and runs the playback sequence on the given participants
+func (m *Manager) startPlayback(musicType string, playbackOption PlaybackOption, participants []ParticipantWithVolume, trigger string) error {
 	// Get lead player (first participant)
 	if len(participants) == 0 {
 		return fmt.Errorf("no participants for music type: %s", musicType)
@@ -631,7 +886,9 @@ func (m *Manager) orchestratePlayback(musicType string, trigger string) error {
 		LeadPlayer:   leadPlayer,
 		Participants: participants,
 	}
+	m.groupedAt = m.timeProvider.Now()
 	m.mu.Unlock()
+	m.watchLeadPlayer(musicType, playbackOption.URI, leadPlayer)
 
 	if m.readOnly {
 		m.logger.Info("Read-only mode: would start playback",
@@ -644,16 +901,129 @@ func (m *Manager) orchestratePlayback(musicType string, trigger string) error {
 	}
 
 	// Execute playback sequence
-	if err := m.executePlayback(musicType, playbackOption, participants, leadPlayer); err != nil {
+	if err := m.executePlayback("", musicType, playbackOption, participants, leadPlayer); err != nil {
 		return fmt.Errorf("failed to execute playback: %w", err)
 	}
 
 	// Record shadow state after successful playback
 	m.recordPlaybackShadowState(musicType, playbackOption, participants, leadPlayer, trigger)
+	m.stateManager.Events().PublishPluginAction(events.PluginAction{
+		Plugin: "music",
+		Action: "play",
+		Reason: fmt.Sprintf("%s music (triggered by %s)", musicType, trigger),
+		Time:   m.timeProvider.Now(),
+	})
+
+	if m.currentConfig().PlaybackVerification != nil {
+		m.goBackground(func() { m.verifyPlaybackStart(musicType, playbackOption, leadPlayer) })
+	}
 
 	return nil
 }
 
+// selectPlaylistIndex picks a playback option for the music type. Options with a
+// time window containing the current time are preferred and chosen by weight;
+// if none match, the mode's options are rotated as usual. A taste profile
+// matching who is home multiplies the weights, and when no window matches it
+// chooses among all options by weight instead of rotating.
+func (m *Manager) selectPlaylistIndex(musicType string, options []PlaybackOption) int {
+	now := m.timeProvider.Now().In(m.timezone)
+
+	candidates := make([]int, 0, len(options))
+	for i, option := range options {
+		for _, window := range option.TimeWindows {
+			if window.Contains(now) {
+				candidates = append(candidates, i)
+				break
+			}
+		}
+	}
+	inWindow := len(candidates) > 0
+	if !inWindow {
+		for i := range options {
+			candidates = append(candidates, i)
+		}
+	}
+
+	weights := make(map[int]float64, len(candidates))
+	for _, i := range candidates {
+		weights[i] = options[i].EffectiveWeight()
+	}
+
+	creditKey := musicType
+	profile, peopleHome := m.matchTasteProfile(musicType, now)
+	if profile != nil {
+		blended := make(map[int]float64, len(candidates))
+		total := 0.0
+		for _, i := range candidates {
+			blended[i] = weights[i] * profile.WeightFor(options[i])
+			total += blended[i]
+		}
+		// A profile that leaves out every candidate is ignored
+		if total > 0 {
+			weights = blended
+			creditKey = musicType + "/" + profile.Name
+		} else {
+			m.logger.Warn("Taste profile gives every candidate playlist zero weight, ignoring it",
+				zap.String("type", musicType),
+				zap.String("profile", profile.Name))
+			profile = nil
+		}
+	}
+
+	if profile == nil && !inWindow {
+		index := m.getNextPlaylistIndex(musicType, len(options))
+		m.recordTasteBlend(musicType, nil, peopleHome, nil, options[index].URI,
+			"No taste profile applies to who is home; rotated playlists", now)
+		return index
+	}
+
+	index := m.nextWeightedIndex(creditKey, candidates, weights)
+
+	uriWeights := make(map[string]float64, len(candidates))
+	for _, i := range candidates {
+		uriWeights[options[i].URI] += weights[i]
+	}
+	m.recordTasteBlend(musicType, profile, peopleHome, uriWeights, options[index].URI,
+		tasteRationale(profile, peopleHome, inWindow), now)
+
+	m.logger.Debug("Selected playlist by weight",
+		zap.String("type", musicType),
+		zap.Int("candidates", len(candidates)),
+		zap.Bool("time_window", inWindow),
+		zap.Bool("taste_profile", profile != nil),
+		zap.String("time", now.Format("15:04")))
+	return index
+}
+
+// nextWeightedIndex chooses among the candidate options using smooth weighted
+// round-robin, so each option's share of plays matches its weight without
+// repeating the same playlist back to back more than necessary
+func (m *Manager) nextWeightedIndex(creditKey string, candidates []int, weights map[int]float64) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credits, ok := m.windowCredits[creditKey]
+	if !ok {
+		credits = make(map[int]float64)
+		m.windowCredits[creditKey] = credits
+	}
+
+	best := -1
+	total := 0.0
+	for _, i := range candidates {
+		weight := weights[i]
+		total += weight
+		credits[i] += weight
+		if best == -1 || credits[i] > credits[best] {
+			best = i
+		}
+	}
+	credits[best] -= total
+
+	return best
+}
+
 // getNextPlaylistIndex returns the next playlist index with rotation
 func (m *Manager) getNextPlaylistIndex(musicType string, optionsCount int) int {
 	m.mu.Lock()
@@ -681,9 +1051,9 @@ func (m *Manager) getNextPlaylistIndex(musicType string, optionsCount int) int {
 // calculateVolume calculates final volume from base and multiplier
 func (m *Manager) calculateVolume(baseVolume int, multiplier float64) int {
 	volume := math.Round(float64(baseVolume) * multiplier)
-	// Cap at 15 (Sonos max for Spotify playback scale)
-	if volume > 15 {
-		volume = 15
+	// Cap at the Sonos max for Spotify playback scale
+	if volume > ha.SonosSteps {
+		volume = ha.SonosSteps
 	}
 	if volume < 0 {
 		volume = 0
@@ -691,8 +1061,9 @@ func (m *Manager) calculateVolume(baseVolume int, multiplier float64) int {
 	return int(volume)
 }
 
-// executePlayback executes the actual playback sequence
-func (m *Manager) executePlayback(musicType string, option PlaybackOption, participants []ParticipantWithVolume, leadPlayer string) error {
+// executePlayback executes the actual playback sequence for the whole house,
+// or for a zone playing its own mode
+func (m *Manager) executePlayback(zone string, musicType string, option PlaybackOption, participants []ParticipantWithVolume, leadPlayer string) error {
 	m.logger.Info("Executing playback sequence",
 		zap.String("type", musicType),
 		zap.String("lead_player", leadPlayer),
@@ -708,16 +1079,13 @@ func (m *Manager) executePlayback(musicType string, option PlaybackOption, parti
 	}
 
 	// Step 2: Mute all speakers initially
+	mutes := make([]ha.ServiceCall, 0, len(participants))
 	for _, p := range participants {
 		entityID := m.getSpeakerEntityID(p.PlayerName)
-		if err := m.callService("media_player", "volume_set", map[string]interface{}{
-			"entity_id":    entityID,
-			"volume_level": 0,
-		}); err != nil {
-			m.logger.Error("Failed to mute speaker",
-				zap.String("speaker", p.PlayerName),
-				zap.Error(err))
-		}
+		mutes = append(mutes, ha.ServiceCall{Domain: "media_player", Service: "volume_set", Data: ha.Volume{}.ServiceData(entityID)})
+	}
+	if err := m.callServices(mutes); err != nil {
+		m.logger.Error("Failed to mute speakers", zap.Error(err))
 	}
 
 	// Step 3: Start playback on lead player
@@ -726,7 +1094,7 @@ func (m *Manager) executePlayback(musicType string, option PlaybackOption, parti
 		"media_content_id":   option.URI,
 		"media_content_type": option.MediaType,
 	}); err != nil {
-		return fmt.Errorf("failed to start playback: %w", err)
+		return fmt.Errorf("%w: %w", errPlayMediaFailed, err)
 	}
 
 	// Step 4: Enable shuffle for Spotify playlists
@@ -741,20 +1109,22 @@ func (m *Manager) executePlayback(musicType string, option PlaybackOption, parti
 		}
 	}
 
-	// Step 5: Evaluate mute conditions and unmute eligible speakers
+	// Step 5: Evaluate mute conditions and fade in eligible speakers together
+	var unmuted []ParticipantWithVolume
 	for _, p := range participants {
 		if m.shouldUnmuteSpeaker(p) {
 			m.logger.Info("Unmuting speaker",
 				zap.String("speaker", p.PlayerName),
 				zap.Int("target_volume", p.Volume))
-
-			// Start fade-in in goroutine
-			go m.fadeInSpeaker(p.PlayerName, p.Volume, musicType)
+			unmuted = append(unmuted, p)
 		} else {
 			m.logger.Info("Keeping speaker muted due to conditions",
 				zap.String("speaker", p.PlayerName))
 		}
 	}
+	if len(unmuted) > 0 {
+		m.goBackground(func() { m.fadeInSpeakers(zone, unmuted, musicType) })
+	}
 
 	m.logger.Info("Playback sequence completed successfully",
 		zap.String("type", musicType))
@@ -785,7 +1155,11 @@ func (m *Manager) buildSpeakerGroup(participants []ParticipantWithVolume, leadEn
 	}
 
 	// Wait for group to stabilize
-	time.Sleep(500 * time.Millisecond)
+	select {
+	case <-m.clock.After(500 * time.Millisecond):
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	}
 
 	return nil
 }
@@ -822,53 +1196,276 @@ func (m *Manager) shouldUnmuteSpeaker(participant ParticipantWithVolume) bool {
 	return true
 }
 
-// fadeInSpeaker gradually increases speaker volume
-func (m *Manager) fadeInSpeaker(speakerName string, targetVolume int, startingMusicType string) {
-	m.logger.Debug("Starting fade-in",
-		zap.String("speaker", speakerName),
-		zap.Int("target_volume", targetVolume))
+// fadeInSpeakers gradually raises the speakers from silence to their target
+// volumes, one Sonos step at a time. A speaker stops fading if it leaves the
+// group, and all stop if the music type changes. A zone's fade follows the
+// zone's own music type and speakers.
+func (m *Manager) fadeInSpeakers(zone string, participants []ParticipantWithVolume, startingMusicType string) {
+	targets := make([]fade.Target, 0, len(participants))
+	names := make(map[string]string, len(participants))
+	for _, p := range participants {
+		entityID := m.getSpeakerEntityID(p.PlayerName)
+		names[entityID] = p.PlayerName
+		targets = append(targets, fade.Target{
+			EntityID: entityID,
+			To:       ha.VolumeFromSonos(p.Volume),
+			Steps:    p.Volume,
+		})
+		m.logger.Debug("Starting fade-in",
+			zap.String("speaker", p.PlayerName),
+			zap.Int("target_volume", p.Volume))
+	}
+
+	typeVariable := "musicPlaybackType"
+	if zone != "" {
+		typeVariable = ZoneVariable(zone)
+	}
+
+	err := fade.Run(m.ctx, m.clock, targets, fade.Options{
+		Resolution: ha.SonosSteps,
+		// Adaptive delay: slower at start, faster as volume increases.
+		// Matches Node-RED's (100 - current) * 250ms, scaled down to ~2ms per
+		// point with at least 100ms between steps
+		Interval: fade.Adaptive(100, 2*time.Millisecond, 100*time.Millisecond),
+		SetVolume: func(entityID string, volume ha.Volume) {
+			if err := m.callService("media_player", "volume_set", volume.ServiceData(entityID)); err != nil {
+				m.logger.Error("Failed to set volume during fade-in",
+					zap.String("speaker", names[entityID]),
+					zap.Int("volume", volume.Sonos()),
+					zap.Error(err))
+			}
+		},
+		Continue: func(entityID string) bool {
+			speakerName := names[entityID]
+
+			// Check if music type changed (stop fade if switched)
+			musicType, err := m.stateManager.GetString(typeVariable)
+			if err == nil && musicType != startingMusicType {
+				m.logger.Info("Music type changed during fade-in, stopping",
+					zap.String("speaker", speakerName),
+					zap.String("starting_type", startingMusicType),
+					zap.String("current_type", musicType))
+				return false
+			}
+
+			// Stop if the speaker was released from the group (e.g., by a speaker group preset)
+			if !m.isPlayingOnIn(zone, speakerName) {
+				m.logger.Info("Speaker left the group during fade-in, stopping",
+					zap.String("speaker", speakerName))
+				return false
+			}
+			return true
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	m.logger.Info("Fade-in completed", zap.Int("speakers", len(participants)))
+}
+
+// GetSpeakerGroupPresets returns the configured speaker group presets and their speakers
+func (m *Manager) GetSpeakerGroupPresets() map[string][]string {
+	config := m.currentConfig()
+	presets := make(map[string][]string, len(config.SpeakerGroups))
+	for name, preset := range config.SpeakerGroups {
+		presets[name] = append([]string(nil), preset.Speakers...)
+	}
+	return presets
+}
 
-	entityID := m.getSpeakerEntityID(speakerName)
+// GetActiveSpeakerGroupPreset returns the active speaker group preset, or "" when
+// the current music mode's own speakers are playing
+func (m *Manager) GetActiveSpeakerGroupPreset() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.activePreset
+}
+
+// ActivateSpeakerGroupPreset requests a speaker group preset through the
+// speakerGroupPreset state variable. An empty name returns playback to the
+// current music mode's speakers.
+func (m *Manager) ActivateSpeakerGroupPreset(name string) error {
+	if name != "" {
+		if _, ok := m.currentConfig().SpeakerGroups[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownSpeakerGroupPreset, name)
+		}
 
-	// Gradual fade-in: 0 → targetVolume
-	for currentVolume := 0; currentVolume <= targetVolume; currentVolume++ {
-		// Check if music type changed (stop fade if switched)
 		musicType, err := m.stateManager.GetString("musicPlaybackType")
-		if err == nil && musicType != startingMusicType {
-			m.logger.Info("Music type changed during fade-in, stopping",
-				zap.String("speaker", speakerName),
-				zap.String("starting_type", startingMusicType),
-				zap.String("current_type", musicType))
+		if err != nil {
+			return fmt.Errorf("failed to get music playback type: %w", err)
+		}
+		if musicType == "" {
+			return ErrNoMusicPlaying
+		}
+	}
+
+	return m.stateManager.SetString("speakerGroupPreset", name)
+}
+
+// handleSpeakerGroupPresetChange regroups active playback onto the requested preset's speakers
+func (m *Manager) handleSpeakerGroupPresetChange(e events.StringChanged) {
+	presetName := e.New
+
+	m.mu.Lock()
+	if presetName == m.activePreset {
+		m.mu.Unlock()
+		return
+	}
+	current := m.currentlyPlaying
+	if presetName != "" {
+		if _, ok := m.currentConfig().SpeakerGroups[presetName]; !ok {
+			m.mu.Unlock()
+			m.logger.Error("Unknown speaker group preset", zap.String("preset", presetName))
 			return
 		}
+		if current == nil {
+			m.mu.Unlock()
+			m.logger.Warn("No music playing, ignoring speaker group preset",
+				zap.String("preset", presetName))
+			return
+		}
+	}
+	m.activePreset = presetName
+	m.mu.Unlock()
+
+	if current == nil {
+		return
+	}
+
+	m.logger.Info("Applying speaker group preset",
+		zap.String("preset", presetName),
+		zap.String("music_type", current.Type))
+
+	if err := m.regroupPlayback(current.Type, "speakerGroupPreset"); err != nil {
+		m.logger.Error("Failed to apply speaker group preset",
+			zap.String("preset", presetName),
+			zap.Error(err))
+	}
+}
+
+// clearSpeakerGroupPreset deactivates any active speaker group preset without regrouping
+func (m *Manager) clearSpeakerGroupPreset() {
+	m.mu.Lock()
+	previous := m.activePreset
+	m.activePreset = ""
+	m.mu.Unlock()
+
+	if previous == "" {
+		return
+	}
+
+	m.logger.Info("Music mode changed, clearing speaker group preset",
+		zap.String("preset", previous))
+
+	if err := m.stateManager.SetString("speakerGroupPreset", ""); err != nil {
+		m.logger.Error("Failed to clear speaker group preset", zap.Error(err))
+	}
+}
+
+// regroupPlayback restarts the current music mode's playlist on the speakers
+// selected by the active preset (or the mode's own participants if none)
+func (m *Manager) regroupPlayback(musicType string, trigger string) error {
+	mode, ok := m.currentConfig().Music[musicType]
+	if !ok {
+		return fmt.Errorf("unknown music type: %s", musicType)
+	}
+	if len(mode.PlaybackOptions) == 0 {
+		return fmt.Errorf("no playback options for music type: %s", musicType)
+	}
+
+	// Keep the playlist that is already playing when possible
+	m.mu.RLock()
+	previous := m.currentlyPlaying
+	m.mu.RUnlock()
+
+	var playbackOption *PlaybackOption
+	if previous != nil && previous.Type == musicType {
+		for i := range mode.PlaybackOptions {
+			if mode.PlaybackOptions[i].URI == previous.URI {
+				playbackOption = &mode.PlaybackOptions[i]
+				break
+			}
+		}
+	}
+	if playbackOption == nil {
+		playbackOption = &mode.PlaybackOptions[m.selectPlaylistIndex(musicType, mode.PlaybackOptions)]
+		m.setCurrentlyPlayingURI(playbackOption.URI)
+	}
+
+	participants := m.buildParticipants(musicType, mode, *playbackOption)
+	if previous != nil && !m.readOnly {
+		m.releaseSpeakers(previous.Participants, participants)
+	}
+
+	return m.startPlayback(musicType, *playbackOption, participants, trigger)
+}
+
+// releaseSpeakers mutes and ungroups speakers that are not part of the new participants
+func (m *Manager) releaseSpeakers(previous []ParticipantWithVolume, next []ParticipantWithVolume) {
+	keep := make(map[string]bool, len(next))
+	for _, p := range next {
+		keep[p.PlayerName] = true
+	}
+
+	for _, p := range previous {
+		if keep[p.PlayerName] {
+			continue
+		}
 
-		// Set volume
-		if err := m.callService("media_player", "volume_set", map[string]interface{}{
-			"entity_id":    entityID,
-			"volume_level": float64(currentVolume) / 15.0, // Normalize to 0.0-1.0
+		entityID := m.getSpeakerEntityID(p.PlayerName)
+		m.logger.Info("Releasing speaker from group", zap.String("speaker", p.PlayerName))
+
+		if err := m.callService("media_player", "volume_set", ha.Volume{}.ServiceData(entityID)); err != nil {
+			m.logger.Error("Failed to mute released speaker",
+				zap.String("speaker", p.PlayerName),
+				zap.Error(err))
+		}
+		if err := m.callService("media_player", "unjoin", map[string]interface{}{
+			"entity_id": entityID,
 		}); err != nil {
-			m.logger.Error("Failed to set volume during fade-in",
-				zap.String("speaker", speakerName),
-				zap.Int("volume", currentVolume),
+			m.logger.Error("Failed to unjoin released speaker",
+				zap.String("speaker", p.PlayerName),
 				zap.Error(err))
 		}
+	}
+}
+
+// isPlayingOn returns true if the speaker is a participant in the current playback
+func (m *Manager) isPlayingOn(speakerName string) bool {
+	return m.isPlayingOnIn("", speakerName)
+}
+
+// isPlayingOnIn returns true if the speaker is a participant in the zone's
+// playback, or the whole house's when zone is ""
+func (m *Manager) isPlayingOnIn(zone string, speakerName string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-		// Adaptive delay: slower at start, faster as volume increases
-		// Matches Node-RED: (100 - current) * 250ms, but scaled for our 0-15 range
-		delayMs := (100 - (currentVolume * 100 / 15)) * 2 // ~2ms per point
-		if delayMs < 100 {
-			delayMs = 100 // Minimum 100ms between steps
+	playing := m.currentlyPlaying
+	if zone != "" {
+		playing = m.zonePlaying[zone]
+	}
+	if playing == nil {
+		return false
+	}
+	for _, p := range playing.Participants {
+		if p.PlayerName == speakerName {
+			return true
 		}
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
 	}
-
-	m.logger.Info("Fade-in completed",
-		zap.String("speaker", speakerName),
-		zap.Int("final_volume", targetVolume))
+	return false
 }
 
-// getSpeakerEntityID converts speaker name to Home Assistant entity ID
+// getSpeakerEntityID converts speaker name to Home Assistant entity ID,
+// using the entity_id configured for the speaker when there is one
 func (m *Manager) getSpeakerEntityID(speakerName string) string {
+	return m.currentConfig().SpeakerEntityID(speakerName)
+}
+
+// SpeakerEntityID derives the media player entity ID from a speaker name.
+// MusicConfig.SpeakerEntityID prefers a configured entity_id.
+func SpeakerEntityID(speakerName string) string {
 	// Convert "Kitchen" to "media_player.kitchen"
 	// Simple conversion - assumes lowercase, spaces to underscores
 	entityName := ""
@@ -939,13 +1536,34 @@ func (m *Manager) callService(domain, service string, serviceData map[string]int
 		zap.Any("service_data", serviceData))
 
 	// Call the service via HA client
-	if err := m.haClient.CallService(domain, service, serviceData); err != nil {
+	if err := m.haClient.CallService(m.ctx, domain, service, serviceData); err != nil {
 		return fmt.Errorf("service call failed: %w", err)
 	}
 
 	return nil
 }
 
+// callServices calls independent Home Assistant services concurrently
+func (m *Manager) callServices(calls []ha.ServiceCall) error {
+	if m.readOnly {
+		for _, call := range calls {
+			m.logger.Debug("Read-only mode: would call service",
+				zap.String("domain", call.Domain),
+				zap.String("service", call.Service),
+				zap.Any("service_data", call.Data))
+		}
+		return nil
+	}
+
+	m.logger.Debug("Calling HA services", zap.Int("count", len(calls)))
+
+	if err := ha.CallServices(m.ctx, m.haClient, calls); err != nil {
+		return fmt.Errorf("service calls failed: %w", err)
+	}
+
+	return nil
+}
+
 // Reset re-evaluates appropriate music mode and triggers playback
 func (m *Manager) Reset() error {
 	m.logger.Info("Resetting Music - re-selecting appropriate music mode")
@@ -1078,6 +1696,13 @@ func (m *Manager) updateShadowState(actionType, reason, trigger string) {
 
 	// Update metadata
 	m.shadowState.Metadata.LastUpdated = m.timeProvider.Now()
+
+	m.actions.NotifyAction(m.shadowState.Outputs.LastActionTime, reason)
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.actions.OnAction(fn)
 }
 
 // updateShadowOutputs updates the output portion of shadow state
@@ -1132,22 +1757,44 @@ func (m *Manager) GetShadowState() *shadowstate.MusicShadowState {
 		shadowCopy.Outputs.PlaylistRotation[k] = v
 	}
 
+	if blend := m.shadowState.Outputs.TasteBlend; blend != nil {
+		blendCopy := *blend
+		blendCopy.PeopleHome = append([]string(nil), blend.PeopleHome...)
+		blendCopy.Weights = make(map[string]float64, len(blend.Weights))
+		for k, v := range blend.Weights {
+			blendCopy.Weights[k] = v
+		}
+		shadowCopy.Outputs.TasteBlend = &blendCopy
+	}
+
+	if zones := m.shadowState.Outputs.Zones; zones != nil {
+		shadowCopy.Outputs.Zones = make(map[string]shadowstate.ZonePlayback, len(zones))
+		for zone, playback := range zones {
+			playback.Speakers = append([]shadowstate.SpeakerState(nil), playback.Speakers...)
+			shadowCopy.Outputs.Zones[zone] = playback
+		}
+	}
+
+	if nowPlaying := m.shadowState.Outputs.NowPlaying; nowPlaying != nil {
+		nowPlayingCopy := *nowPlaying
+		shadowCopy.Outputs.NowPlaying = &nowPlayingCopy
+	}
+
+	shadowCopy.Outputs.PlaybackFailures = append([]shadowstate.PlaybackFailure(nil), m.shadowState.Outputs.PlaybackFailures...)
+
+	if check := m.shadowState.Outputs.GroupCheck; check != nil {
+		checkCopy := *check
+		checkCopy.Missing = append([]string(nil), check.Missing...)
+		checkCopy.Extra = append([]string(nil), check.Extra...)
+		shadowCopy.Outputs.GroupCheck = &checkCopy
+	}
+
 	return &shadowCopy
 }
 
 // recordPlaybackShadowState records shadow state after playback orchestration
 func (m *Manager) recordPlaybackShadowState(musicType string, playbackOption PlaybackOption, participants []ParticipantWithVolume, leadPlayer string, trigger string) {
-	// Convert participants to shadow state speaker format
-	speakers := make([]shadowstate.SpeakerState, 0, len(participants))
-	for _, p := range participants {
-		speakers = append(speakers, shadowstate.SpeakerState{
-			PlayerName:    p.PlayerName,
-			Volume:        p.Volume,
-			BaseVolume:    p.BaseVolume,
-			DefaultVolume: p.DefaultVolume,
-			IsLeader:      p.PlayerName == leadPlayer,
-		})
-	}
+	speakers := speakerStates(participants, leadPlayer)
 
 	// Create playlist info
 	playlistInfo := &shadowstate.PlaylistInfo{
@@ -1156,8 +1803,34 @@ func (m *Manager) recordPlaybackShadowState(musicType string, playbackOption Pla
 		MediaType: playbackOption.MediaType,
 	}
 
+	m.mu.RLock()
+	preset := m.activePreset
+	m.mu.RUnlock()
+
 	// Record the action
 	reason := fmt.Sprintf("Started playback of '%s' in mode '%s'", playbackOption.URI, musicType)
+	if preset != "" {
+		reason = fmt.Sprintf("Started playback of '%s' in mode '%s' on speaker group preset '%s'", playbackOption.URI, musicType, preset)
+	}
 	m.updateShadowState("start_playback", reason, trigger)
 	m.updateShadowOutputs(musicType, playlistInfo, speakers)
+
+	m.shadowMu.Lock()
+	m.shadowState.Outputs.SpeakerPreset = preset
+	m.shadowMu.Unlock()
+}
+
+// speakerStates converts participants to the shadow state speaker format
+func speakerStates(participants []ParticipantWithVolume, leadPlayer string) []shadowstate.SpeakerState {
+	speakers := make([]shadowstate.SpeakerState, 0, len(participants))
+	for _, p := range participants {
+		speakers = append(speakers, shadowstate.SpeakerState{
+			PlayerName:    p.PlayerName,
+			Volume:        p.Volume,
+			BaseVolume:    p.BaseVolume,
+			DefaultVolume: p.DefaultVolume,
+			IsLeader:      p.PlayerName == leadPlayer,
+		})
+	}
+	return speakers
 }
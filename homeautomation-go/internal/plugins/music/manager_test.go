@@ -1,13 +1,18 @@
 package music
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/events"
 	"homeautomation/internal/ha"
 	"homeautomation/internal/state"
+	"homeautomation/internal/testclock"
 
 	"go.uber.org/zap"
 )
@@ -249,7 +254,7 @@ func TestMusicManager_StateChangeHandling(t *testing.T) {
 	}
 
 	// Start manager (which subscribes to state changes)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 
@@ -329,13 +334,13 @@ func TestMusicManager_Stop(t *testing.T) {
 	}
 
 	// Start manager
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 
-	// Verify subscriptions were created (dayPhase, isAnyoneAsleep, isAnyoneHome, musicPlaybackType)
-	if len(manager.subscriptions) != 4 {
-		t.Errorf("Expected 4 subscriptions, got %d", len(manager.subscriptions))
+	// Verify subscriptions were created (dayPhase, isAnyoneAsleep, isAnyoneHome, musicPlaybackType, speakerGroupPreset)
+	if len(manager.subscriptions) != 5 {
+		t.Errorf("Expected 5 subscriptions, got %d", len(manager.subscriptions))
 	}
 
 	// Stop manager
@@ -546,6 +551,76 @@ func TestPlaylistRotation(t *testing.T) {
 	}
 }
 
+// TestSelectPlaylistIndex_TimeWindows tests weighted selection within time windows
+func TestSelectPlaylistIndex_TimeWindows(t *testing.T) {
+	morning := []TimeWindow{{Start: "08:00", End: "12:00"}}
+	afternoon := []TimeWindow{{Start: "13:00", End: "17:00"}}
+	options := []PlaybackOption{
+		{URI: "spotify:playlist:upbeat", TimeWindows: morning, Weight: 2},
+		{URI: "spotify:playlist:party", TimeWindows: morning},
+		{URI: "spotify:playlist:mellow", TimeWindows: afternoon},
+		{URI: "spotify:playlist:any"},
+	}
+
+	newManager := func(hour int) *Manager {
+		logger := zap.NewNop()
+		mockClient := ha.NewMockClient()
+		stateManager := state.NewManager(mockClient, logger, false)
+		config := &MusicConfig{Music: map[string]MusicMode{}}
+		fixedTime := time.Date(2025, 1, 6, hour, 30, 0, 0, time.UTC)
+		manager := NewManager(mockClient, stateManager, config, logger, false, FixedTimeProvider{FixedTime: fixedTime})
+		manager.SetTimezone(time.UTC)
+		return manager
+	}
+
+	t.Run("weights are respected within the morning window", func(t *testing.T) {
+		manager := newManager(9)
+		counts := make(map[int]int)
+		var sequence []int
+		for i := 0; i < 6; i++ {
+			index := manager.selectPlaylistIndex("day", options)
+			counts[index]++
+			sequence = append(sequence, index)
+		}
+		if counts[0] != 4 || counts[1] != 2 {
+			t.Errorf("Expected upbeat 4 times and party 2 times, got sequence %v", sequence)
+		}
+		if sequence[0] != 0 || sequence[1] != 1 {
+			t.Errorf("Expected heaviest option first then interleaved, got sequence %v", sequence)
+		}
+	})
+
+	t.Run("only the matching window is used in the afternoon", func(t *testing.T) {
+		manager := newManager(14)
+		for i := 0; i < 3; i++ {
+			if index := manager.selectPlaylistIndex("day", options); index != 2 {
+				t.Errorf("Call %d: expected mellow option 2, got %d", i, index)
+			}
+		}
+	})
+
+	t.Run("falls back to rotation outside every window", func(t *testing.T) {
+		manager := newManager(20)
+		for i := 0; i < 5; i++ {
+			if index := manager.selectPlaylistIndex("day", options); index != i%len(options) {
+				t.Errorf("Call %d: expected rotation index %d, got %d", i, i%len(options), index)
+			}
+		}
+	})
+
+	t.Run("windows are evaluated in the configured timezone", func(t *testing.T) {
+		manager := newManager(14) // 14:30 UTC is 09:30 in New York
+		tz, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skipf("timezone data unavailable: %v", err)
+		}
+		manager.SetTimezone(tz)
+		if index := manager.selectPlaylistIndex("day", options); index != 0 {
+			t.Errorf("Expected morning option 0, got %d", index)
+		}
+	})
+}
+
 // TestRateLimiting tests rate limiting functionality
 func TestRateLimiting(t *testing.T) {
 	logger := zap.NewNop()
@@ -575,13 +650,13 @@ func TestRateLimiting(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, config, logger, true, timeProvider)
 
 	// First playback should succeed
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "", "day")
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "", New: "day"})
 	if manager.currentlyPlaying == nil {
 		t.Error("First playback should have succeeded")
 	}
 
 	// Immediate second playback should be rate limited
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "day", "evening")
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "day", New: "evening"})
 	if manager.currentlyPlaying.Type != "day" {
 		t.Error("Second immediate playback should have been rate limited")
 	}
@@ -593,7 +668,7 @@ func TestRateLimiting(t *testing.T) {
 	// Now it should succeed
 	_ = stateManager.SetString("musicPlaybackType", "evening")
 	config.Music["evening"] = config.Music["day"] // Add evening config
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "day", "evening")
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "day", New: "evening"})
 	if manager.currentlyPlaying.Type != "evening" {
 		t.Error("Playback after 11 seconds should have succeeded")
 	}
@@ -621,11 +696,11 @@ func TestDoubleActivationPrevention(t *testing.T) {
 	manager := NewManager(mockClient, stateManager, config, logger, true, nil)
 
 	// First playback
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "", "day")
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "", New: "day"})
 	firstURI := manager.currentlyPlaying.URI
 
 	// Second activation of same type should be blocked
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "day", "day")
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "day", New: "day"})
 	if manager.currentlyPlaying.URI != firstURI {
 		t.Error("Double activation should not have changed the playlist")
 	}
@@ -962,7 +1037,7 @@ func TestHandleMusicPlaybackTypeChange_EmptyString(t *testing.T) {
 	}
 
 	// Trigger empty music type (stop)
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "day", "")
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "day", New: ""})
 
 	// Verify playback was stopped
 	if manager.currentlyPlaying != nil {
@@ -970,18 +1045,30 @@ func TestHandleMusicPlaybackTypeChange_EmptyString(t *testing.T) {
 	}
 }
 
-// TestHandleMusicPlaybackTypeChange_InvalidType tests handling of invalid type values
-func TestHandleMusicPlaybackTypeChange_InvalidType(t *testing.T) {
+// TestStart_MuteConditionSubscriptionsMatchValueType tests that mute condition
+// variables are subscribed with the event type of the condition's value, and
+// conditions that can never match their variable are skipped
+func TestStart_MuteConditionSubscriptionsMatchValueType(t *testing.T) {
 	logger := zap.NewNop()
 	mockClient := ha.NewMockClient()
 	stateManager := state.NewManager(mockClient, logger, false)
-	config := &MusicConfig{Music: map[string]MusicMode{}}
-	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	config := &MusicConfig{Music: map[string]MusicMode{
+		"day": {Participants: []Participant{
+			{PlayerName: "Kitchen", LeaveMutedIf: []MuteCondition{{Variable: "isTVPlaying", Value: true}}},
+			{PlayerName: "Office", LeaveMutedIf: []MuteCondition{{Variable: "isNickOfficeOccupied", Value: "yes"}}},
+		}},
+	}}
+	manager := NewManager(mockClient, stateManager, config, logger, true, nil)
 
-	// Pass non-string value (should log error and return)
-	manager.handleMusicPlaybackTypeChange("musicPlaybackType", "", 123)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
 
-	// If we reach here without panic, the invalid type handling worked
+	// The five standard subscriptions plus isTVPlaying; isNickOfficeOccupied is a bool, not a string
+	if len(manager.subscriptions) != 6 {
+		t.Errorf("Expected 6 subscriptions, got %d", len(manager.subscriptions))
+	}
 }
 
 // TestExecutePlayback tests the complete execution flow
@@ -1021,7 +1108,7 @@ func TestExecutePlayback(t *testing.T) {
 		VolumeMultiplier: 1.0,
 	}
 
-	err := manager.executePlayback("day", option, participants, "Kitchen")
+	err := manager.executePlayback("", "day", option, participants, "Kitchen")
 	if err != nil {
 		t.Errorf("executePlayback() failed: %v", err)
 	}
@@ -1034,6 +1121,8 @@ func TestBuildSpeakerGroup(t *testing.T) {
 	stateManager := state.NewManager(mockClient, logger, false)
 	config := &MusicConfig{Music: map[string]MusicMode{}}
 	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	clk := testclock.New(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	manager.SetClock(clk)
 
 	participants := []ParticipantWithVolume{
 		{PlayerName: "Kitchen", Volume: 9},
@@ -1041,8 +1130,25 @@ func TestBuildSpeakerGroup(t *testing.T) {
 		{PlayerName: "Bedroom", Volume: 8},
 	}
 
-	err := manager.buildSpeakerGroup(participants, "media_player.kitchen")
-	if err != nil {
+	result := make(chan error, 1)
+	go func() {
+		result <- manager.buildSpeakerGroup(participants, "media_player.kitchen")
+	}()
+
+	// The other speakers join, then the group is given time to settle
+	clk.BlockUntil(1)
+	joins := 0
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "media_player" && call.Service == "join" {
+			joins++
+		}
+	}
+	if joins != 2 {
+		t.Errorf("Expected 2 speakers to join the group, got %d", joins)
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	if err := <-result; err != nil {
 		t.Errorf("buildSpeakerGroup() failed: %v", err)
 	}
 }
@@ -1068,7 +1174,7 @@ func TestManagerReset(t *testing.T) {
 
 	manager := NewManager(mockClient, stateManager, musicConfig, logger, false, &RealTimeProvider{})
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
@@ -1247,3 +1353,246 @@ func TestCurrentlyPlayingMusicUri_UpdateOnModeChange(t *testing.T) {
 		t.Errorf("Expected currentlyPlayingMusicUri = %q for evening, got %q", eveningURI, currentURI)
 	}
 }
+
+// createSpeakerGroupTestConfig returns a config with a three-speaker day mode and a dinner preset
+func createSpeakerGroupTestConfig() *MusicConfig {
+	return &MusicConfig{
+		Music: map[string]MusicMode{
+			"day": {
+				Participants: []Participant{
+					{PlayerName: "Kitchen", BaseVolume: 9, LeaveMutedIf: []MuteCondition{}},
+					{PlayerName: "Living Room", BaseVolume: 10, LeaveMutedIf: []MuteCondition{}},
+					{PlayerName: "Office", BaseVolume: 8, LeaveMutedIf: []MuteCondition{}},
+				},
+				PlaybackOptions: []PlaybackOption{
+					{URI: "spotify:playlist:day1", MediaType: "playlist", VolumeMultiplier: 1.0},
+					{URI: "spotify:playlist:day2", MediaType: "playlist", VolumeMultiplier: 1.0},
+				},
+			},
+			"evening": {
+				Participants: []Participant{
+					{PlayerName: "Living Room", BaseVolume: 10, LeaveMutedIf: []MuteCondition{}},
+				},
+				PlaybackOptions: []PlaybackOption{
+					{URI: "spotify:playlist:evening1", MediaType: "playlist", VolumeMultiplier: 1.0},
+				},
+			},
+		},
+		SpeakerGroups: map[string]SpeakerGroupPreset{
+			"dinner": {Speakers: []string{"Kitchen", "Dining Room"}, BaseVolume: 7},
+		},
+	}
+}
+
+// TestPlayback_UsesConfiguredEntityID tests that a participant's entity_id
+// replaces the entity derived from its player name
+func TestPlayback_UsesConfiguredEntityID(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	config := createSpeakerGroupTestConfig()
+	day := config.Music["day"]
+	day.Participants = append([]Participant(nil), day.Participants...)
+	day.Participants[0].EntityID = "media_player.kitchen_2"
+	config.Music["day"] = day
+
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+
+	if uri := playMediaOn(mockClient, "media_player.kitchen_2"); uri != "spotify:playlist:day1" {
+		t.Errorf("Expected playback started on the configured entity, got %q", uri)
+	}
+	if uri := playMediaOn(mockClient, "media_player.kitchen"); uri != "" {
+		t.Errorf("Expected nothing sent to the derived entity, got %q", uri)
+	}
+	if nowPlaying := manager.GetNowPlaying(); nowPlaying == nil || nowPlaying.LeadPlayer != "media_player.kitchen_2" {
+		t.Errorf("Expected the configured entity as lead player, got %+v", nowPlaying)
+	}
+}
+
+// TestSpeakerGroupPreset_RegroupsCurrentPlaylist tests that a preset replays the
+// current playlist on the preset's speakers and releases the others
+func TestSpeakerGroupPreset_RegroupsCurrentPlaylist(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	mockClient.ClearServiceCalls()
+
+	manager.handleSpeakerGroupPresetChange(events.StringChanged{Key: "speakerGroupPreset", Old: "", New: "dinner"})
+
+	if preset := manager.GetActiveSpeakerGroupPreset(); preset != "dinner" {
+		t.Errorf("Expected active preset dinner, got %q", preset)
+	}
+
+	manager.mu.RLock()
+	playing := *manager.currentlyPlaying
+	manager.mu.RUnlock()
+
+	if playing.URI != "spotify:playlist:day1" {
+		t.Errorf("Expected the current playlist to keep playing, got %q", playing.URI)
+	}
+	if len(playing.Participants) != 2 || playing.LeadPlayer != "Kitchen" {
+		t.Fatalf("Expected Kitchen-led two-speaker group, got %+v", playing.Participants)
+	}
+	// Kitchen keeps the mode's base volume, Dining Room uses the preset's
+	if playing.Participants[0].BaseVolume != 9 || playing.Participants[1].BaseVolume != 7 {
+		t.Errorf("Unexpected base volumes: %+v", playing.Participants)
+	}
+
+	unjoined := make(map[string]bool)
+	var played, joined bool
+	for _, call := range mockClient.GetServiceCalls() {
+		switch call.Service {
+		case "unjoin":
+			unjoined[call.Data["entity_id"].(string)] = true
+		case "play_media":
+			played = call.Data["entity_id"] == "media_player.kitchen" && call.Data["media_content_id"] == "spotify:playlist:day1"
+		case "join":
+			joined = joined || call.Data["entity_id"] == "media_player.dining_room"
+		}
+	}
+	if !unjoined["media_player.living_room"] || !unjoined["media_player.office"] || len(unjoined) != 2 {
+		t.Errorf("Expected Living Room and Office to be released, got %v", unjoined)
+	}
+	if !played {
+		t.Error("Expected current playlist to be played on the Kitchen speaker")
+	}
+	if !joined {
+		t.Error("Expected Dining Room to join the Kitchen group")
+	}
+
+	if preset := manager.GetShadowState().Outputs.SpeakerPreset; preset != "dinner" {
+		t.Errorf("Expected shadow state speaker preset dinner, got %q", preset)
+	}
+}
+
+// TestSpeakerGroupPreset_IgnoredWithoutPlayback tests that presets need active music
+func TestSpeakerGroupPreset_IgnoredWithoutPlayback(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, true)
+
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, true, nil)
+
+	manager.handleSpeakerGroupPresetChange(events.StringChanged{Key: "speakerGroupPreset", Old: "", New: "dinner"})
+	manager.handleSpeakerGroupPresetChange(events.StringChanged{Key: "speakerGroupPreset", Old: "", New: "unknown"})
+
+	if preset := manager.GetActiveSpeakerGroupPreset(); preset != "" {
+		t.Errorf("Expected no active preset, got %q", preset)
+	}
+}
+
+// TestSpeakerGroupPreset_ClearedOnModeChange tests that a new music mode uses its own speakers
+func TestSpeakerGroupPreset_ClearedOnModeChange(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, true)
+
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, true, nil)
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	if err := stateManager.SetString("speakerGroupPreset", "dinner"); err != nil {
+		t.Fatalf("Failed to set speakerGroupPreset: %v", err)
+	}
+	manager.handleSpeakerGroupPresetChange(events.StringChanged{Key: "speakerGroupPreset", Old: "", New: "dinner"})
+
+	manager.handleMusicPlaybackTypeChange(events.StringChanged{Key: "musicPlaybackType", Old: "day", New: "evening"})
+
+	if preset := manager.GetActiveSpeakerGroupPreset(); preset != "" {
+		t.Errorf("Expected preset to be cleared, got %q", preset)
+	}
+	if value, _ := stateManager.GetString("speakerGroupPreset"); value != "" {
+		t.Errorf("Expected speakerGroupPreset to be cleared, got %q", value)
+	}
+
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	if len(manager.currentlyPlaying.Participants) != 1 || manager.currentlyPlaying.LeadPlayer != "Living Room" {
+		t.Errorf("Expected evening mode's own speakers, got %+v", manager.currentlyPlaying.Participants)
+	}
+}
+
+// TestActivateSpeakerGroupPreset tests preset validation and the state variable request
+func TestActivateSpeakerGroupPreset(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, true, nil)
+
+	if err := manager.ActivateSpeakerGroupPreset("party"); !errors.Is(err, ErrUnknownSpeakerGroupPreset) {
+		t.Errorf("Expected ErrUnknownSpeakerGroupPreset, got %v", err)
+	}
+	if err := manager.ActivateSpeakerGroupPreset("dinner"); !errors.Is(err, ErrNoMusicPlaying) {
+		t.Errorf("Expected ErrNoMusicPlaying, got %v", err)
+	}
+
+	if err := stateManager.SetString("musicPlaybackType", "day"); err != nil {
+		t.Fatalf("Failed to set musicPlaybackType: %v", err)
+	}
+	if err := manager.ActivateSpeakerGroupPreset("dinner"); err != nil {
+		t.Fatalf("ActivateSpeakerGroupPreset() failed: %v", err)
+	}
+	if value, _ := stateManager.GetString("speakerGroupPreset"); value != "dinner" {
+		t.Errorf("Expected speakerGroupPreset dinner, got %q", value)
+	}
+
+	// Clearing is always allowed
+	if err := manager.ActivateSpeakerGroupPreset(""); err != nil {
+		t.Errorf("Clearing preset failed: %v", err)
+	}
+
+	presets := manager.GetSpeakerGroupPresets()
+	if len(presets) != 1 || len(presets["dinner"]) != 2 {
+		t.Errorf("Unexpected presets: %v", presets)
+	}
+}
+
+// TestDoNotDisturb_LeavesOutBedroomSpeakers tests that speakers in do-not-disturb
+// bedrooms are left out of playback, and playback is skipped if none remain
+func TestDoNotDisturb_LeavesOutBedroomSpeakers(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	dndConfig := &donotdisturb.Config{DoNotDisturb: donotdisturb.Settings{Bedrooms: []donotdisturb.Bedroom{
+		{Name: "primary", Variable: "isPrimaryBedroomDoNotDisturb", Entities: []string{"media_player.office", "media_player.living_room"}},
+	}}}
+	stateManager.SetBool("isPrimaryBedroomDoNotDisturb", true)
+
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+	manager.SetDoNotDisturb(donotdisturb.NewGuard(dndConfig, stateManager))
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+
+	manager.mu.RLock()
+	playing := *manager.currentlyPlaying
+	manager.mu.RUnlock()
+
+	if len(playing.Participants) != 1 || playing.Participants[0].PlayerName != "Kitchen" {
+		t.Errorf("Expected only the Kitchen speaker to play, got %+v", playing.Participants)
+	}
+
+	mockClient.ClearServiceCalls()
+	if err := manager.orchestratePlayback("evening", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "play_media" {
+			t.Errorf("Expected no playback when every speaker is in do-not-disturb, got %+v", call.Data)
+		}
+	}
+}
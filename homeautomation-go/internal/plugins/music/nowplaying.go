@@ -0,0 +1,142 @@
+package music
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// nowPlayingTimeout bounds reading the lead player when playback starts
+const nowPlayingTimeout = 5 * time.Second
+
+// GetNowPlaying returns the track the lead player reports for the current
+// whole-house playback, or nil while nothing plays
+func (m *Manager) GetNowPlaying() *shadowstate.NowPlaying {
+	m.shadowMu.RLock()
+	defer m.shadowMu.RUnlock()
+
+	if m.shadowState.Outputs.NowPlaying == nil {
+		return nil
+	}
+	nowPlaying := *m.shadowState.Outputs.NowPlaying
+	return &nowPlaying
+}
+
+// watchLeadPlayer follows the lead player's media attributes for the
+// playback that just started, replacing any previous lead's subscription
+func (m *Manager) watchLeadPlayer(musicType, uri, leadPlayer string) {
+	entityID := m.getSpeakerEntityID(leadPlayer)
+
+	m.shadowMu.Lock()
+	m.shadowState.Outputs.NowPlaying = &shadowstate.NowPlaying{
+		MusicType:   musicType,
+		PlaylistURI: uri,
+		LeadPlayer:  entityID,
+		UpdatedAt:   m.timeProvider.Now(),
+	}
+	m.shadowMu.Unlock()
+
+	m.trackMu.Lock()
+	if m.trackEntity != entityID {
+		if m.trackSub != nil {
+			m.trackSub.Unsubscribe()
+			m.trackSub = nil
+		}
+		sub, err := m.haClient.SubscribeStateChanges(entityID, m.handleLeadPlayerChange)
+		if err != nil {
+			m.logger.Warn("Failed to follow the lead player's track",
+				zap.String("entity_id", entityID),
+				zap.Error(err))
+			m.trackEntity = ""
+		} else {
+			m.trackSub = sub
+			m.trackEntity = entityID
+		}
+	}
+	m.trackMu.Unlock()
+
+	// Seed the track from the player's current state
+	ctx, cancel := context.WithTimeout(m.ctx, nowPlayingTimeout)
+	defer cancel()
+	if current, err := m.haClient.GetState(ctx, entityID); err == nil {
+		m.recordTrack(entityID, current)
+	}
+}
+
+// unwatchLeadPlayer stops following the lead player and clears the track
+func (m *Manager) unwatchLeadPlayer() {
+	m.trackMu.Lock()
+	if m.trackSub != nil {
+		m.trackSub.Unsubscribe()
+		m.trackSub = nil
+	}
+	m.trackEntity = ""
+	m.trackMu.Unlock()
+
+	m.shadowMu.Lock()
+	m.shadowState.Outputs.NowPlaying = nil
+	m.shadowMu.Unlock()
+}
+
+// handleLeadPlayerChange records the track when the lead player's media attributes change
+func (m *Manager) handleLeadPlayerChange(entityID string, _, newState *ha.State) {
+	m.trackMu.Lock()
+	following := entityID == m.trackEntity
+	m.trackMu.Unlock()
+	if !following || newState == nil {
+		return
+	}
+	m.recordTrack(entityID, newState)
+}
+
+// recordTrack copies the player's state and media_title, media_artist and
+// media_album_name attributes into the shadow state's now playing
+func (m *Manager) recordTrack(entityID string, playerState *ha.State) {
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	nowPlaying := m.shadowState.Outputs.NowPlaying
+	if nowPlaying == nil || nowPlaying.LeadPlayer != entityID {
+		return
+	}
+
+	title := mediaAttribute(playerState, "media_title")
+	artist := mediaAttribute(playerState, "media_artist")
+	album := mediaAttribute(playerState, "media_album_name")
+	if title == nowPlaying.Title && artist == nowPlaying.Artist && album == nowPlaying.Album &&
+		playerState.State == nowPlaying.PlayerState {
+		return
+	}
+
+	updated := *nowPlaying
+	updated.PlayerState = playerState.State
+	updated.Title = title
+	updated.Artist = artist
+	updated.Album = album
+	updated.UpdatedAt = m.timeProvider.Now()
+	m.shadowState.Outputs.NowPlaying = &updated
+
+	if title != nowPlaying.Title || artist != nowPlaying.Artist {
+		m.logger.Info("Now playing",
+			zap.String("title", title),
+			zap.String("artist", artist),
+			zap.String("lead_player", entityID))
+	}
+}
+
+// mediaAttribute returns a media player attribute as a string, "" when unset
+func mediaAttribute(playerState *ha.State, name string) string {
+	value, ok := playerState.Attributes[name]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
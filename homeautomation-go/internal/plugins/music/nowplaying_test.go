@@ -0,0 +1,93 @@
+package music
+
+import (
+	"testing"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func TestNowPlaying_FollowsLeadPlayer(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+
+	mockClient.SetMockState("media_player.kitchen", &ha.State{
+		EntityID: "media_player.kitchen",
+		State:    "playing",
+		Attributes: map[string]interface{}{
+			"media_title":  "Opening Track",
+			"media_artist": "First Artist",
+		},
+	})
+
+	if manager.GetNowPlaying() != nil {
+		t.Fatal("Expected nothing playing before playback starts")
+	}
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+
+	// Seeded from the lead player's state when playback starts
+	nowPlaying := manager.GetNowPlaying()
+	if nowPlaying == nil {
+		t.Fatal("Expected a track after playback starts")
+	}
+	if nowPlaying.Title != "Opening Track" || nowPlaying.Artist != "First Artist" {
+		t.Errorf("Expected the seeded track, got %+v", nowPlaying)
+	}
+	if nowPlaying.MusicType != "day" || nowPlaying.PlaylistURI != "spotify:playlist:day1" || nowPlaying.LeadPlayer != "media_player.kitchen" {
+		t.Errorf("Expected day playback on the kitchen lead, got %+v", nowPlaying)
+	}
+
+	// The next track arrives as an attribute change
+	mockClient.SetState("media_player.kitchen", "playing", map[string]interface{}{
+		"media_title":      "Second Track",
+		"media_artist":     "Second Artist",
+		"media_album_name": "The Album",
+	})
+	nowPlaying = manager.GetNowPlaying()
+	if nowPlaying.Title != "Second Track" || nowPlaying.Artist != "Second Artist" || nowPlaying.Album != "The Album" {
+		t.Errorf("Expected the next track, got %+v", nowPlaying)
+	}
+	if shadow := manager.GetShadowState().Outputs.NowPlaying; shadow == nil || shadow.Title != "Second Track" {
+		t.Errorf("Expected the track in the shadow state, got %+v", shadow)
+	}
+
+	// Stopping clears the track and stops following the player
+	manager.stopPlayback()
+	if manager.GetNowPlaying() != nil {
+		t.Error("Expected no track after playback stops")
+	}
+	mockClient.SetState("media_player.kitchen", "playing", map[string]interface{}{"media_title": "Stray Track"})
+	if manager.GetNowPlaying() != nil {
+		t.Error("Expected changes after stopping to be ignored")
+	}
+}
+
+func TestNowPlaying_IgnoresPreviousLead(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	// Evening is led by Living Room
+	if err := manager.orchestratePlayback("evening", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+
+	mockClient.SetState("media_player.kitchen", "playing", map[string]interface{}{"media_title": "Old Lead Track"})
+	mockClient.SetState("media_player.living_room", "playing", map[string]interface{}{"media_title": "New Lead Track"})
+
+	nowPlaying := manager.GetNowPlaying()
+	if nowPlaying == nil || nowPlaying.Title != "New Lead Track" || nowPlaying.LeadPlayer != "media_player.living_room" {
+		t.Errorf("Expected the new lead's track, got %+v", nowPlaying)
+	}
+}
@@ -56,6 +56,7 @@ package music
 // =============================================================================
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -166,7 +167,7 @@ func TestScenario_OfficeSpeaker_UnmutedWhenOccupied(t *testing.T) {
 	_ = stateManager.SetBool("isTVPlaying", false)
 
 	// Start manager to initialize subscriptions
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -288,7 +289,7 @@ func TestScenario_OfficeSpeaker_MutedWhenUnoccupied(t *testing.T) {
 // EXPECTED BEHAVIOR:
 // - Service call: media_player.volume_set
 // - Entity: media_player.office
-// - Data: { entity_id: "media_player.office", volume_level: 0.06 } (6% = base_volume 6)
+// - Data: { entity_id: "media_player.office", volume_level: 0.4 } (base_volume 6 of 15)
 func TestScenario_OfficeSpeaker_UnmuteOnOccupancyChangeDuringPlayback(t *testing.T) {
 	logger := zap.NewNop()
 	mockClient := ha.NewMockClient()
@@ -312,7 +313,7 @@ func TestScenario_OfficeSpeaker_UnmuteOnOccupancyChangeDuringPlayback(t *testing
 	_ = stateManager.SetBool("isTVPlaying", false)
 
 	// Start manager - music should start playing (Kitchen speaker only)
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	assert.NoError(t, err)
 	defer manager.Stop()
 
@@ -348,9 +349,9 @@ func TestScenario_OfficeSpeaker_UnmuteOnOccupancyChangeDuringPlayback(t *testing
 			if ok && entityID == "media_player.office" {
 				volumeLevel, hasVolume := call.Data["volume_level"]
 				if hasVolume {
-					// Should be non-zero volume (unmuting)
-					// Base volume is 6, so expected volume_level is 0.06 (6%)
+					// Base volume is 6 on the 0-15 Sonos scale, so expected volume_level is 0.4
 					if vol, ok := volumeLevel.(float64); ok && vol > 0 {
+						assert.InDelta(t, 0.4, vol, 0.001, "unmute volume should be base_volume/15")
 						foundOfficeVolumeSet = true
 					}
 				}
@@ -0,0 +1,60 @@
+package music
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// currentConfig returns the configuration in effect
+func (m *Manager) currentConfig() *MusicConfig {
+	return m.config.Load()
+}
+
+// Reload applies an edited music_config.yaml. Music already playing keeps
+// going; the new modes, playlists and volumes apply from the next playback.
+// Weighted playlist selection starts over, a speaker group preset that was
+// removed returns playback to the mode's own speakers, and the group
+// reconciliation follows its new schedule. Zones' speakers and volumes can
+// change, but adding or removing a zone needs a restart to register its
+// state variable.
+func (m *Manager) Reload(config *MusicConfig) error {
+	if config == nil {
+		return fmt.Errorf("music config is nil")
+	}
+	if !slices.Equal(slices.Sorted(maps.Keys(config.Zones)), m.zoneNames()) {
+		return fmt.Errorf("zones can't be added or removed without a restart")
+	}
+
+	m.config.Store(config)
+
+	m.mu.Lock()
+	// Option indexes may have moved, so selection credit no longer applies
+	m.windowCredits = make(map[string]map[int]float64)
+	for musicType, index := range m.playlistNumbers {
+		if mode, ok := config.Music[musicType]; !ok || index >= len(mode.PlaybackOptions) {
+			delete(m.playlistNumbers, musicType)
+		}
+	}
+	preset := m.activePreset
+	m.mu.Unlock()
+
+	if m.running.Load() {
+		m.scheduleGroupReconciliation()
+	}
+
+	m.logger.Info("Music config reloaded",
+		zap.Int("modes", len(config.Music)),
+		zap.Int("speaker_group_presets", len(config.SpeakerGroups)))
+
+	if _, ok := config.SpeakerGroups[preset]; preset != "" && !ok {
+		m.logger.Warn("Active speaker group preset was removed, returning to the mode's speakers",
+			zap.String("preset", preset))
+		if err := m.stateManager.SetString("speakerGroupPreset", ""); err != nil {
+			m.logger.Error("Failed to clear speakerGroupPreset", zap.Error(err))
+		}
+	}
+	return nil
+}
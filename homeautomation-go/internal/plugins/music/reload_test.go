@@ -0,0 +1,72 @@
+package music
+
+import (
+	"testing"
+
+	"homeautomation/internal/events"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// TestReload_AppliesToNextPlayback tests that a reloaded playlist is used by
+// the next playback
+func TestReload_AppliesToNextPlayback(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+
+	config := createSpeakerGroupTestConfig()
+	evening := config.Music["evening"]
+	evening.PlaybackOptions[0].URI = "spotify:playlist:evening2"
+	config.Music["evening"] = evening
+	if err := manager.Reload(config); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+
+	if err := manager.orchestratePlayback("evening", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	manager.mu.RLock()
+	uri := manager.currentlyPlaying.URI
+	manager.mu.RUnlock()
+	if uri != "spotify:playlist:evening2" {
+		t.Errorf("Expected the reloaded playlist, got %q", uri)
+	}
+
+	if err := manager.Reload(nil); err == nil {
+		t.Error("Expected an error for a nil config")
+	}
+}
+
+// TestReload_ClearsRemovedPreset tests that removing the active speaker group
+// preset returns playback to the mode's speakers
+func TestReload_ClearsRemovedPreset(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	manager := NewManager(mockClient, stateManager, createSpeakerGroupTestConfig(), logger, false, nil)
+
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	if err := stateManager.SetString("speakerGroupPreset", "dinner"); err != nil {
+		t.Fatalf("Failed to set speakerGroupPreset: %v", err)
+	}
+	manager.handleSpeakerGroupPresetChange(events.StringChanged{Key: "speakerGroupPreset", Old: "", New: "dinner"})
+
+	config := createSpeakerGroupTestConfig()
+	config.SpeakerGroups = nil
+	if err := manager.Reload(config); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+
+	if preset, _ := stateManager.GetString("speakerGroupPreset"); preset != "" {
+		t.Errorf("Expected speakerGroupPreset to be cleared, got %q", preset)
+	}
+	if presets := manager.GetSpeakerGroupPresets(); len(presets) != 0 {
+		t.Errorf("Expected no presets after reload, got %v", presets)
+	}
+}
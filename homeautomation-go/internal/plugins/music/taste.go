@@ -0,0 +1,116 @@
+package music
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// matchTasteProfile returns the first taste profile that applies to the music
+// type now and whose present and absent people match, along with the
+// presence variables (from every profile) that are currently true
+func (m *Manager) matchTasteProfile(musicType string, now time.Time) (*TasteProfile, []string) {
+	config := m.currentConfig()
+	if len(config.TasteProfiles) == 0 {
+		return nil, nil
+	}
+
+	home := make(map[string]bool)
+	peopleHome := make([]string, 0)
+	isHome := func(variable string) bool {
+		if value, ok := home[variable]; ok {
+			return value
+		}
+		value, err := m.stateManager.GetBool(variable)
+		if err != nil {
+			m.logger.Warn("Failed to read presence for taste profile",
+				zap.String("variable", variable),
+				zap.Error(err))
+		}
+		home[variable] = err == nil && value
+		if home[variable] {
+			peopleHome = append(peopleHome, variable)
+		}
+		return home[variable]
+	}
+	for _, profile := range config.TasteProfiles {
+		for _, variable := range profile.Present {
+			isHome(variable)
+		}
+		for _, variable := range profile.Absent {
+			isHome(variable)
+		}
+	}
+
+	for i := range config.TasteProfiles {
+		profile := &config.TasteProfiles[i]
+		if !profile.AppliesTo(musicType, now) {
+			continue
+		}
+		matches := true
+		for _, variable := range profile.Present {
+			matches = matches && home[variable]
+		}
+		for _, variable := range profile.Absent {
+			matches = matches && !home[variable]
+		}
+		if matches {
+			return profile, peopleHome
+		}
+	}
+	return nil, peopleHome
+}
+
+// recordTasteBlend stores the rationale for a playlist selection in the shadow
+// state. Nothing is recorded when no taste profiles are configured.
+func (m *Manager) recordTasteBlend(musicType string, profile *TasteProfile, peopleHome []string, weights map[string]float64, selected, rationale string, now time.Time) {
+	if len(m.currentConfig().TasteProfiles) == 0 {
+		return
+	}
+
+	blend := &shadowstate.TasteBlend{
+		MusicType:  musicType,
+		PeopleHome: peopleHome,
+		Weights:    weights,
+		Selected:   selected,
+		Rationale:  rationale,
+		SelectedAt: now,
+	}
+	if profile != nil {
+		blend.Profile = profile.Name
+	}
+
+	m.shadowMu.Lock()
+	m.shadowState.Outputs.TasteBlend = blend
+	m.shadowMu.Unlock()
+}
+
+// tasteRationale explains a weighted selection, e.g.
+// "Taste profile 'nick-afternoon' (home: isNickHome) weighted sets focus x3, chill x0.5"
+func tasteRationale(profile *TasteProfile, peopleHome []string, inWindow bool) string {
+	if profile == nil {
+		return "Chose by time window weight; no taste profile matches who is home"
+	}
+
+	home := "nobody tracked"
+	if len(peopleHome) > 0 {
+		home = strings.Join(peopleHome, ", ")
+	}
+	sets := make([]string, 0, len(profile.SetWeights))
+	for _, set := range slices.Sorted(maps.Keys(profile.SetWeights)) {
+		sets = append(sets, fmt.Sprintf("%s x%g", set, profile.SetWeights[set]))
+	}
+
+	scope := "all playlists"
+	if inWindow {
+		scope = "playlists in the current time window"
+	}
+	return fmt.Sprintf("Taste profile '%s' (home: %s) weighted sets %s among %s",
+		profile.Name, home, strings.Join(sets, ", "), scope)
+}
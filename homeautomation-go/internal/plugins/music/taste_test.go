@@ -0,0 +1,201 @@
+package music
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// newTasteTestManager returns a manager at the given hour with Nick home and
+// Caroline home as given, and two taste profiles
+func newTasteTestManager(t *testing.T, hour int, nickHome, carolineHome bool) *Manager {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+	if err := stateManager.SetBool("isNickHome", nickHome); err != nil {
+		t.Fatalf("SetBool failed: %v", err)
+	}
+	if err := stateManager.SetBool("isCarolineHome", carolineHome); err != nil {
+		t.Fatalf("SetBool failed: %v", err)
+	}
+
+	config := &MusicConfig{
+		Music: map[string]MusicMode{},
+		TasteProfiles: []TasteProfile{
+			{
+				Name:        "nick-afternoon",
+				Present:     []string{"isNickHome"},
+				Absent:      []string{"isCarolineHome"},
+				MusicTypes:  []string{"day"},
+				TimeWindows: []TimeWindow{{Start: "12:00", End: "18:00"}},
+				SetWeights:  map[string]float64{"a": 3, "b": 0},
+			},
+			{
+				Name:       "both-home",
+				Present:    []string{"isNickHome", "isCarolineHome"},
+				SetWeights: map[string]float64{"b": 2},
+			},
+		},
+	}
+	fixedTime := time.Date(2025, 1, 6, hour, 30, 0, 0, time.UTC)
+	manager := NewManager(mockClient, stateManager, config, logger, false, FixedTimeProvider{FixedTime: fixedTime})
+	manager.SetTimezone(time.UTC)
+	return manager
+}
+
+var tasteOptions = []PlaybackOption{
+	{URI: "spotify:playlist:a1", Sets: []string{"a"}},
+	{URI: "spotify:playlist:b1", Sets: []string{"b"}},
+	{URI: "spotify:playlist:neutral"},
+}
+
+func countSelections(manager *Manager, musicType string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[tasteOptions[manager.selectPlaylistIndex(musicType, tasteOptions)].URI]++
+	}
+	return counts
+}
+
+func TestTasteProfile_NickHomeOnlyAfternoon(t *testing.T) {
+	manager := newTasteTestManager(t, 14, true, false)
+
+	counts := countSelections(manager, "day", 8)
+	if counts["spotify:playlist:a1"] != 6 || counts["spotify:playlist:neutral"] != 2 || counts["spotify:playlist:b1"] != 0 {
+		t.Errorf("Expected set a 3:1 over neutral and set b left out, got %v", counts)
+	}
+
+	blend := manager.GetShadowState().Outputs.TasteBlend
+	if blend == nil {
+		t.Fatal("Expected a taste blend in the shadow state")
+	}
+	if blend.Profile != "nick-afternoon" || blend.MusicType != "day" {
+		t.Errorf("Unexpected blend %+v", blend)
+	}
+	if len(blend.PeopleHome) != 1 || blend.PeopleHome[0] != "isNickHome" {
+		t.Errorf("Expected only isNickHome home, got %v", blend.PeopleHome)
+	}
+	if blend.Weights["spotify:playlist:a1"] != 3 || blend.Weights["spotify:playlist:b1"] != 0 {
+		t.Errorf("Unexpected weights %v", blend.Weights)
+	}
+	if !strings.Contains(blend.Rationale, "nick-afternoon") || !strings.Contains(blend.Rationale, "a x3") {
+		t.Errorf("Unexpected rationale %q", blend.Rationale)
+	}
+}
+
+func TestTasteProfile_BothHome(t *testing.T) {
+	manager := newTasteTestManager(t, 14, true, true)
+
+	counts := countSelections(manager, "evening", 8)
+	if counts["spotify:playlist:b1"] != 4 || counts["spotify:playlist:a1"] != 2 || counts["spotify:playlist:neutral"] != 2 {
+		t.Errorf("Expected set b weighted 2:1:1, got %v", counts)
+	}
+	if blend := manager.GetShadowState().Outputs.TasteBlend; blend == nil || blend.Profile != "both-home" {
+		t.Errorf("Expected both-home blend, got %+v", blend)
+	}
+}
+
+func TestTasteProfile_NoMatchRotates(t *testing.T) {
+	// Nick alone in the evening matches neither profile
+	manager := newTasteTestManager(t, 20, true, false)
+
+	for i := 0; i < 3; i++ {
+		if index := manager.selectPlaylistIndex("day", tasteOptions); index != i {
+			t.Errorf("Call %d: expected rotation index %d, got %d", i, i, index)
+		}
+	}
+
+	blend := manager.GetShadowState().Outputs.TasteBlend
+	if blend == nil || blend.Profile != "" || !strings.Contains(blend.Rationale, "rotated") {
+		t.Errorf("Expected a rotation rationale without a profile, got %+v", blend)
+	}
+}
+
+func TestTasteProfile_WeightsWithinTimeWindow(t *testing.T) {
+	manager := newTasteTestManager(t, 14, true, false)
+	afternoon := []TimeWindow{{Start: "13:00", End: "17:00"}}
+	options := []PlaybackOption{
+		{URI: "spotify:playlist:a1", Sets: []string{"a"}, TimeWindows: afternoon},
+		{URI: "spotify:playlist:neutral", TimeWindows: afternoon, Weight: 3},
+		{URI: "spotify:playlist:a2", Sets: []string{"a"}},
+	}
+
+	counts := make(map[int]int)
+	for i := 0; i < 6; i++ {
+		counts[manager.selectPlaylistIndex("day", options)]++
+	}
+	if counts[0] != 3 || counts[1] != 3 || counts[2] != 0 {
+		t.Errorf("Expected the window's options to be weighted 3:3, got %v", counts)
+	}
+}
+
+func TestTasteProfile_WeightFor(t *testing.T) {
+	profile := TasteProfile{SetWeights: map[string]float64{"a": 3, "b": 0.5}}
+
+	tests := []struct {
+		sets     []string
+		expected float64
+	}{
+		{nil, 1},
+		{[]string{"c"}, 1},
+		{[]string{"b"}, 0.5},
+		{[]string{"a", "b"}, 3},
+	}
+	for _, tt := range tests {
+		if got := profile.WeightFor(PlaybackOption{Sets: tt.sets}); got != tt.expected {
+			t.Errorf("WeightFor(%v) = %v, expected %v", tt.sets, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadConfigTasteProfiles(t *testing.T) {
+	data, err := os.ReadFile("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read production config: %v", err)
+	}
+
+	config, err := LoadConfig("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production config: %v", err)
+	}
+	if len(config.TasteProfiles) == 0 {
+		t.Error("Expected production config to define taste profiles")
+	}
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"missing name", "- name: nick-afternoon", "- name: \"\""},
+		{"unknown music type", `music_types: ["day"]`, `music_types: ["brunch"]`},
+		{"negative weight", "electronic: 3", "electronic: -3"},
+		{"bad window", `- start: "12:00"`, `- start: "noon"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Replace(string(data), tt.from, tt.to, 1)
+			if content == string(data) {
+				t.Fatalf("Production config no longer contains %q", tt.from)
+			}
+			configPath := filepath.Join(t.TempDir(), "music_config.yaml")
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			_, err := LoadConfig(configPath)
+			if err == nil || !strings.Contains(err.Error(), "taste_profiles[") {
+				t.Errorf("Expected taste profile error, got %v", err)
+			}
+		})
+	}
+}
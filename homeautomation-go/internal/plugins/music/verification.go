@@ -0,0 +1,144 @@
+package music
+
+import (
+	"fmt"
+	"time"
+
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// checkDelay returns how long to wait after a start before checking the lead player
+func (m *Manager) checkDelay() time.Duration {
+	return m.currentConfig().PlaybackVerification.CheckDelay()
+}
+
+// verifyPlaybackStart checks that the lead player is playing after a start and
+// re-sends play_media when it isn't. After FailuresBeforeFallback failed checks
+// in a row it moves on to the mode's next playback option, and once every
+// option has failed it gives up, playing the wake fallback for wake music types.
+func (m *Manager) verifyPlaybackStart(musicType string, option PlaybackOption, leadPlayer string) {
+	verification := m.currentConfig().PlaybackVerification
+	leadEntityID := m.getSpeakerEntityID(leadPlayer)
+
+	for failures := 0; ; {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.clock.After(m.checkDelay()):
+		}
+
+		// A newer playback replaces this one and runs its own verification
+		if !m.isCurrentPlayback(musicType, option.URI) {
+			return
+		}
+
+		if m.isLeadPlaying(leadEntityID) {
+			if failures > 0 {
+				m.logger.Info("Playback started after retry",
+					zap.String("type", musicType),
+					zap.String("lead_player", leadPlayer),
+					zap.Int("failed_checks", failures))
+			}
+			return
+		}
+
+		failures++
+		m.logger.Warn("Playback did not start",
+			zap.String("type", musicType),
+			zap.String("lead_player", leadPlayer),
+			zap.String("uri", option.URI),
+			zap.Int("failed_checks", failures))
+
+		if failures >= verification.FailuresBeforeFallback {
+			reason := fmt.Sprintf("lead player not playing after %d checks", failures)
+			fellBack, err := m.fallBackFrom(musicType, option, reason)
+			if !fellBack {
+				m.handlePlaybackStartFailure(musicType, failures)
+			} else if err != nil {
+				m.logger.Error("Failed to start fallback playback",
+					zap.String("type", musicType),
+					zap.Error(err))
+			}
+			return
+		}
+
+		if err := m.callService("media_player", "play_media", map[string]interface{}{
+			"entity_id":          leadEntityID,
+			"media_content_id":   option.URI,
+			"media_content_type": option.MediaType,
+		}); err != nil {
+			m.logger.Error("Failed to retry playback",
+				zap.String("lead_player", leadPlayer),
+				zap.Error(err))
+		}
+	}
+}
+
+// isCurrentPlayback reports whether the given music type and URI are still playing
+func (m *Manager) isCurrentPlayback(musicType, uri string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.currentlyPlaying != nil && m.currentlyPlaying.Type == musicType && m.currentlyPlaying.URI == uri
+}
+
+// isLeadPlaying reports whether Home Assistant shows the lead player as playing
+func (m *Manager) isLeadPlaying(leadEntityID string) bool {
+	leadState, err := m.haClient.GetState(m.ctx, leadEntityID)
+	if err != nil {
+		m.logger.Warn("Failed to read lead player state",
+			zap.String("entity_id", leadEntityID),
+			zap.Error(err))
+		return false
+	}
+	return leadState.State == "playing"
+}
+
+// handlePlaybackStartFailure gives up on the playback and, during a wake
+// sequence, plays the TTS alarm so the wake still happens
+func (m *Manager) handlePlaybackStartFailure(musicType string, failures int) {
+	fallback := m.currentConfig().PlaybackVerification.WakeFallback
+	if fallback == nil || !fallback.Applies(musicType) {
+		m.logger.Error("Giving up on playback after repeated failed starts",
+			zap.String("type", musicType),
+			zap.Int("failed_checks", failures))
+		m.updateShadowState("playback_failed",
+			fmt.Sprintf("%s music did not start after %d checks", musicType, failures), "playback_verification")
+		return
+	}
+
+	entityID := m.getSpeakerEntityID(fallback.Speaker)
+	if bedroom, ok := m.dnd.Suppresses(entityID); ok {
+		m.logger.Info("Skipping wake fallback: speaker is in a do-not-disturb bedroom",
+			zap.String("speaker", fallback.Speaker),
+			zap.String("bedroom", bedroom))
+		return
+	}
+
+	m.logger.Warn("Wake music did not start, playing TTS alarm",
+		zap.String("type", musicType),
+		zap.String("speaker", fallback.Speaker),
+		zap.Int("failed_checks", failures))
+	m.updateShadowState("wake_fallback",
+		fmt.Sprintf("%s music did not start after %d checks, played TTS alarm on %s", musicType, failures, fallback.Speaker),
+		"playback_verification")
+
+	// The speaker was muted for the fade-in, which never got going
+	if err := m.callService("media_player", "volume_set", ha.VolumeFromSonos(fallback.Volume).ServiceData(entityID)); err != nil {
+		m.logger.Error("Failed to set wake fallback volume",
+			zap.String("speaker", fallback.Speaker),
+			zap.Error(err))
+	}
+
+	if err := m.callService("tts", "speak", map[string]interface{}{
+		"entity_id":              fallback.TTSEntity,
+		"media_player_entity_id": []string{entityID},
+		"message":                fallback.Message,
+		"cache":                  true,
+	}); err != nil {
+		m.logger.Error("Failed to play wake fallback",
+			zap.String("speaker", fallback.Speaker),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,228 @@
+package music
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+	"homeautomation/internal/testclock"
+
+	"go.uber.org/zap"
+)
+
+func createVerificationTestConfig() *MusicConfig {
+	return &MusicConfig{
+		Music: map[string]MusicMode{
+			"wakeup": {
+				Participants: []Participant{
+					{PlayerName: "Bedroom", BaseVolume: 6, LeaveMutedIf: []MuteCondition{}},
+				},
+				PlaybackOptions: []PlaybackOption{
+					{URI: "spotify:playlist:wake1", MediaType: "playlist", VolumeMultiplier: 1.0},
+				},
+			},
+			"day": {
+				Participants: []Participant{
+					{PlayerName: "Kitchen", BaseVolume: 9, LeaveMutedIf: []MuteCondition{}},
+				},
+				PlaybackOptions: []PlaybackOption{
+					{URI: "spotify:playlist:day1", MediaType: "playlist", VolumeMultiplier: 1.0},
+				},
+			},
+		},
+		PlaybackVerification: &PlaybackVerification{
+			CheckDelaySeconds:      15,
+			FailuresBeforeFallback: 2,
+			WakeFallback: &WakeFallback{
+				MusicTypes: []string{"wakeup"},
+				Speaker:    "Bedroom",
+				Volume:     6,
+				TTSEntity:  "tts.google_translate_en_com",
+				Message:    "Good morning",
+			},
+		},
+	}
+}
+
+// newVerificationTestManager returns a manager on a virtual clock whose
+// current playback is the given music type's first option
+func newVerificationTestManager(t *testing.T, musicType string) (*Manager, *ha.MockClient, *testclock.Clock) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	manager := NewManager(mockClient, stateManager, createVerificationTestConfig(), logger, false, nil)
+	clk := testclock.New(time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC))
+	manager.SetClock(clk)
+
+	option := manager.currentConfig().Music[musicType].PlaybackOptions[0]
+	lead := manager.currentConfig().Music[musicType].Participants[0].PlayerName
+	manager.currentlyPlaying = &CurrentlyPlayingMusic{
+		Type:       musicType,
+		URI:        option.URI,
+		MediaType:  option.MediaType,
+		LeadPlayer: lead,
+	}
+	return manager, mockClient, clk
+}
+
+// runVerification runs verifyPlaybackStart with clk moved on to each check
+func runVerification(manager *Manager, clk *testclock.Clock, musicType string, leadPlayer string) {
+	option := manager.currentConfig().Music[musicType].PlaybackOptions[0]
+	done := make(chan struct{})
+	go func() {
+		manager.verifyPlaybackStart(musicType, option, leadPlayer)
+		close(done)
+	}()
+	clk.RunUntil(done)
+}
+
+func countCalls(calls []ha.ServiceCall, domain, service string) int {
+	count := 0
+	for _, call := range calls {
+		if call.Domain == domain && call.Service == service {
+			count++
+		}
+	}
+	return count
+}
+
+func TestVerifyPlaybackStart_Playing(t *testing.T) {
+	manager, mockClient, clk := newVerificationTestManager(t, "wakeup")
+	mockClient.SetState("media_player.bedroom", "playing", nil)
+
+	runVerification(manager, clk, "wakeup", "Bedroom")
+
+	if calls := mockClient.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls when playback started, got %d", len(calls))
+	}
+}
+
+func TestVerifyPlaybackStart_WakeFallbackAfterRepeatedFailures(t *testing.T) {
+	manager, mockClient, clk := newVerificationTestManager(t, "wakeup")
+	mockClient.SetState("media_player.bedroom", "idle", nil)
+
+	runVerification(manager, clk, "wakeup", "Bedroom")
+
+	// Two checks 15 seconds apart
+	if elapsed := clk.Since(time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC)); elapsed != 30*time.Second {
+		t.Errorf("Expected the fallback after 30s, got %v", elapsed)
+	}
+
+	calls := mockClient.GetServiceCalls()
+	if got := countCalls(calls, "media_player", "play_media"); got != 1 {
+		t.Errorf("Expected 1 playback retry before the fallback, got %d", got)
+	}
+	if got := countCalls(calls, "tts", "speak"); got != 1 {
+		t.Fatalf("Expected 1 TTS alarm, got %d", got)
+	}
+
+	for _, call := range calls {
+		if call.Domain != "tts" {
+			continue
+		}
+		speakers, _ := call.Data["media_player_entity_id"].([]string)
+		if len(speakers) != 1 || speakers[0] != "media_player.bedroom" {
+			t.Errorf("Expected TTS alarm on media_player.bedroom, got %v", call.Data["media_player_entity_id"])
+		}
+		if call.Data["entity_id"] != "tts.google_translate_en_com" {
+			t.Errorf("Expected TTS entity tts.google_translate_en_com, got %v", call.Data["entity_id"])
+		}
+		if call.Data["message"] != "Good morning" {
+			t.Errorf("Expected configured message, got %v", call.Data["message"])
+		}
+	}
+
+	if got := manager.GetShadowState().Outputs.LastActionType; got != "wake_fallback" {
+		t.Errorf("Expected last action wake_fallback, got %q", got)
+	}
+}
+
+func TestVerifyPlaybackStart_RetrySucceeds(t *testing.T) {
+	manager, mockClient, clk := newVerificationTestManager(t, "wakeup")
+	mockClient.SetState("media_player.bedroom", "idle", nil)
+	manager.currentConfig().PlaybackVerification.FailuresBeforeFallback = 3
+
+	option := manager.currentConfig().Music["wakeup"].PlaybackOptions[0]
+	done := make(chan struct{})
+	go func() {
+		manager.verifyPlaybackStart("wakeup", option, "Bedroom")
+		close(done)
+	}()
+
+	// The first check fails and retries; the retried start takes
+	clk.BlockUntil(1)
+	clk.AdvanceToNext()
+	clk.BlockUntil(1)
+	if got := countCalls(mockClient.GetServiceCalls(), "media_player", "play_media"); got != 1 {
+		t.Fatalf("Expected 1 playback retry after the first check, got %d", got)
+	}
+	mockClient.SetState("media_player.bedroom", "playing", nil)
+	clk.AdvanceToNext()
+	<-done
+
+	if got := countCalls(mockClient.GetServiceCalls(), "tts", "speak"); got != 0 {
+		t.Errorf("Expected no TTS alarm once the retry started playback, got %d", got)
+	}
+}
+
+func TestVerifyPlaybackStart_NoFallbackOutsideWake(t *testing.T) {
+	manager, mockClient, clk := newVerificationTestManager(t, "day")
+	mockClient.SetState("media_player.kitchen", "idle", nil)
+
+	runVerification(manager, clk, "day", "Kitchen")
+
+	calls := mockClient.GetServiceCalls()
+	if got := countCalls(calls, "tts", "speak"); got != 0 {
+		t.Errorf("Expected no TTS alarm for day music, got %d", got)
+	}
+	if got := manager.GetShadowState().Outputs.LastActionType; got != "playback_failed" {
+		t.Errorf("Expected last action playback_failed, got %q", got)
+	}
+}
+
+func TestVerifyPlaybackStart_SupersededPlayback(t *testing.T) {
+	manager, mockClient, clk := newVerificationTestManager(t, "wakeup")
+	mockClient.SetState("media_player.bedroom", "idle", nil)
+
+	// Sleep music replaced the wake music before the check
+	manager.currentlyPlaying = &CurrentlyPlayingMusic{Type: "sleep", URI: "spotify:playlist:sleep1"}
+
+	runVerification(manager, clk, "wakeup", "Bedroom")
+
+	if calls := mockClient.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls for superseded playback, got %d", len(calls))
+	}
+}
+
+func TestPlaybackVerification_Validate(t *testing.T) {
+	modes := createVerificationTestConfig().Music
+
+	tests := []struct {
+		name    string
+		modify  func(v *PlaybackVerification)
+		wantErr bool
+	}{
+		{name: "Valid", modify: func(v *PlaybackVerification) {}},
+		{name: "Without fallback", modify: func(v *PlaybackVerification) { v.WakeFallback = nil }},
+		{name: "Zero delay", modify: func(v *PlaybackVerification) { v.CheckDelaySeconds = 0 }, wantErr: true},
+		{name: "Zero failures", modify: func(v *PlaybackVerification) { v.FailuresBeforeFallback = 0 }, wantErr: true},
+		{name: "Unknown music type", modify: func(v *PlaybackVerification) { v.WakeFallback.MusicTypes = []string{"alarm"} }, wantErr: true},
+		{name: "Volume too high", modify: func(v *PlaybackVerification) { v.WakeFallback.Volume = 20 }, wantErr: true},
+		{name: "Bad TTS entity", modify: func(v *PlaybackVerification) { v.WakeFallback.TTSEntity = "google" }, wantErr: true},
+		{name: "Missing message", modify: func(v *PlaybackVerification) { v.WakeFallback.Message = "" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := createVerificationTestConfig().PlaybackVerification
+			tt.modify(v)
+			err := v.Validate(modes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
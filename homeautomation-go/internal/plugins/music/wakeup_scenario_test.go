@@ -97,6 +97,7 @@ package music
 // =============================================================================
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -251,7 +252,7 @@ func TestScenario_WakeUpDuringMorning_TriggersMorningMusic(t *testing.T) {
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 
 	// Start the manager
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
@@ -349,7 +350,7 @@ func TestScenario_WakeUpOnSunday_TriggersDayMusic(t *testing.T) {
 	_ = stateManager.SetBool("isTVPlaying", false)
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
@@ -414,7 +415,7 @@ func TestScenario_DayPhaseChangesToMorning_TriggersDayMusic(t *testing.T) {
 	_ = stateManager.SetBool("isTVPlaying", false)
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
@@ -473,7 +474,7 @@ func TestScenario_NoOneHome_StopsMusic(t *testing.T) {
 	_ = stateManager.SetBool("isTVPlaying", false)
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
@@ -531,7 +532,7 @@ func TestScenario_SomeoneFallsAsleep_TriggersSleepMusic(t *testing.T) {
 	_ = stateManager.SetBool("isTVPlaying", false)
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
@@ -605,7 +606,7 @@ func TestScenario_SleepMusicPersistsDuringWinddown(t *testing.T) {
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 	// Don't set musicPlaybackType yet - let Start() set it initially
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
@@ -678,7 +679,7 @@ func TestScenario_FullWakeUpCycle(t *testing.T) {
 	_ = stateManager.SetBool("isTVPlaying", false)
 	_ = stateManager.SetBool("isNickOfficeOccupied", false)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	require.NoError(t, err)
 	defer manager.Stop()
 
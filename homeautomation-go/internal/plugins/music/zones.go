@@ -0,0 +1,398 @@
+package music
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"homeautomation/internal/events"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// ZoneVariable returns the state variable holding a zone's own music mode,
+// e.g. "musicPlaybackType.office"
+func ZoneVariable(zone string) string {
+	return state.ZoneKey("musicPlaybackType", zone)
+}
+
+// RegisterZoneVariables registers each zone's musicPlaybackType.<zone>
+// variable, synced with input_text.music_playback_type_<zone>. Must be called
+// before SyncFromHA.
+func RegisterZoneVariables(stateManager *state.Manager, config *MusicConfig) error {
+	for _, zone := range slices.Sorted(maps.Keys(config.Zones)) {
+		if err := stateManager.RegisterZone(zone, []string{"musicPlaybackType"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zoneNames returns the configured zones in order
+func (m *Manager) zoneNames() []string {
+	return slices.Sorted(maps.Keys(m.currentConfig().Zones))
+}
+
+// subscribeZones starts or stops each zone's own playback when its
+// musicPlaybackType.<zone> variable changes
+func (m *Manager) subscribeZones(bus *events.Bus) ([]state.Subscription, error) {
+	zones := m.zoneNames()
+	subs := make([]state.Subscription, 0, len(zones))
+	for _, zone := range zones {
+		sub, err := bus.OnString(ZoneVariable(zone), func(e events.StringChanged) {
+			m.handleZonePlaybackTypeChange(zone, e)
+		})
+		if err != nil {
+			for _, s := range subs {
+				s.Unsubscribe()
+			}
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", ZoneVariable(zone), err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// zoneMode returns the music mode the zone plays on its own, or "" when the
+// zone follows the whole-house mode. Unknown modes and modes kid mode blocks
+// are not played, so the zone follows the whole house instead.
+func (m *Manager) zoneMode(zone string) string {
+	musicType, err := m.stateManager.GetString(ZoneVariable(zone))
+	if err != nil || musicType == "" || m.kid.BlocksMusicMode(musicType) {
+		return ""
+	}
+	if _, ok := m.currentConfig().Music[musicType]; !ok {
+		return ""
+	}
+	return musicType
+}
+
+// claimingZone returns the zone playing its own mode on the speaker, if any
+func (m *Manager) claimingZone(speakerName string) (string, bool) {
+	for name, zone := range m.currentConfig().Zones {
+		if slices.Contains(zone.Speakers, speakerName) && m.zoneMode(name) != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// handleZonePlaybackTypeChange plays the zone's new mode on its speakers, or
+// returns them to the whole-house mode when the variable is cleared
+func (m *Manager) handleZonePlaybackTypeChange(zone string, e events.StringChanged) {
+	m.health.Tick()
+	trigger := ZoneVariable(zone)
+
+	m.logger.Info("Zone music playback type changed",
+		zap.String("zone", zone),
+		zap.String("old", e.Old),
+		zap.String("new", e.New))
+
+	if e.New != "" {
+		if _, ok := m.currentConfig().Music[e.New]; !ok {
+			m.logger.Error("Unknown music type for zone, following the whole-house mode",
+				zap.String("zone", zone),
+				zap.String("type", e.New))
+		} else if m.kid.BlocksMusicMode(e.New) {
+			m.logger.Info("Kid mode blocks the zone's music mode, following the whole-house mode",
+				zap.String("zone", zone),
+				zap.String("type", e.New))
+			m.updateShadowState("kid_mode_blocked",
+				fmt.Sprintf("Kid mode blocks %s music in the %s zone", e.New, zone), trigger)
+		}
+	}
+
+	musicType := m.zoneMode(zone)
+	if musicType == "" {
+		m.stopZone(zone, trigger)
+		return
+	}
+
+	m.mu.RLock()
+	playing := m.zonePlaying[zone]
+	m.mu.RUnlock()
+	if playing != nil && playing.Type == musicType {
+		m.logger.Debug("Zone already playing this music type, ignoring",
+			zap.String("zone", zone),
+			zap.String("type", musicType))
+		return
+	}
+
+	if err := m.playZone(zone, musicType, trigger); err != nil {
+		m.logger.Error("Failed to start zone playback",
+			zap.String("zone", zone),
+			zap.String("type", musicType),
+			zap.Error(err))
+	}
+}
+
+// zoneParticipants builds the zone's speakers for a playback option. Speakers
+// the mode configures keep their base volume and mute conditions; the zone's
+// own mute conditions are added to each.
+func (m *Manager) zoneParticipants(zone string, musicType string, mode MusicMode, option PlaybackOption) []ParticipantWithVolume {
+	z := m.currentConfig().Zones[zone]
+
+	configured := make(map[string]Participant, len(mode.Participants))
+	for _, p := range mode.Participants {
+		configured[p.PlayerName] = p
+	}
+
+	sources := make([]Participant, 0, len(z.Speakers))
+	for _, name := range z.Speakers {
+		p, ok := configured[name]
+		if !ok {
+			p = Participant{PlayerName: name, BaseVolume: z.BaseVolume}
+		}
+		p.LeaveMutedIf = append(append([]MuteCondition(nil), p.LeaveMutedIf...), z.LeaveMutedIf...)
+		sources = append(sources, p)
+	}
+	return m.participantsWithVolume(musicType, sources, option)
+}
+
+// playZone takes the zone's speakers out of the whole-house group and starts
+// the zone's own mode on them
+func (m *Manager) playZone(zone string, musicType string, trigger string) error {
+	mode, ok := m.currentConfig().Music[musicType]
+	if !ok {
+		return fmt.Errorf("unknown music type: %s", musicType)
+	}
+	if len(mode.PlaybackOptions) == 0 {
+		return fmt.Errorf("no playback options for music type: %s", musicType)
+	}
+
+	option := mode.PlaybackOptions[m.selectPlaylistIndex(musicType, mode.PlaybackOptions)]
+	participants := m.zoneParticipants(zone, musicType, mode, option)
+
+	m.mu.Lock()
+	previous := m.zonePlaying[zone]
+	if len(participants) > 0 {
+		m.zonePlaying[zone] = &CurrentlyPlayingMusic{
+			Type:         musicType,
+			URI:          option.URI,
+			MediaType:    option.MediaType,
+			LeadPlayer:   participants[0].PlayerName,
+			Participants: participants,
+		}
+	} else {
+		delete(m.zonePlaying, zone)
+	}
+	m.mu.Unlock()
+
+	if previous != nil && !m.readOnly {
+		m.releaseSpeakers(previous.Participants, participants)
+	}
+	m.handOverToZone(trigger)
+
+	if len(participants) == 0 {
+		m.logger.Info("Skipping zone playback: all speakers are in do-not-disturb bedrooms or kid mode",
+			zap.String("zone", zone),
+			zap.String("type", musicType))
+		m.recordZoneShadowState(zone, nil, trigger,
+			fmt.Sprintf("Skipped %s music in the %s zone: no speakers available", musicType, zone))
+		return nil
+	}
+	leadPlayer := participants[0].PlayerName
+
+	if m.readOnly {
+		m.logger.Info("Read-only mode: would start zone playback",
+			zap.String("zone", zone),
+			zap.String("type", musicType),
+			zap.String("lead_player", leadPlayer))
+	} else if err := m.executePlayback(zone, musicType, option, participants, leadPlayer); err != nil {
+		return fmt.Errorf("failed to execute zone playback: %w", err)
+	}
+
+	m.recordZoneShadowState(zone, &shadowstate.ZonePlayback{
+		MusicType: musicType,
+		Playlist:  shadowstate.PlaylistInfo{URI: option.URI, MediaType: option.MediaType},
+		Speakers:  speakerStates(participants, leadPlayer),
+		StartedAt: m.timeProvider.Now(),
+	}, trigger, fmt.Sprintf("Started playback of '%s' in mode '%s' in the %s zone", option.URI, musicType, zone))
+
+	if !m.readOnly {
+		m.stateManager.Events().PublishPluginAction(events.PluginAction{
+			Plugin: "music",
+			Action: "play",
+			Reason: fmt.Sprintf("%s music in the %s zone (triggered by %s)", musicType, zone, trigger),
+			Time:   m.timeProvider.Now(),
+		})
+	}
+	return nil
+}
+
+// handOverToZone regroups the whole-house playback without the speakers a
+// zone now plays its own mode on
+func (m *Manager) handOverToZone(trigger string) {
+	m.mu.RLock()
+	playing := m.currentlyPlaying
+	m.mu.RUnlock()
+	if playing == nil {
+		return
+	}
+
+	claimed := false
+	for _, p := range playing.Participants {
+		if _, ok := m.claimingZone(p.PlayerName); ok {
+			claimed = true
+			break
+		}
+	}
+	if !claimed {
+		return
+	}
+
+	if err := m.regroupPlayback(playing.Type, trigger); err != nil {
+		// Every speaker went to zones; the whole house resumes when one is cleared
+		m.logger.Info("Whole-house playback has no speakers left outside zones",
+			zap.String("type", playing.Type),
+			zap.Error(err))
+		m.mu.Lock()
+		cleared := m.currentlyPlaying == playing
+		if cleared {
+			m.currentlyPlaying = nil
+		}
+		m.mu.Unlock()
+		if cleared {
+			m.unwatchLeadPlayer()
+		}
+	}
+}
+
+// stopZone stops the zone's own playback and returns its speakers to the
+// whole-house mode
+func (m *Manager) stopZone(zone string, trigger string) {
+	m.mu.Lock()
+	playing := m.zonePlaying[zone]
+	delete(m.zonePlaying, zone)
+	m.mu.Unlock()
+	if playing == nil {
+		return
+	}
+
+	m.logger.Info("Stopping zone playback",
+		zap.String("zone", zone),
+		zap.String("type", playing.Type))
+
+	if !m.readOnly {
+		m.releaseSpeakers(playing.Participants, nil)
+	}
+	m.recordZoneShadowState(zone, nil, trigger,
+		fmt.Sprintf("Stopped %s music in the %s zone, returning it to the whole-house mode", playing.Type, zone))
+
+	musicType, err := m.stateManager.GetString("musicPlaybackType")
+	if err != nil || musicType == "" || m.kid.BlocksMusicMode(musicType) {
+		return
+	}
+	if !m.houseIncludes(musicType, m.currentConfig().Zones[zone].Speakers) {
+		return
+	}
+	if err := m.regroupPlayback(musicType, trigger); err != nil {
+		m.logger.Error("Failed to return zone speakers to the whole-house mode",
+			zap.String("zone", zone),
+			zap.Error(err))
+	}
+}
+
+// houseIncludes reports whether the whole-house mode, or the active speaker
+// group preset, plays on any of the speakers
+func (m *Manager) houseIncludes(musicType string, speakers []string) bool {
+	config := m.currentConfig()
+
+	m.mu.RLock()
+	preset, usePreset := config.SpeakerGroups[m.activePreset]
+	m.mu.RUnlock()
+
+	if usePreset {
+		for _, name := range preset.Speakers {
+			if slices.Contains(speakers, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range config.Music[musicType].Participants {
+		if slices.Contains(speakers, p.PlayerName) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyKidModeToZones stops zone modes kid mode now blocks, starts those it
+// no longer blocks, and brings the other zones' speakers to their kid mode volumes
+func (m *Manager) applyKidModeToZones(key string) {
+	for _, zone := range m.zoneNames() {
+		m.mu.RLock()
+		playing := m.zonePlaying[zone]
+		m.mu.RUnlock()
+
+		musicType := m.zoneMode(zone)
+		switch {
+		case playing == nil && musicType != "":
+			if err := m.playZone(zone, musicType, key); err != nil {
+				m.logger.Error("Failed to start zone playback",
+					zap.String("zone", zone),
+					zap.String("type", musicType),
+					zap.Error(err))
+			}
+			continue
+		case playing == nil:
+			continue
+		case musicType == "":
+			m.logger.Info("Kid mode blocks the zone's music mode, stopping it",
+				zap.String("zone", zone),
+				zap.String("type", playing.Type))
+			m.stopZone(zone, key)
+			continue
+		}
+
+		participants, adjusted := m.applyKidModeVolumes(playing.Type, playing.Participants)
+		if len(adjusted) == 0 {
+			continue
+		}
+
+		m.mu.Lock()
+		if m.zonePlaying[zone] != playing {
+			// Playback changed while the volumes were being set
+			m.mu.Unlock()
+			continue
+		}
+		updated := *playing
+		updated.Participants = participants
+		m.zonePlaying[zone] = &updated
+		m.mu.Unlock()
+
+		m.shadowMu.Lock()
+		if playback, ok := m.shadowState.Outputs.Zones[zone]; ok {
+			playback.Speakers = speakerStates(participants, playing.LeadPlayer)
+			m.shadowState.Outputs.Zones[zone] = playback
+		}
+		m.shadowMu.Unlock()
+		m.updateShadowState("kid_mode",
+			fmt.Sprintf("Kid mode changed %s zone speaker volumes: %s", zone, strings.Join(adjusted, ", ")), key)
+	}
+}
+
+// recordZoneShadowState records a zone starting (playback set) or stopping
+// (playback nil) its own mode
+func (m *Manager) recordZoneShadowState(zone string, playback *shadowstate.ZonePlayback, trigger string, reason string) {
+	actionType := "stop_zone_playback"
+	if playback != nil {
+		actionType = "start_zone_playback"
+	}
+	m.updateShadowState(actionType, reason, trigger)
+
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+	if playback == nil {
+		delete(m.shadowState.Outputs.Zones, zone)
+		return
+	}
+	if m.shadowState.Outputs.Zones == nil {
+		m.shadowState.Outputs.Zones = make(map[string]shadowstate.ZonePlayback)
+	}
+	m.shadowState.Outputs.Zones[zone] = *playback
+}
@@ -0,0 +1,279 @@
+package music
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"homeautomation/internal/events"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// setupZoneTest returns a manager playing day music on Kitchen, Living Room
+// and Office, with Office in an office zone that leaves it muted while the
+// office is empty
+func setupZoneTest(t *testing.T) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.Connect(context.Background())
+	stateManager := state.NewManager(mockClient, logger, false)
+
+	config := createSpeakerGroupTestConfig()
+	config.Zones = map[string]Zone{
+		"office": {
+			Speakers:     []string{"Office"},
+			BaseVolume:   6,
+			LeaveMutedIf: []MuteCondition{{Variable: "isNickOfficeOccupied", Value: false}},
+		},
+	}
+	if err := RegisterZoneVariables(stateManager, config); err != nil {
+		t.Fatalf("RegisterZoneVariables() failed: %v", err)
+	}
+	if err := stateManager.SyncFromHA(); err != nil {
+		t.Fatalf("SyncFromHA() failed: %v", err)
+	}
+
+	manager := NewManager(mockClient, stateManager, config, logger, false, nil)
+	if err := stateManager.SetString("musicPlaybackType", "day"); err != nil {
+		t.Fatalf("Failed to set musicPlaybackType: %v", err)
+	}
+	if err := manager.orchestratePlayback("day", "test_trigger"); err != nil {
+		t.Fatalf("orchestratePlayback() failed: %v", err)
+	}
+	return manager, mockClient, stateManager
+}
+
+// setZoneMode sets the zone's music mode and delivers the change
+func setZoneMode(t *testing.T, manager *Manager, stateManager *state.Manager, zone, musicType string) {
+	t.Helper()
+	old, _ := stateManager.GetString(ZoneVariable(zone))
+	if err := stateManager.SetString(ZoneVariable(zone), musicType); err != nil {
+		t.Fatalf("Failed to set %s: %v", ZoneVariable(zone), err)
+	}
+	manager.handleZonePlaybackTypeChange(zone, events.StringChanged{Key: ZoneVariable(zone), Old: old, New: musicType})
+}
+
+// playMediaOn returns the media started on the entity, or ""
+func playMediaOn(mockClient *ha.MockClient, entityID string) string {
+	uri := ""
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "play_media" && call.Data["entity_id"] == entityID {
+			uri = call.Data["media_content_id"].(string)
+		}
+	}
+	return uri
+}
+
+func TestZonePlayback_TakesSpeakersFromHouse(t *testing.T) {
+	manager, mockClient, stateManager := setupZoneTest(t)
+	mockClient.ClearServiceCalls()
+
+	setZoneMode(t, manager, stateManager, "office", "evening")
+
+	if uri := playMediaOn(mockClient, "media_player.office"); uri != "spotify:playlist:evening1" {
+		t.Errorf("Expected evening music started on Office, got %q", uri)
+	}
+	if !manager.isPlayingOnIn("office", "Office") {
+		t.Error("Expected Office to play the office zone's mode")
+	}
+	if manager.isPlayingOn("Office") {
+		t.Error("Expected Office to leave the whole-house group")
+	}
+	if !manager.isPlayingOn("Kitchen") || !manager.isPlayingOn("Living Room") {
+		t.Error("Expected the rest of the house to keep playing")
+	}
+
+	manager.mu.RLock()
+	zone := *manager.zonePlaying["office"]
+	manager.mu.RUnlock()
+	// Evening doesn't configure Office, so the zone's base volume applies
+	if zone.Type != "evening" || len(zone.Participants) != 1 || zone.Participants[0].BaseVolume != 6 {
+		t.Errorf("Expected Office at the zone's base volume, got %+v", zone)
+	}
+
+	shadow := manager.GetShadowState()
+	playback, ok := shadow.Outputs.Zones["office"]
+	if !ok || playback.MusicType != "evening" || len(playback.Speakers) != 1 {
+		t.Errorf("Expected the office zone in the shadow state, got %+v", shadow.Outputs.Zones)
+	}
+	if shadow.Outputs.LastActionType != "start_zone_playback" {
+		t.Errorf("Expected start_zone_playback, got %q", shadow.Outputs.LastActionType)
+	}
+}
+
+func TestZonePlayback_ClearReturnsSpeakers(t *testing.T) {
+	manager, mockClient, stateManager := setupZoneTest(t)
+	setZoneMode(t, manager, stateManager, "office", "evening")
+	mockClient.ClearServiceCalls()
+
+	setZoneMode(t, manager, stateManager, "office", "")
+
+	if manager.isPlayingOnIn("office", "Office") {
+		t.Error("Expected the office zone to stop")
+	}
+	if !manager.isPlayingOn("Office") {
+		t.Error("Expected Office back in the whole-house group")
+	}
+	if _, ok := manager.GetShadowState().Outputs.Zones["office"]; ok {
+		t.Error("Expected the office zone to be cleared from the shadow state")
+	}
+	joined := false
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Service == "join" && call.Data["entity_id"] == "media_player.office" {
+			joined = true
+		}
+	}
+	if !joined {
+		t.Error("Expected Office to rejoin the whole-house group")
+	}
+}
+
+func TestZonePlayback_MuteConditions(t *testing.T) {
+	manager, mockClient, stateManager := setupZoneTest(t)
+	setZoneMode(t, manager, stateManager, "office", "day")
+
+	manager.mu.RLock()
+	participant := manager.zonePlaying["office"].Participants[0]
+	manager.mu.RUnlock()
+	if manager.shouldUnmuteSpeaker(participant) {
+		t.Error("Expected Office left muted while the office is empty")
+	}
+	if _, ok := manager.collectMuteConditionVariables()["isNickOfficeOccupied"]; !ok {
+		t.Error("Expected the zone's mute condition variable to be subscribed")
+	}
+
+	mockClient.ClearServiceCalls()
+	if err := stateManager.SetBool("isNickOfficeOccupied", true); err != nil {
+		t.Fatalf("Failed to set isNickOfficeOccupied: %v", err)
+	}
+	manager.handleMuteConditionChange("isNickOfficeOccupied")
+
+	// Day configures Office at 8, which the zone keeps
+	if level := volumeSetTo(mockClient, "media_player.office"); level != ha.VolumeFromSonos(8).Level() {
+		t.Errorf("Expected Office unmuted to volume 8, got %v", level)
+	}
+}
+
+func TestZonePlayback_HouseStopLeavesZone(t *testing.T) {
+	manager, mockClient, stateManager := setupZoneTest(t)
+	setZoneMode(t, manager, stateManager, "office", "evening")
+	mockClient.ClearServiceCalls()
+
+	manager.stopPlayback()
+
+	if level := volumeSetTo(mockClient, "media_player.office"); level != -1 {
+		t.Errorf("Expected the office zone left playing, got volume %v", level)
+	}
+	if level := volumeSetTo(mockClient, "media_player.kitchen"); level != 0 {
+		t.Errorf("Expected Kitchen muted, got %v", level)
+	}
+}
+
+func TestZonePlayback_UnknownModeFollowsHouse(t *testing.T) {
+	manager, mockClient, stateManager := setupZoneTest(t)
+	mockClient.ClearServiceCalls()
+
+	setZoneMode(t, manager, stateManager, "office", "disco")
+
+	if uri := playMediaOn(mockClient, "media_player.office"); uri != "" {
+		t.Errorf("Expected nothing started for an unknown mode, got %q", uri)
+	}
+	if !manager.isPlayingOn("Office") {
+		t.Error("Expected Office to stay in the whole-house group")
+	}
+}
+
+func TestZonePlayback_KidModeBlocksZoneMode(t *testing.T) {
+	manager, mockClient, stateManager := setupZoneTest(t)
+	kidConfig := &kidmode.Config{KidMode: kidmode.Settings{
+		ToggleVariable:    "isKidMode",
+		Rooms:             []kidmode.Room{{Name: "common_areas", Speakers: []string{"media_player.kitchen"}}},
+		BlockedMusicModes: []string{"evening"},
+	}}
+	manager.SetKidMode(kidmode.NewGuard(kidConfig, stateManager))
+	stateManager.SetBool(kidmode.ActiveVariable, true)
+	mockClient.ClearServiceCalls()
+
+	setZoneMode(t, manager, stateManager, "office", "evening")
+
+	if uri := playMediaOn(mockClient, "media_player.office"); uri != "" {
+		t.Errorf("Expected the blocked mode not to play in the zone, got %q", uri)
+	}
+	if shadow := manager.GetShadowState(); shadow.Outputs.LastActionType != "kid_mode_blocked" {
+		t.Errorf("Expected kid_mode_blocked, got %q", shadow.Outputs.LastActionType)
+	}
+
+	// When kid mode ends the zone plays the mode it was set to
+	stateManager.SetBool(kidmode.ActiveVariable, false)
+	manager.handleKidModeChange(kidmode.ActiveVariable)
+
+	if uri := playMediaOn(mockClient, "media_player.office"); uri != "spotify:playlist:evening1" {
+		t.Errorf("Expected evening music in the zone after kid mode, got %q", uri)
+	}
+}
+
+func TestReload_RejectsZoneChanges(t *testing.T) {
+	manager, _, _ := setupZoneTest(t)
+
+	config := createSpeakerGroupTestConfig()
+	config.Zones = map[string]Zone{"office": {Speakers: []string{"Office", "Living Room"}}}
+	if err := manager.Reload(config); err != nil {
+		t.Errorf("Expected a zone's speakers to be reloadable, got %v", err)
+	}
+
+	config = createSpeakerGroupTestConfig()
+	config.Zones = map[string]Zone{"patio": {Speakers: []string{"Patio"}}}
+	if err := manager.Reload(config); err == nil {
+		t.Error("Expected an error for a changed set of zones")
+	}
+}
+
+func TestLoadConfigZones(t *testing.T) {
+	data, err := os.ReadFile("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read production config: %v", err)
+	}
+
+	config, err := LoadConfig("../../../../configs/music_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production config: %v", err)
+	}
+	if office := config.Zones["office"]; len(office.Speakers) != 1 || office.Speakers[0] != "Office" {
+		t.Errorf("Expected office zone with speakers [Office], got %+v", office)
+	}
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"invalid name", "  office:\n    speakers", "  Office:\n    speakers"},
+		{"speaker in two zones", "speakers: [\"Kitchen\", \"Dining Room\"]\n    base_volume: 8", "speakers: [\"Kitchen\", \"Office\"]\n    base_volume: 8"},
+		{"no speakers", "speakers: [\"Kitchen\", \"Dining Room\"]\n    base_volume: 8", "speakers: []\n    base_volume: 8"},
+		{"base volume", "    base_volume: 8\n    leave_muted_if:", "    base_volume: 20\n    leave_muted_if:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Replace(string(data), tt.from, tt.to, 1)
+			if content == string(data) {
+				t.Fatalf("Production config no longer contains %q", tt.from)
+			}
+			configPath := filepath.Join(t.TempDir(), "music_config.yaml")
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			if _, err := LoadConfig(configPath); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package openreminder
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SensorConfig describes a door or window contact sensor
+type SensorConfig struct {
+	EntityID string `yaml:"entity_id"`
+	Name     string `yaml:"name"`
+	Room     string `yaml:"room"` // Optional, included in reminders
+}
+
+// ReminderSettings holds the sensors to check and how reminders are delivered
+type ReminderSettings struct {
+	Sensors               []SensorConfig      `yaml:"sensors"`
+	RepeatIntervalMinutes int                 `yaml:"repeat_interval_minutes"`
+	MaxReminders          int                 `yaml:"max_reminders"`      // 0 = repeat until closed or acknowledged
+	NotifyService         notifyrouter.Target `yaml:"notify_service"`     // e.g. "notify.notify"; empty disables notifications
+	AcknowledgeEntity     string              `yaml:"acknowledge_entity"` // Optional input_button
+	AnnounceWhenAsleep    bool                `yaml:"announce_when_asleep"`
+	TTSEntity             string              `yaml:"tts_entity"`
+	AnnounceSpeakers      []string            `yaml:"announce_speakers"`
+}
+
+// OpenReminderConfig represents the open_reminder_config.yaml structure
+type OpenReminderConfig struct {
+	OpenReminder ReminderSettings `yaml:"open_reminder"`
+}
+
+// RepeatInterval returns the time between reminders
+func (c *OpenReminderConfig) RepeatInterval() time.Duration {
+	return time.Duration(c.OpenReminder.RepeatIntervalMinutes) * time.Minute
+}
+
+// Validate checks that the sensors and reminder schedule are usable
+func (c *OpenReminderConfig) Validate() error {
+	s := c.OpenReminder
+	for i, sensor := range s.Sensors {
+		if sensor.EntityID == "" {
+			return fmt.Errorf("open_reminder: sensors[%d]: entity_id is required", i)
+		}
+		if sensor.Name == "" {
+			return fmt.Errorf("open_reminder: sensors[%d]: name is required", i)
+		}
+	}
+	if s.RepeatIntervalMinutes <= 0 {
+		return fmt.Errorf("open_reminder: repeat_interval_minutes must be positive")
+	}
+	if s.MaxReminders < 0 {
+		return fmt.Errorf("open_reminder: max_reminders must not be negative")
+	}
+	if s.NotifyService != "" {
+		if _, _, ok := s.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("open_reminder: invalid notify_service %q (expected domain.service)", s.NotifyService)
+		}
+	}
+	if s.AnnounceWhenAsleep && s.TTSEntity == "" {
+		return fmt.Errorf("open_reminder: tts_entity is required when announce_when_asleep is set")
+	}
+	return nil
+}
+
+// LoadConfig loads the open reminder configuration from a YAML file
+func LoadConfig(path string) (*OpenReminderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config OpenReminderConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,106 @@
+package openreminder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "open_reminder_config.yaml")
+
+	configContent := `---
+open_reminder:
+  sensors:
+    - entity_id: cover.garage_door_door
+      name: Garage door
+      room: Garage
+    - entity_id: binary_sensor.back_door
+      name: Back door
+  repeat_interval_minutes: 15
+  max_reminders: 4
+  notify_service: notify.notify
+  acknowledge_entity: input_button.acknowledge_open_reminder
+  announce_when_asleep: true
+  tts_entity: tts.google_translate_en_com
+  announce_speakers:
+    - media_player.bedroom
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	s := config.OpenReminder
+	require.Len(t, s.Sensors, 2)
+	assert.Equal(t, "cover.garage_door_door", s.Sensors[0].EntityID)
+	assert.Equal(t, "Garage", s.Sensors[0].Room)
+	assert.Equal(t, "", s.Sensors[1].Room)
+	assert.Equal(t, 15*time.Minute, config.RepeatInterval())
+	assert.Equal(t, 4, s.MaxReminders)
+	assert.Equal(t, "input_button.acknowledge_open_reminder", s.AcknowledgeEntity)
+	assert.True(t, s.AnnounceWhenAsleep)
+	assert.Equal(t, []string{"media_player.bedroom"}, s.AnnounceSpeakers)
+
+	domain, service, ok := s.NotifyService.DomainService()
+	assert.True(t, ok)
+	assert.Equal(t, "notify", domain)
+	assert.Equal(t, "notify", service)
+}
+
+func TestLoadConfig_ProductionFile(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/open_reminder_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.OpenReminder.Sensors)
+}
+
+func TestLoadConfig_FileNotFound(t *testing.T) {
+	_, err := LoadConfig("/nonexistent/open_reminder_config.yaml")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *OpenReminderConfig {
+		return &OpenReminderConfig{
+			OpenReminder: ReminderSettings{
+				Sensors:               []SensorConfig{{EntityID: "cover.garage_door_door", Name: "Garage door"}},
+				RepeatIntervalMinutes: 15,
+				NotifyService:         "notify.notify",
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *OpenReminderConfig)
+		wantErr string
+	}{
+		{"valid", func(c *OpenReminderConfig) {}, ""},
+		{"missing entity_id", func(c *OpenReminderConfig) { c.OpenReminder.Sensors[0].EntityID = "" }, "entity_id is required"},
+		{"missing name", func(c *OpenReminderConfig) { c.OpenReminder.Sensors[0].Name = "" }, "name is required"},
+		{"zero interval", func(c *OpenReminderConfig) { c.OpenReminder.RepeatIntervalMinutes = 0 }, "repeat_interval_minutes"},
+		{"negative max reminders", func(c *OpenReminderConfig) { c.OpenReminder.MaxReminders = -1 }, "max_reminders"},
+		{"bad notify service", func(c *OpenReminderConfig) { c.OpenReminder.NotifyService = "notify" }, "notify_service"},
+		{"empty notify service", func(c *OpenReminderConfig) { c.OpenReminder.NotifyService = "" }, ""},
+		{"announce without tts", func(c *OpenReminderConfig) { c.OpenReminder.AnnounceWhenAsleep = true }, "tts_entity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+			err := c.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
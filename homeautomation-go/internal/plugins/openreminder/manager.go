@@ -0,0 +1,506 @@
+package openreminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/focusmode"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// Triggers that start a reminder sequence, named after the state variable
+const (
+	TriggerEveryoneAsleep = "isEveryoneAsleep"
+	TriggerEveryoneLeft   = "isAnyoneHome"
+)
+
+// NotificationTitle is the title used for push notifications
+const NotificationTitle = "Door or window left open"
+
+// ErrNoActiveReminder is returned by Acknowledge when nothing is being reminded about
+var ErrNoActiveReminder = errors.New("no active open reminder")
+
+// reminder tracks one sequence of repeated reminders
+type reminder struct {
+	trigger string
+	started time.Time
+	sent    int
+	timer   clock.Timer
+}
+
+// Manager reminds everyone about doors and windows left open when the house
+// goes to sleep or the last person leaves
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *OpenReminderConfig
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+
+	// Speakers in do-not-disturb bedrooms are skipped by announcements, nil if not configured
+	dnd *donotdisturb.Guard
+	// Office speakers are skipped by announcements during focus mode, nil if not configured
+	focus *focusmode.Guard
+	// Lowers announcements to a chime or push as the evening goes on, nil speaks in full
+	notifications *notifyrouter.Router
+
+	// Current reminder sequence, nil when none is active
+	active *reminder
+	// Last seen trigger values, so HA echoes of our own writes are ignored
+	asleep  bool
+	anyHome bool
+	mu      sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.OpenReminderTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Open Reminder manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *OpenReminderConfig, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	shadowTracker := shadowstate.NewOpenReminderTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("openreminder")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "openreminder", logger.Named("openreminder")),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetDoNotDisturb sets the per-bedroom do-not-disturb guard used to skip
+// announcements on speakers in bedrooms with the toggle on
+func (m *Manager) SetDoNotDisturb(guard *donotdisturb.Guard) {
+	m.dnd = guard
+}
+
+// SetFocusMode sets the office focus mode guard used to skip announcements
+// on the office speakers while focus mode is on
+func (m *Manager) SetFocusMode(guard *focusmode.Guard) {
+	m.focus = guard
+}
+
+// SetNotificationRouter sets the router that quiets announcements during
+// winddown and night
+func (m *Manager) SetNotificationRouter(router *notifyrouter.Router) {
+	m.notifications = router
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.OpenReminderShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start begins watching for the house going to sleep or becoming empty
+func (m *Manager) Start(ctx context.Context) error {
+	// Reminders run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Open Reminder Manager",
+		zap.Int("sensors", len(m.config.OpenReminder.Sensors)),
+		zap.Duration("repeat_interval", m.config.RepeatInterval()),
+		zap.Int("max_reminders", m.config.OpenReminder.MaxReminders))
+
+	// Seed the last seen values so a restart doesn't look like a transition
+	m.mu.Lock()
+	if asleep, err := m.stateManager.GetBool("isEveryoneAsleep"); err == nil {
+		m.asleep = asleep
+	}
+	if anyHome, err := m.stateManager.GetBool("isAnyoneHome"); err == nil {
+		m.anyHome = anyHome
+	}
+	m.mu.Unlock()
+
+	if err := m.subHelper.SubscribeToState("isEveryoneAsleep", m.handleEveryoneAsleepChange); err != nil {
+		return fmt.Errorf("failed to subscribe to isEveryoneAsleep: %w", err)
+	}
+	if err := m.subHelper.SubscribeToState("isAnyoneHome", m.handleAnyoneHomeChange); err != nil {
+		return fmt.Errorf("failed to subscribe to isAnyoneHome: %w", err)
+	}
+
+	for _, sensor := range m.config.OpenReminder.Sensors {
+		if err := m.subHelper.SubscribeToEntity(sensor.EntityID, m.handleSensorChange); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", sensor.EntityID, err)
+		}
+	}
+
+	if entityID := m.config.OpenReminder.AcknowledgeEntity; entityID != "" {
+		if err := m.subHelper.SubscribeToEntity(entityID, m.handleAcknowledgeEntityChange); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+	}
+
+	m.subHelper.CaptureInitialInputs()
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Open Reminder Manager started successfully")
+	return nil
+}
+
+// Stop stops the Open Reminder Manager and cleans up subscriptions
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Open Reminder Manager")
+
+	m.cancel()
+
+	m.mu.Lock()
+	if m.active != nil && m.active.timer != nil {
+		m.active.timer.Stop()
+	}
+	m.active = nil
+	m.mu.Unlock()
+
+	m.subHelper.UnsubscribeAll()
+
+	m.logger.Info("Open Reminder Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset ends any active reminder and re-checks doors and windows if the
+// house is currently asleep or empty
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Open Reminder - re-checking doors and windows")
+
+	m.mu.Lock()
+	m.endLocked("Reset")
+	if asleep, err := m.stateManager.GetBool("isEveryoneAsleep"); err == nil {
+		m.asleep = asleep
+	}
+	if anyHome, err := m.stateManager.GetBool("isAnyoneHome"); err == nil {
+		m.anyHome = anyHome
+	}
+	asleep, anyHome := m.asleep, m.anyHome
+	m.mu.Unlock()
+
+	switch {
+	case !anyHome:
+		m.startReminder(TriggerEveryoneLeft)
+	case asleep:
+		m.startReminder(TriggerEveryoneAsleep)
+	}
+
+	m.logger.Info("Successfully reset Open Reminder")
+	return nil
+}
+
+// Acknowledge stops the active reminder sequence
+func (m *Manager) Acknowledge() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active == nil {
+		return ErrNoActiveReminder
+	}
+
+	m.logger.Info("Open reminder acknowledged", zap.Int("reminders_sent", m.active.sent))
+	m.endLocked("Acknowledged")
+	return nil
+}
+
+// handleEveryoneAsleepChange checks doors and windows when everyone falls asleep.
+// HA echoes our own writes with old == new, so compare against the last seen
+// value rather than oldValue.
+func (m *Manager) handleEveryoneAsleepChange(key string, oldValue, newValue interface{}) {
+	asleep, ok := newValue.(bool)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	changed := asleep != m.asleep
+	m.asleep = asleep
+	if changed && !asleep && m.active != nil && m.active.trigger == TriggerEveryoneAsleep {
+		m.endLocked("Someone woke up")
+	}
+	m.mu.Unlock()
+
+	if changed && asleep {
+		m.startReminder(TriggerEveryoneAsleep)
+	}
+}
+
+// handleAnyoneHomeChange checks doors and windows when the last person leaves
+func (m *Manager) handleAnyoneHomeChange(key string, oldValue, newValue interface{}) {
+	anyHome, ok := newValue.(bool)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	changed := anyHome != m.anyHome
+	m.anyHome = anyHome
+	if changed && anyHome && m.active != nil && m.active.trigger == TriggerEveryoneLeft {
+		m.endLocked("Someone arrived home")
+	}
+	m.mu.Unlock()
+
+	if changed && !anyHome {
+		m.startReminder(TriggerEveryoneLeft)
+	}
+}
+
+// handleSensorChange ends the active reminder once everything is closed
+func (m *Manager) handleSensorChange(entityID string, oldState, newState *ha.State) {
+	m.health.Tick()
+	if newState == nil || isOpen(newState.State) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active == nil {
+		return
+	}
+	if len(m.openSensors()) == 0 {
+		m.logger.Info("All doors and windows closed", zap.String("last_closed", entityID))
+		m.endLocked("All doors and windows closed")
+	}
+}
+
+// handleAcknowledgeEntityChange treats any change of the acknowledge entity
+// (e.g. an input_button press) as an acknowledgement
+func (m *Manager) handleAcknowledgeEntityChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil || (oldState != nil && oldState.State == newState.State) {
+		return
+	}
+	if err := m.Acknowledge(); err != nil {
+		m.logger.Debug("Acknowledge pressed with no active reminder", zap.String("entity_id", entityID))
+	}
+}
+
+// startReminder begins a reminder sequence if any doors or windows are open
+func (m *Manager) startReminder(trigger string) {
+	m.mu.Lock()
+	if m.active != nil {
+		m.mu.Unlock()
+		return
+	}
+
+	open := m.openSensors()
+	if len(open) == 0 {
+		m.mu.Unlock()
+		m.logger.Info("All doors and windows closed", zap.String("trigger", trigger))
+		m.shadowTracker.RecordEnded(trigger, "All doors and windows closed")
+		return
+	}
+
+	r := &reminder{trigger: trigger, started: m.clock.Now()}
+	m.active = r
+	m.mu.Unlock()
+
+	m.sendReminder(r)
+}
+
+// sendReminder delivers one reminder and schedules the next
+func (m *Manager) sendReminder(r *reminder) {
+	m.mu.Lock()
+	if m.active != r {
+		m.mu.Unlock()
+		return
+	}
+
+	open := m.openSensors()
+	if len(open) == 0 {
+		m.endLocked("All doors and windows closed")
+		m.mu.Unlock()
+		return
+	}
+
+	r.sent++
+	sent := r.sent
+	message := formatMessage(open)
+	now := m.clock.Now()
+
+	info := make([]shadowstate.OpenSensorInfo, len(open))
+	for i, sensor := range open {
+		info[i] = shadowstate.OpenSensorInfo{EntityID: sensor.EntityID, Name: sensor.Name, Room: sensor.Room}
+	}
+	m.shadowTracker.RecordReminder(r.trigger, info, sent, message, now)
+
+	limit := m.config.OpenReminder.MaxReminders
+	if limit == 0 || sent < limit {
+		r.timer = m.clock.AfterFunc(m.config.RepeatInterval(), func() {
+			m.sendReminder(r)
+		})
+	} else {
+		m.endLocked("Reminder limit reached")
+	}
+	announce := r.trigger == TriggerEveryoneAsleep && m.config.OpenReminder.AnnounceWhenAsleep
+	m.mu.Unlock()
+
+	m.logger.Info("Reminding about open doors and windows",
+		zap.String("trigger", r.trigger),
+		zap.Int("open", len(open)),
+		zap.Int("reminder", sent),
+		zap.String("message", message))
+
+	m.notify(message)
+	if announce {
+		m.announce(message)
+	}
+}
+
+// endLocked stops the active reminder sequence and records why.
+// Caller must hold m.mu.
+func (m *Manager) endLocked(reason string) {
+	r := m.active
+	if r == nil {
+		return
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	m.active = nil
+	m.shadowTracker.RecordEnded(r.trigger, reason)
+}
+
+// openSensors returns the configured sensors that are currently open.
+// Sensors whose state can't be read are treated as closed.
+func (m *Manager) openSensors() []SensorConfig {
+	var open []SensorConfig
+	for _, sensor := range m.config.OpenReminder.Sensors {
+		st, err := m.haClient.GetState(m.ctx, sensor.EntityID)
+		if err != nil {
+			m.logger.Warn("Failed to read sensor state",
+				zap.String("entity_id", sensor.EntityID),
+				zap.Error(err))
+			continue
+		}
+		if st != nil && isOpen(st.State) {
+			open = append(open, sensor)
+		}
+	}
+	return open
+}
+
+// notify sends a push notification through the configured notify service
+func (m *Manager) notify(message string) {
+	target := m.config.OpenReminder.NotifyService
+	if target == "" {
+		return
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send open reminder notification",
+			zap.String("service", string(target)),
+			zap.String("message", message))
+		return
+	}
+
+	if err := notifyrouter.SendPush(m.ctx, m.haClient, target, notifyrouter.Push{
+		Title:   NotificationTitle,
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send open reminder notification", zap.Error(err))
+	}
+}
+
+// announce speaks the reminder on the configured speakers
+func (m *Manager) announce(message string) {
+	s := m.config.OpenReminder
+	speakers, suppressed := m.dnd.Filter(s.AnnounceSpeakers)
+	if len(suppressed) > 0 {
+		m.logger.Info("Skipping open reminder announcement on do-not-disturb speakers", zap.Strings("suppressed", suppressed))
+	}
+	speakers, suppressed = m.focus.Filter(speakers)
+	if len(suppressed) > 0 {
+		m.logger.Info("Skipping open reminder announcement on focus mode speakers", zap.Strings("suppressed", suppressed))
+	}
+	if len(speakers) == 0 {
+		return
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce open reminder",
+			zap.Strings("speakers", speakers),
+			zap.String("message", message))
+		return
+	}
+
+	if _, err := m.notifications.Announce(m.ctx, m.haClient, notifyrouter.Announcement{
+		Title:     NotificationTitle,
+		Message:   message,
+		Speakers:  speakers,
+		TTSEntity: s.TTSEntity,
+		PushSent:  s.NotifyService != "",
+	}); err != nil {
+		m.logger.Error("Failed to announce open reminder", zap.Error(err))
+	}
+}
+
+// isOpen reports whether a contact sensor or cover state means open
+func isOpen(state string) bool {
+	switch state {
+	case "on", "open", "opening":
+		return true
+	default:
+		return false
+	}
+}
+
+// formatMessage builds a reminder such as
+// "Garage door (Garage) and Back door (Mudroom) are still open"
+func formatMessage(open []SensorConfig) string {
+	names := make([]string, len(open))
+	for i, sensor := range open {
+		names[i] = sensor.Name
+		if sensor.Room != "" {
+			names[i] = fmt.Sprintf("%s (%s)", sensor.Name, sensor.Room)
+		}
+	}
+
+	if len(names) == 1 {
+		return names[0] + " is still open"
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1] + " are still open"
+}
@@ -0,0 +1,312 @@
+package openreminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testGarage   = "cover.garage_door_door"
+	testBackDoor = "binary_sensor.test_back_door"
+	testAck      = "input_button.test_acknowledge"
+)
+
+func createTestConfig() *OpenReminderConfig {
+	return &OpenReminderConfig{
+		OpenReminder: ReminderSettings{
+			Sensors: []SensorConfig{
+				{EntityID: testGarage, Name: "Garage door", Room: "Garage"},
+				{EntityID: testBackDoor, Name: "Back door", Room: "Mudroom"},
+			},
+			RepeatIntervalMinutes: 15,
+			MaxReminders:          3,
+			NotifyService:         "notify.notify",
+			AcknowledgeEntity:     testAck,
+			AnnounceWhenAsleep:    true,
+			TTSEntity:             "tts.google_translate_en_com",
+			AnnounceSpeakers:      []string{"media_player.bedroom"},
+		},
+	}
+}
+
+func setupTest(t *testing.T, config *OpenReminderConfig, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState(testGarage, "closed", nil)
+	mockHA.SetState(testBackDoor, "off", nil)
+	mockHA.SetState(testAck, "unknown", nil)
+
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+
+	manager := NewManager(mockHA, stateManager, config, logger, readOnly, nil)
+	mockClock := clock.NewMockClock(time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+// reminderCalls returns the notify and tts service calls
+func reminderCalls(mockHA *ha.MockClient) (notifications, announcements []ha.ServiceCall) {
+	for _, call := range mockHA.GetServiceCalls() {
+		switch call.Domain {
+		case "notify":
+			notifications = append(notifications, call)
+		case "tts":
+			announcements = append(announcements, call)
+		}
+	}
+	return notifications, announcements
+}
+
+func TestRemindsWhenEveryoneFallsAsleep(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	notifications, announcements := reminderCalls(mockHA)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "notify", notifications[0].Service)
+	assert.Equal(t, NotificationTitle, notifications[0].Data["title"])
+	assert.Equal(t, "Garage door (Garage) is still open", notifications[0].Data["message"])
+
+	require.Len(t, announcements, 1)
+	assert.Equal(t, "speak", announcements[0].Service)
+	assert.Equal(t, []string{"media_player.bedroom"}, announcements[0].Data["media_player_entity_id"])
+
+	state := manager.GetShadowState()
+	assert.True(t, state.Outputs.Active)
+	assert.Equal(t, TriggerEveryoneAsleep, state.Outputs.Trigger)
+	assert.Equal(t, 1, state.Outputs.RemindersSent)
+	require.Len(t, state.Outputs.OpenSensors, 1)
+	assert.Equal(t, testGarage, state.Outputs.OpenSensors[0].EntityID)
+}
+
+func TestRemindsWhenLastPersonLeaves_NoAnnouncement(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+	mockHA.SetState(testBackDoor, "on", nil)
+
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+
+	notifications, announcements := reminderCalls(mockHA)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "Garage door (Garage) and Back door (Mudroom) are still open", notifications[0].Data["message"])
+	assert.Empty(t, announcements, "Nobody is home to hear an announcement")
+}
+
+func TestNoReminderWhenEverythingClosed(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(), false)
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	notifications, announcements := reminderCalls(mockHA)
+	assert.Empty(t, notifications)
+	assert.Empty(t, announcements)
+	assert.False(t, manager.GetShadowState().Outputs.Active)
+}
+
+func TestRepeatsUntilMaxReminders(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+
+	mockClock.Advance(14 * time.Minute)
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1, "Should not repeat before the interval")
+
+	mockClock.Advance(1 * time.Minute)
+	notifications, _ = reminderCalls(mockHA)
+	assert.Len(t, notifications, 2)
+
+	mockClock.Advance(15 * time.Minute)
+	mockClock.Advance(15 * time.Minute)
+	notifications, _ = reminderCalls(mockHA)
+	assert.Len(t, notifications, 3, "Should stop after max_reminders")
+
+	state := manager.GetShadowState()
+	assert.False(t, state.Outputs.Active)
+	assert.Equal(t, 3, state.Outputs.RemindersSent)
+	assert.Equal(t, "Reminder limit reached", state.Outputs.EndedReason)
+}
+
+func TestStopsWhenClosed(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+	mockHA.SetState(testGarage, "closed", nil)
+
+	mockClock.Advance(time.Hour)
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1)
+
+	state := manager.GetShadowState()
+	assert.False(t, state.Outputs.Active)
+	assert.Equal(t, "All doors and windows closed", state.Outputs.EndedReason)
+}
+
+func TestKeepsRemindingWhileAnotherSensorIsOpen(t *testing.T) {
+	_, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+	mockHA.SetState(testBackDoor, "on", nil)
+
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+	mockHA.SetState(testGarage, "closed", nil)
+
+	mockClock.Advance(15 * time.Minute)
+	notifications, _ := reminderCalls(mockHA)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "Back door (Mudroom) is still open", notifications[1].Data["message"])
+}
+
+func TestAcknowledge(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+
+	assert.ErrorIs(t, manager.Acknowledge(), ErrNoActiveReminder)
+
+	mockHA.SetState(testGarage, "open", nil)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", false))
+
+	require.NoError(t, manager.Acknowledge())
+	mockClock.Advance(time.Hour)
+
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, "Acknowledged", manager.GetShadowState().Outputs.EndedReason)
+}
+
+func TestAcknowledgeEntityPress(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	mockHA.SetState(testAck, "2025-01-15T22:01:00+00:00", nil)
+	mockClock.Advance(time.Hour)
+
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1)
+	assert.False(t, manager.GetShadowState().Outputs.Active)
+}
+
+func TestWakingUpEndsSleepReminder(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", false))
+	mockClock.Advance(time.Hour)
+
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, "Someone woke up", manager.GetShadowState().Outputs.EndedReason)
+}
+
+func TestEchoDoesNotRestartAcknowledgedReminder(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+	require.NoError(t, manager.Acknowledge())
+
+	// HA echoes the input_boolean back with old == new
+	mockHA.SimulateStateChange("input_boolean.everyone_asleep", "on")
+
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1)
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(), true)
+	mockHA.SetState(testGarage, "open", nil)
+
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+	notifications, announcements := reminderCalls(mockHA)
+	assert.Empty(t, notifications)
+	assert.Empty(t, announcements)
+	assert.True(t, manager.GetShadowState().Outputs.Active, "Shadow state should still be recorded")
+}
+
+func TestNotificationRouterQuietsAnnouncements(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(), false)
+	manager.SetNotificationRouter(notifyrouter.NewRouter(&notifyrouter.Config{NotificationRouter: notifyrouter.Settings{
+		Chime:       notifyrouter.Chime{MediaContentID: "media-source://media_source/local/chime.mp3"},
+		PushService: "notify.notify",
+		Schedule: []notifyrouter.ScheduleStep{
+			{DayPhase: "winddown", Level: notifyrouter.LevelChime},
+			{DayPhase: "night", Level: notifyrouter.LevelPush},
+		},
+	}}, stateManager))
+	mockHA.SetState(testGarage, "open", nil)
+
+	t.Run("winddown chimes instead of speaking", func(t *testing.T) {
+		require.NoError(t, stateManager.SetString("dayPhase", "winddown"))
+		mockHA.ClearServiceCalls()
+		require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+		notifications, announcements := reminderCalls(mockHA)
+		assert.Len(t, notifications, 1)
+		assert.Empty(t, announcements)
+
+		var chimes int
+		for _, call := range mockHA.GetServiceCalls() {
+			if call.Domain == "media_player" && call.Service == "play_media" {
+				chimes++
+			}
+		}
+		assert.Equal(t, 1, chimes)
+		require.NoError(t, stateManager.SetBool("isEveryoneAsleep", false))
+	})
+
+	t.Run("night sends only the push notification", func(t *testing.T) {
+		require.NoError(t, stateManager.SetString("dayPhase", "night"))
+		mockHA.ClearServiceCalls()
+		require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+
+		notifications, announcements := reminderCalls(mockHA)
+		assert.Len(t, notifications, 1, "the reminder's own notification is not duplicated")
+		assert.Empty(t, announcements)
+	})
+}
+
+func TestReset(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, createTestConfig(), false)
+	mockHA.SetState(testGarage, "open", nil)
+	require.NoError(t, stateManager.SetBool("isEveryoneAsleep", true))
+	mockHA.ClearServiceCalls()
+
+	require.NoError(t, manager.Reset())
+
+	notifications, _ := reminderCalls(mockHA)
+	assert.Len(t, notifications, 1, "Reset should re-check and remind again")
+	state := manager.GetShadowState()
+	assert.True(t, state.Outputs.Active)
+	assert.Equal(t, 1, state.Outputs.RemindersSent)
+}
+
+func TestFormatMessage(t *testing.T) {
+	assert.Equal(t, "Back door is still open", formatMessage([]SensorConfig{{Name: "Back door"}}))
+	assert.Equal(t, "A (X), B and C (Z) are still open", formatMessage([]SensorConfig{
+		{Name: "A", Room: "X"},
+		{Name: "B"},
+		{Name: "C", Room: "Z"},
+	}))
+}
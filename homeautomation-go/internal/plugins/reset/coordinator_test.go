@@ -1,6 +1,7 @@
 package reset
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -27,7 +28,7 @@ func createTestManager(t *testing.T) *state.Manager {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.reset", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := state.NewManager(mockClient, logger, false)
 	if err := manager.SyncFromHA(); err != nil {
@@ -153,7 +154,7 @@ func TestCoordinator_ReadOnlyMode(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.reset", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	// Create manager in read-only mode
 	stateManager := state.NewManager(mockClient, logger, true)
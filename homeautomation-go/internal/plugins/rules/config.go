@@ -0,0 +1,456 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Trigger starts a rule when a state variable or Home Assistant entity
+// changes, or on a cron schedule. Exactly one of State, Entity and Cron is set.
+type Trigger struct {
+	State  string      `yaml:"state"`  // State variable whose change triggers the rule
+	Entity string      `yaml:"entity"` // Home Assistant entity whose state change triggers the rule
+	From   interface{} `yaml:"from"`   // Optional: only changes from this value
+	To     interface{} `yaml:"to"`     // Optional: only changes to this value
+	Cron   string      `yaml:"cron"`   // Five-field cron expression in the configured time zone
+
+	schedule *scheduler.CronSchedule
+}
+
+// Condition must hold for a triggered rule to run its actions. State is
+// compared with exactly one of Equals, NotEquals and In, or with Above
+// and/or Below for numbers.
+type Condition struct {
+	State     string        `yaml:"state"`
+	Equals    interface{}   `yaml:"equals"`
+	NotEquals interface{}   `yaml:"not_equals"`
+	In        []interface{} `yaml:"in"`
+	Above     *float64      `yaml:"above"`
+	Below     *float64      `yaml:"below"`
+}
+
+// Action is a Home Assistant service call, a state variable write, a spoken
+// announcement or a pause before the actions after it. Exactly one of
+// Service, Set, Announce and Delay is set.
+type Action struct {
+	Service  string                 `yaml:"service"`  // "domain.service", e.g. "light.turn_on"
+	Data     map[string]interface{} `yaml:"data"`     // Service data, e.g. entity_id
+	Set      string                 `yaml:"set"`      // State variable to write
+	Value    interface{}            `yaml:"value"`    // Value written to Set
+	Announce string                 `yaml:"announce"` // Message to speak
+	Target   string                 `yaml:"target"`   // Announcement target in tts_config.yaml; defaults to owners
+	Priority tts.Priority           `yaml:"priority"` // Announcement priority; defaults to normal
+	Delay    string                 `yaml:"delay"`    // Wait before the next action, e.g. "2m"
+
+	delay time.Duration
+}
+
+// Rule runs its actions in order when any trigger fires and all conditions hold
+type Rule struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Triggers    []Trigger   `yaml:"triggers"`
+	Conditions  []Condition `yaml:"conditions"`
+	Actions     []Action    `yaml:"actions"`
+}
+
+// RulesConfig represents the rules_config.yaml structure
+type RulesConfig struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Schedule returns the parsed cron schedule of a cron trigger
+func (t *Trigger) Schedule() *scheduler.CronSchedule {
+	return t.schedule
+}
+
+// Matches reports whether a state change fires the trigger. A change to the
+// same value never does.
+func (t *Trigger) Matches(oldValue, newValue interface{}) bool {
+	if valuesEqual(oldValue, newValue) {
+		return false
+	}
+	if t.From != nil && !valuesEqual(t.From, oldValue) {
+		return false
+	}
+	if t.To != nil && !valuesEqual(t.To, newValue) {
+		return false
+	}
+	return true
+}
+
+// Holds reports whether the condition holds for a state variable's value
+func (c *Condition) Holds(value interface{}) bool {
+	switch {
+	case c.Equals != nil:
+		return valuesEqual(c.Equals, value)
+	case c.NotEquals != nil:
+		return !valuesEqual(c.NotEquals, value)
+	case c.In != nil:
+		for _, candidate := range c.In {
+			if valuesEqual(candidate, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return false
+	}
+	if c.Above != nil && number <= *c.Above {
+		return false
+	}
+	if c.Below != nil && number >= *c.Below {
+		return false
+	}
+	return true
+}
+
+// String describes the condition, e.g. "dayPhase in [evening night]"
+func (c *Condition) String() string {
+	switch {
+	case c.Equals != nil:
+		return fmt.Sprintf("%s == %v", c.State, c.Equals)
+	case c.NotEquals != nil:
+		return fmt.Sprintf("%s != %v", c.State, c.NotEquals)
+	case c.In != nil:
+		return fmt.Sprintf("%s in %v", c.State, c.In)
+	case c.Above != nil && c.Below != nil:
+		return fmt.Sprintf("%v < %s < %v", *c.Above, c.State, *c.Below)
+	case c.Above != nil:
+		return fmt.Sprintf("%s > %v", c.State, *c.Above)
+	default:
+		return fmt.Sprintf("%s < %v", c.State, *c.Below)
+	}
+}
+
+// DelayDuration returns the parsed delay of a delay action
+func (a *Action) DelayDuration() time.Duration {
+	return a.delay
+}
+
+// AnnounceTarget returns the announcement target, defaulting to owners
+func (a *Action) AnnounceTarget() string {
+	if a.Target == "" {
+		return tts.TargetOwners
+	}
+	return a.Target
+}
+
+// DomainService splits the service into HA domain and service
+// e.g., "light.turn_on" -> "light", "turn_on"
+func (a *Action) DomainService() (string, string, bool) {
+	domain, service, ok := strings.Cut(a.Service, ".")
+	if !ok || domain == "" || service == "" {
+		return "", "", false
+	}
+	return domain, service, true
+}
+
+// String describes the action, e.g. "light.turn_on light.porch",
+// "set isExpectingSomeone = false", "announce \"Dinner\"" or "wait 2m"
+func (a *Action) String() string {
+	if a.Set != "" {
+		return fmt.Sprintf("set %s = %v", a.Set, a.Value)
+	}
+	if a.Announce != "" {
+		return fmt.Sprintf("announce %q", a.Announce)
+	}
+	if a.Delay != "" {
+		return "wait " + a.Delay
+	}
+	if entityID, ok := a.Data["entity_id"]; ok {
+		return fmt.Sprintf("%s %v", a.Service, entityID)
+	}
+	return a.Service
+}
+
+// Validate checks every rule, parses cron triggers and converts configured
+// values to the type of the state variable they are compared with or written to
+func (c *RulesConfig) Validate() error {
+	variables := state.VariablesByKey()
+	names := make(map[string]bool)
+
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rules[%d]: name is required", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rules[%d]: duplicate rule %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+
+		if len(rule.Triggers) == 0 {
+			return fmt.Errorf("rule %q: at least one trigger is required", rule.Name)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("rule %q: at least one action is required", rule.Name)
+		}
+
+		for j := range rule.Triggers {
+			if err := rule.Triggers[j].validate(variables); err != nil {
+				return fmt.Errorf("rule %q: triggers[%d]: %w", rule.Name, j, err)
+			}
+		}
+		for j := range rule.Conditions {
+			if err := rule.Conditions[j].validate(variables); err != nil {
+				return fmt.Errorf("rule %q: conditions[%d]: %w", rule.Name, j, err)
+			}
+		}
+		for j := range rule.Actions {
+			if err := rule.Actions[j].validate(variables); err != nil {
+				return fmt.Errorf("rule %q: actions[%d]: %w", rule.Name, j, err)
+			}
+		}
+		if rule.Actions[len(rule.Actions)-1].Delay != "" {
+			return fmt.Errorf("rule %q: a delay must be followed by an action", rule.Name)
+		}
+	}
+	return nil
+}
+
+func (t *Trigger) validate(variables map[string]state.StateVariable) error {
+	if countSet(t.State != "", t.Entity != "", t.Cron != "") != 1 {
+		return fmt.Errorf("exactly one of state, entity and cron is required")
+	}
+
+	if t.Cron != "" {
+		if t.From != nil || t.To != nil {
+			return fmt.Errorf("from and to only apply to state triggers")
+		}
+		schedule, err := scheduler.ParseCron(t.Cron)
+		if err != nil {
+			return err
+		}
+		reference := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		if schedule.Next(reference).IsZero() {
+			return fmt.Errorf("cron %q never fires", t.Cron)
+		}
+		t.schedule = schedule
+		return nil
+	}
+
+	if t.Entity != "" {
+		if !strings.Contains(t.Entity, ".") {
+			return fmt.Errorf("invalid entity %q (expected domain.object_id)", t.Entity)
+		}
+		// Entity states are strings, but YAML reads e.g. "on" as a boolean
+		if t.From != nil {
+			t.From = fmt.Sprint(t.From)
+		}
+		if t.To != nil {
+			t.To = fmt.Sprint(t.To)
+		}
+		return nil
+	}
+
+	variable, ok := variables[t.State]
+	if !ok {
+		return fmt.Errorf("unknown state variable %q", t.State)
+	}
+	var err error
+	if t.From, err = convertValue(variable, t.From); err != nil {
+		return fmt.Errorf("from: %w", err)
+	}
+	if t.To, err = convertValue(variable, t.To); err != nil {
+		return fmt.Errorf("to: %w", err)
+	}
+	return nil
+}
+
+func (c *Condition) validate(variables map[string]state.StateVariable) error {
+	variable, ok := variables[c.State]
+	if !ok {
+		return fmt.Errorf("unknown state variable %q", c.State)
+	}
+
+	comparisons := 0
+	for _, set := range []bool{c.Equals != nil, c.NotEquals != nil, c.In != nil, c.Above != nil || c.Below != nil} {
+		if set {
+			comparisons++
+		}
+	}
+	if comparisons != 1 {
+		return fmt.Errorf("%s: exactly one of equals, not_equals, in, or above/below is required", c.State)
+	}
+
+	if (c.Above != nil || c.Below != nil) && variable.Type != state.TypeNumber {
+		return fmt.Errorf("%s: above and below require a number variable", c.State)
+	}
+	if c.Above != nil && c.Below != nil && *c.Above >= *c.Below {
+		return fmt.Errorf("%s: above must be less than below", c.State)
+	}
+
+	var err error
+	if c.Equals, err = convertValue(variable, c.Equals); err != nil {
+		return fmt.Errorf("equals: %w", err)
+	}
+	if c.NotEquals, err = convertValue(variable, c.NotEquals); err != nil {
+		return fmt.Errorf("not_equals: %w", err)
+	}
+	for i := range c.In {
+		if c.In[i], err = convertValue(variable, c.In[i]); err != nil {
+			return fmt.Errorf("in[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (a *Action) validate(variables map[string]state.StateVariable) error {
+	if countSet(a.Service != "", a.Set != "", a.Announce != "", a.Delay != "") != 1 {
+		return fmt.Errorf("exactly one of service, set, announce and delay is required")
+	}
+	if a.Announce == "" && (a.Target != "" || a.Priority != "") {
+		return fmt.Errorf("target and priority only apply to announce actions")
+	}
+
+	if a.Announce != "" || a.Delay != "" {
+		if a.Data != nil || a.Value != nil {
+			return fmt.Errorf("data and value only apply to service and set actions")
+		}
+	}
+
+	if a.Announce != "" {
+		if _, ok := tts.DefaultSpeakers[a.AnnounceTarget()]; !ok {
+			return fmt.Errorf("announce: unknown target %q", a.Target)
+		}
+		if a.Priority != "" && !a.Priority.Valid() {
+			return fmt.Errorf("announce: unknown priority %q, expected low, normal or urgent", a.Priority)
+		}
+		return nil
+	}
+
+	if a.Delay != "" {
+		delay, err := time.ParseDuration(a.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay %q, expected a duration like 2m", a.Delay)
+		}
+		if delay <= 0 || delay > maxDelay {
+			return fmt.Errorf("delay must be between 0 and %s, got %s", maxDelay, a.Delay)
+		}
+		a.delay = delay
+		return nil
+	}
+
+	if a.Service != "" {
+		if _, _, ok := a.DomainService(); !ok {
+			return fmt.Errorf("invalid service %q (expected domain.service)", a.Service)
+		}
+		if a.Value != nil {
+			return fmt.Errorf("value only applies to set actions")
+		}
+		return nil
+	}
+
+	if a.Data != nil {
+		return fmt.Errorf("data only applies to service actions")
+	}
+	variable, ok := variables[a.Set]
+	if !ok {
+		return fmt.Errorf("unknown state variable %q", a.Set)
+	}
+	if a.Value == nil {
+		return fmt.Errorf("set %s: value is required", a.Set)
+	}
+	var err error
+	if a.Value, err = convertValue(variable, a.Value); err != nil {
+		return fmt.Errorf("value: %w", err)
+	}
+	return nil
+}
+
+// maxDelay is the longest a rule may wait between actions. Waiting actions
+// are dropped when the rules engine stops, so long waits are better as a
+// separate cron rule.
+const maxDelay = time.Hour
+
+// countSet returns how many of the given fields are set
+func countSet(set ...bool) int {
+	n := 0
+	for _, s := range set {
+		if s {
+			n++
+		}
+	}
+	return n
+}
+
+// convertValue converts a YAML value to the Go type the state manager uses
+// for the variable. A nil value stays nil.
+func convertValue(variable state.StateVariable, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch variable.Type {
+	case state.TypeBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s is a boolean, got %v", variable.Key, value)
+
+	case state.TypeNumber:
+		switch n := value.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+		return nil, fmt.Errorf("%s is a number, got %v", variable.Key, value)
+
+	case state.TypeString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("%s is a string, got %v", variable.Key, value)
+
+	default:
+		// Round-trip through JSON so values compare like ones read from state
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", variable.Key, err)
+		}
+		var converted interface{}
+		if err := json.Unmarshal(data, &converted); err != nil {
+			return nil, fmt.Errorf("%s: %w", variable.Key, err)
+		}
+		return converted, nil
+	}
+}
+
+// valuesEqual compares state values, which may be JSON maps and slices
+func valuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// LoadConfig loads the rules configuration from a YAML file
+func LoadConfig(path string) (*RulesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config RulesConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,244 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/rules_config.yaml")
+	require.NoError(t, err)
+
+	require.NotEmpty(t, config.Rules)
+	for _, rule := range config.Rules {
+		assert.NotEmpty(t, rule.Triggers, rule.Name)
+		assert.NotEmpty(t, rule.Actions, rule.Name)
+	}
+}
+
+func TestLoadConfig_ConvertsValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - name: alarm
+    triggers:
+      - state: alarmTime
+        to: 7
+      - cron: "*/15 6-8 * * 1-5"
+    conditions:
+      - state: thisHourSolarGeneration
+        above: 1.5
+      - state: dayPhase
+        in: [morning, day]
+    actions:
+      - set: remainingSolarGeneration
+        value: 3
+      - service: light.turn_on
+        data:
+          entity_id: light.kitchen
+`), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	rule := config.Rules[0]
+	assert.Equal(t, 7.0, rule.Triggers[0].To, "numbers are compared as float64")
+	assert.Nil(t, rule.Triggers[0].From)
+	assert.Nil(t, rule.Triggers[0].Schedule())
+	assert.Equal(t, "*/15 6-8 * * 1-5", rule.Triggers[1].Schedule().String())
+	assert.Equal(t, 3.0, rule.Actions[0].Value)
+	assert.Equal(t, "light.turn_on light.kitchen", rule.Actions[1].String())
+}
+
+func TestLoadConfig_EntityTriggersDelaysAndAnnouncements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - name: doorbell_light
+    triggers:
+      - entity: binary_sensor.doorbell
+        to: on
+    actions:
+      - announce: Someone is at the door
+        priority: urgent
+      - service: light.turn_on
+        data:
+          entity_id: light.front_porch
+      - delay: 90s
+      - service: light.turn_off
+        data:
+          entity_id: light.front_porch
+`), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	rule := config.Rules[0]
+	assert.Equal(t, "on", rule.Triggers[0].To, "entity states are strings even when YAML reads a boolean")
+	assert.Equal(t, `announce "Someone is at the door"`, rule.Actions[0].String())
+	assert.Equal(t, "owners", rule.Actions[0].AnnounceTarget())
+	assert.Equal(t, 90*time.Second, rule.Actions[2].DelayDuration())
+	assert.Equal(t, "wait 90s", rule.Actions[2].String())
+}
+
+func TestValidate(t *testing.T) {
+	above, below := 10.0, 5.0
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{
+			name: "valid",
+			rule: Rule{Name: "r", Triggers: []Trigger{{State: "isAnyoneHome", To: true}}, Actions: []Action{{Service: "light.turn_on"}}},
+		},
+		{
+			name:    "no name",
+			rule:    Rule{Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "name is required",
+		},
+		{
+			name:    "no triggers",
+			rule:    Rule{Name: "r", Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "at least one trigger",
+		},
+		{
+			name:    "no actions",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}},
+			wantErr: "at least one action",
+		},
+		{
+			name:    "state and cron",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{State: "isAnyoneHome", Cron: "0 * * * *"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "exactly one of state, entity and cron",
+		},
+		{
+			name:    "unknown trigger variable",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{State: "isNobodyHome"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: `unknown state variable "isNobodyHome"`,
+		},
+		{
+			name:    "wrong trigger value type",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{State: "isAnyoneHome", To: "yes"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "isAnyoneHome is a boolean",
+		},
+		{
+			name:    "invalid cron",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "61 * * * *"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "minute",
+		},
+		{
+			name:    "cron never fires",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 0 30 2 *"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "never fires",
+		},
+		{
+			name: "condition without comparison",
+			rule: Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}},
+				Conditions: []Condition{{State: "isAnyoneHome"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "exactly one of equals",
+		},
+		{
+			name: "above on a string",
+			rule: Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}},
+				Conditions: []Condition{{State: "dayPhase", Above: &above}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "require a number variable",
+		},
+		{
+			name: "empty range",
+			rule: Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}},
+				Conditions: []Condition{{State: "alarmTime", Above: &above, Below: &below}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "above must be less than below",
+		},
+		{
+			name:    "invalid service",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "turn_on"}}},
+			wantErr: "expected domain.service",
+		},
+		{
+			name:    "set without value",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Set: "isExpectingSomeone"}}},
+			wantErr: "value is required",
+		},
+		{
+			name:    "service and set",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "light.turn_on", Set: "isExpectingSomeone"}}},
+			wantErr: "exactly one of service, set, announce and delay",
+		},
+		{
+			name:    "invalid entity",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Entity: "guest_phone"}}, Actions: []Action{{Service: "light.turn_on"}}},
+			wantErr: "expected domain.object_id",
+		},
+		{
+			name:    "unknown announce target",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Announce: "Hello", Target: "attic"}}},
+			wantErr: `unknown target "attic"`,
+		},
+		{
+			name:    "unknown announce priority",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Announce: "Hello", Priority: "loud"}}},
+			wantErr: "unknown priority",
+		},
+		{
+			name:    "target on a service",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "light.turn_on", Target: "owners"}}},
+			wantErr: "only apply to announce actions",
+		},
+		{
+			name: "invalid delay",
+			rule: Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}},
+				Actions: []Action{{Delay: "two minutes"}, {Service: "lock.lock"}}},
+			wantErr: "invalid delay",
+		},
+		{
+			name: "delay too long",
+			rule: Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}},
+				Actions: []Action{{Delay: "3h"}, {Service: "lock.lock"}}},
+			wantErr: "delay must be between",
+		},
+		{
+			name:    "trailing delay",
+			rule:    Rule{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "lock.unlock"}, {Delay: "2m"}}},
+			wantErr: "must be followed by an action",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &RulesConfig{Rules: []Rule{tt.rule}}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	duplicate := &RulesConfig{Rules: []Rule{
+		{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "light.turn_on"}}},
+		{Name: "r", Triggers: []Trigger{{Cron: "0 * * * *"}}, Actions: []Action{{Service: "light.turn_off"}}},
+	}}
+	assert.ErrorContains(t, duplicate.Validate(), "duplicate rule")
+}
+
+func TestConditionHolds(t *testing.T) {
+	above, below := 2.0, 5.0
+
+	assert.True(t, (&Condition{Equals: true}).Holds(true))
+	assert.False(t, (&Condition{Equals: true}).Holds(false))
+	assert.True(t, (&Condition{NotEquals: "night"}).Holds("day"))
+	assert.True(t, (&Condition{In: []interface{}{"dusk", "night"}}).Holds("night"))
+	assert.False(t, (&Condition{In: []interface{}{"dusk", "night"}}).Holds("day"))
+	assert.True(t, (&Condition{Above: &above, Below: &below}).Holds(3.0))
+	assert.False(t, (&Condition{Above: &above, Below: &below}).Holds(5.0))
+	assert.False(t, (&Condition{Above: &above}).Holds("3"))
+}
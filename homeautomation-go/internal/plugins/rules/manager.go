@@ -0,0 +1,574 @@
+// Package rules runs simple automations defined in rules_config.yaml: when a
+// state variable or Home Assistant entity changes or a cron schedule fires,
+// and every condition on state variables holds, call Home Assistant services,
+// set state variables or make announcements, optionally waiting in between.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// ruleTrigger is a state or entity trigger and the rule it starts
+type ruleTrigger struct {
+	rule    *Rule
+	trigger *Trigger
+}
+
+// Manager subscribes to rule triggers and runs rule actions
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *RulesConfig
+	variables    map[string]state.StateVariable
+	logger       *zap.Logger
+	readOnly     bool
+	timezone     *time.Location
+	clock        clock.Clock
+
+	subscriptions   []state.Subscription
+	haSubscriptions []ha.Subscription
+
+	// Speaks announce actions; nil speaks them immediately
+	announcer *tts.Announcer
+
+	// Runs cron triggers and the actions after a delay
+	scheduler *scheduler.Scheduler
+
+	// The last value seen of each trigger variable, rules currently running
+	// their actions, and the actions of rules waiting out a delay (protected
+	// by mu)
+	lastValues map[string]interface{}
+	running    map[string]bool
+	waiting    map[string][]Action
+	mu         sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.RulesTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new rules manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *RulesConfig, logger *zap.Logger, readOnly bool, timezone *time.Location) *Manager {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		variables:     state.VariablesByKey(),
+		logger:        recorder.Logger(logger.Named("rules")),
+		health:        recorder,
+		readOnly:      readOnly,
+		timezone:      timezone,
+		clock:         clock.NewRealClock(),
+		scheduler:     scheduler.New(logger, timezone),
+		lastValues:    make(map[string]interface{}),
+		running:       make(map[string]bool),
+		waiting:       make(map[string][]Action),
+		shadowTracker: shadowstate.NewRulesTracker(),
+	}
+
+	for _, rule := range config.Rules {
+		m.shadowTracker.AddRule(rule.Name, rule.Description)
+	}
+
+	return m
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetScheduler sets the scheduler cron triggers run on, shared with other plugins
+func (m *Manager) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+// SetAnnouncer sets the announcer that speaks announce actions
+func (m *Manager) SetAnnouncer(announcer *tts.Announcer) {
+	m.announcer = announcer
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.RulesShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start subscribes to state triggers and schedules cron triggers
+func (m *Manager) Start(ctx context.Context) error {
+	// Rule actions run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Rules Manager", zap.Int("rules", len(m.config.Rules)))
+
+	// Subscribe once per variable so every trigger on it sees the same change
+	triggers := make(map[string][]ruleTrigger)
+	var keys []string
+	for i := range m.config.Rules {
+		rule := &m.config.Rules[i]
+		for j := range rule.Triggers {
+			trigger := &rule.Triggers[j]
+			if trigger.State == "" {
+				continue
+			}
+			if _, ok := triggers[trigger.State]; !ok {
+				keys = append(keys, trigger.State)
+			}
+			triggers[trigger.State] = append(triggers[trigger.State], ruleTrigger{rule: rule, trigger: trigger})
+		}
+	}
+
+	for _, key := range keys {
+		value, err := m.readValue(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		m.mu.Lock()
+		m.lastValues[key] = value
+		m.mu.Unlock()
+
+		keyTriggers := triggers[key]
+		sub, err := m.stateManager.Subscribe(key, func(key string, _, newValue interface{}) {
+			oldValue, changed := m.observe(key, newValue)
+			if !changed {
+				return
+			}
+			for _, rt := range keyTriggers {
+				if rt.trigger.Matches(oldValue, newValue) {
+					m.Evaluate(rt.rule, fmt.Sprintf("%s: %v -> %v", key, oldValue, newValue))
+				}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", key, err)
+		}
+		m.subscriptions = append(m.subscriptions, sub)
+	}
+
+	// Entity triggers, also subscribed once per entity
+	entityTriggers := make(map[string][]ruleTrigger)
+	var entities []string
+	for i := range m.config.Rules {
+		rule := &m.config.Rules[i]
+		for j := range rule.Triggers {
+			trigger := &rule.Triggers[j]
+			if trigger.Entity == "" {
+				continue
+			}
+			if _, ok := entityTriggers[trigger.Entity]; !ok {
+				entities = append(entities, trigger.Entity)
+			}
+			entityTriggers[trigger.Entity] = append(entityTriggers[trigger.Entity], ruleTrigger{rule: rule, trigger: trigger})
+		}
+	}
+
+	for _, entityID := range entities {
+		triggers := entityTriggers[entityID]
+		sub, err := m.haClient.SubscribeStateChanges(entityID, func(entityID string, oldState, newState *ha.State) {
+			oldValue, newValue := entityState(oldState), entityState(newState)
+			for _, rt := range triggers {
+				if rt.trigger.Matches(oldValue, newValue) {
+					m.Evaluate(rt.rule, fmt.Sprintf("%s: %v -> %v", entityID, oldValue, newValue))
+				}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, sub)
+	}
+
+	m.scheduleAll()
+
+	m.health.Started(len(m.subscriptions) + len(m.haSubscriptions))
+	m.logger.Info("Rules Manager started successfully")
+	return nil
+}
+
+// Stop unsubscribes from state triggers and cancels cron triggers
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Rules Manager")
+
+	m.cancel()
+
+	for _, sub := range m.subscriptions {
+		sub.Unsubscribe()
+	}
+	m.subscriptions = nil
+
+	for _, sub := range m.haSubscriptions {
+		if err := sub.Unsubscribe(); err != nil {
+			m.logger.Warn("Failed to unsubscribe from entity trigger", zap.Error(err))
+		}
+	}
+	m.haSubscriptions = nil
+
+	m.cancelAll()
+	m.cancelWaiting()
+
+	m.logger.Info("Rules Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-arms the cron triggers from the current time. Rules only act on
+// triggers, so there is nothing to re-evaluate.
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Rules - rescheduling cron triggers")
+
+	m.scheduleAll()
+
+	m.logger.Info("Successfully reset Rules")
+	return nil
+}
+
+// Evaluate checks a triggered rule's conditions and, if they all hold, runs
+// its actions in order. An action that fails stops the rest, and a delay
+// action schedules the rest for later.
+func (m *Manager) Evaluate(rule *Rule, trigger string) {
+	m.health.Tick()
+	if !m.begin(rule, trigger) {
+		return
+	}
+	defer m.end(rule)
+
+	now := m.clock.Now()
+
+	for i := range rule.Conditions {
+		condition := &rule.Conditions[i]
+		value, err := m.readValue(condition.State)
+		if err != nil {
+			m.logger.Error("Failed to read rule condition",
+				zap.String("rule", rule.Name),
+				zap.String("variable", condition.State),
+				zap.Error(err))
+			m.shadowTracker.RecordSkipped(rule.Name, trigger, fmt.Sprintf("failed to read %s: %v", condition.State, err), now)
+			return
+		}
+		m.shadowTracker.UpdateCurrentInput(condition.State, value)
+
+		if !condition.Holds(value) {
+			m.logger.Debug("Rule condition not met",
+				zap.String("rule", rule.Name),
+				zap.String("trigger", trigger),
+				zap.String("condition", condition.String()))
+			m.shadowTracker.RecordSkipped(rule.Name, trigger, "condition not met: "+condition.String(), now)
+			return
+		}
+	}
+
+	m.logger.Info("Running rule",
+		zap.String("rule", rule.Name),
+		zap.String("trigger", trigger))
+
+	actions, result := m.runActions(rule, trigger, rule.Actions, []string{})
+	m.shadowTracker.RecordRun(rule.Name, trigger, actions, result, now)
+}
+
+// begin marks a rule running, reporting false if it already is. A rule whose
+// actions change its own trigger would otherwise loop.
+func (m *Manager) begin(rule *Rule, trigger string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running[rule.Name] {
+		m.logger.Warn("Rule triggered while running its actions, ignoring",
+			zap.String("rule", rule.Name),
+			zap.String("trigger", trigger))
+		return false
+	}
+	m.running[rule.Name] = true
+	return true
+}
+
+// end marks a rule no longer running
+func (m *Manager) end(rule *Rule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.running, rule.Name)
+}
+
+// runActions runs actions in order, appending each one run to done, and
+// returns the actions run and the result: "ran", the error that stopped them,
+// or the delay the rest are waiting out
+func (m *Manager) runActions(rule *Rule, trigger string, actions []Action, done []string) ([]string, string) {
+	for i := range actions {
+		action := &actions[i]
+		if action.Delay != "" {
+			done = append(done, action.String())
+			m.wait(rule, trigger, action.DelayDuration(), actions[i+1:], done)
+			return done, "waiting " + action.Delay
+		}
+
+		if err := m.runAction(action); err != nil {
+			m.logger.Error("Rule action failed",
+				zap.String("rule", rule.Name),
+				zap.String("action", action.String()),
+				zap.Error(err))
+			return done, fmt.Sprintf("%s failed: %v", action, err)
+		}
+		done = append(done, action.String())
+	}
+	return done, "ran"
+}
+
+// wait schedules the actions after a delay. A rule triggered again while
+// waiting replaces the waiting actions with its own.
+func (m *Manager) wait(rule *Rule, trigger string, delay time.Duration, rest []Action, done []string) {
+	until := m.clock.Now().Add(delay)
+	done = append([]string{}, done...)
+
+	m.mu.Lock()
+	if _, ok := m.waiting[rule.Name]; ok {
+		m.logger.Warn("Rule triggered again while waiting, restarting its wait",
+			zap.String("rule", rule.Name))
+	}
+	m.waiting[rule.Name] = rest
+	m.mu.Unlock()
+
+	m.logger.Info("Rule waiting before its next action",
+		zap.String("rule", rule.Name),
+		zap.Duration("delay", delay),
+		zap.Time("until", until))
+	m.shadowTracker.UpdateWaitingUntil(rule.Name, until)
+
+	err := m.scheduler.Once(waitJobName(rule), until, func() {
+		m.mu.Lock()
+		delete(m.waiting, rule.Name)
+		m.mu.Unlock()
+		m.shadowTracker.UpdateWaitingUntil(rule.Name, time.Time{})
+
+		m.health.Tick()
+		if !m.begin(rule, trigger) {
+			return
+		}
+		defer m.end(rule)
+
+		m.logger.Info("Rule resuming after delay", zap.String("rule", rule.Name))
+		actions, result := m.runActions(rule, trigger, rest, done)
+		m.shadowTracker.RecordResumed(rule.Name, actions, result, m.clock.Now())
+	})
+	if err != nil {
+		m.logger.Error("Failed to schedule rule actions after delay",
+			zap.String("rule", rule.Name),
+			zap.Error(err))
+	}
+}
+
+// cancelWaiting drops the actions of rules waiting out a delay
+func (m *Manager) cancelWaiting() {
+	m.mu.Lock()
+	waiting := m.waiting
+	m.waiting = make(map[string][]Action)
+	m.mu.Unlock()
+
+	for name, rest := range waiting {
+		m.scheduler.Cancel(waitJobName(&Rule{Name: name}))
+		m.shadowTracker.UpdateWaitingUntil(name, time.Time{})
+
+		dropped := make([]string, 0, len(rest))
+		for i := range rest {
+			dropped = append(dropped, rest[i].String())
+		}
+		m.logger.Warn("Stopped while a rule was waiting, its remaining actions won't run",
+			zap.String("rule", name),
+			zap.Strings("actions", dropped))
+	}
+}
+
+// observe records a trigger variable's new value and returns the previous
+// one. The state manager can report a write this process made with the new
+// value as both old and new, so changes are detected against the last value
+// seen here.
+func (m *Manager) observe(key string, value interface{}) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.lastValues[key]
+	m.lastValues[key] = value
+	return previous, !valuesEqual(previous, value)
+}
+
+// runAction calls the action's service, writes its state variable or makes
+// its announcement
+func (m *Manager) runAction(action *Action) error {
+	if action.Announce != "" {
+		return m.announce(action)
+	}
+
+	if action.Set != "" {
+		if m.readOnly {
+			m.logger.Info("READ-ONLY: Would set state variable",
+				zap.String("variable", action.Set),
+				zap.Any("value", action.Value))
+			return nil
+		}
+		results, err := m.stateManager.SetBatch([]state.Update{{Key: action.Set, Value: action.Value}})
+		if err != nil && len(results) == 1 && results[0].Err != nil {
+			return results[0].Err
+		}
+		return err
+	}
+
+	domain, service, _ := action.DomainService()
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would call service",
+			zap.String("service", action.Service),
+			zap.Any("data", action.Data))
+		return nil
+	}
+	return m.haClient.CallService(m.ctx, domain, service, action.Data)
+}
+
+// announce speaks an announce action on its target's speakers. An
+// announcement skipped for quiet hours doesn't stop the rule.
+func (m *Manager) announce(action *Action) error {
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce",
+			zap.String("message", action.Announce),
+			zap.String("target", action.AnnounceTarget()))
+		return nil
+	}
+
+	speakers, err := m.announcer.Speakers(m.haClient, action.AnnounceTarget())
+	if err != nil {
+		return err
+	}
+	err = m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Message:  action.Announce,
+		Speakers: speakers,
+		Priority: action.Priority,
+	})
+	if errors.Is(err, tts.ErrQuietHours) {
+		m.logger.Info("Skipped rule announcement during quiet hours",
+			zap.String("message", action.Announce))
+		return nil
+	}
+	return err
+}
+
+// entityState returns an entity's state, or "" if it has none
+func entityState(s *ha.State) string {
+	if s == nil {
+		return ""
+	}
+	return s.State
+}
+
+// readValue returns a state variable's value as the type its triggers see
+func (m *Manager) readValue(key string) (interface{}, error) {
+	switch m.variables[key].Type {
+	case state.TypeBool:
+		return m.stateManager.GetBool(key)
+	case state.TypeNumber:
+		return m.stateManager.GetNumber(key)
+	case state.TypeString:
+		return m.stateManager.GetString(key)
+	default:
+		var value interface{}
+		err := m.stateManager.GetJSON(key, &value)
+		return value, err
+	}
+}
+
+// scheduleAll schedules every cron trigger, replacing any pending jobs
+func (m *Manager) scheduleAll() {
+	for i := range m.config.Rules {
+		rule := &m.config.Rules[i]
+		for j := range rule.Triggers {
+			trigger := &rule.Triggers[j]
+			if trigger.Cron == "" {
+				continue
+			}
+			err := m.scheduler.Cron(jobName(rule, j), trigger.Cron, func() {
+				m.updateNextScheduled(rule)
+				m.Evaluate(rule, "cron "+trigger.Cron)
+			})
+			if err != nil {
+				m.logger.Error("Failed to schedule cron trigger",
+					zap.String("rule", rule.Name),
+					zap.String("cron", trigger.Cron),
+					zap.Error(err))
+			}
+		}
+		m.updateNextScheduled(rule)
+	}
+}
+
+// cancelAll removes every cron trigger from the scheduler
+func (m *Manager) cancelAll() {
+	for i := range m.config.Rules {
+		rule := &m.config.Rules[i]
+		for j := range rule.Triggers {
+			if rule.Triggers[j].Cron != "" {
+				m.scheduler.Cancel(jobName(rule, j))
+			}
+		}
+	}
+}
+
+// updateNextScheduled records a rule's earliest scheduled cron time
+func (m *Manager) updateNextScheduled(rule *Rule) {
+	var earliest time.Time
+	for j := range rule.Triggers {
+		if rule.Triggers[j].Cron == "" {
+			continue
+		}
+		next, ok := m.scheduler.Next(jobName(rule, j))
+		if ok && (earliest.IsZero() || next.Before(earliest)) {
+			earliest = next
+		}
+	}
+	if !earliest.IsZero() {
+		m.shadowTracker.UpdateNextScheduled(rule.Name, earliest)
+	}
+}
+
+// waitJobName names the scheduler job of a rule's actions after a delay
+func waitJobName(rule *Rule) string {
+	return fmt.Sprintf("rules/%s/wait", rule.Name)
+}
+
+// jobName names the scheduler job of a rule's cron trigger
+func jobName(rule *Rule, trigger int) string {
+	return fmt.Sprintf("rules/%s/%d", rule.Name, trigger)
+}
@@ -0,0 +1,292 @@
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig(t *testing.T) *RulesConfig {
+	t.Helper()
+
+	config := &RulesConfig{Rules: []Rule{
+		{
+			Name:       "porch_light",
+			Triggers:   []Trigger{{State: "isAnyoneHome", From: false, To: true}},
+			Conditions: []Condition{{State: "dayPhase", In: []interface{}{"dusk", "night"}}},
+			Actions:    []Action{{Service: "light.turn_on", Data: map[string]interface{}{"entity_id": "light.porch"}}},
+		},
+		{
+			Name:       "clear_delivery",
+			Triggers:   []Trigger{{Cron: "0 22 * * *"}},
+			Conditions: []Condition{{State: "isExpectingDelivery", Equals: true}},
+			Actions: []Action{
+				{Set: "isExpectingDelivery", Value: false},
+				{Service: "notify.notify", Data: map[string]interface{}{"message": "Delivery cleared"}},
+			},
+		},
+	}}
+	require.NoError(t, config.Validate())
+	return config
+}
+
+// setupTest starts a manager at 2025-06-16 21:00 UTC with nobody home at dusk
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_boolean.anyone_home", "off", nil)
+	mockHA.SetState("input_boolean.expecting_delivery", "on", nil)
+	mockHA.SetState("input_text.day_phase", "dusk", nil)
+
+	stateManager := state.NewManager(mockHA, logger, readOnly)
+	require.NoError(t, stateManager.SyncFromHA())
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(t), logger, readOnly, time.UTC)
+	mockClock := clock.NewMockClock(time.Date(2025, 6, 16, 21, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+	jobs := scheduler.New(logger, time.UTC)
+	jobs.SetClock(mockClock)
+	manager.SetScheduler(jobs)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+func serviceCalls(mockHA *ha.MockClient, domain string) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == domain {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestStateTrigger_RunsWhenConditionsHold(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false)
+
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+
+	calls := serviceCalls(mockHA, "light")
+	require.Len(t, calls, 1)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, "light.porch", calls[0].Data["entity_id"])
+
+	status := manager.GetShadowState().Outputs.Rules[0]
+	assert.Equal(t, "porch_light", status.Name)
+	assert.Equal(t, "ran", status.LastResult)
+	assert.Equal(t, "isAnyoneHome: false -> true", status.LastTrigger)
+	assert.Equal(t, []string{"light.turn_on light.porch"}, status.LastActions)
+	assert.Equal(t, 1, status.RunCount)
+	assert.Equal(t, "dusk", manager.GetShadowState().Inputs.AtLastAction["dayPhase"])
+	assert.Equal(t, "porch_light (isAnyoneHome: false -> true)", manager.GetShadowState().GetLastActionReason())
+}
+
+func TestStateTrigger_SkipsWhenConditionFails(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false)
+
+	mockHA.SetState("input_text.day_phase", "day", nil)
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+
+	assert.Empty(t, serviceCalls(mockHA, "light"))
+	status := manager.GetShadowState().Outputs.Rules[0]
+	assert.Equal(t, "condition not met: dayPhase in [dusk night]", status.LastResult)
+	assert.Equal(t, 0, status.RunCount)
+}
+
+func TestStateTrigger_OnlyMatchingChanges(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, false)
+
+	// Repeated reports of the same value are not changes
+	mockHA.SetState("input_boolean.anyone_home", "off", nil)
+	assert.Empty(t, serviceCalls(mockHA, "light"))
+
+	// A write from this process is reported with the new value as old and new
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+	assert.Len(t, serviceCalls(mockHA, "light"), 1)
+
+	// true -> false doesn't match from: false, to: true
+	mockHA.SetState("input_boolean.anyone_home", "off", nil)
+	assert.Len(t, serviceCalls(mockHA, "light"), 1)
+}
+
+func TestCronTrigger(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+
+	status := manager.GetShadowState().Outputs.Rules[1]
+	assert.Equal(t, time.Date(2025, 6, 16, 22, 0, 0, 0, time.UTC), status.NextScheduled)
+
+	mockClock.Advance(59 * time.Minute)
+	assert.Empty(t, serviceCalls(mockHA, "notify"))
+
+	mockClock.Advance(time.Minute)
+	expecting, err := stateManager.GetBool("isExpectingDelivery")
+	require.NoError(t, err)
+	assert.False(t, expecting)
+	assert.Len(t, serviceCalls(mockHA, "notify"), 1)
+
+	status = manager.GetShadowState().Outputs.Rules[1]
+	assert.Equal(t, "cron 0 22 * * *", status.LastTrigger)
+	assert.Equal(t, []string{"set isExpectingDelivery = false", "notify.notify"}, status.LastActions)
+	assert.Equal(t, time.Date(2025, 6, 17, 22, 0, 0, 0, time.UTC), status.NextScheduled)
+
+	// The next night the condition no longer holds
+	mockClock.Advance(24 * time.Hour)
+	assert.Len(t, serviceCalls(mockHA, "notify"), 1)
+	assert.Equal(t, "condition not met: isExpectingDelivery == true", manager.GetShadowState().Outputs.Rules[1].LastResult)
+}
+
+func TestReadOnly(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, true)
+
+	mockClock.Advance(time.Hour)
+
+	assert.Empty(t, mockHA.GetServiceCalls())
+	expecting, err := stateManager.GetBool("isExpectingDelivery")
+	require.NoError(t, err)
+	assert.True(t, expecting)
+	assert.Equal(t, "ran", manager.GetShadowState().Outputs.Rules[1].LastResult)
+}
+
+func TestEvaluate_IgnoresReentry(t *testing.T) {
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	// Each run toggles the variable that triggers it
+	config := &RulesConfig{Rules: []Rule{{
+		Name:     "toggle",
+		Triggers: []Trigger{{State: "isOfficeFocusActive"}},
+		Actions:  []Action{{Set: "isOfficeFocusActive", Value: false}},
+	}}}
+	require.NoError(t, config.Validate())
+
+	manager := NewManager(mockHA, stateManager, config, logger, false, time.UTC)
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	require.NoError(t, stateManager.SetBool("isOfficeFocusActive", true))
+
+	status := manager.GetShadowState().Outputs.Rules[0]
+	assert.Equal(t, 1, status.RunCount, "the rule's own write should not run it again")
+	value, err := stateManager.GetBool("isOfficeFocusActive")
+	require.NoError(t, err)
+	assert.False(t, value)
+}
+
+// setupOnboardingTest starts a manager with a guest arrival routine: unlock,
+// announce, isHaveGuests on, then relock two minutes later
+func setupOnboardingTest(t *testing.T) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("person.guest", "not_home", nil)
+	mockHA.SetState("input_boolean.expecting_someone", "on", nil)
+	mockHA.SetState("input_boolean.have_guests", "off", nil)
+
+	stateManager := state.NewManager(mockHA, logger, false)
+	require.NoError(t, stateManager.SyncFromHA())
+
+	config := &RulesConfig{Rules: []Rule{{
+		Name:     "guest_onboarding",
+		Triggers: []Trigger{{Entity: "person.guest", To: "home"}},
+		Conditions: []Condition{
+			{State: "isExpectingSomeone", Equals: true},
+			{State: "isHaveGuests", Equals: false},
+		},
+		Actions: []Action{
+			{Service: "lock.unlock", Data: map[string]interface{}{"entity_id": "lock.front_door"}},
+			{Announce: "Your guest has arrived"},
+			{Set: "isHaveGuests", Value: true},
+			{Delay: "2m"},
+			{Service: "lock.lock", Data: map[string]interface{}{"entity_id": "lock.front_door"}},
+		},
+	}}}
+	require.NoError(t, config.Validate())
+
+	manager := NewManager(mockHA, stateManager, config, logger, false, time.UTC)
+	mockClock := clock.NewMockClock(time.Date(2025, 6, 16, 18, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+	jobs := scheduler.New(logger, time.UTC)
+	jobs.SetClock(mockClock)
+	manager.SetScheduler(jobs)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+func TestEntityTrigger_GuestOnboarding(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupOnboardingTest(t)
+
+	mockHA.SetState("person.guest", "home", nil)
+
+	locks := serviceCalls(mockHA, "lock")
+	require.Len(t, locks, 1)
+	assert.Equal(t, "unlock", locks[0].Service)
+	speech := serviceCalls(mockHA, "tts")
+	require.Len(t, speech, 1)
+	assert.Equal(t, "Your guest has arrived", speech[0].Data["message"])
+	haveGuests, err := stateManager.GetBool("isHaveGuests")
+	require.NoError(t, err)
+	assert.True(t, haveGuests)
+
+	status := manager.GetShadowState().Outputs.Rules[0]
+	assert.Equal(t, "person.guest: not_home -> home", status.LastTrigger)
+	assert.Equal(t, "waiting 2m", status.LastResult)
+	assert.Equal(t, time.Date(2025, 6, 16, 18, 2, 0, 0, time.UTC), status.WaitingUntil)
+
+	mockClock.Advance(2 * time.Minute)
+
+	locks = serviceCalls(mockHA, "lock")
+	require.Len(t, locks, 2)
+	assert.Equal(t, "lock", locks[1].Service)
+
+	status = manager.GetShadowState().Outputs.Rules[0]
+	assert.Equal(t, "ran", status.LastResult)
+	assert.Equal(t, []string{
+		"lock.unlock lock.front_door",
+		`announce "Your guest has arrived"`,
+		"set isHaveGuests = true",
+		"wait 2m",
+		"lock.lock lock.front_door",
+	}, status.LastActions)
+	assert.Equal(t, 1, status.RunCount)
+	assert.True(t, status.WaitingUntil.IsZero())
+
+	// Only the first arrival of the visit runs the routine
+	mockHA.SetState("person.guest", "not_home", nil)
+	mockHA.SetState("person.guest", "home", nil)
+	assert.Len(t, serviceCalls(mockHA, "lock"), 2)
+	assert.Equal(t, "condition not met: isHaveGuests == false", manager.GetShadowState().Outputs.Rules[0].LastResult)
+}
+
+func TestEntityTrigger_StopDropsWaitingActions(t *testing.T) {
+	manager, mockHA, _, mockClock := setupOnboardingTest(t)
+
+	mockHA.SetState("person.guest", "home", nil)
+	require.Len(t, serviceCalls(mockHA, "lock"), 1)
+
+	manager.Stop()
+	mockClock.Advance(5 * time.Minute)
+
+	assert.Len(t, serviceCalls(mockHA, "lock"), 1)
+	assert.True(t, manager.GetShadowState().Outputs.Rules[0].WaitingUntil.IsZero())
+}
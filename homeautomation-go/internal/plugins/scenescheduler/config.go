@@ -0,0 +1,101 @@
+package scenescheduler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/scheduler"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SceneSchedule turns on a scene on a cron schedule or at an offset from a
+// sun event. Exactly one of Cron and Sun is set.
+type SceneSchedule struct {
+	Name          string `yaml:"name"`
+	Scene         string `yaml:"scene"`          // scene.* entity
+	Cron          string `yaml:"cron"`           // Five-field cron expression in the configured time zone
+	Sun           string `yaml:"sun"`            // dawn, sunrise, solarNoon, sunset or dusk
+	Offset        string `yaml:"offset"`         // Optional offset from the sun event, e.g. "-30m"
+	RequireHome   bool   `yaml:"require_home"`   // Skip while nobody is home
+	RequireGuests bool   `yaml:"require_guests"` // Skip unless guests are staying
+}
+
+// Config represents the scene_schedules section of schedule_config.yaml
+type Config struct {
+	SceneSchedules []SceneSchedule `yaml:"scene_schedules"`
+}
+
+// SunOffset returns the parsed offset from the sun event
+func (s *SceneSchedule) SunOffset() time.Duration {
+	offset, _ := time.ParseDuration(s.Offset)
+	return offset
+}
+
+// Validate checks every scene schedule
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+
+	for i, s := range c.SceneSchedules {
+		if s.Name == "" {
+			return fmt.Errorf("scene_schedules[%d]: name is required", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("scene_schedules[%d]: duplicate name %q", i, s.Name)
+		}
+		names[s.Name] = true
+
+		if !strings.HasPrefix(s.Scene, "scene.") {
+			return fmt.Errorf("scene schedule %q: scene must be a scene.* entity, got %q", s.Name, s.Scene)
+		}
+		if (s.Cron == "") == (s.Sun == "") {
+			return fmt.Errorf("scene schedule %q: exactly one of cron and sun is required", s.Name)
+		}
+
+		if s.Cron != "" {
+			if s.Offset != "" {
+				return fmt.Errorf("scene schedule %q: offset only applies to sun schedules", s.Name)
+			}
+			if _, err := scheduler.ParseCron(s.Cron); err != nil {
+				return fmt.Errorf("scene schedule %q: %w", s.Name, err)
+			}
+			continue
+		}
+
+		if _, err := scheduler.ParseSunEvent(s.Sun); err != nil {
+			return fmt.Errorf("scene schedule %q: %w", s.Name, err)
+		}
+		if s.Offset != "" {
+			offset, err := time.ParseDuration(s.Offset)
+			if err != nil {
+				return fmt.Errorf("scene schedule %q: invalid offset %q, expected a duration like -30m", s.Name, s.Offset)
+			}
+			if offset <= -12*time.Hour || offset >= 12*time.Hour {
+				return fmt.Errorf("scene schedule %q: offset must be less than 12 hours", s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the scene schedules from schedule_config.yaml. A file
+// without a scene_schedules section has none.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
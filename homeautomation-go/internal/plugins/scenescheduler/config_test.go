@@ -0,0 +1,89 @@
+package scenescheduler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule SceneSchedule
+		errMsg   string
+	}{
+		{"cron", SceneSchedule{Name: "morning", Scene: "scene.morning", Cron: "0 7 * * 1-5"}, ""},
+		{"sun with offset", SceneSchedule{Name: "evening", Scene: "scene.evening", Sun: "sunset", Offset: "-30m"}, ""},
+		{"missing name", SceneSchedule{Scene: "scene.morning", Cron: "0 7 * * *"}, "name is required"},
+		{"not a scene", SceneSchedule{Name: "morning", Scene: "light.kitchen", Cron: "0 7 * * *"}, "scene.* entity"},
+		{"neither cron nor sun", SceneSchedule{Name: "morning", Scene: "scene.morning"}, "exactly one of cron and sun"},
+		{"both cron and sun", SceneSchedule{Name: "morning", Scene: "scene.morning", Cron: "0 7 * * *", Sun: "sunrise"}, "exactly one of cron and sun"},
+		{"invalid cron", SceneSchedule{Name: "morning", Scene: "scene.morning", Cron: "0 25 * * *"}, "morning"},
+		{"offset on cron", SceneSchedule{Name: "morning", Scene: "scene.morning", Cron: "0 7 * * *", Offset: "5m"}, "offset only applies"},
+		{"unknown sun event", SceneSchedule{Name: "evening", Scene: "scene.evening", Sun: "moonrise"}, "unknown sun event"},
+		{"invalid offset", SceneSchedule{Name: "evening", Scene: "scene.evening", Sun: "sunset", Offset: "half an hour"}, "invalid offset"},
+		{"offset too large", SceneSchedule{Name: "evening", Scene: "scene.evening", Sun: "sunset", Offset: "13h"}, "less than 12 hours"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{SceneSchedules: []SceneSchedule{tt.schedule}}
+			err := config.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidate_DuplicateNames(t *testing.T) {
+	config := &Config{SceneSchedules: []SceneSchedule{
+		{Name: "morning", Scene: "scene.morning", Cron: "0 7 * * *"},
+		{Name: "morning", Scene: "scene.morning", Cron: "0 8 * * *"},
+	}}
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate name")
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule_config.yaml")
+	content := `schedule:
+  - wake: "07:00"
+scene_schedules:
+  - name: evening
+    scene: scene.evening
+    sun: sunset
+    offset: -30m
+    require_home: true
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, config.SceneSchedules, 1)
+	assert.True(t, config.SceneSchedules[0].RequireHome)
+	assert.Equal(t, -30*time.Minute, config.SceneSchedules[0].SunOffset())
+}
+
+func TestLoadConfig_WithoutSceneSchedules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("schedule: []\n"), 0644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Empty(t, config.SceneSchedules)
+}
+
+func TestLoadConfig_RealConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/schedule_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.SceneSchedules)
+}
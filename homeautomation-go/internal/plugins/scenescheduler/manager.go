@@ -0,0 +1,259 @@
+// Package scenescheduler activates Home Assistant scenes on cron schedules or
+// at an offset from sunrise, sunset and the other sun events, as configured in
+// the scene_schedules section of schedule_config.yaml.
+package scenescheduler
+
+import (
+	"context"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// Manager schedules scene activations on the shared scheduler
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *Config
+	logger       *zap.Logger
+	readOnly     bool
+	timezone     *time.Location
+	clock        clock.Clock
+
+	// Runs the scene schedules
+	scheduler *scheduler.Scheduler
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.SceneSchedulerTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new scene scheduler manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *Config, logger *zap.Logger, readOnly bool, timezone *time.Location) *Manager {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("scenescheduler")),
+		health:        recorder,
+		readOnly:      readOnly,
+		timezone:      timezone,
+		clock:         clock.NewRealClock(),
+		scheduler:     scheduler.New(logger, timezone),
+		shadowTracker: shadowstate.NewSceneSchedulerTracker(),
+	}
+
+	for _, s := range config.SceneSchedules {
+		spec := s.Cron
+		if spec == "" {
+			spec = scheduler.SunSpec(scheduler.SunEvent(s.Sun), s.SunOffset())
+		}
+		m.shadowTracker.AddSchedule(s.Name, s.Scene, spec)
+	}
+
+	return m
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetScheduler sets the scheduler scene schedules run on, shared with other plugins
+func (m *Manager) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.SceneSchedulerShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start schedules every scene schedule
+func (m *Manager) Start(ctx context.Context) error {
+	// Scene activations run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Scene Scheduler", zap.Int("schedules", len(m.config.SceneSchedules)))
+
+	if err := m.scheduleAll(); err != nil {
+		m.cancelAll()
+		return err
+	}
+
+	m.health.Started(0)
+	m.logger.Info("Scene Scheduler started successfully")
+	return nil
+}
+
+// Stop cancels every scene schedule
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Scene Scheduler")
+
+	m.cancel()
+	m.cancelAll()
+
+	m.logger.Info("Scene Scheduler stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-arms the scene schedules from the current time. Scenes are only
+// activated when a schedule comes due, so nothing is re-applied.
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Scene Scheduler - rescheduling scenes")
+
+	if err := m.scheduleAll(); err != nil {
+		return err
+	}
+
+	m.logger.Info("Successfully reset Scene Scheduler")
+	return nil
+}
+
+// Activate turns on a schedule's scene unless it requires someone home and
+// nobody is, or requires guests and none are staying
+func (m *Manager) Activate(s *SceneSchedule) {
+	m.health.Tick()
+	now := m.clock.Now()
+
+	if s.RequireGuests {
+		isHaveGuests, err := m.stateManager.GetBool("isHaveGuests")
+		if err != nil {
+			m.logger.Error("Failed to get isHaveGuests", zap.Error(err))
+			m.shadowTracker.RecordSkipped(s.Name, "skipped: failed to read isHaveGuests", now)
+			return
+		}
+		m.shadowTracker.UpdateCurrentInput("isHaveGuests", isHaveGuests)
+
+		if !isHaveGuests {
+			m.logger.Info("No guests staying, skipping scheduled scene",
+				zap.String("schedule", s.Name),
+				zap.String("scene", s.Scene))
+			m.shadowTracker.RecordSkipped(s.Name, "skipped: no guests", now)
+			return
+		}
+	}
+
+	if s.RequireHome {
+		isAnyoneHome, err := m.stateManager.GetBool("isAnyoneHome")
+		if err != nil {
+			m.logger.Error("Failed to get isAnyoneHome", zap.Error(err))
+			m.shadowTracker.RecordSkipped(s.Name, "skipped: failed to read isAnyoneHome", now)
+			return
+		}
+		m.shadowTracker.UpdateCurrentInput("isAnyoneHome", isAnyoneHome)
+
+		if !isAnyoneHome {
+			m.logger.Info("Nobody home, skipping scheduled scene",
+				zap.String("schedule", s.Name),
+				zap.String("scene", s.Scene))
+			m.shadowTracker.RecordSkipped(s.Name, "skipped: nobody home", now)
+			return
+		}
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would activate scheduled scene",
+			zap.String("schedule", s.Name),
+			zap.String("scene", s.Scene))
+		m.shadowTracker.RecordActivation(s.Name, "activated", now)
+		return
+	}
+
+	m.logger.Info("Activating scheduled scene",
+		zap.String("schedule", s.Name),
+		zap.String("scene", s.Scene))
+
+	result := "activated"
+	err := m.haClient.CallService(m.ctx, "scene", "turn_on", map[string]interface{}{
+		"entity_id": s.Scene,
+	})
+	if err != nil {
+		m.logger.Error("Failed to activate scheduled scene",
+			zap.String("schedule", s.Name),
+			zap.String("scene", s.Scene),
+			zap.Error(err))
+		result = "failed: " + err.Error()
+	}
+
+	m.shadowTracker.RecordActivation(s.Name, result, now)
+}
+
+// scheduleAll schedules every scene schedule, replacing any pending jobs
+func (m *Manager) scheduleAll() error {
+	for i := range m.config.SceneSchedules {
+		s := &m.config.SceneSchedules[i]
+		run := func() {
+			m.updateNextRun(s)
+			m.Activate(s)
+		}
+
+		var err error
+		if s.Cron != "" {
+			err = m.scheduler.Cron(jobName(s), s.Cron, run)
+		} else {
+			// Validate has already checked the sun event
+			event, _ := scheduler.ParseSunEvent(s.Sun)
+			err = m.scheduler.Sun(jobName(s), event, s.SunOffset(), run)
+		}
+		if err != nil {
+			return err
+		}
+		m.updateNextRun(s)
+	}
+	return nil
+}
+
+// cancelAll removes every scene schedule from the scheduler
+func (m *Manager) cancelAll() {
+	for i := range m.config.SceneSchedules {
+		m.scheduler.Cancel(jobName(&m.config.SceneSchedules[i]))
+	}
+}
+
+// updateNextRun records when a scene schedule next runs
+func (m *Manager) updateNextRun(s *SceneSchedule) {
+	if next, ok := m.scheduler.Next(jobName(s)); ok {
+		m.shadowTracker.UpdateNextRun(s.Name, next)
+	}
+}
+
+// jobName names the scheduler job of a scene schedule
+func jobName(s *SceneSchedule) string {
+	return "scenescheduler/" + s.Name
+}
@@ -0,0 +1,155 @@
+package scenescheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/scheduler"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig(t *testing.T) *Config {
+	t.Helper()
+
+	config := &Config{SceneSchedules: []SceneSchedule{
+		{Name: "evening", Scene: "scene.evening", Cron: "0 19 * * *", RequireHome: true},
+		{Name: "porch", Scene: "scene.porch_on", Sun: "sunset", Offset: "-30m"},
+	}}
+	require.NoError(t, config.Validate())
+	return config
+}
+
+// setupTest starts a manager at 2025-06-16 18:00 UTC with nobody home
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_boolean.anyone_home", "off", nil)
+
+	stateManager := state.NewManager(mockHA, logger, readOnly)
+	require.NoError(t, stateManager.SyncFromHA())
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(t), logger, readOnly, time.UTC)
+	mockClock := clock.NewMockClock(time.Date(2025, 6, 16, 18, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+	jobs := scheduler.New(logger, time.UTC)
+	jobs.SetClock(mockClock)
+	// Chicago, where sunset is after 01:00 UTC in June
+	jobs.SetLocation(41.88, -87.63)
+	manager.SetScheduler(jobs)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return manager, mockHA, stateManager, mockClock
+}
+
+func sceneCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "scene" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestStart_SchedulesEveryScene(t *testing.T) {
+	manager, _, _, _ := setupTest(t, false)
+
+	schedules := manager.GetShadowState().Outputs.Schedules
+	require.Len(t, schedules, 2)
+	assert.Equal(t, time.Date(2025, 6, 16, 19, 0, 0, 0, time.UTC), schedules[0].NextRun)
+	assert.Equal(t, "0 19 * * *", schedules[0].Spec)
+	assert.Equal(t, "sunset-30m0s", schedules[1].Spec)
+	assert.True(t, schedules[1].NextRun.After(time.Date(2025, 6, 17, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestCron_SkipsWhileNobodyHome(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, false)
+
+	mockClock.Advance(time.Hour)
+
+	assert.Empty(t, sceneCalls(mockHA))
+	status := manager.GetShadowState().Outputs.Schedules[0]
+	assert.Equal(t, "skipped: nobody home", status.LastResult)
+	assert.Equal(t, 0, status.RunCount)
+	assert.Equal(t, time.Date(2025, 6, 17, 19, 0, 0, 0, time.UTC), status.NextRun)
+}
+
+func TestCron_ActivatesSceneWhenHome(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+
+	mockClock.Advance(time.Hour)
+
+	calls := sceneCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, "scene.evening", calls[0].Data["entity_id"])
+
+	state := manager.GetShadowState()
+	assert.Equal(t, 1, state.Outputs.Schedules[0].RunCount)
+	assert.Equal(t, "evening (scene.evening)", state.GetLastActionReason())
+}
+
+func TestActivate_RequiresGuests(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, false)
+	nightlight := &SceneSchedule{Name: "evening", Scene: "scene.guest_bathroom_nightlight", Cron: "0 19 * * *", RequireGuests: true}
+
+	manager.Activate(nightlight)
+	assert.Empty(t, sceneCalls(mockHA))
+	assert.Equal(t, "skipped: no guests", manager.GetShadowState().Outputs.Schedules[0].LastResult)
+
+	require.NoError(t, stateManager.SetBool("isHaveGuests", true))
+	manager.Activate(nightlight)
+
+	calls := sceneCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene.guest_bathroom_nightlight", calls[0].Data["entity_id"])
+}
+
+func TestSun_ActivatesWithoutRequiringHome(t *testing.T) {
+	manager, mockHA, _, mockClock := setupTest(t, false)
+
+	next := manager.GetShadowState().Outputs.Schedules[1].NextRun
+	mockClock.Set(next)
+
+	calls := sceneCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "scene.porch_on", calls[0].Data["entity_id"])
+}
+
+func TestReadOnly_DoesNotCallServices(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, true)
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+	isAnyoneHome, err := stateManager.GetBool("isAnyoneHome")
+	require.NoError(t, err)
+	require.True(t, isAnyoneHome)
+
+	mockClock.Advance(time.Hour)
+
+	assert.Empty(t, sceneCalls(mockHA))
+	assert.Equal(t, "activated", manager.GetShadowState().Outputs.Schedules[0].LastResult)
+}
+
+func TestStop_CancelsJobs(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+	require.NoError(t, stateManager.SetBool("isAnyoneHome", true))
+
+	manager.Stop()
+	mockClock.Advance(time.Hour)
+
+	assert.Empty(t, sceneCalls(mockHA))
+	_, ok := manager.scheduler.Next("scenescheduler/evening")
+	assert.False(t, ok)
+}
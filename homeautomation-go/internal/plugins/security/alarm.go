@@ -0,0 +1,580 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// Alarm modes, set by hand or by presence and sleep
+const (
+	AlarmDisarmed = "disarmed"
+	AlarmHome     = "home"
+	AlarmAway     = "away"
+	AlarmNight    = "night"
+)
+
+// Alarm statuses within a mode
+const (
+	alarmStatusDisarmed  = "disarmed"
+	alarmStatusArming    = "arming" // Exit delay running
+	alarmStatusArmed     = "armed"
+	alarmStatusPending   = "pending" // Entry delay running
+	alarmStatusTriggered = "triggered"
+)
+
+// Default alarm delays and siren duration
+const (
+	defaultAlarmExitDelay     = 60 * time.Second
+	defaultAlarmEntryDelay    = 30 * time.Second
+	defaultAlarmSirenDuration = 5 * time.Minute
+)
+
+var (
+	// ErrUnknownAlarmMode is returned by SetAlarmMode for a mode other than
+	// disarmed, home, away or night
+	ErrUnknownAlarmMode = errors.New("unknown alarm mode")
+
+	// ErrAlarmNotConfigured is returned by SetAlarmMode when no alarm sensors
+	// are configured
+	ErrAlarmNotConfigured = errors.New("no alarm sensors are configured")
+)
+
+// errAlarmSkipped wraps the reason an alarm action was not attempted
+var errAlarmSkipped = errors.New("skipped")
+
+// isAlarmMode reports whether mode is one of the alarm modes
+func isAlarmMode(mode string) bool {
+	return mode == AlarmDisarmed || isArmedAlarmMode(mode)
+}
+
+// isArmedAlarmMode reports whether mode is one of the armed alarm modes
+func isArmedAlarmMode(mode string) bool {
+	switch mode {
+	case AlarmHome, AlarmAway, AlarmNight:
+		return true
+	default:
+		return false
+	}
+}
+
+// startAlarm subscribes to the alarm sensors and mode entity, and picks the
+// starting mode from the mode entity or, with auto-arm, from presence
+func (m *Manager) startAlarm() error {
+	if !m.alarm.Enabled() {
+		return nil
+	}
+
+	for _, sensor := range m.alarm.Sensors {
+		haSub, err := m.haClient.SubscribeStateChanges(sensor.EntityID, m.handleAlarmSensorChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", sensor.EntityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, haSub)
+	}
+
+	if entityID := m.alarm.ModeEntity; entityID != "" {
+		haSub, err := m.haClient.SubscribeStateChanges(entityID, m.handleAlarmModeEntityChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, haSub)
+	}
+
+	m.refreshAlarmMode("startup")
+
+	// Publish the alarm even if the mode didn't change
+	m.mu.Lock()
+	started := m.alarmSince.IsZero()
+	if started {
+		m.alarmSince = m.clock.Now()
+	}
+	alarm := m.alarmStateLocked()
+	m.mu.Unlock()
+	if started {
+		m.recordAlarmTransition(alarm, "startup", "Alarm started", nil)
+	}
+	return nil
+}
+
+// refreshAlarmMode re-reads presence and sleep, and applies the mode selected
+// on the mode entity or, without one, the mode auto-arm would choose
+func (m *Manager) refreshAlarmMode(trigger string) {
+	asleep, asleepErr := m.stateManager.GetBool("isEveryoneAsleep")
+	anyoneHome, anyoneHomeErr := m.stateManager.GetBool("isAnyoneHome")
+	m.mu.Lock()
+	if asleepErr == nil {
+		m.alarmLastAsleep = asleep
+	}
+	if anyoneHomeErr == nil {
+		m.alarmLastAnyoneHome = anyoneHome
+	}
+	m.mu.Unlock()
+
+	if entityID := m.alarm.ModeEntity; entityID != "" {
+		st, err := m.haClient.GetState(m.ctx, entityID)
+		if err != nil {
+			m.logger.Warn("Failed to read alarm mode entity",
+				zap.String("entity_id", entityID),
+				zap.String("trigger", trigger),
+				zap.Error(err))
+			return
+		}
+		if st != nil && isAlarmMode(st.State) {
+			m.setAlarmMode(st.State, trigger, "Selected in Home Assistant", false)
+		}
+		return
+	}
+
+	if !m.alarm.AutoArm {
+		return
+	}
+	switch {
+	case asleepErr == nil && asleep:
+		m.setAlarmMode(AlarmNight, trigger, "Everyone is asleep", false)
+	case anyoneHomeErr == nil && !anyoneHome:
+		m.setAlarmMode(AlarmAway, trigger, "No one is home", false)
+	}
+}
+
+// SetAlarmMode sets the alarm mode by hand. Arming starts the exit delay;
+// disarming silences the siren.
+func (m *Manager) SetAlarmMode(mode string) error {
+	if !isAlarmMode(mode) {
+		return fmt.Errorf("%w: %q", ErrUnknownAlarmMode, mode)
+	}
+	if !m.alarm.Enabled() {
+		return ErrAlarmNotConfigured
+	}
+	m.setAlarmMode(mode, "api", "Set through the API", true)
+	return nil
+}
+
+// GetAlarmState returns the alarm's mode, status and recent transitions
+func (m *Manager) GetAlarmState() shadowstate.AlarmState {
+	return m.shadowTracker.GetState().Outputs.Alarm
+}
+
+// handleAlarmModeEntityChange follows the mode selected in Home Assistant.
+// Our own writes come back with the mode already set and are ignored.
+func (m *Manager) handleAlarmModeEntityChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+	if !isAlarmMode(newState.State) {
+		m.logger.Warn("Ignoring unknown alarm mode",
+			zap.String("entity_id", entityID),
+			zap.String("mode", newState.State))
+		return
+	}
+	m.setAlarmMode(newState.State, entityID, "Selected in Home Assistant", true)
+}
+
+// autoArmAlarmForSleep arms night mode when everyone falls asleep and
+// disarms it when someone wakes. HA echoes our own writes with old == new, so
+// changes are found against the last seen value rather than oldValue.
+func (m *Manager) autoArmAlarmForSleep(asleep bool, trigger string) {
+	if !m.alarm.Enabled() || !m.alarm.AutoArm {
+		return
+	}
+	m.mu.Lock()
+	changed := asleep != m.alarmLastAsleep
+	m.alarmLastAsleep = asleep
+	mode := m.alarmMode
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	switch {
+	case asleep && (mode == AlarmDisarmed || mode == AlarmHome):
+		m.setAlarmMode(AlarmNight, trigger, "Everyone is asleep", false)
+	case !asleep && mode == AlarmNight:
+		m.setAlarmMode(AlarmDisarmed, trigger, "Someone woke up", false)
+	}
+}
+
+// autoArmAlarmForPresence arms away mode when everyone leaves and disarms it
+// when someone comes home
+func (m *Manager) autoArmAlarmForPresence(anyoneHome bool, trigger string) {
+	if !m.alarm.Enabled() || !m.alarm.AutoArm {
+		return
+	}
+	m.mu.Lock()
+	changed := anyoneHome != m.alarmLastAnyoneHome
+	m.alarmLastAnyoneHome = anyoneHome
+	mode := m.alarmMode
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	switch {
+	case !anyoneHome && mode != AlarmAway:
+		m.setAlarmMode(AlarmAway, trigger, "No one is home", false)
+	case anyoneHome && mode == AlarmAway:
+		m.setAlarmMode(AlarmDisarmed, trigger, "Someone came home", false)
+	}
+}
+
+// setAlarmMode moves the alarm to a mode, starting the exit delay for an
+// armed mode. Setting the current mode again does nothing unless the alarm
+// has triggered. Transitions set by hand are announced; automatic ones are
+// not, so nobody asleep is woken.
+func (m *Manager) setAlarmMode(mode, trigger, reason string, announce bool) {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	if mode == m.alarmMode && m.alarmStatus != alarmStatusTriggered {
+		m.mu.Unlock()
+		return
+	}
+	m.stopAlarmTimerLocked()
+	sirenWasOn := m.alarmSirenOn
+	m.alarmSirenOn = false
+	m.alarmMode = mode
+	m.alarmSince = now
+	m.alarmTriggeredBy = ""
+	m.alarmDelayEndsAt = time.Time{}
+	if mode == AlarmDisarmed {
+		m.alarmStatus = alarmStatusDisarmed
+	} else {
+		exitDelay := m.alarm.ExitDelay()
+		m.alarmStatus = alarmStatusArming
+		m.alarmDelayEndsAt = now.Add(exitDelay)
+		m.alarmTimer = m.clock.AfterFunc(exitDelay, func() {
+			m.finishAlarmArming(mode)
+		})
+	}
+	alarm := m.alarmStateLocked()
+	m.mu.Unlock()
+
+	var details []string
+	if sirenWasOn {
+		if err := m.setAlarmSiren(false); err != nil {
+			details = append(details, "siren "+err.Error())
+		}
+	}
+	m.syncAlarmModeEntity(mode, trigger)
+	if announce {
+		message := "Alarm disarmed"
+		if mode != AlarmDisarmed {
+			message = fmt.Sprintf("Alarm arming in %s mode. You have %d seconds to leave", mode, int(m.alarm.ExitDelay()/time.Second))
+		}
+		if err := m.announceAlarm(message, tts.PriorityNormal); err != nil {
+			details = append(details, "announcement "+err.Error())
+		}
+	}
+
+	m.recordAlarmTransition(alarm, trigger, reason, details)
+}
+
+// finishAlarmArming arms the alarm once the exit delay runs out
+func (m *Manager) finishAlarmArming(mode string) {
+	m.mu.Lock()
+	if m.alarmMode != mode || m.alarmStatus != alarmStatusArming {
+		m.mu.Unlock()
+		return
+	}
+	m.alarmTimer = nil
+	m.alarmStatus = alarmStatusArmed
+	m.alarmSince = m.clock.Now()
+	m.alarmDelayEndsAt = time.Time{}
+	alarm := m.alarmStateLocked()
+	m.mu.Unlock()
+
+	m.recordAlarmTransition(alarm, "exit_delay", "Exit delay ended", nil)
+}
+
+// handleAlarmSensorChange starts the entry delay or triggers the alarm when
+// a sensor that isn't bypassed in the current mode trips while armed
+func (m *Manager) handleAlarmSensorChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil || !isDoorOpen(newState.State) {
+		return
+	}
+	if oldState != nil && isDoorOpen(oldState.State) {
+		return // Still open; only attributes changed
+	}
+	sensor, ok := m.alarmSensorConfig(entityID)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	mode, status := m.alarmMode, m.alarmStatus
+	if status != alarmStatusArmed && status != alarmStatusPending {
+		m.mu.Unlock()
+		return
+	}
+	if m.alarm.Bypassed(mode, entityID) {
+		m.mu.Unlock()
+		m.logger.Debug("Alarm sensor bypassed in this mode",
+			zap.String("entity_id", entityID),
+			zap.String("mode", mode))
+		return
+	}
+	if !sensor.EntryDelay {
+		m.mu.Unlock()
+		m.triggerAlarm(sensor, entityID, alarmStatusArmed, alarmStatusPending)
+		return
+	}
+	if status == alarmStatusPending {
+		// The entry delay is already running
+		m.mu.Unlock()
+		return
+	}
+
+	entryDelay := m.alarm.EntryDelay()
+	now := m.clock.Now()
+	m.stopAlarmTimerLocked()
+	m.alarmStatus = alarmStatusPending
+	m.alarmSince = now
+	m.alarmDelayEndsAt = now.Add(entryDelay)
+	m.alarmTriggeredBy = entityID
+	m.alarmTimer = m.clock.AfterFunc(entryDelay, func() {
+		m.triggerAlarm(sensor, "entry_delay", alarmStatusPending)
+	})
+	alarm := m.alarmStateLocked()
+	m.mu.Unlock()
+
+	var details []string
+	message := fmt.Sprintf("%s opened. Disarm the alarm within %d seconds", alarmSensorName(sensor), int(entryDelay/time.Second))
+	if err := m.announceAlarm(message, tts.PriorityUrgent); err != nil {
+		details = append(details, "announcement "+err.Error())
+	}
+	m.recordAlarmTransition(alarm, entityID, "Entry delay started", details)
+}
+
+// triggerAlarm sounds the siren, announces the intrusion and pushes a
+// notification, if the alarm is still in one of the given statuses
+func (m *Manager) triggerAlarm(sensor AlarmSensorConfig, trigger string, from ...string) {
+	m.mu.Lock()
+	current := false
+	for _, status := range from {
+		current = current || m.alarmStatus == status
+	}
+	if !current {
+		m.mu.Unlock()
+		return
+	}
+	m.stopAlarmTimerLocked()
+	m.alarmStatus = alarmStatusTriggered
+	m.alarmSince = m.clock.Now()
+	m.alarmDelayEndsAt = time.Time{}
+	m.alarmTriggeredBy = sensor.EntityID
+	if m.alarm.Siren != "" {
+		m.alarmSirenOn = true
+		m.alarmTimer = m.clock.AfterFunc(m.alarm.SirenDuration(), m.silenceAlarmSiren)
+	}
+	alarm := m.alarmStateLocked()
+	m.mu.Unlock()
+
+	message := fmt.Sprintf("Alarm triggered by %s", alarmSensorName(sensor))
+	m.logger.Warn("Alarm triggered",
+		zap.String("entity_id", sensor.EntityID),
+		zap.String("mode", alarm.Mode))
+
+	var details []string
+	if err := m.setAlarmSiren(true); err != nil {
+		details = append(details, "siren "+err.Error())
+	}
+	if err := m.announceAlarm(message, tts.PriorityUrgent); err != nil {
+		details = append(details, "announcement "+err.Error())
+	}
+	if err := m.notifyAlarm(message); err != nil {
+		details = append(details, "notification "+err.Error())
+	}
+	m.recordAlarmTransition(alarm, trigger, message, details)
+}
+
+// silenceAlarmSiren turns the siren off once it has sounded for the siren
+// duration. The alarm stays triggered until disarmed.
+func (m *Manager) silenceAlarmSiren() {
+	m.mu.Lock()
+	if m.alarmStatus != alarmStatusTriggered || !m.alarmSirenOn {
+		m.mu.Unlock()
+		return
+	}
+	m.alarmTimer = nil
+	m.alarmSirenOn = false
+	alarm := m.alarmStateLocked()
+	m.mu.Unlock()
+
+	var details []string
+	if err := m.setAlarmSiren(false); err != nil {
+		details = append(details, "siren "+err.Error())
+	}
+	m.recordAlarmTransition(alarm, "siren_timer", "Siren timed out", details)
+}
+
+// setAlarmSiren turns the siren on or off
+func (m *Manager) setAlarmSiren(on bool) error {
+	entityID := m.alarm.Siren
+	if entityID == "" {
+		return fmt.Errorf("%w: no siren configured", errAlarmSkipped)
+	}
+	service := "turn_off"
+	if on {
+		service = "turn_on"
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would switch alarm siren",
+			zap.String("entity_id", entityID),
+			zap.String("service", service))
+		return fmt.Errorf("%w: read-only mode", errAlarmSkipped)
+	}
+
+	return m.haClient.CallService(m.ctx, ha.EntityDomain(entityID), service, map[string]interface{}{
+		"entity_id": entityID,
+	})
+}
+
+// announceAlarm speaks an alarm message on the security speakers. Urgent
+// messages are spoken in do-not-disturb bedrooms and during focus mode too.
+func (m *Manager) announceAlarm(message string, priority tts.Priority) error {
+	speakers := m.ttsSpeakers()
+	if priority != tts.PriorityUrgent {
+		speakers, _ = m.dnd.Filter(speakers)
+		speakers, _ = m.focus.Filter(speakers)
+	}
+	if len(speakers) == 0 {
+		return fmt.Errorf("%w: no speakers", errAlarmSkipped)
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce alarm",
+			zap.Strings("speakers", speakers),
+			zap.String("message", message))
+		return fmt.Errorf("%w: read-only mode", errAlarmSkipped)
+	}
+
+	err := m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Title:    "Alarm",
+		Message:  message,
+		Speakers: speakers,
+		Priority: priority,
+	})
+	if errors.Is(err, tts.ErrQuietHours) {
+		return fmt.Errorf("%w: quiet hours", errAlarmSkipped)
+	}
+	return err
+}
+
+// notifyAlarm pushes an alarm message through the configured notify service
+func (m *Manager) notifyAlarm(message string) error {
+	if m.alarm.NotifyService == "" {
+		return fmt.Errorf("%w: no notify_service configured", errAlarmSkipped)
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send alarm notification",
+			zap.String("service", string(m.alarm.NotifyService)),
+			zap.String("message", message))
+		return fmt.Errorf("%w: read-only mode", errAlarmSkipped)
+	}
+
+	return notifyrouter.SendPush(m.ctx, m.haClient, m.alarm.NotifyService, notifyrouter.Push{
+		Title:   "Alarm",
+		Message: message,
+	})
+}
+
+// syncAlarmModeEntity selects the mode on the mode entity, unless the change
+// came from it
+func (m *Manager) syncAlarmModeEntity(mode, trigger string) {
+	entityID := m.alarm.ModeEntity
+	if entityID == "" || trigger == entityID {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would select alarm mode",
+			zap.String("entity_id", entityID),
+			zap.String("mode", mode))
+		return
+	}
+
+	if err := m.haClient.CallService(m.ctx, "input_select", "select_option", map[string]interface{}{
+		"entity_id": entityID,
+		"option":    mode,
+	}); err != nil {
+		m.logger.Error("Failed to select alarm mode",
+			zap.String("entity_id", entityID),
+			zap.String("mode", mode),
+			zap.Error(err))
+	}
+}
+
+// stopAlarmTimer cancels a pending exit delay, entry delay or siren timeout
+func (m *Manager) stopAlarmTimer() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopAlarmTimerLocked()
+}
+
+// stopAlarmTimerLocked cancels the alarm timer. Caller must hold mu.
+func (m *Manager) stopAlarmTimerLocked() {
+	if m.alarmTimer != nil {
+		m.alarmTimer.Stop()
+		m.alarmTimer = nil
+	}
+}
+
+// alarmStateLocked builds the shadow state for the alarm. Caller must hold mu.
+func (m *Manager) alarmStateLocked() shadowstate.AlarmState {
+	return shadowstate.AlarmState{
+		Enabled:     m.alarm.Enabled(),
+		Mode:        m.alarmMode,
+		Status:      m.alarmStatus,
+		Since:       m.alarmSince,
+		DelayEndsAt: m.alarmDelayEndsAt,
+		TriggeredBy: m.alarmTriggeredBy,
+		SirenOn:     m.alarmSirenOn,
+		Bypassed:    append([]string{}, m.alarm.Bypass[m.alarmMode]...),
+	}
+}
+
+// recordAlarmTransition records the alarm's new mode and status in shadow state
+func (m *Manager) recordAlarmTransition(alarm shadowstate.AlarmState, trigger, reason string, details []string) {
+	detail := strings.Join(details, "; ")
+	m.logger.Info("Alarm state changed",
+		zap.String("mode", alarm.Mode),
+		zap.String("status", alarm.Status),
+		zap.String("trigger", trigger),
+		zap.String("reason", reason),
+		zap.String("detail", detail))
+
+	m.updateShadowInputsWithTrigger(trigger)
+	m.shadowTracker.SnapshotInputsForAction()
+	m.shadowTracker.RecordAlarmTransition(alarm, shadowstate.AlarmTransition{
+		Timestamp: m.clock.Now(),
+		Mode:      alarm.Mode,
+		Status:    alarm.Status,
+		Trigger:   trigger,
+		Reason:    reason,
+		Detail:    detail,
+	})
+}
+
+// alarmSensorConfig returns the alarm settings for a sensor
+func (m *Manager) alarmSensorConfig(entityID string) (AlarmSensorConfig, bool) {
+	for _, sensor := range m.alarm.Sensors {
+		if sensor.EntityID == entityID {
+			return sensor, true
+		}
+	}
+	return AlarmSensorConfig{}, false
+}
+
+// alarmSensorName returns the sensor's spoken name
+func alarmSensorName(sensor AlarmSensorConfig) string {
+	if sensor.Name != "" {
+		return sensor.Name
+	}
+	return sensor.EntityID
+}
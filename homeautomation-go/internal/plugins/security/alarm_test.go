@@ -0,0 +1,284 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newAlarmTestManager(t *testing.T, autoArm bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("binary_sensor.front_door", "off", nil)
+	mockHA.SetState("binary_sensor.back_door", "off", nil)
+	mockHA.SetState("binary_sensor.hall_motion", "off", nil)
+	mockHA.SetState("input_select.alarm_mode", "disarmed", nil)
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+	mockHA.SetState("input_boolean.everyone_asleep", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
+	securityManager.SetClock(mockClock)
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{Alarm: AlarmConfig{
+		ModeEntity:        "input_select.alarm_mode",
+		AutoArm:           autoArm,
+		ExitDelaySeconds:  60,
+		EntryDelaySeconds: 30,
+		Sensors: []AlarmSensorConfig{
+			{EntityID: "binary_sensor.front_door", Name: "Front door", EntryDelay: true},
+			{EntityID: "binary_sensor.back_door", Name: "Back door"},
+			{EntityID: "binary_sensor.hall_motion", Name: "Hall motion"},
+		},
+		Bypass:        map[string][]string{AlarmNight: {"binary_sensor.hall_motion"}},
+		Siren:         "siren.outdoor",
+		SirenMinutes:  5,
+		NotifyService: "notify.mobile_app_phone",
+	}}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA, stateManager, mockClock
+}
+
+// armAlarm sets a mode and waits out the exit delay
+func armAlarm(t *testing.T, securityManager *Manager, mockHA *ha.MockClient, mockClock *clock.MockClock, mode string) {
+	t.Helper()
+	if err := securityManager.SetAlarmMode(mode); err != nil {
+		t.Fatalf("SetAlarmMode(%q) error = %v", mode, err)
+	}
+	mockClock.Advance(60 * time.Second)
+	if status := securityManager.GetAlarmState().Status; status != alarmStatusArmed {
+		t.Fatalf("Expected the alarm to be armed after the exit delay, got %q", status)
+	}
+	mockHA.ClearServiceCalls()
+}
+
+// TestSecurityManager_AlarmExitDelay tests that arming waits out the exit
+// delay, ignoring sensors, and writes the mode back to the mode entity
+func TestSecurityManager_AlarmExitDelay(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newAlarmTestManager(t, false)
+
+	if err := securityManager.SetAlarmMode(AlarmAway); err != nil {
+		t.Fatalf("SetAlarmMode() error = %v", err)
+	}
+
+	alarm := securityManager.GetAlarmState()
+	if alarm.Mode != AlarmAway || alarm.Status != alarmStatusArming {
+		t.Fatalf("Expected away mode arming, got %s/%s", alarm.Mode, alarm.Status)
+	}
+	if want := mockClock.Now().Add(60 * time.Second); !alarm.DelayEndsAt.Equal(want) {
+		t.Errorf("Expected the exit delay to end at %v, got %v", want, alarm.DelayEndsAt)
+	}
+	var selected bool
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "input_select" && call.Service == "select_option" && call.Data["option"] == AlarmAway {
+			selected = true
+		}
+	}
+	if !selected {
+		t.Errorf("Expected away to be selected on the mode entity, got %v", mockHA.GetServiceCalls())
+	}
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 1 {
+		t.Errorf("Expected the arming to be announced, got %d announcements", n)
+	}
+
+	mockHA.ClearServiceCalls()
+	mockHA.SimulateStateChange("binary_sensor.back_door", "on")
+	mockHA.SimulateStateChange("binary_sensor.back_door", "off")
+	if n := countServiceCalls(mockHA, "siren", "turn_on"); n != 0 {
+		t.Error("Expected sensors to be ignored during the exit delay")
+	}
+
+	mockClock.Advance(60 * time.Second)
+	alarm = securityManager.GetAlarmState()
+	if alarm.Status != alarmStatusArmed || !alarm.DelayEndsAt.IsZero() {
+		t.Errorf("Expected the alarm armed after the exit delay, got %+v", alarm)
+	}
+}
+
+// TestSecurityManager_AlarmEntryDelayTriggers tests that an entry door starts
+// the entry delay and the alarm triggers when it runs out
+func TestSecurityManager_AlarmEntryDelayTriggers(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newAlarmTestManager(t, false)
+	armAlarm(t, securityManager, mockHA, mockClock, AlarmAway)
+
+	mockHA.SimulateStateChange("binary_sensor.front_door", "on")
+
+	alarm := securityManager.GetAlarmState()
+	if alarm.Status != alarmStatusPending || alarm.TriggeredBy != "binary_sensor.front_door" {
+		t.Fatalf("Expected the entry delay to start, got %+v", alarm)
+	}
+	if n := countServiceCalls(mockHA, "siren", "turn_on"); n != 0 {
+		t.Fatal("Expected no siren during the entry delay")
+	}
+
+	mockClock.Advance(30 * time.Second)
+
+	alarm = securityManager.GetAlarmState()
+	if alarm.Status != alarmStatusTriggered || !alarm.SirenOn {
+		t.Fatalf("Expected the alarm to trigger after the entry delay, got %+v", alarm)
+	}
+	if n := countServiceCalls(mockHA, "siren", "turn_on"); n != 1 {
+		t.Errorf("Expected the siren to sound, got %d calls", n)
+	}
+	if n := countServiceCalls(mockHA, "notify", "mobile_app_phone"); n != 1 {
+		t.Errorf("Expected a push notification, got %d", n)
+	}
+
+	mockClock.Advance(5 * time.Minute)
+	alarm = securityManager.GetAlarmState()
+	if alarm.Status != alarmStatusTriggered || alarm.SirenOn {
+		t.Errorf("Expected the siren to time out with the alarm still triggered, got %+v", alarm)
+	}
+	if n := countServiceCalls(mockHA, "siren", "turn_off"); n != 1 {
+		t.Errorf("Expected the siren to be turned off, got %d calls", n)
+	}
+}
+
+// TestSecurityManager_AlarmDisarmDuringEntryDelay tests that disarming
+// within the entry delay stops the alarm from triggering
+func TestSecurityManager_AlarmDisarmDuringEntryDelay(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newAlarmTestManager(t, false)
+	armAlarm(t, securityManager, mockHA, mockClock, AlarmAway)
+
+	mockHA.SimulateStateChange("binary_sensor.front_door", "on")
+	mockClock.Advance(10 * time.Second)
+	if err := securityManager.SetAlarmMode(AlarmDisarmed); err != nil {
+		t.Fatalf("SetAlarmMode() error = %v", err)
+	}
+	mockClock.Advance(time.Minute)
+
+	alarm := securityManager.GetAlarmState()
+	if alarm.Mode != AlarmDisarmed || alarm.Status != alarmStatusDisarmed {
+		t.Errorf("Expected the alarm disarmed, got %s/%s", alarm.Mode, alarm.Status)
+	}
+	if n := countServiceCalls(mockHA, "siren", "turn_on"); n != 0 {
+		t.Errorf("Expected no siren after disarming, got %d calls", n)
+	}
+}
+
+// TestSecurityManager_AlarmBypassAndInstantTrigger tests that bypassed
+// sensors are ignored and other sensors trigger the alarm at once
+func TestSecurityManager_AlarmBypassAndInstantTrigger(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newAlarmTestManager(t, false)
+	armAlarm(t, securityManager, mockHA, mockClock, AlarmNight)
+
+	if bypassed := securityManager.GetAlarmState().Bypassed; len(bypassed) != 1 || bypassed[0] != "binary_sensor.hall_motion" {
+		t.Errorf("Expected hall motion bypassed in night mode, got %v", bypassed)
+	}
+
+	mockHA.SimulateStateChange("binary_sensor.hall_motion", "on")
+	if status := securityManager.GetAlarmState().Status; status != alarmStatusArmed {
+		t.Fatalf("Expected a bypassed sensor to be ignored, got %q", status)
+	}
+
+	mockHA.SimulateStateChange("binary_sensor.back_door", "on")
+	alarm := securityManager.GetAlarmState()
+	if alarm.Status != alarmStatusTriggered || alarm.TriggeredBy != "binary_sensor.back_door" {
+		t.Fatalf("Expected the back door to trigger the alarm at once, got %+v", alarm)
+	}
+
+	if err := securityManager.SetAlarmMode(AlarmDisarmed); err != nil {
+		t.Fatalf("SetAlarmMode() error = %v", err)
+	}
+	if n := countServiceCalls(mockHA, "siren", "turn_off"); n != 1 {
+		t.Errorf("Expected disarming to silence the siren, got %d calls", n)
+	}
+}
+
+// TestSecurityManager_AlarmAutoArm tests that sleep and presence arm and
+// disarm the alarm without announcements
+func TestSecurityManager_AlarmAutoArm(t *testing.T) {
+	securityManager, mockHA, stateManager, _ := newAlarmTestManager(t, true)
+
+	if err := stateManager.SetBool("isEveryoneAsleep", true); err != nil {
+		t.Fatalf("Failed to set isEveryoneAsleep: %v", err)
+	}
+	alarm := securityManager.GetAlarmState()
+	if alarm.Mode != AlarmNight {
+		t.Fatalf("Expected night mode when everyone is asleep, got %q", alarm.Mode)
+	}
+	if last := alarm.Transitions[len(alarm.Transitions)-1]; last.Trigger != "isEveryoneAsleep" {
+		t.Errorf("Expected the transition triggered by isEveryoneAsleep, got %+v", last)
+	}
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 0 {
+		t.Errorf("Expected no announcement while everyone is asleep, got %d", n)
+	}
+
+	if err := stateManager.SetBool("isEveryoneAsleep", false); err != nil {
+		t.Fatalf("Failed to set isEveryoneAsleep: %v", err)
+	}
+	if mode := securityManager.GetAlarmState().Mode; mode != AlarmDisarmed {
+		t.Fatalf("Expected the alarm disarmed on waking, got %q", mode)
+	}
+
+	if err := stateManager.SetBool("isAnyoneHome", false); err != nil {
+		t.Fatalf("Failed to set isAnyoneHome: %v", err)
+	}
+	if mode := securityManager.GetAlarmState().Mode; mode != AlarmAway {
+		t.Fatalf("Expected away mode when no one is home, got %q", mode)
+	}
+
+	if err := stateManager.SetBool("isAnyoneHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyoneHome: %v", err)
+	}
+	if mode := securityManager.GetAlarmState().Mode; mode != AlarmDisarmed {
+		t.Errorf("Expected the alarm disarmed when someone comes home, got %q", mode)
+	}
+}
+
+// TestSecurityManager_AlarmModeEntity tests that selecting a mode in Home
+// Assistant sets it without writing it back
+func TestSecurityManager_AlarmModeEntity(t *testing.T) {
+	securityManager, mockHA, _, _ := newAlarmTestManager(t, false)
+
+	mockHA.SimulateStateChange("input_select.alarm_mode", AlarmHome)
+
+	alarm := securityManager.GetAlarmState()
+	if alarm.Mode != AlarmHome || alarm.Status != alarmStatusArming {
+		t.Fatalf("Expected home mode arming, got %s/%s", alarm.Mode, alarm.Status)
+	}
+	if n := countServiceCalls(mockHA, "input_select", "select_option"); n != 0 {
+		t.Errorf("Expected no write back to the mode entity, got %d", n)
+	}
+
+	mockHA.SimulateStateChange("input_select.alarm_mode", "vacation")
+	if mode := securityManager.GetAlarmState().Mode; mode != AlarmHome {
+		t.Errorf("Expected an unknown option to be ignored, got %q", mode)
+	}
+}
+
+// TestSecurityManager_SetAlarmModeErrors tests unknown modes and an alarm
+// without sensors
+func TestSecurityManager_SetAlarmModeErrors(t *testing.T) {
+	securityManager, _, _, _ := newAlarmTestManager(t, false)
+	if err := securityManager.SetAlarmMode("vacation"); !errors.Is(err, ErrUnknownAlarmMode) {
+		t.Errorf("Expected ErrUnknownAlarmMode, got %v", err)
+	}
+
+	mockHA := ha.NewMockClient()
+	mockHA.Connect(context.Background())
+	unconfigured := NewManager(mockHA, state.NewManager(mockHA, zap.NewNop(), false), zap.NewNop(), false, nil)
+	if err := unconfigured.SetAlarmMode(AlarmAway); !errors.Is(err, ErrAlarmNotConfigured) {
+		t.Errorf("Expected ErrAlarmNotConfigured, got %v", err)
+	}
+	if unconfigured.GetAlarmState().Enabled {
+		t.Error("Expected the alarm to be reported disabled without sensors")
+	}
+}
@@ -0,0 +1,586 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CameraPrivacyConfig lists the indoor camera privacy switches
+type CameraPrivacyConfig struct {
+	// PrivacySwitches are HA switch entities that put an indoor camera in
+	// privacy mode (not recording) when on
+	PrivacySwitches []string `yaml:"privacy_switches"`
+}
+
+// RateLimitPolicy limits how many notifications an event type sends within a
+// sliding window. Zero values fall back to the defaults.
+type RateLimitPolicy struct {
+	WindowSeconds int `yaml:"window_seconds"` // Default 20
+	Burst         int `yaml:"burst"`          // Notifications allowed per window, default 1
+	// BypassWhenExpecting skips the limit while isExpectingSomeone is true
+	BypassWhenExpecting bool `yaml:"bypass_when_expecting"`
+}
+
+// Window returns the policy's window, or def if unset
+func (p RateLimitPolicy) Window(def time.Duration) time.Duration {
+	if p.WindowSeconds <= 0 {
+		return def
+	}
+	return time.Duration(p.WindowSeconds) * time.Second
+}
+
+// BurstOrDefault returns the policy's burst allowance, or 1 if unset
+func (p RateLimitPolicy) BurstOrDefault() int {
+	if p.Burst <= 0 {
+		return 1
+	}
+	return p.Burst
+}
+
+// RateLimitConfig holds the notification rate limit policy for each event type
+type RateLimitConfig struct {
+	Doorbell        RateLimitPolicy `yaml:"doorbell"`
+	VehicleArrival  RateLimitPolicy `yaml:"vehicle_arrival"`
+	PersonDetection RateLimitPolicy `yaml:"person_detection"`
+}
+
+// DrillConfig holds the optional actuators exercised by a supervised drill
+type DrillConfig struct {
+	// TTSVolume is the speaker volume (0-1) for the drill announcement, default 0.2
+	TTSVolume float64 `yaml:"tts_volume"`
+	// NotifyService (domain.service) receives the drill notification, empty to skip
+	NotifyService notifyrouter.Target `yaml:"notify_service"`
+	// ValveRelay is a switch entity clicked on and off, empty to skip
+	ValveRelay string `yaml:"valve_relay"`
+}
+
+// TTSVolumeOrDefault returns the drill announcement volume, or the default if unset
+func (d DrillConfig) TTSVolumeOrDefault() float64 {
+	if d.TTSVolume <= 0 {
+		return defaultDrillTTSVolume
+	}
+	return d.TTSVolume
+}
+
+// HeldOpenDoorConfig configures the held-open alarm for one exterior door.
+// Zero thresholds fall back to the defaults.
+type HeldOpenDoorConfig struct {
+	// EntityID is a contact sensor ("on" = open) or cover ("open"/"opening")
+	EntityID string `yaml:"entity_id"`
+	Name     string `yaml:"name"` // Spoken name, e.g. "Back door"
+	// How long the door may stay open before the alarm starts
+	DayMinutes    float64 `yaml:"day_minutes"`    // Default 10
+	NightMinutes  float64 `yaml:"night_minutes"`  // During winddown and night, default 5
+	AsleepMinutes float64 `yaml:"asleep_minutes"` // While everyone is asleep, default 2
+	// Lights flashed at the last escalation step, default the doorbell lights
+	Lights []string `yaml:"lights"`
+}
+
+// Threshold returns how long the door may stay open during a period
+// (heldOpenDay, heldOpenNight or heldOpenAsleep)
+func (d HeldOpenDoorConfig) Threshold(period string) time.Duration {
+	minutes, def := d.DayMinutes, defaultHeldOpenDayMinutes
+	switch period {
+	case heldOpenNight:
+		minutes, def = d.NightMinutes, defaultHeldOpenNightMinutes
+	case heldOpenAsleep:
+		minutes, def = d.AsleepMinutes, defaultHeldOpenAsleepMinutes
+	}
+	if minutes <= 0 {
+		minutes = def
+	}
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+// DoorHeldOpenConfig configures the escalating alarm for exterior doors left
+// open: a TTS reminder, then a notification, then flashing lights
+type DoorHeldOpenConfig struct {
+	// StepIntervalSeconds is the time between escalation steps, default 60
+	StepIntervalSeconds int `yaml:"step_interval_seconds"`
+	// NotifyService (domain.service) receives the notification step, empty to skip it
+	NotifyService notifyrouter.Target  `yaml:"notify_service"`
+	Doors         []HeldOpenDoorConfig `yaml:"doors"`
+}
+
+// StepInterval returns the time between escalation steps, or the default if unset
+func (d DoorHeldOpenConfig) StepInterval() time.Duration {
+	if d.StepIntervalSeconds <= 0 {
+		return defaultHeldOpenStepInterval
+	}
+	return time.Duration(d.StepIntervalSeconds) * time.Second
+}
+
+// DeliveryWindowConfig configures the delivery window, during which the
+// doorbell is silent indoors and rings are announced later as a summary
+type DeliveryWindowConfig struct {
+	ToggleVariable string `yaml:"toggle_variable"` // Boolean state variable that opens the window by hand
+	CalendarEntity string `yaml:"calendar_entity"` // Optional calendar.* entity; the window follows its matching events
+	EventKeyword   string `yaml:"event_keyword"`   // Case-insensitive; defaults to "Delivery"
+	// CameraEntity is snapshotted on each ring, empty to skip
+	CameraEntity string `yaml:"camera_entity"`
+	// SnapshotPath is the file written by camera.snapshot; {timestamp} is
+	// replaced with the ring time
+	SnapshotPath string `yaml:"snapshot_path"`
+	SummaryTime  string `yaml:"summary_time"` // HH:MM when the deliveries are announced, default 18:00
+}
+
+// Enabled reports whether a toggle or calendar opens the delivery window
+func (d DeliveryWindowConfig) Enabled() bool {
+	return d.ToggleVariable != "" || d.CalendarEntity != ""
+}
+
+// Keyword returns the calendar event keyword
+func (d DeliveryWindowConfig) Keyword() string {
+	if d.EventKeyword == "" {
+		return defaultDeliveryEventKeyword
+	}
+	return d.EventKeyword
+}
+
+// SnapshotPathOrDefault returns the snapshot file template
+func (d DeliveryWindowConfig) SnapshotPathOrDefault() string {
+	if d.SnapshotPath == "" {
+		return defaultDeliverySnapshotPath
+	}
+	return d.SnapshotPath
+}
+
+// SummaryTimeOrDefault returns when the delivery summary is announced
+func (d DeliveryWindowConfig) SummaryTimeOrDefault() string {
+	if d.SummaryTime == "" {
+		return defaultDeliverySummaryTime
+	}
+	return d.SummaryTime
+}
+
+// PersonCameraConfig maps one camera's person detection sensor to a state
+// variable
+type PersonCameraConfig struct {
+	// Sensor is a person binary_sensor ("on" while a person is seen), such as
+	// Frigate's binary_sensor.front_door_person_occupancy or an HA-native
+	// detection, or a person count sensor such as Frigate's
+	// sensor.front_door_person_count
+	Sensor        string `yaml:"sensor"`
+	Name          string `yaml:"name"`           // Spoken name, e.g. "the front door"
+	StateVariable string `yaml:"state_variable"` // Boolean state variable that follows the sensor
+	CameraEntity  string `yaml:"camera_entity"`  // Snapshotted on each detection, empty to skip
+	Notify        bool   `yaml:"notify"`         // Announce detections (rate limited)
+}
+
+// PersonDetectionConfig configures person detection from camera sensors
+type PersonDetectionConfig struct {
+	// NotifyService (domain.service) receives a push with the snapshot, empty
+	// for TTS only
+	NotifyService notifyrouter.Target `yaml:"notify_service"`
+	// SnapshotPath is the file written by camera.snapshot, and SnapshotURL
+	// where the dashboard loads it from; {camera} is replaced with the camera
+	// name and {timestamp} with the detection time
+	SnapshotPath string               `yaml:"snapshot_path"`
+	SnapshotURL  string               `yaml:"snapshot_url"`
+	Cameras      []PersonCameraConfig `yaml:"cameras"`
+}
+
+// SnapshotPathOrDefault returns the snapshot file template
+func (p PersonDetectionConfig) SnapshotPathOrDefault() string {
+	if p.SnapshotPath == "" {
+		return defaultPersonSnapshotPath
+	}
+	return p.SnapshotPath
+}
+
+// SnapshotURLOrDefault returns the snapshot URL template
+func (p PersonDetectionConfig) SnapshotURLOrDefault() string {
+	if p.SnapshotURL == "" {
+		return defaultPersonSnapshotURL
+	}
+	return p.SnapshotURL
+}
+
+// AlarmSensorConfig is one sensor watched while the alarm is armed
+type AlarmSensorConfig struct {
+	// EntityID is a binary_sensor ("on" = door open or motion) or a cover
+	EntityID string `yaml:"entity_id"`
+	Name     string `yaml:"name"` // Spoken name, e.g. "Front door"
+	// EntryDelay starts the entry delay instead of triggering the alarm at
+	// once, for doors used to come home
+	EntryDelay bool `yaml:"entry_delay"`
+}
+
+// AlarmConfig configures the alarm state machine. Zero delays fall back to
+// the defaults.
+type AlarmConfig struct {
+	// ModeEntity is an input_select with the options disarmed, home, away and
+	// night. Selecting an option sets the alarm mode, and the mode is written
+	// back when it changes. Empty to control the alarm from the API only.
+	ModeEntity string `yaml:"mode_entity"`
+	// AutoArm arms night mode when everyone is asleep and away mode when no
+	// one is home, and disarms when they wake or someone comes home
+	AutoArm           bool                `yaml:"auto_arm"`
+	ExitDelaySeconds  int                 `yaml:"exit_delay_seconds"`  // Default 60
+	EntryDelaySeconds int                 `yaml:"entry_delay_seconds"` // Default 30
+	Sensors           []AlarmSensorConfig `yaml:"sensors"`
+	// Bypass lists the sensors ignored in each armed mode (home, away, night)
+	Bypass map[string][]string `yaml:"bypass"`
+	// Siren is a siren or switch entity turned on when the alarm triggers,
+	// empty to skip
+	Siren        string  `yaml:"siren"`
+	SirenMinutes float64 `yaml:"siren_minutes"` // How long the siren sounds, default 5
+	// NotifyService (domain.service) receives a push when the alarm
+	// triggers, empty for TTS only
+	NotifyService notifyrouter.Target `yaml:"notify_service"`
+}
+
+// Enabled reports whether any sensors are watched
+func (a AlarmConfig) Enabled() bool {
+	return len(a.Sensors) > 0
+}
+
+// ExitDelay returns how long after arming the sensors are ignored
+func (a AlarmConfig) ExitDelay() time.Duration {
+	if a.ExitDelaySeconds <= 0 {
+		return defaultAlarmExitDelay
+	}
+	return time.Duration(a.ExitDelaySeconds) * time.Second
+}
+
+// EntryDelay returns how long an entry door gives to disarm
+func (a AlarmConfig) EntryDelay() time.Duration {
+	if a.EntryDelaySeconds <= 0 {
+		return defaultAlarmEntryDelay
+	}
+	return time.Duration(a.EntryDelaySeconds) * time.Second
+}
+
+// SirenDuration returns how long the siren sounds once triggered
+func (a AlarmConfig) SirenDuration() time.Duration {
+	if a.SirenMinutes <= 0 {
+		return defaultAlarmSirenDuration
+	}
+	return time.Duration(a.SirenMinutes * float64(time.Minute))
+}
+
+// Bypassed reports whether a sensor is ignored in an armed mode
+func (a AlarmConfig) Bypassed(mode, entityID string) bool {
+	for _, bypassed := range a.Bypass[mode] {
+		if bypassed == entityID {
+			return true
+		}
+	}
+	return false
+}
+
+// DoorLockConfig is one door lock, optionally paired with the door's contact
+// sensor
+type DoorLockConfig struct {
+	Lock string `yaml:"lock"` // lock entity, e.g. lock.front_door
+	Name string `yaml:"name"` // Spoken name, e.g. "Front door"
+	// DoorSensor is the door's binary_sensor ("on" = open) or cover. The
+	// auto-lock countdown starts when it closes, and the lock is not thrown
+	// while it is open. Without one, the countdown starts when the lock is
+	// unlocked.
+	DoorSensor string `yaml:"door_sensor"`
+	// LockdownOnly locks the door on lockdown but never auto-locks it
+	LockdownOnly bool `yaml:"lockdown_only"`
+}
+
+// DoorLocksConfig configures auto-locking and the lock audit trail
+type DoorLocksConfig struct {
+	// AutoLockMinutes is how long a closed door stays unlocked, default 5
+	AutoLockMinutes float64          `yaml:"auto_lock_minutes"`
+	Doors           []DoorLockConfig `yaml:"doors"`
+}
+
+// AutoLockDelay returns how long after closing an unlocked door is locked
+func (l DoorLocksConfig) AutoLockDelay() time.Duration {
+	if l.AutoLockMinutes <= 0 {
+		return defaultAutoLockDelay
+	}
+	return time.Duration(l.AutoLockMinutes * float64(time.Minute))
+}
+
+// LockdownCueRoomConfig is a room whose light pulses red on lockdown while
+// it is occupied
+type LockdownCueRoomConfig struct {
+	OccupancyVariable string `yaml:"occupancy_variable"` // Boolean state variable, e.g. isKitchenOccupied
+	Light             string `yaml:"light"`              // light entity pulsed red
+}
+
+// LockdownConfig configures the cues given when lockdown activates
+type LockdownConfig struct {
+	CueRooms []LockdownCueRoomConfig `yaml:"cue_rooms"`
+	// OutdoorSpeakers are media_player entities, or entity group or area
+	// references, paused on lockdown
+	OutdoorSpeakers []string `yaml:"outdoor_speakers"`
+}
+
+// SecuritySettings holds optional security plugin settings
+type SecuritySettings struct {
+	CameraPrivacy   CameraPrivacyConfig   `yaml:"camera_privacy"`
+	RateLimits      RateLimitConfig       `yaml:"rate_limits"`
+	Drill           DrillConfig           `yaml:"drill"`
+	DoorHeldOpen    DoorHeldOpenConfig    `yaml:"door_held_open"`
+	DeliveryWindow  DeliveryWindowConfig  `yaml:"delivery_window"`
+	PersonDetection PersonDetectionConfig `yaml:"person_detection"`
+	Alarm           AlarmConfig           `yaml:"alarm"`
+	DoorLocks       DoorLocksConfig       `yaml:"door_locks"`
+	Lockdown        LockdownConfig        `yaml:"lockdown"`
+}
+
+// SecurityConfig represents the security_config.yaml structure
+type SecurityConfig struct {
+	Security SecuritySettings `yaml:"security"`
+}
+
+// Validate checks that every privacy switch is a switch entity, that rate
+// limit policies are not negative, and that drill, held-open door, delivery
+// window, person detection, alarm, door lock and lockdown settings are usable
+func (c *SecurityConfig) Validate() error {
+	for i, entityID := range c.Security.CameraPrivacy.PrivacySwitches {
+		if !strings.HasPrefix(entityID, "switch.") {
+			return fmt.Errorf("security: camera_privacy.privacy_switches[%d]: %q is not a switch entity", i, entityID)
+		}
+	}
+
+	policies := []struct {
+		name   string
+		policy RateLimitPolicy
+	}{
+		{"doorbell", c.Security.RateLimits.Doorbell},
+		{"vehicle_arrival", c.Security.RateLimits.VehicleArrival},
+		{"person_detection", c.Security.RateLimits.PersonDetection},
+	}
+	for _, p := range policies {
+		if p.policy.WindowSeconds < 0 {
+			return fmt.Errorf("security: rate_limits.%s.window_seconds must not be negative", p.name)
+		}
+		if p.policy.Burst < 0 {
+			return fmt.Errorf("security: rate_limits.%s.burst must not be negative", p.name)
+		}
+	}
+
+	drill := c.Security.Drill
+	if drill.TTSVolume < 0 || drill.TTSVolume > 1 {
+		return fmt.Errorf("security: drill.tts_volume %v must be between 0 and 1", drill.TTSVolume)
+	}
+	if drill.NotifyService != "" {
+		if _, _, ok := drill.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("security: drill.notify_service %q must be domain.service", drill.NotifyService)
+		}
+	}
+	if drill.ValveRelay != "" && !strings.HasPrefix(drill.ValveRelay, "switch.") {
+		return fmt.Errorf("security: drill.valve_relay %q is not a switch entity", drill.ValveRelay)
+	}
+
+	heldOpen := c.Security.DoorHeldOpen
+	if heldOpen.StepIntervalSeconds < 0 {
+		return fmt.Errorf("security: door_held_open.step_interval_seconds must not be negative")
+	}
+	if heldOpen.NotifyService != "" {
+		if _, _, ok := heldOpen.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("security: door_held_open.notify_service %q must be domain.service", heldOpen.NotifyService)
+		}
+	}
+	seen := make(map[string]bool, len(heldOpen.Doors))
+	for i, door := range heldOpen.Doors {
+		if door.EntityID == "" {
+			return fmt.Errorf("security: door_held_open.doors[%d]: entity_id is required", i)
+		}
+		if seen[door.EntityID] {
+			return fmt.Errorf("security: door_held_open.doors[%d]: %s is listed more than once", i, door.EntityID)
+		}
+		seen[door.EntityID] = true
+		if door.DayMinutes < 0 || door.NightMinutes < 0 || door.AsleepMinutes < 0 {
+			return fmt.Errorf("security: door_held_open.doors[%d]: thresholds must not be negative", i)
+		}
+		for j, light := range door.Lights {
+			if !strings.HasPrefix(light, "light.") {
+				return fmt.Errorf("security: door_held_open.doors[%d].lights[%d]: %q is not a light entity", i, j, light)
+			}
+		}
+	}
+
+	delivery := c.Security.DeliveryWindow
+	if delivery.ToggleVariable != "" {
+		variable, ok := state.VariablesByKey()[delivery.ToggleVariable]
+		if !ok {
+			return fmt.Errorf("security: delivery_window.toggle_variable: unknown variable %q", delivery.ToggleVariable)
+		}
+		if variable.Type != state.TypeBool {
+			return fmt.Errorf("security: delivery_window.toggle_variable %q must be a boolean", delivery.ToggleVariable)
+		}
+	}
+	if delivery.CalendarEntity != "" && !strings.HasPrefix(delivery.CalendarEntity, "calendar.") {
+		return fmt.Errorf("security: delivery_window.calendar_entity %q is not a calendar entity", delivery.CalendarEntity)
+	}
+	if delivery.CameraEntity != "" && !strings.HasPrefix(delivery.CameraEntity, "camera.") {
+		return fmt.Errorf("security: delivery_window.camera_entity %q is not a camera entity", delivery.CameraEntity)
+	}
+	if _, err := time.Parse("15:04", delivery.SummaryTimeOrDefault()); err != nil {
+		return fmt.Errorf("security: delivery_window.summary_time %q must be HH:MM", delivery.SummaryTime)
+	}
+
+	if err := c.validatePersonDetection(); err != nil {
+		return err
+	}
+	if err := c.validateAlarm(); err != nil {
+		return err
+	}
+	if err := c.validateDoorLocks(); err != nil {
+		return err
+	}
+	return c.validateLockdown()
+}
+
+// validatePersonDetection checks each camera's sensor, state variable and
+// camera entity
+func (c *SecurityConfig) validatePersonDetection() error {
+	person := c.Security.PersonDetection
+	if person.NotifyService != "" {
+		if _, _, ok := person.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("security: person_detection.notify_service %q must be domain.service", person.NotifyService)
+		}
+	}
+	sensors := make(map[string]bool, len(person.Cameras))
+	variables := make(map[string]bool, len(person.Cameras))
+	for i, camera := range person.Cameras {
+		if !strings.HasPrefix(camera.Sensor, "binary_sensor.") && !strings.HasPrefix(camera.Sensor, "sensor.") {
+			return fmt.Errorf("security: person_detection.cameras[%d]: sensor %q must be a binary_sensor or sensor entity", i, camera.Sensor)
+		}
+		if sensors[camera.Sensor] {
+			return fmt.Errorf("security: person_detection.cameras[%d]: %s is listed more than once", i, camera.Sensor)
+		}
+		sensors[camera.Sensor] = true
+
+		if camera.StateVariable != "" {
+			variable, ok := state.VariablesByKey()[camera.StateVariable]
+			if !ok {
+				return fmt.Errorf("security: person_detection.cameras[%d]: unknown state_variable %q", i, camera.StateVariable)
+			}
+			if variable.Type != state.TypeBool {
+				return fmt.Errorf("security: person_detection.cameras[%d]: state_variable %q must be a boolean", i, camera.StateVariable)
+			}
+			if variables[camera.StateVariable] {
+				return fmt.Errorf("security: person_detection.cameras[%d]: state_variable %q is used by another camera", i, camera.StateVariable)
+			}
+			variables[camera.StateVariable] = true
+		}
+		if camera.CameraEntity != "" && !strings.HasPrefix(camera.CameraEntity, "camera.") {
+			return fmt.Errorf("security: person_detection.cameras[%d]: camera_entity %q is not a camera entity", i, camera.CameraEntity)
+		}
+	}
+	return nil
+}
+
+// validateAlarm checks the alarm's mode entity, sensors, bypass lists and siren
+func (c *SecurityConfig) validateAlarm() error {
+	alarm := c.Security.Alarm
+	if alarm.ModeEntity != "" && !strings.HasPrefix(alarm.ModeEntity, "input_select.") {
+		return fmt.Errorf("security: alarm.mode_entity %q is not an input_select entity", alarm.ModeEntity)
+	}
+	if alarm.ExitDelaySeconds < 0 || alarm.EntryDelaySeconds < 0 || alarm.SirenMinutes < 0 {
+		return fmt.Errorf("security: alarm delays and siren_minutes must not be negative")
+	}
+	if alarm.NotifyService != "" {
+		if _, _, ok := alarm.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("security: alarm.notify_service %q must be domain.service", alarm.NotifyService)
+		}
+	}
+	if alarm.Siren != "" && !strings.HasPrefix(alarm.Siren, "siren.") && !strings.HasPrefix(alarm.Siren, "switch.") {
+		return fmt.Errorf("security: alarm.siren %q must be a siren or switch entity", alarm.Siren)
+	}
+
+	sensors := make(map[string]bool, len(alarm.Sensors))
+	for i, sensor := range alarm.Sensors {
+		if !strings.HasPrefix(sensor.EntityID, "binary_sensor.") && !strings.HasPrefix(sensor.EntityID, "cover.") {
+			return fmt.Errorf("security: alarm.sensors[%d]: %q must be a binary_sensor or cover entity", i, sensor.EntityID)
+		}
+		if sensors[sensor.EntityID] {
+			return fmt.Errorf("security: alarm.sensors[%d]: %s is listed more than once", i, sensor.EntityID)
+		}
+		sensors[sensor.EntityID] = true
+	}
+	for mode, bypassed := range alarm.Bypass {
+		if !isArmedAlarmMode(mode) {
+			return fmt.Errorf("security: alarm.bypass: unknown mode %q (want home, away or night)", mode)
+		}
+		for _, entityID := range bypassed {
+			if !sensors[entityID] {
+				return fmt.Errorf("security: alarm.bypass.%s: %s is not an alarm sensor", mode, entityID)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDoorLocks checks each door's lock and door sensor
+func (c *SecurityConfig) validateDoorLocks() error {
+	locks := c.Security.DoorLocks
+	if locks.AutoLockMinutes < 0 {
+		return fmt.Errorf("security: door_locks.auto_lock_minutes must not be negative")
+	}
+	seen := make(map[string]bool, len(locks.Doors))
+	for i, door := range locks.Doors {
+		if !strings.HasPrefix(door.Lock, "lock.") {
+			return fmt.Errorf("security: door_locks.doors[%d]: %q is not a lock entity", i, door.Lock)
+		}
+		if seen[door.Lock] {
+			return fmt.Errorf("security: door_locks.doors[%d]: %s is listed more than once", i, door.Lock)
+		}
+		seen[door.Lock] = true
+		if door.DoorSensor != "" && !strings.HasPrefix(door.DoorSensor, "binary_sensor.") && !strings.HasPrefix(door.DoorSensor, "cover.") {
+			return fmt.Errorf("security: door_locks.doors[%d]: door_sensor %q must be a binary_sensor or cover entity", i, door.DoorSensor)
+		}
+	}
+	return nil
+}
+
+// validateLockdown checks each cue room's occupancy variable and light, and
+// the outdoor speakers
+func (c *SecurityConfig) validateLockdown() error {
+	lockdown := c.Security.Lockdown
+	for i, room := range lockdown.CueRooms {
+		variable, ok := state.VariablesByKey()[room.OccupancyVariable]
+		if !ok {
+			return fmt.Errorf("security: lockdown.cue_rooms[%d]: unknown occupancy_variable %q", i, room.OccupancyVariable)
+		}
+		if variable.Type != state.TypeBool {
+			return fmt.Errorf("security: lockdown.cue_rooms[%d]: occupancy_variable %q must be a boolean", i, room.OccupancyVariable)
+		}
+		if !strings.HasPrefix(room.Light, "light.") {
+			return fmt.Errorf("security: lockdown.cue_rooms[%d]: %q is not a light entity", i, room.Light)
+		}
+	}
+	for i, speaker := range lockdown.OutdoorSpeakers {
+		if !strings.HasPrefix(speaker, "media_player.") && !entitygroups.IsReference(speaker) {
+			return fmt.Errorf("security: lockdown.outdoor_speakers[%d]: %q must be a media_player entity or an entity group or area reference", i, speaker)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the security configuration from a YAML file
+func LoadConfig(path string) (*SecurityConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config SecurityConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
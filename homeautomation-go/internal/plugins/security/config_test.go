@@ -0,0 +1,293 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "security_config.yaml")
+	configContent := `---
+security:
+  camera_privacy:
+    privacy_switches:
+      - switch.living_room_camera_privacy
+      - switch.kitchen_camera_privacy
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	switches := config.Security.CameraPrivacy.PrivacySwitches
+	if len(switches) != 2 || switches[0] != "switch.living_room_camera_privacy" {
+		t.Errorf("Unexpected privacy switches: %v", switches)
+	}
+}
+
+func TestLoadConfig_ProductionFile(t *testing.T) {
+	if _, err := LoadConfig("../../../../configs/security_config.yaml"); err != nil {
+		t.Fatalf("Failed to load production security config: %v", err)
+	}
+}
+
+func TestValidate_RejectsNonSwitchEntities(t *testing.T) {
+	config := &SecurityConfig{Security: SecuritySettings{
+		CameraPrivacy: CameraPrivacyConfig{PrivacySwitches: []string{"camera.living_room"}},
+	}}
+
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for non-switch privacy entity")
+	}
+}
+
+func TestLoadConfig_RateLimits(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "security_config.yaml")
+	configContent := `---
+security:
+  rate_limits:
+    doorbell:
+      window_seconds: 60
+      burst: 2
+      bypass_when_expecting: true
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	doorbell := config.Security.RateLimits.Doorbell
+	if doorbell.Window(DoorbellRateLimit) != time.Minute || doorbell.BurstOrDefault() != 2 || !doorbell.BypassWhenExpecting {
+		t.Errorf("Unexpected doorbell policy: %+v", doorbell)
+	}
+	vehicle := config.Security.RateLimits.VehicleArrival
+	if vehicle.Window(VehicleArrivalRateLimit) != VehicleArrivalRateLimit || vehicle.BurstOrDefault() != 1 {
+		t.Errorf("Expected default vehicle arrival policy, got %+v", vehicle)
+	}
+}
+
+func TestValidate_RejectsNegativeRateLimits(t *testing.T) {
+	config := &SecurityConfig{Security: SecuritySettings{
+		RateLimits: RateLimitConfig{VehicleArrival: RateLimitPolicy{Burst: -1}},
+	}}
+
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative burst")
+	}
+}
+
+func TestValidate_Drill(t *testing.T) {
+	tests := []struct {
+		name    string
+		drill   DrillConfig
+		wantErr bool
+	}{
+		{"empty", DrillConfig{}, false},
+		{"configured", DrillConfig{TTSVolume: 0.3, NotifyService: "notify.mobile_app", ValveRelay: "switch.valve_relay"}, false},
+		{"volume too high", DrillConfig{TTSVolume: 1.5}, true},
+		{"bad notify service", DrillConfig{NotifyService: "mobile_app"}, true},
+		{"valve relay not a switch", DrillConfig{ValveRelay: "valve.main"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{Drill: tt.drill}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if volume := (DrillConfig{}).TTSVolumeOrDefault(); volume != defaultDrillTTSVolume {
+		t.Errorf("Expected default drill volume, got %v", volume)
+	}
+}
+
+func TestValidate_DoorHeldOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  DoorHeldOpenConfig
+		wantErr bool
+	}{
+		{"Valid", DoorHeldOpenConfig{NotifyService: "notify.notify", Doors: []HeldOpenDoorConfig{{EntityID: "binary_sensor.back_door", Lights: []string{"light.porch"}}}}, false},
+		{"Missing entity", DoorHeldOpenConfig{Doors: []HeldOpenDoorConfig{{Name: "Back door"}}}, true},
+		{"Duplicate door", DoorHeldOpenConfig{Doors: []HeldOpenDoorConfig{{EntityID: "binary_sensor.back_door"}, {EntityID: "binary_sensor.back_door"}}}, true},
+		{"Negative threshold", DoorHeldOpenConfig{Doors: []HeldOpenDoorConfig{{EntityID: "binary_sensor.back_door", NightMinutes: -1}}}, true},
+		{"Non-light entity", DoorHeldOpenConfig{Doors: []HeldOpenDoorConfig{{EntityID: "binary_sensor.back_door", Lights: []string{"switch.porch"}}}}, true},
+		{"Bad notify service", DoorHeldOpenConfig{NotifyService: "notify"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{DoorHeldOpen: tt.config}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeldOpenDoorConfig_Threshold(t *testing.T) {
+	door := HeldOpenDoorConfig{DayMinutes: 15, AsleepMinutes: 0.5}
+
+	if got := door.Threshold(heldOpenDay); got != 15*time.Minute {
+		t.Errorf("Expected day threshold 15m, got %v", got)
+	}
+	if got := door.Threshold(heldOpenNight); got != 5*time.Minute {
+		t.Errorf("Expected default night threshold 5m, got %v", got)
+	}
+	if got := door.Threshold(heldOpenAsleep); got != 30*time.Second {
+		t.Errorf("Expected asleep threshold 30s, got %v", got)
+	}
+}
+
+func TestValidate_DeliveryWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  DeliveryWindowConfig
+		wantErr bool
+	}{
+		{"Valid", DeliveryWindowConfig{ToggleVariable: "isExpectingDelivery", CalendarEntity: "calendar.home", CameraEntity: "camera.doorbell", SummaryTime: "17:30"}, false},
+		{"Unknown toggle", DeliveryWindowConfig{ToggleVariable: "isExpectingParcels"}, true},
+		{"Non-boolean toggle", DeliveryWindowConfig{ToggleVariable: "dayPhase"}, true},
+		{"Non-calendar entity", DeliveryWindowConfig{CalendarEntity: "input_boolean.deliveries"}, true},
+		{"Non-camera entity", DeliveryWindowConfig{ToggleVariable: "isExpectingDelivery", CameraEntity: "image.doorbell"}, true},
+		{"Bad summary time", DeliveryWindowConfig{ToggleVariable: "isExpectingDelivery", SummaryTime: "6pm"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{DeliveryWindow: tt.config}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_PersonDetection(t *testing.T) {
+	frontDoor := PersonCameraConfig{Sensor: "binary_sensor.front_door_person_occupancy", StateVariable: "isPersonAtFrontDoor", CameraEntity: "camera.front_door"}
+	driveway := PersonCameraConfig{Sensor: "sensor.driveway_person_count", StateVariable: "isPersonInDriveway"}
+
+	tests := []struct {
+		name    string
+		config  PersonDetectionConfig
+		wantErr bool
+	}{
+		{"Valid", PersonDetectionConfig{NotifyService: "notify.notify", Cameras: []PersonCameraConfig{frontDoor, driveway}}, false},
+		{"Bad notify service", PersonDetectionConfig{NotifyService: "notify"}, true},
+		{"Non-sensor entity", PersonDetectionConfig{Cameras: []PersonCameraConfig{{Sensor: "camera.front_door"}}}, true},
+		{"Duplicate sensor", PersonDetectionConfig{Cameras: []PersonCameraConfig{frontDoor, {Sensor: frontDoor.Sensor}}}, true},
+		{"Unknown variable", PersonDetectionConfig{Cameras: []PersonCameraConfig{{Sensor: frontDoor.Sensor, StateVariable: "isPersonOnPorch"}}}, true},
+		{"Non-boolean variable", PersonDetectionConfig{Cameras: []PersonCameraConfig{{Sensor: frontDoor.Sensor, StateVariable: "dayPhase"}}}, true},
+		{"Shared variable", PersonDetectionConfig{Cameras: []PersonCameraConfig{frontDoor, {Sensor: driveway.Sensor, StateVariable: frontDoor.StateVariable}}}, true},
+		{"Non-camera entity", PersonDetectionConfig{Cameras: []PersonCameraConfig{{Sensor: frontDoor.Sensor, CameraEntity: "image.front_door"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{PersonDetection: tt.config}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Alarm(t *testing.T) {
+	frontDoor := AlarmSensorConfig{EntityID: "binary_sensor.front_door", EntryDelay: true}
+	motion := AlarmSensorConfig{EntityID: "binary_sensor.hall_motion"}
+
+	tests := []struct {
+		name    string
+		config  AlarmConfig
+		wantErr bool
+	}{
+		{"Valid", AlarmConfig{
+			ModeEntity:    "input_select.alarm_mode",
+			Sensors:       []AlarmSensorConfig{frontDoor, motion},
+			Bypass:        map[string][]string{"night": {motion.EntityID}},
+			Siren:         "siren.outdoor",
+			NotifyService: "notify.notify",
+		}, false},
+		{"Switch siren", AlarmConfig{Sensors: []AlarmSensorConfig{frontDoor}, Siren: "switch.siren_relay"}, false},
+		{"Non-select mode entity", AlarmConfig{ModeEntity: "input_text.alarm_mode"}, true},
+		{"Negative delay", AlarmConfig{ExitDelaySeconds: -1}, true},
+		{"Bad notify service", AlarmConfig{NotifyService: "notify"}, true},
+		{"Light siren", AlarmConfig{Siren: "light.porch"}, true},
+		{"Non-sensor entity", AlarmConfig{Sensors: []AlarmSensorConfig{{EntityID: "lock.front_door"}}}, true},
+		{"Duplicate sensor", AlarmConfig{Sensors: []AlarmSensorConfig{frontDoor, frontDoor}}, true},
+		{"Unknown bypass mode", AlarmConfig{Sensors: []AlarmSensorConfig{motion}, Bypass: map[string][]string{"vacation": {motion.EntityID}}}, true},
+		{"Bypass of unknown sensor", AlarmConfig{Sensors: []AlarmSensorConfig{frontDoor}, Bypass: map[string][]string{"night": {motion.EntityID}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{Alarm: tt.config}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_DoorLocks(t *testing.T) {
+	frontDoor := DoorLockConfig{Lock: "lock.front_door", DoorSensor: "binary_sensor.front_door"}
+
+	tests := []struct {
+		name    string
+		config  DoorLocksConfig
+		wantErr bool
+	}{
+		{"Valid", DoorLocksConfig{AutoLockMinutes: 5, Doors: []DoorLockConfig{frontDoor, {Lock: "lock.shed", LockdownOnly: true}}}, false},
+		{"Cover door sensor", DoorLocksConfig{Doors: []DoorLockConfig{{Lock: "lock.garage_entry", DoorSensor: "cover.garage_door"}}}, false},
+		{"Negative auto-lock", DoorLocksConfig{AutoLockMinutes: -1}, true},
+		{"Non-lock entity", DoorLocksConfig{Doors: []DoorLockConfig{{Lock: "switch.front_door"}}}, true},
+		{"Duplicate lock", DoorLocksConfig{Doors: []DoorLockConfig{frontDoor, frontDoor}}, true},
+		{"Non-sensor door", DoorLocksConfig{Doors: []DoorLockConfig{{Lock: "lock.front_door", DoorSensor: "light.porch"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{DoorLocks: tt.config}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Lockdown(t *testing.T) {
+	kitchen := LockdownCueRoomConfig{OccupancyVariable: "isKitchenOccupied", Light: "light.kitchen"}
+
+	tests := []struct {
+		name    string
+		config  LockdownConfig
+		wantErr bool
+	}{
+		{"Valid", LockdownConfig{CueRooms: []LockdownCueRoomConfig{kitchen}, OutdoorSpeakers: []string{"media_player.patio"}}, false},
+		{"Group and area speakers", LockdownConfig{OutdoorSpeakers: []string{"group:outdoor_speakers", "area:patio/media_player"}}, false},
+		{"Unknown occupancy variable", LockdownConfig{CueRooms: []LockdownCueRoomConfig{{OccupancyVariable: "isAtticOccupied", Light: "light.attic"}}}, true},
+		{"Non-boolean occupancy variable", LockdownConfig{CueRooms: []LockdownCueRoomConfig{{OccupancyVariable: "dayPhase", Light: "light.kitchen"}}}, true},
+		{"Non-light entity", LockdownConfig{CueRooms: []LockdownCueRoomConfig{{OccupancyVariable: "isKitchenOccupied", Light: "switch.kitchen"}}}, true},
+		{"Non-speaker entity", LockdownConfig{OutdoorSpeakers: []string{"light.patio"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SecurityConfig{Security: SecuritySettings{Lockdown: tt.config}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
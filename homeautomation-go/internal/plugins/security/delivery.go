@@ -0,0 +1,287 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// Delivery window defaults
+const (
+	defaultDeliveryEventKeyword = "Delivery"
+	defaultDeliverySummaryTime  = "18:00"
+	defaultDeliverySnapshotPath = "/config/www/snapshots/delivery_{timestamp}.jpg"
+)
+
+// Delivery window sources
+const (
+	deliverySourceToggle   = "toggle"
+	deliverySourceCalendar = "calendar"
+)
+
+// startDeliveryWindow subscribes to the delivery window's toggle and calendar
+// and schedules the first summary
+func (m *Manager) startDeliveryWindow() error {
+	if !m.delivery.Enabled() {
+		return nil
+	}
+
+	if key := m.delivery.ToggleVariable; key != "" {
+		sub, err := m.stateManager.Subscribe(key, m.handleDeliveryToggleChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", key, err)
+		}
+		m.stateSubscriptions = append(m.stateSubscriptions, sub)
+	}
+
+	if entityID := m.delivery.CalendarEntity; entityID != "" {
+		haSub, err := m.haClient.SubscribeStateChanges(entityID, m.handleDeliveryCalendarChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", entityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, haSub)
+	}
+
+	m.refreshDeliveryWindow("startup")
+	m.scheduleDeliverySummary()
+	return nil
+}
+
+// refreshDeliveryWindow re-reads the toggle and calendar
+func (m *Manager) refreshDeliveryWindow(trigger string) {
+	toggle := false
+	if key := m.delivery.ToggleVariable; key != "" {
+		value, err := m.stateManager.GetBool(key)
+		if err != nil {
+			m.logger.Warn("Failed to read delivery window toggle",
+				zap.String("key", key),
+				zap.String("trigger", trigger),
+				zap.Error(err))
+		}
+		toggle = value
+	}
+
+	event := ""
+	if entityID := m.delivery.CalendarEntity; entityID != "" {
+		calendar, err := m.haClient.GetState(m.ctx, entityID)
+		if err != nil {
+			m.logger.Warn("Failed to read delivery calendar",
+				zap.String("entity_id", entityID),
+				zap.String("trigger", trigger),
+				zap.Error(err))
+		}
+		event = m.deliveryEvent(calendar)
+	}
+
+	m.mu.Lock()
+	m.deliveryToggle = toggle
+	m.deliveryCalendarEvent = event
+	m.mu.Unlock()
+	m.updateDeliveryShadow()
+}
+
+// handleDeliveryToggleChange opens or closes the delivery window by hand
+func (m *Manager) handleDeliveryToggleChange(key string, oldValue, newValue interface{}) {
+	on, ok := newValue.(bool)
+	if !ok {
+		m.logger.Error("Invalid type for delivery window toggle", zap.String("key", key), zap.Any("value", newValue))
+		return
+	}
+
+	m.mu.Lock()
+	changed := on != m.deliveryToggle
+	m.deliveryToggle = on
+	m.mu.Unlock()
+	if changed {
+		m.logger.Info("Delivery window toggled", zap.Bool("on", on))
+		m.updateDeliveryShadow()
+	}
+}
+
+// handleDeliveryCalendarChange follows delivery events on the calendar
+func (m *Manager) handleDeliveryCalendarChange(entityID string, oldState, newState *ha.State) {
+	event := m.deliveryEvent(newState)
+
+	m.mu.Lock()
+	changed := event != m.deliveryCalendarEvent
+	m.deliveryCalendarEvent = event
+	m.mu.Unlock()
+	if changed {
+		m.logger.Info("Delivery calendar event changed", zap.String("event", event))
+		m.updateDeliveryShadow()
+	}
+}
+
+// deliveryEvent returns the title of the calendar's current event if it is a
+// delivery event, or "" if not
+func (m *Manager) deliveryEvent(calendar *ha.State) string {
+	if calendar == nil || calendar.State != "on" {
+		return ""
+	}
+	title, _ := calendar.Attributes["message"].(string)
+	if !strings.Contains(strings.ToLower(title), strings.ToLower(m.delivery.Keyword())) {
+		return ""
+	}
+	return title
+}
+
+// deliveryWindowSource returns what holds the delivery window open, or "" if
+// it is closed. Callers must hold mu.
+func (m *Manager) deliveryWindowSource() string {
+	switch {
+	case m.deliveryToggle:
+		return deliverySourceToggle
+	case m.deliveryCalendarEvent != "":
+		return deliverySourceCalendar
+	default:
+		return ""
+	}
+}
+
+// inDeliveryWindow reports whether deliveries are expected now
+func (m *Manager) inDeliveryWindow() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deliveryWindowSource() != ""
+}
+
+// handleDeliveryRing logs a doorbell ring during the delivery window and
+// snapshots the camera instead of announcing it indoors
+func (m *Manager) handleDeliveryRing() {
+	now := m.clock.Now()
+	event := shadowstate.DeliveryEvent{Timestamp: now}
+	if path, err := m.snapshotDelivery(now); err != nil {
+		event.Detail = err.Error()
+	} else {
+		event.Snapshot = path
+	}
+
+	m.mu.Lock()
+	m.pendingDeliveries++
+	pending := m.pendingDeliveries
+	m.mu.Unlock()
+
+	m.logger.Info("Doorbell rang during delivery window, holding for summary",
+		zap.Int("pending", pending),
+		zap.String("snapshot", event.Snapshot),
+		zap.String("detail", event.Detail))
+
+	m.updateShadowInputsWithTrigger("doorbell")
+	m.shadowTracker.SnapshotInputsForAction()
+	m.shadowTracker.RecordDeliveryEvent(event)
+}
+
+// snapshotDelivery saves a camera snapshot of the ring and returns its path
+func (m *Manager) snapshotDelivery(at time.Time) (string, error) {
+	if m.delivery.CameraEntity == "" {
+		return "", fmt.Errorf("no camera_entity configured")
+	}
+	path := strings.ReplaceAll(m.delivery.SnapshotPathOrDefault(), "{timestamp}", at.In(m.timezone).Format("20060102_150405"))
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would snapshot doorbell camera",
+			zap.String("camera", m.delivery.CameraEntity),
+			zap.String("filename", path))
+		return "", fmt.Errorf("read-only mode")
+	}
+
+	if err := m.haClient.CallService(m.ctx, "camera", "snapshot", map[string]interface{}{
+		"entity_id": m.delivery.CameraEntity,
+		"filename":  path,
+	}); err != nil {
+		m.logger.Error("Failed to snapshot doorbell camera", zap.Error(err))
+		return "", err
+	}
+	return path, nil
+}
+
+// scheduleDeliverySummary arms the timer for the next summary time
+func (m *Manager) scheduleDeliverySummary() {
+	now := m.clock.Now()
+	next, ok := nextDailyTime(now, m.delivery.SummaryTimeOrDefault(), m.timezone)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	if m.deliveryTimer != nil {
+		m.deliveryTimer.Stop()
+	}
+	m.deliverySummaryAt = next
+	m.deliveryTimer = m.clock.AfterFunc(next.Sub(now), m.announceDeliveries)
+	m.mu.Unlock()
+	m.updateDeliveryShadow()
+}
+
+// announceDeliveries announces the rings held during the delivery window,
+// if any, and schedules the next summary
+func (m *Manager) announceDeliveries() {
+	m.mu.Lock()
+	count := m.pendingDeliveries
+	m.pendingDeliveries = 0
+	m.mu.Unlock()
+
+	if count > 0 {
+		message := deliverySummaryMessage(count)
+		m.logger.Info("Announcing delivery summary", zap.Int("count", count))
+		m.sendTTSNotification(message, false)
+
+		m.updateShadowInputsWithTrigger("delivery_summary")
+		m.shadowTracker.SnapshotInputsForAction()
+		m.shadowTracker.RecordDeliverySummary(shadowstate.DeliverySummary{
+			Timestamp: m.clock.Now(),
+			Count:     count,
+			Message:   message,
+		})
+	}
+
+	m.scheduleDeliverySummary()
+}
+
+// stopDeliveryTimer cancels the pending summary
+func (m *Manager) stopDeliveryTimer() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.deliveryTimer != nil {
+		m.deliveryTimer.Stop()
+		m.deliveryTimer = nil
+	}
+}
+
+// updateDeliveryShadow publishes the delivery window state
+func (m *Manager) updateDeliveryShadow() {
+	m.mu.Lock()
+	source := m.deliveryWindowSource()
+	event := m.deliveryCalendarEvent
+	next := m.deliverySummaryAt
+	m.mu.Unlock()
+
+	m.shadowTracker.UpdateDeliveryWindow(source != "", source, event, next)
+}
+
+// deliverySummaryMessage builds an announcement such as "2 deliveries arrived"
+func deliverySummaryMessage(count int) string {
+	if count == 1 {
+		return "1 delivery arrived"
+	}
+	return fmt.Sprintf("%d deliveries arrived", count)
+}
+
+// nextDailyTime returns the next occurrence of an HH:MM local time after now
+func nextDailyTime(now time.Time, hhmm string, loc *time.Location) (time.Time, bool) {
+	parsed, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, false
+	}
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), parsed.Hour(), parsed.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, parsed.Hour(), parsed.Minute(), 0, 0, loc)
+	}
+	return next, true
+}
@@ -0,0 +1,186 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newDeliveryTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("calendar.home", "off", map[string]interface{}{"message": "Dentist"})
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	securityManager := NewManager(mockHA, stateManager, logger, readOnly, nil)
+	securityManager.SetClock(mockClock)
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{DeliveryWindow: DeliveryWindowConfig{
+		ToggleVariable: "isExpectingDelivery",
+		CalendarEntity: "calendar.home",
+		CameraEntity:   "camera.doorbell",
+		SnapshotPath:   "/config/www/delivery_{timestamp}.jpg",
+		SummaryTime:    "18:00",
+	}}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA, stateManager, mockClock
+}
+
+func ringDoorbell(mockHA *ha.MockClient, mockClock *clock.MockClock) {
+	mockClock.Advance(DoorbellRateLimit + time.Second)
+	mockHA.SimulateStateChange("input_button.doorbell", mockClock.Now().Format(time.RFC3339))
+}
+
+// TestSecurityManager_DeliveryWindowQuietsDoorbell tests that rings during the
+// window are snapshotted instead of announced, then summarized at the summary time
+func TestSecurityManager_DeliveryWindowQuietsDoorbell(t *testing.T) {
+	securityManager, mockHA, stateManager, mockClock := newDeliveryTestManager(t, false)
+
+	if err := stateManager.SetBool("isExpectingDelivery", true); err != nil {
+		t.Fatalf("Failed to set isExpectingDelivery: %v", err)
+	}
+	mockHA.ClearServiceCalls()
+
+	ringDoorbell(mockHA, mockClock)
+	ringDoorbell(mockHA, mockClock)
+
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 0 {
+		t.Errorf("Expected no TTS during the delivery window, got %d", n)
+	}
+	if n := countServiceCalls(mockHA, "light", "turn_on"); n != 0 {
+		t.Errorf("Expected no light flashes during the delivery window, got %d", n)
+	}
+	var snapshots []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "camera" && call.Service == "snapshot" {
+			snapshots = append(snapshots, call)
+		}
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected a snapshot for each ring, got %v", mockHA.GetServiceCalls())
+	}
+	if snapshots[0].Data["entity_id"] != "camera.doorbell" || snapshots[0].Data["filename"] != "/config/www/delivery_20240101_120021.jpg" {
+		t.Errorf("Unexpected snapshot call: %v", snapshots[0].Data)
+	}
+
+	shadow := securityManager.GetShadowState().Outputs
+	if !shadow.DeliveryWindow.Active || shadow.DeliveryWindow.Source != deliverySourceToggle {
+		t.Errorf("Expected the window to be open by the toggle, got %+v", shadow.DeliveryWindow)
+	}
+	if len(shadow.DeliveryWindow.Pending) != 2 || shadow.DeliveryWindow.Pending[0].Snapshot == "" {
+		t.Errorf("Expected 2 pending deliveries with snapshots, got %+v", shadow.DeliveryWindow.Pending)
+	}
+	if shadow.LastDoorbell == nil || !shadow.LastDoorbell.DeliveryWindow || shadow.LastDoorbell.TTSSent {
+		t.Errorf("Expected the last doorbell to be held for the summary, got %+v", shadow.LastDoorbell)
+	}
+
+	// The summary is announced at 18:00
+	mockHA.ClearServiceCalls()
+	mockClock.Set(time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC))
+
+	calls := mockHA.GetServiceCalls()
+	if countServiceCalls(mockHA, "tts", "speak") != 1 {
+		t.Fatalf("Expected one summary announcement, got %v", calls)
+	}
+	for _, call := range calls {
+		if call.Domain == "tts" && call.Data["message"] != "2 deliveries arrived" {
+			t.Errorf("Unexpected summary message: %v", call.Data["message"])
+		}
+	}
+
+	shadow = securityManager.GetShadowState().Outputs
+	if len(shadow.DeliveryWindow.Pending) != 0 {
+		t.Errorf("Expected pending deliveries to be cleared, got %+v", shadow.DeliveryWindow.Pending)
+	}
+	if shadow.DeliveryWindow.LastSummary == nil || shadow.DeliveryWindow.LastSummary.Count != 2 {
+		t.Errorf("Expected a summary of 2 deliveries, got %+v", shadow.DeliveryWindow.LastSummary)
+	}
+	if want := time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC); !shadow.DeliveryWindow.NextSummaryAt.Equal(want) {
+		t.Errorf("Expected the next summary at %v, got %v", want, shadow.DeliveryWindow.NextSummaryAt)
+	}
+
+	// Nothing is announced the next day without new rings
+	mockHA.ClearServiceCalls()
+	mockClock.Advance(24 * time.Hour)
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 0 {
+		t.Errorf("Expected no summary without deliveries, got %d", n)
+	}
+}
+
+// TestSecurityManager_DeliveryWindowFollowsCalendar tests the window opening
+// for calendar events with the keyword
+func TestSecurityManager_DeliveryWindowFollowsCalendar(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newDeliveryTestManager(t, false)
+
+	// Other events don't open the window
+	mockHA.SimulateStateChange("calendar.home", "on")
+	if securityManager.inDeliveryWindow() {
+		t.Fatal("Expected the window to stay closed for a non-delivery event")
+	}
+
+	mockHA.SetState("calendar.home", "on", map[string]interface{}{"message": "Furniture DELIVERY"})
+	shadow := securityManager.GetShadowState().Outputs.DeliveryWindow
+	if !shadow.Active || shadow.Source != deliverySourceCalendar || shadow.CalendarEvent != "Furniture DELIVERY" {
+		t.Fatalf("Expected the calendar to open the window, got %+v", shadow)
+	}
+
+	mockHA.ClearServiceCalls()
+	ringDoorbell(mockHA, mockClock)
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 0 {
+		t.Errorf("Expected no TTS during the delivery window, got %d", n)
+	}
+
+	// The doorbell rings normally once the event ends
+	mockHA.SetState("calendar.home", "off", map[string]interface{}{"message": "Furniture DELIVERY"})
+	mockHA.ClearServiceCalls()
+	ringDoorbell(mockHA, mockClock)
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 1 {
+		t.Errorf("Expected the doorbell TTS after the window closed, got %d", n)
+	}
+	if got := len(securityManager.GetShadowState().Outputs.DeliveryWindow.Pending); got != 1 {
+		t.Errorf("Expected only the ring during the window to be pending, got %d", got)
+	}
+}
+
+// TestSecurityManager_DeliveryWindowReadOnly tests that read-only mode logs
+// rings without taking snapshots
+func TestSecurityManager_DeliveryWindowReadOnly(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newDeliveryTestManager(t, true)
+
+	mockHA.SetState("input_boolean.expecting_delivery", "on", nil)
+	mockHA.ClearServiceCalls()
+	ringDoorbell(mockHA, mockClock)
+
+	if calls := mockHA.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls in read-only mode, got %v", calls)
+	}
+	pending := securityManager.GetShadowState().Outputs.DeliveryWindow.Pending
+	if len(pending) != 1 || pending[0].Snapshot != "" || pending[0].Detail != "read-only mode" {
+		t.Errorf("Expected a pending ring without a snapshot, got %+v", pending)
+	}
+}
+
+func TestDeliverySummaryMessage(t *testing.T) {
+	if got := deliverySummaryMessage(1); got != "1 delivery arrived" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+	if got := deliverySummaryMessage(3); got != "3 deliveries arrived" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+}
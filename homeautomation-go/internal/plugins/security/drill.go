@@ -0,0 +1,265 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// drillMessage is announced and sent during a drill so nobody mistakes it for a real event
+	drillMessage = "This is a drill. This is only a drill."
+
+	// defaultDrillTTSVolume is the speaker volume for the drill announcement
+	defaultDrillTTSVolume = 0.2
+
+	// drillTTSRestoreDelay is how long the announcement plays before speaker volumes are restored
+	drillTTSRestoreDelay = 10 * time.Second
+
+	// drillRelayClickDuration is how long the valve relay stays on
+	drillRelayClickDuration = time.Second
+
+	// drillRelayOffTimeout bounds turning the valve relay back off, which
+	// still runs after the drill is stopped
+	drillRelayOffTimeout = 10 * time.Second
+)
+
+// Drill actuators, in the order they are exercised
+const (
+	drillNotification = "notification"
+	drillLights       = "lights"
+	drillTTS          = "tts"
+	drillValveRelay   = "valve_relay"
+)
+
+// ErrDrillInProgress is returned by StartDrill while a drill is already running
+var ErrDrillInProgress = errors.New("a drill is already running")
+
+// StartDrill runs a supervised drill in the background. It exercises the
+// response path (notification, light flashes, TTS at reduced volume, valve
+// relay click) without lockdown or any other emergency state, and records
+// which actuators responded in the shadow state.
+func (m *Manager) StartDrill() error {
+	m.mu.Lock()
+	if m.drillRunning {
+		m.mu.Unlock()
+		return ErrDrillInProgress
+	}
+	m.drillRunning = true
+	m.mu.Unlock()
+
+	m.goBackground(func() {
+		defer func() {
+			m.mu.Lock()
+			m.drillRunning = false
+			m.mu.Unlock()
+		}()
+		m.runDrill("api")
+	})
+	return nil
+}
+
+// runDrill exercises each actuator in turn and records the checklist
+func (m *Manager) runDrill(trigger string) shadowstate.DrillReport {
+	m.logger.Info("Starting security drill", zap.String("trigger", trigger))
+
+	report := shadowstate.DrillReport{
+		StartedAt: m.clock.Now(),
+		Trigger:   trigger,
+	}
+	report.Checks = append(report.Checks,
+		m.drillNotify(),
+		m.drillFlashLights(),
+		m.drillAnnounce(),
+		m.drillClickValveRelay(),
+	)
+	report.FinishedAt = m.clock.Now()
+
+	report.Passed = true
+	for _, check := range report.Checks {
+		if !check.Skipped && !check.Responded {
+			report.Passed = false
+		}
+	}
+
+	m.shadowTracker.RecordDrill(report)
+	m.logger.Info("Security drill finished",
+		zap.Bool("passed", report.Passed),
+		zap.Any("checks", report.Checks))
+	return report
+}
+
+// drillSkipped returns a check for an actuator that wasn't exercised
+func drillSkipped(actuator string, entities []string, detail string) shadowstate.DrillCheck {
+	return shadowstate.DrillCheck{Actuator: actuator, Entities: entities, Skipped: true, Detail: detail}
+}
+
+// drillResult returns a check for an actuator that was exercised
+func drillResult(actuator string, entities []string, err error) shadowstate.DrillCheck {
+	check := shadowstate.DrillCheck{Actuator: actuator, Entities: entities, Responded: err == nil}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// drillNotify sends the drill notification
+func (m *Manager) drillNotify() shadowstate.DrillCheck {
+	target := m.drill.NotifyService
+	if target == "" {
+		return drillSkipped(drillNotification, nil, "no notify_service configured")
+	}
+	entities := []string{string(target)}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send drill notification", zap.String("service", string(target)))
+		return drillSkipped(drillNotification, entities, "read-only mode")
+	}
+
+	err := notifyrouter.SendPush(m.ctx, m.haClient, target, notifyrouter.Push{
+		Title:   "Security drill",
+		Message: drillMessage,
+	})
+	return drillResult(drillNotification, entities, err)
+}
+
+// drillFlashLights flashes the doorbell lights twice
+func (m *Manager) drillFlashLights() shadowstate.DrillCheck {
+	lights := m.expandEntities(doorbellLights)
+	if len(lights) == 0 {
+		return drillSkipped(drillLights, nil, "no doorbell lights configured")
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would flash lights for drill", zap.Strings("lights", lights))
+		return drillSkipped(drillLights, lights, "read-only mode")
+	}
+
+	var err error
+	for i := 0; i < 2 && err == nil; i++ {
+		if i > 0 {
+			if err = m.clock.SleepContext(m.ctx, DoorbellFlashDelay); err != nil {
+				break
+			}
+		}
+		for _, data := range m.kid.FlashCalls(lights) {
+			if err = m.haClient.CallService(m.ctx, "light", "turn_on", data); err != nil {
+				break
+			}
+		}
+	}
+	return drillResult(drillLights, lights, err)
+}
+
+// drillAnnounce plays the drill announcement at reduced volume, then restores
+// each speaker's previous volume
+func (m *Manager) drillAnnounce() shadowstate.DrillCheck {
+	speakers, _ := m.dnd.Filter(m.ttsSpeakers())
+	if len(speakers) == 0 {
+		return drillSkipped(drillTTS, nil, "all speakers are in do-not-disturb bedrooms")
+	}
+	volume := ha.VolumeFromLevel(m.drill.TTSVolumeOrDefault())
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce drill",
+			zap.Strings("speakers", speakers),
+			zap.Float64("volume", volume.Level()))
+		return drillSkipped(drillTTS, speakers, "read-only mode")
+	}
+
+	// Remember volumes so the announcement doesn't leave speakers quiet
+	previous := make(map[string]ha.Volume)
+	for _, speaker := range speakers {
+		if st, err := m.haClient.GetState(m.ctx, speaker); err == nil {
+			if level, ok := ha.VolumeOf(st); ok {
+				previous[speaker] = level
+			}
+		}
+	}
+
+	if err := ha.SetVolume(m.ctx, m.haClient, speakers, volume); err != nil {
+		return drillResult(drillTTS, speakers, fmt.Errorf("failed to lower volume: %w", err))
+	}
+
+	err := m.haClient.CallService(m.ctx, "tts", "speak", map[string]interface{}{
+		"entity_id":              m.entities.DrillTTS(),
+		"media_player_entity_id": speakers,
+		"message":                drillMessage,
+		"cache":                  true,
+	})
+
+	if sleepErr := m.clock.SleepContext(m.ctx, drillTTSRestoreDelay); sleepErr != nil {
+		return drillResult(drillTTS, speakers, sleepErr)
+	}
+	for speaker, level := range previous {
+		if restoreErr := ha.SetVolume(m.ctx, m.haClient, speaker, level); restoreErr != nil {
+			m.logger.Error("Failed to restore speaker volume after drill",
+				zap.String("speaker", speaker),
+				zap.Error(restoreErr))
+		}
+	}
+
+	return drillResult(drillTTS, speakers, err)
+}
+
+// drillClickValveRelay turns the valve relay on and back off, checking that
+// Home Assistant reports it on. Once turned on, the relay is always turned
+// back off, even if the drill fails or is stopped part way.
+func (m *Manager) drillClickValveRelay() (check shadowstate.DrillCheck) {
+	relay := m.drill.ValveRelay
+	if relay == "" {
+		return drillSkipped(drillValveRelay, nil, "no valve_relay configured")
+	}
+	entities := []string{relay}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would click valve relay for drill", zap.String("entity_id", relay))
+		return drillSkipped(drillValveRelay, entities, "read-only mode")
+	}
+
+	// A failed turn_on may still have reached the relay
+	defer func() {
+		if err := m.drillRelayOff(relay); err != nil {
+			m.logger.Error("Failed to turn valve relay off after drill",
+				zap.String("entity_id", relay),
+				zap.Error(err))
+			if check.Responded {
+				check = drillResult(drillValveRelay, entities, fmt.Errorf("failed to turn off: %w", err))
+			}
+		}
+	}()
+
+	if err := m.haClient.CallService(m.ctx, "switch", "turn_on", map[string]interface{}{"entity_id": relay}); err != nil {
+		return drillResult(drillValveRelay, entities, err)
+	}
+	if err := m.clock.SleepContext(m.ctx, drillRelayClickDuration); err != nil {
+		return drillResult(drillValveRelay, entities, err)
+	}
+	return drillResult(drillValveRelay, entities, m.expectEntityState(relay, "on"))
+}
+
+// drillRelayOff turns the valve relay off. It doesn't use the manager's
+// context, so it still runs when Stop cancels the drill.
+func (m *Manager) drillRelayOff(relay string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(m.ctx), drillRelayOffTimeout)
+	defer cancel()
+	return m.haClient.CallService(ctx, "switch", "turn_off", map[string]interface{}{"entity_id": relay})
+}
+
+// expectEntityState checks that Home Assistant reports the entity in the given state
+func (m *Manager) expectEntityState(entityID, want string) error {
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		return err
+	}
+	if st.State != want {
+		return fmt.Errorf("%s is %q, expected %q", entityID, st.State, want)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newDrillTestManager(t *testing.T, readOnly bool, drill DrillConfig) (*Manager, *ha.MockClient) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("media_player.kitchen", "idle", map[string]interface{}{"volume_level": 0.6})
+	mockHA.SetState("switch.valve_relay", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	groups, err := entitygroups.LoadConfig("../../../../configs/entity_groups_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load entity groups: %v", err)
+	}
+	securityManager := NewManager(entitygroups.NewClient(mockHA, groups), stateManager, logger, readOnly, nil)
+	securityManager.SetClock(clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{Drill: drill}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA
+}
+
+func findDrillCheck(t *testing.T, report shadowstate.DrillReport, actuator string) shadowstate.DrillCheck {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Actuator == actuator {
+			return check
+		}
+	}
+	t.Fatalf("No %s check in drill report %+v", actuator, report)
+	return shadowstate.DrillCheck{}
+}
+
+// TestSecurityManager_Drill tests that a drill exercises every actuator and records the checklist
+func TestSecurityManager_Drill(t *testing.T) {
+	securityManager, mockHA := newDrillTestManager(t, false, DrillConfig{
+		TTSVolume:     0.1,
+		NotifyService: "notify.mobile_app",
+		ValveRelay:    "switch.valve_relay",
+	})
+
+	report := securityManager.runDrill("test")
+
+	if !report.Passed {
+		t.Errorf("Expected drill to pass, got %+v", report)
+	}
+	for _, actuator := range []string{drillNotification, drillLights, drillTTS, drillValveRelay} {
+		check := findDrillCheck(t, report, actuator)
+		if !check.Responded || check.Skipped {
+			t.Errorf("Expected %s to respond, got %+v", actuator, check)
+		}
+	}
+
+	var flashes, lowered, restored, announced bool
+	for _, call := range mockHA.GetServiceCalls() {
+		switch {
+		case call.Domain == "light" && call.Data["flash"] == "short":
+			flashes = true
+		case call.Domain == "media_player" && call.Service == "volume_set" && call.Data["volume_level"] == 0.1:
+			lowered = true
+		case call.Domain == "media_player" && call.Service == "volume_set" && call.Data["volume_level"] == 0.6:
+			restored = true
+		case call.Domain == "tts" && call.Data["message"] == drillMessage:
+			announced = true
+		case call.Domain == "input_boolean" && call.Data["entity_id"] == "input_boolean.lockdown":
+			t.Error("Drill must not activate lockdown")
+		}
+	}
+	if !flashes || !lowered || !announced || !restored {
+		t.Errorf("Expected flashes, lowered volume, announcement and restored volume, got flashes=%v lowered=%v announced=%v restored=%v",
+			flashes, lowered, announced, restored)
+	}
+
+	if st, _ := mockHA.GetState(context.Background(), "switch.valve_relay"); st.State != "off" {
+		t.Errorf("Expected valve relay to be left off, got %s", st.State)
+	}
+
+	last := securityManager.GetShadowState().Outputs.LastDrill
+	if last == nil || last.Trigger != "test" || !last.Passed {
+		t.Errorf("Expected drill report in shadow state, got %+v", last)
+	}
+}
+
+// TestSecurityManager_DrillSkipsUnconfigured tests that optional actuators are skipped, not failed
+func TestSecurityManager_DrillSkipsUnconfigured(t *testing.T) {
+	securityManager, _ := newDrillTestManager(t, false, DrillConfig{})
+
+	report := securityManager.runDrill("test")
+
+	if !report.Passed {
+		t.Errorf("Expected drill to pass with skipped actuators, got %+v", report)
+	}
+	if check := findDrillCheck(t, report, drillNotification); !check.Skipped {
+		t.Errorf("Expected notification to be skipped, got %+v", check)
+	}
+	if check := findDrillCheck(t, report, drillValveRelay); !check.Skipped {
+		t.Errorf("Expected valve relay to be skipped, got %+v", check)
+	}
+	if check := findDrillCheck(t, report, drillLights); !check.Responded {
+		t.Errorf("Expected lights to respond, got %+v", check)
+	}
+}
+
+// TestSecurityManager_DrillReadOnly tests that a read-only drill makes no service calls
+func TestSecurityManager_DrillReadOnly(t *testing.T) {
+	securityManager, mockHA := newDrillTestManager(t, true, DrillConfig{
+		NotifyService: "notify.mobile_app",
+		ValveRelay:    "switch.valve_relay",
+	})
+
+	report := securityManager.runDrill("test")
+
+	if calls := mockHA.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls in read-only mode, got %d", len(calls))
+	}
+	for _, check := range report.Checks {
+		if !check.Skipped {
+			t.Errorf("Expected %s to be skipped in read-only mode, got %+v", check.Actuator, check)
+		}
+	}
+}
+
+// cancellingClock stops the manager during its first sleep, as Stop would mid-drill
+type cancellingClock struct {
+	*clock.MockClock
+	cancel context.CancelFunc
+}
+
+func (c cancellingClock) SleepContext(ctx context.Context, d time.Duration) error {
+	c.cancel()
+	return ctx.Err()
+}
+
+// TestSecurityManager_DrillRelayOffAfterStop tests that a drill stopped while
+// the valve relay is on still turns it back off
+func TestSecurityManager_DrillRelayOffAfterStop(t *testing.T) {
+	securityManager, mockHA := newDrillTestManager(t, false, DrillConfig{ValveRelay: "switch.valve_relay"})
+	securityManager.SetClock(cancellingClock{
+		MockClock: clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)),
+		cancel:    securityManager.cancel,
+	})
+
+	check := securityManager.drillClickValveRelay()
+	if check.Responded {
+		t.Errorf("Expected the stopped click to fail, got %+v", check)
+	}
+
+	calls := mockHA.GetServiceCalls()
+	if len(calls) == 0 || calls[len(calls)-1].Service != "turn_off" {
+		t.Errorf("Expected the relay to be turned off last, got %+v", calls)
+	}
+	if st, _ := mockHA.GetState(context.Background(), "switch.valve_relay"); st.State != "off" {
+		t.Errorf("Expected valve relay to be left off, got %s", st.State)
+	}
+}
+
+// TestSecurityManager_StartDrillInProgress tests that only one drill runs at a time
+func TestSecurityManager_StartDrillInProgress(t *testing.T) {
+	securityManager, _ := newDrillTestManager(t, true, DrillConfig{})
+
+	securityManager.mu.Lock()
+	securityManager.drillRunning = true
+	securityManager.mu.Unlock()
+
+	if err := securityManager.StartDrill(); !errors.Is(err, ErrDrillInProgress) {
+		t.Errorf("Expected ErrDrillInProgress, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// Periods with their own held-open thresholds
+const (
+	heldOpenDay    = "day"
+	heldOpenNight  = "night"  // dayPhase is winddown or night
+	heldOpenAsleep = "asleep" // isEveryoneAsleep
+)
+
+// Default held-open thresholds and escalation interval
+const (
+	defaultHeldOpenDayMinutes    = 10.0
+	defaultHeldOpenNightMinutes  = 5.0
+	defaultHeldOpenAsleepMinutes = 2.0
+	defaultHeldOpenStepInterval  = time.Minute
+)
+
+// Held-open alarm escalation steps, and the event recorded when the door closes
+const (
+	heldOpenStepTTS          = "tts"
+	heldOpenStepNotification = "notification"
+	heldOpenStepLights       = "lights"
+	heldOpenClosed           = "closed"
+)
+
+// heldOpenSteps are the escalation steps, in order
+var heldOpenSteps = []string{heldOpenStepTTS, heldOpenStepNotification, heldOpenStepLights}
+
+// heldOpenDoor tracks one open exterior door and its alarm escalation
+type heldOpenDoor struct {
+	config    HeldOpenDoorConfig
+	openedAt  time.Time
+	period    string
+	threshold time.Duration
+	step      int // Index of the next escalation step
+	timer     clock.Timer
+}
+
+// handleHeldOpenDoorChange starts the held-open timer when a door opens and
+// ends the alarm when it closes
+func (m *Manager) handleHeldOpenDoorChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+	door, ok := m.heldOpenDoorConfig(entityID)
+	if !ok {
+		return
+	}
+
+	if isDoorOpen(newState.State) {
+		m.startHeldOpen(door, m.clock.Now())
+		return
+	}
+	m.endHeldOpen(entityID)
+}
+
+// checkHeldOpenDoors starts the held-open timer for every configured door
+// that is currently open, counting from now
+func (m *Manager) checkHeldOpenDoors(trigger string) {
+	for _, door := range m.heldOpen.Doors {
+		st, err := m.haClient.GetState(m.ctx, door.EntityID)
+		if err != nil {
+			m.logger.Warn("Failed to read door state",
+				zap.String("entity_id", door.EntityID),
+				zap.String("trigger", trigger),
+				zap.Error(err))
+			continue
+		}
+		if st != nil && isDoorOpen(st.State) {
+			m.startHeldOpen(door, m.clock.Now())
+		}
+	}
+}
+
+// startHeldOpen schedules the first escalation step for a door that just
+// opened. The threshold is chosen for the period when the door opened.
+func (m *Manager) startHeldOpen(door HeldOpenDoorConfig, openedAt time.Time) {
+	period := m.heldOpenPeriod()
+	threshold := door.Threshold(period)
+
+	m.mu.Lock()
+	if _, ok := m.heldOpenDoors[door.EntityID]; ok {
+		m.mu.Unlock()
+		return
+	}
+	h := &heldOpenDoor{config: door, openedAt: openedAt, period: period, threshold: threshold}
+	h.timer = m.clock.AfterFunc(threshold, func() {
+		m.escalateHeldOpen(h)
+	})
+	m.heldOpenDoors[door.EntityID] = h
+	m.mu.Unlock()
+
+	m.logger.Debug("Exterior door opened, watching for it being held open",
+		zap.String("entity_id", door.EntityID),
+		zap.String("period", period),
+		zap.Duration("threshold", threshold))
+}
+
+// endHeldOpen stops watching a door that closed, recording the close if the
+// alarm had started
+func (m *Manager) endHeldOpen(entityID string) {
+	m.mu.Lock()
+	h, ok := m.heldOpenDoors[entityID]
+	alarmed := ok && h.step > 0
+	if ok {
+		h.timer.Stop()
+		delete(m.heldOpenDoors, entityID)
+	}
+	m.mu.Unlock()
+
+	if !alarmed {
+		return
+	}
+
+	now := m.clock.Now()
+	openFor := now.Sub(h.openedAt).Round(time.Second)
+	m.logger.Info("Held-open door closed",
+		zap.String("entity_id", entityID),
+		zap.Duration("open_for", openFor))
+	m.shadowTracker.RecordDoorHeldOpenEvent(m.heldOpenEvent(h, heldOpenClosed, now, true, fmt.Sprintf("open for %s", openFor)))
+}
+
+// escalateHeldOpen runs the next escalation step and schedules the one after it
+func (m *Manager) escalateHeldOpen(h *heldOpenDoor) {
+	m.mu.Lock()
+	if m.heldOpenDoors[h.config.EntityID] != h || h.step >= len(heldOpenSteps) {
+		m.mu.Unlock()
+		return
+	}
+	step := heldOpenSteps[h.step]
+	h.step++
+	if h.step < len(heldOpenSteps) {
+		h.timer = m.clock.AfterFunc(m.heldOpen.StepInterval(), func() {
+			m.escalateHeldOpen(h)
+		})
+	}
+	m.mu.Unlock()
+
+	now := m.clock.Now()
+	message := heldOpenMessage(h.config, now.Sub(h.openedAt))
+	m.logger.Info("Exterior door held open",
+		zap.String("entity_id", h.config.EntityID),
+		zap.String("step", step),
+		zap.String("period", h.period),
+		zap.String("message", message))
+
+	var err error
+	switch step {
+	case heldOpenStepTTS:
+		err = m.announceHeldOpen(message)
+	case heldOpenStepNotification:
+		err = m.notifyHeldOpen(message)
+	case heldOpenStepLights:
+		err = m.flashHeldOpenLights(h.config)
+	}
+
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+		if !errors.Is(err, errHeldOpenSkipped) {
+			m.logger.Error("Held-open alarm step failed",
+				zap.String("entity_id", h.config.EntityID),
+				zap.String("step", step),
+				zap.Error(err))
+		}
+	}
+
+	m.updateShadowInputsWithTrigger(h.config.EntityID)
+	m.shadowTracker.SnapshotInputsForAction()
+	m.shadowTracker.RecordDoorHeldOpenEvent(m.heldOpenEvent(h, step, now, err == nil, detail))
+}
+
+// errHeldOpenSkipped wraps the reason an escalation step was not attempted
+var errHeldOpenSkipped = errors.New("skipped")
+
+// announceHeldOpen speaks the reminder on the security speakers, skipping
+// do-not-disturb bedrooms
+func (m *Manager) announceHeldOpen(message string) error {
+	speakers, _ := m.dnd.Filter(m.ttsSpeakers())
+	if len(speakers) == 0 {
+		return fmt.Errorf("%w: all speakers are in do-not-disturb bedrooms", errHeldOpenSkipped)
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce held-open door",
+			zap.Strings("speakers", speakers),
+			zap.String("message", message))
+		return fmt.Errorf("%w: read-only mode", errHeldOpenSkipped)
+	}
+
+	err := m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Title:    "Door held open",
+		Message:  message,
+		Speakers: speakers,
+		Priority: tts.PriorityNormal,
+		PushSent: m.heldOpen.NotifyService != "", // The notification step follows
+	})
+	if errors.Is(err, tts.ErrQuietHours) {
+		return fmt.Errorf("%w: quiet hours", errHeldOpenSkipped)
+	}
+	return err
+}
+
+// notifyHeldOpen sends the reminder through the configured notify service
+func (m *Manager) notifyHeldOpen(message string) error {
+	if m.heldOpen.NotifyService == "" {
+		return fmt.Errorf("%w: no notify_service configured", errHeldOpenSkipped)
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send held-open door notification",
+			zap.String("service", string(m.heldOpen.NotifyService)),
+			zap.String("message", message))
+		return fmt.Errorf("%w: read-only mode", errHeldOpenSkipped)
+	}
+
+	return notifyrouter.SendPush(m.ctx, m.haClient, m.heldOpen.NotifyService, notifyrouter.Push{
+		Title:   "Door held open",
+		Message: message,
+	})
+}
+
+// flashHeldOpenLights flashes the door's lights (or the doorbell lights) twice
+func (m *Manager) flashHeldOpenLights(door HeldOpenDoorConfig) error {
+	lights := door.Lights
+	if len(lights) == 0 {
+		lights = m.expandEntities(doorbellLights)
+	}
+	if len(lights) == 0 {
+		return fmt.Errorf("%w: no lights configured", errHeldOpenSkipped)
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would flash lights for held-open door", zap.Strings("lights", lights))
+		return fmt.Errorf("%w: read-only mode", errHeldOpenSkipped)
+	}
+
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			if err := m.clock.SleepContext(m.ctx, DoorbellFlashDelay); err != nil {
+				return err
+			}
+		}
+		for _, data := range m.kid.FlashCalls(lights) {
+			if err := m.haClient.CallService(m.ctx, "light", "turn_on", data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stopHeldOpenTimers cancels every pending escalation and forgets open doors
+func (m *Manager) stopHeldOpenTimers() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for entityID, h := range m.heldOpenDoors {
+		h.timer.Stop()
+		delete(m.heldOpenDoors, entityID)
+	}
+}
+
+// heldOpenPeriod returns which threshold applies now
+func (m *Manager) heldOpenPeriod() string {
+	if asleep, err := m.stateManager.GetBool("isEveryoneAsleep"); err == nil && asleep {
+		return heldOpenAsleep
+	}
+	if dayPhase, err := m.stateManager.GetString("dayPhase"); err == nil && (dayPhase == "winddown" || dayPhase == "night") {
+		return heldOpenNight
+	}
+	return heldOpenDay
+}
+
+// heldOpenDoorConfig returns the held-open settings for a door
+func (m *Manager) heldOpenDoorConfig(entityID string) (HeldOpenDoorConfig, bool) {
+	for _, door := range m.heldOpen.Doors {
+		if door.EntityID == entityID {
+			return door, true
+		}
+	}
+	return HeldOpenDoorConfig{}, false
+}
+
+// heldOpenEvent builds the shadow state record for an alarm event
+func (m *Manager) heldOpenEvent(h *heldOpenDoor, event string, at time.Time, sent bool, detail string) shadowstate.DoorHeldOpenEvent {
+	return shadowstate.DoorHeldOpenEvent{
+		Timestamp:        at,
+		EntityID:         h.config.EntityID,
+		Name:             h.config.Name,
+		Event:            event,
+		Period:           h.period,
+		OpenedAt:         h.openedAt,
+		ThresholdSeconds: int(h.threshold / time.Second),
+		Sent:             sent,
+		Detail:           detail,
+	}
+}
+
+// heldOpenMessage builds a reminder such as "Back door has been open for 5 minutes"
+func heldOpenMessage(door HeldOpenDoorConfig, openFor time.Duration) string {
+	name := door.Name
+	if name == "" {
+		name = "An exterior door"
+	}
+	minutes := int(openFor.Round(time.Minute) / time.Minute)
+	if minutes <= 1 {
+		return fmt.Sprintf("%s has been open for a minute", name)
+	}
+	return fmt.Sprintf("%s has been open for %d minutes", name, minutes)
+}
+
+// isDoorOpen reports whether a contact sensor or cover state means open
+func isDoorOpen(state string) bool {
+	switch state {
+	case "on", "open", "opening":
+		return true
+	default:
+		return false
+	}
+}
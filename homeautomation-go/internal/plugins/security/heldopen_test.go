@@ -0,0 +1,181 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newHeldOpenTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *clock.MockClock) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("binary_sensor.back_door", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	securityManager := NewManager(mockHA, stateManager, logger, readOnly, nil)
+	securityManager.SetClock(mockClock)
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{DoorHeldOpen: DoorHeldOpenConfig{
+		StepIntervalSeconds: 60,
+		NotifyService:       "notify.mobile_app_phone",
+		Doors: []HeldOpenDoorConfig{{
+			EntityID:      "binary_sensor.back_door",
+			Name:          "Back door",
+			DayMinutes:    10,
+			NightMinutes:  5,
+			AsleepMinutes: 2,
+			Lights:        []string{"light.porch"},
+		}},
+	}}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA, mockClock
+}
+
+func countServiceCalls(mockHA *ha.MockClient, domain, service string) int {
+	count := 0
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == domain && call.Service == service {
+			count++
+		}
+	}
+	return count
+}
+
+// TestSecurityManager_DoorHeldOpenEscalates tests the TTS, notification, light flash escalation
+func TestSecurityManager_DoorHeldOpenEscalates(t *testing.T) {
+	securityManager, mockHA, mockClock := newHeldOpenTestManager(t, false)
+
+	mockHA.SetState("binary_sensor.back_door", "on", nil)
+
+	mockClock.Advance(9 * time.Minute)
+	if len(mockHA.GetServiceCalls()) != 0 {
+		t.Fatalf("Expected no alarm before the day threshold, got %v", mockHA.GetServiceCalls())
+	}
+
+	mockClock.Advance(time.Minute)
+	if countServiceCalls(mockHA, "tts", "speak") != 1 {
+		t.Fatalf("Expected a TTS reminder at 10 minutes, got %v", mockHA.GetServiceCalls())
+	}
+	if countServiceCalls(mockHA, "notify", "mobile_app_phone") != 0 {
+		t.Error("Expected no notification before the second step")
+	}
+
+	mockClock.Advance(time.Minute)
+	calls := mockHA.GetServiceCalls()
+	last := calls[len(calls)-1]
+	if last.Domain != "notify" || last.Data["message"] != "Back door has been open for 11 minutes" {
+		t.Fatalf("Expected a notification at 11 minutes, got %+v", last)
+	}
+
+	mockClock.Advance(time.Minute)
+	if countServiceCalls(mockHA, "light", "turn_on") != 2 {
+		t.Fatalf("Expected two light flashes at 12 minutes, got %v", mockHA.GetServiceCalls())
+	}
+
+	// Escalation stops after the last step
+	mockHA.ClearServiceCalls()
+	mockClock.Advance(10 * time.Minute)
+	if len(mockHA.GetServiceCalls()) != 0 {
+		t.Errorf("Expected no further steps, got %v", mockHA.GetServiceCalls())
+	}
+
+	mockHA.SetState("binary_sensor.back_door", "off", nil)
+
+	events := securityManager.GetShadowState().Outputs.DoorHeldOpen
+	if len(events) != 4 {
+		t.Fatalf("Expected 3 steps and a close in the event history, got %+v", events)
+	}
+	for i, want := range []string{heldOpenStepTTS, heldOpenStepNotification, heldOpenStepLights, heldOpenClosed} {
+		if events[i].Event != want || !events[i].Sent {
+			t.Errorf("Event %d: expected sent %s, got %+v", i, want, events[i])
+		}
+	}
+	if events[0].Period != heldOpenDay || events[0].ThresholdSeconds != 600 {
+		t.Errorf("Expected the day threshold, got %+v", events[0])
+	}
+}
+
+// TestSecurityManager_DoorHeldOpenAsleepThreshold tests the shorter threshold while everyone is asleep
+func TestSecurityManager_DoorHeldOpenAsleepThreshold(t *testing.T) {
+	securityManager, mockHA, mockClock := newHeldOpenTestManager(t, false)
+	if err := securityManager.stateManager.SetBool("isEveryoneAsleep", true); err != nil {
+		t.Fatalf("Failed to set isEveryoneAsleep: %v", err)
+	}
+	mockHA.ClearServiceCalls()
+
+	mockHA.SetState("binary_sensor.back_door", "on", nil)
+	mockClock.Advance(2 * time.Minute)
+
+	if countServiceCalls(mockHA, "tts", "speak") != 1 {
+		t.Fatalf("Expected a TTS reminder at 2 minutes while asleep, got %v", mockHA.GetServiceCalls())
+	}
+	events := securityManager.GetShadowState().Outputs.DoorHeldOpen
+	if len(events) != 1 || events[0].Period != heldOpenAsleep {
+		t.Errorf("Expected one asleep event, got %+v", events)
+	}
+}
+
+// TestSecurityManager_DoorHeldOpenNightThreshold tests the night threshold during winddown
+func TestSecurityManager_DoorHeldOpenNightThreshold(t *testing.T) {
+	securityManager, mockHA, mockClock := newHeldOpenTestManager(t, false)
+	if err := securityManager.stateManager.SetString("dayPhase", "winddown"); err != nil {
+		t.Fatalf("Failed to set dayPhase: %v", err)
+	}
+	mockHA.ClearServiceCalls()
+
+	mockHA.SetState("binary_sensor.back_door", "on", nil)
+	mockClock.Advance(5 * time.Minute)
+
+	if countServiceCalls(mockHA, "tts", "speak") != 1 {
+		t.Fatalf("Expected a TTS reminder at 5 minutes during winddown, got %v", mockHA.GetServiceCalls())
+	}
+}
+
+// TestSecurityManager_DoorClosedBeforeThreshold tests that closing the door cancels the alarm
+func TestSecurityManager_DoorClosedBeforeThreshold(t *testing.T) {
+	securityManager, mockHA, mockClock := newHeldOpenTestManager(t, false)
+
+	mockHA.SetState("binary_sensor.back_door", "on", nil)
+	mockClock.Advance(5 * time.Minute)
+	mockHA.SetState("binary_sensor.back_door", "off", nil)
+	mockClock.Advance(20 * time.Minute)
+
+	if len(mockHA.GetServiceCalls()) != 0 {
+		t.Errorf("Expected no alarm for a door closed in time, got %v", mockHA.GetServiceCalls())
+	}
+	if events := securityManager.GetShadowState().Outputs.DoorHeldOpen; len(events) != 0 {
+		t.Errorf("Expected no events, got %+v", events)
+	}
+}
+
+// TestSecurityManager_DoorHeldOpenReadOnly tests that read-only mode records steps without calling services
+func TestSecurityManager_DoorHeldOpenReadOnly(t *testing.T) {
+	securityManager, mockHA, mockClock := newHeldOpenTestManager(t, true)
+
+	mockHA.SetState("binary_sensor.back_door", "on", nil)
+	mockClock.Advance(10 * time.Minute)
+
+	if len(mockHA.GetServiceCalls()) != 0 {
+		t.Errorf("Expected no service calls in read-only mode, got %v", mockHA.GetServiceCalls())
+	}
+	events := securityManager.GetShadowState().Outputs.DoorHeldOpen
+	if len(events) != 1 || events[0].Sent || events[0].Detail != "skipped: read-only mode" {
+		t.Errorf("Expected an unsent TTS step, got %+v", events)
+	}
+}
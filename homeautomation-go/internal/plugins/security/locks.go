@@ -0,0 +1,395 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// defaultAutoLockDelay is how long a closed door stays unlocked
+const defaultAutoLockDelay = 5 * time.Minute
+
+// Lock states reported by Home Assistant
+const (
+	lockStateLocked    = "locked"
+	lockStateUnlocked  = "unlocked"
+	lockStateJammed    = "jammed"
+	lockStateLocking   = "locking"
+	lockStateUnlocking = "unlocking"
+)
+
+// lockEventCommand is the audit event for a lock command we sent; observed
+// changes are recorded under the lock's new state
+const lockEventCommand = "lock"
+
+// Sources of lock commands, and of changes with no known cause
+const (
+	lockSourceAutoLock = "auto_lock"
+	lockSourceLockdown = "lockdown"
+	lockSourceManual   = "manual"
+)
+
+// errLockSkipped wraps the reason a lock command was not sent
+var errLockSkipped = errors.New("skipped")
+
+// doorLock tracks one lock's last seen state and its auto-lock countdown
+type doorLock struct {
+	config     DoorLockConfig
+	state      string
+	doorOpen   bool
+	autoLockAt time.Time // Zero when no auto-lock is pending
+	timer      clock.Timer
+	// pendingSource is auto_lock or lockdown while our lock command waits
+	// for the lock to report its new state
+	pendingSource string
+}
+
+// startDoorLocks subscribes to the locks and their door sensors, and starts
+// the auto-lock countdown for unlocked doors that are closed
+func (m *Manager) startDoorLocks() error {
+	if len(m.doorLocks.Doors) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	m.locks = make(map[string]*doorLock, len(m.doorLocks.Doors))
+	for _, door := range m.doorLocks.Doors {
+		m.locks[door.Lock] = &doorLock{config: door}
+	}
+	m.mu.Unlock()
+
+	for _, door := range m.doorLocks.Doors {
+		haSub, err := m.haClient.SubscribeStateChanges(door.Lock, m.handleLockChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", door.Lock, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, haSub)
+
+		if door.DoorSensor != "" {
+			haSub, err = m.haClient.SubscribeStateChanges(door.DoorSensor, m.handleLockDoorChange)
+			if err != nil {
+				return fmt.Errorf("failed to subscribe to %s: %w", door.DoorSensor, err)
+			}
+			m.haSubscriptions = append(m.haSubscriptions, haSub)
+		}
+	}
+
+	m.refreshDoorLocks("startup")
+	return nil
+}
+
+// refreshDoorLocks re-reads every lock and door sensor, and starts the
+// auto-lock countdown for unlocked doors that are closed and not already
+// counting down
+func (m *Manager) refreshDoorLocks(trigger string) {
+	for _, door := range m.doorLocks.Doors {
+		lockState, err := m.haClient.GetState(m.ctx, door.Lock)
+		if err != nil {
+			m.logger.Warn("Failed to read lock state",
+				zap.String("entity_id", door.Lock),
+				zap.String("trigger", trigger),
+				zap.Error(err))
+			continue
+		}
+		doorOpen := false
+		if door.DoorSensor != "" {
+			if st, err := m.haClient.GetState(m.ctx, door.DoorSensor); err == nil && st != nil {
+				doorOpen = isDoorOpen(st.State)
+			}
+		}
+
+		m.mu.Lock()
+		l, ok := m.locks[door.Lock]
+		if ok && lockState != nil {
+			l.state = lockState.State
+			l.doorOpen = doorOpen
+			if m.shouldAutoLockLocked(l) && l.timer == nil {
+				m.scheduleAutoLockLocked(l)
+			}
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	locks := m.lockStatusesLocked()
+	m.mu.Unlock()
+	m.shadowTracker.UpdateDoorLocks(locks)
+}
+
+// GetDoorLocks returns each lock's status and the lock audit trail
+func (m *Manager) GetDoorLocks() shadowstate.DoorLocksState {
+	return m.shadowTracker.GetState().Outputs.DoorLocks
+}
+
+// handleLockChange records a lock changing state, and starts the auto-lock
+// countdown when a closed door is unlocked
+func (m *Manager) handleLockChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+
+	m.mu.Lock()
+	l, ok := m.locks[entityID]
+	if !ok || newState.State == l.state {
+		m.mu.Unlock()
+		return
+	}
+	l.state = newState.State
+
+	switch newState.State {
+	case lockStateUnlocked:
+		if m.shouldAutoLockLocked(l) {
+			m.scheduleAutoLockLocked(l)
+		}
+	case lockStateLocked:
+		m.stopAutoLockLocked(l)
+	}
+
+	if newState.State == lockStateLocking || newState.State == lockStateUnlocking {
+		locks := m.lockStatusesLocked()
+		m.mu.Unlock()
+		m.shadowTracker.UpdateDoorLocks(locks)
+		return
+	}
+
+	source := l.pendingSource
+	if newState.State == lockStateLocked || newState.State == lockStateJammed {
+		l.pendingSource = ""
+	}
+	if source == "" || newState.State == lockStateUnlocked {
+		source = lockChangeSource(newState)
+	}
+	locks := m.lockStatusesLocked()
+	m.mu.Unlock()
+
+	m.logger.Info("Lock changed state",
+		zap.String("entity_id", entityID),
+		zap.String("state", newState.State),
+		zap.String("source", source))
+	m.recordLockEvent(locks, shadowstate.LockEvent{
+		Timestamp: m.clock.Now(),
+		EntityID:  entityID,
+		Name:      l.config.Name,
+		Event:     newState.State,
+		Source:    source,
+		Success:   newState.State != lockStateJammed,
+	}, entityID)
+}
+
+// handleLockDoorChange tracks whether a locked door is open, and restarts the
+// auto-lock countdown when an unlocked door closes
+func (m *Manager) handleLockDoorChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+	open := isDoorOpen(newState.State)
+
+	m.mu.Lock()
+	changed := false
+	for _, l := range m.locks {
+		if l.config.DoorSensor != entityID || l.doorOpen == open {
+			continue
+		}
+		l.doorOpen = open
+		changed = true
+		if !open && m.shouldAutoLockLocked(l) {
+			m.scheduleAutoLockLocked(l)
+		}
+	}
+	if !changed {
+		m.mu.Unlock()
+		return
+	}
+	locks := m.lockStatusesLocked()
+	m.mu.Unlock()
+
+	m.shadowTracker.UpdateDoorLocks(locks)
+}
+
+// autoLock locks a door whose countdown ran out, unless the door is open
+func (m *Manager) autoLock(l *doorLock) {
+	m.mu.Lock()
+	if m.locks[l.config.Lock] != l || l.timer == nil {
+		m.mu.Unlock()
+		return
+	}
+	l.timer = nil
+	l.autoLockAt = time.Time{}
+	m.mu.Unlock()
+
+	err := m.lockDoor(l, lockSourceAutoLock)
+	m.recordLockCommand(l, lockSourceAutoLock, err)
+}
+
+// lockAllDoors locks every door that is not already locked, for lockdown
+func (m *Manager) lockAllDoors(trigger string) {
+	m.mu.Lock()
+	var unlocked []*doorLock
+	for _, door := range m.doorLocks.Doors {
+		l, ok := m.locks[door.Lock]
+		if !ok || l.state == lockStateLocked || l.state == lockStateLocking {
+			continue
+		}
+		m.stopAutoLockLocked(l)
+		unlocked = append(unlocked, l)
+	}
+	m.mu.Unlock()
+
+	for _, l := range unlocked {
+		m.logger.Info("Locking door for lockdown",
+			zap.String("entity_id", l.config.Lock),
+			zap.String("trigger", trigger))
+		err := m.lockDoor(l, lockSourceLockdown)
+		m.recordLockCommand(l, lockSourceLockdown, err)
+	}
+}
+
+// lockDoor sends the lock command, refusing while the door is open. The
+// door sensor is read from Home Assistant rather than trusting the last seen
+// state, and a door that can't be read is not locked.
+func (m *Manager) lockDoor(l *doorLock, source string) error {
+	if sensor := l.config.DoorSensor; sensor != "" {
+		st, err := m.haClient.GetState(m.ctx, sensor)
+		if err != nil {
+			return fmt.Errorf("%w: failed to read %s: %v", errLockSkipped, sensor, err)
+		}
+		if st != nil && isDoorOpen(st.State) {
+			return fmt.Errorf("%w: door is open", errLockSkipped)
+		}
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would lock door",
+			zap.String("entity_id", l.config.Lock),
+			zap.String("source", source))
+		return fmt.Errorf("%w: read-only mode", errLockSkipped)
+	}
+
+	m.mu.Lock()
+	l.pendingSource = source
+	m.mu.Unlock()
+
+	err := m.haClient.CallService(m.ctx, "lock", "lock", map[string]interface{}{
+		"entity_id": l.config.Lock,
+	})
+	if err != nil {
+		m.mu.Lock()
+		l.pendingSource = ""
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// recordLockCommand records a lock command and whether it was sent
+func (m *Manager) recordLockCommand(l *doorLock, source string, err error) {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+		if errors.Is(err, errLockSkipped) {
+			m.logger.Warn("Lock command not sent",
+				zap.String("entity_id", l.config.Lock),
+				zap.String("source", source),
+				zap.String("reason", detail))
+		} else {
+			m.logger.Error("Failed to lock door",
+				zap.String("entity_id", l.config.Lock),
+				zap.String("source", source),
+				zap.Error(err))
+		}
+	}
+
+	m.mu.Lock()
+	locks := m.lockStatusesLocked()
+	m.mu.Unlock()
+
+	m.recordLockEvent(locks, shadowstate.LockEvent{
+		Timestamp: m.clock.Now(),
+		EntityID:  l.config.Lock,
+		Name:      l.config.Name,
+		Event:     lockEventCommand,
+		Source:    source,
+		Success:   err == nil,
+		Detail:    detail,
+	}, l.config.Lock)
+}
+
+// recordLockEvent adds an event to the lock audit trail in shadow state
+func (m *Manager) recordLockEvent(locks []shadowstate.DoorLockStatus, event shadowstate.LockEvent, trigger string) {
+	m.updateShadowInputsWithTrigger(trigger)
+	m.shadowTracker.SnapshotInputsForAction()
+	m.shadowTracker.RecordLockEvent(locks, event)
+}
+
+// stopAutoLockTimers cancels every pending auto-lock
+func (m *Manager) stopAutoLockTimers() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, l := range m.locks {
+		m.stopAutoLockLocked(l)
+	}
+}
+
+// shouldAutoLockLocked reports whether a lock's countdown should run: it is
+// unlocked, auto-locks, and its door is closed. Caller must hold mu.
+func (m *Manager) shouldAutoLockLocked(l *doorLock) bool {
+	return l.state == lockStateUnlocked && !l.config.LockdownOnly && !l.doorOpen
+}
+
+// scheduleAutoLockLocked (re)starts a lock's auto-lock countdown. Caller
+// must hold mu.
+func (m *Manager) scheduleAutoLockLocked(l *doorLock) {
+	m.stopAutoLockLocked(l)
+	delay := m.doorLocks.AutoLockDelay()
+	l.autoLockAt = m.clock.Now().Add(delay)
+	l.timer = m.clock.AfterFunc(delay, func() {
+		m.autoLock(l)
+	})
+}
+
+// stopAutoLockLocked cancels a lock's auto-lock countdown. Caller must hold mu.
+func (m *Manager) stopAutoLockLocked(l *doorLock) {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.autoLockAt = time.Time{}
+}
+
+// lockStatusesLocked builds the shadow state for each lock, in config order.
+// Caller must hold mu.
+func (m *Manager) lockStatusesLocked() []shadowstate.DoorLockStatus {
+	statuses := make([]shadowstate.DoorLockStatus, 0, len(m.doorLocks.Doors))
+	for _, door := range m.doorLocks.Doors {
+		l, ok := m.locks[door.Lock]
+		if !ok {
+			continue
+		}
+		statuses = append(statuses, shadowstate.DoorLockStatus{
+			EntityID:   door.Lock,
+			Name:       door.Name,
+			State:      l.state,
+			DoorOpen:   l.doorOpen,
+			AutoLockAt: l.autoLockAt,
+		})
+	}
+	return statuses
+}
+
+// lockChangeSource names who changed a lock: the lock's changed_by attribute
+// (a keypad code or user slot on Z-Wave and Wi-Fi locks), else the Home
+// Assistant user, else manual
+func lockChangeSource(st *ha.State) string {
+	if changedBy, ok := st.Attributes["changed_by"].(string); ok && changedBy != "" {
+		return changedBy
+	}
+	if st.Context != nil && st.Context.UserID != "" {
+		return "user:" + st.Context.UserID
+	}
+	return lockSourceManual
+}
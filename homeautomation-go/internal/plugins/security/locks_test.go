@@ -0,0 +1,199 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newLocksTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *clock.MockClock) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("lock.front_door", "locked", nil)
+	mockHA.SetState("binary_sensor.front_door", "off", nil)
+	mockHA.SetState("lock.back_door", "locked", nil)
+	mockHA.SetState("binary_sensor.back_door", "off", nil)
+	mockHA.SetState("lock.shed", "unlocked", nil)
+	mockHA.SetState("input_boolean.lockdown", "off", nil)
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+	mockHA.SetState("input_boolean.everyone_asleep", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	securityManager := NewManager(mockHA, stateManager, logger, readOnly, nil)
+	securityManager.SetClock(mockClock)
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{DoorLocks: DoorLocksConfig{
+		AutoLockMinutes: 5,
+		Doors: []DoorLockConfig{
+			{Lock: "lock.front_door", Name: "Front door", DoorSensor: "binary_sensor.front_door"},
+			{Lock: "lock.back_door", Name: "Back door", DoorSensor: "binary_sensor.back_door"},
+			{Lock: "lock.shed", Name: "Shed", LockdownOnly: true},
+		},
+	}}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA, mockClock
+}
+
+// lockCalls returns the locks sent a lock command
+func lockCalls(mockHA *ha.MockClient) []string {
+	var locks []string
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "lock" && call.Service == "lock" {
+			locks = append(locks, call.Data["entity_id"].(string))
+		}
+	}
+	return locks
+}
+
+// lastLockEvent returns the most recent lock audit event
+func lastLockEvent(t *testing.T, securityManager *Manager) shadowstate.LockEvent {
+	t.Helper()
+	events := securityManager.GetDoorLocks().Events
+	if len(events) == 0 {
+		t.Fatal("Expected a lock event")
+	}
+	return events[len(events)-1]
+}
+
+// TestSecurityManager_AutoLockAfterDoorCloses tests that an unlocked door is
+// locked N minutes after it closes, and that the lock is credited to auto-lock
+func TestSecurityManager_AutoLockAfterDoorCloses(t *testing.T) {
+	securityManager, mockHA, mockClock := newLocksTestManager(t, false)
+
+	mockHA.SetState("lock.front_door", "unlocked", map[string]interface{}{"changed_by": "Keypad 2"})
+	event := lastLockEvent(t, securityManager)
+	if event.Event != lockStateUnlocked || event.Source != "Keypad 2" || event.Name != "Front door" {
+		t.Errorf("Expected the unlock by Keypad 2 to be audited, got %+v", event)
+	}
+
+	mockHA.SimulateStateChange("binary_sensor.front_door", "on")
+	mockClock.Advance(3 * time.Minute)
+	mockHA.SimulateStateChange("binary_sensor.front_door", "off")
+
+	// The countdown restarted when the door closed
+	mockClock.Advance(4 * time.Minute)
+	if locks := lockCalls(mockHA); len(locks) != 0 {
+		t.Fatalf("Expected no lock before 5 minutes closed, got %v", locks)
+	}
+	status := securityManager.GetDoorLocks().Locks[0]
+	if want := mockClock.Now().Add(time.Minute); !status.AutoLockAt.Equal(want) {
+		t.Errorf("Expected auto-lock at %v, got %v", want, status.AutoLockAt)
+	}
+
+	mockClock.Advance(time.Minute)
+	if locks := lockCalls(mockHA); len(locks) != 1 || locks[0] != "lock.front_door" {
+		t.Fatalf("Expected the front door to be locked, got %v", locks)
+	}
+	if event := lastLockEvent(t, securityManager); event.Event != lockEventCommand || event.Source != lockSourceAutoLock || !event.Success {
+		t.Errorf("Expected a successful auto-lock command, got %+v", event)
+	}
+
+	mockHA.SimulateStateChange("lock.front_door", "locked")
+	if event := lastLockEvent(t, securityManager); event.Event != lockStateLocked || event.Source != lockSourceAutoLock {
+		t.Errorf("Expected the lock to be credited to auto-lock, got %+v", event)
+	}
+	if status := securityManager.GetDoorLocks().Locks[0]; status.State != lockStateLocked || !status.AutoLockAt.IsZero() {
+		t.Errorf("Expected the front door locked with no auto-lock pending, got %+v", status)
+	}
+}
+
+// TestSecurityManager_AutoLockRefusedWhileOpen tests that auto-lock does not
+// throw the bolt while the door is open, and tries again once it closes
+func TestSecurityManager_AutoLockRefusedWhileOpen(t *testing.T) {
+	securityManager, mockHA, mockClock := newLocksTestManager(t, false)
+
+	mockHA.SimulateStateChange("lock.back_door", "unlocked")
+	mockClock.Advance(4 * time.Minute)
+	mockHA.SimulateStateChange("binary_sensor.back_door", "on")
+	mockClock.Advance(time.Minute)
+
+	if locks := lockCalls(mockHA); len(locks) != 0 {
+		t.Fatalf("Expected no lock while the door is open, got %v", locks)
+	}
+	event := lastLockEvent(t, securityManager)
+	if event.Event != lockEventCommand || event.Success || event.Detail != "skipped: door is open" {
+		t.Errorf("Expected a refused auto-lock, got %+v", event)
+	}
+
+	mockHA.SimulateStateChange("binary_sensor.back_door", "off")
+	mockClock.Advance(5 * time.Minute)
+	if locks := lockCalls(mockHA); len(locks) != 1 || locks[0] != "lock.back_door" {
+		t.Errorf("Expected the back door to be locked after it closed, got %v", locks)
+	}
+}
+
+// TestSecurityManager_LockdownLocksDoors tests that lockdown locks every
+// unlocked door, including lockdown-only ones, except doors that are open
+func TestSecurityManager_LockdownLocksDoors(t *testing.T) {
+	securityManager, mockHA, mockClock := newLocksTestManager(t, false)
+
+	// The shed is lockdown-only and never auto-locks
+	mockClock.Advance(10 * time.Minute)
+	if locks := lockCalls(mockHA); len(locks) != 0 {
+		t.Fatalf("Expected the lockdown-only shed not to auto-lock, got %v", locks)
+	}
+
+	mockHA.SimulateStateChange("lock.front_door", "unlocked")
+	mockHA.SimulateStateChange("lock.back_door", "unlocked")
+	mockHA.SimulateStateChange("binary_sensor.back_door", "on")
+	mockHA.ClearServiceCalls()
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+
+	locks := lockCalls(mockHA)
+	if len(locks) != 2 || locks[0] != "lock.front_door" || locks[1] != "lock.shed" {
+		t.Fatalf("Expected the front door and shed to be locked, got %v", locks)
+	}
+	var refused bool
+	for _, event := range securityManager.GetDoorLocks().Events {
+		if event.EntityID == "lock.back_door" && event.Source == lockSourceLockdown && !event.Success {
+			refused = true
+		}
+	}
+	if !refused {
+		t.Error("Expected the open back door to be refused on lockdown")
+	}
+
+	// The pending auto-lock was replaced by the lockdown command
+	mockHA.SimulateStateChange("lock.front_door", "locked")
+	mockClock.Advance(10 * time.Minute)
+	if n := len(lockCalls(mockHA)); n != 2 {
+		t.Errorf("Expected no further lock commands, got %d", n)
+	}
+}
+
+// TestSecurityManager_AutoLockReadOnly tests that read-only mode records the
+// auto-lock it would have sent
+func TestSecurityManager_AutoLockReadOnly(t *testing.T) {
+	securityManager, mockHA, mockClock := newLocksTestManager(t, true)
+
+	mockHA.SimulateStateChange("lock.front_door", "unlocked")
+	if source := lastLockEvent(t, securityManager).Source; source != lockSourceManual {
+		t.Errorf("Expected an unlock with no attribution to be manual, got %q", source)
+	}
+
+	mockClock.Advance(5 * time.Minute)
+	if locks := lockCalls(mockHA); len(locks) != 0 {
+		t.Errorf("Expected no lock command in read-only mode, got %v", locks)
+	}
+	if event := lastLockEvent(t, securityManager); event.Success || event.Detail != "skipped: read-only mode" {
+		t.Errorf("Expected a skipped auto-lock, got %+v", event)
+	}
+}
@@ -1,14 +1,23 @@
 package security
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"homeautomation/internal/clock"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/entities"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/focusmode"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/kidmode"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
 
 	"go.uber.org/zap"
 )
@@ -18,16 +27,25 @@ const (
 	// LockdownResetDelay is how long to wait before auto-resetting lockdown
 	LockdownResetDelay = 5 * time.Second
 
-	// DoorbellRateLimit is the minimum time between doorbell notifications
+	// DoorbellRateLimit is the default window for doorbell notifications
 	DoorbellRateLimit = 20 * time.Second
 
 	// DoorbellFlashDelay is the delay between light flashes for doorbell
 	DoorbellFlashDelay = 2 * time.Second
 
-	// VehicleArrivalRateLimit is the minimum time between vehicle arrival notifications
+	// VehicleArrivalRateLimit is the default window for vehicle arrival notifications
 	VehicleArrivalRateLimit = 20 * time.Second
+
+	// PersonDetectionRateLimit is the default window for person detection notifications
+	PersonDetectionRateLimit = 60 * time.Second
 )
 
+// lockdownSnapshotSceneID is the temporary HA scene holding lighting captured before the lockdown pulse
+const lockdownSnapshotSceneID = "security_lockdown_snapshot"
+
+// doorbellLights flash when the doorbell rings
+var doorbellLights = []string{entitygroups.Group(entitygroups.DoorbellLights)}
+
 // Manager handles security-related automation
 type Manager struct {
 	haClient      ha.HAClient
@@ -35,6 +53,7 @@ type Manager struct {
 	logger        *zap.Logger
 	readOnly      bool
 	clock         clock.Clock
+	entities      *entities.Registry // Doorbell, garage and lockdown cue entities; nil uses the defaults
 	shadowTracker *shadowstate.SecurityTracker
 
 	// Automatic shadow state input tracking
@@ -46,19 +65,105 @@ type Manager struct {
 	haSubscriptions    []ha.Subscription
 	stateSubscriptions []state.Subscription
 
-	// Rate limiting for notifications
-	lastDoorbellNotification       time.Time
-	lastVehicleArrivalNotification time.Time
-	mu                             sync.Mutex
+	// Rate limiting for notifications (protected by mu)
+	doorbellLimiter *rateLimiter
+	vehicleLimiter  *rateLimiter
+	personLimiter   *rateLimiter
+	mu              sync.Mutex
+
+	// Lockdown cue tracking (protected by mu)
+	lockdownCuesActive    bool
+	lockdownSnapshotTaken bool
+
+	// Speakers in do-not-disturb bedrooms are skipped by TTS notifications, nil if not configured
+	dnd *donotdisturb.Guard
+	// Office speakers are skipped by non-critical TTS during focus mode, nil if not configured
+	focus *focusmode.Guard
+	// Doorbell and held-open flashes glow dimly in rooms in kid mode, nil if not configured
+	kid *kidmode.Guard
+	// Queues TTS notifications and picks their speakers, nil speaks immediately on the defaults
+	announcer *tts.Announcer
+
+	// Indoor camera privacy switches, empty to disable camera privacy automation
+	privacySwitches []string
+	// Last commanded privacy mode, nil until first set (protected by mu)
+	cameraPrivacy *bool
+	// Last seen presence values, so HA echoes don't re-apply privacy mode (protected by mu)
+	lastOwnerHome      bool
+	lastEveryoneAsleep bool
+
+	// Drill actuators, and whether a drill is running (protected by mu)
+	drill        DrillConfig
+	drillRunning bool
+
+	// Exterior doors watched for being held open, and the open ones keyed by
+	// entity ID (protected by mu)
+	heldOpen      DoorHeldOpenConfig
+	heldOpenDoors map[string]*heldOpenDoor
+
+	// Delivery window triggers, the quiet rings awaiting the summary, and the
+	// summary timer (protected by mu)
+	delivery              DeliveryWindowConfig
+	deliveryToggle        bool
+	deliveryCalendarEvent string // Title of the delivery event in progress, empty if none
+	pendingDeliveries     int
+	deliverySummaryAt     time.Time
+	deliveryTimer         clock.Timer
+	// Timezone for the delivery summary time and snapshot file names
+	timezone *time.Location
+
+	// Person detection cameras, and whether each sensor sees someone, keyed
+	// by sensor entity ID (protected by mu)
+	person      PersonDetectionConfig
+	personsSeen map[string]bool
+
+	// Alarm settings, mode and status, and the exit delay, entry delay or
+	// siren timer (protected by mu)
+	alarm            AlarmConfig
+	alarmMode        string
+	alarmStatus      string
+	alarmSince       time.Time
+	alarmDelayEndsAt time.Time
+	alarmTriggeredBy string
+	alarmSirenOn     bool
+	alarmTimer       clock.Timer
+	// Last seen presence values, so HA echoes don't re-arm or disarm (protected by mu)
+	alarmLastAsleep     bool
+	alarmLastAnyoneHome bool
+
+	// Door locks, and each lock's last seen state and auto-lock countdown
+	// keyed by lock entity ID (protected by mu)
+	doorLocks DoorLocksConfig
+	locks     map[string]*doorLock
+
+	// Rooms pulsed red and speakers paused when lockdown activates
+	lockdown LockdownConfig
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests and delays
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Lockdown resets, light flashes and drills in progress; Stop waits for them
+	background sync.WaitGroup
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new Security manager
 func NewManager(haClient ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
 	const pluginName = "security"
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
 	m := &Manager{
+		ctx:                ctx,
+		cancel:             cancel,
 		haClient:           haClient,
 		stateManager:       stateManager,
-		logger:             logger.Named("security"),
+		logger:             recorder.Logger(logger.Named("security")),
+		health:             recorder,
 		readOnly:           readOnly,
 		clock:              clock.NewRealClock(),
 		shadowTracker:      shadowstate.NewSecurityTracker(),
@@ -66,6 +171,15 @@ func NewManager(haClient ha.HAClient, stateManager *state.Manager, logger *zap.L
 		registry:           registry,
 		haSubscriptions:    make([]ha.Subscription, 0),
 		stateSubscriptions: make([]state.Subscription, 0),
+		doorbellLimiter:    newRateLimiter(RateLimitPolicy{}, DoorbellRateLimit),
+		vehicleLimiter:     newRateLimiter(RateLimitPolicy{}, VehicleArrivalRateLimit),
+		personLimiter:      newRateLimiter(RateLimitPolicy{}, PersonDetectionRateLimit),
+		heldOpenDoors:      make(map[string]*heldOpenDoor),
+		timezone:           time.UTC,
+		personsSeen:        make(map[string]bool),
+		alarmMode:          AlarmDisarmed,
+		alarmStatus:        alarmStatusDisarmed,
+		locks:              make(map[string]*doorLock),
 	}
 
 	// Create input capture helper if registry is provided
@@ -81,8 +195,63 @@ func (m *Manager) SetClock(c clock.Clock) {
 	m.clock = c
 }
 
+// SetConfig applies optional settings from security_config.yaml. Must be called before Start.
+func (m *Manager) SetConfig(config *SecurityConfig) {
+	m.privacySwitches = append([]string(nil), config.Security.CameraPrivacy.PrivacySwitches...)
+	m.doorbellLimiter = newRateLimiter(config.Security.RateLimits.Doorbell, DoorbellRateLimit)
+	m.vehicleLimiter = newRateLimiter(config.Security.RateLimits.VehicleArrival, VehicleArrivalRateLimit)
+	m.personLimiter = newRateLimiter(config.Security.RateLimits.PersonDetection, PersonDetectionRateLimit)
+	m.drill = config.Security.Drill
+	m.heldOpen = config.Security.DoorHeldOpen
+	m.delivery = config.Security.DeliveryWindow
+	m.person = config.Security.PersonDetection
+	m.alarm = config.Security.Alarm
+	m.doorLocks = config.Security.DoorLocks
+	m.lockdown = config.Security.Lockdown
+}
+
+// SetEntities sets the registry the doorbell, garage and drill
+// entities are looked up in. Must be called before Start.
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
+// SetTimezone sets the timezone used for the delivery summary time
+func (m *Manager) SetTimezone(tz *time.Location) {
+	if tz != nil {
+		m.timezone = tz
+	}
+}
+
+// SetDoNotDisturb sets the per-bedroom do-not-disturb guard used to skip
+// TTS notifications on speakers in bedrooms with the toggle on
+func (m *Manager) SetDoNotDisturb(guard *donotdisturb.Guard) {
+	m.dnd = guard
+}
+
+// SetFocusMode sets the office focus mode guard used to skip non-critical
+// TTS notifications on the office speakers while focus mode is on
+func (m *Manager) SetFocusMode(guard *focusmode.Guard) {
+	m.focus = guard
+}
+
+// SetKidMode sets the kid mode guard used to soften light flashes in rooms
+// in kid mode. The lockdown alert is left as it is.
+func (m *Manager) SetKidMode(guard *kidmode.Guard) {
+	m.kid = guard
+}
+
+// SetAnnouncer sets the announcer that speaks TTS notifications
+func (m *Manager) SetAnnouncer(announcer *tts.Announcer) {
+	m.announcer = announcer
+}
+
 // Start begins monitoring security-related events
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
+	// Locks, flashes and drills run under ctx until Stop cancels it
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	m.logger.Info("Starting Security Manager")
 
 	// Register subscriptions with the registry for automatic input tracking
@@ -92,15 +261,43 @@ func (m *Manager) Start() error {
 		m.registry.RegisterStateSubscription(m.pluginName, "isAnyoneHome")
 		m.registry.RegisterStateSubscription(m.pluginName, "didOwnerJustReturnHome")
 		m.registry.RegisterStateSubscription(m.pluginName, "isExpectingSomeone")
+		if len(m.privacySwitches) > 0 {
+			m.registry.RegisterStateSubscription(m.pluginName, "isAnyOwnerHome")
+		}
 
 		// HA subscriptions
-		m.registry.RegisterHASubscription(m.pluginName, "input_button.doorbell")
-		m.registry.RegisterHASubscription(m.pluginName, "input_button.vehicle_arriving")
+		m.registry.RegisterHASubscription(m.pluginName, m.entities.Doorbell())
+		m.registry.RegisterHASubscription(m.pluginName, m.entities.VehicleArriving())
 		m.registry.RegisterHASubscription(m.pluginName, "input_boolean.lockdown")
+		for _, door := range m.heldOpen.Doors {
+			m.registry.RegisterHASubscription(m.pluginName, door.EntityID)
+		}
+		if m.delivery.ToggleVariable != "" {
+			m.registry.RegisterStateSubscription(m.pluginName, m.delivery.ToggleVariable)
+		}
+		if m.delivery.CalendarEntity != "" {
+			m.registry.RegisterHASubscription(m.pluginName, m.delivery.CalendarEntity)
+		}
+		for _, camera := range m.person.Cameras {
+			m.registry.RegisterHASubscription(m.pluginName, camera.Sensor)
+		}
+		for _, sensor := range m.alarm.Sensors {
+			m.registry.RegisterHASubscription(m.pluginName, sensor.EntityID)
+		}
+		if m.alarm.Enabled() && m.alarm.ModeEntity != "" {
+			m.registry.RegisterHASubscription(m.pluginName, m.alarm.ModeEntity)
+		}
+		for _, door := range m.doorLocks.Doors {
+			m.registry.RegisterHASubscription(m.pluginName, door.Lock)
+			if door.DoorSensor != "" {
+				m.registry.RegisterHASubscription(m.pluginName, door.DoorSensor)
+			}
+		}
 	}
 
-	// Initialize shadow state with current input values
+	// Initialize shadow state with current input values and rate limit policies
 	m.updateShadowInputs()
+	m.updateRateLimitShadow()
 
 	// 1. Subscribe to sleep/home states for lockdown activation
 	sub, err := m.stateManager.Subscribe("isEveryoneAsleep", m.handleEveryoneAsleepChange)
@@ -123,14 +320,14 @@ func (m *Manager) Start() error {
 	m.stateSubscriptions = append(m.stateSubscriptions, sub)
 
 	// 3. Subscribe to doorbell button
-	haSub, err := m.haClient.SubscribeStateChanges("input_button.doorbell", m.handleDoorbellPressed)
+	haSub, err := m.haClient.SubscribeStateChanges(m.entities.Doorbell(), m.handleDoorbellPressed)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to doorbell: %w", err)
 	}
 	m.haSubscriptions = append(m.haSubscriptions, haSub)
 
 	// 4. Subscribe to vehicle arriving button
-	haSub, err = m.haClient.SubscribeStateChanges("input_button.vehicle_arriving", m.handleVehicleArriving)
+	haSub, err = m.haClient.SubscribeStateChanges(m.entities.VehicleArriving(), m.handleVehicleArriving)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to vehicle_arriving: %w", err)
 	}
@@ -143,14 +340,68 @@ func (m *Manager) Start() error {
 	}
 	m.haSubscriptions = append(m.haSubscriptions, haSub)
 
+	// 6. Subscribe to owner presence for indoor camera privacy mode
+	if len(m.privacySwitches) > 0 {
+		m.mu.Lock()
+		if ownerHome, err := m.stateManager.GetBool("isAnyOwnerHome"); err == nil {
+			m.lastOwnerHome = ownerHome
+		}
+		if asleep, err := m.stateManager.GetBool("isEveryoneAsleep"); err == nil {
+			m.lastEveryoneAsleep = asleep
+		}
+		m.mu.Unlock()
+
+		sub, err = m.stateManager.Subscribe("isAnyOwnerHome", m.handleAnyOwnerHomeChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to isAnyOwnerHome: %w", err)
+		}
+		m.stateSubscriptions = append(m.stateSubscriptions, sub)
+
+		m.applyCameraPrivacy("startup", true)
+	}
+
+	// 7. Subscribe to exterior doors for the held-open alarm
+	for _, door := range m.heldOpen.Doors {
+		haSub, err = m.haClient.SubscribeStateChanges(door.EntityID, m.handleHeldOpenDoorChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", door.EntityID, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, haSub)
+	}
+	m.checkHeldOpenDoors("startup")
+
+	// 8. Subscribe to the delivery window toggle and calendar
+	if err := m.startDeliveryWindow(); err != nil {
+		return err
+	}
+
+	// 9. Subscribe to the camera person sensors
+	if err := m.startPersonDetection(); err != nil {
+		return err
+	}
+
+	// 10. Subscribe to the alarm sensors and mode entity
+	if err := m.startAlarm(); err != nil {
+		return err
+	}
+
+	// 11. Subscribe to the door locks and their door sensors for auto-lock
+	if err := m.startDoorLocks(); err != nil {
+		return err
+	}
+
+	m.health.Started(len(m.haSubscriptions) + len(m.stateSubscriptions))
 	m.logger.Info("Security Manager started successfully")
 	return nil
 }
 
 // Stop stops the Security Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping Security Manager")
 
+	m.cancel()
+
 	// Unsubscribe from all HA subscriptions
 	for _, sub := range m.haSubscriptions {
 		sub.Unsubscribe()
@@ -163,9 +414,22 @@ func (m *Manager) Stop() {
 	}
 	m.stateSubscriptions = nil
 
+	m.stopHeldOpenTimers()
+	m.stopDeliveryTimer()
+	m.stopAlarmTimer()
+	m.stopAutoLockTimers()
+
+	// Delayed actions end once they see the cancelled context
+	m.background.Wait()
+
 	m.logger.Info("Security Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handleEveryoneAsleepChange activates lockdown when everyone is asleep
 func (m *Manager) handleEveryoneAsleepChange(key string, oldValue, newValue interface{}) {
 	// Update shadow state current inputs immediately
@@ -181,6 +445,105 @@ func (m *Manager) handleEveryoneAsleepChange(key string, oldValue, newValue inte
 		m.logger.Info("Everyone is asleep, activating lockdown")
 		m.activateLockdown("Everyone is asleep", key)
 	}
+	m.autoArmAlarmForSleep(asleep, key)
+
+	m.mu.Lock()
+	changed := asleep != m.lastEveryoneAsleep
+	m.lastEveryoneAsleep = asleep
+	m.mu.Unlock()
+	if changed {
+		m.applyCameraPrivacy(key, false)
+	}
+}
+
+// handleAnyOwnerHomeChange toggles indoor camera privacy mode as owners come and go.
+// HA echoes our own writes with old == new, so compare against the last seen
+// value rather than oldValue.
+func (m *Manager) handleAnyOwnerHomeChange(key string, oldValue, newValue interface{}) {
+	// Update shadow state current inputs immediately
+	m.updateShadowInputs()
+
+	ownerHome, ok := newValue.(bool)
+	if !ok {
+		m.logger.Error("Invalid type for isAnyOwnerHome", zap.Any("value", newValue))
+		return
+	}
+
+	m.mu.Lock()
+	changed := ownerHome != m.lastOwnerHome
+	m.lastOwnerHome = ownerHome
+	m.mu.Unlock()
+	if changed {
+		m.applyCameraPrivacy(key, false)
+	}
+}
+
+// applyCameraPrivacy enables privacy mode while an owner is home and awake,
+// and disables it (re-enabling recording) otherwise
+func (m *Manager) applyCameraPrivacy(trigger string, force bool) {
+	if len(m.privacySwitches) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	ownerHome, asleep := m.lastOwnerHome, m.lastEveryoneAsleep
+	m.mu.Unlock()
+
+	switch {
+	case ownerHome && !asleep:
+		m.setCameraPrivacy(true, "Owner is home", trigger, force)
+	case !ownerHome:
+		m.setCameraPrivacy(false, "No owner is home", trigger, force)
+	default:
+		m.setCameraPrivacy(false, "Everyone is asleep", trigger, force)
+	}
+}
+
+// setCameraPrivacy switches indoor camera privacy mode on or off, skipping the
+// call if that mode was already commanded unless force is set
+func (m *Manager) setCameraPrivacy(enabled bool, reason string, trigger string, force bool) {
+	if len(m.privacySwitches) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if !force && m.cameraPrivacy != nil && *m.cameraPrivacy == enabled {
+		m.mu.Unlock()
+		return
+	}
+	m.cameraPrivacy = &enabled
+	m.mu.Unlock()
+
+	// Record transition in shadow state before executing
+	m.updateShadowInputsWithTrigger(trigger)
+	m.shadowTracker.SnapshotInputsForAction()
+	m.shadowTracker.RecordCameraPrivacy(enabled, reason, trigger, m.privacySwitches)
+
+	service := "turn_off"
+	if enabled {
+		service = "turn_on"
+	}
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set indoor camera privacy mode",
+			zap.Bool("enabled", enabled),
+			zap.Strings("switches", m.privacySwitches),
+			zap.String("reason", reason))
+		return
+	}
+
+	if err := m.haClient.CallService(m.ctx, "switch", service, map[string]interface{}{
+		"entity_id": m.privacySwitches,
+	}); err != nil {
+		m.logger.Error("Failed to set indoor camera privacy mode",
+			zap.Bool("enabled", enabled),
+			zap.Error(err))
+		return
+	}
+
+	m.logger.Info("Indoor camera privacy mode updated",
+		zap.Bool("enabled", enabled),
+		zap.String("reason", reason))
 }
 
 // handleAnyoneHomeChange activates lockdown when no one is home
@@ -198,6 +561,7 @@ func (m *Manager) handleAnyoneHomeChange(key string, oldValue, newValue interfac
 		m.logger.Info("No one is home, activating lockdown")
 		m.activateLockdown("No one is home", key)
 	}
+	m.autoArmAlarmForPresence(anyoneHome, key)
 }
 
 // activateLockdown turns on the lockdown input_boolean
@@ -210,7 +574,7 @@ func (m *Manager) activateLockdown(reason string, trigger string) {
 		return
 	}
 
-	if err := m.haClient.CallService("input_boolean", "turn_on", map[string]interface{}{
+	if err := m.haClient.CallService(m.ctx, "input_boolean", "turn_on", map[string]interface{}{
 		"entity_id": "input_boolean.lockdown",
 	}); err != nil {
 		m.logger.Error("Failed to activate lockdown", zap.Error(err))
@@ -219,14 +583,30 @@ func (m *Manager) activateLockdown(reason string, trigger string) {
 	}
 }
 
-// handleLockdownActivated auto-resets lockdown after 5 seconds
+// handleLockdownActivated applies lockdown cues, locks the doors, and auto-resets lockdown after 5 seconds.
+// When lockdown clears, the cues are cleared and lighting is restored.
 func (m *Manager) handleLockdownActivated(entity string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+
+	if newState.State == "off" {
+		m.clearLockdownCues()
+		return
+	}
+
 	if newState.State == "on" {
 		m.logger.Info("Lockdown activated, will auto-reset in 5 seconds")
 
+		m.applyLockdownCues()
+		m.setCameraPrivacy(false, "Lockdown activated", "lockdown", false)
+		m.lockAllDoors(entity)
+
 		// Wait 5 seconds, then reset
-		go func() {
-			m.clock.Sleep(LockdownResetDelay)
+		m.goBackground(func() {
+			if m.clock.SleepContext(m.ctx, LockdownResetDelay) != nil {
+				return
+			}
 
 			// Record deactivation in shadow state
 			m.recordLockdownAction(false, "Auto-reset after 5 seconds", "lockdown_timer")
@@ -236,17 +616,152 @@ func (m *Manager) handleLockdownActivated(entity string, oldState, newState *ha.
 				return
 			}
 
-			if err := m.haClient.CallService("input_boolean", "turn_off", map[string]interface{}{
+			if err := m.haClient.CallService(m.ctx, "input_boolean", "turn_off", map[string]interface{}{
 				"entity_id": "input_boolean.lockdown",
 			}); err != nil {
 				m.logger.Error("Failed to reset lockdown", zap.Error(err))
 			} else {
 				m.logger.Info("Lockdown reset")
 			}
-		}()
+		})
 	}
 }
 
+// applyLockdownCues publishes lockdownActive, pulses occupied room lights red, and pauses outdoor speakers
+func (m *Manager) applyLockdownCues() {
+	m.mu.Lock()
+	if m.lockdownCuesActive {
+		m.mu.Unlock()
+		return
+	}
+	m.lockdownCuesActive = true
+	m.mu.Unlock()
+
+	m.setLockdownActive(true)
+
+	lights := m.occupiedRoomLights()
+	if len(lights) > 0 {
+		m.pulseLightsForLockdown(lights)
+	}
+	m.pauseOutdoorSpeakers()
+
+	m.shadowTracker.RecordLockdownCues(lights, m.expandEntities(m.lockdown.OutdoorSpeakers))
+}
+
+// clearLockdownCues clears lockdownActive and restores the lighting captured before the pulse
+func (m *Manager) clearLockdownCues() {
+	m.mu.Lock()
+	if !m.lockdownCuesActive {
+		m.mu.Unlock()
+		return
+	}
+	m.lockdownCuesActive = false
+	restore := m.lockdownSnapshotTaken
+	m.lockdownSnapshotTaken = false
+	m.mu.Unlock()
+
+	m.logger.Info("Lockdown cleared")
+	m.setLockdownActive(false)
+
+	if !restore {
+		return
+	}
+
+	if err := ha.RestoreScene(m.ctx, m.haClient, lockdownSnapshotSceneID); err != nil {
+		m.logger.Error("Failed to restore lighting after lockdown", zap.Error(err))
+		return
+	}
+
+	m.shadowTracker.RecordLockdownLightsRestored()
+	m.logger.Info("Restored lighting after lockdown")
+}
+
+// setLockdownActive publishes lockdownActive for other plugins to consume
+func (m *Manager) setLockdownActive(active bool) {
+	if err := m.stateManager.SetBool("lockdownActive", active); err != nil {
+		m.logger.Error("Failed to set lockdownActive", zap.Bool("active", active), zap.Error(err))
+	}
+}
+
+// occupiedRoomLights returns the lockdown cue lights for rooms that are currently occupied
+func (m *Manager) occupiedRoomLights() []string {
+	lights := make([]string, 0, len(m.lockdown.CueRooms))
+	for _, room := range m.lockdown.CueRooms {
+		occupied, err := m.stateManager.GetBool(room.OccupancyVariable)
+		if err != nil {
+			m.logger.Warn("Failed to get room occupancy",
+				zap.String("key", room.OccupancyVariable),
+				zap.Error(err))
+			continue
+		}
+		if occupied {
+			lights = append(lights, room.Light)
+		}
+	}
+	return lights
+}
+
+// pulseLightsForLockdown snapshots the given lights and briefly pulses them red.
+// The pulse is skipped if the snapshot fails, so lights are never left red without a way back.
+// It targets the lights by entity_id, so the arbiter holds them at security
+// priority and lighting can't restore its scene over the pulse.
+func (m *Manager) pulseLightsForLockdown(lights []string) {
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would pulse lights red for lockdown", zap.Strings("lights", lights))
+		return
+	}
+
+	if err := ha.SnapshotScene(m.ctx, m.haClient, lockdownSnapshotSceneID, lights); err != nil {
+		m.logger.Error("Failed to snapshot lighting before lockdown pulse, skipping pulse", zap.Error(err))
+		return
+	}
+
+	m.mu.Lock()
+	m.lockdownSnapshotTaken = true
+	m.mu.Unlock()
+
+	if err := m.haClient.CallService(m.ctx, "light", "turn_on", map[string]interface{}{
+		"entity_id": lights,
+		"rgb_color": []int{255, 0, 0},
+		"flash":     "short",
+	}); err != nil {
+		m.logger.Error("Failed to pulse lights for lockdown", zap.Error(err))
+	} else {
+		m.logger.Info("Pulsed lights red for lockdown", zap.Strings("lights", lights))
+	}
+}
+
+// pauseOutdoorSpeakers pauses playback on outdoor speakers
+func (m *Manager) pauseOutdoorSpeakers() {
+	speakers := m.expandEntities(m.lockdown.OutdoorSpeakers)
+	if len(speakers) == 0 {
+		return
+	}
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would pause outdoor speakers", zap.Strings("speakers", speakers))
+		return
+	}
+
+	if err := m.haClient.CallService(m.ctx, "media_player", "media_pause", map[string]interface{}{
+		"entity_id": speakers,
+	}); err != nil {
+		m.logger.Error("Failed to pause outdoor speakers", zap.Error(err))
+	} else {
+		m.logger.Info("Paused outdoor speakers for lockdown", zap.Strings("speakers", speakers))
+	}
+}
+
+// expandEntities expands entity group references so the concrete entities
+// can be filtered, logged and recorded in shadow state
+func (m *Manager) expandEntities(ids []string) []string {
+	expanded, err := entitygroups.Expand(m.haClient, ids)
+	if err != nil {
+		m.logger.Error("Failed to expand entity groups", zap.Strings("entities", ids), zap.Error(err))
+		return nil
+	}
+	return expanded
+}
+
 // handleOwnerReturnHome opens garage door if owner just returned home
 func (m *Manager) handleOwnerReturnHome(key string, oldValue, newValue interface{}) {
 	// Update shadow state current inputs immediately
@@ -265,7 +780,7 @@ func (m *Manager) handleOwnerReturnHome(key string, oldValue, newValue interface
 	m.logger.Info("Owner just returned home, checking garage status")
 
 	// Check if garage is empty (no vehicle detected)
-	currentState, err := m.haClient.GetState("binary_sensor.garage_door_vehicle_detected")
+	currentState, err := m.haClient.GetState(m.ctx, m.entities.GarageVehicleDetected())
 	if err != nil {
 		m.logger.Error("Failed to get garage sensor state", zap.Error(err))
 		return
@@ -290,8 +805,8 @@ func (m *Manager) openGarageDoor(garageWasEmpty bool) {
 		return
 	}
 
-	if err := m.haClient.CallService("cover", "open_cover", map[string]interface{}{
-		"entity_id": "cover.garage_door_door",
+	if err := m.haClient.CallService(m.ctx, "cover", "open_cover", map[string]interface{}{
+		"entity_id": m.entities.GarageDoor(),
 	}); err != nil {
 		m.logger.Error("Failed to open garage door", zap.Error(err))
 	} else {
@@ -299,46 +814,99 @@ func (m *Manager) openGarageDoor(garageWasEmpty bool) {
 	}
 }
 
-// handleDoorbellPressed sends notifications when doorbell is pressed
+// handleDoorbellPressed sends notifications when doorbell is pressed. During the
+// delivery window the ring is logged and snapshotted instead.
 func (m *Manager) handleDoorbellPressed(entity string, oldState, newState *ha.State) {
-	// Rate limit: max 1 notification per 20 seconds
-	m.mu.Lock()
-	rateLimited := m.clock.Since(m.lastDoorbellNotification) < DoorbellRateLimit
-	if rateLimited {
+	expectingSomeone, err := m.stateManager.GetBool("isExpectingSomeone")
+	if err != nil {
+		m.logger.Warn("Failed to get isExpectingSomeone state, applying rate limit", zap.Error(err))
+		expectingSomeone = false
+	}
+
+	allowed, bypassed := m.allowNotification(rateLimitDoorbell, expectingSomeone)
+	if !allowed {
 		m.logger.Info("Doorbell notification rate limited")
-		m.mu.Unlock()
 		// Record the rate-limited event
 		m.recordDoorbellEvent(true, false, false, "doorbell")
 		return
 	}
-	m.lastDoorbellNotification = m.clock.Now()
-	m.mu.Unlock()
+	if bypassed {
+		m.logger.Info("Doorbell rate limit bypassed, expecting someone")
+	}
+
+	if m.inDeliveryWindow() {
+		m.handleDeliveryRing()
+		return
+	}
 
 	m.logger.Info("Doorbell pressed, sending notifications")
 
 	// Send TTS notification
-	m.sendTTSNotification("Doorbell ringing")
+	m.sendTTSNotification("Doorbell ringing", true)
 
 	// Flash lights twice
-	go m.flashLightsForDoorbell()
+	m.goBackground(m.flashLightsForDoorbell)
 
 	// Record the successful event
 	m.recordDoorbellEvent(false, true, true, "doorbell")
 }
 
+// goBackground runs f in a goroutine that Stop waits for
+func (m *Manager) goBackground(f func()) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		f()
+	}()
+}
+
+// allowNotification applies an event type's rate limit and publishes the
+// limiter state to the shadow state
+func (m *Manager) allowNotification(eventType string, expectingSomeone bool) (allowed bool, bypassed bool) {
+	m.mu.Lock()
+	limiter := m.doorbellLimiter
+	switch eventType {
+	case rateLimitVehicleArrival:
+		limiter = m.vehicleLimiter
+	case rateLimitPersonDetection:
+		limiter = m.personLimiter
+	}
+	allowed, bypassed = limiter.allow(m.clock.Now(), expectingSomeone)
+	m.mu.Unlock()
+
+	m.updateRateLimitShadow()
+	return allowed, bypassed
+}
+
+// updateRateLimitShadow publishes the current state of each rate limiter
+func (m *Manager) updateRateLimitShadow() {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	doorbell := m.doorbellLimiter.shadowState(now)
+	vehicle := m.vehicleLimiter.shadowState(now)
+	person := m.personLimiter.shadowState(now)
+	m.mu.Unlock()
+
+	m.shadowTracker.UpdateRateLimit(rateLimitDoorbell, doorbell)
+	m.shadowTracker.UpdateRateLimit(rateLimitVehicleArrival, vehicle)
+	m.shadowTracker.UpdateRateLimit(rateLimitPersonDetection, person)
+}
+
 // flashLightsForDoorbell flashes lights twice with 2-second delay
 func (m *Manager) flashLightsForDoorbell() {
-	lights := []string{
-		"light.primary_suite",
-		"light.living_room",
-		"light.independent",
+	lights := m.expandEntities(doorbellLights)
+	if len(lights) == 0 {
+		return
 	}
 
 	// First flash
 	m.flashLights(lights)
 
 	// Wait 2 seconds
-	m.clock.Sleep(DoorbellFlashDelay)
+	if m.clock.SleepContext(m.ctx, DoorbellFlashDelay) != nil {
+		return
+	}
 
 	// Second flash
 	m.flashLights(lights)
@@ -351,11 +919,10 @@ func (m *Manager) flashLights(lights []string) {
 		return
 	}
 
-	if err := m.haClient.CallService("light", "turn_on", map[string]interface{}{
-		"entity_id": lights,
-		"flash":     "short",
-	}); err != nil {
-		m.logger.Error("Failed to flash lights", zap.Error(err))
+	for _, data := range m.kid.FlashCalls(lights) {
+		if err := m.haClient.CallService(m.ctx, "light", "turn_on", data); err != nil {
+			m.logger.Error("Failed to flash lights", zap.Error(err))
+		}
 	}
 }
 
@@ -378,30 +945,28 @@ func (m *Manager) handleVehicleArriving(entity string, oldState, newState *ha.St
 		return
 	}
 
-	// Rate limit: max 1 notification per 20 seconds
-	m.mu.Lock()
-	rateLimited := m.clock.Since(m.lastVehicleArrivalNotification) < VehicleArrivalRateLimit
-	if rateLimited {
+	allowed, bypassed := m.allowNotification(rateLimitVehicleArrival, expectingSomeone)
+	if !allowed {
 		m.logger.Info("Vehicle arrival notification rate limited")
-		m.mu.Unlock()
 		// Record the rate-limited event
 		m.recordVehicleArrivalEvent(true, false, true, "vehicle_arriving")
 		return
 	}
-	m.lastVehicleArrivalNotification = m.clock.Now()
-	m.mu.Unlock()
+	if bypassed {
+		m.logger.Info("Vehicle arrival rate limit bypassed, expecting someone")
+	}
 
 	m.logger.Info("Expected vehicle has arrived, sending notification")
 
 	// Send TTS notification
-	m.sendTTSNotification("They have arrived")
+	m.sendTTSNotification("They have arrived", false)
 
 	// Record the successful event
 	m.recordVehicleArrivalEvent(false, true, true, "vehicle_arriving")
 
 	// Reset expecting someone flag
 	if !m.readOnly {
-		if err := m.haClient.CallService("input_boolean", "turn_off", map[string]interface{}{
+		if err := m.haClient.CallService(m.ctx, "input_boolean", "turn_off", map[string]interface{}{
 			"entity_id": "input_boolean.expecting_someone",
 		}); err != nil {
 			m.logger.Error("Failed to reset expecting_someone", zap.Error(err))
@@ -409,31 +974,56 @@ func (m *Manager) handleVehicleArriving(entity string, oldState, newState *ha.St
 	}
 }
 
-// sendTTSNotification sends a TTS message to all Sonos speakers
-func (m *Manager) sendTTSNotification(message string) {
+// sendTTSNotification announces a message on the security speakers. Non-critical
+// messages skip the office speakers during focus mode.
+func (m *Manager) sendTTSNotification(message string, critical bool) {
 	if m.readOnly {
 		m.logger.Info("READ-ONLY: Would send TTS notification", zap.String("message", message))
 		return
 	}
 
-	speakers := []string{
-		"media_player.bedroom",
-		"media_player.kitchen",
-		"media_player.dining_room",
-		"media_player.soundbar",
-		"media_player.kids_bathroom",
+	speakers, suppressed := m.dnd.Filter(m.ttsSpeakers())
+	if len(suppressed) > 0 {
+		m.logger.Info("Skipping TTS notification on do-not-disturb speakers", zap.Strings("suppressed", suppressed))
+	}
+	if !critical {
+		speakers, suppressed = m.focus.Filter(speakers)
+		if len(suppressed) > 0 {
+			m.logger.Info("Skipping TTS notification on focus mode speakers", zap.Strings("suppressed", suppressed))
+		}
+	}
+	if len(speakers) == 0 {
+		return
 	}
 
-	if err := m.haClient.CallService("tts", "speak", map[string]interface{}{
-		"entity_id":              "tts.google_translate_en_com",
-		"media_player_entity_id": speakers,
-		"message":                message,
-		"cache":                  true,
-	}); err != nil {
+	priority := tts.PriorityNormal
+	if critical {
+		priority = tts.PriorityUrgent
+	}
+	err := m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Title:    "Security",
+		Message:  message,
+		Speakers: speakers,
+		Priority: priority,
+	})
+	switch {
+	case errors.Is(err, tts.ErrQuietHours):
+		m.logger.Info("Skipping TTS notification during quiet hours", zap.String("message", message))
+	case err != nil:
 		m.logger.Error("Failed to send TTS notification", zap.Error(err), zap.String("message", message))
-	} else {
-		m.logger.Info("TTS notification sent", zap.String("message", message))
+	default:
+		m.logger.Info("TTS notification sent", zap.String("message", message), zap.String("priority", string(priority)))
+	}
+}
+
+// ttsSpeakers returns the speakers security announcements are spoken on
+func (m *Manager) ttsSpeakers() []string {
+	speakers, err := m.announcer.Speakers(m.haClient, tts.TargetSecurity)
+	if err != nil {
+		m.logger.Error("Failed to resolve TTS speakers", zap.Error(err))
+		return nil
 	}
+	return speakers
 }
 
 // updateShadowInputs updates the current shadow state inputs
@@ -461,12 +1051,23 @@ func (m *Manager) updateShadowInputs() {
 	if val, err := m.stateManager.GetBool("didOwnerJustReturnHome"); err == nil {
 		inputs["didOwnerJustReturnHome"] = val
 	}
+	if len(m.privacySwitches) > 0 {
+		if val, err := m.stateManager.GetBool("isAnyOwnerHome"); err == nil {
+			inputs["isAnyOwnerHome"] = val
+		}
+	}
+	if key := m.delivery.ToggleVariable; key != "" {
+		if val, err := m.stateManager.GetBool(key); err == nil {
+			inputs[key] = val
+		}
+	}
 
 	m.shadowTracker.UpdateCurrentInputs(inputs)
 }
 
 // updateShadowInputsWithTrigger updates the current shadow state inputs including the trigger
 func (m *Manager) updateShadowInputsWithTrigger(trigger string) {
+	m.health.Tick()
 	additional := map[string]interface{}{"trigger": trigger}
 
 	// Use automatic input capture with additional fields if available
@@ -492,6 +1093,16 @@ func (m *Manager) updateShadowInputsWithTrigger(trigger string) {
 	if val, err := m.stateManager.GetBool("didOwnerJustReturnHome"); err == nil {
 		inputs["didOwnerJustReturnHome"] = val
 	}
+	if len(m.privacySwitches) > 0 {
+		if val, err := m.stateManager.GetBool("isAnyOwnerHome"); err == nil {
+			inputs["isAnyOwnerHome"] = val
+		}
+	}
+	if key := m.delivery.ToggleVariable; key != "" {
+		if val, err := m.stateManager.GetBool(key); err == nil {
+			inputs[key] = val
+		}
+	}
 
 	// Add the trigger field
 	inputs["trigger"] = trigger
@@ -552,15 +1163,22 @@ func (m *Manager) GetShadowState() *shadowstate.SecurityShadowState {
 	return m.shadowTracker.GetState()
 }
 
-// Reset re-evaluates security conditions and resets rate limiters
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Reset re-evaluates security conditions, resets rate limiters, and clears stale lockdown cues
 func (m *Manager) Reset() error {
 	m.logger.Info("Resetting Security - re-evaluating lockdown conditions and clearing rate limiters")
 
 	// Clear rate limiters to allow immediate notifications
 	m.mu.Lock()
-	m.lastDoorbellNotification = time.Time{}
-	m.lastVehicleArrivalNotification = time.Time{}
+	m.doorbellLimiter.reset()
+	m.vehicleLimiter.reset()
+	m.personLimiter.reset()
 	m.mu.Unlock()
+	m.updateRateLimitShadow()
 
 	// Re-evaluate lockdown conditions
 	isEveryoneAsleep, err := m.stateManager.GetBool("isEveryoneAsleep")
@@ -579,6 +1197,49 @@ func (m *Manager) Reset() error {
 		m.activateLockdown("No one is home (reset)", "reset")
 	}
 
+	// Re-apply camera privacy mode for current presence
+	if len(m.privacySwitches) > 0 {
+		m.mu.Lock()
+		if ownerHome, err := m.stateManager.GetBool("isAnyOwnerHome"); err == nil {
+			m.lastOwnerHome = ownerHome
+		}
+		if isEveryoneAsleep, err := m.stateManager.GetBool("isEveryoneAsleep"); err == nil {
+			m.lastEveryoneAsleep = isEveryoneAsleep
+		}
+		m.mu.Unlock()
+		m.applyCameraPrivacy("reset", true)
+	}
+
+	// Clear stale lockdown cues (and restore lighting) if lockdown is no longer on
+	isLockdown, err := m.stateManager.GetBool("isLockdown")
+	if err != nil {
+		m.logger.Error("Failed to get isLockdown", zap.Error(err))
+	} else if !isLockdown {
+		m.clearLockdownCues()
+	}
+
+	// Restart held-open alarms for doors that are open now
+	m.stopHeldOpenTimers()
+	m.checkHeldOpenDoors("reset")
+
+	// Re-read the delivery window triggers
+	if m.delivery.Enabled() {
+		m.refreshDeliveryWindow("reset")
+	}
+
+	// Re-read the person sensors
+	m.refreshPersonDetection("reset")
+
+	// Re-apply the alarm mode from the mode entity or presence
+	if m.alarm.Enabled() {
+		m.refreshAlarmMode("reset")
+	}
+
+	// Re-read the locks and restart auto-lock for closed, unlocked doors
+	if len(m.doorLocks.Doors) > 0 {
+		m.refreshDoorLocks("reset")
+	}
+
 	m.logger.Info("Successfully reset Security")
 	return nil
 }
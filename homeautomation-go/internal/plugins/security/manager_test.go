@@ -1,9 +1,12 @@
 package security
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"homeautomation/internal/clock"
+	"homeautomation/internal/focusmode"
 	"homeautomation/internal/ha"
 	"homeautomation/internal/state"
 
@@ -15,7 +18,7 @@ func TestSecurityManager_LockdownOnEveryoneAsleep(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.everyone_asleep", "off", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -23,7 +26,7 @@ func TestSecurityManager_LockdownOnEveryoneAsleep(t *testing.T) {
 
 	// Create security manager (not read-only so it can call services)
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -60,7 +63,7 @@ func TestSecurityManager_LockdownOnNoOneHome(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.anyone_home", "on", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -68,7 +71,7 @@ func TestSecurityManager_LockdownOnNoOneHome(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -104,7 +107,7 @@ func TestSecurityManager_LockdownOnNoOneHome(t *testing.T) {
 func TestSecurityManager_LockdownAutoReset(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -112,7 +115,7 @@ func TestSecurityManager_LockdownAutoReset(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -149,7 +152,7 @@ func TestSecurityManager_GarageAutoOpen(t *testing.T) {
 
 	// Set garage as empty (no vehicle detected)
 	mockHA.SetState("binary_sensor.garage_door_vehicle_detected", "off", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -157,7 +160,7 @@ func TestSecurityManager_GarageAutoOpen(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -196,7 +199,7 @@ func TestSecurityManager_GarageNotOpenedWhenOccupied(t *testing.T) {
 
 	// Set garage as occupied (vehicle detected)
 	mockHA.SetState("binary_sensor.garage_door_vehicle_detected", "on", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -204,7 +207,7 @@ func TestSecurityManager_GarageNotOpenedWhenOccupied(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -234,7 +237,7 @@ func TestSecurityManager_GarageNotOpenedWhenOccupied(t *testing.T) {
 func TestSecurityManager_DoorbellNotification(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -242,7 +245,7 @@ func TestSecurityManager_DoorbellNotification(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -282,11 +285,55 @@ func TestSecurityManager_DoorbellNotification(t *testing.T) {
 	}
 }
 
+// TestSecurityManager_FocusModeSkipsNonCriticalTTS tests that focus mode
+// keeps vehicle arrival TTS off the office speakers but not the doorbell
+func TestSecurityManager_FocusModeSkipsNonCriticalTTS(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
+	securityManager.SetFocusMode(focusmode.NewGuard(&focusmode.Config{FocusMode: focusmode.Settings{
+		ToggleVariable: "isOfficeFocusMode",
+		Speakers:       []string{"media_player.bedroom"},
+	}}, stateManager))
+	if err := stateManager.SetBool(focusmode.ActiveVariable, true); err != nil {
+		t.Fatalf("Failed to turn on focus mode: %v", err)
+	}
+
+	spokenOn := func() []string {
+		for _, call := range mockHA.GetServiceCalls() {
+			if call.Domain == "tts" && call.Service == "speak" {
+				speakers, _ := call.Data["media_player_entity_id"].([]string)
+				return speakers
+			}
+		}
+		return nil
+	}
+
+	securityManager.sendTTSNotification("They have arrived", false)
+	for _, speaker := range spokenOn() {
+		if speaker == "media_player.bedroom" {
+			t.Errorf("Expected non-critical TTS to skip focus mode speakers, got %v", spokenOn())
+		}
+	}
+
+	mockHA.ClearServiceCalls()
+	securityManager.sendTTSNotification("Doorbell ringing", true)
+	found := false
+	for _, speaker := range spokenOn() {
+		found = found || speaker == "media_player.bedroom"
+	}
+	if !found {
+		t.Errorf("Expected critical TTS on focus mode speakers, got %v", spokenOn())
+	}
+}
+
 // TestSecurityManager_DoorbellRateLimiting tests doorbell rate limiting
 func TestSecurityManager_DoorbellRateLimiting(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -294,7 +341,7 @@ func TestSecurityManager_DoorbellRateLimiting(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -319,12 +366,68 @@ func TestSecurityManager_DoorbellRateLimiting(t *testing.T) {
 	}
 }
 
+// TestSecurityManager_DoorbellRateLimitPolicy tests a configured doorbell
+// policy and its bypass while someone is expected
+func TestSecurityManager_DoorbellRateLimitPolicy(t *testing.T) {
+	// Setup
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_boolean.expecting_someone", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	securityManager := NewManager(mockHA, stateManager, logger, true, nil)
+	securityManager.SetClock(clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{
+		RateLimits: RateLimitConfig{
+			Doorbell: RateLimitPolicy{WindowSeconds: 60, Burst: 2, BypassWhenExpecting: true},
+		},
+	}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	defer securityManager.Stop()
+
+	policy := securityManager.GetShadowState().Outputs.RateLimits["doorbell"]
+	if policy.WindowSeconds != 60 || policy.Burst != 2 || !policy.BypassWhenExpecting {
+		t.Errorf("Expected configured doorbell policy in shadow state, got %+v", policy)
+	}
+
+	// Two presses fit the burst, the third is limited
+	for i := 0; i < 3; i++ {
+		mockHA.SimulateStateChange("input_button.doorbell", time.Date(2024, 1, 1, 12, 0, i, 0, time.UTC).Format(time.RFC3339))
+	}
+	shadow := securityManager.GetShadowState()
+	if !shadow.Outputs.LastDoorbell.RateLimited {
+		t.Error("Expected third doorbell press to be rate limited")
+	}
+	if !shadow.Outputs.RateLimits["doorbell"].Limited {
+		t.Error("Expected doorbell rate limit to be reported as limited")
+	}
+
+	// While someone is expected every press is announced
+	if err := stateManager.SetBool("isExpectingSomeone", true); err != nil {
+		t.Fatalf("Failed to set isExpectingSomeone: %v", err)
+	}
+	mockHA.SimulateStateChange("input_button.doorbell", "2024-01-01T12:00:04")
+
+	shadow = securityManager.GetShadowState()
+	if shadow.Outputs.LastDoorbell.RateLimited || !shadow.Outputs.LastDoorbell.TTSSent {
+		t.Errorf("Expected doorbell to bypass rate limit while expecting someone, got %+v", shadow.Outputs.LastDoorbell)
+	}
+	if shadow.Outputs.RateLimits["doorbell"].LastBypassedAt.IsZero() {
+		t.Error("Expected bypass to be recorded in shadow state")
+	}
+}
+
 // TestSecurityManager_VehicleArrivalWithExpecting tests vehicle arrival when expecting someone
 func TestSecurityManager_VehicleArrivalWithExpecting(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.expecting_someone", "on", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -332,7 +435,7 @@ func TestSecurityManager_VehicleArrivalWithExpecting(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -377,7 +480,7 @@ func TestSecurityManager_VehicleArrivalWithoutExpecting(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.expecting_someone", "off", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -385,7 +488,7 @@ func TestSecurityManager_VehicleArrivalWithoutExpecting(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -414,7 +517,7 @@ func TestSecurityManager_ReadOnlyMode(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.everyone_asleep", "off", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false) // Not read-only for state manager
@@ -423,7 +526,7 @@ func TestSecurityManager_ReadOnlyMode(t *testing.T) {
 
 	// Create security manager in read-only mode (this is what we're testing)
 	securityManager := NewManager(mockHA, stateManager, logger, true, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -449,7 +552,7 @@ func TestSecurityManager_ReadOnlyMode(t *testing.T) {
 func TestSecurityManager_InvalidTypeHandling(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -457,7 +560,7 @@ func TestSecurityManager_InvalidTypeHandling(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -497,7 +600,7 @@ func TestSecurityManager_OwnerReturnHome_DidNotReturn(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("binary_sensor.garage_door_vehicle_detected", "off", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -505,7 +608,7 @@ func TestSecurityManager_OwnerReturnHome_DidNotReturn(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -532,7 +635,7 @@ func TestSecurityManager_VehicleArrivalRateLimiting(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.expecting_someone", "on", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -540,7 +643,7 @@ func TestSecurityManager_VehicleArrivalRateLimiting(t *testing.T) {
 
 	// Create security manager
 	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -573,7 +676,7 @@ func TestSecurityManager_ReadOnlyModeGarage(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("binary_sensor.garage_door_vehicle_detected", "off", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -581,7 +684,7 @@ func TestSecurityManager_ReadOnlyModeGarage(t *testing.T) {
 
 	// Create security manager in READ-ONLY mode
 	securityManager := NewManager(mockHA, stateManager, logger, true, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -607,7 +710,7 @@ func TestSecurityManager_ReadOnlyModeGarage(t *testing.T) {
 func TestSecurityManager_ReadOnlyModeLockdownReset(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -615,7 +718,7 @@ func TestSecurityManager_ReadOnlyModeLockdownReset(t *testing.T) {
 
 	// Create security manager in READ-ONLY mode
 	securityManager := NewManager(mockHA, stateManager, logger, true, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -641,7 +744,7 @@ func TestSecurityManager_ReadOnlyModeVehicleArrival(t *testing.T) {
 	// Setup
 	mockHA := ha.NewMockClient()
 	mockHA.SetState("input_boolean.expecting_someone", "on", nil)
-	mockHA.Connect()
+	mockHA.Connect(context.Background())
 
 	logger := zap.NewNop()
 	stateManager := state.NewManager(mockHA, logger, false)
@@ -649,7 +752,7 @@ func TestSecurityManager_ReadOnlyModeVehicleArrival(t *testing.T) {
 
 	// Create security manager in READ-ONLY mode
 	securityManager := NewManager(mockHA, stateManager, logger, true, nil)
-	if err := securityManager.Start(); err != nil {
+	if err := securityManager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start security manager: %v", err)
 	}
 
@@ -668,3 +771,381 @@ func TestSecurityManager_ReadOnlyModeVehicleArrival(t *testing.T) {
 		}
 	}
 }
+
+// newLockdownCueTestManager creates a started security manager whose auto-reset timer never fires
+func newLockdownCueTestManager(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_boolean.kitchen_occupied", "on", nil)
+	mockHA.SetState("input_boolean.nick_office_occupied", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	securityManager := NewManager(mockHA, stateManager, logger, readOnly, nil)
+	securityManager.SetConfig(lockdownCueTestConfig())
+	// Mock clock keeps the 5 second auto-reset from firing during the test
+	securityManager.SetClock(clock.NewMockClock(time.Now()))
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA, stateManager
+}
+
+// lockdownCueTestConfig cues the kitchen and office lights and the patio speaker
+func lockdownCueTestConfig() *SecurityConfig {
+	return &SecurityConfig{Security: SecuritySettings{Lockdown: LockdownConfig{
+		CueRooms: []LockdownCueRoomConfig{
+			{OccupancyVariable: "isKitchenOccupied", Light: "light.kitchen"},
+			{OccupancyVariable: "isNickOfficeOccupied", Light: "light.n_office"},
+		},
+		OutdoorSpeakers: []string{"media_player.patio"},
+	}}}
+}
+
+// TestSecurityManager_LockdownCues tests the light pulse, speaker pause, and lockdownActive on lockdown
+func TestSecurityManager_LockdownCues(t *testing.T) {
+	securityManager, mockHA, stateManager := newLockdownCueTestManager(t, false)
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+
+	active, err := stateManager.GetBool("lockdownActive")
+	if err != nil {
+		t.Fatalf("Failed to get lockdownActive: %v", err)
+	}
+	if !active {
+		t.Error("Expected lockdownActive to be true after lockdown")
+	}
+
+	var snapshot, pulse, pause *ha.ServiceCall
+	calls := mockHA.GetServiceCalls()
+	for i := range calls {
+		call := &calls[i]
+		switch {
+		case call.Domain == "scene" && call.Service == "create":
+			snapshot = call
+		case call.Domain == "light" && call.Service == "turn_on":
+			pulse = call
+		case call.Domain == "media_player" && call.Service == "media_pause":
+			pause = call
+		}
+	}
+
+	if snapshot == nil {
+		t.Fatal("Expected lighting snapshot before pulse")
+	}
+	if entities, _ := snapshot.Data["snapshot_entities"].([]string); len(entities) != 1 || entities[0] != "light.kitchen" {
+		t.Errorf("Expected snapshot of occupied kitchen light only, got %v", snapshot.Data["snapshot_entities"])
+	}
+
+	if pulse == nil {
+		t.Fatal("Expected red pulse on occupied room lights")
+	}
+	if entities, _ := pulse.Data["entity_id"].([]string); len(entities) != 1 || entities[0] != "light.kitchen" {
+		t.Errorf("Expected pulse on light.kitchen only, got %v", pulse.Data["entity_id"])
+	}
+	if rgb, _ := pulse.Data["rgb_color"].([]int); len(rgb) != 3 || rgb[0] != 255 || rgb[1] != 0 || rgb[2] != 0 {
+		t.Errorf("Expected red pulse, got %v", pulse.Data["rgb_color"])
+	}
+
+	if pause == nil {
+		t.Fatal("Expected outdoor speakers to be paused")
+	}
+	if speakers, _ := pause.Data["entity_id"].([]string); len(speakers) != 1 || speakers[0] != "media_player.patio" {
+		t.Errorf("Expected the configured outdoor speaker to be paused, got %v", pause.Data["entity_id"])
+	}
+
+	lockdown := securityManager.GetShadowState().Outputs.Lockdown
+	if len(lockdown.CuedLights) != 1 || lockdown.CuedLights[0] != "light.kitchen" {
+		t.Errorf("Expected cued lights [light.kitchen] in shadow state, got %v", lockdown.CuedLights)
+	}
+	if len(lockdown.PausedSpeakers) == 0 {
+		t.Error("Expected paused speakers in shadow state")
+	}
+}
+
+// TestSecurityManager_LockdownPulseHeldBySecurity tests that the pulse goes
+// through the arbiter at security priority, so lighting can't undo it
+func TestSecurityManager_LockdownPulseHeldBySecurity(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_boolean.kitchen_occupied", "on", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	arbiter := ha.NewArbiter(ha.DefaultCommandPriorities, time.Minute, logger)
+	lighting := arbiter.Client("lighting", mockHA)
+	ctx := context.Background()
+	if err := lighting.CallService(ctx, "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}); err != nil {
+		t.Fatalf("Failed to turn on the kitchen light: %v", err)
+	}
+
+	securityManager := NewManager(arbiter.Client("security", mockHA), stateManager, logger, false, nil)
+	securityManager.SetConfig(lockdownCueTestConfig())
+	securityManager.SetClock(clock.NewMockClock(time.Now()))
+	if err := securityManager.Start(ctx); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+	mockHA.ClearServiceCalls()
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+
+	pulses := 0
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "light" && call.Service == "turn_on" {
+			pulses++
+		}
+	}
+	if pulses != 1 {
+		t.Fatalf("Expected the pulse to override lighting's hold on light.kitchen, got %d light calls", pulses)
+	}
+
+	mockHA.ClearServiceCalls()
+	if err := lighting.CallService(ctx, "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}); err != nil {
+		t.Fatalf("Suppressed call should not fail: %v", err)
+	}
+	if calls := mockHA.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected lighting's call to be dropped while security holds light.kitchen, got %v", calls)
+	}
+}
+
+// TestSecurityManager_LockdownClearRestoresLighting tests restoring the snapshot when lockdown clears
+func TestSecurityManager_LockdownClearRestoresLighting(t *testing.T) {
+	securityManager, mockHA, stateManager := newLockdownCueTestManager(t, false)
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+	mockHA.ClearServiceCalls()
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "off")
+
+	active, err := stateManager.GetBool("lockdownActive")
+	if err != nil {
+		t.Fatalf("Failed to get lockdownActive: %v", err)
+	}
+	if active {
+		t.Error("Expected lockdownActive to be false after lockdown cleared")
+	}
+
+	restored := false
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "scene" && call.Service == "turn_on" && call.Data["entity_id"] == "scene."+lockdownSnapshotSceneID {
+			restored = true
+		}
+	}
+	if !restored {
+		t.Error("Expected lighting snapshot to be restored when lockdown cleared")
+	}
+	if !securityManager.GetShadowState().Outputs.Lockdown.LightsRestored {
+		t.Error("Expected shadow state to record lights restored")
+	}
+}
+
+// TestSecurityManager_LockdownCuesNoOccupiedRooms tests that no pulse or restore happens without occupied rooms
+func TestSecurityManager_LockdownCuesNoOccupiedRooms(t *testing.T) {
+	_, mockHA, stateManager := newLockdownCueTestManager(t, false)
+	if err := stateManager.SetBool("isKitchenOccupied", false); err != nil {
+		t.Fatalf("Failed to set isKitchenOccupied: %v", err)
+	}
+	mockHA.ClearServiceCalls()
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+	mockHA.SimulateStateChange("input_boolean.lockdown", "off")
+
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "scene" || call.Domain == "light" {
+			t.Errorf("Expected no lighting cues without occupied rooms, got %s.%s", call.Domain, call.Service)
+		}
+	}
+}
+
+// TestSecurityManager_ReadOnlyModeLockdownCues tests that cues are only logged in read-only mode
+func TestSecurityManager_ReadOnlyModeLockdownCues(t *testing.T) {
+	_, mockHA, stateManager := newLockdownCueTestManager(t, true)
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "scene" || call.Domain == "light" || call.Domain == "media_player" {
+			t.Errorf("Expected NO cue service calls in read-only mode, got %s.%s", call.Domain, call.Service)
+		}
+	}
+
+	// lockdownActive is local-only, so it is still published for other plugins
+	active, err := stateManager.GetBool("lockdownActive")
+	if err != nil {
+		t.Fatalf("Failed to get lockdownActive: %v", err)
+	}
+	if !active {
+		t.Error("Expected lockdownActive to be true in read-only mode")
+	}
+}
+
+const testPrivacySwitch = "switch.test_indoor_camera_privacy"
+
+// setupCameraPrivacyTest starts a security manager with one indoor camera privacy switch
+func setupCameraPrivacyTest(t *testing.T, ownerHome bool, readOnly bool) (*Manager, *ha.MockClient, *state.Manager) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	if ownerHome {
+		mockHA.SetState("input_boolean.any_owner_home", "on", nil)
+	} else {
+		mockHA.SetState("input_boolean.any_owner_home", "off", nil)
+	}
+	mockHA.SetState("input_boolean.everyone_asleep", "off", nil)
+	mockHA.SetState("input_boolean.anyone_home", "on", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	securityManager := NewManager(mockHA, stateManager, logger, readOnly, nil)
+	securityManager.SetClock(clock.NewMockClock(time.Now()))
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{
+		CameraPrivacy: CameraPrivacyConfig{PrivacySwitches: []string{testPrivacySwitch}},
+	}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	return securityManager, mockHA, stateManager
+}
+
+// privacySwitchCalls returns the services called on the camera privacy switches
+func privacySwitchCalls(mockHA *ha.MockClient) []string {
+	var services []string
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain != "switch" {
+			continue
+		}
+		if ids, ok := call.Data["entity_id"].([]string); ok && len(ids) == 1 && ids[0] == testPrivacySwitch {
+			services = append(services, call.Service)
+		}
+	}
+	return services
+}
+
+func TestSecurityManager_CameraPrivacyAppliedOnStartup(t *testing.T) {
+	securityManager, mockHA, _ := setupCameraPrivacyTest(t, true, false)
+
+	if calls := privacySwitchCalls(mockHA); len(calls) != 1 || calls[0] != "turn_on" {
+		t.Fatalf("Expected privacy mode turned on at startup, got %v", calls)
+	}
+
+	privacy := securityManager.GetShadowState().Outputs.CameraPrivacy
+	if !privacy.Enabled || privacy.Transitions[0].Trigger != "startup" {
+		t.Errorf("Expected startup transition to privacy mode, got %+v", privacy)
+	}
+}
+
+func TestSecurityManager_CameraPrivacyFollowsOwnerPresence(t *testing.T) {
+	securityManager, mockHA, stateManager := setupCameraPrivacyTest(t, false, false)
+	mockHA.ClearServiceCalls()
+
+	if err := stateManager.SetBool("isAnyOwnerHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyOwnerHome: %v", err)
+	}
+	if err := stateManager.SetBool("isAnyOwnerHome", false); err != nil {
+		t.Fatalf("Failed to set isAnyOwnerHome: %v", err)
+	}
+
+	calls := privacySwitchCalls(mockHA)
+	if len(calls) != 2 || calls[0] != "turn_on" || calls[1] != "turn_off" {
+		t.Fatalf("Expected privacy on then off, got %v", calls)
+	}
+
+	privacy := securityManager.GetShadowState().Outputs.CameraPrivacy
+	if privacy.Enabled {
+		t.Error("Expected privacy mode disabled after owner left")
+	}
+	if privacy.Reason != "No owner is home" {
+		t.Errorf("Expected reason 'No owner is home', got %q", privacy.Reason)
+	}
+	if len(privacy.Transitions) != 3 {
+		t.Errorf("Expected 3 transitions (startup, arrive, leave), got %d", len(privacy.Transitions))
+	}
+}
+
+func TestSecurityManager_CameraPrivacyDisabledOnLockdown(t *testing.T) {
+	securityManager, mockHA, _ := setupCameraPrivacyTest(t, true, false)
+	mockHA.ClearServiceCalls()
+
+	mockHA.SimulateStateChange("input_boolean.lockdown", "on")
+
+	if calls := privacySwitchCalls(mockHA); len(calls) != 1 || calls[0] != "turn_off" {
+		t.Fatalf("Expected privacy mode turned off on lockdown, got %v", calls)
+	}
+	privacy := securityManager.GetShadowState().Outputs.CameraPrivacy
+	last := privacy.Transitions[len(privacy.Transitions)-1]
+	if last.Enabled || last.Trigger != "lockdown" {
+		t.Errorf("Expected lockdown transition, got %+v", last)
+	}
+}
+
+func TestSecurityManager_CameraPrivacyWhileAsleep(t *testing.T) {
+	_, mockHA, stateManager := setupCameraPrivacyTest(t, true, false)
+	mockHA.ClearServiceCalls()
+
+	if err := stateManager.SetBool("isEveryoneAsleep", true); err != nil {
+		t.Fatalf("Failed to set isEveryoneAsleep: %v", err)
+	}
+	if err := stateManager.SetBool("isEveryoneAsleep", false); err != nil {
+		t.Fatalf("Failed to set isEveryoneAsleep: %v", err)
+	}
+
+	calls := privacySwitchCalls(mockHA)
+	if len(calls) != 2 || calls[0] != "turn_off" || calls[1] != "turn_on" {
+		t.Fatalf("Expected recording while asleep and privacy on wake, got %v", calls)
+	}
+}
+
+func TestSecurityManager_CameraPrivacyReadOnly(t *testing.T) {
+	securityManager, mockHA, _ := setupCameraPrivacyTest(t, true, true)
+
+	if calls := privacySwitchCalls(mockHA); len(calls) != 0 {
+		t.Errorf("Expected no switch calls in read-only mode, got %v", calls)
+	}
+	if !securityManager.GetShadowState().Outputs.CameraPrivacy.Enabled {
+		t.Error("Expected shadow state to record privacy mode in read-only mode")
+	}
+}
+
+func TestSecurityManager_CameraPrivacyNotConfigured(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("input_boolean.any_owner_home", "off", nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	securityManager := NewManager(mockHA, stateManager, logger, false, nil)
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	defer securityManager.Stop()
+
+	if err := stateManager.SetBool("isAnyOwnerHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyOwnerHome: %v", err)
+	}
+
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "switch" {
+			t.Errorf("Expected no switch calls without privacy switches, got %+v", call)
+		}
+	}
+	if n := len(securityManager.GetShadowState().Outputs.CameraPrivacy.Transitions); n != 0 {
+		t.Errorf("Expected no privacy transitions, got %d", n)
+	}
+}
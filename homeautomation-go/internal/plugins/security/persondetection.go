@@ -0,0 +1,265 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/tts"
+
+	"go.uber.org/zap"
+)
+
+// Person detection snapshot defaults. Home Assistant serves /config/www as /local.
+const (
+	defaultPersonSnapshotPath = "/config/www/snapshots/person_{camera}_{timestamp}.jpg"
+	defaultPersonSnapshotURL  = "/local/snapshots/person_{camera}_{timestamp}.jpg"
+)
+
+// errPersonSkipped wraps the reason a detection wasn't snapshotted or notified
+var errPersonSkipped = errors.New("skipped")
+
+// startPersonDetection subscribes to each camera's person sensor and
+// publishes who is seen now, without notifying
+func (m *Manager) startPersonDetection() error {
+	for _, camera := range m.person.Cameras {
+		haSub, err := m.haClient.SubscribeStateChanges(camera.Sensor, m.handlePersonSensorChange)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", camera.Sensor, err)
+		}
+		m.haSubscriptions = append(m.haSubscriptions, haSub)
+	}
+	m.refreshPersonDetection("startup")
+	return nil
+}
+
+// refreshPersonDetection re-reads every person sensor and sets the state
+// variables to match. Detections already in progress aren't announced.
+func (m *Manager) refreshPersonDetection(trigger string) {
+	for _, camera := range m.person.Cameras {
+		sensor, err := m.haClient.GetState(m.ctx, camera.Sensor)
+		if err != nil {
+			m.logger.Warn("Failed to read person sensor",
+				zap.String("entity_id", camera.Sensor),
+				zap.String("trigger", trigger),
+				zap.Error(err))
+			continue
+		}
+		detected := personDetected(sensor)
+
+		m.mu.Lock()
+		m.personsSeen[camera.Sensor] = detected
+		m.mu.Unlock()
+		m.setPersonVariable(camera, detected)
+	}
+}
+
+// handlePersonSensorChange follows a person sensor into its state variable,
+// and snapshots, announces and records each new detection
+func (m *Manager) handlePersonSensorChange(entityID string, oldState, newState *ha.State) {
+	if newState == nil {
+		return
+	}
+	camera, ok := m.personCameraConfig(entityID)
+	if !ok {
+		return
+	}
+
+	// Count sensors change value while people come and go; only the first
+	// person seen is a new detection
+	detected := personDetected(newState)
+	m.mu.Lock()
+	changed := detected != m.personsSeen[entityID]
+	m.personsSeen[entityID] = detected
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	m.setPersonVariable(camera, detected)
+	if !detected {
+		m.logger.Info("Person no longer detected", zap.String("entity_id", entityID))
+		return
+	}
+	m.handlePersonDetected(camera)
+}
+
+// handlePersonDetected snapshots the camera and, if the camera notifies and
+// the rate limit allows, announces the detection
+func (m *Manager) handlePersonDetected(camera PersonCameraConfig) {
+	now := m.clock.Now()
+	event := shadowstate.PersonDetectionEvent{
+		Timestamp:     now,
+		Sensor:        camera.Sensor,
+		Name:          camera.Name,
+		StateVariable: camera.StateVariable,
+	}
+
+	var details []string
+	if path, url, err := m.snapshotPerson(camera, now); err != nil {
+		details = append(details, "snapshot "+err.Error())
+	} else {
+		event.Snapshot = path
+		event.SnapshotURL = url
+	}
+
+	if camera.Notify {
+		expectingSomeone, err := m.stateManager.GetBool("isExpectingSomeone")
+		if err != nil {
+			m.logger.Warn("Failed to get isExpectingSomeone state, applying rate limit", zap.Error(err))
+			expectingSomeone = false
+		}
+
+		allowed, bypassed := m.allowNotification(rateLimitPersonDetection, expectingSomeone)
+		switch {
+		case !allowed:
+			m.logger.Info("Person detection notification rate limited", zap.String("entity_id", camera.Sensor))
+			event.RateLimited = true
+		default:
+			if bypassed {
+				m.logger.Info("Person detection rate limit bypassed, expecting someone")
+			}
+			if err := m.notifyPerson(camera, event.SnapshotURL); err != nil {
+				details = append(details, "notification "+err.Error())
+				if !errors.Is(err, errPersonSkipped) {
+					m.logger.Error("Failed to notify person detection", zap.String("entity_id", camera.Sensor), zap.Error(err))
+				}
+			} else {
+				event.Notified = true
+			}
+		}
+	}
+	event.Detail = strings.Join(details, "; ")
+
+	m.logger.Info("Person detected",
+		zap.String("entity_id", camera.Sensor),
+		zap.String("snapshot_url", event.SnapshotURL),
+		zap.Bool("notified", event.Notified),
+		zap.Bool("rate_limited", event.RateLimited))
+
+	m.updateShadowInputsWithTrigger(camera.Sensor)
+	m.shadowTracker.SnapshotInputsForAction()
+	m.shadowTracker.RecordPersonDetection(event)
+}
+
+// snapshotPerson saves a camera snapshot of the detection and returns its
+// path and the URL the dashboard loads it from
+func (m *Manager) snapshotPerson(camera PersonCameraConfig, at time.Time) (string, string, error) {
+	if camera.CameraEntity == "" {
+		return "", "", fmt.Errorf("%w: no camera_entity configured", errPersonSkipped)
+	}
+	replacer := strings.NewReplacer(
+		"{camera}", strings.TrimPrefix(camera.CameraEntity, "camera."),
+		"{timestamp}", at.In(m.timezone).Format("20060102_150405"))
+	path := replacer.Replace(m.person.SnapshotPathOrDefault())
+	url := replacer.Replace(m.person.SnapshotURLOrDefault())
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would snapshot camera for person detection",
+			zap.String("camera", camera.CameraEntity),
+			zap.String("filename", path))
+		return "", "", fmt.Errorf("%w: read-only mode", errPersonSkipped)
+	}
+
+	if err := m.haClient.CallService(m.ctx, "camera", "snapshot", map[string]interface{}{
+		"entity_id": camera.CameraEntity,
+		"filename":  path,
+	}); err != nil {
+		return "", "", err
+	}
+	return path, url, nil
+}
+
+// notifyPerson announces the detection on the security speakers and, if a
+// notify service is configured, pushes it with the snapshot attached
+func (m *Manager) notifyPerson(camera PersonCameraConfig, snapshotURL string) error {
+	message := personMessage(camera)
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would announce person detection",
+			zap.String("message", message),
+			zap.String("service", string(m.person.NotifyService)))
+		return fmt.Errorf("%w: read-only mode", errPersonSkipped)
+	}
+
+	push := m.person.NotifyService != ""
+	if push {
+		if err := notifyrouter.SendPush(m.ctx, m.haClient, m.person.NotifyService, notifyrouter.Push{
+			Title:   "Person detected",
+			Message: message,
+			Image:   snapshotURL,
+		}); err != nil {
+			return err
+		}
+	}
+
+	speakers, _ := m.dnd.Filter(m.ttsSpeakers())
+	speakers, _ = m.focus.Filter(speakers)
+	if len(speakers) == 0 {
+		if push {
+			return nil
+		}
+		return fmt.Errorf("%w: no speakers outside do-not-disturb and focus mode", errPersonSkipped)
+	}
+	err := m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Title:    "Person detected",
+		Message:  message,
+		Speakers: speakers,
+		Priority: tts.PriorityNormal,
+		PushSent: push,
+	})
+	if errors.Is(err, tts.ErrQuietHours) {
+		if push {
+			return nil
+		}
+		return fmt.Errorf("%w: quiet hours", errPersonSkipped)
+	}
+	return err
+}
+
+// setPersonVariable publishes whether the camera sees a person
+func (m *Manager) setPersonVariable(camera PersonCameraConfig, detected bool) {
+	if camera.StateVariable == "" {
+		return
+	}
+	if err := m.stateManager.SetBool(camera.StateVariable, detected); err != nil {
+		m.logger.Error("Failed to set person detection variable",
+			zap.String("key", camera.StateVariable),
+			zap.Bool("detected", detected),
+			zap.Error(err))
+	}
+}
+
+// personCameraConfig returns the person detection settings for a sensor
+func (m *Manager) personCameraConfig(entityID string) (PersonCameraConfig, bool) {
+	for _, camera := range m.person.Cameras {
+		if camera.Sensor == entityID {
+			return camera, true
+		}
+	}
+	return PersonCameraConfig{}, false
+}
+
+// personDetected reports whether a person sensor sees someone: a binary
+// sensor that is on, or a count sensor above zero
+func personDetected(sensor *ha.State) bool {
+	if sensor == nil {
+		return false
+	}
+	if sensor.State == "on" {
+		return true
+	}
+	count, err := strconv.ParseFloat(sensor.State, 64)
+	return err == nil && count > 0
+}
+
+// personMessage builds an announcement such as "Someone is at the front door"
+func personMessage(camera PersonCameraConfig) string {
+	if camera.Name == "" {
+		return "Someone is outside"
+	}
+	return fmt.Sprintf("Someone is at %s", camera.Name)
+}
@@ -0,0 +1,204 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newPersonTestManager(t *testing.T, readOnly bool, drivewayCount string) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	mockHA := ha.NewMockClient()
+	mockHA.SetState("binary_sensor.front_door_person_occupancy", "off", nil)
+	mockHA.SetState("sensor.driveway_person_count", drivewayCount, nil)
+	mockHA.Connect(context.Background())
+
+	logger := zap.NewNop()
+	stateManager := state.NewManager(mockHA, logger, false)
+	stateManager.SyncFromHA()
+
+	mockClock := clock.NewMockClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	securityManager := NewManager(mockHA, stateManager, logger, readOnly, nil)
+	securityManager.SetClock(mockClock)
+	securityManager.SetConfig(&SecurityConfig{Security: SecuritySettings{
+		RateLimits: RateLimitConfig{PersonDetection: RateLimitPolicy{WindowSeconds: 60}},
+		PersonDetection: PersonDetectionConfig{
+			NotifyService: "notify.mobile_app_phone",
+			Cameras: []PersonCameraConfig{
+				{
+					Sensor:        "binary_sensor.front_door_person_occupancy",
+					Name:          "the front door",
+					StateVariable: "isPersonAtFrontDoor",
+					CameraEntity:  "camera.front_door",
+					Notify:        true,
+				},
+				{
+					Sensor:        "sensor.driveway_person_count",
+					Name:          "the driveway",
+					StateVariable: "isPersonInDriveway",
+				},
+			},
+		},
+	}})
+	if err := securityManager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start security manager: %v", err)
+	}
+	t.Cleanup(securityManager.Stop)
+
+	mockHA.ClearServiceCalls()
+	return securityManager, mockHA, stateManager, mockClock
+}
+
+// TestSecurityManager_PersonDetected tests that a detection sets the state
+// variable, snapshots the camera, notifies with the snapshot and is recorded
+func TestSecurityManager_PersonDetected(t *testing.T) {
+	securityManager, mockHA, stateManager, _ := newPersonTestManager(t, false, "0")
+
+	mockHA.SimulateStateChange("binary_sensor.front_door_person_occupancy", "on")
+
+	if atDoor, _ := stateManager.GetBool("isPersonAtFrontDoor"); !atDoor {
+		t.Error("Expected isPersonAtFrontDoor to be true")
+	}
+
+	var snapshot, push *ha.ServiceCall
+	calls := mockHA.GetServiceCalls()
+	for i := range calls {
+		switch {
+		case calls[i].Domain == "camera" && calls[i].Service == "snapshot":
+			snapshot = &calls[i]
+		case calls[i].Domain == "notify" && calls[i].Service == "mobile_app_phone":
+			push = &calls[i]
+		}
+	}
+	if snapshot == nil || snapshot.Data["entity_id"] != "camera.front_door" || snapshot.Data["filename"] != "/config/www/snapshots/person_front_door_20240101_120000.jpg" {
+		t.Fatalf("Expected a front door snapshot, got %v", calls)
+	}
+	if push == nil || push.Data["message"] != "Someone is at the front door" {
+		t.Fatalf("Expected a push notification, got %v", calls)
+	}
+	if image := push.Data["data"].(map[string]interface{})["image"]; image != "/local/snapshots/person_front_door_20240101_120000.jpg" {
+		t.Errorf("Expected the snapshot URL attached, got %v", image)
+	}
+	if n := countServiceCalls(mockHA, "tts", "speak"); n != 1 {
+		t.Errorf("Expected one TTS announcement, got %d", n)
+	}
+
+	detections := securityManager.GetShadowState().Outputs.PersonDetections
+	if len(detections) != 1 {
+		t.Fatalf("Expected one recorded detection, got %d", len(detections))
+	}
+	event := detections[0]
+	if event.Sensor != "binary_sensor.front_door_person_occupancy" || event.StateVariable != "isPersonAtFrontDoor" {
+		t.Errorf("Unexpected detection: %+v", event)
+	}
+	if event.SnapshotURL != "/local/snapshots/person_front_door_20240101_120000.jpg" || !event.Notified || event.RateLimited {
+		t.Errorf("Unexpected detection: %+v", event)
+	}
+
+	mockHA.SimulateStateChange("binary_sensor.front_door_person_occupancy", "off")
+	if atDoor, _ := stateManager.GetBool("isPersonAtFrontDoor"); atDoor {
+		t.Error("Expected isPersonAtFrontDoor to be false once the person leaves")
+	}
+	if n := len(securityManager.GetShadowState().Outputs.PersonDetections); n != 1 {
+		t.Errorf("Expected the person leaving not to be recorded, got %d detections", n)
+	}
+}
+
+// TestSecurityManager_PersonDetectionRateLimited tests that repeat detections
+// within the window are recorded but not notified
+func TestSecurityManager_PersonDetectionRateLimited(t *testing.T) {
+	securityManager, mockHA, _, mockClock := newPersonTestManager(t, false, "0")
+
+	for i := 0; i < 2; i++ {
+		mockHA.SimulateStateChange("binary_sensor.front_door_person_occupancy", "on")
+		mockHA.SimulateStateChange("binary_sensor.front_door_person_occupancy", "off")
+		mockClock.Advance(10 * time.Second)
+	}
+
+	if n := countServiceCalls(mockHA, "notify", "mobile_app_phone"); n != 1 {
+		t.Errorf("Expected one notification within the window, got %d", n)
+	}
+	if n := countServiceCalls(mockHA, "camera", "snapshot"); n != 2 {
+		t.Errorf("Expected every detection to be snapshotted, got %d", n)
+	}
+	detections := securityManager.GetShadowState().Outputs.PersonDetections
+	if len(detections) != 2 || !detections[1].RateLimited || detections[1].Notified {
+		t.Fatalf("Expected the second detection to be rate limited, got %+v", detections)
+	}
+	if !securityManager.GetShadowState().Outputs.RateLimits[rateLimitPersonDetection].Limited {
+		t.Error("Expected the person detection rate limit to be published as limited")
+	}
+
+	mockClock.Advance(PersonDetectionRateLimit)
+	mockHA.SimulateStateChange("binary_sensor.front_door_person_occupancy", "on")
+	if n := countServiceCalls(mockHA, "notify", "mobile_app_phone"); n != 2 {
+		t.Errorf("Expected a notification once the window passed, got %d", n)
+	}
+}
+
+// TestSecurityManager_PersonCountSensor tests that a count sensor detects on
+// the first person only, and that cameras without notify stay quiet
+func TestSecurityManager_PersonCountSensor(t *testing.T) {
+	securityManager, mockHA, stateManager, _ := newPersonTestManager(t, false, "0")
+
+	mockHA.SimulateStateChange("sensor.driveway_person_count", "1")
+	mockHA.SimulateStateChange("sensor.driveway_person_count", "2")
+
+	if inDriveway, _ := stateManager.GetBool("isPersonInDriveway"); !inDriveway {
+		t.Error("Expected isPersonInDriveway to be true")
+	}
+	if n := countServiceCalls(mockHA, "notify", "mobile_app_phone") + countServiceCalls(mockHA, "tts", "speak"); n != 0 {
+		t.Errorf("Expected no notifications for a camera without notify, got %d", n)
+	}
+	detections := securityManager.GetShadowState().Outputs.PersonDetections
+	if len(detections) != 1 || detections[0].Detail == "" {
+		t.Fatalf("Expected one detection noting the missing camera, got %+v", detections)
+	}
+
+	mockHA.SimulateStateChange("sensor.driveway_person_count", "0")
+	if inDriveway, _ := stateManager.GetBool("isPersonInDriveway"); inDriveway {
+		t.Error("Expected isPersonInDriveway to be false with nobody counted")
+	}
+}
+
+// TestSecurityManager_PersonSeenAtStartup tests that a person already seen at
+// startup sets the variable without a notification
+func TestSecurityManager_PersonSeenAtStartup(t *testing.T) {
+	securityManager, mockHA, stateManager, _ := newPersonTestManager(t, false, "1")
+
+	if inDriveway, _ := stateManager.GetBool("isPersonInDriveway"); !inDriveway {
+		t.Error("Expected isPersonInDriveway to be true at startup")
+	}
+	if n := len(securityManager.GetShadowState().Outputs.PersonDetections); n != 0 {
+		t.Errorf("Expected no detections recorded at startup, got %d", n)
+	}
+	if calls := mockHA.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls, got %v", calls)
+	}
+}
+
+// TestSecurityManager_PersonDetectionReadOnly tests that read-only mode sets
+// the variable but skips the snapshot and notifications
+func TestSecurityManager_PersonDetectionReadOnly(t *testing.T) {
+	securityManager, mockHA, stateManager, _ := newPersonTestManager(t, true, "0")
+
+	mockHA.SimulateStateChange("binary_sensor.front_door_person_occupancy", "on")
+
+	if atDoor, _ := stateManager.GetBool("isPersonAtFrontDoor"); !atDoor {
+		t.Error("Expected isPersonAtFrontDoor to be true in read-only mode")
+	}
+	if calls := mockHA.GetServiceCalls(); len(calls) != 0 {
+		t.Errorf("Expected no service calls in read-only mode, got %v", calls)
+	}
+	detections := securityManager.GetShadowState().Outputs.PersonDetections
+	if len(detections) != 1 || detections[0].Notified || detections[0].SnapshotURL != "" {
+		t.Errorf("Expected an unnotified detection without a snapshot, got %+v", detections)
+	}
+}
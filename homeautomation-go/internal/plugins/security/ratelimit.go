@@ -0,0 +1,86 @@
+package security
+
+import (
+	"time"
+
+	"homeautomation/internal/shadowstate"
+)
+
+// Event types with their own notification rate limit
+const (
+	rateLimitDoorbell        = "doorbell"
+	rateLimitVehicleArrival  = "vehicle_arrival"
+	rateLimitPersonDetection = "person_detection"
+)
+
+// rateLimiter allows up to burst notifications within a sliding window.
+// It is not safe for concurrent use; the manager guards it with mu.
+type rateLimiter struct {
+	window              time.Duration
+	burst               int
+	bypassWhenExpecting bool
+
+	sent           []time.Time // Notification times within the window, oldest first
+	lastBypassedAt time.Time
+}
+
+// newRateLimiter creates a rate limiter from a policy, using defaultWindow if
+// the policy doesn't set one
+func newRateLimiter(policy RateLimitPolicy, defaultWindow time.Duration) *rateLimiter {
+	return &rateLimiter{
+		window:              policy.Window(defaultWindow),
+		burst:               policy.BurstOrDefault(),
+		bypassWhenExpecting: policy.BypassWhenExpecting,
+	}
+}
+
+// allow reports whether a notification may be sent now and, if so, records it.
+// While someone is expected a policy with bypassWhenExpecting always allows
+// the notification; it still counts toward the window.
+func (r *rateLimiter) allow(now time.Time, expectingSomeone bool) (allowed bool, bypassed bool) {
+	r.prune(now)
+
+	if len(r.sent) >= r.burst {
+		if !r.bypassWhenExpecting || !expectingSomeone {
+			return false, false
+		}
+		bypassed = true
+		r.lastBypassedAt = now
+	}
+
+	r.sent = append(r.sent, now)
+	return true, bypassed
+}
+
+// reset forgets all recorded notifications
+func (r *rateLimiter) reset() {
+	r.sent = nil
+}
+
+// prune drops notifications that have left the window
+func (r *rateLimiter) prune(now time.Time) {
+	i := 0
+	for i < len(r.sent) && now.Sub(r.sent[i]) >= r.window {
+		i++
+	}
+	r.sent = r.sent[i:]
+}
+
+// shadowState describes the limiter for the shadow state as of now
+func (r *rateLimiter) shadowState(now time.Time) shadowstate.RateLimitState {
+	r.prune(now)
+
+	state := shadowstate.RateLimitState{
+		WindowSeconds:       int(r.window / time.Second),
+		Burst:               r.burst,
+		BypassWhenExpecting: r.bypassWhenExpecting,
+		SentInWindow:        len(r.sent),
+		LastBypassedAt:      r.lastBypassedAt,
+	}
+	if len(r.sent) >= r.burst {
+		state.Limited = true
+		// A slot frees up when the oldest notification that keeps the window full expires
+		state.NextAllowedAt = r.sent[len(r.sent)-r.burst].Add(r.window)
+	}
+	return state
+}
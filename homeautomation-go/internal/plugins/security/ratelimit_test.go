@@ -0,0 +1,65 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Burst(t *testing.T) {
+	limiter := newRateLimiter(RateLimitPolicy{WindowSeconds: 60, Burst: 2}, DoorbellRateLimit)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, at := range []time.Duration{0, 10 * time.Second} {
+		if allowed, _ := limiter.allow(start.Add(at), false); !allowed {
+			t.Fatalf("Notification %d should be within the burst", i+1)
+		}
+	}
+	if allowed, _ := limiter.allow(start.Add(20*time.Second), false); allowed {
+		t.Error("Third notification within the window should be rate limited")
+	}
+
+	state := limiter.shadowState(start.Add(20 * time.Second))
+	if !state.Limited || state.SentInWindow != 2 {
+		t.Errorf("Expected limiter to be full with 2 notifications, got %+v", state)
+	}
+	if want := start.Add(60 * time.Second); !state.NextAllowedAt.Equal(want) {
+		t.Errorf("Expected next allowed at %v, got %v", want, state.NextAllowedAt)
+	}
+
+	// The first notification leaves the window after 60 seconds
+	if allowed, _ := limiter.allow(start.Add(60*time.Second), false); !allowed {
+		t.Error("Notification should be allowed once the oldest leaves the window")
+	}
+}
+
+func TestRateLimiter_Defaults(t *testing.T) {
+	limiter := newRateLimiter(RateLimitPolicy{}, VehicleArrivalRateLimit)
+
+	if limiter.window != VehicleArrivalRateLimit || limiter.burst != 1 {
+		t.Errorf("Expected default window %v and burst 1, got %v and %d", VehicleArrivalRateLimit, limiter.window, limiter.burst)
+	}
+}
+
+func TestRateLimiter_BypassWhenExpecting(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := newRateLimiter(RateLimitPolicy{BypassWhenExpecting: true}, DoorbellRateLimit)
+	limiter.allow(start, true)
+	allowed, bypassed := limiter.allow(start.Add(time.Second), true)
+	if !allowed || !bypassed {
+		t.Errorf("Expected limit to be bypassed while expecting someone, got allowed=%v bypassed=%v", allowed, bypassed)
+	}
+	if state := limiter.shadowState(start.Add(time.Second)); !state.LastBypassedAt.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected bypass time to be recorded, got %v", state.LastBypassedAt)
+	}
+
+	if allowed, _ := limiter.allow(start.Add(2*time.Second), false); allowed {
+		t.Error("Limit should apply when not expecting anyone")
+	}
+
+	strict := newRateLimiter(RateLimitPolicy{}, DoorbellRateLimit)
+	strict.allow(start, true)
+	if allowed, _ := strict.allow(start.Add(time.Second), true); allowed {
+		t.Error("Limit should apply while expecting someone unless the policy allows bypass")
+	}
+}
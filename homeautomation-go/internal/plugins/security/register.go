@@ -1,8 +1,12 @@
 package security
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"homeautomation/internal/entities"
 	pkgha "homeautomation/pkg/ha"
 	"homeautomation/pkg/plugin"
 	pkgstate "homeautomation/pkg/state"
@@ -32,6 +36,27 @@ func createPlugin(ctx *plugin.Context) (plugin.Plugin, error) {
 	}
 
 	manager := NewManager(haClient, stateManager, ctx.Logger, ctx.ReadOnly, nil)
+
+	// security_config.yaml is optional for the reference plugin, and so is
+	// entities_config.yaml (the default entity IDs are used without it)
+	if ctx.ConfigDir != "" {
+		config, err := LoadConfig(filepath.Join(ctx.ConfigDir, "security_config.yaml"))
+		switch {
+		case err == nil:
+			manager.SetConfig(config)
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf("failed to load security config: %w", err)
+		}
+
+		entitiesConfig, err := entities.LoadConfig(filepath.Join(ctx.ConfigDir, "entities_config.yaml"))
+		switch {
+		case err == nil:
+			manager.SetEntities(entities.NewRegistry(entitiesConfig))
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf("failed to load entities config: %w", err)
+		}
+	}
+
 	return &pluginAdapter{manager: manager}, nil
 }
 
@@ -40,12 +65,20 @@ type pluginAdapter struct {
 	manager *Manager
 }
 
+var (
+	_ plugin.Plugin              = (*pluginAdapter)(nil)
+	_ plugin.Resettable          = (*pluginAdapter)(nil)
+	_ plugin.ShadowStateProvider = (*pluginAdapter)(nil)
+)
+
 func (p *pluginAdapter) Name() string {
 	return "security"
 }
 
+// Start starts the manager; plugin.Plugin has no context, so its work runs
+// until Stop
 func (p *pluginAdapter) Start() error {
-	return p.manager.Start()
+	return p.manager.Start(context.Background())
 }
 
 func (p *pluginAdapter) Stop() {
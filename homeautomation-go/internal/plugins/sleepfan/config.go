@@ -0,0 +1,134 @@
+package sleepfan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpeedBand sets the fan speed from a room temperature upwards
+type SpeedBand struct {
+	MinTemperature float64 `yaml:"min_temperature"`
+	Speed          int     `yaml:"speed"` // Fan percentage, 0 = off
+}
+
+// Bedroom configures the fan of one bedroom
+type Bedroom struct {
+	Name                   string      `yaml:"name"`
+	AsleepVariable         string      `yaml:"asleep_variable"` // Boolean state variable, e.g. isMasterAsleep
+	FanEntity              string      `yaml:"fan_entity"`      // fan.* supporting set_percentage
+	TemperatureSensor      string      `yaml:"temperature_sensor"`
+	WindowSensor           string      `yaml:"window_sensor"` // Optional binary_sensor; the fan is off while it's on
+	Bands                  []SpeedBand `yaml:"bands"`         // Ascending by min_temperature
+	MaxStep                int         `yaml:"max_step"`      // Largest speed change at once, in percentage points
+	MinStepIntervalMinutes int         `yaml:"min_step_interval_minutes"`
+}
+
+// SleepFanSettings lists the bedrooms whose fans are controlled overnight
+type SleepFanSettings struct {
+	Bedrooms []Bedroom `yaml:"bedrooms"`
+}
+
+// SleepFanConfig represents the sleep_fan_config.yaml structure
+type SleepFanConfig struct {
+	SleepFan SleepFanSettings `yaml:"sleep_fan"`
+}
+
+// MinStepInterval returns the minimum time between two speed changes
+func (b *Bedroom) MinStepInterval() time.Duration {
+	return time.Duration(b.MinStepIntervalMinutes) * time.Minute
+}
+
+// TargetSpeed returns the speed of the band a temperature falls in. Below
+// the first band the fan is off.
+func (b *Bedroom) TargetSpeed(temperature float64) int {
+	speed := 0
+	for _, band := range b.Bands {
+		if temperature < band.MinTemperature {
+			break
+		}
+		speed = band.Speed
+	}
+	return speed
+}
+
+// Validate checks every bedroom's fan, sensors, bands and step limits
+func (c *SleepFanConfig) Validate() error {
+	variables := state.VariablesByKey()
+	names := make(map[string]bool)
+	fans := make(map[string]string)
+
+	for i, b := range c.SleepFan.Bedrooms {
+		if b.Name == "" {
+			return fmt.Errorf("bedrooms[%d]: name is required", i)
+		}
+		if names[b.Name] {
+			return fmt.Errorf("bedrooms[%d]: duplicate bedroom %q", i, b.Name)
+		}
+		names[b.Name] = true
+
+		variable, ok := variables[b.AsleepVariable]
+		if !ok {
+			return fmt.Errorf("bedroom %q: unknown asleep_variable %q", b.Name, b.AsleepVariable)
+		}
+		if variable.Type != state.TypeBool {
+			return fmt.Errorf("bedroom %q: asleep_variable %s is not a boolean", b.Name, b.AsleepVariable)
+		}
+
+		if !strings.HasPrefix(b.FanEntity, "fan.") {
+			return fmt.Errorf("bedroom %q: fan_entity must be a fan.* entity, got %q", b.Name, b.FanEntity)
+		}
+		if other, ok := fans[b.FanEntity]; ok {
+			return fmt.Errorf("bedroom %q: fan %s is already controlled by bedroom %q", b.Name, b.FanEntity, other)
+		}
+		fans[b.FanEntity] = b.Name
+
+		if b.TemperatureSensor == "" {
+			return fmt.Errorf("bedroom %q: temperature_sensor is required", b.Name)
+		}
+
+		if len(b.Bands) == 0 {
+			return fmt.Errorf("bedroom %q: at least one band is required", b.Name)
+		}
+		for j, band := range b.Bands {
+			if band.Speed < 0 || band.Speed > 100 {
+				return fmt.Errorf("bedroom %q: bands[%d]: speed must be between 0 and 100", b.Name, j)
+			}
+			if j > 0 && band.MinTemperature <= b.Bands[j-1].MinTemperature {
+				return fmt.Errorf("bedroom %q: bands[%d]: min_temperature must be above the previous band's", b.Name, j)
+			}
+		}
+
+		if b.MaxStep <= 0 || b.MaxStep > 100 {
+			return fmt.Errorf("bedroom %q: max_step must be between 1 and 100", b.Name)
+		}
+		if b.MinStepIntervalMinutes < 0 {
+			return fmt.Errorf("bedroom %q: min_step_interval_minutes must not be negative", b.Name)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the sleep fan configuration from a YAML file
+func LoadConfig(path string) (*SleepFanConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config SleepFanConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
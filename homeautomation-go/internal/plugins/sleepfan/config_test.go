@@ -0,0 +1,120 @@
+package sleepfan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sleep_fan_config.yaml")
+
+	configContent := `---
+sleep_fan:
+  bedrooms:
+    - name: primary_suite
+      asleep_variable: isMasterAsleep
+      fan_entity: fan.bedroom
+      temperature_sensor: sensor.bedroom_temperature
+      window_sensor: binary_sensor.bedroom_window
+      bands:
+        - min_temperature: 68
+          speed: 20
+        - min_temperature: 71
+          speed: 50
+      max_step: 10
+      min_step_interval_minutes: 15
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	require.Len(t, config.SleepFan.Bedrooms, 1)
+	b := config.SleepFan.Bedrooms[0]
+	assert.Equal(t, "primary_suite", b.Name)
+	assert.Equal(t, "isMasterAsleep", b.AsleepVariable)
+	assert.Equal(t, "binary_sensor.bedroom_window", b.WindowSensor)
+	assert.Len(t, b.Bands, 2)
+	assert.Equal(t, 10, b.MaxStep)
+	assert.Equal(t, 15*time.Minute, b.MinStepInterval())
+}
+
+func TestLoadConfig_FileNotFound(t *testing.T) {
+	_, err := LoadConfig("/nonexistent/sleep_fan_config.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_RepoConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/sleep_fan_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.SleepFan.Bedrooms)
+}
+
+func TestTargetSpeed(t *testing.T) {
+	b := Bedroom{Bands: []SpeedBand{
+		{MinTemperature: 68, Speed: 20},
+		{MinTemperature: 71, Speed: 50},
+	}}
+
+	assert.Equal(t, 0, b.TargetSpeed(67.9))
+	assert.Equal(t, 20, b.TargetSpeed(68))
+	assert.Equal(t, 20, b.TargetSpeed(70.9))
+	assert.Equal(t, 50, b.TargetSpeed(71))
+	assert.Equal(t, 50, b.TargetSpeed(80))
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *SleepFanConfig {
+		return &SleepFanConfig{SleepFan: SleepFanSettings{Bedrooms: []Bedroom{
+			{
+				Name:              "primary_suite",
+				AsleepVariable:    "isMasterAsleep",
+				FanEntity:         "fan.primary",
+				TemperatureSensor: "sensor.primary_temperature",
+				Bands:             []SpeedBand{{MinTemperature: 68, Speed: 20}, {MinTemperature: 71, Speed: 50}},
+				MaxStep:           10,
+			},
+			{
+				Name:              "guest",
+				AsleepVariable:    "isGuestAsleep",
+				FanEntity:         "fan.guest",
+				TemperatureSensor: "sensor.guest_temperature",
+				Bands:             []SpeedBand{{MinTemperature: 70, Speed: 30}},
+				MaxStep:           10,
+			},
+		}}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*SleepFanConfig)
+	}{
+		{"missing name", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].Name = "" }},
+		{"duplicate name", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[1].Name = "primary_suite" }},
+		{"unknown asleep variable", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].AsleepVariable = "isNobodyAsleep" }},
+		{"non-boolean asleep variable", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].AsleepVariable = "dayPhase" }},
+		{"not a fan", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].FanEntity = "switch.primary_fan" }},
+		{"shared fan", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[1].FanEntity = "fan.primary" }},
+		{"missing temperature sensor", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].TemperatureSensor = "" }},
+		{"no bands", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].Bands = nil }},
+		{"speed out of range", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].Bands[1].Speed = 120 }},
+		{"bands out of order", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].Bands[1].MinTemperature = 68 }},
+		{"zero step", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].MaxStep = 0 }},
+		{"negative interval", func(c *SleepFanConfig) { c.SleepFan.Bedrooms[0].MinStepIntervalMinutes = -1 }},
+	}
+
+	require.NoError(t, valid().Validate())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			assert.Error(t, config.Validate())
+		})
+	}
+}
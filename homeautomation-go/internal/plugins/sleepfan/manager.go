@@ -0,0 +1,439 @@
+// Package sleepfan adjusts bedroom fan speeds overnight. While a bedroom is
+// asleep its fan follows the speed band of the room temperature, moving in
+// small steps spaced out so the change doesn't wake anyone, and is turned
+// off outright while the bedroom window is open.
+package sleepfan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// bedroom is a configured bedroom and the fan speed this plugin last set
+type bedroom struct {
+	config *Bedroom
+
+	asleep      bool
+	windowOpen  bool
+	temperature *float64
+
+	speed      int       // Speed last commanded or read from the fan, 0 = off
+	controlled bool      // Speed was changed by this plugin since the bedroom fell asleep
+	lastStep   time.Time // When the speed was last changed
+	stepTimer  clock.Timer
+}
+
+// Manager steps each bedroom fan towards the speed band of the room
+// temperature while the bedroom is asleep
+type Manager struct {
+	haClient     ha.HAClient
+	stateManager *state.Manager
+	config       *SleepFanConfig
+	logger       *zap.Logger
+	readOnly     bool
+	clock        clock.Clock
+
+	// Bedrooms in config order (protected by mu)
+	bedrooms []*bedroom
+	mu       sync.Mutex
+
+	// Shadow state tracking
+	shadowTracker *shadowstate.SleepFanTracker
+
+	// Subscription helper for automatic shadow state input capture
+	subHelper *shadowstate.SubscriptionHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new Sleep Fan manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, config *SleepFanConfig, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
+	shadowTracker := shadowstate.NewSleepFanTracker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
+	m := &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("sleepfan")),
+		health:        recorder,
+		readOnly:      readOnly,
+		clock:         clock.NewRealClock(),
+		shadowTracker: shadowTracker,
+		subHelper:     shadowstate.NewSubscriptionHelper(haClient, stateManager, registry, shadowTracker, "sleepfan", logger.Named("sleepfan")),
+	}
+
+	for i := range config.SleepFan.Bedrooms {
+		b := &config.SleepFan.Bedrooms[i]
+		m.bedrooms = append(m.bedrooms, &bedroom{config: b})
+		shadowTracker.AddBedroom(b.Name, b.FanEntity)
+	}
+
+	return m
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// GetShadowState returns the current shadow state
+func (m *Manager) GetShadowState() *shadowstate.SleepFanShadowState {
+	return m.shadowTracker.GetState()
+}
+
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
+// Start subscribes to each bedroom's sleep state and sensors
+func (m *Manager) Start(ctx context.Context) error {
+	// Fan commands run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Sleep Fan Manager", zap.Int("bedrooms", len(m.bedrooms)))
+
+	for _, b := range m.bedrooms {
+		if err := m.subscribe(b); err != nil {
+			return err
+		}
+	}
+
+	m.subHelper.CaptureInitialInputs()
+
+	// Pick up bedrooms already asleep (e.g. after a restart)
+	m.mu.Lock()
+	for _, b := range m.bedrooms {
+		if temperature, err := m.readNumber(b.config.TemperatureSensor); err == nil {
+			b.temperature = &temperature
+		}
+		if b.config.WindowSensor != "" {
+			if st, err := m.haClient.GetState(m.ctx, b.config.WindowSensor); err == nil {
+				b.windowOpen = st.State == "on"
+			}
+		}
+		if asleep, err := m.stateManager.GetBool(b.config.AsleepVariable); err == nil && asleep {
+			m.fallAsleep(b)
+		} else {
+			m.evaluate(b, "startup")
+		}
+	}
+	m.mu.Unlock()
+
+	m.health.Started(len(m.subHelper.GetHASubscriptions()) + len(m.subHelper.GetStateSubscriptions()))
+	m.logger.Info("Sleep Fan Manager started successfully")
+	return nil
+}
+
+// Stop cancels pending steps and unsubscribes
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Sleep Fan Manager")
+
+	m.cancel()
+	m.subHelper.UnsubscribeAll()
+
+	m.mu.Lock()
+	for _, b := range m.bedrooms {
+		m.cancelStep(b)
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Sleep Fan Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// Reset re-reads each fan's speed and re-evaluates immediately, ignoring the
+// step interval
+func (m *Manager) Reset() error {
+	m.logger.Info("Resetting Sleep Fan - re-evaluating bedroom fans")
+
+	m.mu.Lock()
+	for _, b := range m.bedrooms {
+		if b.asleep {
+			b.speed = m.readFanSpeed(b)
+		}
+		b.lastStep = time.Time{}
+		m.evaluate(b, "reset")
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Successfully reset Sleep Fan")
+	return nil
+}
+
+// subscribe watches a bedroom's sleep state, temperature and window
+func (m *Manager) subscribe(b *bedroom) error {
+	if err := m.subHelper.SubscribeToState(b.config.AsleepVariable, func(_ string, _, newValue interface{}) {
+		m.handleAsleepChange(b, newValue)
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", b.config.AsleepVariable, err)
+	}
+
+	if err := m.subHelper.SubscribeToSensor(b.config.TemperatureSensor, func(value float64) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		b.temperature = &value
+		m.evaluate(b, b.config.TemperatureSensor)
+	}); err != nil {
+		return err
+	}
+
+	if b.config.WindowSensor != "" {
+		if err := m.subHelper.SubscribeToEntity(b.config.WindowSensor, func(entityID string, _, newState *ha.State) {
+			if newState == nil {
+				return
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			b.windowOpen = newState.State == "on"
+			m.evaluate(b, entityID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleAsleepChange starts controlling a bedroom's fan when it falls asleep
+// and turns off a fan this plugin ran when it wakes.
+// HA echoes our own writes with old == new, so compare against the bedroom
+// state rather than oldValue.
+func (m *Manager) handleAsleepChange(b *bedroom, newValue interface{}) {
+	asleep, ok := newValue.(bool)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case asleep && !b.asleep:
+		m.fallAsleep(b)
+	case !asleep && b.asleep:
+		m.wake(b)
+	}
+}
+
+// fallAsleep starts stepping from the fan's current speed; callers must hold mu
+func (m *Manager) fallAsleep(b *bedroom) {
+	b.asleep = true
+	b.speed = m.readFanSpeed(b)
+	b.controlled = false
+	b.lastStep = time.Time{}
+
+	m.logger.Info("Bedroom asleep, controlling fan speed",
+		zap.String("bedroom", b.config.Name),
+		zap.String("fan_entity", b.config.FanEntity),
+		zap.Int("speed", b.speed))
+
+	m.evaluate(b, b.config.AsleepVariable)
+}
+
+// wake stops stepping and turns off the fan if this plugin was running it;
+// callers must hold mu
+func (m *Manager) wake(b *bedroom) {
+	b.asleep = false
+	m.cancelStep(b)
+
+	m.logger.Info("Bedroom awake, releasing fan",
+		zap.String("bedroom", b.config.Name))
+
+	if b.controlled && b.speed > 0 {
+		m.setSpeed(b, 0, "Bedroom awake")
+	}
+	b.controlled = false
+
+	m.evaluate(b, b.config.AsleepVariable)
+}
+
+// evaluate makes at most one speed change towards the temperature band's
+// speed. An open window turns the fan off immediately; otherwise changes are
+// at most max_step apart and min_step_interval_minutes apart. Callers must
+// hold mu.
+func (m *Manager) evaluate(b *bedroom, trigger string) {
+	m.health.Tick()
+	c := b.config
+
+	target := 0
+	if b.temperature != nil {
+		target = c.TargetSpeed(*b.temperature)
+	}
+	m.shadowTracker.UpdateConditions(c.Name, b.asleep, b.windowOpen, b.temperature, target)
+
+	if !b.asleep {
+		return
+	}
+
+	if b.windowOpen {
+		m.cancelStep(b)
+		if b.speed > 0 {
+			m.setSpeed(b, 0, "Window open")
+		}
+		return
+	}
+
+	if b.temperature == nil {
+		m.logger.Debug("No bedroom temperature yet, leaving fan unchanged",
+			zap.String("bedroom", c.Name))
+		return
+	}
+	if target == b.speed {
+		m.cancelStep(b)
+		return
+	}
+
+	now := m.clock.Now()
+	if !b.lastStep.IsZero() {
+		if wait := b.lastStep.Add(c.MinStepInterval()).Sub(now); wait > 0 {
+			m.scheduleStep(b, wait)
+			return
+		}
+	}
+
+	next := target
+	if next > b.speed+c.MaxStep {
+		next = b.speed + c.MaxStep
+	}
+	if next < b.speed-c.MaxStep {
+		next = b.speed - c.MaxStep
+	}
+
+	reason := fmt.Sprintf("Temperature %.1f calls for %d%% (%s)", *b.temperature, target, trigger)
+	if err := m.setSpeed(b, next, reason); err != nil {
+		return
+	}
+
+	if next != target {
+		m.scheduleStep(b, c.MinStepInterval())
+	} else {
+		m.cancelStep(b)
+	}
+}
+
+// scheduleStep re-evaluates a bedroom once the step interval has passed;
+// callers must hold mu
+func (m *Manager) scheduleStep(b *bedroom, wait time.Duration) {
+	m.cancelStep(b)
+	b.stepTimer = m.clock.AfterFunc(wait, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		b.stepTimer = nil
+		m.evaluate(b, "step")
+	})
+}
+
+// cancelStep cancels a pending step; callers must hold mu
+func (m *Manager) cancelStep(b *bedroom) {
+	if b.stepTimer != nil {
+		b.stepTimer.Stop()
+		b.stepTimer = nil
+	}
+}
+
+// setSpeed sets the bedroom fan's percentage, turning it off at 0, and
+// records the change
+func (m *Manager) setSpeed(b *bedroom, speed int, reason string) error {
+	entityID := b.config.FanEntity
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set bedroom fan speed",
+			zap.String("bedroom", b.config.Name),
+			zap.String("entity_id", entityID),
+			zap.Int("speed", speed),
+			zap.String("reason", reason))
+	} else {
+		var err error
+		if speed == 0 {
+			err = m.haClient.CallService(m.ctx, "fan", "turn_off", map[string]interface{}{
+				"entity_id": entityID,
+			})
+		} else {
+			err = m.haClient.CallService(m.ctx, "fan", "set_percentage", map[string]interface{}{
+				"entity_id":  entityID,
+				"percentage": speed,
+			})
+		}
+		if err != nil {
+			m.logger.Error("Failed to set bedroom fan speed",
+				zap.String("bedroom", b.config.Name),
+				zap.String("entity_id", entityID),
+				zap.Int("speed", speed),
+				zap.Error(err))
+			return err
+		}
+	}
+
+	m.logger.Info("Bedroom fan speed changed",
+		zap.String("bedroom", b.config.Name),
+		zap.Int("from", b.speed),
+		zap.Int("to", speed),
+		zap.String("reason", reason))
+
+	now := m.clock.Now()
+	b.speed = speed
+	b.controlled = true
+	b.lastStep = now
+	m.shadowTracker.RecordSpeedChange(b.config.Name, speed, reason, now)
+	return nil
+}
+
+// readFanSpeed reads the fan's current percentage, 0 if it is off or can't be read
+func (m *Manager) readFanSpeed(b *bedroom) int {
+	st, err := m.haClient.GetState(m.ctx, b.config.FanEntity)
+	if err != nil {
+		m.logger.Warn("Failed to read bedroom fan, assuming off",
+			zap.String("entity_id", b.config.FanEntity),
+			zap.Error(err))
+		return 0
+	}
+	if st.State != "on" {
+		return 0
+	}
+	percentage, err := ha.ToFloat(st.Attributes["percentage"])
+	if err != nil {
+		return 0
+	}
+	return int(percentage)
+}
+
+// readNumber reads a numeric sensor state
+func (m *Manager) readNumber(entityID string) (float64, error) {
+	st, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		return 0, err
+	}
+	return ha.ToFloat(st.State)
+}
@@ -0,0 +1,227 @@
+package sleepfan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testFan         = "fan.primary_suite_ceiling_fan"
+	testTemperature = "sensor.primary_suite_temperature"
+	testWindow      = "binary_sensor.primary_suite_window"
+)
+
+func createTestConfig() *SleepFanConfig {
+	return &SleepFanConfig{SleepFan: SleepFanSettings{Bedrooms: []Bedroom{{
+		Name:              "primary_suite",
+		AsleepVariable:    "isMasterAsleep",
+		FanEntity:         testFan,
+		TemperatureSensor: testTemperature,
+		WindowSensor:      testWindow,
+		Bands: []SpeedBand{
+			{MinTemperature: 68, Speed: 20},
+			{MinTemperature: 70, Speed: 40},
+			{MinTemperature: 72, Speed: 60},
+		},
+		MaxStep:                20,
+		MinStepIntervalMinutes: 15,
+	}}}}
+}
+
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+
+	mockHA.SetState(testFan, "off", map[string]interface{}{})
+	mockHA.SetState(testTemperature, "66", map[string]interface{}{})
+	mockHA.SetState(testWindow, "off", map[string]interface{}{})
+
+	manager := NewManager(mockHA, stateManager, createTestConfig(), logger, readOnly, nil)
+	mockClock := clock.NewMockClock(time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC))
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	return manager, mockHA, stateManager, mockClock
+}
+
+// setAsleep sets isMasterAsleep and discards the resulting input_boolean service call
+func setAsleep(t *testing.T, mockHA *ha.MockClient, stateManager *state.Manager, asleep bool) {
+	t.Helper()
+	mockHA.ClearServiceCalls()
+	require.NoError(t, stateManager.SetBool("isMasterAsleep", asleep))
+}
+
+// fanCalls returns service calls made to the fan
+func fanCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var result []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "fan" {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+func TestStepsTowardsBandSpeed(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+	mockHA.SetState(testTemperature, "73", map[string]interface{}{})
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	calls := fanCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "set_percentage", calls[0].Service)
+	assert.Equal(t, 20, calls[0].Data["percentage"])
+
+	// The next step waits for the step interval
+	mockClock.Advance(10 * time.Minute)
+	assert.Len(t, fanCalls(mockHA), 1)
+
+	mockClock.Advance(5 * time.Minute)
+	calls = fanCalls(mockHA)
+	require.Len(t, calls, 2)
+	assert.Equal(t, 40, calls[1].Data["percentage"])
+
+	mockClock.Advance(15 * time.Minute)
+	calls = fanCalls(mockHA)
+	require.Len(t, calls, 3)
+	assert.Equal(t, 60, calls[2].Data["percentage"])
+
+	// Band speed reached, no further steps
+	mockClock.Advance(time.Hour)
+	assert.Len(t, fanCalls(mockHA), 3)
+
+	status := manager.GetShadowState().Outputs.Bedrooms[0]
+	assert.True(t, status.Asleep)
+	assert.Equal(t, 60, status.Speed)
+	assert.Equal(t, 60, status.TargetSpeed)
+}
+
+func TestTemperatureChangeRespectsStepInterval(t *testing.T) {
+	_, mockHA, stateManager, mockClock := setupTest(t, false)
+	mockHA.SetState(testTemperature, "69", map[string]interface{}{})
+	setAsleep(t, mockHA, stateManager, true)
+	require.Len(t, fanCalls(mockHA), 1)
+
+	// Room cools off shortly after the last change
+	mockClock.Advance(5 * time.Minute)
+	mockHA.SetState(testTemperature, "67", map[string]interface{}{})
+	assert.Len(t, fanCalls(mockHA), 1)
+
+	mockClock.Advance(10 * time.Minute)
+	calls := fanCalls(mockHA)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "turn_off", calls[1].Service)
+}
+
+func TestStartsFromCurrentFanSpeed(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, false)
+	mockHA.SetState(testFan, "on", map[string]interface{}{"percentage": 50.0})
+	mockHA.SetState(testTemperature, "73", map[string]interface{}{})
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	calls := fanCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, 60, calls[0].Data["percentage"])
+}
+
+func TestOpenWindowTurnsFanOff(t *testing.T) {
+	manager, mockHA, stateManager, mockClock := setupTest(t, false)
+	mockHA.SetState(testTemperature, "71", map[string]interface{}{})
+	setAsleep(t, mockHA, stateManager, true)
+	require.Len(t, fanCalls(mockHA), 1)
+
+	// Off immediately, regardless of the step interval
+	mockClock.Advance(time.Minute)
+	mockHA.SetState(testWindow, "on", map[string]interface{}{})
+
+	calls := fanCalls(mockHA)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "turn_off", calls[1].Service)
+
+	// Stays off while the window is open
+	mockClock.Advance(time.Hour)
+	mockHA.SetState(testTemperature, "73", map[string]interface{}{})
+	assert.Len(t, fanCalls(mockHA), 2)
+	assert.True(t, manager.GetShadowState().Outputs.Bedrooms[0].WindowOpen)
+
+	// Resumes stepping once it's closed
+	mockHA.SetState(testWindow, "off", map[string]interface{}{})
+	calls = fanCalls(mockHA)
+	require.Len(t, calls, 3)
+	assert.Equal(t, 20, calls[2].Data["percentage"])
+}
+
+func TestNoChangesWhileAwake(t *testing.T) {
+	manager, mockHA, _, _ := setupTest(t, false)
+
+	mockHA.SetState(testTemperature, "75", map[string]interface{}{})
+
+	assert.Empty(t, fanCalls(mockHA))
+	status := manager.GetShadowState().Outputs.Bedrooms[0]
+	assert.False(t, status.Asleep)
+	assert.Equal(t, 60, status.TargetSpeed)
+}
+
+func TestWakeTurnsOffControlledFan(t *testing.T) {
+	_, mockHA, stateManager, mockClock := setupTest(t, false)
+	mockHA.SetState(testTemperature, "71", map[string]interface{}{})
+	setAsleep(t, mockHA, stateManager, true)
+
+	mockClock.Advance(8 * time.Hour)
+	setAsleep(t, mockHA, stateManager, false)
+
+	calls := fanCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "turn_off", calls[0].Service)
+
+	// A pending step doesn't fire after waking
+	mockClock.Advance(time.Hour)
+	assert.Len(t, fanCalls(mockHA), 1)
+}
+
+func TestWakeLeavesUncontrolledFan(t *testing.T) {
+	_, mockHA, stateManager, _ := setupTest(t, false)
+	mockHA.SetState(testFan, "on", map[string]interface{}{"percentage": 20.0})
+	mockHA.SetState(testTemperature, "69", map[string]interface{}{})
+	setAsleep(t, mockHA, stateManager, true)
+	require.Empty(t, fanCalls(mockHA))
+
+	setAsleep(t, mockHA, stateManager, false)
+
+	assert.Empty(t, fanCalls(mockHA))
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	manager, mockHA, stateManager, _ := setupTest(t, true)
+	mockHA.SetState(testTemperature, "73", map[string]interface{}{})
+
+	setAsleep(t, mockHA, stateManager, true)
+
+	assert.Empty(t, fanCalls(mockHA))
+	// Changes are still recorded so the shadow state shows what would happen
+	status := manager.GetShadowState().Outputs.Bedrooms[0]
+	assert.Equal(t, 20, status.Speed)
+	assert.NotEmpty(t, status.LastReason)
+}
+
+func TestShadowStateImplementsActionTimeProvider(t *testing.T) {
+	manager, _, _, _ := setupTest(t, false)
+	var _ shadowstate.ActionTimeProvider = manager.GetShadowState()
+}
@@ -0,0 +1,156 @@
+package sleephygiene
+
+import (
+	"fmt"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// haCalendarTimeFormat is the format of a calendar entity's start_time attribute
+const haCalendarTimeFormat = "2006-01-02 15:04:05"
+
+// calendarEvent is the first timed event of the day on a person's calendar
+type calendarEvent struct {
+	person   CalendarPerson
+	summary  string
+	start    time.Time
+	wakeByAt time.Time // Start minus the preparation buffer
+}
+
+// SetAdaptiveWake enables moving alarmTime earlier when someone's first
+// calendar event of the day needs more preparation time than the schedule allows
+func (m *Manager) SetAdaptiveWake(config *AdaptiveWakeConfig) {
+	m.adaptiveWake = config
+}
+
+// updateAdaptiveWake computes today's alarm from the scheduled wake time and
+// each person's first calendar event, and writes it to alarmTime if it changed.
+// The alarm is locked in once the floor time passes.
+func (m *Manager) updateAdaptiveWake(now, scheduledWake time.Time) {
+	if m.adaptiveWake == nil || !m.adaptiveWake.AdaptiveWake.Enabled {
+		return
+	}
+
+	floor := m.adaptiveWake.FloorOn(now)
+	if !now.Before(floor) {
+		return
+	}
+
+	wake := shadowstate.AdaptiveWake{
+		ScheduledWake: scheduledWake,
+		AlarmTime:     scheduledWake,
+		Source:        "schedule",
+		ComputedAt:    now,
+	}
+
+	if event, ok := m.earliestCalendarEvent(now); ok && event.wakeByAt.Before(scheduledWake) {
+		wake.Source = "calendar"
+		wake.Person = event.person.Name
+		wake.CalendarEntity = event.person.CalendarEntity
+		wake.EventSummary = event.summary
+		wake.EventStart = event.start
+		wake.AlarmTime = event.wakeByAt
+		if wake.AlarmTime.Before(floor) {
+			wake.AlarmTime = floor
+			wake.ClampedToFloor = true
+		}
+		wake.AdjustmentMinutes = int(scheduledWake.Sub(wake.AlarmTime) / time.Minute)
+	}
+
+	m.shadowTracker.UpdateAdaptiveWake(wake)
+
+	alarmMillis := float64(wake.AlarmTime.UnixMilli())
+	if current, err := m.stateManager.GetNumber("alarmTime"); err == nil && current == alarmMillis {
+		return
+	}
+
+	m.logger.Info("Adaptive wake updated alarm time",
+		zap.String("source", wake.Source),
+		zap.String("person", wake.Person),
+		zap.Time("scheduled_wake", scheduledWake),
+		zap.Time("alarm_time", wake.AlarmTime),
+		zap.Int("adjustment_minutes", wake.AdjustmentMinutes),
+		zap.Bool("clamped_to_floor", wake.ClampedToFloor))
+
+	reason := fmt.Sprintf("Alarm set to scheduled wake %s", wake.AlarmTime.Format("15:04"))
+	if wake.Source == "calendar" {
+		reason = fmt.Sprintf("Alarm moved %d minutes earlier to %s for %s's %q at %s",
+			wake.AdjustmentMinutes, wake.AlarmTime.Format("15:04"), wake.Person, wake.EventSummary, wake.EventStart.Format("15:04"))
+	}
+	m.recordAction("adaptive_wake", reason, "calendar")
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would set alarmTime", zap.Time("alarm_time", wake.AlarmTime))
+		return
+	}
+	if err := m.stateManager.SetNumber("alarmTime", alarmMillis); err != nil {
+		m.logger.Error("Failed to set alarmTime", zap.Error(err))
+	}
+}
+
+// earliestCalendarEvent returns the event that needs the earliest wake-up
+// among each person's first timed event today. All-day events are ignored.
+func (m *Manager) earliestCalendarEvent(now time.Time) (calendarEvent, bool) {
+	var earliest calendarEvent
+	found := false
+
+	for _, person := range m.adaptiveWake.AdaptiveWake.People {
+		event, ok := m.firstCalendarEvent(person, now)
+		if !ok {
+			continue
+		}
+		if !found || event.wakeByAt.Before(earliest.wakeByAt) {
+			earliest = event
+			found = true
+		}
+	}
+	return earliest, found
+}
+
+// firstCalendarEvent reads a person's calendar entity, which holds the
+// current or next upcoming event, and returns it if it starts today
+func (m *Manager) firstCalendarEvent(person CalendarPerson, now time.Time) (calendarEvent, bool) {
+	calendar, err := m.haClient.GetState(m.ctx, person.CalendarEntity)
+	if err != nil {
+		m.logger.Warn("Failed to read calendar",
+			zap.String("person", person.Name),
+			zap.String("calendar_entity", person.CalendarEntity),
+			zap.Error(err))
+		return calendarEvent{}, false
+	}
+
+	if allDay, _ := calendar.Attributes["all_day"].(bool); allDay {
+		return calendarEvent{}, false
+	}
+	startStr, ok := calendar.Attributes["start_time"].(string)
+	if !ok || startStr == "" {
+		return calendarEvent{}, false
+	}
+	start, err := time.ParseInLocation(haCalendarTimeFormat, startStr, now.Location())
+	if err != nil {
+		m.logger.Warn("Calendar has an unparseable start_time",
+			zap.String("calendar_entity", person.CalendarEntity),
+			zap.String("start_time", startStr))
+		return calendarEvent{}, false
+	}
+	if !isSameDay(start, now) {
+		return calendarEvent{}, false
+	}
+
+	summary, _ := calendar.Attributes["message"].(string)
+	return calendarEvent{
+		person:   person,
+		summary:  summary,
+		start:    start,
+		wakeByAt: start.Add(-m.adaptiveWake.PreparationBuffer()),
+	}, true
+}
+
+// scheduledWakeOn returns the schedule's wake time on the day of now
+func scheduledWakeOn(now, wake time.Time) time.Time {
+	return clock.DayAt(now, wake)
+}
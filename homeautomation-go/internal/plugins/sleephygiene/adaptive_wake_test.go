@@ -0,0 +1,180 @@
+package sleephygiene
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+)
+
+func newTestAdaptiveWake() *AdaptiveWakeConfig {
+	return &AdaptiveWakeConfig{AdaptiveWake: AdaptiveWakeSettings{
+		Enabled:                  true,
+		PreparationBufferMinutes: 90,
+		FloorTime:                "06:30",
+		People: []CalendarPerson{
+			{Name: "Nick", CalendarEntity: "calendar.nick_work"},
+			{Name: "Caroline", CalendarEntity: "calendar.caroline_work"},
+		},
+	}}
+}
+
+func setCalendarEvent(mockHA *ha.MockClient, entityID, summary, start string, allDay bool) {
+	mockHA.SetState(entityID, "off", map[string]interface{}{
+		"message":    summary,
+		"start_time": start,
+		"all_day":    allDay,
+	})
+}
+
+func TestAdaptiveWake(t *testing.T) {
+	now := time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)
+	scheduledWake := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		nickEvent      string
+		carolineEvent  string
+		carolineAllDay bool
+		expectedAlarm  time.Time
+		expectedSource string
+		expectedPerson string
+		expectedClamp  bool
+	}{
+		{
+			name:           "no events keeps schedule",
+			expectedAlarm:  scheduledWake,
+			expectedSource: "schedule",
+		},
+		{
+			name:           "late meeting keeps schedule",
+			nickEvent:      "2024-01-15 11:00:00",
+			expectedAlarm:  scheduledWake,
+			expectedSource: "schedule",
+		},
+		{
+			name:           "early meeting moves alarm",
+			nickEvent:      "2024-01-15 09:30:00",
+			expectedAlarm:  time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+			expectedSource: "calendar",
+			expectedPerson: "Nick",
+		},
+		{
+			name:           "earliest person wins",
+			nickEvent:      "2024-01-15 09:30:00",
+			carolineEvent:  "2024-01-15 09:00:00",
+			expectedAlarm:  time.Date(2024, 1, 15, 7, 30, 0, 0, time.UTC),
+			expectedSource: "calendar",
+			expectedPerson: "Caroline",
+		},
+		{
+			name:           "clamped to floor",
+			nickEvent:      "2024-01-15 07:00:00",
+			expectedAlarm:  time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC),
+			expectedSource: "calendar",
+			expectedPerson: "Nick",
+			expectedClamp:  true,
+		},
+		{
+			name:           "all-day and tomorrow's events ignored",
+			nickEvent:      "2024-01-16 08:00:00",
+			carolineEvent:  "2024-01-15 00:00:00",
+			carolineAllDay: true,
+			expectedAlarm:  scheduledWake,
+			expectedSource: "schedule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, mockHA, stateManager, _ := setupTest(t, now)
+			manager.SetAdaptiveWake(newTestAdaptiveWake())
+			if tt.nickEvent != "" {
+				setCalendarEvent(mockHA, "calendar.nick_work", "Standup", tt.nickEvent, false)
+			}
+			if tt.carolineEvent != "" {
+				setCalendarEvent(mockHA, "calendar.caroline_work", "Flight", tt.carolineEvent, tt.carolineAllDay)
+			}
+
+			manager.updateAdaptiveWake(now, scheduledWake)
+
+			alarmTime, err := stateManager.GetNumber("alarmTime")
+			if err != nil {
+				t.Fatalf("Failed to get alarmTime: %v", err)
+			}
+			if alarmTime != float64(tt.expectedAlarm.UnixMilli()) {
+				t.Errorf("Expected alarmTime %v, got %v", tt.expectedAlarm, time.UnixMilli(int64(alarmTime)).UTC())
+			}
+
+			wake := manager.GetShadowState().Outputs.AdaptiveWake
+			if wake == nil {
+				t.Fatal("Expected adaptive wake in shadow state")
+			}
+			if wake.Source != tt.expectedSource {
+				t.Errorf("Expected source %q, got %q", tt.expectedSource, wake.Source)
+			}
+			if wake.Person != tt.expectedPerson {
+				t.Errorf("Expected person %q, got %q", tt.expectedPerson, wake.Person)
+			}
+			if wake.ClampedToFloor != tt.expectedClamp {
+				t.Errorf("Expected clampedToFloor %v, got %v", tt.expectedClamp, wake.ClampedToFloor)
+			}
+			expectedAdjustment := int(scheduledWake.Sub(tt.expectedAlarm) / time.Minute)
+			if wake.AdjustmentMinutes != expectedAdjustment {
+				t.Errorf("Expected adjustment %d minutes, got %d", expectedAdjustment, wake.AdjustmentMinutes)
+			}
+		})
+	}
+}
+
+func TestAdaptiveWake_LockedAfterFloor(t *testing.T) {
+	now := time.Date(2024, 1, 15, 6, 45, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetAdaptiveWake(newTestAdaptiveWake())
+	setCalendarEvent(mockHA, "calendar.nick_work", "Standup", "2024-01-15 08:00:00", false)
+
+	manager.updateAdaptiveWake(now, time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+
+	alarmTime, _ := stateManager.GetNumber("alarmTime")
+	if alarmTime != 0 {
+		t.Errorf("Expected alarmTime to be left alone after the floor time, got %v", alarmTime)
+	}
+	if manager.GetShadowState().Outputs.AdaptiveWake != nil {
+		t.Error("Expected no adaptive wake computation after the floor time")
+	}
+}
+
+func TestAdaptiveWake_Disabled(t *testing.T) {
+	now := time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	config := newTestAdaptiveWake()
+	config.AdaptiveWake.Enabled = false
+	manager.SetAdaptiveWake(config)
+	setCalendarEvent(mockHA, "calendar.nick_work", "Standup", "2024-01-15 08:00:00", false)
+
+	manager.updateAdaptiveWake(now, time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+
+	alarmTime, _ := stateManager.GetNumber("alarmTime")
+	if alarmTime != 0 {
+		t.Errorf("Expected alarmTime unchanged while disabled, got %v", alarmTime)
+	}
+}
+
+func TestAdaptiveWake_ReadOnly(t *testing.T) {
+	now := time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.readOnly = true
+	manager.SetAdaptiveWake(newTestAdaptiveWake())
+	setCalendarEvent(mockHA, "calendar.nick_work", "Standup", "2024-01-15 08:00:00", false)
+
+	manager.updateAdaptiveWake(now, time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+
+	alarmTime, _ := stateManager.GetNumber("alarmTime")
+	if alarmTime != 0 {
+		t.Errorf("Expected alarmTime unchanged in read-only mode, got %v", alarmTime)
+	}
+	wake := manager.GetShadowState().Outputs.AdaptiveWake
+	if wake == nil || wake.Source != "calendar" {
+		t.Errorf("Expected the calendar computation in shadow state, got %+v", wake)
+	}
+}
@@ -0,0 +1,303 @@
+package sleephygiene
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CalendarPerson is someone whose first calendar event of the day can move the alarm earlier
+type CalendarPerson struct {
+	Name           string `yaml:"name"`
+	CalendarEntity string `yaml:"calendar_entity"`
+}
+
+// AdaptiveWakeSettings controls shifting alarmTime earlier for early meetings
+type AdaptiveWakeSettings struct {
+	Enabled                  bool             `yaml:"enabled"`
+	PreparationBufferMinutes int              `yaml:"preparation_buffer_minutes"`
+	FloorTime                string           `yaml:"floor_time"` // "HH:MM"; the alarm is never moved earlier than this
+	People                   []CalendarPerson `yaml:"people"`
+}
+
+// AdaptiveWakeConfig represents the adaptive_wake_config.yaml structure
+type AdaptiveWakeConfig struct {
+	AdaptiveWake AdaptiveWakeSettings `yaml:"adaptive_wake"`
+}
+
+// PreparationBuffer returns how long before the first event the alarm should go off
+func (c *AdaptiveWakeConfig) PreparationBuffer() time.Duration {
+	return time.Duration(c.AdaptiveWake.PreparationBufferMinutes) * time.Minute
+}
+
+// FloorOn returns the floor time on the day of t, in t's location
+func (c *AdaptiveWakeConfig) FloorOn(t time.Time) time.Time {
+	floor, err := time.Parse("15:04", c.AdaptiveWake.FloorTime)
+	if err != nil {
+		return clock.WallClock(t, 0, 0)
+	}
+	return clock.DayAt(t, floor)
+}
+
+// Validate checks the buffer, floor time and calendar entities
+func (c *AdaptiveWakeConfig) Validate() error {
+	s := c.AdaptiveWake
+	if s.PreparationBufferMinutes <= 0 {
+		return fmt.Errorf("preparation_buffer_minutes must be positive")
+	}
+	if _, err := time.Parse("15:04", s.FloorTime); err != nil {
+		return fmt.Errorf("invalid floor_time %q, expected HH:MM", s.FloorTime)
+	}
+
+	names := make(map[string]bool)
+	for i, p := range s.People {
+		if p.Name == "" {
+			return fmt.Errorf("people[%d]: name is required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("people[%d]: duplicate person %q", i, p.Name)
+		}
+		names[p.Name] = true
+
+		if !strings.HasPrefix(p.CalendarEntity, "calendar.") {
+			return fmt.Errorf("person %q: calendar_entity must be a calendar.* entity, got %q", p.Name, p.CalendarEntity)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the adaptive wake configuration from a YAML file
+func LoadConfig(path string) (*AdaptiveWakeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config AdaptiveWakeConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// WakeLightProfile sets the wake light ramp for a kind of morning
+type WakeLightProfile struct {
+	Name               string   `yaml:"name"`
+	MaxLux             *float64 `yaml:"max_lux"`    // Matches when outdoor light is at or below this
+	MinLux             *float64 `yaml:"min_lux"`    // Matches when outdoor light is at or above this
+	Conditions         []string `yaml:"conditions"` // Weather conditions that match when there's no lux reading
+	FinalBrightnessPct int      `yaml:"final_brightness_pct"`
+	TransitionMinutes  int      `yaml:"transition_minutes"`
+	LeadMinutes        int      `yaml:"lead_minutes"` // How long before the wake time the ramp starts
+}
+
+// Lead returns how long before the wake time the ramp starts
+func (p WakeLightProfile) Lead() time.Duration {
+	return time.Duration(p.LeadMinutes) * time.Minute
+}
+
+// matches reports whether the profile fits the morning. A lux reading is
+// used when the profile has lux bounds; otherwise the weather condition is.
+func (p WakeLightProfile) matches(lux *float64, condition string) bool {
+	if lux != nil && (p.MaxLux != nil || p.MinLux != nil) {
+		return (p.MaxLux == nil || *lux <= *p.MaxLux) && (p.MinLux == nil || *lux >= *p.MinLux)
+	}
+	return condition != "" && slices.Contains(p.Conditions, condition)
+}
+
+func (p WakeLightProfile) validate() error {
+	if p.FinalBrightnessPct < 1 || p.FinalBrightnessPct > 100 {
+		return fmt.Errorf("final_brightness_pct must be between 1 and 100, got %d", p.FinalBrightnessPct)
+	}
+	if p.TransitionMinutes <= 0 {
+		return fmt.Errorf("transition_minutes must be positive")
+	}
+	if p.LeadMinutes < 0 || p.LeadMinutes > maxWakeLightLeadMinutes {
+		return fmt.Errorf("lead_minutes must be between 0 and %d, got %d", maxWakeLightLeadMinutes, p.LeadMinutes)
+	}
+	return nil
+}
+
+// maxWakeLightLeadMinutes caps how early the ramp may start
+const maxWakeLightLeadMinutes = 60
+
+// defaultWakeLightProfile is the ramp used without a wake light config
+var defaultWakeLightProfile = WakeLightProfile{Name: "default", FinalBrightnessPct: 100, TransitionMinutes: 30}
+
+// WakeLightSettings controls adapting the wake light ramp to the morning's light
+type WakeLightSettings struct {
+	Enabled       bool               `yaml:"enabled"`
+	LuxEntity     string             `yaml:"lux_entity"`     // Outdoor illuminance sensor
+	WeatherEntity string             `yaml:"weather_entity"` // Weather entity, used for profiles without lux bounds
+	Default       WakeLightProfile   `yaml:"default"`        // Used when no profile matches
+	Profiles      []WakeLightProfile `yaml:"profiles"`       // First match wins
+}
+
+// WakeLightConfig represents the wake_light_config.yaml structure
+type WakeLightConfig struct {
+	WakeLight WakeLightSettings `yaml:"wake_light"`
+}
+
+// Choose returns the first profile matching the morning, or the default
+func (c *WakeLightConfig) Choose(lux *float64, condition string) WakeLightProfile {
+	for _, profile := range c.WakeLight.Profiles {
+		if profile.matches(lux, condition) {
+			return profile
+		}
+	}
+	profile := c.WakeLight.Default
+	profile.Name = "default"
+	return profile
+}
+
+// MaxLead returns the earliest any profile starts the ramp before the wake time
+func (c *WakeLightConfig) MaxLead() time.Duration {
+	lead := c.WakeLight.Default.Lead()
+	for _, profile := range c.WakeLight.Profiles {
+		lead = max(lead, profile.Lead())
+	}
+	return lead
+}
+
+// Validate checks the entities and every profile
+func (c *WakeLightConfig) Validate() error {
+	s := c.WakeLight
+	if s.LuxEntity != "" && !strings.HasPrefix(s.LuxEntity, "sensor.") {
+		return fmt.Errorf("lux_entity must be a sensor.* entity, got %q", s.LuxEntity)
+	}
+	if s.WeatherEntity != "" && !strings.HasPrefix(s.WeatherEntity, "weather.") {
+		return fmt.Errorf("weather_entity must be a weather.* entity, got %q", s.WeatherEntity)
+	}
+	if err := s.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, p := range s.Profiles {
+		if p.Name == "" || p.Name == "default" {
+			return fmt.Errorf("profiles[%d]: name is required and can't be \"default\"", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("profiles[%d]: duplicate profile %q", i, p.Name)
+		}
+		names[p.Name] = true
+
+		hasLux := p.MaxLux != nil || p.MinLux != nil
+		if !hasLux && len(p.Conditions) == 0 {
+			return fmt.Errorf("profile %q: needs max_lux, min_lux or conditions", p.Name)
+		}
+		if hasLux && s.LuxEntity == "" {
+			return fmt.Errorf("profile %q: lux bounds need lux_entity", p.Name)
+		}
+		if len(p.Conditions) > 0 && s.WeatherEntity == "" {
+			return fmt.Errorf("profile %q: conditions need weather_entity", p.Name)
+		}
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// LoadWakeLightConfig loads the wake light configuration from a YAML file
+func LoadWakeLightConfig(path string) (*WakeLightConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config WakeLightConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// maxSonosAlarmDelayMinutes caps how long after the wake time the backup alarm sounds
+const maxSonosAlarmDelayMinutes = 60
+
+// SonosAlarmSettings controls mirroring the wake time into a native Sonos alarm
+type SonosAlarmSettings struct {
+	Enabled            bool   `yaml:"enabled"`
+	Speaker            string `yaml:"speaker"`              // Sonos media player the alarm belongs to
+	AlarmID            int    `yaml:"alarm_id"`             // Existing alarm to update; the integration can't create alarms
+	AlarmEntity        string `yaml:"alarm_entity"`         // Defaults to switch.sonos_alarm_<alarm_id>
+	DelayMinutes       int    `yaml:"delay_minutes"`        // How long after the wake time the alarm sounds
+	Volume             int    `yaml:"volume"`               // 0-15 Sonos scale
+	IncludeLinkedZones bool   `yaml:"include_linked_zones"` // Also sound on speakers grouped with Speaker
+}
+
+// SonosAlarmConfig represents the sonos_alarm_config.yaml structure
+type SonosAlarmConfig struct {
+	SonosAlarm SonosAlarmSettings `yaml:"sonos_alarm"`
+}
+
+// Entity returns the switch entity Home Assistant exposes for the alarm
+func (c *SonosAlarmConfig) Entity() string {
+	if c.SonosAlarm.AlarmEntity != "" {
+		return c.SonosAlarm.AlarmEntity
+	}
+	return fmt.Sprintf("switch.sonos_alarm_%d", c.SonosAlarm.AlarmID)
+}
+
+// Delay returns how long after the wake time the alarm sounds
+func (c *SonosAlarmConfig) Delay() time.Duration {
+	return time.Duration(c.SonosAlarm.DelayMinutes) * time.Minute
+}
+
+// Validate checks the speaker, alarm, delay and volume
+func (c *SonosAlarmConfig) Validate() error {
+	s := c.SonosAlarm
+	if !strings.HasPrefix(s.Speaker, "media_player.") {
+		return fmt.Errorf("speaker must be a media_player.* entity, got %q", s.Speaker)
+	}
+	if s.AlarmID <= 0 {
+		return fmt.Errorf("alarm_id must be positive")
+	}
+	if s.AlarmEntity != "" && !strings.HasPrefix(s.AlarmEntity, "switch.") {
+		return fmt.Errorf("alarm_entity must be a switch.* entity, got %q", s.AlarmEntity)
+	}
+	if s.DelayMinutes < 0 || s.DelayMinutes > maxSonosAlarmDelayMinutes {
+		return fmt.Errorf("delay_minutes must be between 0 and %d, got %d", maxSonosAlarmDelayMinutes, s.DelayMinutes)
+	}
+	if s.Volume < 1 || s.Volume > ha.SonosSteps {
+		return fmt.Errorf("volume must be between 1 and %d, got %d", ha.SonosSteps, s.Volume)
+	}
+	return nil
+}
+
+// LoadSonosAlarmConfig loads the Sonos alarm configuration from a YAML file
+func LoadSonosAlarmConfig(path string) (*SonosAlarmConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config SonosAlarmConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
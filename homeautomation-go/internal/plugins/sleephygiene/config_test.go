@@ -0,0 +1,176 @@
+package sleephygiene
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_ProductionConfig(t *testing.T) {
+	config, err := LoadConfig("../../../../configs/adaptive_wake_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production adaptive wake config: %v", err)
+	}
+	if len(config.AdaptiveWake.People) == 0 {
+		t.Error("Expected at least one person in the production config")
+	}
+}
+
+func TestAdaptiveWakeConfig_Validate(t *testing.T) {
+	valid := func() AdaptiveWakeConfig {
+		return AdaptiveWakeConfig{AdaptiveWake: AdaptiveWakeSettings{
+			Enabled:                  true,
+			PreparationBufferMinutes: 60,
+			FloorTime:                "06:00",
+			People:                   []CalendarPerson{{Name: "Nick", CalendarEntity: "calendar.nick_work"}},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(c *AdaptiveWakeConfig)
+		wantErr string
+	}{
+		{"valid", func(c *AdaptiveWakeConfig) {}, ""},
+		{"zero buffer", func(c *AdaptiveWakeConfig) { c.AdaptiveWake.PreparationBufferMinutes = 0 }, "preparation_buffer_minutes"},
+		{"bad floor", func(c *AdaptiveWakeConfig) { c.AdaptiveWake.FloorTime = "6am" }, "floor_time"},
+		{"missing name", func(c *AdaptiveWakeConfig) { c.AdaptiveWake.People[0].Name = "" }, "name is required"},
+		{"duplicate person", func(c *AdaptiveWakeConfig) {
+			c.AdaptiveWake.People = append(c.AdaptiveWake.People, CalendarPerson{Name: "Nick", CalendarEntity: "calendar.other"})
+		}, "duplicate person"},
+		{"not a calendar", func(c *AdaptiveWakeConfig) { c.AdaptiveWake.People[0].CalendarEntity = "sensor.nick_work" }, "calendar.*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.mutate(&config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid config, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAdaptiveWakeConfig_FloorOn(t *testing.T) {
+	config := AdaptiveWakeConfig{AdaptiveWake: AdaptiveWakeSettings{FloorTime: "06:30"}}
+	day := time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)
+
+	floor := config.FloorOn(day)
+	expected := time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)
+	if !floor.Equal(expected) {
+		t.Errorf("Expected floor %v, got %v", expected, floor)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "adaptive_wake_config.yaml")
+	if err := os.WriteFile(path, []byte("adaptive_wake:\n  preparation_buffer_minutes: -5\n  floor_time: \"06:00\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Expected an error for a negative preparation buffer")
+	}
+}
+
+func TestLoadWakeLightConfig_ProductionConfig(t *testing.T) {
+	config, err := LoadWakeLightConfig("../../../../configs/wake_light_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production wake light config: %v", err)
+	}
+	if len(config.WakeLight.Profiles) == 0 {
+		t.Error("Expected at least one profile in the production config")
+	}
+}
+
+func luxPtr(lux float64) *float64 {
+	return &lux
+}
+
+func newTestWakeLight() *WakeLightConfig {
+	return &WakeLightConfig{WakeLight: WakeLightSettings{
+		Enabled:       true,
+		LuxEntity:     "sensor.outdoor_illuminance",
+		WeatherEntity: "weather.home",
+		Default:       WakeLightProfile{FinalBrightnessPct: 85, TransitionMinutes: 30},
+		Profiles: []WakeLightProfile{
+			{Name: "dark", MaxLux: luxPtr(400), Conditions: []string{"cloudy", "rainy"}, FinalBrightnessPct: 100, TransitionMinutes: 40, LeadMinutes: 10},
+			{Name: "bright", MinLux: luxPtr(5000), Conditions: []string{"sunny"}, FinalBrightnessPct: 60, TransitionMinutes: 30},
+		},
+	}}
+}
+
+func TestWakeLightConfig_Choose(t *testing.T) {
+	config := newTestWakeLight()
+
+	tests := []struct {
+		name      string
+		lux       *float64
+		condition string
+		want      string
+	}{
+		{"dark by lux", luxPtr(120), "sunny", "dark"},
+		{"bright by lux", luxPtr(8000), "cloudy", "bright"},
+		{"in between", luxPtr(1500), "cloudy", "default"},
+		{"no lux, overcast", nil, "cloudy", "dark"},
+		{"no lux, sunny", nil, "sunny", "bright"},
+		{"nothing readable", nil, "", "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.Choose(tt.lux, tt.condition).Name; got != tt.want {
+				t.Errorf("Expected profile %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if lead := config.MaxLead(); lead != 10*time.Minute {
+		t.Errorf("Expected max lead of 10 minutes, got %v", lead)
+	}
+}
+
+func TestWakeLightConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *WakeLightConfig)
+		wantErr string
+	}{
+		{"valid", func(c *WakeLightConfig) {}, ""},
+		{"not a sensor", func(c *WakeLightConfig) { c.WakeLight.LuxEntity = "weather.home" }, "sensor.*"},
+		{"brightness too high", func(c *WakeLightConfig) { c.WakeLight.Default.FinalBrightnessPct = 120 }, "final_brightness_pct"},
+		{"lead too long", func(c *WakeLightConfig) { c.WakeLight.Profiles[0].LeadMinutes = 90 }, "lead_minutes"},
+		{"no match criteria", func(c *WakeLightConfig) {
+			c.WakeLight.Profiles[1].MinLux = nil
+			c.WakeLight.Profiles[1].Conditions = nil
+		}, "needs max_lux, min_lux or conditions"},
+		{"conditions without weather", func(c *WakeLightConfig) { c.WakeLight.WeatherEntity = "" }, "weather_entity"},
+		{"duplicate profile", func(c *WakeLightConfig) { c.WakeLight.Profiles[1].Name = "dark" }, "duplicate profile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestWakeLight()
+			tt.mutate(config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid config, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package sleephygiene
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeautomation/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// setupDSTTest returns a manager in America/Chicago whose schedule has
+// stop_screens at stopScreens every day
+func setupDSTTest(t *testing.T, stopScreens string) (*Manager, *time.Location) {
+	t.Helper()
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("America/Chicago not available: %v", err)
+	}
+
+	entry := `  - begin_wake: "05:00"
+    wake: "07:00"
+    dusk: "18:00"
+    winddown: "21:00"
+    stop_screens: "` + stopScreens + `"
+    go_to_bed: "23:30"
+    night: "23:45"
+`
+	scheduleYAML := "schedule:\n"
+	for i := 0; i < 7; i++ {
+		scheduleYAML += entry
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "schedule_config.yaml"), []byte(scheduleYAML), 0644); err != nil {
+		t.Fatalf("Failed to write schedule: %v", err)
+	}
+
+	loader := config.NewLoader(dir, zap.NewNop())
+	loader.SetTimezone(chicago)
+	if err := loader.LoadScheduleConfig(); err != nil {
+		t.Fatalf("Failed to load schedule: %v", err)
+	}
+
+	manager, _, stateManager, _ := setupTest(t, time.Time{})
+	manager.configLoader = loader
+	manager.SetTimezone(chicago)
+	stateManager.SetBool("isEveryoneAsleep", false)
+
+	return manager, chicago
+}
+
+// checkAt runs the trigger check as the scheduler would at the given instant
+func checkAt(manager *Manager, at time.Time) {
+	manager.timeProvider = FixedTimeProvider{FixedTime: at}
+	manager.clearStaleTriggers(manager.now())
+	manager.checkTimeTriggers()
+}
+
+// TestTriggers_SkippedHour tests that a trigger scheduled in the hour skipped
+// when clocks spring forward fires once, as soon as clocks jump
+func TestTriggers_SkippedHour(t *testing.T) {
+	manager, chicago := setupDSTTest(t, "02:30")
+
+	// 01:59 CST, just before clocks jump from 02:00 to 03:00
+	checkAt(manager, time.Date(2024, 3, 10, 7, 59, 0, 0, time.UTC))
+	if _, triggered := manager.triggeredToday["stop_screens"]; triggered {
+		t.Fatal("stop_screens should not fire before the skipped hour")
+	}
+
+	// 03:01 CDT
+	checkAt(manager, time.Date(2024, 3, 10, 8, 1, 0, 0, time.UTC))
+	firedAt, triggered := manager.triggeredToday["stop_screens"]
+	if !triggered {
+		t.Fatal("stop_screens scheduled in the skipped hour should fire once clocks jump")
+	}
+	if firedAt.Location() != chicago {
+		t.Errorf("Expected trigger time in America/Chicago, got %v", firedAt.Location())
+	}
+
+	checkAt(manager, time.Date(2024, 3, 10, 8, 30, 0, 0, time.UTC))
+	if got := manager.triggeredToday["stop_screens"]; !got.Equal(firedAt) {
+		t.Errorf("stop_screens should fire only once, fired again at %v", got)
+	}
+}
+
+// TestTriggers_RepeatedHour tests that a trigger scheduled in the hour
+// repeated when clocks fall back fires on the first pass only
+func TestTriggers_RepeatedHour(t *testing.T) {
+	manager, _ := setupDSTTest(t, "01:30")
+
+	// 01:31 CDT, the first 01:31
+	first := time.Date(2024, 11, 3, 6, 31, 0, 0, time.UTC)
+	checkAt(manager, first)
+	if got, triggered := manager.triggeredToday["stop_screens"]; !triggered || !got.Equal(first) {
+		t.Fatalf("stop_screens should fire on the first 01:30, got %v (triggered=%v)", got, triggered)
+	}
+
+	// 01:31 CST, the same wall time an hour later
+	checkAt(manager, time.Date(2024, 11, 3, 7, 31, 0, 0, time.UTC))
+	if got := manager.triggeredToday["stop_screens"]; !got.Equal(first) {
+		t.Errorf("stop_screens should not fire again in the repeated hour, fired at %v", got)
+	}
+}
+
+// TestTriggers_UseConfiguredTimezone tests that schedule times are wall-clock
+// times in the configured zone even though the process clock is UTC
+func TestTriggers_UseConfiguredTimezone(t *testing.T) {
+	manager, _ := setupDSTTest(t, "22:00")
+
+	// 22:00 UTC is 17:00 in Chicago
+	checkAt(manager, time.Date(2024, 6, 3, 22, 1, 0, 0, time.UTC))
+	if _, triggered := manager.triggeredToday["stop_screens"]; triggered {
+		t.Fatal("stop_screens should not fire at 22:00 UTC")
+	}
+
+	// 03:01 UTC the next day is 22:01 in Chicago
+	checkAt(manager, time.Date(2024, 6, 4, 3, 1, 0, 0, time.UTC))
+	if _, triggered := manager.triggeredToday["stop_screens"]; !triggered {
+		t.Error("stop_screens should fire at 22:00 in Chicago")
+	}
+}
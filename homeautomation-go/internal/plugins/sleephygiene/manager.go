@@ -1,20 +1,33 @@
 package sleephygiene
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"homeautomation/internal/clock"
 	"homeautomation/internal/config"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/entities"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/fade"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/kidmode"
+	"homeautomation/internal/scheduler"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
 
 	"go.uber.org/zap"
 )
 
 // TimeProvider is an interface for getting the current time
 // This allows tests to inject a fixed time instead of using time.Now()
+// Every clock.Clock is one; tests that need time to pass use SetClock instead
 type TimeProvider interface {
 	Now() time.Time
 }
@@ -35,12 +48,11 @@ func (f FixedTimeProvider) Now() time.Time {
 	return f.FixedTime
 }
 
-// Eight Sleep Pod sensor entity IDs
-const (
-	eightSleepNickSensorEntity     = "sensor.nick_s_eight_sleep_side_bed_state_type"
-	eightSleepCarolineSensorEntity = "sensor.caroline_s_eight_sleep_side_bed_state_type"
-	eightSleepAlarmState           = "alarm"
-)
+// timeTriggersJob is the scheduler job that checks time triggers every minute
+const timeTriggersJob = "sleephygiene/time_triggers"
+
+// eightSleepAlarmState is the Eight Sleep Pod bed state while its alarm goes off
+const eightSleepAlarmState = "alarm"
 
 // Manager handles sleep hygiene automations including wake-up sequences
 type Manager struct {
@@ -50,16 +62,49 @@ type Manager struct {
 	logger          *zap.Logger
 	readOnly        bool
 	timeProvider    TimeProvider
-	stopChan        chan struct{}
-	ticker          *time.Ticker
+	clock           clock.Clock        // Times the fade-out steps
+	entities        *entities.Registry // Bedroom lights, speaker and Eight Sleep sensors; nil uses the defaults
+	timezone        *time.Location     // nil keeps the time provider's location
+	scheduler       *scheduler.Scheduler
 	subscriptions   []state.Subscription
 	haSubscriptions []ha.Subscription
 
 	// Track which triggers have been fired today
 	triggeredToday map[string]time.Time
 
+	// Per-bedroom do-not-disturb toggles, nil if not configured
+	dnd *donotdisturb.Guard
+	// Queues the cuddle announcement and picks its speakers, nil speaks immediately on the defaults
+	announcer *tts.Announcer
+	// Softens the reminder flashes in rooms in kid mode, nil if not configured
+	kid *kidmode.Guard
+	// When each bedroom's toggle was last seen turning on, for auto-expiry
+	dndSince map[string]time.Time
+	dndMu    sync.Mutex
+
+	// Calendar-based alarm adjustment, nil if not configured
+	adaptiveWake *AdaptiveWakeConfig
+
+	// Light-level-based wake light ramp, nil if not configured
+	wakeLight *WakeLightConfig
+
+	// Native Sonos alarm mirroring the wake time, nil if not configured
+	sonosAlarm       *SonosAlarmConfig
+	sonosAlarmSynced *sonosAlarmTarget // Last values written, nil until the first sync
+	sonosAlarmMu     sync.Mutex
+
 	// Shadow state tracking
 	shadowTracker *shadowstate.SleepHygieneTracker
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests and fades
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Fade-outs in progress; Stop waits for them to end
+	fades sync.WaitGroup
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new Sleep Hygiene manager
@@ -68,23 +113,88 @@ func NewManager(haClient ha.HAClient, stateManager *state.Manager, configLoader
 	if timeProvider == nil {
 		timeProvider = RealTimeProvider{}
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(time.Minute)
+
 	return &Manager{
+		ctx:             ctx,
+		cancel:          cancel,
 		haClient:        haClient,
 		stateManager:    stateManager,
 		configLoader:    configLoader,
-		logger:          logger.Named("sleephygiene"),
+		logger:          recorder.Logger(logger.Named("sleephygiene")),
+		health:          recorder,
 		readOnly:        readOnly,
 		timeProvider:    timeProvider,
-		stopChan:        make(chan struct{}),
+		clock:           clock.NewRealClock(),
+		scheduler:       scheduler.New(logger, nil),
 		subscriptions:   make([]state.Subscription, 0),
 		haSubscriptions: make([]ha.Subscription, 0),
 		triggeredToday:  make(map[string]time.Time),
+		dndSince:        make(map[string]time.Time),
 		shadowTracker:   shadowstate.NewSleepHygieneTracker(),
 	}
 }
 
+// SetClock sets the clock fade-outs step on and makes it the time provider,
+// so tests can run a whole wake sequence on a virtual clock
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+	m.timeProvider = c
+}
+
+// SetEntities sets the registry the bedroom lights, bedroom speaker and
+// Eight Sleep sensors are looked up in
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
+// SetTimezone sets the time zone the schedule, trigger days and wake times
+// are evaluated in
+func (m *Manager) SetTimezone(timezone *time.Location) {
+	m.timezone = timezone
+}
+
+// now returns the current time in the manager's time zone
+func (m *Manager) now() time.Time {
+	now := m.timeProvider.Now()
+	if m.timezone != nil {
+		now = now.In(m.timezone)
+	}
+	return now
+}
+
+// SetScheduler sets the scheduler the minute trigger check runs on, shared
+// with other plugins
+func (m *Manager) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+// SetDoNotDisturb sets the per-bedroom do-not-disturb guard. Wake actions,
+// reminders, and announcements skip entities in bedrooms with the toggle on,
+// and toggles are cleared at their configured expiry time.
+func (m *Manager) SetDoNotDisturb(guard *donotdisturb.Guard) {
+	m.dnd = guard
+}
+
+// SetKidMode sets the kid mode guard. Common area lights in rooms in kid mode
+// glow dimly instead of flashing for the bedtime reminders.
+func (m *Manager) SetKidMode(guard *kidmode.Guard) {
+	m.kid = guard
+}
+
+// SetAnnouncer sets the announcer that speaks the cuddle announcement
+func (m *Manager) SetAnnouncer(announcer *tts.Announcer) {
+	m.announcer = announcer
+}
+
 // Start begins monitoring state changes and managing sleep hygiene
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
+	// Fades and wake sequences run under ctx and end when Stop cancels it
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	m.logger.Info("Starting Sleep Hygiene Manager")
 
 	// Track subscriptions locally so we can clean up on partial failure
@@ -98,7 +208,7 @@ func (m *Manager) Start() error {
 	}
 
 	// Subscribe to bedroom lights state changes (for cancel auto-wake logic)
-	lightSub, err := m.haClient.SubscribeStateChanges("light.primary_suite", m.handleBedroomLightsChange)
+	lightSub, err := m.haClient.SubscribeStateChanges(m.entities.PrimarySuiteLight(), m.handleBedroomLightsChange)
 	if err != nil {
 		cleanup()
 		return fmt.Errorf("failed to subscribe to bedroom lights: %w", err)
@@ -106,14 +216,14 @@ func (m *Manager) Start() error {
 	haSubscriptions = append(haSubscriptions, lightSub)
 
 	// Subscribe to Eight Sleep Pod alarm sensors for instant wake-up triggers
-	nickEightSleepSub, err := m.haClient.SubscribeStateChanges(eightSleepNickSensorEntity, m.handleEightSleepAlarm)
+	nickEightSleepSub, err := m.haClient.SubscribeStateChanges(m.entities.NickEightSleep(), m.handleEightSleepAlarm)
 	if err != nil {
 		cleanup()
 		return fmt.Errorf("failed to subscribe to Nick's Eight Sleep sensor: %w", err)
 	}
 	haSubscriptions = append(haSubscriptions, nickEightSleepSub)
 
-	carolineEightSleepSub, err := m.haClient.SubscribeStateChanges(eightSleepCarolineSensorEntity, m.handleEightSleepAlarm)
+	carolineEightSleepSub, err := m.haClient.SubscribeStateChanges(m.entities.CarolineEightSleep(), m.handleEightSleepAlarm)
 	if err != nil {
 		cleanup()
 		return fmt.Errorf("failed to subscribe to Caroline's Eight Sleep sensor: %w", err)
@@ -123,28 +233,53 @@ func (m *Manager) Start() error {
 	// All subscriptions successful - commit them to the manager
 	m.haSubscriptions = append(m.haSubscriptions, haSubscriptions...)
 
-	// Start ticker to check time triggers every minute
-	m.ticker = time.NewTicker(1 * time.Minute)
-	go m.runTimerLoop()
+	// Track do-not-disturb toggles so they can expire
+	now := m.now()
+	for _, bedroom := range m.dnd.Bedrooms() {
+		bedroom := bedroom
+		if m.dnd.IsActive(bedroom) {
+			m.setDoNotDisturbSince(bedroom, true, now)
+		}
+		sub, err := m.stateManager.Subscribe(bedroom.Variable, func(key string, oldValue, newValue interface{}) {
+			active, ok := newValue.(bool)
+			if !ok {
+				return
+			}
+			m.setDoNotDisturbSince(bedroom, active, m.now())
+		})
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to subscribe to %s: %w", bedroom.Variable, err)
+		}
+		m.subscriptions = append(m.subscriptions, sub)
+	}
+
+	// Restore triggers that fired today before a restart so they aren't replayed
+	m.restoreTriggers(now)
+
+	// Check time triggers at the start of every minute
+	if err := m.scheduler.Cron(timeTriggersJob, "* * * * *", m.onMinute); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to schedule time trigger check: %w", err)
+	}
 
 	// Perform initial check
 	m.checkTimeTriggers()
 
+	m.health.Started(len(m.subscriptions) + len(m.haSubscriptions))
 	m.logger.Info("Sleep Hygiene Manager started successfully")
 	return nil
 }
 
 // Stop stops the Sleep Hygiene Manager and cleans up resources
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping Sleep Hygiene Manager")
 
-	// Stop ticker
-	if m.ticker != nil {
-		m.ticker.Stop()
-	}
+	m.cancel()
 
-	// Signal stop
-	close(m.stopChan)
+	// Stop checking time triggers
+	m.scheduler.Cancel(timeTriggersJob)
 
 	// Unsubscribe from all state subscriptions
 	for _, sub := range m.subscriptions {
@@ -160,9 +295,17 @@ func (m *Manager) Stop() {
 	}
 	m.haSubscriptions = nil
 
+	// The cancelled context ends any fade-out at its next step
+	m.fades.Wait()
+
 	m.logger.Info("Sleep Hygiene Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handleBedroomLightsChange processes bedroom lights state changes from Home Assistant
 func (m *Manager) handleBedroomLightsChange(entityID string, oldState, newState *ha.State) {
 	if newState == nil {
@@ -210,7 +353,7 @@ func (m *Manager) handleEightSleepAlarm(entityID string, oldState, newState *ha.
 	}
 
 	// Check if we already triggered begin_wake today (deduplication)
-	now := m.timeProvider.Now()
+	now := m.now()
 	if triggerTime, triggered := m.triggeredToday["begin_wake"]; triggered {
 		if isSameDay(now, triggerTime) {
 			m.logger.Debug("begin_wake already triggered today, ignoring Eight Sleep alarm",
@@ -224,52 +367,42 @@ func (m *Manager) handleEightSleepAlarm(entityID string, oldState, newState *ha.
 		zap.String("entity_id", entityID),
 		zap.Time("now", now))
 
-	// Mark as triggered to prevent duplicate triggers
-	m.triggeredToday["begin_wake"] = now
+	// Mark as triggered to prevent duplicate triggers, including after a restart
+	m.markTriggered("begin_wake", now)
 
 	// Trigger the begin_wake sequence
 	m.handleBeginWake()
 }
 
-// runTimerLoop runs the main timer loop that checks for time triggers
-func (m *Manager) runTimerLoop() {
-	for {
-		select {
-		case <-m.ticker.C:
-			// Check if we crossed midnight - reset triggers
-			now := m.timeProvider.Now()
-			if len(m.triggeredToday) > 0 {
-				// Check if any triggered time is from a previous day
-				for trigger, triggerTime := range m.triggeredToday {
-					if !isSameDay(now, triggerTime) {
-						m.logger.Debug("Resetting trigger for new day",
-							zap.String("trigger", trigger))
-						delete(m.triggeredToday, trigger)
-					}
-				}
-			}
-
-			// Check time triggers
-			m.checkTimeTriggers()
+// onMinute checks time triggers, run by the scheduler every minute
+func (m *Manager) onMinute() {
+	m.health.Tick()
+	// Check if we crossed midnight - reset triggers
+	m.clearStaleTriggers(m.now())
 
-		case <-m.stopChan:
-			return
-		}
-	}
+	// Check time triggers
+	m.checkTimeTriggers()
 }
 
-// checkTimeTriggers checks schedule-based triggers (stop_screens and go_to_bed)
+// checkTimeTriggers checks schedule-based triggers (stop_screens, go_to_bed and
+// the wake light), recomputes the adaptive wake alarm and syncs the Sonos alarm
 // Note: Wake-up triggers (begin_wake and wake) are handled by Eight Sleep alarm sensors
 func (m *Manager) checkTimeTriggers() {
-	now := m.timeProvider.Now()
+	now := m.now()
+
+	m.expireDoNotDisturb(now)
 
 	// Get today's schedule
-	schedule, err := m.configLoader.GetTodaysSchedule()
+	schedule, err := m.configLoader.GetScheduleFor(now)
 	if err != nil {
 		m.logger.Error("Failed to get today's schedule", zap.Error(err))
 		return
 	}
 
+	m.updateAdaptiveWake(now, scheduledWakeOn(now, schedule.Wake))
+	m.checkWakeLight(now, scheduledWakeOn(now, schedule.Wake))
+	m.syncSonosAlarm(now, scheduledWakeOn(now, schedule.Wake))
+
 	const ONE_HOUR = time.Hour
 
 	// Check stop_screens trigger
@@ -278,7 +411,7 @@ func (m *Manager) checkTimeTriggers() {
 			m.logger.Info("Triggering stop_screens",
 				zap.Time("stop_screens_time", schedule.StopScreens),
 				zap.Time("now", now))
-			m.triggeredToday["stop_screens"] = now
+			m.markTriggered("stop_screens", now)
 			m.handleStopScreens()
 		}
 	}
@@ -289,7 +422,7 @@ func (m *Manager) checkTimeTriggers() {
 			m.logger.Info("Triggering go_to_bed",
 				zap.Time("go_to_bed_time", schedule.GoToBed),
 				zap.Time("now", now))
-			m.triggeredToday["go_to_bed"] = now
+			m.markTriggered("go_to_bed", now)
 			m.handleGoToBed()
 		}
 	}
@@ -326,7 +459,13 @@ func (m *Manager) handleBeginWake() {
 	bedroomSpeakers := m.getBedroomSpeakers()
 	if len(bedroomSpeakers) == 0 {
 		m.logger.Warn("No bedroom speakers found in currentlyPlayingMusic, using default")
-		bedroomSpeakers = []string{"media_player.bedroom"}
+		bedroomSpeakers = []string{m.entities.BedroomSpeaker()}
+	}
+
+	bedroomSpeakers = m.allowedTargets("begin_wake", bedroomSpeakers)
+	if len(bedroomSpeakers) == 0 {
+		m.recordAction("dnd_suppressed", "Skipped begin_wake: bedroom speakers are in do-not-disturb", "eight_sleep_alarm")
+		return
 	}
 
 	// Record action in shadow state
@@ -339,14 +478,15 @@ func (m *Manager) handleBeginWake() {
 			m.logger.Error("Failed to set isFadeOutInProgress", zap.Error(err))
 		}
 
-		// Start fade out goroutine for each bedroom speaker
+		// Record fade out start in shadow state, then fade every bedroom speaker together
 		for _, speaker := range bedroomSpeakers {
-			// Record fade out start in shadow state
-			currentVolume := m.getSpeakerVolume(speaker)
-			m.shadowTracker.RecordFadeOutStart(speaker, currentVolume)
-
-			go m.fadeOutSpeaker(speaker)
+			m.shadowTracker.RecordFadeOutStart(speaker, m.getSpeakerVolume(speaker))
 		}
+		m.fades.Add(1)
+		go func() {
+			defer m.fades.Done()
+			m.fadeOutSpeakers(bedroomSpeakers)
+		}()
 	} else {
 		m.logger.Info("READ-ONLY: Would start fade out")
 		// In read-only mode, still record shadow state with estimated volumes
@@ -362,13 +502,13 @@ func (m *Manager) getBedroomSpeakers() []string {
 	var currentMusic map[string]interface{}
 	if err := m.stateManager.GetJSON("currentlyPlayingMusic", &currentMusic); err != nil {
 		m.logger.Warn("Failed to get currentlyPlayingMusic, using default bedroom speaker", zap.Error(err))
-		return []string{"media_player.bedroom"}
+		return []string{m.entities.BedroomSpeaker()}
 	}
 
 	participants, ok := currentMusic["participants"].([]interface{})
 	if !ok {
 		m.logger.Warn("currentlyPlayingMusic has no participants array")
-		return []string{"media_player.bedroom"}
+		return []string{m.entities.BedroomSpeaker()}
 	}
 
 	var bedroomSpeakers []string
@@ -393,111 +533,116 @@ func (m *Manager) getBedroomSpeakers() []string {
 	return bedroomSpeakers
 }
 
-// fadeOutSpeaker gradually reduces speaker volume to 0
-// This runs in a goroutine and implements the sleep music fade-out logic
-// matching the Node-RED "Repeat turn downs until 0" function
-func (m *Manager) fadeOutSpeaker(speakerEntityID string) {
-	m.logger.Info("Starting speaker fade-out", zap.String("speaker", speakerEntityID))
-
-	// Get actual current volume from Home Assistant
-	currentVolume := m.getSpeakerVolume(speakerEntityID)
-	if currentVolume == 0 {
-		m.logger.Info("Speaker volume already at 0, skipping fade-out", zap.String("speaker", speakerEntityID))
+// fadeOutSpeakers fades the speakers to 0 together, one percent at a time.
+// This implements the sleep music fade-out logic matching the Node-RED
+// "Repeat turn downs until 0" function, and returns once every speaker is
+// silent or the fade is aborted.
+func (m *Manager) fadeOutSpeakers(speakers []string) {
+	var targets []fade.Target
+	for _, speaker := range speakers {
+		// Get actual current volume from Home Assistant
+		currentVolume := m.getSpeakerVolume(speaker)
+		if currentVolume == 0 {
+			m.logger.Info("Speaker volume already at 0, skipping fade-out", zap.String("speaker", speaker))
+			continue
+		}
+		m.logger.Info("Starting speaker fade-out",
+			zap.String("speaker", speaker),
+			zap.Int("volume", currentVolume))
+		targets = append(targets, fade.Target{
+			EntityID: speaker,
+			From:     ha.VolumeFromPercent(currentVolume),
+			Steps:    currentVolume,
+		})
+	}
+	if len(targets) == 0 {
 		return
 	}
 
-	m.logger.Info("Got initial speaker volume",
-		zap.String("speaker", speakerEntityID),
-		zap.Int("volume", currentVolume))
-
-	for currentVolume > 0 {
-		// Check if fade out was aborted
-		isFadeOut, err := m.stateManager.GetBool("isFadeOutInProgress")
-		if err != nil || !isFadeOut {
-			m.logger.Info("Fade out aborted - isFadeOutInProgress is false",
-				zap.String("speaker", speakerEntityID))
-
-			// Mark fade-out as inactive in shadow state
-			m.shadowTracker.UpdateFadeOutProgress(speakerEntityID, 0)
-
-			return
-		}
-
-		// Check if still playing sleep music
-		musicType, err := m.stateManager.GetString("musicPlaybackType")
-		if err != nil || musicType != "sleep" {
-			m.logger.Info("Sleep music stopped, cancelling fade out",
-				zap.String("speaker", speakerEntityID),
-				zap.String("current_music_type", musicType))
+	err := fade.Run(m.ctx, m.clock, targets, fade.Options{
+		Resolution: 100,
+		// Longer as volume gets lower, matching Node-RED's
+		// (60 - current_volume) * 1000 ms: 10 seconds at 50, 50 seconds at 10
+		Interval:  fade.Adaptive(60, time.Second, time.Second),
+		SetVolume: m.setFadeVolume,
+		Continue:  m.continueFadeOut,
+		OnStep: func(speaker string, volume ha.Volume) {
+			m.updateSpeakerVolumeInState(speaker, volume.Percent())
+			m.shadowTracker.UpdateFadeOutProgress(speaker, volume.Percent())
+		},
+	})
+	if err != nil {
+		m.logger.Info("Fade out stopped with the plugin", zap.Strings("speakers", speakers))
+		return
+	}
 
-			// Clear fade-out state on abort
-			if !m.readOnly {
-				if err := m.stateManager.SetBool("isFadeOutInProgress", false); err != nil {
-					m.logger.Error("Failed to clear isFadeOutInProgress", zap.Error(err))
-				}
-			}
+	m.logger.Info("Fade out finished", zap.Strings("speakers", speakers))
 
-			// Mark fade-out as inactive in shadow state
-			m.shadowTracker.UpdateFadeOutProgress(speakerEntityID, 0)
+	// Reset fade out flag when complete
+	if err := m.stateManager.SetBool("isFadeOutInProgress", false); err != nil {
+		m.logger.Error("Failed to reset isFadeOutInProgress", zap.Error(err))
+	}
+}
 
-			return
-		}
+// fadeOutSpeaker fades a single speaker to 0
+func (m *Manager) fadeOutSpeaker(speakerEntityID string) {
+	m.fadeOutSpeakers([]string{speakerEntityID})
+}
 
-		// Reduce volume by 1
-		currentVolume--
-		volumeLevel := float64(currentVolume) / 100.0
+// setFadeVolume sets one step of a fade-out
+func (m *Manager) setFadeVolume(speakerEntityID string, volume ha.Volume) {
+	m.logger.Debug("Reducing speaker volume",
+		zap.String("speaker", speakerEntityID),
+		zap.Int("volume", volume.Percent()),
+		zap.Float64("volume_level", volume.Level()))
 
-		m.logger.Debug("Reducing speaker volume",
+	if err := ha.SetVolume(m.ctx, m.haClient, speakerEntityID, volume); err != nil {
+		m.logger.Error("Failed to set volume",
 			zap.String("speaker", speakerEntityID),
-			zap.Int("volume", currentVolume),
-			zap.Float64("volume_level", volumeLevel))
-
-		// Set volume on speaker
-		if err := m.haClient.CallService("media_player", "volume_set", map[string]interface{}{
-			"entity_id":    speakerEntityID,
-			"volume_level": volumeLevel,
-		}); err != nil {
-			m.logger.Error("Failed to set volume",
-				zap.String("speaker", speakerEntityID),
-				zap.Error(err))
-			// Continue anyway - don't abort the fade out for transient errors
-		}
-
-		// Update currentlyPlayingMusic state
-		m.updateSpeakerVolumeInState(speakerEntityID, currentVolume)
+			zap.Error(err))
+		// Continue anyway - don't abort the fade out for transient errors
+	}
+}
 
-		// Update shadow state fade out progress
-		m.shadowTracker.UpdateFadeOutProgress(speakerEntityID, currentVolume)
+// continueFadeOut reports whether a speaker's fade-out should take its next
+// step: it stops once isFadeOutInProgress is cleared or sleep music stops
+func (m *Manager) continueFadeOut(speakerEntityID string) bool {
+	// Check if fade out was aborted
+	isFadeOut, err := m.stateManager.GetBool("isFadeOutInProgress")
+	if err != nil || !isFadeOut {
+		m.logger.Info("Fade out aborted - isFadeOutInProgress is false",
+			zap.String("speaker", speakerEntityID))
 
-		// Calculate adaptive delay (longer as volume gets lower)
-		// Formula matches Node-RED: (60 - current_volume) * 1000 ms
-		// At volume 50: delay = 10 seconds
-		// At volume 10: delay = 50 seconds
-		delaySeconds := 60 - currentVolume
-		if delaySeconds < 1 {
-			delaySeconds = 1 // Minimum 1 second delay
-		}
+		// Mark fade-out as inactive in shadow state
+		m.shadowTracker.UpdateFadeOutProgress(speakerEntityID, 0)
+		return false
+	}
 
-		m.logger.Debug("Waiting before next volume reduction",
+	// Check if still playing sleep music
+	musicType, err := m.stateManager.GetString("musicPlaybackType")
+	if err != nil || musicType != "sleep" {
+		m.logger.Info("Sleep music stopped, cancelling fade out",
 			zap.String("speaker", speakerEntityID),
-			zap.Int("delay_seconds", delaySeconds))
+			zap.String("current_music_type", musicType))
 
-		time.Sleep(time.Duration(delaySeconds) * time.Second)
-	}
-
-	m.logger.Info("Fade out complete - speaker volume reached 0",
-		zap.String("speaker", speakerEntityID))
+		// Clear fade-out state on abort
+		if !m.readOnly {
+			if err := m.stateManager.SetBool("isFadeOutInProgress", false); err != nil {
+				m.logger.Error("Failed to clear isFadeOutInProgress", zap.Error(err))
+			}
+		}
 
-	// Reset fade out flag when complete
-	if err := m.stateManager.SetBool("isFadeOutInProgress", false); err != nil {
-		m.logger.Error("Failed to reset isFadeOutInProgress", zap.Error(err))
+		// Mark fade-out as inactive in shadow state
+		m.shadowTracker.UpdateFadeOutProgress(speakerEntityID, 0)
+		return false
 	}
+	return true
 }
 
 // getSpeakerVolume queries the current volume from Home Assistant
 // Returns volume as percentage (0-100)
 func (m *Manager) getSpeakerVolume(speakerEntityID string) int {
-	state, err := m.haClient.GetState(speakerEntityID)
+	state, err := m.haClient.GetState(m.ctx, speakerEntityID)
 	if err != nil {
 		m.logger.Warn("Failed to get speaker state, defaulting to volume 60",
 			zap.String("speaker", speakerEntityID),
@@ -505,17 +650,13 @@ func (m *Manager) getSpeakerVolume(speakerEntityID string) int {
 		return 60 // Default to typical sleep music volume
 	}
 
-	// Get volume_level attribute (0.0-1.0)
-	volumeLevel, ok := state.Attributes["volume_level"].(float64)
+	volume, ok := ha.VolumeOf(state)
 	if !ok {
 		m.logger.Warn("Speaker has no volume_level attribute, defaulting to volume 60",
 			zap.String("speaker", speakerEntityID))
 		return 60
 	}
-
-	// Convert to percentage (0-100)
-	volume := int(volumeLevel * 100)
-	return volume
+	return volume.Percent()
 }
 
 // updateSpeakerVolumeInState updates the volume in currentlyPlayingMusic state
@@ -574,7 +715,7 @@ func (m *Manager) updateSpeakerVolumeInState(speakerEntityID string, volume int)
 // fadeOutBedroomSpeaker is a legacy wrapper that calls fadeOutSpeaker
 // Kept for backward compatibility with existing tests
 func (m *Manager) fadeOutBedroomSpeaker() {
-	m.fadeOutSpeaker("media_player.bedroom")
+	m.fadeOutSpeaker(m.entities.BedroomSpeaker())
 }
 
 // handleWake handles the wake trigger (turn on lights, flash, cuddle announcement)
@@ -600,6 +741,12 @@ func (m *Manager) handleWake() {
 		return
 	}
 
+	if bedroom, ok := m.dnd.Suppresses(m.entities.MasterBedroomLight()); ok {
+		m.logger.Info("Skipping wake: bedroom is in do-not-disturb", zap.String("bedroom", bedroom))
+		m.recordAction("dnd_suppressed", fmt.Sprintf("Skipped wake sequence: %s bedroom is in do-not-disturb", bedroom), "wake_timer")
+		return
+	}
+
 	// All conditions met - execute wake sequence
 	m.logger.Info("Conditions met for wake, executing wake sequence")
 
@@ -608,8 +755,20 @@ func (m *Manager) handleWake() {
 	m.shadowTracker.UpdateWakeSequenceStatus("wake_in_progress")
 
 	if !m.readOnly {
-		// 1. Turn on master bedroom lights slowly (30 minute transition)
-		m.turnOnMasterBedroomLights()
+		// 1. Turn on master bedroom lights slowly, unless the wake light
+		// already started the ramp
+		if _, started := m.triggeredToday[wakeLightTrigger]; started {
+			m.logger.Debug("Wake light already ramping, leaving bedroom lights alone")
+		} else {
+			profile, record := m.chooseWakeLight(m.now())
+			if record.Profile != "" {
+				record.WakeTime = record.ChosenAt
+				record.StartAt = record.ChosenAt
+				record.Started = true
+				m.shadowTracker.UpdateWakeLight(record)
+			}
+			m.turnOnMasterBedroomLights(profile)
+		}
 
 		// 2. Check if both owners can cuddle and announce
 		m.checkAndAnnounceCuddle()
@@ -683,13 +842,17 @@ func (m *Manager) handleGoToBed() {
 	}
 }
 
-// turnOnMasterBedroomLights turns on master bedroom lights with a slow 30-minute transition
-func (m *Manager) turnOnMasterBedroomLights() {
-	m.logger.Info("Turning on master bedroom lights slowly")
+// turnOnMasterBedroomLights starts the master bedroom lights dim and white,
+// then ramps them to the profile's brightness over its transition
+func (m *Manager) turnOnMasterBedroomLights(profile WakeLightProfile) {
+	m.logger.Info("Turning on master bedroom lights slowly",
+		zap.String("profile", profile.Name),
+		zap.Int("brightness_pct", profile.FinalBrightnessPct),
+		zap.Int("transition_minutes", profile.TransitionMinutes))
 
 	// First, ensure lights start dim and white
-	if err := m.haClient.CallService("light", "turn_on", map[string]interface{}{
-		"entity_id":      "light.master_bedroom",
+	if err := m.haClient.CallService(m.ctx, "light", "turn_on", map[string]interface{}{
+		"entity_id":      m.entities.MasterBedroomLight(),
 		"transition":     0,
 		"color_temp":     290,
 		"brightness_pct": 1,
@@ -698,12 +861,12 @@ func (m *Manager) turnOnMasterBedroomLights() {
 		return
 	}
 
-	// Then start slow transition to full brightness over 30 minutes
-	if err := m.haClient.CallService("light", "turn_on", map[string]interface{}{
-		"entity_id":      "light.master_bedroom",
-		"transition":     1800, // 30 minutes in seconds
+	// Then start the slow transition to the final brightness
+	if err := m.haClient.CallService(m.ctx, "light", "turn_on", map[string]interface{}{
+		"entity_id":      m.entities.MasterBedroomLight(),
+		"transition":     profile.TransitionMinutes * 60,
 		"color_temp":     290,
-		"brightness_pct": 100,
+		"brightness_pct": profile.FinalBrightnessPct,
 	}); err != nil {
 		m.logger.Error("Failed to start bedroom light transition", zap.Error(err))
 	}
@@ -713,18 +876,17 @@ func (m *Manager) turnOnMasterBedroomLights() {
 func (m *Manager) flashCommonAreaLights() {
 	m.logger.Info("Flashing common area lights")
 
-	commonAreaLights := []string{
-		"light.living_room",
-		"light.kitchen",
+	commonAreaLights, err := entitygroups.Expand(m.haClient, []string{entitygroups.Group(entitygroups.CommonAreaLights)})
+	if err != nil {
+		m.logger.Error("Failed to expand common area lights", zap.Error(err))
+		return
 	}
+	commonAreaLights = m.allowedTargets("flash_lights", commonAreaLights)
 
-	for _, lightEntity := range commonAreaLights {
-		if err := m.haClient.CallService("light", "turn_on", map[string]interface{}{
-			"entity_id": lightEntity,
-			"flash":     "short",
-		}); err != nil {
-			m.logger.Error("Failed to flash light",
-				zap.String("entity", lightEntity),
+	for _, data := range m.kid.FlashCalls(commonAreaLights) {
+		if err := m.haClient.CallService(m.ctx, "light", "turn_on", data); err != nil {
+			m.logger.Error("Failed to flash lights",
+				zap.Any("entity", data["entity_id"]),
 				zap.Error(err))
 		}
 	}
@@ -747,17 +909,32 @@ func (m *Manager) checkAndAnnounceCuddle() {
 	}
 
 	if isNickHome && isCarolineHome {
+		speakers, err := m.announcer.Speakers(m.haClient, tts.TargetCuddle)
+		if err != nil {
+			m.logger.Error("Failed to resolve cuddle announcement speakers", zap.Error(err))
+			return
+		}
+		speakers = m.allowedTargets("cuddle_announcement", speakers)
+		if len(speakers) == 0 {
+			return
+		}
+
 		m.logger.Info("Both owners home, announcing cuddle time")
 
-		if err := m.haClient.CallService("tts", "speak", map[string]interface{}{
-			"cache":                  true,
-			"media_player_entity_id": []string{"media_player.bedroom"},
-			"message":                "Time to cuddle",
-		}); err != nil {
+		err = m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+			Title:    "Sleep hygiene",
+			Message:  "Time to cuddle",
+			Speakers: speakers,
+			Priority: tts.PriorityLow,
+		})
+		switch {
+		case errors.Is(err, tts.ErrQuietHours):
+			m.logger.Info("Skipping cuddle announcement during quiet hours")
+		case err != nil:
 			m.logger.Error("Failed to announce cuddle time", zap.Error(err))
-		} else {
+		default:
 			// Record TTS announcement in shadow state
-			m.shadowTracker.RecordTTSAnnouncement("Time to cuddle", "media_player.bedroom")
+			m.shadowTracker.RecordTTSAnnouncement("Time to cuddle", strings.Join(speakers, ","))
 		}
 	} else {
 		m.logger.Debug("Only one owner home, skipping cuddle announcement",
@@ -770,8 +947,8 @@ func (m *Manager) checkAndAnnounceCuddle() {
 func (m *Manager) turnOffBathroomLights() {
 	m.logger.Info("Turning off primary bathroom lights")
 
-	if err := m.haClient.CallService("light", "turn_off", map[string]interface{}{
-		"entity_id": "light.primary_bathroom_main_lights",
+	if err := m.haClient.CallService(m.ctx, "light", "turn_off", map[string]interface{}{
+		"entity_id": m.entities.PrimaryBathroomLight(),
 	}); err != nil {
 		m.logger.Error("Failed to turn off bathroom lights", zap.Error(err))
 	}
@@ -818,6 +995,70 @@ func (m *Manager) handleBedroomLightsOff(state string) {
 	}
 }
 
+// allowedTargets drops entities in do-not-disturb bedrooms from an action's targets
+func (m *Manager) allowedTargets(action string, entityIDs []string) []string {
+	allowed, suppressed := m.dnd.Filter(entityIDs)
+	if len(suppressed) > 0 {
+		m.logger.Info("Skipping do-not-disturb targets",
+			zap.String("action", action),
+			zap.Strings("suppressed", suppressed))
+	}
+	return allowed
+}
+
+// setDoNotDisturbSince records when a bedroom's do-not-disturb toggle turned on.
+// HA echoes our own writes with old == new, so only the first "on" is kept.
+func (m *Manager) setDoNotDisturbSince(bedroom donotdisturb.Bedroom, active bool, now time.Time) {
+	m.dndMu.Lock()
+	defer m.dndMu.Unlock()
+
+	_, tracked := m.dndSince[bedroom.Name]
+	switch {
+	case active && !tracked:
+		m.dndSince[bedroom.Name] = now
+		expiresAt, _ := bedroom.ExpiryAfter(now)
+		m.logger.Info("Do-not-disturb on", zap.String("bedroom", bedroom.Name), zap.Time("expires_at", expiresAt))
+		m.shadowTracker.UpdateDoNotDisturb(bedroom.Name, true, now, expiresAt)
+	case !active && tracked:
+		delete(m.dndSince, bedroom.Name)
+		m.logger.Info("Do-not-disturb off", zap.String("bedroom", bedroom.Name))
+		m.shadowTracker.UpdateDoNotDisturb(bedroom.Name, false, time.Time{}, time.Time{})
+	}
+}
+
+// expireDoNotDisturb clears do-not-disturb toggles whose expiry time has passed
+func (m *Manager) expireDoNotDisturb(now time.Time) {
+	for _, bedroom := range m.dnd.Bedrooms() {
+		m.dndMu.Lock()
+		since, tracked := m.dndSince[bedroom.Name]
+		m.dndMu.Unlock()
+		if !tracked {
+			continue
+		}
+
+		expiresAt, ok := bedroom.ExpiryAfter(since)
+		if !ok || now.Before(expiresAt) {
+			continue
+		}
+
+		m.logger.Info("Do-not-disturb expired",
+			zap.String("bedroom", bedroom.Name),
+			zap.Time("since", since),
+			zap.Time("expires_at", expiresAt))
+
+		if m.readOnly {
+			m.logger.Info("READ-ONLY: Would clear do-not-disturb", zap.String("variable", bedroom.Variable))
+			m.setDoNotDisturbSince(bedroom, false, now)
+			continue
+		}
+		if err := m.stateManager.SetBool(bedroom.Variable, false); err != nil {
+			m.logger.Error("Failed to clear do-not-disturb",
+				zap.String("variable", bedroom.Variable),
+				zap.Error(err))
+		}
+	}
+}
+
 // isSameDay checks if two times are on the same day
 func isSameDay(t1, t2 time.Time) bool {
 	y1, m1, d1 := t1.Date()
@@ -851,6 +1092,11 @@ func (m *Manager) captureCurrentInputs() map[string]interface{} {
 	if val, err := m.stateManager.GetBool("isCarolineHome"); err == nil {
 		inputs["isCarolineHome"] = val
 	}
+	for _, bedroom := range m.dnd.Bedrooms() {
+		if val, err := m.stateManager.GetBool(bedroom.Variable); err == nil {
+			inputs[bedroom.Variable] = val
+		}
+	}
 
 	// Get currentlyPlayingMusic JSON
 	var currentMusic map[string]interface{}
@@ -891,6 +1137,11 @@ func (m *Manager) GetShadowState() *shadowstate.SleepHygieneShadowState {
 	return m.shadowTracker.GetState()
 }
 
+// OnAction calls fn for each action the plugin records (implements shadowstate.ActionSource)
+func (m *Manager) OnAction(fn shadowstate.ActionListener) {
+	m.shadowTracker.OnAction(fn)
+}
+
 // Reset re-checks all wake-up triggers for current day
 func (m *Manager) Reset() error {
 	m.logger.Info("Resetting Sleep Hygiene - re-checking all wake-up triggers")
@@ -0,0 +1,115 @@
+package sleephygiene
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/state"
+)
+
+func newTestDoNotDisturb(t *testing.T, stateManager *state.Manager) *donotdisturb.Guard {
+	t.Helper()
+
+	config := &donotdisturb.Config{DoNotDisturb: donotdisturb.Settings{Bedrooms: []donotdisturb.Bedroom{
+		{
+			Name:      "primary",
+			Variable:  "isPrimaryBedroomDoNotDisturb",
+			ExpiresAt: "12:00",
+			Entities:  []string{"media_player.bedroom", "light.master_bedroom"},
+		},
+	}}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("invalid do-not-disturb config: %v", err)
+	}
+	return donotdisturb.NewGuard(config, stateManager)
+}
+
+func TestBeginWake_DoNotDisturb(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
+	manager, _, stateManager, _ := setupTest(t, now)
+	manager.SetDoNotDisturb(newTestDoNotDisturb(t, stateManager))
+	stateManager.SetBool("isPrimaryBedroomDoNotDisturb", true)
+
+	manager.handleBeginWake()
+
+	fadeOut, _ := stateManager.GetBool("isFadeOutInProgress")
+	if fadeOut {
+		t.Error("begin_wake should not start a fade out while the bedroom is in do-not-disturb")
+	}
+
+	shadow := manager.GetShadowState()
+	if shadow.Outputs.LastActionType != "dnd_suppressed" {
+		t.Errorf("Expected last action dnd_suppressed, got %q", shadow.Outputs.LastActionType)
+	}
+}
+
+func TestWake_DoNotDisturb(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetDoNotDisturb(newTestDoNotDisturb(t, stateManager))
+	stateManager.SetBool("isFadeOutInProgress", true)
+	stateManager.SetBool("isPrimaryBedroomDoNotDisturb", true)
+	mockHA.ClearServiceCalls()
+
+	manager.handleWake()
+
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "light" || call.Domain == "tts" {
+			t.Errorf("Expected no wake actions during do-not-disturb, got %s.%s", call.Domain, call.Service)
+		}
+	}
+}
+
+func TestWake_DoNotDisturbOff(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetDoNotDisturb(newTestDoNotDisturb(t, stateManager))
+	stateManager.SetBool("isFadeOutInProgress", true)
+	mockHA.ClearServiceCalls()
+
+	manager.handleWake()
+
+	foundMasterBedroom := false
+	for _, call := range mockHA.GetServiceCalls() {
+		if entityID, ok := call.Data["entity_id"].(string); ok && entityID == "light.master_bedroom" {
+			foundMasterBedroom = true
+		}
+	}
+	if !foundMasterBedroom {
+		t.Error("Expected master bedroom lights to turn on when do-not-disturb is off")
+	}
+}
+
+func TestExpireDoNotDisturb(t *testing.T) {
+	enabledAt := time.Date(2024, 1, 14, 23, 0, 0, 0, time.UTC)
+	manager, _, stateManager, _ := setupTest(t, enabledAt)
+	manager.SetDoNotDisturb(newTestDoNotDisturb(t, stateManager))
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+
+	stateManager.SetBool("isPrimaryBedroomDoNotDisturb", true)
+
+	// Before the configured expiry the toggle stays on
+	manager.expireDoNotDisturb(time.Date(2024, 1, 15, 11, 59, 0, 0, time.UTC))
+	active, _ := stateManager.GetBool("isPrimaryBedroomDoNotDisturb")
+	if !active {
+		t.Fatal("do-not-disturb should still be on before 12:00")
+	}
+	if dnd := manager.GetShadowState().Outputs.DoNotDisturb["primary"]; !dnd.Active {
+		t.Error("Shadow state should report do-not-disturb as active")
+	}
+
+	manager.expireDoNotDisturb(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	active, _ = stateManager.GetBool("isPrimaryBedroomDoNotDisturb")
+	if active {
+		t.Error("do-not-disturb should be cleared at 12:00")
+	}
+	if dnd := manager.GetShadowState().Outputs.DoNotDisturb["primary"]; dnd.Active {
+		t.Error("Shadow state should report do-not-disturb as inactive after expiry")
+	}
+}
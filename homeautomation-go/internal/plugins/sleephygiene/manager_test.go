@@ -1,12 +1,16 @@
 package sleephygiene
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"homeautomation/internal/config"
+	"homeautomation/internal/entitygroups"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/kidmode"
 	"homeautomation/internal/state"
+	"homeautomation/internal/testclock"
 
 	"go.uber.org/zap"
 )
@@ -30,13 +34,40 @@ func setupTest(t *testing.T, currentTime time.Time) (*Manager, *ha.MockClient, *
 	// Create a config loader
 	configLoader := config.NewLoader("../../../configs", logger)
 
+	// Expand entity group references the way the production client does
+	groups, err := entitygroups.LoadConfig("../../../../configs/entity_groups_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load entity groups: %v", err)
+	}
+
 	// Create manager with fixed time provider
 	timeProvider := FixedTimeProvider{FixedTime: currentTime}
-	manager := NewManager(mockHA, stateManager, configLoader, logger, false, timeProvider)
+	manager := NewManager(entitygroups.NewClient(mockHA, groups), stateManager, configLoader, logger, false, timeProvider)
 
 	return manager, mockHA, stateManager, configLoader
 }
 
+// stepFadeOut waits for a fade-out on clk to take its current step, then lets
+// it take steps more, returning once it is waiting for the next
+func stepFadeOut(clk *testclock.Clock, steps int) {
+	clk.BlockUntil(1)
+	for i := 0; i < steps; i++ {
+		clk.AdvanceToNext()
+		clk.BlockUntil(1)
+	}
+}
+
+// countVolumeSetCalls counts the media_player.volume_set calls made
+func countVolumeSetCalls(mockHA *ha.MockClient) int {
+	count := 0
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "media_player" && call.Service == "volume_set" {
+			count++
+		}
+	}
+	return count
+}
+
 func TestNewManager(t *testing.T) {
 	logger := zap.NewNop()
 	mockHA := ha.NewMockClient()
@@ -67,13 +98,13 @@ func TestStartStop(t *testing.T) {
 	manager, _, _, _ := setupTest(t, now)
 
 	// Start manager
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 
-	// Check that ticker is running
-	if manager.ticker == nil {
-		t.Error("Ticker should be initialized after Start()")
+	// Check that the minute trigger check is scheduled
+	if _, ok := manager.scheduler.Next(timeTriggersJob); !ok {
+		t.Error("Time trigger check should be scheduled after Start()")
 	}
 
 	// Check that HA subscriptions exist (bedroom lights + 2 Eight Sleep sensors)
@@ -88,6 +119,9 @@ func TestStartStop(t *testing.T) {
 	if manager.haSubscriptions != nil {
 		t.Error("HA subscriptions should be nil after Stop()")
 	}
+	if _, ok := manager.scheduler.Next(timeTriggersJob); ok {
+		t.Error("Time trigger check should be cancelled after Stop()")
+	}
 }
 
 func TestBeginWake_AllConditionsMet(t *testing.T) {
@@ -269,6 +303,42 @@ func TestWake_OnlyOneOwnerHome(t *testing.T) {
 	}
 }
 
+func TestStopScreens_KidModeSoftensFlash(t *testing.T) {
+	now := time.Date(2024, 1, 15, 22, 30, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetKidMode(kidmode.NewGuard(&kidmode.Config{KidMode: kidmode.Settings{
+		ToggleVariable:      "isKidMode",
+		Rooms:               []kidmode.Room{{Name: "living", Lights: []string{"light.living_room"}}},
+		SoftFlashBrightness: 30,
+	}}, stateManager))
+
+	stateManager.SetBool("isAnyoneHome", true)
+	stateManager.SetBool("isEveryoneAsleep", false)
+	stateManager.SetBool(kidmode.ActiveVariable, true)
+	mockHA.ClearServiceCalls()
+
+	manager.handleStopScreens()
+
+	var flashed, softened interface{}
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain != "light" || call.Service != "turn_on" {
+			continue
+		}
+		if call.Data["flash"] == "short" {
+			flashed = call.Data["entity_id"]
+		} else if call.Data["brightness_pct"] == 30 {
+			softened = call.Data["entity_id"]
+		}
+	}
+
+	if lights, ok := flashed.([]string); !ok || len(lights) != 1 || lights[0] != "light.kitchen" {
+		t.Errorf("Expected only the kitchen light to flash, got %v", flashed)
+	}
+	if lights, ok := softened.([]string); !ok || len(lights) != 1 || lights[0] != "light.living_room" {
+		t.Errorf("Expected the living room light to glow instead, got %v", softened)
+	}
+}
+
 func TestStopScreens_AllConditionsMet(t *testing.T) {
 	now := time.Date(2024, 1, 15, 22, 30, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
@@ -663,7 +733,7 @@ func TestRunTimerLoop_MidnightRollover(t *testing.T) {
 	manager.triggeredToday["begin_wake"] = now
 
 	// Start the manager to start the timer loop
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 
@@ -687,11 +757,13 @@ func TestRunTimerLoop_MidnightRollover(t *testing.T) {
 	}
 }
 
-// TestFadeOutBedroomSpeaker_Complete tests fade out runs and makes volume calls
-// Note: We test a partial fade-out since a complete fade-out from 60 to 0 would take 30+ minutes
+// TestFadeOutBedroomSpeaker_Complete runs a whole fade-out from 60 to 0 on a
+// virtual clock; in real time it would take about half an hour
 func TestFadeOutBedroomSpeaker_Complete(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for fade out
 	stateManager.SetBool("isFadeOutInProgress", true)
@@ -700,31 +772,17 @@ func TestFadeOutBedroomSpeaker_Complete(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Start fade out in goroutine
-	done := make(chan bool)
+	done := make(chan struct{})
 	go func() {
 		manager.fadeOutBedroomSpeaker()
-		done <- true
+		close(done)
 	}()
+	clk.RunUntil(done)
 
-	// Wait for several volume changes (5 seconds should allow for a few iterations)
-	time.Sleep(5 * time.Second)
-
-	// Abort the fade out to complete the test quickly
-	stateManager.SetBool("isFadeOutInProgress", false)
-
-	// Wait for goroutine to complete
-	select {
-	case <-done:
-		// Fade out completed
-	case <-time.After(10 * time.Second):
-		t.Fatal("Fade out did not stop within timeout")
-	}
-
-	// Verify volume_set calls were made
+	// Verify one volume_set call per percent, ending at 0
 	calls := mockHA.GetServiceCalls()
 	volumeSetCalls := 0
-	lastVolume := 0.0
+	lastVolume := -1.0
 	for _, call := range calls {
 		if call.Domain == "media_player" && call.Service == "volume_set" {
 			volumeSetCalls++
@@ -738,21 +796,22 @@ func TestFadeOutBedroomSpeaker_Complete(t *testing.T) {
 			}
 		}
 	}
-
-	// Should have made multiple volume set calls
-	if volumeSetCalls < 3 {
-		t.Errorf("Expected at least 3 volume_set calls, got %d", volumeSetCalls)
+	if volumeSetCalls != 60 {
+		t.Errorf("Expected 60 volume_set calls, got %d", volumeSetCalls)
+	}
+	if lastVolume != 0 {
+		t.Errorf("Expected the fade to end at 0, last volume was %.2f", lastVolume)
 	}
 
-	// Last volume should be less than initial (59/100)
-	if lastVolume >= 0.59 {
-		t.Errorf("Expected volume to decrease from initial 0.59, last volume was %.2f", lastVolume)
+	// Each step waits (60 - volume) seconds: 1s after 59 up to 59s after 1
+	if elapsed := clk.Since(now); elapsed != 1770*time.Second {
+		t.Errorf("Expected the fade to take 1770s, took %v", elapsed)
 	}
 
-	// Verify isFadeOutInProgress was NOT reset (we aborted externally)
+	// A finished fade clears the flag itself
 	fadeOut, _ := stateManager.GetBool("isFadeOutInProgress")
 	if fadeOut {
-		t.Error("isFadeOutInProgress should be false (we set it to abort)")
+		t.Error("isFadeOutInProgress should be reset when the fade finishes")
 	}
 }
 
@@ -760,6 +819,8 @@ func TestFadeOutBedroomSpeaker_Complete(t *testing.T) {
 func TestFadeOutBedroomSpeaker_AbortedByFlag(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for fade out
 	stateManager.SetBool("isFadeOutInProgress", true)
@@ -768,44 +829,20 @@ func TestFadeOutBedroomSpeaker_AbortedByFlag(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Start fade out in goroutine
-	done := make(chan bool)
+	done := make(chan struct{})
 	go func() {
 		manager.fadeOutBedroomSpeaker()
-		done <- true
+		close(done)
 	}()
 
-	// Wait a bit for fade out to start
-	time.Sleep(100 * time.Millisecond)
-
-	// Abort the fade out
+	// Let the fade take three steps, then abort it before the fourth
+	stepFadeOut(clk, 2)
 	stateManager.SetBool("isFadeOutInProgress", false)
+	clk.AdvanceToNext()
+	<-done
 
-	// Wait for completion
-	select {
-	case <-done:
-		// Fade out aborted
-	case <-time.After(10 * time.Second):
-		t.Fatal("Fade out did not abort within timeout")
-	}
-
-	// Verify volume_set calls were made but not all 60
-	calls := mockHA.GetServiceCalls()
-	volumeSetCalls := 0
-	for _, call := range calls {
-		if call.Domain == "media_player" && call.Service == "volume_set" {
-			volumeSetCalls++
-		}
-	}
-
-	// Should have fewer than 60 calls since we aborted early
-	if volumeSetCalls >= 60 {
-		t.Errorf("Expected fewer than 60 volume_set calls after abort, got %d", volumeSetCalls)
-	}
-
-	// Should have at least 1 call (fade out started)
-	if volumeSetCalls < 1 {
-		t.Error("Expected at least 1 volume_set call before abort")
+	if got := countVolumeSetCalls(mockHA); got != 3 {
+		t.Errorf("Expected 3 volume_set calls before the abort, got %d", got)
 	}
 }
 
@@ -813,6 +850,8 @@ func TestFadeOutBedroomSpeaker_AbortedByFlag(t *testing.T) {
 func TestFadeOutBedroomSpeaker_CancelledByMusicType(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for fade out
 	stateManager.SetBool("isFadeOutInProgress", true)
@@ -821,44 +860,26 @@ func TestFadeOutBedroomSpeaker_CancelledByMusicType(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Start fade out in goroutine
-	done := make(chan bool)
+	done := make(chan struct{})
 	go func() {
 		manager.fadeOutBedroomSpeaker()
-		done <- true
+		close(done)
 	}()
 
-	// Wait a bit for fade out to start
-	time.Sleep(100 * time.Millisecond)
-
-	// Change music type to cancel fade out
+	// Change music type after the first step to cancel fade out
+	stepFadeOut(clk, 0)
 	stateManager.SetString("musicPlaybackType", "day")
+	clk.AdvanceToNext()
+	<-done
 
-	// Wait for completion
-	select {
-	case <-done:
-		// Fade out cancelled
-	case <-time.After(10 * time.Second):
-		t.Fatal("Fade out did not cancel within timeout")
-	}
-
-	// Verify volume_set calls were made but not all 60
-	calls := mockHA.GetServiceCalls()
-	volumeSetCalls := 0
-	for _, call := range calls {
-		if call.Domain == "media_player" && call.Service == "volume_set" {
-			volumeSetCalls++
-		}
-	}
-
-	// Should have fewer than 60 calls since we cancelled early
-	if volumeSetCalls >= 60 {
-		t.Errorf("Expected fewer than 60 volume_set calls after cancel, got %d", volumeSetCalls)
+	if got := countVolumeSetCalls(mockHA); got != 1 {
+		t.Errorf("Expected 1 volume_set call before the cancel, got %d", got)
 	}
 
-	// Should have at least 1 call (fade out started)
-	if volumeSetCalls < 1 {
-		t.Error("Expected at least 1 volume_set call before cancel")
+	// Cancelling clears the flag
+	fadeOut, _ := stateManager.GetBool("isFadeOutInProgress")
+	if fadeOut {
+		t.Error("isFadeOutInProgress should be cleared when sleep music stops")
 	}
 }
 
@@ -866,6 +887,8 @@ func TestFadeOutBedroomSpeaker_CancelledByMusicType(t *testing.T) {
 func TestFadeOutBedroomSpeaker_VolumeSequence(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for fade out
 	stateManager.SetBool("isFadeOutInProgress", true)
@@ -874,21 +897,12 @@ func TestFadeOutBedroomSpeaker_VolumeSequence(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Start fade out in goroutine
-	done := make(chan bool)
+	done := make(chan struct{})
 	go func() {
 		manager.fadeOutBedroomSpeaker()
-		done <- true
+		close(done)
 	}()
-
-	// Wait for a few volume changes
-	time.Sleep(500 * time.Millisecond)
-
-	// Abort to stop the test quickly
-	stateManager.SetBool("isFadeOutInProgress", false)
-
-	// Wait for completion
-	<-done
+	clk.RunUntil(done)
 
 	// Verify volume levels are decreasing
 	calls := mockHA.GetServiceCalls()
@@ -900,6 +914,9 @@ func TestFadeOutBedroomSpeaker_VolumeSequence(t *testing.T) {
 			}
 		}
 	}
+	if len(volumeLevels) != 60 {
+		t.Fatalf("Expected 60 volume levels, got %d", len(volumeLevels))
+	}
 
 	// Verify volumes are decreasing
 	for i := 1; i < len(volumeLevels); i++ {
@@ -910,11 +927,8 @@ func TestFadeOutBedroomSpeaker_VolumeSequence(t *testing.T) {
 	}
 
 	// Verify first volume is 59/100 (60 - 1)
-	if len(volumeLevels) > 0 {
-		expectedFirst := 0.59
-		if volumeLevels[0] != expectedFirst {
-			t.Errorf("Expected first volume to be %.2f, got %.2f", expectedFirst, volumeLevels[0])
-		}
+	if volumeLevels[0] != 0.59 {
+		t.Errorf("Expected first volume to be 0.59, got %.2f", volumeLevels[0])
 	}
 }
 
@@ -922,6 +936,8 @@ func TestFadeOutBedroomSpeaker_VolumeSequence(t *testing.T) {
 func TestBeginWake_LaunchesFadeOut(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for begin_wake
 	stateManager.SetBool("isAnyoneHome", true)
@@ -932,30 +948,25 @@ func TestBeginWake_LaunchesFadeOut(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Trigger begin_wake
+	// Trigger begin_wake and wait for the fade's first step
 	manager.handleBeginWake()
+	clk.BlockUntil(1)
 
-	// Give goroutine time to start
-	time.Sleep(200 * time.Millisecond)
-
-	// Abort fade out to stop it quickly
-	stateManager.SetBool("isFadeOutInProgress", false)
-
-	// Wait a bit more for goroutine to exit
-	time.Sleep(200 * time.Millisecond)
-
-	// Verify volume_set calls were made
-	calls := mockHA.GetServiceCalls()
-	volumeSetCalls := 0
-	for _, call := range calls {
-		if call.Domain == "media_player" && call.Service == "volume_set" {
-			volumeSetCalls++
-		}
+	// Stop cancels the fade and returns once its goroutine has exited
+	stopped := make(chan struct{})
+	go func() {
+		manager.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not end the fade out")
 	}
 
-	// Should have at least 1 volume set call (fade out started)
-	if volumeSetCalls < 1 {
-		t.Error("Expected at least 1 volume_set call after begin_wake")
+	// The fade took its first step before waiting on the clock
+	if got := countVolumeSetCalls(mockHA); got != 1 {
+		t.Errorf("Expected 1 volume_set call after begin_wake, got %d", got)
 	}
 }
 
@@ -1127,15 +1138,16 @@ func TestGetBedroomSpeakers_EmptyState(t *testing.T) {
 func TestFadeOutSpeaker_WithVolumeQuery(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for fade out
 	stateManager.SetBool("isFadeOutInProgress", true)
 	stateManager.SetString("musicPlaybackType", "sleep")
 
-	// Set up mock to return initial volume of 58 (higher volume = shorter delays)
+	// Set up mock to return initial volume of 58
 	// At 58: delay after first reduction = 60-57 = 3 seconds
 	// At 57: delay = 60-56 = 4 seconds
-	// Total: ~7 seconds for 2 reductions
 	mockHA.SetMockState("media_player.bedroom", &ha.State{
 		EntityID: "media_player.bedroom",
 		State:    "playing",
@@ -1158,44 +1170,28 @@ func TestFadeOutSpeaker_WithVolumeQuery(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Start fade out in goroutine
-	done := make(chan bool)
+	done := make(chan struct{})
 	go func() {
 		manager.fadeOutSpeaker("media_player.bedroom")
-		done <- true
+		close(done)
 	}()
 
-	// Wait for several volume changes (8 seconds to allow 2 reductions)
-	time.Sleep(8 * time.Second)
-
-	// Abort the fade out
-	stateManager.SetBool("isFadeOutInProgress", false)
-
-	// Wait for completion
-	select {
-	case <-done:
-		// Fade out completed
-	case <-time.After(10 * time.Second):
-		t.Fatal("Fade out did not stop within timeout")
+	// Let the fade take two reductions, then abort it
+	stepFadeOut(clk, 1)
+	if elapsed := clk.Since(now); elapsed != 3*time.Second {
+		t.Errorf("Expected the second reduction 3s in, got %v", elapsed)
 	}
+	stateManager.SetBool("isFadeOutInProgress", false)
+	clk.AdvanceToNext()
+	<-done
 
 	// Verify GetState was called to query initial volume
 	if !mockHA.WasGetStateCalled("media_player.bedroom") {
 		t.Error("Expected GetState to be called for media_player.bedroom")
 	}
 
-	// Verify volume_set calls were made
-	calls := mockHA.GetServiceCalls()
-	volumeSetCalls := 0
-	for _, call := range calls {
-		if call.Domain == "media_player" && call.Service == "volume_set" {
-			volumeSetCalls++
-		}
-	}
-
-	// Should have made multiple volume set calls
-	if volumeSetCalls < 2 {
-		t.Errorf("Expected at least 2 volume_set calls, got %d", volumeSetCalls)
+	if got := countVolumeSetCalls(mockHA); got != 2 {
+		t.Errorf("Expected 2 volume_set calls, got %d", got)
 	}
 
 	// Verify currentlyPlayingMusic was updated
@@ -1219,8 +1215,8 @@ func TestFadeOutSpeaker_WithVolumeQuery(t *testing.T) {
 		t.Fatalf("Unexpected volume type: %T", bedroom["volume"])
 	}
 
-	if bedroomVolume >= 58 {
-		t.Errorf("Expected volume to be reduced from 58, got %d", bedroomVolume)
+	if bedroomVolume != 56 {
+		t.Errorf("Expected volume to be reduced from 58 to 56, got %d", bedroomVolume)
 	}
 }
 
@@ -1228,6 +1224,8 @@ func TestFadeOutSpeaker_WithVolumeQuery(t *testing.T) {
 func TestBeginWake_MultipleSpeakers(t *testing.T) {
 	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
 	manager, mockHA, stateManager, _ := setupTest(t, now)
+	clk := testclock.New(now)
+	manager.SetClock(clk)
 
 	// Set conditions for begin_wake
 	stateManager.SetBool("isAnyoneHome", true)
@@ -1269,17 +1267,14 @@ func TestBeginWake_MultipleSpeakers(t *testing.T) {
 	// Clear previous calls
 	mockHA.ClearServiceCalls()
 
-	// Trigger begin_wake
+	// Trigger begin_wake and wait for both speakers' first step
 	manager.handleBeginWake()
+	clk.BlockUntil(2)
 
-	// Give goroutines time to start
-	time.Sleep(300 * time.Millisecond)
-
-	// Abort fade out to stop it quickly
+	// Abort fade out before either takes another
 	stateManager.SetBool("isFadeOutInProgress", false)
-
-	// Wait a bit more for goroutines to exit
-	time.Sleep(300 * time.Millisecond)
+	clk.Advance(time.Minute)
+	manager.fades.Wait()
 
 	// Verify both speakers were queried
 	if !mockHA.WasGetStateCalled("media_player.bedroom") {
@@ -1305,12 +1300,12 @@ func TestBeginWake_MultipleSpeakers(t *testing.T) {
 		}
 	}
 
-	// Should have at least 1 call for each speaker
-	if bedroomCalls < 1 {
-		t.Error("Expected at least 1 volume_set call for media_player.bedroom")
+	// Should have 1 call for each speaker
+	if bedroomCalls != 1 {
+		t.Errorf("Expected 1 volume_set call for media_player.bedroom, got %d", bedroomCalls)
 	}
-	if bedroomLeftCalls < 1 {
-		t.Error("Expected at least 1 volume_set call for media_player.bedroom_left")
+	if bedroomLeftCalls != 1 {
+		t.Errorf("Expected 1 volume_set call for media_player.bedroom_left, got %d", bedroomLeftCalls)
 	}
 }
 
@@ -1323,7 +1318,7 @@ func TestManagerReset(t *testing.T) {
 
 	manager := NewManager(mockClient, stateManager, configLoader, logger, false, timeProvider)
 
-	err := manager.Start()
+	err := manager.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
@@ -1567,7 +1562,7 @@ func TestStart_SubscribesToEightSleepSensors(t *testing.T) {
 	manager, mockHA, _, _ := setupTest(t, now)
 
 	// Start manager
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
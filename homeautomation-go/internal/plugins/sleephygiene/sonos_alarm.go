@@ -0,0 +1,188 @@
+package sleephygiene
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// sonosTimeFormat is how the Sonos integration writes and reports alarm times
+const sonosTimeFormat = "15:04:05"
+
+// sonosAlarmTarget is what the Sonos alarm should be set to
+type sonosAlarmTarget struct {
+	wakeAt  time.Time
+	time    string // wakeAt plus the delay, in sonosTimeFormat
+	enabled bool
+	reason  string // Why it's disabled
+}
+
+// SetSonosAlarm enables mirroring the wake time into a native Sonos alarm, so
+// the bedroom speaker still wakes everyone if this service is down at wake time
+func (m *Manager) SetSonosAlarm(config *SonosAlarmConfig) {
+	m.sonosAlarm = config
+}
+
+// sonosAlarmEnabled reports whether the Sonos alarm is kept in sync
+func (m *Manager) sonosAlarmEnabled() bool {
+	return m.sonosAlarm != nil && m.sonosAlarm.SonosAlarm.Enabled
+}
+
+// syncSonosAlarm updates the Sonos alarm when the next wake time or whether it
+// should sound changed. It runs every minute, so changes to alarmTime, the
+// schedule, presence and today's wake sequence reach Sonos within a minute.
+func (m *Manager) syncSonosAlarm(now, scheduledWake time.Time) {
+	if !m.sonosAlarmEnabled() {
+		return
+	}
+
+	target, err := m.nextSonosAlarm(now, scheduledWake)
+	if err != nil {
+		m.logger.Error("Failed to compute the Sonos alarm time", zap.Error(err))
+		return
+	}
+
+	m.sonosAlarmMu.Lock()
+	synced := m.sonosAlarmSynced
+	m.sonosAlarmMu.Unlock()
+	if synced != nil && synced.time == target.time && synced.enabled == target.enabled {
+		return
+	}
+
+	settings := m.sonosAlarm.SonosAlarm
+	m.logger.Info("Updating Sonos alarm",
+		zap.Int("alarm_id", settings.AlarmID),
+		zap.String("time", target.time),
+		zap.Bool("enabled", target.enabled),
+		zap.String("reason", target.reason))
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would update Sonos alarm", zap.Int("alarm_id", settings.AlarmID))
+	} else if err := m.haClient.CallService(m.ctx, "sonos", "update_alarm", map[string]interface{}{
+		"entity_id":            settings.Speaker,
+		"alarm_id":             settings.AlarmID,
+		"time":                 target.time,
+		"volume":               ha.VolumeFromSonos(settings.Volume).Level(),
+		"enabled":              target.enabled,
+		"include_linked_zones": settings.IncludeLinkedZones,
+	}); err != nil {
+		m.logger.Error("Failed to update Sonos alarm", zap.Error(err))
+		return
+	}
+
+	m.sonosAlarmMu.Lock()
+	m.sonosAlarmSynced = &target
+	m.sonosAlarmMu.Unlock()
+
+	m.shadowTracker.UpdateSonosAlarm(shadowstate.SonosAlarm{
+		AlarmEntity: m.sonosAlarm.Entity(),
+		Time:        target.time,
+		WakeTime:    target.wakeAt,
+		Enabled:     target.enabled,
+		Reason:      target.reason,
+		SyncedAt:    now,
+	})
+}
+
+// nextSonosAlarm returns the alarm for the next wake this service hasn't
+// handled: today's while it's still ahead, otherwise tomorrow's. Once the wake
+// sequence has run, the alarm stays off until today's alarm time passes, since
+// a repeating Sonos alarm would otherwise still sound today.
+func (m *Manager) nextSonosAlarm(now, scheduledWake time.Time) (sonosAlarmTarget, error) {
+	delay := m.sonosAlarm.Delay()
+	target := sonosAlarmTarget{enabled: true}
+
+	target.wakeAt = m.wakeTimeOn(now, scheduledWake)
+	if now.Before(target.wakeAt.Add(delay)) {
+		if m.wokeToday() {
+			target.enabled = false
+			target.reason = "wake sequence already ran today"
+		}
+	} else {
+		tomorrow := now.AddDate(0, 0, 1)
+		schedule, err := m.configLoader.GetScheduleFor(tomorrow)
+		if err != nil {
+			return sonosAlarmTarget{}, fmt.Errorf("failed to get tomorrow's schedule: %w", err)
+		}
+		target.wakeAt = m.wakeTimeOn(tomorrow, scheduledWakeOn(tomorrow, schedule.Wake))
+	}
+	target.time = target.wakeAt.Add(delay).Format(sonosTimeFormat)
+
+	if target.enabled {
+		if anyoneHome, err := m.stateManager.GetBool("isAnyoneHome"); err == nil && !anyoneHome {
+			target.enabled = false
+			target.reason = "no one is home"
+		}
+	}
+	return target, nil
+}
+
+// wokeToday reports whether this service started a wake sequence today
+func (m *Manager) wokeToday() bool {
+	for _, trigger := range []string{"begin_wake", wakeLightTrigger} {
+		if _, triggered := m.triggeredToday[trigger]; triggered {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifySonosAlarm checks that the Sonos alarm exists and still matches its
+// last sync, for the nightly self-check. A mismatch (e.g. the alarm was edited
+// in the Sonos app) is reported and re-synced on the next minute.
+func (m *Manager) VerifySonosAlarm() error {
+	if !m.sonosAlarmEnabled() {
+		return nil
+	}
+
+	entityID := m.sonosAlarm.Entity()
+	problem := m.sonosAlarmProblem(entityID)
+	m.shadowTracker.RecordSonosAlarmCheck(entityID, m.now(), problem)
+	if problem == "" {
+		m.logger.Info("Sonos alarm verified", zap.String("alarm_entity", entityID))
+		return nil
+	}
+
+	m.logger.Warn("Sonos alarm check failed", zap.String("alarm_entity", entityID), zap.String("problem", problem))
+	return errors.New(problem)
+}
+
+// sonosAlarmProblem compares the alarm entity with the last sync, returning
+// what's wrong or "" if it matches. A mismatch forgets the sync so the next
+// minute writes the alarm again.
+func (m *Manager) sonosAlarmProblem(entityID string) string {
+	alarm, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil || alarm == nil || alarm.State == "unavailable" || alarm.State == "unknown" {
+		return fmt.Sprintf("Sonos alarm %s not found; create alarm %d in the Sonos app", entityID, m.sonosAlarm.SonosAlarm.AlarmID)
+	}
+
+	m.sonosAlarmMu.Lock()
+	defer m.sonosAlarmMu.Unlock()
+	synced := m.sonosAlarmSynced
+	if synced == nil || m.readOnly {
+		// Nothing written yet to compare against
+		return ""
+	}
+
+	alarmTime, _ := alarm.Attributes["time"].(string)
+	enabled := alarm.State == "on"
+	if alarmTime == synced.time && enabled == synced.enabled {
+		return ""
+	}
+
+	m.sonosAlarmSynced = nil
+	return fmt.Sprintf("Sonos alarm %s is set to %s (%s) instead of %s (%s); re-syncing",
+		entityID, alarmTime, onOff(enabled), synced.time, onOff(synced.enabled))
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
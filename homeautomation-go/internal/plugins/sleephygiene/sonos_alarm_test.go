@@ -0,0 +1,239 @@
+package sleephygiene
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/config"
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+func newTestSonosAlarm() *SonosAlarmConfig {
+	return &SonosAlarmConfig{SonosAlarm: SonosAlarmSettings{
+		Enabled:      true,
+		Speaker:      "media_player.bedroom",
+		AlarmID:      7,
+		DelayMinutes: 10,
+		Volume:       6,
+	}}
+}
+
+// setupSonosAlarmTest returns a manager waking at 07:00 UTC every day with
+// the Sonos alarm enabled
+func setupSonosAlarmTest(t *testing.T, now time.Time) (*Manager, *ha.MockClient) {
+	t.Helper()
+
+	entry := `  - begin_wake: "06:30"
+    wake: "07:00"
+    dusk: "18:00"
+    winddown: "21:00"
+    stop_screens: "22:00"
+    go_to_bed: "22:30"
+    night: "23:00"
+`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "schedule_config.yaml"), []byte("schedule:\n"+strings.Repeat(entry, 7)), 0644); err != nil {
+		t.Fatalf("Failed to write schedule: %v", err)
+	}
+	loader := config.NewLoader(dir, zap.NewNop())
+	loader.SetTimezone(time.UTC)
+	if err := loader.LoadScheduleConfig(); err != nil {
+		t.Fatalf("Failed to load schedule: %v", err)
+	}
+
+	manager, mockHA, _, _ := setupTest(t, now)
+	manager.configLoader = loader
+	manager.SetTimezone(time.UTC)
+	manager.SetSonosAlarm(newTestSonosAlarm())
+	mockHA.ClearServiceCalls()
+	return manager, mockHA
+}
+
+// sonosAlarmUpdates returns the sonos.update_alarm calls made
+func sonosAlarmUpdates(mockHA *ha.MockClient) []ha.ServiceCall {
+	var updates []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "sonos" && call.Service == "update_alarm" {
+			updates = append(updates, call)
+		}
+	}
+	return updates
+}
+
+func TestSonosAlarm_SyncsNextWake(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		setup    func(m *Manager)
+		wantTime string
+		wantOn   bool
+	}{
+		{"evening: tomorrow's schedule", time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC), nil, "07:10:00", true},
+		{"early morning: today's schedule", time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC), nil, "07:10:00", true},
+		{"alarmTime set for tomorrow", time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC), func(m *Manager) {
+			m.stateManager.SetNumber("alarmTime", float64(time.Date(2024, 1, 16, 6, 15, 0, 0, time.UTC).UnixMilli()))
+		}, "06:25:00", true},
+		{"wake sequence already ran", time.Date(2024, 1, 15, 7, 2, 0, 0, time.UTC), func(m *Manager) {
+			m.triggeredToday["begin_wake"] = time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)
+		}, "07:10:00", false},
+		{"no one home", time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC), func(m *Manager) {
+			m.stateManager.SetBool("isAnyoneHome", false)
+		}, "07:10:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, mockHA := setupSonosAlarmTest(t, tt.now)
+			if tt.setup != nil {
+				tt.setup(manager)
+			}
+
+			manager.checkTimeTriggers()
+
+			updates := sonosAlarmUpdates(mockHA)
+			if len(updates) != 1 {
+				t.Fatalf("Expected 1 alarm update, got %d", len(updates))
+			}
+			data := updates[0].Data
+			if data["entity_id"] != "media_player.bedroom" || data["alarm_id"] != 7 {
+				t.Errorf("Expected alarm 7 on the bedroom speaker, got %v", data)
+			}
+			if data["time"] != tt.wantTime || data["enabled"] != tt.wantOn {
+				t.Errorf("Expected %s enabled=%v, got %v enabled=%v", tt.wantTime, tt.wantOn, data["time"], data["enabled"])
+			}
+			if data["volume"] != 0.4 {
+				t.Errorf("Expected volume 6/15 = 0.4, got %v", data["volume"])
+			}
+
+			alarm := manager.GetShadowState().Outputs.SonosAlarm
+			if alarm == nil || alarm.Time != tt.wantTime || alarm.Enabled != tt.wantOn {
+				t.Errorf("Expected shadow state to record the sync, got %+v", alarm)
+			}
+		})
+	}
+}
+
+func TestSonosAlarm_UpdatesOnlyOnChange(t *testing.T) {
+	now := time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC)
+	manager, mockHA := setupSonosAlarmTest(t, now)
+
+	manager.checkTimeTriggers()
+	manager.checkTimeTriggers()
+	if updates := sonosAlarmUpdates(mockHA); len(updates) != 1 {
+		t.Fatalf("Expected an unchanged alarm to be written once, got %d", len(updates))
+	}
+
+	manager.stateManager.SetNumber("alarmTime", float64(time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC).UnixMilli()))
+	manager.checkTimeTriggers()
+	updates := sonosAlarmUpdates(mockHA)
+	if len(updates) != 2 || updates[1].Data["time"] != "06:10:00" {
+		t.Fatalf("Expected alarmTime change to move the alarm to 06:10:00, got %v", updates)
+	}
+}
+
+func TestSonosAlarm_Disabled(t *testing.T) {
+	now := time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC)
+	manager, mockHA := setupSonosAlarmTest(t, now)
+	manager.sonosAlarm.SonosAlarm.Enabled = false
+
+	manager.checkTimeTriggers()
+	if updates := sonosAlarmUpdates(mockHA); len(updates) != 0 {
+		t.Errorf("Expected no alarm updates when disabled, got %d", len(updates))
+	}
+	if err := manager.VerifySonosAlarm(); err != nil {
+		t.Errorf("Expected no verification when disabled, got %v", err)
+	}
+}
+
+func TestVerifySonosAlarm(t *testing.T) {
+	now := time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC)
+	manager, mockHA := setupSonosAlarmTest(t, now)
+
+	// Missing alarm entity
+	err := manager.VerifySonosAlarm()
+	if err == nil || !strings.Contains(err.Error(), "switch.sonos_alarm_7 not found") {
+		t.Fatalf("Expected missing alarm error, got %v", err)
+	}
+
+	manager.checkTimeTriggers()
+
+	// Matches the sync
+	mockHA.SetMockState("switch.sonos_alarm_7", &ha.State{
+		EntityID:   "switch.sonos_alarm_7",
+		State:      "on",
+		Attributes: map[string]interface{}{"time": "07:10:00"},
+	})
+	if err := manager.VerifySonosAlarm(); err != nil {
+		t.Fatalf("Expected alarm to verify, got %v", err)
+	}
+	if alarm := manager.GetShadowState().Outputs.SonosAlarm; alarm.VerifiedAt.IsZero() || alarm.Problem != "" {
+		t.Errorf("Expected a clean verification in shadow state, got %+v", alarm)
+	}
+
+	// Edited in the Sonos app: reported, then re-synced on the next check
+	mockHA.SetMockState("switch.sonos_alarm_7", &ha.State{
+		EntityID:   "switch.sonos_alarm_7",
+		State:      "on",
+		Attributes: map[string]interface{}{"time": "08:00:00"},
+	})
+	err = manager.VerifySonosAlarm()
+	if err == nil || !strings.Contains(err.Error(), "08:00:00") {
+		t.Fatalf("Expected drift error, got %v", err)
+	}
+	if alarm := manager.GetShadowState().Outputs.SonosAlarm; alarm.Problem == "" {
+		t.Error("Expected the problem in shadow state")
+	}
+
+	manager.checkTimeTriggers()
+	if updates := sonosAlarmUpdates(mockHA); len(updates) != 2 {
+		t.Errorf("Expected the alarm to be written again after drift, got %d updates", len(updates))
+	}
+}
+
+func TestLoadSonosAlarmConfig_ProductionConfig(t *testing.T) {
+	config, err := LoadSonosAlarmConfig("../../../../configs/sonos_alarm_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load production Sonos alarm config: %v", err)
+	}
+	if config.Entity() != "switch.sonos_alarm_1" {
+		t.Errorf("Expected switch.sonos_alarm_1, got %s", config.Entity())
+	}
+}
+
+func TestSonosAlarmConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *SonosAlarmConfig)
+		wantErr string
+	}{
+		{"valid", func(c *SonosAlarmConfig) {}, ""},
+		{"not a media player", func(c *SonosAlarmConfig) { c.SonosAlarm.Speaker = "switch.bedroom" }, "media_player.*"},
+		{"no alarm id", func(c *SonosAlarmConfig) { c.SonosAlarm.AlarmID = 0 }, "alarm_id"},
+		{"alarm entity not a switch", func(c *SonosAlarmConfig) { c.SonosAlarm.AlarmEntity = "sensor.alarm" }, "switch.*"},
+		{"delay too long", func(c *SonosAlarmConfig) { c.SonosAlarm.DelayMinutes = 90 }, "delay_minutes"},
+		{"volume on the wrong scale", func(c *SonosAlarmConfig) { c.SonosAlarm.Volume = 40 }, "volume"},
+		{"silent", func(c *SonosAlarmConfig) { c.SonosAlarm.Volume = 0 }, "volume"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestSonosAlarm()
+			tt.mutate(config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid config, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package sleephygiene
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// triggersVariable saves when each trigger fired today in Home Assistant, so
+// a restart during the morning or evening doesn't replay actions already taken
+const triggersVariable = "sleepHygieneTriggers"
+
+// restoreTriggers loads the triggers that fired today before a restart
+func (m *Manager) restoreTriggers(now time.Time) {
+	var saved map[string]string
+	if err := m.stateManager.GetJSON(triggersVariable, &saved); err != nil {
+		m.logger.Warn("Failed to restore sleep hygiene triggers", zap.Error(err))
+		return
+	}
+
+	for trigger, value := range saved {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			m.logger.Warn("Ignoring invalid saved trigger time",
+				zap.String("trigger", trigger),
+				zap.String("value", value))
+			continue
+		}
+		at = at.In(now.Location())
+		if !isSameDay(now, at) {
+			continue
+		}
+		if _, exists := m.triggeredToday[trigger]; exists {
+			continue
+		}
+		m.triggeredToday[trigger] = at
+		m.logger.Info("Restored trigger fired before restart",
+			zap.String("trigger", trigger),
+			zap.Time("triggered_at", at))
+	}
+}
+
+// markTriggered records that a trigger fired and saves today's triggers
+func (m *Manager) markTriggered(trigger string, now time.Time) {
+	m.triggeredToday[trigger] = now
+	m.saveTriggers()
+}
+
+// clearStaleTriggers forgets triggers that fired on a previous day
+func (m *Manager) clearStaleTriggers(now time.Time) {
+	cleared := false
+	for trigger, triggerTime := range m.triggeredToday {
+		if !isSameDay(now, triggerTime) {
+			m.logger.Debug("Resetting trigger for new day",
+				zap.String("trigger", trigger))
+			delete(m.triggeredToday, trigger)
+			cleared = true
+		}
+	}
+	if cleared {
+		m.saveTriggers()
+	}
+}
+
+// saveTriggers writes triggeredToday to triggersVariable
+func (m *Manager) saveTriggers() {
+	if m.readOnly {
+		m.logger.Debug("READ-ONLY: Would save sleep hygiene triggers")
+		return
+	}
+
+	saved := make(map[string]interface{}, len(m.triggeredToday))
+	for trigger, at := range m.triggeredToday {
+		saved[trigger] = at.Format(time.RFC3339)
+	}
+	if err := m.stateManager.SetJSON(triggersVariable, saved); err != nil {
+		m.logger.Warn("Failed to save sleep hygiene triggers", zap.Error(err))
+	}
+}
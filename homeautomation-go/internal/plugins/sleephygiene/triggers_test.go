@@ -0,0 +1,83 @@
+package sleephygiene
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// TestTriggers_SurviveRestart tests that begin_wake isn't replayed by a
+// manager started later the same morning
+func TestTriggers_SurviveRestart(t *testing.T) {
+	wakeTime := time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)
+	manager, mockHA, stateManager, configLoader := setupTest(t, wakeTime)
+
+	manager.handleEightSleepAlarm("sensor.nick_s_eight_sleep_side_bed_state_type", &ha.State{State: "off"}, &ha.State{State: "alarm"})
+
+	var saved map[string]string
+	if err := stateManager.GetJSON(triggersVariable, &saved); err != nil {
+		t.Fatalf("Failed to read saved triggers: %v", err)
+	}
+	if saved["begin_wake"] != "2024-01-15T06:30:00Z" {
+		t.Fatalf("Expected begin_wake to be saved, got %v", saved)
+	}
+
+	// The process restarts at 09:10 while the sensor still reports an alarm
+	stateManager.SetBool("isMasterAsleep", true)
+	stateManager.SetString("musicPlaybackType", "sleep")
+	stateManager.SetBool("isFadeOutInProgress", false)
+
+	restartTime := time.Date(2024, 1, 15, 9, 10, 0, 0, time.UTC)
+	restarted := NewManager(mockHA, stateManager, configLoader, zap.NewNop(), false, FixedTimeProvider{FixedTime: restartTime})
+	restarted.restoreTriggers(restartTime)
+
+	if got := restarted.triggeredToday["begin_wake"]; !got.Equal(wakeTime) {
+		t.Fatalf("Expected begin_wake restored at %v, got %v", wakeTime, got)
+	}
+
+	restarted.handleEightSleepAlarm("sensor.nick_s_eight_sleep_side_bed_state_type", &ha.State{State: "off"}, &ha.State{State: "alarm"})
+	if fadeOut, _ := stateManager.GetBool("isFadeOutInProgress"); fadeOut {
+		t.Error("begin_wake should not be replayed after a restart")
+	}
+}
+
+// TestTriggers_IgnoresPreviousDay tests that triggers saved on an earlier day
+// are not restored
+func TestTriggers_IgnoresPreviousDay(t *testing.T) {
+	now := time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC)
+	manager, _, stateManager, _ := setupTest(t, now)
+
+	if err := stateManager.SetJSON(triggersVariable, map[string]interface{}{
+		"begin_wake": "2024-01-15T06:30:00Z",
+		"go_to_bed":  "not a time",
+	}); err != nil {
+		t.Fatalf("Failed to save triggers: %v", err)
+	}
+
+	manager.restoreTriggers(now)
+
+	if len(manager.triggeredToday) != 0 {
+		t.Errorf("Expected no triggers restored, got %v", manager.triggeredToday)
+	}
+}
+
+// TestTriggers_ClearStaleSaves tests that clearing yesterday's triggers also
+// clears the saved records
+func TestTriggers_ClearStaleSaves(t *testing.T) {
+	now := time.Date(2024, 1, 15, 22, 30, 0, 0, time.UTC)
+	manager, _, stateManager, _ := setupTest(t, now)
+
+	manager.markTriggered("stop_screens", now)
+	manager.clearStaleTriggers(now.Add(2 * time.Hour))
+
+	var saved map[string]string
+	if err := stateManager.GetJSON(triggersVariable, &saved); err != nil {
+		t.Fatalf("Failed to read saved triggers: %v", err)
+	}
+	if len(saved) != 0 || len(manager.triggeredToday) != 0 {
+		t.Errorf("Expected triggers to be cleared, got saved=%v in memory=%v", saved, manager.triggeredToday)
+	}
+}
@@ -0,0 +1,163 @@
+package sleephygiene
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// wakeLightTrigger is the triggeredToday key for the scheduled wake light ramp
+const wakeLightTrigger = "wake_light"
+
+// SetWakeLight enables choosing the wake light ramp from the morning's outdoor
+// light level, and starting it ahead of the wake time on dark mornings
+func (m *Manager) SetWakeLight(config *WakeLightConfig) {
+	m.wakeLight = config
+}
+
+// wakeLightEnabled reports whether the wake light adapts to the morning
+func (m *Manager) wakeLightEnabled() bool {
+	return m.wakeLight != nil && m.wakeLight.WakeLight.Enabled
+}
+
+// checkWakeLight starts the wake light ramp once today's wake time, less the
+// chosen profile's lead, arrives. The profile is re-chosen each minute until
+// then, so a brightening sky can still move the start later.
+func (m *Manager) checkWakeLight(now, scheduledWake time.Time) {
+	if !m.wakeLightEnabled() {
+		return
+	}
+	if _, triggered := m.triggeredToday[wakeLightTrigger]; triggered {
+		return
+	}
+
+	wakeAt := m.wakeTimeOn(now, scheduledWake)
+	if now.Before(wakeAt.Add(-m.wakeLight.MaxLead())) || !now.Before(wakeAt.Add(time.Hour)) {
+		return
+	}
+
+	profile, record := m.chooseWakeLight(now)
+	record.WakeTime = wakeAt
+	record.StartAt = wakeAt.Add(-profile.Lead())
+	record.Started = !now.Before(record.StartAt)
+	m.shadowTracker.UpdateWakeLight(record)
+	if !record.Started {
+		return
+	}
+
+	m.logger.Info("Triggering wake light",
+		zap.String("profile", profile.Name),
+		zap.Time("wake_time", wakeAt),
+		zap.Int("lead_minutes", profile.LeadMinutes))
+	m.markTriggered(wakeLightTrigger, now)
+	m.handleWakeLight(profile)
+}
+
+// wakeTimeOn returns today's alarmTime if it was set for today, otherwise the
+// scheduled wake time
+func (m *Manager) wakeTimeOn(now, scheduledWake time.Time) time.Time {
+	alarmMillis, err := m.stateManager.GetNumber("alarmTime")
+	if err != nil || alarmMillis <= 0 {
+		return scheduledWake
+	}
+	alarm := time.UnixMilli(int64(alarmMillis)).In(now.Location())
+	if !isSameDay(alarm, now) {
+		return scheduledWake
+	}
+	return alarm
+}
+
+// chooseWakeLight picks the ramp profile from the current outdoor light level
+// and weather, returning it with its shadow state record
+func (m *Manager) chooseWakeLight(now time.Time) (WakeLightProfile, shadowstate.WakeLight) {
+	if !m.wakeLightEnabled() {
+		return defaultWakeLightProfile, shadowstate.WakeLight{}
+	}
+
+	lux := m.readLux()
+	condition := m.readWeatherCondition()
+	profile := m.wakeLight.Choose(lux, condition)
+	return profile, shadowstate.WakeLight{
+		Profile:            profile.Name,
+		Lux:                lux,
+		Condition:          condition,
+		FinalBrightnessPct: profile.FinalBrightnessPct,
+		TransitionMinutes:  profile.TransitionMinutes,
+		LeadMinutes:        profile.LeadMinutes,
+		ChosenAt:           now,
+	}
+}
+
+// readLux returns the outdoor light level, or nil if it can't be read
+func (m *Manager) readLux() *float64 {
+	entityID := m.wakeLight.WakeLight.LuxEntity
+	if entityID == "" {
+		return nil
+	}
+	sensor, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		m.logger.Warn("Failed to read lux sensor", zap.String("entity_id", entityID), zap.Error(err))
+		return nil
+	}
+	lux, err := strconv.ParseFloat(sensor.State, 64)
+	if err != nil {
+		m.logger.Debug("Lux sensor has no reading", zap.String("entity_id", entityID), zap.String("state", sensor.State))
+		return nil
+	}
+	return &lux
+}
+
+// readWeatherCondition returns the weather entity's condition, or "" if it
+// can't be read
+func (m *Manager) readWeatherCondition() string {
+	entityID := m.wakeLight.WakeLight.WeatherEntity
+	if entityID == "" {
+		return ""
+	}
+	weather, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		m.logger.Warn("Failed to read weather", zap.String("entity_id", entityID), zap.Error(err))
+		return ""
+	}
+	if weather.State == "unavailable" || weather.State == "unknown" {
+		return ""
+	}
+	return weather.State
+}
+
+// handleWakeLight starts the master bedroom light ramp with the chosen profile
+func (m *Manager) handleWakeLight(profile WakeLightProfile) {
+	isAnyoneHome, err := m.stateManager.GetBool("isAnyoneHome")
+	if err != nil || !isAnyoneHome {
+		m.logger.Debug("Skipping wake light: no one home")
+		return
+	}
+
+	isMasterAsleep, err := m.stateManager.GetBool("isMasterAsleep")
+	if err != nil || !isMasterAsleep {
+		m.logger.Debug("Skipping wake light: master not asleep")
+		return
+	}
+
+	if bedroom, ok := m.dnd.Suppresses(m.entities.MasterBedroomLight()); ok {
+		m.logger.Info("Skipping wake light: bedroom is in do-not-disturb", zap.String("bedroom", bedroom))
+		m.recordAction("dnd_suppressed", fmt.Sprintf("Skipped wake light: %s bedroom is in do-not-disturb", bedroom), "wake_light_timer")
+		return
+	}
+
+	m.recordAction("wake_light", fmt.Sprintf("Starting %s wake light: %d%% over %d minutes",
+		profile.Name, profile.FinalBrightnessPct, profile.TransitionMinutes), "wake_light_timer")
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would start wake light",
+			zap.String("profile", profile.Name),
+			zap.Int("brightness_pct", profile.FinalBrightnessPct),
+			zap.Int("transition_minutes", profile.TransitionMinutes))
+		return
+	}
+	m.turnOnMasterBedroomLights(profile)
+}
@@ -0,0 +1,174 @@
+package sleephygiene
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/ha"
+)
+
+// wakeLightRamp returns the brightness and transition of the ramp call to the
+// master bedroom lights, if one was made
+func wakeLightRamp(mockHA *ha.MockClient) (brightness, transition int, found bool) {
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain != "light" || call.Service != "turn_on" {
+			continue
+		}
+		if t, ok := call.Data["transition"].(int); ok && t > 0 {
+			return call.Data["brightness_pct"].(int), t, true
+		}
+	}
+	return 0, 0, false
+}
+
+func TestWakeLight_DarkMorningStartsEarlier(t *testing.T) {
+	scheduledWake := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		lux         string
+		wantStarted bool
+		wantProfile string
+	}{
+		{"dark, inside the lead", time.Date(2024, 1, 15, 9, 6, 0, 0, time.UTC), "80", true, "dark"},
+		{"bright, before the wake time", time.Date(2024, 1, 15, 9, 6, 0, 0, time.UTC), "9000", false, "bright"},
+		{"bright, at the wake time", time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC), "9000", true, "bright"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, mockHA, _, _ := setupTest(t, tt.now)
+			manager.SetWakeLight(newTestWakeLight())
+			mockHA.SetState("sensor.outdoor_illuminance", tt.lux, nil)
+			mockHA.ClearServiceCalls()
+
+			manager.checkWakeLight(tt.now, scheduledWake)
+
+			_, _, found := wakeLightRamp(mockHA)
+			if found != tt.wantStarted {
+				t.Errorf("Expected ramp started %v, got %v", tt.wantStarted, found)
+			}
+
+			wakeLight := manager.GetShadowState().Outputs.WakeLight
+			if wakeLight == nil {
+				t.Fatal("Expected wake light in shadow state")
+			}
+			if wakeLight.Profile != tt.wantProfile {
+				t.Errorf("Expected profile %q, got %q", tt.wantProfile, wakeLight.Profile)
+			}
+			if wakeLight.Started != tt.wantStarted {
+				t.Errorf("Expected started %v in shadow state, got %v", tt.wantStarted, wakeLight.Started)
+			}
+		})
+	}
+}
+
+func TestWakeLight_NotCheckedBeforeLongestLead(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 4, 0, 0, time.UTC)
+	manager, mockHA, _, _ := setupTest(t, now)
+	manager.SetWakeLight(newTestWakeLight())
+	mockHA.SetState("sensor.outdoor_illuminance", "80", nil)
+	mockHA.ClearServiceCalls()
+
+	manager.checkWakeLight(now, time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+
+	if _, _, found := wakeLightRamp(mockHA); found {
+		t.Error("Expected no ramp before the longest lead")
+	}
+	if manager.GetShadowState().Outputs.WakeLight != nil {
+		t.Error("Expected no profile chosen before the longest lead")
+	}
+}
+
+func TestWakeLight_RampUsesProfile(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 5, 0, 0, time.UTC)
+	manager, mockHA, _, _ := setupTest(t, now)
+	manager.SetWakeLight(newTestWakeLight())
+	mockHA.SetState("sensor.outdoor_illuminance", "unavailable", nil)
+	mockHA.SetState("weather.home", "rainy", nil)
+	mockHA.ClearServiceCalls()
+
+	manager.checkWakeLight(now, time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+
+	brightness, transition, found := wakeLightRamp(mockHA)
+	if !found {
+		t.Fatal("Expected the wake light ramp to start")
+	}
+	if brightness != 100 || transition != 40*60 {
+		t.Errorf("Expected dark ramp to 100%% over 40 minutes, got %d%% over %ds", brightness, transition)
+	}
+
+	wakeLight := manager.GetShadowState().Outputs.WakeLight
+	if wakeLight.Lux != nil || wakeLight.Condition != "rainy" {
+		t.Errorf("Expected no lux and a rainy condition recorded, got %+v", wakeLight)
+	}
+
+	// Only once a day
+	mockHA.ClearServiceCalls()
+	manager.checkWakeLight(now.Add(time.Minute), time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+	if _, _, found := wakeLightRamp(mockHA); found {
+		t.Error("Expected the wake light to start only once a day")
+	}
+}
+
+func TestWakeLight_FollowsAlarmTime(t *testing.T) {
+	now := time.Date(2024, 1, 15, 7, 55, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetWakeLight(newTestWakeLight())
+	mockHA.SetState("sensor.outdoor_illuminance", "50", nil)
+	stateManager.SetNumber("alarmTime", float64(time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC).UnixMilli()))
+	mockHA.ClearServiceCalls()
+
+	manager.checkWakeLight(now, time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC))
+
+	if _, _, found := wakeLightRamp(mockHA); !found {
+		t.Error("Expected the ramp to start ahead of today's earlier alarm")
+	}
+}
+
+func TestWakeLight_SkippedWhenMasterAwake(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetWakeLight(newTestWakeLight())
+	stateManager.SetBool("isMasterAsleep", false)
+	mockHA.ClearServiceCalls()
+
+	manager.checkWakeLight(now, now)
+
+	if _, _, found := wakeLightRamp(mockHA); found {
+		t.Error("Expected no wake light when the master bedroom is awake")
+	}
+}
+
+func TestWake_SkipsLightsWhenWakeLightStarted(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	manager.SetWakeLight(newTestWakeLight())
+	stateManager.SetBool("isFadeOutInProgress", true)
+	manager.markTriggered(wakeLightTrigger, now.Add(-20*time.Minute))
+	mockHA.ClearServiceCalls()
+
+	manager.handleWake()
+
+	if _, _, found := wakeLightRamp(mockHA); found {
+		t.Error("Expected the wake sequence to leave the running ramp alone")
+	}
+}
+
+func TestWake_DefaultRampWithoutConfig(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	manager, mockHA, stateManager, _ := setupTest(t, now)
+	stateManager.SetBool("isFadeOutInProgress", true)
+	mockHA.ClearServiceCalls()
+
+	manager.handleWake()
+
+	brightness, transition, found := wakeLightRamp(mockHA)
+	if !found || brightness != 100 || transition != 1800 {
+		t.Errorf("Expected the default ramp to 100%% over 30 minutes, got %d%% over %ds (found %v)", brightness, transition, found)
+	}
+	if manager.GetShadowState().Outputs.WakeLight != nil {
+		t.Error("Expected no wake light record without a config")
+	}
+}
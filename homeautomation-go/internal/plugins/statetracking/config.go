@@ -0,0 +1,68 @@
+package statetracking
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConsistencyCheckSettings controls the nightly cross-check of derived state
+type ConsistencyCheckSettings struct {
+	Time          string              `yaml:"time"`           // Format: "04:00"
+	NotifyService notifyrouter.Target `yaml:"notify_service"` // e.g. "notify.notify"; empty disables notifications
+}
+
+// ConsistencyCheckConfig represents the consistency_check_config.yaml structure
+type ConsistencyCheckConfig struct {
+	ConsistencyCheck ConsistencyCheckSettings `yaml:"consistency_check"`
+}
+
+// NextRun returns the first scheduled check time strictly after now, in loc
+func (c *ConsistencyCheckConfig) NextRun(now time.Time, loc *time.Location) time.Time {
+	checkTime, err := time.Parse("15:04", c.ConsistencyCheck.Time)
+	if err != nil {
+		checkTime = time.Time{}
+	}
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), checkTime.Hour(), checkTime.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, checkTime.Hour(), checkTime.Minute(), 0, 0, loc)
+	}
+	return next
+}
+
+// Validate checks the check time and notify service
+func (c *ConsistencyCheckConfig) Validate() error {
+	if _, err := time.Parse("15:04", c.ConsistencyCheck.Time); err != nil {
+		return fmt.Errorf("consistency_check: invalid time %q, expected HH:MM", c.ConsistencyCheck.Time)
+	}
+	if c.ConsistencyCheck.NotifyService != "" {
+		if _, _, ok := c.ConsistencyCheck.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("consistency_check: invalid notify_service %q (expected domain.service)", c.ConsistencyCheck.NotifyService)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the consistency check configuration from a YAML file
+func LoadConfig(path string) (*ConsistencyCheckConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ConsistencyCheckConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
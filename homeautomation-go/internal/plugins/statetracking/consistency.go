@@ -0,0 +1,257 @@
+package statetracking
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+
+	"go.uber.org/zap"
+)
+
+// consistencyRule defines a derived state in terms of its inputs
+type consistencyRule struct {
+	variable string
+	inputs   []string
+	applies  func(values map[string]bool) bool // nil means the rule always applies
+	expected func(values map[string]bool) bool
+}
+
+// consistencyRules mirror the derived state helper's computations. Rules are
+// checked in order, so a repaired value is used by the rules after it.
+var consistencyRules = []consistencyRule{
+	{
+		variable: "isAnyOwnerHome",
+		inputs:   []string{"isNickHome", "isCarolineHome"},
+		expected: func(v map[string]bool) bool { return v["isNickHome"] || v["isCarolineHome"] },
+	},
+	{
+		variable: "isAnyoneHome",
+		inputs:   []string{"isAnyOwnerHome", "isToriHere"},
+		expected: func(v map[string]bool) bool { return v["isAnyOwnerHome"] || v["isToriHere"] },
+	},
+	{
+		// Without guests, the guest bedroom mirrors the master bedroom
+		variable: "isGuestAsleep",
+		inputs:   []string{"isHaveGuests", "isMasterAsleep"},
+		applies:  func(v map[string]bool) bool { return !v["isHaveGuests"] },
+		expected: func(v map[string]bool) bool { return v["isMasterAsleep"] },
+	},
+	{
+		variable: "isAnyoneAsleep",
+		inputs:   []string{"isMasterAsleep", "isGuestAsleep"},
+		expected: func(v map[string]bool) bool { return v["isMasterAsleep"] || v["isGuestAsleep"] },
+	},
+	{
+		variable: "isEveryoneAsleep",
+		inputs:   []string{"isMasterAsleep", "isGuestAsleep"},
+		expected: func(v map[string]bool) bool { return v["isMasterAsleep"] && v["isGuestAsleep"] },
+	},
+}
+
+// SetConsistencyCheck enables the nightly check that derived states match
+// their inputs, scheduled in the given timezone. Derived states are normally
+// updated on input changes, so a change missed during a reconnect would
+// otherwise leave them wrong until the next change.
+func (m *Manager) SetConsistencyCheck(config *ConsistencyCheckConfig, timezone *time.Location) {
+	m.consistencyCheck = config
+	if timezone != nil {
+		m.timezone = timezone
+	}
+}
+
+// nightlyCheck is a check another plugin runs as part of the nightly self-check
+type nightlyCheck struct {
+	name  string
+	check func() error
+}
+
+// AddNightlyCheck runs check alongside the derived state rules each night. A
+// returned error is recorded in the shadow state and included in the alert.
+func (m *Manager) AddNightlyCheck(name string, check func() error) {
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+	m.nightlyChecks = append(m.nightlyChecks, nightlyCheck{name: name, check: check})
+}
+
+// runNightlyChecks runs the checks added by other plugins, returning the failures
+func (m *Manager) runNightlyChecks() []shadowstate.ConsistencyFailure {
+	m.timerMutex.Lock()
+	checks := append([]nightlyCheck(nil), m.nightlyChecks...)
+	m.timerMutex.Unlock()
+
+	var failures []shadowstate.ConsistencyFailure
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			m.logger.Warn("Nightly check failed", zap.String("check", c.name), zap.Error(err))
+			failures = append(failures, shadowstate.ConsistencyFailure{Check: c.name, Error: err.Error()})
+		}
+	}
+	return failures
+}
+
+// scheduleConsistencyCheck arms the timer for the next nightly check
+func (m *Manager) scheduleConsistencyCheck() {
+	if m.consistencyCheck == nil {
+		return
+	}
+
+	now := m.clock.Now()
+	next := m.consistencyCheck.NextRun(now, m.timezone)
+
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+
+	if m.consistencyTimer != nil {
+		m.consistencyTimer.Stop()
+	}
+	m.consistencyTimer = m.clock.AfterFunc(next.Sub(now), func() {
+		m.CheckConsistency()
+		m.scheduleConsistencyCheck()
+	})
+
+	m.logger.Debug("Scheduled state consistency check", zap.Time("next_check", next))
+}
+
+// CheckConsistency recomputes each derived state from its inputs, repairs any
+// that don't match, runs the checks other plugins added, and alerts when
+// repairs were needed or a check failed. It returns the repairs.
+func (m *Manager) CheckConsistency() []shadowstate.ConsistencyRepair {
+	if m.ctx.Err() != nil {
+		return nil
+	}
+
+	values := make(map[string]bool)
+	read := func(key string) (bool, error) {
+		if value, ok := values[key]; ok {
+			return value, nil
+		}
+		value, err := m.stateManager.GetBool(key)
+		if err != nil {
+			return false, err
+		}
+		values[key] = value
+		return value, nil
+	}
+
+	repairs := make([]shadowstate.ConsistencyRepair, 0)
+	for _, rule := range consistencyRules {
+		inputs := make(map[string]bool, len(rule.inputs))
+		readable := true
+		for _, key := range rule.inputs {
+			value, err := read(key)
+			if err != nil {
+				m.logger.Error("Failed to read input for consistency check",
+					zap.String("variable", rule.variable),
+					zap.String("input", key),
+					zap.Error(err))
+				readable = false
+				break
+			}
+			inputs[key] = value
+		}
+		if !readable || (rule.applies != nil && !rule.applies(values)) {
+			continue
+		}
+
+		found, err := read(rule.variable)
+		if err != nil {
+			m.logger.Error("Failed to read derived state for consistency check",
+				zap.String("variable", rule.variable),
+				zap.Error(err))
+			continue
+		}
+		expected := rule.expected(values)
+		if found == expected {
+			continue
+		}
+
+		m.logger.Warn("Derived state does not match its inputs, repairing",
+			zap.String("variable", rule.variable),
+			zap.Bool("found", found),
+			zap.Bool("expected", expected),
+			zap.Any("inputs", inputs))
+
+		repair := shadowstate.ConsistencyRepair{
+			Variable: rule.variable,
+			Found:    found,
+			Expected: expected,
+			Inputs:   inputs,
+		}
+		if m.readOnly {
+			m.logger.Info("READ-ONLY: Would repair derived state",
+				zap.String("variable", rule.variable),
+				zap.Bool("value", expected))
+		} else if err := m.stateManager.SetBool(rule.variable, expected); err != nil {
+			m.logger.Error("Failed to repair derived state",
+				zap.String("variable", rule.variable),
+				zap.Error(err))
+		} else {
+			repair.Repaired = true
+		}
+		repairs = append(repairs, repair)
+
+		// Later rules see the corrected value
+		values[rule.variable] = expected
+	}
+
+	failures := m.runNightlyChecks()
+	m.shadowTracker.RecordConsistencyCheck(shadowstate.ConsistencyCheck{
+		CheckedAt: m.clock.Now(),
+		Repairs:   repairs,
+		Failures:  failures,
+	})
+
+	if len(repairs) == 0 && len(failures) == 0 {
+		m.logger.Info("State consistency check passed")
+		return repairs
+	}
+
+	m.logger.Warn("State consistency check found problems",
+		zap.Int("repairs", len(repairs)),
+		zap.Int("failed_checks", len(failures)))
+	m.sendConsistencyAlert(repairs, failures)
+	return repairs
+}
+
+// sendConsistencyAlert reports repairs and failed checks through the
+// configured notify service
+func (m *Manager) sendConsistencyAlert(repairs []shadowstate.ConsistencyRepair, failures []shadowstate.ConsistencyFailure) {
+	if m.consistencyCheck == nil {
+		return
+	}
+	target := m.consistencyCheck.ConsistencyCheck.NotifyService
+	if target == "" {
+		return
+	}
+
+	var parts []string
+	if len(repairs) > 0 {
+		fixes := make([]string, 0, len(repairs))
+		for _, repair := range repairs {
+			fixes = append(fixes, fmt.Sprintf("%s %t → %t", repair.Variable, repair.Found, repair.Expected))
+		}
+		parts = append(parts, fmt.Sprintf("Repaired %d derived state(s) that didn't match their inputs: %s",
+			len(repairs), strings.Join(fixes, ", ")))
+	}
+	for _, failure := range failures {
+		parts = append(parts, fmt.Sprintf("%s: %s", failure.Check, failure.Error))
+	}
+	message := strings.Join(parts, ". ")
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send consistency check notification",
+			zap.String("service", string(target)),
+			zap.String("message", message))
+		return
+	}
+
+	if err := notifyrouter.SendPush(m.ctx, m.haClient, target, notifyrouter.Push{
+		Title:   "State consistency check",
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send consistency check notification", zap.Error(err))
+	}
+}
@@ -0,0 +1,307 @@
+package statetracking
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+func newConsistencyTestConfig() *ConsistencyCheckConfig {
+	return &ConsistencyCheckConfig{
+		ConsistencyCheck: ConsistencyCheckSettings{
+			Time:          "04:00",
+			NotifyService: "notify.notify",
+		},
+	}
+}
+
+// countNotifications returns the number of notify.notify calls made
+func countNotifications(mockHA *ha.MockClient) int {
+	count := 0
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "notify" && call.Service == "notify" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCheckConsistency_RepairsDerivedStates(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	// Nobody home, Master and guest both asleep
+	if err := stateMgr.SetBool("isHaveGuests", true); err != nil {
+		t.Fatalf("Failed to set isHaveGuests: %v", err)
+	}
+	if err := stateMgr.SetBool("isMasterAsleep", true); err != nil {
+		t.Fatalf("Failed to set isMasterAsleep: %v", err)
+	}
+	if err := stateMgr.SetBool("isGuestAsleep", true); err != nil {
+		t.Fatalf("Failed to set isGuestAsleep: %v", err)
+	}
+
+	manager := NewManager(mockHA, stateMgr, logger, false, nil)
+	manager.SetConsistencyCheck(newConsistencyTestConfig(), time.UTC)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+
+	// Simulate derived states left stale by missed subscription events
+	if err := stateMgr.SetBool("isAnyoneHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyoneHome: %v", err)
+	}
+	if err := stateMgr.SetBool("isEveryoneAsleep", false); err != nil {
+		t.Fatalf("Failed to set isEveryoneAsleep: %v", err)
+	}
+	mockHA.ClearServiceCalls()
+
+	repairs := manager.CheckConsistency()
+
+	if len(repairs) != 2 {
+		t.Fatalf("Expected 2 repairs, got %d: %+v", len(repairs), repairs)
+	}
+	if repairs[0].Variable != "isAnyoneHome" || repairs[0].Found != true || repairs[0].Expected != false {
+		t.Errorf("Expected isAnyoneHome repaired from true to false, got %+v", repairs[0])
+	}
+	if repairs[1].Variable != "isEveryoneAsleep" || repairs[1].Expected != true {
+		t.Errorf("Expected isEveryoneAsleep repaired to true, got %+v", repairs[1])
+	}
+	for _, repair := range repairs {
+		if !repair.Repaired {
+			t.Errorf("Expected %s to be repaired", repair.Variable)
+		}
+	}
+
+	if isAnyoneHome, _ := stateMgr.GetBool("isAnyoneHome"); isAnyoneHome {
+		t.Error("Expected isAnyoneHome to be false after repair")
+	}
+	if isEveryoneAsleep, _ := stateMgr.GetBool("isEveryoneAsleep"); !isEveryoneAsleep {
+		t.Error("Expected isEveryoneAsleep to be true after repair")
+	}
+	if count := countNotifications(mockHA); count != 1 {
+		t.Errorf("Expected 1 notification, got %d", count)
+	}
+
+	check := manager.GetShadowState().Outputs.ConsistencyCheck
+	if check == nil || len(check.Repairs) != 2 {
+		t.Fatalf("Expected consistency check with 2 repairs in shadow state, got %+v", check)
+	}
+	if check.Repairs[0].Inputs["isToriHere"] {
+		t.Errorf("Expected recorded input isToriHere=false, got %v", check.Repairs[0].Inputs)
+	}
+}
+
+func TestCheckConsistency_UsesRepairedValuesDownstream(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	manager := NewManager(mockHA, stateMgr, logger, false, nil)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+
+	// Both derived presence states stuck on after everyone left
+	if err := stateMgr.SetBool("isAnyOwnerHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyOwnerHome: %v", err)
+	}
+	if err := stateMgr.SetBool("isAnyoneHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyoneHome: %v", err)
+	}
+
+	repairs := manager.CheckConsistency()
+
+	if len(repairs) != 2 {
+		t.Fatalf("Expected 2 repairs, got %d: %+v", len(repairs), repairs)
+	}
+	if repairs[1].Variable != "isAnyoneHome" || repairs[1].Inputs["isAnyOwnerHome"] {
+		t.Errorf("Expected isAnyoneHome to be checked against the repaired isAnyOwnerHome, got %+v", repairs[1])
+	}
+	if isAnyoneHome, _ := stateMgr.GetBool("isAnyoneHome"); isAnyoneHome {
+		t.Error("Expected isAnyoneHome to be false after repair")
+	}
+}
+
+func TestCheckConsistency_NoRepairsNeeded(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	if err := stateMgr.SetBool("isNickHome", true); err != nil {
+		t.Fatalf("Failed to set isNickHome: %v", err)
+	}
+
+	manager := NewManager(mockHA, stateMgr, logger, false, nil)
+	manager.SetConsistencyCheck(newConsistencyTestConfig(), time.UTC)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+	mockHA.ClearServiceCalls()
+
+	if repairs := manager.CheckConsistency(); len(repairs) != 0 {
+		t.Errorf("Expected no repairs, got %+v", repairs)
+	}
+	if count := countNotifications(mockHA); count != 0 {
+		t.Errorf("Expected no notifications, got %d", count)
+	}
+
+	check := manager.GetShadowState().Outputs.ConsistencyCheck
+	if check == nil || len(check.Repairs) != 0 {
+		t.Errorf("Expected a recorded check without repairs, got %+v", check)
+	}
+}
+
+func TestCheckConsistency_NightlyCheckFailure(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	manager := NewManager(mockHA, stateMgr, logger, false, nil)
+	manager.SetConsistencyCheck(newConsistencyTestConfig(), time.UTC)
+	manager.AddNightlyCheck("passing", func() error { return nil })
+	manager.AddNightlyCheck("sonos_alarm", func() error { return errors.New("Sonos alarm switch.sonos_alarm_1 not found") })
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+	mockHA.ClearServiceCalls()
+
+	if repairs := manager.CheckConsistency(); len(repairs) != 0 {
+		t.Errorf("Expected no repairs, got %+v", repairs)
+	}
+	if count := countNotifications(mockHA); count != 1 {
+		t.Fatalf("Expected 1 notification for the failed check, got %d", count)
+	}
+	message, _ := mockHA.GetServiceCalls()[0].Data["message"].(string)
+	if !strings.Contains(message, "switch.sonos_alarm_1 not found") {
+		t.Errorf("Expected the failure in the alert, got %q", message)
+	}
+
+	check := manager.GetShadowState().Outputs.ConsistencyCheck
+	if check == nil || len(check.Failures) != 1 || check.Failures[0].Check != "sonos_alarm" {
+		t.Errorf("Expected the sonos_alarm failure in shadow state, got %+v", check)
+	}
+}
+
+func TestCheckConsistency_ReadOnlyMode(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	manager := NewManager(mockHA, stateMgr, logger, true, nil)
+	manager.SetConsistencyCheck(newConsistencyTestConfig(), time.UTC)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+
+	if err := stateMgr.SetBool("isAnyoneAsleep", true); err != nil {
+		t.Fatalf("Failed to set isAnyoneAsleep: %v", err)
+	}
+	mockHA.ClearServiceCalls()
+
+	repairs := manager.CheckConsistency()
+
+	if len(repairs) != 1 || repairs[0].Repaired {
+		t.Fatalf("Expected 1 unrepaired inconsistency in read-only mode, got %+v", repairs)
+	}
+	if isAnyoneAsleep, _ := stateMgr.GetBool("isAnyoneAsleep"); !isAnyoneAsleep {
+		t.Error("Expected isAnyoneAsleep to be left unchanged in read-only mode")
+	}
+	if count := countNotifications(mockHA); count != 0 {
+		t.Errorf("Expected no notifications in read-only mode, got %d", count)
+	}
+}
+
+func TestConsistencyCheck_RunsNightly(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+	mockClock := clock.NewMockClock(time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC))
+
+	manager := NewManager(mockHA, stateMgr, logger, false, nil)
+	manager.SetClock(mockClock)
+	manager.SetConsistencyCheck(newConsistencyTestConfig(), time.UTC)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+
+	if err := stateMgr.SetBool("isAnyoneHome", true); err != nil {
+		t.Fatalf("Failed to set isAnyoneHome: %v", err)
+	}
+
+	// 03:59 - not yet
+	mockClock.Advance(4*time.Hour + 59*time.Minute)
+	if manager.GetShadowState().Outputs.ConsistencyCheck != nil {
+		t.Fatal("Expected no consistency check before 04:00")
+	}
+
+	// 04:00 - check runs and repairs
+	mockClock.Advance(time.Minute)
+	check := manager.GetShadowState().Outputs.ConsistencyCheck
+	if check == nil || len(check.Repairs) != 1 {
+		t.Fatalf("Expected consistency check with 1 repair at 04:00, got %+v", check)
+	}
+	if isAnyoneHome, _ := stateMgr.GetBool("isAnyoneHome"); isAnyoneHome {
+		t.Error("Expected isAnyoneHome to be repaired to false")
+	}
+
+	// Next night the check runs again
+	mockClock.Advance(24 * time.Hour)
+	next := manager.GetShadowState().Outputs.ConsistencyCheck
+	if next == nil || !next.CheckedAt.Equal(time.Date(2026, 3, 12, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the check to run again the next night, got %+v", next)
+	}
+}
+
+func TestConsistencyCheckConfig_NextRun(t *testing.T) {
+	config := newConsistencyTestConfig()
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Before check time runs today",
+			now:      time.Date(2026, 3, 10, 1, 0, 0, 0, chicago),
+			expected: time.Date(2026, 3, 10, 4, 0, 0, 0, chicago),
+		},
+		{
+			name:     "At check time runs tomorrow",
+			now:      time.Date(2026, 3, 10, 4, 0, 0, 0, chicago),
+			expected: time.Date(2026, 3, 11, 4, 0, 0, 0, chicago),
+		},
+		{
+			name:     "UTC now is converted to the local timezone",
+			now:      time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 3, 11, 4, 0, 0, 0, chicago),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if next := config.NextRun(tt.now, chicago); !next.Equal(tt.expected) {
+				t.Errorf("Expected next run %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
@@ -1,15 +1,22 @@
 package statetracking
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"homeautomation/internal/clock"
+	"homeautomation/internal/donotdisturb"
+	"homeautomation/internal/entities"
+	"homeautomation/internal/events"
+	"homeautomation/internal/focusmode"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
+	"homeautomation/internal/tts"
 
 	"go.uber.org/zap"
 )
@@ -39,6 +46,7 @@ const (
 //   - Automatic master sleep detection when primary suite lights off for 1 minute
 //   - Automatic master wake detection when bedroom door open for 20 seconds
 //   - Automatic guest sleep detection when guest bedroom door closes
+//   - Nightly consistency check that repairs derived states missed during reconnects
 type Manager struct {
 	haClient     ha.HAClient
 	stateManager *state.Manager
@@ -46,9 +54,18 @@ type Manager struct {
 	readOnly     bool
 	helper       *state.DerivedStateHelper
 	clock        clock.Clock
+	entities     *entities.Registry // nil uses the default entity IDs
+
+	// Speakers in do-not-disturb bedrooms are skipped by announcements, nil if not configured
+	dnd *donotdisturb.Guard
+	// Office speakers are skipped by announcements during focus mode, nil if not configured
+	focus *focusmode.Guard
+	// Queues arrival announcements and picks their speakers, nil speaks immediately on the defaults
+	announcer *tts.Announcer
 
 	// Subscriptions for cleanup
 	haSubscriptions []ha.Subscription
+	subscriptions   []state.Subscription
 
 	// Timers for sleep/wake detection
 	masterSleepTimer clock.Timer
@@ -57,6 +74,12 @@ type Manager struct {
 	// Timer for owner return home auto-reset
 	ownerReturnHomeTimer clock.Timer
 
+	// Nightly derived state consistency check, nil config if not configured
+	consistencyCheck *ConsistencyCheckConfig
+	consistencyTimer clock.Timer
+	nightlyChecks    []nightlyCheck // Run with the consistency check (protected by timerMutex)
+	timezone         *time.Location
+
 	timerMutex sync.Mutex
 
 	// Shadow state tracking
@@ -66,17 +89,32 @@ type Manager struct {
 	pluginName  string
 	registry    *shadowstate.SubscriptionRegistry
 	inputHelper *shadowstate.InputCaptureHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new State Tracking manager
 func NewManager(haClient ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
 	const pluginName = "statetracking"
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
 	m := &Manager{
+		ctx:             ctx,
+		cancel:          cancel,
 		haClient:        haClient,
 		stateManager:    stateManager,
-		logger:          logger.Named("statetracking"),
+		logger:          recorder.Logger(logger.Named("statetracking")),
+		health:          recorder,
 		readOnly:        readOnly,
 		clock:           clock.NewRealClock(),
+		timezone:        time.Local,
 		haSubscriptions: make([]ha.Subscription, 0),
 		shadowTracker:   shadowstate.NewStateTrackingTracker(),
 		pluginName:      pluginName,
@@ -101,21 +139,44 @@ func (m *Manager) SetClock(c clock.Clock) {
 	m.clock = c
 }
 
+// SetEntities sets the registry the primary suite light's entity ID comes from
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
+// SetDoNotDisturb sets the per-bedroom do-not-disturb guard used to skip
+// arrival announcements on speakers in bedrooms with the toggle on
+func (m *Manager) SetDoNotDisturb(guard *donotdisturb.Guard) {
+	m.dnd = guard
+}
+
+// SetFocusMode sets the office focus mode guard used to skip arrival
+// announcements on the office speakers while focus mode is on
+func (m *Manager) SetFocusMode(guard *focusmode.Guard) {
+	m.focus = guard
+}
+
+// SetAnnouncer sets the announcer that speaks arrival announcements
+func (m *Manager) SetAnnouncer(announcer *tts.Announcer) {
+	m.announcer = announcer
+}
+
 // Start begins computing and maintaining derived states.
 // This must be called before other plugins that depend on derived states (Music, Security).
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
 	m.logger.Info("Starting State Tracking Manager")
 
+	// Announcements and helper writes run under ctx until Stop
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	// Register subscriptions with the registry for automatic input tracking
 	if m.registry != nil {
 		// HA subscriptions
-		m.registry.RegisterHASubscription(m.pluginName, "light.primary_suite")
-		m.registry.RegisterHASubscription(m.pluginName, "input_boolean.primary_bedroom_door_open")
-		m.registry.RegisterHASubscription(m.pluginName, "input_boolean.nick_home")
-		m.registry.RegisterHASubscription(m.pluginName, "input_boolean.caroline_home")
-		m.registry.RegisterHASubscription(m.pluginName, "input_boolean.tori_here")
+		m.registry.RegisterHASubscription(m.pluginName, m.entities.PrimarySuiteLight())
 
 		// State variables this plugin reads
+		m.registry.RegisterStateSubscription(m.pluginName, "isPrimaryBedroomDoorOpen")
 		m.registry.RegisterStateSubscription(m.pluginName, "isNickHome")
 		m.registry.RegisterStateSubscription(m.pluginName, "isCarolineHome")
 		m.registry.RegisterStateSubscription(m.pluginName, "isToriHere")
@@ -138,40 +199,45 @@ func (m *Manager) Start() error {
 	}
 
 	// Subscribe to primary suite lights for master sleep detection
-	lightSub, err := m.haClient.SubscribeStateChanges("light.primary_suite", m.handlePrimarySuiteLightsChange)
+	lightSub, err := m.haClient.SubscribeStateChanges(m.entities.PrimarySuiteLight(), m.handlePrimarySuiteLightsChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to light.primary_suite: %w", err)
+		return fmt.Errorf("failed to subscribe to %s: %w", m.entities.PrimarySuiteLight(), err)
 	}
 	m.haSubscriptions = append(m.haSubscriptions, lightSub)
 
+	bus := m.stateManager.Events()
+
 	// Subscribe to primary bedroom door for master wake detection
-	doorSub, err := m.haClient.SubscribeStateChanges("input_boolean.primary_bedroom_door_open", m.handlePrimaryBedroomDoorChange)
+	doorSub, err := bus.OnBool("isPrimaryBedroomDoorOpen", m.handlePrimaryBedroomDoorChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to input_boolean.primary_bedroom_door_open: %w", err)
+		return fmt.Errorf("failed to subscribe to isPrimaryBedroomDoorOpen: %w", err)
 	}
-	m.haSubscriptions = append(m.haSubscriptions, doorSub)
+	m.subscriptions = append(m.subscriptions, doorSub)
 
 	// Subscribe to Nick's presence for arrival announcements
-	nickSub, err := m.haClient.SubscribeStateChanges("input_boolean.nick_home", m.handleNickHomeChange)
+	nickSub, err := bus.OnBool("isNickHome", m.handleNickHomeChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to input_boolean.nick_home: %w", err)
+		return fmt.Errorf("failed to subscribe to isNickHome: %w", err)
 	}
-	m.haSubscriptions = append(m.haSubscriptions, nickSub)
+	m.subscriptions = append(m.subscriptions, nickSub)
 
 	// Subscribe to Caroline's presence for arrival announcements
-	carolineSub, err := m.haClient.SubscribeStateChanges("input_boolean.caroline_home", m.handleCarolineHomeChange)
+	carolineSub, err := bus.OnBool("isCarolineHome", m.handleCarolineHomeChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to input_boolean.caroline_home: %w", err)
+		return fmt.Errorf("failed to subscribe to isCarolineHome: %w", err)
 	}
-	m.haSubscriptions = append(m.haSubscriptions, carolineSub)
+	m.subscriptions = append(m.subscriptions, carolineSub)
 
 	// Subscribe to Tori's presence for arrival announcements
-	toriSub, err := m.haClient.SubscribeStateChanges("input_boolean.tori_here", m.handleToriHereChange)
+	toriSub, err := bus.OnBool("isToriHere", m.handleToriHereChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to input_boolean.tori_here: %w", err)
+		return fmt.Errorf("failed to subscribe to isToriHere: %w", err)
 	}
-	m.haSubscriptions = append(m.haSubscriptions, toriSub)
+	m.subscriptions = append(m.subscriptions, toriSub)
+
+	m.scheduleConsistencyCheck()
 
+	m.health.Started(len(m.haSubscriptions) + len(m.subscriptions))
 	m.logger.Info("State Tracking Manager started successfully",
 		zap.Strings("derivedStates", []string{
 			"isAnyOwnerHome",
@@ -180,13 +246,13 @@ func (m *Manager) Start() error {
 			"isEveryoneAsleep",
 		}),
 		zap.Strings("sleepDetection", []string{
-			"light.primary_suite (1min off → asleep)",
-			"input_boolean.primary_bedroom_door_open (20sec open → awake)",
+			m.entities.PrimarySuiteLight() + " (1min off → asleep)",
+			"isPrimaryBedroomDoorOpen (20sec open → awake)",
 		}),
 		zap.Strings("presenceAnnouncements", []string{
-			"input_boolean.nick_home (arrival → TTS)",
-			"input_boolean.caroline_home (arrival → TTS)",
-			"input_boolean.tori_here (arrival → TTS)",
+			"isNickHome (arrival → TTS)",
+			"isCarolineHome (arrival → TTS)",
+			"isToriHere (arrival → TTS)",
 		}),
 		zap.Strings("ownerReturnHome", []string{
 			"isNickHome/isCarolineHome (arrival → didOwnerJustReturnHome=true, 10min auto-reset)",
@@ -196,8 +262,11 @@ func (m *Manager) Start() error {
 
 // Stop stops the State Tracking Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping State Tracking Manager")
 
+	m.cancel()
+
 	// Stop any active timers
 	m.timerMutex.Lock()
 	if m.masterSleepTimer != nil {
@@ -212,13 +281,21 @@ func (m *Manager) Stop() {
 		m.ownerReturnHomeTimer.Stop()
 		m.ownerReturnHomeTimer = nil
 	}
+	if m.consistencyTimer != nil {
+		m.consistencyTimer.Stop()
+		m.consistencyTimer = nil
+	}
 	m.timerMutex.Unlock()
 
-	// Unsubscribe from all HA subscriptions
+	// Unsubscribe from all HA and state subscriptions
 	for _, sub := range m.haSubscriptions {
 		sub.Unsubscribe()
 	}
 	m.haSubscriptions = nil
+	for _, sub := range m.subscriptions {
+		sub.Unsubscribe()
+	}
+	m.subscriptions = nil
 
 	if m.helper != nil {
 		m.helper.Stop()
@@ -226,6 +303,11 @@ func (m *Manager) Stop() {
 	m.logger.Info("State Tracking Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // handlePrimarySuiteLightsChange processes primary suite lights state changes
 func (m *Manager) handlePrimarySuiteLightsChange(entityID string, oldState, newState *ha.State) {
 	if newState == nil {
@@ -306,19 +388,14 @@ func (m *Manager) detectMasterAsleep() {
 }
 
 // handlePrimaryBedroomDoorChange processes primary bedroom door state changes
-func (m *Manager) handlePrimaryBedroomDoorChange(entityID string, oldState, newState *ha.State) {
-	if newState == nil {
-		return
-	}
-
+func (m *Manager) handlePrimaryBedroomDoorChange(e events.BoolChanged) {
 	// Update shadow state inputs
 	m.updateShadowInputs()
 
-	doorOpen := newState.State == "on"
+	doorOpen := e.New
 
 	m.logger.Debug("Primary bedroom door changed",
-		zap.String("entity_id", entityID),
-		zap.String("new_state", newState.State),
+		zap.String("key", e.Key),
 		zap.Bool("door_open", doorOpen))
 
 	m.timerMutex.Lock()
@@ -359,20 +436,19 @@ func (m *Manager) detectMasterAwake() {
 }
 
 // handleNickHomeChange processes Nick's presence state changes for TTS announcements
-func (m *Manager) handleNickHomeChange(entityID string, oldState, newState *ha.State) {
-	if newState == nil || oldState == nil {
+func (m *Manager) handleNickHomeChange(e events.BoolChanged) {
+	// Skip the first value seen, which is not an arrival
+	if e.Initial {
 		return
 	}
 
 	// Update shadow state inputs
 	m.updateShadowInputs()
 
-	// Check if Nick just arrived (state changed to "on" from something else)
-	if newState.State == "on" && oldState.State != "on" {
+	// Check if Nick just arrived (false → true)
+	if e.New && !e.Old {
 		m.logger.Debug("Nick arrived home, checking if should announce",
-			zap.String("entity_id", entityID),
-			zap.String("old_state", oldState.State),
-			zap.String("new_state", newState.State))
+			zap.String("key", e.Key))
 
 		// Set didOwnerJustReturnHome for garage automation
 		m.setOwnerJustReturnedHome()
@@ -389,36 +465,30 @@ func (m *Manager) handleNickHomeChange(entityID string, oldState, newState *ha.S
 
 		if wasAnyoneHome {
 			// Run announcement asynchronously to avoid deadlocks
-			go m.announceArrivalDirect("Nick", "Nick is home", []string{
-				"media_player.kitchen",
-				"media_player.dining_room",
-				"media_player.soundbar",
-				"media_player.kids_bathroom",
-			})
+			go m.announceArrivalDirect("Nick", "Nick is home", tts.TargetArrivalNick)
 		} else {
 			m.logger.Debug("Nobody else was home, not announcing Nick's arrival")
 		}
-	} else if newState.State != "on" && oldState.State == "on" {
+	} else if !e.New && e.Old {
 		// Nick left home - clear didOwnerJustReturnHome
 		m.clearOwnerJustReturnedHome()
 	}
 }
 
 // handleCarolineHomeChange processes Caroline's presence state changes for TTS announcements
-func (m *Manager) handleCarolineHomeChange(entityID string, oldState, newState *ha.State) {
-	if newState == nil || oldState == nil {
+func (m *Manager) handleCarolineHomeChange(e events.BoolChanged) {
+	// Skip the first value seen, which is not an arrival
+	if e.Initial {
 		return
 	}
 
 	// Update shadow state inputs
 	m.updateShadowInputs()
 
-	// Check if Caroline just arrived (state changed to "on" from something else)
-	if newState.State == "on" && oldState.State != "on" {
+	// Check if Caroline just arrived (false → true)
+	if e.New && !e.Old {
 		m.logger.Debug("Caroline arrived home, checking if should announce",
-			zap.String("entity_id", entityID),
-			zap.String("old_state", oldState.State),
-			zap.String("new_state", newState.State))
+			zap.String("key", e.Key))
 
 		// Set didOwnerJustReturnHome for garage automation
 		m.setOwnerJustReturnedHome()
@@ -434,37 +504,30 @@ func (m *Manager) handleCarolineHomeChange(entityID string, oldState, newState *
 
 		if wasAnyoneHome {
 			// Run announcement asynchronously to avoid deadlocks
-			go m.announceArrivalDirect("Caroline", "Caroline is home", []string{
-				"media_player.kitchen",
-				"media_player.dining_room",
-				"media_player.kids_bathroom",
-				"media_player.soundbar",
-				"media_player.office",
-			})
+			go m.announceArrivalDirect("Caroline", "Caroline is home", tts.TargetArrivalCaroline)
 		} else {
 			m.logger.Debug("Nobody else was home, not announcing Caroline's arrival")
 		}
-	} else if newState.State != "on" && oldState.State == "on" {
+	} else if !e.New && e.Old {
 		// Caroline left home - clear didOwnerJustReturnHome
 		m.clearOwnerJustReturnedHome()
 	}
 }
 
 // handleToriHereChange processes Tori's presence state changes for TTS announcements
-func (m *Manager) handleToriHereChange(entityID string, oldState, newState *ha.State) {
-	if newState == nil || oldState == nil {
+func (m *Manager) handleToriHereChange(e events.BoolChanged) {
+	// Skip the first value seen, which is not an arrival
+	if e.Initial {
 		return
 	}
 
 	// Update shadow state inputs
 	m.updateShadowInputs()
 
-	// Check if Tori just arrived (state changed to "on" from something else)
-	if newState.State == "on" && oldState.State != "on" {
+	// Check if Tori just arrived (false → true)
+	if e.New && !e.Old {
 		m.logger.Debug("Tori arrived, checking if should announce",
-			zap.String("entity_id", entityID),
-			zap.String("old_state", oldState.State),
-			zap.String("new_state", newState.State))
+			zap.String("key", e.Key))
 
 		// Check if anyone else was already home (Nick or Caroline)
 		wasAnyoneHome := false
@@ -477,13 +540,7 @@ func (m *Manager) handleToriHereChange(entityID string, oldState, newState *ha.S
 
 		if wasAnyoneHome {
 			// Run announcement asynchronously to avoid deadlocks
-			go m.announceArrivalDirect("Tori", "Tori is here", []string{
-				"media_player.kitchen",
-				"media_player.dining_room",
-				"media_player.kids_bathroom",
-				"media_player.soundbar",
-				"media_player.office",
-			})
+			go m.announceArrivalDirect("Tori", "Tori is here", tts.TargetArrivalTori)
 		} else {
 			m.logger.Debug("Nobody else was home, not announcing Tori's arrival")
 		}
@@ -491,7 +548,31 @@ func (m *Manager) handleToriHereChange(entityID string, oldState, newState *ha.S
 }
 
 // announceArrivalDirect makes a TTS announcement (caller has already checked if someone is home)
-func (m *Manager) announceArrivalDirect(person, message string, mediaPlayers []string) {
+func (m *Manager) announceArrivalDirect(person, message, target string) {
+	mediaPlayers, err := m.announcer.Speakers(m.haClient, target)
+	if err != nil {
+		m.logger.Error("Failed to resolve arrival announcement speakers",
+			zap.String("person", person),
+			zap.Error(err))
+		return
+	}
+
+	mediaPlayers, suppressed := m.dnd.Filter(mediaPlayers)
+	if len(suppressed) > 0 {
+		m.logger.Info("Skipping arrival announcement on do-not-disturb speakers",
+			zap.String("person", person),
+			zap.Strings("suppressed", suppressed))
+	}
+	mediaPlayers, suppressed = m.focus.Filter(mediaPlayers)
+	if len(suppressed) > 0 {
+		m.logger.Info("Skipping arrival announcement on focus mode speakers",
+			zap.String("person", person),
+			zap.Strings("suppressed", suppressed))
+	}
+	if len(mediaPlayers) == 0 {
+		return
+	}
+
 	// Skip TTS in read-only mode
 	if m.readOnly {
 		m.logger.Info("Would announce arrival (read-only mode)",
@@ -509,17 +590,27 @@ func (m *Manager) announceArrivalDirect(person, message string, mediaPlayers []s
 		zap.String("message", message),
 		zap.Strings("media_players", mediaPlayers))
 
-	err := m.haClient.CallService("tts", "speak", map[string]interface{}{
-		"entity_id":              "tts.google_translate_en_com",
-		"message":                message,
-		"cache":                  true,
-		"media_player_entity_id": mediaPlayers,
+	err = m.announcer.Announce(m.ctx, m.haClient, tts.Announcement{
+		Title:    "Arrival",
+		Message:  message,
+		Speakers: mediaPlayers,
+		Priority: tts.PriorityNormal,
 	})
 
-	if err != nil {
+	if errors.Is(err, tts.ErrQuietHours) {
+		m.logger.Info("Skipping arrival announcement during quiet hours", zap.String("person", person))
+		return
+	} else if err != nil {
 		m.logger.Error("Failed to announce arrival via TTS",
 			zap.String("person", person),
 			zap.Error(err))
+	} else {
+		m.stateManager.Events().PublishPluginAction(events.PluginAction{
+			Plugin: m.pluginName,
+			Action: "announce",
+			Reason: message,
+			Time:   m.clock.Now(),
+		})
 	}
 
 	// Record in shadow state
@@ -609,6 +700,7 @@ func (m *Manager) Reset() error {
 
 // updateShadowInputs captures the current input values used for state tracking
 func (m *Manager) updateShadowInputs() {
+	m.health.Tick()
 	// Use automatic input capture if available
 	if m.inputHelper != nil {
 		inputs := m.inputHelper.CaptureInputs(m.pluginName)
@@ -1,15 +1,28 @@
 package statetracking
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/events"
 	"homeautomation/internal/ha"
 	"homeautomation/internal/state"
 
 	"go.uber.org/zap"
 )
 
+// newEntityGroupsClient wraps the mock so announcements to the production entity groups expand
+func newEntityGroupsClient(t *testing.T, mockHA *ha.MockClient) *entitygroups.Client {
+	t.Helper()
+	groups, err := entitygroups.LoadConfig("../../../../configs/entity_groups_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load entity groups: %v", err)
+	}
+	return entitygroups.NewClient(mockHA, groups)
+}
+
 func TestStateTrackingManager_IsAnyOwnerHome(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -65,7 +78,7 @@ func TestStateTrackingManager_IsAnyOwnerHome(t *testing.T) {
 
 			// Create and start manager
 			manager := NewManager(mockHA, stateMgr, logger, false, nil)
-			if err := manager.Start(); err != nil {
+			if err := manager.Start(context.Background()); err != nil {
 				t.Fatalf("Failed to start manager: %v", err)
 			}
 			defer manager.Stop()
@@ -163,7 +176,7 @@ func TestStateTrackingManager_IsAnyoneHome(t *testing.T) {
 
 			// Create and start manager
 			manager := NewManager(mockHA, stateMgr, logger, false, nil)
-			if err := manager.Start(); err != nil {
+			if err := manager.Start(context.Background()); err != nil {
 				t.Fatalf("Failed to start manager: %v", err)
 			}
 			defer manager.Stop()
@@ -242,7 +255,7 @@ func TestStateTrackingManager_IsAnyoneAsleep(t *testing.T) {
 
 			// Create and start manager
 			manager := NewManager(mockHA, stateMgr, logger, false, nil)
-			if err := manager.Start(); err != nil {
+			if err := manager.Start(context.Background()); err != nil {
 				t.Fatalf("Failed to start manager: %v", err)
 			}
 			defer manager.Stop()
@@ -321,7 +334,7 @@ func TestStateTrackingManager_IsEveryoneAsleep(t *testing.T) {
 
 			// Create and start manager
 			manager := NewManager(mockHA, stateMgr, logger, false, nil)
-			if err := manager.Start(); err != nil {
+			if err := manager.Start(context.Background()); err != nil {
 				t.Fatalf("Failed to start manager: %v", err)
 			}
 			defer manager.Stop()
@@ -359,7 +372,7 @@ func TestStateTrackingManager_DynamicUpdates(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -427,7 +440,7 @@ func TestStateTrackingManager_SleepDynamicUpdates(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -495,7 +508,7 @@ func TestStateTrackingManager_StopCleansUpSubscriptions(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 
@@ -538,7 +551,7 @@ func TestStateTrackingManager_GuestAsleepAutoSync_NoGuests(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -595,7 +608,7 @@ func TestStateTrackingManager_GuestAsleepAutoSync_WithGuests(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -642,7 +655,7 @@ func TestStateTrackingManager_GuestAsleepAutoSync_GuestsLeave(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -690,7 +703,7 @@ func TestStateTrackingManager_GuestAsleepAutoSync_InitialSync(t *testing.T) {
 
 	// Create and start manager - should auto-sync immediately
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -724,7 +737,7 @@ func TestStateTrackingManager_Reset(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -762,8 +775,8 @@ func TestStateTrackingManager_NickArrivalAnnouncement_SomeoneHome(t *testing.T)
 	}
 
 	// Create and start manager
-	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	manager := NewManager(newEntityGroupsClient(t, mockHA), stateMgr, logger, false, nil)
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -861,7 +874,7 @@ func TestStateTrackingManager_NickArrivalAnnouncement_NobodyHome(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -900,8 +913,8 @@ func TestStateTrackingManager_CarolineArrivalAnnouncement(t *testing.T) {
 	}
 
 	// Create and start manager
-	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	manager := NewManager(newEntityGroupsClient(t, mockHA), stateMgr, logger, false, nil)
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -969,7 +982,7 @@ func TestStateTrackingManager_ToriArrivalAnnouncement(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -1019,7 +1032,7 @@ func TestStateTrackingManager_ArrivalAnnouncement_ReadOnlyMode(t *testing.T) {
 
 	// Create manager in READ-ONLY mode
 	manager := NewManager(mockHA, stateMgr, logger, true, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -1056,7 +1069,7 @@ func TestStateTrackingManager_NoAnnouncement_OnStateChangeFromUnknown(t *testing
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -1079,3 +1092,38 @@ func TestStateTrackingManager_NoAnnouncement_OnStateChangeFromUnknown(t *testing
 		}
 	}
 }
+
+func TestStateTrackingManager_ArrivalAnnouncementPublishesPluginAction(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	if err := stateMgr.SetBool("isCarolineHome", true); err != nil {
+		t.Fatalf("Failed to set isCarolineHome: %v", err)
+	}
+	if err := stateMgr.SetBool("isNickHome", false); err != nil {
+		t.Fatalf("Failed to set isNickHome: %v", err)
+	}
+
+	actions := make(chan events.PluginAction, 1)
+	if _, err := stateMgr.Events().OnPluginAction("statetracking", func(e events.PluginAction) { actions <- e }); err != nil {
+		t.Fatalf("Failed to subscribe to plugin actions: %v", err)
+	}
+
+	manager := NewManager(newEntityGroupsClient(t, mockHA), stateMgr, logger, false, nil)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+
+	mockHA.SetState("input_boolean.nick_home", "on", nil)
+
+	select {
+	case action := <-actions:
+		if action.Action != "announce" || action.Reason != "Nick is home" {
+			t.Errorf("Expected announce action for Nick, got %+v", action)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a plugin action for the arrival announcement")
+	}
+}
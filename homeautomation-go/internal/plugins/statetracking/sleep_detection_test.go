@@ -1,8 +1,10 @@
 package statetracking
 
 import (
+	"context"
 	"testing"
 
+	"homeautomation/internal/events"
 	"homeautomation/internal/ha"
 	"homeautomation/internal/state"
 
@@ -25,14 +27,18 @@ func TestSleepDetection_HandlersInitialized(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
 
-	// Verify HA subscriptions were created (2 for sleep detection + 3 for arrival announcements)
-	if len(manager.haSubscriptions) != 5 {
-		t.Errorf("Expected 5 HA subscriptions, got %d", len(manager.haSubscriptions))
+	// Verify the lights HA subscription and the event subscriptions were created
+	// (bedroom door for wake detection + 3 for arrival announcements)
+	if len(manager.haSubscriptions) != 1 {
+		t.Errorf("Expected 1 HA subscription, got %d", len(manager.haSubscriptions))
+	}
+	if len(manager.subscriptions) != 4 {
+		t.Errorf("Expected 4 event subscriptions, got %d", len(manager.subscriptions))
 	}
 }
 
@@ -52,7 +58,7 @@ func TestSleepDetection_LightsOffStartsTimer(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -95,7 +101,7 @@ func TestSleepDetection_LightsOnCancelsTimer(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -140,7 +146,7 @@ func TestWakeDetection_DoorOpenStartsTimer(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -153,11 +159,7 @@ func TestWakeDetection_DoorOpenStartsTimer(t *testing.T) {
 	manager.timerMutex.Unlock()
 
 	// Simulate primary bedroom door opening
-	doorState := &ha.State{
-		EntityID: "input_boolean.primary_bedroom_door_open",
-		State:    "on",
-	}
-	manager.handlePrimaryBedroomDoorChange("input_boolean.primary_bedroom_door_open", nil, doorState)
+	manager.handlePrimaryBedroomDoorChange(events.BoolChanged{Key: "isPrimaryBedroomDoorOpen", New: true, Initial: true})
 
 	// Verify timer was started
 	manager.timerMutex.Lock()
@@ -180,17 +182,13 @@ func TestWakeDetection_DoorClosedCancelsTimer(t *testing.T) {
 
 	// Create and start manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
 
 	// Simulate door opening (starts timer)
-	doorOpenState := &ha.State{
-		EntityID: "input_boolean.primary_bedroom_door_open",
-		State:    "on",
-	}
-	manager.handlePrimaryBedroomDoorChange("input_boolean.primary_bedroom_door_open", nil, doorOpenState)
+	manager.handlePrimaryBedroomDoorChange(events.BoolChanged{Key: "isPrimaryBedroomDoorOpen", New: true, Initial: true})
 
 	// Verify timer exists
 	manager.timerMutex.Lock()
@@ -201,11 +199,7 @@ func TestWakeDetection_DoorClosedCancelsTimer(t *testing.T) {
 	}
 
 	// Close door (should cancel timer)
-	doorClosedState := &ha.State{
-		EntityID: "input_boolean.primary_bedroom_door_open",
-		State:    "off",
-	}
-	manager.handlePrimaryBedroomDoorChange("input_boolean.primary_bedroom_door_open", doorOpenState, doorClosedState)
+	manager.handlePrimaryBedroomDoorChange(events.BoolChanged{Key: "isPrimaryBedroomDoorOpen", Old: true, New: false})
 
 	// Code path exercised - timer should be stopped
 }
@@ -226,7 +220,7 @@ func TestDetectMasterAsleep_SkipsIfNobodyHome(t *testing.T) {
 
 	// Create manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -260,7 +254,7 @@ func TestDetectMasterAsleep_SkipsIfAlreadyAsleep(t *testing.T) {
 
 	// Create manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -294,7 +288,7 @@ func TestDetectMasterAsleep_SetsSleepWhenConditionsMet(t *testing.T) {
 
 	// Create manager in read-write mode (not read-only)
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
@@ -328,7 +322,7 @@ func TestDetectMasterAwake_SetsAwake(t *testing.T) {
 
 	// Create manager
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start manager: %v", err)
 	}
 	defer manager.Stop()
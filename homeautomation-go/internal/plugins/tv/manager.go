@@ -1,11 +1,14 @@
 package tv
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 
+	"homeautomation/internal/entities"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
 	"homeautomation/internal/shadowstate"
 	"homeautomation/internal/state"
 
@@ -18,6 +21,7 @@ type Manager struct {
 	stateManager *state.Manager
 	logger       *zap.Logger
 	readOnly     bool
+	entities     *entities.Registry // Apple TV and sync box entities, nil uses the defaults
 
 	// Subscriptions for cleanup
 	haSubscriptions    []ha.Subscription
@@ -30,15 +34,29 @@ type Manager struct {
 	pluginName  string
 	registry    *shadowstate.SubscriptionRegistry
 	inputHelper *shadowstate.InputCaptureHelper
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
 }
 
 // NewManager creates a new TV manager
 func NewManager(haClient ha.HAClient, stateManager *state.Manager, logger *zap.Logger, readOnly bool, registry *shadowstate.SubscriptionRegistry) *Manager {
 	const pluginName = "tv"
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(0)
+
 	m := &Manager{
+		ctx:                ctx,
+		cancel:             cancel,
 		haClient:           haClient,
 		stateManager:       stateManager,
-		logger:             logger.Named("tv"),
+		logger:             recorder.Logger(logger.Named("tv")),
+		health:             recorder,
 		readOnly:           readOnly,
 		haSubscriptions:    make([]ha.Subscription, 0),
 		stateSubscriptions: make([]state.Subscription, 0),
@@ -60,16 +78,26 @@ func (m *Manager) GetShadowState() *shadowstate.TVShadowState {
 	return m.shadowTracker.GetState()
 }
 
+// SetEntities sets the registry the Apple TV and sync box entities are
+// looked up in. It must be called before Start.
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
 // Start begins monitoring TV-related entities
-func (m *Manager) Start() error {
+func (m *Manager) Start(ctx context.Context) error {
+	// Run under ctx until Stop cancels it
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
 	m.logger.Info("Starting TV Manager")
 
 	// Register subscriptions with the registry for automatic input tracking
 	if m.registry != nil {
 		// HA subscriptions
-		m.registry.RegisterHASubscription(m.pluginName, "media_player.big_beautiful_oled")
-		m.registry.RegisterHASubscription(m.pluginName, "switch.sync_box_power")
-		m.registry.RegisterHASubscription(m.pluginName, "select.sync_box_hdmi_input")
+		m.registry.RegisterHASubscription(m.pluginName, m.entities.AppleTV())
+		m.registry.RegisterHASubscription(m.pluginName, m.entities.SyncBoxPower())
+		m.registry.RegisterHASubscription(m.pluginName, m.entities.SyncBoxInput())
 
 		// State subscriptions
 		m.registry.RegisterStateSubscription(m.pluginName, "isAppleTVPlaying")
@@ -77,23 +105,23 @@ func (m *Manager) Start() error {
 	}
 
 	// Subscribe to Apple TV media player state changes
-	appleTVSub, err := m.haClient.SubscribeStateChanges("media_player.big_beautiful_oled", m.handleAppleTVStateChange)
+	appleTVSub, err := m.haClient.SubscribeStateChanges(m.entities.AppleTV(), m.handleAppleTVStateChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to media_player.big_beautiful_oled: %w", err)
+		return fmt.Errorf("failed to subscribe to %s: %w", m.entities.AppleTV(), err)
 	}
 	m.haSubscriptions = append(m.haSubscriptions, appleTVSub)
 
 	// Subscribe to sync box power state changes
-	syncBoxSub, err := m.haClient.SubscribeStateChanges("switch.sync_box_power", m.handleSyncBoxPowerChange)
+	syncBoxSub, err := m.haClient.SubscribeStateChanges(m.entities.SyncBoxPower(), m.handleSyncBoxPowerChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to switch.sync_box_power: %w", err)
+		return fmt.Errorf("failed to subscribe to %s: %w", m.entities.SyncBoxPower(), err)
 	}
 	m.haSubscriptions = append(m.haSubscriptions, syncBoxSub)
 
 	// Subscribe to HDMI input selector changes
-	hdmiInputSub, err := m.haClient.SubscribeStateChanges("select.sync_box_hdmi_input", m.handleHDMIInputChange)
+	hdmiInputSub, err := m.haClient.SubscribeStateChanges(m.entities.SyncBoxInput(), m.handleHDMIInputChange)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to select.sync_box_hdmi_input: %w", err)
+		return fmt.Errorf("failed to subscribe to %s: %w", m.entities.SyncBoxInput(), err)
 	}
 	m.haSubscriptions = append(m.haSubscriptions, hdmiInputSub)
 
@@ -110,14 +138,18 @@ func (m *Manager) Start() error {
 		m.logger.Warn("Failed to initialize some TV states", zap.Error(err))
 	}
 
+	m.health.Started(len(m.haSubscriptions) + len(m.stateSubscriptions))
 	m.logger.Info("TV Manager started successfully")
 	return nil
 }
 
 // Stop stops the TV Manager and cleans up subscriptions
 func (m *Manager) Stop() {
+	m.health.Stopped()
 	m.logger.Info("Stopping TV Manager")
 
+	m.cancel()
+
 	// Unsubscribe from all HA subscriptions
 	for _, sub := range m.haSubscriptions {
 		sub.Unsubscribe()
@@ -133,28 +165,33 @@ func (m *Manager) Stop() {
 	m.logger.Info("TV Manager stopped")
 }
 
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
 // initializeStates fetches current HA entity states and initializes state variables
 func (m *Manager) initializeStates() error {
 	// Get Apple TV state
-	appleTVState, err := m.haClient.GetState("media_player.big_beautiful_oled")
+	appleTVState, err := m.haClient.GetState(m.ctx, m.entities.AppleTV())
 	if err == nil && appleTVState != nil {
-		m.handleAppleTVStateChange("media_player.big_beautiful_oled", nil, appleTVState)
+		m.handleAppleTVStateChange(m.entities.AppleTV(), nil, appleTVState)
 	} else if err != nil {
 		m.logger.Warn("Failed to get initial Apple TV state", zap.Error(err))
 	}
 
 	// Get sync box power state
-	syncBoxState, err := m.haClient.GetState("switch.sync_box_power")
+	syncBoxState, err := m.haClient.GetState(m.ctx, m.entities.SyncBoxPower())
 	if err == nil && syncBoxState != nil {
-		m.handleSyncBoxPowerChange("switch.sync_box_power", nil, syncBoxState)
+		m.handleSyncBoxPowerChange(m.entities.SyncBoxPower(), nil, syncBoxState)
 	} else if err != nil {
 		m.logger.Warn("Failed to get initial sync box state", zap.Error(err))
 	}
 
 	// Get HDMI input state
-	hdmiInputState, err := m.haClient.GetState("select.sync_box_hdmi_input")
+	hdmiInputState, err := m.haClient.GetState(m.ctx, m.entities.SyncBoxInput())
 	if err == nil && hdmiInputState != nil {
-		m.handleHDMIInputChange("select.sync_box_hdmi_input", nil, hdmiInputState)
+		m.handleHDMIInputChange(m.entities.SyncBoxInput(), nil, hdmiInputState)
 	} else if err != nil {
 		m.logger.Warn("Failed to get initial HDMI input state", zap.Error(err))
 	}
@@ -268,7 +305,7 @@ func (m *Manager) handleAppleTVPlayingChange(key string, oldValue, newValue inte
 		zap.Any("new", newValue))
 
 	// Get current HDMI input to recalculate isTVPlaying
-	hdmiInputState, err := m.haClient.GetState("select.sync_box_hdmi_input")
+	hdmiInputState, err := m.haClient.GetState(m.ctx, m.entities.SyncBoxInput())
 	if err != nil {
 		m.logger.Warn("Failed to get HDMI input state", zap.Error(err))
 		return
@@ -281,6 +318,7 @@ func (m *Manager) handleAppleTVPlayingChange(key string, oldValue, newValue inte
 
 // updateShadowInputs captures the current input values from HA entities
 func (m *Manager) updateShadowInputs() {
+	m.health.Tick()
 	// Use automatic input capture if available
 	if m.inputHelper != nil {
 		inputs := m.inputHelper.CaptureInputs(m.pluginName)
@@ -292,14 +330,14 @@ func (m *Manager) updateShadowInputs() {
 	inputs := make(map[string]interface{})
 
 	// Capture raw HA entity states
-	if state, err := m.haClient.GetState("media_player.big_beautiful_oled"); err == nil && state != nil {
-		inputs["media_player.big_beautiful_oled"] = state.State
+	if state, err := m.haClient.GetState(m.ctx, m.entities.AppleTV()); err == nil && state != nil {
+		inputs[m.entities.AppleTV()] = state.State
 	}
-	if state, err := m.haClient.GetState("switch.sync_box_power"); err == nil && state != nil {
-		inputs["switch.sync_box_power"] = state.State
+	if state, err := m.haClient.GetState(m.ctx, m.entities.SyncBoxPower()); err == nil && state != nil {
+		inputs[m.entities.SyncBoxPower()] = state.State
 	}
-	if state, err := m.haClient.GetState("select.sync_box_hdmi_input"); err == nil && state != nil {
-		inputs["select.sync_box_hdmi_input"] = state.State
+	if state, err := m.haClient.GetState(m.ctx, m.entities.SyncBoxInput()); err == nil && state != nil {
+		inputs[m.entities.SyncBoxInput()] = state.State
 	}
 
 	// Also capture derived state variables
@@ -1,9 +1,11 @@
 package tv
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"homeautomation/internal/entities"
 	"homeautomation/internal/ha"
 	"homeautomation/internal/state"
 
@@ -330,7 +332,7 @@ func TestTVManager_Start_InitializesStates(t *testing.T) {
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
 
 	// Start the manager
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start TV manager: %v", err)
 	}
 
@@ -366,6 +368,44 @@ func TestTVManager_Start_InitializesStates(t *testing.T) {
 	manager.Stop()
 }
 
+func TestTVManager_Start_UsesEntityRegistry(t *testing.T) {
+	mockHA := ha.NewMockClient()
+	logger := zap.NewNop()
+	stateMgr := state.NewManager(mockHA, logger, false)
+
+	// The Apple TV was renamed; the old entity still reports playing
+	mockHA.SetState("media_player.big_beautiful_oled", "playing", nil)
+	mockHA.SetState("media_player.living_room_apple_tv", "paused", nil)
+
+	manager := NewManager(mockHA, stateMgr, logger, false, nil)
+	manager.SetEntities(entities.NewRegistry(&entities.Config{Entities: map[entities.Name]string{
+		entities.AppleTV: "media_player.living_room_apple_tv",
+	}}))
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start TV manager: %v", err)
+	}
+	defer manager.Stop()
+
+	isAppleTVPlaying, err := stateMgr.GetBool("isAppleTVPlaying")
+	if err != nil {
+		t.Fatalf("Failed to get isAppleTVPlaying: %v", err)
+	}
+	if isAppleTVPlaying {
+		t.Errorf("Expected isAppleTVPlaying=false from the renamed Apple TV, got true")
+	}
+
+	mockHA.SimulateStateChange("media_player.living_room_apple_tv", "playing")
+	time.Sleep(50 * time.Millisecond)
+
+	isAppleTVPlaying, err = stateMgr.GetBool("isAppleTVPlaying")
+	if err != nil {
+		t.Fatalf("Failed to get isAppleTVPlaying: %v", err)
+	}
+	if !isAppleTVPlaying {
+		t.Errorf("Expected isAppleTVPlaying=true after the renamed Apple TV started playing, got false")
+	}
+}
+
 func TestTVManager_Stop_CleansUpSubscriptions(t *testing.T) {
 	// Create mock HA client and state manager
 	mockHA := ha.NewMockClient()
@@ -376,7 +416,7 @@ func TestTVManager_Stop_CleansUpSubscriptions(t *testing.T) {
 	manager := NewManager(mockHA, stateMgr, logger, false, nil)
 
 	// Start the manager
-	if err := manager.Start(); err != nil {
+	if err := manager.Start(context.Background()); err != nil {
 		t.Fatalf("Failed to start TV manager: %v", err)
 	}
 
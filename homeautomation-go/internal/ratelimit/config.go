@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/warmup"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnyDomain is the domains key whose limit applies to domains not listed
+const AnyDomain = "*"
+
+// Limit is a token bucket: calls refill at PerSecond, and up to Burst can be
+// made at once
+type Limit struct {
+	PerSecond float64 `yaml:"per_second"`
+	Burst     int     `yaml:"burst"`
+}
+
+// Limits sets a plugin's rate limits and repeat suppression
+type Limits struct {
+	Domains             map[string]Limit `yaml:"domains"`               // Per service domain, or AnyDomain
+	RepeatWindowSeconds *float64         `yaml:"repeat_window_seconds"` // Identical calls within it are dropped; 0 turns it off
+}
+
+// Settings sets the limits for every plugin and per-plugin overrides
+type Settings struct {
+	Default Limits            `yaml:"default"` // Limits for every plugin
+	Plugins map[string]Limits `yaml:"plugins"` // Per-plugin overrides
+}
+
+// Config represents the rate_limits_config.yaml structure
+type Config struct {
+	RateLimits Settings `yaml:"rate_limits"`
+}
+
+// Limit returns a plugin's limit for a domain: the plugin's own domain or
+// AnyDomain entry, else the default's. It returns false if none is set.
+func (s Settings) Limit(plugin, domain string) (Limit, bool) {
+	for _, limits := range []Limits{s.Plugins[plugin], s.Default} {
+		if limit, ok := limits.Domains[domain]; ok {
+			return limit, true
+		}
+		if limit, ok := limits.Domains[AnyDomain]; ok {
+			return limit, true
+		}
+	}
+	return Limit{}, false
+}
+
+// RepeatWindow returns how long a plugin's identical calls are suppressed
+func (s Settings) RepeatWindow(plugin string) time.Duration {
+	seconds := s.Default.RepeatWindowSeconds
+	if override := s.Plugins[plugin].RepeatWindowSeconds; override != nil {
+		seconds = override
+	}
+	if seconds == nil {
+		return 0
+	}
+	return time.Duration(*seconds * float64(time.Second))
+}
+
+// Validate checks limits, domains and plugin names
+func (c *Config) Validate() error {
+	if err := c.RateLimits.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+
+	known := make(map[string]bool, len(warmup.Plugins))
+	for _, name := range warmup.Plugins {
+		known[name] = true
+	}
+	for name, limits := range c.RateLimits.Plugins {
+		if !known[name] {
+			return fmt.Errorf("plugins: unknown plugin %q", name)
+		}
+		if err := limits.validate(); err != nil {
+			return fmt.Errorf("plugins: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (l Limits) validate() error {
+	for domain, limit := range l.Domains {
+		if domain == "" || (domain != AnyDomain && strings.Contains(domain, ".")) {
+			return fmt.Errorf("domains: %q is not a domain", domain)
+		}
+		if limit.PerSecond <= 0 {
+			return fmt.Errorf("domains: %s: per_second must be positive", domain)
+		}
+		if limit.Burst < 1 {
+			return fmt.Errorf("domains: %s: burst must be at least 1", domain)
+		}
+	}
+	if l.RepeatWindowSeconds != nil && *l.RepeatWindowSeconds < 0 {
+		return fmt.Errorf("repeat_window_seconds must not be negative")
+	}
+	return nil
+}
+
+// LoadConfig loads the rate limit configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("../../../configs/rate_limits_config.yaml")
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.RateLimits.Default.Domains)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "unknown plugin",
+			yaml:    "rate_limits:\n  plugins:\n    fader: {repeat_window_seconds: 1}\n",
+			wantErr: `plugins: unknown plugin "fader"`,
+		},
+		{
+			name:    "service instead of domain",
+			yaml:    "rate_limits:\n  default:\n    domains:\n      light.turn_on: {per_second: 1, burst: 1}\n",
+			wantErr: `default: domains: "light.turn_on" is not a domain`,
+		},
+		{
+			name:    "zero rate",
+			yaml:    "rate_limits:\n  plugins:\n    music:\n      domains:\n        media_player: {per_second: 0, burst: 5}\n",
+			wantErr: "plugins: music: domains: media_player: per_second must be positive",
+		},
+		{
+			name:    "zero burst",
+			yaml:    "rate_limits:\n  default:\n    domains:\n      \"*\": {per_second: 1}\n",
+			wantErr: "default: domains: *: burst must be at least 1",
+		},
+		{
+			name:    "negative repeat window",
+			yaml:    "rate_limits:\n  default:\n    repeat_window_seconds: -1\n",
+			wantErr: "default: repeat_window_seconds must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rate_limits_config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o644))
+
+			_, err := LoadConfig(path)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSettings_Overrides(t *testing.T) {
+	two, zero := 2.0, 0.0
+	s := Settings{
+		Default: Limits{
+			RepeatWindowSeconds: &two,
+			Domains: map[string]Limit{
+				AnyDomain: {PerSecond: 5, Burst: 30},
+				"light":   {PerSecond: 10, Burst: 60},
+			},
+		},
+		Plugins: map[string]Limits{
+			"music":     {Domains: map[string]Limit{"media_player": {PerSecond: 1, Burst: 5}}},
+			"emergency": {RepeatWindowSeconds: &zero, Domains: map[string]Limit{AnyDomain: {PerSecond: 50, Burst: 200}}},
+		},
+	}
+
+	limit, ok := s.Limit("music", "media_player")
+	require.True(t, ok)
+	assert.Equal(t, Limit{PerSecond: 1, Burst: 5}, limit, "plugin domain")
+
+	limit, _ = s.Limit("music", "light")
+	assert.Equal(t, Limit{PerSecond: 10, Burst: 60}, limit, "default domain")
+
+	limit, _ = s.Limit("music", "switch")
+	assert.Equal(t, Limit{PerSecond: 5, Burst: 30}, limit, "default for any domain")
+
+	limit, _ = s.Limit("emergency", "light")
+	assert.Equal(t, Limit{PerSecond: 50, Burst: 200}, limit, "a plugin's own entries win over every default")
+
+	_, ok = Settings{}.Limit("music", "light")
+	assert.False(t, ok)
+
+	assert.Equal(t, 2*time.Second, s.RepeatWindow("music"))
+	assert.Equal(t, time.Duration(0), s.RepeatWindow("emergency"))
+	assert.Equal(t, time.Duration(0), Settings{}.RepeatWindow("music"))
+}
@@ -0,0 +1,204 @@
+// Package ratelimit keeps a misbehaving plugin from hammering Home Assistant.
+// Each plugin's service calls draw from token buckets per service domain, and
+// a call identical to the plugin's last call to the same entities is dropped
+// within a short window, so a bug like a fade loop stalls instead of flooding
+// the connection.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// bucket is one plugin's token bucket for a domain
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	dropped int // Calls dropped since the bucket last let one through
+}
+
+// sentCall is the last call a plugin sent to a set of entities
+type sentCall struct {
+	signature string
+	at        time.Time
+}
+
+// Limiter applies the configured limits to each plugin's service calls.
+// All methods are safe to call on a nil Limiter, which limits nothing.
+type Limiter struct {
+	config *Config
+	logger *zap.Logger
+	clock  clock.Clock
+
+	// Buckets and last calls by plugin, and calls dropped per plugin
+	// (protected by mu)
+	buckets map[string]map[string]*bucket
+	last    map[string]map[string]sentCall
+	dropped map[string]int
+	mu      sync.Mutex
+}
+
+// NewLimiter creates a limiter from a validated config
+func NewLimiter(config *Config, logger *zap.Logger) *Limiter {
+	return &Limiter{
+		config:  config,
+		logger:  logger.Named("ratelimit"),
+		clock:   clock.NewRealClock(),
+		buckets: make(map[string]map[string]*bucket),
+		last:    make(map[string]map[string]sentCall),
+		dropped: make(map[string]int),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (l *Limiter) SetClock(c clock.Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = c
+}
+
+// Dropped returns how many service calls each plugin had dropped
+func (l *Limiter) Dropped() map[string]int {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := make(map[string]int, len(l.dropped))
+	for plugin, count := range l.dropped {
+		counts[plugin] = count
+	}
+	return counts
+}
+
+// allow reports whether a plugin's call may be sent, logging it if not
+func (l *Limiter) allow(plugin, domain, service string, data map[string]interface{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	action := domain + "." + service
+
+	// Identical to the plugin's last call to the same entities
+	target, signature := callKeys(action, data)
+	window := l.config.RateLimits.RepeatWindow(plugin)
+	if window > 0 {
+		if last, ok := l.last[plugin][target]; ok && last.signature == signature && now.Sub(last.at) < window {
+			l.dropped[plugin]++
+			l.logger.Debug("RATE-LIMIT: Dropped repeated service call",
+				zap.String("plugin", plugin),
+				zap.String("action", action),
+				zap.Any("data", data),
+				zap.Duration("since_last", now.Sub(last.at)))
+			return false
+		}
+	}
+
+	if limit, ok := l.config.RateLimits.Limit(plugin, domain); ok {
+		b := l.bucket(plugin, domain, limit, now)
+		if b.tokens < 1 {
+			// Log the first of a run of drops; the run's total is logged
+			// when calls resume
+			if b.dropped == 0 {
+				l.logger.Warn("RATE-LIMIT: Dropped service call",
+					zap.String("plugin", plugin),
+					zap.String("action", action),
+					zap.Any("data", data),
+					zap.Float64("per_second", limit.PerSecond),
+					zap.Int("burst", limit.Burst))
+			}
+			b.dropped++
+			l.dropped[plugin]++
+			return false
+		}
+		b.tokens--
+		if b.dropped > 0 {
+			l.logger.Info("RATE-LIMIT: Service calls resumed",
+				zap.String("plugin", plugin),
+				zap.String("domain", domain),
+				zap.Int("dropped", b.dropped))
+			b.dropped = 0
+		}
+	}
+
+	if window > 0 {
+		if l.last[plugin] == nil {
+			l.last[plugin] = make(map[string]sentCall)
+		}
+		l.last[plugin][target] = sentCall{signature: signature, at: now}
+	}
+	return true
+}
+
+// bucket returns a plugin's bucket for a domain, refilled up to now;
+// callers must hold mu
+func (l *Limiter) bucket(plugin, domain string, limit Limit, now time.Time) *bucket {
+	buckets, ok := l.buckets[plugin]
+	if !ok {
+		buckets = make(map[string]*bucket)
+		l.buckets[plugin] = buckets
+	}
+	b, ok := buckets[domain]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		buckets[domain] = b
+		return b
+	}
+
+	b.tokens += now.Sub(b.updated).Seconds() * limit.PerSecond
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.updated = now
+	return b
+}
+
+// callKeys returns the entities a call targets and a signature that is equal
+// for identical calls. encoding/json sorts map keys, so equal data encodes
+// equally.
+func callKeys(action string, data map[string]interface{}) (target, signature string) {
+	entities, ok := data["media_player_entity_id"]
+	if !ok {
+		entities = data["entity_id"]
+	}
+	encodedTarget, _ := json.Marshal(entities)
+	encodedData, _ := json.Marshal(data)
+	return string(encodedTarget), action + ":" + string(encodedData)
+}
+
+// Client wraps a plugin's HA client so its service calls are rate limited.
+// Input helper writes, reads and subscriptions pass through.
+func (l *Limiter) Client(plugin string, client ha.HAClient) ha.HAClient {
+	if l == nil {
+		return client
+	}
+	return &limitedClient{HAClient: client, limiter: l, plugin: plugin}
+}
+
+// limitedClient drops a plugin's service calls over its limits
+type limitedClient struct {
+	ha.HAClient
+	limiter *Limiter
+	plugin  string
+}
+
+// CallService drops the call over the plugin's limits, reporting success
+func (c *limitedClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	if !c.limiter.allow(c.plugin, domain, service, data) {
+		return nil
+	}
+	return c.HAClient.CallService(ctx, domain, service, data)
+}
+
+// Expand keeps entity group references working through the wrapper
+func (c *limitedClient) Expand(ids []string) ([]string, error) {
+	return entitygroups.Expand(c.HAClient, ids)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestLimiter(t *testing.T) (*Limiter, *clock.MockClock) {
+	t.Helper()
+	window := 2.0
+	config := &Config{RateLimits: Settings{
+		Default: Limits{
+			RepeatWindowSeconds: &window,
+			Domains:             map[string]Limit{"media_player": {PerSecond: 1, Burst: 3}},
+		},
+		Plugins: map[string]Limits{
+			"emergency": {Domains: map[string]Limit{AnyDomain: {PerSecond: 100, Burst: 100}}},
+		},
+	}}
+	require.NoError(t, config.Validate())
+
+	limiter := NewLimiter(config, zap.NewNop())
+	mockClock := clock.NewMockClock(time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC))
+	limiter.SetClock(mockClock)
+	return limiter, mockClock
+}
+
+func setVolume(t *testing.T, client ha.HAClient, entityID string, level float64) {
+	t.Helper()
+	require.NoError(t, client.CallService(context.Background(), "media_player", "volume_set", map[string]interface{}{
+		"entity_id":    entityID,
+		"volume_level": level,
+	}))
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	limiter, mockClock := newTestLimiter(t)
+	mockClient := ha.NewMockClient()
+	client := limiter.Client("sleephygiene", mockClient)
+
+	for i := 0; i < 5; i++ {
+		setVolume(t, client, "media_player.bedroom", 0.5-float64(i)*0.01)
+	}
+	assert.Len(t, mockClient.GetServiceCalls(), 3, "the burst goes through, the rest is dropped")
+	assert.Equal(t, map[string]int{"sleephygiene": 2}, limiter.Dropped())
+
+	mockClock.Advance(time.Second)
+	setVolume(t, client, "media_player.bedroom", 0.4)
+	setVolume(t, client, "media_player.bedroom", 0.39)
+	assert.Len(t, mockClient.GetServiceCalls(), 4, "one token refills per second")
+
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.bedroom"}))
+	assert.Len(t, mockClient.GetServiceCalls(), 5, "domains without a limit aren't limited")
+
+	other := limiter.Client("music", mockClient)
+	setVolume(t, other, "media_player.bedroom", 0.2)
+	assert.Len(t, mockClient.GetServiceCalls(), 6, "each plugin has its own buckets")
+}
+
+func TestLimiter_RepeatedCalls(t *testing.T) {
+	limiter, mockClock := newTestLimiter(t)
+	mockClient := ha.NewMockClient()
+	client := limiter.Client("emergency", mockClient)
+
+	setVolume(t, client, "media_player.kitchen", 0.8)
+	setVolume(t, client, "media_player.kitchen", 0.8)
+	assert.Len(t, mockClient.GetServiceCalls(), 1, "an identical call within the window is dropped")
+
+	setVolume(t, client, "media_player.living_room", 0.8)
+	setVolume(t, client, "media_player.kitchen", 0.6)
+	setVolume(t, client, "media_player.kitchen", 0.8)
+	assert.Len(t, mockClient.GetServiceCalls(), 4, "other entities and different calls go through")
+
+	mockClock.Advance(2 * time.Second)
+	setVolume(t, client, "media_player.kitchen", 0.8)
+	assert.Len(t, mockClient.GetServiceCalls(), 5, "the window has passed")
+}
+
+func TestLimiter_NilLimitsNothing(t *testing.T) {
+	var limiter *Limiter
+	mockClient := ha.NewMockClient()
+	assert.Same(t, mockClient, limiter.Client("music", mockClient))
+	assert.Nil(t, limiter.Dropped())
+}
+
+func TestLimiter_ClientKeepsEntityGroups(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+	groups := &entitygroups.Config{EntityGroups: map[string][]string{"bedroom": {"media_player.bedroom", "media_player.bathroom"}}}
+	client := limiter.Client("music", entitygroups.NewClient(ha.NewMockClient(), groups))
+
+	ids, err := entitygroups.Expand(client, []string{entitygroups.Group("bedroom")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"media_player.bedroom", "media_player.bathroom"}, ids)
+}
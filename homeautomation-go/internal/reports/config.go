@@ -0,0 +1,98 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"homeautomation/internal/notifyrouter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WeeklyReportConfig holds the weekly report schedule and data sources
+type WeeklyReportConfig struct {
+	Day                   string              `yaml:"day"`                     // Day of week the report is generated, e.g. "sunday"
+	Time                  string              `yaml:"time"`                    // Format: "18:00"
+	NotifyService         notifyrouter.Target `yaml:"notify_service"`          // e.g. "notify.notify"; empty disables notifications
+	SolarProductionSensor string              `yaml:"solar_production_sensor"` // Cumulative solar production (kWh); optional
+	GridExportSensor      string              `yaml:"grid_export_sensor"`      // Cumulative energy exported to the grid (kWh); optional
+}
+
+// defaultHeatmapWeeks is how much occupancy history is kept when weeks is unset
+const defaultHeatmapWeeks = 4
+
+// OccupancyHeatmapConfig lists the rooms sampled for the occupancy heatmap
+type OccupancyHeatmapConfig struct {
+	Weeks int               `yaml:"weeks"` // Weeks of history kept; defaults to 4
+	Rooms map[string]string `yaml:"rooms"` // Room name -> boolean occupancy state variable
+}
+
+// ReportConfig represents the report_config.yaml structure
+type ReportConfig struct {
+	WeeklyReport     WeeklyReportConfig     `yaml:"weekly_report"`
+	OccupancyHeatmap OccupancyHeatmapConfig `yaml:"occupancy_heatmap"`
+}
+
+// Retention returns how long occupancy samples are kept
+func (c *OccupancyHeatmapConfig) Retention() time.Duration {
+	weeks := c.Weeks
+	if weeks == 0 {
+		weeks = defaultHeatmapWeeks
+	}
+	return time.Duration(weeks) * 7 * 24 * time.Hour
+}
+
+// Weekday returns the configured report day
+func (c *WeeklyReportConfig) Weekday() (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), c.Day) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid day %q", c.Day)
+}
+
+// Validate checks that the report schedule can be parsed
+func (c *ReportConfig) Validate() error {
+	if _, err := c.WeeklyReport.Weekday(); err != nil {
+		return fmt.Errorf("weekly_report: %w", err)
+	}
+	if _, err := time.Parse("15:04", c.WeeklyReport.Time); err != nil {
+		return fmt.Errorf("weekly_report: invalid time: %w", err)
+	}
+	if c.WeeklyReport.NotifyService != "" {
+		if _, _, ok := c.WeeklyReport.NotifyService.DomainService(); !ok {
+			return fmt.Errorf("weekly_report: invalid notify_service %q (expected domain.service)", c.WeeklyReport.NotifyService)
+		}
+	}
+	if c.OccupancyHeatmap.Weeks < 0 {
+		return fmt.Errorf("occupancy_heatmap: weeks must not be negative, got %d", c.OccupancyHeatmap.Weeks)
+	}
+	for room, variable := range c.OccupancyHeatmap.Rooms {
+		if room == "" || variable == "" {
+			return fmt.Errorf("occupancy_heatmap: room %q needs an occupancy variable", room)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the report configuration from a YAML file
+func LoadConfig(path string) (*ReportConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ReportConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,105 @@
+package reports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "report_config.yaml")
+
+	configContent := `---
+weekly_report:
+  day: Monday
+  time: "08:30"
+  notify_service: notify.mobile_app_phone
+  solar_production_sensor: sensor.solar_total
+  grid_export_sensor: sensor.grid_export_total
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	weekday, err := config.WeeklyReport.Weekday()
+	require.NoError(t, err)
+	assert.Equal(t, time.Monday, weekday)
+	assert.Equal(t, "08:30", config.WeeklyReport.Time)
+	assert.Equal(t, "sensor.solar_total", config.WeeklyReport.SolarProductionSensor)
+
+	domain, service, ok := config.WeeklyReport.NotifyService.DomainService()
+	assert.True(t, ok)
+	assert.Equal(t, "notify", domain)
+	assert.Equal(t, "mobile_app_phone", service)
+}
+
+func TestLoadConfig_FileNotFound(t *testing.T) {
+	_, err := LoadConfig("/nonexistent/report_config.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_ProductionConfig(t *testing.T) {
+	_, err := LoadConfig("../../../configs/report_config.yaml")
+	require.NoError(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  WeeklyReportConfig
+		wantErr bool
+	}{
+		{"valid", WeeklyReportConfig{Day: "sunday", Time: "18:00", NotifyService: "notify.notify"}, false},
+		{"notifications disabled", WeeklyReportConfig{Day: "sunday", Time: "18:00"}, false},
+		{"invalid day", WeeklyReportConfig{Day: "someday", Time: "18:00"}, true},
+		{"invalid time", WeeklyReportConfig{Day: "sunday", Time: "6pm"}, true},
+		{"invalid notify service", WeeklyReportConfig{Day: "sunday", Time: "18:00", NotifyService: "notify"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ReportConfig{WeeklyReport: tt.config}
+			err := config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_OccupancyHeatmap(t *testing.T) {
+	weekly := WeeklyReportConfig{Day: "sunday", Time: "18:00"}
+
+	tests := []struct {
+		name    string
+		config  OccupancyHeatmapConfig
+		wantErr bool
+	}{
+		{"unset", OccupancyHeatmapConfig{}, false},
+		{"valid", OccupancyHeatmapConfig{Weeks: 2, Rooms: map[string]string{"kitchen": "isKitchenOccupied"}}, false},
+		{"negative weeks", OccupancyHeatmapConfig{Weeks: -1}, true},
+		{"missing variable", OccupancyHeatmapConfig{Rooms: map[string]string{"kitchen": ""}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ReportConfig{WeeklyReport: weekly, OccupancyHeatmap: tt.config}
+			err := config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, 4*7*24*time.Hour, (&OccupancyHeatmapConfig{}).Retention())
+}
@@ -0,0 +1,437 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entities"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/health"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+// SampleInterval is how often energy meters and room occupancy are sampled
+const SampleInterval = 1 * time.Minute
+
+// historyRetention is how long raw events are kept; a little longer than a
+// report period so a slightly late report still sees the whole week
+const historyRetention = ReportPeriod + 24*time.Hour
+
+// Manager collects automation history in memory and produces a weekly report.
+// History is not persisted, so a report generated soon after a restart only
+// covers the time since startup.
+type Manager struct {
+	haClient      ha.HAClient
+	stateManager  *state.Manager
+	shadowTracker *shadowstate.Tracker
+	config        *ReportConfig
+	logger        *zap.Logger
+	readOnly      bool
+	timezone      *time.Location
+	clock         clock.Clock
+	entities      *entities.Registry // The doorbell counted in reports, nil uses the default
+
+	mu         sync.Mutex
+	history    history
+	lastAsleep *bool // last observed isMasterAsleep, nil until first change
+	latest     *WeeklyReport
+	nextReport time.Time
+
+	// Subscriptions for cleanup
+	haSubscriptions    []ha.Subscription
+	stateSubscriptions []state.Subscription
+	unsubscribeActions func()
+
+	stopChan chan struct{}
+
+	// Goroutines started by Start, waited for by Stop
+	background sync.WaitGroup
+
+	// Cancelled by Stop to abort in-flight Home Assistant requests
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Ticks, subscriptions and errors for /health
+	health *health.Recorder
+}
+
+// NewManager creates a new weekly report manager
+func NewManager(haClient ha.HAClient, stateManager *state.Manager, shadowTracker *shadowstate.Tracker, config *ReportConfig, logger *zap.Logger, readOnly bool, timezone *time.Location) *Manager {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	recorder := health.NewRecorder(SampleInterval)
+
+	return &Manager{
+		ctx:           ctx,
+		cancel:        cancel,
+		haClient:      haClient,
+		stateManager:  stateManager,
+		shadowTracker: shadowTracker,
+		config:        config,
+		logger:        recorder.Logger(logger.Named("reports")),
+		health:        recorder,
+		readOnly:      readOnly,
+		timezone:      timezone,
+		clock:         clock.NewRealClock(),
+		history: history{
+			automations:   make(map[string][]time.Time),
+			energyEnabled: config.WeeklyReport.SolarProductionSensor != "" && config.WeeklyReport.GridExportSensor != "",
+			occupancy:     make(map[string]map[time.Time]occupancyCount),
+		},
+		stopChan: make(chan struct{}),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetEntities sets the registry the doorbell is looked up in. It must be
+// called before Start.
+func (m *Manager) SetEntities(registry *entities.Registry) {
+	m.entities = registry
+}
+
+// Start begins collecting history and schedules the weekly report
+func (m *Manager) Start(ctx context.Context) error {
+	// Reports run under ctx; a re-enabled manager also needs an open stopChan
+	if m.ctx.Err() != nil {
+		m.stopChan = make(chan struct{})
+	}
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	m.logger.Info("Starting Report Manager",
+		zap.String("day", m.config.WeeklyReport.Day),
+		zap.String("time", m.config.WeeklyReport.Time))
+
+	sub, err := m.stateManager.Subscribe("isMasterAsleep", m.handleMasterAsleepChange)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to isMasterAsleep: %w", err)
+	}
+	m.stateSubscriptions = append(m.stateSubscriptions, sub)
+
+	haSub, err := m.haClient.SubscribeStateChanges(m.entities.Doorbell(), m.handleDoorbellPressed)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to doorbell: %w", err)
+	}
+	m.haSubscriptions = append(m.haSubscriptions, haSub)
+
+	// Every action is counted as it happens, so bursts between samples aren't lost
+	m.unsubscribeActions = m.shadowTracker.SubscribeActions(m.handleAction)
+
+	now := m.clock.Now()
+	m.mu.Lock()
+	m.nextReport = m.nextReportTime(now)
+	next := m.nextReport
+	m.mu.Unlock()
+
+	m.logger.Info("Next weekly report scheduled", zap.Time("at", next))
+
+	// The ticker starts here so ticks of a mock clock advanced right after
+	// Start aren't missed
+	ticker := m.clock.NewTicker(SampleInterval)
+	stop := m.stopChan
+	m.goBackground(func() { m.runLoop(ticker, stop) })
+
+	m.health.Started(len(m.haSubscriptions) + len(m.stateSubscriptions))
+	m.logger.Info("Report Manager started successfully")
+	return nil
+}
+
+// Stop stops the Report Manager and cleans up subscriptions
+func (m *Manager) Stop() {
+	m.health.Stopped()
+	m.logger.Info("Stopping Report Manager")
+
+	m.cancel()
+
+	close(m.stopChan)
+	m.background.Wait()
+
+	if m.unsubscribeActions != nil {
+		m.unsubscribeActions()
+		m.unsubscribeActions = nil
+	}
+
+	for _, sub := range m.haSubscriptions {
+		sub.Unsubscribe()
+	}
+	m.haSubscriptions = nil
+
+	for _, sub := range m.stateSubscriptions {
+		sub.Unsubscribe()
+	}
+	m.stateSubscriptions = nil
+
+	m.logger.Info("Report Manager stopped")
+}
+
+// Health reports whether the manager is working
+func (m *Manager) Health() health.Report {
+	return m.health.Report()
+}
+
+// goBackground runs f in a goroutine that Stop waits for
+func (m *Manager) goBackground(f func()) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		f()
+	}()
+}
+
+// GetWeeklyReport returns the most recently generated weekly report. Before the
+// first scheduled report, a preview covering the trailing week is returned.
+func (m *Manager) GetWeeklyReport() *WeeklyReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.latest != nil {
+		return copyReport(m.latest)
+	}
+
+	preview := buildReport(m.history, m.clock.Now(), m.timezone)
+	preview.Notes = append(preview.Notes, "Preview: the first weekly report has not been generated yet")
+	return preview
+}
+
+// runLoop samples history every SampleInterval and generates the report when due
+func (m *Manager) runLoop(ticker clock.Ticker, stop <-chan struct{}) {
+	defer ticker.Stop()
+
+	m.sample()
+
+	for {
+		select {
+		case <-ticker.C():
+			m.sample()
+			m.generateIfDue()
+		case <-stop:
+			m.logger.Info("Stopping report loop")
+			return
+		}
+	}
+}
+
+// sample records energy meter readings and room occupancy, and prunes old
+// history
+func (m *Manager) sample() {
+	m.health.Tick()
+	now := m.clock.Now()
+
+	if m.history.energyEnabled {
+		m.sampleEnergy(now)
+	}
+
+	m.sampleOccupancy(now)
+
+	m.mu.Lock()
+	m.pruneLocked(now)
+	m.pruneOccupancyLocked(now)
+	m.mu.Unlock()
+}
+
+// handleAction counts a plugin action
+func (m *Manager) handleAction(action shadowstate.ActionEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.history.automations[action.Plugin] = append(m.history.automations[action.Plugin], action.Timestamp)
+}
+
+// sampleEnergy reads the cumulative energy meters
+func (m *Manager) sampleEnergy(now time.Time) {
+	solar, err := m.readSensor(m.config.WeeklyReport.SolarProductionSensor)
+	if err != nil {
+		m.logger.Debug("Failed to read solar production sensor", zap.Error(err))
+		return
+	}
+	export, err := m.readSensor(m.config.WeeklyReport.GridExportSensor)
+	if err != nil {
+		m.logger.Debug("Failed to read grid export sensor", zap.Error(err))
+		return
+	}
+
+	m.mu.Lock()
+	m.history.energy = append(m.history.energy, energyReading{
+		Timestamp:       now,
+		SolarProduction: solar,
+		GridExport:      export,
+	})
+	m.mu.Unlock()
+}
+
+// readSensor returns the numeric state of a sensor
+func (m *Manager) readSensor(entityID string) (float64, error) {
+	sensorState, err := m.haClient.GetState(m.ctx, entityID)
+	if err != nil {
+		return 0, err
+	}
+	if sensorState == nil {
+		return 0, fmt.Errorf("sensor %s not found", entityID)
+	}
+	value, err := strconv.ParseFloat(sensorState.State, 64)
+	if err != nil {
+		return 0, fmt.Errorf("sensor %s has non-numeric state %q", entityID, sensorState.State)
+	}
+	return value, nil
+}
+
+// pruneLocked drops history older than historyRetention. Caller must hold mu.
+func (m *Manager) pruneLocked(now time.Time) {
+	cutoff := now.Add(-historyRetention)
+	keep := func(t time.Time) bool { return !t.Before(cutoff) }
+
+	for plugin, times := range m.history.automations {
+		m.history.automations[plugin] = pruneTimes(times, keep)
+	}
+	m.history.sleepTimes = pruneTimes(m.history.sleepTimes, keep)
+	m.history.wakeTimes = pruneTimes(m.history.wakeTimes, keep)
+	m.history.doorbellEvents = pruneTimes(m.history.doorbellEvents, keep)
+
+	readings := m.history.energy[:0]
+	for _, r := range m.history.energy {
+		if keep(r.Timestamp) {
+			readings = append(readings, r)
+		}
+	}
+	m.history.energy = readings
+}
+
+// pruneTimes filters times in place, keeping those accepted by keep
+func pruneTimes(times []time.Time, keep func(time.Time) bool) []time.Time {
+	result := times[:0]
+	for _, t := range times {
+		if keep(t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// generateIfDue generates and delivers the weekly report once its scheduled time has passed
+func (m *Manager) generateIfDue() {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	if now.Before(m.nextReport) {
+		m.mu.Unlock()
+		return
+	}
+	report := buildReport(m.history, now, m.timezone)
+	m.latest = report
+	m.nextReport = m.nextReportTime(now)
+	next := m.nextReport
+	m.mu.Unlock()
+
+	m.logger.Info("Weekly report generated",
+		zap.Int("total_automations", report.TotalAutomations),
+		zap.Int("doorbell_events", report.DoorbellEvents),
+		zap.Time("next_report", next))
+
+	m.sendNotification(report)
+}
+
+// sendNotification delivers the report summary through the configured notify service
+func (m *Manager) sendNotification(report *WeeklyReport) {
+	target := m.config.WeeklyReport.NotifyService
+	if target == "" {
+		return
+	}
+
+	message := report.Summary()
+
+	if m.readOnly {
+		m.logger.Info("READ-ONLY: Would send weekly report notification",
+			zap.String("service", string(target)),
+			zap.String("message", message))
+		return
+	}
+
+	if err := notifyrouter.SendPush(m.ctx, m.haClient, target, notifyrouter.Push{
+		Title:   "Weekly home automation report",
+		Message: message,
+	}); err != nil {
+		m.logger.Error("Failed to send weekly report notification", zap.Error(err))
+	} else {
+		m.logger.Info("Weekly report notification sent")
+	}
+}
+
+// nextReportTime returns the first scheduled report time strictly after the given time
+func (m *Manager) nextReportTime(after time.Time) time.Time {
+	// Config is validated at load time
+	weekday, _ := m.config.WeeklyReport.Weekday()
+	at, _ := time.Parse("15:04", m.config.WeeklyReport.Time)
+
+	local := after.In(m.timezone)
+	daysAhead := (int(weekday) - int(local.Weekday()) + 7) % 7
+	candidate := time.Date(local.Year(), local.Month(), local.Day()+daysAhead, at.Hour(), at.Minute(), 0, 0, m.timezone)
+	if !candidate.After(after) {
+		candidate = candidate.AddDate(0, 0, 7)
+	}
+	return candidate
+}
+
+// handleMasterAsleepChange records sleep and wake times
+func (m *Manager) handleMasterAsleepChange(key string, oldValue, newValue interface{}) {
+	asleep, ok := newValue.(bool)
+	if !ok {
+		m.logger.Error("Invalid type for isMasterAsleep", zap.Any("value", newValue))
+		return
+	}
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Ignore repeated notifications for the same value (e.g. attribute-only updates)
+	if m.lastAsleep != nil && *m.lastAsleep == asleep {
+		return
+	}
+	m.lastAsleep = &asleep
+
+	if asleep {
+		m.history.sleepTimes = append(m.history.sleepTimes, now)
+	} else {
+		m.history.wakeTimes = append(m.history.wakeTimes, now)
+	}
+}
+
+// handleDoorbellPressed records a doorbell event
+func (m *Manager) handleDoorbellPressed(entity string, oldState, newState *ha.State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.history.doorbellEvents = append(m.history.doorbellEvents, m.clock.Now())
+}
+
+// copyReport returns a deep copy of a report
+func copyReport(r *WeeklyReport) *WeeklyReport {
+	c := *r
+	c.AutomationsByPlugin = make(map[string]int, len(r.AutomationsByPlugin))
+	for k, v := range r.AutomationsByPlugin {
+		c.AutomationsByPlugin[k] = v
+	}
+	if r.EnergySelfConsumptionPct != nil {
+		pct := *r.EnergySelfConsumptionPct
+		c.EnergySelfConsumptionPct = &pct
+	}
+	c.Notes = append([]string(nil), r.Notes...)
+	return &c
+}
@@ -0,0 +1,236 @@
+package reports
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/shadowstate"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func createTestConfig() *ReportConfig {
+	return &ReportConfig{
+		WeeklyReport: WeeklyReportConfig{
+			Day:                   "sunday",
+			Time:                  "18:00",
+			NotifyService:         "notify.notify",
+			SolarProductionSensor: "sensor.solar_total",
+			GridExportSensor:      "sensor.grid_export_total",
+		},
+	}
+}
+
+// Saturday 2025-06-14 12:00 UTC
+var testStart = time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+
+func setupTest(t *testing.T, readOnly bool) (*Manager, *ha.MockClient, *state.Manager, *shadowstate.Tracker, *clock.MockClock) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	mockHA := ha.NewMockClient()
+	stateManager := state.NewManager(mockHA, logger, false)
+	tracker := shadowstate.NewTracker()
+
+	manager := NewManager(mockHA, stateManager, tracker, createTestConfig(), logger, readOnly, time.UTC)
+	mockClock := clock.NewMockClock(testStart)
+	manager.SetClock(mockClock)
+
+	require.NoError(t, manager.Start(context.Background()))
+	t.Cleanup(manager.Stop)
+
+	return manager, mockHA, stateManager, tracker, mockClock
+}
+
+func notifyCalls(mockHA *ha.MockClient) []ha.ServiceCall {
+	var calls []ha.ServiceCall
+	for _, call := range mockHA.GetServiceCalls() {
+		if call.Domain == "notify" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestNextReportTime(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), nil, shadowstate.NewTracker(), createTestConfig(), zap.NewNop(), false, time.UTC)
+
+	tests := []struct {
+		name     string
+		after    time.Time
+		expected time.Time
+	}{
+		{"saturday before", testStart, time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC)},
+		{"sunday morning", time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC), time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC)},
+		{"exactly at report time", time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC), time.Date(2025, 6, 22, 18, 0, 0, 0, time.UTC)},
+		{"sunday evening", time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC), time.Date(2025, 6, 22, 18, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, manager.nextReportTime(tt.after))
+		})
+	}
+}
+
+func TestCountsEveryPluginAction(t *testing.T) {
+	manager, _, _, tracker, _ := setupTest(t, false)
+
+	// A burst within one sample interval, some in the same instant, is all counted
+	for i := 0; i < 5; i++ {
+		tracker.RecordAction("lighting", testStart.Add(-time.Duration(i)*time.Second), "Motion")
+	}
+	tracker.RecordAction("lighting", testStart, "Motion")
+	tracker.RecordAction("rules", testStart, "Porch light (sunset)")
+
+	report := manager.GetWeeklyReport()
+	assert.Equal(t, map[string]int{"lighting": 6, "rules": 1}, report.AutomationsByPlugin)
+
+	// Actions while stopped aren't counted
+	manager.Stop()
+	tracker.RecordAction("lighting", testStart, "Motion")
+	require.NoError(t, manager.Start(context.Background()))
+	tracker.RecordAction("rules", testStart, "Porch light (sunset)")
+
+	report = manager.GetWeeklyReport()
+	assert.Equal(t, map[string]int{"lighting": 6, "rules": 2}, report.AutomationsByPlugin)
+}
+
+func TestHistory_SleepWakeAndDoorbell(t *testing.T) {
+	manager, mockHA, stateManager, _, mockClock := setupTest(t, false)
+
+	mockClock.Set(time.Date(2025, 6, 13, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, stateManager.SetBool("isMasterAsleep", true))
+
+	mockClock.Set(time.Date(2025, 6, 14, 7, 0, 0, 0, time.UTC))
+	require.NoError(t, stateManager.SetBool("isMasterAsleep", false))
+
+	mockHA.SimulateStateChange("input_button.doorbell", "2025-06-14T07:00:01")
+	mockHA.SimulateStateChange("input_button.doorbell", "2025-06-14T07:00:02")
+
+	mockClock.Set(testStart)
+	report := manager.GetWeeklyReport()
+
+	assert.Equal(t, "23:00", report.AverageSleepTime)
+	assert.Equal(t, "07:00", report.AverageWakeTime)
+	assert.Equal(t, 2, report.DoorbellEvents)
+}
+
+func TestSampleEnergy(t *testing.T) {
+	manager, mockHA, _, _, mockClock := setupTest(t, false)
+
+	mockHA.SetState("sensor.solar_total", "1000", nil)
+	mockHA.SetState("sensor.grid_export_total", "100", nil)
+	manager.sample()
+
+	mockClock.Advance(24 * time.Hour)
+	mockHA.SetState("sensor.solar_total", "1200", nil)
+	mockHA.SetState("sensor.grid_export_total", "150", nil)
+	manager.sample()
+
+	report := manager.GetWeeklyReport()
+	require.NotNil(t, report.EnergySelfConsumptionPct)
+	assert.InDelta(t, 75.0, *report.EnergySelfConsumptionPct, 0.001)
+}
+
+func TestGetWeeklyReport_PreviewBeforeFirstReport(t *testing.T) {
+	manager, _, _, _, _ := setupTest(t, false)
+
+	report := manager.GetWeeklyReport()
+
+	assert.Contains(t, report.Notes, "Preview: the first weekly report has not been generated yet")
+}
+
+func TestGenerateIfDue(t *testing.T) {
+	manager, mockHA, _, tracker, mockClock := setupTest(t, false)
+
+	tracker.RecordAction("security", testStart, "Doorbell")
+
+	// Not yet due
+	manager.generateIfDue()
+	assert.Empty(t, notifyCalls(mockHA))
+
+	// Sunday 18:00
+	mockClock.Set(time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC))
+	manager.generateIfDue()
+
+	calls := notifyCalls(mockHA)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "notify", calls[0].Service)
+	assert.Contains(t, calls[0].Data["message"], "security 1")
+
+	report := manager.GetWeeklyReport()
+	assert.Equal(t, 1, report.AutomationsByPlugin["security"])
+	assert.NotContains(t, report.Notes, "Preview: the first weekly report has not been generated yet")
+
+	// The stored report is returned until the next one is due
+	mockClock.Advance(time.Hour)
+	manager.generateIfDue()
+	assert.Len(t, notifyCalls(mockHA), 1)
+	assert.Equal(t, report.GeneratedAt, manager.GetWeeklyReport().GeneratedAt)
+}
+
+func TestGenerateIfDue_ReadOnlyMode(t *testing.T) {
+	manager, mockHA, _, _, mockClock := setupTest(t, true)
+
+	mockClock.Set(time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC))
+	manager.generateIfDue()
+
+	assert.Empty(t, notifyCalls(mockHA), "read-only mode should not send notifications")
+	assert.Equal(t, time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC), manager.GetWeeklyReport().GeneratedAt)
+}
+
+func TestPrune_DropsOldHistory(t *testing.T) {
+	manager, _, _, tracker, mockClock := setupTest(t, false)
+
+	tracker.RecordAction("lighting", testStart, "Motion")
+
+	mockClock.Advance(historyRetention + time.Minute)
+	manager.sample()
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	assert.Empty(t, manager.history.automations["lighting"])
+}
+
+func TestGetWeeklyReport_ReturnsCopy(t *testing.T) {
+	manager, _, _, tracker, mockClock := setupTest(t, false)
+
+	tracker.RecordAction("lighting", testStart, "Motion")
+	mockClock.Set(time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC))
+	manager.generateIfDue()
+
+	report := manager.GetWeeklyReport()
+	report.AutomationsByPlugin["lighting"] = 99
+
+	assert.Equal(t, 1, manager.GetWeeklyReport().AutomationsByPlugin["lighting"])
+}
+
+func TestRunLoop_SamplesOnClockTicks(t *testing.T) {
+	manager, mockHA, _, _, mockClock := setupTest(t, false)
+
+	readings := func() int {
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+		return len(manager.history.energy)
+	}
+
+	mockHA.SetState("sensor.solar_total", "1000", nil)
+	mockHA.SetState("sensor.grid_export_total", "100", nil)
+	manager.sample()
+	before := readings()
+
+	mockClock.Advance(SampleInterval)
+	assert.Eventually(t, func() bool {
+		return readings() > before
+	}, time.Second, 10*time.Millisecond, "a tick of the manager's clock should sample the meters")
+
+	manager.Stop()
+	require.NoError(t, manager.Start(context.Background()), "a stopped manager can be started again")
+}
@@ -0,0 +1,175 @@
+package reports
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OccupancyHeatmap is how often each room was occupied at each local hour
+// over the retained occupancy history
+type OccupancyHeatmap struct {
+	PeriodStart time.Time                 `json:"periodStart"`
+	PeriodEnd   time.Time                 `json:"periodEnd"`
+	GeneratedAt time.Time                 `json:"generatedAt"`
+	Rooms       map[string]*RoomOccupancy `json:"rooms"`
+	Notes       []string                  `json:"notes,omitempty"`
+}
+
+// RoomOccupancy holds the percentage of samples a room was occupied, indexed
+// by hour of day. Hours without samples are null.
+type RoomOccupancy struct {
+	Variable  string                `json:"variable"`
+	Samples   int                   `json:"samples"`
+	Hourly    []*float64            `json:"hourly"`
+	ByWeekday map[string][]*float64 `json:"byWeekday"` // "monday" -> hour of day
+}
+
+// occupancyCount tallies the samples taken during one local clock hour
+type occupancyCount struct {
+	samples  int
+	occupied int
+}
+
+// sampleOccupancy records whether each configured room is occupied
+func (m *Manager) sampleOccupancy(now time.Time) {
+	rooms := m.config.OccupancyHeatmap.Rooms
+	if len(rooms) == 0 {
+		return
+	}
+
+	occupied := make(map[string]bool, len(rooms))
+	for room, variable := range rooms {
+		value, err := m.stateManager.GetBool(variable)
+		if err != nil {
+			m.logger.Debug("Failed to read room occupancy",
+				zap.String("room", room),
+				zap.String("variable", variable),
+				zap.Error(err))
+			continue
+		}
+		occupied[room] = value
+	}
+
+	hour := startOfHour(now, m.timezone)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for room, value := range occupied {
+		hours := m.history.occupancy[room]
+		if hours == nil {
+			hours = make(map[time.Time]occupancyCount)
+			m.history.occupancy[room] = hours
+		}
+		count := hours[hour]
+		count.samples++
+		if value {
+			count.occupied++
+		}
+		hours[hour] = count
+	}
+}
+
+// pruneOccupancyLocked drops occupancy older than the heatmap's retention.
+// Caller must hold mu.
+func (m *Manager) pruneOccupancyLocked(now time.Time) {
+	cutoff := startOfHour(now.Add(-m.config.OccupancyHeatmap.Retention()), m.timezone)
+	for _, hours := range m.history.occupancy {
+		for hour := range hours {
+			if hour.Before(cutoff) {
+				delete(hours, hour)
+			}
+		}
+	}
+}
+
+// GetOccupancyHeatmap returns each configured room's occupancy by hour of day
+// and by weekday over the retained history
+func (m *Manager) GetOccupancyHeatmap() *OccupancyHeatmap {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return buildOccupancyHeatmap(m.history.occupancy, m.config.OccupancyHeatmap, now, m.timezone)
+}
+
+// buildOccupancyHeatmap aggregates hourly counts into percentages per hour of
+// day, across all days and per weekday
+func buildOccupancyHeatmap(occupancy map[string]map[time.Time]occupancyCount, config OccupancyHeatmapConfig, end time.Time, timezone *time.Location) *OccupancyHeatmap {
+	heatmap := &OccupancyHeatmap{
+		PeriodStart: end.Add(-config.Retention()),
+		PeriodEnd:   end,
+		GeneratedAt: end,
+		Rooms:       make(map[string]*RoomOccupancy, len(config.Rooms)),
+	}
+
+	if len(config.Rooms) == 0 {
+		heatmap.Notes = append(heatmap.Notes, "No rooms configured under occupancy_heatmap")
+		return heatmap
+	}
+
+	var earliest time.Time
+	for room, variable := range config.Rooms {
+		var hourly [24]occupancyCount
+		var weekly [7][24]occupancyCount
+		samples := 0
+
+		for hour, count := range occupancy[room] {
+			local := hour.In(timezone)
+			hourly[local.Hour()].add(count)
+			weekly[local.Weekday()][local.Hour()].add(count)
+			samples += count.samples
+			if earliest.IsZero() || hour.Before(earliest) {
+				earliest = hour
+			}
+		}
+
+		roomOccupancy := &RoomOccupancy{
+			Variable:  variable,
+			Samples:   samples,
+			Hourly:    percentages(hourly),
+			ByWeekday: make(map[string][]*float64, 7),
+		}
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			roomOccupancy.ByWeekday[strings.ToLower(day.String())] = percentages(weekly[day])
+		}
+		heatmap.Rooms[room] = roomOccupancy
+	}
+
+	if earliest.IsZero() {
+		heatmap.Notes = append(heatmap.Notes, "No occupancy samples yet")
+	} else if earliest.After(startOfHour(heatmap.PeriodStart, timezone)) {
+		heatmap.Notes = append(heatmap.Notes, "Occupancy history is kept in memory, so it only covers the time since startup")
+	}
+
+	return heatmap
+}
+
+// add accumulates another hour's samples
+func (c *occupancyCount) add(other occupancyCount) {
+	c.samples += other.samples
+	c.occupied += other.occupied
+}
+
+// percentages converts counts to occupied percentages rounded to one decimal,
+// leaving hours without samples nil
+func percentages(counts [24]occupancyCount) []*float64 {
+	result := make([]*float64, len(counts))
+	for i, count := range counts {
+		if count.samples == 0 {
+			continue
+		}
+		pct := math.Round(float64(count.occupied)/float64(count.samples)*1000) / 10
+		result[i] = &pct
+	}
+	return result
+}
+
+// startOfHour returns the start of the local clock hour containing t
+func startOfHour(t time.Time, timezone *time.Location) time.Time {
+	local := t.In(timezone)
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, timezone)
+}
@@ -0,0 +1,77 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOccupancyHeatmap_HourlyPercentages(t *testing.T) {
+	manager, _, stateManager, _, mockClock := setupTest(t, false)
+	manager.config.OccupancyHeatmap = OccupancyHeatmapConfig{
+		Rooms: map[string]string{"kitchen": "isKitchenOccupied"},
+	}
+
+	// Saturday 12:00-12:03 occupied three of four samples
+	for i, occupied := range []bool{true, true, false, true} {
+		mockClock.Set(testStart.Add(time.Duration(i) * time.Minute))
+		require.NoError(t, stateManager.SetBool("isKitchenOccupied", occupied))
+		manager.sample()
+	}
+	// Sunday 12:00 occupied
+	mockClock.Set(testStart.Add(24 * time.Hour))
+	require.NoError(t, stateManager.SetBool("isKitchenOccupied", true))
+	manager.sample()
+
+	heatmap := manager.GetOccupancyHeatmap()
+	kitchen, ok := heatmap.Rooms["kitchen"]
+	require.True(t, ok)
+	assert.Equal(t, "isKitchenOccupied", kitchen.Variable)
+	assert.Equal(t, 5, kitchen.Samples)
+
+	require.Len(t, kitchen.Hourly, 24)
+	require.NotNil(t, kitchen.Hourly[12])
+	assert.Equal(t, 80.0, *kitchen.Hourly[12])
+	assert.Nil(t, kitchen.Hourly[13], "hours without samples are null")
+
+	require.NotNil(t, kitchen.ByWeekday["saturday"][12])
+	assert.Equal(t, 75.0, *kitchen.ByWeekday["saturday"][12])
+	require.NotNil(t, kitchen.ByWeekday["sunday"][12])
+	assert.Equal(t, 100.0, *kitchen.ByWeekday["sunday"][12])
+	assert.Nil(t, kitchen.ByWeekday["monday"][12])
+
+	assert.Contains(t, heatmap.Notes, "Occupancy history is kept in memory, so it only covers the time since startup")
+}
+
+func TestOccupancyHeatmap_PrunesOldSamples(t *testing.T) {
+	manager, _, stateManager, _, mockClock := setupTest(t, false)
+	manager.config.OccupancyHeatmap = OccupancyHeatmapConfig{
+		Weeks: 1,
+		Rooms: map[string]string{"kitchen": "isKitchenOccupied"},
+	}
+
+	require.NoError(t, stateManager.SetBool("isKitchenOccupied", true))
+	manager.sample()
+
+	mockClock.Advance(7*24*time.Hour + 2*time.Hour)
+	require.NoError(t, stateManager.SetBool("isKitchenOccupied", false))
+	manager.sample()
+
+	kitchen := manager.GetOccupancyHeatmap().Rooms["kitchen"]
+	assert.Equal(t, 1, kitchen.Samples)
+	assert.Nil(t, kitchen.Hourly[12], "the first week's sample is pruned")
+	require.NotNil(t, kitchen.Hourly[14])
+	assert.Equal(t, 0.0, *kitchen.Hourly[14])
+}
+
+func TestOccupancyHeatmap_NoRooms(t *testing.T) {
+	manager, _, _, _, _ := setupTest(t, false)
+
+	heatmap := manager.GetOccupancyHeatmap()
+
+	assert.Empty(t, heatmap.Rooms)
+	assert.Contains(t, heatmap.Notes, "No rooms configured under occupancy_heatmap")
+	assert.Equal(t, testStart.Add(-4*7*24*time.Hour), heatmap.PeriodStart)
+}
@@ -0,0 +1,203 @@
+package reports
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportPeriod is the span of history covered by a weekly report
+const ReportPeriod = 7 * 24 * time.Hour
+
+// uncountedNote names the plugins that only derive state from Home Assistant
+// and never record actions, so they never appear in AutomationsByPlugin
+const uncountedNote = "Automations exclude tv, dayphase and statetracking, which only track state"
+
+// WeeklyReport summarizes automation activity over one week
+type WeeklyReport struct {
+	PeriodStart         time.Time      `json:"periodStart"`
+	PeriodEnd           time.Time      `json:"periodEnd"`
+	GeneratedAt         time.Time      `json:"generatedAt"`
+	AutomationsByPlugin map[string]int `json:"automationsByPlugin"`
+	TotalAutomations    int            `json:"totalAutomations"`
+
+	// Nil when the energy meters are not configured or have no usable readings
+	EnergySelfConsumptionPct *float64 `json:"energySelfConsumptionPct"`
+
+	AverageSleepTime string `json:"averageSleepTime,omitempty"` // "HH:MM" local time
+	AverageWakeTime  string `json:"averageWakeTime,omitempty"`  // "HH:MM" local time
+	SleepsTracked    int    `json:"sleepsTracked"`
+	WakesTracked     int    `json:"wakesTracked"`
+
+	DoorbellEvents int `json:"doorbellEvents"`
+
+	Notes []string `json:"notes,omitempty"`
+}
+
+// history is the raw event data a report is built from
+type history struct {
+	automations    map[string][]time.Time // plugin -> action times
+	sleepTimes     []time.Time
+	wakeTimes      []time.Time
+	doorbellEvents []time.Time
+	energy         []energyReading
+	energyEnabled  bool
+	occupancy      map[string]map[time.Time]occupancyCount // room -> local hour start -> samples
+}
+
+// energyReading is a sample of the cumulative energy meters
+type energyReading struct {
+	Timestamp       time.Time
+	SolarProduction float64
+	GridExport      float64
+}
+
+// buildReport builds a report covering (end-ReportPeriod, end] from the given history
+func buildReport(h history, end time.Time, timezone *time.Location) *WeeklyReport {
+	start := end.Add(-ReportPeriod)
+	inPeriod := func(t time.Time) bool {
+		return t.After(start) && !t.After(end)
+	}
+
+	report := &WeeklyReport{
+		PeriodStart:         start,
+		PeriodEnd:           end,
+		GeneratedAt:         end,
+		AutomationsByPlugin: make(map[string]int),
+	}
+
+	for plugin, times := range h.automations {
+		count := 0
+		for _, t := range times {
+			if inPeriod(t) {
+				count++
+			}
+		}
+		if count > 0 {
+			report.AutomationsByPlugin[plugin] = count
+			report.TotalAutomations += count
+		}
+	}
+
+	report.Notes = append(report.Notes, uncountedNote)
+
+	sleeps := filterPeriod(h.sleepTimes, inPeriod)
+	report.SleepsTracked = len(sleeps)
+	if len(sleeps) > 0 {
+		// Bedtimes straddle midnight, so average relative to noon
+		report.AverageSleepTime = averageTimeOfDay(sleeps, timezone, 12*time.Hour)
+	}
+
+	wakes := filterPeriod(h.wakeTimes, inPeriod)
+	report.WakesTracked = len(wakes)
+	if len(wakes) > 0 {
+		report.AverageWakeTime = averageTimeOfDay(wakes, timezone, 0)
+	}
+
+	report.DoorbellEvents = len(filterPeriod(h.doorbellEvents, inPeriod))
+
+	if !h.energyEnabled {
+		report.Notes = append(report.Notes, "Energy self-consumption unavailable: solar production and grid export sensors are not configured")
+	} else if pct, ok := selfConsumption(h.energy, inPeriod); ok {
+		report.EnergySelfConsumptionPct = &pct
+	} else {
+		report.Notes = append(report.Notes, "Energy self-consumption unavailable: not enough meter readings this week")
+	}
+
+	return report
+}
+
+// selfConsumption returns the percentage of solar production used on site
+func selfConsumption(readings []energyReading, inPeriod func(time.Time) bool) (float64, bool) {
+	var first, last *energyReading
+	for i := range readings {
+		if !inPeriod(readings[i].Timestamp) {
+			continue
+		}
+		if first == nil {
+			first = &readings[i]
+		}
+		last = &readings[i]
+	}
+	if first == nil || last == first {
+		return 0, false
+	}
+
+	produced := last.SolarProduction - first.SolarProduction
+	exported := last.GridExport - first.GridExport
+	// Meter resets or no production make the ratio meaningless
+	if produced <= 0 || exported < 0 || exported > produced {
+		return 0, false
+	}
+
+	return (produced - exported) / produced * 100, true
+}
+
+// filterPeriod returns the times accepted by inPeriod
+func filterPeriod(times []time.Time, inPeriod func(time.Time) bool) []time.Time {
+	result := make([]time.Time, 0, len(times))
+	for _, t := range times {
+		if inPeriod(t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// averageTimeOfDay averages clock times, measuring each as an offset from anchor
+// so that times on either side of midnight average sensibly. Returns "HH:MM".
+func averageTimeOfDay(times []time.Time, timezone *time.Location, anchor time.Duration) string {
+	const day = 24 * time.Hour
+
+	var total time.Duration
+	for _, t := range times {
+		local := t.In(timezone)
+		sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+		total += (sinceMidnight - anchor + day) % day
+	}
+
+	avg := (total/time.Duration(len(times)) + anchor) % day
+	return fmt.Sprintf("%02d:%02d", int(avg.Hours()), int(avg.Minutes())%60)
+}
+
+// Summary renders the report as a short notification message
+func (r *WeeklyReport) Summary() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Weekly home automation summary (%s - %s)\n",
+		r.PeriodStart.Format("Jan 2"), r.PeriodEnd.Format("Jan 2"))
+
+	fmt.Fprintf(&b, "Automations: %d", r.TotalAutomations)
+	if len(r.AutomationsByPlugin) > 0 {
+		plugins := make([]string, 0, len(r.AutomationsByPlugin))
+		for plugin := range r.AutomationsByPlugin {
+			plugins = append(plugins, plugin)
+		}
+		sort.Strings(plugins)
+
+		parts := make([]string, 0, len(plugins))
+		for _, plugin := range plugins {
+			parts = append(parts, fmt.Sprintf("%s %d", plugin, r.AutomationsByPlugin[plugin]))
+		}
+		fmt.Fprintf(&b, " (%s)", strings.Join(parts, ", "))
+	}
+	b.WriteString("\n")
+
+	if r.EnergySelfConsumptionPct != nil {
+		fmt.Fprintf(&b, "Energy self-consumption: %.0f%%\n", *r.EnergySelfConsumptionPct)
+	} else {
+		b.WriteString("Energy self-consumption: unknown\n")
+	}
+
+	if r.AverageSleepTime != "" {
+		fmt.Fprintf(&b, "Average bedtime: %s\n", r.AverageSleepTime)
+	}
+	if r.AverageWakeTime != "" {
+		fmt.Fprintf(&b, "Average wake time: %s\n", r.AverageWakeTime)
+	}
+
+	fmt.Fprintf(&b, "Doorbell rings: %d", r.DoorbellEvents)
+
+	return b.String()
+}
@@ -0,0 +1,159 @@
+package reports
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildReport_CountsOnlyEventsInPeriod(t *testing.T) {
+	end := time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC)
+	inside := end.Add(-24 * time.Hour)
+	outside := end.Add(-8 * 24 * time.Hour)
+
+	h := history{
+		automations: map[string][]time.Time{
+			"lighting": {inside, inside.Add(time.Hour), outside},
+			"security": {outside},
+		},
+		doorbellEvents: []time.Time{inside, outside},
+	}
+
+	report := buildReport(h, end, time.UTC)
+
+	assert.Equal(t, end.Add(-ReportPeriod), report.PeriodStart)
+	assert.Equal(t, end, report.PeriodEnd)
+	assert.Equal(t, map[string]int{"lighting": 2}, report.AutomationsByPlugin)
+	assert.Equal(t, 2, report.TotalAutomations)
+	assert.Equal(t, 1, report.DoorbellEvents)
+}
+
+func TestBuildReport_AverageSleepAndWakeTimes(t *testing.T) {
+	end := time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC)
+
+	h := history{
+		automations: map[string][]time.Time{},
+		// 23:30 and 00:30 average to midnight, not noon
+		sleepTimes: []time.Time{
+			time.Date(2025, 6, 12, 23, 30, 0, 0, time.UTC),
+			time.Date(2025, 6, 14, 0, 30, 0, 0, time.UTC),
+		},
+		wakeTimes: []time.Time{
+			time.Date(2025, 6, 13, 6, 30, 0, 0, time.UTC),
+			time.Date(2025, 6, 14, 7, 30, 0, 0, time.UTC),
+		},
+	}
+
+	report := buildReport(h, end, time.UTC)
+
+	assert.Equal(t, "00:00", report.AverageSleepTime)
+	assert.Equal(t, "07:00", report.AverageWakeTime)
+	assert.Equal(t, 2, report.SleepsTracked)
+	assert.Equal(t, 2, report.WakesTracked)
+}
+
+func TestBuildReport_UsesTimezoneForTimesOfDay(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+
+	end := time.Date(2025, 6, 15, 18, 0, 0, 0, la)
+	h := history{
+		automations: map[string][]time.Time{},
+		wakeTimes:   []time.Time{time.Date(2025, 6, 14, 7, 0, 0, 0, la).UTC()},
+	}
+
+	report := buildReport(h, end, la)
+	assert.Equal(t, "07:00", report.AverageWakeTime)
+}
+
+func TestBuildReport_EnergySelfConsumption(t *testing.T) {
+	end := time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		readings []energyReading
+		expected *float64
+	}{
+		{
+			name:    "sensors not configured",
+			enabled: false,
+		},
+		{
+			name:    "single reading",
+			enabled: true,
+			readings: []energyReading{
+				{Timestamp: end.Add(-time.Hour), SolarProduction: 100, GridExport: 10},
+			},
+		},
+		{
+			name:    "75 percent self-consumed",
+			enabled: true,
+			readings: []energyReading{
+				{Timestamp: end.Add(-6 * 24 * time.Hour), SolarProduction: 1000, GridExport: 200},
+				{Timestamp: end.Add(-3 * 24 * time.Hour), SolarProduction: 1100, GridExport: 220},
+				{Timestamp: end.Add(-time.Hour), SolarProduction: 1200, GridExport: 250},
+			},
+			expected: func() *float64 { v := 75.0; return &v }(),
+		},
+		{
+			name:    "meter reset",
+			enabled: true,
+			readings: []energyReading{
+				{Timestamp: end.Add(-6 * 24 * time.Hour), SolarProduction: 1000, GridExport: 200},
+				{Timestamp: end.Add(-time.Hour), SolarProduction: 50, GridExport: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := history{automations: map[string][]time.Time{}, energy: tt.readings, energyEnabled: tt.enabled}
+			report := buildReport(h, end, time.UTC)
+
+			if tt.expected == nil {
+				assert.Nil(t, report.EnergySelfConsumptionPct)
+				assert.Contains(t, strings.Join(report.Notes, "\n"), "Energy self-consumption unavailable", "missing self-consumption should be explained")
+				return
+			}
+			require.NotNil(t, report.EnergySelfConsumptionPct)
+			assert.InDelta(t, *tt.expected, *report.EnergySelfConsumptionPct, 0.001)
+		})
+	}
+}
+
+func TestWeeklyReportSummary(t *testing.T) {
+	pct := 82.4
+	report := &WeeklyReport{
+		PeriodStart:              time.Date(2025, 6, 8, 18, 0, 0, 0, time.UTC),
+		PeriodEnd:                time.Date(2025, 6, 15, 18, 0, 0, 0, time.UTC),
+		AutomationsByPlugin:      map[string]int{"security": 3, "lighting": 12},
+		TotalAutomations:         15,
+		EnergySelfConsumptionPct: &pct,
+		AverageSleepTime:         "23:15",
+		AverageWakeTime:          "06:45",
+		DoorbellEvents:           4,
+	}
+
+	summary := report.Summary()
+
+	assert.Contains(t, summary, "Jun 8 - Jun 15")
+	assert.Contains(t, summary, "Automations: 15 (lighting 12, security 3)")
+	assert.Contains(t, summary, "Energy self-consumption: 82%")
+	assert.Contains(t, summary, "Average bedtime: 23:15")
+	assert.Contains(t, summary, "Average wake time: 06:45")
+	assert.True(t, strings.HasSuffix(summary, "Doorbell rings: 4"))
+}
+
+func TestWeeklyReportSummary_UnknownEnergy(t *testing.T) {
+	report := &WeeklyReport{AutomationsByPlugin: map[string]int{}}
+
+	summary := report.Summary()
+
+	assert.Contains(t, summary, "Automations: 0\n")
+	assert.Contains(t, summary, "Energy self-consumption: unknown")
+	assert.NotContains(t, summary, "bedtime")
+}
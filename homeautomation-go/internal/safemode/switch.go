@@ -0,0 +1,114 @@
+// Package safemode shares whether the house is running on a low battery with
+// the grid down. The energy plugin turns safe mode on and off; while it is on,
+// non-essential plugins are suspended, decorative lights stay off and
+// periodic evaluation loops run less often, so the battery lasts for presence,
+// security and safety automations.
+package safemode
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Status describes the current safe mode
+type Status struct {
+	Active         bool
+	Reason         string
+	SuspendPlugins []string // Lifecycle plugin names stopped while active
+	SlowdownFactor int      // Evaluation loops run every Nth tick while active
+	Since          time.Time
+}
+
+// Switch holds the current safe mode and notifies listeners when it changes.
+// All methods are safe to call on a nil Switch, which is never active.
+type Switch struct {
+	mu        sync.Mutex
+	status    Status
+	listeners []func(Status)
+}
+
+// NewSwitch creates a switch with safe mode off
+func NewSwitch() *Switch {
+	return &Switch{}
+}
+
+// Active reports whether safe mode is on
+func (s *Switch) Active() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status.Active
+}
+
+// Status returns the current safe mode
+func (s *Switch) Status() Status {
+	if s == nil {
+		return Status{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.SuspendPlugins = append([]string(nil), status.SuspendPlugins...)
+	return status
+}
+
+// ShouldEvaluate reports whether a periodic loop should evaluate on its nth
+// tick: every tick normally, every SlowdownFactor-th tick in safe mode
+func (s *Switch) ShouldEvaluate(tick int) bool {
+	status := s.Status()
+	if !status.Active || status.SlowdownFactor <= 1 {
+		return true
+	}
+	return tick%status.SlowdownFactor == 0
+}
+
+// OnChange registers fn to be called after safe mode turns on or off
+func (s *Switch) OnChange(fn func(Status)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Enter turns safe mode on, reporting whether it was off. Listeners are
+// called outside the lock so they may read the switch.
+func (s *Switch) Enter(reason string, suspendPlugins []string, slowdownFactor int, now time.Time) bool {
+	return s.set(Status{
+		Active:         true,
+		Reason:         reason,
+		SuspendPlugins: append([]string(nil), suspendPlugins...),
+		SlowdownFactor: slowdownFactor,
+		Since:          now,
+	})
+}
+
+// Exit turns safe mode off, reporting whether it was on
+func (s *Switch) Exit(reason string, now time.Time) bool {
+	return s.set(Status{Reason: reason, Since: now})
+}
+
+// set stores the status and notifies listeners if active changed
+func (s *Switch) set(status Status) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	changed := s.status.Active != status.Active
+	if !changed {
+		s.mu.Unlock()
+		return false
+	}
+	s.status = status
+	listeners := slices.Clone(s.listeners)
+	s.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(status)
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package safemode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwitch_EnterExit(t *testing.T) {
+	s := NewSwitch()
+	now := time.Date(2025, 1, 10, 20, 0, 0, 0, time.UTC)
+
+	var changes []Status
+	s.OnChange(func(status Status) {
+		assert.Equal(t, status.Active, s.Active(), "listeners can read the switch")
+		changes = append(changes, status)
+	})
+
+	assert.True(t, s.Enter("battery low", []string{"music"}, 5, now))
+	assert.False(t, s.Enter("battery lower", []string{"music"}, 5, now), "already on")
+	assert.True(t, s.Active())
+	assert.Equal(t, []string{"music"}, s.Status().SuspendPlugins)
+	assert.Equal(t, now, s.Status().Since)
+
+	assert.True(t, s.Exit("grid available", now.Add(time.Hour)))
+	assert.False(t, s.Exit("grid available", now.Add(time.Hour)), "already off")
+	assert.False(t, s.Active())
+
+	if assert.Len(t, changes, 2) {
+		assert.True(t, changes[0].Active)
+		assert.Equal(t, "battery low", changes[0].Reason)
+		assert.False(t, changes[1].Active)
+	}
+}
+
+func TestSwitch_ShouldEvaluate(t *testing.T) {
+	s := NewSwitch()
+	for tick := 1; tick <= 3; tick++ {
+		assert.True(t, s.ShouldEvaluate(tick), "every tick evaluates outside safe mode")
+	}
+
+	s.Enter("battery low", nil, 3, time.Now())
+	var evaluated []int
+	for tick := 1; tick <= 7; tick++ {
+		if s.ShouldEvaluate(tick) {
+			evaluated = append(evaluated, tick)
+		}
+	}
+	assert.Equal(t, []int{3, 6}, evaluated)
+}
+
+func TestSwitch_Nil(t *testing.T) {
+	var s *Switch
+	assert.False(t, s.Active())
+	assert.True(t, s.ShouldEvaluate(1))
+	assert.False(t, s.Enter("battery low", nil, 5, time.Now()))
+	s.OnChange(func(Status) {})
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds the search for the next cron time, so a schedule
+// that never fires (e.g. "0 0 30 2 *") is detected instead of looping
+const maxSearchYears = 5
+
+// CronSchedule is a parsed five-field cron expression:
+// minute hour day-of-month month day-of-week
+type CronSchedule struct {
+	expression string
+	minutes    [60]bool
+	hours      [24]bool
+	days       [32]bool // 1-31
+	months     [13]bool // 1-12
+	weekdays   [7]bool  // 0-6, Sunday is 0
+	anyDay     bool     // Day-of-month field is "*"
+	anyWeekday bool     // Day-of-week field is "*"
+}
+
+// cronField describes the allowed range of one cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 7 is also Sunday
+}
+
+// ParseCron parses a five-field cron expression. Each field is "*", a value,
+// a range "a-b", or a comma-separated list of those, optionally with a step
+// ("*/15", "8-18/2").
+func ParseCron(expression string) (*CronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q: expected 5 fields (minute hour day month weekday), got %d", expression, len(fields))
+	}
+
+	s := &CronSchedule{
+		expression: expression,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+
+	var values [5][]int
+	for i, field := range fields {
+		parsed, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expression, err)
+		}
+		values[i] = parsed
+	}
+
+	for _, v := range values[0] {
+		s.minutes[v] = true
+	}
+	for _, v := range values[1] {
+		s.hours[v] = true
+	}
+	for _, v := range values[2] {
+		s.days[v] = true
+	}
+	for _, v := range values[3] {
+		s.months[v] = true
+	}
+	for _, v := range values[4] {
+		s.weekdays[v%7] = true
+	}
+
+	return s, nil
+}
+
+// parseCronField returns the values a field matches
+func parseCronField(field string, f cronField) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%s: invalid step %q", f.name, stepPart)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseCronValue(from, f); err != nil {
+				return nil, err
+			}
+			if high, err = parseCronValue(to, f); err != nil {
+				return nil, err
+			}
+			if low > high {
+				return nil, fmt.Errorf("%s: invalid range %q", f.name, rangePart)
+			}
+		default:
+			value, err := parseCronValue(rangePart, f)
+			if err != nil {
+				return nil, err
+			}
+			low = value
+			if !hasStep {
+				high = value
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, f cronField) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", f.name, value, f.min, f.max)
+	}
+	return n, nil
+}
+
+// String returns the cron expression
+func (s *CronSchedule) String() string {
+	return s.expression
+}
+
+// dayMatches applies cron's day rule: when both day fields are restricted,
+// either may match
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Next returns the first time strictly after after at which the schedule
+// fires, as wall-clock time in after's location, or the zero time if it
+// never does. On a daylight saving change a skipped time fires at the
+// moment clocks jump forward and the repeated hour is not run a second time.
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	limit := after.AddDate(maxSearchYears, 0, 0)
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		if !s.months[t.Month()] || !s.dayMatches(t) {
+			year, month, day := t.Date()
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hours[t.Hour()] && s.minutes[t.Minute()] {
+			return t
+		}
+
+		next := t.Add(time.Minute)
+		if next.Day() == t.Day() {
+			wall, nextWall := minuteOfDay(t), minuteOfDay(next)
+			switch {
+			case nextWall < wall:
+				// Clocks went back: skip past the repeated wall times
+				next = next.Add(time.Duration(wall-nextWall+1) * time.Minute)
+			case nextWall > wall+1:
+				// Clocks jumped forward: skipped times fire at the jump
+				for m := wall + 1; m < nextWall; m++ {
+					if s.hours[m/60] && s.minutes[m%60] {
+						return next
+					}
+				}
+			}
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("ParseCron(%q) should fail", expression)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Monday 2025-06-16 10:07 UTC
+	from := time.Date(2025, 6, 16, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2025, 6, 16, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 6, 16, 10, 15, 0, 0, time.UTC)},
+		{"0 22 * * *", time.Date(2025, 6, 16, 22, 0, 0, 0, time.UTC)},
+		{"30 7 * * *", time.Date(2025, 6, 17, 7, 30, 0, 0, time.UTC)},
+		{"0 9 * * 6,0", time.Date(2025, 6, 21, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2025, 6, 22, 9, 0, 0, 0, time.UTC)},
+		{"0 8-18/4 * * 1-5", time.Date(2025, 6, 16, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches, so Friday the 20th comes first
+		{"0 0 25 * 5", time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expression)
+			if err != nil {
+				t.Fatalf("ParseCron failed: %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduleNext_DaylightSaving(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("America/Chicago not available: %v", err)
+	}
+
+	// 02:30 is skipped on 2024-03-10 and fires when clocks jump to 03:00 CDT
+	skipped, _ := ParseCron("30 2 * * *")
+	got := skipped.Next(time.Date(2024, 3, 10, 0, 0, 0, 0, chicago))
+	if expected := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC); !got.Equal(expected) {
+		t.Errorf("Skipped time: expected %v, got %v", expected, got.UTC())
+	}
+	if got.Location() != chicago {
+		t.Errorf("Expected America/Chicago, got %v", got.Location())
+	}
+
+	// 01:30 happens twice on 2024-11-03 and only fires the first time
+	repeated, _ := ParseCron("30 1 * * *")
+	first := repeated.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, chicago))
+	if expected := time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC); !first.Equal(expected) {
+		t.Errorf("Repeated time: expected %v, got %v", expected, first.UTC())
+	}
+	if second := repeated.Next(first); !second.Equal(time.Date(2024, 11, 4, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("Repeated time should not fire again until the next day, got %v", second.UTC())
+	}
+}
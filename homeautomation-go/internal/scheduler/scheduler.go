@@ -0,0 +1,298 @@
+// Package scheduler runs named jobs on cron schedules, at an offset from a
+// sun event, or once at a given time. One scheduler is shared by every
+// plugin, so all timed automations are listed in one place and follow the
+// same time zone and daylight saving rules.
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+
+	"github.com/sixdouglas/suncalc"
+	"go.uber.org/zap"
+)
+
+// Kind is how a job's times are computed
+type Kind string
+
+const (
+	KindCron Kind = "cron"
+	KindSun  Kind = "sun"
+	KindOnce Kind = "once"
+)
+
+// SunEvent is a sun event a job can be scheduled relative to
+type SunEvent string
+
+const (
+	Dawn      SunEvent = "dawn"
+	Sunrise   SunEvent = "sunrise"
+	SolarNoon SunEvent = "solarNoon"
+	Sunset    SunEvent = "sunset"
+	Dusk      SunEvent = "dusk"
+)
+
+// sunEvents maps sun events to their suncalc names
+var sunEvents = map[SunEvent]suncalc.DayTimeName{
+	Dawn:      suncalc.Dawn,
+	Sunrise:   suncalc.Sunrise,
+	SolarNoon: suncalc.SolarNoon,
+	Sunset:    suncalc.Sunset,
+	Dusk:      suncalc.Dusk,
+}
+
+// ParseSunEvent validates a sun event name
+func ParseSunEvent(name string) (SunEvent, error) {
+	event := SunEvent(name)
+	if _, ok := sunEvents[event]; !ok {
+		return "", fmt.Errorf("unknown sun event %q (expected dawn, sunrise, solarNoon, sunset or dusk)", name)
+	}
+	return event, nil
+}
+
+// SunSpec describes a sun event plus offset, e.g. "sunset-30m0s"
+func SunSpec(event SunEvent, offset time.Duration) string {
+	switch {
+	case offset > 0:
+		return string(event) + "+" + offset.String()
+	case offset < 0:
+		return string(event) + offset.String()
+	}
+	return string(event)
+}
+
+// Job describes a scheduled job
+type Job struct {
+	Name string    `json:"name"`
+	Kind Kind      `json:"kind"`
+	Spec string    `json:"spec"` // Cron expression, "sunset-30m", or the one-shot time
+	Next time.Time `json:"next"`
+}
+
+// job is a scheduled job and its pending timer
+type job struct {
+	Job
+	next  func(after time.Time) time.Time
+	fn    func()
+	timer clock.Timer
+}
+
+// Scheduler runs named jobs. Scheduling a name that is already in use
+// replaces the existing job.
+type Scheduler struct {
+	logger    *zap.Logger
+	clock     clock.Clock
+	timezone  *time.Location
+	latitude  float64
+	longitude float64
+
+	// Jobs by name, and whether Stop was called (protected by mu)
+	jobs    map[string]*job
+	stopped bool
+	mu      sync.Mutex
+}
+
+// New creates a scheduler that evaluates cron and sun times in timezone
+func New(logger *zap.Logger, timezone *time.Location) *Scheduler {
+	// Default to UTC if no timezone provided
+	if timezone == nil {
+		timezone = time.UTC
+	}
+
+	return &Scheduler{
+		logger:   logger.Named("scheduler"),
+		clock:    clock.NewRealClock(),
+		timezone: timezone,
+		jobs:     make(map[string]*job),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetLocation sets the coordinates sun events are calculated for
+func (s *Scheduler) SetLocation(latitude, longitude float64) {
+	s.latitude = latitude
+	s.longitude = longitude
+}
+
+// Cron runs fn at every time matching a five-field cron expression
+func (s *Scheduler) Cron(name, expression string, fn func()) error {
+	schedule, err := ParseCron(expression)
+	if err != nil {
+		return err
+	}
+	return s.add(&job{
+		Job:  Job{Name: name, Kind: KindCron, Spec: expression},
+		next: func(after time.Time) time.Time { return schedule.Next(after.In(s.timezone)) },
+		fn:   fn,
+	})
+}
+
+// Sun runs fn every day at a sun event plus offset, e.g. 30 minutes before
+// sunset with an offset of -30m. Days when the event doesn't happen, such as
+// polar summer, are skipped.
+func (s *Scheduler) Sun(name string, event SunEvent, offset time.Duration, fn func()) error {
+	if _, ok := sunEvents[event]; !ok {
+		return fmt.Errorf("unknown sun event %q", event)
+	}
+	return s.add(&job{
+		Job:  Job{Name: name, Kind: KindSun, Spec: SunSpec(event, offset)},
+		next: func(after time.Time) time.Time { return s.nextSunEvent(after, event, offset) },
+		fn:   fn,
+	})
+}
+
+// Once runs fn once at the given time. A time already past runs as soon as
+// possible.
+func (s *Scheduler) Once(name string, at time.Time, fn func()) error {
+	fired := false
+	return s.add(&job{
+		Job: Job{Name: name, Kind: KindOnce, Spec: at.In(s.timezone).Format(time.RFC3339)},
+		next: func(after time.Time) time.Time {
+			if fired {
+				return time.Time{}
+			}
+			fired = true
+			if at.Before(after) {
+				return after
+			}
+			return at
+		},
+		fn: fn,
+	})
+}
+
+// Cancel removes a job, reporting whether it was scheduled
+func (s *Scheduler) Cancel(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return false
+	}
+	j.timer.Stop()
+	delete(s.jobs, name)
+	return true
+}
+
+// Next returns when a job next runs
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	return j.Job.Next, true
+}
+
+// Jobs returns every scheduled job, soonest first
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.Job)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		if !jobs[i].Next.Equal(jobs[k].Next) {
+			return jobs[i].Next.Before(jobs[k].Next)
+		}
+		return jobs[i].Name < jobs[k].Name
+	})
+	return jobs
+}
+
+// Stop cancels every job. Jobs scheduled afterwards are ignored.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, j := range s.jobs {
+		j.timer.Stop()
+	}
+	s.jobs = make(map[string]*job)
+	s.stopped = true
+}
+
+// add schedules a job, replacing any job with the same name
+func (s *Scheduler) add(j *job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return nil
+	}
+	if existing, ok := s.jobs[j.Name]; ok {
+		existing.timer.Stop()
+		delete(s.jobs, j.Name)
+	}
+	if !s.arm(j) {
+		return fmt.Errorf("job %s (%s %s) never runs", j.Name, j.Kind, j.Spec)
+	}
+	return nil
+}
+
+// arm starts the timer for a job's next time, reporting false and dropping
+// the job when there is none; callers must hold mu
+func (s *Scheduler) arm(j *job) bool {
+	now := s.clock.Now()
+	next := j.next(now)
+	if next.IsZero() {
+		delete(s.jobs, j.Name)
+		return false
+	}
+
+	j.Job.Next = next
+	j.timer = s.clock.AfterFunc(next.Sub(now), func() { s.run(j) })
+	s.jobs[j.Name] = j
+
+	s.logger.Debug("Scheduled job",
+		zap.String("job", j.Name),
+		zap.String("kind", string(j.Kind)),
+		zap.String("spec", j.Spec),
+		zap.Time("next", next))
+	return true
+}
+
+// run re-arms a job for its following time, then runs it
+func (s *Scheduler) run(j *job) {
+	s.mu.Lock()
+	if s.jobs[j.Name] != j {
+		// Cancelled or replaced after the timer fired
+		s.mu.Unlock()
+		return
+	}
+	s.arm(j)
+	s.mu.Unlock()
+
+	j.fn()
+}
+
+// nextSunEvent returns the first sun event plus offset strictly after after
+func (s *Scheduler) nextSunEvent(after time.Time, event SunEvent, offset time.Duration) time.Time {
+	local := after.In(s.timezone)
+	// Start a day early in case a negative offset moves tomorrow's event into today
+	for day := -1; day <= 366; day++ {
+		noon := time.Date(local.Year(), local.Month(), local.Day()+day, 12, 0, 0, 0, s.timezone)
+		times := suncalc.GetTimes(noon, s.latitude, s.longitude)
+		at := times[sunEvents[event]].Value
+		if at.IsZero() {
+			continue
+		}
+		if at = at.Add(offset).In(s.timezone); at.After(after) {
+			return at
+		}
+	}
+	return time.Time{}
+}
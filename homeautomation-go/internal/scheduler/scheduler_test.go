@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/testclock"
+
+	"github.com/sixdouglas/suncalc"
+	"go.uber.org/zap"
+)
+
+// newTestScheduler returns a scheduler on a mock clock at Monday 2025-06-16 10:07 UTC
+func newTestScheduler(t *testing.T) (*Scheduler, *clock.MockClock) {
+	t.Helper()
+
+	mockClock := clock.NewMockClock(time.Date(2025, 6, 16, 10, 7, 0, 0, time.UTC))
+	s := New(zap.NewNop(), time.UTC)
+	s.SetClock(mockClock)
+	t.Cleanup(s.Stop)
+	return s, mockClock
+}
+
+func TestCron_RunsAndReschedules(t *testing.T) {
+	s, mockClock := newTestScheduler(t)
+
+	runs := 0
+	if err := s.Cron("test/quarter", "*/15 * * * *", func() { runs++ }); err != nil {
+		t.Fatalf("Cron failed: %v", err)
+	}
+
+	next, ok := s.Next("test/quarter")
+	if !ok || !next.Equal(time.Date(2025, 6, 16, 10, 15, 0, 0, time.UTC)) {
+		t.Fatalf("Expected next run at 10:15, got %v (ok=%v)", next, ok)
+	}
+
+	mockClock.Advance(8 * time.Minute)
+	if runs != 1 {
+		t.Fatalf("Expected 1 run at 10:15, got %d", runs)
+	}
+	if next, _ := s.Next("test/quarter"); !next.Equal(time.Date(2025, 6, 16, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected the job to be re-armed for 10:30, got %v", next)
+	}
+
+	mockClock.Advance(15 * time.Minute)
+	if runs != 2 {
+		t.Errorf("Expected 2 runs, got %d", runs)
+	}
+}
+
+// A virtual clock fires every run a long advance passes, each at its time,
+// where the mock clock fires a re-armed job once per Advance
+func TestCron_RunsThroughTheDay(t *testing.T) {
+	clk := testclock.New(time.Date(2025, 6, 16, 10, 7, 0, 0, time.UTC))
+	s := New(zap.NewNop(), time.UTC)
+	s.SetClock(clk)
+	t.Cleanup(s.Stop)
+
+	var runs []time.Time
+	if err := s.Cron("test/quarter", "*/15 * * * *", func() { runs = append(runs, clk.Now()) }); err != nil {
+		t.Fatalf("Cron failed: %v", err)
+	}
+
+	clk.Advance(24 * time.Hour)
+	if len(runs) != 96 {
+		t.Fatalf("Expected 96 runs in a day, got %d", len(runs))
+	}
+	if first := runs[0]; !first.Equal(time.Date(2025, 6, 16, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("Expected the first run at 10:15, got %v", first)
+	}
+	if last := runs[95]; !last.Equal(time.Date(2025, 6, 17, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the last run at 10:00 the next day, got %v", last)
+	}
+}
+
+func TestCron_InvalidExpression(t *testing.T) {
+	s, _ := newTestScheduler(t)
+
+	if err := s.Cron("test/bad", "61 * * * *", func() {}); err == nil {
+		t.Error("Expected an invalid cron expression to fail")
+	}
+	if _, ok := s.Next("test/bad"); ok {
+		t.Error("A job that failed to schedule should not be listed")
+	}
+}
+
+func TestOnce_RunsOnce(t *testing.T) {
+	s, mockClock := newTestScheduler(t)
+
+	runs := 0
+	if err := s.Once("test/once", mockClock.Now().Add(time.Hour), func() { runs++ }); err != nil {
+		t.Fatalf("Once failed: %v", err)
+	}
+
+	mockClock.Advance(time.Hour)
+	mockClock.Advance(24 * time.Hour)
+	if runs != 1 {
+		t.Errorf("Expected 1 run, got %d", runs)
+	}
+	if _, ok := s.Next("test/once"); ok {
+		t.Error("A one-shot job should be removed after it runs")
+	}
+}
+
+func TestSun_OffsetFromSunset(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("America/Chicago not available: %v", err)
+	}
+
+	s, _ := newTestScheduler(t)
+	s.timezone = chicago
+	s.SetLocation(41.88, -87.63)
+
+	if err := s.Sun("test/sunset", Sunset, -30*time.Minute, func() {}); err != nil {
+		t.Fatalf("Sun failed: %v", err)
+	}
+
+	sunset := suncalc.GetTimes(time.Date(2025, 6, 16, 12, 0, 0, 0, chicago), 41.88, -87.63)[suncalc.Sunset].Value
+	next, _ := s.Next("test/sunset")
+	if !next.Equal(sunset.Add(-30 * time.Minute)) {
+		t.Errorf("Expected 30 minutes before sunset (%v), got %v", sunset, next)
+	}
+
+	jobs := s.Jobs()
+	if len(jobs) != 1 || jobs[0].Spec != "sunset-30m0s" || jobs[0].Kind != KindSun {
+		t.Errorf("Unexpected jobs %+v", jobs)
+	}
+}
+
+func TestSun_SkipsDaysWithoutEvent(t *testing.T) {
+	s, _ := newTestScheduler(t)
+	// Longyearbyen has midnight sun from April to August
+	s.SetLocation(78.22, 15.65)
+
+	if err := s.Sun("test/sunset", Sunset, 0, func() {}); err != nil {
+		t.Fatalf("Sun failed: %v", err)
+	}
+
+	next, _ := s.Next("test/sunset")
+	if next.Month() < time.August {
+		t.Errorf("Expected the first sunset after the midnight sun, got %v", next)
+	}
+}
+
+func TestCancelAndReplace(t *testing.T) {
+	s, mockClock := newTestScheduler(t)
+
+	first, second := 0, 0
+	_ = s.Cron("test/job", "* * * * *", func() { first++ })
+	_ = s.Cron("test/job", "* * * * *", func() { second++ })
+
+	mockClock.Advance(time.Minute)
+	if first != 0 || second != 1 {
+		t.Errorf("Expected only the replacement to run, got first=%d second=%d", first, second)
+	}
+
+	if !s.Cancel("test/job") {
+		t.Fatal("Expected Cancel to report the job was scheduled")
+	}
+	mockClock.Advance(time.Minute)
+	if second != 1 {
+		t.Errorf("Expected a cancelled job not to run, got %d runs", second)
+	}
+	if s.Cancel("test/job") {
+		t.Error("Expected Cancel of an unknown job to report false")
+	}
+}
+
+func TestJobs_SoonestFirst(t *testing.T) {
+	s, _ := newTestScheduler(t)
+
+	_ = s.Cron("test/nightly", "0 22 * * *", func() {})
+	_ = s.Cron("test/hourly", "0 * * * *", func() {})
+
+	jobs := s.Jobs()
+	if len(jobs) != 2 || jobs[0].Name != "test/hourly" || jobs[1].Name != "test/nightly" {
+		t.Errorf("Expected jobs soonest first, got %+v", jobs)
+	}
+
+	s.Stop()
+	if len(s.Jobs()) != 0 {
+		t.Error("Expected Stop to cancel every job")
+	}
+	_ = s.Cron("test/hourly", "0 * * * *", func() {})
+	if len(s.Jobs()) != 0 {
+		t.Error("Expected jobs scheduled after Stop to be ignored")
+	}
+}
@@ -0,0 +1,74 @@
+package shadowstate
+
+import (
+	"sync"
+	"time"
+)
+
+// ActionEvent is one action a plugin took
+type ActionEvent struct {
+	Plugin    string
+	Timestamp time.Time
+	Reason    string
+}
+
+// ActionListener is called each time a plugin records an action
+type ActionListener func(at time.Time, reason string)
+
+// ActionSource is implemented by plugins that report each action as it is
+// recorded, rather than only their latest one in their shadow state
+type ActionSource interface {
+	OnAction(fn ActionListener)
+}
+
+// ActionNotifier calls listeners for every action a plugin tracker records.
+// Trackers embed it and notify with their own lock held, so listeners must
+// not call back into the tracker.
+type ActionNotifier struct {
+	mu        sync.RWMutex
+	listeners []ActionListener
+}
+
+// OnAction adds a listener for recorded actions
+func (n *ActionNotifier) OnAction(fn ActionListener) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.listeners = append(n.listeners, fn)
+}
+
+// NotifyAction calls every listener with an action
+func (n *ActionNotifier) NotifyAction(at time.Time, reason string) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for _, fn := range n.listeners {
+		fn(at, reason)
+	}
+}
+
+// RecordAction passes a plugin's action to every action subscriber
+func (t *Tracker) RecordAction(plugin string, at time.Time, reason string) {
+	event := ActionEvent{Plugin: plugin, Timestamp: at, Reason: reason}
+
+	t.actionMu.RLock()
+	defer t.actionMu.RUnlock()
+	for _, fn := range t.actionSubscribers {
+		fn(event)
+	}
+}
+
+// SubscribeActions calls fn for every action any plugin records until the
+// returned function is called
+func (t *Tracker) SubscribeActions(fn func(ActionEvent)) func() {
+	t.actionMu.Lock()
+	defer t.actionMu.Unlock()
+
+	id := t.nextActionID
+	t.nextActionID++
+	t.actionSubscribers[id] = fn
+
+	return func() {
+		t.actionMu.Lock()
+		defer t.actionMu.Unlock()
+		delete(t.actionSubscribers, id)
+	}
+}
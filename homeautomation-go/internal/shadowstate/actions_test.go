@@ -0,0 +1,77 @@
+package shadowstate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackerSubscribeActions(t *testing.T) {
+	tracker := NewTracker()
+	var events []ActionEvent
+	unsubscribe := tracker.SubscribeActions(func(e ActionEvent) {
+		events = append(events, e)
+	})
+
+	// Actions in the same instant are each delivered, unlike LastActionTime
+	at := time.Date(2026, time.October, 17, 22, 0, 0, 0, time.UTC)
+	lighting := NewLightingTracker()
+	lighting.OnAction(func(at time.Time, reason string) {
+		tracker.RecordAction("lighting", at, reason)
+	})
+	lighting.RecordRoomAction("Kitchen", "activate_scene", "Evening", "evening", false)
+	lighting.RecordRoomAction("Office", "turn_off", "Unoccupied", "", true)
+	tracker.RecordAction("rules", at, "Porch light (sunset)")
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if events[0].Plugin != "lighting" || events[0].Reason != "Evening" {
+		t.Errorf("Unexpected first event %+v", events[0])
+	}
+	if events[1].Reason != "Unoccupied" {
+		t.Errorf("Expected second reason Unoccupied, got %q", events[1].Reason)
+	}
+	if events[2] != (ActionEvent{Plugin: "rules", Timestamp: at, Reason: "Porch light (sunset)"}) {
+		t.Errorf("Unexpected third event %+v", events[2])
+	}
+
+	unsubscribe()
+	tracker.RecordAction("rules", at, "Porch light (sunset)")
+	if len(events) != 3 {
+		t.Errorf("Expected no events after unsubscribing, got %d", len(events))
+	}
+}
+
+func TestEnergyAndGrowLightsTrackersNotifyActions(t *testing.T) {
+	at := time.Date(2026, time.October, 17, 22, 0, 0, 0, time.UTC)
+	var reasons []string
+	listener := func(_ time.Time, reason string) {
+		reasons = append(reasons, reason)
+	}
+
+	energy := NewEnergyTracker()
+	energy.OnAction(listener)
+	energy.RecordInverterCommand(at, "Inverter mode backup: High outage risk")
+	energy.RecordFreeEnergyAnnouncement(FreeEnergyAnnouncement{Boundary: "start", SuppressedReason: "quiet_hours", EvaluatedAt: at})
+	energy.RecordFreeEnergyAnnouncement(FreeEnergyAnnouncement{Boundary: "start", Sent: true, EvaluatedAt: at})
+	energy.RecordLevelAnnouncement(EnergyLevelAnnouncement{From: "white", To: "red", Notified: true, EvaluatedAt: at})
+
+	growLights := NewGrowLightsTracker()
+	growLights.OnAction(listener)
+	growLights.RecordFixtureSwitch("Seedlings", true, "in window", at)
+
+	expected := []string{
+		"Inverter mode backup: High outage risk",
+		"Free energy announcement",
+		"Energy level white to red",
+		"Seedlings on: in window",
+	}
+	if len(reasons) != len(expected) {
+		t.Fatalf("Expected %d actions, got %v", len(expected), reasons)
+	}
+	for i, reason := range expected {
+		if reasons[i] != reason {
+			t.Errorf("Action %d: expected %q, got %q", i, reason, reasons[i])
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package shadowstate
 
 import (
+	"context"
 	"fmt"
 
 	"homeautomation/internal/ha"
@@ -38,9 +39,9 @@ func NewInputCaptureHelper(registry *SubscriptionRegistry, haClient ha.HAClient,
 func (h *InputCaptureHelper) CaptureInputs(pluginName string) map[string]interface{} {
 	inputs := make(map[string]interface{})
 
-	// Capture all HA entity subscriptions
+	// Capture all HA entity subscriptions (best effort, bounded by the client's default timeout)
 	for _, entityID := range h.registry.GetHASubscriptions(pluginName) {
-		if state, err := h.haClient.GetState(entityID); err == nil && state != nil {
+		if state, err := h.haClient.GetState(context.Background(), entityID); err == nil && state != nil {
 			inputs[entityID] = state.State
 		}
 	}
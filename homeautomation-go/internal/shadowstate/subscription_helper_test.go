@@ -1,6 +1,7 @@
 package shadowstate
 
 import (
+	"context"
 	"testing"
 
 	"homeautomation/internal/ha"
@@ -38,18 +39,24 @@ func newMockHAClient() *mockHAClient {
 	}
 }
 
-func (m *mockHAClient) Connect() error                     { return nil }
-func (m *mockHAClient) Disconnect() error                  { return nil }
-func (m *mockHAClient) IsConnected() bool                  { return true }
-func (m *mockHAClient) GetAllStates() ([]*ha.State, error) { return nil, nil }
-func (m *mockHAClient) CallService(domain, service string, data map[string]interface{}) error {
+func (m *mockHAClient) Connect(ctx context.Context) error                     { return nil }
+func (m *mockHAClient) Disconnect() error                                     { return nil }
+func (m *mockHAClient) IsConnected() bool                                     { return true }
+func (m *mockHAClient) GetAllStates(ctx context.Context) ([]*ha.State, error) { return nil, nil }
+func (m *mockHAClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	return nil
+}
+func (m *mockHAClient) SetInputBoolean(ctx context.Context, name string, value bool) error {
+	return nil
+}
+func (m *mockHAClient) SetInputNumber(ctx context.Context, name string, value float64) error {
+	return nil
+}
+func (m *mockHAClient) SetInputText(ctx context.Context, name string, value string) error {
 	return nil
 }
-func (m *mockHAClient) SetInputBoolean(name string, value bool) error   { return nil }
-func (m *mockHAClient) SetInputNumber(name string, value float64) error { return nil }
-func (m *mockHAClient) SetInputText(name string, value string) error    { return nil }
 
-func (m *mockHAClient) GetState(entityID string) (*ha.State, error) {
+func (m *mockHAClient) GetState(ctx context.Context, entityID string) (*ha.State, error) {
 	if s, ok := m.states[entityID]; ok {
 		return s, nil
 	}
@@ -0,0 +1,138 @@
+package shadowstate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TestingT is the subset of testing.TB used by the shadow state matchers.
+// It keeps the testing package out of non-test builds.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// ExpectLastAction checks the lastActionType output of a plugin's shadow state
+func ExpectLastAction(t TestingT, state PluginShadowState, actionType string) bool {
+	t.Helper()
+	return ExpectOutput(t, state, "lastActionType", actionType)
+}
+
+// ExpectOutput checks a value in a plugin's outputs. The path uses the JSON
+// field names as served by /api/shadow, separated by dots, e.g.
+// "lockdown.active" or "fadeOutProgress.media_player.bedroom.isActive"; map
+// keys containing dots (such as entity IDs) are matched as a whole, and list
+// elements are addressed by index ("rooms.0.name"). Values are
+// compared after a JSON round trip, so 5 matches 5.0 and a time.Time matches
+// its RFC 3339 form.
+func ExpectOutput(t TestingT, state PluginShadowState, path string, want interface{}) bool {
+	t.Helper()
+
+	outputs, err := toJSONValue(state.GetOutputs())
+	if err != nil {
+		t.Errorf("shadow state outputs cannot be encoded: %v", err)
+		return false
+	}
+	got, ok := lookupPath(outputs, strings.Split(path, "."))
+	if !ok {
+		t.Errorf("shadow state output %q not found", path)
+		return false
+	}
+	return expectEqual(t, "output "+path, got, want)
+}
+
+// ExpectInputSnapshotContains checks that the inputs captured at the last
+// action include each of the given key/value pairs
+func ExpectInputSnapshotContains(t TestingT, state PluginShadowState, want map[string]interface{}) bool {
+	t.Helper()
+	return expectInputsContain(t, "input snapshot", state.GetLastActionInputs(), want)
+}
+
+// ExpectCurrentInputsContain checks that the current inputs include each of
+// the given key/value pairs
+func ExpectCurrentInputsContain(t TestingT, state PluginShadowState, want map[string]interface{}) bool {
+	t.Helper()
+	return expectInputsContain(t, "current inputs", state.GetCurrentInputs(), want)
+}
+
+func expectInputsContain(t TestingT, what string, inputs map[string]interface{}, want map[string]interface{}) bool {
+	t.Helper()
+
+	ok := true
+	for key, wantValue := range want {
+		got, exists := inputs[key]
+		if !exists {
+			t.Errorf("shadow state %s has no %q", what, key)
+			ok = false
+			continue
+		}
+		if !expectEqual(t, what+" "+key, got, wantValue) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func expectEqual(t TestingT, what string, got, want interface{}) bool {
+	t.Helper()
+
+	gotValue, err := toJSONValue(got)
+	if err != nil {
+		t.Errorf("shadow state %s cannot be encoded: %v", what, err)
+		return false
+	}
+	wantValue, err := toJSONValue(want)
+	if err != nil {
+		t.Errorf("expected value for %s cannot be encoded: %v", what, err)
+		return false
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("shadow state %s: expected %v, got %v", what, wantValue, gotValue)
+		return false
+	}
+	return true
+}
+
+// toJSONValue converts v to the generic form encoding/json decodes into
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// lookupPath walks a decoded JSON value. At each object the longest run of
+// segments that names an existing key is tried first, so keys may contain dots.
+func lookupPath(value interface{}, segments []string) (interface{}, bool) {
+	if len(segments) == 0 {
+		return value, true
+	}
+	if list, ok := value.([]interface{}); ok {
+		i, err := strconv.Atoi(segments[0])
+		if err != nil || i < 0 || i >= len(list) {
+			return nil, false
+		}
+		return lookupPath(list[i], segments[1:])
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	for n := len(segments); n > 0; n-- {
+		child, exists := object[strings.Join(segments[:n], ".")]
+		if !exists {
+			continue
+		}
+		if found, ok := lookupPath(child, segments[n:]); ok {
+			return found, true
+		}
+	}
+	return nil, false
+}
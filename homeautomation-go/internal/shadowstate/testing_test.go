@@ -0,0 +1,102 @@
+package shadowstate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingT captures matcher failures so they can be asserted on
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectLastAction(t *testing.T) {
+	lst := NewLoadSheddingTracker()
+	lst.RecordLoadSheddingAction(true, "enable", "Low battery", ThermostatSettings{HoldMode: true})
+
+	ExpectLastAction(t, lst.GetState(), "enable")
+
+	rt := &recordingT{}
+	if ExpectLastAction(rt, lst.GetState(), "disable") {
+		t.Error("Expected mismatch to be reported")
+	}
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "expected disable, got enable") {
+		t.Errorf("Unexpected failure message: %v", rt.errors)
+	}
+
+	// Lighting outputs have no lastActionType
+	rt = &recordingT{}
+	if ExpectLastAction(rt, NewLightingTracker().GetState(), "activate_scene") {
+		t.Error("Expected missing output to be reported")
+	}
+}
+
+func TestExpectOutput(t *testing.T) {
+	st := NewSleepHygieneTracker()
+	st.RecordFadeOutStart("media_player.bedroom", 60)
+	activated := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+	st.UpdateDoNotDisturb("primary", true, activated, time.Time{})
+
+	state := st.GetState()
+	ExpectOutput(t, state, "fadeOutProgress.media_player.bedroom.isActive", true)
+	ExpectOutput(t, state, "fadeOutProgress.media_player.bedroom.startVolume", 60)
+	ExpectOutput(t, state, "doNotDisturb.primary.since", activated)
+
+	tests := []struct {
+		name string
+		path string
+		want interface{}
+	}{
+		{"missing field", "fadeOutProgress.media_player.kitchen.isActive", true},
+		{"wrong value", "fadeOutProgress.media_player.bedroom.startVolume", 30},
+		{"path through scalar", "wakeSequenceStatus.value", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{}
+			if ExpectOutput(rt, state, tt.path, tt.want) || len(rt.errors) == 0 {
+				t.Errorf("Expected %s to fail", tt.path)
+			}
+		})
+	}
+}
+
+func TestExpectOutput_ListIndex(t *testing.T) {
+	st := NewSecurityTracker()
+	st.RecordCameraPrivacy(true, "Owner home", "isAnyOwnerHome", []string{"switch.test_camera_privacy"})
+
+	ExpectOutput(t, st.GetState(), "cameraPrivacy.transitions.0.trigger", "isAnyOwnerHome")
+	ExpectOutput(t, st.GetState(), "cameraPrivacy.switches", []string{"switch.test_camera_privacy"})
+
+	rt := &recordingT{}
+	if ExpectOutput(rt, st.GetState(), "cameraPrivacy.transitions.1.trigger", "isAnyOwnerHome") {
+		t.Error("Expected out of range index to fail")
+	}
+}
+
+func TestExpectInputs(t *testing.T) {
+	lst := NewLoadSheddingTracker()
+	lst.UpdateCurrentInputs(map[string]interface{}{"currentEnergyLevel": "red", "trigger": "reset"})
+	lst.SnapshotInputsForAction()
+	lst.UpdateCurrentInputs(map[string]interface{}{"currentEnergyLevel": "green"})
+
+	state := lst.GetState()
+	ExpectInputSnapshotContains(t, state, map[string]interface{}{"currentEnergyLevel": "red", "trigger": "reset"})
+	ExpectCurrentInputsContain(t, state, map[string]interface{}{"currentEnergyLevel": "green"})
+
+	rt := &recordingT{}
+	if ExpectInputSnapshotContains(rt, state, map[string]interface{}{"currentEnergyLevel": "green", "missing": 1}) {
+		t.Error("Expected mismatches to be reported")
+	}
+	if len(rt.errors) != 2 {
+		t.Errorf("Expected 2 failures, got %v", rt.errors)
+	}
+}
@@ -10,13 +10,19 @@ type Tracker struct {
 	mu             sync.RWMutex
 	pluginStates   map[string]PluginShadowState
 	stateProviders map[string]func() PluginShadowState
+
+	// Action subscribers by ID (protected by actionMu)
+	actionMu          sync.RWMutex
+	actionSubscribers map[int]func(ActionEvent)
+	nextActionID      int
 }
 
 // NewTracker creates a new shadow state tracker
 func NewTracker() *Tracker {
 	return &Tracker{
-		pluginStates:   make(map[string]PluginShadowState),
-		stateProviders: make(map[string]func() PluginShadowState),
+		pluginStates:      make(map[string]PluginShadowState),
+		stateProviders:    make(map[string]func() PluginShadowState),
+		actionSubscribers: make(map[int]func(ActionEvent)),
 	}
 }
 
@@ -34,6 +40,14 @@ func (t *Tracker) RegisterPluginProvider(pluginName string, provider func() Plug
 	t.stateProviders[pluginName] = provider
 }
 
+// UnregisterPluginProvider removes a plugin's shadow state provider, e.g. when
+// the plugin is disabled
+func (t *Tracker) UnregisterPluginProvider(pluginName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.stateProviders, pluginName)
+}
+
 // GetPluginState retrieves a plugin's shadow state
 func (t *Tracker) GetPluginState(pluginName string) (PluginShadowState, bool) {
 	t.mu.RLock()
@@ -74,6 +88,8 @@ func (t *Tracker) GetAllPluginStates() map[string]PluginShadowState {
 
 // LightingTracker manages shadow state specifically for the lighting plugin
 type LightingTracker struct {
+	ActionNotifier
+
 	mu    sync.RWMutex
 	state *LightingShadowState
 }
@@ -110,19 +126,91 @@ func (lt *LightingTracker) SnapshotInputsForAction() {
 
 // RecordRoomAction records an action taken on a room
 func (lt *LightingTracker) RecordRoomAction(roomName string, actionType string, reason string, activeScene string, turnedOff bool) {
+	lt.RecordRoomActionDetail(roomName, actionType, reason, activeScene, turnedOff, RoomActionDetail{})
+}
+
+// RecordRoomActionDetail records an action taken on a room along with what
+// was sent to Home Assistant and the conditions that led to it
+func (lt *LightingTracker) RecordRoomActionDetail(roomName string, actionType string, reason string, activeScene string, turnedOff bool, detail RoomActionDetail) {
 	lt.mu.Lock()
 	defer lt.mu.Unlock()
 
 	now := time.Now()
 	lt.state.Outputs.Rooms[roomName] = RoomState{
-		ActiveScene: activeScene,
-		TurnedOff:   turnedOff,
-		LastAction:  now,
-		ActionType:  actionType,
-		Reason:      reason,
+		ActiveScene:      activeScene,
+		TurnedOff:        turnedOff,
+		LastAction:       now,
+		ActionType:       actionType,
+		Reason:           reason,
+		RoomActionDetail: detail.clone(),
 	}
 	lt.state.Outputs.LastActionTime = now
 	lt.state.Metadata.LastUpdated = now
+	lt.NotifyAction(now, reason)
+}
+
+// UpdateOnBudget records a room's daily on-time budget usage
+func (lt *LightingTracker) UpdateOnBudget(roomName string, budget OnBudgetState) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.state.Outputs.OnBudgets[roomName] = budget
+	lt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RemoveOnBudget drops a room whose on-time budget is no longer configured
+func (lt *LightingTracker) RemoveOnBudget(roomName string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	delete(lt.state.Outputs.OnBudgets, roomName)
+	lt.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateGridOutage updates the grid outage dimming state
+func (lt *LightingTracker) UpdateGridOutage(outage GridOutageDimmingState) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	outage.DecorativeRooms = append([]string(nil), outage.DecorativeRooms...)
+	lt.state.Outputs.GridOutage = &outage
+	lt.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateMotionOverride records a room held in its occupied scene by motion
+func (lt *LightingTracker) UpdateMotionOverride(roomName string, override MotionOverrideState) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.state.Outputs.MotionOverrides[roomName] = override
+	lt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RemoveMotionOverride drops a room handed back from its motion override
+func (lt *LightingTracker) RemoveMotionOverride(roomName string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	delete(lt.state.Outputs.MotionOverrides, roomName)
+	lt.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateManualOverride records a room left alone after a manual change
+func (lt *LightingTracker) UpdateManualOverride(roomName string, override ManualOverrideState) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.state.Outputs.ManualOverrides[roomName] = override
+	lt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RemoveManualOverride drops a room whose manual change is no longer respected
+func (lt *LightingTracker) RemoveManualOverride(roomName string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	delete(lt.state.Outputs.ManualOverrides, roomName)
+	lt.state.Metadata.LastUpdated = time.Now()
 }
 
 // GetState returns the current shadow state (thread-safe copy)
@@ -138,8 +226,11 @@ func (lt *LightingTracker) GetState() *LightingShadowState {
 			AtLastAction: make(map[string]interface{}),
 		},
 		Outputs: LightingOutputs{
-			Rooms:          make(map[string]RoomState),
-			LastActionTime: lt.state.Outputs.LastActionTime,
+			Rooms:           make(map[string]RoomState),
+			OnBudgets:       make(map[string]OnBudgetState),
+			MotionOverrides: make(map[string]MotionOverrideState),
+			ManualOverrides: make(map[string]ManualOverrideState),
+			LastActionTime:  lt.state.Outputs.LastActionTime,
 		},
 		Metadata: lt.state.Metadata,
 	}
@@ -156,14 +247,35 @@ func (lt *LightingTracker) GetState() *LightingShadowState {
 
 	// Copy room states
 	for k, v := range lt.state.Outputs.Rooms {
+		v.RoomActionDetail = v.RoomActionDetail.clone()
 		stateCopy.Outputs.Rooms[k] = v
 	}
 
+	for k, v := range lt.state.Outputs.OnBudgets {
+		stateCopy.Outputs.OnBudgets[k] = v
+	}
+
+	for k, v := range lt.state.Outputs.MotionOverrides {
+		stateCopy.Outputs.MotionOverrides[k] = v
+	}
+
+	for k, v := range lt.state.Outputs.ManualOverrides {
+		stateCopy.Outputs.ManualOverrides[k] = v
+	}
+
+	if outage := lt.state.Outputs.GridOutage; outage != nil {
+		outageCopy := *outage
+		outageCopy.DecorativeRooms = append([]string(nil), outage.DecorativeRooms...)
+		stateCopy.Outputs.GridOutage = &outageCopy
+	}
+
 	return stateCopy
 }
 
 // SecurityTracker manages shadow state specifically for the security plugin
 type SecurityTracker struct {
+	ActionNotifier
+
 	mu    sync.RWMutex
 	state *SecurityShadowState
 }
@@ -217,6 +329,27 @@ func (st *SecurityTracker) RecordLockdownAction(active bool, reason string) {
 
 	st.state.Outputs.LastActionTime = now
 	st.state.Metadata.LastUpdated = now
+	st.NotifyAction(now, reason)
+}
+
+// RecordLockdownCues records the light and speaker cues applied when lockdown activated
+func (st *SecurityTracker) RecordLockdownCues(cuedLights []string, pausedSpeakers []string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.Lockdown.CuedLights = append([]string(nil), cuedLights...)
+	st.state.Outputs.Lockdown.PausedSpeakers = append([]string(nil), pausedSpeakers...)
+	st.state.Outputs.Lockdown.LightsRestored = false
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordLockdownLightsRestored records that lighting was restored after lockdown cleared
+func (st *SecurityTracker) RecordLockdownLightsRestored() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.Lockdown.LightsRestored = true
+	st.state.Metadata.LastUpdated = time.Now()
 }
 
 // RecordDoorbellEvent records a doorbell press event
@@ -233,6 +366,7 @@ func (st *SecurityTracker) RecordDoorbellEvent(rateLimited bool, ttsSent bool, l
 	}
 	st.state.Outputs.LastActionTime = now
 	st.state.Metadata.LastUpdated = now
+	st.NotifyAction(now, "Doorbell")
 }
 
 // RecordVehicleArrivalEvent records a vehicle arrival event
@@ -249,6 +383,7 @@ func (st *SecurityTracker) RecordVehicleArrivalEvent(rateLimited bool, ttsSent b
 	}
 	st.state.Outputs.LastActionTime = now
 	st.state.Metadata.LastUpdated = now
+	st.NotifyAction(now, "Vehicle arrival")
 }
 
 // RecordGarageOpenEvent records a garage auto-open event
@@ -264,6 +399,182 @@ func (st *SecurityTracker) RecordGarageOpenEvent(reason string, garageWasEmpty b
 	}
 	st.state.Outputs.LastActionTime = now
 	st.state.Metadata.LastUpdated = now
+	st.NotifyAction(now, reason)
+}
+
+// RecordCameraPrivacy records a change of indoor camera privacy mode, keeping
+// the most recent MaxCameraPrivacyTransitions transitions
+func (st *SecurityTracker) RecordCameraPrivacy(enabled bool, reason string, trigger string, switches []string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	privacy := &st.state.Outputs.CameraPrivacy
+	privacy.Enabled = enabled
+	privacy.Reason = reason
+	privacy.ChangedAt = now
+	privacy.Switches = append([]string(nil), switches...)
+	privacy.Transitions = append(privacy.Transitions, CameraPrivacyTransition{
+		Timestamp: now,
+		Enabled:   enabled,
+		Reason:    reason,
+		Trigger:   trigger,
+	})
+	if len(privacy.Transitions) > MaxCameraPrivacyTransitions {
+		privacy.Transitions = privacy.Transitions[len(privacy.Transitions)-MaxCameraPrivacyTransitions:]
+	}
+
+	st.state.Outputs.LastActionTime = now
+	st.state.Metadata.LastUpdated = now
+	st.NotifyAction(now, reason)
+}
+
+// RecordDrill records the checklist from a supervised drill
+func (st *SecurityTracker) RecordDrill(report DrillReport) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	report.Checks = append([]DrillCheck(nil), report.Checks...)
+	st.state.Outputs.LastDrill = &report
+	st.state.Outputs.LastActionTime = report.FinishedAt
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(report.FinishedAt, "Security drill")
+}
+
+// RecordDoorHeldOpenEvent records a held-open door alarm event, keeping the
+// most recent MaxDoorHeldOpenEvents events
+func (st *SecurityTracker) RecordDoorHeldOpenEvent(event DoorHeldOpenEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	events := append(st.state.Outputs.DoorHeldOpen, event)
+	if len(events) > MaxDoorHeldOpenEvents {
+		events = events[len(events)-MaxDoorHeldOpenEvents:]
+	}
+	st.state.Outputs.DoorHeldOpen = events
+	st.state.Outputs.LastActionTime = event.Timestamp
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(event.Timestamp, "Door held open: "+event.Event)
+}
+
+// UpdateDeliveryWindow records whether the delivery window is open, what
+// opened it, and when the next summary is due
+func (st *SecurityTracker) UpdateDeliveryWindow(active bool, source string, calendarEvent string, nextSummaryAt time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	window := &st.state.Outputs.DeliveryWindow
+	window.Active = active
+	window.Source = source
+	window.CalendarEvent = calendarEvent
+	window.NextSummaryAt = nextSummaryAt
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordDeliveryEvent records a doorbell ring silenced by the delivery
+// window, keeping the most recent MaxPendingDeliveries pending rings
+func (st *SecurityTracker) RecordDeliveryEvent(event DeliveryEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	pending := append(st.state.Outputs.DeliveryWindow.Pending, event)
+	if len(pending) > MaxPendingDeliveries {
+		pending = pending[len(pending)-MaxPendingDeliveries:]
+	}
+	st.state.Outputs.DeliveryWindow.Pending = pending
+	st.state.Outputs.LastDoorbell = &DoorbellEvent{
+		Timestamp:      event.Timestamp,
+		DeliveryWindow: true,
+	}
+	st.state.Outputs.LastActionTime = event.Timestamp
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(event.Timestamp, "Delivery")
+}
+
+// RecordDeliverySummary records the delivery summary announcement and clears
+// the pending rings it covered
+func (st *SecurityTracker) RecordDeliverySummary(summary DeliverySummary) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.DeliveryWindow.Pending = []DeliveryEvent{}
+	st.state.Outputs.DeliveryWindow.LastSummary = &summary
+	st.state.Outputs.LastActionTime = summary.Timestamp
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(summary.Timestamp, summary.Message)
+}
+
+// RecordPersonDetection records a camera person detection, keeping the most
+// recent MaxPersonDetections detections
+func (st *SecurityTracker) RecordPersonDetection(event PersonDetectionEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	events := append(st.state.Outputs.PersonDetections, event)
+	if len(events) > MaxPersonDetections {
+		events = events[len(events)-MaxPersonDetections:]
+	}
+	st.state.Outputs.PersonDetections = events
+	st.state.Outputs.LastActionTime = event.Timestamp
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(event.Timestamp, "Person detected")
+}
+
+// RecordAlarmTransition records the alarm's new mode and status, keeping the
+// most recent MaxAlarmTransitions transitions
+func (st *SecurityTracker) RecordAlarmTransition(alarm AlarmState, transition AlarmTransition) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	transitions := append(st.state.Outputs.Alarm.Transitions, transition)
+	if len(transitions) > MaxAlarmTransitions {
+		transitions = transitions[len(transitions)-MaxAlarmTransitions:]
+	}
+	alarm.Transitions = transitions
+	if alarm.Bypassed == nil {
+		alarm.Bypassed = []string{}
+	}
+	st.state.Outputs.Alarm = alarm
+	st.state.Outputs.LastActionTime = transition.Timestamp
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(transition.Timestamp, transition.Reason)
+}
+
+// RecordLockEvent records the locks' latest status and a lock event, keeping
+// the most recent MaxLockEvents events
+func (st *SecurityTracker) RecordLockEvent(locks []DoorLockStatus, event LockEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	events := append(st.state.Outputs.DoorLocks.Events, event)
+	if len(events) > MaxLockEvents {
+		events = events[len(events)-MaxLockEvents:]
+	}
+	st.state.Outputs.DoorLocks = DoorLocksState{
+		Locks:  locks,
+		Events: events,
+	}
+	st.state.Outputs.LastActionTime = event.Timestamp
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(event.Timestamp, event.Name+" "+event.Event)
+}
+
+// UpdateDoorLocks records the locks' latest status without an audit event
+func (st *SecurityTracker) UpdateDoorLocks(locks []DoorLockStatus) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.DoorLocks.Locks = locks
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateRateLimit records the current state of an event type's notification rate limit
+func (st *SecurityTracker) UpdateRateLimit(eventType string, rateLimit RateLimitState) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.RateLimits[eventType] = rateLimit
+	st.state.Metadata.LastUpdated = time.Now()
 }
 
 // GetState returns the current shadow state (thread-safe copy)
@@ -279,15 +590,47 @@ func (st *SecurityTracker) GetState() *SecurityShadowState {
 			AtLastAction: make(map[string]interface{}),
 		},
 		Outputs: SecurityOutputs{
-			Lockdown:       st.state.Outputs.Lockdown,
-			LastDoorbell:   st.state.Outputs.LastDoorbell,
-			LastVehicle:    st.state.Outputs.LastVehicle,
-			LastGarageOpen: st.state.Outputs.LastGarageOpen,
-			LastActionTime: st.state.Outputs.LastActionTime,
+			Lockdown:         st.state.Outputs.Lockdown,
+			LastDoorbell:     st.state.Outputs.LastDoorbell,
+			LastVehicle:      st.state.Outputs.LastVehicle,
+			LastGarageOpen:   st.state.Outputs.LastGarageOpen,
+			CameraPrivacy:    st.state.Outputs.CameraPrivacy,
+			RateLimits:       make(map[string]RateLimitState, len(st.state.Outputs.RateLimits)),
+			LastDrill:        st.state.Outputs.LastDrill,
+			DoorHeldOpen:     append([]DoorHeldOpenEvent{}, st.state.Outputs.DoorHeldOpen...),
+			DeliveryWindow:   st.state.Outputs.DeliveryWindow,
+			PersonDetections: append([]PersonDetectionEvent{}, st.state.Outputs.PersonDetections...),
+			Alarm:            st.state.Outputs.Alarm,
+			DoorLocks:        st.state.Outputs.DoorLocks,
+			LastActionTime:   st.state.Outputs.LastActionTime,
 		},
 		Metadata: st.state.Metadata,
 	}
 
+	// Copy rate limits
+	for k, v := range st.state.Outputs.RateLimits {
+		stateCopy.Outputs.RateLimits[k] = v
+	}
+
+	// Copy camera privacy slices
+	stateCopy.Outputs.CameraPrivacy.Switches = append([]string(nil), st.state.Outputs.CameraPrivacy.Switches...)
+	stateCopy.Outputs.CameraPrivacy.Transitions = append([]CameraPrivacyTransition{}, st.state.Outputs.CameraPrivacy.Transitions...)
+
+	// Copy pending deliveries
+	stateCopy.Outputs.DeliveryWindow.Pending = append([]DeliveryEvent{}, st.state.Outputs.DeliveryWindow.Pending...)
+
+	// Copy alarm slices
+	stateCopy.Outputs.Alarm.Bypassed = append([]string{}, st.state.Outputs.Alarm.Bypassed...)
+	stateCopy.Outputs.Alarm.Transitions = append([]AlarmTransition{}, st.state.Outputs.Alarm.Transitions...)
+
+	// Copy door lock slices
+	stateCopy.Outputs.DoorLocks.Locks = append([]DoorLockStatus{}, st.state.Outputs.DoorLocks.Locks...)
+	stateCopy.Outputs.DoorLocks.Events = append([]LockEvent{}, st.state.Outputs.DoorLocks.Events...)
+
+	// Copy lockdown cue slices
+	stateCopy.Outputs.Lockdown.CuedLights = append([]string(nil), st.state.Outputs.Lockdown.CuedLights...)
+	stateCopy.Outputs.Lockdown.PausedSpeakers = append([]string(nil), st.state.Outputs.Lockdown.PausedSpeakers...)
+
 	// Copy current inputs
 	for k, v := range st.state.Inputs.Current {
 		stateCopy.Inputs.Current[k] = v
@@ -303,6 +646,8 @@ func (st *SecurityTracker) GetState() *SecurityShadowState {
 
 // LoadSheddingTracker manages shadow state specifically for the load shedding plugin
 type LoadSheddingTracker struct {
+	ActionNotifier
+
 	mu    sync.RWMutex
 	state *LoadSheddingShadowState
 }
@@ -316,6 +661,8 @@ func NewLoadSheddingTracker() *LoadSheddingTracker {
 
 // SleepHygieneTracker manages shadow state specifically for the sleep hygiene plugin
 type SleepHygieneTracker struct {
+	ActionNotifier
+
 	mu    sync.RWMutex
 	state *SleepHygieneShadowState
 }
@@ -362,6 +709,16 @@ func (lst *LoadSheddingTracker) RecordLoadSheddingAction(active bool, actionType
 	lst.state.Outputs.ThermostatSettings = thermostatSettings
 	lst.state.Outputs.LastActionTime = now
 	lst.state.Metadata.LastUpdated = now
+	lst.NotifyAction(now, reason)
+}
+
+// RecordShedDevices records the tiered devices currently shed
+func (lst *LoadSheddingTracker) RecordShedDevices(devices []ShedDevice) {
+	lst.mu.Lock()
+	defer lst.mu.Unlock()
+
+	lst.state.Outputs.ShedDevices = append([]ShedDevice(nil), devices...)
+	lst.state.Metadata.LastUpdated = time.Now()
 }
 
 // GetState returns the current shadow state (thread-safe copy)
@@ -382,6 +739,7 @@ func (lst *LoadSheddingTracker) GetState() *LoadSheddingShadowState {
 			LastActionReason:   lst.state.Outputs.LastActionReason,
 			ThermostatSettings: lst.state.Outputs.ThermostatSettings,
 			LastActionTime:     lst.state.Outputs.LastActionTime,
+			ShedDevices:        append([]ShedDevice(nil), lst.state.Outputs.ShedDevices...),
 		},
 		Metadata: lst.state.Metadata,
 	}
@@ -434,6 +792,7 @@ func (st *SleepHygieneTracker) RecordAction(actionType string, reason string) {
 	st.state.Outputs.LastActionType = actionType
 	st.state.Outputs.LastActionReason = reason
 	st.state.Metadata.LastUpdated = now
+	st.NotifyAction(now, reason)
 }
 
 // UpdateWakeSequenceStatus updates the wake sequence status
@@ -524,6 +883,65 @@ func (st *SleepHygieneTracker) RecordGoToBedReminder() {
 	st.state.Metadata.LastUpdated = time.Now()
 }
 
+// UpdateDoNotDisturb records a bedroom's do-not-disturb toggle
+func (st *SleepHygieneTracker) UpdateDoNotDisturb(bedroom string, active bool, since time.Time, expiresAt time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.DoNotDisturb[bedroom] = DoNotDisturb{
+		Active:    active,
+		Since:     since,
+		ExpiresAt: expiresAt,
+	}
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateAdaptiveWake records the latest adaptive wake computation
+func (st *SleepHygieneTracker) UpdateAdaptiveWake(wake AdaptiveWake) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.AdaptiveWake = &wake
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateWakeLight records the latest wake light profile choice
+func (st *SleepHygieneTracker) UpdateWakeLight(wakeLight WakeLight) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.WakeLight = &wakeLight
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateSonosAlarm records the Sonos alarm's latest sync, keeping the last
+// verification
+func (st *SleepHygieneTracker) UpdateSonosAlarm(alarm SonosAlarm) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if previous := st.state.Outputs.SonosAlarm; previous != nil {
+		alarm.VerifiedAt = previous.VerifiedAt
+		alarm.Problem = previous.Problem
+	}
+	st.state.Outputs.SonosAlarm = &alarm
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordSonosAlarmCheck records a nightly check of the Sonos alarm; problem is
+// empty if it matched
+func (st *SleepHygieneTracker) RecordSonosAlarmCheck(alarmEntity string, at time.Time, problem string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.state.Outputs.SonosAlarm == nil {
+		st.state.Outputs.SonosAlarm = &SonosAlarm{AlarmEntity: alarmEntity}
+	}
+	st.state.Outputs.SonosAlarm.VerifiedAt = at
+	st.state.Outputs.SonosAlarm.Problem = problem
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
 // GetState returns the current shadow state (thread-safe copy)
 func (st *SleepHygieneTracker) GetState() *SleepHygieneShadowState {
 	st.mu.RLock()
@@ -539,6 +957,7 @@ func (st *SleepHygieneTracker) GetState() *SleepHygieneShadowState {
 		Outputs: SleepHygieneOutputs{
 			WakeSequenceStatus: st.state.Outputs.WakeSequenceStatus,
 			FadeOutProgress:    make(map[string]SpeakerFadeOut),
+			DoNotDisturb:       make(map[string]DoNotDisturb),
 			LastActionTime:     st.state.Outputs.LastActionTime,
 			LastActionType:     st.state.Outputs.LastActionType,
 			LastActionReason:   st.state.Outputs.LastActionReason,
@@ -561,6 +980,11 @@ func (st *SleepHygieneTracker) GetState() *SleepHygieneShadowState {
 		stateCopy.Outputs.FadeOutProgress[k] = v
 	}
 
+	// Copy do-not-disturb toggles
+	for k, v := range st.state.Outputs.DoNotDisturb {
+		stateCopy.Outputs.DoNotDisturb[k] = v
+	}
+
 	// Copy TTS announcement if it exists
 	if st.state.Outputs.LastTTSAnnouncement != nil {
 		announcement := *st.state.Outputs.LastTTSAnnouncement
@@ -579,6 +1003,28 @@ func (st *SleepHygieneTracker) GetState() *SleepHygieneShadowState {
 		stateCopy.Outputs.GoToBedReminder = &reminder
 	}
 
+	// Copy adaptive wake if it exists
+	if st.state.Outputs.AdaptiveWake != nil {
+		wake := *st.state.Outputs.AdaptiveWake
+		stateCopy.Outputs.AdaptiveWake = &wake
+	}
+
+	// Copy wake light if it exists
+	if st.state.Outputs.WakeLight != nil {
+		wakeLight := *st.state.Outputs.WakeLight
+		if wakeLight.Lux != nil {
+			lux := *wakeLight.Lux
+			wakeLight.Lux = &lux
+		}
+		stateCopy.Outputs.WakeLight = &wakeLight
+	}
+
+	// Copy Sonos alarm if it exists
+	if st.state.Outputs.SonosAlarm != nil {
+		alarm := *st.state.Outputs.SonosAlarm
+		stateCopy.Outputs.SonosAlarm = &alarm
+	}
+
 	return stateCopy
 }
 
@@ -588,6 +1034,8 @@ func (st *SleepHygieneTracker) GetState() *SleepHygieneShadowState {
 
 // EnergyTracker manages shadow state for the energy plugin
 type EnergyTracker struct {
+	ActionNotifier
+
 	mu    sync.RWMutex
 	state *EnergyShadowState
 }
@@ -703,52 +1151,204 @@ func (et *EnergyTracker) UpdateFreeEnergyAvailable(available bool) {
 	et.state.Metadata.LastUpdated = time.Now()
 }
 
-// GetState returns the current shadow state (thread-safe copy)
-func (et *EnergyTracker) GetState() *EnergyShadowState {
-	et.mu.RLock()
-	defer et.mu.RUnlock()
+// RecordFreeEnergyAnnouncement records a free energy window announcement
+func (et *EnergyTracker) RecordFreeEnergyAnnouncement(announcement FreeEnergyAnnouncement) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
 
-	// Create a deep copy
-	stateCopy := &EnergyShadowState{
-		Plugin: et.state.Plugin,
-		Inputs: EnergyInputs{
-			Current: make(map[string]interface{}),
-		},
-		Outputs: EnergyOutputs{
-			BatteryEnergyLevel:         et.state.Outputs.BatteryEnergyLevel,
-			SolarProductionEnergyLevel: et.state.Outputs.SolarProductionEnergyLevel,
-			CurrentEnergyLevel:         et.state.Outputs.CurrentEnergyLevel,
-			IsFreeEnergyAvailable:      et.state.Outputs.IsFreeEnergyAvailable,
-			LastComputations:           et.state.Outputs.LastComputations,
-			SensorReadings:             et.state.Outputs.SensorReadings,
-		},
-		Metadata: et.state.Metadata,
+	et.state.Outputs.LastFreeEnergyAnnouncement = &announcement
+	et.state.Metadata.LastUpdated = time.Now()
+	if announcement.Sent {
+		et.NotifyAction(announcement.EvaluatedAt, "Free energy announcement")
 	}
+}
 
-	// Copy current inputs
-	for k, v := range et.state.Inputs.Current {
-		stateCopy.Inputs.Current[k] = v
-	}
+// RecordLevelAnnouncement records an energy level change announcement
+func (et *EnergyTracker) RecordLevelAnnouncement(announcement EnergyLevelAnnouncement) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
 
-	return stateCopy
+	et.state.Outputs.LastLevelAnnouncement = &announcement
+	et.state.Metadata.LastUpdated = time.Now()
+	if announcement.Notified || announcement.SpokenLevel != "" {
+		et.NotifyAction(announcement.EvaluatedAt, "Energy level "+announcement.From+" to "+announcement.To)
+	}
 }
 
-// StateTrackingTracker manages shadow state for the state tracking plugin
-type StateTrackingTracker struct {
-	mu    sync.RWMutex
-	state *StateTrackingShadowState
+// RecordInverterCommand records a change sent to the inverter, e.g. a mode
+// switch or a new backup reserve
+func (et *EnergyTracker) RecordInverterCommand(at time.Time, reason string) {
+	et.NotifyAction(at, reason)
 }
 
-// NewStateTrackingTracker creates a new state tracking shadow state tracker
-func NewStateTrackingTracker() *StateTrackingTracker {
-	return &StateTrackingTracker{
-		state: NewStateTrackingShadowState(),
-	}
+// RecordBackupReserveDecision records the latest backup-reserve decision
+func (et *EnergyTracker) RecordBackupReserveDecision(decision BackupReserveDecision) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	decision.Reasons = append([]string(nil), decision.Reasons...)
+	decision.RiskSensorValue = copyFloatPtr(decision.RiskSensorValue)
+	et.state.Outputs.BackupReserve = &decision
+	et.state.Metadata.LastUpdated = time.Now()
 }
 
-// UpdateCurrentInputs updates the current input values
-func (stt *StateTrackingTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
-	stt.mu.Lock()
+// RecordSafeModeDecision records the latest battery safe mode evaluation
+func (et *EnergyTracker) RecordSafeModeDecision(decision SafeModeDecision) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	decision.BatteryPct = copyFloatPtr(decision.BatteryPct)
+	decision.SuspendedPlugins = append([]string(nil), decision.SuspendedPlugins...)
+	et.state.Outputs.SafeMode = &decision
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordForecastLookAheadDecision records the latest solar forecast look-ahead evaluation
+func (et *EnergyTracker) RecordForecastLookAheadDecision(decision ForecastLookAheadDecision) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	et.state.Outputs.ForecastLookAhead = &decision
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordGridPricingDecision records the latest time-of-use price evaluation
+func (et *EnergyTracker) RecordGridPricingDecision(decision GridPricingDecision) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	decision.SensorPrice = copyFloatPtr(decision.SensorPrice)
+	et.state.Outputs.GridPricing = &decision
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordReservePolicyEvaluation records the reserve policy in effect, keeping
+// the transitions recorded so far
+func (et *EnergyTracker) RecordReservePolicyEvaluation(policy string, reservePct float64, reason string, evaluatedAt time.Time) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	current := et.state.Outputs.ReservePolicy
+	if current == nil {
+		current = &ReservePolicyState{Since: evaluatedAt}
+		et.state.Outputs.ReservePolicy = current
+	}
+	current.Policy = policy
+	current.ReservePct = reservePct
+	current.Reason = reason
+	current.EvaluatedAt = evaluatedAt
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordReservePolicyTransition records a change of reserve policy
+func (et *EnergyTracker) RecordReservePolicyTransition(transition ReservePolicyTransition) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	current := et.state.Outputs.ReservePolicy
+	if current == nil {
+		current = &ReservePolicyState{}
+		et.state.Outputs.ReservePolicy = current
+	}
+	current.Policy = transition.To
+	current.ReservePct = transition.ReservePct
+	current.Reason = transition.Reason
+	current.Since = transition.At
+	current.EvaluatedAt = transition.At
+	current.Transitions = append(current.Transitions, transition)
+	if excess := len(current.Transitions) - MaxReservePolicyTransitions; excess > 0 {
+		current.Transitions = append([]ReservePolicyTransition(nil), current.Transitions[excess:]...)
+	}
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (et *EnergyTracker) GetState() *EnergyShadowState {
+	et.mu.RLock()
+	defer et.mu.RUnlock()
+
+	// Create a deep copy
+	stateCopy := &EnergyShadowState{
+		Plugin: et.state.Plugin,
+		Inputs: EnergyInputs{
+			Current: make(map[string]interface{}),
+		},
+		Outputs: EnergyOutputs{
+			BatteryEnergyLevel:         et.state.Outputs.BatteryEnergyLevel,
+			SolarProductionEnergyLevel: et.state.Outputs.SolarProductionEnergyLevel,
+			CurrentEnergyLevel:         et.state.Outputs.CurrentEnergyLevel,
+			IsFreeEnergyAvailable:      et.state.Outputs.IsFreeEnergyAvailable,
+			LastComputations:           et.state.Outputs.LastComputations,
+			SensorReadings:             et.state.Outputs.SensorReadings,
+		},
+		Metadata: et.state.Metadata,
+	}
+
+	// Copy current inputs
+	for k, v := range et.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+
+	if et.state.Outputs.LastFreeEnergyAnnouncement != nil {
+		announcement := *et.state.Outputs.LastFreeEnergyAnnouncement
+		stateCopy.Outputs.LastFreeEnergyAnnouncement = &announcement
+	}
+
+	if et.state.Outputs.BackupReserve != nil {
+		decision := *et.state.Outputs.BackupReserve
+		decision.Reasons = append([]string(nil), decision.Reasons...)
+		decision.RiskSensorValue = copyFloatPtr(decision.RiskSensorValue)
+		stateCopy.Outputs.BackupReserve = &decision
+	}
+
+	if et.state.Outputs.LastLevelAnnouncement != nil {
+		announcement := *et.state.Outputs.LastLevelAnnouncement
+		stateCopy.Outputs.LastLevelAnnouncement = &announcement
+	}
+
+	if et.state.Outputs.SafeMode != nil {
+		decision := *et.state.Outputs.SafeMode
+		decision.BatteryPct = copyFloatPtr(decision.BatteryPct)
+		decision.SuspendedPlugins = append([]string(nil), decision.SuspendedPlugins...)
+		stateCopy.Outputs.SafeMode = &decision
+	}
+
+	if et.state.Outputs.ForecastLookAhead != nil {
+		decision := *et.state.Outputs.ForecastLookAhead
+		stateCopy.Outputs.ForecastLookAhead = &decision
+	}
+
+	if et.state.Outputs.GridPricing != nil {
+		decision := *et.state.Outputs.GridPricing
+		decision.SensorPrice = copyFloatPtr(decision.SensorPrice)
+		stateCopy.Outputs.GridPricing = &decision
+	}
+
+	if et.state.Outputs.ReservePolicy != nil {
+		policy := *et.state.Outputs.ReservePolicy
+		policy.Transitions = append([]ReservePolicyTransition(nil), policy.Transitions...)
+		stateCopy.Outputs.ReservePolicy = &policy
+	}
+
+	return stateCopy
+}
+
+// StateTrackingTracker manages shadow state for the state tracking plugin
+type StateTrackingTracker struct {
+	mu    sync.RWMutex
+	state *StateTrackingShadowState
+}
+
+// NewStateTrackingTracker creates a new state tracking shadow state tracker
+func NewStateTrackingTracker() *StateTrackingTracker {
+	return &StateTrackingTracker{
+		state: NewStateTrackingShadowState(),
+	}
+}
+
+// UpdateCurrentInputs updates the current input values
+func (stt *StateTrackingTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	stt.mu.Lock()
 	defer stt.mu.Unlock()
 
 	for key, value := range inputs {
@@ -825,6 +1425,15 @@ func (stt *StateTrackingTracker) RecordArrivalAnnouncement(person, message strin
 	stt.state.Metadata.LastUpdated = time.Now()
 }
 
+// RecordConsistencyCheck records the result of a derived state consistency check
+func (stt *StateTrackingTracker) RecordConsistencyCheck(check ConsistencyCheck) {
+	stt.mu.Lock()
+	defer stt.mu.Unlock()
+
+	stt.state.Outputs.ConsistencyCheck = &check
+	stt.state.Metadata.LastUpdated = time.Now()
+}
+
 // GetState returns the current shadow state (thread-safe copy)
 func (stt *StateTrackingTracker) GetState() *StateTrackingShadowState {
 	stt.mu.RLock()
@@ -855,6 +1464,23 @@ func (stt *StateTrackingTracker) GetState() *StateTrackingShadowState {
 		stateCopy.Outputs.LastAnnouncement = &announcement
 	}
 
+	// Copy consistency check repairs and their inputs
+	if check := stt.state.Outputs.ConsistencyCheck; check != nil {
+		checkCopy := ConsistencyCheck{
+			CheckedAt: check.CheckedAt,
+			Repairs:   make([]ConsistencyRepair, len(check.Repairs)),
+		}
+		for i, repair := range check.Repairs {
+			repair.Inputs = make(map[string]bool, len(check.Repairs[i].Inputs))
+			for k, v := range check.Repairs[i].Inputs {
+				repair.Inputs[k] = v
+			}
+			checkCopy.Repairs[i] = repair
+		}
+		checkCopy.Failures = append([]ConsistencyFailure(nil), check.Failures...)
+		stateCopy.Outputs.ConsistencyCheck = &checkCopy
+	}
+
 	return stateCopy
 }
 
@@ -912,6 +1538,15 @@ func (dpt *DayPhaseTracker) UpdateNextTransition(transitionTime time.Time, nextP
 	dpt.state.Metadata.LastUpdated = time.Now()
 }
 
+// UpdateTemperatureShift records the applied outdoor-temperature offsets
+func (dpt *DayPhaseTracker) UpdateTemperatureShift(shift TemperatureShift) {
+	dpt.mu.Lock()
+	defer dpt.mu.Unlock()
+
+	dpt.state.Outputs.TemperatureShift = &shift
+	dpt.state.Metadata.LastUpdated = time.Now()
+}
+
 // GetState returns the current shadow state (thread-safe copy)
 func (dpt *DayPhaseTracker) GetState() *DayPhaseShadowState {
 	dpt.mu.RLock()
@@ -932,6 +1567,11 @@ func (dpt *DayPhaseTracker) GetState() *DayPhaseShadowState {
 		stateCopy.Inputs.Current[k] = v
 	}
 
+	if dpt.state.Outputs.TemperatureShift != nil {
+		shift := *dpt.state.Outputs.TemperatureShift
+		stateCopy.Outputs.TemperatureShift = &shift
+	}
+
 	return stateCopy
 }
 
@@ -1022,3 +1662,1503 @@ func (tvt *TVTracker) GetState() *TVShadowState {
 
 	return stateCopy
 }
+
+// GrowLightsTracker manages shadow state for the grow lights plugin
+type GrowLightsTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *GrowLightsShadowState
+}
+
+// NewGrowLightsTracker creates a new grow lights shadow state tracker
+func NewGrowLightsTracker() *GrowLightsTracker {
+	return &GrowLightsTracker{
+		state: NewGrowLightsShadowState(),
+	}
+}
+
+// UpdateCurrentInputs updates the current input values
+func (glt *GrowLightsTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	glt.mu.Lock()
+	defer glt.mu.Unlock()
+
+	for key, value := range inputs {
+		glt.state.Inputs.Current[key] = value
+	}
+	glt.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateEvaluation records the plugin-wide results of a schedule evaluation
+func (glt *GrowLightsTracker) UpdateEvaluation(naturalDayLengthHours float64, deferred bool) {
+	glt.mu.Lock()
+	defer glt.mu.Unlock()
+
+	now := time.Now()
+	glt.state.Outputs.NaturalDayLengthHours = naturalDayLengthHours
+	glt.state.Outputs.Deferred = deferred
+	glt.state.Outputs.LastEvaluation = now
+	glt.state.Metadata.LastUpdated = now
+}
+
+// UpdateFixture records the computed schedule and state of a fixture
+func (glt *GrowLightsTracker) UpdateFixture(name string, fixture GrowLightFixtureState) {
+	glt.mu.Lock()
+	defer glt.mu.Unlock()
+
+	glt.state.Outputs.Fixtures[name] = fixture
+	glt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordFixtureSwitch records a fixture being switched on or off
+func (glt *GrowLightsTracker) RecordFixtureSwitch(name string, on bool, reason string, at time.Time) {
+	state := "off"
+	if on {
+		state = "on"
+	}
+	glt.NotifyAction(at, name+" "+state+": "+reason)
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (glt *GrowLightsTracker) GetState() *GrowLightsShadowState {
+	glt.mu.RLock()
+	defer glt.mu.RUnlock()
+
+	// Create a deep copy
+	stateCopy := &GrowLightsShadowState{
+		Plugin: glt.state.Plugin,
+		Inputs: GrowLightsInputs{
+			Current: make(map[string]interface{}),
+		},
+		Outputs: GrowLightsOutputs{
+			NaturalDayLengthHours: glt.state.Outputs.NaturalDayLengthHours,
+			Deferred:              glt.state.Outputs.Deferred,
+			Fixtures:              make(map[string]GrowLightFixtureState),
+			LastEvaluation:        glt.state.Outputs.LastEvaluation,
+		},
+		Metadata: glt.state.Metadata,
+	}
+
+	// Copy current inputs
+	for k, v := range glt.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+
+	// Copy fixtures (including their window slices)
+	for name, fixture := range glt.state.Outputs.Fixtures {
+		fixtureCopy := fixture
+		fixtureCopy.Windows = append([]GrowLightWindow(nil), fixture.Windows...)
+		stateCopy.Outputs.Fixtures[name] = fixtureCopy
+	}
+
+	return stateCopy
+}
+
+// BedroomComfortTracker manages shadow state for the bedroom comfort plugin
+type BedroomComfortTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *BedroomComfortShadowState
+}
+
+// NewBedroomComfortTracker creates a new bedroom comfort shadow state tracker
+func NewBedroomComfortTracker() *BedroomComfortTracker {
+	return &BedroomComfortTracker{
+		state: NewBedroomComfortShadowState(),
+	}
+}
+
+// UpdateCurrentInputs updates the current input values
+func (bct *BedroomComfortTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	bct.mu.Lock()
+	defer bct.mu.Unlock()
+
+	for key, value := range inputs {
+		bct.state.Inputs.Current[key] = value
+	}
+	bct.state.Metadata.LastUpdated = time.Now()
+}
+
+// StartNight clears the previous night's adjustments and marks the night active
+func (bct *BedroomComfortTracker) StartNight(started time.Time, maxAdjustments int) {
+	bct.mu.Lock()
+	defer bct.mu.Unlock()
+
+	bct.state.Outputs.NightActive = true
+	bct.state.Outputs.NightStarted = started
+	bct.state.Outputs.AdjustmentsTonight = 0
+	bct.state.Outputs.MaxAdjustments = maxAdjustments
+	bct.state.Outputs.FanOn = false
+	bct.state.Outputs.TargetTemperature = nil
+	bct.state.Outputs.Adjustments = []BedroomComfortAdjustment{}
+	bct.state.Metadata.LastUpdated = time.Now()
+}
+
+// EndNight marks the night inactive, keeping its adjustments for review
+func (bct *BedroomComfortTracker) EndNight() {
+	bct.mu.Lock()
+	defer bct.mu.Unlock()
+
+	bct.state.Outputs.NightActive = false
+	bct.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordAdjustment records a fan or thermostat change. Counted adjustments
+// contribute to the nightly cap; restoring settings on wake does not.
+func (bct *BedroomComfortTracker) RecordAdjustment(adjustment BedroomComfortAdjustment, counted bool) {
+	bct.mu.Lock()
+	defer bct.mu.Unlock()
+
+	bct.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range bct.state.Inputs.Current {
+		bct.state.Inputs.AtLastAction[key] = value
+	}
+
+	if counted {
+		bct.state.Outputs.AdjustmentsTonight++
+	}
+	bct.state.Outputs.FanOn = adjustment.FanOn
+	bct.state.Outputs.TargetTemperature = adjustment.TargetTemperature
+	bct.state.Outputs.Adjustments = append(bct.state.Outputs.Adjustments, adjustment)
+	bct.state.Outputs.LastActionTime = adjustment.Time
+	bct.state.Outputs.LastActionReason = adjustment.Reason
+	bct.state.Metadata.LastUpdated = time.Now()
+	bct.NotifyAction(adjustment.Time, adjustment.Reason)
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (bct *BedroomComfortTracker) GetState() *BedroomComfortShadowState {
+	bct.mu.RLock()
+	defer bct.mu.RUnlock()
+
+	// Create a deep copy
+	stateCopy := &BedroomComfortShadowState{
+		Plugin: bct.state.Plugin,
+		Inputs: BedroomComfortInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  bct.state.Outputs,
+		Metadata: bct.state.Metadata,
+	}
+
+	for k, v := range bct.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range bct.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+
+	stateCopy.Outputs.TargetTemperature = copyFloatPtr(bct.state.Outputs.TargetTemperature)
+	stateCopy.Outputs.Adjustments = make([]BedroomComfortAdjustment, len(bct.state.Outputs.Adjustments))
+	for i, adj := range bct.state.Outputs.Adjustments {
+		adj.Humidity = copyFloatPtr(adj.Humidity)
+		adj.TargetTemperature = copyFloatPtr(adj.TargetTemperature)
+		stateCopy.Outputs.Adjustments[i] = adj
+	}
+
+	return stateCopy
+}
+
+func copyFloatPtr(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+// copyMap returns a shallow copy of m, nil if m is empty
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// OpenReminderTracker manages shadow state for the open reminder plugin
+type OpenReminderTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *OpenReminderShadowState
+}
+
+// NewOpenReminderTracker creates a new open reminder shadow state tracker
+func NewOpenReminderTracker() *OpenReminderTracker {
+	return &OpenReminderTracker{
+		state: NewOpenReminderShadowState(),
+	}
+}
+
+// UpdateCurrentInputs updates the current input values
+func (ort *OpenReminderTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	ort.mu.Lock()
+	defer ort.mu.Unlock()
+
+	for key, value := range inputs {
+		ort.state.Inputs.Current[key] = value
+	}
+	ort.state.Metadata.LastUpdated = time.Now()
+}
+
+// snapshotInputsLocked copies current inputs to the at-last-action snapshot.
+// Caller must hold ort.mu.
+func (ort *OpenReminderTracker) snapshotInputsLocked() {
+	ort.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range ort.state.Inputs.Current {
+		ort.state.Inputs.AtLastAction[key] = value
+	}
+}
+
+// RecordReminder records a reminder about open doors or windows. The first
+// reminder of a sequence (remindersSent == 1) starts a new sequence.
+func (ort *OpenReminderTracker) RecordReminder(trigger string, open []OpenSensorInfo, remindersSent int, message string, at time.Time) {
+	ort.mu.Lock()
+	defer ort.mu.Unlock()
+
+	ort.snapshotInputsLocked()
+
+	if remindersSent == 1 {
+		ort.state.Outputs.StartedAt = at
+		ort.state.Outputs.EndedReason = ""
+	}
+	ort.state.Outputs.Active = true
+	ort.state.Outputs.Trigger = trigger
+	ort.state.Outputs.OpenSensors = append([]OpenSensorInfo{}, open...)
+	ort.state.Outputs.RemindersSent = remindersSent
+	ort.state.Outputs.LastMessage = message
+	ort.state.Outputs.LastActionTime = at
+	ort.state.Outputs.LastActionReason = message
+	ort.state.Metadata.LastUpdated = time.Now()
+	ort.NotifyAction(at, message)
+}
+
+// RecordEnded records why reminders stopped (or were never needed)
+func (ort *OpenReminderTracker) RecordEnded(trigger string, reason string) {
+	ort.mu.Lock()
+	defer ort.mu.Unlock()
+
+	ort.state.Outputs.Active = false
+	ort.state.Outputs.Trigger = trigger
+	ort.state.Outputs.EndedReason = reason
+	ort.state.Metadata.LastUpdated = time.Now()
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (ort *OpenReminderTracker) GetState() *OpenReminderShadowState {
+	ort.mu.RLock()
+	defer ort.mu.RUnlock()
+
+	// Create a deep copy
+	stateCopy := &OpenReminderShadowState{
+		Plugin: ort.state.Plugin,
+		Inputs: OpenReminderInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  ort.state.Outputs,
+		Metadata: ort.state.Metadata,
+	}
+
+	for k, v := range ort.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range ort.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.OpenSensors = append([]OpenSensorInfo{}, ort.state.Outputs.OpenSensors...)
+
+	return stateCopy
+}
+
+// LowBatteryTracker manages shadow state for the low-battery plugin
+type LowBatteryTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *LowBatteryShadowState
+}
+
+// NewLowBatteryTracker creates a new low-battery shadow state tracker
+func NewLowBatteryTracker() *LowBatteryTracker {
+	return &LowBatteryTracker{
+		state: NewLowBatteryShadowState(),
+	}
+}
+
+// RecordSweep records the result of a battery sweep
+func (lbt *LowBatteryTracker) RecordSweep(devices []LowBatteryDevice, scanned int, at time.Time, sweepErr error) {
+	lbt.mu.Lock()
+	defer lbt.mu.Unlock()
+
+	lbt.state.Outputs.LastSweep = at
+	if sweepErr != nil {
+		lbt.state.Outputs.LastSweepError = sweepErr.Error()
+		lbt.state.Metadata.LastUpdated = time.Now()
+		return
+	}
+	lbt.state.Outputs.LastSweepError = ""
+	lbt.state.Outputs.Devices = append([]LowBatteryDevice{}, devices...)
+	lbt.state.Outputs.BatteriesScanned = scanned
+	lbt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordReport records the weekly consolidated notification
+func (lbt *LowBatteryTracker) RecordReport(message string, at time.Time) {
+	lbt.mu.Lock()
+	defer lbt.mu.Unlock()
+
+	lbt.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range lbt.state.Inputs.Current {
+		lbt.state.Inputs.AtLastAction[key] = value
+	}
+	lbt.state.Outputs.LastReport = at
+	lbt.state.Outputs.LastReportMessage = message
+	lbt.state.Outputs.LastActionTime = at
+	lbt.state.Outputs.LastActionReason = "Weekly low-battery report"
+	lbt.state.Metadata.LastUpdated = time.Now()
+	lbt.NotifyAction(at, "Weekly low-battery report")
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (lbt *LowBatteryTracker) GetState() *LowBatteryShadowState {
+	lbt.mu.RLock()
+	defer lbt.mu.RUnlock()
+
+	stateCopy := &LowBatteryShadowState{
+		Plugin: lbt.state.Plugin,
+		Inputs: LowBatteryInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  lbt.state.Outputs,
+		Metadata: lbt.state.Metadata,
+	}
+
+	for k, v := range lbt.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range lbt.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Devices = append([]LowBatteryDevice{}, lbt.state.Outputs.Devices...)
+
+	return stateCopy
+}
+
+// FocusModeTracker manages shadow state for office focus mode
+type FocusModeTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *FocusModeShadowState
+}
+
+// NewFocusModeTracker creates a new focus mode shadow state tracker
+func NewFocusModeTracker() *FocusModeTracker {
+	return &FocusModeTracker{
+		state: NewFocusModeShadowState(),
+	}
+}
+
+// UpdateInputs records the toggle and the current focus calendar event
+func (ft *FocusModeTracker) UpdateInputs(toggle bool, calendarEvent string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	ft.state.Inputs.Current["toggle"] = toggle
+	ft.state.Inputs.Current["calendarEvent"] = calendarEvent
+	ft.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordChange records focus mode turning on or off
+func (ft *FocusModeTracker) RecordChange(active bool, source string, at time.Time, reason string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	ft.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range ft.state.Inputs.Current {
+		ft.state.Inputs.AtLastAction[key] = value
+	}
+	ft.state.Outputs.Active = active
+	ft.state.Outputs.Source = source
+	if active {
+		ft.state.Outputs.ActiveSince = at
+	} else {
+		ft.state.Outputs.ActiveSince = time.Time{}
+	}
+	ft.state.Outputs.LastActionTime = at
+	ft.state.Outputs.LastActionReason = reason
+	ft.state.Metadata.LastUpdated = time.Now()
+	ft.NotifyAction(at, reason)
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (ft *FocusModeTracker) GetState() *FocusModeShadowState {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+
+	stateCopy := &FocusModeShadowState{
+		Plugin: ft.state.Plugin,
+		Inputs: FocusModeInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  ft.state.Outputs,
+		Metadata: ft.state.Metadata,
+	}
+
+	for k, v := range ft.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range ft.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+
+	return stateCopy
+}
+
+// KidModeTracker manages shadow state for kid mode
+type KidModeTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *KidModeShadowState
+}
+
+// NewKidModeTracker creates a new kid mode shadow state tracker
+func NewKidModeTracker() *KidModeTracker {
+	return &KidModeTracker{
+		state: NewKidModeShadowState(),
+	}
+}
+
+// UpdateInputs records the household toggle, whether a schedule window is
+// open, and the room toggles
+func (kt *KidModeTracker) UpdateInputs(toggle, inSchedule bool, roomToggles map[string]bool) {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	kt.state.Inputs.Current["toggle"] = toggle
+	kt.state.Inputs.Current["inSchedule"] = inSchedule
+	for room, on := range roomToggles {
+		kt.state.Inputs.Current["room:"+room] = on
+	}
+	kt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordChange records kid mode turning on or off, household-wide or in rooms
+func (kt *KidModeTracker) RecordChange(active bool, source string, rooms []string, at time.Time, reason string) {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	kt.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range kt.state.Inputs.Current {
+		kt.state.Inputs.AtLastAction[key] = value
+	}
+	if active && !kt.state.Outputs.Active {
+		kt.state.Outputs.ActiveSince = at
+	} else if !active {
+		kt.state.Outputs.ActiveSince = time.Time{}
+	}
+	kt.state.Outputs.Active = active
+	kt.state.Outputs.Source = source
+	kt.state.Outputs.ActiveRooms = append([]string{}, rooms...)
+	kt.state.Outputs.LastActionTime = at
+	kt.state.Outputs.LastActionReason = reason
+	kt.state.Metadata.LastUpdated = time.Now()
+	kt.NotifyAction(at, reason)
+}
+
+// RecordHeldThermostats records the thermostats held and their setpoints
+func (kt *KidModeTracker) RecordHeldThermostats(held map[string]float64) {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	kt.state.Outputs.HeldThermostats = make(map[string]float64, len(held))
+	for entityID, setpoint := range held {
+		kt.state.Outputs.HeldThermostats[entityID] = setpoint
+	}
+	kt.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordAction records an action taken while kid mode is on, such as
+// reverting a thermostat
+func (kt *KidModeTracker) RecordAction(at time.Time, reason string) {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	kt.state.Outputs.LastActionTime = at
+	kt.state.Outputs.LastActionReason = reason
+	kt.state.Metadata.LastUpdated = time.Now()
+	kt.NotifyAction(at, reason)
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (kt *KidModeTracker) GetState() *KidModeShadowState {
+	kt.mu.RLock()
+	defer kt.mu.RUnlock()
+
+	stateCopy := &KidModeShadowState{
+		Plugin: kt.state.Plugin,
+		Inputs: KidModeInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  kt.state.Outputs,
+		Metadata: kt.state.Metadata,
+	}
+
+	for k, v := range kt.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range kt.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.ActiveRooms = append([]string{}, kt.state.Outputs.ActiveRooms...)
+	if kt.state.Outputs.HeldThermostats != nil {
+		stateCopy.Outputs.HeldThermostats = make(map[string]float64, len(kt.state.Outputs.HeldThermostats))
+		for k, v := range kt.state.Outputs.HeldThermostats {
+			stateCopy.Outputs.HeldThermostats[k] = v
+		}
+	}
+
+	return stateCopy
+}
+
+// HotWaterTracker manages shadow state for the hot water recirculation plugin
+type HotWaterTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *HotWaterShadowState
+}
+
+// NewHotWaterTracker creates a new hot water shadow state tracker
+func NewHotWaterTracker() *HotWaterTracker {
+	return &HotWaterTracker{
+		state: NewHotWaterShadowState(),
+	}
+}
+
+// UpdateCurrentInputs replaces the current input values
+func (ht *HotWaterTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.state.Inputs.Current = make(map[string]interface{})
+	for key, value := range inputs {
+		ht.state.Inputs.Current[key] = value
+	}
+	ht.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateEvaluation records the pump's desired state and the learned schedule
+func (ht *HotWaterTracker) UpdateEvaluation(on bool, reason string, runUntil, nextRun time.Time, schedule HotWaterSchedule) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.state.Outputs.PumpOn = on
+	ht.state.Outputs.Reason = reason
+	ht.state.Outputs.RunUntil = runUntil
+	ht.state.Outputs.NextRun = nextRun
+	ht.state.Outputs.Schedule = schedule
+	ht.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordDraw records a hot water draw
+func (ht *HotWaterTracker) RecordDraw(at time.Time, drawsToday int) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.state.Outputs.LastDraw = at
+	ht.state.Outputs.DrawsToday = drawsToday
+	ht.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordPumpChange records the pump being switched on or off
+func (ht *HotWaterTracker) RecordPumpChange(on bool, reason string, at time.Time) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range ht.state.Inputs.Current {
+		ht.state.Inputs.AtLastAction[key] = value
+	}
+	ht.state.Outputs.PumpOn = on
+	ht.state.Outputs.LastActionTime = at
+	ht.state.Outputs.LastActionReason = reason
+	ht.state.Metadata.LastUpdated = time.Now()
+	ht.NotifyAction(at, reason)
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (ht *HotWaterTracker) GetState() *HotWaterShadowState {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	stateCopy := &HotWaterShadowState{
+		Plugin: ht.state.Plugin,
+		Inputs: HotWaterInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  ht.state.Outputs,
+		Metadata: ht.state.Metadata,
+	}
+
+	for k, v := range ht.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range ht.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Schedule.Weekday.Slots = append([]HotWaterSlot{}, ht.state.Outputs.Schedule.Weekday.Slots...)
+	stateCopy.Outputs.Schedule.Weekend.Slots = append([]HotWaterSlot{}, ht.state.Outputs.Schedule.Weekend.Slots...)
+
+	return stateCopy
+}
+
+// RulesTracker manages shadow state for the YAML rules engine
+type RulesTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *RulesShadowState
+}
+
+// NewRulesTracker creates a new rules shadow state tracker
+func NewRulesTracker() *RulesTracker {
+	return &RulesTracker{
+		state: NewRulesShadowState(),
+	}
+}
+
+// AddRule lists a rule before it has been triggered
+func (rt *RulesTracker) AddRule(name, description string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.state.Outputs.Rules = append(rt.state.Outputs.Rules, RuleStatus{
+		Name:        name,
+		Description: description,
+		LastActions: []string{},
+	})
+	rt.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateCurrentInput records the current value of a state variable
+func (rt *RulesTracker) UpdateCurrentInput(key string, value interface{}) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.state.Inputs.Current[key] = value
+	rt.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateNextScheduled records a rule's next cron trigger
+func (rt *RulesTracker) UpdateNextScheduled(name string, next time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if status := rt.rule(name); status != nil {
+		status.NextScheduled = next
+		rt.state.Metadata.LastUpdated = time.Now()
+	}
+}
+
+// RecordSkipped records a rule triggered while a condition did not hold
+func (rt *RulesTracker) RecordSkipped(name, trigger, reason string, at time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if status := rt.rule(name); status != nil {
+		status.LastTriggered = at
+		status.LastTrigger = trigger
+		status.LastResult = reason
+		rt.state.Metadata.LastUpdated = time.Now()
+	}
+}
+
+// RecordRun records a rule running its actions. result is "ran" or the
+// error that stopped the actions.
+func (rt *RulesTracker) RecordRun(name, trigger string, actions []string, result string, at time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	status := rt.rule(name)
+	if status == nil {
+		return
+	}
+	status.LastTriggered = at
+	status.LastTrigger = trigger
+	status.LastResult = result
+	status.LastRun = at
+	status.LastActions = append([]string{}, actions...)
+	status.RunCount++
+
+	rt.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range rt.state.Inputs.Current {
+		rt.state.Inputs.AtLastAction[key] = value
+	}
+	rt.state.Outputs.LastActionTime = at
+	rt.state.Outputs.LastActionReason = name + " (" + trigger + ")"
+	rt.state.Metadata.LastUpdated = time.Now()
+	rt.NotifyAction(at, name+" ("+trigger+")")
+}
+
+// UpdateWaitingUntil records when a rule waiting out a delay resumes; the
+// zero time clears it
+func (rt *RulesTracker) UpdateWaitingUntil(name string, until time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if status := rt.rule(name); status != nil {
+		status.WaitingUntil = until
+		rt.state.Metadata.LastUpdated = time.Now()
+	}
+}
+
+// RecordResumed records a rule running the actions after a delay. actions
+// lists every action of the run so far.
+func (rt *RulesTracker) RecordResumed(name string, actions []string, result string, at time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	status := rt.rule(name)
+	if status == nil {
+		return
+	}
+	status.LastResult = result
+	status.LastActions = append([]string{}, actions...)
+
+	rt.state.Outputs.LastActionTime = at
+	rt.state.Outputs.LastActionReason = name + " (after delay)"
+	rt.state.Metadata.LastUpdated = time.Now()
+	rt.NotifyAction(at, name+" (after delay)")
+}
+
+// rule returns the status of a rule; callers must hold the lock
+func (rt *RulesTracker) rule(name string) *RuleStatus {
+	for i := range rt.state.Outputs.Rules {
+		if rt.state.Outputs.Rules[i].Name == name {
+			return &rt.state.Outputs.Rules[i]
+		}
+	}
+	return nil
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (rt *RulesTracker) GetState() *RulesShadowState {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	stateCopy := &RulesShadowState{
+		Plugin: rt.state.Plugin,
+		Inputs: RulesInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  rt.state.Outputs,
+		Metadata: rt.state.Metadata,
+	}
+
+	for k, v := range rt.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range rt.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Rules = make([]RuleStatus, len(rt.state.Outputs.Rules))
+	for i, status := range rt.state.Outputs.Rules {
+		status.LastActions = append([]string{}, status.LastActions...)
+		stateCopy.Outputs.Rules[i] = status
+	}
+
+	return stateCopy
+}
+
+// SceneSchedulerTracker manages shadow state for the scene scheduler plugin
+type SceneSchedulerTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *SceneSchedulerShadowState
+}
+
+// NewSceneSchedulerTracker creates a new scene scheduler shadow state tracker
+func NewSceneSchedulerTracker() *SceneSchedulerTracker {
+	return &SceneSchedulerTracker{
+		state: NewSceneSchedulerShadowState(),
+	}
+}
+
+// AddSchedule lists a scene schedule before it has run
+func (st *SceneSchedulerTracker) AddSchedule(name, scene, spec string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.Schedules = append(st.state.Outputs.Schedules, SceneScheduleStatus{
+		Name:  name,
+		Scene: scene,
+		Spec:  spec,
+	})
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateCurrentInput records the current value of a state variable
+func (st *SceneSchedulerTracker) UpdateCurrentInput(key string, value interface{}) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Inputs.Current[key] = value
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateNextRun records when a scene schedule next runs
+func (st *SceneSchedulerTracker) UpdateNextRun(name string, next time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if status := st.schedule(name); status != nil {
+		status.NextRun = next
+		st.state.Metadata.LastUpdated = time.Now()
+	}
+}
+
+// RecordSkipped records a scene schedule that came due but didn't activate its scene
+func (st *SceneSchedulerTracker) RecordSkipped(name, reason string, at time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if status := st.schedule(name); status != nil {
+		status.LastRun = at
+		status.LastResult = reason
+		st.state.Metadata.LastUpdated = time.Now()
+	}
+}
+
+// RecordActivation records a scene schedule activating its scene. result is
+// "activated" or the service error.
+func (st *SceneSchedulerTracker) RecordActivation(name, result string, at time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	status := st.schedule(name)
+	if status == nil {
+		return
+	}
+	status.LastRun = at
+	status.LastResult = result
+	status.RunCount++
+
+	st.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range st.state.Inputs.Current {
+		st.state.Inputs.AtLastAction[key] = value
+	}
+	st.state.Outputs.LastActionTime = at
+	st.state.Outputs.LastActionReason = name + " (" + status.Scene + ")"
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(at, name+" ("+status.Scene+")")
+}
+
+// schedule returns the status of a scene schedule; callers must hold the lock
+func (st *SceneSchedulerTracker) schedule(name string) *SceneScheduleStatus {
+	for i := range st.state.Outputs.Schedules {
+		if st.state.Outputs.Schedules[i].Name == name {
+			return &st.state.Outputs.Schedules[i]
+		}
+	}
+	return nil
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (st *SceneSchedulerTracker) GetState() *SceneSchedulerShadowState {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	stateCopy := &SceneSchedulerShadowState{
+		Plugin: st.state.Plugin,
+		Inputs: SceneSchedulerInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  st.state.Outputs,
+		Metadata: st.state.Metadata,
+	}
+
+	for k, v := range st.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range st.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Schedules = append([]SceneScheduleStatus{}, st.state.Outputs.Schedules...)
+
+	return stateCopy
+}
+
+// SleepFanTracker manages shadow state for the sleep fan plugin
+type SleepFanTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *SleepFanShadowState
+}
+
+// NewSleepFanTracker creates a new sleep fan shadow state tracker
+func NewSleepFanTracker() *SleepFanTracker {
+	return &SleepFanTracker{
+		state: NewSleepFanShadowState(),
+	}
+}
+
+// AddBedroom lists a bedroom before its fan has been changed
+func (st *SleepFanTracker) AddBedroom(name, fanEntity string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.state.Outputs.Bedrooms = append(st.state.Outputs.Bedrooms, SleepFanBedroomStatus{
+		Name:      name,
+		FanEntity: fanEntity,
+	})
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateCurrentInputs updates the current input values
+func (st *SleepFanTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for key, value := range inputs {
+		st.state.Inputs.Current[key] = value
+	}
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateConditions records a bedroom's sleep, window and temperature readings
+// and the speed of its temperature band
+func (st *SleepFanTracker) UpdateConditions(name string, asleep, windowOpen bool, temperature *float64, targetSpeed int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	status := st.bedroom(name)
+	if status == nil {
+		return
+	}
+	status.Asleep = asleep
+	status.WindowOpen = windowOpen
+	status.Temperature = copyFloatPtr(temperature)
+	status.TargetSpeed = targetSpeed
+	st.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordSpeedChange records a bedroom fan being set to a new speed
+func (st *SleepFanTracker) RecordSpeedChange(name string, speed int, reason string, at time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	status := st.bedroom(name)
+	if status == nil {
+		return
+	}
+	status.Speed = speed
+	status.LastChange = at
+	status.LastReason = reason
+
+	st.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range st.state.Inputs.Current {
+		st.state.Inputs.AtLastAction[key] = value
+	}
+	st.state.Outputs.LastActionTime = at
+	st.state.Outputs.LastActionReason = name + ": " + reason
+	st.state.Metadata.LastUpdated = time.Now()
+	st.NotifyAction(at, name+": "+reason)
+}
+
+// bedroom returns the status of a bedroom; callers must hold the lock
+func (st *SleepFanTracker) bedroom(name string) *SleepFanBedroomStatus {
+	for i := range st.state.Outputs.Bedrooms {
+		if st.state.Outputs.Bedrooms[i].Name == name {
+			return &st.state.Outputs.Bedrooms[i]
+		}
+	}
+	return nil
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (st *SleepFanTracker) GetState() *SleepFanShadowState {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	stateCopy := &SleepFanShadowState{
+		Plugin: st.state.Plugin,
+		Inputs: SleepFanInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  st.state.Outputs,
+		Metadata: st.state.Metadata,
+	}
+
+	for k, v := range st.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range st.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Bedrooms = make([]SleepFanBedroomStatus, len(st.state.Outputs.Bedrooms))
+	for i, status := range st.state.Outputs.Bedrooms {
+		status.Temperature = copyFloatPtr(status.Temperature)
+		stateCopy.Outputs.Bedrooms[i] = status
+	}
+
+	return stateCopy
+}
+
+// FreezeProtectionTracker manages shadow state for the freeze protection plugin
+type FreezeProtectionTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *FreezeProtectionShadowState
+}
+
+// NewFreezeProtectionTracker creates a new freeze protection shadow state tracker
+func NewFreezeProtectionTracker() *FreezeProtectionTracker {
+	return &FreezeProtectionTracker{
+		state: NewFreezeProtectionShadowState(),
+	}
+}
+
+// AddSpace lists a space before it has been evaluated
+func (ft *FreezeProtectionTracker) AddSpace(name string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	ft.state.Outputs.Spaces = append(ft.state.Outputs.Spaces, FreezeProtectionSpaceStatus{Name: name})
+	ft.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateCurrentInputs updates the current input values
+func (ft *FreezeProtectionTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	for key, value := range inputs {
+		ft.state.Inputs.Current[key] = value
+	}
+	ft.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateConditions records a space's temperature, the energy level and the
+// heater thresholds it sets
+func (ft *FreezeProtectionTracker) UpdateConditions(name string, temperature *float64, energyLevel string, heaterOnBelow, heaterOffAbove float64, heatersAllowed bool) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	status := ft.space(name)
+	if status == nil {
+		return
+	}
+	status.Temperature = copyFloatPtr(temperature)
+	status.EnergyLevel = energyLevel
+	status.HeaterOnBelow = heaterOnBelow
+	status.HeaterOffAbove = heaterOffAbove
+	status.HeatersAllowed = heatersAllowed
+	ft.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordMitigation records a space's heaters or dampers being switched. since
+// and startTemperature describe the mitigation in progress, zero and nil once
+// it has ended.
+func (ft *FreezeProtectionTracker) RecordMitigation(name string, heatersOn, dampersOpen bool, since time.Time, startTemperature *float64, reason string, at time.Time) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	status := ft.space(name)
+	if status == nil {
+		return
+	}
+	status.HeatersOn = heatersOn
+	status.DampersOpen = dampersOpen
+	status.MitigatingSince = since
+	status.StartTemperature = copyFloatPtr(startTemperature)
+	status.LastChange = at
+	status.LastReason = reason
+
+	ft.recordAction(name+": "+reason, at)
+}
+
+// RecordAlert records an alert that a space keeps getting colder
+func (ft *FreezeProtectionTracker) RecordAlert(name, text string, at time.Time) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	status := ft.space(name)
+	if status == nil {
+		return
+	}
+	status.LastAlert = at
+	status.AlertText = text
+
+	ft.recordAction(name+": "+text, at)
+}
+
+// recordAction snapshots the inputs for an action; callers must hold the lock
+func (ft *FreezeProtectionTracker) recordAction(reason string, at time.Time) {
+	ft.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range ft.state.Inputs.Current {
+		ft.state.Inputs.AtLastAction[key] = value
+	}
+	ft.state.Outputs.LastActionTime = at
+	ft.state.Outputs.LastActionReason = reason
+	ft.state.Metadata.LastUpdated = time.Now()
+	ft.NotifyAction(at, reason)
+}
+
+// space returns the status of a space; callers must hold the lock
+func (ft *FreezeProtectionTracker) space(name string) *FreezeProtectionSpaceStatus {
+	for i := range ft.state.Outputs.Spaces {
+		if ft.state.Outputs.Spaces[i].Name == name {
+			return &ft.state.Outputs.Spaces[i]
+		}
+	}
+	return nil
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (ft *FreezeProtectionTracker) GetState() *FreezeProtectionShadowState {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+
+	stateCopy := &FreezeProtectionShadowState{
+		Plugin: ft.state.Plugin,
+		Inputs: FreezeProtectionInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  ft.state.Outputs,
+		Metadata: ft.state.Metadata,
+	}
+
+	for k, v := range ft.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range ft.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Spaces = make([]FreezeProtectionSpaceStatus, len(ft.state.Outputs.Spaces))
+	for i, status := range ft.state.Outputs.Spaces {
+		status.Temperature = copyFloatPtr(status.Temperature)
+		status.StartTemperature = copyFloatPtr(status.StartTemperature)
+		stateCopy.Outputs.Spaces[i] = status
+	}
+
+	return stateCopy
+}
+
+// ClimateTracker manages shadow state for the climate plugin
+type ClimateTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *ClimateShadowState
+}
+
+// NewClimateTracker creates a new climate shadow state tracker
+func NewClimateTracker() *ClimateTracker {
+	return &ClimateTracker{
+		state: NewClimateShadowState(),
+	}
+}
+
+// AddThermostat lists a thermostat before it has been evaluated
+func (ct *ClimateTracker) AddThermostat(entityID string) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.state.Outputs.Thermostats = append(ct.state.Outputs.Thermostats, ClimateThermostatStatus{EntityID: entityID})
+	ct.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateCurrentInputs updates the current input values
+func (ct *ClimateTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for key, value := range inputs {
+		ct.state.Inputs.Current[key] = value
+	}
+	ct.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateTarget records a thermostat's scheduled mode and setpoints, and what
+// holds them, if anything
+func (ct *ClimateTracker) UpdateTarget(entityID, mode string, low, high float64, heldBy string) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	status := ct.thermostat(entityID)
+	if status == nil {
+		return
+	}
+	status.Mode = mode
+	status.TargetLow = low
+	status.TargetHigh = high
+	status.HeldBy = heldBy
+	ct.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateActual records the setpoints a thermostat reports
+func (ct *ClimateTracker) UpdateActual(entityID string, low, high *float64) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	status := ct.thermostat(entityID)
+	if status == nil {
+		return
+	}
+	status.ActualLow = copyFloatPtr(low)
+	status.ActualHigh = copyFloatPtr(high)
+	ct.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordChange records a thermostat's setpoints being changed
+func (ct *ClimateTracker) RecordChange(entityID, reason string, at time.Time) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	status := ct.thermostat(entityID)
+	if status == nil {
+		return
+	}
+	status.LastChange = at
+	status.LastReason = reason
+
+	ct.recordAction(entityID+": "+reason, at)
+}
+
+// UpdateOpenSensors records the windows and doors open now
+func (ct *ClimateTracker) UpdateOpenSensors(sensors []ClimateOpenSensor) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.state.Outputs.OpenSensors = append([]ClimateOpenSensor(nil), sensors...)
+	ct.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordPause records a thermostat being paused for an open window or door,
+// or resumed when since is zero
+func (ct *ClimateTracker) RecordPause(entityID, pausedMode string, since time.Time, reason string, at time.Time) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	status := ct.thermostat(entityID)
+	if status == nil {
+		return
+	}
+	status.PausedSince = since
+	status.PausedMode = pausedMode
+	status.LastChange = at
+	status.LastReason = reason
+
+	ct.recordAction(entityID+": "+reason, at)
+}
+
+// recordAction snapshots the inputs for an action; callers must hold the lock
+func (ct *ClimateTracker) recordAction(reason string, at time.Time) {
+	ct.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range ct.state.Inputs.Current {
+		ct.state.Inputs.AtLastAction[key] = value
+	}
+	ct.state.Outputs.LastActionTime = at
+	ct.state.Outputs.LastActionReason = reason
+	ct.state.Metadata.LastUpdated = time.Now()
+	ct.NotifyAction(at, reason)
+}
+
+// thermostat returns the status of a thermostat; callers must hold the lock
+func (ct *ClimateTracker) thermostat(entityID string) *ClimateThermostatStatus {
+	for i := range ct.state.Outputs.Thermostats {
+		if ct.state.Outputs.Thermostats[i].EntityID == entityID {
+			return &ct.state.Outputs.Thermostats[i]
+		}
+	}
+	return nil
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (ct *ClimateTracker) GetState() *ClimateShadowState {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	stateCopy := &ClimateShadowState{
+		Plugin: ct.state.Plugin,
+		Inputs: ClimateInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  ct.state.Outputs,
+		Metadata: ct.state.Metadata,
+	}
+
+	for k, v := range ct.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range ct.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Thermostats = make([]ClimateThermostatStatus, len(ct.state.Outputs.Thermostats))
+	for i, status := range ct.state.Outputs.Thermostats {
+		status.ActualLow = copyFloatPtr(status.ActualLow)
+		status.ActualHigh = copyFloatPtr(status.ActualHigh)
+		stateCopy.Outputs.Thermostats[i] = status
+	}
+	stateCopy.Outputs.OpenSensors = append([]ClimateOpenSensor(nil), ct.state.Outputs.OpenSensors...)
+
+	return stateCopy
+}
+
+// EmergencyTracker manages shadow state for the emergency plugin
+type EmergencyTracker struct {
+	ActionNotifier
+
+	mu    sync.RWMutex
+	state *EmergencyShadowState
+}
+
+// NewEmergencyTracker creates a new emergency shadow state tracker
+func NewEmergencyTracker() *EmergencyTracker {
+	return &EmergencyTracker{
+		state: NewEmergencyShadowState(),
+	}
+}
+
+// UpdateCurrentInputs updates the current input values
+func (et *EmergencyTracker) UpdateCurrentInputs(inputs map[string]interface{}) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	for key, value := range inputs {
+		et.state.Inputs.Current[key] = value
+	}
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateDetectors records each detector's latest status
+func (et *EmergencyTracker) UpdateDetectors(detectors []EmergencyDetectorStatus) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	et.state.Outputs.Detectors = append([]EmergencyDetectorStatus(nil), detectors...)
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordActivation records an emergency starting, or ending when active is
+// false, and resets the announcement count and acknowledgement
+func (et *EmergencyTracker) RecordActivation(active bool, reason string, at time.Time) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	et.state.Outputs.Active = active
+	et.state.Outputs.Since = at
+	et.state.Outputs.Reason = reason
+	et.state.Outputs.Acknowledged = false
+	et.state.Outputs.Announcements = 0
+	et.recordAction(reason, at)
+}
+
+// RecordAcknowledged records announcements being stopped, or re-armed when
+// acknowledged is false
+func (et *EmergencyTracker) RecordAcknowledged(acknowledged bool, at time.Time) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	et.state.Outputs.Acknowledged = acknowledged
+	if acknowledged {
+		et.recordAction("acknowledged", at)
+	}
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// UpdateSuspendedPlugins records the plugins suspended for the emergency
+func (et *EmergencyTracker) UpdateSuspendedPlugins(plugins []string) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	et.state.Outputs.SuspendedPlugins = append([]string(nil), plugins...)
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordAnnouncement counts an evacuation announcement
+func (et *EmergencyTracker) RecordAnnouncement(at time.Time) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	et.state.Outputs.Announcements++
+	et.state.Outputs.LastAnnouncementAt = at
+	et.state.Metadata.LastUpdated = time.Now()
+}
+
+// RecordAction records a response action, keeping the most recent
+// MaxEmergencyActions
+func (et *EmergencyTracker) RecordAction(action EmergencyAction) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	actions := append(et.state.Outputs.Actions, action)
+	if len(actions) > MaxEmergencyActions {
+		actions = actions[len(actions)-MaxEmergencyActions:]
+	}
+	et.state.Outputs.Actions = actions
+	et.state.Outputs.LastActionTime = action.Timestamp
+	et.state.Metadata.LastUpdated = time.Now()
+	et.NotifyAction(action.Timestamp, action.Action)
+}
+
+// recordAction snapshots the inputs for an action; callers must hold the lock
+func (et *EmergencyTracker) recordAction(reason string, at time.Time) {
+	et.state.Inputs.AtLastAction = make(map[string]interface{})
+	for key, value := range et.state.Inputs.Current {
+		et.state.Inputs.AtLastAction[key] = value
+	}
+	et.state.Outputs.LastActionTime = at
+	et.state.Outputs.LastActionReason = reason
+	et.state.Metadata.LastUpdated = time.Now()
+	et.NotifyAction(at, reason)
+}
+
+// GetState returns the current shadow state (thread-safe copy)
+func (et *EmergencyTracker) GetState() *EmergencyShadowState {
+	et.mu.RLock()
+	defer et.mu.RUnlock()
+
+	stateCopy := &EmergencyShadowState{
+		Plugin: et.state.Plugin,
+		Inputs: EmergencyInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs:  et.state.Outputs,
+		Metadata: et.state.Metadata,
+	}
+
+	for k, v := range et.state.Inputs.Current {
+		stateCopy.Inputs.Current[k] = v
+	}
+	for k, v := range et.state.Inputs.AtLastAction {
+		stateCopy.Inputs.AtLastAction[k] = v
+	}
+	stateCopy.Outputs.Detectors = append([]EmergencyDetectorStatus(nil), et.state.Outputs.Detectors...)
+	stateCopy.Outputs.SuspendedPlugins = append([]string(nil), et.state.Outputs.SuspendedPlugins...)
+	stateCopy.Outputs.Actions = append([]EmergencyAction(nil), et.state.Outputs.Actions...)
+
+	return stateCopy
+}
@@ -35,6 +35,22 @@ func TestTrackerRegisterPlugin(t *testing.T) {
 	}
 }
 
+func TestTrackerUnregisterPluginProvider(t *testing.T) {
+	tracker := NewTracker()
+	tracker.RegisterPluginProvider("lighting", func() PluginShadowState {
+		return NewLightingShadowState()
+	})
+
+	tracker.UnregisterPluginProvider("lighting")
+
+	if _, ok := tracker.GetPluginState("lighting"); ok {
+		t.Error("Expected no state after unregistering the provider")
+	}
+	if _, ok := tracker.GetAllPluginStates()["lighting"]; ok {
+		t.Error("Expected unregistered plugin to be missing from all states")
+	}
+}
+
 func TestTrackerRegisterPluginProvider(t *testing.T) {
 	tracker := NewTracker()
 	callCount := 0
@@ -177,47 +193,39 @@ func TestLightingTrackerUpdateCurrentInputs(t *testing.T) {
 
 	lt.UpdateCurrentInputs(inputs)
 
-	state := lt.GetState()
-	if state.Inputs.Current["dayPhase"] != "evening" {
-		t.Errorf("Expected dayPhase to be 'evening', got %v", state.Inputs.Current["dayPhase"])
-	}
-	if state.Inputs.Current["sunevent"] != "sunset" {
-		t.Errorf("Expected sunevent to be 'sunset', got %v", state.Inputs.Current["sunevent"])
-	}
-	if state.Inputs.Current["isAnyoneHome"] != true {
-		t.Errorf("Expected isAnyoneHome to be true, got %v", state.Inputs.Current["isAnyoneHome"])
-	}
+	ExpectCurrentInputsContain(t, lt.GetState(), inputs)
 }
 
 func TestLightingTrackerSnapshotInputsForAction(t *testing.T) {
 	lt := NewLightingTracker()
 
-	// Set initial inputs
-	inputs := map[string]interface{}{
-		"dayPhase": "afternoon",
-	}
-	lt.UpdateCurrentInputs(inputs)
-
-	// Snapshot
+	lt.UpdateCurrentInputs(map[string]interface{}{"dayPhase": "afternoon"})
 	lt.SnapshotInputsForAction()
-
-	// Change current inputs
-	newInputs := map[string]interface{}{
-		"dayPhase": "evening",
-	}
-	lt.UpdateCurrentInputs(newInputs)
+	lt.UpdateCurrentInputs(map[string]interface{}{"dayPhase": "evening"})
 
 	state := lt.GetState()
+	ExpectCurrentInputsContain(t, state, map[string]interface{}{"dayPhase": "evening"})
+	ExpectInputSnapshotContains(t, state, map[string]interface{}{"dayPhase": "afternoon"})
+}
 
-	// Current should be evening
-	if state.Inputs.Current["dayPhase"] != "evening" {
-		t.Errorf("Expected current dayPhase to be 'evening', got %v", state.Inputs.Current["dayPhase"])
-	}
+func TestLightingTrackerUpdateOnBudget(t *testing.T) {
+	lt := NewLightingTracker()
 
-	// At last action should be afternoon
-	if state.Inputs.AtLastAction["dayPhase"] != "afternoon" {
-		t.Errorf("Expected atLastAction dayPhase to be 'afternoon', got %v", state.Inputs.AtLastAction["dayPhase"])
-	}
+	lt.UpdateOnBudget("Closet", OnBudgetState{
+		LightEntity:     "light.closet",
+		Day:             "2024-03-10",
+		BudgetMinutes:   30,
+		ConsumedMinutes: 12.5,
+		LightsOn:        true,
+	})
+
+	state := lt.GetState()
+	ExpectOutput(t, state, "onBudgets.Closet.consumedMinutes", 12.5)
+	ExpectOutput(t, state, "onBudgets.Closet.lightsOn", true)
+
+	// The returned copy is independent of the tracker
+	state.Outputs.OnBudgets["Closet"] = OnBudgetState{}
+	ExpectOutput(t, lt.GetState(), "onBudgets.Closet.budgetMinutes", 30)
 }
 
 func TestLightingTrackerRecordRoomAction(t *testing.T) {
@@ -246,6 +254,45 @@ func TestLightingTrackerRecordRoomAction(t *testing.T) {
 	}
 }
 
+func TestLightingTrackerRecordRoomActionDetail(t *testing.T) {
+	lt := NewLightingTracker()
+
+	serviceData := map[string]interface{}{"entity_id": "scene.living_room_evening", "area_id": "living_room"}
+	lt.RecordRoomActionDetail("Living Room", "activate_scene", "Activated scene 'evening'", "evening", false, RoomActionDetail{
+		Service:        "scene.turn_on",
+		ServiceData:    serviceData,
+		SceneEntityID:  "scene.living_room_evening",
+		TargetEntities: []string{"light.sofa", "light.floor_lamp"},
+		SceneValues:    map[string]interface{}{"brightness": 128},
+		Conditions: []ConditionEvaluation{
+			{Kind: "on_if_true", Variable: "isAnyoneHome", Value: true, Met: true},
+		},
+	})
+
+	// Later changes to the caller's map don't leak into the shadow state
+	serviceData["transition"] = 30
+
+	room := lt.GetState().Outputs.Rooms["Living Room"]
+	if room.Service != "scene.turn_on" || room.SceneEntityID != "scene.living_room_evening" {
+		t.Errorf("Unexpected service %q / scene %q", room.Service, room.SceneEntityID)
+	}
+	if _, ok := room.ServiceData["transition"]; ok {
+		t.Error("Expected service data to be copied when recorded")
+	}
+	if len(room.TargetEntities) != 2 || room.SceneValues["brightness"] != 128 {
+		t.Errorf("Unexpected targets %v / values %v", room.TargetEntities, room.SceneValues)
+	}
+	if len(room.Conditions) != 1 || !room.Conditions[0].Met {
+		t.Errorf("Unexpected conditions %+v", room.Conditions)
+	}
+
+	// Copies returned by GetState are independent
+	room.TargetEntities[0] = "light.changed"
+	if lt.GetState().Outputs.Rooms["Living Room"].TargetEntities[0] != "light.sofa" {
+		t.Error("Expected GetState to return a deep copy of the action detail")
+	}
+}
+
 func TestLightingTrackerRecordTurnOff(t *testing.T) {
 	lt := NewLightingTracker()
 
@@ -408,44 +455,19 @@ func TestSecurityTrackerUpdateCurrentInputs(t *testing.T) {
 
 	st.UpdateCurrentInputs(inputs)
 
-	state := st.GetState()
-	if state.Inputs.Current["isEveryoneAsleep"] != true {
-		t.Errorf("Expected isEveryoneAsleep to be true, got %v", state.Inputs.Current["isEveryoneAsleep"])
-	}
-	if state.Inputs.Current["isAnyoneHome"] != false {
-		t.Errorf("Expected isAnyoneHome to be false, got %v", state.Inputs.Current["isAnyoneHome"])
-	}
+	ExpectCurrentInputsContain(t, st.GetState(), inputs)
 }
 
 func TestSecurityTrackerSnapshotInputsForAction(t *testing.T) {
 	st := NewSecurityTracker()
 
-	// Set initial inputs
-	inputs := map[string]interface{}{
-		"isEveryoneAsleep": false,
-	}
-	st.UpdateCurrentInputs(inputs)
-
-	// Snapshot
+	st.UpdateCurrentInputs(map[string]interface{}{"isEveryoneAsleep": false})
 	st.SnapshotInputsForAction()
-
-	// Change current inputs
-	newInputs := map[string]interface{}{
-		"isEveryoneAsleep": true,
-	}
-	st.UpdateCurrentInputs(newInputs)
+	st.UpdateCurrentInputs(map[string]interface{}{"isEveryoneAsleep": true})
 
 	state := st.GetState()
-
-	// Current should be true
-	if state.Inputs.Current["isEveryoneAsleep"] != true {
-		t.Errorf("Expected current isEveryoneAsleep to be true, got %v", state.Inputs.Current["isEveryoneAsleep"])
-	}
-
-	// At last action should be false
-	if state.Inputs.AtLastAction["isEveryoneAsleep"] != false {
-		t.Errorf("Expected atLastAction isEveryoneAsleep to be false, got %v", state.Inputs.AtLastAction["isEveryoneAsleep"])
-	}
+	ExpectCurrentInputsContain(t, state, map[string]interface{}{"isEveryoneAsleep": true})
+	ExpectInputSnapshotContains(t, state, map[string]interface{}{"isEveryoneAsleep": false})
 }
 
 func TestSecurityTrackerRecordLockdownAction(t *testing.T) {
@@ -455,12 +477,8 @@ func TestSecurityTrackerRecordLockdownAction(t *testing.T) {
 
 	state := st.GetState()
 
-	if !state.Outputs.Lockdown.Active {
-		t.Error("Expected lockdown to be active")
-	}
-	if state.Outputs.Lockdown.Reason != "Everyone is asleep" {
-		t.Errorf("Expected reason 'Everyone is asleep', got %s", state.Outputs.Lockdown.Reason)
-	}
+	ExpectOutput(t, state, "lockdown.active", true)
+	ExpectOutput(t, state, "lockdown.reason", "Everyone is asleep")
 	if state.Outputs.Lockdown.ActivatedAt.IsZero() {
 		t.Error("Expected ActivatedAt to be set")
 	}
@@ -584,6 +602,186 @@ func TestSecurityTrackerGetStateReturnsDeepCopy(t *testing.T) {
 	}
 }
 
+func TestSecurityTrackerRecordLockdownCues(t *testing.T) {
+	st := NewSecurityTracker()
+
+	st.RecordLockdownCues([]string{"light.kitchen"}, []string{"media_player.patio"})
+
+	state := st.GetState()
+	if len(state.Outputs.Lockdown.CuedLights) != 1 || state.Outputs.Lockdown.CuedLights[0] != "light.kitchen" {
+		t.Errorf("Expected cued lights [light.kitchen], got %v", state.Outputs.Lockdown.CuedLights)
+	}
+	if len(state.Outputs.Lockdown.PausedSpeakers) != 1 {
+		t.Errorf("Expected 1 paused speaker, got %d", len(state.Outputs.Lockdown.PausedSpeakers))
+	}
+	if state.Outputs.Lockdown.LightsRestored {
+		t.Error("Expected LightsRestored to be false before restore")
+	}
+
+	// Modifying the returned slice must not affect internal state
+	state.Outputs.Lockdown.CuedLights[0] = "light.other"
+	if st.GetState().Outputs.Lockdown.CuedLights[0] != "light.kitchen" {
+		t.Error("Modifying returned cued lights affected the internal state")
+	}
+
+	st.RecordLockdownLightsRestored()
+	if !st.GetState().Outputs.Lockdown.LightsRestored {
+		t.Error("Expected LightsRestored to be true after restore")
+	}
+}
+
+func TestSecurityTrackerRecordCameraPrivacy(t *testing.T) {
+	st := NewSecurityTracker()
+	switches := []string{"switch.test_camera_privacy"}
+
+	st.RecordCameraPrivacy(true, "Owner home", "isAnyOwnerHome", switches)
+	st.RecordCameraPrivacy(false, "Lockdown activated", "lockdown", switches)
+
+	state := st.GetState()
+	privacy := state.Outputs.CameraPrivacy
+	if privacy.Enabled {
+		t.Error("Expected privacy mode to be disabled after the last transition")
+	}
+	if privacy.Reason != "Lockdown activated" {
+		t.Errorf("Expected reason 'Lockdown activated', got '%s'", privacy.Reason)
+	}
+	if len(privacy.Transitions) != 2 {
+		t.Fatalf("Expected 2 transitions, got %d", len(privacy.Transitions))
+	}
+	if !privacy.Transitions[0].Enabled || privacy.Transitions[1].Trigger != "lockdown" {
+		t.Errorf("Unexpected transitions: %+v", privacy.Transitions)
+	}
+	if state.Outputs.LastActionTime.IsZero() {
+		t.Error("Expected LastActionTime to be set")
+	}
+
+	// Modifying the returned slices must not affect internal state
+	privacy.Switches[0] = "switch.other"
+	privacy.Transitions[0].Reason = "modified"
+	internal := st.GetState().Outputs.CameraPrivacy
+	if internal.Switches[0] != "switch.test_camera_privacy" || internal.Transitions[0].Reason != "Owner home" {
+		t.Error("Modifying returned camera privacy state affected the internal state")
+	}
+}
+
+func TestSecurityTrackerCameraPrivacyTransitionsAreBounded(t *testing.T) {
+	st := NewSecurityTracker()
+
+	for i := 0; i < MaxCameraPrivacyTransitions+5; i++ {
+		st.RecordCameraPrivacy(i%2 == 0, fmt.Sprintf("transition %d", i), "test", nil)
+	}
+
+	transitions := st.GetState().Outputs.CameraPrivacy.Transitions
+	if len(transitions) != MaxCameraPrivacyTransitions {
+		t.Fatalf("Expected %d transitions, got %d", MaxCameraPrivacyTransitions, len(transitions))
+	}
+	if transitions[len(transitions)-1].Reason != fmt.Sprintf("transition %d", MaxCameraPrivacyTransitions+4) {
+		t.Errorf("Expected the most recent transition last, got %q", transitions[len(transitions)-1].Reason)
+	}
+}
+
+func TestSecurityTrackerRecordAlarmTransition(t *testing.T) {
+	st := NewSecurityTracker()
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < MaxAlarmTransitions+5; i++ {
+		st.RecordAlarmTransition(
+			AlarmState{Enabled: true, Mode: "away", Status: "armed", Bypassed: []string{"binary_sensor.hall_motion"}},
+			AlarmTransition{Timestamp: at.Add(time.Duration(i) * time.Minute), Mode: "away", Status: "armed", Reason: fmt.Sprintf("transition %d", i)},
+		)
+	}
+
+	alarm := st.GetState().Outputs.Alarm
+	if alarm.Mode != "away" || alarm.Status != "armed" || !alarm.Enabled {
+		t.Errorf("Unexpected alarm state: %+v", alarm)
+	}
+	if len(alarm.Transitions) != MaxAlarmTransitions {
+		t.Fatalf("Expected %d transitions, got %d", MaxAlarmTransitions, len(alarm.Transitions))
+	}
+	if last := alarm.Transitions[len(alarm.Transitions)-1]; last.Reason != fmt.Sprintf("transition %d", MaxAlarmTransitions+4) {
+		t.Errorf("Expected the most recent transition last, got %q", last.Reason)
+	}
+
+	alarm.Bypassed[0] = "modified"
+	alarm.Transitions[0].Reason = "modified"
+	internal := st.GetState().Outputs.Alarm
+	if internal.Bypassed[0] != "binary_sensor.hall_motion" || internal.Transitions[0].Reason == "modified" {
+		t.Error("Modifying returned alarm state affected the internal state")
+	}
+}
+
+func TestSecurityTrackerRecordLockEvent(t *testing.T) {
+	st := NewSecurityTracker()
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	locks := []DoorLockStatus{{EntityID: "lock.front_door", Name: "Front door", State: "locked"}}
+
+	for i := 0; i < MaxLockEvents+5; i++ {
+		st.RecordLockEvent(locks, LockEvent{
+			Timestamp: at.Add(time.Duration(i) * time.Minute),
+			EntityID:  "lock.front_door",
+			Event:     "locked",
+			Source:    fmt.Sprintf("event %d", i),
+			Success:   true,
+		})
+	}
+
+	doorLocks := st.GetState().Outputs.DoorLocks
+	if len(doorLocks.Events) != MaxLockEvents {
+		t.Fatalf("Expected %d events, got %d", MaxLockEvents, len(doorLocks.Events))
+	}
+	if last := doorLocks.Events[len(doorLocks.Events)-1]; last.Source != fmt.Sprintf("event %d", MaxLockEvents+4) {
+		t.Errorf("Expected the most recent event last, got %q", last.Source)
+	}
+
+	st.UpdateDoorLocks([]DoorLockStatus{{EntityID: "lock.front_door", Name: "Front door", State: "unlocked", AutoLockAt: at}})
+	doorLocks = st.GetState().Outputs.DoorLocks
+	if len(doorLocks.Locks) != 1 || doorLocks.Locks[0].State != "unlocked" || len(doorLocks.Events) != MaxLockEvents {
+		t.Errorf("Unexpected door locks after status update: %+v", doorLocks.Locks)
+	}
+
+	doorLocks.Locks[0].State = "modified"
+	doorLocks.Events[0].Source = "modified"
+	internal := st.GetState().Outputs.DoorLocks
+	if internal.Locks[0].State != "unlocked" || internal.Events[0].Source == "modified" {
+		t.Error("Modifying returned door locks affected the internal state")
+	}
+}
+
+func TestSecurityTrackerDeliveryWindow(t *testing.T) {
+	st := NewSecurityTracker()
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	st.UpdateDeliveryWindow(true, "calendar", "Furniture delivery", at.Add(6*time.Hour))
+	for i := 0; i < MaxPendingDeliveries+5; i++ {
+		st.RecordDeliveryEvent(DeliveryEvent{Timestamp: at.Add(time.Duration(i) * time.Minute)})
+	}
+
+	state := st.GetState()
+	window := state.Outputs.DeliveryWindow
+	if !window.Active || window.Source != "calendar" || window.CalendarEvent != "Furniture delivery" {
+		t.Errorf("Unexpected delivery window: %+v", window)
+	}
+	if len(window.Pending) != MaxPendingDeliveries {
+		t.Fatalf("Expected %d pending deliveries, got %d", MaxPendingDeliveries, len(window.Pending))
+	}
+	if state.Outputs.LastDoorbell == nil || !state.Outputs.LastDoorbell.DeliveryWindow {
+		t.Errorf("Expected the last doorbell to be marked as a delivery, got %+v", state.Outputs.LastDoorbell)
+	}
+
+	st.RecordDeliverySummary(DeliverySummary{Timestamp: at.Add(6 * time.Hour), Count: 2, Message: "2 deliveries arrived"})
+
+	state = st.GetState()
+	if len(state.Outputs.DeliveryWindow.Pending) != 0 {
+		t.Errorf("Expected pending deliveries to be cleared, got %d", len(state.Outputs.DeliveryWindow.Pending))
+	}
+	if summary := state.Outputs.DeliveryWindow.LastSummary; summary == nil || summary.Count != 2 {
+		t.Errorf("Unexpected last summary: %+v", summary)
+	}
+	if !state.Outputs.LastActionTime.Equal(at.Add(6 * time.Hour)) {
+		t.Errorf("Expected the summary to set the last action time, got %v", state.Outputs.LastActionTime)
+	}
+}
+
 func TestSecurityTrackerConcurrentAccess(t *testing.T) {
 	st := NewSecurityTracker()
 
@@ -737,44 +935,19 @@ func TestSleepHygieneTrackerUpdateCurrentInputs(t *testing.T) {
 
 	st.UpdateCurrentInputs(inputs)
 
-	state := st.GetState()
-	if state.Inputs.Current["isMasterAsleep"] != true {
-		t.Errorf("Expected isMasterAsleep to be true, got %v", state.Inputs.Current["isMasterAsleep"])
-	}
-	if state.Inputs.Current["musicPlaybackType"] != "sleep" {
-		t.Errorf("Expected musicPlaybackType to be 'sleep', got %v", state.Inputs.Current["musicPlaybackType"])
-	}
+	ExpectCurrentInputsContain(t, st.GetState(), inputs)
 }
 
 func TestSleepHygieneTrackerSnapshotInputsForAction(t *testing.T) {
 	st := NewSleepHygieneTracker()
 
-	// Set initial inputs
-	inputs := map[string]interface{}{
-		"isMasterAsleep": true,
-	}
-	st.UpdateCurrentInputs(inputs)
-
-	// Snapshot
+	st.UpdateCurrentInputs(map[string]interface{}{"isMasterAsleep": true})
 	st.SnapshotInputsForAction()
-
-	// Change current inputs
-	newInputs := map[string]interface{}{
-		"isMasterAsleep": false,
-	}
-	st.UpdateCurrentInputs(newInputs)
+	st.UpdateCurrentInputs(map[string]interface{}{"isMasterAsleep": false})
 
 	state := st.GetState()
-
-	// Current should be false
-	if state.Inputs.Current["isMasterAsleep"] != false {
-		t.Errorf("Expected current isMasterAsleep to be false, got %v", state.Inputs.Current["isMasterAsleep"])
-	}
-
-	// At last action should be true
-	if state.Inputs.AtLastAction["isMasterAsleep"] != true {
-		t.Errorf("Expected atLastAction isMasterAsleep to be true, got %v", state.Inputs.AtLastAction["isMasterAsleep"])
-	}
+	ExpectCurrentInputsContain(t, state, map[string]interface{}{"isMasterAsleep": false})
+	ExpectInputSnapshotContains(t, state, map[string]interface{}{"isMasterAsleep": true})
 }
 
 func TestSleepHygieneTrackerRecordAction(t *testing.T) {
@@ -784,12 +957,8 @@ func TestSleepHygieneTrackerRecordAction(t *testing.T) {
 
 	state := st.GetState()
 
-	if state.Outputs.LastActionType != "begin_wake" {
-		t.Errorf("Expected action type 'begin_wake', got %s", state.Outputs.LastActionType)
-	}
-	if state.Outputs.LastActionReason != "Starting wake sequence" {
-		t.Errorf("Expected reason 'Starting wake sequence', got %s", state.Outputs.LastActionReason)
-	}
+	ExpectLastAction(t, state, "begin_wake")
+	ExpectOutput(t, state, "lastActionReason", "Starting wake sequence")
 	if state.Outputs.LastActionTime.IsZero() {
 		t.Error("Expected LastActionTime to be set")
 	}
@@ -812,6 +981,34 @@ func TestSleepHygieneTrackerUpdateWakeSequenceStatus(t *testing.T) {
 	}
 }
 
+func TestSleepHygieneTrackerUpdateDoNotDisturb(t *testing.T) {
+	st := NewSleepHygieneTracker()
+
+	since := time.Date(2025, 1, 5, 22, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	st.UpdateDoNotDisturb("primary", true, since, expiresAt)
+
+	state := st.GetState()
+	dnd, exists := state.Outputs.DoNotDisturb["primary"]
+	if !exists {
+		t.Fatal("Expected do-not-disturb entry for primary bedroom")
+	}
+	if !dnd.Active || !dnd.Since.Equal(since) || !dnd.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Unexpected do-not-disturb state: %+v", dnd)
+	}
+
+	// Returned map is a copy
+	state.Outputs.DoNotDisturb["primary"] = DoNotDisturb{}
+	if !st.GetState().Outputs.DoNotDisturb["primary"].Active {
+		t.Error("Modifying returned state should not affect the tracker")
+	}
+
+	st.UpdateDoNotDisturb("primary", false, time.Time{}, time.Time{})
+	if st.GetState().Outputs.DoNotDisturb["primary"].Active {
+		t.Error("Expected do-not-disturb to be inactive")
+	}
+}
+
 func TestSleepHygieneTrackerFadeOutProgress(t *testing.T) {
 	st := NewSleepHygieneTracker()
 
@@ -984,44 +1181,19 @@ func TestLoadSheddingTrackerUpdateCurrentInputs(t *testing.T) {
 
 	lst.UpdateCurrentInputs(inputs)
 
-	state := lst.GetState()
-	if state.Inputs.Current["currentEnergyLevel"] != "high" {
-		t.Errorf("Expected currentEnergyLevel to be 'high', got %v", state.Inputs.Current["currentEnergyLevel"])
-	}
-	if state.Inputs.Current["outsideTemperature"] != 85.0 {
-		t.Errorf("Expected outsideTemperature to be 85.0, got %v", state.Inputs.Current["outsideTemperature"])
-	}
+	ExpectCurrentInputsContain(t, lst.GetState(), inputs)
 }
 
 func TestLoadSheddingTrackerSnapshotInputsForAction(t *testing.T) {
 	lst := NewLoadSheddingTracker()
 
-	// Set initial inputs
-	inputs := map[string]interface{}{
-		"currentEnergyLevel": "low",
-	}
-	lst.UpdateCurrentInputs(inputs)
-
-	// Snapshot
+	lst.UpdateCurrentInputs(map[string]interface{}{"currentEnergyLevel": "low"})
 	lst.SnapshotInputsForAction()
-
-	// Change current inputs
-	newInputs := map[string]interface{}{
-		"currentEnergyLevel": "high",
-	}
-	lst.UpdateCurrentInputs(newInputs)
+	lst.UpdateCurrentInputs(map[string]interface{}{"currentEnergyLevel": "high"})
 
 	state := lst.GetState()
-
-	// Current should be high
-	if state.Inputs.Current["currentEnergyLevel"] != "high" {
-		t.Errorf("Expected current currentEnergyLevel to be 'high', got %v", state.Inputs.Current["currentEnergyLevel"])
-	}
-
-	// At last action should be low
-	if state.Inputs.AtLastAction["currentEnergyLevel"] != "low" {
-		t.Errorf("Expected atLastAction currentEnergyLevel to be 'low', got %v", state.Inputs.AtLastAction["currentEnergyLevel"])
-	}
+	ExpectCurrentInputsContain(t, state, map[string]interface{}{"currentEnergyLevel": "high"})
+	ExpectInputSnapshotContains(t, state, map[string]interface{}{"currentEnergyLevel": "low"})
 }
 
 func TestLoadSheddingTrackerRecordAction(t *testing.T) {
@@ -1037,21 +1209,10 @@ func TestLoadSheddingTrackerRecordAction(t *testing.T) {
 
 	state := lst.GetState()
 
-	if !state.Outputs.Active {
-		t.Error("Expected load shedding to be active")
-	}
-	if state.Outputs.LastActionType != "increase_temp" {
-		t.Errorf("Expected action type 'increase_temp', got %s", state.Outputs.LastActionType)
-	}
-	if state.Outputs.LastActionReason != "Low energy level" {
-		t.Errorf("Expected reason 'Low energy level', got %s", state.Outputs.LastActionReason)
-	}
-	if state.Outputs.ThermostatSettings.TempHigh != 78.0 {
-		t.Errorf("Expected temp high 78.0, got %f", state.Outputs.ThermostatSettings.TempHigh)
-	}
-	if !state.Outputs.ThermostatSettings.HoldMode {
-		t.Error("Expected HoldMode to be true")
-	}
+	ExpectLastAction(t, state, "increase_temp")
+	ExpectOutput(t, state, "active", true)
+	ExpectOutput(t, state, "lastActionReason", "Low energy level")
+	ExpectOutput(t, state, "thermostatSettings", settings)
 	if state.Outputs.LastActionTime.IsZero() {
 		t.Error("Expected LastActionTime to be set")
 	}
@@ -1108,13 +1269,7 @@ func TestEnergyTrackerUpdateCurrentInputs(t *testing.T) {
 
 	et.UpdateCurrentInputs(inputs)
 
-	state := et.GetState()
-	if state.Inputs.Current["batteryPercentage"] != 75.0 {
-		t.Errorf("Expected batteryPercentage to be 75.0, got %v", state.Inputs.Current["batteryPercentage"])
-	}
-	if state.Inputs.Current["solarGenerationKW"] != 5.2 {
-		t.Errorf("Expected solarGenerationKW to be 5.2, got %v", state.Inputs.Current["solarGenerationKW"])
-	}
+	ExpectCurrentInputsContain(t, et.GetState(), inputs)
 }
 
 func TestEnergyTrackerUpdateSensorReadings(t *testing.T) {
@@ -1281,16 +1436,7 @@ func TestStateTrackingTrackerUpdateCurrentInputs(t *testing.T) {
 
 	stt.UpdateCurrentInputs(inputs)
 
-	state := stt.GetState()
-	if state.Inputs.Current["isNickHome"] != true {
-		t.Errorf("Expected isNickHome to be true, got %v", state.Inputs.Current["isNickHome"])
-	}
-	if state.Inputs.Current["isCarolineHome"] != false {
-		t.Errorf("Expected isCarolineHome to be false, got %v", state.Inputs.Current["isCarolineHome"])
-	}
-	if state.Inputs.Current["isMasterAsleep"] != true {
-		t.Errorf("Expected isMasterAsleep to be true, got %v", state.Inputs.Current["isMasterAsleep"])
-	}
+	ExpectCurrentInputsContain(t, stt.GetState(), inputs)
 }
 
 func TestStateTrackingTrackerUpdateDerivedStates(t *testing.T) {
@@ -1492,13 +1638,7 @@ func TestDayPhaseTrackerUpdateCurrentInputs(t *testing.T) {
 
 	dpt.UpdateCurrentInputs(inputs)
 
-	state := dpt.GetState()
-	if state.Inputs.Current["sunElevation"] != 45.5 {
-		t.Errorf("Expected sunElevation to be 45.5, got %v", state.Inputs.Current["sunElevation"])
-	}
-	if state.Inputs.Current["sunAzimuth"] != 180.0 {
-		t.Errorf("Expected sunAzimuth to be 180.0, got %v", state.Inputs.Current["sunAzimuth"])
-	}
+	ExpectCurrentInputsContain(t, dpt.GetState(), inputs)
 }
 
 func TestDayPhaseTrackerUpdateSunEvent(t *testing.T) {
@@ -1620,16 +1760,7 @@ func TestTVTrackerUpdateCurrentInputs(t *testing.T) {
 
 	tvt.UpdateCurrentInputs(inputs)
 
-	state := tvt.GetState()
-	if state.Inputs.Current["appleTVState"] != "playing" {
-		t.Errorf("Expected appleTVState to be 'playing', got %v", state.Inputs.Current["appleTVState"])
-	}
-	if state.Inputs.Current["tvPowerState"] != "on" {
-		t.Errorf("Expected tvPowerState to be 'on', got %v", state.Inputs.Current["tvPowerState"])
-	}
-	if state.Inputs.Current["currentInput"] != "HDMI1" {
-		t.Errorf("Expected currentInput to be 'HDMI1', got %v", state.Inputs.Current["currentInput"])
-	}
+	ExpectCurrentInputsContain(t, tvt.GetState(), inputs)
 }
 
 func TestTVTrackerUpdateAppleTVState(t *testing.T) {
@@ -1768,3 +1899,518 @@ func TestTVTrackerConcurrentAccess(t *testing.T) {
 func TestTVShadowStateImplementsInterface(t *testing.T) {
 	var _ PluginShadowState = (*TVShadowState)(nil)
 }
+
+// GrowLightsTracker tests
+
+func TestNewGrowLightsTracker(t *testing.T) {
+	glt := NewGrowLightsTracker()
+	state := glt.GetState()
+
+	if state.Plugin != "growlights" {
+		t.Errorf("Expected plugin name 'growlights', got '%s'", state.Plugin)
+	}
+	if len(state.Outputs.Fixtures) != 0 {
+		t.Errorf("Expected no fixtures, got %d", len(state.Outputs.Fixtures))
+	}
+}
+
+func TestGrowLightsTrackerUpdateEvaluation(t *testing.T) {
+	glt := NewGrowLightsTracker()
+
+	glt.UpdateEvaluation(10.5, true)
+
+	state := glt.GetState()
+	if state.Outputs.NaturalDayLengthHours != 10.5 {
+		t.Errorf("Expected natural day length 10.5, got %f", state.Outputs.NaturalDayLengthHours)
+	}
+	if !state.Outputs.Deferred {
+		t.Error("Expected deferred to be true")
+	}
+	if state.Outputs.LastEvaluation.IsZero() {
+		t.Error("Expected LastEvaluation to be set")
+	}
+}
+
+func TestGrowLightsTrackerUpdateFixture(t *testing.T) {
+	glt := NewGrowLightsTracker()
+
+	start := time.Date(2025, 1, 15, 17, 0, 0, 0, time.UTC)
+	glt.UpdateFixture("Herbs", GrowLightFixtureState{
+		EntityID:          "switch.herb_light",
+		On:                true,
+		SupplementalHours: 4,
+		Windows:           []GrowLightWindow{{Start: start, End: start.Add(4 * time.Hour)}},
+		Reason:            "Within supplemental lighting window",
+	})
+
+	fixture, ok := glt.GetState().Outputs.Fixtures["Herbs"]
+	if !ok {
+		t.Fatal("Expected fixture 'Herbs' to be recorded")
+	}
+	if !fixture.On {
+		t.Error("Expected fixture to be on")
+	}
+	if len(fixture.Windows) != 1 {
+		t.Errorf("Expected 1 window, got %d", len(fixture.Windows))
+	}
+}
+
+func TestGrowLightsTrackerGetStateReturnsDeepCopy(t *testing.T) {
+	glt := NewGrowLightsTracker()
+
+	start := time.Date(2025, 1, 15, 17, 0, 0, 0, time.UTC)
+	glt.UpdateFixture("Herbs", GrowLightFixtureState{
+		Windows: []GrowLightWindow{{Start: start, End: start.Add(time.Hour)}},
+	})
+
+	// Modify the returned state
+	state1 := glt.GetState()
+	state1.Outputs.Fixtures["Herbs"].Windows[0] = GrowLightWindow{}
+	state1.Outputs.Fixtures["Other"] = GrowLightFixtureState{}
+
+	// Original should be unchanged
+	state2 := glt.GetState()
+	if !state2.Outputs.Fixtures["Herbs"].Windows[0].Start.Equal(start) {
+		t.Error("Modifying returned windows affected the internal state")
+	}
+	if _, ok := state2.Outputs.Fixtures["Other"]; ok {
+		t.Error("Modifying returned fixtures affected the internal state")
+	}
+}
+
+func TestGrowLightsTrackerConcurrentAccess(t *testing.T) {
+	glt := NewGrowLightsTracker()
+
+	var wg sync.WaitGroup
+
+	// Concurrent writes
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				glt.UpdateEvaluation(float64(j), i%2 == 0)
+				glt.UpdateFixture(fmt.Sprintf("fixture-%d", i), GrowLightFixtureState{On: j%2 == 0})
+			}
+		}(i)
+	}
+
+	// Concurrent reads
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = glt.GetState()
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestGrowLightsShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*GrowLightsShadowState)(nil)
+}
+
+// BedroomComfortTracker tests
+
+func TestNewBedroomComfortTracker(t *testing.T) {
+	bct := NewBedroomComfortTracker()
+	state := bct.GetState()
+
+	if state.Plugin != "bedroomcomfort" {
+		t.Errorf("Expected plugin name 'bedroomcomfort', got '%s'", state.Plugin)
+	}
+	if state.Outputs.NightActive {
+		t.Error("Expected night to be inactive")
+	}
+	if len(state.Outputs.Adjustments) != 0 {
+		t.Errorf("Expected no adjustments, got %d", len(state.Outputs.Adjustments))
+	}
+}
+
+func TestBedroomComfortTrackerRecordAdjustment(t *testing.T) {
+	bct := NewBedroomComfortTracker()
+	started := time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC)
+	bct.StartNight(started, 4)
+	bct.UpdateCurrentInputs(map[string]interface{}{"temperature": 72.0})
+
+	target := 69.0
+	bct.RecordAdjustment(BedroomComfortAdjustment{
+		Time:              started.Add(time.Hour),
+		Action:            "lower_setpoint",
+		Temperature:       72.0,
+		TargetTemperature: &target,
+		Reason:            "Temperature above comfort band",
+	}, true)
+	bct.RecordAdjustment(BedroomComfortAdjustment{
+		Time:   started.Add(8 * time.Hour),
+		Action: "restore",
+		Reason: "Woke up",
+	}, false)
+
+	state := bct.GetState()
+	if state.Outputs.AdjustmentsTonight != 1 {
+		t.Errorf("Expected 1 counted adjustment, got %d", state.Outputs.AdjustmentsTonight)
+	}
+	if len(state.Outputs.Adjustments) != 2 {
+		t.Errorf("Expected 2 logged adjustments, got %d", len(state.Outputs.Adjustments))
+	}
+	if !state.GetLastActionTime().Equal(started.Add(8 * time.Hour)) {
+		t.Errorf("Expected last action time to be the restore, got %v", state.GetLastActionTime())
+	}
+	if state.Inputs.AtLastAction["temperature"] != 72.0 {
+		t.Error("Expected inputs to be snapshotted at last action")
+	}
+
+	// Starting a new night clears the log and count
+	bct.StartNight(started.Add(24*time.Hour), 4)
+	state = bct.GetState()
+	if state.Outputs.AdjustmentsTonight != 0 || len(state.Outputs.Adjustments) != 0 {
+		t.Error("Expected StartNight to clear tonight's adjustments")
+	}
+}
+
+func TestBedroomComfortTrackerGetStateReturnsDeepCopy(t *testing.T) {
+	bct := NewBedroomComfortTracker()
+	target := 69.0
+	bct.RecordAdjustment(BedroomComfortAdjustment{Action: "lower_setpoint", TargetTemperature: &target}, true)
+
+	state1 := bct.GetState()
+	*state1.Outputs.TargetTemperature = 50
+	*state1.Outputs.Adjustments[0].TargetTemperature = 50
+	state1.Outputs.Adjustments[0].Action = "modified"
+
+	state2 := bct.GetState()
+	if *state2.Outputs.TargetTemperature != 69.0 {
+		t.Error("Modifying returned target temperature affected the internal state")
+	}
+	if *state2.Outputs.Adjustments[0].TargetTemperature != 69.0 || state2.Outputs.Adjustments[0].Action != "lower_setpoint" {
+		t.Error("Modifying returned adjustments affected the internal state")
+	}
+}
+
+func TestBedroomComfortShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*BedroomComfortShadowState)(nil)
+	var _ ActionTimeProvider = (*BedroomComfortShadowState)(nil)
+}
+
+// OpenReminderTracker tests
+
+func TestNewOpenReminderTracker(t *testing.T) {
+	ort := NewOpenReminderTracker()
+	state := ort.GetState()
+
+	if state.Plugin != "openreminder" {
+		t.Errorf("Expected plugin name 'openreminder', got '%s'", state.Plugin)
+	}
+	if state.Outputs.Active {
+		t.Error("Expected no active reminder")
+	}
+}
+
+func TestOpenReminderTrackerRecordReminderAndEnd(t *testing.T) {
+	ort := NewOpenReminderTracker()
+	start := time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC)
+	open := []OpenSensorInfo{{EntityID: "cover.garage_door_door", Name: "Garage door", Room: "Garage"}}
+
+	ort.UpdateCurrentInputs(map[string]interface{}{"isEveryoneAsleep": true})
+	ort.RecordReminder("isEveryoneAsleep", open, 1, "Garage door is still open", start)
+	ort.RecordReminder("isEveryoneAsleep", open, 2, "Garage door is still open", start.Add(15*time.Minute))
+
+	state := ort.GetState()
+	if !state.Outputs.Active {
+		t.Error("Expected reminder to be active")
+	}
+	if state.Outputs.RemindersSent != 2 {
+		t.Errorf("Expected 2 reminders sent, got %d", state.Outputs.RemindersSent)
+	}
+	if !state.Outputs.StartedAt.Equal(start) {
+		t.Errorf("Expected start time to be kept from the first reminder, got %v", state.Outputs.StartedAt)
+	}
+	if !state.GetLastActionTime().Equal(start.Add(15 * time.Minute)) {
+		t.Errorf("Expected last action time of the second reminder, got %v", state.GetLastActionTime())
+	}
+	if state.Inputs.AtLastAction["isEveryoneAsleep"] != true {
+		t.Error("Expected inputs to be snapshotted at last action")
+	}
+
+	ort.RecordEnded("isEveryoneAsleep", "Acknowledged")
+	state = ort.GetState()
+	if state.Outputs.Active {
+		t.Error("Expected reminder to be inactive after RecordEnded")
+	}
+	if state.Outputs.EndedReason != "Acknowledged" {
+		t.Errorf("Expected ended reason 'Acknowledged', got '%s'", state.Outputs.EndedReason)
+	}
+}
+
+func TestOpenReminderTrackerGetStateReturnsDeepCopy(t *testing.T) {
+	ort := NewOpenReminderTracker()
+	ort.RecordReminder("isAnyoneHome", []OpenSensorInfo{{Name: "Back door"}}, 1, "Back door is still open", time.Now())
+
+	state1 := ort.GetState()
+	state1.Outputs.OpenSensors[0].Name = "modified"
+
+	state2 := ort.GetState()
+	if state2.Outputs.OpenSensors[0].Name != "Back door" {
+		t.Error("Modifying returned open sensors affected the internal state")
+	}
+}
+
+func TestOpenReminderShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*OpenReminderShadowState)(nil)
+	var _ ActionTimeProvider = (*OpenReminderShadowState)(nil)
+}
+
+func TestRulesTrackerRecordSkippedAndRun(t *testing.T) {
+	rt := NewRulesTracker()
+	rt.AddRule("porch_light", "Porch light on arrival")
+	at := time.Date(2025, 6, 16, 21, 0, 0, 0, time.UTC)
+
+	rt.UpdateCurrentInput("dayPhase", "day")
+	rt.RecordSkipped("porch_light", "isAnyoneHome: false -> true", "condition not met: dayPhase in [dusk night]", at)
+
+	state := rt.GetState()
+	if state.Outputs.Rules[0].RunCount != 0 || !state.GetLastActionTime().IsZero() {
+		t.Error("A skipped rule should not count as an action")
+	}
+
+	rt.UpdateCurrentInput("dayPhase", "dusk")
+	rt.RecordRun("porch_light", "isAnyoneHome: false -> true", []string{"light.turn_on light.porch"}, "ran", at.Add(time.Hour))
+	rt.RecordRun("unknown", "cron 0 22 * * *", nil, "ran", at.Add(2*time.Hour))
+
+	state = rt.GetState()
+	status := state.Outputs.Rules[0]
+	if status.RunCount != 1 || status.LastResult != "ran" {
+		t.Errorf("Expected one run, got %d (%s)", status.RunCount, status.LastResult)
+	}
+	if !state.GetLastActionTime().Equal(at.Add(time.Hour)) {
+		t.Errorf("Expected last action time of the run, got %v", state.GetLastActionTime())
+	}
+	if state.GetLastActionReason() != "porch_light (isAnyoneHome: false -> true)" {
+		t.Errorf("Unexpected last action reason %q", state.GetLastActionReason())
+	}
+	if state.Inputs.AtLastAction["dayPhase"] != "dusk" {
+		t.Error("Expected inputs to be snapshotted at last action")
+	}
+}
+
+func TestRulesTrackerGetStateReturnsDeepCopy(t *testing.T) {
+	rt := NewRulesTracker()
+	rt.AddRule("porch_light", "")
+	rt.RecordRun("porch_light", "cron 0 22 * * *", []string{"light.turn_on light.porch"}, "ran", time.Now())
+
+	state1 := rt.GetState()
+	state1.Outputs.Rules[0].LastActions[0] = "modified"
+	state1.Outputs.Rules[0].Name = "modified"
+
+	state2 := rt.GetState()
+	if state2.Outputs.Rules[0].LastActions[0] != "light.turn_on light.porch" || state2.Outputs.Rules[0].Name != "porch_light" {
+		t.Error("Modifying returned rules affected the internal state")
+	}
+}
+
+func TestRulesShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*RulesShadowState)(nil)
+	var _ ActionTimeProvider = (*RulesShadowState)(nil)
+	var _ ActionReasonProvider = (*RulesShadowState)(nil)
+}
+
+func TestSceneSchedulerTrackerRecordSkippedAndActivation(t *testing.T) {
+	st := NewSceneSchedulerTracker()
+	st.AddSchedule("evening", "scene.evening", "sunset-30m0s")
+	at := time.Date(2025, 6, 16, 20, 0, 0, 0, time.UTC)
+
+	st.UpdateCurrentInput("isAnyoneHome", false)
+	st.RecordSkipped("evening", "skipped: nobody home", at)
+
+	state := st.GetState()
+	if state.Outputs.Schedules[0].RunCount != 0 || !state.GetLastActionTime().IsZero() {
+		t.Error("A skipped schedule should not count as an action")
+	}
+
+	st.UpdateCurrentInput("isAnyoneHome", true)
+	st.RecordActivation("evening", "activated", at.Add(24*time.Hour))
+	st.RecordActivation("unknown", "activated", at.Add(48*time.Hour))
+
+	state = st.GetState()
+	status := state.Outputs.Schedules[0]
+	if status.RunCount != 1 || status.LastResult != "activated" {
+		t.Errorf("Expected one activation, got %d (%s)", status.RunCount, status.LastResult)
+	}
+	if !state.GetLastActionTime().Equal(at.Add(24 * time.Hour)) {
+		t.Errorf("Expected last action time of the activation, got %v", state.GetLastActionTime())
+	}
+	if state.GetLastActionReason() != "evening (scene.evening)" {
+		t.Errorf("Unexpected last action reason %q", state.GetLastActionReason())
+	}
+	if state.Inputs.AtLastAction["isAnyoneHome"] != true {
+		t.Error("Expected inputs to be snapshotted at last action")
+	}
+}
+
+func TestSceneSchedulerTrackerGetStateReturnsCopy(t *testing.T) {
+	st := NewSceneSchedulerTracker()
+	st.AddSchedule("evening", "scene.evening", "0 19 * * *")
+
+	state1 := st.GetState()
+	state1.Outputs.Schedules[0].Name = "modified"
+
+	state2 := st.GetState()
+	if state2.Outputs.Schedules[0].Name != "evening" {
+		t.Error("Modifying returned schedules affected the internal state")
+	}
+}
+
+func TestSceneSchedulerShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*SceneSchedulerShadowState)(nil)
+	var _ ActionTimeProvider = (*SceneSchedulerShadowState)(nil)
+	var _ ActionReasonProvider = (*SceneSchedulerShadowState)(nil)
+}
+
+func TestSleepFanTrackerRecordSpeedChange(t *testing.T) {
+	st := NewSleepFanTracker()
+	st.AddBedroom("primary_suite", "fan.primary_suite")
+	at := time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC)
+	temperature := 71.5
+
+	st.UpdateCurrentInputs(map[string]interface{}{"isMasterAsleep": true})
+	st.UpdateConditions("primary_suite", true, false, &temperature, 40)
+	st.RecordSpeedChange("primary_suite", 20, "Temperature 71.5 calls for 40%", at)
+	st.RecordSpeedChange("unknown", 50, "ignored", at.Add(time.Hour))
+
+	state := st.GetState()
+	status := state.Outputs.Bedrooms[0]
+	if !status.Asleep || status.TargetSpeed != 40 || status.Speed != 20 {
+		t.Errorf("Unexpected bedroom status %+v", status)
+	}
+	if status.Temperature == nil || *status.Temperature != 71.5 {
+		t.Errorf("Expected temperature 71.5, got %v", status.Temperature)
+	}
+	if !state.GetLastActionTime().Equal(at) {
+		t.Errorf("Expected last action time %v, got %v", at, state.GetLastActionTime())
+	}
+	if state.GetLastActionReason() != "primary_suite: Temperature 71.5 calls for 40%" {
+		t.Errorf("Unexpected last action reason %q", state.GetLastActionReason())
+	}
+	if state.Inputs.AtLastAction["isMasterAsleep"] != true {
+		t.Error("Expected inputs to be snapshotted at last action")
+	}
+}
+
+func TestSleepFanTrackerGetStateReturnsCopy(t *testing.T) {
+	st := NewSleepFanTracker()
+	st.AddBedroom("primary_suite", "fan.primary_suite")
+	temperature := 70.0
+	st.UpdateConditions("primary_suite", true, false, &temperature, 40)
+
+	state1 := st.GetState()
+	state1.Outputs.Bedrooms[0].Name = "modified"
+	*state1.Outputs.Bedrooms[0].Temperature = 80
+
+	state2 := st.GetState()
+	if state2.Outputs.Bedrooms[0].Name != "primary_suite" || *state2.Outputs.Bedrooms[0].Temperature != 70 {
+		t.Error("Modifying returned bedrooms affected the internal state")
+	}
+}
+
+func TestSleepFanShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*SleepFanShadowState)(nil)
+	var _ ActionTimeProvider = (*SleepFanShadowState)(nil)
+	var _ ActionReasonProvider = (*SleepFanShadowState)(nil)
+}
+
+func TestFreezeProtectionTrackerRecordMitigationAndAlert(t *testing.T) {
+	ft := NewFreezeProtectionTracker()
+	ft.AddSpace("garage")
+	at := time.Date(2025, 1, 15, 3, 0, 0, 0, time.UTC)
+	temperature := 37.0
+
+	ft.UpdateCurrentInputs(map[string]interface{}{"currentEnergyLevel": "green"})
+	ft.UpdateConditions("garage", &temperature, "green", 38, 42, true)
+	ft.RecordMitigation("garage", true, false, at, &temperature, "Temperature 37.0 below 38.0", at)
+	ft.RecordAlert("garage", "Garage is at 34.0 and still dropping", at.Add(time.Hour))
+	ft.RecordMitigation("unknown", true, true, at, nil, "ignored", at.Add(2*time.Hour))
+
+	state := ft.GetState()
+	status := state.Outputs.Spaces[0]
+	if !status.HeatersOn || status.DampersOpen || !status.HeatersAllowed || status.HeaterOnBelow != 38 {
+		t.Errorf("Unexpected space status %+v", status)
+	}
+	if status.StartTemperature == nil || *status.StartTemperature != 37 {
+		t.Errorf("Expected start temperature 37, got %v", status.StartTemperature)
+	}
+	if !status.LastAlert.Equal(at.Add(time.Hour)) {
+		t.Errorf("Expected alert at %v, got %v", at.Add(time.Hour), status.LastAlert)
+	}
+	if state.GetLastActionReason() != "garage: Garage is at 34.0 and still dropping" {
+		t.Errorf("Unexpected last action reason %q", state.GetLastActionReason())
+	}
+	if state.Inputs.AtLastAction["currentEnergyLevel"] != "green" {
+		t.Error("Expected inputs to be snapshotted at last action")
+	}
+}
+
+func TestFreezeProtectionTrackerGetStateReturnsCopy(t *testing.T) {
+	ft := NewFreezeProtectionTracker()
+	ft.AddSpace("garage")
+	temperature := 40.0
+	ft.UpdateConditions("garage", &temperature, "green", 38, 42, true)
+
+	state1 := ft.GetState()
+	state1.Outputs.Spaces[0].Name = "modified"
+	*state1.Outputs.Spaces[0].Temperature = 20
+
+	state2 := ft.GetState()
+	if state2.Outputs.Spaces[0].Name != "garage" || *state2.Outputs.Spaces[0].Temperature != 40 {
+		t.Error("Modifying returned spaces affected the internal state")
+	}
+}
+
+func TestFreezeProtectionShadowStateImplementsInterface(t *testing.T) {
+	var _ PluginShadowState = (*FreezeProtectionShadowState)(nil)
+	var _ ActionTimeProvider = (*FreezeProtectionShadowState)(nil)
+	var _ ActionReasonProvider = (*FreezeProtectionShadowState)(nil)
+}
+
+func TestEmergencyTracker(t *testing.T) {
+	et := NewEmergencyTracker()
+	at := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	et.UpdateCurrentInputs(map[string]interface{}{"isEmergencyAcknowledged": false})
+	et.RecordActivation(true, "smoke: Kitchen", at)
+	et.RecordAnnouncement(at)
+	et.RecordAcknowledged(true, at.Add(time.Minute))
+
+	outputs := et.GetState().Outputs
+	if !outputs.Active || !outputs.Acknowledged || outputs.Announcements != 1 || outputs.LastActionReason != "acknowledged" {
+		t.Errorf("Unexpected outputs after activation and acknowledgement: %+v", outputs)
+	}
+	if _, ok := et.GetState().Inputs.AtLastAction["isEmergencyAcknowledged"]; !ok {
+		t.Error("Expected the inputs to be snapshotted at the last action")
+	}
+
+	for i := 0; i < MaxEmergencyActions+5; i++ {
+		et.RecordAction(EmergencyAction{Timestamp: at, Action: "unlock", Target: fmt.Sprintf("lock.%d", i), Success: true})
+	}
+	actions := et.GetState().Outputs.Actions
+	if len(actions) != MaxEmergencyActions {
+		t.Fatalf("Expected %d actions, got %d", MaxEmergencyActions, len(actions))
+	}
+	if last := actions[len(actions)-1]; last.Target != fmt.Sprintf("lock.%d", MaxEmergencyActions+4) {
+		t.Errorf("Expected the most recent action last, got %q", last.Target)
+	}
+
+	actions[0].Target = "modified"
+	if et.GetState().Outputs.Actions[0].Target == "modified" {
+		t.Error("Modifying returned actions affected the internal state")
+	}
+
+	et.RecordActivation(false, "all detectors clear", at.Add(time.Hour))
+	outputs = et.GetState().Outputs
+	if outputs.Active || outputs.Acknowledged || outputs.Announcements != 0 {
+		t.Errorf("Expected the emergency to be cleared, got %+v", outputs)
+	}
+}
@@ -10,6 +10,16 @@ type PluginShadowState interface {
 	GetMetadata() StateMetadata
 }
 
+// ActionTimeProvider is implemented by shadow states that record when the plugin last acted
+type ActionTimeProvider interface {
+	GetLastActionTime() time.Time
+}
+
+// ActionReasonProvider is implemented by shadow states that record why the plugin last acted
+type ActionReasonProvider interface {
+	GetLastActionReason() string
+}
+
 // InputSnapshot represents a snapshot of input values at a specific time
 type InputSnapshot struct {
 	Timestamp time.Time              `json:"timestamp"`
@@ -46,8 +56,58 @@ type LightingInputs struct {
 
 // LightingOutputs tracks the state of lighting control outputs
 type LightingOutputs struct {
-	Rooms          map[string]RoomState `json:"rooms"`
-	LastActionTime time.Time            `json:"lastActionTime"`
+	Rooms          map[string]RoomState     `json:"rooms"`
+	OnBudgets      map[string]OnBudgetState `json:"onBudgets"` // Room name -> daily on-time budget
+	GridOutage     *GridOutageDimmingState  `json:"gridOutage,omitempty"`
+	LastActionTime time.Time                `json:"lastActionTime"`
+
+	// Room name -> motion override holding the room in its occupied scene
+	MotionOverrides map[string]MotionOverrideState `json:"motionOverrides,omitempty"`
+
+	// Room name -> manual change being respected
+	ManualOverrides map[string]ManualOverrideState `json:"manualOverrides,omitempty"`
+}
+
+// ManualOverrideState represents a room left alone after someone changed its
+// lights outside this service
+type ManualOverrideState struct {
+	LightEntity    string    `json:"lightEntity"`
+	LightState     string    `json:"lightState"` // "on" or "off" after the manual change
+	Since          time.Time `json:"since"`
+	Until          time.Time `json:"until"`
+	SkippedActions int       `json:"skippedActions"`        // Scene decisions not applied while respected
+	LastSkipped    string    `json:"lastSkipped,omitempty"` // Trigger of the last skipped decision
+	LastSkippedAt  time.Time `json:"lastSkippedAt,omitempty"`
+}
+
+// MotionOverrideState represents a room raised to its occupied scene by motion
+type MotionOverrideState struct {
+	Scene      string    `json:"scene"`
+	Sensor     string    `json:"sensor"` // Sensor that started the override
+	Since      time.Time `json:"since"`
+	PriorOn    bool      `json:"priorOn"`              // Whether the room's lights were on before
+	ClearSince time.Time `json:"clearSince,omitempty"` // When every sensor went clear, zero while there is motion
+	RevertAt   time.Time `json:"revertAt,omitempty"`
+	Deferred   string    `json:"deferred,omitempty"` // Day phase decision held back until the override ends: "activate_scene" or "turn_off"
+}
+
+// GridOutageDimmingState represents night-time dimming while the grid is down
+type GridOutageDimmingState struct {
+	Active           bool      `json:"active"`
+	BrightnessCapPct int       `json:"brightnessCapPct"`
+	DecorativeRooms  []string  `json:"decorativeRooms,omitempty"` // Rooms kept off while active
+	Since            time.Time `json:"since,omitempty"`
+}
+
+// OnBudgetState represents a room's daily on-time budget
+type OnBudgetState struct {
+	LightEntity     string    `json:"lightEntity"`
+	Day             string    `json:"day"` // Local date the consumed time applies to (YYYY-MM-DD)
+	BudgetMinutes   float64   `json:"budgetMinutes"`
+	ConsumedMinutes float64   `json:"consumedMinutes"`
+	LightsOn        bool      `json:"lightsOn"`
+	Exhausted       bool      `json:"exhausted"`
+	ExhaustedAt     time.Time `json:"exhaustedAt,omitempty"`
 }
 
 // RoomState represents the state of a single room
@@ -57,6 +117,42 @@ type RoomState struct {
 	LastAction  time.Time `json:"lastAction"`
 	ActionType  string    `json:"actionType"` // "activate_scene" or "turn_off"
 	Reason      string    `json:"reason"`
+	RoomActionDetail
+}
+
+// RoomActionDetail records what a room action sent to Home Assistant and why
+type RoomActionDetail struct {
+	Service            string                 `json:"service,omitempty"`            // e.g. "scene.turn_on"
+	ServiceData        map[string]interface{} `json:"serviceData,omitempty"`        // Data sent (or that would be sent) with the call
+	SceneEntityID      string                 `json:"sceneEntityId,omitempty"`      // Resolved scene entity, e.g. "scene.living_room_evening"
+	TargetEntities     []string               `json:"targetEntities,omitempty"`     // Lights the scene sets, from its entity_id attribute
+	SceneValues        map[string]interface{} `json:"sceneValues,omitempty"`        // Brightness and color attributes of the scene
+	BrightnessCapPct   int                    `json:"brightnessCapPct,omitempty"`   // Grid outage cap applied after the scene
+	AmbientLux         *float64               `json:"ambientLux,omitempty"`         // Room lux reading adaptive brightness used
+	BrightnessScalePct int                    `json:"brightnessScalePct,omitempty"` // Percentage of the scene's brightness set for the ambient light
+	Conditions         []ConditionEvaluation  `json:"conditions,omitempty"`         // The room's conditions as evaluated for this action
+}
+
+// ConditionEvaluation is one room condition as evaluated for an action
+type ConditionEvaluation struct {
+	Kind     string `json:"kind"` // on_if_true, on_if_false, off_if_true or off_if_false
+	Variable string `json:"variable"`
+	Value    bool   `json:"value"`
+	Met      bool   `json:"met"` // Whether the condition asks for its kind's action
+	Error    string `json:"error,omitempty"`
+}
+
+// clone returns a copy that shares no maps or slices with d
+func (d RoomActionDetail) clone() RoomActionDetail {
+	d.ServiceData = copyMap(d.ServiceData)
+	d.SceneValues = copyMap(d.SceneValues)
+	d.TargetEntities = append([]string(nil), d.TargetEntities...)
+	d.Conditions = append([]ConditionEvaluation(nil), d.Conditions...)
+	if d.AmbientLux != nil {
+		lux := *d.AmbientLux
+		d.AmbientLux = &lux
+	}
+	return d
 }
 
 // GetCurrentInputs implements PluginShadowState
@@ -79,6 +175,11 @@ func (l *LightingShadowState) GetMetadata() StateMetadata {
 	return l.Metadata
 }
 
+// GetLastActionTime implements ActionTimeProvider
+func (l *LightingShadowState) GetLastActionTime() time.Time {
+	return l.Outputs.LastActionTime
+}
+
 // NewLightingShadowState creates a new lighting shadow state
 func NewLightingShadowState() *LightingShadowState {
 	return &LightingShadowState{
@@ -88,8 +189,11 @@ func NewLightingShadowState() *LightingShadowState {
 			AtLastAction: make(map[string]interface{}),
 		},
 		Outputs: LightingOutputs{
-			Rooms:          make(map[string]RoomState),
-			LastActionTime: time.Time{},
+			Rooms:           make(map[string]RoomState),
+			OnBudgets:       make(map[string]OnBudgetState),
+			MotionOverrides: make(map[string]MotionOverrideState),
+			ManualOverrides: make(map[string]ManualOverrideState),
+			LastActionTime:  time.Time{},
 		},
 		Metadata: StateMetadata{
 			LastUpdated: time.Now(),
@@ -128,14 +232,73 @@ type SecurityInputs struct {
 
 // MusicOutputs tracks the state of music control outputs
 type MusicOutputs struct {
-	CurrentMode      string         `json:"currentMode,omitempty"` // e.g., "morning", "working", "evening"
-	ActivePlaylist   PlaylistInfo   `json:"activePlaylist,omitempty"`
-	SpeakerGroup     []SpeakerState `json:"speakerGroup,omitempty"`
-	FadeState        string         `json:"fadeState"`        // "idle", "fading_in", "fading_out"
-	PlaylistRotation map[string]int `json:"playlistRotation"` // Music type -> playlist number
-	LastActionTime   time.Time      `json:"lastActionTime"`
-	LastActionType   string         `json:"lastActionType,omitempty"` // "select_mode", "start_playback", "fade_out", etc.
-	LastActionReason string         `json:"lastActionReason,omitempty"`
+	CurrentMode      string                  `json:"currentMode,omitempty"` // e.g., "morning", "working", "evening"
+	ActivePlaylist   PlaylistInfo            `json:"activePlaylist,omitempty"`
+	SpeakerGroup     []SpeakerState          `json:"speakerGroup,omitempty"`
+	SpeakerPreset    string                  `json:"speakerPreset,omitempty"`    // Active speaker group preset, empty for the mode's own speakers
+	FadeState        string                  `json:"fadeState"`                  // "idle", "fading_in", "fading_out"
+	PlaylistRotation map[string]int          `json:"playlistRotation"`           // Music type -> playlist number
+	TasteBlend       *TasteBlend             `json:"tasteBlend,omitempty"`       // How who is home weighted the last playlist selection
+	GroupCheck       *GroupCheck             `json:"groupCheck,omitempty"`       // Last comparison of the Sonos group with the participants
+	Zones            map[string]ZonePlayback `json:"zones,omitempty"`            // Zones playing a music mode of their own
+	NowPlaying       *NowPlaying             `json:"nowPlaying,omitempty"`       // Track on the lead player, nil while nothing plays
+	PlaybackFailures []PlaybackFailure       `json:"playbackFailures,omitempty"` // Most recent playlists that failed to start, oldest first
+	LastActionTime   time.Time               `json:"lastActionTime"`
+	LastActionType   string                  `json:"lastActionType,omitempty"` // "select_mode", "start_playback", "fade_out", etc.
+	LastActionReason string                  `json:"lastActionReason,omitempty"`
+}
+
+// TasteBlend explains how a taste profile weighted a playlist selection
+type TasteBlend struct {
+	MusicType  string             `json:"musicType"`
+	Profile    string             `json:"profile,omitempty"` // Empty when no profile matched who is home
+	PeopleHome []string           `json:"peopleHome"`        // Presence variables that were true
+	Weights    map[string]float64 `json:"weights,omitempty"` // Playlist URI -> weight used for the selection
+	Selected   string             `json:"selected"`          // Chosen playlist URI
+	Rationale  string             `json:"rationale"`
+	SelectedAt time.Time          `json:"selectedAt"`
+}
+
+// GroupCheck records the last comparison of the lead player's Sonos group
+// with the participants the music plugin started playback on
+type GroupCheck struct {
+	CheckedAt  time.Time `json:"checkedAt"`
+	LeadPlayer string    `json:"leadPlayer"`
+	InSync     bool      `json:"inSync"`
+	Missing    []string  `json:"missing,omitempty"` // Participants no longer in the group
+	Extra      []string  `json:"extra,omitempty"`   // Speakers in the group that aren't participants
+	Action     string    `json:"action,omitempty"`  // "rejoined", "followed", or empty when nothing was done
+	Problem    string    `json:"problem,omitempty"` // Why the group couldn't be checked or fixed
+}
+
+// PlaybackFailure records a playback option that failed to start
+type PlaybackFailure struct {
+	MusicType  string    `json:"musicType"`
+	URI        string    `json:"uri"`
+	Reason     string    `json:"reason"`
+	FellBackTo string    `json:"fellBackTo,omitempty"` // Playlist URI tried next, empty when every option had failed
+	FailedAt   time.Time `json:"failedAt"`
+}
+
+// NowPlaying is the track the lead player reports for the current playback
+type NowPlaying struct {
+	MusicType   string    `json:"musicType"`
+	PlaylistURI string    `json:"playlistUri"`
+	LeadPlayer  string    `json:"leadPlayer"`            // Entity ID, e.g. "media_player.kitchen"
+	PlayerState string    `json:"playerState,omitempty"` // e.g. "playing", "paused"
+	Title       string    `json:"title,omitempty"`
+	Artist      string    `json:"artist,omitempty"`
+	Album       string    `json:"album,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ZonePlayback is a zone playing a music mode of its own, apart from the
+// whole-house mode
+type ZonePlayback struct {
+	MusicType string         `json:"musicType"`
+	Playlist  PlaylistInfo   `json:"playlist"`
+	Speakers  []SpeakerState `json:"speakers"`
+	StartedAt time.Time      `json:"startedAt"`
 }
 
 // PlaylistInfo represents the currently playing playlist
@@ -174,6 +337,16 @@ func (m *MusicShadowState) GetMetadata() StateMetadata {
 	return m.Metadata
 }
 
+// GetLastActionTime implements ActionTimeProvider
+func (m *MusicShadowState) GetLastActionTime() time.Time {
+	return m.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (m *MusicShadowState) GetLastActionReason() string {
+	return m.Outputs.LastActionReason
+}
+
 // NewMusicShadowState creates a new music shadow state
 func NewMusicShadowState() *MusicShadowState {
 	return &MusicShadowState{
@@ -196,11 +369,192 @@ func NewMusicShadowState() *MusicShadowState {
 
 // SecurityOutputs tracks the state of security control outputs
 type SecurityOutputs struct {
-	Lockdown       LockdownState        `json:"lockdown"`
-	LastDoorbell   *DoorbellEvent       `json:"lastDoorbell,omitempty"`
-	LastVehicle    *VehicleArrivalEvent `json:"lastVehicle,omitempty"`
-	LastGarageOpen *GarageOpenEvent     `json:"lastGarageOpen,omitempty"`
-	LastActionTime time.Time            `json:"lastActionTime"`
+	Lockdown       LockdownState             `json:"lockdown"`
+	LastDoorbell   *DoorbellEvent            `json:"lastDoorbell,omitempty"`
+	LastVehicle    *VehicleArrivalEvent      `json:"lastVehicle,omitempty"`
+	LastGarageOpen *GarageOpenEvent          `json:"lastGarageOpen,omitempty"`
+	CameraPrivacy  CameraPrivacyState        `json:"cameraPrivacy"`
+	RateLimits     map[string]RateLimitState `json:"rateLimits"` // Keyed by event type (doorbell, vehicle_arrival)
+	LastDrill      *DrillReport              `json:"lastDrill,omitempty"`
+	DoorHeldOpen   []DoorHeldOpenEvent       `json:"doorHeldOpen"` // Most recent last
+	DeliveryWindow DeliveryWindowState       `json:"deliveryWindow"`
+	// PersonDetections are camera person detections, most recent last
+	PersonDetections []PersonDetectionEvent `json:"personDetections"`
+	Alarm            AlarmState             `json:"alarm"`
+	DoorLocks        DoorLocksState         `json:"doorLocks"`
+	LastActionTime   time.Time              `json:"lastActionTime"`
+}
+
+// MaxLockEvents is how many lock audit events are kept
+const MaxLockEvents = 100
+
+// DoorLocksState represents the door locks and their audit trail
+type DoorLocksState struct {
+	Locks []DoorLockStatus `json:"locks"`
+	// Events are lock and unlock events and auto-lock attempts, most recent last
+	Events []LockEvent `json:"events"`
+}
+
+// DoorLockStatus is one lock's last seen state
+type DoorLockStatus struct {
+	EntityID   string    `json:"entityId"`
+	Name       string    `json:"name"`
+	State      string    `json:"state"` // locked, unlocked, jammed, ...
+	DoorOpen   bool      `json:"doorOpen"`
+	AutoLockAt time.Time `json:"autoLockAt,omitempty"` // When the pending auto-lock fires
+}
+
+// LockEvent records a lock changing state, or a lock command we sent
+type LockEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	EntityID  string    `json:"entityId"`
+	Name      string    `json:"name"`
+	// Event is the observed state (locked, unlocked, jammed), or lock for a
+	// lock command we sent
+	Event string `json:"event"`
+	// Source is who or what caused it: auto_lock, lockdown, the lock's
+	// changed_by attribute, a Home Assistant user (user:<id>), or manual
+	Source  string `json:"source"`
+	Success bool   `json:"success"`
+	Detail  string `json:"detail,omitempty"` // Why a lock command was refused, skipped or failed
+}
+
+// MaxAlarmTransitions is how many alarm transitions are kept
+const MaxAlarmTransitions = 50
+
+// AlarmState represents the alarm state machine
+type AlarmState struct {
+	Enabled     bool      `json:"enabled"`
+	Mode        string    `json:"mode"`   // disarmed, home, away, night
+	Status      string    `json:"status"` // disarmed, arming, armed, pending, triggered
+	Since       time.Time `json:"since,omitempty"`
+	DelayEndsAt time.Time `json:"delayEndsAt,omitempty"` // When the exit or entry delay runs out
+	TriggeredBy string    `json:"triggeredBy,omitempty"` // Sensor that started the entry delay or triggered the alarm
+	SirenOn     bool      `json:"sirenOn"`
+	Bypassed    []string  `json:"bypassed"` // Sensors ignored in the current mode
+	// Transitions are mode and status changes, most recent last
+	Transitions []AlarmTransition `json:"transitions"`
+}
+
+// AlarmTransition records one change of the alarm's mode or status
+type AlarmTransition struct {
+	Timestamp time.Time `json:"timestamp"`
+	Mode      string    `json:"mode"`
+	Status    string    `json:"status"`
+	Trigger   string    `json:"trigger"` // api, the mode entity, a presence variable, a sensor or a timer
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail,omitempty"` // Actions that were skipped or failed
+}
+
+// MaxPendingDeliveries is how many quiet doorbell rings are kept for the next
+// delivery summary
+const MaxPendingDeliveries = 50
+
+// DeliveryWindowState represents the delivery window, during which the
+// doorbell is silent indoors and rings wait for the daily summary
+type DeliveryWindowState struct {
+	Active        bool             `json:"active"`
+	Source        string           `json:"source,omitempty"`        // toggle, calendar
+	CalendarEvent string           `json:"calendarEvent,omitempty"` // Title of the matching calendar event
+	Pending       []DeliveryEvent  `json:"pending"`                 // Rings not yet announced, oldest first
+	NextSummaryAt time.Time        `json:"nextSummaryAt,omitempty"`
+	LastSummary   *DeliverySummary `json:"lastSummary,omitempty"`
+}
+
+// DeliveryEvent records a doorbell ring during the delivery window
+type DeliveryEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Snapshot  string    `json:"snapshot,omitempty"` // Camera snapshot file, empty if none was taken
+	Detail    string    `json:"detail,omitempty"`   // Why no snapshot was taken
+}
+
+// DeliverySummary records the announcement of the rings during the window
+type DeliverySummary struct {
+	Timestamp time.Time `json:"timestamp"`
+	Count     int       `json:"count"`
+	Message   string    `json:"message"`
+}
+
+// MaxDoorHeldOpenEvents is how many held-open door alarm events are kept
+const MaxDoorHeldOpenEvents = 50
+
+// DoorHeldOpenEvent records one escalation step of a held-open door alarm,
+// or the door closing after the alarm started
+type DoorHeldOpenEvent struct {
+	Timestamp        time.Time `json:"timestamp"`
+	EntityID         string    `json:"entityId"`
+	Name             string    `json:"name,omitempty"`
+	Event            string    `json:"event"`  // tts, notification, lights, closed
+	Period           string    `json:"period"` // day, night, asleep: which threshold applied
+	OpenedAt         time.Time `json:"openedAt"`
+	ThresholdSeconds int       `json:"thresholdSeconds"`
+	Sent             bool      `json:"sent"`
+	Detail           string    `json:"detail,omitempty"` // Why a step wasn't sent, or how long the door was open
+}
+
+// MaxPersonDetections is how many camera person detections are kept
+const MaxPersonDetections = 50
+
+// PersonDetectionEvent records a camera seeing a person
+type PersonDetectionEvent struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Sensor        string    `json:"sensor"`
+	Name          string    `json:"name,omitempty"`
+	StateVariable string    `json:"stateVariable,omitempty"`
+	Snapshot      string    `json:"snapshot,omitempty"`    // Camera snapshot file, empty if none was taken
+	SnapshotURL   string    `json:"snapshotUrl,omitempty"` // Where the dashboard loads the snapshot from
+	Notified      bool      `json:"notified"`
+	RateLimited   bool      `json:"rateLimited"`
+	Detail        string    `json:"detail,omitempty"` // Why no snapshot was taken or no notification sent
+}
+
+// DrillReport is the verification checklist from a supervised drill
+type DrillReport struct {
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Trigger    string       `json:"trigger"`
+	Checks     []DrillCheck `json:"checks"`
+	Passed     bool         `json:"passed"` // Every check that ran responded
+}
+
+// DrillCheck records whether one actuator responded during a drill
+type DrillCheck struct {
+	Actuator  string   `json:"actuator"` // notification, lights, tts, valve_relay
+	Entities  []string `json:"entities,omitempty"`
+	Responded bool     `json:"responded"`
+	Skipped   bool     `json:"skipped"`
+	Detail    string   `json:"detail,omitempty"`
+}
+
+// RateLimitState represents a notification rate limit policy and its current usage
+type RateLimitState struct {
+	WindowSeconds       int       `json:"windowSeconds"`
+	Burst               int       `json:"burst"`
+	BypassWhenExpecting bool      `json:"bypassWhenExpecting"`
+	SentInWindow        int       `json:"sentInWindow"`
+	Limited             bool      `json:"limited"`                 // Next notification would be suppressed
+	NextAllowedAt       time.Time `json:"nextAllowedAt,omitempty"` // Zero when not limited
+	LastBypassedAt      time.Time `json:"lastBypassedAt,omitempty"`
+}
+
+// MaxCameraPrivacyTransitions is how many privacy mode transitions are kept
+const MaxCameraPrivacyTransitions = 20
+
+// CameraPrivacyState represents the indoor camera privacy mode
+type CameraPrivacyState struct {
+	Enabled     bool                      `json:"enabled"` // true = privacy mode on, cameras not recording
+	Reason      string                    `json:"reason,omitempty"`
+	ChangedAt   time.Time                 `json:"changedAt,omitempty"`
+	Switches    []string                  `json:"switches,omitempty"`
+	Transitions []CameraPrivacyTransition `json:"transitions"` // Most recent last
+}
+
+// CameraPrivacyTransition records one change of camera privacy mode
+type CameraPrivacyTransition struct {
+	Timestamp time.Time `json:"timestamp"`
+	Enabled   bool      `json:"enabled"`
+	Reason    string    `json:"reason"`
+	Trigger   string    `json:"trigger"`
 }
 
 // LockdownState represents the current lockdown status
@@ -209,6 +563,11 @@ type LockdownState struct {
 	Reason      string    `json:"reason,omitempty"`
 	ActivatedAt time.Time `json:"activatedAt,omitempty"`
 	WillResetAt time.Time `json:"willResetAt,omitempty"`
+
+	// Cues applied when lockdown activated
+	CuedLights     []string `json:"cuedLights,omitempty"`
+	PausedSpeakers []string `json:"pausedSpeakers,omitempty"`
+	LightsRestored bool     `json:"lightsRestored"`
 }
 
 // DoorbellEvent represents a doorbell press event
@@ -217,6 +576,8 @@ type DoorbellEvent struct {
 	RateLimited   bool      `json:"rateLimited"`
 	TTSSent       bool      `json:"ttsSent"`
 	LightsFlashed bool      `json:"lightsFlashed"`
+	// DeliveryWindow means the ring was silenced indoors and held for the delivery summary
+	DeliveryWindow bool `json:"deliveryWindow"`
 }
 
 // VehicleArrivalEvent represents a vehicle arrival notification
@@ -254,6 +615,11 @@ func (s *SecurityShadowState) GetMetadata() StateMetadata {
 	return s.Metadata
 }
 
+// GetLastActionTime implements ActionTimeProvider
+func (s *SecurityShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
 // NewSecurityShadowState creates a new security shadow state
 func NewSecurityShadowState() *SecurityShadowState {
 	return &SecurityShadowState{
@@ -263,7 +629,26 @@ func NewSecurityShadowState() *SecurityShadowState {
 			AtLastAction: make(map[string]interface{}),
 		},
 		Outputs: SecurityOutputs{
-			Lockdown:       LockdownState{},
+			Lockdown: LockdownState{},
+			CameraPrivacy: CameraPrivacyState{
+				Transitions: []CameraPrivacyTransition{},
+			},
+			RateLimits:   make(map[string]RateLimitState),
+			DoorHeldOpen: []DoorHeldOpenEvent{},
+			DeliveryWindow: DeliveryWindowState{
+				Pending: []DeliveryEvent{},
+			},
+			PersonDetections: []PersonDetectionEvent{},
+			Alarm: AlarmState{
+				Mode:        "disarmed",
+				Status:      "disarmed",
+				Bypassed:    []string{},
+				Transitions: []AlarmTransition{},
+			},
+			DoorLocks: DoorLocksState{
+				Locks:  []DoorLockStatus{},
+				Events: []LockEvent{},
+			},
 			LastActionTime: time.Time{},
 		},
 		Metadata: StateMetadata{
@@ -308,6 +693,18 @@ type LoadSheddingOutputs struct {
 	LastActionReason   string             `json:"lastActionReason,omitempty"`
 	ThermostatSettings ThermostatSettings `json:"thermostatSettings,omitempty"`
 	LastActionTime     time.Time          `json:"lastActionTime"`
+
+	// Tiered devices currently shed, in the order they were shed
+	ShedDevices []ShedDevice `json:"shedDevices,omitempty"`
+}
+
+// ShedDevice is a tiered device load shedding turned off or down
+type ShedDevice struct {
+	EntityID string    `json:"entityId"`
+	Tier     int       `json:"tier"`
+	Previous string    `json:"previous"` // State it is restored to
+	Restore  string    `json:"restore"`  // "previous" or "manual"
+	ShedAt   time.Time `json:"shedAt"`
 }
 
 // ThermostatSettings represents thermostat configuration
@@ -337,6 +734,16 @@ func (ls *LoadSheddingShadowState) GetMetadata() StateMetadata {
 	return ls.Metadata
 }
 
+// GetLastActionTime implements ActionTimeProvider
+func (ls *LoadSheddingShadowState) GetLastActionTime() time.Time {
+	return ls.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (ls *LoadSheddingShadowState) GetLastActionReason() string {
+	return ls.Outputs.LastActionReason
+}
+
 // NewLoadSheddingShadowState creates a new load shedding shadow state
 func NewLoadSheddingShadowState() *LoadSheddingShadowState {
 	return &LoadSheddingShadowState{
@@ -363,11 +770,65 @@ type SleepHygieneOutputs struct {
 	LastTTSAnnouncement *TTSAnnouncement          `json:"lastTTSAnnouncement,omitempty"`
 	StopScreensReminder *ReminderTrigger          `json:"stopScreensReminder,omitempty"`
 	GoToBedReminder     *ReminderTrigger          `json:"goToBedReminder,omitempty"`
+	DoNotDisturb        map[string]DoNotDisturb   `json:"doNotDisturb"` // Bedroom name -> do-not-disturb state
+	AdaptiveWake        *AdaptiveWake             `json:"adaptiveWake,omitempty"`
+	WakeLight           *WakeLight                `json:"wakeLight,omitempty"`
+	SonosAlarm          *SonosAlarm               `json:"sonosAlarm,omitempty"`
 	LastActionTime      time.Time                 `json:"lastActionTime"`
-	LastActionType      string                    `json:"lastActionType,omitempty"` // "begin_wake", "wake", "stop_screens", "go_to_bed", "cancel_wake"
+	LastActionType      string                    `json:"lastActionType,omitempty"` // "begin_wake", "wake", "stop_screens", "go_to_bed", "cancel_wake", "dnd_suppressed"
 	LastActionReason    string                    `json:"lastActionReason,omitempty"`
 }
 
+// DoNotDisturb represents a bedroom's do-not-disturb toggle
+type DoNotDisturb struct {
+	Active    bool      `json:"active"`
+	Since     time.Time `json:"since,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"` // Zero if it stays on until cleared
+}
+
+// AdaptiveWake records how today's alarmTime was computed from the schedule
+// and the first calendar event of each person
+type AdaptiveWake struct {
+	ScheduledWake     time.Time `json:"scheduledWake"`
+	AlarmTime         time.Time `json:"alarmTime"`
+	Source            string    `json:"source"`                   // "schedule" or "calendar"
+	Person            string    `json:"person,omitempty"`         // Whose event moved the alarm
+	CalendarEntity    string    `json:"calendarEntity,omitempty"` // Calendar the event came from
+	EventSummary      string    `json:"eventSummary,omitempty"`
+	EventStart        time.Time `json:"eventStart,omitempty"`
+	AdjustmentMinutes int       `json:"adjustmentMinutes"` // How much earlier than the scheduled wake
+	ClampedToFloor    bool      `json:"clampedToFloor"`
+	ComputedAt        time.Time `json:"computedAt"`
+}
+
+// WakeLight records the wake light ramp chosen from the morning's outdoor
+// light level or weather
+type WakeLight struct {
+	Profile            string    `json:"profile"`             // Matching profile name, or "default"
+	Lux                *float64  `json:"lux,omitempty"`       // Outdoor light level, if it could be read
+	Condition          string    `json:"condition,omitempty"` // Weather condition, if it could be read
+	FinalBrightnessPct int       `json:"finalBrightnessPct"`
+	TransitionMinutes  int       `json:"transitionMinutes"`
+	LeadMinutes        int       `json:"leadMinutes"`
+	WakeTime           time.Time `json:"wakeTime"`
+	StartAt            time.Time `json:"startAt"` // Wake time minus the profile's lead
+	Started            bool      `json:"started"`
+	ChosenAt           time.Time `json:"chosenAt"`
+}
+
+// SonosAlarm records the native Sonos alarm kept in sync with the wake time as
+// a backup, and the last nightly check that it still matches
+type SonosAlarm struct {
+	AlarmEntity string    `json:"alarmEntity"`
+	Time        string    `json:"time"` // "HH:MM:SS" as Sonos stores it
+	WakeTime    time.Time `json:"wakeTime"`
+	Enabled     bool      `json:"enabled"`
+	Reason      string    `json:"reason,omitempty"` // Why the alarm is disabled
+	SyncedAt    time.Time `json:"syncedAt"`
+	VerifiedAt  time.Time `json:"verifiedAt,omitempty"`
+	Problem     string    `json:"problem,omitempty"` // What the last check found wrong
+}
+
 // SpeakerFadeOut represents the fade-out state of a single speaker
 type SpeakerFadeOut struct {
 	SpeakerEntityID string    `json:"speakerEntityID"`
@@ -411,6 +872,16 @@ func (s *SleepHygieneShadowState) GetMetadata() StateMetadata {
 	return s.Metadata
 }
 
+// GetLastActionTime implements ActionTimeProvider
+func (s *SleepHygieneShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (s *SleepHygieneShadowState) GetLastActionReason() string {
+	return s.Outputs.LastActionReason
+}
+
 // NewSleepHygieneShadowState creates a new sleep hygiene shadow state
 func NewSleepHygieneShadowState() *SleepHygieneShadowState {
 	return &SleepHygieneShadowState{
@@ -422,6 +893,7 @@ func NewSleepHygieneShadowState() *SleepHygieneShadowState {
 		Outputs: SleepHygieneOutputs{
 			WakeSequenceStatus: "inactive",
 			FadeOutProgress:    make(map[string]SpeakerFadeOut),
+			DoNotDisturb:       make(map[string]DoNotDisturb),
 		},
 		Metadata: StateMetadata{
 			LastUpdated: time.Now(),
@@ -455,6 +927,132 @@ type EnergyOutputs struct {
 	IsFreeEnergyAvailable      bool                 `json:"isFreeEnergyAvailable"`
 	LastComputations           EnergyComputations   `json:"lastComputations"`
 	SensorReadings             EnergySensorReadings `json:"sensorReadings"`
+
+	// LastFreeEnergyAnnouncement is the most recent free energy window
+	// announcement, sent or suppressed
+	LastFreeEnergyAnnouncement *FreeEnergyAnnouncement `json:"lastFreeEnergyAnnouncement,omitempty"`
+
+	// BackupReserve is the latest inverter backup-reserve decision and the
+	// outage risk inputs behind it
+	BackupReserve *BackupReserveDecision `json:"backupReserve,omitempty"`
+
+	// LastLevelAnnouncement is the most recent announced currentEnergyLevel
+	// change, sent or suppressed
+	LastLevelAnnouncement *EnergyLevelAnnouncement `json:"lastLevelAnnouncement,omitempty"`
+
+	// SafeMode is the latest battery safe mode evaluation, nil when safe
+	// mode isn't configured
+	SafeMode *SafeModeDecision `json:"safeMode,omitempty"`
+
+	// ForecastLookAhead is the latest solar forecast look-ahead evaluation,
+	// nil when the look-ahead isn't configured
+	ForecastLookAhead *ForecastLookAheadDecision `json:"forecastLookAhead,omitempty"`
+
+	// GridPricing is the latest time-of-use price evaluation, nil when no
+	// rate schedule is configured
+	GridPricing *GridPricingDecision `json:"gridPricing,omitempty"`
+
+	// ReservePolicy is the battery reserve policy in effect and its recent
+	// transitions, nil when no reserve policy is configured
+	ReservePolicy *ReservePolicyState `json:"reservePolicy,omitempty"`
+}
+
+// ReservePolicyState records the battery reserve policy in effect
+type ReservePolicyState struct {
+	Policy      string                    `json:"policy"` // "normal", "storm_watch" or "grid_outage"
+	ReservePct  float64                   `json:"reservePct"`
+	Reason      string                    `json:"reason"`
+	Since       time.Time                 `json:"since"`
+	EvaluatedAt time.Time                 `json:"evaluatedAt"`
+	Transitions []ReservePolicyTransition `json:"transitions"` // Oldest first, capped at MaxReservePolicyTransitions
+}
+
+// ReservePolicyTransition records one change of reserve policy and whether
+// the inverter's reserve was set
+type ReservePolicyTransition struct {
+	From       string    `json:"from,omitempty"` // Empty for the first evaluation after start, reset or reload
+	To         string    `json:"to"`
+	ReservePct float64   `json:"reservePct"`
+	Reason     string    `json:"reason"`
+	Applied    bool      `json:"applied"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// MaxReservePolicyTransitions is how many reserve policy transitions are kept
+const MaxReservePolicyTransitions = 20
+
+// GridPricingDecision records the grid price in effect and whether it is peak pricing
+type GridPricingDecision struct {
+	Window      string    `json:"window"` // Rate window name, or "default" outside every window
+	Price       float64   `json:"price"`
+	Source      string    `json:"source"` // "schedule" or "sensor"
+	SensorPrice *float64  `json:"sensorPrice,omitempty"`
+	Peak        bool      `json:"peak"`
+	Reason      string    `json:"reason"`
+	EvaluatedAt time.Time `json:"evaluatedAt"`
+}
+
+// ForecastLookAheadDecision records whether the solar forecast raised
+// currentEnergyLevel and why
+type ForecastLookAheadDecision struct {
+	Raised            bool      `json:"raised"`
+	Reason            string    `json:"reason"`
+	ForecastKWh       float64   `json:"forecastKwh"`
+	WindowHours       float64   `json:"windowHours"`
+	FromLevel         string    `json:"fromLevel,omitempty"`
+	ToLevel           string    `json:"toLevel,omitempty"`
+	ForecastFetchedAt time.Time `json:"forecastFetchedAt,omitempty"`
+	EvaluatedAt       time.Time `json:"evaluatedAt"`
+}
+
+// SafeModeDecision records whether the house is in battery safe mode and why
+type SafeModeDecision struct {
+	Active           bool      `json:"active"`
+	Reason           string    `json:"reason"`
+	BatteryPct       *float64  `json:"batteryPct,omitempty"`
+	GridAvailable    bool      `json:"gridAvailable"`
+	SuspendedPlugins []string  `json:"suspendedPlugins,omitempty"`
+	SlowdownFactor   int       `json:"slowdownFactor,omitempty"` // Evaluation loops run every Nth tick while active
+	Since            time.Time `json:"since,omitempty"`
+	EvaluatedAt      time.Time `json:"evaluatedAt"`
+}
+
+// EnergyLevelAnnouncement records one announcement of a currentEnergyLevel change
+type EnergyLevelAnnouncement struct {
+	From             string    `json:"from"`
+	To               string    `json:"to"`
+	Message          string    `json:"message"`
+	Notified         bool      `json:"notified"`                   // Push sent through the notify service
+	SpokenLevel      string    `json:"spokenLevel,omitempty"`      // Notification router level used for speakers
+	SuppressedReason string    `json:"suppressedReason,omitempty"` // "quiet_hours"
+	EvaluatedAt      time.Time `json:"evaluatedAt"`
+}
+
+// BackupReserveDecision records one evaluation of grid outage risk and the
+// inverter mode chosen for it
+type BackupReserveDecision struct {
+	HighRisk         bool      `json:"highRisk"`
+	Reasons          []string  `json:"reasons,omitempty"` // Why risk is high, e.g. "forecast lightning at 15:00"
+	RiskSensorValue  *float64  `json:"riskSensorValue,omitempty"`
+	WeatherCondition string    `json:"weatherCondition,omitempty"`
+	NextRiskyPeriod  time.Time `json:"nextRiskyPeriod,omitempty"` // Earliest risky forecast period within the lead time
+	GridAvailable    bool      `json:"gridAvailable"`
+	Mode             string    `json:"mode"`     // "backup" or "normal"
+	Decision         string    `json:"decision"` // What was done and why
+	LowRiskSince     time.Time `json:"lowRiskSince,omitempty"`
+	LastSwitch       time.Time `json:"lastSwitch,omitempty"`
+	EvaluatedAt      time.Time `json:"evaluatedAt"`
+}
+
+// FreeEnergyAnnouncement records one announcement ahead of a free energy window boundary
+type FreeEnergyAnnouncement struct {
+	Boundary         string    `json:"boundary"`   // "start" or "end"
+	WindowTime       time.Time `json:"windowTime"` // When the window begins or ends
+	Message          string    `json:"message"`
+	Sent             bool      `json:"sent"`
+	SuppressedReason string    `json:"suppressedReason,omitempty"` // "quiet_hours" or "nobody_home"
+	EvaluatedAt      time.Time `json:"evaluatedAt"`
 }
 
 // EnergyComputations tracks when various energy calculations were last performed
@@ -532,6 +1130,29 @@ type StateTrackingOutputs struct {
 	TimerStates      StateTrackingTimers  `json:"timerStates"`
 	LastAnnouncement *ArrivalAnnouncement `json:"lastAnnouncement,omitempty"`
 	LastComputation  time.Time            `json:"lastComputation"`
+	ConsistencyCheck *ConsistencyCheck    `json:"consistencyCheck,omitempty"`
+}
+
+// ConsistencyCheck records the last nightly cross-check of derived states against their inputs
+type ConsistencyCheck struct {
+	CheckedAt time.Time            `json:"checkedAt"`
+	Repairs   []ConsistencyRepair  `json:"repairs"`
+	Failures  []ConsistencyFailure `json:"failures,omitempty"` // Other nightly checks that failed
+}
+
+// ConsistencyFailure is a nightly check registered by another plugin that failed
+type ConsistencyFailure struct {
+	Check string `json:"check"`
+	Error string `json:"error"`
+}
+
+// ConsistencyRepair is a derived state that did not match its inputs
+type ConsistencyRepair struct {
+	Variable string          `json:"variable"`
+	Found    bool            `json:"found"`
+	Expected bool            `json:"expected"`
+	Inputs   map[string]bool `json:"inputs"`
+	Repaired bool            `json:"repaired"` // False if the write failed
 }
 
 // DerivedStates tracks the computed presence/sleep states
@@ -618,6 +1239,21 @@ type DayPhaseOutputs struct {
 	LastDayPhaseCalc    time.Time `json:"lastDayPhaseCalc,omitempty"`
 	NextTransitionTime  time.Time `json:"nextTransitionTime,omitempty"`
 	NextTransitionPhase string    `json:"nextTransitionPhase,omitempty"`
+
+	// TemperatureShift is the outdoor-temperature offset applied to the
+	// evening boundaries (nil when temperature shifting isn't configured)
+	TemperatureShift *TemperatureShift `json:"temperatureShift,omitempty"`
+}
+
+// TemperatureShift records the evening boundary offsets derived from the
+// outdoor temperature
+type TemperatureShift struct {
+	OutdoorTemperature    float64   `json:"outdoorTemperature"`
+	TemperatureAvailable  bool      `json:"temperatureAvailable"` // false: sensor unreadable, offsets are zero
+	WinddownOffsetMinutes int       `json:"winddownOffsetMinutes"`
+	NightOffsetMinutes    int       `json:"nightOffsetMinutes"`
+	Held                  bool      `json:"held"` // Offsets are held from winddown until morning
+	ComputedAt            time.Time `json:"computedAt"`
 }
 
 // GetCurrentInputs implements PluginShadowState
@@ -712,3 +1348,1100 @@ func NewTVShadowState() *TVShadowState {
 		},
 	}
 }
+
+// GrowLightsShadowState represents the shadow state for the grow lights plugin
+type GrowLightsShadowState struct {
+	Plugin   string            `json:"plugin"`
+	Inputs   GrowLightsInputs  `json:"inputs"`
+	Outputs  GrowLightsOutputs `json:"outputs"`
+	Metadata StateMetadata     `json:"metadata"`
+}
+
+// GrowLightsInputs tracks current sun and energy inputs
+type GrowLightsInputs struct {
+	Current map[string]interface{} `json:"current"`
+}
+
+// GrowLightsOutputs tracks the computed schedule and state of each fixture
+type GrowLightsOutputs struct {
+	NaturalDayLengthHours float64                          `json:"naturalDayLengthHours"`
+	Deferred              bool                             `json:"deferred"`
+	Fixtures              map[string]GrowLightFixtureState `json:"fixtures"`
+	LastEvaluation        time.Time                        `json:"lastEvaluation"`
+}
+
+// GrowLightFixtureState represents the schedule and state of a single fixture
+type GrowLightFixtureState struct {
+	EntityID          string            `json:"entityID"`
+	On                bool              `json:"on"`
+	Deferred          bool              `json:"deferred"`
+	SupplementalHours float64           `json:"supplementalHours"`
+	Windows           []GrowLightWindow `json:"windows"`
+	Reason            string            `json:"reason"`
+	LastChanged       time.Time         `json:"lastChanged,omitempty"`
+}
+
+// GrowLightWindow is a period during which a fixture should be on
+type GrowLightWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (g *GrowLightsShadowState) GetCurrentInputs() map[string]interface{} {
+	return g.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (g *GrowLightsShadowState) GetLastActionInputs() map[string]interface{} {
+	return g.Inputs.Current
+}
+
+// GetOutputs implements PluginShadowState
+func (g *GrowLightsShadowState) GetOutputs() interface{} {
+	return g.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (g *GrowLightsShadowState) GetMetadata() StateMetadata {
+	return g.Metadata
+}
+
+// NewGrowLightsShadowState creates a new grow lights shadow state
+func NewGrowLightsShadowState() *GrowLightsShadowState {
+	return &GrowLightsShadowState{
+		Plugin: "growlights",
+		Inputs: GrowLightsInputs{
+			Current: make(map[string]interface{}),
+		},
+		Outputs: GrowLightsOutputs{
+			Fixtures: make(map[string]GrowLightFixtureState),
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "growlights",
+		},
+	}
+}
+
+// BedroomComfortShadowState represents the shadow state for the bedroom comfort plugin
+type BedroomComfortShadowState struct {
+	Plugin   string                `json:"plugin"`
+	Inputs   BedroomComfortInputs  `json:"inputs"`
+	Outputs  BedroomComfortOutputs `json:"outputs"`
+	Metadata StateMetadata         `json:"metadata"`
+}
+
+// BedroomComfortInputs tracks current and last-action input values
+type BedroomComfortInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// BedroomComfortOutputs tracks tonight's comfort adjustments
+type BedroomComfortOutputs struct {
+	NightActive        bool                       `json:"nightActive"`
+	NightStarted       time.Time                  `json:"nightStarted,omitempty"`
+	AdjustmentsTonight int                        `json:"adjustmentsTonight"`
+	MaxAdjustments     int                        `json:"maxAdjustments"`
+	FanOn              bool                       `json:"fanOn"`
+	TargetTemperature  *float64                   `json:"targetTemperature,omitempty"`
+	Adjustments        []BedroomComfortAdjustment `json:"adjustments"`
+	LastActionTime     time.Time                  `json:"lastActionTime"`
+	LastActionReason   string                     `json:"lastActionReason,omitempty"`
+}
+
+// BedroomComfortAdjustment is a single fan or thermostat change made overnight
+type BedroomComfortAdjustment struct {
+	Time              time.Time `json:"time"`
+	Action            string    `json:"action"` // "fan_on", "fan_off", "lower_setpoint", "raise_setpoint", "restore"
+	Temperature       float64   `json:"temperature"`
+	Humidity          *float64  `json:"humidity,omitempty"`
+	FanOn             bool      `json:"fanOn"`
+	TargetTemperature *float64  `json:"targetTemperature,omitempty"`
+	Reason            string    `json:"reason"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (b *BedroomComfortShadowState) GetCurrentInputs() map[string]interface{} {
+	return b.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (b *BedroomComfortShadowState) GetLastActionInputs() map[string]interface{} {
+	return b.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (b *BedroomComfortShadowState) GetOutputs() interface{} {
+	return b.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (b *BedroomComfortShadowState) GetMetadata() StateMetadata {
+	return b.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (b *BedroomComfortShadowState) GetLastActionTime() time.Time {
+	return b.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (b *BedroomComfortShadowState) GetLastActionReason() string {
+	return b.Outputs.LastActionReason
+}
+
+// NewBedroomComfortShadowState creates a new bedroom comfort shadow state
+func NewBedroomComfortShadowState() *BedroomComfortShadowState {
+	return &BedroomComfortShadowState{
+		Plugin: "bedroomcomfort",
+		Inputs: BedroomComfortInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: BedroomComfortOutputs{
+			Adjustments: []BedroomComfortAdjustment{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "bedroomcomfort",
+		},
+	}
+}
+
+// OpenReminderShadowState represents the shadow state for the open reminder plugin
+type OpenReminderShadowState struct {
+	Plugin   string              `json:"plugin"`
+	Inputs   OpenReminderInputs  `json:"inputs"`
+	Outputs  OpenReminderOutputs `json:"outputs"`
+	Metadata StateMetadata       `json:"metadata"`
+}
+
+// OpenReminderInputs tracks current and last-action input values
+type OpenReminderInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// OpenReminderOutputs tracks the current or most recent left-open reminder
+type OpenReminderOutputs struct {
+	Active           bool             `json:"active"`
+	Trigger          string           `json:"trigger,omitempty"` // "isEveryoneAsleep" or "isAnyoneHome"
+	OpenSensors      []OpenSensorInfo `json:"openSensors"`
+	RemindersSent    int              `json:"remindersSent"`
+	StartedAt        time.Time        `json:"startedAt,omitempty"`
+	LastMessage      string           `json:"lastMessage,omitempty"`
+	EndedReason      string           `json:"endedReason,omitempty"`
+	LastActionTime   time.Time        `json:"lastActionTime"`
+	LastActionReason string           `json:"lastActionReason,omitempty"`
+}
+
+// OpenSensorInfo identifies a door or window that was found open
+type OpenSensorInfo struct {
+	EntityID string `json:"entityID"`
+	Name     string `json:"name"`
+	Room     string `json:"room,omitempty"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (o *OpenReminderShadowState) GetCurrentInputs() map[string]interface{} {
+	return o.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (o *OpenReminderShadowState) GetLastActionInputs() map[string]interface{} {
+	return o.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (o *OpenReminderShadowState) GetOutputs() interface{} {
+	return o.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (o *OpenReminderShadowState) GetMetadata() StateMetadata {
+	return o.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (o *OpenReminderShadowState) GetLastActionTime() time.Time {
+	return o.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (o *OpenReminderShadowState) GetLastActionReason() string {
+	return o.Outputs.LastActionReason
+}
+
+// NewOpenReminderShadowState creates a new open reminder shadow state
+func NewOpenReminderShadowState() *OpenReminderShadowState {
+	return &OpenReminderShadowState{
+		Plugin: "openreminder",
+		Inputs: OpenReminderInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: OpenReminderOutputs{
+			OpenSensors: []OpenSensorInfo{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "openreminder",
+		},
+	}
+}
+
+// LowBatteryShadowState represents the shadow state for the low-battery plugin
+type LowBatteryShadowState struct {
+	Plugin   string            `json:"plugin"`
+	Inputs   LowBatteryInputs  `json:"inputs"`
+	Outputs  LowBatteryOutputs `json:"outputs"`
+	Metadata StateMetadata     `json:"metadata"`
+}
+
+// LowBatteryInputs tracks current and last-action input values
+type LowBatteryInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// LowBatteryOutputs tracks the latest sweep and weekly notification
+type LowBatteryOutputs struct {
+	Devices           []LowBatteryDevice `json:"devices"`
+	BatteriesScanned  int                `json:"batteriesScanned"`
+	LastSweep         time.Time          `json:"lastSweep,omitempty"`
+	LastSweepError    string             `json:"lastSweepError,omitempty"`
+	LastReport        time.Time          `json:"lastReport,omitempty"`
+	LastReportMessage string             `json:"lastReportMessage,omitempty"`
+	LastActionTime    time.Time          `json:"lastActionTime"`
+	LastActionReason  string             `json:"lastActionReason,omitempty"`
+}
+
+// LowBatteryDevice is an entity whose battery is under its threshold
+type LowBatteryDevice struct {
+	EntityID  string  `json:"entityID"`
+	Name      string  `json:"name"`
+	Level     float64 `json:"level"`     // Percent
+	Threshold float64 `json:"threshold"` // Percent
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (l *LowBatteryShadowState) GetCurrentInputs() map[string]interface{} {
+	return l.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (l *LowBatteryShadowState) GetLastActionInputs() map[string]interface{} {
+	return l.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (l *LowBatteryShadowState) GetOutputs() interface{} {
+	return l.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (l *LowBatteryShadowState) GetMetadata() StateMetadata {
+	return l.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (l *LowBatteryShadowState) GetLastActionTime() time.Time {
+	return l.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (l *LowBatteryShadowState) GetLastActionReason() string {
+	return l.Outputs.LastActionReason
+}
+
+// NewLowBatteryShadowState creates a new low-battery shadow state
+func NewLowBatteryShadowState() *LowBatteryShadowState {
+	return &LowBatteryShadowState{
+		Plugin: "lowbattery",
+		Inputs: LowBatteryInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: LowBatteryOutputs{
+			Devices: []LowBatteryDevice{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "lowbattery",
+		},
+	}
+}
+
+// FocusModeShadowState represents the shadow state for office focus mode
+type FocusModeShadowState struct {
+	Plugin   string           `json:"plugin"`
+	Inputs   FocusModeInputs  `json:"inputs"`
+	Outputs  FocusModeOutputs `json:"outputs"`
+	Metadata StateMetadata    `json:"metadata"`
+}
+
+// FocusModeInputs tracks current and last-action input values
+type FocusModeInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// FocusModeOutputs tracks whether focus mode is on and what started it
+type FocusModeOutputs struct {
+	Active           bool      `json:"active"`
+	Source           string    `json:"source,omitempty"` // "toggle" or "calendar"
+	ActiveSince      time.Time `json:"activeSince,omitempty"`
+	LastActionTime   time.Time `json:"lastActionTime"`
+	LastActionReason string    `json:"lastActionReason,omitempty"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (f *FocusModeShadowState) GetCurrentInputs() map[string]interface{} {
+	return f.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (f *FocusModeShadowState) GetLastActionInputs() map[string]interface{} {
+	return f.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (f *FocusModeShadowState) GetOutputs() interface{} {
+	return f.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (f *FocusModeShadowState) GetMetadata() StateMetadata {
+	return f.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (f *FocusModeShadowState) GetLastActionTime() time.Time {
+	return f.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (f *FocusModeShadowState) GetLastActionReason() string {
+	return f.Outputs.LastActionReason
+}
+
+// NewFocusModeShadowState creates a new focus mode shadow state
+func NewFocusModeShadowState() *FocusModeShadowState {
+	return &FocusModeShadowState{
+		Plugin: "focusmode",
+		Inputs: FocusModeInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "focusmode",
+		},
+	}
+}
+
+// KidModeShadowState represents the shadow state for kid mode
+type KidModeShadowState struct {
+	Plugin   string         `json:"plugin"`
+	Inputs   KidModeInputs  `json:"inputs"`
+	Outputs  KidModeOutputs `json:"outputs"`
+	Metadata StateMetadata  `json:"metadata"`
+}
+
+// KidModeInputs tracks current and last-action input values
+type KidModeInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// KidModeOutputs tracks where kid mode is on and the thermostats it holds
+type KidModeOutputs struct {
+	Active           bool               `json:"active"`           // Household kid mode
+	Source           string             `json:"source,omitempty"` // "toggle" or "schedule"
+	ActiveSince      time.Time          `json:"activeSince,omitempty"`
+	ActiveRooms      []string           `json:"activeRooms"`
+	HeldThermostats  map[string]float64 `json:"heldThermostats,omitempty"` // Entity ID to held setpoint
+	LastActionTime   time.Time          `json:"lastActionTime"`
+	LastActionReason string             `json:"lastActionReason,omitempty"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (k *KidModeShadowState) GetCurrentInputs() map[string]interface{} {
+	return k.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (k *KidModeShadowState) GetLastActionInputs() map[string]interface{} {
+	return k.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (k *KidModeShadowState) GetOutputs() interface{} {
+	return k.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (k *KidModeShadowState) GetMetadata() StateMetadata {
+	return k.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (k *KidModeShadowState) GetLastActionTime() time.Time {
+	return k.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (k *KidModeShadowState) GetLastActionReason() string {
+	return k.Outputs.LastActionReason
+}
+
+// NewKidModeShadowState creates a new kid mode shadow state
+func NewKidModeShadowState() *KidModeShadowState {
+	return &KidModeShadowState{
+		Plugin: "kidmode",
+		Inputs: KidModeInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: KidModeOutputs{
+			ActiveRooms: []string{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "kidmode",
+		},
+	}
+}
+
+// HotWaterShadowState represents the shadow state for the hot water recirculation plugin
+type HotWaterShadowState struct {
+	Plugin   string          `json:"plugin"`
+	Inputs   HotWaterInputs  `json:"inputs"`
+	Outputs  HotWaterOutputs `json:"outputs"`
+	Metadata StateMetadata   `json:"metadata"`
+}
+
+// HotWaterInputs tracks current and last-action input values
+type HotWaterInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// HotWaterOutputs tracks the pump, hot water draws and the learned schedule
+type HotWaterOutputs struct {
+	PumpOn           bool             `json:"pumpOn"`
+	Reason           string           `json:"reason"`
+	RunUntil         time.Time        `json:"runUntil,omitempty"`
+	NextRun          time.Time        `json:"nextRun,omitempty"`
+	LastDraw         time.Time        `json:"lastDraw,omitempty"`
+	DrawsToday       int              `json:"drawsToday"`
+	Schedule         HotWaterSchedule `json:"schedule"`
+	LastActionTime   time.Time        `json:"lastActionTime"`
+	LastActionReason string           `json:"lastActionReason,omitempty"`
+}
+
+// HotWaterSchedule is the recirculation schedule learned from hot water draws
+type HotWaterSchedule struct {
+	SlotMinutes int                `json:"slotMinutes"`
+	Weekday     HotWaterDayProfile `json:"weekday"`
+	Weekend     HotWaterDayProfile `json:"weekend"`
+	LearnedAt   time.Time          `json:"learnedAt"`
+}
+
+// HotWaterDayProfile is the learned use for weekdays or weekends
+type HotWaterDayProfile struct {
+	DaysObserved int            `json:"daysObserved"`
+	Active       bool           `json:"active"` // Enough days observed for the slots to run the pump
+	Slots        []HotWaterSlot `json:"slots"`  // Slots drawn from often enough, earliest first
+}
+
+// HotWaterSlot is a time of day when hot water is usually drawn
+type HotWaterSlot struct {
+	Start       string  `json:"start"`       // Format: "06:30"
+	DrawDays    int     `json:"drawDays"`    // Observed days with a draw in the slot
+	Probability float64 `json:"probability"` // DrawDays / DaysObserved
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (h *HotWaterShadowState) GetCurrentInputs() map[string]interface{} {
+	return h.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (h *HotWaterShadowState) GetLastActionInputs() map[string]interface{} {
+	return h.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (h *HotWaterShadowState) GetOutputs() interface{} {
+	return h.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (h *HotWaterShadowState) GetMetadata() StateMetadata {
+	return h.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (h *HotWaterShadowState) GetLastActionTime() time.Time {
+	return h.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (h *HotWaterShadowState) GetLastActionReason() string {
+	return h.Outputs.LastActionReason
+}
+
+// NewHotWaterShadowState creates a new hot water shadow state
+func NewHotWaterShadowState() *HotWaterShadowState {
+	return &HotWaterShadowState{
+		Plugin: "hotwater",
+		Inputs: HotWaterInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: HotWaterOutputs{
+			Schedule: HotWaterSchedule{
+				Weekday: HotWaterDayProfile{Slots: []HotWaterSlot{}},
+				Weekend: HotWaterDayProfile{Slots: []HotWaterSlot{}},
+			},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "hotwater",
+		},
+	}
+}
+
+// RulesShadowState represents the shadow state for the YAML rules engine
+type RulesShadowState struct {
+	Plugin   string        `json:"plugin"`
+	Inputs   RulesInputs   `json:"inputs"`
+	Outputs  RulesOutputs  `json:"outputs"`
+	Metadata StateMetadata `json:"metadata"`
+}
+
+// RulesInputs tracks the state variables rule conditions read
+type RulesInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// RulesOutputs tracks each rule's last trigger and result
+type RulesOutputs struct {
+	Rules            []RuleStatus `json:"rules"` // In config order
+	LastActionTime   time.Time    `json:"lastActionTime"`
+	LastActionReason string       `json:"lastActionReason,omitempty"`
+}
+
+// RuleStatus is the last evaluation of one rule
+type RuleStatus struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	LastTriggered time.Time `json:"lastTriggered,omitempty"`
+	LastTrigger   string    `json:"lastTrigger,omitempty"` // e.g. "isAnyoneHome: false -> true" or "cron 0 22 * * *"
+	LastResult    string    `json:"lastResult,omitempty"`  // "ran", "condition not met: ..." or the action error
+	LastRun       time.Time `json:"lastRun,omitempty"`     // Last time the actions ran
+	LastActions   []string  `json:"lastActions"`           // Actions run last time, in order
+	RunCount      int       `json:"runCount"`
+	NextScheduled time.Time `json:"nextScheduled,omitempty"` // Next cron trigger
+	WaitingUntil  time.Time `json:"waitingUntil,omitempty"`  // When the actions after a delay run
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (r *RulesShadowState) GetCurrentInputs() map[string]interface{} {
+	return r.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (r *RulesShadowState) GetLastActionInputs() map[string]interface{} {
+	return r.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (r *RulesShadowState) GetOutputs() interface{} {
+	return r.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (r *RulesShadowState) GetMetadata() StateMetadata {
+	return r.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (r *RulesShadowState) GetLastActionTime() time.Time {
+	return r.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (r *RulesShadowState) GetLastActionReason() string {
+	return r.Outputs.LastActionReason
+}
+
+// NewRulesShadowState creates a new rules shadow state
+func NewRulesShadowState() *RulesShadowState {
+	return &RulesShadowState{
+		Plugin: "rules",
+		Inputs: RulesInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: RulesOutputs{
+			Rules: []RuleStatus{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "rules",
+		},
+	}
+}
+
+// SceneSchedulerShadowState represents the shadow state for the scene scheduler plugin
+type SceneSchedulerShadowState struct {
+	Plugin   string                `json:"plugin"`
+	Inputs   SceneSchedulerInputs  `json:"inputs"`
+	Outputs  SceneSchedulerOutputs `json:"outputs"`
+	Metadata StateMetadata         `json:"metadata"`
+}
+
+// SceneSchedulerInputs tracks the state variables read before activating a scene
+type SceneSchedulerInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// SceneSchedulerOutputs tracks each scene schedule's next and last run
+type SceneSchedulerOutputs struct {
+	Schedules        []SceneScheduleStatus `json:"schedules"` // In config order
+	LastActionTime   time.Time             `json:"lastActionTime"`
+	LastActionReason string                `json:"lastActionReason,omitempty"`
+}
+
+// SceneScheduleStatus is the state of one scene schedule
+type SceneScheduleStatus struct {
+	Name       string    `json:"name"`
+	Scene      string    `json:"scene"`
+	Spec       string    `json:"spec"` // Cron expression or sun event, e.g. "sunset-30m0s"
+	NextRun    time.Time `json:"nextRun,omitempty"`
+	LastRun    time.Time `json:"lastRun,omitempty"`
+	LastResult string    `json:"lastResult,omitempty"` // "activated", "skipped: nobody home" or the service error
+	RunCount   int       `json:"runCount"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (s *SceneSchedulerShadowState) GetCurrentInputs() map[string]interface{} {
+	return s.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (s *SceneSchedulerShadowState) GetLastActionInputs() map[string]interface{} {
+	return s.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (s *SceneSchedulerShadowState) GetOutputs() interface{} {
+	return s.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (s *SceneSchedulerShadowState) GetMetadata() StateMetadata {
+	return s.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (s *SceneSchedulerShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (s *SceneSchedulerShadowState) GetLastActionReason() string {
+	return s.Outputs.LastActionReason
+}
+
+// NewSceneSchedulerShadowState creates a new scene scheduler shadow state
+func NewSceneSchedulerShadowState() *SceneSchedulerShadowState {
+	return &SceneSchedulerShadowState{
+		Plugin: "scenescheduler",
+		Inputs: SceneSchedulerInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: SceneSchedulerOutputs{
+			Schedules: []SceneScheduleStatus{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "scenescheduler",
+		},
+	}
+}
+
+// SleepFanShadowState represents the shadow state for the sleep fan plugin
+type SleepFanShadowState struct {
+	Plugin   string          `json:"plugin"`
+	Inputs   SleepFanInputs  `json:"inputs"`
+	Outputs  SleepFanOutputs `json:"outputs"`
+	Metadata StateMetadata   `json:"metadata"`
+}
+
+// SleepFanInputs tracks current and last-action input values
+type SleepFanInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// SleepFanOutputs tracks each bedroom fan's conditions and speed
+type SleepFanOutputs struct {
+	Bedrooms         []SleepFanBedroomStatus `json:"bedrooms"` // In config order
+	LastActionTime   time.Time               `json:"lastActionTime"`
+	LastActionReason string                  `json:"lastActionReason,omitempty"`
+}
+
+// SleepFanBedroomStatus is the state of one bedroom's fan
+type SleepFanBedroomStatus struct {
+	Name        string    `json:"name"`
+	FanEntity   string    `json:"fanEntity"`
+	Asleep      bool      `json:"asleep"`
+	WindowOpen  bool      `json:"windowOpen"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TargetSpeed int       `json:"targetSpeed"` // Speed of the current temperature band
+	Speed       int       `json:"speed"`       // Speed last commanded, 0 = off
+	LastChange  time.Time `json:"lastChange,omitempty"`
+	LastReason  string    `json:"lastReason,omitempty"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (s *SleepFanShadowState) GetCurrentInputs() map[string]interface{} {
+	return s.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (s *SleepFanShadowState) GetLastActionInputs() map[string]interface{} {
+	return s.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (s *SleepFanShadowState) GetOutputs() interface{} {
+	return s.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (s *SleepFanShadowState) GetMetadata() StateMetadata {
+	return s.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (s *SleepFanShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (s *SleepFanShadowState) GetLastActionReason() string {
+	return s.Outputs.LastActionReason
+}
+
+// NewSleepFanShadowState creates a new sleep fan shadow state
+func NewSleepFanShadowState() *SleepFanShadowState {
+	return &SleepFanShadowState{
+		Plugin: "sleepfan",
+		Inputs: SleepFanInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: SleepFanOutputs{
+			Bedrooms: []SleepFanBedroomStatus{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "sleepfan",
+		},
+	}
+}
+
+// FreezeProtectionShadowState represents the shadow state for the freeze
+// protection plugin
+type FreezeProtectionShadowState struct {
+	Plugin   string                  `json:"plugin"`
+	Inputs   FreezeProtectionInputs  `json:"inputs"`
+	Outputs  FreezeProtectionOutputs `json:"outputs"`
+	Metadata StateMetadata           `json:"metadata"`
+}
+
+// FreezeProtectionInputs tracks current and last-action input values
+type FreezeProtectionInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// FreezeProtectionOutputs tracks each protected space's temperature and
+// mitigation
+type FreezeProtectionOutputs struct {
+	Spaces           []FreezeProtectionSpaceStatus `json:"spaces"` // In config order
+	LastActionTime   time.Time                     `json:"lastActionTime"`
+	LastActionReason string                        `json:"lastActionReason,omitempty"`
+}
+
+// FreezeProtectionSpaceStatus is the state of one unconditioned space
+type FreezeProtectionSpaceStatus struct {
+	Name           string   `json:"name"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	EnergyLevel    string   `json:"energyLevel,omitempty"`
+	HeaterOnBelow  float64  `json:"heaterOnBelow"` // Heater thresholds at the current energy level
+	HeaterOffAbove float64  `json:"heaterOffAbove"`
+	HeatersAllowed bool     `json:"heatersAllowed"`
+	HeatersOn      bool     `json:"heatersOn"`
+	DampersOpen    bool     `json:"dampersOpen"`
+
+	// Set while heaters or dampers are on, to tell whether the temperature
+	// keeps dropping despite them
+	MitigatingSince  time.Time `json:"mitigatingSince,omitempty"`
+	StartTemperature *float64  `json:"startTemperature,omitempty"`
+
+	LastChange time.Time `json:"lastChange,omitempty"`
+	LastReason string    `json:"lastReason,omitempty"`
+	LastAlert  time.Time `json:"lastAlert,omitempty"`
+	AlertText  string    `json:"alertText,omitempty"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (s *FreezeProtectionShadowState) GetCurrentInputs() map[string]interface{} {
+	return s.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (s *FreezeProtectionShadowState) GetLastActionInputs() map[string]interface{} {
+	return s.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (s *FreezeProtectionShadowState) GetOutputs() interface{} {
+	return s.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (s *FreezeProtectionShadowState) GetMetadata() StateMetadata {
+	return s.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (s *FreezeProtectionShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (s *FreezeProtectionShadowState) GetLastActionReason() string {
+	return s.Outputs.LastActionReason
+}
+
+// NewFreezeProtectionShadowState creates a new freeze protection shadow state
+func NewFreezeProtectionShadowState() *FreezeProtectionShadowState {
+	return &FreezeProtectionShadowState{
+		Plugin: "freezeprotection",
+		Inputs: FreezeProtectionInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: FreezeProtectionOutputs{
+			Spaces: []FreezeProtectionSpaceStatus{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "freezeprotection",
+		},
+	}
+}
+
+// ClimateShadowState represents the shadow state for the climate plugin
+type ClimateShadowState struct {
+	Plugin   string         `json:"plugin"`
+	Inputs   ClimateInputs  `json:"inputs"`
+	Outputs  ClimateOutputs `json:"outputs"`
+	Metadata StateMetadata  `json:"metadata"`
+}
+
+// ClimateInputs tracks current and last-action input values
+type ClimateInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// ClimateOutputs tracks each scheduled thermostat's target and actual setpoints
+type ClimateOutputs struct {
+	Thermostats      []ClimateThermostatStatus `json:"thermostats"`           // In config order
+	OpenSensors      []ClimateOpenSensor       `json:"openSensors,omitempty"` // Windows and doors open now
+	LastActionTime   time.Time                 `json:"lastActionTime"`
+	LastActionReason string                    `json:"lastActionReason,omitempty"`
+}
+
+// ClimateThermostatStatus is one thermostat's comfort schedule state
+type ClimateThermostatStatus struct {
+	EntityID   string   `json:"entityId"`
+	Mode       string   `json:"mode,omitempty"` // "home", "asleep" or "away"
+	TargetLow  float64  `json:"targetLow"`
+	TargetHigh float64  `json:"targetHigh"`
+	ActualLow  *float64 `json:"actualLow,omitempty"` // As the thermostat reports them
+	ActualHigh *float64 `json:"actualHigh,omitempty"`
+	HeldBy     string   `json:"heldBy,omitempty"` // "loadshedding", "kidmode" or "openpause" while it keeps the setpoints
+
+	// Set while paused for an open window or door
+	PausedSince time.Time `json:"pausedSince,omitempty"`
+	PausedMode  string    `json:"pausedMode,omitempty"` // HVAC mode restored on resume
+
+	LastChange time.Time `json:"lastChange,omitempty"`
+	LastReason string    `json:"lastReason,omitempty"`
+}
+
+// ClimateOpenSensor is a window or door sensor reporting open
+type ClimateOpenSensor struct {
+	EntityID  string    `json:"entityId"`
+	OpenSince time.Time `json:"openSince"`
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (s *ClimateShadowState) GetCurrentInputs() map[string]interface{} {
+	return s.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (s *ClimateShadowState) GetLastActionInputs() map[string]interface{} {
+	return s.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (s *ClimateShadowState) GetOutputs() interface{} {
+	return s.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (s *ClimateShadowState) GetMetadata() StateMetadata {
+	return s.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (s *ClimateShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (s *ClimateShadowState) GetLastActionReason() string {
+	return s.Outputs.LastActionReason
+}
+
+// NewClimateShadowState creates a new climate shadow state
+func NewClimateShadowState() *ClimateShadowState {
+	return &ClimateShadowState{
+		Plugin: "climate",
+		Inputs: ClimateInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: ClimateOutputs{
+			Thermostats: []ClimateThermostatStatus{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "climate",
+		},
+	}
+}
+
+// MaxEmergencyActions is how many emergency response actions are kept
+const MaxEmergencyActions = 50
+
+// EmergencyShadowState represents the shadow state for the emergency plugin
+type EmergencyShadowState struct {
+	Plugin   string           `json:"plugin"`
+	Inputs   EmergencyInputs  `json:"inputs"`
+	Outputs  EmergencyOutputs `json:"outputs"`
+	Metadata StateMetadata    `json:"metadata"`
+}
+
+// EmergencyInputs tracks current and last-action input values
+type EmergencyInputs struct {
+	Current      map[string]interface{} `json:"current"`
+	AtLastAction map[string]interface{} `json:"atLastAction"`
+}
+
+// EmergencyOutputs tracks the smoke/CO emergency and the response to it
+type EmergencyOutputs struct {
+	Active             bool                      `json:"active"`
+	Since              time.Time                 `json:"since,omitempty"`  // When the emergency started, or last ended
+	Reason             string                    `json:"reason,omitempty"` // e.g. "smoke: Kitchen smoke detector"
+	Acknowledged       bool                      `json:"acknowledged"`     // Announcements stopped by the acknowledge variable
+	Detectors          []EmergencyDetectorStatus `json:"detectors"`        // In config order
+	SuspendedPlugins   []string                  `json:"suspendedPlugins,omitempty"`
+	Announcements      int                       `json:"announcements"` // Spoken since the emergency started
+	LastAnnouncementAt time.Time                 `json:"lastAnnouncementAt,omitempty"`
+	Actions            []EmergencyAction         `json:"actions"` // Most recent MaxEmergencyActions, oldest first
+	LastActionTime     time.Time                 `json:"lastActionTime"`
+	LastActionReason   string                    `json:"lastActionReason,omitempty"`
+}
+
+// EmergencyDetectorStatus is one smoke or CO detector
+type EmergencyDetectorStatus struct {
+	EntityID string    `json:"entityId"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"` // "smoke" or "co"
+	Detected bool      `json:"detected"`
+	Since    time.Time `json:"since,omitempty"` // When it last started detecting
+}
+
+// EmergencyAction is a response action taken, or skipped, during an emergency
+type EmergencyAction struct {
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"`           // e.g. "lights", "unlock", "announce"
+	Target    string    `json:"target,omitempty"` // Entity the action was sent to
+	Success   bool      `json:"success"`          // False if the call failed or was skipped
+	Detail    string    `json:"detail,omitempty"` // Error or skip reason
+}
+
+// GetCurrentInputs implements PluginShadowState
+func (s *EmergencyShadowState) GetCurrentInputs() map[string]interface{} {
+	return s.Inputs.Current
+}
+
+// GetLastActionInputs implements PluginShadowState
+func (s *EmergencyShadowState) GetLastActionInputs() map[string]interface{} {
+	return s.Inputs.AtLastAction
+}
+
+// GetOutputs implements PluginShadowState
+func (s *EmergencyShadowState) GetOutputs() interface{} {
+	return s.Outputs
+}
+
+// GetMetadata implements PluginShadowState
+func (s *EmergencyShadowState) GetMetadata() StateMetadata {
+	return s.Metadata
+}
+
+// GetLastActionTime implements ActionTimeProvider
+func (s *EmergencyShadowState) GetLastActionTime() time.Time {
+	return s.Outputs.LastActionTime
+}
+
+// GetLastActionReason implements ActionReasonProvider
+func (s *EmergencyShadowState) GetLastActionReason() string {
+	return s.Outputs.LastActionReason
+}
+
+// NewEmergencyShadowState creates a new emergency shadow state
+func NewEmergencyShadowState() *EmergencyShadowState {
+	return &EmergencyShadowState{
+		Plugin: "emergency",
+		Inputs: EmergencyInputs{
+			Current:      make(map[string]interface{}),
+			AtLastAction: make(map[string]interface{}),
+		},
+		Outputs: EmergencyOutputs{
+			Detectors: []EmergencyDetectorStatus{},
+			Actions:   []EmergencyAction{},
+		},
+		Metadata: StateMetadata{
+			LastUpdated: time.Now(),
+			PluginName:  "emergency",
+		},
+	}
+}
@@ -0,0 +1,225 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ErrBatchInvalid is returned when a batch is rejected because at least one
+// update failed validation. Nothing in the batch is written.
+var ErrBatchInvalid = errors.New("batch contains invalid updates")
+
+// ErrBatchRolledBack is returned when a write in a batch failed and the
+// updates already written were restored to their previous values
+var ErrBatchRolledBack = errors.New("batch write failed and was rolled back")
+
+// Update is one variable change in a batch write
+type Update struct {
+	Key   string
+	Value interface{}
+	Type  StateType // If set, the variable must be of this type
+}
+
+// UpdateResult reports the outcome of one update in a batch
+type UpdateResult struct {
+	Key     string
+	Err     error // Validation or write error for this update
+	Applied bool  // True when the value was written and not rolled back
+}
+
+// SetBatch validates every update before writing any, then writes them in
+// order. If any update is invalid (unknown or read-only variable, wrong value
+// type, duplicate key) nothing is written and ErrBatchInvalid is returned. If
+// a write to Home Assistant fails, the updates already written are restored
+// and ErrBatchRolledBack is returned. Results are in the order of updates.
+func (m *Manager) SetBatch(updates []Update) ([]UpdateResult, error) {
+	if m.parent != nil {
+		results, err := m.parent.SetBatch(m.qualifyUpdates(updates))
+		return unqualifyResults(results, updates), err
+	}
+
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+
+	results, values, err := m.validateBatch(updates)
+	if err != nil {
+		return results, err
+	}
+
+	previous := make([]interface{}, len(updates))
+	for i, update := range updates {
+		old, err := m.currentValue(update.Key)
+		if err == nil {
+			previous[i] = old
+			err = m.setValue(update.Key, values[i])
+		}
+		if err != nil {
+			results[i].Err = err
+			m.rollbackBatch(updates[:i], previous[:i], results[:i])
+			return results, ErrBatchRolledBack
+		}
+		results[i].Applied = true
+	}
+
+	return results, nil
+}
+
+// ValidateBatch checks every update as SetBatch would without writing any.
+// It returns ErrBatchInvalid if any update is invalid; the failing updates
+// have Err set in the results.
+func (m *Manager) ValidateBatch(updates []Update) ([]UpdateResult, error) {
+	if m.parent != nil {
+		results, err := m.parent.ValidateBatch(m.qualifyUpdates(updates))
+		return unqualifyResults(results, updates), err
+	}
+
+	results, _, err := m.validateBatch(updates)
+	return results, err
+}
+
+// validateBatch validates each update and returns the values converted to
+// their variables' types
+func (m *Manager) validateBatch(updates []Update) ([]UpdateResult, []interface{}, error) {
+	results := make([]UpdateResult, len(updates))
+	values := make([]interface{}, len(updates))
+	seen := make(map[string]bool, len(updates))
+	invalid := false
+	for i, update := range updates {
+		results[i].Key = update.Key
+		value, err := m.validateUpdate(update)
+		if err == nil && seen[update.Key] {
+			err = fmt.Errorf("variable %s is updated more than once", update.Key)
+		}
+		seen[update.Key] = true
+		if err != nil {
+			results[i].Err = err
+			invalid = true
+			continue
+		}
+		values[i] = value
+	}
+	if invalid {
+		return results, nil, ErrBatchInvalid
+	}
+	return results, values, nil
+}
+
+// validateUpdate checks that the variable exists and is writable and returns
+// the value converted to the variable's type
+func (m *Manager) validateUpdate(update Update) (interface{}, error) {
+	variable, ok := m.variables[update.Key]
+	if !ok {
+		return nil, fmt.Errorf("variable %s not found", update.Key)
+	}
+	if update.Type != "" && update.Type != variable.Type {
+		return nil, fmt.Errorf("variable %s is a %s, not a %s", update.Key, variable.Type, update.Type)
+	}
+	if err := m.ensureWritable(variable); err != nil {
+		return nil, err
+	}
+
+	switch variable.Type {
+	case TypeBool:
+		if value, ok := update.Value.(bool); ok {
+			return value, nil
+		}
+		return nil, fmt.Errorf("variable %s is not a boolean", update.Key)
+
+	case TypeNumber:
+		switch value := update.Value.(type) {
+		case float64:
+			return value, nil
+		case int:
+			return float64(value), nil
+		case json.Number:
+			number, err := value.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: invalid number %q", update.Key, value)
+			}
+			return number, nil
+		}
+		return nil, fmt.Errorf("variable %s is not a number", update.Key)
+
+	case TypeString:
+		if value, ok := update.Value.(string); ok {
+			return value, nil
+		}
+		return nil, fmt.Errorf("variable %s is not a string", update.Key)
+
+	case TypeJSON:
+		if update.Value == nil {
+			return nil, fmt.Errorf("variable %s: JSON value must not be null", update.Key)
+		}
+		if _, err := json.Marshal(update.Value); err != nil {
+			return nil, fmt.Errorf("variable %s: %w", update.Key, err)
+		}
+		return update.Value, nil
+	}
+
+	return nil, fmt.Errorf("variable %s has unknown type %s", update.Key, variable.Type)
+}
+
+// currentValue returns a variable's value for restoring on rollback
+func (m *Manager) currentValue(key string) (interface{}, error) {
+	switch m.variables[key].Type {
+	case TypeBool:
+		return m.GetBool(key)
+	case TypeNumber:
+		return m.GetNumber(key)
+	case TypeString:
+		return m.GetString(key)
+	default:
+		var value interface{}
+		err := m.GetJSON(key, &value)
+		return value, err
+	}
+}
+
+// setValue writes an already validated value with the setter for its type
+func (m *Manager) setValue(key string, value interface{}) error {
+	switch m.variables[key].Type {
+	case TypeBool:
+		return m.SetBool(key, value.(bool))
+	case TypeNumber:
+		return m.SetNumber(key, value.(float64))
+	case TypeString:
+		return m.SetString(key, value.(string))
+	default:
+		return m.SetJSON(key, value)
+	}
+}
+
+// rollbackBatch restores written updates to their previous values, newest
+// first. An update whose restore fails stays marked as applied.
+func (m *Manager) rollbackBatch(written []Update, previous []interface{}, results []UpdateResult) {
+	for i := len(written) - 1; i >= 0; i-- {
+		if err := m.setValue(written[i].Key, previous[i]); err != nil {
+			results[i].Err = fmt.Errorf("rollback failed: %w", err)
+			m.logger.Error("Failed to roll back batch update",
+				zap.String("key", written[i].Key),
+				zap.Error(err))
+			continue
+		}
+		results[i].Applied = false
+	}
+}
+
+// qualifyUpdates maps a namespaced Manager's keys to the root Manager's keys
+func (m *Manager) qualifyUpdates(updates []Update) []Update {
+	qualified := make([]Update, len(updates))
+	for i, update := range updates {
+		qualified[i] = Update{Key: m.qualify(update.Key), Value: update.Value, Type: update.Type}
+	}
+	return qualified
+}
+
+// unqualifyResults reports results under the keys the caller used
+func unqualifyResults(results []UpdateResult, updates []Update) []UpdateResult {
+	for i := range results {
+		results[i].Key = updates[i].Key
+	}
+	return results
+}
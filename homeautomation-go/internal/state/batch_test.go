@@ -0,0 +1,142 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// failingTextClient fails every input_text write
+type failingTextClient struct {
+	*ha.MockClient
+}
+
+func (c *failingTextClient) SetInputText(ctx context.Context, name string, value string) error {
+	return errors.New("input_text unavailable")
+}
+
+func TestManager_SetBatch(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	manager := NewManager(mockClient, zap.NewNop(), false)
+
+	results, err := manager.SetBatch([]Update{
+		{Key: "isHaveGuests", Value: true},
+		{Key: "alarmTime", Value: 1700000000000},
+		{Key: "dayPhase", Value: "winddown", Type: TypeString},
+		{Key: "currentlyPlayingMusic", Value: map[string]interface{}{"title": "Song"}},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	for _, result := range results {
+		assert.True(t, result.Applied, "%s should be applied", result.Key)
+		assert.NoError(t, result.Err)
+	}
+
+	isHaveGuests, _ := manager.GetBool("isHaveGuests")
+	assert.True(t, isHaveGuests)
+	alarmTime, _ := manager.GetNumber("alarmTime")
+	assert.Equal(t, 1700000000000.0, alarmTime)
+	dayPhase, _ := manager.GetString("dayPhase")
+	assert.Equal(t, "winddown", dayPhase)
+}
+
+func TestManager_SetBatchInvalidWritesNothing(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	manager := NewManager(mockClient, zap.NewNop(), false)
+
+	results, err := manager.SetBatch([]Update{
+		{Key: "isHaveGuests", Value: true},
+		{Key: "dayPhase", Value: 3.0},
+		{Key: "noSuchVariable", Value: true},
+		{Key: "isHaveGuests", Value: false},
+		{Key: "isTVPlaying", Value: true, Type: TypeString},
+	})
+
+	assert.ErrorIs(t, err, ErrBatchInvalid)
+	require.Len(t, results, 5)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorContains(t, results[1].Err, "not a string")
+	assert.ErrorContains(t, results[2].Err, "not found")
+	assert.ErrorContains(t, results[3].Err, "more than once")
+	assert.ErrorContains(t, results[4].Err, "not a string")
+	for _, result := range results {
+		assert.False(t, result.Applied)
+	}
+
+	isHaveGuests, _ := manager.GetBool("isHaveGuests")
+	assert.False(t, isHaveGuests, "valid updates must not be written when the batch is invalid")
+}
+
+func TestManager_SetBatchReadOnlyMode(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), true)
+
+	results, err := manager.SetBatch([]Update{{Key: "isHaveGuests", Value: true}})
+
+	assert.ErrorIs(t, err, ErrBatchInvalid)
+	assert.ErrorIs(t, results[0].Err, ErrReadOnlyMode)
+}
+
+func TestManager_SetBatchRollsBackOnWriteFailure(t *testing.T) {
+	client := &failingTextClient{MockClient: ha.NewMockClient()}
+	manager := NewManager(client, zap.NewNop(), false)
+	require.NoError(t, manager.SetBool("isHaveGuests", false))
+
+	results, err := manager.SetBatch([]Update{
+		{Key: "isHaveGuests", Value: true},
+		{Key: "dayPhase", Value: "night"},
+	})
+
+	assert.ErrorIs(t, err, ErrBatchRolledBack)
+	require.Len(t, results, 2)
+	assert.False(t, results[0].Applied)
+	assert.NoError(t, results[0].Err)
+	assert.False(t, results[1].Applied)
+	assert.ErrorContains(t, results[1].Err, "input_text unavailable")
+
+	isHaveGuests, _ := manager.GetBool("isHaveGuests")
+	assert.False(t, isHaveGuests, "earlier writes should be rolled back")
+}
+
+func TestManager_ValidateBatch(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+
+	results, err := manager.ValidateBatch([]Update{
+		{Key: "isHaveGuests", Value: true},
+		{Key: "alarmTime", Value: "7am"},
+	})
+
+	assert.ErrorIs(t, err, ErrBatchInvalid)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorContains(t, results[1].Err, "not a number")
+
+	isHaveGuests, _ := manager.GetBool("isHaveGuests")
+	assert.False(t, isHaveGuests, "ValidateBatch must not write")
+}
+
+func TestManager_SetBatchNamespaced(t *testing.T) {
+	root := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	suite, err := root.RegisterNamespace("suite", []string{"isAnyoneHome"})
+	require.NoError(t, err)
+
+	results, err := suite.SetBatch([]Update{
+		{Key: "isAnyoneHome", Value: true},
+		{Key: "isHaveGuests", Value: true},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "isAnyoneHome", results[0].Key)
+
+	suiteHome, _ := root.GetBool("suite.isAnyoneHome")
+	assert.True(t, suiteHome)
+	globalHome, _ := root.GetBool("isAnyoneHome")
+	assert.False(t, globalHome)
+	isHaveGuests, _ := root.GetBool("isHaveGuests")
+	assert.True(t, isHaveGuests)
+}
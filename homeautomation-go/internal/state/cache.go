@@ -0,0 +1,157 @@
+package state
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// valueCache holds the cached value of each state variable.
+//
+// Reads never lock: the slot map is copy-on-write and each slot's value is an
+// atomic pointer. Writes lock only their own variable's slot, so a resync
+// burst rewriting every variable doesn't stall readers or writers of other
+// variables. The zero value is an empty cache.
+type valueCache struct {
+	mu    sync.Mutex // Serializes adding slots
+	slots atomic.Pointer[map[string]*cacheSlot]
+}
+
+// cacheSlot is one variable's value; mu serializes read-modify-write updates
+type cacheSlot struct {
+	mu    sync.Mutex
+	value atomic.Pointer[cachedValue]
+}
+
+// cachedValue boxes a value so a stored nil can be told apart from no value
+type cachedValue struct {
+	value interface{}
+}
+
+// newValueCache creates a cache with slots for the given keys, so the slot
+// map is only copied for keys added later (namespaced variables)
+func newValueCache(keys []string) *valueCache {
+	slots := make(map[string]*cacheSlot, len(keys))
+	for _, key := range keys {
+		slots[key] = &cacheSlot{}
+	}
+	c := &valueCache{}
+	c.slots.Store(&slots)
+	return c
+}
+
+// slot returns the key's slot, or nil if it has none
+func (c *valueCache) slot(key string) *cacheSlot {
+	slots := c.slots.Load()
+	if slots == nil {
+		return nil
+	}
+	return (*slots)[key]
+}
+
+// slotFor returns the key's slot, adding one if needed
+func (c *valueCache) slotFor(key string) *cacheSlot {
+	if s := c.slot(key); s != nil {
+		return s
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := c.slots.Load()
+	if current != nil {
+		if s, ok := (*current)[key]; ok {
+			return s
+		}
+	}
+
+	slots := make(map[string]*cacheSlot, 1)
+	if current != nil {
+		slots = make(map[string]*cacheSlot, len(*current)+1)
+		for k, v := range *current {
+			slots[k] = v
+		}
+	}
+	s := &cacheSlot{}
+	slots[key] = s
+	c.slots.Store(&slots)
+	return s
+}
+
+// load returns the cached value and whether there is one
+func (c *valueCache) load(key string) (interface{}, bool) {
+	s := c.slot(key)
+	if s == nil {
+		return nil, false
+	}
+	v := s.value.Load()
+	if v == nil {
+		return nil, false
+	}
+	return v.value, true
+}
+
+// store sets the cached value
+func (c *valueCache) store(key string, value interface{}) {
+	s := c.slotFor(key)
+	s.mu.Lock()
+	s.value.Store(&cachedValue{value: value})
+	s.mu.Unlock()
+}
+
+// swap sets the cached value and returns the previous one (nil if none)
+func (c *valueCache) swap(key string, value interface{}) interface{} {
+	old, _ := c.update(key, func(interface{}, bool) (interface{}, bool) {
+		return value, true
+	})
+	return old
+}
+
+// update calls fn with the current value while holding the variable's lock
+// and stores the value it returns if it also returns true. It returns the
+// previous value (nil if none) and whether a value was stored.
+func (c *valueCache) update(key string, fn func(current interface{}, ok bool) (interface{}, bool)) (interface{}, bool) {
+	s := c.slotFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current interface{}
+	v := s.value.Load()
+	if v != nil {
+		current = v.value
+	}
+
+	next, write := fn(current, v != nil)
+	if write {
+		s.value.Store(&cachedValue{value: next})
+	}
+	return current, write
+}
+
+// loadOrStore returns the cached value if there is one; otherwise it stores
+// and returns value. loaded reports whether the value was already cached.
+func (c *valueCache) loadOrStore(key string, value interface{}) (actual interface{}, loaded bool) {
+	c.update(key, func(current interface{}, ok bool) (interface{}, bool) {
+		if ok {
+			actual, loaded = current, true
+			return nil, false
+		}
+		actual = value
+		return value, true
+	})
+	return actual, loaded
+}
+
+// snapshot copies every cached value. Each value is read atomically, but
+// variables written during the copy may show either their old or new value.
+func (c *valueCache) snapshot() map[string]interface{} {
+	values := make(map[string]interface{})
+	slots := c.slots.Load()
+	if slots == nil {
+		return values
+	}
+	for key, s := range *slots {
+		if v := s.value.Load(); v != nil {
+			values[key] = v.value
+		}
+	}
+	return values
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueCache_LoadStore(t *testing.T) {
+	cache := newValueCache([]string{"known"})
+
+	_, ok := cache.load("known")
+	assert.False(t, ok, "a slot without a value is a miss")
+
+	cache.store("known", nil)
+	value, ok := cache.load("known")
+	assert.True(t, ok, "a stored nil is cached")
+	assert.Nil(t, value)
+
+	// Keys without a slot (namespaced variables) get one on first write
+	cache.store("suite.isAnyoneHome", true)
+	value, ok = cache.load("suite.isAnyoneHome")
+	assert.True(t, ok)
+	assert.Equal(t, true, value)
+
+	assert.Equal(t, map[string]interface{}{"known": nil, "suite.isAnyoneHome": true}, cache.snapshot())
+}
+
+func TestValueCache_Update(t *testing.T) {
+	var cache valueCache
+
+	old, wrote := cache.update("count", func(current interface{}, ok bool) (interface{}, bool) {
+		assert.False(t, ok)
+		return 1.0, true
+	})
+	assert.Nil(t, old)
+	assert.True(t, wrote)
+
+	old, wrote = cache.update("count", func(current interface{}, ok bool) (interface{}, bool) {
+		return 2.0, false
+	})
+	assert.Equal(t, 1.0, old)
+	assert.False(t, wrote)
+	value, _ := cache.load("count")
+	assert.Equal(t, 1.0, value, "the value is kept when update declines")
+
+	assert.Equal(t, 1.0, cache.swap("count", 3.0))
+
+	actual, loaded := cache.loadOrStore("count", 4.0)
+	assert.True(t, loaded)
+	assert.Equal(t, 3.0, actual)
+	actual, loaded = cache.loadOrStore("fresh", 5.0)
+	assert.False(t, loaded)
+	assert.Equal(t, 5.0, actual)
+}
+
+func TestValueCache_ConcurrentUpdates(t *testing.T) {
+	cache := newValueCache(nil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				// Every goroutine increments the shared counter and adds keys
+				cache.update("counter", func(current interface{}, ok bool) (interface{}, bool) {
+					n, _ := current.(int)
+					return n + 1, true
+				})
+				cache.store("key"+strconv.Itoa(g)+"_"+strconv.Itoa(i), i)
+				cache.snapshot()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	value, _ := cache.load("counter")
+	assert.Equal(t, 800, value, "updates to one variable are serialized")
+	assert.Len(t, cache.snapshot(), 801)
+}
@@ -14,6 +14,8 @@ func (m *Manager) SetupComputedState() error {
 	}
 
 	// Subscribe to dependency changes
+	m.DeclareWrites("isAnyoneHome", "isAnyoneHomeAndAwake")
+	m.DeclareWrites("isAnyoneAsleep", "isAnyoneHomeAndAwake")
 	_, err := m.Subscribe("isAnyoneHome", func(key string, oldValue, newValue interface{}) {
 		if err := m.recomputeAnyoneHomeAndAwake(); err != nil {
 			m.logger.Error("Failed to recompute isAnyoneHomeAndAwake",
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"context"
 	"sync/atomic"
 	"testing"
 
@@ -17,7 +18,7 @@ func TestSetupComputedState(t *testing.T) {
 	mockClient.SetState("input_boolean.anyone_home", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_asleep", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	err := manager.SyncFromHA()
@@ -67,7 +68,7 @@ func TestComputedState_IsAnyoneHomeAndAwake_InitialComputation(t *testing.T) {
 			mockClient.SetState("input_boolean.anyone_home", tc.isAnyoneHome, map[string]interface{}{})
 			mockClient.SetState("input_boolean.anyone_asleep", tc.isAnyoneAsleep, map[string]interface{}{})
 			mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-			mockClient.Connect()
+			mockClient.Connect(context.Background())
 
 			manager := NewManager(mockClient, logger, false)
 			err := manager.SyncFromHA()
@@ -90,7 +91,7 @@ func TestComputedState_IsAnyoneHomeAndAwake_ReactsToIsAnyoneHomeChange(t *testin
 	mockClient.SetState("input_boolean.anyone_home", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_asleep", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	err := manager.SyncFromHA()
@@ -125,7 +126,7 @@ func TestComputedState_IsAnyoneHomeAndAwake_ReactsToIsAnyoneAsleepChange(t *test
 	mockClient.SetState("input_boolean.anyone_home", "on", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_asleep", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	err := manager.SyncFromHA()
@@ -159,7 +160,7 @@ func TestComputedState_IsAnyoneHomeAndAwake_SyncsToHA(t *testing.T) {
 	mockClient.SetState("input_boolean.anyone_home", "on", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_asleep", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	err := manager.SyncFromHA()
@@ -196,7 +197,7 @@ func TestComputedState_IsAnyoneHomeAndAwake_WorksInReadOnlyMode(t *testing.T) {
 	mockClient.SetState("input_boolean.anyone_home", "on", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_asleep", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	// Create manager in read-only mode
 	manager := NewManager(mockClient, logger, true)
@@ -231,7 +232,7 @@ func TestComputedState_IsAnyoneHomeAndAwake_SubscriberNotification(t *testing.T)
 	mockClient.SetState("input_boolean.anyone_home", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_asleep", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.anyone_home_and_awake", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	err := manager.SyncFromHA()
@@ -0,0 +1,243 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultMaxCascadeDepth is how many variables a write may set off in a
+// chain (a write to A whose subscriber writes B whose subscriber writes C is
+// depth 2) before CheckCascades warns about it
+const DefaultMaxCascadeDepth = 3
+
+// callbackStats accumulates the time spent in one variable's subscribers
+type callbackStats struct {
+	calls uint64
+	total time.Duration
+}
+
+// VariableFanOut describes who listens to a variable and what a write to it
+// sets off
+type VariableFanOut struct {
+	Key            string   `json:"key"`
+	Subscribers    int      `json:"subscribers"`
+	Notifications  uint64   `json:"notifications"` // Changes delivered to the subscribers so far
+	AvgCallbackMs  float64  `json:"avgCallbackMs"` // Mean time for all subscribers to handle a change, including any cascade
+	Writes         []string `json:"writes,omitempty"`
+	CascadeDepth   int      `json:"cascadeDepth"`             // Longest chain of declared writes a change sets off
+	CascadeExample []string `json:"cascadeExample,omitempty"` // The chain reaching CascadeDepth
+}
+
+// FanOutReport is the subscription graph of the state variables
+type FanOutReport struct {
+	Variables []VariableFanOut `json:"variables"`
+	Cycles    [][]string       `json:"cycles,omitempty"` // Chains of declared writes that lead back to their start
+}
+
+// DeclareWrites records that a subscriber to trigger writes the given
+// variables. The declarations form the graph FanOut and CheckCascades walk;
+// writes can't be observed from the callbacks themselves.
+func (m *Manager) DeclareWrites(trigger string, writes ...string) {
+	if m.parent != nil {
+		qualified := make([]string, len(writes))
+		for i, key := range writes {
+			qualified[i] = m.qualify(key)
+		}
+		m.parent.DeclareWrites(m.qualify(trigger), qualified...)
+		return
+	}
+
+	m.fanOutMu.Lock()
+	defer m.fanOutMu.Unlock()
+	if m.declaredWrites == nil {
+		m.declaredWrites = make(map[string]map[string]bool)
+	}
+	if m.declaredWrites[trigger] == nil {
+		m.declaredWrites[trigger] = make(map[string]bool)
+	}
+	for _, key := range writes {
+		m.declaredWrites[trigger][key] = true
+	}
+}
+
+// recordCallbacks adds the time a variable's subscribers took for a change
+func (m *Manager) recordCallbacks(key string, elapsed time.Duration) {
+	m.fanOutMu.Lock()
+	defer m.fanOutMu.Unlock()
+	if m.callbackStats == nil {
+		m.callbackStats = make(map[string]*callbackStats)
+	}
+	stats := m.callbackStats[key]
+	if stats == nil {
+		stats = &callbackStats{}
+		m.callbackStats[key] = stats
+	}
+	stats.calls++
+	stats.total += elapsed
+}
+
+// FanOut reports, for every variable with subscribers or declared writes, how
+// many subscribers it has, how long they take and how far a change cascades
+func (m *Manager) FanOut() FanOutReport {
+	if m.parent != nil {
+		return m.parent.FanOut()
+	}
+
+	m.subsMu.RLock()
+	subscribers := make(map[string]int, len(m.subscribers))
+	for key, handlers := range m.subscribers {
+		subscribers[key] = len(handlers)
+	}
+	m.subsMu.RUnlock()
+
+	m.fanOutMu.Lock()
+	graph := m.writeGraph()
+	stats := make(map[string]callbackStats, len(m.callbackStats))
+	for key, s := range m.callbackStats {
+		stats[key] = *s
+	}
+	m.fanOutMu.Unlock()
+
+	keys := make(map[string]bool)
+	for key := range subscribers {
+		keys[key] = true
+	}
+	for key := range graph {
+		keys[key] = true
+	}
+	for key := range stats {
+		keys[key] = true
+	}
+
+	report := FanOutReport{Variables: make([]VariableFanOut, 0, len(keys)), Cycles: findCycles(graph)}
+	for _, key := range sortedKeys(keys) {
+		chain := longestCascade(graph, key)
+		variable := VariableFanOut{
+			Key:           key,
+			Subscribers:   subscribers[key],
+			Notifications: stats[key].calls,
+			Writes:        graph[key],
+			CascadeDepth:  len(chain) - 1,
+		}
+		if s := stats[key]; s.calls > 0 {
+			variable.AvgCallbackMs = float64(s.total) / float64(s.calls) / float64(time.Millisecond)
+		}
+		if len(chain) > 1 {
+			variable.CascadeExample = chain
+		}
+		report.Variables = append(report.Variables, variable)
+	}
+	return report
+}
+
+// CheckCascades logs a warning for every variable whose changes can set off a
+// chain of writes deeper than maxDepth, and for every cycle of writes, and
+// returns the warnings. Call it once the plugins have subscribed.
+func (m *Manager) CheckCascades(maxDepth int) []string {
+	report := m.FanOut()
+
+	var warnings []string
+	for _, cycle := range report.Cycles {
+		warning := fmt.Sprintf("state writes form a cycle: %s", strings.Join(cycle, " -> "))
+		m.logger.Warn("State variable writes can re-trigger themselves",
+			zap.Strings("cycle", cycle))
+		warnings = append(warnings, warning)
+	}
+	for _, variable := range report.Variables {
+		if variable.CascadeDepth <= maxDepth {
+			continue
+		}
+		warning := fmt.Sprintf("a change to %s cascades %d writes deep: %s",
+			variable.Key, variable.CascadeDepth, strings.Join(variable.CascadeExample, " -> "))
+		m.logger.Warn("State variable change cascades deeper than allowed",
+			zap.String("key", variable.Key),
+			zap.Int("depth", variable.CascadeDepth),
+			zap.Int("max_depth", maxDepth),
+			zap.Strings("cascade", variable.CascadeExample))
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// writeGraph returns the declared writes as sorted adjacency lists. The
+// caller holds fanOutMu.
+func (m *Manager) writeGraph() map[string][]string {
+	graph := make(map[string][]string, len(m.declaredWrites))
+	for trigger, writes := range m.declaredWrites {
+		graph[trigger] = sortedKeys(writes)
+	}
+	return graph
+}
+
+// longestCascade returns the longest chain of writes starting at key that
+// doesn't revisit a variable, beginning with key itself
+func longestCascade(graph map[string][]string, key string) []string {
+	onPath := map[string]bool{key: true}
+	var walk func(key string) []string
+	walk = func(key string) []string {
+		var longest []string
+		for _, next := range graph[key] {
+			if onPath[next] {
+				continue
+			}
+			onPath[next] = true
+			if chain := walk(next); len(chain) > len(longest) {
+				longest = chain
+			}
+			delete(onPath, next)
+		}
+		return append([]string{key}, longest...)
+	}
+	return walk(key)
+}
+
+// findCycles returns every cycle of writes once, each starting at its
+// alphabetically first variable and ending back at it
+func findCycles(graph map[string][]string) [][]string {
+	seen := make(map[string]bool)
+	var cycles [][]string
+	var walk func(start string, path []string, onPath map[string]bool)
+	walk = func(start string, path []string, onPath map[string]bool) {
+		key := path[len(path)-1]
+		for _, next := range graph[key] {
+			switch {
+			case next == start:
+				cycle := append(append([]string(nil), path...), start)
+				if id := strings.Join(cycle, "\x00"); !seen[id] {
+					seen[id] = true
+					cycles = append(cycles, cycle)
+				}
+			case !onPath[next] && next > start:
+				// Only cycles through variables after start, so each is found
+				// from its first variable
+				onPath[next] = true
+				walk(start, append(path, next), onPath)
+				delete(onPath, next)
+			}
+		}
+	}
+
+	starts := make([]string, 0, len(graph))
+	for key := range graph {
+		starts = append(starts, key)
+	}
+	sort.Strings(starts)
+	for _, start := range starts {
+		walk(start, []string{start}, map[string]bool{start: true})
+	}
+	return cycles
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package state
+
+import (
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fanOutFor returns the report entry for key
+func fanOutFor(t *testing.T, report FanOutReport, key string) VariableFanOut {
+	t.Helper()
+	for _, variable := range report.Variables {
+		if variable.Key == key {
+			return variable
+		}
+	}
+	t.Fatalf("no fan-out entry for %s", key)
+	return VariableFanOut{}
+}
+
+func TestFanOut_SubscribersAndCallbacks(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	for i := 0; i < 2; i++ {
+		_, err := manager.Subscribe("isNickHome", func(string, interface{}, interface{}) {})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, manager.SetBool("isNickHome", true))
+	require.NoError(t, manager.SetBool("isNickHome", false))
+
+	variable := fanOutFor(t, manager.FanOut(), "isNickHome")
+	assert.Equal(t, 2, variable.Subscribers)
+	assert.Equal(t, uint64(2), variable.Notifications)
+	assert.GreaterOrEqual(t, variable.AvgCallbackMs, 0.0)
+	assert.Zero(t, variable.CascadeDepth)
+}
+
+func TestFanOut_CascadeDepth(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	manager.DeclareWrites("isNickHome", "isAnyOwnerHome", "isAnyoneHome")
+	manager.DeclareWrites("isAnyOwnerHome", "isAnyoneHome")
+	manager.DeclareWrites("isAnyoneHome", "isAnyoneHomeAndAwake")
+
+	report := manager.FanOut()
+	assert.Empty(t, report.Cycles)
+
+	variable := fanOutFor(t, report, "isNickHome")
+	assert.Equal(t, []string{"isAnyOwnerHome", "isAnyoneHome"}, variable.Writes)
+	assert.Equal(t, 3, variable.CascadeDepth)
+	assert.Equal(t, []string{"isNickHome", "isAnyOwnerHome", "isAnyoneHome", "isAnyoneHomeAndAwake"}, variable.CascadeExample)
+
+	assert.Empty(t, manager.CheckCascades(3))
+	warnings := manager.CheckCascades(2)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "a change to isNickHome cascades 3 writes deep")
+}
+
+func TestFanOut_Cycles(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	manager.DeclareWrites("isMasterAsleep", "isGuestAsleep")
+	manager.DeclareWrites("isGuestAsleep", "isAnyoneAsleep")
+	manager.DeclareWrites("isAnyoneAsleep", "isMasterAsleep")
+	manager.DeclareWrites("isHaveGuests", "isHaveGuests")
+
+	report := manager.FanOut()
+	assert.Equal(t, [][]string{
+		{"isAnyoneAsleep", "isMasterAsleep", "isGuestAsleep", "isAnyoneAsleep"},
+		{"isHaveGuests", "isHaveGuests"},
+	}, report.Cycles)
+
+	// Cascades stop at a variable already on the chain
+	assert.Equal(t, 2, fanOutFor(t, report, "isGuestAsleep").CascadeDepth)
+
+	warnings := manager.CheckCascades(DefaultMaxCascadeDepth)
+	assert.Len(t, warnings, 2)
+}
+
+func TestFanOut_NamespacedDeclarations(t *testing.T) {
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	suite, err := manager.RegisterNamespace("suite", []string{"isAnyoneHome", "isAnyoneHomeAndAwake"})
+	require.NoError(t, err)
+
+	suite.DeclareWrites("isAnyoneHome", "isAnyoneHomeAndAwake")
+
+	variable := fanOutFor(t, manager.FanOut(), QualifiedKey("suite", "isAnyoneHome"))
+	assert.Equal(t, []string{QualifiedKey("suite", "isAnyoneHomeAndAwake")}, variable.Writes)
+}
@@ -70,6 +70,7 @@ func (h *DerivedStateHelper) Stop() {
 // setupPresenceTracking subscribes to presence changes and updates isAnyOwnerHome and isAnyoneHome
 func (h *DerivedStateHelper) setupPresenceTracking() error {
 	// Subscribe to Nick's presence
+	h.manager.DeclareWrites("isNickHome", "isAnyOwnerHome", "isAnyoneHome")
 	sub1, err := h.manager.Subscribe("isNickHome", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Nick's presence changed", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.updateIsAnyOwnerHome()
@@ -81,6 +82,7 @@ func (h *DerivedStateHelper) setupPresenceTracking() error {
 	h.subs = append(h.subs, sub1)
 
 	// Subscribe to Caroline's presence
+	h.manager.DeclareWrites("isCarolineHome", "isAnyOwnerHome", "isAnyoneHome")
 	sub2, err := h.manager.Subscribe("isCarolineHome", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Caroline's presence changed", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.updateIsAnyOwnerHome()
@@ -92,6 +94,7 @@ func (h *DerivedStateHelper) setupPresenceTracking() error {
 	h.subs = append(h.subs, sub2)
 
 	// Subscribe to Tori's presence
+	h.manager.DeclareWrites("isToriHere", "isAnyoneHome")
 	sub3, err := h.manager.Subscribe("isToriHere", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Tori's presence changed", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.updateIsAnyoneHome()
@@ -107,6 +110,7 @@ func (h *DerivedStateHelper) setupPresenceTracking() error {
 // setupSleepTracking subscribes to sleep state changes and updates isAnyoneAsleep and isEveryoneAsleep
 func (h *DerivedStateHelper) setupSleepTracking() error {
 	// Subscribe to master bedroom sleep state
+	h.manager.DeclareWrites("isMasterAsleep", "isAnyoneAsleep", "isEveryoneAsleep")
 	sub1, err := h.manager.Subscribe("isMasterAsleep", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Master sleep state changed", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.updateIsAnyoneAsleep()
@@ -118,6 +122,7 @@ func (h *DerivedStateHelper) setupSleepTracking() error {
 	h.subs = append(h.subs, sub1)
 
 	// Subscribe to guest bedroom sleep state
+	h.manager.DeclareWrites("isGuestAsleep", "isAnyoneAsleep", "isEveryoneAsleep")
 	sub2, err := h.manager.Subscribe("isGuestAsleep", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Guest sleep state changed", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.updateIsAnyoneAsleep()
@@ -139,6 +144,7 @@ func (h *DerivedStateHelper) setupSleepTracking() error {
 // - Guest bedroom door is closed
 func (h *DerivedStateHelper) setupAutoSleepDetection() error {
 	// Subscribe to guest bedroom door state
+	h.manager.DeclareWrites("isGuestBedroomDoorOpen", "isGuestAsleep")
 	sub, err := h.manager.Subscribe("isGuestBedroomDoorOpen", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Guest bedroom door state changed", zap.Any("old", oldValue), zap.Any("new", newValue))
 
@@ -321,6 +327,7 @@ func (h *DerivedStateHelper) checkAutoGuestSleep() {
 // This matches Node-RED behavior in flows.json:2366-2396
 func (h *DerivedStateHelper) setupGuestAsleepAutoSync() error {
 	// Subscribe to master bedroom sleep state changes
+	h.manager.DeclareWrites("isMasterAsleep", "isGuestAsleep")
 	sub1, err := h.manager.Subscribe("isMasterAsleep", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Master sleep state changed (for guest auto-sync)", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.syncGuestAsleepIfNoGuests()
@@ -331,6 +338,7 @@ func (h *DerivedStateHelper) setupGuestAsleepAutoSync() error {
 	h.subs = append(h.subs, sub1)
 
 	// Subscribe to isHaveGuests changes
+	h.manager.DeclareWrites("isHaveGuests", "isGuestAsleep")
 	sub2, err := h.manager.Subscribe("isHaveGuests", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Have guests state changed (for guest auto-sync)", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.syncGuestAsleepIfNoGuests()
@@ -341,6 +349,7 @@ func (h *DerivedStateHelper) setupGuestAsleepAutoSync() error {
 	h.subs = append(h.subs, sub2)
 
 	// Subscribe to guest asleep changes (to handle edge cases)
+	h.manager.DeclareWrites("isGuestAsleep", "isGuestAsleep")
 	sub3, err := h.manager.Subscribe("isGuestAsleep", func(key string, oldValue, newValue interface{}) {
 		h.logger.Debug("Guest sleep state changed (for guest auto-sync)", zap.Any("old", oldValue), zap.Any("new", newValue))
 		h.syncGuestAsleepIfNoGuests()
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,11 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"homeautomation/internal/events"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/metrics"
 
 	"go.uber.org/zap"
 )
@@ -40,8 +44,7 @@ func (s *subscription) Unsubscribe() {
 type Manager struct {
 	client      ha.HAClient
 	logger      *zap.Logger
-	cache       map[string]interface{}
-	cacheMu     sync.RWMutex
+	cache       *valueCache
 	variables   map[string]StateVariable
 	entityToKey map[string]string
 	subscribers map[string]map[uint64]StateChangeHandler
@@ -50,6 +53,31 @@ type Manager struct {
 	haSubsMu    sync.Mutex
 	nextSubID   uint64
 	readOnly    bool
+
+	// Serializes SetBatch so one batch's rollback can't interleave with another's writes
+	batchMu sync.Mutex
+
+	// readThroughTimeout bounds HA reads on a cache miss; zero disables read-through
+	readThroughTimeout time.Duration
+
+	// Typed events for every change, published after the subscribers above
+	events *events.Bus
+
+	// Records how long subscriber callbacks take; nil until SetMetrics
+	callbackDuration atomic.Pointer[metrics.Histogram]
+
+	// Declared subscriber writes and per-variable callback timings, for FanOut
+	fanOutMu       sync.Mutex
+	declaredWrites map[string]map[string]bool
+	callbackStats  map[string]*callbackStats
+
+	// Namespaced and zone copies of variables, synced along with AllVariables
+	namespaced []StateVariable
+
+	// Set on a Manager scoped to a namespace; all calls go to parent
+	parent    *Manager
+	namespace string
+	scoped    map[string]bool // Keys that resolve to the namespace's copy
 }
 
 // NewManager creates a new state manager
@@ -61,23 +89,71 @@ func NewManager(client ha.HAClient, logger *zap.Logger, readOnly bool) *Manager
 		entityToKey[v.EntityID] = key
 	}
 
-	return &Manager{
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+
+	m := &Manager{
 		client:      client,
 		logger:      logger,
-		cache:       make(map[string]interface{}),
+		cache:       newValueCache(keys),
 		variables:   variables,
 		entityToKey: entityToKey,
 		subscribers: make(map[string]map[uint64]StateChangeHandler),
 		haSubs:      make(map[string]ha.Subscription),
 		readOnly:    readOnly,
+		events:      events.NewBus(logger),
 	}
+	m.events.SetSource(m)
+	return m
 }
 
-// SyncFromHA reads all state variables from Home Assistant
+// Events returns the bus that carries typed change events for every state
+// variable. A namespaced Manager shares its root's bus, so its events carry
+// qualified keys.
+func (m *Manager) Events() *events.Bus {
+	if m.parent != nil {
+		return m.parent.Events()
+	}
+	return m.events
+}
+
+// SetReadThrough makes reads of variables missing from the cache query Home
+// Assistant directly, waiting at most timeout, and cache the result. This lets
+// plugins that start before SyncFromHA completes see real values instead of
+// defaults. A zero timeout disables read-through (the default).
+func (m *Manager) SetReadThrough(timeout time.Duration) {
+	if m.parent != nil {
+		m.parent.SetReadThrough(timeout)
+		return
+	}
+	m.readThroughTimeout = timeout
+}
+
+// SetMetrics records how long subscriber callbacks take in the registry
+func (m *Manager) SetMetrics(registry *metrics.Registry) {
+	if m.parent != nil {
+		m.parent.SetMetrics(registry)
+		return
+	}
+	m.callbackDuration.Store(registry.SubscriptionCallbackDuration())
+}
+
+// SyncFromHA reads all state variables from Home Assistant.
+// State reads and writes are bounded by ha.DefaultRequestTimeout.
+//
+// It is also called after a reconnect to backfill changes missed while
+// disconnected: subscribers are notified of every value that differs from the
+// cache, and local-only variables keep their values.
 func (m *Manager) SyncFromHA() error {
+	if m.parent != nil {
+		return fmt.Errorf("namespace %s: sync the root state manager instead", m.namespace)
+	}
+
 	m.logger.Info("Syncing state from Home Assistant...")
 
-	states, err := m.client.GetAllStates()
+	states, err := m.client.GetAllStates(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get states: %w", err)
 	}
@@ -91,12 +167,11 @@ func (m *Manager) SyncFromHA() error {
 	// Sync each variable
 	syncCount := 0
 	localCount := 0
-	for _, variable := range AllVariables {
+	variables := append(append([]StateVariable(nil), AllVariables...), m.namespaced...)
+	for _, variable := range variables {
 		// Skip local-only variables (not synced with HA)
 		if variable.LocalOnly {
-			m.cacheMu.Lock()
-			m.cache[variable.Key] = variable.Default
-			m.cacheMu.Unlock()
+			m.cache.loadOrStore(variable.Key, variable.Default)
 			localCount++
 			m.logger.Debug("Initialized local-only variable",
 				zap.String("key", variable.Key))
@@ -108,9 +183,7 @@ func (m *Manager) SyncFromHA() error {
 			m.logger.Warn("Entity not found in HA, using default",
 				zap.String("entity_id", variable.EntityID),
 				zap.String("key", variable.Key))
-			m.cacheMu.Lock()
-			m.cache[variable.Key] = variable.Default
-			m.cacheMu.Unlock()
+			m.cache.store(variable.Key, variable.Default)
 			continue
 		}
 
@@ -121,17 +194,24 @@ func (m *Manager) SyncFromHA() error {
 				zap.String("entity_id", variable.EntityID),
 				zap.String("key", variable.Key),
 				zap.Error(err))
-			m.cacheMu.Lock()
-			m.cache[variable.Key] = variable.Default
-			m.cacheMu.Unlock()
+			m.cache.store(variable.Key, variable.Default)
 			continue
 		}
 
-		m.cacheMu.Lock()
-		m.cache[variable.Key] = value
-		m.cacheMu.Unlock()
+		oldValue, changed := m.cache.update(variable.Key, func(current interface{}, ok bool) (interface{}, bool) {
+			return value, !ok || !reflect.DeepEqual(current, value)
+		})
 		syncCount++
 
+		// A value that was already cached changed while events were missed
+		if changed && oldValue != nil {
+			m.logger.Info("State changed while disconnected",
+				zap.String("key", variable.Key),
+				zap.Any("old", oldValue),
+				zap.Any("new", value))
+			m.notifySubscribers(variable.Key, oldValue, value)
+		}
+
 		// Subscribe to state changes
 		if err := m.subscribeToEntity(variable.EntityID, variable.Key); err != nil {
 			m.logger.Warn("Failed to subscribe to entity",
@@ -143,7 +223,7 @@ func (m *Manager) SyncFromHA() error {
 	m.logger.Info("State sync complete",
 		zap.Int("synced", syncCount),
 		zap.Int("local_only", localCount),
-		zap.Int("total", len(AllVariables)))
+		zap.Int("total", len(variables)))
 
 	return nil
 }
@@ -199,10 +279,7 @@ func (m *Manager) subscribeToEntity(entityID, key string) error {
 		}
 
 		// Update cache
-		m.cacheMu.Lock()
-		oldValue := m.cache[key]
-		m.cache[key] = newValue
-		m.cacheMu.Unlock()
+		oldValue := m.cache.swap(key, newValue)
 
 		m.logger.Debug("State changed",
 			zap.String("key", key),
@@ -238,9 +315,14 @@ func (m *Manager) notifySubscribers(key string, oldValue, newValue interface{})
 	}
 	m.subsMu.RUnlock()
 
+	callbackDuration := m.callbackDuration.Load()
+	notifyStart := time.Now()
 	for idx, handler := range handlers {
 		func(h StateChangeHandler, ordinal int) {
+			start := time.Now()
 			defer func() {
+				callbackDuration.Observe(time.Since(start).Seconds(), "state")
+
 				if r := recover(); r != nil {
 					m.logger.Warn("State change handler panicked",
 						zap.String("key", key),
@@ -253,6 +335,40 @@ func (m *Manager) notifySubscribers(key string, oldValue, newValue interface{})
 			h(key, oldValue, newValue)
 		}(handler, idx)
 	}
+	if len(handlers) > 0 {
+		m.recordCallbacks(key, time.Since(notifyStart))
+	}
+
+	m.publishEvent(key, oldValue, newValue)
+}
+
+// publishEvent publishes a change as the typed event for the variable's type.
+// JSON variables have no typed event.
+func (m *Manager) publishEvent(key string, oldValue, newValue interface{}) {
+	initial := oldValue == nil
+	switch m.variables[key].Type {
+	case TypeBool:
+		newBool, ok := newValue.(bool)
+		if !ok {
+			return
+		}
+		oldBool, _ := oldValue.(bool)
+		m.events.PublishBool(events.BoolChanged{Key: key, Old: oldBool, New: newBool, Initial: initial})
+	case TypeString:
+		newString, ok := newValue.(string)
+		if !ok {
+			return
+		}
+		oldString, _ := oldValue.(string)
+		m.events.PublishString(events.StringChanged{Key: key, Old: oldString, New: newString, Initial: initial})
+	case TypeNumber:
+		newNumber, ok := newValue.(float64)
+		if !ok {
+			return
+		}
+		oldNumber, _ := oldValue.(float64)
+		m.events.PublishNumber(events.NumberChanged{Key: key, Old: oldNumber, New: newNumber, Initial: initial})
+	}
 }
 
 func (m *Manager) ensureWritable(variable StateVariable) error {
@@ -269,6 +385,10 @@ func (m *Manager) ensureWritable(variable StateVariable) error {
 
 // GetBool retrieves a boolean state variable
 func (m *Manager) GetBool(key string) (bool, error) {
+	if m.parent != nil {
+		return m.parent.GetBool(m.qualify(key))
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return false, fmt.Errorf("variable %s not found", key)
@@ -278,10 +398,7 @@ func (m *Manager) GetBool(key string) (bool, error) {
 		return false, fmt.Errorf("variable %s is not a boolean", key)
 	}
 
-	m.cacheMu.RLock()
-	value, ok := m.cache[key]
-	m.cacheMu.RUnlock()
-
+	value, ok := m.lookup(variable)
 	if !ok {
 		return variable.Default.(bool), nil
 	}
@@ -296,6 +413,10 @@ func (m *Manager) GetBool(key string) (bool, error) {
 
 // SetBool sets a boolean state variable
 func (m *Manager) SetBool(key string, value bool) error {
+	if m.parent != nil {
+		return m.parent.SetBool(m.qualify(key), value)
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return fmt.Errorf("variable %s not found", key)
@@ -308,21 +429,15 @@ func (m *Manager) SetBool(key string, value bool) error {
 		return err
 	}
 
-	// Check if value has actually changed
-	m.cacheMu.Lock()
-	oldValue, ok := m.cache[key]
-	if ok {
-		if oldBool, isBool := oldValue.(bool); isBool && oldBool == value {
-			// Value hasn't changed, skip update
-			m.cacheMu.Unlock()
-			return nil
-		}
+	// Update the cache unless the value hasn't changed
+	oldValue, changed := m.cache.update(key, func(current interface{}, ok bool) (interface{}, bool) {
+		oldBool, isBool := current.(bool)
+		return value, !ok || !isBool || oldBool != value
+	})
+	if !changed {
+		return nil
 	}
 
-	// Update cache
-	m.cache[key] = value
-	m.cacheMu.Unlock()
-
 	// Skip HA sync for local-only variables, but still notify subscribers
 	if variable.LocalOnly {
 		m.notifySubscribers(key, oldValue, value)
@@ -331,11 +446,9 @@ func (m *Manager) SetBool(key string, value bool) error {
 
 	// Sync to HA
 	entityName := extractEntityName(variable.EntityID)
-	if err := m.client.SetInputBoolean(entityName, value); err != nil {
+	if err := m.client.SetInputBoolean(context.Background(), entityName, value); err != nil {
 		// Rollback cache on error
-		m.cacheMu.Lock()
-		m.cache[key] = oldValue
-		m.cacheMu.Unlock()
+		m.cache.store(key, oldValue)
 		return fmt.Errorf("failed to set HA value: %w", err)
 	}
 
@@ -344,6 +457,10 @@ func (m *Manager) SetBool(key string, value bool) error {
 
 // GetString retrieves a string state variable
 func (m *Manager) GetString(key string) (string, error) {
+	if m.parent != nil {
+		return m.parent.GetString(m.qualify(key))
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return "", fmt.Errorf("variable %s not found", key)
@@ -353,10 +470,7 @@ func (m *Manager) GetString(key string) (string, error) {
 		return "", fmt.Errorf("variable %s is not a string", key)
 	}
 
-	m.cacheMu.RLock()
-	value, ok := m.cache[key]
-	m.cacheMu.RUnlock()
-
+	value, ok := m.lookup(variable)
 	if !ok {
 		return variable.Default.(string), nil
 	}
@@ -371,6 +485,10 @@ func (m *Manager) GetString(key string) (string, error) {
 
 // SetString sets a string state variable
 func (m *Manager) SetString(key string, value string) error {
+	if m.parent != nil {
+		return m.parent.SetString(m.qualify(key), value)
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return fmt.Errorf("variable %s not found", key)
@@ -383,21 +501,15 @@ func (m *Manager) SetString(key string, value string) error {
 		return err
 	}
 
-	// Check if value has actually changed
-	m.cacheMu.Lock()
-	oldValue, ok := m.cache[key]
-	if ok {
-		if oldStr, isStr := oldValue.(string); isStr && oldStr == value {
-			// Value hasn't changed, skip update
-			m.cacheMu.Unlock()
-			return nil
-		}
+	// Update the cache unless the value hasn't changed
+	oldValue, changed := m.cache.update(key, func(current interface{}, ok bool) (interface{}, bool) {
+		oldStr, isStr := current.(string)
+		return value, !ok || !isStr || oldStr != value
+	})
+	if !changed {
+		return nil
 	}
 
-	// Update cache
-	m.cache[key] = value
-	m.cacheMu.Unlock()
-
 	// Skip HA sync for local-only variables, but still notify subscribers
 	if variable.LocalOnly {
 		m.notifySubscribers(key, oldValue, value)
@@ -406,11 +518,9 @@ func (m *Manager) SetString(key string, value string) error {
 
 	// Sync to HA
 	entityName := extractEntityName(variable.EntityID)
-	if err := m.client.SetInputText(entityName, value); err != nil {
+	if err := m.client.SetInputText(context.Background(), entityName, value); err != nil {
 		// Rollback cache on error
-		m.cacheMu.Lock()
-		m.cache[key] = oldValue
-		m.cacheMu.Unlock()
+		m.cache.store(key, oldValue)
 		return fmt.Errorf("failed to set HA value: %w", err)
 	}
 
@@ -419,6 +529,10 @@ func (m *Manager) SetString(key string, value string) error {
 
 // GetNumber retrieves a number state variable
 func (m *Manager) GetNumber(key string) (float64, error) {
+	if m.parent != nil {
+		return m.parent.GetNumber(m.qualify(key))
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return 0, fmt.Errorf("variable %s not found", key)
@@ -428,10 +542,7 @@ func (m *Manager) GetNumber(key string) (float64, error) {
 		return 0, fmt.Errorf("variable %s is not a number", key)
 	}
 
-	m.cacheMu.RLock()
-	value, ok := m.cache[key]
-	m.cacheMu.RUnlock()
-
+	value, ok := m.lookup(variable)
 	if !ok {
 		return variable.Default.(float64), nil
 	}
@@ -446,6 +557,10 @@ func (m *Manager) GetNumber(key string) (float64, error) {
 
 // SetNumber sets a number state variable
 func (m *Manager) SetNumber(key string, value float64) error {
+	if m.parent != nil {
+		return m.parent.SetNumber(m.qualify(key), value)
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return fmt.Errorf("variable %s not found", key)
@@ -458,21 +573,15 @@ func (m *Manager) SetNumber(key string, value float64) error {
 		return err
 	}
 
-	// Check if value has actually changed
-	m.cacheMu.Lock()
-	oldValue, ok := m.cache[key]
-	if ok {
-		if oldNum, isNum := oldValue.(float64); isNum && oldNum == value {
-			// Value hasn't changed, skip update
-			m.cacheMu.Unlock()
-			return nil
-		}
+	// Update the cache unless the value hasn't changed
+	oldValue, changed := m.cache.update(key, func(current interface{}, ok bool) (interface{}, bool) {
+		oldNum, isNum := current.(float64)
+		return value, !ok || !isNum || oldNum != value
+	})
+	if !changed {
+		return nil
 	}
 
-	// Update cache
-	m.cache[key] = value
-	m.cacheMu.Unlock()
-
 	// Skip HA sync for local-only variables, but still notify subscribers
 	if variable.LocalOnly {
 		m.notifySubscribers(key, oldValue, value)
@@ -481,11 +590,9 @@ func (m *Manager) SetNumber(key string, value float64) error {
 
 	// Sync to HA
 	entityName := extractEntityName(variable.EntityID)
-	if err := m.client.SetInputNumber(entityName, value); err != nil {
+	if err := m.client.SetInputNumber(context.Background(), entityName, value); err != nil {
 		// Rollback cache on error
-		m.cacheMu.Lock()
-		m.cache[key] = oldValue
-		m.cacheMu.Unlock()
+		m.cache.store(key, oldValue)
 		return fmt.Errorf("failed to set HA value: %w", err)
 	}
 
@@ -494,6 +601,10 @@ func (m *Manager) SetNumber(key string, value float64) error {
 
 // GetJSON retrieves a JSON state variable
 func (m *Manager) GetJSON(key string, target interface{}) error {
+	if m.parent != nil {
+		return m.parent.GetJSON(m.qualify(key), target)
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return fmt.Errorf("variable %s not found", key)
@@ -503,10 +614,7 @@ func (m *Manager) GetJSON(key string, target interface{}) error {
 		return fmt.Errorf("variable %s is not JSON", key)
 	}
 
-	m.cacheMu.RLock()
-	value, ok := m.cache[key]
-	m.cacheMu.RUnlock()
-
+	value, ok := m.lookup(variable)
 	if !ok {
 		jsonBytes, err := marshalJSONValue(variable.Default)
 		if err != nil {
@@ -526,6 +634,10 @@ func (m *Manager) GetJSON(key string, target interface{}) error {
 
 // SetJSON sets a JSON state variable
 func (m *Manager) SetJSON(key string, value interface{}) error {
+	if m.parent != nil {
+		return m.parent.SetJSON(m.qualify(key), value)
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return fmt.Errorf("variable %s not found", key)
@@ -538,19 +650,14 @@ func (m *Manager) SetJSON(key string, value interface{}) error {
 		return err
 	}
 
-	// Check if value has actually changed (using deep equality for JSON)
-	m.cacheMu.Lock()
-	oldValue, ok := m.cache[key]
-	if ok && reflect.DeepEqual(oldValue, value) {
-		// Value hasn't changed, skip update
-		m.cacheMu.Unlock()
+	// Update the cache unless the value hasn't changed (using deep equality for JSON)
+	oldValue, changed := m.cache.update(key, func(current interface{}, ok bool) (interface{}, bool) {
+		return value, !ok || !reflect.DeepEqual(current, value)
+	})
+	if !changed {
 		return nil
 	}
 
-	// Update cache
-	m.cache[key] = value
-	m.cacheMu.Unlock()
-
 	// Skip HA sync for local-only variables, but still notify subscribers
 	if variable.LocalOnly {
 		m.notifySubscribers(key, oldValue, value)
@@ -561,19 +668,15 @@ func (m *Manager) SetJSON(key string, value interface{}) error {
 	jsonBytes, err := json.Marshal(value)
 	if err != nil {
 		// Rollback cache on error
-		m.cacheMu.Lock()
-		m.cache[key] = oldValue
-		m.cacheMu.Unlock()
+		m.cache.store(key, oldValue)
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
 	// Sync to HA
 	entityName := extractEntityName(variable.EntityID)
-	if err := m.client.SetInputText(entityName, string(jsonBytes)); err != nil {
+	if err := m.client.SetInputText(context.Background(), entityName, string(jsonBytes)); err != nil {
 		// Rollback cache on error
-		m.cacheMu.Lock()
-		m.cache[key] = oldValue
-		m.cacheMu.Unlock()
+		m.cache.store(key, oldValue)
 		return fmt.Errorf("failed to set HA value: %w", err)
 	}
 
@@ -582,6 +685,10 @@ func (m *Manager) SetJSON(key string, value interface{}) error {
 
 // CompareAndSwapBool atomically compares and swaps a boolean value
 func (m *Manager) CompareAndSwapBool(key string, old, new bool) (bool, error) {
+	if m.parent != nil {
+		return m.parent.CompareAndSwapBool(m.qualify(key), old, new)
+	}
+
 	variable, ok := m.variables[key]
 	if !ok {
 		return false, fmt.Errorf("variable %s not found", key)
@@ -594,37 +701,32 @@ func (m *Manager) CompareAndSwapBool(key string, old, new bool) (bool, error) {
 		return false, err
 	}
 
-	m.cacheMu.Lock()
-
-	currentValue, ok := m.cache[key]
-	if !ok {
-		currentValue = variable.Default
-	}
-
-	currentBool, ok := currentValue.(bool)
-	if !ok {
-		m.cacheMu.Unlock()
-		return false, fmt.Errorf("cached value for %s is not a boolean", key)
+	// Compare and update under the variable's lock, released before calling
+	// the HA client to avoid deadlock
+	var typeErr error
+	_, swapped := m.cache.update(key, func(current interface{}, ok bool) (interface{}, bool) {
+		if !ok {
+			current = variable.Default
+		}
+		currentBool, isBool := current.(bool)
+		if !isBool {
+			typeErr = fmt.Errorf("cached value for %s is not a boolean", key)
+			return nil, false
+		}
+		return new, currentBool == old
+	})
+	if typeErr != nil {
+		return false, typeErr
 	}
-
-	if currentBool != old {
-		m.cacheMu.Unlock()
+	if !swapped {
 		return false, nil
 	}
 
-	// Update cache (still holding lock)
-	m.cache[key] = new
-
-	// Release lock before calling HA client to avoid deadlock
-	m.cacheMu.Unlock()
-
 	// Sync to HA
 	entityName := extractEntityName(variable.EntityID)
-	if err := m.client.SetInputBoolean(entityName, new); err != nil {
+	if err := m.client.SetInputBoolean(context.Background(), entityName, new); err != nil {
 		// Rollback on error
-		m.cacheMu.Lock()
-		m.cache[key] = old
-		m.cacheMu.Unlock()
+		m.cache.store(key, old)
 		return false, fmt.Errorf("failed to set HA value: %w", err)
 	}
 
@@ -633,6 +735,13 @@ func (m *Manager) CompareAndSwapBool(key string, old, new bool) (bool, error) {
 
 // Subscribe subscribes to state changes for a variable
 func (m *Manager) Subscribe(key string, handler StateChangeHandler) (Subscription, error) {
+	if m.parent != nil {
+		// Handlers see the key they subscribed with, not the qualified key
+		return m.parent.Subscribe(m.qualify(key), func(_ string, oldValue, newValue interface{}) {
+			handler(key, oldValue, newValue)
+		})
+	}
+
 	if _, ok := m.variables[key]; !ok {
 		return nil, fmt.Errorf("variable %s not found", key)
 	}
@@ -659,6 +768,59 @@ func (m *Manager) Subscribe(key string, handler StateChangeHandler) (Subscriptio
 	}, nil
 }
 
+// eventKind is the kind of event a variable of this type publishes, "" for none
+func (t StateType) eventKind() events.Kind {
+	switch t {
+	case TypeBool:
+		return events.KindBool
+	case TypeString:
+		return events.KindString
+	case TypeNumber:
+		return events.KindNumber
+	default:
+		return ""
+	}
+}
+
+// Watch implements events.Source: it checks that the variables matching
+// pattern are of kind and keeps their Home Assistant entities subscribed until
+// release is called
+func (m *Manager) Watch(pattern string, kind events.Kind) (func(), error) {
+	if m.parent != nil {
+		return m.parent.Watch(pattern, kind)
+	}
+
+	var keys []string
+	for key, variable := range m.variables {
+		if events.Match(pattern, key) && variable.Type.eventKind() == kind {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		if variable, ok := m.variables[pattern]; ok {
+			return nil, fmt.Errorf("variable %s is %s, not %s", pattern, variable.Type, kind)
+		}
+		return nil, fmt.Errorf("no %s variables match %q", kind, pattern)
+	}
+	sort.Strings(keys)
+
+	subs := make([]Subscription, 0, len(keys))
+	release := func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}
+	for _, key := range keys {
+		sub, err := m.Subscribe(key, func(string, interface{}, interface{}) {})
+		if err != nil {
+			release()
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return release, nil
+}
+
 // unsubscribe removes a specific subscription
 func (m *Manager) unsubscribe(key string, id uint64) {
 	m.subsMu.Lock()
@@ -714,16 +876,66 @@ func (m *Manager) teardownHASubscription(key string) {
 	}
 }
 
+// lookup returns the cached value of a variable, reading it through from
+// Home Assistant on a cache miss if read-through is enabled
+func (m *Manager) lookup(variable StateVariable) (interface{}, bool) {
+	value, ok := m.cache.load(variable.Key)
+
+	if ok || m.readThroughTimeout <= 0 || variable.LocalOnly || variable.EntityID == "" {
+		return value, ok
+	}
+	return m.readThrough(variable)
+}
+
+// readThrough fetches a variable from Home Assistant and caches it
+func (m *Manager) readThrough(variable StateVariable) (interface{}, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.readThroughTimeout)
+	defer cancel()
+
+	state, err := m.client.GetState(ctx, variable.EntityID)
+	if err != nil {
+		m.logger.Warn("Read-through from HA failed, using default",
+			zap.String("entity_id", variable.EntityID),
+			zap.String("key", variable.Key),
+			zap.Error(err))
+		return nil, false
+	}
+
+	value, err := m.parseStateValue(state.State, variable.Type)
+	if err != nil {
+		m.logger.Warn("Failed to parse read-through value, using default",
+			zap.String("entity_id", variable.EntityID),
+			zap.String("key", variable.Key),
+			zap.Error(err))
+		return nil, false
+	}
+
+	// A sync or write may have filled the cache while HA was being queried
+	if cached, loaded := m.cache.loadOrStore(variable.Key, value); loaded {
+		return cached, true
+	}
+
+	m.logger.Debug("Read through cold cache from HA",
+		zap.String("key", variable.Key),
+		zap.Any("value", value))
+
+	// Keep the cached value current until SyncFromHA takes over
+	if err := m.ensureHASubscription(variable); err != nil {
+		m.logger.Warn("Failed to subscribe to entity",
+			zap.String("entity_id", variable.EntityID),
+			zap.Error(err))
+	}
+
+	return value, true
+}
+
 // GetAllValues returns all cached values
 func (m *Manager) GetAllValues() map[string]interface{} {
-	m.cacheMu.RLock()
-	defer m.cacheMu.RUnlock()
-
-	values := make(map[string]interface{})
-	for k, v := range m.cache {
-		values[k] = v
+	if m.parent != nil {
+		return m.scopedValues()
 	}
-	return values
+
+	return m.cache.snapshot()
 }
 
 // extractEntityName extracts the entity name from full entity ID
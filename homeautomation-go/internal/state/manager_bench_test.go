@@ -0,0 +1,180 @@
+package state
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// newBenchmarkManager returns a manager synced from a mock with every
+// HA-backed variable present, like a running system after startup
+func newBenchmarkManager(b *testing.B) (*Manager, *ha.MockClient, []StateVariable) {
+	b.Helper()
+	mockClient := ha.NewMockClient()
+	var synced []StateVariable
+	for _, variable := range AllVariables {
+		if variable.LocalOnly {
+			continue
+		}
+		mockClient.SetState(variable.EntityID, benchmarkState(variable, 0), nil)
+		synced = append(synced, variable)
+	}
+	if err := mockClient.Connect(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	manager := NewManager(mockClient, zap.NewNop(), false)
+	if err := manager.SyncFromHA(); err != nil {
+		b.Fatal(err)
+	}
+	return manager, mockClient, synced
+}
+
+// benchmarkState returns an HA state string for a variable that differs for
+// each round, so every resync is a real change
+func benchmarkState(variable StateVariable, round int) string {
+	switch variable.Type {
+	case TypeBool:
+		if round%2 == 0 {
+			return "off"
+		}
+		return "on"
+	case TypeNumber:
+		return strconv.Itoa(round)
+	case TypeJSON:
+		return `{"round":` + strconv.Itoa(round) + `}`
+	default:
+		return "value" + strconv.Itoa(round)
+	}
+}
+
+// readVariable reads one variable with the typed getter plugins use
+func readVariable(manager *Manager, variable StateVariable) {
+	switch variable.Type {
+	case TypeBool:
+		_, _ = manager.GetBool(variable.Key)
+	case TypeNumber:
+		_, _ = manager.GetNumber(variable.Key)
+	case TypeString:
+		_, _ = manager.GetString(variable.Key)
+	}
+}
+
+// resync replays a state change for every synced variable, as Home
+// Assistant does after a reconnect
+func resync(mockClient *ha.MockClient, synced []StateVariable, round int) {
+	for _, variable := range synced {
+		mockClient.SimulateStateChange(variable.EntityID, benchmarkState(variable, round))
+	}
+}
+
+// BenchmarkManager_ParallelReads measures uncontended reads from many goroutines
+func BenchmarkManager_ParallelReads(b *testing.B) {
+	manager, _, synced := newBenchmarkManager(b)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			readVariable(manager, synced[i%len(synced)])
+			i++
+		}
+	})
+}
+
+// BenchmarkManager_ReadsDuringResync measures reads while a resync burst
+// rewrites every variable in the background
+func BenchmarkManager_ReadsDuringResync(b *testing.B) {
+	manager, mockClient, synced := newBenchmarkManager(b)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for round := 1; !stop.Load(); round++ {
+			resync(mockClient, synced, round)
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			readVariable(manager, synced[i%len(synced)])
+			i++
+		}
+	})
+	b.StopTimer()
+
+	stop.Store(true)
+	wg.Wait()
+}
+
+// BenchmarkManager_ResyncDuringReads measures how long a resync burst takes
+// while readers hammer the cache, including subscriber callbacks
+func BenchmarkManager_ResyncDuringReads(b *testing.B) {
+	manager, mockClient, synced := newBenchmarkManager(b)
+	for _, variable := range synced {
+		sub, err := manager.Subscribe(variable.Key, func(key string, _, _ interface{}) {
+			_, _ = manager.GetBool("isAnyoneHome")
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer sub.Unsubscribe()
+	}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := offset; !stop.Load(); i++ {
+				readVariable(manager, synced[i%len(synced)])
+			}
+		}(r)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resync(mockClient, synced, i+1)
+	}
+	b.StopTimer()
+
+	stop.Store(true)
+	wg.Wait()
+}
+
+// BenchmarkManager_ParallelLocalWrites measures writes to different
+// local-only variables from many goroutines
+func BenchmarkManager_ParallelLocalWrites(b *testing.B) {
+	manager, _, _ := newBenchmarkManager(b)
+	var local []string
+	for _, variable := range AllVariables {
+		if variable.LocalOnly && variable.Type == TypeBool {
+			local = append(local, variable.Key)
+		}
+	}
+	if len(local) == 0 {
+		b.Skip("no local-only boolean variables")
+	}
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		key := local[int(next.Add(1))%len(local)]
+		value := false
+		for pb.Next() {
+			value = !value
+			_ = manager.SetBool(key, value)
+		}
+	})
+}
@@ -1,12 +1,16 @@
 package state
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"homeautomation/internal/events"
 	"homeautomation/internal/ha"
+	"homeautomation/internal/metrics"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,7 +36,7 @@ func TestManager_SyncFromHA(t *testing.T) {
 	mockClient.SetState("input_number.alarm_time", "1668524400000", map[string]interface{}{})
 	mockClient.SetState("input_text.day_phase", "morning", map[string]interface{}{})
 
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	err := manager.SyncFromHA()
@@ -58,11 +62,48 @@ func TestManager_SyncFromHA(t *testing.T) {
 	assert.Equal(t, "morning", strValue)
 }
 
+func TestManager_SyncFromHA_BackfillsMissedChanges(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("input_boolean.nick_home", "on", map[string]interface{}{})
+	mockClient.SetState("input_text.day_phase", "morning", map[string]interface{}{})
+	mockClient.Connect(context.Background())
+
+	manager := NewManager(mockClient, logger, false)
+	require.NoError(t, manager.SyncFromHA())
+	require.NoError(t, manager.SetBool("lockdownActive", true))
+
+	var changes []string
+	_, err := manager.Subscribe("isNickHome", func(key string, oldValue, newValue interface{}) {
+		changes = append(changes, key)
+	})
+	require.NoError(t, err)
+	_, err = manager.Subscribe("dayPhase", func(key string, oldValue, newValue interface{}) {
+		changes = append(changes, key)
+	})
+	require.NoError(t, err)
+
+	// Changed while disconnected: no state_changed event reaches the manager
+	mockClient.SetMockState("input_boolean.nick_home", &ha.State{EntityID: "input_boolean.nick_home", State: "off"})
+
+	require.NoError(t, manager.SyncFromHA())
+
+	home, err := manager.GetBool("isNickHome")
+	require.NoError(t, err)
+	assert.False(t, home)
+	assert.Equal(t, []string{"isNickHome"}, changes, "only the changed variable is notified")
+
+	// Local-only variables survive the re-sync
+	lockdown, err := manager.GetBool("lockdownActive")
+	require.NoError(t, err)
+	assert.True(t, lockdown)
+}
+
 func TestManager_GetBool(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.nick_home", "on", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -97,7 +138,7 @@ func TestManager_SetBool(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.expecting_someone", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -147,7 +188,7 @@ func TestManager_GetSetString(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_text.day_phase", "morning", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -178,7 +219,7 @@ func TestManager_GetSetNumber(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_number.alarm_time", "1668524400000", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -211,7 +252,7 @@ func TestManager_ChangeDetection(t *testing.T) {
 	mockClient.SetState("input_boolean.nick_home", "off", map[string]interface{}{})
 	mockClient.SetState("input_text.day_phase", "morning", map[string]interface{}{})
 	mockClient.SetState("input_number.alarm_time", "100", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -300,7 +341,7 @@ func TestManager_CompareAndSwapBool(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.fade_out_in_progress", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -337,7 +378,7 @@ func TestManager_Subscribe(t *testing.T) {
 	mockClient.SetState("input_boolean.nick_home", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.caroline_home", "off", map[string]interface{}{})
 	mockClient.SetState("input_boolean.tori_here", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -456,7 +497,7 @@ func TestManager_GetAllValues(t *testing.T) {
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.nick_home", "on", map[string]interface{}{})
 	mockClient.SetState("input_text.day_phase", "morning", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -505,7 +546,7 @@ func TestManager_GetJSON(t *testing.T) {
 		"title":  "Test Song",
 		"album":  "Test Album",
 	}
-	manager.cache["currentlyPlayingMusic"] = testData
+	manager.cache.store("currentlyPlayingMusic", testData)
 
 	var cached map[string]interface{}
 	err = manager.GetJSON("currentlyPlayingMusic", &cached)
@@ -525,10 +566,77 @@ func TestManager_GetJSON(t *testing.T) {
 	assert.Contains(t, err.Error(), "not JSON")
 }
 
+func TestManager_ReadThrough(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("input_boolean.nick_home", "on", map[string]interface{}{})
+	mockClient.SetState("input_text.day_phase", "morning", map[string]interface{}{})
+	mockClient.SetState("input_number.alarm_time", "1668524400000", map[string]interface{}{})
+	mockClient.SetState("input_text.load_shedding_plan", `{"tier":"normal"}`, map[string]interface{}{})
+
+	manager := NewManager(mockClient, zap.NewNop(), false)
+	manager.SetReadThrough(time.Second)
+
+	// Cache is cold: values come from HA rather than defaults
+	isHome, err := manager.GetBool("isNickHome")
+	require.NoError(t, err)
+	assert.True(t, isHome)
+
+	phase, err := manager.GetString("dayPhase")
+	require.NoError(t, err)
+	assert.Equal(t, "morning", phase)
+
+	alarm, err := manager.GetNumber("alarmTime")
+	require.NoError(t, err)
+	assert.Equal(t, 1668524400000.0, alarm)
+
+	var plan map[string]interface{}
+	require.NoError(t, manager.GetJSON("loadSheddingPlan", &plan))
+	assert.Equal(t, "normal", plan["tier"])
+
+	// Subsequent reads are served from the cache
+	_, err = manager.GetBool("isNickHome")
+	require.NoError(t, err)
+	assert.Equal(t, 1, mockClient.GetStateCallCount("input_boolean.nick_home"))
+
+	// The read-through value keeps tracking HA
+	mockClient.SimulateStateChange("input_boolean.nick_home", "off")
+	isHome, err = manager.GetBool("isNickHome")
+	require.NoError(t, err)
+	assert.False(t, isHome)
+}
+
+func TestManager_ReadThroughFallsBackToDefault(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	manager := NewManager(mockClient, zap.NewNop(), false)
+	manager.SetReadThrough(time.Second)
+
+	// Entity missing from HA
+	gridAvailable, err := manager.GetBool("isGridAvailable")
+	require.NoError(t, err)
+	assert.True(t, gridAvailable, "Should fall back to the variable's default")
+
+	// Local-only variables never query HA
+	_, err = manager.GetBool("didOwnerJustReturnHome")
+	require.NoError(t, err)
+	assert.False(t, mockClient.WasGetStateCalled(""))
+}
+
+func TestManager_ReadThroughDisabledByDefault(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("input_boolean.nick_home", "on", map[string]interface{}{})
+
+	manager := NewManager(mockClient, zap.NewNop(), false)
+
+	isHome, err := manager.GetBool("isNickHome")
+	require.NoError(t, err)
+	assert.False(t, isHome)
+	assert.False(t, mockClient.WasGetStateCalled("input_boolean.nick_home"))
+}
+
 func TestManager_SetJSON(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 
@@ -561,7 +669,7 @@ func TestManager_ConcurrentAccess(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.nick_home", "off", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	manager := NewManager(mockClient, logger, false)
 	manager.SyncFromHA()
@@ -606,7 +714,7 @@ func TestManager_ReadOnlyMode(t *testing.T) {
 	mockClient := ha.NewMockClient()
 	mockClient.SetState("input_boolean.expecting_someone", "off", map[string]interface{}{})
 	mockClient.SetState("input_text.battery_energy_level", "green", map[string]interface{}{})
-	mockClient.Connect()
+	mockClient.Connect(context.Background())
 
 	// Create manager in read-only mode
 	manager := NewManager(mockClient, logger, true)
@@ -703,3 +811,58 @@ func TestManager_ComputedOutputFlagVerification(t *testing.T) {
 	nickHomeVar := vars["isNickHome"]
 	assert.False(t, nickHomeVar.ComputedOutput, "isNickHome should not have ComputedOutput flag")
 }
+
+func TestManager_SetMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	manager := NewManager(ha.NewMockClient(), zap.NewNop(), false)
+	manager.SetMetrics(registry)
+
+	_, err := manager.Subscribe("isExpectingSomeone", func(key string, oldValue, newValue interface{}) {})
+	require.NoError(t, err)
+	require.NoError(t, manager.SetBool("isExpectingSomeone", true))
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+	assert.Contains(t, b.String(), `homeautomation_subscription_callback_duration_seconds_count{source="state"} 1`)
+}
+
+func TestManager_EventsCarryTypedValues(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	manager := NewManager(mockClient, zap.NewNop(), false)
+
+	var changes []events.BoolChanged
+	sub, err := manager.Events().OnBool("isNickHome", func(e events.BoolChanged) { changes = append(changes, e) })
+	require.NoError(t, err)
+
+	mockClient.SetState("input_boolean.nick_home", "on", nil)
+	mockClient.SetState("input_boolean.nick_home", "off", nil)
+	assert.Equal(t, []events.BoolChanged{
+		{Key: "isNickHome", New: true, Initial: true},
+		{Key: "isNickHome", Old: true, New: false},
+	}, changes)
+
+	sub.Unsubscribe()
+	manager.haSubsMu.Lock()
+	_, subscribed := manager.haSubs["input_boolean.nick_home"]
+	manager.haSubsMu.Unlock()
+	assert.False(t, subscribed, "the HA subscription goes with the last event subscription")
+}
+
+func TestManager_EventsWatchChecksKind(t *testing.T) {
+	mockClient := ha.NewMockClient()
+	manager := NewManager(mockClient, zap.NewNop(), false)
+
+	_, err := manager.Events().OnString("isNickHome", func(events.StringChanged) {})
+	assert.ErrorContains(t, err, "variable isNickHome is bool, not string")
+
+	_, err = manager.Events().OnBool("noSuchVariable", func(events.BoolChanged) {})
+	assert.ErrorContains(t, err, `no bool variables match "noSuchVariable"`)
+
+	var keys []string
+	_, err = manager.Events().OnBool("is*Home", func(e events.BoolChanged) { keys = append(keys, e.Key) })
+	require.NoError(t, err)
+	mockClient.SetState("input_text.day_phase", "night", nil)
+	mockClient.SetState("input_boolean.caroline_home", "on", nil)
+	mockClient.SetState("input_boolean.tori_here", "on", nil)
+	assert.Equal(t, []string{"isCarolineHome"}, keys)
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// namespacePattern matches valid namespace names, which become part of HA entity IDs
+var namespacePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidNamespace reports whether name can be used as a state namespace
+func ValidNamespace(name string) bool {
+	return namespacePattern.MatchString(name)
+}
+
+// QualifiedKey returns the key of a variable within a namespace,
+// e.g. QualifiedKey("suite", "isAnyoneHome") is "suite.isAnyoneHome"
+func QualifiedKey(namespace, key string) string {
+	return namespace + "." + key
+}
+
+// NamespacedVariable returns the namespace's copy of a variable. Its entity
+// is the global entity with the namespace prepended to the object ID, e.g.
+// input_boolean.anyone_home becomes input_boolean.suite_anyone_home.
+func NamespacedVariable(namespace string, variable StateVariable) StateVariable {
+	variable.Key = QualifiedKey(namespace, variable.Key)
+	if variable.EntityID != "" {
+		domain, object, _ := strings.Cut(variable.EntityID, ".")
+		variable.EntityID = domain + "." + namespace + "_" + object
+	}
+	return variable
+}
+
+// RegisterNamespace adds namespaced copies of the given variables and returns
+// a Manager scoped to the namespace. The scoped Manager reads and writes
+// "<namespace>.<key>" for the listed keys and the global variable for every
+// other key, so plugins run against it unchanged while shared state (energy,
+// day phase) stays global.
+//
+// Must be called on the root Manager before SyncFromHA.
+func (m *Manager) RegisterNamespace(namespace string, keys []string) (*Manager, error) {
+	if m.parent != nil {
+		return nil, fmt.Errorf("namespace %s: namespaces cannot be nested", namespace)
+	}
+	if !ValidNamespace(namespace) {
+		return nil, fmt.Errorf("invalid namespace %q: must be lowercase letters, digits and underscores", namespace)
+	}
+
+	// Validate every key before registering any, so a bad config leaves no partial namespace
+	variables := make([]StateVariable, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		variable, ok := m.variables[key]
+		if !ok || strings.Contains(key, ".") {
+			return nil, fmt.Errorf("namespace %s: variable %s not found", namespace, key)
+		}
+		namespaced := NamespacedVariable(namespace, variable)
+		if _, exists := m.variables[namespaced.Key]; exists || seen[key] {
+			return nil, fmt.Errorf("namespace %s: variable %s registered twice", namespace, key)
+		}
+		variables = append(variables, namespaced)
+		seen[key] = true
+	}
+
+	scoped := make(map[string]bool, len(keys))
+	for i, namespaced := range variables {
+		m.variables[namespaced.Key] = namespaced
+		if namespaced.EntityID != "" {
+			m.entityToKey[namespaced.EntityID] = namespaced.Key
+		}
+		m.namespaced = append(m.namespaced, namespaced)
+		scoped[keys[i]] = true
+	}
+
+	return &Manager{
+		client:    m.client,
+		logger:    m.logger.With(zap.String("namespace", namespace)),
+		parent:    m,
+		namespace: namespace,
+		scoped:    scoped,
+	}, nil
+}
+
+// Namespace returns the namespace a scoped Manager was created for, or "" for the root Manager
+func (m *Manager) Namespace() string {
+	return m.namespace
+}
+
+// qualify maps a key used with a scoped Manager to the root Manager's key
+func (m *Manager) qualify(key string) string {
+	if m.scoped[key] {
+		return QualifiedKey(m.namespace, key)
+	}
+	return key
+}
+
+// scopedValues returns the root Manager's values as seen from the namespace:
+// scoped keys show the namespace's values and other namespaces are left out
+func (m *Manager) scopedValues() map[string]interface{} {
+	all := m.parent.GetAllValues()
+	global := VariablesByKey()
+
+	values := make(map[string]interface{})
+	for key, value := range all {
+		if _, ok := global[key]; ok && !m.scoped[key] {
+			values[key] = value
+		}
+	}
+	for key := range m.scoped {
+		if value, ok := all[QualifiedKey(m.namespace, key)]; ok {
+			values[key] = value
+		}
+	}
+	return values
+}
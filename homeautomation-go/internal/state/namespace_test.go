@@ -0,0 +1,117 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNamespacedVariable(t *testing.T) {
+	variable := NamespacedVariable("suite", StateVariable{Key: "isAnyoneHome", EntityID: "input_boolean.anyone_home", Type: TypeBool})
+	assert.Equal(t, "suite.isAnyoneHome", variable.Key)
+	assert.Equal(t, "input_boolean.suite_anyone_home", variable.EntityID)
+
+	local := NamespacedVariable("suite", StateVariable{Key: "speakerGroupPreset", Type: TypeString, LocalOnly: true})
+	assert.Equal(t, "suite.speakerGroupPreset", local.Key)
+	assert.Empty(t, local.EntityID)
+}
+
+func TestManager_RegisterNamespace(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("input_boolean.anyone_home", "off", nil)
+	mockClient.SetState("input_boolean.suite_anyone_home", "on", nil)
+	mockClient.SetState("input_text.current_energy_level", "green", nil)
+	mockClient.Connect(context.Background())
+
+	manager := NewManager(mockClient, logger, false)
+	suite, err := manager.RegisterNamespace("suite", []string{"isAnyoneHome"})
+	require.NoError(t, err)
+	require.NoError(t, manager.SyncFromHA())
+
+	assert.Equal(t, "suite", suite.Namespace())
+
+	// Scoped keys read the namespace's entity
+	home, err := suite.GetBool("isAnyoneHome")
+	require.NoError(t, err)
+	assert.True(t, home)
+
+	home, err = manager.GetBool("isAnyoneHome")
+	require.NoError(t, err)
+	assert.False(t, home)
+
+	home, err = manager.GetBool("suite.isAnyoneHome")
+	require.NoError(t, err)
+	assert.True(t, home)
+
+	// Other keys stay global
+	level, err := suite.GetString("currentEnergyLevel")
+	require.NoError(t, err)
+	assert.Equal(t, "green", level)
+
+	values := suite.GetAllValues()
+	assert.Equal(t, true, values["isAnyoneHome"])
+	assert.Equal(t, "green", values["currentEnergyLevel"])
+	assert.NotContains(t, values, "suite.isAnyoneHome")
+}
+
+func TestManager_NamespaceWritesAndSubscriptions(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.Connect(context.Background())
+
+	manager := NewManager(mockClient, logger, false)
+	suite, err := manager.RegisterNamespace("suite", []string{"isAnyoneHome"})
+	require.NoError(t, err)
+	require.NoError(t, manager.SyncFromHA())
+
+	var gotKey string
+	var gotValue interface{}
+	sub, err := suite.Subscribe("isAnyoneHome", func(key string, oldValue, newValue interface{}) {
+		gotKey = key
+		gotValue = newValue
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	mockClient.SimulateStateChange("input_boolean.suite_anyone_home", "on")
+	assert.Equal(t, "isAnyoneHome", gotKey, "handlers see the unqualified key")
+	assert.Equal(t, true, gotValue)
+
+	// Writes go to the namespace's entity and leave the global variable alone
+	require.NoError(t, suite.SetBool("isAnyoneHome", false))
+	calls := mockClient.GetServiceCalls()
+	require.NotEmpty(t, calls)
+	assert.Equal(t, "input_boolean.suite_anyone_home", calls[len(calls)-1].Data["entity_id"])
+
+	global, err := manager.GetBool("isAnyoneHome")
+	require.NoError(t, err)
+	assert.False(t, global)
+}
+
+func TestManager_RegisterNamespaceErrors(t *testing.T) {
+	logger := zap.NewNop()
+	manager := NewManager(ha.NewMockClient(), logger, false)
+
+	_, err := manager.RegisterNamespace("Suite", []string{"isAnyoneHome"})
+	assert.Error(t, err, "namespace names must be lowercase")
+
+	_, err = manager.RegisterNamespace("suite", []string{"isNobodyHome"})
+	assert.Error(t, err, "unknown variable")
+
+	suite, err := manager.RegisterNamespace("suite", []string{"isAnyoneHome"})
+	require.NoError(t, err)
+
+	_, err = suite.RegisterNamespace("inner", []string{"isAnyoneHome"})
+	assert.Error(t, err, "namespaces cannot be nested")
+
+	_, err = manager.RegisterNamespace("suite", []string{"isAnyoneHome"})
+	assert.Error(t, err, "variable registered twice")
+
+	assert.Error(t, suite.SyncFromHA())
+}
@@ -21,9 +21,9 @@ type StateVariable struct {
 	ComputedOutput bool        // If true, can be written even in read-only mode (for computed values)
 }
 
-// AllVariables contains all 37 state variables (35 synced with HA + 2 local-only)
+// AllVariables contains all 51 state variables (44 synced with HA + 7 local-only)
 var AllVariables = []StateVariable{
-	// Booleans (25)
+	// Booleans (34)
 	{Key: "isNickHome", EntityID: "input_boolean.nick_home", Type: TypeBool, Default: false},
 	{Key: "isCarolineHome", EntityID: "input_boolean.caroline_home", Type: TypeBool, Default: false},
 	{Key: "isToriHere", EntityID: "input_boolean.tori_here", Type: TypeBool, Default: false},
@@ -49,6 +49,14 @@ var AllVariables = []StateVariable{
 	{Key: "isNickNearHome", EntityID: "input_boolean.nick_near_home", Type: TypeBool, Default: false},
 	{Key: "isCarolineNearHome", EntityID: "input_boolean.caroline_near_home", Type: TypeBool, Default: false},
 	{Key: "isLockdown", EntityID: "input_boolean.lockdown", Type: TypeBool, Default: false},
+	{Key: "isPrimaryBedroomDoNotDisturb", EntityID: "input_boolean.primary_bedroom_do_not_disturb", Type: TypeBool, Default: false},
+	{Key: "isGuestBedroomDoNotDisturb", EntityID: "input_boolean.guest_bedroom_do_not_disturb", Type: TypeBool, Default: false},
+	{Key: "isOfficeFocusMode", EntityID: "input_boolean.office_focus_mode", Type: TypeBool, Default: false},
+	{Key: "isKidMode", EntityID: "input_boolean.kid_mode", Type: TypeBool, Default: false},
+	{Key: "isGuestBedroomKidMode", EntityID: "input_boolean.guest_bedroom_kid_mode", Type: TypeBool, Default: false},
+	{Key: "isExpectingDelivery", EntityID: "input_boolean.expecting_delivery", Type: TypeBool, Default: false},
+	{Key: "isStormWatch", EntityID: "input_boolean.storm_watch", Type: TypeBool, Default: false},
+	{Key: "isEmergencyAcknowledged", EntityID: "input_boolean.emergency_acknowledged", Type: TypeBool, Default: false},
 	{Key: "reset", EntityID: "input_boolean.reset", Type: TypeBool, Default: false},
 
 	// Numbers (3)
@@ -65,9 +73,25 @@ var AllVariables = []StateVariable{
 	{Key: "currentEnergyLevel", EntityID: "input_text.current_energy_level", Type: TypeString, Default: "", ComputedOutput: true},
 	{Key: "solarProductionEnergyLevel", EntityID: "input_text.solar_production_energy_level", Type: TypeString, Default: "", ComputedOutput: true},
 
+	// JSON (2)
+	{Key: "loadSheddingPlan", EntityID: "input_text.load_shedding_plan", Type: TypeJSON, Default: map[string]interface{}{}},
+	{Key: "sleepHygieneTriggers", EntityID: "input_text.sleep_hygiene_triggers", Type: TypeJSON, Default: map[string]interface{}{}},
+
 	// Local-only variables (not synced with HA)
 	{Key: "didOwnerJustReturnHome", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
 	{Key: "currentlyPlayingMusic", EntityID: "", Type: TypeJSON, Default: map[string]interface{}{}, LocalOnly: true},
+	{Key: "lockdownActive", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
+	{Key: "speakerGroupPreset", EntityID: "", Type: TypeString, Default: "", LocalOnly: true},
+	{Key: "isOfficeFocusActive", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
+	{Key: "isKidModeActive", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
+	{Key: "lowBatteryDevices", EntityID: "", Type: TypeJSON, Default: []interface{}{}, LocalOnly: true}, // Too large for an input_text
+	{Key: "isHomeAssistantConnected", EntityID: "", Type: TypeBool, Default: true, LocalOnly: true},     // Starts true: startup fails without a connection
+	{Key: "isPeakPricing", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
+	{Key: "currentGridPrice", EntityID: "", Type: TypeNumber, Default: 0.0, LocalOnly: true},
+	{Key: "solarForecast", EntityID: "", Type: TypeJSON, Default: map[string]interface{}{}, LocalOnly: true}, // Hourly periods, too large for an input_text
+	{Key: "isPersonAtFrontDoor", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
+	{Key: "isPersonInDriveway", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
+	{Key: "isEmergencyActive", EntityID: "", Type: TypeBool, Default: false, LocalOnly: true},
 }
 
 // VariablesByKey creates a map of variables by their key
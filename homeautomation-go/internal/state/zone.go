@@ -0,0 +1,61 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ZoneKey returns the key of a variable's copy for one zone of the house,
+// e.g. ZoneKey("musicPlaybackType", "office") is "musicPlaybackType.office"
+func ZoneKey(key, zone string) string {
+	return key + "." + zone
+}
+
+// ZoneVariable returns a zone's copy of a variable. Its entity is the global
+// entity with the zone appended to the object ID, e.g.
+// input_text.music_playback_type becomes input_text.music_playback_type_office.
+func ZoneVariable(variable StateVariable, zone string) StateVariable {
+	variable.Key = ZoneKey(variable.Key, zone)
+	if variable.EntityID != "" {
+		variable.EntityID += "_" + zone
+	}
+	return variable
+}
+
+// RegisterZone adds a zone's copies of the given variables. Unlike a
+// namespace, a zone has no scoped Manager: its variables sit alongside the
+// global ones under their ZoneKey, for a plugin that drives several areas of
+// the house independently.
+//
+// Must be called on the root Manager before SyncFromHA.
+func (m *Manager) RegisterZone(zone string, keys []string) error {
+	if m.parent != nil {
+		return fmt.Errorf("zone %s: register zones on the root state manager", zone)
+	}
+	if !ValidNamespace(zone) {
+		return fmt.Errorf("invalid zone %q: must be lowercase letters, digits and underscores", zone)
+	}
+
+	// Validate every key before registering any, so a bad config leaves no partial zone
+	variables := make([]StateVariable, 0, len(keys))
+	for _, key := range keys {
+		variable, ok := m.variables[key]
+		if !ok || strings.Contains(key, ".") {
+			return fmt.Errorf("zone %s: variable %s not found", zone, key)
+		}
+		zoned := ZoneVariable(variable, zone)
+		if _, exists := m.variables[zoned.Key]; exists {
+			return fmt.Errorf("zone %s: variable %s registered twice", zone, key)
+		}
+		variables = append(variables, zoned)
+	}
+
+	for _, zoned := range variables {
+		m.variables[zoned.Key] = zoned
+		if zoned.EntityID != "" {
+			m.entityToKey[zoned.EntityID] = zoned.Key
+		}
+		m.namespaced = append(m.namespaced, zoned)
+	}
+	return nil
+}
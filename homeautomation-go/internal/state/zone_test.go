@@ -0,0 +1,78 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestZoneVariable(t *testing.T) {
+	variable := ZoneVariable(StateVariable{Key: "musicPlaybackType", EntityID: "input_text.music_playback_type", Type: TypeString}, "office")
+	assert.Equal(t, "musicPlaybackType.office", variable.Key)
+	assert.Equal(t, "input_text.music_playback_type_office", variable.EntityID)
+
+	local := ZoneVariable(StateVariable{Key: "speakerGroupPreset", Type: TypeString, LocalOnly: true}, "office")
+	assert.Equal(t, "speakerGroupPreset.office", local.Key)
+	assert.Empty(t, local.EntityID)
+}
+
+func TestManager_RegisterZone(t *testing.T) {
+	logger := zap.NewNop()
+	mockClient := ha.NewMockClient()
+	mockClient.SetState("input_text.music_playback_type", "day", nil)
+	mockClient.SetState("input_text.music_playback_type_office", "evening", nil)
+	mockClient.Connect(context.Background())
+
+	manager := NewManager(mockClient, logger, false)
+	require.NoError(t, manager.RegisterZone("office", []string{"musicPlaybackType"}))
+	require.NoError(t, manager.SyncFromHA())
+
+	zone, err := manager.GetString("musicPlaybackType.office")
+	require.NoError(t, err)
+	assert.Equal(t, "evening", zone)
+
+	global, err := manager.GetString("musicPlaybackType")
+	require.NoError(t, err)
+	assert.Equal(t, "day", global)
+
+	// Changes to the zone's entity reach the zone's key only
+	var gotKey string
+	sub, err := manager.Subscribe("musicPlaybackType.office", func(key string, oldValue, newValue interface{}) {
+		gotKey = key
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	mockClient.SimulateStateChange("input_text.music_playback_type_office", "focus")
+	assert.Equal(t, "musicPlaybackType.office", gotKey)
+
+	global, err = manager.GetString("musicPlaybackType")
+	require.NoError(t, err)
+	assert.Equal(t, "day", global)
+
+	// Writes go to the zone's entity
+	require.NoError(t, manager.SetString("musicPlaybackType.office", ""))
+	calls := mockClient.GetServiceCalls()
+	require.NotEmpty(t, calls)
+	assert.Equal(t, "input_text.music_playback_type_office", calls[len(calls)-1].Data["entity_id"])
+}
+
+func TestManager_RegisterZoneErrors(t *testing.T) {
+	logger := zap.NewNop()
+	manager := NewManager(ha.NewMockClient(), logger, false)
+
+	assert.Error(t, manager.RegisterZone("Office", []string{"musicPlaybackType"}), "zone names must be lowercase")
+	assert.Error(t, manager.RegisterZone("office", []string{"musicPlaybackKind"}), "unknown variable")
+
+	require.NoError(t, manager.RegisterZone("office", []string{"musicPlaybackType"}))
+	assert.Error(t, manager.RegisterZone("office", []string{"musicPlaybackType"}), "variable registered twice")
+
+	suite, err := manager.RegisterNamespace("suite", []string{"isAnyoneHome"})
+	require.NoError(t, err)
+	assert.Error(t, suite.RegisterZone("office", []string{"musicPlaybackType"}), "zones are registered on the root")
+}
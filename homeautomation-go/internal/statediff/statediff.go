@@ -0,0 +1,248 @@
+// Package statediff compares the state variables and plugin shadow outputs of
+// two running instances, so a new build can be checked against the current
+// one before it is allowed to write.
+package statediff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Difference kinds
+const (
+	KindChanged = "changed" // Present on both sides with different values
+	KindMissing = "missing" // Only in the base snapshot
+	KindAdded   = "added"   // Only in the candidate snapshot
+)
+
+// Snapshot is one instance's state variables and plugin shadow outputs
+type Snapshot struct {
+	State   map[string]interface{} // Variable key -> value
+	Outputs map[string]interface{} // Plugin name -> shadow state outputs
+}
+
+// Difference is one value that differs between the snapshots
+type Difference struct {
+	Path      string      `json:"path"` // e.g. "dayPhase" or "lighting.rooms[0].activeScene"
+	Kind      string      `json:"kind"`
+	Base      interface{} `json:"base,omitempty"`
+	Candidate interface{} `json:"candidate,omitempty"`
+}
+
+// Diff is the result of comparing two snapshots
+type Diff struct {
+	Identical bool         `json:"identical"`
+	State     []Difference `json:"state"`  // State variables
+	Shadow    []Difference `json:"shadow"` // Plugin shadow outputs
+}
+
+// Options control what counts as a difference
+type Options struct {
+	// IgnoreTimestamps treats any two RFC3339 timestamps as equal, since two
+	// instances acting on the same event record it a few milliseconds apart
+	IgnoreTimestamps bool
+}
+
+// Parse builds a snapshot from the /api/state and /api/shadow documents
+func Parse(stateDoc, shadowDoc []byte) (*Snapshot, error) {
+	// /api/state groups variables by type: booleans, numbers, strings, jsons
+	var groups map[string]map[string]interface{}
+	if err := json.Unmarshal(stateDoc, &groups); err != nil {
+		return nil, fmt.Errorf("invalid state document: %w", err)
+	}
+
+	var shadow struct {
+		Plugins map[string]struct {
+			Outputs interface{} `json:"outputs"`
+		} `json:"plugins"`
+	}
+	if err := json.Unmarshal(shadowDoc, &shadow); err != nil {
+		return nil, fmt.Errorf("invalid shadow document: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		State:   make(map[string]interface{}),
+		Outputs: make(map[string]interface{}),
+	}
+	for _, variables := range groups {
+		for key, value := range variables {
+			snapshot.State[key] = value
+		}
+	}
+	for name, plugin := range shadow.Plugins {
+		snapshot.Outputs[name] = stripLocalTimestamps(plugin.Outputs)
+	}
+	return snapshot, nil
+}
+
+// Fetch reads a snapshot from the /api/state and /api/shadow endpoints of the
+// instance at baseURL, e.g. "http://blue:8080"
+func Fetch(ctx context.Context, client *http.Client, baseURL string) (*Snapshot, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	stateDoc, err := get(ctx, client, baseURL+"/api/state")
+	if err != nil {
+		return nil, err
+	}
+	shadowDoc, err := get(ctx, client, baseURL+"/api/shadow")
+	if err != nil {
+		return nil, err
+	}
+	return Parse(stateDoc, shadowDoc)
+}
+
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// Compare lists every state variable and shadow output that differs between
+// base and candidate, sorted by path
+func Compare(base, candidate *Snapshot, opts Options) *Diff {
+	c := comparer{opts: opts}
+
+	diff := &Diff{
+		State:  append([]Difference{}, c.compareMaps("", base.State, candidate.State)...),
+		Shadow: append([]Difference{}, c.compareMaps("", base.Outputs, candidate.Outputs)...),
+	}
+	diff.Identical = len(diff.State) == 0 && len(diff.Shadow) == 0
+	return diff
+}
+
+type comparer struct {
+	opts Options
+}
+
+func (c comparer) compare(path string, base, candidate interface{}) []Difference {
+	if baseMap, ok := base.(map[string]interface{}); ok {
+		if candidateMap, ok := candidate.(map[string]interface{}); ok {
+			return c.compareMaps(path, baseMap, candidateMap)
+		}
+	}
+	if baseList, ok := base.([]interface{}); ok {
+		if candidateList, ok := candidate.([]interface{}); ok {
+			return c.compareLists(path, baseList, candidateList)
+		}
+	}
+	if c.equal(base, candidate) {
+		return nil
+	}
+	return []Difference{{Path: path, Kind: KindChanged, Base: base, Candidate: candidate}}
+}
+
+func (c comparer) compareMaps(path string, base, candidate map[string]interface{}) []Difference {
+	keys := make(map[string]bool)
+	for key := range base {
+		keys[key] = true
+	}
+	for key := range candidate {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var diffs []Difference
+	for _, key := range sorted {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+		baseValue, inBase := base[key]
+		candidateValue, inCandidate := candidate[key]
+		switch {
+		case !inCandidate:
+			diffs = append(diffs, Difference{Path: childPath, Kind: KindMissing, Base: baseValue})
+		case !inBase:
+			diffs = append(diffs, Difference{Path: childPath, Kind: KindAdded, Candidate: candidateValue})
+		default:
+			diffs = append(diffs, c.compare(childPath, baseValue, candidateValue)...)
+		}
+	}
+	return diffs
+}
+
+func (c comparer) compareLists(path string, base, candidate []interface{}) []Difference {
+	var diffs []Difference
+	for i := 0; i < len(base) || i < len(candidate); i++ {
+		childPath := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i >= len(candidate):
+			diffs = append(diffs, Difference{Path: childPath, Kind: KindMissing, Base: base[i]})
+		case i >= len(base):
+			diffs = append(diffs, Difference{Path: childPath, Kind: KindAdded, Candidate: candidate[i]})
+		default:
+			diffs = append(diffs, c.compare(childPath, base[i], candidate[i])...)
+		}
+	}
+	return diffs
+}
+
+func (c comparer) equal(base, candidate interface{}) bool {
+	if reflect.DeepEqual(base, candidate) {
+		return true
+	}
+	if c.opts.IgnoreTimestamps {
+		return isTimestamp(base) && isTimestamp(candidate)
+	}
+	return false
+}
+
+func isTimestamp(value interface{}) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+// stripLocalTimestamps drops the "<field>Local" display copies the API adds
+// next to each timestamp; they follow the server's time zone, not its behaviour
+func stripLocalTimestamps(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			if original, ok := strings.CutSuffix(key, "Local"); ok && isTimestamp(v[original]) {
+				continue
+			}
+			result[key] = stripLocalTimestamps(val)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, val := range v {
+			result[i] = stripLocalTimestamps(val)
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,131 @@
+package statediff
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testState = `{
+  "booleans": {"isAnyoneHome": true},
+  "numbers": {"alarmTime": 1718000000000},
+  "strings": {"dayPhase": "evening"},
+  "jsons": {"lowBatteryDevices": []}
+}`
+
+const testShadow = `{
+  "plugins": {
+    "lighting": {
+      "plugin": "lighting",
+      "inputs": {"current": {"dayPhase": "evening"}},
+      "outputs": {
+        "rooms": [{"name": "Kitchen", "activeScene": "evening"}],
+        "lastActionTime": "2025-06-16T19:00:00.001Z",
+        "lastActionTimeLocal": "Jun 16, 2025 2:00:00 PM CDT"
+      },
+      "metadata": {"lastUpdated": "2025-06-16T19:00:00Z"}
+    }
+  },
+  "metadata": {"timestamp": "2025-06-16T19:05:00Z"}
+}`
+
+func mustParse(t *testing.T, stateDoc, shadowDoc string) *Snapshot {
+	t.Helper()
+	snapshot, err := Parse([]byte(stateDoc), []byte(shadowDoc))
+	require.NoError(t, err)
+	return snapshot
+}
+
+func TestParse(t *testing.T) {
+	snapshot := mustParse(t, testState, testShadow)
+
+	assert.Equal(t, true, snapshot.State["isAnyoneHome"])
+	assert.Equal(t, "evening", snapshot.State["dayPhase"])
+	assert.Equal(t, float64(1718000000000), snapshot.State["alarmTime"])
+
+	outputs, ok := snapshot.Outputs["lighting"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, outputs, "lastActionTime")
+	assert.NotContains(t, outputs, "lastActionTimeLocal", "display copies of timestamps should be dropped")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("not json"), []byte(testShadow))
+	assert.Error(t, err)
+	_, err = Parse([]byte(testState), []byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestCompare_Identical(t *testing.T) {
+	diff := Compare(mustParse(t, testState, testShadow), mustParse(t, testState, testShadow), Options{})
+
+	assert.True(t, diff.Identical)
+	assert.Empty(t, diff.State)
+	assert.Empty(t, diff.Shadow)
+}
+
+func TestCompare_Differences(t *testing.T) {
+	base := mustParse(t, testState, testShadow)
+	candidate := mustParse(t, testState, testShadow)
+	candidate.State["dayPhase"] = "night"
+	delete(candidate.State, "isAnyoneHome")
+	candidate.State["isHaveGuests"] = false
+	candidate.Outputs["lighting"].(map[string]interface{})["rooms"] = []interface{}{
+		map[string]interface{}{"name": "Kitchen", "activeScene": "night"},
+		map[string]interface{}{"name": "Office", "activeScene": "night"},
+	}
+
+	diff := Compare(base, candidate, Options{})
+
+	assert.False(t, diff.Identical)
+	assert.Equal(t, []Difference{
+		{Path: "dayPhase", Kind: KindChanged, Base: "evening", Candidate: "night"},
+		{Path: "isAnyoneHome", Kind: KindMissing, Base: true},
+		{Path: "isHaveGuests", Kind: KindAdded, Candidate: false},
+	}, diff.State)
+	require.Len(t, diff.Shadow, 2)
+	assert.Equal(t, Difference{Path: "lighting.rooms[0].activeScene", Kind: KindChanged, Base: "evening", Candidate: "night"}, diff.Shadow[0])
+	assert.Equal(t, "lighting.rooms[1]", diff.Shadow[1].Path)
+	assert.Equal(t, KindAdded, diff.Shadow[1].Kind)
+}
+
+func TestCompare_IgnoreTimestamps(t *testing.T) {
+	base := mustParse(t, testState, testShadow)
+	candidate := mustParse(t, testState, testShadow)
+	candidate.Outputs["lighting"].(map[string]interface{})["lastActionTime"] = "2025-06-16T19:00:00.004Z"
+
+	assert.Len(t, Compare(base, candidate, Options{}).Shadow, 1)
+	assert.True(t, Compare(base, candidate, Options{IgnoreTimestamps: true}).Identical)
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/state":
+			_, _ = w.Write([]byte(testState))
+		case "/api/shadow":
+			_, _ = w.Write([]byte(testShadow))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	snapshot, err := Fetch(context.Background(), server.Client(), server.URL+"/")
+	require.NoError(t, err)
+	assert.Equal(t, "evening", snapshot.State["dayPhase"])
+	assert.Contains(t, snapshot.Outputs, "lighting")
+}
+
+func TestFetch_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Fetch(context.Background(), server.Client(), server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
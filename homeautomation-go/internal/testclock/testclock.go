@@ -0,0 +1,335 @@
+// Package testclock provides a virtual clock for tests of time-based code.
+//
+// Time stands still until the test moves it. Sleeps, timers and tickers fire
+// in deadline order with Now set to their deadline, so a fade that takes
+// minutes in production runs instantly and produces the same steps on every
+// run. Inject it wherever a clock.Clock is taken, usually with SetClock.
+//
+// A test drives code running in another goroutine by waiting for it to block
+// on the clock and then moving time on:
+//
+//	done := make(chan struct{})
+//	go func() {
+//		manager.fadeOutSpeaker("media_player.bedroom")
+//		close(done)
+//	}()
+//	clk.RunUntil(done)
+package testclock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+)
+
+var _ clock.Clock = (*Clock)(nil)
+
+// Clock is a clock.Clock whose time only moves when the test calls Advance,
+// AdvanceToNext, Set or RunUntil
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	seq     uint64        // Orders waiters with the same deadline by creation
+	changed chan struct{} // Closed and replaced whenever a waiter is added
+}
+
+// waiter is a pending sleep, timer or ticker
+type waiter struct {
+	clock    *Clock
+	deadline time.Time
+	seq      uint64
+	period   time.Duration // Tickers re-arm by period after each tick
+	fire     func(now time.Time)
+}
+
+// New creates a virtual clock at start
+func New(start time.Time) *Clock {
+	return &Clock{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the virtual time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the virtual time elapsed since t
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After sends the virtual time once d has passed
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.add(d, 0, func(now time.Time) { ch <- now })
+	return ch
+}
+
+// AfterFunc calls f once d has passed. Unlike time.AfterFunc, f runs on the
+// goroutine moving the clock, so its effects are done when Advance returns.
+func (c *Clock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return &timer{waiter: c.add(d, 0, func(time.Time) { f() })}
+}
+
+// NewTicker sends the virtual time every d, dropping ticks that aren't read
+// in time like time.Ticker does
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("testclock: non-positive interval for NewTicker")
+	}
+	ch := make(chan time.Time, 1)
+	w := c.add(d, d, func(now time.Time) {
+		select {
+		case ch <- now:
+		default:
+		}
+	})
+	return &ticker{waiter: w, c: ch}
+}
+
+// Sleep blocks until d of virtual time has passed
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// SleepContext blocks until d of virtual time has passed or ctx is done
+func (c *Clock) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ch := make(chan time.Time, 1)
+	w := c.add(d, 0, func(now time.Time) { ch <- now })
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		c.remove(w)
+		return ctx.Err()
+	}
+}
+
+// Waiters returns how many sleeps, timers and tickers are pending
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil waits until at least n sleeps, timers or tickers are pending,
+// so the test knows the code under test has got to its next wait
+func (c *Clock) BlockUntil(n int) {
+	c.blockUntil(n, nil)
+}
+
+// blockUntil is BlockUntil that gives up, returning false, once done is closed
+func (c *Clock) blockUntil(n int, done <-chan struct{}) bool {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) >= n {
+			c.mu.Unlock()
+			return true
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// Advance moves the clock forward by d, firing everything due on the way in
+// deadline order. A goroutine woken by it that waits again is caught by the
+// next Advance, not this one.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+	c.advanceTo(target)
+}
+
+// Set moves the clock to t, firing everything due by then. Setting an earlier
+// time fires nothing.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	if !t.After(c.now) {
+		c.now = t
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	c.advanceTo(t)
+}
+
+// AdvanceToNext moves the clock to the earliest pending deadline and fires
+// what is due then. It returns false, leaving the clock alone, if nothing is
+// pending.
+func (c *Clock) AdvanceToNext() bool {
+	c.mu.Lock()
+	if len(c.waiters) == 0 {
+		c.mu.Unlock()
+		return false
+	}
+	next := c.waiters[0].deadline
+	c.mu.Unlock()
+
+	c.advanceTo(next)
+	return true
+}
+
+// RunUntil moves the clock from wait to wait until done is closed: it waits
+// for the code under test to block on the clock, then advances to its
+// deadline. It suits code with one goroutine waiting on the clock at a time;
+// with several, call BlockUntil with their count before each Advance, so none
+// is left behind.
+func (c *Clock) RunUntil(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if !c.blockUntil(1, done) {
+			return
+		}
+		c.AdvanceToNext()
+	}
+}
+
+// advanceTo fires every waiter due by target, one at a time in deadline
+// order with the clock at its deadline, then leaves the clock at target
+func (c *Clock) advanceTo(target time.Time) {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(target) {
+			if target.After(c.now) {
+				c.now = target
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		if w.deadline.After(c.now) {
+			c.now = w.deadline
+		}
+		now := c.now
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+			c.insert(w)
+		}
+		c.mu.Unlock()
+
+		// Fire without the lock so the waiter can use the clock
+		w.fire(now)
+	}
+}
+
+// add registers a waiter due after d
+func (c *Clock) add(d, period time.Duration, fire func(now time.Time)) *waiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &waiter{clock: c, deadline: c.now.Add(d), period: period, fire: fire}
+	c.insert(w)
+	return w
+}
+
+// insert adds w to the waiters in deadline order and wakes BlockUntil. It
+// must be called with mu held.
+func (c *Clock) insert(w *waiter) {
+	c.seq++
+	w.seq = c.seq
+	i := sort.Search(len(c.waiters), func(i int) bool {
+		o := c.waiters[i]
+		return o.deadline.After(w.deadline) || (o.deadline.Equal(w.deadline) && o.seq > w.seq)
+	})
+	c.waiters = append(c.waiters, nil)
+	copy(c.waiters[i+1:], c.waiters[i:])
+	c.waiters[i] = w
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove drops w if it is pending, reporting whether it was
+func (c *Clock) remove(w *waiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.removeLocked(w)
+}
+
+// removeLocked is remove with mu held
+func (c *Clock) removeLocked(w *waiter) bool {
+	for i, o := range c.waiters {
+		if o == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// timer is a clock.Timer on the virtual clock
+type timer struct {
+	waiter *waiter
+}
+
+// Stop prevents the timer from firing
+func (t *timer) Stop() bool {
+	return t.waiter.clock.remove(t.waiter)
+}
+
+// Reset makes the timer fire d from now, whether or not it already has
+func (t *timer) Reset(d time.Duration) bool {
+	c := t.waiter.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active := c.removeLocked(t.waiter)
+	t.waiter.deadline = c.now.Add(d)
+	c.insert(t.waiter)
+	return active
+}
+
+// ticker is a clock.Ticker on the virtual clock
+type ticker struct {
+	waiter *waiter
+	c      chan time.Time
+}
+
+// C returns the channel ticks are sent on
+func (t *ticker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop turns off the ticker
+func (t *ticker) Stop() {
+	t.waiter.clock.remove(t.waiter)
+}
+
+// Reset restarts the ticker with period d
+func (t *ticker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("testclock: non-positive interval for Ticker.Reset")
+	}
+	c := t.waiter.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(t.waiter)
+	t.waiter.period = d
+	t.waiter.deadline = c.now.Add(d)
+	c.insert(t.waiter)
+}
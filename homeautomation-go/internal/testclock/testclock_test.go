@@ -0,0 +1,159 @@
+package testclock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStart = time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+
+func TestClock_FiresInDeadlineOrder(t *testing.T) {
+	clk := New(testStart)
+
+	var fired []string
+	var firedAt []time.Time
+	record := func(name string) func() {
+		return func() {
+			fired = append(fired, name)
+			firedAt = append(firedAt, clk.Now())
+		}
+	}
+	clk.AfterFunc(3*time.Second, record("third"))
+	clk.AfterFunc(time.Second, record("first"))
+	clk.AfterFunc(2*time.Second, record("second a"))
+	clk.AfterFunc(2*time.Second, record("second b"))
+	stopped := clk.AfterFunc(2*time.Second, record("stopped"))
+	assert.True(t, stopped.Stop())
+	assert.Equal(t, 4, clk.Waiters())
+
+	clk.Advance(10 * time.Second)
+
+	assert.Equal(t, []string{"first", "second a", "second b", "third"}, fired)
+	assert.Equal(t, []time.Time{
+		testStart.Add(time.Second),
+		testStart.Add(2 * time.Second),
+		testStart.Add(2 * time.Second),
+		testStart.Add(3 * time.Second),
+	}, firedAt, "each fires with the clock at its deadline")
+	assert.Equal(t, testStart.Add(10*time.Second), clk.Now())
+	assert.Zero(t, clk.Waiters())
+}
+
+func TestClock_TimerReset(t *testing.T) {
+	clk := New(testStart)
+
+	fired := 0
+	timer := clk.AfterFunc(time.Second, func() { fired++ })
+	clk.Advance(500 * time.Millisecond)
+	assert.True(t, timer.Reset(time.Second), "the timer was still pending")
+
+	clk.Advance(900 * time.Millisecond)
+	assert.Zero(t, fired)
+	clk.Advance(100 * time.Millisecond)
+	assert.Equal(t, 1, fired)
+
+	assert.False(t, timer.Reset(time.Second), "the timer had fired")
+	clk.Advance(time.Second)
+	assert.Equal(t, 2, fired)
+}
+
+func TestClock_Ticker(t *testing.T) {
+	clk := New(testStart)
+	ticker := clk.NewTicker(time.Minute)
+
+	clk.Advance(time.Minute)
+	assert.Equal(t, testStart.Add(time.Minute), <-ticker.C())
+
+	// Unread ticks are dropped rather than queued
+	clk.Advance(3 * time.Minute)
+	assert.Equal(t, testStart.Add(2*time.Minute), <-ticker.C())
+	select {
+	case tick := <-ticker.C():
+		t.Fatalf("unexpected queued tick %v", tick)
+	default:
+	}
+
+	ticker.Reset(time.Hour)
+	clk.Advance(59 * time.Minute)
+	assert.Empty(t, ticker.C())
+	clk.Advance(time.Minute)
+	assert.Equal(t, testStart.Add(time.Hour+4*time.Minute), <-ticker.C())
+
+	ticker.Stop()
+	clk.Advance(2 * time.Hour)
+	assert.Empty(t, ticker.C())
+	assert.Zero(t, clk.Waiters())
+}
+
+func TestClock_SleepWaitsForAdvance(t *testing.T) {
+	clk := New(testStart)
+
+	woke := make(chan time.Time)
+	go func() {
+		clk.Sleep(time.Hour)
+		woke <- clk.Now()
+	}()
+
+	clk.BlockUntil(1)
+	select {
+	case <-woke:
+		t.Fatal("Sleep returned before the clock moved")
+	default:
+	}
+
+	clk.Advance(time.Hour)
+	assert.Equal(t, testStart.Add(time.Hour), <-woke)
+}
+
+func TestClock_SleepContextCancelled(t *testing.T) {
+	clk := New(testStart)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result := make(chan error)
+	go func() {
+		result <- clk.SleepContext(ctx, time.Hour)
+	}()
+
+	clk.BlockUntil(1)
+	cancel()
+	require.ErrorIs(t, <-result, context.Canceled)
+	assert.Zero(t, clk.Waiters(), "a cancelled sleep stops waiting on the clock")
+	assert.Equal(t, testStart, clk.Now())
+}
+
+func TestClock_RunUntil(t *testing.T) {
+	clk := New(testStart)
+
+	// A loop that waits longer each step, like a fade-out near silence
+	var steps []time.Time
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 5; i++ {
+			clk.Sleep(time.Duration(i) * time.Minute)
+			steps = append(steps, clk.Now())
+		}
+	}()
+
+	clk.RunUntil(done)
+
+	require.Len(t, steps, 5)
+	assert.Equal(t, testStart.Add(time.Minute), steps[0])
+	assert.Equal(t, testStart.Add(15*time.Minute), steps[4])
+	assert.Equal(t, testStart.Add(15*time.Minute), clk.Now(), "time stops at the last wait")
+}
+
+func TestClock_AdvanceToNext(t *testing.T) {
+	clk := New(testStart)
+	assert.False(t, clk.AdvanceToNext(), "nothing pending")
+	assert.Equal(t, testStart, clk.Now())
+
+	ch := clk.After(90 * time.Second)
+	require.True(t, clk.AdvanceToNext())
+	assert.Equal(t, testStart.Add(90*time.Second), <-ch)
+	assert.Equal(t, testStart.Add(90*time.Second), clk.Now())
+}
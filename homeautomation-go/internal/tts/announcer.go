@@ -0,0 +1,291 @@
+// Package tts queues spoken announcements for every plugin. Plugins name a
+// target (e.g. "security") instead of listing speakers, give each announcement
+// a priority, and hand it to the Announcer, which skips low-priority
+// announcements during quiet hours and speaks the rest one at a time so
+// simultaneous announcements don't cut each other off. Delivery goes through
+// the notification router, so the evening's chime/push schedule still applies.
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/notifyrouter"
+	"homeautomation/internal/state"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrQuietHours is returned for announcements skipped during quiet hours
+	ErrQuietHours = errors.New("quiet hours")
+
+	// ErrQueueFull is returned when the queue holds only announcements of the
+	// same or higher priority
+	ErrQueueFull = errors.New("announcement queue is full")
+
+	// ErrStopped is returned for announcements made after Stop
+	ErrStopped = errors.New("announcer stopped")
+)
+
+// speakingTimePerWord estimates how long TTS takes to say one word
+const speakingTimePerWord = 400 * time.Millisecond
+
+// Announcement is a message to speak on some speakers
+type Announcement struct {
+	Title    string   // Push notification title, if the router downgrades to a push
+	Message  string   // Spoken text
+	Speakers []string // Media players, usually from Speakers(target) after the caller's own filtering
+	Priority Priority // Defaults to normal
+	PushSent bool     // The caller sends its own push, so the router sends none
+}
+
+// queued is an announcement waiting to be spoken with the plugin's client
+type queued struct {
+	ctx          context.Context
+	client       ha.HAClient
+	announcement Announcement
+}
+
+// Announcer resolves speaker targets and speaks announcements in priority
+// order. A nil Announcer speaks each announcement immediately on the default
+// speakers, with no quiet hours or queue.
+type Announcer struct {
+	config       *Config
+	stateManager *state.Manager
+	router       *notifyrouter.Router
+	logger       *zap.Logger
+	clock        clock.Clock
+
+	// Waiting announcements, highest priority first (protected by mu)
+	queue   []queued
+	running bool
+	stopped bool
+	mu      sync.Mutex
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+// New creates an announcer reading quiet hours inputs from the state manager
+func New(config *Config, stateManager *state.Manager, logger *zap.Logger) *Announcer {
+	return &Announcer{
+		config:       config,
+		stateManager: stateManager,
+		logger:       logger.Named("tts"),
+		clock:        clock.NewRealClock(),
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+}
+
+// SetNotificationRouter sets the router that lowers announcements to a chime
+// or push as the evening goes on
+func (a *Announcer) SetNotificationRouter(router *notifyrouter.Router) {
+	a.router = router
+}
+
+// SetClock sets the clock implementation (useful for testing)
+func (a *Announcer) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// settings returns the configured settings, or the defaults for a nil Announcer
+func (a *Announcer) settings() Settings {
+	if a == nil {
+		return DefaultConfig().TTS
+	}
+	return a.config.TTS
+}
+
+// Speakers returns the media players for a target with entity group
+// references expanded
+func (a *Announcer) Speakers(client ha.HAClient, target string) ([]string, error) {
+	speakers, ok := a.settings().SpeakersFor(target)
+	if !ok {
+		return nil, fmt.Errorf("unknown announcement target %q", target)
+	}
+	return entitygroups.Expand(client, speakers)
+}
+
+// TTSEntity returns the configured TTS entity, for callers that must speak
+// directly rather than through the queue and router
+func (a *Announcer) TTSEntity() string {
+	return a.settings().Entity()
+}
+
+// Quiet reports whether quiet hours are in effect
+func (a *Announcer) Quiet() bool {
+	if a == nil {
+		return false
+	}
+	quiet := a.config.TTS.QuietHours
+	if quiet.WhenAnyoneAsleep {
+		if asleep, err := a.stateManager.GetBool("isAnyoneAsleep"); err == nil && asleep {
+			return true
+		}
+	}
+	if len(quiet.DayPhases) > 0 {
+		if dayPhase, err := a.stateManager.GetString("dayPhase"); err == nil {
+			for _, phase := range quiet.DayPhases {
+				if phase == dayPhase {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Announce queues an announcement to be spoken with client. It returns
+// ErrQuietHours if quiet hours skip it and ErrQueueFull if there's no room;
+// delivery errors are logged when it's spoken.
+func (a *Announcer) Announce(ctx context.Context, client ha.HAClient, announcement Announcement) error {
+	if announcement.Priority == "" {
+		announcement.Priority = PriorityNormal
+	}
+	if a == nil {
+		_, err := deliver(ctx, client, nil, DefaultConfig().TTS, announcement)
+		return err
+	}
+
+	if a.Quiet() && announcement.Priority.rank() < a.config.TTS.QuietHours.MinQuietPriority().rank() {
+		a.logger.Info("Skipping announcement during quiet hours",
+			zap.String("message", announcement.Message),
+			zap.String("priority", string(announcement.Priority)))
+		return ErrQuietHours
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return ErrStopped
+	}
+
+	if len(a.queue) >= a.config.TTS.Capacity() {
+		// The lowest priority waiting announcement is last
+		last := a.queue[len(a.queue)-1]
+		if last.announcement.Priority.rank() >= announcement.Priority.rank() {
+			return ErrQueueFull
+		}
+		a.queue = a.queue[:len(a.queue)-1]
+		a.logger.Warn("Dropping queued announcement for a higher priority one",
+			zap.String("dropped", last.announcement.Message),
+			zap.String("message", announcement.Message))
+	}
+
+	// After everything of the same or higher priority
+	i := len(a.queue)
+	for i > 0 && a.queue[i-1].announcement.Priority.rank() < announcement.Priority.rank() {
+		i--
+	}
+	a.queue = append(a.queue, queued{})
+	copy(a.queue[i+1:], a.queue[i:])
+	a.queue[i] = queued{ctx: ctx, client: client, announcement: announcement}
+
+	if !a.running {
+		a.running = true
+		go a.run()
+	}
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending returns how many announcements are waiting to be spoken
+func (a *Announcer) Pending() int {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.queue)
+}
+
+// Stop drops waiting announcements and stops speaking
+func (a *Announcer) Stop() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return
+	}
+	a.stopped = true
+	a.queue = nil
+	close(a.done)
+}
+
+// run speaks queued announcements one at a time, waiting for each to finish
+func (a *Announcer) run() {
+	for {
+		item, ok := a.next()
+		if !ok {
+			select {
+			case <-a.wake:
+				continue
+			case <-a.done:
+				return
+			}
+		}
+
+		level, err := deliver(item.ctx, item.client, a.router, a.config.TTS, item.announcement)
+		if err != nil {
+			a.logger.Error("Failed to speak announcement",
+				zap.String("message", item.announcement.Message),
+				zap.Error(err))
+			continue
+		}
+		a.logger.Info("Spoke announcement",
+			zap.String("message", item.announcement.Message),
+			zap.String("priority", string(item.announcement.Priority)),
+			zap.String("level", string(level)),
+			zap.Strings("speakers", item.announcement.Speakers))
+
+		if level != notifyrouter.LevelPush {
+			a.clock.Sleep(speakingTime(item.announcement.Message, level) + time.Duration(a.config.TTS.Gap())*time.Second)
+		}
+	}
+}
+
+// next pops the highest priority waiting announcement
+func (a *Announcer) next() (queued, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.queue) == 0 || a.stopped {
+		return queued{}, false
+	}
+	item := a.queue[0]
+	a.queue = a.queue[1:]
+	return item, true
+}
+
+// speakingTime estimates how long the speakers are busy with a message
+func speakingTime(message string, level notifyrouter.Level) time.Duration {
+	if level != notifyrouter.LevelFull {
+		return 0
+	}
+	return time.Duration(len(strings.Fields(message))) * speakingTimePerWord
+}
+
+// deliver hands the announcement to the router, which speaks it in full or
+// lowers it to a chime or push
+func deliver(ctx context.Context, client ha.HAClient, router *notifyrouter.Router, settings Settings, announcement Announcement) (notifyrouter.Level, error) {
+	return router.Announce(ctx, client, notifyrouter.Announcement{
+		Title:     announcement.Title,
+		Message:   announcement.Message,
+		Speakers:  announcement.Speakers,
+		TTSEntity: settings.Entity(),
+		PushSent:  announcement.PushSent,
+	})
+}
@@ -0,0 +1,173 @@
+package tts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+	"homeautomation/internal/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// gatedClient holds every service call until the gate is opened, so tests can
+// queue announcements while the first one is still being spoken
+type gatedClient struct {
+	*ha.MockClient
+	gate chan struct{}
+}
+
+func (c *gatedClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	<-c.gate
+	return c.MockClient.CallService(ctx, domain, service, data)
+}
+
+func newTestAnnouncer(t *testing.T, settings Settings) (*Announcer, *state.Manager, *ha.MockClient) {
+	t.Helper()
+
+	mockClient := ha.NewMockClient()
+	stateManager := state.NewManager(mockClient, zap.NewNop(), false)
+	config := &Config{TTS: settings}
+	require.NoError(t, config.Validate())
+
+	announcer := New(config, stateManager, zap.NewNop())
+	announcer.SetClock(clock.NewMockClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+	t.Cleanup(announcer.Stop)
+	return announcer, stateManager, mockClient
+}
+
+func spokenMessages(mockClient *ha.MockClient) []string {
+	var messages []string
+	for _, call := range mockClient.GetServiceCalls() {
+		if call.Domain == "tts" && call.Service == "speak" {
+			messages = append(messages, call.Data["message"].(string))
+		}
+	}
+	return messages
+}
+
+func TestAnnouncer_SpeaksOnTargetSpeakers(t *testing.T) {
+	announcer, _, mockClient := newTestAnnouncer(t, Settings{
+		TTSEntity: "tts.piper",
+		Speakers:  map[string][]string{TargetArrivalTori: {"media_player.office"}},
+	})
+
+	speakers, err := announcer.Speakers(mockClient, TargetArrivalTori)
+	require.NoError(t, err)
+	require.NoError(t, announcer.Announce(context.Background(), mockClient, Announcement{
+		Message:  "Tori is here",
+		Speakers: speakers,
+	}))
+
+	require.Eventually(t, func() bool { return len(spokenMessages(mockClient)) == 1 }, time.Second, 5*time.Millisecond)
+	call := mockClient.GetServiceCalls()[0]
+	assert.Equal(t, "tts.piper", call.Data["entity_id"])
+	assert.Equal(t, []string{"media_player.office"}, call.Data["media_player_entity_id"])
+
+	_, err = announcer.Speakers(mockClient, "doorbell")
+	assert.ErrorContains(t, err, "unknown announcement target")
+}
+
+func TestAnnouncer_SpeakersExpandsGroups(t *testing.T) {
+	announcer, _, mockClient := newTestAnnouncer(t, Settings{})
+	client := entitygroups.NewClient(mockClient, &entitygroups.Config{EntityGroups: map[string][]string{
+		entitygroups.CommonSpeakers: {"media_player.kitchen", "media_player.dining_room"},
+	}})
+
+	speakers, err := announcer.Speakers(client, TargetSecurity)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"media_player.bedroom", "media_player.kitchen", "media_player.dining_room"}, speakers)
+}
+
+func TestAnnouncer_QuietHours(t *testing.T) {
+	announcer, stateManager, mockClient := newTestAnnouncer(t, Settings{
+		QuietHours: QuietHours{DayPhases: []string{"night"}, WhenAnyoneAsleep: true, MinPriority: PriorityNormal},
+	})
+	ctx := context.Background()
+
+	require.NoError(t, stateManager.SetString("dayPhase", "night"))
+	assert.True(t, announcer.Quiet())
+	assert.ErrorIs(t, announcer.Announce(ctx, mockClient, Announcement{Message: "Snuggle time", Priority: PriorityLow}), ErrQuietHours)
+	require.NoError(t, announcer.Announce(ctx, mockClient, Announcement{Message: "Front door open"}))
+
+	require.NoError(t, stateManager.SetString("dayPhase", "day"))
+	require.NoError(t, stateManager.SetBool("isAnyoneAsleep", true))
+	assert.True(t, announcer.Quiet(), "someone napping during the day is quiet hours too")
+
+	require.NoError(t, stateManager.SetBool("isAnyoneAsleep", false))
+	assert.False(t, announcer.Quiet())
+	require.NoError(t, announcer.Announce(ctx, mockClient, Announcement{Message: "Snuggle time", Priority: PriorityLow}))
+
+	require.Eventually(t, func() bool { return len(spokenMessages(mockClient)) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Front door open", "Snuggle time"}, spokenMessages(mockClient))
+}
+
+func TestAnnouncer_SpeaksOneAtATimeByPriority(t *testing.T) {
+	announcer, _, mockClient := newTestAnnouncer(t, Settings{})
+	client := &gatedClient{MockClient: mockClient, gate: make(chan struct{})}
+	ctx := context.Background()
+
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "first"}))
+	require.Eventually(t, func() bool { return announcer.Pending() == 0 }, time.Second, 5*time.Millisecond,
+		"the first announcement is being spoken")
+
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "low", Priority: PriorityLow}))
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "normal"}))
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "urgent", Priority: PriorityUrgent}))
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "second normal"}))
+	assert.Equal(t, 4, announcer.Pending())
+	assert.Empty(t, spokenMessages(mockClient))
+
+	close(client.gate)
+	require.Eventually(t, func() bool { return len(spokenMessages(mockClient)) == 5 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"first", "urgent", "normal", "second normal", "low"}, spokenMessages(mockClient))
+}
+
+func TestAnnouncer_FullQueueDropsLowestPriority(t *testing.T) {
+	announcer, _, mockClient := newTestAnnouncer(t, Settings{QueueSize: 2})
+	client := &gatedClient{MockClient: mockClient, gate: make(chan struct{})}
+	ctx := context.Background()
+
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "first"}))
+	require.Eventually(t, func() bool { return announcer.Pending() == 0 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "low", Priority: PriorityLow}))
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "normal"}))
+	require.NoError(t, announcer.Announce(ctx, client, Announcement{Message: "urgent", Priority: PriorityUrgent}))
+	assert.ErrorIs(t, announcer.Announce(ctx, client, Announcement{Message: "another normal"}), ErrQueueFull)
+	assert.Equal(t, 2, announcer.Pending())
+
+	close(client.gate)
+	require.Eventually(t, func() bool { return len(spokenMessages(mockClient)) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"first", "urgent", "normal"}, spokenMessages(mockClient))
+}
+
+func TestAnnouncer_Stop(t *testing.T) {
+	announcer, _, mockClient := newTestAnnouncer(t, Settings{})
+
+	announcer.Stop()
+	announcer.Stop()
+	assert.ErrorIs(t, announcer.Announce(context.Background(), mockClient, Announcement{Message: "hello"}), ErrStopped)
+}
+
+func TestAnnouncer_NilSpeaksImmediately(t *testing.T) {
+	var announcer *Announcer
+	mockClient := ha.NewMockClient()
+
+	speakers, err := announcer.Speakers(mockClient, TargetCuddle)
+	require.NoError(t, err)
+	assert.False(t, announcer.Quiet())
+	assert.Zero(t, announcer.Pending())
+
+	require.NoError(t, announcer.Announce(context.Background(), mockClient, Announcement{Message: "Snuggle time", Speakers: speakers}))
+	calls := mockClient.GetServiceCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, DefaultTTSEntity, calls[0].Data["entity_id"])
+	assert.Equal(t, []string{"media_player.bedroom"}, calls[0].Data["media_player_entity_id"])
+	announcer.Stop()
+}
@@ -0,0 +1,198 @@
+package tts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"homeautomation/internal/dayphase"
+	"homeautomation/internal/entitygroups"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Priority decides which announcements speak during quiet hours and which go
+// first when several are waiting
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"    // Pleasantries that can be skipped
+	PriorityNormal Priority = "normal" // Arrivals and routine security notices
+	PriorityUrgent Priority = "urgent" // The doorbell and anything that needs attention now
+)
+
+// rank orders priorities, 0 for unknown
+func (p Priority) rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityNormal:
+		return 2
+	case PriorityUrgent:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Valid reports whether p is a known priority
+func (p Priority) Valid() bool {
+	return p.rank() > 0
+}
+
+// Targets plugins announce to
+const (
+	TargetArrivalNick     = "arrival_nick"
+	TargetArrivalCaroline = "arrival_caroline"
+	TargetArrivalTori     = "arrival_tori"
+	TargetSecurity        = "security"
+	TargetCuddle          = "cuddle"
+	TargetOwners          = "owners" // Wherever the owners are likely to hear, e.g. for rules
+)
+
+// DefaultSpeakers are the speakers each target uses when the config doesn't
+// list it
+var DefaultSpeakers = map[string][]string{
+	TargetArrivalNick:     {entitygroups.Group(entitygroups.CommonSpeakers)},
+	TargetArrivalCaroline: {entitygroups.Group(entitygroups.CommonSpeakers), "media_player.office"},
+	TargetArrivalTori:     {entitygroups.Group(entitygroups.CommonSpeakers), "media_player.office"},
+	TargetSecurity:        {"media_player.bedroom", entitygroups.Group(entitygroups.CommonSpeakers)},
+	TargetCuddle:          {"media_player.bedroom"},
+	TargetOwners:          {entitygroups.Group(entitygroups.CommonSpeakers), "media_player.office"},
+}
+
+// Defaults for settings left out of the config
+const (
+	DefaultTTSEntity  = "tts.google_translate_en_com"
+	DefaultGapSeconds = 2
+	DefaultQueueSize  = 10
+)
+
+// QuietHours sets when announcements below a priority are skipped
+type QuietHours struct {
+	DayPhases        []string `yaml:"day_phases"`         // Quiet while dayPhase is one of these
+	WhenAnyoneAsleep bool     `yaml:"when_anyone_asleep"` // Quiet while isAnyoneAsleep is true
+	MinPriority      Priority `yaml:"min_priority"`       // Lowest priority still spoken; defaults to urgent
+}
+
+// Settings configures the announcer
+type Settings struct {
+	TTSEntity  string              `yaml:"tts_entity"`
+	Speakers   map[string][]string `yaml:"speakers"`    // Media players or entity group references per target
+	QuietHours QuietHours          `yaml:"quiet_hours"` // No quiet hours if empty
+	GapSeconds int                 `yaml:"gap_seconds"` // Silence after each announcement before the next starts
+	QueueSize  int                 `yaml:"queue_size"`  // Announcements waiting beyond this drop the lowest priority
+}
+
+// Config represents the tts_config.yaml structure
+type Config struct {
+	TTS Settings `yaml:"tts"`
+}
+
+// DefaultConfig returns the settings used without a config file: the default
+// speakers and no quiet hours
+func DefaultConfig() *Config {
+	return &Config{}
+}
+
+// SpeakersFor returns the speakers configured for a target, falling back to
+// DefaultSpeakers
+func (s Settings) SpeakersFor(target string) ([]string, bool) {
+	if speakers, ok := s.Speakers[target]; ok {
+		return speakers, true
+	}
+	speakers, ok := DefaultSpeakers[target]
+	return speakers, ok
+}
+
+// Entity returns the TTS entity, defaulting to DefaultTTSEntity
+func (s Settings) Entity() string {
+	if s.TTSEntity == "" {
+		return DefaultTTSEntity
+	}
+	return s.TTSEntity
+}
+
+// MinQuietPriority returns the lowest priority spoken during quiet hours
+func (q QuietHours) MinQuietPriority() Priority {
+	if q.MinPriority == "" {
+		return PriorityUrgent
+	}
+	return q.MinPriority
+}
+
+// Gap returns the seconds of silence between announcements
+func (s Settings) Gap() int {
+	if s.GapSeconds == 0 {
+		return DefaultGapSeconds
+	}
+	return s.GapSeconds
+}
+
+// Capacity returns how many announcements may wait
+func (s Settings) Capacity() int {
+	if s.QueueSize == 0 {
+		return DefaultQueueSize
+	}
+	return s.QueueSize
+}
+
+// Validate checks targets, speakers, quiet hours and queue settings
+func (c *Config) Validate() error {
+	s := c.TTS
+	for target, speakers := range s.Speakers {
+		if _, ok := DefaultSpeakers[target]; !ok {
+			return fmt.Errorf("speakers: unknown target %q", target)
+		}
+		if len(speakers) == 0 {
+			return fmt.Errorf("speakers: %s has no speakers", target)
+		}
+		for _, speaker := range speakers {
+			if !strings.HasPrefix(speaker, "media_player.") && !entitygroups.IsReference(speaker) {
+				return fmt.Errorf("speakers: %s: %q is not a media player or entity group reference", target, speaker)
+			}
+		}
+	}
+
+	for i, phase := range s.QuietHours.DayPhases {
+		switch dayphase.DayPhase(phase) {
+		case dayphase.DayPhaseMorning, dayphase.DayPhaseDay, dayphase.DayPhaseSunset,
+			dayphase.DayPhaseDusk, dayphase.DayPhaseWinddown, dayphase.DayPhaseNight:
+		default:
+			return fmt.Errorf("quiet_hours.day_phases[%d]: unknown day phase %q", i, phase)
+		}
+	}
+	if s.QuietHours.MinPriority != "" && !s.QuietHours.MinPriority.Valid() {
+		return fmt.Errorf("quiet_hours.min_priority: unknown priority %q, expected low, normal or urgent", s.QuietHours.MinPriority)
+	}
+
+	if s.TTSEntity != "" && !strings.HasPrefix(s.TTSEntity, "tts.") {
+		return fmt.Errorf("tts_entity %q is not a tts entity", s.TTSEntity)
+	}
+	if s.GapSeconds < 0 {
+		return fmt.Errorf("gap_seconds must not be negative")
+	}
+	if s.QueueSize < 0 {
+		return fmt.Errorf("queue_size must not be negative")
+	}
+	return nil
+}
+
+// LoadConfig loads the TTS configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
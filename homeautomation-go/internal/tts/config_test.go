@@ -0,0 +1,81 @@
+package tts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Production(t *testing.T) {
+	config, err := LoadConfig("../../../configs/tts_config.yaml")
+	require.NoError(t, err)
+
+	s := config.TTS
+	speakers, ok := s.SpeakersFor(TargetSecurity)
+	require.True(t, ok)
+	assert.Contains(t, speakers, "media_player.bedroom")
+	assert.Equal(t, PriorityNormal, s.QuietHours.MinQuietPriority())
+	assert.True(t, s.QuietHours.WhenAnyoneAsleep)
+}
+
+func TestSettings_Defaults(t *testing.T) {
+	s := DefaultConfig().TTS
+
+	assert.Equal(t, DefaultTTSEntity, s.Entity())
+	assert.Equal(t, DefaultGapSeconds, s.Gap())
+	assert.Equal(t, DefaultQueueSize, s.Capacity())
+	assert.Equal(t, PriorityUrgent, s.QuietHours.MinQuietPriority())
+
+	speakers, ok := s.SpeakersFor(TargetCuddle)
+	require.True(t, ok)
+	assert.Equal(t, []string{"media_player.bedroom"}, speakers)
+
+	_, ok = s.SpeakersFor("doorbell")
+	assert.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  string
+	}{
+		{"empty", Settings{}, ""},
+		{"valid", Settings{
+			TTSEntity:  "tts.piper",
+			Speakers:   map[string][]string{TargetSecurity: {"media_player.bedroom", "group:common_speakers", "area:office"}},
+			QuietHours: QuietHours{DayPhases: []string{"night"}, WhenAnyoneAsleep: true, MinPriority: PriorityNormal},
+		}, ""},
+		{"unknown target", Settings{Speakers: map[string][]string{"doorbell": {"media_player.kitchen"}}}, "unknown target"},
+		{"no speakers", Settings{Speakers: map[string][]string{TargetCuddle: {}}}, "has no speakers"},
+		{"not a media player", Settings{Speakers: map[string][]string{TargetCuddle: {"light.bedroom"}}}, "not a media player"},
+		{"unknown day phase", Settings{QuietHours: QuietHours{DayPhases: []string{"evening"}}}, "unknown day phase"},
+		{"unknown priority", Settings{QuietHours: QuietHours{MinPriority: "critical"}}, "unknown priority"},
+		{"not a tts entity", Settings{TTSEntity: "media_player.bedroom"}, "not a tts entity"},
+		{"negative gap", Settings{GapSeconds: -1}, "gap_seconds"},
+		{"negative queue", Settings{QueueSize: -1}, "queue_size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{TTS: tt.settings}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tts_config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("tts:\n  queue_size: -2\n"), 0644))
+
+	_, err := LoadConfig(path)
+	assert.ErrorContains(t, err, "queue_size")
+}
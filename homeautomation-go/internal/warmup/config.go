@@ -0,0 +1,79 @@
+package warmup
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plugins lists the plugin names a warm-up window can be set for; they match
+// the plugins' shadow state names
+var Plugins = []string{
+	"bedroomcomfort", "climate", "dayphase", "emergency", "energy",
+	"focusmode", "freezeprotection", "growlights", "hotwater", "kidmode",
+	"lighting", "loadshedding", "lowbattery", "music", "openreminder",
+	"reports", "rules", "scenescheduler", "security", "sleepfan",
+	"sleephygiene", "statetracking", "tv",
+}
+
+// Settings sets how long each plugin is kept from acting after startup
+type Settings struct {
+	DefaultSeconds int            `yaml:"default_seconds"` // Window for plugins not listed under plugins
+	Plugins        map[string]int `yaml:"plugins"`         // Per-plugin window in seconds; 0 turns warm-up off for the plugin
+}
+
+// Config represents the warmup_config.yaml structure
+type Config struct {
+	Warmup Settings `yaml:"warmup"`
+}
+
+// Window returns a plugin's warm-up window
+func (s Settings) Window(plugin string) time.Duration {
+	seconds, ok := s.Plugins[plugin]
+	if !ok {
+		seconds = s.DefaultSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Validate checks that windows aren't negative and name known plugins
+func (c *Config) Validate() error {
+	if c.Warmup.DefaultSeconds < 0 {
+		return fmt.Errorf("default_seconds must not be negative")
+	}
+
+	known := make(map[string]bool, len(Plugins))
+	for _, name := range Plugins {
+		known[name] = true
+	}
+	for name, seconds := range c.Warmup.Plugins {
+		if !known[name] {
+			return fmt.Errorf("plugins: unknown plugin %q", name)
+		}
+		if seconds < 0 {
+			return fmt.Errorf("plugins: %s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads the warm-up configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,55 @@
+package warmup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettings_Window(t *testing.T) {
+	settings := Settings{DefaultSeconds: 30, Plugins: map[string]int{"lighting": 90, "energy": 0}}
+
+	assert.Equal(t, 90*time.Second, settings.Window("lighting"))
+	assert.Equal(t, time.Duration(0), settings.Window("energy"), "0 turns warm-up off")
+	assert.Equal(t, 30*time.Second, settings.Window("music"), "unlisted plugins use the default")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "valid", config: Config{Warmup: Settings{DefaultSeconds: 30, Plugins: map[string]int{"music": 60}}}},
+		{name: "negative default", config: Config{Warmup: Settings{DefaultSeconds: -1}}, wantErr: "default_seconds"},
+		{name: "unknown plugin", config: Config{Warmup: Settings{Plugins: map[string]int{"lights": 60}}}, wantErr: `unknown plugin "lights"`},
+		{name: "negative plugin window", config: Config{Warmup: Settings{Plugins: map[string]int{"music": -5}}}, wantErr: "music must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadConfig_RepositoryConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join("..", "..", "..", "configs", "warmup_config.yaml"))
+	require.NoError(t, err)
+	assert.Positive(t, config.Warmup.DefaultSeconds)
+}
+
+func TestLoadConfig_Missing(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "warmup_config.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
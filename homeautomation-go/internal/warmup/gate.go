@@ -0,0 +1,179 @@
+// Package warmup keeps plugins from acting on half-synced state right after
+// startup. During a plugin's warm-up window its evaluations still run, but the
+// service calls they make are logged and dropped, so lights don't flick and
+// music doesn't restart while state settles. All windows end early once state
+// sync is confirmed complete.
+package warmup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// Gate tracks each plugin's warm-up window.
+// All methods are safe to call on a nil Gate, which suppresses nothing.
+type Gate struct {
+	config *Config
+	logger *zap.Logger
+	clock  clock.Clock
+
+	// Start of the windows, whether sync was confirmed, and service calls
+	// dropped per plugin (protected by mu)
+	started    time.Time
+	synced     bool
+	suppressed map[string]int
+	mu         sync.Mutex
+}
+
+// NewGate creates a gate whose windows start now
+func NewGate(config *Config, logger *zap.Logger) *Gate {
+	c := clock.NewRealClock()
+	return &Gate{
+		config:     config,
+		logger:     logger.Named("warmup"),
+		clock:      c,
+		started:    c.Now(),
+		suppressed: make(map[string]int),
+	}
+}
+
+// SetClock sets the clock implementation (useful for testing); the windows
+// start over from its current time
+func (g *Gate) SetClock(c clock.Clock) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.clock = c
+	g.started = c.Now()
+}
+
+// Active reports whether a plugin is still warming up
+func (g *Gate) Active(plugin string) bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.remaining(plugin) > 0
+}
+
+// remaining returns how much of a plugin's window is left; callers must hold mu
+func (g *Gate) remaining(plugin string) time.Duration {
+	if g.synced {
+		return 0
+	}
+	return g.started.Add(g.config.Warmup.Window(plugin)).Sub(g.clock.Now())
+}
+
+// MarkSynced ends every plugin's warm-up once state sync is confirmed complete
+func (g *Gate) MarkSynced() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.synced {
+		return
+	}
+	g.synced = true
+
+	fields := []zap.Field{zap.Duration("after", g.clock.Now().Sub(g.started))}
+	for plugin, count := range g.suppressed {
+		fields = append(fields, zap.Int(plugin, count))
+	}
+	g.logger.Info("State sync confirmed, warm-up over", fields...)
+}
+
+// Suppressed returns how many service calls each plugin had dropped
+func (g *Gate) Suppressed() map[string]int {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	counts := make(map[string]int, len(g.suppressed))
+	for plugin, count := range g.suppressed {
+		counts[plugin] = count
+	}
+	return counts
+}
+
+// suppress reports whether a plugin's action should be dropped, logging it if so
+func (g *Gate) suppress(plugin, action string, fields ...zap.Field) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	remaining := g.remaining(plugin)
+	if remaining <= 0 {
+		return false
+	}
+	g.suppressed[plugin]++
+
+	g.logger.Info("WARM-UP: Suppressed action",
+		append([]zap.Field{
+			zap.String("plugin", plugin),
+			zap.String("action", action),
+			zap.Duration("remaining", remaining.Round(time.Second)),
+		}, fields...)...)
+	return true
+}
+
+// Client wraps a plugin's HA client so its service calls and input helper
+// writes are dropped while the plugin warms up. Reads and subscriptions pass
+// through. Plugins with no window get client unchanged.
+func (g *Gate) Client(plugin string, client ha.HAClient) ha.HAClient {
+	if g == nil || g.config.Warmup.Window(plugin) <= 0 {
+		return client
+	}
+	return &gatedClient{HAClient: client, gate: g, plugin: plugin}
+}
+
+// gatedClient drops a plugin's actions during its warm-up window
+type gatedClient struct {
+	ha.HAClient
+	gate   *Gate
+	plugin string
+}
+
+// CallService drops the call during warm-up, reporting success
+func (c *gatedClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	if c.gate.suppress(c.plugin, domain+"."+service, zap.Any("data", data)) {
+		return nil
+	}
+	return c.HAClient.CallService(ctx, domain, service, data)
+}
+
+// SetInputBoolean drops the write during warm-up
+func (c *gatedClient) SetInputBoolean(ctx context.Context, name string, value bool) error {
+	if c.gate.suppress(c.plugin, "input_boolean."+name, zap.Bool("value", value)) {
+		return nil
+	}
+	return c.HAClient.SetInputBoolean(ctx, name, value)
+}
+
+// SetInputNumber drops the write during warm-up
+func (c *gatedClient) SetInputNumber(ctx context.Context, name string, value float64) error {
+	if c.gate.suppress(c.plugin, "input_number."+name, zap.Float64("value", value)) {
+		return nil
+	}
+	return c.HAClient.SetInputNumber(ctx, name, value)
+}
+
+// SetInputText drops the write during warm-up
+func (c *gatedClient) SetInputText(ctx context.Context, name string, value string) error {
+	if c.gate.suppress(c.plugin, "input_text."+name, zap.String("value", value)) {
+		return nil
+	}
+	return c.HAClient.SetInputText(ctx, name, value)
+}
+
+// Expand keeps entity group references working through the wrapper
+func (c *gatedClient) Expand(ids []string) ([]string, error) {
+	return entitygroups.Expand(c.HAClient, ids)
+}
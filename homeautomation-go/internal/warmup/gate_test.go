@@ -0,0 +1,89 @@
+package warmup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"homeautomation/internal/clock"
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestGate(t *testing.T) (*Gate, *clock.MockClock) {
+	t.Helper()
+	config := &Config{Warmup: Settings{DefaultSeconds: 30, Plugins: map[string]int{"lighting": 120, "energy": 0}}}
+	require.NoError(t, config.Validate())
+
+	gate := NewGate(config, zap.NewNop())
+	mockClock := clock.NewMockClock(time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC))
+	gate.SetClock(mockClock)
+	return gate, mockClock
+}
+
+func TestGate_SuppressesServiceCallsDuringWindow(t *testing.T) {
+	gate, mockClock := newTestGate(t)
+	mockClient := ha.NewMockClient()
+	client := gate.Client("lighting", mockClient)
+
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}))
+	require.NoError(t, client.SetInputBoolean(context.Background(), "fade_out_in_progress", true))
+	assert.Empty(t, mockClient.GetServiceCalls(), "actions are dropped while warming up")
+	assert.Equal(t, map[string]int{"lighting": 2}, gate.Suppressed())
+
+	mockClock.Advance(119 * time.Second)
+	assert.True(t, gate.Active("lighting"))
+
+	mockClock.Advance(time.Second)
+	assert.False(t, gate.Active("lighting"))
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}))
+	assert.Len(t, mockClient.GetServiceCalls(), 1, "actions go through after the window")
+}
+
+func TestGate_PerPluginWindows(t *testing.T) {
+	gate, mockClock := newTestGate(t)
+
+	mockClock.Advance(45 * time.Second)
+	assert.True(t, gate.Active("lighting"), "per-plugin window")
+	assert.False(t, gate.Active("music"), "default window")
+	assert.False(t, gate.Active("energy"), "warm-up turned off")
+
+	mockClient := ha.NewMockClient()
+	assert.Same(t, mockClient, gate.Client("energy", mockClient), "plugins without a window aren't wrapped")
+}
+
+func TestGate_MarkSyncedEndsWarmupEarly(t *testing.T) {
+	gate, _ := newTestGate(t)
+	mockClient := ha.NewMockClient()
+	client := gate.Client("lighting", mockClient)
+
+	gate.MarkSynced()
+
+	assert.False(t, gate.Active("lighting"))
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}))
+	assert.Len(t, mockClient.GetServiceCalls(), 1)
+}
+
+func TestGate_ClientKeepsEntityGroups(t *testing.T) {
+	gate, _ := newTestGate(t)
+	groups := &entitygroups.Config{EntityGroups: map[string][]string{"downstairs": {"light.kitchen", "light.living_room"}}}
+	client := gate.Client("lighting", entitygroups.NewClient(ha.NewMockClient(), groups))
+
+	ids, err := entitygroups.Expand(client, []string{entitygroups.Group("downstairs")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.kitchen", "light.living_room"}, ids)
+}
+
+func TestGate_NilSuppressesNothing(t *testing.T) {
+	var gate *Gate
+	mockClient := ha.NewMockClient()
+
+	assert.False(t, gate.Active("lighting"))
+	assert.Same(t, mockClient, gate.Client("lighting", mockClient))
+	gate.MarkSynced()
+	assert.Nil(t, gate.Suppressed())
+}
@@ -0,0 +1,137 @@
+package writescope
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"homeautomation/internal/warmup"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scope values
+const (
+	ReadOnly  = "read_only"
+	ReadWrite = "read_write"
+)
+
+// Writers lists the names a scope can be set for: every plugin, plus "state"
+// for state variable writes to Home Assistant helpers and "mqtt" for the MQTT
+// bridge
+var Writers = append([]string{"mqtt", "state"}, warmup.Plugins...)
+
+// Settings sets which writers may act and which domains are never written
+type Settings struct {
+	Default         string            `yaml:"default"`           // Scope for writers not listed under plugins
+	Plugins         map[string]string `yaml:"plugins"`           // Per-writer scope
+	ReadOnlyDomains []string          `yaml:"read_only_domains"` // Service domains no writer may call
+}
+
+// Config represents the write_scopes_config.yaml structure
+type Config struct {
+	WriteScopes Settings `yaml:"write_scopes"`
+}
+
+// Validate checks scope values and writer names
+func (c *Config) Validate() error {
+	if !validScope(c.WriteScopes.Default) {
+		return fmt.Errorf("default must be %s or %s, got %q", ReadOnly, ReadWrite, c.WriteScopes.Default)
+	}
+
+	known := make(map[string]bool, len(Writers))
+	for _, name := range Writers {
+		known[name] = true
+	}
+	for name, scope := range c.WriteScopes.Plugins {
+		if !known[name] {
+			return fmt.Errorf("plugins: unknown plugin %q", name)
+		}
+		if !validScope(scope) {
+			return fmt.Errorf("plugins: %s must be %s or %s, got %q", name, ReadOnly, ReadWrite, scope)
+		}
+	}
+	for i, domain := range c.WriteScopes.ReadOnlyDomains {
+		if domain == "" || strings.Contains(domain, ".") {
+			return fmt.Errorf("read_only_domains[%d]: %q is not a domain", i, domain)
+		}
+	}
+	return nil
+}
+
+func validScope(scope string) bool {
+	return scope == ReadOnly || scope == ReadWrite
+}
+
+// DefaultConfig returns the scopes used without a config file: everything
+// read-write, or everything read-only when readOnly is set
+func DefaultConfig(readOnly bool) *Config {
+	scope := ReadWrite
+	if readOnly {
+		scope = ReadOnly
+	}
+	return &Config{WriteScopes: Settings{Default: scope}}
+}
+
+// ApplyEnv overrides the config from environment variables:
+//   - READ_ONLY=true|false sets the default scope
+//   - WRITE_PLUGINS and READ_ONLY_PLUGINS (comma-separated) set writers
+//     read-write or read-only
+//   - READ_ONLY_DOMAINS (comma-separated) adds read-only domains
+//
+// The result is validated.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	switch getenv("READ_ONLY") {
+	case "true":
+		c.WriteScopes.Default = ReadOnly
+	case "false":
+		c.WriteScopes.Default = ReadWrite
+	}
+
+	// Read-only last, so a writer listed in both stays read-only
+	for _, env := range []struct{ name, scope string }{{"WRITE_PLUGINS", ReadWrite}, {"READ_ONLY_PLUGINS", ReadOnly}} {
+		scope := env.scope
+		for _, name := range splitList(getenv(env.name)) {
+			if c.WriteScopes.Plugins == nil {
+				c.WriteScopes.Plugins = make(map[string]string)
+			}
+			c.WriteScopes.Plugins[name] = scope
+		}
+	}
+	c.WriteScopes.ReadOnlyDomains = append(c.WriteScopes.ReadOnlyDomains, splitList(getenv("READ_ONLY_DOMAINS"))...)
+
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("write scopes from environment: %w", err)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// LoadConfig loads the write scope configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
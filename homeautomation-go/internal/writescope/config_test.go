@@ -0,0 +1,67 @@
+package writescope
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := &Config{WriteScopes: Settings{
+		Default:         ReadOnly,
+		Plugins:         map[string]string{"lighting": ReadWrite, "state": ReadOnly},
+		ReadOnlyDomains: []string{"lock", "cover"},
+	}}
+	assert.NoError(t, valid.Validate())
+
+	tests := []struct {
+		name     string
+		settings Settings
+		want     string
+	}{
+		{"missing default", Settings{}, "default must be"},
+		{"unknown plugin", Settings{Default: ReadWrite, Plugins: map[string]string{"lights": ReadWrite}}, `unknown plugin "lights"`},
+		{"bad plugin scope", Settings{Default: ReadWrite, Plugins: map[string]string{"music": "yes"}}, "music must be"},
+		{"entity instead of domain", Settings{Default: ReadWrite, ReadOnlyDomains: []string{"cover.garage_door"}}, "is not a domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{WriteScopes: tt.settings}).Validate()
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"READ_ONLY":         "true",
+		"WRITE_PLUGINS":     "lighting, music",
+		"READ_ONLY_PLUGINS": "music",
+		"READ_ONLY_DOMAINS": "lock,cover",
+	}
+	config := DefaultConfig(false)
+	require.NoError(t, config.ApplyEnv(func(key string) string { return env[key] }))
+
+	assert.Equal(t, ReadOnly, config.WriteScopes.Default)
+	assert.Equal(t, map[string]string{"lighting": ReadWrite, "music": ReadOnly}, config.WriteScopes.Plugins,
+		"read-only wins for a plugin listed in both")
+	assert.Equal(t, []string{"lock", "cover"}, config.WriteScopes.ReadOnlyDomains)
+}
+
+func TestConfig_ApplyEnvRejectsUnknownPlugin(t *testing.T) {
+	config := DefaultConfig(true)
+	err := config.ApplyEnv(func(key string) string {
+		if key == "WRITE_PLUGINS" {
+			return "lights"
+		}
+		return ""
+	})
+	assert.ErrorContains(t, err, `unknown plugin "lights"`)
+}
+
+func TestLoadConfig_ProjectFile(t *testing.T) {
+	config, err := LoadConfig("../../../configs/write_scopes_config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, ReadWrite, config.WriteScopes.Default)
+}
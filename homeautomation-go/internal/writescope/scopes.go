@@ -0,0 +1,209 @@
+// Package writescope decides which plugins may write to Home Assistant. Each
+// plugin (and the state manager and MQTT bridge) is read-only or read-write,
+// and some service domains can be kept read-only for everyone, so e.g.
+// lighting can be tested for real while locks and garage doors stay untouched.
+// Plugins still honour their own read-only flag; the client wrapper is the
+// central check that drops and logs anything that gets past it.
+package writescope
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+
+	"go.uber.org/zap"
+)
+
+// Scopes answers write scope questions for every writer.
+// All methods are safe to call on a nil Scopes, which allows every write.
+type Scopes struct {
+	config          *Config
+	readOnlyDomains map[string]bool
+	logger          *zap.Logger
+
+	// Denied writes per writer (protected by mu)
+	denied map[string]int
+	mu     sync.Mutex
+}
+
+// New creates scopes from a validated config
+func New(config *Config, logger *zap.Logger) *Scopes {
+	domains := make(map[string]bool, len(config.WriteScopes.ReadOnlyDomains))
+	for _, domain := range config.WriteScopes.ReadOnlyDomains {
+		domains[domain] = true
+	}
+	return &Scopes{
+		config:          config,
+		readOnlyDomains: domains,
+		logger:          logger.Named("writescope"),
+		denied:          make(map[string]int),
+	}
+}
+
+// ReadOnly reports whether a writer is read-only
+func (s *Scopes) ReadOnly(writer string) bool {
+	if s == nil {
+		return false
+	}
+	scope, ok := s.config.WriteScopes.Plugins[writer]
+	if !ok {
+		scope = s.config.WriteScopes.Default
+	}
+	return scope == ReadOnly
+}
+
+// DomainReadOnly reports whether a service domain is closed to every writer
+func (s *Scopes) DomainReadOnly(domain string) bool {
+	return s != nil && s.readOnlyDomains[domain]
+}
+
+// Denied returns how many writes each writer had denied
+func (s *Scopes) Denied() map[string]int {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int, len(s.denied))
+	for writer, count := range s.denied {
+		counts[writer] = count
+	}
+	return counts
+}
+
+// deny reports whether a writer's call touching domains should be dropped,
+// logging it if so
+func (s *Scopes) deny(writer string, domains []string, action string, fields ...zap.Field) bool {
+	reason := ""
+	if s.ReadOnly(writer) {
+		reason = "plugin is read-only"
+	}
+	for _, domain := range domains {
+		if reason == "" && s.DomainReadOnly(domain) {
+			reason = "domain " + domain + " is read-only"
+		}
+	}
+	if reason == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	s.denied[writer]++
+	s.mu.Unlock()
+
+	s.logger.Warn("READ-ONLY: Denied write",
+		append([]zap.Field{
+			zap.String("plugin", writer),
+			zap.String("action", action),
+			zap.String("reason", reason),
+		}, fields...)...)
+	return true
+}
+
+// Client wraps a writer's HA client so service calls and input helper writes
+// outside its scope are dropped and logged. Reads and subscriptions pass
+// through. When nothing could be denied, client is returned unchanged.
+func (s *Scopes) Client(writer string, client ha.HAClient) ha.HAClient {
+	if s == nil || (!s.ReadOnly(writer) && len(s.readOnlyDomains) == 0) {
+		return client
+	}
+	return &scopedClient{HAClient: client, scopes: s, writer: writer}
+}
+
+// scopedClient enforces a writer's scope on its HA client
+type scopedClient struct {
+	ha.HAClient
+	scopes *Scopes
+	writer string
+}
+
+// CallService drops calls outside the writer's scope, reporting success. Both
+// the service's domain and the domains of its target entities are checked, so
+// homeassistant.turn_on can't open a read-only cover.
+func (c *scopedClient) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	if c.scopes.deny(c.writer, append([]string{domain}, c.targetDomains(data)...), domain+"."+service, zap.Any("data", data)) {
+		return nil
+	}
+	return c.HAClient.CallService(ctx, domain, service, data)
+}
+
+// SetInputBoolean drops the write outside the writer's scope
+func (c *scopedClient) SetInputBoolean(ctx context.Context, name string, value bool) error {
+	if c.scopes.deny(c.writer, []string{"input_boolean"}, "input_boolean."+name, zap.Bool("value", value)) {
+		return nil
+	}
+	return c.HAClient.SetInputBoolean(ctx, name, value)
+}
+
+// SetInputNumber drops the write outside the writer's scope
+func (c *scopedClient) SetInputNumber(ctx context.Context, name string, value float64) error {
+	if c.scopes.deny(c.writer, []string{"input_number"}, "input_number."+name, zap.Float64("value", value)) {
+		return nil
+	}
+	return c.HAClient.SetInputNumber(ctx, name, value)
+}
+
+// SetInputText drops the write outside the writer's scope
+func (c *scopedClient) SetInputText(ctx context.Context, name string, value string) error {
+	if c.scopes.deny(c.writer, []string{"input_text"}, "input_text."+name, zap.String("value", value)) {
+		return nil
+	}
+	return c.HAClient.SetInputText(ctx, name, value)
+}
+
+// targetDomains returns the domains of the call's entity_id targets, with
+// entity group references expanded. If a reference can't be expanded, the
+// call fails on it further down anyway.
+func (c *scopedClient) targetDomains(data map[string]interface{}) []string {
+	var ids []string
+	switch v := data["entity_id"].(type) {
+	case string:
+		ids = []string{v}
+	case []string:
+		ids = v
+	case []interface{}:
+		for _, item := range v {
+			if id, ok := item.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	expanded, err := entitygroups.Expand(c.HAClient, ids)
+	if err != nil {
+		expanded = ids
+	}
+	domains := make([]string, 0, len(expanded))
+	for _, id := range expanded {
+		if domain, _, ok := strings.Cut(id, "."); ok {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// Expand keeps entity group references working through the wrapper
+func (c *scopedClient) Expand(ids []string) ([]string, error) {
+	return entitygroups.Expand(c.HAClient, ids)
+}
+
+// String describes the scopes for the startup log, e.g.
+// "default=read_only lighting=read_write read_only_domains=cover,lock"
+func (s *Scopes) String() string {
+	if s == nil {
+		return "default=" + ReadWrite
+	}
+	parts := []string{"default=" + s.config.WriteScopes.Default}
+	for _, writer := range Writers {
+		if scope, ok := s.config.WriteScopes.Plugins[writer]; ok {
+			parts = append(parts, writer+"="+scope)
+		}
+	}
+	if len(s.config.WriteScopes.ReadOnlyDomains) > 0 {
+		parts = append(parts, "read_only_domains="+strings.Join(s.config.WriteScopes.ReadOnlyDomains, ","))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,103 @@
+package writescope
+
+import (
+	"context"
+	"testing"
+
+	"homeautomation/internal/entitygroups"
+	"homeautomation/internal/ha"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestScopes(t *testing.T) *Scopes {
+	t.Helper()
+	config := &Config{WriteScopes: Settings{
+		Default:         ReadOnly,
+		Plugins:         map[string]string{"lighting": ReadWrite, "security": ReadWrite},
+		ReadOnlyDomains: []string{"cover", "lock"},
+	}}
+	require.NoError(t, config.Validate())
+	return New(config, zap.NewNop())
+}
+
+func TestScopes_ReadOnly(t *testing.T) {
+	scopes := newTestScopes(t)
+	assert.False(t, scopes.ReadOnly("lighting"))
+	assert.True(t, scopes.ReadOnly("music"), "unlisted plugins get the default")
+	assert.True(t, scopes.DomainReadOnly("lock"))
+	assert.False(t, scopes.DomainReadOnly("light"))
+
+	var none *Scopes
+	assert.False(t, none.ReadOnly("music"), "nil scopes allow every write")
+	assert.False(t, none.DomainReadOnly("lock"))
+}
+
+func TestScopes_ClientAllowsWritablePlugin(t *testing.T) {
+	scopes := newTestScopes(t)
+	mockClient := ha.NewMockClient()
+	client := scopes.Client("lighting", mockClient)
+
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.kitchen"}))
+	assert.Len(t, mockClient.GetServiceCalls(), 1)
+	assert.Empty(t, scopes.Denied())
+}
+
+func TestScopes_ClientDeniesReadOnlyPlugin(t *testing.T) {
+	scopes := newTestScopes(t)
+	mockClient := ha.NewMockClient()
+	client := scopes.Client("music", mockClient)
+
+	require.NoError(t, client.CallService(context.Background(), "media_player", "play_media", map[string]interface{}{"entity_id": "media_player.kitchen"}))
+	require.NoError(t, client.SetInputBoolean(context.Background(), "music_playing", true))
+	assert.Empty(t, mockClient.GetServiceCalls())
+	assert.Equal(t, map[string]int{"music": 2}, scopes.Denied())
+}
+
+func TestScopes_ClientDeniesReadOnlyDomains(t *testing.T) {
+	scopes := newTestScopes(t)
+	mockClient := ha.NewMockClient()
+	client := scopes.Client("security", mockClient)
+
+	require.NoError(t, client.CallService(context.Background(), "cover", "close_cover", map[string]interface{}{"entity_id": "cover.garage_door"}))
+	require.NoError(t, client.CallService(context.Background(), "homeassistant", "turn_on", map[string]interface{}{
+		"entity_id": []interface{}{"light.porch", "lock.front_door"},
+	}))
+	assert.Empty(t, mockClient.GetServiceCalls(), "target entities in read-only domains deny the call")
+
+	require.NoError(t, client.CallService(context.Background(), "light", "turn_on", map[string]interface{}{"entity_id": "light.porch"}))
+	assert.Len(t, mockClient.GetServiceCalls(), 1)
+	assert.Equal(t, map[string]int{"security": 2}, scopes.Denied())
+}
+
+func TestScopes_ClientChecksExpandedGroups(t *testing.T) {
+	scopes := newTestScopes(t)
+	mockClient := ha.NewMockClient()
+	groups := &entitygroups.Config{EntityGroups: map[string][]string{"garage": {"cover.garage_door"}}}
+	client := scopes.Client("security", entitygroups.NewClient(mockClient, groups))
+
+	require.NoError(t, client.CallService(context.Background(), "homeassistant", "turn_off", map[string]interface{}{
+		"entity_id": entitygroups.Group("garage"),
+	}))
+	assert.Empty(t, mockClient.GetServiceCalls())
+
+	ids, err := entitygroups.Expand(client, []string{entitygroups.Group("garage")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cover.garage_door"}, ids)
+}
+
+func TestScopes_ClientUnchangedWhenNothingDenied(t *testing.T) {
+	config := DefaultConfig(false)
+	scopes := New(config, zap.NewNop())
+	mockClient := ha.NewMockClient()
+	assert.Same(t, mockClient, scopes.Client("music", mockClient))
+
+	var none *Scopes
+	assert.Same(t, mockClient, none.Client("music", mockClient))
+}
+
+func TestScopes_String(t *testing.T) {
+	assert.Equal(t, "default=read_only lighting=read_write security=read_write read_only_domains=cover,lock", newTestScopes(t).String())
+}
@@ -1,6 +1,8 @@
 package ha
 
 import (
+	"context"
+
 	"homeautomation/internal/ha"
 )
 
@@ -36,8 +38,8 @@ func UnwrapClient(c Client) ha.HAClient {
 	return nil
 }
 
-func (a *ClientAdapter) Connect() error {
-	return a.internal.Connect()
+func (a *ClientAdapter) Connect(ctx context.Context) error {
+	return a.internal.Connect(ctx)
 }
 
 func (a *ClientAdapter) Disconnect() error {
@@ -48,16 +50,16 @@ func (a *ClientAdapter) IsConnected() bool {
 	return a.internal.IsConnected()
 }
 
-func (a *ClientAdapter) GetState(entityID string) (*State, error) {
-	s, err := a.internal.GetState(entityID)
+func (a *ClientAdapter) GetState(ctx context.Context, entityID string) (*State, error) {
+	s, err := a.internal.GetState(ctx, entityID)
 	if err != nil {
 		return nil, err
 	}
 	return internalToState(s), nil
 }
 
-func (a *ClientAdapter) GetAllStates() ([]*State, error) {
-	states, err := a.internal.GetAllStates()
+func (a *ClientAdapter) GetAllStates(ctx context.Context) ([]*State, error) {
+	states, err := a.internal.GetAllStates(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -68,8 +70,8 @@ func (a *ClientAdapter) GetAllStates() ([]*State, error) {
 	return result, nil
 }
 
-func (a *ClientAdapter) CallService(domain, service string, data map[string]interface{}) error {
-	return a.internal.CallService(domain, service, data)
+func (a *ClientAdapter) CallService(ctx context.Context, domain, service string, data map[string]interface{}) error {
+	return a.internal.CallService(ctx, domain, service, data)
 }
 
 func (a *ClientAdapter) SubscribeStateChanges(entityID string, handler StateChangeHandler) (Subscription, error) {
@@ -80,14 +82,14 @@ func (a *ClientAdapter) SubscribeStateChanges(entityID string, handler StateChan
 	return a.internal.SubscribeStateChanges(entityID, internalHandler)
 }
 
-func (a *ClientAdapter) SetInputBoolean(name string, value bool) error {
-	return a.internal.SetInputBoolean(name, value)
+func (a *ClientAdapter) SetInputBoolean(ctx context.Context, name string, value bool) error {
+	return a.internal.SetInputBoolean(ctx, name, value)
 }
 
-func (a *ClientAdapter) SetInputNumber(name string, value float64) error {
-	return a.internal.SetInputNumber(name, value)
+func (a *ClientAdapter) SetInputNumber(ctx context.Context, name string, value float64) error {
+	return a.internal.SetInputNumber(ctx, name, value)
 }
 
-func (a *ClientAdapter) SetInputText(name string, value string) error {
-	return a.internal.SetInputText(name, value)
+func (a *ClientAdapter) SetInputText(ctx context.Context, name string, value string) error {
+	return a.internal.SetInputText(ctx, name, value)
 }
@@ -7,6 +7,7 @@
 package ha
 
 import (
+	"context"
 	"time"
 )
 
@@ -29,15 +30,17 @@ type Subscription interface {
 
 // Client defines the interface for Home Assistant WebSocket client.
 // This interface matches internal/ha.HAClient and can be used by external packages.
+// Requests made with a context that has no deadline are bounded by the
+// client's default request timeout.
 type Client interface {
-	Connect() error
+	Connect(ctx context.Context) error
 	Disconnect() error
 	IsConnected() bool
-	GetState(entityID string) (*State, error)
-	GetAllStates() ([]*State, error)
-	CallService(domain, service string, data map[string]interface{}) error
+	GetState(ctx context.Context, entityID string) (*State, error)
+	GetAllStates(ctx context.Context) ([]*State, error)
+	CallService(ctx context.Context, domain, service string, data map[string]interface{}) error
 	SubscribeStateChanges(entityID string, handler StateChangeHandler) (Subscription, error)
-	SetInputBoolean(name string, value bool) error
-	SetInputNumber(name string, value float64) error
-	SetInputText(name string, value string) error
+	SetInputBoolean(ctx context.Context, name string, value bool) error
+	SetInputNumber(ctx context.Context, name string, value float64) error
+	SetInputText(ctx context.Context, name string, value string) error
 }
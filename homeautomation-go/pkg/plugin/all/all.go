@@ -0,0 +1,19 @@
+// Package all registers the built-in plugins that can be overridden with the
+// global plugin registry. Only the security plugin registers itself;
+// the other built-ins are constructed directly by cmd/main.go, which doesn't
+// read the registry. The built-in plugins live under internal/, which code
+// outside this module can't import, so a private build imports this package
+// for the reference plugins and its own packages for overrides:
+//
+//	import (
+//		_ "homeautomation/pkg/plugin/all"
+//		_ "example.com/private/security" // Registers "security" with plugin.PriorityOverride
+//	)
+//
+// and creates them with plugin.CreateAll. An override registered with a
+// higher priority wins whichever package's init runs first.
+package all
+
+import (
+	_ "homeautomation/internal/plugins/security" // Registers "security"
+)
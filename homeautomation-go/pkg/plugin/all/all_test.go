@@ -0,0 +1,48 @@
+package all_test
+
+import (
+	"testing"
+
+	"homeautomation/pkg/plugin"
+	_ "homeautomation/pkg/plugin/all"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type overridePlugin struct{}
+
+func (overridePlugin) Name() string { return "security" }
+func (overridePlugin) Start() error { return nil }
+func (overridePlugin) Stop()        {}
+
+func TestAll_RegistersBuiltInPlugins(t *testing.T) {
+	info := plugin.Get("security")
+	require.NotNil(t, info, "security plugin should be registered by importing all")
+	assert.Equal(t, plugin.PriorityDefault, info.Priority)
+}
+
+func TestAll_OverrideTakesPriority(t *testing.T) {
+	// A copy of the global registry, so the override doesn't leak into other tests
+	builtIn := plugin.Get("security")
+	require.NotNil(t, builtIn)
+	registry := plugin.NewRegistry()
+	require.NoError(t, registry.Register(*builtIn))
+
+	factory := func(ctx *plugin.Context) (plugin.Plugin, error) { return overridePlugin{}, nil }
+	require.NoError(t, registry.Register(plugin.PluginInfo{
+		Name:        "security",
+		Description: "Private security plugin",
+		Priority:    plugin.PriorityOverride,
+		Factory:     factory,
+	}))
+
+	info := registry.Get("security")
+	require.NotNil(t, info)
+	assert.Equal(t, "Private security plugin", info.Description)
+
+	// A later default-priority registration doesn't replace the override
+	require.NoError(t, registry.Register(*builtIn))
+	assert.Equal(t, "Private security plugin", registry.Get("security").Description)
+	assert.Equal(t, plugin.PriorityDefault, plugin.Get("security").Priority, "the global registry is untouched")
+}
@@ -4,8 +4,6 @@
 // and override mechanisms for private implementations.
 package plugin
 
-import "homeautomation/internal/shadowstate"
-
 // Plugin is the core interface that all plugins must implement.
 // Plugins are responsible for automation logic in a specific domain
 // (e.g., security, lighting, music).
@@ -43,8 +41,10 @@ type Resettable interface {
 // led to each action, enabling debugging and verification.
 type ShadowStateProvider interface {
 	// GetShadowState returns the current shadow state for the plugin.
-	// The returned state captures recent decisions and their triggering inputs.
-	GetShadowState() shadowstate.PluginShadowState
+	// The returned state captures recent decisions and their triggering inputs
+	// and must encode to JSON. It is untyped so plugins outside this module,
+	// which can't import the internal shadow state types, can implement it.
+	GetShadowState() interface{}
 }
 
 // Factory is a function that creates a new plugin instance given a context.
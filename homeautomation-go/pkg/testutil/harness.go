@@ -3,6 +3,7 @@
 package testutil
 
 import (
+	"context"
 	"fmt"
 
 	"homeautomation/internal/ha"
@@ -53,7 +54,7 @@ func NewTestEnv(addr, token string) (*TestEnv, error) {
 
 	// Create and connect client
 	client := ha.NewClient(fmt.Sprintf("ws://%s/api/websocket", addr), token, logger)
-	if err := client.Connect(); err != nil {
+	if err := client.Connect(context.Background()); err != nil {
 		server.Stop()
 		return nil, fmt.Errorf("failed to connect client: %w", err)
 	}
@@ -81,7 +82,7 @@ func NewTestEnv(addr, token string) (*TestEnv, error) {
 // isAnyoneHome, isEveryoneAsleep, etc.
 func (e *TestEnv) StartStateTracking() error {
 	e.stateTracking = statetracking.NewManager(e.internalClient, e.internalStateManager, e.Logger, false, nil)
-	return e.stateTracking.Start()
+	return e.stateTracking.Start(context.Background())
 }
 
 // InitializeSecurityStates sets up common initial states for security plugin testing.
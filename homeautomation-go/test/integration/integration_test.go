@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -32,7 +33,7 @@ func setupTest(t *testing.T) (*MockHAServer, *ha.Client, *state.Manager, func())
 
 	// Create and connect client
 	client := ha.NewClient(fmt.Sprintf("ws://%s/api/websocket", testAddr), testToken, logger)
-	err = client.Connect()
+	err = client.Connect(context.Background())
 	require.NoError(t, err)
 
 	// Create state manager
@@ -425,7 +426,7 @@ func TestReconnection(t *testing.T) {
 
 	// Connect client
 	client := ha.NewClient(fmt.Sprintf("ws://%s/api/websocket", testAddr), testToken, logger)
-	err = client.Connect()
+	err = client.Connect(context.Background())
 	require.NoError(t, err)
 
 	assert.True(t, client.IsConnected())
@@ -474,14 +475,14 @@ func TestReconnectionMessageIDReset(t *testing.T) {
 
 	// Connect client
 	client := ha.NewClient(fmt.Sprintf("ws://%s/api/websocket", testAddr), testToken, logger)
-	err = client.Connect()
+	err = client.Connect(context.Background())
 	require.NoError(t, err)
 	require.True(t, client.IsConnected())
 
 	// Send several messages to increment message ID counter
 	t.Log("Sending initial messages to increment message ID counter...")
 	for i := 0; i < 10; i++ {
-		err = client.SetInputBoolean("nick_home", i%2 == 0)
+		err = client.SetInputBoolean(context.Background(), "nick_home", i%2 == 0)
 		require.NoError(t, err, "Message %d should succeed before reconnection", i)
 	}
 
@@ -517,7 +518,7 @@ func TestReconnectionMessageIDReset(t *testing.T) {
 	// because the new session expects IDs to start from 1, not continue from 11+
 	t.Log("Sending messages after reconnection (testing message ID reset)...")
 	for i := 0; i < 10; i++ {
-		err = client.SetInputBoolean("nick_home", i%2 == 1)
+		err = client.SetInputBoolean(context.Background(), "nick_home", i%2 == 1)
 		assert.NoError(t, err, "Message %d should succeed after reconnection (message IDs should be reset)", i)
 		if err != nil {
 			t.Logf("ERROR: Failed to send message after reconnection: %v", err)
@@ -528,14 +529,14 @@ func TestReconnectionMessageIDReset(t *testing.T) {
 
 	// Also test with different service types to ensure ID reset works for all message types
 	t.Log("Testing different service types after reconnection...")
-	err = client.SetInputNumber("test_number", 42.5)
+	err = client.SetInputNumber(context.Background(), "test_number", 42.5)
 	assert.NoError(t, err, "SetInputNumber should work after reconnection")
 
-	err = client.SetInputText("test_text", "reconnection_test")
+	err = client.SetInputText(context.Background(), "test_text", "reconnection_test")
 	assert.NoError(t, err, "SetInputText should work after reconnection")
 
 	// Verify we can still read state (GetState uses message IDs too)
-	state, err := client.GetState("input_boolean.nick_home")
+	state, err := client.GetState(context.Background(), "input_boolean.nick_home")
 	assert.NoError(t, err, "GetState should work after reconnection")
 	assert.NotNil(t, state, "State should not be nil")
 
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -36,7 +37,7 @@ func setupComputedStateTest(t *testing.T) (*MockHAServer, *ha.Client, *state.Man
 
 	// Create and connect client
 	client := ha.NewClient(fmt.Sprintf("ws://%s/api/websocket", testAddr), testToken, logger)
-	err = client.Connect()
+	err = client.Connect(context.Background())
 	require.NoError(t, err)
 
 	// Create state manager
@@ -113,7 +114,7 @@ func TestScenario_ComputedState_IsAnyoneHomeAndAwake_InitialComputation(t *testi
 
 			// Create and connect client
 			client := ha.NewClient(fmt.Sprintf("ws://%s/api/websocket", testAddr), testToken, logger)
-			err = client.Connect()
+			err = client.Connect(context.Background())
 			require.NoError(t, err)
 			defer client.Disconnect()
 
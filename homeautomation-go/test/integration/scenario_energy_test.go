@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 	"time"
@@ -40,7 +41,7 @@ func setupEnergyScenarioTest(t *testing.T) (*MockHAServer, *energy.Manager, *sta
 	energyMgr := energy.NewManager(client, manager, energyConfig, logger, false, timezone, nil)
 
 	// Start the energy plugin
-	err = energyMgr.Start()
+	err = energyMgr.Start(context.Background())
 	require.NoError(t, err, "Failed to start energy manager")
 
 	// Give the plugin time to initialize
@@ -181,7 +182,7 @@ func TestScenario_GridAvailability_RecalculatesFreeEnergy(t *testing.T) {
 
 	// Create energy plugin (NOT read-only so it can set states)
 	energyMgr := energy.NewManager(client, manager, energyConfig, logger, false, testTimezone, nil)
-	err = energyMgr.Start()
+	err = energyMgr.Start(context.Background())
 	require.NoError(t, err, "Failed to start energy manager")
 	defer energyMgr.Stop()
 
@@ -256,7 +257,7 @@ func TestScenario_OverallEnergyLevel_ReflectsWorstState(t *testing.T) {
 
 	// Create energy plugin
 	energyMgr := energy.NewManager(client, manager, energyConfig, logger, false, testTimezone, nil)
-	err = energyMgr.Start()
+	err = energyMgr.Start(context.Background())
 	require.NoError(t, err, "Failed to start energy manager")
 	defer energyMgr.Stop()
 
@@ -338,7 +339,7 @@ func TestScenario_FreeEnergyTimeWindow_OverridesEnergyLevel(t *testing.T) {
 
 	logger, _ := zap.NewDevelopment()
 	energyMgr := energy.NewManager(client, manager, energyConfig, logger, false, testTimezone, nil)
-	err = energyMgr.Start()
+	err = energyMgr.Start(context.Background())
 	require.NoError(t, err)
 	defer energyMgr.Stop()
 
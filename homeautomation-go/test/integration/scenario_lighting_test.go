@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 	"time"
@@ -35,7 +36,7 @@ func setupLightingScenarioTest(t *testing.T) (*MockHAServer, *lighting.Manager,
 	lightingMgr := lighting.NewManager(client, manager, lightingConfig, logger, false, nil)
 
 	// Start the lighting plugin
-	err = lightingMgr.Start()
+	err = lightingMgr.Start(context.Background())
 	require.NoError(t, err, "Failed to start lighting manager")
 
 	cleanup := func() {
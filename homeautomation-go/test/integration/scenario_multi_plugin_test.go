@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -58,10 +59,10 @@ func setupMultiPluginTest(t *testing.T) (*pluginTestEnv, func()) {
 	}
 
 	// Start all plugins (state tracking MUST start first as other plugins depend on derived states)
-	require.NoError(t, env.stateTracking.Start(), "Failed to start state tracking plugin")
-	require.NoError(t, env.lighting.Start(), "Failed to start lighting plugin")
-	require.NoError(t, env.tv.Start(), "Failed to start TV plugin")
-	require.NoError(t, env.energy.Start(), "Failed to start energy plugin")
+	require.NoError(t, env.stateTracking.Start(context.Background()), "Failed to start state tracking plugin")
+	require.NoError(t, env.lighting.Start(context.Background()), "Failed to start lighting plugin")
+	require.NoError(t, env.tv.Start(context.Background()), "Failed to start TV plugin")
+	require.NoError(t, env.energy.Start(context.Background()), "Failed to start energy plugin")
 
 	// Allow plugins to initialize
 	time.Sleep(300 * time.Millisecond)
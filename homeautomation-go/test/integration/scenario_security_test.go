@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -23,11 +24,11 @@ func setupSecurityScenarioTest(t *testing.T) (*MockHAServer, *statetracking.Mana
 
 	// Create and start State Tracking plugin (must start before Security)
 	stateTracking := statetracking.NewManager(client, stateManager, logger, false, nil)
-	require.NoError(t, stateTracking.Start(), "State Tracking manager should start successfully")
+	require.NoError(t, stateTracking.Start(context.Background()), "State Tracking manager should start successfully")
 
 	// Create and start Security plugin
 	securityManager := security.NewManager(client, stateManager, logger, false, nil)
-	require.NoError(t, securityManager.Start(), "Security manager should start successfully")
+	require.NoError(t, securityManager.Start(context.Background()), "Security manager should start successfully")
 
 	cleanup := func() {
 		securityManager.Stop()
@@ -51,12 +52,12 @@ func setupSecurityScenarioTestWithMockClock(t *testing.T) (*MockHAServer, *state
 	// Create and start State Tracking plugin with mock clock
 	stateTracking := statetracking.NewManager(client, stateManager, logger, false, nil)
 	stateTracking.SetClock(mockClock)
-	require.NoError(t, stateTracking.Start(), "State Tracking manager should start successfully")
+	require.NoError(t, stateTracking.Start(context.Background()), "State Tracking manager should start successfully")
 
 	// Create and start Security plugin with mock clock
 	securityManager := security.NewManager(client, stateManager, logger, false, nil)
 	securityManager.SetClock(mockClock)
-	require.NoError(t, securityManager.Start(), "Security manager should start successfully")
+	require.NoError(t, securityManager.Start(context.Background()), "Security manager should start successfully")
 
 	cleanup := func() {
 		securityManager.Stop()
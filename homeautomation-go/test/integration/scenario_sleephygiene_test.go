@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -34,7 +35,7 @@ func setupSleepHygieneScenarioTest(t *testing.T) (*MockHAServer, *sleephygiene.M
 	sleepMgr := sleephygiene.NewManager(client, manager, configLoader, logger, false, nil)
 
 	// Start the sleep hygiene plugin
-	err := sleepMgr.Start()
+	err := sleepMgr.Start(context.Background())
 	require.NoError(t, err, "Failed to start sleep hygiene manager")
 
 	cleanup := func() {
@@ -62,7 +63,7 @@ func setupSleepHygieneScenarioTestWithTime(t *testing.T, fixedTime time.Time) (*
 	sleepMgr := sleephygiene.NewManager(client, manager, configLoader, logger, false, timeProvider)
 
 	// Start the sleep hygiene plugin
-	err := sleepMgr.Start()
+	err := sleepMgr.Start(context.Background())
 	require.NoError(t, err, "Failed to start sleep hygiene manager")
 
 	cleanup := func() {
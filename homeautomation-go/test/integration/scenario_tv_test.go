@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -21,7 +22,7 @@ func setupTVScenarioTest(t *testing.T) (*MockHAServer, *state.Manager, func()) {
 
 	// Create and start TV plugin
 	tvManager := tv.NewManager(client, stateManager, logger, false, nil)
-	require.NoError(t, tvManager.Start(), "TV manager should start successfully")
+	require.NoError(t, tvManager.Start(context.Background()), "TV manager should start successfully")
 
 	cleanup := func() {
 		tvManager.Stop()